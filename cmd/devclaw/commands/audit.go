@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newAuditCmd creates the `devclaw audit` command for reading the tool
+// audit log.
+func newAuditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Inspect the tool execution audit log",
+		Long: `Inspect the JSONL audit log the tool guard writes for every tool call:
+tool, caller, access level, outcome, a hash of the arguments, duration and
+result size.
+
+Examples:
+  devclaw audit query --tool bash --since 24h --denied
+  devclaw audit query --caller 5511999999999@s.whatsapp.net -n 100
+  devclaw audit query --outcome timeout --json`,
+	}
+	cmd.AddCommand(newAuditQueryCmd())
+	return cmd
+}
+
+func newAuditQueryCmd() *cobra.Command {
+	var (
+		lines   int
+		asJSON  bool
+		tools   []string
+		caller  string
+		outcome string
+		since   time.Duration
+		denied  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Print audit entries matching the filters",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path := auditLogPath(cmd)
+
+			filter := copilot.AuditFilter{
+				Tools:   tools,
+				Caller:  caller,
+				Denied:  denied,
+				Outcome: outcome,
+				Limit:   lines,
+			}
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+
+			entries, err := copilot.ReadAuditEntries(path, filter)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+			for _, e := range entries {
+				if asJSON {
+					data, _ := json.Marshal(e)
+					fmt.Println(string(data))
+					continue
+				}
+				fmt.Println(e.Format())
+			}
+			if len(entries) == 0 && !asJSON {
+				fmt.Fprintf(os.Stderr, "No matching entries in %s\n", path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&lines, "lines", "n", 50, "number of most recent matches to print (0 = all)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print entries as JSON lines")
+	cmd.Flags().StringSliceVar(&tools, "tool", nil, "only calls of this tool (repeatable)")
+	cmd.Flags().StringVar(&caller, "caller", "", "only calls made by this caller JID")
+	cmd.Flags().StringVar(&outcome, "outcome", "", "only calls with this outcome (ok, error, timeout, denied)")
+	cmd.Flags().DurationVar(&since, "since", 0, "only calls newer than this (e.g. 1h, 24h)")
+	cmd.Flags().BoolVar(&denied, "denied", false, "only calls the guard or the user refused")
+	return cmd
+}
+
+// auditLogPath returns the configured audit log path.
+func auditLogPath(cmd *cobra.Command) string {
+	if cfg, _, err := loadConfig(cmd); err == nil && cfg.Security.ToolGuard.AuditLogPath != "" {
+		return cfg.Security.ToolGuard.AuditLogPath
+	}
+	return copilot.DefaultAuditLogPath
+}
@@ -0,0 +1,598 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// auditListMax caps the entries listed per report section.
+const auditListMax = 10
+
+// auditDepsTimeout bounds the dependency update check, which hits the network.
+const auditDepsTimeout = 2 * time.Minute
+
+// newAuditRepoCmd creates the `devclaw audit-repo` command that writes a
+// repository health report.
+func newAuditRepoCmd() *cobra.Command {
+	var (
+		format    string
+		out       string
+		staleDays int
+		largeKB   int64
+		noDeps    bool
+		noSummary bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "audit-repo [path]",
+		Short: "Write a repository health report",
+		Long: `Scan a git repository and report on its health:
+
+  - stale branches (no commits for --stale-days)
+  - code paths without an owner in CODEOWNERS
+  - dependencies behind their latest release (Go modules, npm)
+  - large files tracked in git
+  - test-to-code ratio
+  - TODO/FIXME density
+
+The scanners are deterministic; the agent then writes an executive summary
+with the main risks and next steps (skip it with --no-summary, which needs
+no API key).
+
+Examples:
+  devclaw audit-repo
+  devclaw audit-repo ../service --stale-days 60 --out health.md
+  devclaw audit-repo --no-deps --no-summary --format json`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "markdown" && format != "json" {
+				return fmt.Errorf("unknown format %q (markdown or json)", format)
+			}
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+			if err != nil {
+				return fmt.Errorf("not inside a git repository: %w", err)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Fprintf(os.Stderr, "Auditing %s...\n", root)
+			report, err := auditRepo(ctx, root, repoAuditOptions{
+				StaleAfter: time.Duration(staleDays) * 24 * time.Hour,
+				LargeBytes: largeKB * 1024,
+				Deps:       !noDeps,
+			})
+			if err != nil {
+				return err
+			}
+
+			if !noSummary {
+				report.Summary, err = summarizeRepoAudit(cmd, report)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Skipping summary: %v\n", err)
+				}
+			}
+
+			var output string
+			if format == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				output = string(data) + "\n"
+			} else {
+				output = report.Markdown()
+			}
+
+			if out == "" {
+				fmt.Print(output)
+				return nil
+			}
+			if err := os.WriteFile(out, []byte(output), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Report written to %s\n", out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "markdown", "report format: markdown or json")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write the report to a file instead of stdout")
+	cmd.Flags().IntVar(&staleDays, "stale-days", 90, "branches without commits for this many days are stale")
+	cmd.Flags().Int64Var(&largeKB, "large-kb", 1024, "tracked files above this size (KB) are reported")
+	cmd.Flags().BoolVar(&noDeps, "no-deps", false, "skip the dependency update check (it needs network access)")
+	cmd.Flags().BoolVar(&noSummary, "no-summary", false, "don't ask the agent for an executive summary")
+	return cmd
+}
+
+// repoAuditOptions tunes the scanners.
+type repoAuditOptions struct {
+	StaleAfter time.Duration
+	LargeBytes int64
+	Deps       bool
+}
+
+// repoAudit is the health report of one repository.
+type repoAudit struct {
+	Root        string    `json:"root"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Summary     string    `json:"summary,omitempty"`
+
+	StaleBranches []staleBranch `json:"stale_branches"`
+	Branches      int           `json:"branches"`
+
+	CodeOwners    string         `json:"codeowners,omitempty"` // path of the CODEOWNERS file
+	UnownedPaths  []pathCount    `json:"unowned_paths"`
+	UnownedFiles  int            `json:"unowned_files"`
+	TrackedFiles  int            `json:"tracked_files"`
+	Dependencies  []depLag       `json:"dependencies"`
+	DepsChecked   []string       `json:"deps_checked,omitempty"` // ecosystems checked
+	DepsSkipped   string         `json:"deps_skipped,omitempty"`
+	LargeFiles    []largeFile    `json:"large_files"`
+	CodeLines     int            `json:"code_lines"`
+	TestLines     int            `json:"test_lines"`
+	CodeFiles     int            `json:"code_files"`
+	TestFiles     int            `json:"test_files"`
+	Markers       int            `json:"todo_markers"`
+	MarkerFiles   []pathCount    `json:"todo_files"`
+	MarkerPerKLOC float64        `json:"todo_per_kloc"`
+	Languages     map[string]int `json:"languages"` // code lines per extension
+}
+
+type staleBranch struct {
+	Name       string    `json:"name"`
+	LastCommit time.Time `json:"last_commit"`
+	Author     string    `json:"author"`
+}
+
+type pathCount struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+type depLag struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+}
+
+type largeFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// TestRatio returns test lines per code line.
+func (r *repoAudit) TestRatio() float64 {
+	if r.CodeLines == 0 {
+		return 0
+	}
+	return float64(r.TestLines) / float64(r.CodeLines)
+}
+
+// auditRepo runs every scanner on the repository at root.
+func auditRepo(ctx context.Context, root string, opts repoAuditOptions) (*repoAudit, error) {
+	files, err := gitOutput(root, "ls-files", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("listing tracked files: %w", err)
+	}
+	tracked := strings.Split(strings.TrimRight(files, "\x00"), "\x00")
+	if len(tracked) == 1 && tracked[0] == "" {
+		tracked = nil
+	}
+
+	r := &repoAudit{Root: root, GeneratedAt: time.Now(), TrackedFiles: len(tracked)}
+	if err := r.scanBranches(root, opts.StaleAfter); err != nil {
+		return nil, err
+	}
+	r.scanOwners(root, tracked)
+	r.scanFiles(root, tracked, opts.LargeBytes)
+	if opts.Deps {
+		r.scanDeps(ctx, root)
+	} else {
+		r.DepsSkipped = "disabled with --no-deps"
+	}
+	return r, nil
+}
+
+// scanBranches finds local and remote branches without recent commits.
+func (r *repoAudit) scanBranches(root string, staleAfter time.Duration) error {
+	out, err := gitOutput(root, "for-each-ref",
+		"--format=%(refname:short)%09%(committerdate:unix)%09%(authorname)", "refs/heads", "refs/remotes")
+	if err != nil {
+		return fmt.Errorf("listing branches: %w", err)
+	}
+	cutoff := time.Now().Add(-staleAfter)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		// Skip symbolic refs such as origin/HEAD.
+		if len(fields) < 3 || fields[1] == "" || strings.HasSuffix(fields[0], "/HEAD") {
+			continue
+		}
+		r.Branches++
+		unix, _ := strconv.ParseInt(fields[1], 10, 64)
+		when := time.Unix(unix, 0)
+		if when.Before(cutoff) {
+			r.StaleBranches = append(r.StaleBranches, staleBranch{Name: fields[0], LastCommit: when, Author: fields[2]})
+		}
+	}
+	sort.Slice(r.StaleBranches, func(i, j int) bool {
+		return r.StaleBranches[i].LastCommit.Before(r.StaleBranches[j].LastCommit)
+	})
+	return nil
+}
+
+// codeOwnersPaths are where GitHub and GitLab look for CODEOWNERS.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// scanOwners finds tracked files no CODEOWNERS rule covers, grouped by
+// their top two directories. Without a CODEOWNERS file every file is
+// unowned and no paths are listed.
+func (r *repoAudit) scanOwners(root string, tracked []string) {
+	var patterns []string
+	for _, p := range codeOwnersPaths {
+		data, err := os.ReadFile(filepath.Join(root, p))
+		if err != nil {
+			continue
+		}
+		r.CodeOwners = p
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			// A rule without owners explicitly leaves paths unowned.
+			if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") && !strings.HasPrefix(fields[0], "[") {
+				patterns = append(patterns, fields[0])
+			}
+		}
+		break
+	}
+	if r.CodeOwners == "" {
+		r.UnownedFiles = len(tracked)
+		return
+	}
+
+	counts := map[string]int{}
+	for _, f := range tracked {
+		if slices.ContainsFunc(patterns, func(p string) bool { return codeOwnersMatch(p, f) }) {
+			continue
+		}
+		r.UnownedFiles++
+		counts[topDirs(f, 2)]++
+	}
+	r.UnownedPaths = topCounts(counts, auditListMax)
+}
+
+// codeOwnersMatch reports whether a CODEOWNERS pattern (gitignore syntax)
+// covers file.
+func codeOwnersMatch(pattern, file string) bool {
+	if pattern == "*" {
+		return true
+	}
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.Trim(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "/**"), "/")
+
+	// Candidates: the file and each of its parent directories.
+	parts := strings.Split(file, "/")
+	for i := len(parts); i >= 1; i-- {
+		if dirOnly && i == len(parts) {
+			continue
+		}
+		candidate := strings.Join(parts[:i], "/")
+		if anchored {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+			continue
+		}
+		// Unanchored patterns match a name at any depth.
+		if ok, _ := path.Match(pattern, parts[i-1]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// topDirs returns the first n directories of file ("." for root files).
+func topDirs(file string, n int) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return "."
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > n {
+		parts = parts[:n]
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+// topCounts returns the max entries with the highest counts.
+func topCounts(counts map[string]int, max int) []pathCount {
+	list := make([]pathCount, 0, len(counts))
+	for p, n := range counts {
+		list = append(list, pathCount{Path: p, Count: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Path < list[j].Path
+	})
+	if len(list) > max {
+		list = list[:max]
+	}
+	return list
+}
+
+// codeExtensions are the source files counted for the test ratio and
+// TODO density.
+var codeExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".java": true,
+	".kt": true, ".rb": true, ".rs": true, ".php": true, ".cs": true, ".c": true, ".cc": true,
+	".cpp": true, ".h": true, ".hpp": true, ".swift": true, ".scala": true, ".ex": true, ".exs": true,
+	".vue": true, ".svelte": true, ".sh": true,
+}
+
+// testFilePattern matches test files of the common ecosystems.
+var testFilePattern = regexp.MustCompile(`(_test\.go|_test\.py|\.(test|spec)\.[jt]sx?|Test\.java|Tests?\.cs|_spec\.rb|_test\.exs)$|(^|/)(tests?|__tests__|spec)/|(^|/)test_[^/]+\.py$`)
+
+// todoMarker matches work-left markers in comments.
+var todoMarker = regexp.MustCompile(`\b(TODO|FIXME|HACK|XXX)\b`)
+
+// auditMaxFileBytes skips huge (usually generated) files when counting lines.
+const auditMaxFileBytes = 2 << 20
+
+// scanFiles measures file sizes, code and test lines, and TODO markers.
+func (r *repoAudit) scanFiles(root string, tracked []string, largeBytes int64) {
+	r.Languages = map[string]int{}
+	markers := map[string]int{}
+	for _, f := range tracked {
+		info, err := os.Stat(filepath.Join(root, f))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if largeBytes > 0 && info.Size() >= largeBytes {
+			r.LargeFiles = append(r.LargeFiles, largeFile{Path: f, Size: info.Size()})
+		}
+
+		ext := strings.ToLower(filepath.Ext(f))
+		if !codeExtensions[ext] || info.Size() > auditMaxFileBytes || isVendored(f) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, f))
+		if err != nil {
+			continue
+		}
+		lines := bytes.Count(data, []byte("\n"))
+		if testFilePattern.MatchString(f) {
+			r.TestFiles++
+			r.TestLines += lines
+		} else {
+			r.CodeFiles++
+			r.CodeLines += lines
+			r.Languages[ext] += lines
+		}
+		if n := len(todoMarker.FindAllIndex(data, -1)); n > 0 {
+			r.Markers += n
+			markers[f] = n
+		}
+	}
+
+	sort.Slice(r.LargeFiles, func(i, j int) bool { return r.LargeFiles[i].Size > r.LargeFiles[j].Size })
+	if len(r.LargeFiles) > auditListMax {
+		r.LargeFiles = r.LargeFiles[:auditListMax]
+	}
+	r.MarkerFiles = topCounts(markers, auditListMax)
+	if total := r.CodeLines + r.TestLines; total > 0 {
+		r.MarkerPerKLOC = float64(r.Markers) * 1000 / float64(total)
+	}
+}
+
+// isVendored reports whether f is third-party code checked into the repo.
+func isVendored(f string) bool {
+	for _, dir := range []string{"vendor/", "node_modules/", "third_party/"} {
+		if strings.HasPrefix(f, dir) || strings.Contains(f, "/"+dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDeps lists direct dependencies with a newer release, for the
+// ecosystems whose manifest is at the repository root.
+func (r *repoAudit) scanDeps(ctx context.Context, root string) {
+	ctx, cancel := context.WithTimeout(ctx, auditDepsTimeout)
+	defer cancel()
+
+	var skipped []string
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+		if deps, err := goDepLag(ctx, root); err != nil {
+			skipped = append(skipped, "go: "+err.Error())
+		} else {
+			r.DepsChecked = append(r.DepsChecked, "go")
+			r.Dependencies = append(r.Dependencies, deps...)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "package.json")); err == nil {
+		if deps, err := npmDepLag(ctx, root); err != nil {
+			skipped = append(skipped, "npm: "+err.Error())
+		} else {
+			r.DepsChecked = append(r.DepsChecked, "npm")
+			r.Dependencies = append(r.Dependencies, deps...)
+		}
+	}
+	if len(r.DepsChecked) == 0 && len(skipped) == 0 {
+		skipped = append(skipped, "no go.mod or package.json at the repository root")
+	}
+	r.DepsSkipped = strings.Join(skipped, "; ")
+}
+
+// goDepLag runs `go list -m -u` for the direct module requirements.
+func goDepLag(ctx context.Context, root string) ([]depLag, error) {
+	c := exec.CommandContext(ctx, "go", "list", "-m", "-u", "-json", "all")
+	c.Dir = root
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %w", err)
+	}
+	var deps []depLag
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var m struct {
+			Path     string
+			Version  string
+			Main     bool
+			Indirect bool
+			Update   *struct{ Version string }
+		}
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+		if !m.Main && !m.Indirect && m.Update != nil {
+			deps = append(deps, depLag{Ecosystem: "go", Name: m.Path, Current: m.Version, Latest: m.Update.Version})
+		}
+	}
+	return deps, nil
+}
+
+// npmDepLag runs `npm outdated`, which exits 1 when anything is outdated.
+func npmDepLag(ctx context.Context, root string) ([]depLag, error) {
+	c := exec.CommandContext(ctx, "npm", "outdated", "--json")
+	c.Dir = root
+	out, err := c.Output()
+	if err != nil && len(bytes.TrimSpace(out)) == 0 {
+		return nil, fmt.Errorf("npm outdated failed: %w", err)
+	}
+	var outdated map[string]struct {
+		Current string `json:"current"`
+		Latest  string `json:"latest"`
+	}
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &outdated); err != nil {
+			return nil, fmt.Errorf("parsing npm outdated: %w", err)
+		}
+	}
+	var deps []depLag
+	for name, v := range outdated {
+		deps = append(deps, depLag{Ecosystem: "npm", Name: name, Current: v.Current, Latest: v.Latest})
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	return deps, nil
+}
+
+// Markdown renders the report.
+func (r *repoAudit) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Repository health: %s\n\nGenerated %s\n\n", filepath.Base(r.Root), r.GeneratedAt.Format("2006-01-02 15:04"))
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "## Executive summary\n\n%s\n\n", r.Summary)
+	}
+
+	b.WriteString("## Overview\n\n| Check | Result |\n|-------|--------|\n")
+	fmt.Fprintf(&b, "| Stale branches | %d of %d |\n", len(r.StaleBranches), r.Branches)
+	if r.CodeOwners == "" {
+		b.WriteString("| Code owners | no CODEOWNERS file |\n")
+	} else {
+		fmt.Fprintf(&b, "| Unowned files | %d of %d (%s) |\n", r.UnownedFiles, r.TrackedFiles, r.CodeOwners)
+	}
+	if len(r.DepsChecked) > 0 {
+		fmt.Fprintf(&b, "| Outdated direct dependencies | %d (%s) |\n", len(r.Dependencies), strings.Join(r.DepsChecked, ", "))
+	} else {
+		b.WriteString("| Outdated direct dependencies | not checked |\n")
+	}
+	fmt.Fprintf(&b, "| Large files | %d |\n", len(r.LargeFiles))
+	fmt.Fprintf(&b, "| Test-to-code ratio | %.2f (%d test / %d code lines) |\n", r.TestRatio(), r.TestLines, r.CodeLines)
+	fmt.Fprintf(&b, "| TODO/FIXME density | %.1f per 1k lines (%d markers) |\n\n", r.MarkerPerKLOC, r.Markers)
+
+	if len(r.StaleBranches) > 0 {
+		b.WriteString("## Stale branches\n\n| Branch | Last commit | Author |\n|--------|-------------|--------|\n")
+		for i, sb := range r.StaleBranches {
+			if i == auditListMax {
+				fmt.Fprintf(&b, "| … %d more | | |\n", len(r.StaleBranches)-auditListMax)
+				break
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", sb.Name, sb.LastCommit.Format("2006-01-02"), sb.Author)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.UnownedPaths) > 0 {
+		b.WriteString("## Unowned code paths\n\n| Path | Files |\n|------|-------|\n")
+		for _, p := range r.UnownedPaths {
+			fmt.Fprintf(&b, "| %s | %d |\n", p.Path, p.Count)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Dependencies) > 0 || r.DepsSkipped != "" {
+		b.WriteString("## Dependency update lag\n\n")
+		if r.DepsSkipped != "" {
+			fmt.Fprintf(&b, "Not checked: %s\n\n", r.DepsSkipped)
+		}
+		if len(r.Dependencies) > 0 {
+			b.WriteString("| Dependency | Current | Latest |\n|------------|---------|--------|\n")
+			for _, d := range r.Dependencies {
+				fmt.Fprintf(&b, "| %s (%s) | %s | %s |\n", d.Name, d.Ecosystem, d.Current, d.Latest)
+			}
+			b.WriteString("\n")
+		}
+	}
+	if len(r.LargeFiles) > 0 {
+		b.WriteString("## Large files\n\n| File | Size |\n|------|------|\n")
+		for _, f := range r.LargeFiles {
+			fmt.Fprintf(&b, "| %s | %.1f MB |\n", f.Path, float64(f.Size)/(1<<20))
+		}
+		b.WriteString("\n")
+	}
+	if len(r.MarkerFiles) > 0 {
+		b.WriteString("## TODO/FIXME hotspots\n\n| File | Markers |\n|------|---------|\n")
+		for _, p := range r.MarkerFiles {
+			fmt.Fprintf(&b, "| %s | %d |\n", p.Path, p.Count)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// summarizeRepoAudit asks the agent for an executive summary of the report.
+func summarizeRepoAudit(cmd *cobra.Command, r *repoAudit) (string, error) {
+	cfg, _, err := resolveConfig(cmd)
+	if err != nil {
+		return "", err
+	}
+	assistant, cleanup, err := quickAssistant(cfg, cmd)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	prompt := fmt.Sprintf(`Below is an automated health report of a git repository.
+Write a short executive summary for an engineering manager: overall health
+in one sentence, the three most important risks, and one concrete next step
+for each. Use only the data in the report; do not run tools.
+
+%s`, r.Markdown())
+	reply := strings.TrimSpace(executeChat(assistant, prompt))
+	if reply == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+	return reply, nil
+}
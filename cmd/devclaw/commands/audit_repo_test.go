@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeOwnersMatch(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*", "any/file.go", true},
+		{"*.md", "docs/guide/intro.md", true},
+		{"*.md", "docs/guide/intro.go", false},
+		{"/cmd/", "cmd/devclaw/main.go", true},
+		{"/cmd/", "tools/cmd/main.go", false},
+		{"cmd/", "tools/cmd/main.go", true},
+		{"docs/*", "docs/intro.md", true},
+		{"docs/*", "docs/guide/intro.md", true},
+		{"/pkg/api/**", "pkg/api/v1/handler.go", true},
+		{"Makefile", "Makefile", true},
+		{"build/", "build", false},
+	}
+	for _, tc := range cases {
+		if got := codeOwnersMatch(tc.pattern, tc.file); got != tc.want {
+			t.Errorf("codeOwnersMatch(%q, %q) = %v, want %v", tc.pattern, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestAuditRepo(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(date string, args ...string) {
+		t.Helper()
+		c := exec.Command("git", append([]string{"-c", "user.name=Ana", "-c", "user.email=ana@example.com"}, args...)...)
+		c.Dir = root
+		c.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(files map[string]string) {
+		t.Helper()
+		for rel, content := range files {
+			p := filepath.Join(root, rel)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	git("", "init", "-q")
+	write(map[string]string{"README.md": "# app\n"})
+	git("", "add", "-A")
+	git("2020-01-02T10:00:00Z", "commit", "-q", "-m", "init")
+	git("", "branch", "old-feature")
+
+	write(map[string]string{
+		".github/CODEOWNERS":      "# owners\n/cmd/ @core\n*.md @docs\ninternal/legacy/\n",
+		"cmd/main.go":             "package main\n\n// TODO: flags\n// FIXME: exit codes\nfunc main() {}\n",
+		"cmd/main_test.go":        "package main\n\nimport \"testing\"\n",
+		"internal/legacy/old.go":  "package legacy\n\n// HACK: remove\n",
+		"internal/api/handler.go": "package api\n",
+		"vendor/x/x.go":           "package x\n// TODO: upstream\n",
+		"assets/logo.bin":         strings.Repeat("x", 2048),
+	})
+	git("", "add", "-A")
+	git(time.Now().Format(time.RFC3339), "commit", "-q", "-m", "app")
+
+	r, err := auditRepo(context.Background(), root, repoAuditOptions{
+		StaleAfter: 90 * 24 * time.Hour,
+		LargeBytes: 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Branches != 2 || len(r.StaleBranches) != 1 || r.StaleBranches[0].Name != "old-feature" || r.StaleBranches[0].Author != "Ana" {
+		t.Errorf("branches = %d, stale = %+v", r.Branches, r.StaleBranches)
+	}
+
+	// The owner-less legacy rule leaves that path unowned.
+	if r.CodeOwners != ".github/CODEOWNERS" || r.TrackedFiles != 8 || r.UnownedFiles != 5 {
+		t.Errorf("codeowners = %q, tracked = %d, unowned = %d", r.CodeOwners, r.TrackedFiles, r.UnownedFiles)
+	}
+	var unowned []string
+	for _, p := range r.UnownedPaths {
+		unowned = append(unowned, p.Path)
+	}
+	if want := []string{".github/", "assets/", "internal/api/", "internal/legacy/", "vendor/x/"}; !slices.Equal(unowned, want) {
+		t.Errorf("unowned paths = %v, want %v", unowned, want)
+	}
+
+	// Vendored code is not counted.
+	if r.CodeFiles != 3 || r.CodeLines != 9 || r.TestFiles != 1 || r.TestLines != 3 || r.Languages[".go"] != 9 {
+		t.Errorf("code %d files/%d lines, tests %d files/%d lines, languages %v",
+			r.CodeFiles, r.CodeLines, r.TestFiles, r.TestLines, r.Languages)
+	}
+	if r.Markers != 3 || r.MarkerFiles[0] != (pathCount{Path: "cmd/main.go", Count: 2}) || r.MarkerPerKLOC != 250 {
+		t.Errorf("markers = %d %+v %.1f", r.Markers, r.MarkerFiles, r.MarkerPerKLOC)
+	}
+	if len(r.LargeFiles) != 1 || r.LargeFiles[0] != (largeFile{Path: "assets/logo.bin", Size: 2048}) {
+		t.Errorf("large files = %+v", r.LargeFiles)
+	}
+	if r.DepsSkipped != "disabled with --no-deps" || len(r.DepsChecked) != 0 {
+		t.Errorf("deps checked = %v, skipped = %q", r.DepsChecked, r.DepsSkipped)
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"| Stale branches | 1 of 2 |",
+		"| Unowned files | 5 of 8 (.github/CODEOWNERS) |",
+		"| Outdated direct dependencies | not checked |",
+		"| Test-to-code ratio | 0.33 (3 test / 9 code lines) |",
+		"| old-feature | 2020-01-02 | Ana |",
+		"| internal/legacy/ | 1 |",
+		"| cmd/main.go | 2 |",
+		"Not checked: disabled with --no-deps",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown lacks %q:\n%s", want, md)
+		}
+	}
+	if _, err := json.Marshal(r); err != nil {
+		t.Errorf("report does not encode: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newAuthCmd creates the `devclaw auth` command for subscription logins.
+func newAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Log in with a ChatGPT or Claude subscription",
+		Long: `Use a ChatGPT Plus/Pro or Claude Pro/Max subscription instead of an API key.
+
+'auth login' runs an OAuth device login: open the link shown, enter the
+code, and the tokens are stored in the OS keyring. Then set
+api.subscription in config.yaml so the assistant uses them.
+
+Examples:
+  devclaw auth login claude
+  devclaw auth status
+  devclaw auth logout chatgpt`,
+	}
+
+	cmd.AddCommand(newAuthLoginCmd(), newAuthStatusCmd(), newAuthLogoutCmd())
+	return cmd
+}
+
+func newAuthLoginCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "login [chatgpt|claude]",
+		Short: "Log in with a device code and store the tokens in the OS keyring",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !copilot.KeyringAvailable() {
+				return fmt.Errorf("OS keyring not available; subscription tokens can only be stored there")
+			}
+			cfg := authConfig(cmd)
+			provider, err := authProvider(cfg, args)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+
+			client := copilot.NewOAuthClient(provider)
+			dc, err := client.RequestDeviceCode(ctx)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+			if dc.VerificationURIComplete != "" {
+				fmt.Printf("Open %s\n", dc.VerificationURIComplete)
+				fmt.Printf("and confirm the code %s\n", dc.UserCode)
+			} else {
+				fmt.Printf("Open %s\n", dc.VerificationURI)
+				fmt.Printf("and enter the code %s\n", dc.UserCode)
+			}
+			fmt.Println()
+			fmt.Println("Waiting for approval (Ctrl+C to cancel)...")
+
+			tok, err := client.PollToken(ctx, dc)
+			if err != nil {
+				return err
+			}
+			if err := copilot.SaveOAuthToken(provider.Name, tok); err != nil {
+				return fmt.Errorf("storing tokens in keyring: %w", err)
+			}
+
+			fmt.Printf("Logged in to %s. Tokens stored in the OS keyring.\n", provider.Name)
+			if cfg == nil || cfg.API.Subscription != provider.Name {
+				fmt.Println()
+				fmt.Println("To use the subscription, set in config.yaml:")
+				fmt.Println("  api:")
+				fmt.Printf("    subscription: %s\n", provider.Name)
+			}
+			return nil
+		},
+	}
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show which subscriptions are logged in",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !copilot.KeyringAvailable() {
+				return fmt.Errorf("OS keyring not available")
+			}
+			active := ""
+			if cfg := authConfig(cmd); cfg != nil {
+				active = cfg.API.Subscription
+			}
+			for _, name := range copilot.SubscriptionNames() {
+				marker := " "
+				if name == active {
+					marker = "*"
+				}
+				tok := copilot.LoadOAuthToken(name)
+				switch {
+				case tok == nil:
+					fmt.Printf("%s %-8s not logged in\n", marker, name)
+				case tok.ExpiresAt.IsZero():
+					fmt.Printf("%s %-8s logged in\n", marker, name)
+				case tok.Expired() && tok.RefreshToken == "":
+					fmt.Printf("%s %-8s expired, log in again\n", marker, name)
+				case tok.Expired():
+					fmt.Printf("%s %-8s logged in (token refreshes on next use)\n", marker, name)
+				default:
+					fmt.Printf("%s %-8s logged in (token valid until %s)\n", marker, name, tok.ExpiresAt.Local().Format(time.DateTime))
+				}
+			}
+			if active != "" {
+				fmt.Println()
+				fmt.Printf("* used by config (api.subscription: %s)\n", active)
+			}
+			return nil
+		},
+	}
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout [chatgpt|claude]",
+		Short: "Remove the stored subscription tokens",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := authProvider(authConfig(cmd), args)
+			if err != nil {
+				return err
+			}
+			if copilot.LoadOAuthToken(provider.Name) == nil {
+				fmt.Printf("Not logged in to %s.\n", provider.Name)
+				return nil
+			}
+			if err := copilot.DeleteOAuthToken(provider.Name); err != nil {
+				return fmt.Errorf("deleting from keyring: %w", err)
+			}
+			fmt.Printf("Logged out of %s.\n", provider.Name)
+			return nil
+		},
+	}
+}
+
+// authConfig loads the config when there is one; auth commands also work
+// without it.
+func authConfig(cmd *cobra.Command) *copilot.Config {
+	cfg, _, err := loadConfig(cmd)
+	if err != nil {
+		return nil
+	}
+	return cfg
+}
+
+// authProvider picks the subscription from the argument or, without one,
+// from api.subscription.
+func authProvider(cfg *copilot.Config, args []string) (copilot.SubscriptionProvider, error) {
+	var override copilot.SubscriptionProvider
+	name := ""
+	if cfg != nil {
+		name = cfg.API.Subscription
+		override = cfg.API.OAuth
+	}
+	if len(args) > 0 {
+		if args[0] != name {
+			override = copilot.SubscriptionProvider{}
+		}
+		name = args[0]
+	}
+	if name == "" {
+		return copilot.SubscriptionProvider{}, fmt.Errorf("specify a subscription: %v", copilot.SubscriptionNames())
+	}
+	return copilot.ResolveSubscription(name, override)
+}
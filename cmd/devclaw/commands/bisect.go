@@ -0,0 +1,409 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// bisectMaxSteps guards against a bisect that never converges.
+const bisectMaxSteps = 100
+
+// bisectOutputTail is how much of a test run's output the agent sees.
+const bisectOutputTail = 3000
+
+// newBisectCmd creates the `devclaw bisect` command that drives git bisect
+// in a temporary worktree.
+func newBisectCmd() *cobra.Command {
+	var (
+		good      string
+		bad       string
+		testCmd   string
+		describe  string
+		timeout   time.Duration
+		yes       bool
+		noSummary bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bisect",
+		Short: "Find the commit that introduced a failure",
+		Long: `Automate git bisect between a known good and a bad revision.
+
+Each candidate commit is checked out in a temporary worktree, so your
+working tree is left alone, and the test command runs there: exit 0 marks
+the commit good, 125 skips it, anything else marks it bad. With a failure
+description, failing runs are shown to the agent, which tells the described
+failure apart from unrelated ones (e.g. a broken build) and skips the
+latter; timeouts are judged the same way. Without a test command the agent
+proposes one from the description.
+
+When the first bad commit is found, its diff is summarized.
+
+Examples:
+  devclaw bisect --good v1.4.0 --cmd "go test ./pkg/auth/..."
+  devclaw bisect --good v1.4.0 --cmd "make test" --describe "login returns 500"
+  devclaw bisect --good HEAD~50 --describe "the CLI panics on an empty config"`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if good == "" {
+				return fmt.Errorf("--good is required (a revision where things worked)")
+			}
+			if testCmd == "" && describe == "" {
+				return fmt.Errorf("give a test command (--cmd) or a failure description (--describe)")
+			}
+
+			root, err := gitOutput("", "rev-parse", "--show-toplevel")
+			if err != nil {
+				return fmt.Errorf("not inside a git repository: %w", err)
+			}
+			goodSHA, err := gitOutput(root, "rev-parse", "--verify", good+"^{commit}")
+			if err != nil {
+				return fmt.Errorf("unknown good revision %q", good)
+			}
+			badSHA, err := gitOutput(root, "rev-parse", "--verify", bad+"^{commit}")
+			if err != nil {
+				return fmt.Errorf("unknown bad revision %q", bad)
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			b := &bisector{
+				cmd:      cmd,
+				root:     root,
+				testCmd:  testCmd,
+				describe: describe,
+				timeout:  timeout,
+			}
+			defer b.close()
+
+			if b.testCmd == "" {
+				if err := b.proposeTestCommand(yes); err != nil {
+					return err
+				}
+			}
+
+			culprit, err := b.run(ctx, goodSHA, badSHA)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\nFirst bad commit:\n\n")
+			stat, _ := gitOutput(root, "show", "--stat", "--format=fuller", culprit)
+			fmt.Println(stat)
+			if !noSummary {
+				b.summarize(culprit)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&good, "good", "", "revision known to be good (required)")
+	cmd.Flags().StringVar(&bad, "bad", "HEAD", "revision known to be bad")
+	cmd.Flags().StringVar(&testCmd, "cmd", "", "shell command that fails on bad commits")
+	cmd.Flags().StringVar(&describe, "describe", "", "description of the failure being hunted")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "time limit per test run")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "run the proposed test command without asking")
+	cmd.Flags().BoolVar(&noSummary, "no-summary", false, "don't summarize the culprit's diff")
+	return cmd
+}
+
+// bisector drives one bisect session.
+type bisector struct {
+	cmd      *cobra.Command
+	root     string
+	worktree string
+	testCmd  string
+	describe string
+	timeout  time.Duration
+
+	assistant *copilot.Assistant
+	cleanup   func()
+}
+
+// bisectVerdict is the outcome of testing one commit.
+type bisectVerdict string
+
+const (
+	verdictGood bisectVerdict = "good"
+	verdictBad  bisectVerdict = "bad"
+	verdictSkip bisectVerdict = "skip"
+)
+
+// testRun is the result of running the test command at one commit.
+type testRun struct {
+	exitCode int
+	timedOut bool
+	output   string
+	elapsed  time.Duration
+}
+
+// ask sends a prompt to the agent, starting it on first use so purely
+// mechanical bisects don't need an API key.
+func (b *bisector) ask(prompt string) (string, error) {
+	if b.assistant == nil {
+		cfg, _, err := resolveConfig(b.cmd)
+		if err != nil {
+			return "", err
+		}
+		assistant, cleanup, err := quickAssistant(cfg, b.cmd)
+		if err != nil {
+			return "", err
+		}
+		b.assistant, b.cleanup = assistant, cleanup
+	}
+	return strings.TrimSpace(executeChat(b.assistant, prompt)), nil
+}
+
+// proposeTestCommand asks the agent for a command reproducing the described
+// failure and confirms it with the user unless yes is set.
+func (b *bisector) proposeTestCommand(yes bool) error {
+	files, _ := gitOutput(b.root, "ls-files")
+	if lines := strings.Split(files, "\n"); len(lines) > 200 {
+		files = strings.Join(lines[:200], "\n") + "\n..."
+	}
+	prompt := fmt.Sprintf(`I'm running git bisect to find when this failure appeared: %s
+
+Propose ONE shell command, run from the repository root, that exits 0 when
+the failure is absent and non-zero when it is present. Prefer the project's
+own test or build tooling. Reply with ONLY the command, nothing else.
+
+Repository files:
+%s`, b.describe, files)
+
+	reply, err := b.ask(prompt)
+	if err != nil {
+		return fmt.Errorf("proposing a test command: %w", err)
+	}
+	proposed := strings.TrimSpace(strings.Trim(reply, "`"))
+	proposed = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(proposed, "bash\n"), "sh\n"))
+	if proposed == "" {
+		return fmt.Errorf("the agent didn't propose a test command; pass one with --cmd")
+	}
+
+	fmt.Printf("Proposed test command: %s\n", proposed)
+	if !yes {
+		fmt.Print("Run it on each commit? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return fmt.Errorf("aborted; pass a test command with --cmd")
+		}
+	}
+	b.testCmd = proposed
+	return nil
+}
+
+// run bisects between good and bad in a temporary worktree and returns the
+// first bad commit.
+func (b *bisector) run(ctx context.Context, good, bad string) (string, error) {
+	dir, err := os.MkdirTemp("", "devclaw-bisect-")
+	if err != nil {
+		return "", err
+	}
+	if _, err := gitOutput(b.root, "worktree", "add", "--detach", dir, bad); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("creating worktree: %w", err)
+	}
+	b.worktree = dir
+
+	out, err := gitOutput(dir, "bisect", "start", bad, good)
+	if err != nil {
+		return "", fmt.Errorf("git bisect start: %w", err)
+	}
+	fmt.Println(firstLine(out))
+
+	for step := 1; step <= bisectMaxSteps; step++ {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("interrupted")
+		}
+		head, err := gitOutput(dir, "log", "-1", "--format=%h %s")
+		if err != nil {
+			return "", err
+		}
+
+		run := b.runTest(ctx)
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("interrupted")
+		}
+		verdict, why := b.judge(head, run)
+		fmt.Printf("[%d] %s → %s (%s)\n", step, head, verdict, why)
+
+		out, err := gitOutput(dir, "bisect", string(verdict))
+		if err != nil {
+			return "", fmt.Errorf("git bisect %s: %w", verdict, err)
+		}
+		switch {
+		case strings.Contains(out, "is the first bad commit"):
+			return strings.Fields(out)[0], nil
+		case strings.Contains(out, "only 'skip'ped commits left"):
+			return "", fmt.Errorf("could not narrow it down past skipped commits:\n%s", out)
+		}
+		fmt.Println(firstLine(out))
+	}
+	return "", fmt.Errorf("bisect did not converge after %d steps", bisectMaxSteps)
+}
+
+// runTest runs the test command in the worktree.
+func (b *bisector) runTest(ctx context.Context) testRun {
+	runCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	c := exec.CommandContext(runCtx, "sh", "-c", b.testCmd)
+	c.Dir = b.worktree
+	c.Stdout = &buf
+	c.Stderr = &buf
+	// Children (e.g. test binaries) may outlive the killed shell and keep
+	// the pipes open; don't wait on them forever.
+	c.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	err := c.Run()
+	run := testRun{output: buf.String(), elapsed: time.Since(start)}
+	var exitErr *exec.ExitError
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		run.timedOut = true
+		run.exitCode = -1
+	case errors.As(err, &exitErr):
+		run.exitCode = exitErr.ExitCode()
+	case err != nil:
+		run.exitCode = -1
+		run.output += "\n" + err.Error()
+	}
+	return run
+}
+
+// judge turns a test run into a bisect verdict. Clear results are decided
+// mechanically; timeouts, commands that couldn't run, and failures when
+// hunting a described failure go to the agent.
+func (b *bisector) judge(head string, run testRun) (bisectVerdict, string) {
+	ambiguous := run.timedOut || run.exitCode == 126 || run.exitCode == 127 || run.exitCode < 0
+	switch {
+	case run.timedOut:
+	case run.exitCode == 0:
+		return verdictGood, fmt.Sprintf("passed in %s", run.elapsed.Round(time.Second))
+	case run.exitCode == 125:
+		return verdictSkip, "exit 125"
+	case !ambiguous && b.describe == "":
+		return verdictBad, fmt.Sprintf("exit %d", run.exitCode)
+	}
+
+	status := fmt.Sprintf("exited with code %d", run.exitCode)
+	if run.timedOut {
+		status = fmt.Sprintf("timed out after %s", b.timeout)
+	}
+	failure := b.describe
+	if failure == "" {
+		failure = fmt.Sprintf("`%s` fails", b.testCmd)
+	}
+	output := run.output
+	if len(output) > bisectOutputTail {
+		output = "..." + output[len(output)-bisectOutputTail:]
+	}
+	prompt := fmt.Sprintf(`I'm running git bisect to find when this failure appeared: %s
+
+At commit %s the test command `+"`%s`"+` %s. Output:
+`+"```"+`
+%s
+`+"```"+`
+
+Does this show the failure being hunted (BAD), the code working (GOOD), or
+an unrelated problem such as a build error or missing dependency, so the
+commit can't be judged (SKIP)? Answer with one word: GOOD, BAD or SKIP.`,
+		failure, head, b.testCmd, status, output)
+
+	reply, err := b.ask(prompt)
+	if err != nil {
+		return verdictSkip, fmt.Sprintf("%s; agent unavailable: %v", status, err)
+	}
+	for _, word := range strings.Fields(strings.ToUpper(reply)) {
+		switch strings.Trim(word, ".*`'\"") {
+		case "GOOD":
+			return verdictGood, status + "; agent: good"
+		case "BAD":
+			return verdictBad, status + "; agent: bad"
+		case "SKIP":
+			return verdictSkip, status + "; agent: unrelated"
+		}
+	}
+	return verdictSkip, status + "; agent answer unclear"
+}
+
+// summarize asks the agent to explain the culprit's diff.
+func (b *bisector) summarize(sha string) {
+	diff, err := gitOutput(b.root, "show", "--format=%H%n%an <%ae>%n%s%n%n%b", sha)
+	if err != nil {
+		return
+	}
+	const maxDiffLen = 8000
+	if len(diff) > maxDiffLen {
+		diff = diff[:maxDiffLen] + "\n... (truncated)"
+	}
+	failure := b.describe
+	if failure == "" {
+		failure = fmt.Sprintf("`%s` started failing", b.testCmd)
+	}
+	prompt := fmt.Sprintf(`git bisect found the first bad commit for this failure: %s
+
+Summarize what the commit changed and point at the change most likely to
+cause the failure. Be brief.
+
+`+"```diff"+`
+%s
+`+"```", failure, diff)
+
+	reply, err := b.ask(prompt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Skipping summary: %v\n", err)
+		return
+	}
+	fmt.Printf("Summary:\n%s\n", reply)
+}
+
+// close ends the bisect, removes the worktree and stops the agent.
+func (b *bisector) close() {
+	if b.worktree != "" {
+		gitOutput(b.worktree, "bisect", "reset")
+		gitOutput(b.root, "worktree", "remove", "--force", b.worktree)
+		os.RemoveAll(b.worktree)
+	}
+	if b.cleanup != nil {
+		b.cleanup()
+	}
+}
+
+// gitOutput runs git in dir (empty = current directory) and returns its
+// trimmed combined output.
+func gitOutput(dir string, args ...string) (string, error) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	s := strings.TrimSpace(string(out))
+	if err != nil {
+		if s == "" {
+			return "", err
+		}
+		return s, fmt.Errorf("%s", firstLine(s))
+	}
+	return s, nil
+}
+
+// firstLine returns s up to the first newline.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
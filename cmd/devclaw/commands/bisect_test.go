@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBisectRepo creates a repository whose "status" file reads "fine" until
+// the commit at index badAt, and returns the commit SHAs in order.
+func newBisectRepo(t *testing.T, commits, badAt int) (string, []string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := gitOutput(dir, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return out
+	}
+	git("init", "-q")
+
+	var shas []string
+	for i := 0; i < commits; i++ {
+		status := "fine"
+		if i >= badAt {
+			status = "failing"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "counter"), []byte{byte('a' + i)}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "change "+string(rune('a'+i)))
+		shas = append(shas, git("rev-parse", "HEAD"))
+	}
+	return dir, shas
+}
+
+func TestBisectorRun_FindsFirstBadCommit(t *testing.T) {
+	root, shas := newBisectRepo(t, 8, 5)
+	b := &bisector{root: root, testCmd: "grep -qx fine status", timeout: time.Minute}
+	defer b.close()
+
+	culprit, err := b.run(context.Background(), shas[0], shas[len(shas)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if culprit != shas[5] {
+		t.Errorf("culprit = %s, want %s", culprit, shas[5])
+	}
+
+	worktree := b.worktree
+	b.close()
+	b.worktree = ""
+	if _, err := os.Stat(worktree); !os.IsNotExist(err) {
+		t.Errorf("worktree %s left behind", worktree)
+	}
+	if out, _ := gitOutput(root, "worktree", "list"); strings.Contains(out, worktree) {
+		t.Errorf("worktree still registered:\n%s", out)
+	}
+}
+
+func TestBisectorRunTest(t *testing.T) {
+	t.Parallel()
+	b := &bisector{worktree: t.TempDir(), timeout: 200 * time.Millisecond}
+
+	b.testCmd = "echo building; exit 3"
+	if run := b.runTest(context.Background()); run.exitCode != 3 || run.timedOut || run.output != "building\n" {
+		t.Errorf("failing run = %+v", run)
+	}
+	b.testCmd = "exec sleep 5"
+	if run := b.runTest(context.Background()); !run.timedOut || run.exitCode != -1 {
+		t.Errorf("slow run = %+v", run)
+	}
+}
+
+func TestBisectorJudge_Mechanical(t *testing.T) {
+	t.Parallel()
+	b := &bisector{testCmd: "make test"}
+	cases := []struct {
+		run  testRun
+		want bisectVerdict
+	}{
+		{testRun{exitCode: 0}, verdictGood},
+		{testRun{exitCode: 125}, verdictSkip},
+		{testRun{exitCode: 1}, verdictBad},
+		{testRun{exitCode: 2, output: "FAIL"}, verdictBad},
+	}
+	for _, tc := range cases {
+		if got, why := b.judge("abc123 change", tc.run); got != tc.want {
+			t.Errorf("exit %d: verdict %s (%s), want %s", tc.run.exitCode, got, why, tc.want)
+		}
+	}
+}
@@ -69,8 +69,8 @@ func runChat(cmd *cobra.Command, args []string) error {
 	copilot.AuditSecrets(cfg, logger)
 	vault := copilot.ResolveAPIKey(cfg, logger)
 
-	if cfg.API.APIKey == "" || copilot.IsEnvReference(cfg.API.APIKey) {
-		return fmt.Errorf("no API key configured. Run: devclaw config vault-set")
+	if cfg.API.Subscription == "" && (cfg.API.APIKey == "" || copilot.IsEnvReference(cfg.API.APIKey)) {
+		return fmt.Errorf("no API key configured. Run: devclaw config vault-set (or devclaw auth login)")
 	}
 
 	// ── Create and start assistant ──
@@ -78,6 +78,8 @@ func runChat(cmd *cobra.Command, args []string) error {
 	if vault != nil {
 		assistant.SetVault(vault)
 	}
+	// File changes that need confirmation are reviewed as diffs here.
+	assistant.ToolExecutor().SetDiffReviewer(terminalDiffReviewer())
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"golang.org/x/term"
+)
+
+// pagerThreshold is the diff length (in lines) above which the diff is
+// shown through the pager instead of printed inline.
+const pagerThreshold = 40
+
+// terminalDiffReviewer reviews proposed file changes in the terminal: each
+// diff is printed (or paged when long) and approved with y/N.
+func terminalDiffReviewer() copilot.DiffReviewer {
+	return func(_ context.Context, _, _, toolName string, files []copilot.ProposedFileChange) ([]bool, string, error) {
+		in, closeIn, err := openTerminal()
+		if err != nil {
+			return nil, "", err
+		}
+		defer closeIn()
+		reader := bufio.NewReader(in)
+
+		approved := make([]bool, len(files))
+		rejectedAny := false
+		fmt.Printf("\n  \033[1m%s wants to change %d file(s)\033[0m\n", toolName, len(files))
+		for i, f := range files {
+			fmt.Printf("\n  \033[1m%d/%d %s %s\033[0m\n", i+1, len(files), f.Action, f.Path)
+			showDiff(f.Diff)
+
+			fmt.Print("  Apply this change? [y/N/a=all/q=reject rest] ")
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				approved[i] = true
+			case "a", "all":
+				for j := i; j < len(files); j++ {
+					approved[j] = true
+				}
+				return approved, "", nil
+			case "q", "quit":
+				return approved, askReason(reader), nil
+			default:
+				rejectedAny = true
+			}
+		}
+
+		reason := ""
+		if rejectedAny {
+			reason = askReason(reader)
+		}
+		return approved, reason, nil
+	}
+}
+
+// askReason asks why changes were rejected; the answer goes to the model.
+func askReason(reader *bufio.Reader) string {
+	fmt.Print("  Reason for the model (optional): ")
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+// openTerminal returns a reader on the controlling terminal, so reviews
+// work even when stdin is a pipe.
+func openTerminal() (io.Reader, func(), error) {
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		return tty, func() { tty.Close() }, nil
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return os.Stdin, func() {}, nil
+	}
+	return nil, nil, fmt.Errorf("no terminal to review file changes; remove the tool from require_confirmation or run interactively")
+}
+
+// showDiff prints a colored diff, through $PAGER (default less -R) when it
+// doesn't fit on the screen.
+func showDiff(diff string) {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			b.WriteString("\033[1m" + line + "\033[0m\n")
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString("\033[36m" + line + "\033[0m\n")
+		case strings.HasPrefix(line, "+"):
+			b.WriteString("\033[32m" + line + "\033[0m\n")
+		case strings.HasPrefix(line, "-"):
+			b.WriteString("\033[31m" + line + "\033[0m\n")
+		default:
+			b.WriteString(line + "\n")
+		}
+	}
+	colored := b.String()
+
+	if strings.Count(colored, "\n") > pagerThreshold && term.IsTerminal(int(os.Stdout.Fd())) {
+		pager := os.Getenv("PAGER")
+		if pager == "" {
+			pager = "less -R"
+		}
+		cmd := exec.Command("sh", "-c", pager)
+		cmd.Stdin = strings.NewReader(colored)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if cmd.Run() == nil {
+			return
+		}
+	}
+	fmt.Print(colored)
+}
@@ -102,29 +102,33 @@ func newConfigValidateCmd() *cobra.Command {
 				return err
 			}
 
-			fmt.Printf("Config: %s\n", path)
-			fmt.Printf("  Name:      %s\n", cfg.Name)
-			fmt.Printf("  Model:     %s\n", cfg.Model)
-			fmt.Printf("  Trigger:   %s\n", cfg.Trigger)
-			fmt.Printf("  Language:  %s\n", cfg.Language)
-			fmt.Printf("  Policy:    %s\n", cfg.Access.DefaultPolicy)
-			fmt.Printf("  Owners:    %d\n", len(cfg.Access.Owners))
-			fmt.Printf("  Admins:    %d\n", len(cfg.Access.Admins))
-			fmt.Printf("  Users:     %d\n", len(cfg.Access.AllowedUsers))
-
-			wsCount := len(cfg.Workspaces.Workspaces)
-			fmt.Printf("  Workspaces: %d\n", wsCount)
-			for _, ws := range cfg.Workspaces.Workspaces {
-				fmt.Printf("    - %s (%s): %d members, %d groups\n",
-					ws.ID, ws.Name, len(ws.Members), len(ws.Groups))
-			}
-
+			printConfigSummary(cfg, path)
 			fmt.Println("\nConfiguration is valid.")
 			return nil
 		},
 	}
 }
 
+// printConfigSummary prints the main settings of a loaded configuration.
+func printConfigSummary(cfg *copilot.Config, path string) {
+	fmt.Printf("Config: %s\n", path)
+	fmt.Printf("  Name:      %s\n", cfg.Name)
+	fmt.Printf("  Model:     %s\n", cfg.Model)
+	fmt.Printf("  Trigger:   %s\n", cfg.Trigger)
+	fmt.Printf("  Language:  %s\n", cfg.Language)
+	fmt.Printf("  Policy:    %s\n", cfg.Access.DefaultPolicy)
+	fmt.Printf("  Owners:    %d\n", len(cfg.Access.Owners))
+	fmt.Printf("  Admins:    %d\n", len(cfg.Access.Admins))
+	fmt.Printf("  Users:     %d\n", len(cfg.Access.AllowedUsers))
+
+	wsCount := len(cfg.Workspaces.Workspaces)
+	fmt.Printf("  Workspaces: %d\n", wsCount)
+	for _, ws := range cfg.Workspaces.Workspaces {
+		fmt.Printf("    - %s (%s): %d members, %d groups\n",
+			ws.ID, ws.Name, len(ws.Members), len(ws.Groups))
+	}
+}
+
 // newConfigSetKeyCmd stores the API key in the OS keyring.
 func newConfigSetKeyCmd() *cobra.Command {
 	return &cobra.Command{
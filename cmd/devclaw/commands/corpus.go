@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newCorpusCmd creates the `devclaw corpus` command for registering
+// document corpora searched by the corpus_search tool.
+func newCorpusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "corpus",
+		Short: "Manage private document corpora the assistant can search",
+		Long: `Register folders of documents (PDFs, DOCX, HTML wiki dumps, mail exports,
+Markdown and text files) as named corpora. Each corpus is split into chunks
+and indexed under corpora.dir; the assistant searches it with corpus_search.
+
+Examples:
+  devclaw corpus add contracts ~/Documents/contracts
+  devclaw corpus add wiki ./confluence-export --workspace engineering
+  devclaw corpus reindex contracts
+  devclaw corpus search contracts "termination notice period"`,
+	}
+	cmd.AddCommand(
+		newCorpusAddCmd(),
+		newCorpusListCmd(),
+		newCorpusReindexCmd(),
+		newCorpusRemoveCmd(),
+		newCorpusSearchCmd(),
+	)
+	return cmd
+}
+
+// corpusStore opens the corpus store of the resolved config.
+func corpusStore(cmd *cobra.Command) (*copilot.CorpusStore, error) {
+	cfg, _, err := resolveConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return copilot.NewCorpusStore(cfg.Corpora, nil), nil
+}
+
+// printCorpusStats prints the result of an indexing pass.
+func printCorpusStats(c copilot.Corpus, stats copilot.CorpusIndexStats) {
+	fmt.Printf("Corpus %s: %d files, %d chunks (%d indexed, %d unchanged, %d skipped, %d removed)\n",
+		c.Name, c.Files, c.Chunks, stats.Indexed, stats.Unchanged, stats.Skipped, stats.Removed)
+}
+
+func newCorpusAddCmd() *cobra.Command {
+	var workspaces []string
+
+	cmd := &cobra.Command{
+		Use:   "add <name> <path>",
+		Short: "Register and index a folder or file",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := corpusStore(cmd)
+			if err != nil {
+				return err
+			}
+			c, stats, err := store.Add(args[0], args[1], workspaces)
+			if err != nil {
+				return err
+			}
+			printCorpusStats(c, stats)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&workspaces, "workspace", nil, "limit the corpus to these workspaces (default: all)")
+	return cmd
+}
+
+func newCorpusListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List registered corpora",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := corpusStore(cmd)
+			if err != nil {
+				return err
+			}
+			list, err := store.List()
+			if err != nil {
+				return err
+			}
+			if len(list) == 0 {
+				fmt.Println("No corpora registered. Add one with: devclaw corpus add <name> <path>")
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tFILES\tCHUNKS\tWORKSPACES\tINDEXED\tPATH")
+			for _, c := range list {
+				ws := "all"
+				if len(c.Workspaces) > 0 {
+					ws = strings.Join(c.Workspaces, ",")
+				}
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n",
+					c.Name, c.Files, c.Chunks, ws, c.IndexedAt.Format("2006-01-02 15:04"), c.Path)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newCorpusReindexCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex <name>",
+		Short: "Re-read new and changed files of a corpus",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := corpusStore(cmd)
+			if err != nil {
+				return err
+			}
+			c, stats, err := store.Reindex(args[0])
+			if err != nil {
+				return err
+			}
+			printCorpusStats(c, stats)
+			return nil
+		},
+	}
+}
+
+func newCorpusRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Unregister a corpus and delete its index (documents are kept)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := corpusStore(cmd)
+			if err != nil {
+				return err
+			}
+			if err := store.Remove(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Corpus %s removed.\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newCorpusSearchCmd() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "search <name> <query>",
+		Short: "Search a corpus from the terminal",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := corpusStore(cmd)
+			if err != nil {
+				return err
+			}
+			hits, err := store.Search(args[0], strings.Join(args[1:], " "), limit)
+			if err != nil {
+				return err
+			}
+			if len(hits) == 0 {
+				fmt.Println("No matches.")
+				return nil
+			}
+			for _, h := range hits {
+				fmt.Printf("── %s (part %d, score %.1f)\n%s\n\n", h.File, h.Chunk+1, h.Score, h.Snippet)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "n", 5, "maximum results")
+	return cmd
+}
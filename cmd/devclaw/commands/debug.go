@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newDebugCmd creates the `devclaw debug` command for inspecting raw
+// provider traffic recorded with api.debug.enabled.
+func newDebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Inspect recorded LLM provider requests",
+		Long: `Inspect raw LLM provider requests and responses recorded while
+api.debug.enabled is set. API keys and detected secrets are redacted.
+
+Examples:
+  devclaw debug last-request
+  devclaw debug last-request --response
+  devclaw debug last-request -n 3 --json`,
+	}
+	cmd.AddCommand(newDebugLastRequestCmd())
+	return cmd
+}
+
+func newDebugLastRequestCmd() *cobra.Command {
+	var (
+		nth          int
+		asJSON       bool
+		requestOnly  bool
+		responseOnly bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "last-request",
+		Short: "Print the most recent provider request and response",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			dir := llmDebugDir(cmd)
+			ex, path, err := copilot.ReadLLMExchange(dir, nth)
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				data, _ := json.MarshalIndent(ex, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+			if requestOnly {
+				fmt.Println(prettyBody(ex.RequestBody))
+				return nil
+			}
+			if responseOnly {
+				fmt.Println(prettyBody(ex.ResponseBody))
+				return nil
+			}
+
+			fmt.Printf("%s %s\n", ex.Method, ex.URL)
+			fmt.Printf("Time:     %s (%d ms)\n", ex.Time.Local().Format("2006-01-02 15:04:05"), ex.DurationMs)
+			if ex.Error != "" {
+				fmt.Printf("Error:    %s\n", ex.Error)
+			} else {
+				fmt.Printf("Status:   %d\n", ex.Status)
+			}
+			if ex.Truncated {
+				fmt.Println("Note:     bodies truncated at api.debug.max_body_kb")
+			}
+			fmt.Printf("File:     %s\n", path)
+
+			fmt.Println("\n── Request headers ──")
+			printHeaders(ex.RequestHeaders)
+			fmt.Println("\n── Request body ──")
+			fmt.Println(prettyBody(ex.RequestBody))
+			if ex.Error == "" {
+				fmt.Println("\n── Response headers ──")
+				printHeaders(ex.ResponseHeaders)
+				fmt.Println("\n── Response body ──")
+				fmt.Println(prettyBody(ex.ResponseBody))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&nth, "nth", "n", 1, "which request to show, counting back from the newest (1 = last)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the recorded exchange as JSON")
+	cmd.Flags().BoolVar(&requestOnly, "request", false, "print only the request body")
+	cmd.Flags().BoolVar(&responseOnly, "response", false, "print only the response body")
+	return cmd
+}
+
+// llmDebugDir returns the configured debug log directory.
+func llmDebugDir(cmd *cobra.Command) string {
+	if cfg, _, err := loadConfig(cmd); err == nil {
+		return cfg.API.Debug.Effective().Dir
+	}
+	return copilot.DefaultLLMDebugConfig().Dir
+}
+
+// prettyBody indents JSON bodies and returns anything else as-is.
+func prettyBody(body string) string {
+	if body == "" {
+		return "(empty)"
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), "", "  "); err == nil {
+		return buf.String()
+	}
+	return body
+}
+
+// printHeaders prints headers sorted by name.
+func printHeaders(h map[string]string) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("%s: %s\n", k, h[k])
+	}
+}
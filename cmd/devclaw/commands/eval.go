@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newEvalCmd creates the `devclaw eval` command group.
+func newEvalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Evaluate models on a suite of prompts",
+	}
+	cmd.AddCommand(newEvalCompareCmd())
+	return cmd
+}
+
+// newEvalCompareCmd creates `devclaw eval compare`, which runs a prompt
+// suite against several models and reports them side by side.
+func newEvalCompareCmd() *cobra.Command {
+	var (
+		models   string
+		suite    string
+		format   string
+		out      string
+		timeout  time.Duration
+		maxChars int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare",
+		Short: "Compare models side by side on a prompt suite",
+		Long: `Run every prompt of a suite against each model and report the answers,
+latency, tokens and cost side by side, to choose the default model with data.
+
+Models are called on the endpoint of the fallback provider that lists them
+(api.fallbacks / fallback.chain), else on the primary endpoint, without
+fallback. Tools declared in the suite are mocked: every call returns the
+tool's canned result. Prompts with "expect" are checked for those strings.
+
+Suite format (prompts.yaml):
+  name: support triage
+  system: You are a support assistant.
+  tools:
+    - name: lookup_order
+      description: Look up an order by id
+      parameters: {type: object, properties: {id: {type: string}}}
+      result: '{"status": "shipped"}'
+  prompts:
+    - name: refund
+      prompt: Order 42 arrived broken, what can I do?
+      expect: [refund]
+
+Examples:
+  devclaw eval compare --models gpt-5-mini,claude-sonnet-4.5 --suite prompts.yaml
+  devclaw eval compare --models gpt-5-mini,glm-4.7 --suite prompts.yaml --out report.md
+  devclaw eval compare --models gpt-5-mini,gpt-4o --suite prompts.yaml --format json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var list []string
+			for _, m := range strings.Split(models, ",") {
+				if m = strings.TrimSpace(m); m != "" {
+					list = append(list, m)
+				}
+			}
+			if len(list) == 0 {
+				return fmt.Errorf("--models is required (comma-separated)")
+			}
+			if suite == "" {
+				return fmt.Errorf("--suite is required")
+			}
+			if format != "markdown" && format != "json" {
+				return fmt.Errorf("unknown format %q (markdown or json)", format)
+			}
+
+			s, err := copilot.LoadEvalSuite(suite)
+			if err != nil {
+				return err
+			}
+			cfg, _, err := resolveConfig(cmd)
+			if err != nil {
+				return err
+			}
+			logger := quietLogger()
+			copilot.ResolveAPIKey(cfg, logger)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Fprintf(os.Stderr, "Running %d prompt(s) on %s...\n", len(s.Prompts), strings.Join(list, ", "))
+			report, err := copilot.CompareModels(ctx, cfg, s, list, timeout, logger)
+			if err != nil {
+				return err
+			}
+
+			var output string
+			if format == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return err
+				}
+				output = string(data) + "\n"
+			} else {
+				output = report.Markdown(maxChars)
+			}
+
+			if out == "" {
+				fmt.Print(output)
+				return nil
+			}
+			if err := os.WriteFile(out, []byte(output), 0o644); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Report written to %s\n", out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&models, "models", "", "comma-separated models to compare")
+	cmd.Flags().StringVar(&suite, "suite", "", "YAML file with the prompts")
+	cmd.Flags().StringVar(&format, "format", "markdown", "report format: markdown or json")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write the report to a file instead of stdout")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "time limit per prompt and model")
+	cmd.Flags().IntVar(&maxChars, "max-chars", 600, "cut answers longer than this in the markdown report (0 = full)")
+	return cmd
+}
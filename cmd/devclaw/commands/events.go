@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newEventsCmd creates the `devclaw events` command for reading the state
+// change event log.
+func newEventsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Inspect the state change event log",
+		Long: `Inspect the append-only log of state changes: access grants, config
+reloads, workspace changes and skill installs.
+
+Examples:
+  devclaw events tail
+  devclaw events tail -f --type access
+  devclaw events tail --since 24h --actor 5511999999999
+  devclaw events tail --after 120 --json`,
+	}
+	cmd.AddCommand(newEventsTailCmd())
+	return cmd
+}
+
+func newEventsTailCmd() *cobra.Command {
+	var (
+		lines    int
+		follow   bool
+		asJSON   bool
+		types    []string
+		actor    string
+		subject  string
+		since    time.Duration
+		afterSeq int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print recent events, optionally following new ones",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			path := eventLogPath(cmd)
+
+			filter := copilot.StateEventFilter{
+				Types:    types,
+				Actor:    actor,
+				Subject:  subject,
+				AfterSeq: afterSeq,
+				Limit:    lines,
+			}
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+
+			print := func(e copilot.StateEvent) {
+				if asJSON {
+					data, _ := json.Marshal(e)
+					fmt.Println(string(data))
+					return
+				}
+				fmt.Println(e.Format())
+			}
+
+			events, err := copilot.ReadStateEvents(path, filter)
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", path, err)
+			}
+			for _, e := range events {
+				print(e)
+			}
+			if !follow {
+				if len(events) == 0 && !asJSON {
+					fmt.Fprintf(os.Stderr, "No matching events in %s\n", path)
+				}
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			filter.Limit = 0
+			return copilot.FollowStateEvents(ctx, path, filter, print)
+		},
+	}
+
+	cmd.Flags().IntVarP(&lines, "lines", "n", 20, "number of recent events to print (0 = all)")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "keep printing new events as they are appended")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print events as JSON lines")
+	cmd.Flags().StringSliceVar(&types, "type", nil, "event type or category (access, workspace.create, ...)")
+	cmd.Flags().StringVar(&actor, "actor", "", "only events made by this actor")
+	cmd.Flags().StringVar(&subject, "subject", "", "only events about this contact, workspace or skill")
+	cmd.Flags().DurationVar(&since, "since", 0, "only events newer than this (e.g. 1h, 24h)")
+	cmd.Flags().Int64Var(&afterSeq, "after", 0, "only events after this sequence number")
+	return cmd
+}
+
+// eventLogPath returns the configured event log path.
+func eventLogPath(cmd *cobra.Command) string {
+	if cfg, _, err := loadConfig(cmd); err == nil && cfg.EventLog.Path != "" {
+		return cfg.EventLog.Path
+	}
+	return copilot.DefaultEventLogConfig().Path
+}
+
+// recordCLIEvent appends a state event for a change made from the CLI, so
+// the log also covers changes made while the server is not running.
+func recordCLIEvent(cmd *cobra.Command, eventType, subject string, data map[string]any) {
+	if cfg, _, err := loadConfig(cmd); err == nil && !cfg.EventLog.Enabled {
+		return
+	}
+	el, err := copilot.OpenStateEventLog(eventLogPath(cmd), slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	if err != nil {
+		return
+	}
+	defer el.Close()
+	el.Emit(eventType, "cli", subject, data)
+}
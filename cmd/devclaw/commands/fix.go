@@ -60,8 +60,8 @@ func quickAssistant(cfg *copilot.Config, cmd *cobra.Command) (*copilot.Assistant
 	copilot.AuditSecrets(cfg, logger)
 	vault := copilot.ResolveAPIKey(cfg, logger)
 
-	if cfg.API.APIKey == "" || copilot.IsEnvReference(cfg.API.APIKey) {
-		return nil, nil, fmt.Errorf("no API key configured. Run: devclaw config vault-set")
+	if cfg.API.Subscription == "" && (cfg.API.APIKey == "" || copilot.IsEnvReference(cfg.API.APIKey)) {
+		return nil, nil, fmt.Errorf("no API key configured. Run: devclaw config vault-set (or devclaw auth login)")
 	}
 
 	assistant := copilot.New(cfg, logger)
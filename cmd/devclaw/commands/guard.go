@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// newGuardCmd creates the `devclaw guard` command for inspecting tool guard
+// presets and the effective security policy.
+func newGuardCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "guard",
+		Short: "Inspect tool guard presets and the effective security policy",
+		Long: `Inspect the tool security guard.
+
+Presets are layered rule packs selected with security.tool_guard.preset.
+Keys set explicitly under security.tool_guard are applied on top.
+
+Examples:
+  devclaw guard presets
+  devclaw guard show-effective
+  devclaw guard show-effective --preset business-strict
+  devclaw guard forensics
+  devclaw guard forensics 2026-10-16/142501.123456789-bash`,
+	}
+
+	cmd.AddCommand(
+		newGuardPresetsCmd(),
+		newGuardShowEffectiveCmd(),
+		newGuardForensicsCmd(),
+	)
+	return cmd
+}
+
+func newGuardPresetsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "presets",
+		Short: "List available presets and rule packs",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var userPacks map[string]copilot.GuardRulePack
+			if cfg, _, err := loadConfig(cmd); err == nil {
+				userPacks = cfg.Security.ToolGuard.RulePacks
+			}
+			fmt.Print(copilot.FormatGuardPresets(userPacks))
+			return nil
+		},
+	}
+}
+
+// effectiveGuardView is the YAML shape printed by show-effective. Empty
+// protected paths are expanded to the built-in defaults so the output shows
+// what is actually enforced.
+type effectiveGuardView struct {
+	Enabled             bool              `yaml:"enabled"`
+	Preset              string            `yaml:"preset,omitempty"`
+	AllowDestructive    bool              `yaml:"allow_destructive"`
+	AllowSudo           bool              `yaml:"allow_sudo"`
+	AllowReboot         bool              `yaml:"allow_reboot"`
+	BlockSudo           bool              `yaml:"block_sudo"`
+	ToolPermissions     map[string]string `yaml:"tool_permissions"`
+	RequireConfirmation []string          `yaml:"require_confirmation"`
+	AutoApprove         []string          `yaml:"auto_approve"`
+	DangerousCommands   []string          `yaml:"dangerous_commands"`
+	ProtectedPaths      []string          `yaml:"protected_paths"`
+	SSHAllowedHosts     []string          `yaml:"ssh_allowed_hosts"`
+}
+
+func newGuardShowEffectiveCmd() *cobra.Command {
+	var preset string
+
+	cmd := &cobra.Command{
+		Use:   "show-effective",
+		Short: "Print the merged tool guard policy",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			guardCfg := copilot.DefaultToolGuardConfig()
+			source := "built-in defaults"
+			if cfg, path, err := loadConfig(cmd); err == nil {
+				guardCfg = cfg.Security.ToolGuard
+				source = path
+			}
+
+			layers := []string{"defaults"}
+			if preset != "" {
+				// Preview a preset without the config.yaml overrides.
+				resolved, l, err := copilot.ResolveGuardPreset(copilot.DefaultToolGuardConfig(), preset, guardCfg.RulePacks, nil)
+				if err != nil {
+					return err
+				}
+				guardCfg, layers = resolved, l
+				source += " (preview)"
+			} else if guardCfg.Preset != "" {
+				chain, err := copilot.GuardPresetChain(guardCfg.Preset, guardCfg.RulePacks)
+				if err != nil {
+					return err
+				}
+				layers = append(append(layers, chain...), "config.yaml")
+			}
+
+			view := effectiveGuardView{
+				Enabled:             guardCfg.Enabled,
+				Preset:              guardCfg.Preset,
+				AllowDestructive:    guardCfg.AllowDestructive,
+				AllowSudo:           guardCfg.AllowSudo,
+				AllowReboot:         guardCfg.AllowReboot,
+				BlockSudo:           guardCfg.BlockSudo,
+				ToolPermissions:     guardCfg.ToolPermissions,
+				RequireConfirmation: guardCfg.RequireConfirmation,
+				AutoApprove:         guardCfg.AutoApprove,
+				DangerousCommands:   guardCfg.DangerousCommands,
+				ProtectedPaths:      copilot.EffectiveProtectedPaths(guardCfg),
+				SSHAllowedHosts:     guardCfg.SSHAllowedHosts,
+			}
+
+			data, err := yaml.Marshal(view)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("# Source: %s\n", source)
+			fmt.Printf("# Layers: %s\n", strings.Join(layers, " → "))
+			fmt.Println("# dangerous_commands are in addition to the built-in blocklist.")
+			fmt.Println()
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&preset, "preset", "", "preview a preset instead of the configured policy")
+	return cmd
+}
+
+func newGuardForensicsCmd() *cobra.Command {
+	var tool string
+
+	cmd := &cobra.Command{
+		Use:   "forensics [id]",
+		Short: "List forensic bundles of high-risk tool calls, or print one",
+		Long: `List the forensic bundles recorded for high-risk tool calls (bash, ssh,
+file writes), newest last. With an ID (as shown in the list and in the audit
+log as [forensics:<id>]), print the full bundle as JSON.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guardCfg := copilot.DefaultToolGuardConfig()
+			if cfg, _, err := loadConfig(cmd); err == nil {
+				guardCfg = cfg.Security.ToolGuard
+			}
+			dir := copilot.ForensicsDir(guardCfg.Forensics, guardCfg.AuditLogPath)
+
+			if len(args) == 1 {
+				b, err := copilot.ReadForensicBundle(dir, args[0])
+				if err != nil {
+					return err
+				}
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				enc.SetEscapeHTML(false)
+				return enc.Encode(b)
+			}
+
+			entries, err := copilot.ListForensicBundles(dir)
+			if err != nil {
+				return err
+			}
+			shown := 0
+			for _, e := range entries {
+				if tool != "" && !strings.HasSuffix(e.ID, "-"+tool) {
+					continue
+				}
+				fmt.Printf("%s  %6.1f KB\n", e.ID, float64(e.Size)/1024)
+				shown++
+			}
+			if shown == 0 {
+				fmt.Printf("No forensic bundles in %s\n", dir)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&tool, "tool", "", "only list bundles of this tool")
+	return cmd
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newImportCmd creates the `devclaw import` command for migrating from other
+// assistants.
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Migrate configuration, persona and skills from another assistant",
+	}
+	cmd.AddCommand(newImportOpenClawCmd())
+	return cmd
+}
+
+func newImportOpenClawCmd() *cobra.Command {
+	var (
+		out    string
+		dryRun bool
+		force  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "openclaw [dir]",
+		Short: "Import an OpenClaw installation (config, SOUL.md & co., skills)",
+		Long: `Converts an OpenClaw installation (default: ~/.openclaw) into the
+DevClaw layout:
+
+  openclaw.json                      -> config.yaml
+  workspace/SOUL.md, AGENTS.md, ...  -> bootstrap files in the output dir
+  workspace/memory/*.md              -> data/memory/
+  workspace/skills, skills, extraDirs -> skills/<name>/
+
+Options without a DevClaw equivalent are listed, secrets are replaced by
+${ENV} references, and the written config is loaded back to validate it.
+
+Examples:
+  devclaw import openclaw
+  devclaw import openclaw ~/.openclaw --out ~/devclaw
+  devclaw import openclaw ./openclaw-backup --dry-run`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "~/.openclaw"
+			if len(args) > 0 {
+				dir = args[0]
+			}
+			if home, err := os.UserHomeDir(); err == nil && len(dir) > 1 && dir[:2] == "~/" {
+				dir = filepath.Join(home, dir[2:])
+			}
+
+			imp, err := copilot.ImportOpenClaw(dir)
+			if err != nil {
+				return err
+			}
+			printOpenClawImport(imp)
+
+			configPath := filepath.Join(out, "config.yaml")
+			if dryRun {
+				fmt.Printf("\nDry run: would write %s and %d files under %s\n", configPath, len(imp.Files), out)
+				return nil
+			}
+			if _, err := os.Stat(configPath); err == nil && !force {
+				return fmt.Errorf("%s already exists (use --force to overwrite, or --out for another directory)", configPath)
+			}
+
+			if err := os.MkdirAll(out, 0o755); err != nil {
+				return err
+			}
+			skipped, err := imp.CopyFiles(out, force)
+			if err != nil {
+				return err
+			}
+			for _, s := range skipped {
+				fmt.Printf("  kept existing %s\n", s)
+			}
+			if err := copilot.SaveConfigToFile(imp.Config, configPath); err != nil {
+				return err
+			}
+			fmt.Printf("\nWrote %s and %d files\n\n", configPath, len(imp.Files)-len(skipped))
+
+			cfg, err := copilot.LoadConfigFromFile(configPath)
+			if err != nil {
+				return fmt.Errorf("imported config does not validate: %w", err)
+			}
+			printConfigSummary(cfg, configPath)
+			fmt.Println("\nConfiguration is valid.")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&out, "out", "o", ".", "DevClaw directory to write into")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the conversion without writing anything")
+	cmd.Flags().BoolVar(&force, "force", false, "overwrite an existing config.yaml and files")
+	return cmd
+}
+
+// printOpenClawImport prints what an import converts and what it leaves out.
+func printOpenClawImport(imp *copilot.OpenClawImport) {
+	if imp.Source != "" {
+		fmt.Printf("Config:  %s\n", imp.Source)
+	}
+	fmt.Printf("Model:   %s\n", imp.Config.Model)
+	fmt.Printf("Files:   %d\n", len(imp.Files))
+	if len(imp.Skills) > 0 {
+		fmt.Printf("Skills:  %d\n", len(imp.Skills))
+		for _, s := range imp.Skills {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
+	if len(imp.Unsupported) > 0 {
+		fmt.Printf("\nNot imported (%d):\n", len(imp.Unsupported))
+		for _, u := range imp.Unsupported {
+			fmt.Printf("  - %s\n", u)
+		}
+	}
+	if len(imp.Notes) > 0 {
+		fmt.Println("\nTo do:")
+		for _, n := range imp.Notes {
+			fmt.Printf("  - %s\n", n)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newLSPProxyCmd creates the `devclaw lsp-proxy` command, the backend used by
+// editor extensions.
+func newLSPProxyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp-proxy",
+		Short: "Serve editor extension requests over stdio",
+		Long: `Start the editor integration backend using JSON-RPC 2.0 over stdin/stdout
+with LSP-style Content-Length framing. Editor plugins (VSCode, Neovim, ...)
+spawn this process and send requests; all prompting and tool use happens here.
+
+Methods:
+  initialize                 sets the project root from rootUri/rootPath
+  devclaw/explainSelection   {path, language, text, range}
+  devclaw/fixDiagnostics     {path, language, text, diagnostics}
+  devclaw/generateTests      {path, language, text, framework}
+  devclaw/cancel             {id}
+  devclaw/resetSession       starts a fresh conversation for the project
+  shutdown, exit
+
+While a request runs, text is streamed as "devclaw/stream" notifications
+({id, delta}); the final response carries the full text.
+
+Requests from the same project root share one session, so follow-up
+questions keep their context.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, _, err := resolveConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			cmd.SetContext(ctx)
+
+			assistant, cleanup, err := quickAssistant(cfg, cmd)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			// stdout carries the protocol; logs must go to stderr.
+			logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+			proxy := copilot.NewEditorProxy(assistant, logger)
+
+			if err := proxy.Serve(ctx, os.Stdin, os.Stdout); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("lsp-proxy error: %w", err)
+			}
+			return nil
+		},
+	}
+	return cmd
+}
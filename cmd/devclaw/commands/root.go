@@ -40,6 +40,20 @@ Examples:
 		newHowCmd(),
 		newShellHookCmd(),
 		newMCPCmd(),
+		newGuardCmd(),
+		newLSPProxyCmd(),
+		newEventsCmd(),
+		newBisectCmd(),
+		newAuthCmd(),
+		newEvalCmd(),
+		newSessionsCmd(),
+		newImportCmd(),
+		newDebugCmd(),
+		newAuditRepoCmd(),
+		newAuditCmd(),
+		newCorpusCmd(),
+		newUsageCmd(),
+		newVaultCmd(),
 	)
 
 	// Flags globais.
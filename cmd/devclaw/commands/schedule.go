@@ -2,7 +2,9 @@ package commands
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
 	"github.com/spf13/cobra"
 )
 
@@ -46,17 +48,28 @@ func newScheduleAddCmd() *cobra.Command {
 		Use:   "add <schedule> <command>",
 		Short: "Adiciona uma nova tarefa agendada",
 		Args:  cobra.ExactArgs(2),
-		RunE: func(_ *cobra.Command, args []string) error {
-			schedule := args[0]
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loc := time.Local
+			if tz, _ := cmd.Flags().GetString("timezone"); tz != "" {
+				var err error
+				if loc, err = scheduler.LoadTimezone(tz); err != nil {
+					return err
+				}
+			}
+			spec, err := scheduler.ParseSchedule(args[0], "", time.Now().In(loc))
+			if err != nil {
+				return err
+			}
 			command := args[1]
 			// TODO: Adicionar job ao scheduler.
-			fmt.Printf("Tarefa agendada: %q → %q\n", schedule, command)
+			fmt.Printf("Tarefa agendada: %s (%s, %s) → %q\n", spec.Schedule, spec.Type, loc, command)
 			return nil
 		},
 	}
 
 	cmd.Flags().String("channel", "whatsapp", "canal para enviar resultado")
 	cmd.Flags().String("chat-id", "", "ID do chat/grupo destino")
+	cmd.Flags().String("timezone", "", "fuso horário da agenda (ex.: America/Sao_Paulo; padrão: local)")
 	return cmd
 }
 
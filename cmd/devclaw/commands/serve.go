@@ -7,12 +7,16 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/discord"
+	emailchan "github.com/jholhewres/devclaw/pkg/devclaw/channels/email"
 	slackchan "github.com/jholhewres/devclaw/pkg/devclaw/channels/slack"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/telegram"
+	webhookchan "github.com/jholhewres/devclaw/pkg/devclaw/channels/webhook"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/whatsapp"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
 	"github.com/jholhewres/devclaw/pkg/devclaw/gateway"
@@ -36,7 +40,7 @@ Examples:
 		RunE: runServe,
 	}
 
-	cmd.Flags().StringSlice("channel", nil, "channels to enable (whatsapp, discord, telegram)")
+	cmd.Flags().StringSlice("channel", nil, "channels to enable (whatsapp, discord, telegram, slack, email, webhook)")
 	return cmd
 }
 
@@ -44,8 +48,15 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	// ── Load config ──
 	cfg, configPath, err := resolveConfig(cmd)
 	if err != nil {
-		// No config? Start in web setup mode.
-		return runWebSetupMode()
+		// No config? Run the web setup wizard, then start with the config
+		// it wrote — no restart needed.
+		done, setupErr := runWebSetupMode()
+		if setupErr != nil || !done {
+			return setupErr
+		}
+		if cfg, configPath, err = resolveConfig(cmd); err != nil {
+			return err
+		}
 	}
 
 	// ── Configure logger ──
@@ -115,7 +126,7 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	}
 
 	// Discord (core channel).
-	if shouldEnable("discord", channelFilter, false) && cfg.Channels.Discord.Token != "" {
+	if shouldEnable("discord", channelFilter, true) && cfg.Channels.Discord.Token != "" {
 		dc := discord.New(cfg.Channels.Discord, logger)
 		if err := assistant.ChannelManager().Register(dc); err != nil {
 			logger.Error("failed to register Discord", "error", err)
@@ -124,6 +135,27 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
+	// Email (IMAP/SMTP, enabled when an IMAP host is configured).
+	if shouldEnable("email", channelFilter, true) && cfg.Channels.Email.IMAPHost != "" {
+		em := emailchan.New(cfg.Channels.Email, logger)
+		if err := assistant.ChannelManager().Register(em); err != nil {
+			logger.Error("failed to register email", "error", err)
+		} else {
+			logger.Info("Email channel registered")
+		}
+	}
+
+	// Webhook (inbound events on /hooks/{name}, replies forwarded to a notify target).
+	if shouldEnable("webhook", channelFilter, true) && cfg.Channels.Webhook.Enabled {
+		wh := webhookchan.New(cfg.Channels.Webhook, logger)
+		wh.SetForwarder(assistant.ChannelManager().Send)
+		if err := assistant.ChannelManager().Register(wh); err != nil {
+			logger.Error("failed to register webhook", "error", err)
+		} else {
+			logger.Info("Webhook channel registered")
+		}
+	}
+
 	// Load plugins (other channels).
 	pluginLoader := plugins.NewLoader(cfg.Plugins, logger)
 	if err := pluginLoader.LoadAll(ctx); err != nil {
@@ -250,8 +282,10 @@ func resolveConfig(cmd *cobra.Command) (*copilot.Config, string, error) {
 }
 
 // runWebSetupMode starts a minimal webui server in setup-only mode.
-// Blocks until the setup wizard completes or the user cancels.
-func runWebSetupMode() error {
+// Blocks until the setup wizard completes (done = true) or the user cancels.
+// A WhatsApp session linked in the wizard is released before returning so
+// the assistant can reuse it.
+func runWebSetupMode() (done bool, err error) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	fmt.Println()
@@ -297,25 +331,62 @@ func runWebSetupMode() error {
 		return nil
 	})
 
+	// WhatsApp linking: the channel connects (and starts emitting QR codes)
+	// only when the wizard asks for it.
+	waCfg := whatsapp.DefaultConfig()
+	wa := whatsapp.New(waCfg, logger)
+	var (
+		waOnce    sync.Once
+		waStarted bool
+		waErr     error
+	)
+	qr := &webui.AssistantAdapter{}
+	wireWhatsAppQR(qr, wa)
+	webServer.SetSetupHooks(webui.SetupHooks{
+		WhatsApp: qr,
+		StartWhatsAppFn: func() error {
+			waOnce.Do(func() {
+				if waErr = os.MkdirAll(waCfg.SessionDir, 0o755); waErr != nil {
+					return
+				}
+				waStarted = true
+				waErr = wa.Connect(context.Background())
+			})
+			return waErr
+		},
+		SendWhatsAppFn: func(phone, text string) error {
+			return wa.Send(context.Background(), phone+"@s.whatsapp.net", &channels.OutgoingMessage{Content: text})
+		},
+		ValidateConfigFn: func(data []byte) error {
+			_, err := copilot.ParseConfig(data)
+			return err
+		},
+	})
+
 	if err := webServer.Start(context.Background()); err != nil {
-		return fmt.Errorf("failed to start setup server: %w", err)
+		return false, fmt.Errorf("failed to start setup server: %w", err)
 	}
 
 	// Wait for setup completion or interrupt.
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	defer signal.Stop(sigChan)
+
 	select {
 	case <-setupDone:
-		webServer.Stop()
+		done = true
 		fmt.Println()
 		fmt.Println("Setup complete! config.yaml saved.")
-		fmt.Println("Restarting...")
-		return nil
+		fmt.Println("Starting DevClaw...")
 	case <-sigChan:
-		webServer.Stop()
-		return nil
 	}
+	webServer.Stop()
+	waOnce.Do(func() {}) // no linking after this point
+	if waStarted {
+		wa.Disconnect()
+	}
+	return done, nil
 }
 
 // shouldEnable checks if a channel should be enabled.
@@ -532,6 +603,7 @@ func buildWebUIAdapter(assistant *copilot.Assistant, cfg *copilot.Config, wa *wh
 					ID:        j.ID,
 					Schedule:  j.Schedule,
 					Type:      j.Type,
+					Timezone:  sched.Location(j).String(),
 					Command:   j.Command,
 					Enabled:   j.Enabled,
 					RunCount:  j.RunCount,
@@ -878,32 +950,38 @@ func buildWebUIAdapter(assistant *copilot.Assistant, cfg *copilot.Config, wa *wh
 
 	// Wire up WhatsApp QR callbacks if WhatsApp channel is available.
 	if wa != nil {
-		adapter.GetWhatsAppStatusFn = func() webui.WhatsAppStatus {
-			return webui.WhatsAppStatus{
-				Connected: wa.IsConnected(),
-				NeedsQR:   wa.NeedsQR(),
-			}
-		}
-		adapter.SubscribeWhatsAppQRFn = func() (chan webui.WhatsAppQREvent, func()) {
-			ch, unsub := wa.SubscribeQR()
-			// Bridge whatsapp.QREvent → webui.WhatsAppQREvent
-			out := make(chan webui.WhatsAppQREvent, 8)
-			go func() {
-				defer close(out)
-				for evt := range ch {
-					out <- webui.WhatsAppQREvent{
-						Type:    evt.Type,
-						Code:    evt.Code,
-						Message: evt.Message,
-					}
-				}
-			}()
-			return out, unsub
-		}
-		adapter.RequestWhatsAppQRFn = func() error {
-			return wa.RequestNewQR(context.Background())
-		}
+		wireWhatsAppQR(adapter, wa)
 	}
 
 	return adapter
 }
+
+// wireWhatsAppQR connects the WhatsApp status and QR functions of adapter
+// to wa.
+func wireWhatsAppQR(adapter *webui.AssistantAdapter, wa *whatsapp.WhatsApp) {
+	adapter.GetWhatsAppStatusFn = func() webui.WhatsAppStatus {
+		return webui.WhatsAppStatus{
+			Connected: wa.IsConnected(),
+			NeedsQR:   wa.NeedsQR(),
+		}
+	}
+	adapter.SubscribeWhatsAppQRFn = func() (chan webui.WhatsAppQREvent, func()) {
+		ch, unsub := wa.SubscribeQR()
+		// Bridge whatsapp.QREvent → webui.WhatsAppQREvent
+		out := make(chan webui.WhatsAppQREvent, 8)
+		go func() {
+			defer close(out)
+			for evt := range ch {
+				out <- webui.WhatsAppQREvent{
+					Type:    evt.Type,
+					Code:    evt.Code,
+					Message: evt.Message,
+				}
+			}
+		}()
+		return out, unsub
+	}
+	adapter.RequestWhatsAppQRFn = func() error {
+		return wa.RequestNewQR(context.Background())
+	}
+}
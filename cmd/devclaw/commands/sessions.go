@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newSessionsCmd creates the `devclaw sessions` command for working with
+// persisted conversation sessions.
+func newSessionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Work with persisted conversation sessions",
+		Long: `Work with conversation sessions stored by the persistence backend
+(memory.backend: SQLite by default, or JSONL).
+
+Examples:
+  devclaw sessions export whatsapp:5511999999999@s.whatsapp.net
+  devclaw sessions export 3f2a9c1e0b7d4a11 --format json -o session.json
+  devclaw sessions export telegram:123456:research --out research.md
+  devclaw sessions export 3f2a9c1e0b7d4a11 --format html -o session.html
+  devclaw sessions publish whatsapp:5511999999999@s.whatsapp.net`,
+	}
+	cmd.AddCommand(newSessionsExportCmd(), newSessionsPublishCmd())
+	return cmd
+}
+
+func newSessionsExportCmd() *cobra.Command {
+	var (
+		format string
+		out    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export <session-id|channel:chatID[:branch]>",
+		Short: "Export a session transcript with tool calls and usage",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := resolveConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			export, err := copilot.LoadSessionExport(cfg, args[0])
+			if err != nil {
+				return err
+			}
+			data, err := export.Render(format)
+			if err != nil {
+				return err
+			}
+
+			if out == "" || out == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			if err := os.WriteFile(out, data, 0o600); err != nil {
+				return fmt.Errorf("writing %s: %w", out, err)
+			}
+			fmt.Fprintf(os.Stderr, "Exported %d messages to %s\n", len(export.Messages), out)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "md", "output format: md, json or html")
+	cmd.Flags().StringVarP(&out, "out", "o", "", "write to file instead of stdout")
+	return cmd
+}
+
+func newSessionsPublishCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "publish <session-id|channel:chatID[:branch]>",
+		Short: "Publish a session as a redacted static HTML page and print its link",
+		Long: `Render a session transcript into a self-contained HTML page with secrets
+redacted and tool calls collapsed, write it to share.dir, run
+share.upload_command when set, and print the link (share.base_url/<name>, or
+the local path).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, _, err := resolveConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			export, err := copilot.LoadSessionExport(cfg, args[0])
+			if err != nil {
+				return err
+			}
+			link, err := copilot.PublishSessionExport(cmd.Context(), cfg, export)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Published %d messages (secrets redacted)\n", len(export.Messages))
+			fmt.Println(link)
+			return nil
+		},
+	}
+}
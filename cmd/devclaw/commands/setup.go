@@ -16,7 +16,11 @@ func newSetupCmd() *cobra.Command {
 headless servers, containers, and automation tools like pm2/systemd.
 
 Just run 'devclaw serve' — if no config.yaml exists, the web setup
-wizard will start automatically at http://localhost:8090/setup`,
+wizard will start automatically at http://localhost:8090/setup
+
+The wizard walks through provider, owner verification, channel linking
+(with a live WhatsApp QR code), skills, security preset and a final
+validation, then starts the assistant without a restart.`,
 		Run: func(_ *cobra.Command, _ []string) {
 			fmt.Println()
 			fmt.Println("╭────────────────────────────────────────────────╮")
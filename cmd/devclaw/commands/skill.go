@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -36,7 +39,8 @@ Other:
   devclaw skill search calendar                        # Search ClawHub
   devclaw skill info <name>                            # Show skill details
   devclaw skill remove <name>                          # Remove a skill
-  devclaw skill update --all                           # Update all GitHub skills`,
+  devclaw skill update --all                           # Update all GitHub skills
+  devclaw skill publish ./my-skill --dry-run           # Lint, test, bundle, show payload`,
 	}
 
 	cmd.AddCommand(
@@ -47,6 +51,7 @@ Other:
 		newSkillUpdateCmd(),
 		newSkillRemoveCmd(),
 		newSkillInfoCmd(),
+		newSkillPublishCmd(),
 	)
 
 	return cmd
@@ -263,6 +268,9 @@ Explicit prefixes: clawhub:<slug>, github:<user/repo>`,
 			if err != nil {
 				return fmt.Errorf("install failed: %w", err)
 			}
+			recordCLIEvent(cmd, copilot.EventSkillInstall, result.Name, map[string]any{
+				"source": result.Source, "updated": !result.IsNew,
+			})
 
 			fmt.Println()
 			if result.IsNew {
@@ -359,6 +367,7 @@ func newSkillRemoveCmd() *cobra.Command {
 			if err := os.RemoveAll(targetDir); err != nil {
 				return fmt.Errorf("removing skill: %w", err)
 			}
+			recordCLIEvent(cmd, copilot.EventSkillRemove, name, nil)
 
 			fmt.Printf("Removed skill: %s\n", name)
 			return nil
@@ -413,3 +422,215 @@ func newSkillInfoCmd() *cobra.Command {
 		},
 	}
 }
+
+func newSkillPublishCmd() *cobra.Command {
+	var (
+		to        string
+		bump      string
+		slug      string
+		branch    string
+		hubURL    string
+		out       string
+		dryRun    bool
+		skipTests bool
+	)
+	cmd := &cobra.Command{
+		Use:   "publish [dir]",
+		Short: "Lint, test, bundle and publish a skill to ClawHub or a git repo",
+		Long: `Publish a local skill. Steps: lint SKILL.md, run the skill's tests
+(test.sh, tests/run.sh, Go, npm or pytest), bump the version, bundle it
+and upload it. The new version is written to SKILL.md only after the
+upload succeeds.
+
+Credentials come from the vault (devclaw config vault-set) or the
+environment: clawhub_token / DEVCLAW_CLAWHUB_TOKEN for ClawHub,
+git_token / DEVCLAW_GIT_TOKEN for https git remotes (ssh remotes use
+your SSH agent).
+
+Examples:
+  devclaw skill publish ./skills/my-skill --dry-run
+  devclaw skill publish ./skills/my-skill --bump minor
+  devclaw skill publish . --to git@github.com:me/skills.git`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := "."
+			if len(args) > 0 {
+				dir = args[0]
+			}
+
+			// 1. Lint.
+			def, issues := skills.LintSkill(dir)
+			for _, issue := range issues {
+				fmt.Println("  " + issue.String())
+			}
+			if skills.HasLintErrors(issues) {
+				return fmt.Errorf("lint failed")
+			}
+			fmt.Printf("Lint:    ok (%s)\n", def.Name)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+
+			// 2. Tests.
+			if skipTests {
+				fmt.Println("Tests:   skipped")
+			} else {
+				ran, output, err := skills.RunSkillTests(ctx, dir)
+				if err != nil {
+					fmt.Println(output)
+					return err
+				}
+				if ran {
+					fmt.Println("Tests:   passed")
+				} else {
+					fmt.Println("Tests:   none found")
+				}
+			}
+
+			// 3. Version.
+			oldVersion := def.Version
+			part := bump
+			if oldVersion == "" {
+				part = "" // first publish starts at 0.1.0
+			}
+			version, err := skills.BumpVersion(oldVersion, part)
+			if err != nil {
+				return err
+			}
+			def.Version = version
+			if oldVersion != "" && oldVersion != version {
+				fmt.Printf("Version: %s -> %s\n", oldVersion, version)
+			} else {
+				fmt.Printf("Version: %s\n", version)
+			}
+
+			// 4. Bundle, with the bumped SKILL.md.
+			skillPath := filepath.Join(dir, "SKILL.md")
+			original, err := os.ReadFile(skillPath)
+			if err != nil {
+				return err
+			}
+			bumped := []byte(skills.SetFrontmatterVersion(string(original), version))
+			bundle, err := skills.BundleSkill(dir, map[string][]byte{"SKILL.md": bumped})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Bundle:  %d files, %d bytes, sha256 %s\n", len(bundle.Files), len(bundle.Data), bundle.SHA256[:12])
+
+			// 5. Publish.
+			if skills.IsGitTarget(to) {
+				target := skills.GitPublish{Repo: to, Branch: branch}
+				if dryRun {
+					fmt.Println("\nDry run, would run:")
+					for _, c := range target.Commands(def) {
+						fmt.Println("  " + c)
+					}
+					fmt.Println("\nFiles:")
+					for _, f := range bundle.Files {
+						fmt.Println("  " + f)
+					}
+					return writePublishOutput(out, bundle.Data)
+				}
+				if strings.HasPrefix(strings.TrimPrefix(to, "git+"), "https://") {
+					if target.Token, err = publishSecret("git_token"); err != nil {
+						return err
+					}
+				}
+				if err := target.Publish(ctx, def, bundle); err != nil {
+					return fmt.Errorf("publish failed: %w", err)
+				}
+				fmt.Printf("\nPublished %s v%s to %s\n", def.Name, version, to)
+			} else {
+				if to != "" && to != "clawhub" {
+					return fmt.Errorf("unknown target %q (use clawhub or a git URL)", to)
+				}
+				payload := skills.NewPublishRequest(def, slug, bundle)
+				if dryRun {
+					shown := payload
+					shown.Archive = fmt.Sprintf("<%d bytes base64, --out writes the full payload>", len(payload.Archive))
+					var data bytes.Buffer
+					enc := json.NewEncoder(&data)
+					enc.SetEscapeHTML(false)
+					enc.SetIndent("", "  ")
+					_ = enc.Encode(shown)
+					hub := hubURL
+					if hub == "" {
+						hub = skills.DefaultClawHubURL
+					}
+					fmt.Printf("\nDry run, would POST %s/publish:\n%s", hub, data.String())
+					full, _ := json.MarshalIndent(payload, "", "  ")
+					return writePublishOutput(out, full)
+				}
+				token, err := publishSecret("clawhub_token")
+				if err != nil {
+					return err
+				}
+				result, err := skills.NewClawHubClient(hubURL).Publish(ctx, token, payload)
+				if err != nil {
+					return fmt.Errorf("publish failed: %w", err)
+				}
+				fmt.Printf("\nPublished %s v%s", result.Slug, result.Version)
+				if result.URL != "" {
+					fmt.Printf(": %s", result.URL)
+				}
+				fmt.Println()
+			}
+
+			if !bytes.Equal(bumped, original) {
+				if err := os.WriteFile(skillPath, bumped, 0o644); err != nil {
+					return fmt.Errorf("published, but updating SKILL.md failed: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "clawhub", "publish target: clawhub or a git repository URL")
+	cmd.Flags().StringVar(&bump, "bump", "patch", "version part to bump: major, minor, patch or \"\" to keep it")
+	cmd.Flags().StringVar(&slug, "slug", "", "ClawHub slug (default: the skill name)")
+	cmd.Flags().StringVar(&branch, "branch", "", "git branch to push to (default: the remote's default)")
+	cmd.Flags().StringVar(&hubURL, "hub-url", "", "ClawHub API base URL")
+	cmd.Flags().StringVar(&out, "out", "", "with --dry-run, write the full payload (ClawHub) or bundle (git) to this file")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "stop before uploading and show what would be sent")
+	cmd.Flags().BoolVar(&skipTests, "skip-tests", false, "don't run the skill's tests")
+	return cmd
+}
+
+// writePublishOutput saves a dry run's payload when --out is set.
+func writePublishOutput(path string, data []byte) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote %s\n", path)
+	return nil
+}
+
+// publishSecret reads a publishing credential from the environment
+// (DEVCLAW_<KEY>) or the encrypted vault.
+func publishSecret(key string) (string, error) {
+	if v := os.Getenv("DEVCLAW_" + strings.ToUpper(key)); v != "" {
+		return v, nil
+	}
+	vault := copilot.NewVault(copilot.VaultFile)
+	if !vault.Exists() {
+		return "", fmt.Errorf("no %s: set DEVCLAW_%s or store it with 'devclaw config vault-init'", key, strings.ToUpper(key))
+	}
+	password := os.Getenv("DEVCLAW_VAULT_PASSWORD")
+	if password == "" {
+		var err error
+		if password, err = copilot.ReadPassword("Vault password: "); err != nil {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+	}
+	if err := vault.Unlock(password); err != nil {
+		return "", err
+	}
+	defer vault.Lock()
+	value, err := vault.Get(key)
+	if err != nil || value == "" {
+		return "", fmt.Errorf("%s not found in vault", key)
+	}
+	return value, nil
+}
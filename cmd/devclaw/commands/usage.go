@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newUsageCmd creates the `devclaw usage` command for reporting token usage
+// and estimated cost from the usage ledger.
+func newUsageCmd() *cobra.Command {
+	var (
+		since       string
+		workspace   string
+		byWorkspace bool
+		asJSON      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show token usage and estimated cost per model",
+		Long: `Report LLM calls, tokens and estimated cost per model from the usage
+ledger in devclaw.db (billing.enabled). --since takes a date (2026-03-01),
+a duration (24h, 7d) or "today" (the default).
+
+Examples:
+  devclaw usage
+  devclaw usage --since 7d --by-workspace
+  devclaw usage --since 2026-03-01 --workspace sales --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, _, err := resolveConfig(cmd)
+			if err != nil {
+				return err
+			}
+			start, err := parseUsageSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+
+			report, err := copilot.LoadUsageReport(cfg, start, workspace, byWorkspace)
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			fmt.Println(report.Format(cfg.LocaleFor("").Locale))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "today", "start of the period: today, a date (YYYY-MM-DD) or a duration (24h, 7d)")
+	cmd.Flags().StringVar(&workspace, "workspace", "", "only this workspace")
+	cmd.Flags().BoolVar(&byWorkspace, "by-workspace", false, "break usage down per workspace")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the report as JSON")
+	return cmd
+}
+
+// parseUsageSince resolves --since relative to now (local time).
+func parseUsageSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "" || s == "today":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case strings.HasSuffix(s, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil && days > 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use today, YYYY-MM-DD, 24h or 7d)", s)
+}
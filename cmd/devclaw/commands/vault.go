@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
+	"github.com/spf13/cobra"
+)
+
+// newVaultCmd creates the `devclaw vault` command for managing the secrets
+// in the encrypted vault.
+func newVaultCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage secrets in the encrypted vault",
+		Long: `Store, read and remove secrets in the encrypted vault (.devclaw.vault,
+AES-256-GCM with an Argon2id-derived key). The master password is read from
+DEVCLAW_VAULT_PASSWORD or prompted for.
+
+Vault secrets are exported to tools as environment variables (name
+uppercased) and redacted from tool results before they reach the model.
+
+Examples:
+  devclaw vault set github_token
+  devclaw vault get github_token
+  devclaw vault list
+  devclaw vault delete github_token`,
+	}
+	cmd.AddCommand(
+		newVaultSetSecretCmd(),
+		newVaultGetSecretCmd(),
+		newVaultListCmd(),
+		newVaultDeleteCmd(),
+	)
+	return cmd
+}
+
+func newVaultSetSecretCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <name> [value]",
+		Short: "Store a secret (prompts for the value when omitted)",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			vault, err := openVault()
+			if err != nil {
+				return err
+			}
+			defer vault.Lock()
+
+			value := ""
+			if len(args) == 2 {
+				value = args[1]
+			} else if value, err = copilot.ReadPassword("Value for " + args[0] + ": "); err != nil {
+				return fmt.Errorf("reading value: %w", err)
+			}
+			if value == "" {
+				return fmt.Errorf("no value provided")
+			}
+			if err := vault.Set(args[0], value); err != nil {
+				return err
+			}
+			fmt.Printf("Secret '%s' stored in %s\n", args[0], vault.Path())
+			return nil
+		},
+	}
+}
+
+func newVaultGetSecretCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <name>",
+		Short: "Print a secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			vault, err := openVault()
+			if err != nil {
+				return err
+			}
+			defer vault.Lock()
+
+			value, err := vault.Get(args[0])
+			if err != nil {
+				return err
+			}
+			if value == "" {
+				return fmt.Errorf("secret '%s' not found", args[0])
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+}
+
+func newVaultListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List secret names (never values)",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			vault, err := openVault()
+			if err != nil {
+				return err
+			}
+			defer vault.Lock()
+
+			names := vault.List()
+			if len(names) == 0 {
+				fmt.Fprintln(os.Stderr, "Vault is empty.")
+				return nil
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Println(name)
+			}
+			return nil
+		},
+	}
+}
+
+func newVaultDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Remove a secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			vault, err := openVault()
+			if err != nil {
+				return err
+			}
+			defer vault.Lock()
+
+			if value, _ := vault.Get(args[0]); value == "" {
+				return fmt.Errorf("secret '%s' not found", args[0])
+			}
+			if err := vault.Delete(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Secret '%s' removed.\n", args[0])
+			return nil
+		},
+	}
+}
+
+// openVault unlocks the vault with DEVCLAW_VAULT_PASSWORD or a prompted
+// master password.
+func openVault() (*copilot.Vault, error) {
+	vault := copilot.NewVault(copilot.VaultFile)
+	if !vault.Exists() {
+		return nil, fmt.Errorf("no vault found. Run 'devclaw config vault-init' first")
+	}
+	password := os.Getenv("DEVCLAW_VAULT_PASSWORD")
+	if password == "" {
+		var err error
+		if password, err = copilot.ReadPassword("Master password: "); err != nil {
+			return nil, fmt.Errorf("reading password: %w", err)
+		}
+	}
+	if err := vault.Unlock(password); err != nil {
+		return nil, err
+	}
+	return vault, nil
+}
@@ -24,6 +24,16 @@ const (
 	MessageReaction MessageType = "reaction"
 )
 
+// Metadata keys set on IncomingMessage by channels that can tell whether a
+// message is addressed to the bot (read with MetaBool).
+const (
+	// MetaMentionsBot is true when the message @mentions the bot.
+	MetaMentionsBot = "mentions_bot"
+
+	// MetaReplyToBot is true when the message replies to one of the bot's messages.
+	MetaReplyToBot = "reply_to_bot"
+)
+
 // Channel defines the interface that every communication channel must implement.
 type Channel interface {
 	// Name returns the channel identifier (e.g. "whatsapp", "discord").
@@ -84,6 +94,44 @@ type ReactionChannel interface {
 	SendReaction(ctx context.Context, chatID, messageID, emoji string) error
 }
 
+// EditChannel extends Channel with in-place edits of sent messages.
+// Used to stream responses progressively into a single message.
+type EditChannel interface {
+	Channel
+
+	// SendEditable sends a message and returns its platform message ID so it
+	// can be edited later.
+	SendEditable(ctx context.Context, to string, message *OutgoingMessage) (string, error)
+
+	// EditMessage replaces the text of a message previously sent by the bot.
+	EditMessage(ctx context.Context, chatID, messageID, content string) error
+}
+
+// CommandSpec describes a chat command ("/status", "/model", ...) for
+// platforms with a native command menu.
+type CommandSpec struct {
+	// Name is the command without the leading slash (e.g. "status").
+	Name string
+
+	// Description is a short help text shown by the platform.
+	Description string
+
+	// TakesArgs is true if the command accepts free-form arguments.
+	TakesArgs bool
+}
+
+// CommandChannel extends Channel with native command registration (e.g.
+// Discord slash commands). Invoked commands are delivered as regular
+// IncomingMessages whose Content is "/name args", so they reach the same
+// command handler as typed commands.
+type CommandChannel interface {
+	Channel
+
+	// SetCommands sets the commands to register. Channels register them on
+	// the next Connect, or immediately if already connected.
+	SetCommands(ctx context.Context, commands []CommandSpec) error
+}
+
 // IncomingMessage represents a message received from any channel.
 type IncomingMessage struct {
 	// ID is the unique message identifier in the source channel.
@@ -135,6 +183,20 @@ type IncomingMessage struct {
 	Metadata map[string]any
 }
 
+// SetMeta sets a metadata value, creating the map if needed.
+func (m *IncomingMessage) SetMeta(key string, value any) {
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]any)
+	}
+	m.Metadata[key] = value
+}
+
+// MetaBool returns a boolean metadata value (false when absent).
+func (m *IncomingMessage) MetaBool(key string) bool {
+	v, _ := m.Metadata[key].(bool)
+	return v
+}
+
 // OutgoingMessage represents a message to be sent through a channel.
 type OutgoingMessage struct {
 	// Content is the text content of the message.
@@ -145,6 +207,39 @@ type OutgoingMessage struct {
 
 	// Metadata contains additional channel-specific data.
 	Metadata map[string]any
+
+	// Attachments are media files sent after Content. Channels without
+	// media support receive a text line per attachment instead.
+	Attachments []*MediaMessage
+
+	// Buttons are quick replies shown under the message (Telegram inline
+	// keyboard, Discord components, WhatsApp quick-reply buttons). Channels
+	// without buttons send Content only, so it should say how to answer in
+	// text as well.
+	Buttons []Button
+}
+
+// Button styles. Channels without styles ignore them.
+const (
+	ButtonDefault = ""
+	ButtonPrimary = "primary"
+	ButtonSuccess = "success"
+	ButtonDanger  = "danger"
+)
+
+// Button is a quick-reply button. Pressing it is delivered as an
+// IncomingMessage from the user who pressed it, with Content set to Data,
+// so a button with Data "/approve 42" acts as if the user typed it. The
+// channel removes the message's buttons once one is pressed.
+type Button struct {
+	// Label is the text on the button.
+	Label string
+
+	// Data is the message sent on press (at most 64 bytes for Telegram).
+	Data string
+
+	// Style is one of the Button* styles.
+	Style string
 }
 
 // MediaMessage represents a media file to be sent.
@@ -270,5 +365,6 @@ var (
 	ErrSendFailed          = fmt.Errorf("failed to send message")
 	ErrConnectionFailed    = fmt.Errorf("failed to connect to channel")
 	ErrMediaNotSupported   = fmt.Errorf("media not supported by this channel")
+	ErrEditNotSupported    = fmt.Errorf("message edits not supported by this channel")
 	ErrMediaDownloadFailed = fmt.Errorf("failed to download media")
 )
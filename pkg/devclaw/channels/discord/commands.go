@@ -0,0 +1,145 @@
+package discord
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// maxSlashCommands is Discord's limit of commands per scope.
+const maxSlashCommands = 100
+
+// slashNameRe matches a valid slash command name.
+var slashNameRe = regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+// SetCommands stores the chat commands and registers them as slash commands
+// if the session is already open; otherwise they are registered on Connect.
+func (d *Discord) SetCommands(_ context.Context, commands []channels.CommandSpec) error {
+	d.commandsMu.Lock()
+	d.commands = append([]channels.CommandSpec(nil), commands...)
+	d.commandsMu.Unlock()
+
+	if d.connected.Load() {
+		d.registerCommands()
+	}
+	return nil
+}
+
+// registerCommands overwrites the bot's slash commands with the current
+// command list, globally or per guild in CommandGuilds.
+func (d *Discord) registerCommands() {
+	if !d.cfg.SlashCommands || d.session == nil || d.session.State.User == nil {
+		return
+	}
+	d.commandsMu.Lock()
+	cmds := buildSlashCommands(d.commands)
+	d.commandsMu.Unlock()
+	if len(cmds) == 0 {
+		return
+	}
+
+	appID := d.session.State.User.ID
+	guilds := d.cfg.CommandGuilds
+	if len(guilds) == 0 {
+		guilds = []string{""}
+	}
+	for _, guildID := range guilds {
+		if _, err := d.session.ApplicationCommandBulkOverwrite(appID, guildID, cmds); err != nil {
+			d.logger.Warn("discord: failed to register slash commands", "guild", guildID, "error", err)
+			continue
+		}
+		d.logger.Info("discord: slash commands registered", "count", len(cmds), "guild", guildID)
+	}
+}
+
+// buildSlashCommands converts command specs to Discord application commands,
+// skipping names Discord would reject.
+func buildSlashCommands(specs []channels.CommandSpec) []*discordgo.ApplicationCommand {
+	var out []*discordgo.ApplicationCommand
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		name := strings.ToLower(strings.TrimPrefix(spec.Name, "/"))
+		if !slashNameRe.MatchString(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		desc := spec.Description
+		if desc == "" {
+			desc = "/" + name
+		}
+		if len(desc) > 100 {
+			desc = desc[:97] + "..."
+		}
+
+		cmd := &discordgo.ApplicationCommand{
+			Name:        name,
+			Description: desc,
+		}
+		if spec.TakesArgs {
+			cmd.Options = []*discordgo.ApplicationCommandOption{{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "args",
+				Description: "Command arguments",
+			}}
+		}
+		out = append(out, cmd)
+		if len(out) == maxSlashCommands {
+			break
+		}
+	}
+	return out
+}
+
+// onSlashCommand turns a slash command into an incoming "/name args"
+// message so it goes through the same command handler as typed commands.
+// The interaction is answered immediately with the command text; the
+// actual reply is sent to the channel as a regular message.
+func (d *Discord) onSlashCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !d.allowed(i.GuildID, i.ChannelID) {
+		respondEphemeral(s, i, "Commands are not enabled in this channel.")
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil || user.Bot {
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	content := "/" + data.Name
+	for _, opt := range data.Options {
+		if opt.Name == "args" && opt.Type == discordgo.ApplicationCommandOptionString {
+			if args := strings.TrimSpace(opt.StringValue()); args != "" {
+				content += " " + args
+			}
+		}
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: "`" + content + "`"},
+	}); err != nil {
+		d.logger.Warn("discord: failed to ack slash command", "command", data.Name, "error", err)
+	}
+
+	d.deliver(&channels.IncomingMessage{
+		ID:        i.ID,
+		Channel:   "discord",
+		From:      user.ID,
+		FromName:  user.Username,
+		ChatID:    i.ChannelID,
+		IsGroup:   i.GuildID != "",
+		Type:      channels.MessageText,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata:  map[string]any{"discord_slash_command": data.Name},
+	})
+}
@@ -6,11 +6,13 @@ package discord
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
 )
 
 // ComponentKind identifies the type of component.
@@ -225,3 +227,68 @@ func (r *ComponentRegistry) Copy() map[string]ComponentSpec {
 	}
 	return out
 }
+
+// quickReplyTTL is how long quick-reply buttons stay pressable.
+const quickReplyTTL = 24 * time.Hour
+
+// quickReplyRow registers channel-neutral buttons for a message with the
+// given content and returns their action row (at most 5 buttons). A press
+// is delivered as a message from the user with the button's data, and the
+// buttons are replaced by the choice made.
+func (d *Discord) quickReplyRow(content string, buttons []channels.Button) discordgo.MessageComponent {
+	nonce := time.Now().UnixNano()
+	row := discordgo.ActionsRow{}
+	for i, b := range buttons {
+		if i == 5 {
+			break
+		}
+		customID := fmt.Sprintf("devclaw:reply:%d:%d", nonce, i)
+		d.components.Register(customID, ComponentSpec{
+			Kind: ComponentKindButton,
+			TTL:  quickReplyTTL,
+			Handler: func(_ context.Context, evt *InteractionEvent) (string, error) {
+				if !d.allowed(evt.GuildID, evt.ChannelID) {
+					return content, nil
+				}
+				incoming := &channels.IncomingMessage{
+					ID:        evt.MessageID + ":" + customID,
+					Channel:   "discord",
+					From:      evt.UserID,
+					FromName:  evt.Username,
+					ChatID:    evt.ChannelID,
+					IsGroup:   evt.GuildID != "",
+					Type:      channels.MessageText,
+					Content:   b.Data,
+					Timestamp: time.Now(),
+					ReplyTo:   evt.MessageID,
+					Metadata:  map[string]any{"discord_button": b.Label},
+				}
+				incoming.SetMeta(channels.MetaReplyToBot, true)
+				d.deliver(incoming)
+				if choice := "\n\n→ **" + b.Label + "** (" + evt.Username + ")"; len(content)+len(choice) <= 2000 {
+					return content + choice, nil
+				}
+				return content, nil
+			},
+		})
+		row.Components = append(row.Components, discordgo.Button{
+			CustomID: customID,
+			Label:    b.Label,
+			Style:    discordButtonStyle(b.Style),
+		})
+	}
+	return row
+}
+
+// discordButtonStyle maps a channel-neutral button style.
+func discordButtonStyle(style string) discordgo.ButtonStyle {
+	switch style {
+	case channels.ButtonPrimary:
+		return discordgo.PrimaryButton
+	case channels.ButtonSuccess:
+		return discordgo.SuccessButton
+	case channels.ButtonDanger:
+		return discordgo.DangerButton
+	}
+	return discordgo.SecondaryButton
+}
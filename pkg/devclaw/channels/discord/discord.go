@@ -8,6 +8,7 @@
 //   - Embed messages for long responses
 //   - Guild and channel allowlists
 //   - Interactive components (buttons, select menus) with Reusable and AllowedUsers
+//   - Slash commands mapped to the chat command handler (/status, /model, ...)
 //   - Automatic reconnection via discordgo's gateway
 package discord
 
@@ -48,6 +49,16 @@ type Config struct {
 
 	// SendTyping sends "typing..." indicators while processing.
 	SendTyping bool `yaml:"send_typing"`
+
+	// SlashCommands registers the chat commands as Discord slash commands.
+	SlashCommands bool `yaml:"slash_commands"`
+
+	// CommandGuilds registers slash commands per guild instead of globally.
+	// Guild commands show up immediately; global ones can take up to an hour.
+	CommandGuilds []string `yaml:"command_guilds"`
+
+	// MaxMediaSizeMB caps attachment downloads (default: 25).
+	MaxMediaSizeMB int `yaml:"max_media_size_mb"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -55,11 +66,14 @@ func DefaultConfig() Config {
 	return Config{
 		RespondToThreads: true,
 		SendTyping:       true,
+		SlashCommands:    true,
+		MaxMediaSizeMB:   25,
 	}
 }
 
 // Discord implements channels.Channel, channels.MediaChannel,
-// channels.PresenceChannel, and channels.ReactionChannel.
+// channels.PresenceChannel, channels.ReactionChannel, and
+// channels.CommandChannel.
 type Discord struct {
 	cfg     Config
 	logger  *slog.Logger
@@ -83,6 +97,10 @@ type Discord struct {
 	// components manages interactive component registration and TTL cleanup.
 	components *ComponentRegistry
 
+	// commands are the chat commands to register as slash commands.
+	commands   []channels.CommandSpec
+	commandsMu sync.Mutex
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	mu     sync.RWMutex
@@ -142,6 +160,8 @@ func (d *Discord) Connect(ctx context.Context) error {
 	user := session.State.User
 	d.logger.Info("discord: connected", "bot", user.Username+"#"+user.Discriminator, "id", user.ID)
 
+	d.registerCommands()
+
 	return nil
 }
 
@@ -175,6 +195,9 @@ func (d *Discord) Send(ctx context.Context, to string, message *channels.Outgoin
 		if message.ReplyTo != "" {
 			msgSend.Reference = &discordgo.MessageReference{MessageID: message.ReplyTo}
 		}
+		if len(message.Buttons) > 0 {
+			msgSend.Components = []discordgo.MessageComponent{d.quickReplyRow(content, message.Buttons)}
+		}
 		_, err := d.session.ChannelMessageSendComplex(to, msgSend)
 		return err
 	}
@@ -186,6 +209,9 @@ func (d *Discord) Send(ctx context.Context, to string, message *channels.Outgoin
 		if i == 0 && message.ReplyTo != "" {
 			msgSend.Reference = &discordgo.MessageReference{MessageID: message.ReplyTo}
 		}
+		if i == len(chunks)-1 && len(message.Buttons) > 0 {
+			msgSend.Components = []discordgo.MessageComponent{d.quickReplyRow(chunk, message.Buttons)}
+		}
 		if _, err := d.session.ChannelMessageSendComplex(to, msgSend); err != nil {
 			return err
 		}
@@ -268,16 +294,35 @@ func (d *Discord) DownloadMedia(ctx context.Context, msg *channels.IncomingMessa
 		return nil, "", channels.ErrMediaDownloadFailed
 	}
 
-	resp, err := d.httpClient.Get(msg.Media.URL)
+	maxBytes := int64(d.cfg.MaxMediaSizeMB) << 20
+	if maxBytes <= 0 {
+		maxBytes = 25 << 20
+	}
+	if msg.Media.FileSize > uint64(maxBytes) {
+		return nil, "", fmt.Errorf("discord: attachment too large (%d bytes, max %d)", msg.Media.FileSize, maxBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, msg.Media.URL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("discord: download: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
 	if err != nil {
 		return nil, "", fmt.Errorf("discord: download: %w", err)
 	}
 	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("discord: download: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
 		return nil, "", fmt.Errorf("discord: reading attachment: %w", err)
 	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("discord: attachment exceeds %d bytes", maxBytes)
+	}
 
 	return data, msg.Media.MimeType, nil
 }
@@ -319,6 +364,42 @@ func (d *Discord) SendReaction(ctx context.Context, chatID, messageID, emoji str
 	return d.session.MessageReactionAdd(chatID, messageID, emoji)
 }
 
+// ---------- EditChannel Interface ----------
+
+// SendEditable sends a message and returns its ID for later edits.
+// Content beyond Discord's 2000-character limit is truncated.
+func (d *Discord) SendEditable(_ context.Context, to string, message *channels.OutgoingMessage) (string, error) {
+	if d.session == nil {
+		return "", channels.ErrChannelDisconnected
+	}
+	msgSend := &discordgo.MessageSend{Content: truncateDiscord(message.Content)}
+	if message.ReplyTo != "" {
+		msgSend.Reference = &discordgo.MessageReference{MessageID: message.ReplyTo}
+	}
+	sent, err := d.session.ChannelMessageSendComplex(to, msgSend)
+	if err != nil {
+		return "", err
+	}
+	return sent.ID, nil
+}
+
+// EditMessage edits a message previously sent by the bot.
+func (d *Discord) EditMessage(_ context.Context, chatID, messageID, content string) error {
+	if d.session == nil {
+		return channels.ErrChannelDisconnected
+	}
+	_, err := d.session.ChannelMessageEdit(chatID, messageID, truncateDiscord(content))
+	return err
+}
+
+// truncateDiscord caps content at Discord's per-message limit.
+func truncateDiscord(content string) string {
+	if len(content) <= 2000 {
+		return content
+	}
+	return strings.ToValidUTF8(content[:1997], "") + "..."
+}
+
 // ---------- Event Handlers ----------
 
 // onMessageCreate handles incoming Discord messages.
@@ -333,32 +414,8 @@ func (d *Discord) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		return
 	}
 
-	// Apply guild filter.
-	if len(d.cfg.AllowedGuilds) > 0 && m.GuildID != "" {
-		allowed := false
-		for _, id := range d.cfg.AllowedGuilds {
-			if id == m.GuildID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return
-		}
-	}
-
-	// Apply channel filter.
-	if len(d.cfg.AllowedChannels) > 0 {
-		allowed := false
-		for _, id := range d.cfg.AllowedChannels {
-			if id == m.ChannelID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return
-		}
+	if !d.allowed(m.GuildID, m.ChannelID) {
+		return
 	}
 
 	// Determine if it's a group (guild) or DM.
@@ -381,6 +438,15 @@ func (d *Discord) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	if m.ReferencedMessage != nil {
 		incoming.ReplyTo = m.ReferencedMessage.ID
 		incoming.QuotedContent = m.ReferencedMessage.Content
+		if ref := m.ReferencedMessage.Author; ref != nil && ref.ID == s.State.User.ID {
+			incoming.SetMeta(channels.MetaReplyToBot, true)
+		}
+	}
+	for _, u := range m.Mentions {
+		if u.ID == s.State.User.ID {
+			incoming.SetMeta(channels.MetaMentionsBot, true)
+			break
+		}
 	}
 
 	// Handle attachments.
@@ -399,6 +465,42 @@ func (d *Discord) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		}
 	}
 
+	d.deliver(incoming)
+}
+
+// allowed applies the guild and channel allowlists. DMs (empty guildID)
+// pass the guild filter.
+func (d *Discord) allowed(guildID, channelID string) bool {
+	if len(d.cfg.AllowedGuilds) > 0 && guildID != "" {
+		allowed := false
+		for _, id := range d.cfg.AllowedGuilds {
+			if id == guildID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if len(d.cfg.AllowedChannels) > 0 {
+		allowed := false
+		for _, id := range d.cfg.AllowedChannels {
+			if id == channelID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver forwards an incoming message to the assistant.
+func (d *Discord) deliver(incoming *channels.IncomingMessage) {
 	d.lastMsg.Store(time.Now())
 	d.errorCount.Store(0)
 
@@ -409,8 +511,13 @@ func (d *Discord) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	}
 }
 
-// onInteractionCreate handles button clicks and select menu choices.
+// onInteractionCreate handles slash commands, button clicks and select
+// menu choices.
 func (d *Discord) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionApplicationCommand {
+		d.onSlashCommand(s, i)
+		return
+	}
 	if i.Type != discordgo.InteractionMessageComponent {
 		return
 	}
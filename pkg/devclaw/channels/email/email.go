@@ -0,0 +1,620 @@
+// Package email implements the email channel for DevClaw: it polls an IMAP
+// inbox and answers over SMTP — no external dependencies beyond the
+// standard library.
+//
+// Features:
+//   - Each thread (first Message-ID of References) is one chat, so a
+//     conversation by email keeps its session across replies
+//   - The sender's address is the user ID, so access control, workspaces
+//     and owner lists work as on any other channel
+//   - Quoted text of replies is stripped; text attachments are inlined and
+//     the first other attachment (image, PDF, audio) is processed as media
+//   - Replies are threaded (In-Reply-To/References); the chunks of one
+//     answer are merged into a single email
+//   - Auto-replies, bounces and mailing-list traffic are ignored
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// Config holds email channel configuration.
+type Config struct {
+	// Address is the assistant's mailbox (From of replies).
+	Address string `yaml:"address"`
+
+	// Name is the display name used in From (default: none).
+	Name string `yaml:"name"`
+
+	// Username and Password authenticate to IMAP and SMTP. Username
+	// defaults to Address. Use an app password where the provider offers one.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	// IMAPHost is the IMAP server with port (e.g. "imap.gmail.com:993").
+	// The channel starts when it is set.
+	IMAPHost string `yaml:"imap_host"`
+
+	// IMAPPlaintext connects without TLS (local bridges only).
+	IMAPPlaintext bool `yaml:"imap_plaintext"`
+
+	// Mailbox is the folder to poll (default: INBOX).
+	Mailbox string `yaml:"mailbox"`
+
+	// SMTPHost is the SMTP server with port: 465 uses implicit TLS, other
+	// ports STARTTLS (e.g. "smtp.gmail.com:587").
+	SMTPHost string `yaml:"smtp_host"`
+
+	// PollInterval is how often the inbox is checked (default: 1m).
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// AllowedSenders restricts which addresses (or "@domain") are read at
+	// all. Empty means every sender reaches access control.
+	AllowedSenders []string `yaml:"allowed_senders"`
+
+	// MaxAttachmentMB skips larger attachments (default: 10).
+	MaxAttachmentMB int `yaml:"max_attachment_mb"`
+
+	// ReplyDelay merges the messages of one answer sent within this window
+	// into a single email (default: 3s).
+	ReplyDelay time.Duration `yaml:"reply_delay"`
+}
+
+// DefaultConfig returns a Config with sensible defaults.
+func DefaultConfig() Config {
+	return Config{
+		Mailbox:         "INBOX",
+		PollInterval:    time.Minute,
+		MaxAttachmentMB: 10,
+		ReplyDelay:      3 * time.Second,
+	}
+}
+
+// imapTimeout bounds one poll (connect, search and fetch).
+const imapTimeout = 2 * time.Minute
+
+// maxThreads bounds the thread and attachment caches.
+const maxThreads = 500
+
+// thread is what the channel remembers to answer within a thread.
+type thread struct {
+	to         string
+	subject    string
+	lastID     string
+	references []string
+	updatedAt  time.Time
+}
+
+// outbox buffers the messages of one answer until ReplyDelay passes.
+type outbox struct {
+	parts       []string
+	attachments []attachment
+	timer       *time.Timer
+}
+
+// Email implements channels.Channel and channels.MediaChannel.
+type Email struct {
+	cfg    Config
+	logger *slog.Logger
+
+	messages chan *channels.IncomingMessage
+
+	connected  atomic.Bool
+	lastMsg    atomic.Value // time.Time
+	errorCount atomic.Int64
+
+	since time.Time
+
+	mu          sync.Mutex
+	ignored     map[uint32]bool        // UIDs unread before startup
+	threads     map[string]*thread     // thread ID -> reply state
+	attachments map[string]*attachment // message ID -> media attachment
+	outboxes    map[string]*outbox     // thread ID -> pending answer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a new email channel instance.
+func New(cfg Config, logger *slog.Logger) *Email {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	defaults := DefaultConfig()
+	if cfg.Username == "" {
+		cfg.Username = cfg.Address
+	}
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = defaults.Mailbox
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaults.PollInterval
+	}
+	if cfg.MaxAttachmentMB <= 0 {
+		cfg.MaxAttachmentMB = defaults.MaxAttachmentMB
+	}
+	if cfg.ReplyDelay <= 0 {
+		cfg.ReplyDelay = defaults.ReplyDelay
+	}
+	return &Email{
+		cfg:         cfg,
+		logger:      logger.With("component", "email"),
+		messages:    make(chan *channels.IncomingMessage, 256),
+		threads:     make(map[string]*thread),
+		attachments: make(map[string]*attachment),
+		outboxes:    make(map[string]*outbox),
+		ignored:     make(map[uint32]bool),
+	}
+}
+
+// ---------- Channel Interface ----------
+
+// Name returns "email".
+func (e *Email) Name() string { return "email" }
+
+// Connect checks the IMAP login and starts polling. Mail already unread at
+// startup is left alone, so an old backlog is not answered.
+func (e *Email) Connect(ctx context.Context) error {
+	if e.cfg.IMAPHost == "" || e.cfg.SMTPHost == "" {
+		return fmt.Errorf("email: imap_host and smtp_host are required")
+	}
+	if e.cfg.Address == "" || e.cfg.Password == "" {
+		return fmt.Errorf("email: address and password are required")
+	}
+	if e.connected.Load() {
+		return nil
+	}
+
+	c, err := e.openInbox()
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	e.since = time.Now()
+	backlog, err := c.searchUnseen(e.since)
+	c.close()
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	e.mu.Lock()
+	for _, uid := range backlog {
+		e.ignored[uid] = true
+	}
+	e.mu.Unlock()
+
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	e.connected.Store(true)
+	e.logger.Info("email: connected", "address", e.cfg.Address, "imap", e.cfg.IMAPHost)
+
+	go e.pollLoop()
+	return nil
+}
+
+// Disconnect stops polling and sends pending answers.
+func (e *Email) Disconnect() error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.mu.Lock()
+	pending := make([]string, 0, len(e.outboxes))
+	for id, box := range e.outboxes {
+		box.timer.Stop()
+		pending = append(pending, id)
+	}
+	e.mu.Unlock()
+	for _, id := range pending {
+		e.flush(id)
+	}
+	e.connected.Store(false)
+	e.logger.Info("email: disconnected")
+	return nil
+}
+
+// Send queues text for the thread (or address) to. Messages sent within
+// ReplyDelay of each other go out as one email.
+func (e *Email) Send(_ context.Context, to string, message *channels.OutgoingMessage) error {
+	if !e.connected.Load() {
+		return channels.ErrChannelDisconnected
+	}
+	e.queue(to, message.Content, nil)
+	return nil
+}
+
+// Receive returns the incoming messages channel.
+func (e *Email) Receive() <-chan *channels.IncomingMessage {
+	return e.messages
+}
+
+// IsConnected returns true while the inbox is polled.
+func (e *Email) IsConnected() bool { return e.connected.Load() }
+
+// Health returns the channel health status.
+func (e *Email) Health() channels.HealthStatus {
+	var lastAt time.Time
+	if v := e.lastMsg.Load(); v != nil {
+		lastAt = v.(time.Time)
+	}
+	return channels.HealthStatus{
+		Connected:     e.connected.Load(),
+		LastMessageAt: lastAt,
+		ErrorCount:    int(e.errorCount.Load()),
+		Details:       map[string]any{"address": e.cfg.Address, "mailbox": e.cfg.Mailbox},
+	}
+}
+
+// ---------- MediaChannel Interface ----------
+
+// SendMedia attaches a file to the thread's next email.
+func (e *Email) SendMedia(_ context.Context, to string, media *channels.MediaMessage) error {
+	if !e.connected.Load() {
+		return channels.ErrChannelDisconnected
+	}
+	if len(media.Data) == 0 {
+		if media.URL == "" {
+			return fmt.Errorf("email: media data or URL is required")
+		}
+		e.queue(to, strings.TrimSpace(media.Caption+"\n"+media.URL), nil)
+		return nil
+	}
+	name := media.Filename
+	if name == "" {
+		name = "attachment"
+	}
+	e.queue(to, media.Caption, &attachment{Filename: name, MimeType: media.MimeType, Data: media.Data})
+	return nil
+}
+
+// DownloadMedia returns the attachment kept for an incoming message.
+func (e *Email) DownloadMedia(_ context.Context, msg *channels.IncomingMessage) ([]byte, string, error) {
+	e.mu.Lock()
+	a := e.attachments[msg.ID]
+	e.mu.Unlock()
+	if a == nil || len(a.Data) == 0 {
+		return nil, "", channels.ErrMediaDownloadFailed
+	}
+	return a.Data, a.MimeType, nil
+}
+
+// ---------- Inbound ----------
+
+// openInbox logs in and selects the mailbox.
+func (e *Email) openInbox() (*imapClient, error) {
+	c, err := dialIMAP(e.cfg.IMAPHost, e.cfg.IMAPPlaintext, imapTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("imap connect: %w", err)
+	}
+	if err := c.login(e.cfg.Username, e.cfg.Password); err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("imap login: %w", err)
+	}
+	if err := c.selectMailbox(e.cfg.Mailbox); err != nil {
+		c.close()
+		return nil, fmt.Errorf("imap select %s: %w", e.cfg.Mailbox, err)
+	}
+	return c, nil
+}
+
+func (e *Email) pollLoop() {
+	ticker := time.NewTicker(e.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := e.poll(); err != nil {
+			e.errorCount.Add(1)
+			e.logger.Warn("email: poll failed", "error", err)
+		} else {
+			e.errorCount.Store(0)
+		}
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads unseen messages, marks them seen and forwards them.
+func (e *Email) poll() error {
+	c, err := e.openInbox()
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	uids, err := c.searchUnseen(e.since)
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		e.mu.Lock()
+		skip := e.ignored[uid]
+		e.mu.Unlock()
+		if skip {
+			continue
+		}
+		raw, err := c.fetch(uid)
+		if err != nil {
+			return err
+		}
+		if err := c.markSeen(uid); err != nil {
+			return err
+		}
+		m, err := parseMail(raw, int64(e.cfg.MaxAttachmentMB)<<20)
+		if err != nil {
+			e.logger.Warn("email: unparseable message", "uid", uid, "error", err)
+			continue
+		}
+		e.handle(m)
+	}
+	return nil
+}
+
+// handle filters a parsed message and forwards it to the assistant.
+func (e *Email) handle(m *parsedMail) {
+	if m.From == "" || strings.EqualFold(m.From, e.cfg.Address) {
+		return
+	}
+	if m.Automated {
+		e.logger.Debug("email: ignoring automated message", "from", m.From, "subject", m.Subject)
+		return
+	}
+	if !e.senderAllowed(m.From) {
+		e.logger.Debug("email: sender not allowed", "from", m.From)
+		return
+	}
+
+	threadID := m.threadID()
+	refs := append(append([]string{}, m.References...), m.MessageID)
+	e.mu.Lock()
+	e.threads[threadID] = &thread{
+		to:         m.From,
+		subject:    m.Subject,
+		lastID:     m.MessageID,
+		references: refs,
+		updatedAt:  time.Now(),
+	}
+	e.mu.Unlock()
+
+	content := m.Body
+	if m.InReplyTo == "" && m.Subject != "" {
+		content = fmt.Sprintf("Subject: %s\n\n%s", m.Subject, content)
+	}
+
+	msg := &channels.IncomingMessage{
+		ID:        m.MessageID,
+		Channel:   "email",
+		From:      m.From,
+		FromName:  m.FromName,
+		ChatID:    threadID,
+		Type:      channels.MessageText,
+		Timestamp: m.Date,
+	}
+	msg.SetMeta("subject", m.Subject)
+
+	var skipped []string
+	for i := range m.Attachments {
+		a := &m.Attachments[i]
+		switch {
+		case len(a.Data) == 0:
+			skipped = append(skipped, a.Filename+" (too large)")
+		case isTextAttachment(a):
+			content += fmt.Sprintf("\n\n[Attachment: %s]\n%s", a.Filename, strings.TrimSpace(string(a.Data)))
+		case msg.Media == nil:
+			msg.Type = mediaType(a.MimeType)
+			msg.Media = &channels.MediaInfo{
+				Type:     msg.Type,
+				MimeType: a.MimeType,
+				Filename: a.Filename,
+				FileSize: uint64(len(a.Data)),
+			}
+			e.mu.Lock()
+			e.attachments[msg.ID] = a
+			e.mu.Unlock()
+		default:
+			skipped = append(skipped, a.Filename)
+		}
+	}
+	if len(skipped) > 0 {
+		content += "\n\n[Attachments not processed: " + strings.Join(skipped, ", ") + "]"
+	}
+	msg.Content = strings.TrimSpace(content)
+	e.prune()
+
+	e.lastMsg.Store(time.Now())
+	select {
+	case e.messages <- msg:
+	default:
+		e.logger.Warn("email: message buffer full, dropping message", "from", m.From)
+	}
+}
+
+// senderAllowed applies AllowedSenders (exact addresses or "@domain").
+func (e *Email) senderAllowed(from string) bool {
+	if len(e.cfg.AllowedSenders) == 0 {
+		return true
+	}
+	for _, s := range e.cfg.AllowedSenders {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == from || (strings.HasPrefix(s, "@") && strings.HasSuffix(from, s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTextAttachment reports whether an attachment is readable as plain text.
+func isTextAttachment(a *attachment) bool {
+	if strings.HasPrefix(a.MimeType, "text/") {
+		return true
+	}
+	switch a.MimeType {
+	case "application/json", "application/xml", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return false
+}
+
+// mediaType maps a MIME type to the message type the assistant processes.
+func mediaType(mimeType string) channels.MessageType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return channels.MessageImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return channels.MessageAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return channels.MessageVideo
+	default:
+		return channels.MessageDocument
+	}
+}
+
+// prune bounds the thread and attachment caches.
+func (e *Email) prune() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.attachments) > maxThreads {
+		e.attachments = make(map[string]*attachment)
+	}
+	if len(e.threads) <= maxThreads {
+		return
+	}
+	var oldestID string
+	var oldest time.Time
+	for id, t := range e.threads {
+		if oldestID == "" || t.updatedAt.Before(oldest) {
+			oldestID, oldest = id, t.updatedAt
+		}
+	}
+	delete(e.threads, oldestID)
+}
+
+// ---------- Outbound ----------
+
+// queue adds text (and an attachment) to the pending answer for to and
+// (re)arms its flush timer.
+func (e *Email) queue(to, text string, att *attachment) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	box := e.outboxes[to]
+	if box == nil {
+		box = &outbox{}
+		e.outboxes[to] = box
+		box.timer = time.AfterFunc(e.cfg.ReplyDelay, func() { e.flush(to) })
+	} else {
+		box.timer.Reset(e.cfg.ReplyDelay)
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		box.parts = append(box.parts, text)
+	}
+	if att != nil {
+		box.attachments = append(box.attachments, *att)
+	}
+}
+
+// flush sends the pending answer for to: a threaded reply when to is a
+// known thread, else a new email when to is an address.
+func (e *Email) flush(to string) {
+	e.mu.Lock()
+	box := e.outboxes[to]
+	delete(e.outboxes, to)
+	t := e.threads[to]
+	var out outgoingMail
+	if t != nil {
+		out = outgoingMail{
+			To:         t.to,
+			Subject:    replySubject(t.subject),
+			InReplyTo:  t.lastID,
+			References: append([]string{}, t.references...),
+		}
+	}
+	e.mu.Unlock()
+	if box == nil || (len(box.parts) == 0 && len(box.attachments) == 0) {
+		return
+	}
+
+	if t == nil {
+		addr, err := mail.ParseAddress(to)
+		if err != nil || strings.HasPrefix(to, "<") {
+			e.logger.Warn("email: unknown thread, reply dropped", "to", to)
+			return
+		}
+		out = outgoingMail{To: addr.Address, Subject: e.cfg.Name}
+		if out.Subject == "" {
+			out.Subject = "DevClaw"
+		}
+	}
+	out.From = e.cfg.Address
+	out.FromName = e.cfg.Name
+	out.MessageID = newMessageID(e.cfg.Address)
+	out.Body = strings.Join(box.parts, "\n\n")
+	out.Attachments = box.attachments
+
+	if err := e.sendMail(&out); err != nil {
+		e.errorCount.Add(1)
+		e.logger.Error("email: send failed", "to", out.To, "error", err)
+		return
+	}
+
+	// Later replies in the thread follow this one.
+	if t != nil {
+		e.mu.Lock()
+		t.lastID = out.MessageID
+		t.references = append(t.references, out.MessageID)
+		t.updatedAt = time.Now()
+		e.mu.Unlock()
+	}
+}
+
+// sendMail delivers a message over SMTP.
+func (e *Email) sendMail(out *outgoingMail) error {
+	host, port, err := net.SplitHostPort(e.cfg.SMTPHost)
+	if err != nil {
+		return fmt.Errorf("invalid smtp_host %q: %w", e.cfg.SMTPHost, err)
+	}
+	auth := smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)
+	data := out.compose()
+	if port != "465" {
+		return smtp.SendMail(e.cfg.SMTPHost, auth, e.cfg.Address, []string{out.To}, data)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", e.cfg.SMTPHost, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if err := c.Auth(auth); err != nil {
+		return err
+	}
+	if err := c.Mail(e.cfg.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(out.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,223 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// fakeSMTP accepts mail on a local port and hands each message's data to
+// the returned channel.
+func fakeSMTP(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	mails := make(chan string, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mails)
+		}
+	}()
+	return ln.Addr().String(), mails
+}
+
+func serveSMTP(conn net.Conn, mails chan<- string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			mails <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func newTestEmail(t *testing.T, smtpAddr string, allowed ...string) *Email {
+	t.Helper()
+	e := New(Config{
+		Address:        "bot@example.com",
+		Name:           "DevClaw",
+		Password:       "secret",
+		SMTPHost:       smtpAddr,
+		AllowedSenders: allowed,
+		ReplyDelay:     20 * time.Millisecond,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.connected.Store(true)
+	return e
+}
+
+func receive(t *testing.T, e *Email) *channels.IncomingMessage {
+	t.Helper()
+	select {
+	case msg := <-e.messages:
+		return msg
+	default:
+		return nil
+	}
+}
+
+func waitMail(t *testing.T, mails <-chan string) *parsedMail {
+	t.Helper()
+	select {
+	case raw := <-mails:
+		m, err := parseMail([]byte(raw), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email was sent")
+		return nil
+	}
+}
+
+func TestEmailHandle(t *testing.T) {
+	t.Parallel()
+	e := newTestEmail(t, "", "ana@example.com", "@corp.example")
+
+	for _, m := range []*parsedMail{
+		{From: "bot@example.com", MessageID: "<own@x>", Body: "our own mail"},
+		{From: "ana@example.com", MessageID: "<auto@x>", Body: "out of office", Automated: true},
+		{From: "eve@elsewhere.example", MessageID: "<eve@x>", Body: "let me in"},
+	} {
+		e.handle(m)
+		if msg := receive(t, e); msg != nil {
+			t.Errorf("%s was forwarded: %+v", m.MessageID, msg)
+		}
+	}
+
+	e.handle(&parsedMail{
+		From: "rui@corp.example", FromName: "Rui", MessageID: "<m1@corp>", Subject: "Numbers",
+		Body: "see attached",
+		Attachments: []attachment{
+			{Filename: "data.csv", MimeType: "text/csv", Data: []byte("a,b\n1,2\n")},
+			{Filename: "chart.png", MimeType: "image/png", Data: []byte("png")},
+			{Filename: "photo.jpg", MimeType: "image/jpeg", Data: []byte("jpg")},
+			{Filename: "huge.zip", MimeType: "application/zip"},
+		},
+	})
+	msg := receive(t, e)
+	if msg == nil {
+		t.Fatal("message from an allowed domain was dropped")
+	}
+	if msg.ChatID != "<m1@corp>" || msg.From != "rui@corp.example" || msg.Type != channels.MessageImage {
+		t.Errorf("msg = %+v", msg)
+	}
+	want := "Subject: Numbers\n\nsee attached\n\n[Attachment: data.csv]\na,b\n1,2\n\n[Attachments not processed: photo.jpg, huge.zip (too large)]"
+	if msg.Content != want {
+		t.Errorf("content = %q", msg.Content)
+	}
+	data, mimeType, err := e.DownloadMedia(context.Background(), msg)
+	if err != nil || string(data) != "png" || mimeType != "image/png" {
+		t.Errorf("DownloadMedia = %q, %q, %v", data, mimeType, err)
+	}
+
+	// Replies stay in the thread's chat and don't repeat the subject.
+	e.handle(&parsedMail{From: "rui@corp.example", MessageID: "<m2@corp>", InReplyTo: "<m1@corp>",
+		References: []string{"<m1@corp>"}, Subject: "Re: Numbers", Body: "thanks"})
+	if msg := receive(t, e); msg == nil || msg.ChatID != "<m1@corp>" || msg.Content != "thanks" {
+		t.Errorf("reply = %+v", msg)
+	}
+}
+
+func TestEmailSend_ThreadsReplies(t *testing.T) {
+	t.Parallel()
+	addr, mails := fakeSMTP(t)
+	e := newTestEmail(t, addr)
+	ctx := context.Background()
+
+	e.handle(&parsedMail{From: "ana@example.com", MessageID: "<m1@x>", Subject: "Deploy", Body: "status?"})
+	thread := receive(t, e).ChatID
+
+	// Chunks of one answer are merged into a single email.
+	for _, part := range []string{"Deploy is green.", "Rollout finished at 10:02."} {
+		if err := e.Send(ctx, thread, &channels.OutgoingMessage{Content: part}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := waitMail(t, mails)
+	if first.Subject != "Re: Deploy" || first.From != "bot@example.com" || first.InReplyTo != "<m1@x>" {
+		t.Errorf("first reply headers = %q %q %q", first.Subject, first.From, first.InReplyTo)
+	}
+	if first.Body != "Deploy is green.\n\nRollout finished at 10:02." {
+		t.Errorf("first reply body = %q", first.Body)
+	}
+
+	// The next reply follows the previous one.
+	if err := e.SendMedia(ctx, thread, &channels.MediaMessage{Data: []byte("log line"), Filename: "deploy.log", MimeType: "text/plain", Caption: "Full log"}); err != nil {
+		t.Fatal(err)
+	}
+	second := waitMail(t, mails)
+	if second.InReplyTo != first.MessageID || strings.Join(second.References, " ") != "<m1@x> "+first.MessageID {
+		t.Errorf("second reply threading = %q %v", second.InReplyTo, second.References)
+	}
+	if second.Body != "Full log" || len(second.Attachments) != 1 || string(second.Attachments[0].Data) != "log line" {
+		t.Errorf("second reply = %q %+v", second.Body, second.Attachments)
+	}
+
+	// A plain address starts a new email; an unknown thread is dropped.
+	if err := e.Send(ctx, "<gone@x>", &channels.OutgoingMessage{Content: "lost"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Send(ctx, "rui@example.com", &channels.OutgoingMessage{Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	fresh := waitMail(t, mails)
+	if fresh.Subject != "DevClaw" || fresh.InReplyTo != "" || fresh.Body != "hello" {
+		t.Errorf("new email = %q %q %q", fresh.Subject, fresh.InReplyTo, fresh.Body)
+	}
+	select {
+	case raw := <-mails:
+		t.Errorf("unexpected email:\n%s", raw)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	e.connected.Store(false)
+	if err := e.Send(ctx, thread, &channels.OutgoingMessage{Content: "late"}); err != channels.ErrChannelDisconnected {
+		t.Errorf("Send while disconnected = %v", err)
+	}
+}
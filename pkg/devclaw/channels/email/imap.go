@@ -0,0 +1,186 @@
+package email
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient is the small subset of IMAP4rev1 (RFC 3501) the channel needs:
+// LOGIN, SELECT, UID SEARCH, UID FETCH, UID STORE and LOGOUT.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line, with any literals it carried.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// dialIMAP connects to addr with implicit TLS, or in plaintext when
+// plaintext is set (local bridges such as Proton Mail Bridge).
+func dialIMAP(addr string, plaintext bool, timeout time.Duration) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if plaintext {
+		conn, err = dialer.Dial("tcp", addr)
+	} else {
+		host, _, _ := net.SplitHostPort(addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: host})
+	}
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting: %s", strings.TrimSpace(greeting))
+	}
+	return c, nil
+}
+
+// cmd sends a command and reads responses up to its tagged completion.
+func (c *imapClient) cmd(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := "a" + strconv.Itoa(c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	var out []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return out, err
+		}
+		if strings.HasPrefix(resp.line, tag+" ") {
+			status := strings.TrimPrefix(resp.line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return out, fmt.Errorf("imap: %s", status)
+			}
+			return out, nil
+		}
+		out = append(out, resp)
+	}
+}
+
+// readResponse reads one response line, following {n} literals.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var line strings.Builder
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		n, ok := literalSize(part)
+		if !ok {
+			line.WriteString(part)
+			resp.line = line.String()
+			return resp, nil
+		}
+		line.WriteString(part)
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, buf)
+	}
+}
+
+// literalSize parses a trailing "{n}" literal marker.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// imapQuote quotes a string argument.
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func (c *imapClient) login(user, password string) error {
+	_, err := c.cmd("LOGIN %s %s", imapQuote(user), imapQuote(password))
+	return err
+}
+
+func (c *imapClient) selectMailbox(name string) error {
+	_, err := c.cmd("SELECT %s", imapQuote(name))
+	return err
+}
+
+// searchUnseen returns the UIDs of unseen messages received since the given
+// day (IMAP dates have day granularity).
+func (c *imapClient) searchUnseen(since time.Time) ([]uint32, error) {
+	resps, err := c.cmd("UID SEARCH UNSEEN SINCE %s", since.Format("2-Jan-2006"))
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, r := range resps {
+		if !strings.HasPrefix(r.line, "* SEARCH") {
+			continue
+		}
+		for _, f := range strings.Fields(strings.TrimPrefix(r.line, "* SEARCH")) {
+			if n, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw RFC 5322 message with the given UID, without
+// marking it as seen.
+func (c *imapClient) fetch(uid uint32) ([]byte, error) {
+	resps, err := c.cmd("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resps {
+		if strings.Contains(r.line, "FETCH") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not returned", uid)
+}
+
+func (c *imapClient) markSeen(uid uint32) error {
+	_, err := c.cmd(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapClient) close() {
+	_, _ = c.cmd("LOGOUT")
+	c.conn.Close()
+}
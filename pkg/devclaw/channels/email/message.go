@@ -0,0 +1,349 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// parsedMail is the part of an inbound email the channel uses.
+type parsedMail struct {
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	From        string
+	FromName    string
+	Subject     string
+	Date        time.Time
+	Body        string
+	Attachments []attachment
+	Automated   bool
+}
+
+// attachment is a decoded MIME attachment.
+type attachment struct {
+	Filename string
+	MimeType string
+	Data     []byte
+}
+
+// threadID returns the Message-ID of the first message of the thread, so
+// every reply maps to the same session.
+func (m *parsedMail) threadID() string {
+	if len(m.References) > 0 {
+		return m.References[0]
+	}
+	if m.InReplyTo != "" {
+		return m.InReplyTo
+	}
+	return m.MessageID
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "iso-8859-1", "latin1", "windows-1252":
+			data, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			return strings.NewReader(latin1ToUTF8(data)), nil
+		}
+		return input, nil // best effort for other charsets
+	},
+}
+
+// parseMail parses a raw RFC 5322 message, skipping attachments larger
+// than maxAttachment bytes.
+func parseMail(raw []byte, maxAttachment int64) (*parsedMail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	h := msg.Header
+	m := &parsedMail{
+		MessageID:  strings.TrimSpace(h.Get("Message-Id")),
+		InReplyTo:  firstMessageID(h.Get("In-Reply-To")),
+		References: messageIDs(h.Get("References")),
+	}
+	if subject, err := wordDecoder.DecodeHeader(h.Get("Subject")); err == nil {
+		m.Subject = strings.TrimSpace(subject)
+	}
+	if from, err := (&mail.AddressParser{WordDecoder: wordDecoder}).Parse(h.Get("From")); err == nil {
+		m.From = strings.ToLower(from.Address)
+		m.FromName = from.Name
+	}
+	if d, err := h.Date(); err == nil {
+		m.Date = d
+	} else {
+		m.Date = time.Now()
+	}
+	if m.MessageID == "" {
+		m.MessageID = newMessageID(m.From)
+	}
+	// Auto-replies, bounces and list traffic must never get an answer, or
+	// two assistants can mail each other forever.
+	auto := strings.ToLower(h.Get("Auto-Submitted"))
+	prec := strings.ToLower(h.Get("Precedence"))
+	m.Automated = (auto != "" && auto != "no") || prec == "bulk" || prec == "list" || prec == "junk" ||
+		h.Get("List-Id") != "" || strings.HasPrefix(m.From, "mailer-daemon@")
+
+	var plain, htmlBody string
+	walkPart(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), h.Get("Content-Disposition"), msg.Body,
+		func(mediaType, filename string, data []byte) {
+			switch {
+			case filename == "" && mediaType == "text/plain" && plain == "":
+				plain = string(data)
+			case filename == "" && mediaType == "text/html" && htmlBody == "":
+				htmlBody = string(data)
+			case filename != "" || !strings.HasPrefix(mediaType, "text/"):
+				if maxAttachment > 0 && int64(len(data)) > maxAttachment {
+					m.Attachments = append(m.Attachments, attachment{Filename: filename, MimeType: mediaType})
+					return
+				}
+				m.Attachments = append(m.Attachments, attachment{Filename: filename, MimeType: mediaType, Data: data})
+			}
+		})
+	if plain == "" && htmlBody != "" {
+		plain = htmlToText(htmlBody)
+	}
+	m.Body = stripQuotedReply(plain)
+	return m, nil
+}
+
+// walkPart decodes a MIME part, recursing into multiparts, and calls fn for
+// every leaf with its media type, filename and decoded content.
+func walkPart(contentType, encoding, disposition string, body io.Reader, fn func(mediaType, filename string, data []byte)) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			walkPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"), part, fn)
+		}
+	}
+
+	var r io.Reader = body
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	case "quoted-printable":
+		r = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil && len(data) == 0 {
+		return
+	}
+
+	filename := params["name"]
+	if _, dparams, err := mime.ParseMediaType(disposition); err == nil && dparams["filename"] != "" {
+		filename = dparams["filename"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		if cs := strings.ToLower(params["charset"]); cs == "iso-8859-1" || cs == "latin1" || cs == "windows-1252" {
+			data = []byte(latin1ToUTF8(data))
+		}
+	}
+	fn(mediaType, filename, data)
+}
+
+// newlineStripper drops CR/LF so base64 bodies wrapped at 76 columns decode.
+type newlineStripper struct{ r io.Reader }
+
+func (s *newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// latin1ToUTF8 converts ISO-8859-1 bytes to UTF-8.
+func latin1ToUTF8(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+var (
+	htmlBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>|</h[1-6]>`)
+	htmlDropRe  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlTagRe   = regexp.MustCompile(`<[^>]+>`)
+	blankRunRe  = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText renders an HTML body as plain text.
+func htmlToText(s string) string {
+	s = htmlDropRe.ReplaceAllString(s, "")
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = htmlTagRe.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	return blankRunRe.ReplaceAllString(strings.TrimSpace(s), "\n\n")
+}
+
+// quoteHeaderRe matches the line mail clients put above quoted text
+// ("On Mon, Jan 2, 2026 at 10:00 Ana <ana@x.com> wrote:").
+var quoteHeaderRe = regexp.MustCompile(`(?i)^(on|em|el|le|am) .{4,}(wrote|escreveu|escribió|a écrit|schrieb)\s*:\s*$`)
+
+// stripQuotedReply keeps only the new part of a reply: everything above the
+// quoted previous message.
+func stripQuotedReply(body string) string {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	lines := strings.Split(body, "\n")
+	var out []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if quoteHeaderRe.MatchString(trimmed) ||
+			strings.HasPrefix(trimmed, "-----Original Message-----") ||
+			strings.HasPrefix(trimmed, "________________________________") {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+var messageIDRe = regexp.MustCompile(`<[^<>\s]+>`)
+
+func messageIDs(header string) []string {
+	return messageIDRe.FindAllString(header, -1)
+}
+
+func firstMessageID(header string) string {
+	if ids := messageIDs(header); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.TrimSpace(header)
+}
+
+// newMessageID returns a fresh Message-ID in the sender's domain.
+func newMessageID(from string) string {
+	domain := "devclaw.local"
+	if at := strings.LastIndexByte(from, '@'); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+// replySubject prefixes "Re: " unless the subject already has it.
+func replySubject(subject string) string {
+	if subject == "" {
+		return "Re: (no subject)"
+	}
+	if len(subject) >= 3 && strings.EqualFold(subject[:3], "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// outgoingMail is a message to send over SMTP.
+type outgoingMail struct {
+	From        string
+	FromName    string
+	To          string
+	Subject     string
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	Body        string
+	Attachments []attachment
+}
+
+// compose renders the message as RFC 5322 bytes.
+func (o *outgoingMail) compose() []byte {
+	var b bytes.Buffer
+	from := (&mail.Address{Name: o.FromName, Address: o.From}).String()
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", o.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", o.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", o.MessageID)
+	if o.InReplyTo != "" {
+		fmt.Fprintf(&b, "In-Reply-To: %s\r\n", o.InReplyTo)
+	}
+	if len(o.References) > 0 {
+		fmt.Fprintf(&b, "References: %s\r\n", strings.Join(o.References, " "))
+	}
+	b.WriteString("Auto-Submitted: auto-replied\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(o.Attachments) == 0 {
+		writeTextPart(&b, o.Body)
+		return b.Bytes()
+	}
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	if w, err := mw.CreatePart(map[string][]string{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	}); err == nil {
+		writeQP(w, o.Body)
+	}
+	for _, a := range o.Attachments {
+		mimeType := a.MimeType
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		w, err := mw.CreatePart(map[string][]string{
+			"Content-Type":              {mime.FormatMediaType(mimeType, map[string]string{"name": a.Filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			continue
+		}
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			io.WriteString(w, enc[:76]+"\r\n")
+			enc = enc[76:]
+		}
+		io.WriteString(w, enc+"\r\n")
+	}
+	mw.Close()
+	return b.Bytes()
+}
+
+func writeTextPart(b *bytes.Buffer, body string) {
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	writeQP(b, body)
+}
+
+func writeQP(w io.Writer, body string) {
+	qp := quotedprintable.NewWriter(w)
+	io.WriteString(qp, strings.ReplaceAll(body, "\n", "\r\n"))
+	qp.Close()
+}
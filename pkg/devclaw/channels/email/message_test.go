@@ -0,0 +1,229 @@
+package email
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// rawMail joins header and body lines with CRLF.
+func rawMail(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\r\n"))
+}
+
+func TestParseMail_MIME(t *testing.T) {
+	t.Parallel()
+	raw := rawMail(
+		"From: =?ISO-8859-1?Q?Jo=E3o_Silva?= <Joao@Example.com>",
+		"To: bot@example.com",
+		"Subject: =?UTF-8?B?UmVsYXTDs3JpbyBtZW5zYWw=?=",
+		"Message-ID: <m1@example.com>",
+		"Date: Mon, 02 Mar 2026 10:00:00 +0000",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		`Content-Type: multipart/alternative; boundary="inner"`,
+		"",
+		"--inner",
+		"Content-Type: text/plain; charset=iso-8859-1",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Ol=E1, segue o relat=F3rio.",
+		"",
+		"Em seg., 2 de mar. de 2026 Bot <bot@example.com> escreveu:",
+		"> old text",
+		"--inner",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>ignored when text/plain exists</p>",
+		"--inner--",
+		"--outer",
+		`Content-Type: application/pdf; name="report.pdf"`,
+		`Content-Disposition: attachment; filename="report.pdf"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		"JVBERi0x",
+		"LjQK",
+		"--outer",
+		`Content-Type: text/csv; name="data.csv"`,
+		`Content-Disposition: attachment; filename="data.csv"`,
+		"",
+		"a,b",
+		"1,2",
+		"--outer",
+		`Content-Type: image/png; name="big.png"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		"iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk",
+		"--outer--",
+	)
+
+	m, err := parseMail(raw, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.From != "joao@example.com" || m.FromName != "João Silva" {
+		t.Errorf("from = %q <%s>", m.FromName, m.From)
+	}
+	if m.Subject != "Relatório mensal" || m.MessageID != "<m1@example.com>" || m.Date.Day() != 2 {
+		t.Errorf("headers = %q %q %v", m.Subject, m.MessageID, m.Date)
+	}
+	if m.Body != "Olá, segue o relatório." {
+		t.Errorf("body = %q", m.Body)
+	}
+	if m.Automated {
+		t.Error("a personal message was flagged as automated")
+	}
+
+	if len(m.Attachments) != 3 {
+		t.Fatalf("attachments = %+v", m.Attachments)
+	}
+	pdf, csv, png := m.Attachments[0], m.Attachments[1], m.Attachments[2]
+	if pdf.Filename != "report.pdf" || pdf.MimeType != "application/pdf" || string(pdf.Data) != "%PDF-1.4\n" {
+		t.Errorf("pdf = %s %s %q", pdf.Filename, pdf.MimeType, pdf.Data)
+	}
+	if csv.Filename != "data.csv" || strings.TrimSpace(string(csv.Data)) != "a,b\r\n1,2" {
+		t.Errorf("csv = %s %q", csv.Filename, csv.Data)
+	}
+	// Attachments over the limit are listed without data.
+	if png.Filename != "big.png" || png.Data != nil {
+		t.Errorf("oversized attachment kept its data: %+v", png)
+	}
+}
+
+func TestParseMail_HTMLOnlyAndAutomated(t *testing.T) {
+	t.Parallel()
+	m, err := parseMail(rawMail(
+		"From: ana@example.com",
+		"Subject: hi",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<html><head><style>p{}</style></head><body><p>First &amp; second</p><br>Third</body></html>",
+	), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Body != "First & second\n\nThird" {
+		t.Errorf("html body = %q", m.Body)
+	}
+	if !strings.HasPrefix(m.MessageID, "<") || !strings.HasSuffix(m.MessageID, "@example.com>") {
+		t.Errorf("missing Message-ID not generated in the sender's domain: %q", m.MessageID)
+	}
+
+	for _, tc := range []struct {
+		headers   []string
+		automated bool
+	}{
+		{[]string{"From: ana@example.com", "Auto-Submitted: auto-replied"}, true},
+		{[]string{"From: ana@example.com", "Precedence: bulk"}, true},
+		{[]string{"From: ana@example.com", "List-Id: <dev.lists.example.com>"}, true},
+		{[]string{"From: MAILER-DAEMON@example.com"}, true},
+		{[]string{"From: ana@example.com", "Auto-Submitted: no"}, false},
+	} {
+		m, err := parseMail(rawMail(append(tc.headers, "", "out of office")...), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Automated != tc.automated {
+			t.Errorf("%v: automated = %v", tc.headers, m.Automated)
+		}
+	}
+}
+
+func TestThreadID(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"new thread", "Message-ID: <m3@x>", "<m3@x>"},
+		{"reply", "Message-ID: <m3@x>\r\nIn-Reply-To: <m2@x>", "<m2@x>"},
+		{"deep reply", "Message-ID: <m3@x>\r\nIn-Reply-To: <m2@x>\r\nReferences: <m1@x>\r\n <m2@x>", "<m1@x>"},
+	}
+	for _, tc := range cases {
+		m, err := parseMail(rawMail("From: ana@example.com", tc.header, "", "hi"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.threadID(); got != tc.want {
+			t.Errorf("%s: threadID = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"Sounds good.\n\nOn Mon, Mar 2, 2026 at 10:00 Bot <bot@x.com> wrote:\n> earlier": "Sounds good.",
+		"Yes\r\n-----Original Message-----\r\nFrom: bot":                                 "Yes",
+		"> quoted\nmy answer\n> more quoted":                                             "my answer",
+		"plain text, no quote":                                                           "plain text, no quote",
+	}
+	for in, want := range cases {
+		if got := stripQuotedReply(in); got != want {
+			t.Errorf("stripQuotedReply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestReplySubject(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]string{
+		"":              "Re: (no subject)",
+		"Report":        "Re: Report",
+		"RE: Report":    "RE: Report",
+		"re: lowercase": "re: lowercase",
+	} {
+		if got := replySubject(in); got != want {
+			t.Errorf("replySubject(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestOutgoingMail_ComposeRoundTrip(t *testing.T) {
+	t.Parallel()
+	body := "Olá!\nA line that is long enough to be wrapped by the quoted-printable encoder because it goes past seventy-six columns."
+	out := &outgoingMail{
+		From:       "bot@example.com",
+		FromName:   "DevClaw",
+		To:         "ana@example.com",
+		Subject:    "Re: Relatório",
+		MessageID:  "<r1@example.com>",
+		InReplyTo:  "<m2@x>",
+		References: []string{"<m1@x>", "<m2@x>"},
+		Body:       body,
+		Attachments: []attachment{
+			{Filename: "chart.png", MimeType: "image/png", Data: bytes.Repeat([]byte{0x89, 'P', 'N', 'G'}, 40)},
+		},
+	}
+	raw := out.compose()
+
+	m, err := parseMail(raw, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Subject != "Re: Relatório" || m.From != "bot@example.com" || m.FromName != "DevClaw" {
+		t.Errorf("headers = %q %q %q", m.Subject, m.From, m.FromName)
+	}
+	if m.InReplyTo != "<m2@x>" || strings.Join(m.References, " ") != "<m1@x> <m2@x>" || m.threadID() != "<m1@x>" {
+		t.Errorf("threading = %q %v", m.InReplyTo, m.References)
+	}
+	// Our own replies are marked so other assistants don't answer them.
+	if !m.Automated {
+		t.Error("composed reply lacks Auto-Submitted")
+	}
+	if m.Body != body {
+		t.Errorf("body = %q", m.Body)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Filename != "chart.png" || !bytes.Equal(m.Attachments[0].Data, out.Attachments[0].Data) {
+		t.Errorf("attachments = %+v", m.Attachments)
+	}
+
+	// Without attachments the body is a single text part.
+	out.Attachments = nil
+	if raw := string(out.compose()); strings.Contains(raw, "multipart") || !strings.Contains(raw, "Content-Type: text/plain; charset=utf-8") {
+		t.Errorf("plain reply:\n%s", raw)
+	}
+}
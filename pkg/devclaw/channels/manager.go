@@ -5,11 +5,21 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 )
 
+// Coordinator elects which instance owns a channel when several instances
+// share the same channel sessions (see package coordination).
+type Coordinator interface {
+	// Campaign competes for key until ctx is cancelled, calling onElected
+	// when this instance becomes the owner and onDemoted when it stops
+	// being the owner.
+	Campaign(ctx context.Context, key string, onElected func(ctx context.Context), onDemoted func())
+}
+
 // Manager orchestrates multiple communication channels, aggregating
 // incoming messages into a single stream and routing responses.
 type Manager struct {
@@ -18,6 +28,11 @@ type Manager struct {
 	logger   *slog.Logger
 	listenWg sync.WaitGroup
 
+	// coordinator, when set, gates each channel behind leader election.
+	// owned tracks the channels this instance currently leads.
+	coordinator Coordinator
+	owned       map[string]bool
+
 	mu     sync.RWMutex
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -33,9 +48,26 @@ func NewManager(logger *slog.Logger) *Manager {
 		channels: make(map[string]Channel),
 		messages: make(chan *IncomingMessage, 256),
 		logger:   logger,
+		owned:    make(map[string]bool),
 	}
 }
 
+// SetCoordinator enables leader election: each channel is connected only
+// while this instance owns it. Must be called before Start.
+func (m *Manager) SetCoordinator(c Coordinator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coordinator = c
+}
+
+// Owns reports whether this instance should serve the channel. Without a
+// coordinator every instance owns every channel.
+func (m *Manager) Owns(channelName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.coordinator == nil || m.owned[channelName]
+}
+
 // Register adds a channel. Must be called before Start.
 func (m *Manager) Register(ch Channel) error {
 	m.mu.Lock()
@@ -63,6 +95,7 @@ func (m *Manager) Start(ctx context.Context) error {
 	for k, v := range m.channels {
 		snapshot[k] = v
 	}
+	coordinator := m.coordinator
 	m.mu.RUnlock()
 
 	if len(snapshot) == 0 {
@@ -70,6 +103,22 @@ func (m *Manager) Start(ctx context.Context) error {
 		return nil
 	}
 
+	if coordinator != nil {
+		for name, ch := range snapshot {
+			m.listenWg.Add(1)
+			go func(c Channel) {
+				defer m.listenWg.Done()
+				m.listenChannel(c)
+			}(ch)
+			go coordinator.Campaign(m.ctx, "channel/"+name,
+				func(ctx context.Context) { m.takeOwnership(ctx, name, ch) },
+				func() { m.dropOwnership(name, ch) },
+			)
+		}
+		m.logger.Info("manager started, channels awaiting leader election", "channels", len(snapshot))
+		return nil
+	}
+
 	var connected int
 	for name, ch := range snapshot {
 		if err := ch.Connect(m.ctx); err != nil {
@@ -103,8 +152,8 @@ func (m *Manager) Stop() {
 		m.cancel()
 	}
 
-	// Disconnect channels first — this closes their Receive() channels,
-	// which unblocks listenChannel goroutines.
+	// Disconnect channels first. listenChannel goroutines exit on the
+	// cancelled context (some channels also close their Receive() channels).
 	m.mu.RLock()
 	for name, ch := range m.channels {
 		if err := ch.Disconnect(); err != nil {
@@ -121,6 +170,34 @@ func (m *Manager) Stop() {
 	m.logger.Info("manager stopped")
 }
 
+// takeOwnership connects a channel after this instance won its election.
+func (m *Manager) takeOwnership(ctx context.Context, name string, ch Channel) {
+	m.mu.Lock()
+	m.owned[name] = true
+	m.mu.Unlock()
+
+	if err := ch.Connect(ctx); err != nil {
+		m.logger.Error("failed to connect channel after election",
+			"channel", name, "error", err)
+		return
+	}
+	m.logger.Info("channel connected (this instance is owner)", "channel", name)
+}
+
+// dropOwnership disconnects a channel that another instance now owns, so
+// both instances never reply to the same conversation.
+func (m *Manager) dropOwnership(name string, ch Channel) {
+	m.mu.Lock()
+	m.owned[name] = false
+	m.mu.Unlock()
+
+	if err := ch.Disconnect(); err != nil {
+		m.logger.Error("error disconnecting channel after losing ownership",
+			"channel", name, "error", err)
+	}
+	m.logger.Warn("channel released to another instance", "channel", name)
+}
+
 // Messages returns the aggregated message stream from all channels.
 func (m *Manager) Messages() <-chan *IncomingMessage {
 	return m.messages
@@ -140,7 +217,67 @@ func (m *Manager) Send(ctx context.Context, channelName, to string, msg *Outgoin
 		return fmt.Errorf("channel %q disconnected", channelName)
 	}
 
-	return ch.Send(ctx, to, msg)
+	if len(msg.Attachments) == 0 {
+		return ch.Send(ctx, to, msg)
+	}
+
+	// Text first, then each attachment as its own media message.
+	text := *msg
+	text.Attachments = nil
+	mc, hasMedia := ch.(MediaChannel)
+	if !hasMedia {
+		for _, att := range msg.Attachments {
+			text.Content = joinNonEmpty(text.Content, attachmentFallback(att))
+		}
+	}
+	if text.Content != "" {
+		if err := ch.Send(ctx, to, &text); err != nil {
+			return err
+		}
+	}
+	if !hasMedia {
+		return nil
+	}
+
+	var errs []error
+	for _, att := range msg.Attachments {
+		media := *att
+		if media.ReplyTo == "" {
+			media.ReplyTo = msg.ReplyTo
+		}
+		if err := mc.SendMedia(ctx, to, &media); err != nil {
+			errs = append(errs, fmt.Errorf("send attachment %q: %w", att.Filename, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// attachmentFallback describes an attachment as text for channels that
+// cannot send media.
+func attachmentFallback(att *MediaMessage) string {
+	name := att.Filename
+	if name == "" {
+		name = string(att.Type)
+	}
+	line := "📎 " + name
+	if att.URL != "" {
+		line += ": " + att.URL
+	}
+	if att.Caption != "" {
+		line += " — " + att.Caption
+	}
+	return line
+}
+
+// joinNonEmpty joins a and b with a newline, skipping empty parts.
+func joinNonEmpty(a, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return a + "\n" + b
 }
 
 // SendMedia sends a media message through the specified channel.
@@ -210,6 +347,77 @@ func (m *Manager) SendReaction(ctx context.Context, channelName, chatID, message
 	}
 }
 
+// SetCommands passes the chat command list to every channel that supports
+// native commands. Errors are logged per channel, not returned.
+func (m *Manager) SetCommands(ctx context.Context, commands []CommandSpec) {
+	m.mu.RLock()
+	var targets []CommandChannel
+	for _, ch := range m.channels {
+		if cc, ok := ch.(CommandChannel); ok {
+			targets = append(targets, cc)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, cc := range targets {
+		if err := cc.SetCommands(ctx, commands); err != nil {
+			m.logger.Warn("failed to register native commands",
+				"channel", cc.Name(), "error", err)
+		}
+	}
+}
+
+// SupportsEdit reports whether the named channel can edit sent messages.
+func (m *Manager) SupportsEdit(channelName string) bool {
+	m.mu.RLock()
+	ch, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	_, ok := ch.(EditChannel)
+	return exists && ok
+}
+
+// SendEditable sends a message that can later be updated with EditMessage.
+// Returns the platform message ID, or ErrEditNotSupported.
+func (m *Manager) SendEditable(ctx context.Context, channelName, to string, msg *OutgoingMessage) (string, error) {
+	m.mu.RLock()
+	ch, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("channel %q not found", channelName)
+	}
+
+	ec, ok := ch.(EditChannel)
+	if !ok {
+		return "", ErrEditNotSupported
+	}
+	if !ch.IsConnected() {
+		return "", fmt.Errorf("channel %q disconnected", channelName)
+	}
+	return ec.SendEditable(ctx, to, msg)
+}
+
+// EditMessage replaces the content of a message sent with SendEditable.
+func (m *Manager) EditMessage(ctx context.Context, channelName, chatID, messageID, content string) error {
+	m.mu.RLock()
+	ch, exists := m.channels[channelName]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("channel %q not found", channelName)
+	}
+
+	ec, ok := ch.(EditChannel)
+	if !ok {
+		return ErrEditNotSupported
+	}
+	if !ch.IsConnected() {
+		return fmt.Errorf("channel %q disconnected", channelName)
+	}
+	return ec.EditMessage(ctx, chatID, messageID, content)
+}
+
 // Channel returns a specific channel by name.
 func (m *Manager) Channel(name string) (Channel, bool) {
 	m.mu.RLock()
@@ -247,6 +455,13 @@ func (m *Manager) listenChannel(ch Channel) {
 			if !ok {
 				return // Channel closed.
 			}
+			if !m.Owns(ch.Name()) {
+				// Late event from a channel we no longer own; the owner
+				// instance handles it.
+				m.logger.Debug("dropping message from channel owned by another instance",
+					"channel", ch.Name())
+				continue
+			}
 			select {
 			case m.messages <- msg:
 			case <-m.ctx.Done():
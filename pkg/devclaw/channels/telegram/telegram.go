@@ -2,13 +2,13 @@
 // Telegram Bot API directly via HTTP — no external dependencies.
 //
 // Features:
-//   - Long polling for updates (getUpdates)
+//   - Long polling for updates (getUpdates) or webhook mode (setWebhook)
 //   - Send/receive text, images, audio, video, documents, voice notes
 //   - Typing indicators (sendChatAction)
 //   - Reactions (setMessageReaction, Bot API 7.0+)
 //   - Media download via getFile
 //   - HTML formatting for rich messages
-//   - Group and DM support
+//   - Group and DM support, replies threaded into forum topics
 package telegram
 
 import (
@@ -55,8 +55,43 @@ type Config struct {
 	// "own": only reactions to bot messages
 	// "all": all reactions in allowed chats
 	ReactionNotifications string `yaml:"reaction_notifications"`
+
+	// Mode selects how updates are received: "polling" (default, getUpdates)
+	// or "webhook" (Telegram POSTs updates to WebhookURL).
+	Mode string `yaml:"mode"`
+
+	// WebhookURL is the public HTTPS URL Telegram delivers updates to in
+	// webhook mode (e.g. https://bot.example.com/telegram/webhook). Its path
+	// is served on WebhookListen.
+	WebhookURL string `yaml:"webhook_url"`
+
+	// WebhookListen is the local address of the webhook server (default ":8443").
+	WebhookListen string `yaml:"webhook_listen"`
+
+	// WebhookSecret is checked against the X-Telegram-Bot-Api-Secret-Token
+	// header of every update. A random secret is generated when empty.
+	WebhookSecret string `yaml:"webhook_secret"`
+
+	// APIURL overrides the Bot API endpoint, e.g. for a self-hosted
+	// telegram-bot-api server (default: https://api.telegram.org).
+	APIURL string `yaml:"api_url"`
+
+	// MaxMediaSizeMB caps the size of downloaded media (default: 20, the
+	// limit of the cloud Bot API).
+	MaxMediaSizeMB int `yaml:"max_media_size_mb"`
 }
 
+const (
+	// ModePolling receives updates with getUpdates long polling.
+	ModePolling = "polling"
+	// ModeWebhook receives updates on an HTTP endpoint registered with setWebhook.
+	ModeWebhook = "webhook"
+
+	defaultAPIURL         = "https://api.telegram.org"
+	defaultWebhookListen  = ":8443"
+	defaultMaxMediaSizeMB = 20
+)
+
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() Config {
 	return Config{
@@ -65,6 +100,9 @@ func DefaultConfig() Config {
 		SendTyping:           true,
 		ParseMode:            "HTML",
 		ReactionNotifications: "off",
+		Mode:                  ModePolling,
+		WebhookListen:         defaultWebhookListen,
+		MaxMediaSizeMB:        defaultMaxMediaSizeMB,
 	}
 }
 
@@ -97,6 +135,9 @@ type Telegram struct {
 	// baseURL is the Telegram Bot API base URL (https://api.telegram.org/bot<token>).
 	baseURL string
 
+	// fileURL is the base URL for media downloads (https://api.telegram.org/file/bot<token>).
+	fileURL string
+
 	// messages is the channel for incoming messages forwarded to the assistant.
 	messages chan *channels.IncomingMessage
 
@@ -109,14 +150,29 @@ type Telegram struct {
 	// errorCount tracks consecutive errors.
 	errorCount atomic.Int64
 
-	// offset is the last processed update ID + 1.
-	offset int64
+	// offset is the last processed update ID + 1. In webhook mode it is
+	// used to drop redelivered updates.
+	offset      int64
+	seenUpdates map[int64]struct{}
+	offsetMu    sync.Mutex
+
+	// topics maps "chatID:messageID" of incoming forum-topic messages to
+	// their message_thread_id, so replies to them land in the same topic.
+	topics   map[string]int64
+	topicsMu sync.RWMutex
+
+	// webhook is the HTTP server receiving updates in webhook mode.
+	webhook *http.Server
 
 	// sentMessageIDs tracks (chatID, messageID) of messages sent by the bot,
 	// used for ReactionNotifications "own" scope.
 	sentMessageIDs map[string]bool
 	sentMu         sync.RWMutex
 
+	// me is the bot account (from getMe), used to detect mentions and
+	// replies addressed to the bot.
+	me *tgBotUser
+
 	ctx    context.Context
 	cancel context.CancelFunc
 	mu     sync.RWMutex
@@ -130,13 +186,29 @@ func New(cfg Config, logger *slog.Logger) *Telegram {
 	if cfg.ParseMode == "" {
 		cfg.ParseMode = "HTML"
 	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModePolling
+	}
+	if cfg.WebhookListen == "" {
+		cfg.WebhookListen = defaultWebhookListen
+	}
+	if cfg.MaxMediaSizeMB <= 0 {
+		cfg.MaxMediaSizeMB = defaultMaxMediaSizeMB
+	}
+	apiURL := strings.TrimRight(cfg.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
 	return &Telegram{
 		cfg:             cfg,
 		logger:          logger.With("component", "telegram"),
 		client:          &http.Client{Timeout: 60 * time.Second},
-		baseURL:         "https://api.telegram.org/bot" + cfg.Token,
+		baseURL:         apiURL + "/bot" + cfg.Token,
+		fileURL:         apiURL + "/file/bot" + cfg.Token,
 		messages:        make(chan *channels.IncomingMessage, 256),
 		sentMessageIDs:  make(map[string]bool),
+		topics:          make(map[string]int64),
+		seenUpdates:     make(map[int64]struct{}),
 	}
 }
 
@@ -145,11 +217,15 @@ func New(cfg Config, logger *slog.Logger) *Telegram {
 // Name returns "telegram".
 func (t *Telegram) Name() string { return "telegram" }
 
-// Connect starts the long-polling loop for receiving updates.
+// Connect verifies the token and starts receiving updates, either with the
+// long-polling loop or by registering the webhook.
 func (t *Telegram) Connect(ctx context.Context) error {
 	if t.cfg.Token == "" {
 		return fmt.Errorf("telegram: bot token is required")
 	}
+	if t.cfg.Mode != ModePolling && t.cfg.Mode != ModeWebhook {
+		return fmt.Errorf("telegram: unknown mode %q (use polling or webhook)", t.cfg.Mode)
+	}
 
 	// Prevent double-connect goroutine leak.
 	if t.connected.Load() {
@@ -163,7 +239,25 @@ func (t *Telegram) Connect(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("telegram: failed to verify token: %w", err)
 	}
-	t.logger.Info("telegram: connected", "bot", me.Username, "id", me.ID)
+	t.logger.Info("telegram: connected", "bot", me.Username, "id", me.ID, "mode", t.cfg.Mode)
+	t.mu.Lock()
+	t.me = me
+	t.mu.Unlock()
+
+	if t.cfg.Mode == ModeWebhook {
+		if err := t.startWebhook(); err != nil {
+			t.cancel()
+			return err
+		}
+		t.connected.Store(true)
+		return nil
+	}
+
+	// getUpdates fails while a webhook is registered (e.g. after switching
+	// modes), so clear it first. Pending updates are kept.
+	if _, err := t.apiCall("deleteWebhook", map[string]any{"drop_pending_updates": false}); err != nil {
+		t.logger.Warn("telegram: deleteWebhook failed", "error", err)
+	}
 	t.connected.Store(true)
 
 	// Start polling loop.
@@ -172,11 +266,13 @@ func (t *Telegram) Connect(ctx context.Context) error {
 	return nil
 }
 
-// Disconnect stops the polling loop.
+// Disconnect stops the polling loop or the webhook server. The webhook
+// registration is kept so updates queue up until the next Connect.
 func (t *Telegram) Disconnect() error {
 	if t.cancel != nil {
 		t.cancel()
 	}
+	t.stopWebhook()
 	t.connected.Store(false)
 	t.logger.Info("telegram: disconnected")
 	return nil
@@ -184,12 +280,18 @@ func (t *Telegram) Disconnect() error {
 
 // Send sends a text message to the specified chat.
 func (t *Telegram) Send(ctx context.Context, to string, message *channels.OutgoingMessage) error {
+	_, err := t.sendText(to, message)
+	return err
+}
+
+// sendText sends a text message and returns the raw API result.
+func (t *Telegram) sendText(to string, message *channels.OutgoingMessage) (json.RawMessage, error) {
 	if !t.connected.Load() {
-		return channels.ErrChannelDisconnected
+		return nil, channels.ErrChannelDisconnected
 	}
 	chatID, err := strconv.ParseInt(to, 10, 64)
 	if err != nil {
-		return fmt.Errorf("telegram: invalid chat ID %q: %w", to, err)
+		return nil, fmt.Errorf("telegram: invalid chat ID %q: %w", to, err)
 	}
 
 	payload := map[string]any{
@@ -199,9 +301,15 @@ func (t *Telegram) Send(ctx context.Context, to string, message *channels.Outgoi
 	}
 	if message.ReplyTo != "" {
 		if msgID, e := strconv.ParseInt(message.ReplyTo, 10, 64); e == nil {
-			payload["reply_parameters"] = map[string]any{"message_id": msgID}
+			payload["reply_parameters"] = map[string]any{
+				"message_id":                  msgID,
+				"allow_sending_without_reply": true,
+			}
 		}
 	}
+	if thread := t.threadFor(chatID, message.ReplyTo, message.Metadata); thread != 0 {
+		payload["message_thread_id"] = thread
+	}
 
 	// Add inline keyboard if buttons are provided via Metadata.
 	if replyMarkup := t.buildReplyMarkup(message); replyMarkup != nil {
@@ -210,14 +318,14 @@ func (t *Telegram) Send(ctx context.Context, to string, message *channels.Outgoi
 
 	result, err := t.apiCall("sendMessage", payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Record sent message ID for reaction notifications "own" scope.
 	if t.cfg.ReactionNotifications == "own" && result != nil {
 		t.recordSentMessage(chatID, result)
 	}
-	return nil
+	return result, nil
 }
 
 // Receive returns the incoming messages channel.
@@ -238,6 +346,7 @@ func (t *Telegram) Health() channels.HealthStatus {
 		Connected:     t.connected.Load(),
 		LastMessageAt: lastAt,
 		ErrorCount:    int(t.errorCount.Load()),
+		Details:       map[string]any{"mode": t.cfg.Mode},
 	}
 }
 
@@ -283,6 +392,9 @@ func (t *Telegram) SendMedia(ctx context.Context, to string, media *channels.Med
 			payload["caption"] = media.Caption
 			payload["parse_mode"] = t.cfg.ParseMode
 		}
+		if thread := t.threadFor(chatID, media.ReplyTo, nil); thread != 0 {
+			payload["message_thread_id"] = thread
+		}
 		_, err = t.apiCall(method, payload)
 		return err
 	}
@@ -303,8 +415,13 @@ func (t *Telegram) DownloadMedia(ctx context.Context, msg *channels.IncomingMess
 		return nil, "", fmt.Errorf("telegram: getFile failed: %w", err)
 	}
 
+	maxBytes := int64(t.cfg.MaxMediaSizeMB) << 20
+	if fileInfo.FileSize > 0 && int64(fileInfo.FileSize) > maxBytes {
+		return nil, "", fmt.Errorf("telegram: media too large (%d bytes, max %d MB)", fileInfo.FileSize, t.cfg.MaxMediaSizeMB)
+	}
+
 	// Download from https://api.telegram.org/file/bot<token>/<file_path>
-	downloadURL := fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", t.cfg.Token, fileInfo.FilePath)
+	downloadURL := t.fileURL + "/" + fileInfo.FilePath
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
 	if err != nil {
 		return nil, "", fmt.Errorf("telegram: creating download request: %w", err)
@@ -314,11 +431,17 @@ func (t *Telegram) DownloadMedia(ctx context.Context, msg *channels.IncomingMess
 		return nil, "", fmt.Errorf("telegram: download failed: %w", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("telegram: download failed: %s", resp.Status)
+	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
 		return nil, "", fmt.Errorf("telegram: reading media: %w", err)
 	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("telegram: media too large (max %d MB)", t.cfg.MaxMediaSizeMB)
+	}
 
 	return data, msg.Media.MimeType, nil
 }
@@ -372,11 +495,56 @@ func (t *Telegram) SendReaction(ctx context.Context, chatID, messageID, emoji st
 	return err
 }
 
+// ---------- EditChannel Interface ----------
+
+// SendEditable sends a text message and returns its message ID for later edits.
+func (t *Telegram) SendEditable(_ context.Context, to string, message *channels.OutgoingMessage) (string, error) {
+	result, err := t.sendText(to, message)
+	if err != nil {
+		return "", err
+	}
+	var sent struct {
+		MessageID int `json:"message_id"`
+	}
+	if err := json.Unmarshal(result, &sent); err != nil {
+		return "", fmt.Errorf("telegram: parsing sendMessage result: %w", err)
+	}
+	return strconv.Itoa(sent.MessageID), nil
+}
+
+// EditMessage edits the text of a message previously sent by the bot.
+func (t *Telegram) EditMessage(_ context.Context, chatID, messageID, content string) error {
+	if !t.connected.Load() {
+		return channels.ErrChannelDisconnected
+	}
+	cid, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("telegram: invalid chat ID %q: %w", chatID, err)
+	}
+	mid, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("telegram: invalid message ID %q: %w", messageID, err)
+	}
+	_, err = t.apiCall("editMessageText", map[string]any{
+		"chat_id":    cid,
+		"message_id": mid,
+		"text":       content,
+		"parse_mode": t.cfg.ParseMode,
+	})
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+	return err
+}
+
 // ---------- Internal Methods ----------
 
 // buildReplyMarkup builds an InlineKeyboardMarkup from OutgoingMessage.Metadata["telegram_buttons"].
 // Each button can have text, callback_data or url, and optional style (primary/success/danger).
 func (t *Telegram) buildReplyMarkup(msg *channels.OutgoingMessage) map[string]any {
+	if msg != nil && len(msg.Buttons) > 0 {
+		return t.quickReplyMarkup(msg.Buttons)
+	}
 	if msg == nil || msg.Metadata == nil {
 		return nil
 	}
@@ -470,6 +638,62 @@ func (t *Telegram) buildReplyMarkup(msg *channels.OutgoingMessage) map[string]an
 	return map[string]any{"inline_keyboard": rows}
 }
 
+// quickReplyMarkup builds a one-row inline keyboard from channel-neutral
+// buttons. Presses come back as callback queries (see processCallbackQuery).
+func (t *Telegram) quickReplyMarkup(buttons []channels.Button) map[string]any {
+	row := make([]map[string]any, 0, len(buttons))
+	for _, b := range buttons {
+		data := b.Data
+		if len(data) > 64 {
+			t.logger.Warn("telegram: button data over 64 bytes, skipping button", "label", b.Label)
+			continue
+		}
+		btn := map[string]any{
+			"text":          t.applyButtonStyle(InlineButton{Text: b.Label, Style: b.Style}),
+			"callback_data": data,
+		}
+		if b.Style == ButtonStylePrimary || b.Style == ButtonStyleSuccess || b.Style == ButtonStyleDanger {
+			btn["style"] = b.Style
+		}
+		row = append(row, btn)
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return map[string]any{"inline_keyboard": [][]map[string]any{row}}
+}
+
+// processCallbackQuery answers an inline keyboard press, removes the
+// keyboard so it cannot be pressed twice, and delivers the button's data
+// as a message from the user, replying to the bot's message.
+func (t *Telegram) processCallbackQuery(q *tgCallbackQuery) {
+	if _, err := t.apiCall("answerCallbackQuery", map[string]any{"callback_query_id": q.ID}); err != nil {
+		t.logger.Warn("telegram: answerCallbackQuery failed", "error", err)
+	}
+	if q.Message == nil || q.Data == "" {
+		return
+	}
+	if _, err := t.apiCall("editMessageReplyMarkup", map[string]any{
+		"chat_id":      q.Message.Chat.ID,
+		"message_id":   q.Message.MessageID,
+		"reply_markup": map[string]any{"inline_keyboard": [][]map[string]any{}},
+	}); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		t.logger.Warn("telegram: removing inline keyboard failed", "error", err)
+	}
+
+	from := q.From
+	t.processUpdate(tgUpdate{Message: &tgMessage{
+		MessageID:       q.Message.MessageID,
+		From:            &from,
+		Chat:            q.Message.Chat,
+		Date:            int(time.Now().Unix()),
+		Text:            q.Data,
+		ReplyToMessage:  q.Message,
+		MessageThreadID: q.Message.MessageThreadID,
+		IsTopicMessage:  q.Message.IsTopicMessage,
+	}})
+}
+
 // applyButtonStyle prefixes button text with emoji for older clients that don't support native style.
 // Returns the display text. Native style is sent separately in buildReplyMarkup.
 func (t *Telegram) applyButtonStyle(b InlineButton) string {
@@ -623,7 +847,11 @@ func (t *Telegram) pollLoop() {
 		default:
 		}
 
-		updates, err := t.getUpdates(t.offset, 100, 30)
+		t.offsetMu.Lock()
+		offset := t.offset
+		t.offsetMu.Unlock()
+
+		updates, err := t.getUpdates(offset, 100, 30)
 		if err != nil {
 			t.errorCount.Add(1)
 			t.logger.Warn("telegram: getUpdates error", "error", err, "backoff", backoff)
@@ -642,10 +870,9 @@ func (t *Telegram) pollLoop() {
 		t.errorCount.Store(0)
 
 		for _, u := range updates {
-			if u.UpdateID >= t.offset {
-				t.offset = u.UpdateID + 1
+			if t.advanceOffset(u.UpdateID) {
+				t.processUpdate(u)
 			}
-			t.processUpdate(u)
 		}
 	}
 }
@@ -657,6 +884,10 @@ func (t *Telegram) processUpdate(u tgUpdate) {
 		t.processMessageReaction(u.MessageReaction)
 		return
 	}
+	if u.CallbackQuery != nil {
+		t.processCallbackQuery(u.CallbackQuery)
+		return
+	}
 
 	msg := u.Message
 	if msg == nil {
@@ -716,17 +947,35 @@ func (t *Telegram) processUpdate(u tgUpdate) {
 		Timestamp: time.Unix(int64(msg.Date), 0),
 	}
 
+	// Forum topics: remember the message's topic so replies to it go there.
+	if msg.IsTopicMessage && msg.MessageThreadID != 0 {
+		incoming.Metadata = map[string]any{"telegram_thread_id": msg.MessageThreadID}
+		t.recordTopic(msg.Chat.ID, msg.MessageID, msg.MessageThreadID)
+	}
+
 	// Handle caption (media messages have caption instead of text).
 	if msg.Caption != "" && incoming.Content == "" {
 		incoming.Content = msg.Caption
 	}
 
 	// Handle reply.
+	t.mu.RLock()
+	me := t.me
+	t.mu.RUnlock()
 	if msg.ReplyToMessage != nil {
 		incoming.ReplyTo = strconv.FormatInt(int64(msg.ReplyToMessage.MessageID), 10)
 		if msg.ReplyToMessage.Text != "" {
 			incoming.QuotedContent = msg.ReplyToMessage.Text
 		}
+		if from := msg.ReplyToMessage.From; me != nil && from != nil && from.ID == me.ID {
+			incoming.SetMeta(channels.MetaReplyToBot, true)
+		}
+	}
+	if me != nil && me.Username != "" {
+		text := strings.ToLower(msg.Text + " " + msg.Caption)
+		if strings.Contains(text, "@"+strings.ToLower(me.Username)) {
+			incoming.SetMeta(channels.MetaMentionsBot, true)
+		}
 	}
 
 	// Handle media.
@@ -803,6 +1052,15 @@ type tgUpdate struct {
 	Message         *tgMessage           `json:"message"`
 	EditedMessage   *tgMessage           `json:"edited_message"`
 	MessageReaction *tgMessageReaction   `json:"message_reaction"`
+	CallbackQuery   *tgCallbackQuery     `json:"callback_query"`
+}
+
+// tgCallbackQuery is an inline keyboard button press.
+type tgCallbackQuery struct {
+	ID      string     `json:"id"`
+	From    tgUser     `json:"from"`
+	Message *tgMessage `json:"message"`
+	Data    string     `json:"data"`
 }
 
 // tgMessageReaction is the MessageReactionUpdated object from the Bot API.
@@ -831,6 +1089,8 @@ type tgMessage struct {
 	Text           string     `json:"text"`
 	Caption        string     `json:"caption"`
 	ReplyToMessage *tgMessage `json:"reply_to_message"`
+	MessageThreadID int64     `json:"message_thread_id"`
+	IsTopicMessage  bool      `json:"is_topic_message"`
 	Photo          []tgPhoto  `json:"photo"`
 	Audio          *tgAudio   `json:"audio"`
 	Voice          *tgVoice   `json:"voice"`
@@ -966,7 +1226,7 @@ func (t *Telegram) getUpdates(offset int64, limit, timeoutSecs int) ([]tgUpdate,
 		"limit":   limit,
 		"timeout": timeoutSecs,
 		"allowed_updates": []string{
-			"message", "edited_message", "message_reaction",
+			"message", "edited_message", "message_reaction", "callback_query",
 		},
 	}
 	data, err := t.apiCall("getUpdates", payload)
@@ -1003,6 +1263,9 @@ func (t *Telegram) uploadFile(method string, chatID int64, fieldName string, med
 	w := multipart.NewWriter(&buf)
 
 	_ = w.WriteField("chat_id", strconv.FormatInt(chatID, 10))
+	if thread := t.threadFor(chatID, media.ReplyTo, nil); thread != 0 {
+		_ = w.WriteField("message_thread_id", strconv.FormatInt(thread, 10))
+	}
 	if media.Caption != "" {
 		_ = w.WriteField("caption", media.Caption)
 		_ = w.WriteField("parse_mode", t.cfg.ParseMode)
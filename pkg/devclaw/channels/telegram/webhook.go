@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// seenUpdatesWindow is how many recent update IDs are kept for dedup.
+const seenUpdatesWindow = 1024
+
+// startWebhook serves the webhook endpoint and registers it with setWebhook.
+func (t *Telegram) startWebhook() error {
+	u, err := url.Parse(t.cfg.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("telegram: webhook mode requires an https webhook_url, got %q", t.cfg.WebhookURL)
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	if t.cfg.WebhookSecret == "" {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("telegram: generating webhook secret: %w", err)
+		}
+		t.cfg.WebhookSecret = hex.EncodeToString(buf)
+	}
+
+	// Bind before registering so a busy port fails Connect instead of
+	// leaving Telegram posting to nowhere.
+	ln, err := net.Listen("tcp", t.cfg.WebhookListen)
+	if err != nil {
+		return fmt.Errorf("telegram: webhook listen on %s: %w", t.cfg.WebhookListen, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, t.handleWebhook)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	t.mu.Lock()
+	t.webhook = srv
+	t.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.errorCount.Add(1)
+			t.logger.Error("telegram: webhook server stopped", "error", err)
+		}
+	}()
+
+	_, err = t.apiCall("setWebhook", map[string]any{
+		"url":          t.cfg.WebhookURL,
+		"secret_token": t.cfg.WebhookSecret,
+		"allowed_updates": []string{
+			"message", "edited_message", "message_reaction", "callback_query",
+		},
+	})
+	if err != nil {
+		t.stopWebhook()
+		return fmt.Errorf("telegram: setWebhook: %w", err)
+	}
+
+	t.logger.Info("telegram: webhook registered", "url", t.cfg.WebhookURL, "listen", t.cfg.WebhookListen)
+	return nil
+}
+
+// stopWebhook shuts down the webhook server, if running.
+func (t *Telegram) stopWebhook() {
+	t.mu.Lock()
+	srv := t.webhook
+	t.webhook = nil
+	t.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+}
+
+// handleWebhook receives a single update from Telegram.
+func (t *Telegram) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(t.cfg.WebhookSecret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var u tgUpdate
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&u); err != nil {
+		// A 2xx stops Telegram from retrying an update we can never parse.
+		t.logger.Warn("telegram: invalid webhook update", "error", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if t.advanceOffset(u.UpdateID) {
+		t.processUpdate(u)
+	}
+}
+
+// advanceOffset records an update ID and reports whether it is seen for
+// the first time. Telegram redelivers webhook updates that were not
+// acknowledged in time, possibly out of order, so recent IDs are remembered.
+func (t *Telegram) advanceOffset(updateID int64) bool {
+	t.offsetMu.Lock()
+	defer t.offsetMu.Unlock()
+	if _, dup := t.seenUpdates[updateID]; dup {
+		return false
+	}
+	t.seenUpdates[updateID] = struct{}{}
+	if updateID >= t.offset {
+		t.offset = updateID + 1
+	}
+	if len(t.seenUpdates) > seenUpdatesWindow {
+		for id := range t.seenUpdates {
+			if id < t.offset-seenUpdatesWindow {
+				delete(t.seenUpdates, id)
+			}
+		}
+	}
+	return true
+}
+
+// threadFor returns the forum topic to post into: an explicit
+// metadata["telegram_thread_id"], else the topic of the message being
+// replied to. Anything else goes to the chat itself, never to a topic
+// guessed from other traffic.
+func (t *Telegram) threadFor(chatID int64, replyTo string, metadata map[string]any) int64 {
+	switch v := metadata["telegram_thread_id"].(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	}
+	if replyTo == "" {
+		return 0
+	}
+	t.topicsMu.RLock()
+	defer t.topicsMu.RUnlock()
+	return t.topics[fmt.Sprintf("%d:%s", chatID, replyTo)]
+}
+
+// recordTopic remembers the forum topic of an incoming message.
+func (t *Telegram) recordTopic(chatID int64, messageID int, thread int64) {
+	key := fmt.Sprintf("%d:%d", chatID, messageID)
+	t.topicsMu.Lock()
+	if len(t.topics) >= 5000 {
+		// Simple eviction: clear half when full.
+		for k := range t.topics {
+			delete(t.topics, k)
+			if len(t.topics) < 2500 {
+				break
+			}
+		}
+	}
+	t.topics[key] = thread
+	t.topicsMu.Unlock()
+}
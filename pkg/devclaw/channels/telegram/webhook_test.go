@@ -0,0 +1,161 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+func newTestTelegram(t *testing.T, cfg Config) *Telegram {
+	t.Helper()
+	cfg.Token = "test-token"
+	cfg.RespondToGroups = true
+	cfg.RespondToDMs = true
+	return New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func postUpdate(tg *Telegram, secret, body string) int {
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", strings.NewReader(body))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+	rec := httptest.NewRecorder()
+	tg.handleWebhook(rec, req)
+	return rec.Code
+}
+
+func TestHandleWebhook(t *testing.T) {
+	t.Parallel()
+	tg := newTestTelegram(t, Config{WebhookSecret: "s3cret"})
+	update := `{"update_id":10,"message":{"message_id":7,"chat":{"id":-100,"type":"supergroup"},"text":"hi","message_thread_id":42,"is_topic_message":true}}`
+
+	if code := postUpdate(tg, "wrong", update); code != http.StatusForbidden {
+		t.Errorf("wrong secret: status %d", code)
+	}
+	rec := httptest.NewRecorder()
+	tg.handleWebhook(rec, httptest.NewRequest(http.MethodGet, "/telegram/webhook", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", rec.Code)
+	}
+	// Unparseable updates are acknowledged so Telegram stops retrying.
+	if code := postUpdate(tg, "s3cret", "{not json"); code != http.StatusOK {
+		t.Errorf("invalid JSON: status %d", code)
+	}
+	if len(tg.messages) != 0 {
+		t.Fatal("rejected requests produced messages")
+	}
+
+	// A redelivered update is delivered once.
+	for i := 0; i < 2; i++ {
+		if code := postUpdate(tg, "s3cret", update); code != http.StatusOK {
+			t.Fatalf("valid update: status %d", code)
+		}
+	}
+	if len(tg.messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(tg.messages))
+	}
+	msg := <-tg.messages
+	if msg.ChatID != "-100" || msg.Content != "hi" || msg.Metadata["telegram_thread_id"] != int64(42) {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestAdvanceOffset(t *testing.T) {
+	t.Parallel()
+	tg := newTestTelegram(t, Config{})
+
+	for _, tc := range []struct {
+		id    int64
+		fresh bool
+	}{
+		{5, true},
+		{7, true},
+		{6, true}, // out of order, still new
+		{5, false},
+		{7, false},
+	} {
+		if got := tg.advanceOffset(tc.id); got != tc.fresh {
+			t.Errorf("advanceOffset(%d) = %v, want %v", tc.id, got, tc.fresh)
+		}
+	}
+	if tg.offset != 8 {
+		t.Errorf("offset = %d, want 8", tg.offset)
+	}
+
+	// Old IDs are evicted once the window is exceeded.
+	for id := int64(8); id < 8+2*seenUpdatesWindow; id++ {
+		tg.advanceOffset(id)
+	}
+	if len(tg.seenUpdates) > seenUpdatesWindow+1 {
+		t.Errorf("seenUpdates grew to %d", len(tg.seenUpdates))
+	}
+}
+
+func TestStartWebhook_RequiresHTTPS(t *testing.T) {
+	t.Parallel()
+	for _, u := range []string{"", "http://bot.example.com/hook", "https://"} {
+		tg := newTestTelegram(t, Config{Mode: ModeWebhook, WebhookURL: u})
+		if err := tg.startWebhook(); err == nil {
+			tg.stopWebhook()
+			t.Errorf("webhook_url %q accepted", u)
+		}
+	}
+}
+
+func TestThreadFor_ExplicitOnly(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		posts []map[string]any
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		posts = append(posts, payload)
+		mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":{"message_id":1}}`)
+	}))
+	defer api.Close()
+
+	tg := newTestTelegram(t, Config{APIURL: api.URL})
+	tg.ctx = context.Background()
+	tg.connected.Store(true)
+	tg.processUpdate(tgUpdate{UpdateID: 1, Message: &tgMessage{
+		MessageID: 7, Chat: tgChat{ID: -100, Type: "supergroup"}, Text: "in a topic",
+		MessageThreadID: 42, IsTopicMessage: true,
+	}})
+	tg.processUpdate(tgUpdate{UpdateID: 2, Message: &tgMessage{
+		MessageID: 8, Chat: tgChat{ID: -100, Type: "supergroup"}, Text: "in general",
+	}})
+
+	cases := []struct {
+		name string
+		msg  *channels.OutgoingMessage
+		want int64
+	}{
+		{"reply to topic message", &channels.OutgoingMessage{Content: "a", ReplyTo: "7"}, 42},
+		{"explicit thread", &channels.OutgoingMessage{Content: "b", Metadata: map[string]any{"telegram_thread_id": 9}}, 9},
+		{"reply to general message", &channels.OutgoingMessage{Content: "c", ReplyTo: "8"}, 0},
+		{"unknown reply", &channels.OutgoingMessage{Content: "d", ReplyTo: "99"}, 0},
+		// The last topic seen in the chat is not a routing hint.
+		{"no reply", &channels.OutgoingMessage{Content: "e"}, 0},
+	}
+	for _, tc := range cases {
+		if err := tg.Send(context.Background(), "-100", tc.msg); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		mu.Lock()
+		got, _ := posts[len(posts)-1]["message_thread_id"].(float64)
+		mu.Unlock()
+		if int64(got) != tc.want {
+			t.Errorf("%s: message_thread_id = %v, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+// Package webhook implements a generic inbound webhook channel for DevClaw:
+// external systems (GitHub, Stripe, Grafana alerts, CI) POST events to
+// /hooks/{name} and each verified event becomes an incoming message, so the
+// agent can react to it like to any chat message.
+//
+// Features:
+//   - Per-hook signature verification: GitHub (X-Hub-Signature-256),
+//     Stripe (Stripe-Signature with timestamp tolerance), a generic
+//     HMAC-SHA256 header, or a static token (Grafana, Alertmanager)
+//   - Each hook is its own sender ("hook:<name>") and chat, so access
+//     control, workspaces and sessions work as on any other channel
+//   - Delivery IDs (X-GitHub-Delivery, Stripe event id, Idempotency-Key)
+//     become message IDs, so provider retries are deduplicated
+//   - The agent's reply is forwarded to a configured channel and chat
+//     (e.g. the owner's Telegram); without one it is only logged
+//   - Served by the HTTP gateway under /hooks/, or by its own listener
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// ChannelName is the name the channel registers under.
+const ChannelName = "webhook"
+
+// MetaAccess is the metadata key carrying the hook's access level
+// ("user" or "admin") on incoming messages.
+const MetaAccess = "webhook_access"
+
+// Config holds webhook channel configuration.
+type Config struct {
+	// Enabled registers the channel (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// Address starts a dedicated listener (e.g. ":8095"), for setups
+	// without the HTTP gateway. When the gateway is enabled it always
+	// serves /hooks/{name} too.
+	Address string `yaml:"address"`
+
+	// MaxBodyKB rejects larger payloads (default: 512).
+	MaxBodyKB int `yaml:"max_body_kb"`
+
+	// Hooks are the accepted endpoints.
+	Hooks []Hook `yaml:"hooks"`
+}
+
+// Hook is one named endpoint.
+type Hook struct {
+	// Name is the path segment: POST /hooks/{name}.
+	Name string `yaml:"name"`
+
+	// Secret verifies requests. Required unless Signature is "none".
+	Secret string `yaml:"secret"`
+
+	// Signature is the verification scheme: "hmac" (default), "github",
+	// "stripe", "token" or "none" (trusted networks only).
+	Signature string `yaml:"signature"`
+
+	// Header carries the signature for "hmac" (default: X-Signature-256)
+	// or the token for "token" (default: Authorization, "Bearer " optional).
+	Header string `yaml:"header"`
+
+	// ChatID is the session events land in (default: "hook:<name>").
+	ChatID string `yaml:"chat_id"`
+
+	// Workspace routes the hook's events to a workspace (default: the
+	// default workspace).
+	Workspace string `yaml:"workspace"`
+
+	// Access is the level the agent runs with: "user" (default) or
+	// "admin". Payloads are external input, so owner is not available.
+	Access string `yaml:"access"`
+
+	// Instructions are prepended to every event, e.g. "Summarize the alert
+	// and check the service logs".
+	Instructions string `yaml:"instructions"`
+
+	// Notify is where the agent's reply is sent. Empty drops the reply.
+	Notify NotifyTarget `yaml:"notify"`
+}
+
+// NotifyTarget is a channel and chat to forward replies to.
+type NotifyTarget struct {
+	Channel string `yaml:"channel"`
+	To      string `yaml:"to"`
+}
+
+// DefaultConfig returns a Config with sensible defaults.
+func DefaultConfig() Config {
+	return Config{MaxBodyKB: 512}
+}
+
+// maxEventChars bounds the payload text passed to the agent.
+const maxEventChars = 16000
+
+// stripeTolerance is how old a Stripe signature timestamp may be.
+const stripeTolerance = 5 * time.Minute
+
+// Forwarder delivers a reply through another channel.
+type Forwarder func(ctx context.Context, channel, to string, msg *channels.OutgoingMessage) error
+
+// Webhook implements channels.Channel and http.Handler.
+type Webhook struct {
+	cfg    Config
+	logger *slog.Logger
+	hooks  map[string]Hook
+
+	messages chan *channels.IncomingMessage
+	forward  atomic.Value // Forwarder
+
+	connected  atomic.Bool
+	lastMsg    atomic.Value // time.Time
+	errorCount atomic.Int64
+	received   atomic.Int64
+
+	server *http.Server
+}
+
+// New creates a new webhook channel instance. Hooks without a name, or
+// without a secret while requiring one, are skipped with a warning.
+func New(cfg Config, logger *slog.Logger) *Webhook {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.MaxBodyKB <= 0 {
+		cfg.MaxBodyKB = DefaultConfig().MaxBodyKB
+	}
+	w := &Webhook{
+		cfg:      cfg,
+		logger:   logger.With("channel", ChannelName),
+		hooks:    make(map[string]Hook),
+		messages: make(chan *channels.IncomingMessage, 256),
+	}
+	for _, h := range cfg.Hooks {
+		h = normalizeHook(h)
+		switch {
+		case h.Name == "":
+			w.logger.Warn("webhook: hook without name skipped")
+			continue
+		case h.Secret == "" && h.Signature != "none":
+			w.logger.Warn("webhook: hook without secret skipped", "hook", h.Name)
+			continue
+		}
+		w.hooks[h.Name] = h
+	}
+	return w
+}
+
+// normalizeHook fills defaults.
+func normalizeHook(h Hook) Hook {
+	h.Name = strings.Trim(strings.TrimSpace(h.Name), "/")
+	h.Signature = strings.ToLower(h.Signature)
+	if h.Signature == "" {
+		h.Signature = "hmac"
+	}
+	if h.ChatID == "" {
+		h.ChatID = Sender(h.Name)
+	}
+	if h.Access != "admin" {
+		h.Access = "user"
+	}
+	return h
+}
+
+// Sender returns the sender ID used for a hook's events.
+func Sender(name string) string { return "hook:" + name }
+
+// Hooks returns the active hooks.
+func (w *Webhook) Hooks() []Hook {
+	out := make([]Hook, 0, len(w.hooks))
+	for _, h := range w.hooks {
+		out = append(out, h)
+	}
+	return out
+}
+
+// SetForwarder sets how replies reach the notify targets.
+func (w *Webhook) SetForwarder(fn Forwarder) {
+	w.forward.Store(fn)
+}
+
+// Name returns the channel name.
+func (w *Webhook) Name() string { return ChannelName }
+
+// Connect starts accepting events, and the dedicated listener when an
+// address is configured.
+func (w *Webhook) Connect(ctx context.Context) error {
+	if w.connected.Load() {
+		return nil
+	}
+	if len(w.hooks) == 0 {
+		return fmt.Errorf("webhook: no hooks configured")
+	}
+	if w.cfg.Address != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/hooks/", w)
+		w.server = &http.Server{
+			Addr:              w.cfg.Address,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := w.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				w.logger.Error("webhook: listener failed", "address", w.cfg.Address, "error", err)
+				w.connected.Store(false)
+			}
+		}()
+	}
+	w.connected.Store(true)
+	w.logger.Info("webhook: accepting events", "hooks", len(w.hooks), "address", w.cfg.Address)
+	return nil
+}
+
+// Disconnect stops accepting events.
+func (w *Webhook) Disconnect() error {
+	w.connected.Store(false)
+	if w.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return w.server.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Receive returns the incoming messages channel.
+func (w *Webhook) Receive() <-chan *channels.IncomingMessage {
+	return w.messages
+}
+
+// IsConnected returns true while events are accepted.
+func (w *Webhook) IsConnected() bool { return w.connected.Load() }
+
+// Health returns the channel health status.
+func (w *Webhook) Health() channels.HealthStatus {
+	var lastAt time.Time
+	if v := w.lastMsg.Load(); v != nil {
+		lastAt = v.(time.Time)
+	}
+	return channels.HealthStatus{
+		Connected:     w.connected.Load(),
+		LastMessageAt: lastAt,
+		ErrorCount:    int(w.errorCount.Load()),
+		Details:       map[string]any{"hooks": len(w.hooks), "received": w.received.Load()},
+	}
+}
+
+// Send forwards the agent's reply to the hook's notify target.
+func (w *Webhook) Send(ctx context.Context, to string, msg *channels.OutgoingMessage) error {
+	hook, ok := w.hookForChat(to)
+	if !ok || hook.Notify.Channel == "" || hook.Notify.To == "" {
+		w.logger.Debug("webhook: reply dropped (no notify target)", "chat", to)
+		return nil
+	}
+	if hook.Notify.Channel == ChannelName {
+		return fmt.Errorf("webhook: notify target cannot be the webhook channel")
+	}
+	fn, _ := w.forward.Load().(Forwarder)
+	if fn == nil {
+		w.logger.Warn("webhook: reply dropped (no forwarder)", "hook", hook.Name)
+		return nil
+	}
+	out := *msg
+	out.ReplyTo = ""
+	out.Content = fmt.Sprintf("🔔 *%s*\n%s", hook.Name, msg.Content)
+	return fn(ctx, hook.Notify.Channel, hook.Notify.To, &out)
+}
+
+// hookForChat finds the hook whose events land in chatID.
+func (w *Webhook) hookForChat(chatID string) (Hook, bool) {
+	for _, h := range w.hooks {
+		if h.ChatID == chatID {
+			return h, true
+		}
+	}
+	return Hook{}, false
+}
+
+// ServeHTTP handles POST /hooks/{name}.
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/hooks/"), "/")
+	hook, ok := w.hooks[name]
+	if !ok {
+		http.Error(rw, "unknown hook", http.StatusNotFound)
+		return
+	}
+
+	limit := int64(w.cfg.MaxBodyKB) << 10
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		http.Error(rw, "reading body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > limit {
+		http.Error(rw, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := Verify(hook, r.Header, body, time.Now()); err != nil {
+		w.errorCount.Add(1)
+		w.logger.Warn("webhook: rejected event", "hook", name, "remote", r.RemoteAddr, "error", err)
+		http.Error(rw, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if !w.connected.Load() {
+		http.Error(rw, "channel not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	msg := w.toMessage(hook, r.Header, body)
+	select {
+	case w.messages <- msg:
+	default:
+		w.logger.Warn("webhook: queue full, event rejected", "hook", name)
+		http.Error(rw, "busy", http.StatusServiceUnavailable)
+		return
+	}
+	w.received.Add(1)
+	w.lastMsg.Store(time.Now())
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(rw).Encode(map[string]any{"accepted": true, "id": msg.ID})
+}
+
+// toMessage turns a verified event into an incoming message.
+func (w *Webhook) toMessage(hook Hook, h http.Header, body []byte) *channels.IncomingMessage {
+	var payload map[string]any
+	_ = json.Unmarshal(body, &payload)
+
+	event := eventType(h, payload)
+	id := deliveryID(h, payload)
+	if id == "" {
+		id = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Webhook event from %q", hook.Name)
+	if event != "" {
+		fmt.Fprintf(&b, ", type %q", event)
+	}
+	b.WriteString("]\n")
+	if hook.Instructions != "" {
+		b.WriteString(hook.Instructions + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(payloadText(body))
+
+	msg := &channels.IncomingMessage{
+		ID:        hook.Name + ":" + id,
+		Channel:   ChannelName,
+		From:      Sender(hook.Name),
+		FromName:  hook.Name,
+		ChatID:    hook.ChatID,
+		Type:      channels.MessageText,
+		Content:   b.String(),
+		Timestamp: time.Now(),
+	}
+	msg.SetMeta(MetaAccess, hook.Access)
+	msg.SetMeta("webhook_event", event)
+	return msg
+}
+
+// payloadText pretty-prints JSON payloads and truncates long ones.
+func payloadText(body []byte) string {
+	text := string(body)
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err == nil {
+		text = buf.String()
+	}
+	if len(text) > maxEventChars {
+		text = text[:maxEventChars] + "\n… (truncated)"
+	}
+	return text
+}
+
+// eventType reads the event name from well-known headers or payload fields.
+func eventType(h http.Header, payload map[string]any) string {
+	for _, k := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Event-Type", "X-Event-Key"} {
+		if v := h.Get(k); v != "" {
+			if action, _ := payload["action"].(string); action != "" {
+				return v + "." + action
+			}
+			return v
+		}
+	}
+	for _, k := range []string{"type", "event", "status"} {
+		if v, _ := payload[k].(string); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// deliveryID reads the provider's delivery ID, stable across retries.
+func deliveryID(h http.Header, payload map[string]any) string {
+	for _, k := range []string{"X-GitHub-Delivery", "X-Gitlab-Event-UUID", "Idempotency-Key", "X-Request-Id"} {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+	// Stripe events carry their ID in the payload ("evt_...").
+	if v, _ := payload["id"].(string); strings.HasPrefix(v, "evt_") {
+		return v
+	}
+	return ""
+}
+
+// Verify checks a request against the hook's signature scheme.
+func Verify(hook Hook, h http.Header, body []byte, now time.Time) error {
+	switch hook.Signature {
+	case "none":
+		return nil
+
+	case "github":
+		sig, ok := strings.CutPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return fmt.Errorf("missing X-Hub-Signature-256")
+		}
+		return checkHMAC(hook.Secret, body, sig)
+
+	case "stripe":
+		var ts string
+		var sigs []string
+		for _, part := range strings.Split(h.Get("Stripe-Signature"), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				sigs = append(sigs, v)
+			}
+		}
+		if ts == "" || len(sigs) == 0 {
+			return fmt.Errorf("missing Stripe-Signature")
+		}
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid Stripe-Signature timestamp")
+		}
+		if age := now.Sub(time.Unix(sec, 0)); age > stripeTolerance || age < -stripeTolerance {
+			return fmt.Errorf("stale Stripe-Signature timestamp")
+		}
+		signed := append([]byte(ts+"."), body...)
+		for _, sig := range sigs {
+			if checkHMAC(hook.Secret, signed, sig) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("signature mismatch")
+
+	case "token":
+		header := hook.Header
+		if header == "" {
+			header = "Authorization"
+		}
+		token := strings.TrimPrefix(h.Get(header), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(hook.Secret)) != 1 {
+			return fmt.Errorf("invalid token")
+		}
+		return nil
+
+	case "hmac":
+		header := hook.Header
+		if header == "" {
+			header = "X-Signature-256"
+		}
+		sig := h.Get(header)
+		if sig == "" {
+			return fmt.Errorf("missing %s", header)
+		}
+		return checkHMAC(hook.Secret, body, strings.TrimPrefix(sig, "sha256="))
+
+	default:
+		return fmt.Errorf("unknown signature scheme %q", hook.Signature)
+	}
+}
+
+// checkHMAC compares a hex HMAC-SHA256 of data with sig in constant time.
+func checkHMAC(secret string, data []byte, sig string) error {
+	want, err := hex.DecodeString(strings.TrimSpace(sig))
+	if err != nil {
+		return fmt.Errorf("malformed signature")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), want) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
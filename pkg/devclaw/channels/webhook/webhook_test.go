@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sign(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	const secret, body = "s3cret", `{"id":"evt_1"}`
+	now := time.Unix(1_800_000_000, 0)
+	stripeSig := func(at time.Time, key string) string {
+		ts := strconv.FormatInt(at.Unix(), 10)
+		return "t=" + ts + ",v1=" + sign(key, ts+"."+body)
+	}
+
+	cases := []struct {
+		name    string
+		hook    Hook
+		headers map[string]string
+		wantErr string
+	}{
+		{"hmac", Hook{Signature: "hmac"}, map[string]string{"X-Signature-256": "sha256=" + sign(secret, body)}, ""},
+		{"hmac custom header", Hook{Signature: "hmac", Header: "X-Sig"}, map[string]string{"X-Sig": sign(secret, body)}, ""},
+		{"hmac bad signature", Hook{Signature: "hmac"}, map[string]string{"X-Signature-256": sign("other", body)}, "signature mismatch"},
+		{"hmac malformed", Hook{Signature: "hmac"}, map[string]string{"X-Signature-256": "not-hex"}, "malformed signature"},
+		{"hmac missing header", Hook{Signature: "hmac"}, nil, "missing X-Signature-256"},
+
+		{"github", Hook{Signature: "github"}, map[string]string{"X-Hub-Signature-256": "sha256=" + sign(secret, body)}, ""},
+		{"github bad signature", Hook{Signature: "github"}, map[string]string{"X-Hub-Signature-256": "sha256=" + sign("other", body)}, "signature mismatch"},
+		{"github without prefix", Hook{Signature: "github"}, map[string]string{"X-Hub-Signature-256": sign(secret, body)}, "missing X-Hub-Signature-256"},
+		{"github missing header", Hook{Signature: "github"}, nil, "missing X-Hub-Signature-256"},
+
+		{"stripe", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": stripeSig(now, secret)}, ""},
+		{"stripe rotated secret", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": stripeSig(now, "old") + ",v1=" + sign(secret, strconv.FormatInt(now.Unix(), 10)+"."+body)}, ""},
+		{"stripe bad signature", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": stripeSig(now, "other")}, "signature mismatch"},
+		{"stripe missing header", Hook{Signature: "stripe"}, nil, "missing Stripe-Signature"},
+		{"stripe bad timestamp", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": "t=soon,v1=" + sign(secret, body)}, "invalid Stripe-Signature timestamp"},
+		{"stripe old timestamp", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": stripeSig(now.Add(-stripeTolerance-time.Second), secret)}, "stale"},
+		{"stripe future timestamp", Hook{Signature: "stripe"}, map[string]string{"Stripe-Signature": stripeSig(now.Add(stripeTolerance+time.Second), secret)}, "stale"},
+
+		{"token", Hook{Signature: "token"}, map[string]string{"Authorization": "Bearer " + secret}, ""},
+		{"token without bearer", Hook{Signature: "token"}, map[string]string{"Authorization": secret}, ""},
+		{"token custom header", Hook{Signature: "token", Header: "X-Token"}, map[string]string{"X-Token": secret}, ""},
+		{"token wrong", Hook{Signature: "token"}, map[string]string{"Authorization": "Bearer nope"}, "invalid token"},
+		{"token missing header", Hook{Signature: "token"}, nil, "invalid token"},
+
+		{"none", Hook{Signature: "none"}, nil, ""},
+		{"unknown scheme", Hook{Signature: "md5"}, nil, "unknown signature scheme"},
+	}
+	for _, tc := range cases {
+		tc.hook.Secret = secret
+		h := http.Header{}
+		for k, v := range tc.headers {
+			h.Set(k, v)
+		}
+		err := Verify(tc.hook, h, []byte(body), now)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNew_SkipsInvalidHooks(t *testing.T) {
+	t.Parallel()
+	w := New(Config{Hooks: []Hook{
+		{Name: "/ci/", Secret: "s"},
+		{Name: "", Secret: "s"},
+		{Name: "nosecret"},
+		{Name: "nosecret-token", Signature: "token"},
+		{Name: "open", Signature: "none"},
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if len(w.hooks) != 2 {
+		t.Fatalf("hooks = %v, want ci and open", w.Hooks())
+	}
+	ci, ok := w.hooks["ci"]
+	if !ok || ci.Signature != "hmac" || ci.ChatID != "hook:ci" || ci.Access != "user" {
+		t.Errorf("ci hook = %+v", ci)
+	}
+	if _, ok := w.hooks["open"]; !ok {
+		t.Error("a hook with signature none needs no secret")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+	w := New(Config{MaxBodyKB: 1, Hooks: []Hook{
+		{Name: "ci", Secret: "s3cret", Instructions: "Check the build."},
+	}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	post := func(path, body, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if sig != "" {
+			req.Header.Set("X-Signature-256", sig)
+		}
+		req.Header.Set("X-GitHub-Event", "workflow_run")
+		req.Header.Set("X-GitHub-Delivery", "d-1")
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	body := `{"action":"completed"}`
+	big := `{"log":"` + strings.Repeat("x", 1024) + `"}`
+
+	// Events are refused until the channel is connected.
+	if code := post("/hooks/ci", body, sign("s3cret", body)); code != http.StatusServiceUnavailable {
+		t.Errorf("before connect: status %d", code)
+	}
+	if err := w.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Disconnect()
+
+	cases := []struct {
+		name string
+		path string
+		body string
+		sig  string
+		want int
+	}{
+		{"unknown hook", "/hooks/deploy", body, sign("s3cret", body), http.StatusNotFound},
+		{"nested unknown hook", "/hooks/ci/extra", body, sign("s3cret", body), http.StatusNotFound},
+		{"oversized body", "/hooks/ci", big, sign("s3cret", big), http.StatusRequestEntityTooLarge},
+		{"bad signature", "/hooks/ci", body, sign("wrong", body), http.StatusUnauthorized},
+		{"missing signature", "/hooks/ci", body, "", http.StatusUnauthorized},
+		{"accepted", "/hooks/ci/", body, sign("s3cret", body), http.StatusAccepted},
+	}
+	for _, tc := range cases {
+		if code := post(tc.path, tc.body, tc.sig); code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, code, tc.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hooks/ci", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", rec.Code)
+	}
+
+	if len(w.messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(w.messages))
+	}
+	msg := <-w.messages
+	if msg.ID != "ci:d-1" || msg.From != "hook:ci" || msg.ChatID != "hook:ci" || msg.Metadata[MetaAccess] != "user" {
+		t.Errorf("message = %+v", msg)
+	}
+	if !strings.Contains(msg.Content, `type "workflow_run.completed"`) || !strings.Contains(msg.Content, "Check the build.") {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if h := w.Health(); h.ErrorCount != 2 || h.Details["received"] != int64(1) {
+		t.Errorf("health = %+v", h)
+	}
+}
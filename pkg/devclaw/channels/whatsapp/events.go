@@ -144,6 +144,18 @@ func (w *WhatsApp) extractMessageContent(waMsg *waE2E.Message, msg *channels.Inc
 		return
 	}
 
+	// Quick-reply button press: deliver the button's data as text.
+	if br := waMsg.ButtonsResponseMessage; br != nil {
+		msg.Type = channels.MessageText
+		msg.Content = br.GetSelectedButtonID()
+		return
+	}
+	if tr := waMsg.TemplateButtonReplyMessage; tr != nil {
+		msg.Type = channels.MessageText
+		msg.Content = tr.GetSelectedID()
+		return
+	}
+
 	// Extended text message (with preview, formatting, etc.).
 	if ext := waMsg.ExtendedTextMessage; ext != nil {
 		msg.Type = channels.MessageText
@@ -341,10 +353,34 @@ func (w *WhatsApp) extractQuotedMessage(waMsg *waE2E.Message, msg *channels.Inco
 
 	if ctxInfo.StanzaID != nil {
 		msg.ReplyTo = ctxInfo.GetStanzaID()
+		if w.isOwnJID(ctxInfo.GetParticipant()) {
+			msg.SetMeta(channels.MetaReplyToBot, true)
+		}
 	}
 	if quoted := ctxInfo.QuotedMessage; quoted != nil {
 		msg.QuotedContent = extractQuotedText(quoted)
 	}
+	for _, jid := range ctxInfo.GetMentionedJID() {
+		if w.isOwnJID(jid) {
+			msg.SetMeta(channels.MetaMentionsBot, true)
+			break
+		}
+	}
+}
+
+// isOwnJID reports whether jid (phone or LID form) is the connected account.
+func (w *WhatsApp) isOwnJID(jid string) bool {
+	if jid == "" || w.client == nil || w.client.Store == nil {
+		return false
+	}
+	parsed, err := types.ParseJID(jid)
+	if err != nil {
+		return false
+	}
+	if id := w.client.Store.ID; id != nil && parsed.User == id.User {
+		return true
+	}
+	return !w.client.Store.LID.IsEmpty() && parsed.User == w.client.Store.LID.User
 }
 
 // extractQuotedText gets the text from a quoted message.
@@ -41,6 +41,26 @@ func buildTextMessage(text, replyTo string) *waE2E.Message {
 	}
 }
 
+// buildButtonsMessage creates a message with quick-reply buttons (at most
+// 3). The button ID carries the data delivered back when one is pressed.
+func buildButtonsMessage(text string, buttons []channels.Button) *waE2E.Message {
+	bm := &waE2E.ButtonsMessage{
+		ContentText: proto.String(text),
+		HeaderType:  waE2E.ButtonsMessage_EMPTY.Enum(),
+	}
+	for i, b := range buttons {
+		if i == 3 {
+			break
+		}
+		bm.Buttons = append(bm.Buttons, &waE2E.ButtonsMessage_Button{
+			ButtonID:   proto.String(b.Data),
+			ButtonText: &waE2E.ButtonsMessage_Button_ButtonText{DisplayText: proto.String(b.Label)},
+			Type:       waE2E.ButtonsMessage_Button_RESPONSE.Enum(),
+		})
+	}
+	return &waE2E.Message{ButtonsMessage: bm}
+}
+
 // buildReactionMessage creates a reaction message proto.
 func buildReactionMessage(messageID string, chatJID types.JID, emoji string) *waE2E.Message {
 	return &waE2E.Message{
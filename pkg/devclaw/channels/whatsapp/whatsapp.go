@@ -58,6 +58,11 @@ type Config struct {
 
 	// MaxMediaSizeMB is the maximum media file size to process.
 	MaxMediaSizeMB int `yaml:"max_media_size_mb"`
+
+	// QuickReplies sends message buttons (e.g. tool approvals) as
+	// quick-reply buttons. Off by default: not every WhatsApp client shows
+	// them, and the text of the message already says how to answer.
+	QuickReplies bool `yaml:"quick_replies"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -233,7 +238,8 @@ func (w *WhatsApp) Disconnect() error {
 	if w.client != nil {
 		w.client.Disconnect()
 	}
-	close(w.messages)
+	// w.messages stays open: the channel may be connected again (e.g. after
+	// regaining ownership), and the manager's listener exits on its context.
 	w.logger.Info("whatsapp: disconnected")
 	return nil
 }
@@ -249,6 +255,14 @@ func (w *WhatsApp) Send(ctx context.Context, to string, msg *channels.OutgoingMe
 		return fmt.Errorf("invalid JID %q: %w", to, err)
 	}
 
+	if w.cfg.QuickReplies && len(msg.Buttons) > 0 {
+		_, err := w.client.SendMessage(ctx, jid, buildButtonsMessage(msg.Content, msg.Buttons))
+		if err == nil {
+			return nil
+		}
+		w.logger.Warn("whatsapp: quick-reply buttons rejected, sending text", "error", err)
+	}
+
 	waMsg := buildTextMessage(msg.Content, msg.ReplyTo)
 
 	_, err = w.client.SendMessage(ctx, jid, waMsg)
@@ -383,6 +397,46 @@ func (w *WhatsApp) SendReaction(ctx context.Context, chatID, messageID, emoji st
 	return err
 }
 
+// ---------- EditChannel Interface ----------
+
+// SendEditable sends a text message and returns its ID for later edits.
+func (w *WhatsApp) SendEditable(ctx context.Context, to string, msg *channels.OutgoingMessage) (string, error) {
+	if !w.connected.Load() {
+		return "", channels.ErrChannelDisconnected
+	}
+
+	jid, err := parseJID(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid JID %q: %w", to, err)
+	}
+
+	resp, err := w.client.SendMessage(ctx, jid, buildTextMessage(msg.Content, msg.ReplyTo))
+	if err != nil {
+		w.errorCount.Add(1)
+		return "", fmt.Errorf("sending message: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// EditMessage edits a text message previously sent by the bot.
+func (w *WhatsApp) EditMessage(ctx context.Context, chatID, messageID, content string) error {
+	if !w.connected.Load() {
+		return channels.ErrChannelDisconnected
+	}
+
+	jid, err := parseJID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid JID %q: %w", chatID, err)
+	}
+
+	edit := w.client.BuildEdit(jid, messageID, buildTextMessage(content, ""))
+	if _, err := w.client.SendMessage(ctx, jid, edit); err != nil {
+		w.errorCount.Add(1)
+		return fmt.Errorf("editing message: %w", err)
+	}
+	return nil
+}
+
 // ---------- Internal ----------
 
 // getDevice retrieves an existing device or creates a new one.
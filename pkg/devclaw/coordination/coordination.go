@@ -0,0 +1,352 @@
+// Package coordination provides leader election between DevClaw instances
+// that share the same channel sessions. Each channel is guarded by a lease;
+// only the instance holding the lease connects the channel, the others stay
+// on standby and take over when the lease is released or expires.
+//
+// Backends:
+//   - file: exclusive flock on a lock file (instances on the same host/volume)
+//   - redis: SET NX PX lease renewed by a compare-and-expire script
+//   - etcd: v3 lease + transaction via the JSON gateway
+package coordination
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures multi-instance coordination.
+type Config struct {
+	// Enabled turns on leader election for channels. When disabled every
+	// instance connects all channels (the historical behavior).
+	Enabled bool `yaml:"enabled"`
+
+	// Backend selects the lease store: "file" (default), "redis" or "etcd".
+	Backend string `yaml:"backend"`
+
+	// InstanceID identifies this instance in leases and logs.
+	// Default: "<hostname>-<pid>".
+	InstanceID string `yaml:"instance_id"`
+
+	// LeaseSeconds is how long a lease survives without renewal (default: 15).
+	LeaseSeconds int `yaml:"lease_seconds"`
+
+	// RenewSeconds is how often the leader renews and standbys retry
+	// (default: LeaseSeconds / 3).
+	RenewSeconds int `yaml:"renew_seconds"`
+
+	// LockDir holds the lock files for the file backend (default: ./data/locks).
+	LockDir string `yaml:"lock_dir"`
+
+	// KeyPrefix namespaces lease keys in Redis/etcd (default: "devclaw/").
+	KeyPrefix string `yaml:"key_prefix"`
+
+	// Redis configures the redis backend.
+	Redis RedisConfig `yaml:"redis"`
+
+	// Etcd configures the etcd backend.
+	Etcd EtcdConfig `yaml:"etcd"`
+}
+
+// DefaultConfig returns the default coordination configuration.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:      false,
+		Backend:      "file",
+		LeaseSeconds: 15,
+		LockDir:      "./data/locks",
+		KeyPrefix:    "devclaw/",
+	}
+}
+
+// Lease is a named, expiring lock held by one instance at a time.
+type Lease interface {
+	// TryAcquire takes the lease for owner, or renews it if owner already
+	// holds it. It returns whether owner holds the lease afterwards and the
+	// current holder's identity.
+	TryAcquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, string, error)
+
+	// Release gives up the lease if owner holds it.
+	Release(ctx context.Context, key, owner string) error
+
+	// Close releases backend resources.
+	Close() error
+}
+
+// Claimer is implemented by lease stores that can take one-shot claims: a
+// key that is set once and expires after ttl, with no owner to renew it.
+// The file backend holds one open lock per key and does not support them.
+type Claimer interface {
+	// Claim sets key if it is absent and reports whether it did.
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Ownership describes who owns a lease key, as seen by this instance.
+type Ownership struct {
+	Key    string    `json:"key"`
+	Holder string    `json:"holder,omitempty"`
+	Leader bool      `json:"leader"`
+	Since  time.Time `json:"since,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Coordinator runs leader elections for named keys on top of a Lease.
+type Coordinator struct {
+	cfg    Config
+	id     string
+	lease  Lease
+	ttl    time.Duration
+	renew  time.Duration
+	logger *slog.Logger
+
+	mu     sync.RWMutex
+	status map[string]*Ownership
+}
+
+// New creates a coordinator for the configured backend.
+func New(cfg Config, logger *slog.Logger) (*Coordinator, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	def := DefaultConfig()
+	if cfg.LeaseSeconds <= 0 {
+		cfg.LeaseSeconds = def.LeaseSeconds
+	}
+	if cfg.RenewSeconds <= 0 || cfg.RenewSeconds >= cfg.LeaseSeconds {
+		cfg.RenewSeconds = max(cfg.LeaseSeconds/3, 1)
+	}
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = def.KeyPrefix
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = defaultInstanceID()
+	}
+
+	var lease Lease
+	var err error
+	switch strings.ToLower(cfg.Backend) {
+	case "", "file":
+		dir := cfg.LockDir
+		if dir == "" {
+			dir = def.LockDir
+		}
+		lease, err = NewFileLease(dir)
+	case "redis":
+		lease, err = NewRedisLease(cfg.Redis)
+	case "etcd":
+		lease, err = NewEtcdLease(cfg.Etcd)
+	default:
+		err = fmt.Errorf("unknown coordination backend %q (use file, redis or etcd)", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Coordinator{
+		cfg:    cfg,
+		id:     cfg.InstanceID,
+		lease:  lease,
+		ttl:    time.Duration(cfg.LeaseSeconds) * time.Second,
+		renew:  time.Duration(cfg.RenewSeconds) * time.Second,
+		logger: logger.With("component", "coordination", "instance", cfg.InstanceID),
+		status: make(map[string]*Ownership),
+	}, nil
+}
+
+// NewWithLease creates a coordinator around an existing lease (used by tests
+// and embedders with their own lease store).
+func NewWithLease(id string, lease Lease, ttl, renew time.Duration, logger *slog.Logger) *Coordinator {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Coordinator{
+		id:     id,
+		lease:  lease,
+		ttl:    ttl,
+		renew:  renew,
+		logger: logger.With("component", "coordination", "instance", id),
+		status: make(map[string]*Ownership),
+	}
+}
+
+// InstanceID returns this instance's identity.
+func (c *Coordinator) InstanceID() string { return c.id }
+
+// Campaign competes for key until ctx is cancelled. onElected is called each
+// time this instance becomes leader, with a context cancelled on demotion;
+// onDemoted is called when leadership is lost. On shutdown the lease is
+// released so a standby can take over without waiting for expiry.
+func (c *Coordinator) Campaign(ctx context.Context, key string, onElected func(ctx context.Context), onDemoted func()) {
+	fullKey := c.cfg.KeyPrefix + key
+	c.setStatus(key, func(o *Ownership) {})
+
+	var (
+		leader      bool
+		leaderCtx   context.Context
+		leaderStop  context.CancelFunc
+		lastRenewed time.Time
+		lastHolder  string
+	)
+
+	demote := func(reason string, holder string) {
+		leader = false
+		if leaderStop != nil {
+			leaderStop()
+		}
+		c.logger.Warn("lost leadership, switching to standby",
+			"key", key, "reason", reason, "holder", holder)
+		c.setStatus(key, func(o *Ownership) {
+			o.Leader = false
+			o.Holder = holder
+			o.Since = time.Now()
+		})
+		if onDemoted != nil {
+			onDemoted()
+		}
+	}
+
+	ticker := time.NewTicker(c.renew)
+	defer ticker.Stop()
+
+	// A leader steps down one renew interval before its lease could lapse,
+	// so a standby can never take over while it still acts as leader.
+	hold := c.ttl - c.renew
+	if hold <= 0 {
+		hold = c.ttl / 2
+	}
+
+	for {
+		// Renewals are timed from before the call: the store may have set the
+		// expiry any time after that. A leader's call must also answer
+		// before it would have to step down.
+		attempt := time.Now()
+		acqCtx, acqCancel := ctx, context.CancelFunc(func() {})
+		if leader {
+			acqCtx, acqCancel = context.WithDeadline(ctx, lastRenewed.Add(hold))
+		}
+		acquired, holder, err := c.lease.TryAcquire(acqCtx, fullKey, c.id, c.ttl)
+		acqCancel()
+		switch {
+		case ctx.Err() != nil:
+			// Shutting down; handled below.
+
+		case err != nil:
+			c.logger.Warn("lease check failed", "key", key, "error", err)
+			c.setStatus(key, func(o *Ownership) { o.Error = err.Error() })
+			// We can no longer prove we hold the lease once it could
+			// expire: step down rather than risk double replies.
+			if leader && time.Since(lastRenewed) >= hold {
+				demote("lease store unreachable", "")
+			}
+
+		case acquired:
+			lastRenewed = attempt
+			if !leader {
+				leader = true
+				leaderCtx, leaderStop = context.WithCancel(ctx)
+				if lastHolder != "" && lastHolder != c.id {
+					c.logger.Info("took over leadership", "key", key, "previous_holder", lastHolder)
+				} else {
+					c.logger.Info("acquired leadership", "key", key)
+				}
+				c.setStatus(key, func(o *Ownership) {
+					o.Leader = true
+					o.Holder = c.id
+					o.Since = lastRenewed
+					o.Error = ""
+				})
+				if onElected != nil {
+					onElected(leaderCtx)
+				}
+			} else {
+				c.setStatus(key, func(o *Ownership) { o.Error = "" })
+			}
+			lastHolder = c.id
+
+		default:
+			if leader {
+				demote("lease taken by another instance", holder)
+			} else if holder != lastHolder {
+				c.logger.Info("standing by, key owned by another instance", "key", key, "holder", holder)
+				c.setStatus(key, func(o *Ownership) {
+					o.Leader = false
+					o.Holder = holder
+					o.Since = time.Now()
+					o.Error = ""
+				})
+			}
+			lastHolder = holder
+		}
+
+		select {
+		case <-ctx.Done():
+			if leaderStop != nil {
+				leaderStop()
+			}
+			if leader {
+				relCtx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+				if err := c.lease.Release(relCtx, fullKey, c.id); err != nil {
+					c.logger.Warn("failed to release lease", "key", key, "error", err)
+				} else {
+					c.logger.Info("released leadership", "key", key)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Claim takes key for ttl and reports whether it was free. Unlike Campaign,
+// a claim is never renewed or released: it simply expires, so a second Claim
+// for the same key within ttl fails on every instance sharing the lease
+// store. Backends without Claimer support (file) always succeed.
+func (c *Coordinator) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	cl, ok := c.lease.(Claimer)
+	if !ok {
+		return true, nil
+	}
+	return cl.Claim(ctx, c.cfg.KeyPrefix+"claims/"+key, ttl)
+}
+
+// Status returns the ownership of every key this instance campaigns for.
+func (c *Coordinator) Status() []Ownership {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]Ownership, 0, len(c.status))
+	for _, o := range c.status {
+		out = append(out, *o)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Close releases the lease backend.
+func (c *Coordinator) Close() error {
+	return c.lease.Close()
+}
+
+func (c *Coordinator) setStatus(key string, fn func(o *Ownership)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	o, ok := c.status[key]
+	if !ok {
+		o = &Ownership{Key: key}
+		c.status[key] = o
+	}
+	fn(o)
+}
+
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "devclaw"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
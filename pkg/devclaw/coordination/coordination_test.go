@@ -0,0 +1,73 @@
+package coordination
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyLease grants the lease until failing is set, then hangs until the
+// caller gives up, like an unreachable store.
+type flakyLease struct {
+	mu        sync.Mutex
+	failing   bool
+	lastStart time.Time // start of the last successful acquire
+}
+
+func (l *flakyLease) TryAcquire(ctx context.Context, _, owner string, _ time.Duration) (bool, string, error) {
+	start := time.Now()
+	l.mu.Lock()
+	failing := l.failing
+	l.mu.Unlock()
+	if failing {
+		<-ctx.Done()
+		return false, "", ctx.Err()
+	}
+	l.mu.Lock()
+	l.lastStart = start
+	l.mu.Unlock()
+	return true, owner, nil
+}
+
+func (l *flakyLease) Release(context.Context, string, string) error { return nil }
+func (l *flakyLease) Close() error                                  { return nil }
+
+func TestCampaign_DemotesBeforeLeaseExpires(t *testing.T) {
+	t.Parallel()
+	const ttl, renew = 300 * time.Millisecond, 100 * time.Millisecond
+	lease := &flakyLease{}
+	c := NewWithLease("a", lease, ttl, renew, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	elected := make(chan struct{}, 1)
+	demoted := make(chan time.Time, 1)
+	go c.Campaign(ctx, "telegram",
+		func(context.Context) { elected <- struct{}{} },
+		func() { demoted <- time.Now() })
+
+	<-elected
+	time.Sleep(150 * time.Millisecond)
+	lease.mu.Lock()
+	lease.failing = true
+	lease.mu.Unlock()
+
+	select {
+	case at := <-demoted:
+		lease.mu.Lock()
+		renewed := lease.lastStart
+		lease.mu.Unlock()
+		// A standby may take over once the lease expires, ttl after the
+		// last renewal started: the leader must be gone by then.
+		if held := at.Sub(renewed); held >= ttl {
+			t.Errorf("demoted %v after the last renewal, want < %v", held, ttl)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("leader never stepped down")
+	}
+	if st := c.Status(); len(st) != 1 || st[0].Leader {
+		t.Errorf("status = %+v", st)
+	}
+}
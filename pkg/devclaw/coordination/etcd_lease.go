@@ -0,0 +1,285 @@
+package coordination
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EtcdConfig configures the etcd lease backend.
+type EtcdConfig struct {
+	// Endpoint is the etcd client URL (default: http://localhost:2379).
+	Endpoint string `yaml:"endpoint"`
+
+	// Username and Password enable etcd authentication.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// EtcdLease implements Lease with etcd v3 leases, talking to the JSON
+// gateway (/v3/...) so no gRPC client is needed. A key is owned by whoever
+// created it; it disappears when the owner's lease expires or is revoked.
+type EtcdLease struct {
+	endpoint string
+	cfg      EtcdConfig
+	client   *http.Client
+
+	// mu guards token and leases; it is never held across HTTP calls, so a
+	// slow endpoint can't stall the other keys.
+	mu     sync.Mutex
+	token  string
+	leases map[string]string // key -> lease ID held by this instance
+}
+
+// NewEtcdLease creates an etcd lease store.
+func NewEtcdLease(cfg EtcdConfig) (*EtcdLease, error) {
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = "http://localhost:2379"
+	}
+	return &EtcdLease{
+		endpoint: endpoint,
+		cfg:      cfg,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		leases:   make(map[string]string),
+	}, nil
+}
+
+// TryAcquire implements Lease.
+func (l *EtcdLease) TryAcquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, string, error) {
+	l.mu.Lock()
+	id, ok := l.leases[key]
+	l.mu.Unlock()
+
+	// Already ours: keep the lease alive.
+	if ok {
+		var ka struct {
+			Result struct {
+				TTL string `json:"TTL"`
+			} `json:"result"`
+		}
+		if err := l.call(ctx, "/v3/lease/keepalive", map[string]any{"ID": id}, &ka); err != nil {
+			return false, "", err
+		}
+		if n, _ := strconv.ParseInt(ka.Result.TTL, 10, 64); n > 0 {
+			return true, owner, nil
+		}
+		// Lease expired behind our back; compete again.
+		l.forget(key, id)
+	}
+
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	secs := int64(ttl.Seconds())
+	if secs < 1 {
+		secs = 1
+	}
+	if err := l.call(ctx, "/v3/lease/grant", map[string]any{"TTL": secs}, &grant); err != nil {
+		return false, "", err
+	}
+
+	k := b64(key)
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+		Responses []struct {
+			ResponseRange struct {
+				Kvs []struct {
+					Value string `json:"value"`
+				} `json:"kvs"`
+			} `json:"response_range"`
+		} `json:"responses"`
+	}
+	req := map[string]any{
+		"compare": []any{map[string]any{
+			"key": k, "target": "CREATE", "result": "EQUAL", "create_revision": "0",
+		}},
+		"success": []any{map[string]any{
+			"request_put": map[string]any{"key": k, "value": b64(owner), "lease": grant.ID},
+		}},
+		"failure": []any{map[string]any{
+			"request_range": map[string]any{"key": k},
+		}},
+	}
+	if err := l.call(ctx, "/v3/kv/txn", req, &txn); err != nil {
+		l.revoke(ctx, grant.ID)
+		return false, "", err
+	}
+	if txn.Succeeded {
+		l.mu.Lock()
+		l.leases[key] = grant.ID
+		l.mu.Unlock()
+		return true, owner, nil
+	}
+
+	l.revoke(ctx, grant.ID)
+	holder := ""
+	if len(txn.Responses) > 0 && len(txn.Responses[0].ResponseRange.Kvs) > 0 {
+		if v, err := base64.StdEncoding.DecodeString(txn.Responses[0].ResponseRange.Kvs[0].Value); err == nil {
+			holder = string(v)
+		}
+	}
+	return false, holder, nil
+}
+
+// Claim implements Claimer: the key is put under a fresh lease of ttl that
+// is never kept alive, so etcd deletes it on expiry.
+func (l *EtcdLease) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	secs := max(int64(ttl.Seconds()), 1)
+	if err := l.call(ctx, "/v3/lease/grant", map[string]any{"TTL": secs}, &grant); err != nil {
+		return false, err
+	}
+	k := b64(key)
+	var txn struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	req := map[string]any{
+		"compare": []any{map[string]any{
+			"key": k, "target": "CREATE", "result": "EQUAL", "create_revision": "0",
+		}},
+		"success": []any{map[string]any{
+			"request_put": map[string]any{"key": k, "value": b64("1"), "lease": grant.ID},
+		}},
+	}
+	if err := l.call(ctx, "/v3/kv/txn", req, &txn); err != nil {
+		l.revoke(ctx, grant.ID)
+		return false, err
+	}
+	if !txn.Succeeded {
+		l.revoke(ctx, grant.ID)
+	}
+	return txn.Succeeded, nil
+}
+
+// Release implements Lease. Revoking the lease deletes the key.
+func (l *EtcdLease) Release(ctx context.Context, key, _ string) error {
+	l.mu.Lock()
+	id, ok := l.leases[key]
+	delete(l.leases, key)
+	l.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": id}, nil)
+}
+
+// Close revokes all leases held by this instance.
+func (l *EtcdLease) Close() error {
+	l.mu.Lock()
+	ids := make([]string, 0, len(l.leases))
+	for key, id := range l.leases {
+		ids = append(ids, id)
+		delete(l.leases, key)
+	}
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, id := range ids {
+		l.revoke(ctx, id)
+	}
+	return nil
+}
+
+// forget drops key's lease if it is still id.
+func (l *EtcdLease) forget(key, id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leases[key] == id {
+		delete(l.leases, key)
+	}
+}
+
+func (l *EtcdLease) revoke(ctx context.Context, id string) {
+	_ = l.call(ctx, "/v3/lease/revoke", map[string]any{"ID": id}, nil)
+}
+
+// call POSTs a JSON request to the gateway, authenticating first if needed.
+func (l *EtcdLease) call(ctx context.Context, path string, body any, out any) error {
+	token, err := l.authToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = l.post(ctx, path, token, body, out)
+	if err != nil && token != "" && strings.Contains(err.Error(), "invalid auth token") {
+		l.mu.Lock()
+		if l.token == token {
+			l.token = ""
+		}
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// authToken returns the auth token, authenticating when there is none yet.
+// Returns "" without credentials.
+func (l *EtcdLease) authToken(ctx context.Context) (string, error) {
+	if l.cfg.Username == "" {
+		return "", nil
+	}
+	l.mu.Lock()
+	token := l.token
+	l.mu.Unlock()
+	if token != "" {
+		return token, nil
+	}
+
+	var auth struct {
+		Token string `json:"token"`
+	}
+	creds := map[string]string{"name": l.cfg.Username, "password": l.cfg.Password}
+	if err := l.post(ctx, "/v3/auth/authenticate", "", creds, &auth); err != nil {
+		return "", fmt.Errorf("etcd: authenticate: %w", err)
+	}
+	l.mu.Lock()
+	l.token = auth.Token
+	l.mu.Unlock()
+	return auth.Token, nil
+}
+
+func (l *EtcdLease) post(ctx context.Context, path, token string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("etcd: %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd: %s: %s: %s", path, resp.Status, strings.TrimSpace(string(raw)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("etcd: %s: decoding response: %w", path, err)
+	}
+	return nil
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
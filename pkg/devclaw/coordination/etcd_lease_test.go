@@ -0,0 +1,202 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEtcd serves the subset of the etcd v3 JSON gateway EtcdLease uses.
+type fakeEtcd struct {
+	mu      sync.Mutex
+	nextID  int
+	leases  map[string]time.Time // lease ID -> expiry
+	keys    map[string][2]string // key -> {value, lease ID}
+	token   string
+	hold    chan struct{} // when set, txn calls for holdKey wait on it
+	holdKey string
+}
+
+func newFakeEtcd(t *testing.T, token string) (*fakeEtcd, *httptest.Server) {
+	t.Helper()
+	f := &fakeEtcd{leases: map[string]time.Time{}, keys: map[string][2]string{}, token: token}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeEtcd) serve(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	token := f.token
+	f.mu.Unlock()
+	if r.URL.Path == "/v3/auth/authenticate" {
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+		return
+	}
+	if token != "" && r.Header.Get("Authorization") != token {
+		http.Error(w, `{"error":"etcdserver: invalid auth token"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Path == "/v3/kv/txn" {
+		f.mu.Lock()
+		hold, holdKey := f.hold, f.holdKey
+		f.mu.Unlock()
+		cmp := body["compare"].([]any)[0].(map[string]any)
+		if hold != nil && cmp["key"] == b64(holdKey) {
+			<-hold
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expireLocked()
+	out := map[string]any{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		ttl := body["TTL"].(float64)
+		f.leases[id] = time.Now().Add(time.Duration(ttl * float64(time.Second)))
+		out["ID"], out["TTL"] = id, strconv.Itoa(int(ttl))
+	case "/v3/lease/keepalive":
+		id := body["ID"].(string)
+		ttl := "0"
+		if _, ok := f.leases[id]; ok {
+			f.leases[id] = time.Now().Add(time.Second)
+			ttl = "1"
+		}
+		out["result"] = map[string]any{"ID": id, "TTL": ttl}
+	case "/v3/lease/revoke":
+		f.revokeLocked(body["ID"].(string))
+	case "/v3/kv/txn":
+		cmp := body["compare"].([]any)[0].(map[string]any)
+		key := cmp["key"].(string)
+		if kv, exists := f.keys[key]; exists {
+			out["succeeded"] = false
+			out["responses"] = []any{map[string]any{"response_range": map[string]any{
+				"kvs": []any{map[string]any{"value": kv[0]}},
+			}}}
+		} else {
+			put := body["success"].([]any)[0].(map[string]any)["request_put"].(map[string]any)
+			f.keys[key] = [2]string{put["value"].(string), put["lease"].(string)}
+			out["succeeded"] = true
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+func (f *fakeEtcd) expireLocked() {
+	for id, exp := range f.leases {
+		if time.Now().After(exp) {
+			f.revokeLocked(id)
+		}
+	}
+}
+
+func (f *fakeEtcd) revokeLocked(id string) {
+	delete(f.leases, id)
+	for k, kv := range f.keys {
+		if kv[1] == id {
+			delete(f.keys, k)
+		}
+	}
+}
+
+func TestEtcdLease(t *testing.T) {
+	t.Parallel()
+	fake, srv := newFakeEtcd(t, "tok-1")
+	ctx := context.Background()
+	cfg := EtcdConfig{Endpoint: srv.URL + "/", Username: "devclaw", Password: "pw"}
+	a, _ := NewEtcdLease(cfg)
+	b, _ := NewEtcdLease(cfg)
+
+	if ok, holder, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok || holder != "a" || err != nil {
+		t.Fatalf("a acquire = %v %q %v", ok, holder, err)
+	}
+	if ok, holder, err := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); ok || holder != "a" || err != nil {
+		t.Errorf("b acquire = %v %q %v, want held by a", ok, holder, err)
+	}
+	if ok, _, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok || err != nil {
+		t.Errorf("a renew = %v %v", ok, err)
+	}
+
+	// A token the server no longer accepts is dropped and renewed.
+	fake.mu.Lock()
+	fake.token = "tok-2"
+	fake.mu.Unlock()
+	if _, _, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); err == nil {
+		t.Error("expected an error with the stale token")
+	}
+	if ok, _, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok || err != nil {
+		t.Errorf("after re-authenticating = %v %v", ok, err)
+	}
+	if _, _, err := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); err == nil {
+		t.Error("expected an error with b's stale token")
+	}
+
+	if err := a.Release(ctx, "devclaw/telegram", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, holder, _ := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); !ok || holder != "b" {
+		t.Errorf("b should take over after release, holder = %q", holder)
+	}
+
+	if ok, err := a.Claim(ctx, "devclaw/claims/msg-1", time.Minute); !ok || err != nil {
+		t.Errorf("first claim = %v %v", ok, err)
+	}
+	if ok, _ := b.Claim(ctx, "devclaw/claims/msg-1", time.Minute); ok {
+		t.Error("second claim should fail")
+	}
+
+	b.Close()
+	if ok, _, _ := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok {
+		t.Error("Close should revoke b's leases")
+	}
+	a.Close()
+}
+
+func TestEtcdLease_SlowCallDoesNotBlockOtherKeys(t *testing.T) {
+	t.Parallel()
+	fake, srv := newFakeEtcd(t, "")
+	hold := make(chan struct{})
+	fake.hold, fake.holdKey = hold, "devclaw/slow"
+	l, _ := NewEtcdLease(EtcdConfig{Endpoint: srv.URL})
+	defer l.Close()
+
+	slow := make(chan error, 1)
+	go func() {
+		_, _, err := l.TryAcquire(context.Background(), "devclaw/slow", "a", time.Second)
+		slow <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan bool, 1)
+	go func() {
+		ok, _, _ := l.TryAcquire(context.Background(), "devclaw/fast", "a", time.Second)
+		done <- ok
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("fast key not acquired")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a slow call on one key blocked another key")
+	}
+	close(hold)
+	if err := <-slow; err != nil {
+		t.Errorf("slow acquire: %v", err)
+	}
+}
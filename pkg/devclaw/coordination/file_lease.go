@@ -0,0 +1,109 @@
+//go:build !windows
+
+package coordination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileLease implements Lease with an exclusive flock per key. The lock is
+// held for as long as this process leads, and the kernel drops it if the
+// process dies, so no expiry is needed. The holder's identity is written
+// into the lock file for standbys to report.
+type FileLease struct {
+	dir string
+
+	mu   sync.Mutex
+	held map[string]*os.File
+}
+
+// NewFileLease creates a file lease store rooted at dir.
+func NewFileLease(dir string) (*FileLease, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating lock dir: %w", err)
+	}
+	return &FileLease{dir: dir, held: make(map[string]*os.File)}, nil
+}
+
+// TryAcquire implements Lease.
+func (l *FileLease) TryAcquire(_ context.Context, key, owner string, _ time.Duration) (bool, string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.held[key]; ok {
+		return true, owner, nil
+	}
+
+	path := l.path(key)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, "", fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder := readHolder(f)
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, holder, nil
+		}
+		return false, holder, fmt.Errorf("locking %s: %w", path, err)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(owner+"\n"), 0)
+		_ = f.Sync()
+	}
+	l.held[key] = f
+	return true, owner, nil
+}
+
+// Release implements Lease.
+func (l *FileLease) Release(_ context.Context, key, _ string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.held[key]
+	if !ok {
+		return nil
+	}
+	delete(l.held, key)
+	_ = f.Truncate(0)
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return f.Close()
+}
+
+// Close releases every lock held by this process.
+func (l *FileLease) Close() error {
+	l.mu.Lock()
+	keys := make([]string, 0, len(l.held))
+	for k := range l.held {
+		keys = append(keys, k)
+	}
+	l.mu.Unlock()
+	for _, k := range keys {
+		_ = l.Release(context.Background(), k, "")
+	}
+	return nil
+}
+
+func (l *FileLease) path(key string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(key)
+	return filepath.Join(l.dir, name+".lock")
+}
+
+func readHolder(f *os.File) string {
+	buf := make([]byte, 256)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+	return strings.TrimSpace(string(buf[:n]))
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package coordination
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFileLease(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a, err := NewFileLease(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewFileLease(dir)
+
+	if ok, holder, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok || holder != "a" || err != nil {
+		t.Fatalf("a acquire = %v %q %v", ok, holder, err)
+	}
+	if ok, _, _ := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok {
+		t.Error("a should renew its own lease")
+	}
+	if ok, holder, err := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); ok || holder != "a" || err != nil {
+		t.Errorf("b acquire = %v %q %v, want held by a", ok, holder, err)
+	}
+	if ok, _, _ := b.TryAcquire(ctx, "devclaw/whatsapp", "b", time.Second); !ok {
+		t.Error("b should take an unrelated key")
+	}
+
+	if err := a.Release(ctx, "devclaw/telegram", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, holder, _ := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); !ok || holder != "b" {
+		t.Errorf("b should take over after release, holder = %q", holder)
+	}
+
+	// Close drops every lock.
+	b.Close()
+	if ok, _, _ := a.TryAcquire(ctx, "devclaw/whatsapp", "a", time.Second); !ok {
+		t.Error("a should take the key b held before closing")
+	}
+	a.Close()
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package coordination
+
+import "fmt"
+
+// NewFileLease is not available on Windows; use the redis or etcd backend.
+func NewFileLease(dir string) (Lease, error) {
+	return nil, fmt.Errorf("file coordination backend is not supported on windows, use redis or etcd")
+}
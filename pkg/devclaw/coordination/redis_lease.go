@@ -0,0 +1,229 @@
+package coordination
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisConfig configures the redis lease backend.
+type RedisConfig struct {
+	// Addr is host:port of the redis server (default: localhost:6379).
+	Addr string `yaml:"addr"`
+
+	// Password for AUTH (supports ${ENV} via config expansion).
+	Password string `yaml:"password"`
+
+	// DB selects the logical database.
+	DB int `yaml:"db"`
+
+	// TLS enables a TLS connection.
+	TLS bool `yaml:"tls"`
+}
+
+// acquireScript takes the lease if free, renews it if already ours, and
+// returns the current holder either way.
+const acquireScript = `
+local v = redis.call('GET', KEYS[1])
+if not v then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return ARGV[1]
+end
+if v == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return v`
+
+// releaseScript deletes the lease only if it is still ours.
+const releaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// RedisLease implements Lease on a single redis server. It speaks just
+// enough RESP to run the lease scripts, so no client library is needed.
+type RedisLease struct {
+	cfg RedisConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// NewRedisLease creates a redis lease store. The connection is opened lazily
+// and re-established after errors.
+func NewRedisLease(cfg RedisConfig) (*RedisLease, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6379"
+	}
+	return &RedisLease{cfg: cfg}, nil
+}
+
+// TryAcquire implements Lease.
+func (l *RedisLease) TryAcquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, string, error) {
+	reply, err := l.do(ctx, "EVAL", acquireScript, "1", key, owner, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, "", err
+	}
+	holder, _ := reply.(string)
+	return holder == owner, holder, nil
+}
+
+// Release implements Lease.
+func (l *RedisLease) Release(ctx context.Context, key, owner string) error {
+	_, err := l.do(ctx, "EVAL", releaseScript, "1", key, owner)
+	return err
+}
+
+// Claim implements Claimer with SET NX PX.
+func (l *RedisLease) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	reply, err := l.do(ctx, "SET", key, "1", "NX", "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil
+}
+
+// Close closes the connection.
+func (l *RedisLease) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.resetLocked()
+}
+
+func (l *RedisLease) do(ctx context.Context, args ...string) (any, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		if err := l.dialLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := l.roundTripLocked(ctx, args...)
+	if err != nil {
+		// Drop the connection; the next call redials.
+		_ = l.resetLocked()
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (l *RedisLease) dialLocked(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var conn net.Conn
+	var err error
+	if l.cfg.TLS {
+		host, _, _ := net.SplitHostPort(l.cfg.Addr)
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", l.cfg.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", l.cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("redis: connecting to %s: %w", l.cfg.Addr, err)
+	}
+	l.conn = conn
+	l.rd = bufio.NewReader(conn)
+
+	if l.cfg.Password != "" {
+		if _, err := l.roundTripLocked(ctx, "AUTH", l.cfg.Password); err != nil {
+			_ = l.resetLocked()
+			return fmt.Errorf("redis: auth: %w", err)
+		}
+	}
+	if l.cfg.DB != 0 {
+		if _, err := l.roundTripLocked(ctx, "SELECT", strconv.Itoa(l.cfg.DB)); err != nil {
+			_ = l.resetLocked()
+			return fmt.Errorf("redis: select db: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l *RedisLease) resetLocked() error {
+	if l.conn == nil {
+		return nil
+	}
+	err := l.conn.Close()
+	l.conn = nil
+	l.rd = nil
+	return err
+}
+
+func (l *RedisLease) roundTripLocked(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(5 * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = l.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := l.conn.Write([]byte(b.String())); err != nil {
+		return nil, fmt.Errorf("redis: write: %w", err)
+	}
+	return readRESP(l.rd)
+}
+
+// readRESP decodes one RESP2 reply. Bulk and simple strings become string,
+// integers int64, nil bulk strings nil, and arrays []any.
+func readRESP(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: read: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, fmt.Errorf("redis: read bulk: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRESP(rd); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,202 @@
+package coordination
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the commands RedisLease sends, keeping keys in memory.
+type fakeRedis struct {
+	t        *testing.T
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{t: t, ln: ln, password: password, values: map[string]string{}, expires: map[string]time.Time{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, it := range items {
+			args[i], _ = it.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		if !authed && args[0] != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		fmt.Fprint(conn, f.exec(args, &authed))
+	}
+}
+
+func bulk(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+
+func (f *fakeRedis) exec(args []string, authed *bool) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	get := func(key string) (string, bool) {
+		if exp, ok := f.expires[key]; ok && time.Now().After(exp) {
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+		v, ok := f.values[key]
+		return v, ok
+	}
+	set := func(key, value, px string) {
+		ms, _ := strconv.Atoi(px)
+		f.values[key] = value
+		f.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != f.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "SET": // SET key value NX PX ms
+		if _, ok := get(args[1]); ok {
+			return "$-1\r\n"
+		}
+		set(args[1], args[2], args[5])
+		return "+OK\r\n"
+	case "EVAL":
+		key, owner := args[3], args[4]
+		v, ok := get(key)
+		switch args[1] {
+		case acquireScript:
+			if !ok {
+				set(key, owner, args[5])
+				return bulk(owner)
+			}
+			if v == owner {
+				set(key, owner, args[5])
+			}
+			return bulk(v)
+		case releaseScript:
+			if ok && v == owner {
+				delete(f.values, key)
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		}
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisLease(t *testing.T) {
+	t.Parallel()
+	srv := newFakeRedis(t, "s3cret")
+	ctx := context.Background()
+	cfg := RedisConfig{Addr: srv.ln.Addr().String(), Password: "s3cret", DB: 2}
+	a, _ := NewRedisLease(cfg)
+	b, _ := NewRedisLease(cfg)
+	defer a.Close()
+	defer b.Close()
+
+	if ok, holder, err := a.TryAcquire(ctx, "devclaw/telegram", "a", 200*time.Millisecond); !ok || holder != "a" || err != nil {
+		t.Fatalf("a acquire = %v %q %v", ok, holder, err)
+	}
+	if ok, holder, _ := b.TryAcquire(ctx, "devclaw/telegram", "b", 200*time.Millisecond); ok || holder != "a" {
+		t.Errorf("b acquire = %v %q, want held by a", ok, holder)
+	}
+	// b's release must not drop a's lease.
+	if err := b.Release(ctx, "devclaw/telegram", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := a.TryAcquire(ctx, "devclaw/telegram", "a", 200*time.Millisecond); !ok {
+		t.Error("a should still hold and renew its lease")
+	}
+
+	// The lease expires when a stops renewing.
+	time.Sleep(250 * time.Millisecond)
+	if ok, holder, _ := b.TryAcquire(ctx, "devclaw/telegram", "b", time.Second); !ok || holder != "b" {
+		t.Errorf("b should take over an expired lease, holder = %q", holder)
+	}
+	if err := b.Release(ctx, "devclaw/telegram", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok {
+		t.Error("a should take the released lease")
+	}
+
+	// Claims succeed once per ttl.
+	if ok, err := a.Claim(ctx, "devclaw/claims/msg-1", time.Minute); !ok || err != nil {
+		t.Errorf("first claim = %v %v", ok, err)
+	}
+	if ok, _ := b.Claim(ctx, "devclaw/claims/msg-1", time.Minute); ok {
+		t.Error("second claim should fail")
+	}
+
+	// A dropped connection is redialed on the next call.
+	a.mu.Lock()
+	a.conn.Close()
+	a.mu.Unlock()
+	if _, _, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); err == nil {
+		t.Error("expected an error on the closed connection")
+	}
+	if ok, _, err := a.TryAcquire(ctx, "devclaw/telegram", "a", time.Second); !ok || err != nil {
+		t.Errorf("after redial = %v %v", ok, err)
+	}
+
+	bad, _ := NewRedisLease(RedisConfig{Addr: srv.ln.Addr().String(), Password: "wrong"})
+	defer bad.Close()
+	if _, _, err := bad.TryAcquire(ctx, "devclaw/x", "c", time.Second); err == nil || !strings.Contains(err.Error(), "auth") {
+		t.Errorf("wrong password err = %v", err)
+	}
+}
+
+func TestReadRESP(t *testing.T) {
+	t.Parallel()
+	rd := bufio.NewReader(strings.NewReader("*3\r\n+OK\r\n:42\r\n$-1\r\n-ERR boom\r\n"))
+	v, err := readRESP(rd)
+	arr, _ := v.([]any)
+	if err != nil || len(arr) != 3 || arr[0] != "OK" || arr[1] != int64(42) || arr[2] != nil {
+		t.Errorf("array = %#v, %v", v, err)
+	}
+	if _, err := readRESP(rd); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("error reply = %v", err)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels/webhook"
 )
 
 // AccessLevel defines the permission level of a contact.
@@ -103,6 +104,14 @@ type AccessEntry struct {
 
 	// Note is an optional admin note about this contact.
 	Note string
+
+	// ExpiresAt is set for temporary grants (/grant); the entry reverts to
+	// Previous when it passes.
+	ExpiresAt time.Time
+
+	// Previous is the entry a temporary grant replaced (nil if the contact
+	// had no entry before).
+	Previous *AccessEntry
 }
 
 // AccessManager handles access control for incoming messages.
@@ -118,6 +127,12 @@ type AccessManager struct {
 	// to avoid spamming them.
 	askedOnce map[string]time.Time
 
+	// grantHook is notified when temporary grants start and expire.
+	grantHook GrantHook
+
+	// events records admin changes in the state event log (nil = off).
+	events *StateEventLog
+
 	mu sync.RWMutex
 }
 
@@ -228,6 +243,15 @@ func (am *AccessManager) Check(msg *channels.IncomingMessage) CheckResult {
 		}
 	}
 
+	// 2b. Webhook events were authenticated by their signature and carry
+	// the hook's configured level (never owner).
+	if msg.Channel == webhook.ChannelName {
+		if level, _ := msg.Metadata[webhook.MetaAccess].(string); level == string(AccessAdmin) {
+			return CheckResult{Allowed: true, Level: AccessAdmin}
+		}
+		return CheckResult{Allowed: true, Level: AccessUser}
+	}
+
 	// 3. Check if sender has explicit access.
 	if entry, ok := am.users[from]; ok {
 		if entry.Level == AccessOwner || entry.Level == AccessAdmin || entry.Level == AccessUser {
@@ -304,6 +328,7 @@ func (am *AccessManager) Grant(jid string, level AccessLevel, grantedBy string)
 
 	am.logger.Info("access granted",
 		"jid", norm, "level", level, "by", grantedBy)
+	am.events.Emit(EventAccessGrant, grantedBy, norm, map[string]any{"level": string(level)})
 	return nil
 }
 
@@ -323,6 +348,7 @@ func (am *AccessManager) GrantGroup(groupJID string, level AccessLevel, grantedB
 
 	am.logger.Info("group access granted",
 		"group", norm, "level", level, "by", grantedBy)
+	am.events.Emit(EventAccessGroupGrant, grantedBy, norm, map[string]any{"level": string(level)})
 	return nil
 }
 
@@ -334,6 +360,7 @@ func (am *AccessManager) Revoke(jid string, revokedBy string) {
 	norm := normalizeJID(jid)
 	delete(am.users, norm)
 	am.logger.Info("access revoked", "jid", norm, "by", revokedBy)
+	am.events.Emit(EventAccessRevoke, revokedBy, norm, nil)
 }
 
 // Block explicitly blocks a contact.
@@ -350,6 +377,7 @@ func (am *AccessManager) Block(jid string, blockedBy string) {
 	}
 
 	am.logger.Info("user blocked", "jid", norm, "by", blockedBy)
+	am.events.Emit(EventAccessBlock, blockedBy, norm, nil)
 }
 
 // Unblock removes a block from a contact.
@@ -361,6 +389,7 @@ func (am *AccessManager) Unblock(jid string, unblockedBy string) {
 	if entry, ok := am.users[norm]; ok && entry.Level == AccessBlocked {
 		delete(am.users, norm)
 		am.logger.Info("user unblocked", "jid", norm, "by", unblockedBy)
+		am.events.Emit(EventAccessUnblock, unblockedBy, norm, nil)
 	}
 }
 
@@ -412,10 +441,23 @@ func (am *AccessManager) ListGroups() []*AccessEntry {
 
 // ApplyConfig updates access config from hot-reload. Re-seeds config-derived
 // entries (owners, admins, allowed, blocked, groups). Runtime grants (AddedBy != "config")
-// are preserved.
+// are preserved; temporary grants are dropped when the new config blocks the
+// contact or already gives it at least the granted level.
 func (am *AccessManager) ApplyConfig(cfg AccessConfig) {
 	am.mu.Lock()
-	defer am.mu.Unlock()
+
+	// Temporary grants survive the reload; they are re-applied on top of
+	// the new config entries below.
+	temps := make(map[string]*AccessEntry)
+	for jid, e := range am.users {
+		if !e.ExpiresAt.IsZero() {
+			temps[jid] = e
+			delete(am.users, jid)
+			if e.Previous != nil && e.Previous.AddedBy != "config" {
+				am.users[jid] = e.Previous
+			}
+		}
+	}
 
 	// Remove config-derived entries only.
 	for jid, e := range am.users {
@@ -469,6 +511,24 @@ func (am *AccessManager) ApplyConfig(cfg AccessConfig) {
 		}
 	}
 
+	var dropped []AccessEntry
+	for jid, e := range temps {
+		if grantSuperseded(am.users[jid], e.Level) {
+			dropped = append(dropped, *e)
+			continue
+		}
+		e.Previous = am.users[jid]
+		am.users[jid] = e
+	}
+	hook := am.grantHook
+	am.mu.Unlock()
+
+	for _, e := range dropped {
+		am.logger.Info("temporary access dropped by config reload", "jid", e.JID, "level", e.Level)
+		if hook != nil {
+			hook(GrantEventExpired, e)
+		}
+	}
 	am.logger.Info("access config hot-reloaded",
 		"policy", cfg.DefaultPolicy,
 		"owners", len(cfg.Owners),
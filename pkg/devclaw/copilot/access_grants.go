@@ -0,0 +1,183 @@
+// Package copilot – access_grants.go implements time-limited access
+// elevation. An owner can run "/grant admin <contact> 2h" during an incident;
+// the contact's previous entry is restored automatically when the grant
+// expires. Grants live in the AccessManager hot state, so they apply to the
+// next message without a restart.
+package copilot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxGrantDuration caps how long a temporary grant can last.
+const MaxGrantDuration = 30 * 24 * time.Hour
+
+// GrantEvent identifies a temporary grant lifecycle event.
+type GrantEvent string
+
+const (
+	GrantEventGranted GrantEvent = "granted"
+	GrantEventExpired GrantEvent = "expired"
+)
+
+// GrantHook is called when a temporary grant starts or expires.
+type GrantHook func(event GrantEvent, entry AccessEntry)
+
+// SetGrantHook registers a callback for temporary grant events (used for
+// audit logging).
+func (am *AccessManager) SetGrantHook(hook GrantHook) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.grantHook = hook
+}
+
+// SetEventLog records admin access changes in the state event log.
+func (am *AccessManager) SetEventLog(events *StateEventLog) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.events = events
+}
+
+// GrantTemporary elevates a contact to level for the given duration. The
+// contact's current entry is restored when the grant expires; granting again
+// while a grant is active extends it and keeps the original entry.
+func (am *AccessManager) GrantTemporary(jid string, level AccessLevel, d time.Duration, grantedBy string) (AccessEntry, error) {
+	if level != AccessAdmin && level != AccessUser {
+		return AccessEntry{}, fmt.Errorf("temporary grants support admin or user level, not %q", level)
+	}
+	if d < time.Minute || d > MaxGrantDuration {
+		return AccessEntry{}, fmt.Errorf("duration must be between 1m and %s", formatGrantDuration(MaxGrantDuration))
+	}
+
+	norm := normalizeJID(jid)
+	now := time.Now()
+
+	am.mu.Lock()
+	prev := am.users[norm]
+	if prev != nil && !prev.ExpiresAt.IsZero() {
+		prev = prev.Previous
+	}
+	if prev != nil {
+		switch prev.Level {
+		case AccessOwner:
+			am.mu.Unlock()
+			return AccessEntry{}, fmt.Errorf("%s is an owner", norm)
+		case AccessBlocked:
+			am.mu.Unlock()
+			return AccessEntry{}, fmt.Errorf("%s is blocked, unblock first", norm)
+		case AccessAdmin:
+			am.mu.Unlock()
+			return AccessEntry{}, fmt.Errorf("%s is already an admin", norm)
+		case level:
+			am.mu.Unlock()
+			return AccessEntry{}, fmt.Errorf("%s already has %s access", norm, level)
+		}
+	}
+
+	entry := &AccessEntry{
+		JID:       norm,
+		Level:     level,
+		AddedBy:   grantedBy,
+		AddedAt:   now,
+		ExpiresAt: now.Add(d),
+		Previous:  prev,
+	}
+	am.users[norm] = entry
+	delete(am.askedOnce, norm)
+	hook := am.grantHook
+	am.mu.Unlock()
+
+	time.AfterFunc(d, func() { am.expireGrant(entry) })
+
+	am.logger.Info("temporary access granted",
+		"jid", norm, "level", level, "by", grantedBy, "expires_at", entry.ExpiresAt)
+	if hook != nil {
+		hook(GrantEventGranted, *entry)
+	}
+	return *entry, nil
+}
+
+// grantSuperseded reports whether a temporary grant of level over prev is
+// pointless or not allowed: prev is an owner, blocked, an admin, or already
+// at level.
+func grantSuperseded(prev *AccessEntry, level AccessLevel) bool {
+	if prev == nil {
+		return false
+	}
+	switch prev.Level {
+	case AccessOwner, AccessBlocked, AccessAdmin, level:
+		return true
+	}
+	return false
+}
+
+// ListGrants returns the active temporary grants, soonest expiry first.
+func (am *AccessManager) ListGrants() []AccessEntry {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	var out []AccessEntry
+	for _, e := range am.users {
+		if !e.ExpiresAt.IsZero() {
+			out = append(out, *e)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ExpiresAt.Before(out[j].ExpiresAt) })
+	return out
+}
+
+// expireGrant restores the entry a temporary grant replaced. It is a no-op
+// if the grant was already replaced (re-granted, revoked, blocked).
+func (am *AccessManager) expireGrant(entry *AccessEntry) {
+	am.mu.Lock()
+	if am.users[entry.JID] != entry {
+		am.mu.Unlock()
+		return
+	}
+	if entry.Previous != nil {
+		am.users[entry.JID] = entry.Previous
+	} else {
+		delete(am.users, entry.JID)
+	}
+	hook := am.grantHook
+	am.mu.Unlock()
+
+	am.logger.Info("temporary access expired", "jid", entry.JID, "level", entry.Level)
+	if hook != nil {
+		hook(GrantEventExpired, *entry)
+	}
+}
+
+// parseGrantDuration parses durations like "30m", "2h", "1h30m" or "3d".
+func parseGrantDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 30m, 2h, 1d)", s)
+	}
+	return d, nil
+}
+
+// formatGrantDuration renders a duration compactly (e.g. "1h30m", "2d").
+func formatGrantDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	s := strings.TrimSuffix(d.String(), "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -1,9 +1,18 @@
 package copilot
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels/webhook"
 )
 
 func makeMsg(from, chatID string, isGroup bool) *channels.IncomingMessage {
@@ -222,3 +231,200 @@ func TestAccess_BlockedUserOverridesGroupAllow(t *testing.T) {
 		t.Error("blocked user should be denied even in allowed group")
 	}
 }
+
+func TestAccess_TemporaryGrantExpires(t *testing.T) {
+	t.Parallel()
+	am := NewAccessManager(AccessConfig{
+		AllowedUsers:  []string{"user@s.whatsapp.net"},
+		DefaultPolicy: PolicyDeny,
+	}, nil)
+
+	var events []GrantEvent
+	am.SetGrantHook(func(ev GrantEvent, _ AccessEntry) { events = append(events, ev) })
+
+	entry, err := am.GrantTemporary("user@s.whatsapp.net", AccessAdmin, 2*time.Hour, "owner")
+	if err != nil {
+		t.Fatalf("GrantTemporary: %v", err)
+	}
+	if got := am.GetLevel("user@s.whatsapp.net"); got != AccessAdmin {
+		t.Fatalf("level during grant = %v, want admin", got)
+	}
+	if grants := am.ListGrants(); len(grants) != 1 || grants[0].JID != entry.JID {
+		t.Fatalf("ListGrants = %+v", grants)
+	}
+
+	am.mu.RLock()
+	live := am.users[entry.JID]
+	am.mu.RUnlock()
+	am.expireGrant(live)
+
+	if got := am.GetLevel("user@s.whatsapp.net"); got != AccessUser {
+		t.Errorf("level after expiry = %v, want user", got)
+	}
+	if len(am.ListGrants()) != 0 {
+		t.Error("grant should be gone after expiry")
+	}
+	if len(events) != 2 || events[0] != GrantEventGranted || events[1] != GrantEventExpired {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestAccess_TemporaryGrantRejected(t *testing.T) {
+	t.Parallel()
+	am := NewAccessManager(AccessConfig{
+		Owners:        []string{"owner@s.whatsapp.net"},
+		Admins:        []string{"admin@s.whatsapp.net"},
+		BlockedUsers:  []string{"bad@s.whatsapp.net"},
+		DefaultPolicy: PolicyDeny,
+	}, nil)
+
+	cases := []struct {
+		jid   string
+		level AccessLevel
+		d     time.Duration
+	}{
+		{"owner@s.whatsapp.net", AccessAdmin, time.Hour},
+		{"admin@s.whatsapp.net", AccessAdmin, time.Hour},
+		{"bad@s.whatsapp.net", AccessUser, time.Hour},
+		{"new@s.whatsapp.net", AccessOwner, time.Hour},
+		{"new@s.whatsapp.net", AccessAdmin, 0},
+		{"new@s.whatsapp.net", AccessAdmin, MaxGrantDuration + time.Hour},
+	}
+	for _, c := range cases {
+		if _, err := am.GrantTemporary(c.jid, c.level, c.d, "owner"); err == nil {
+			t.Errorf("GrantTemporary(%s, %s, %s) should fail", c.jid, c.level, c.d)
+		}
+	}
+}
+
+func TestAccess_TemporaryGrantSurvivesReload(t *testing.T) {
+	t.Parallel()
+	cfg := AccessConfig{
+		AllowedUsers:  []string{"user@s.whatsapp.net"},
+		DefaultPolicy: PolicyDeny,
+	}
+	am := NewAccessManager(cfg, nil)
+	if _, err := am.GrantTemporary("user@s.whatsapp.net", AccessAdmin, time.Hour, "owner"); err != nil {
+		t.Fatalf("GrantTemporary: %v", err)
+	}
+
+	am.ApplyConfig(cfg)
+
+	if got := am.GetLevel("user@s.whatsapp.net"); got != AccessAdmin {
+		t.Errorf("level after reload = %v, want admin", got)
+	}
+	grants := am.ListGrants()
+	if len(grants) != 1 || grants[0].Previous == nil || grants[0].Previous.Level != AccessUser {
+		t.Errorf("grant should keep the reloaded config entry as previous: %+v", grants)
+	}
+}
+
+func TestAccess_ReloadDropsSupersededGrants(t *testing.T) {
+	t.Parallel()
+	am := NewAccessManager(AccessConfig{DefaultPolicy: PolicyDeny}, nil)
+	for _, jid := range []string{"blocked@s.whatsapp.net", "owner@s.whatsapp.net", "admin@s.whatsapp.net", "kept@s.whatsapp.net"} {
+		if _, err := am.GrantTemporary(jid, AccessAdmin, time.Hour, "owner"); err != nil {
+			t.Fatalf("GrantTemporary(%s): %v", jid, err)
+		}
+	}
+	var expired []string
+	am.SetGrantHook(func(event GrantEvent, e AccessEntry) {
+		if event == GrantEventExpired {
+			expired = append(expired, e.JID)
+		}
+	})
+
+	am.ApplyConfig(AccessConfig{
+		Owners:        []string{"owner@s.whatsapp.net"},
+		Admins:        []string{"admin@s.whatsapp.net"},
+		BlockedUsers:  []string{"blocked@s.whatsapp.net"},
+		DefaultPolicy: PolicyDeny,
+	})
+
+	want := map[string]AccessLevel{
+		"blocked@s.whatsapp.net": AccessBlocked,
+		"owner@s.whatsapp.net":   AccessOwner,
+		"admin@s.whatsapp.net":   AccessAdmin,
+		"kept@s.whatsapp.net":    AccessAdmin,
+	}
+	for jid, level := range want {
+		if got := am.GetLevel(jid); got != level {
+			t.Errorf("%s level = %v, want %v", jid, got, level)
+		}
+	}
+	if grants := am.ListGrants(); len(grants) != 1 || grants[0].JID != "kept@s.whatsapp.net" {
+		t.Errorf("grants after reload = %+v, want only kept", grants)
+	}
+	if len(expired) != 3 {
+		t.Errorf("expired hook calls = %v, want 3", expired)
+	}
+}
+
+func TestParseGrantDuration(t *testing.T) {
+	t.Parallel()
+	cases := map[string]time.Duration{
+		"30m":   30 * time.Minute,
+		"2h":    2 * time.Hour,
+		"1h30m": 90 * time.Minute,
+		"3d":    72 * time.Hour,
+	}
+	for in, want := range cases {
+		got, err := parseGrantDuration(in)
+		if err != nil || got != want {
+			t.Errorf("parseGrantDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-1d", "xd"} {
+		if _, err := parseGrantDuration(bad); err == nil {
+			t.Errorf("parseGrantDuration(%q) should fail", bad)
+		}
+	}
+	if got := formatGrantDuration(90 * time.Minute); got != "1h30m" {
+		t.Errorf("formatGrantDuration(90m) = %q", got)
+	}
+	if got := formatGrantDuration(2 * time.Hour); got != "2h" {
+		t.Errorf("formatGrantDuration(2h) = %q", got)
+	}
+}
+
+func TestAccess_WebhookEvents(t *testing.T) {
+	t.Parallel()
+	wh := webhook.New(webhook.Config{Hooks: []webhook.Hook{
+		{Name: "github", Secret: "s3cret", Signature: "github", Access: "owner"},
+	}}, nil)
+	if err := wh.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"action":"opened","issue":{"title":"Build broken"}}`
+	post := func(sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/hooks/github", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-GitHub-Delivery", "d-1")
+		req.Header.Set("X-Hub-Signature-256", sig)
+		rec := httptest.NewRecorder()
+		wh.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := post("sha256=00"); code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: got %d, want 401", code)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(body))
+	if code := post("sha256=" + hex.EncodeToString(mac.Sum(nil))); code != http.StatusAccepted {
+		t.Fatalf("valid signature: got %d, want 202", code)
+	}
+
+	msg := <-wh.Receive()
+	if msg.ID != "github:d-1" || msg.From != "hook:github" || !strings.Contains(msg.Content, `type "issues.opened"`) {
+		t.Errorf("unexpected message: id=%q from=%q content=%q", msg.ID, msg.From, msg.Content)
+	}
+
+	// Authenticated events pass the deny policy, but never as owner.
+	am := NewAccessManager(AccessConfig{DefaultPolicy: PolicyDeny}, nil)
+	r := am.Check(msg)
+	if !r.Allowed || r.Level != AccessUser {
+		t.Errorf("webhook event: allowed=%v level=%v, want user", r.Allowed, r.Level)
+	}
+}
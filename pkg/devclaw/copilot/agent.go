@@ -7,14 +7,17 @@
 //   - No fixed max turns — the loop runs until the LLM stops calling tools.
 //   - Single run timeout (default: 600s = 10min) controls the whole run.
 //   - Per-LLM-call safety timeout (5min) prevents individual hung requests.
-//   - Reflection nudge every 15 turns for budget awareness.
-//   - Auto-compaction on context overflow (up to 3 attempts).
+//   - Reflection nudges only when progress stalls (repeats, no new
+//     information, context or time burn), see reflection.go.
+//   - Proactive compaction when the estimated prompt exceeds the model's
+//     context window, and auto-compaction on context overflow (up to 3 attempts).
 package copilot
 
 import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"time"
 )
@@ -31,10 +34,6 @@ const (
 	// that even large contexts complete. 5 minutes covers worst-case scenarios.
 	DefaultLLMCallTimeout = 5 * time.Minute
 
-	// reflectionInterval is how often (in turns) the agent receives a budget nudge.
-	// Reduced from 15 to 5 to catch stuck patterns earlier.
-	reflectionInterval = 5
-
 	// DefaultMaxCompactionAttempts is how many times to retry after context overflow compaction.
 	DefaultMaxCompactionAttempts = 3
 )
@@ -58,14 +57,29 @@ type AgentConfig struct {
 	// Only relevant when MaxTurns > 0. Default: 2.
 	MaxContinuations int `yaml:"max_continuations"`
 
-	// ReflectionEnabled enables periodic budget awareness nudges (default: true).
+	// ReflectionEnabled enables progress-aware reflection nudges (default: true).
 	ReflectionEnabled bool `yaml:"reflection_enabled"`
 
 	// MaxCompactionAttempts is how many times to retry after context overflow (default: 3).
 	MaxCompactionAttempts int `yaml:"max_compaction_attempts"`
 
+	// ContextWindow overrides the model's context window in tokens, used to
+	// compact the prompt before sending it (default: 0 = from the model registry).
+	ContextWindow int `yaml:"context_window"`
+
 	// ToolLoop configures tool loop detection thresholds.
 	ToolLoop ToolLoopConfig `yaml:"tool_loop"`
+
+	// Watchdog reports (and optionally cancels) runs that stop making progress.
+	Watchdog RunWatchdogConfig `yaml:"watchdog"`
+
+	// IntentSplit handles messages with several unrelated requests as
+	// ordered sub-tasks (see intent_split.go).
+	IntentSplit IntentSplitConfig `yaml:"intent_split"`
+
+	// ToolCompression shortens the definitions of tools already used and
+	// loads tool families lazily (see tool_compression.go).
+	ToolCompression ToolCompressionConfig `yaml:"tool_compression"`
 }
 
 // DefaultAgentConfig returns sensible defaults for agent autonomy.
@@ -77,6 +91,9 @@ func DefaultAgentConfig() AgentConfig {
 		MaxContinuations:      2,
 		ReflectionEnabled:     true,
 		MaxCompactionAttempts: DefaultMaxCompactionAttempts,
+		Watchdog:              DefaultRunWatchdogConfig(),
+		IntentSplit:           DefaultIntentSplitConfig(),
+		ToolCompression:       DefaultToolCompressionConfig(),
 	}
 }
 
@@ -89,15 +106,17 @@ type AgentRun struct {
 	maxTurns              int           // 0 = unlimited
 	reflectionOn          bool
 	maxCompactionAttempts int
+	contextWindow         int // 0 = from the model registry
 	streamCallback        StreamCallback
 	modelOverride         string                             // When set, use this model instead of default.
+	workspaceID           string                             // When set, only this workspace's tools are offered and callable.
 	usageRecorder         func(model string, usage LLMUsage) // Called after each successful LLM response.
 
 	// interruptCh receives follow-up user messages that should be injected into
 	// the active agent loop. Between turns, the agent drains this channel and
-	// appends the messages to the conversation before the next LLM call.
-	// This enables Claude Code-style live message injection.
-	interruptCh <-chan string
+	// appends the messages to the conversation before the next LLM call,
+	// urgent ones first (see interrupts.go).
+	interruptCh <-chan Interrupt
 
 	// onBeforeToolExec is called right before tool execution starts.
 	// Used to flush any buffered stream text so the user sees the LLM's
@@ -108,9 +127,34 @@ type AgentRun struct {
 	// Used to auto-send media (e.g. generated images) to the channel.
 	onToolResult func(name string, result ToolResult)
 
+	// onTurn is called after each LLM response that requests tools, before
+	// they run. Used to report subagent progress.
+	onTurn func(turn int, content string, calls []ToolCall)
+
 	// loopDetector tracks tool call history and detects repetitive patterns.
 	loopDetector *ToolLoopDetector
 
+	// progress receives LLM call and tool completion events for the run
+	// watchdog (nil = not tracked).
+	progress *runProgress
+
+	// toolBlocks keeps the typed blocks of structured tool results by tool
+	// call ID, so trimming can shrink them without breaking their structure.
+	toolBlocks map[string][]ToolBlock
+
+	// toolLog records every tool call of the run for the session transcript.
+	toolLog []TurnToolCall
+
+	// policy may intercept tool calls before they run (see plan_mode.go).
+	policy ExecutionPolicy
+
+	// tools chooses the tool definitions offered to the LLM (nil = all of
+	// them, unchanged; see tool_compression.go).
+	tools *toolView
+
+	// redactor hides PII from the LLM provider (nil = off; see redaction.go).
+	redactor *Redactor
+
 	logger *slog.Logger
 }
 
@@ -144,6 +188,7 @@ func NewAgentRunWithConfig(llm *LLMClient, executor *ToolExecutor, cfg AgentConf
 	if cfg.MaxCompactionAttempts > 0 {
 		ar.maxCompactionAttempts = cfg.MaxCompactionAttempts
 	}
+	ar.contextWindow = cfg.ContextWindow
 	return ar
 }
 
@@ -160,6 +205,17 @@ func (a *AgentRun) SetModelOverride(model string) {
 	a.modelOverride = model
 }
 
+// SetWorkspace limits the run to the tool registry of a workspace.
+func (a *AgentRun) SetWorkspace(wsID string) {
+	a.workspaceID = wsID
+}
+
+// SetRedactor redacts PII from the messages of every LLM call and
+// restores it in the replies (nil = off).
+func (a *AgentRun) SetRedactor(r *Redactor) {
+	a.redactor = r
+}
+
 // SetUsageRecorder sets a callback invoked after each successful LLM response.
 func (a *AgentRun) SetUsageRecorder(fn func(model string, usage LLMUsage)) {
 	a.usageRecorder = fn
@@ -178,16 +234,32 @@ func (a *AgentRun) SetOnToolResult(fn func(name string, result ToolResult)) {
 	a.onToolResult = fn
 }
 
+// SetOnTurn sets a callback fired after each LLM response that requests
+// tools, with the turn number, the response text and the tool calls.
+func (a *AgentRun) SetOnTurn(fn func(turn int, content string, calls []ToolCall)) {
+	a.onTurn = fn
+}
+
 // SetLoopDetector sets the tool loop detector for this run.
 func (a *AgentRun) SetLoopDetector(d *ToolLoopDetector) {
 	a.loopDetector = d
 }
 
+// setToolView sets how tool definitions are compressed and loaded.
+func (a *AgentRun) setToolView(v *toolView) {
+	a.tools = v
+}
+
+// setProgress wires the run watchdog's progress tracker.
+func (a *AgentRun) setProgress(p *runProgress) {
+	a.progress = p
+}
+
 // SetInterruptChannel sets the channel for receiving follow-up user messages
 // during agent execution. Messages received on this channel are injected into
 // the conversation between agent turns, allowing users to steer the agent
 // mid-run (similar to Claude Code behavior).
-func (a *AgentRun) SetInterruptChannel(ch <-chan string) {
+func (a *AgentRun) SetInterruptChannel(ch <-chan Interrupt) {
 	a.interruptCh = ch
 }
 
@@ -210,17 +282,34 @@ func (a *AgentRun) Run(ctx context.Context, systemPrompt string, history []Conve
 //   - Individual LLM calls have a safety-net timeout (5min) to catch hung connections.
 //   - No fixed turn limit — the agent keeps going as long as it has tools to call.
 func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, history []ConversationEntry, userMessage string) (string, *LLMUsage, error) {
+	ctx, span := startSpan(ctx, "agent.run", "workspace", a.workspaceID, "llm.model_override", a.modelOverride)
+	content, usage, err := a.runWithUsage(ctx, systemPrompt, history, userMessage)
+	if usage != nil {
+		span.SetAttrs("llm.prompt_tokens", usage.PromptTokens, "llm.completion_tokens", usage.CompletionTokens)
+	}
+	span.SetError(err)
+	span.End()
+	return content, usage, err
+}
+
+// runWithUsage is the agent loop of RunWithUsage.
+func (a *AgentRun) runWithUsage(ctx context.Context, systemPrompt string, history []ConversationEntry, userMessage string) (string, *LLMUsage, error) {
 	// ── Run-level timeout (single timer for the whole run) ──
 	runCtx, runCancel := context.WithTimeout(ctx, a.runTimeout)
 	defer runCancel()
+	if a.workspaceID != "" {
+		runCtx = ContextWithWorkspace(runCtx, a.workspaceID)
+	}
 
 	runStart := time.Now()
 
 	// Build initial messages from history.
 	messages := a.buildMessages(systemPrompt, history, userMessage)
 
-	// Collect tool definitions from the executor.
-	tools := a.executor.Tools()
+	// Collect tool definitions from the executor (the workspace's registry
+	// when the run belongs to one), compressed for the session.
+	allTools := a.executor.ToolsFor(a.workspaceID)
+	tools := a.tools.apply(allTools)
 
 	a.logger.Debug("agent run started",
 		"history_entries", len(history),
@@ -243,6 +332,11 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 	var totalUsage LLMUsage
 	totalTurns := 0
 
+	var reflection *reflectionMonitor
+	if a.reflectionOn {
+		reflection = newReflectionMonitor()
+	}
+
 	// Progress cooldown: avoid flooding the user with tool progress messages.
 	// Short 3s cooldown for faster feedback while avoiding message spam.
 	const progressCooldown = 3 * time.Second
@@ -290,16 +384,7 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 		// Check for follow-up user messages sent while the agent was working.
 		if totalTurns > 1 {
 			if interrupts := a.drainInterrupts(); len(interrupts) > 0 {
-				for _, interrupt := range interrupts {
-					messages = append(messages, chatMessage{
-						Role:    "user",
-						Content: "[Follow-up from user while processing]\n" + interrupt,
-					})
-				}
-				a.logger.Info("injected interrupt messages into agent loop",
-					"count", len(interrupts),
-					"turn", totalTurns,
-				)
+				messages = a.injectInterrupts(messages, interrupts, totalTurns)
 			}
 		}
 
@@ -312,25 +397,20 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			messages = a.pruneOldToolResults(messages, totalTurns)
 		}
 
-		// Inject reflection nudge periodically so the agent is aware of duration.
-		// More aggressive messaging to catch stuck patterns early.
-		if a.reflectionOn && totalTurns > 1 && totalTurns%reflectionInterval == 0 {
-			elapsed := time.Since(runStart).Seconds()
-			remaining := a.runTimeout.Seconds() - elapsed
-			messages = append(messages, chatMessage{
-				Role: "user",
-				Content: fmt.Sprintf(
-					"[System: Turn %d checkpoint (%.0fs elapsed, ~%.0fs remaining). "+
-						"If you're stuck or repeating the same approach, STOP and investigate the root cause. "+
-						"Don't repeat failed approaches — think before acting.]",
-					totalTurns, elapsed, remaining,
-				),
-			})
+		// Inject a reflection nudge only when the run shows signs of
+		// stalling (see reflection.go).
+		if reflection != nil && totalTurns > 1 {
+			if nudge := reflection.nudge(totalTurns, time.Since(runStart), a.runTimeout); nudge != "" {
+				a.logger.Info("reflection nudge", "turn", totalTurns, "nudge", truncateStr(nudge, 120))
+				messages = append(messages, chatMessage{Role: "user", Content: nudge})
+			}
 		}
 
 		// ── Call LLM ──
 		llmStart := time.Now()
+		a.progress.llmStart(totalTurns)
 		resp, err := a.doLLMCallWithOverflowRetry(runCtx, messages, tools)
+		a.progress.llmDone()
 		llmDuration := time.Since(llmStart)
 		if err != nil {
 			// If the parent/run context was cancelled, propagate immediately.
@@ -358,7 +438,9 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 
 				// Retry the LLM call with compacted context.
 				llmStart = time.Now()
+				a.progress.llmStart(totalTurns)
 				resp, err = a.doLLMCallWithOverflowRetry(runCtx, messages, tools)
+				a.progress.llmDone()
 				llmDuration = time.Since(llmStart)
 			}
 
@@ -368,6 +450,9 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			}
 		}
 		a.accumulateUsage(&totalUsage, resp)
+		if reflection != nil {
+			reflection.recordUsage(resp.Usage.PromptTokens)
+		}
 
 		a.logger.Info("LLM call complete",
 			"turn", totalTurns,
@@ -378,7 +463,17 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 		)
 
 		// ── No tool calls → final response ──
+		// An urgent message that arrived while the answer was being written
+		// reopens the run, together with any other pending follow-up.
 		if len(resp.ToolCalls) == 0 {
+			if interrupts := a.drainInterrupts(); len(interrupts) > 0 {
+				if hasUrgent(interrupts) {
+					messages = append(messages, chatMessage{Role: "assistant", Content: resp.Content})
+					messages = a.injectInterrupts(messages, interrupts, totalTurns)
+					continue
+				}
+				a.logger.Warn("follow-ups arrived after the final answer, not injected", "count", len(interrupts))
+			}
 			a.logger.Info("agent completed",
 				"total_turns", totalTurns,
 				"response_len", len(resp.Content),
@@ -433,6 +528,9 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 		if a.onBeforeToolExec != nil {
 			a.onBeforeToolExec()
 		}
+		if a.onTurn != nil {
+			a.onTurn(totalTurns, resp.Content, resp.ToolCalls)
+		}
 
 		// Send progress to the user so they see what the agent is doing
 		// while tools execute (especially for long-running tools).
@@ -464,7 +562,17 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			}
 		}
 
-		results := a.executor.Execute(runCtx, resp.ToolCalls)
+		a.progress.toolsStart(toolNames)
+		var results []ToolResult
+		intercepted := false
+		if a.policy != nil {
+			results, intercepted = a.policy.Intercept(runCtx, resp.ToolCalls)
+		}
+		if !intercepted {
+			results = a.executeCalls(runCtx, resp.ToolCalls)
+			a.tools.recordResults(results)
+		}
+		a.progress.toolsDone(results)
 
 		a.logger.Info("tool calls complete",
 			"count", len(results),
@@ -472,6 +580,11 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			"turn_ms", time.Since(turnStart).Milliseconds(),
 		)
 
+		args := make(map[string]string, len(resp.ToolCalls))
+		for _, tc := range resp.ToolCalls {
+			args[tc.ID] = tc.Function.Arguments
+		}
+
 		// Append each tool result as a message.
 		// Classify recoverable errors: the model should retry silently without
 		// the user seeing transient failures.
@@ -485,9 +598,21 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			}
 			messages = append(messages, chatMessage{
 				Role:       "tool",
-				Content:    content,
+				Content:    result.llmContent(),
 				ToolCallID: result.ToolCallID,
 			})
+			a.rememberToolBlocks(result)
+			a.toolLog = append(a.toolLog, TurnToolCall{
+				Name:        result.Name,
+				Args:        truncateStr(args[result.ToolCallID], turnToolArgsMax),
+				Result:      truncateStr(content, turnToolResultMax),
+				Error:       result.Error != nil,
+				DurationMs:  result.Duration.Milliseconds(),
+				Blocked:     result.Blocked,
+				Approval:    result.Approval,
+				OutputBytes: len(content),
+				Truncated:   result.Truncated,
+			})
 
 			// Track tool output for progress-aware loop detection.
 			if a.loopDetector != nil {
@@ -495,11 +620,20 @@ func (a *AgentRun) RunWithUsage(ctx context.Context, systemPrompt string, histor
 			}
 
 			// Notify hook (e.g. auto-send media for generate_image).
-			if a.onToolResult != nil && result.Error == nil {
+			if a.onToolResult != nil && result.Error == nil && !intercepted {
 				a.onToolResult(result.Name, result)
 			}
 		}
 
+		if reflection != nil {
+			reflection.recordTurn(resp.ToolCalls, results)
+		}
+
+		// A tool family was loaded: offer its tools from the next call.
+		if a.tools.takeChanged() {
+			tools = a.tools.apply(allTools)
+		}
+
 		// Inject deferred loop warning AFTER tool results (valid message order:
 		// assistant→tool→user). This ensures providers that validate message
 		// sequences don't reject the request.
@@ -590,6 +724,19 @@ func describeToolAction(name string, args map[string]any) string {
 		}
 		return "✏️ Editando arquivo..."
 
+	case "send_file":
+		p, _ := args["path"].(string)
+		if p != "" {
+			return "📎 Enviando " + shortPath(p)
+		}
+		return "📎 Enviando arquivo..."
+
+	case "apply_changes":
+		if changes, ok := args["changes"].([]any); ok && len(changes) > 0 {
+			return fmt.Sprintf("🧩 Aplicando alterações em %d arquivos", len(changes))
+		}
+		return "🧩 Aplicando alterações..."
+
 	case "list_files", "glob_files":
 		p, _ := args["path"].(string)
 		if p == "" {
@@ -682,7 +829,7 @@ func describeToolAction(name string, args map[string]any) string {
 	// ── Images ──
 	case "describe_image":
 		return "👁️ Analisando imagem..."
-	case "image-gen_generate_image":
+	case "generate_image", "image-gen_generate_image":
 		p, _ := args["prompt"].(string)
 		if p != "" {
 			if len(p) > 50 {
@@ -741,6 +888,8 @@ func describeToolAction(name string, args map[string]any) string {
 		return "⏳ Aguardando subagente..."
 	case "stop_subagent":
 		return "🛑 Parando subagente..."
+	case "subagent_status":
+		return "🧵 Verificando progresso dos subagentes..."
 
 	// ── Project Manager ──
 	case "project-manager_activate":
@@ -825,12 +974,13 @@ func truncateStr(s string, n int) string {
 }
 
 // drainInterrupts reads all pending messages from the interrupt channel
-// without blocking. Returns nil if no messages are available.
-func (a *AgentRun) drainInterrupts() []string {
+// without blocking, urgent ones first. Returns nil if no messages are
+// available.
+func (a *AgentRun) drainInterrupts() []Interrupt {
 	if a.interruptCh == nil {
 		return nil
 	}
-	var msgs []string
+	var msgs []Interrupt
 	for {
 		select {
 		case msg, ok := <-a.interruptCh:
@@ -839,11 +989,30 @@ func (a *AgentRun) drainInterrupts() []string {
 			}
 			msgs = append(msgs, msg)
 		default:
+			slices.SortStableFunc(msgs, func(x, y Interrupt) int { return int(y.Priority) - int(x.Priority) })
 			return msgs
 		}
 	}
 }
 
+// injectInterrupts appends follow-up messages to the conversation.
+func (a *AgentRun) injectInterrupts(messages []chatMessage, interrupts []Interrupt, turn int) []chatMessage {
+	for _, interrupt := range interrupts {
+		messages = append(messages, chatMessage{Role: "user", Content: interrupt.message()})
+	}
+	a.logger.Info("injected interrupt messages into agent loop",
+		"count", len(interrupts),
+		"urgent", hasUrgent(interrupts),
+		"turn", turn,
+	)
+	return messages
+}
+
+// ToolLog returns the tool calls made during the run, in order.
+func (a *AgentRun) ToolLog() []TurnToolCall {
+	return a.toolLog
+}
+
 // accumulateUsage adds resp.Usage into total.
 func (a *AgentRun) accumulateUsage(total *LLMUsage, resp *LLMResponse) {
 	if resp == nil {
@@ -852,6 +1021,8 @@ func (a *AgentRun) accumulateUsage(total *LLMUsage, resp *LLMResponse) {
 	total.PromptTokens += resp.Usage.PromptTokens
 	total.CompletionTokens += resp.Usage.CompletionTokens
 	total.TotalTokens += resp.Usage.TotalTokens
+	total.CacheReadTokens += resp.Usage.CacheReadTokens
+	total.CacheWriteTokens += resp.Usage.CacheWriteTokens
 }
 
 // buildMessages converts conversation history into the chat message format.
@@ -942,14 +1113,38 @@ func (a *AgentRun) truncateToolResults(messages []chatMessage, maxLen int) []cha
 	for i, m := range messages {
 		result[i] = m
 		if m.Role == "tool" {
+			// Images go first when the context overflows.
+			if _, ok := m.Content.([]contentPart); ok {
+				result[i].Content = contentText(m.Content)
+				m.Content = result[i].Content
+			}
 			if s, ok := m.Content.(string); ok && len(s) > maxLen {
-				result[i].Content = s[:keepChars] + truncSuffix
+				if blocks, ok := a.toolBlocks[m.ToolCallID]; ok {
+					result[i].Content = RenderToolBlocks(blocks, maxLen)
+				} else {
+					result[i].Content = s[:keepChars] + truncSuffix
+				}
 			}
 		}
 	}
 	return result
 }
 
+// rememberToolBlocks records the blocks of a result that has structure
+// worth preserving (anything beyond a single text block).
+func (a *AgentRun) rememberToolBlocks(result ToolResult) {
+	if result.ToolCallID == "" || len(result.Blocks) == 0 {
+		return
+	}
+	if len(result.Blocks) == 1 && result.Blocks[0].Type == ToolBlockText {
+		return
+	}
+	if a.toolBlocks == nil {
+		a.toolBlocks = make(map[string][]ToolBlock)
+	}
+	a.toolBlocks[result.ToolCallID] = result.Blocks
+}
+
 // pruneOldToolResults implements proactive context trimming.
 // Tool results are tagged with their turn number. Older results are progressively
 // truncated or removed to keep the context lean without waiting for overflow.
@@ -998,9 +1193,16 @@ func (a *AgentRun) pruneOldToolResults(messages []chatMessage, currentTurn int)
 			}
 
 			if age > softTrimAge {
-				// Soft trim: truncate to 500 chars.
+				// Soft trim: drop images, truncate to 500 chars.
+				if _, ok := m.Content.([]contentPart); ok {
+					m.Content = contentText(m.Content)
+				}
 				if s, ok := m.Content.(string); ok && len(s) > softTrimChars {
-					m.Content = s[:softTrimChars] + "... [truncated — old result]"
+					if blocks, ok := a.toolBlocks[m.ToolCallID]; ok {
+						m.Content = RenderToolBlocks(blocks, softTrimChars)
+					} else {
+						m.Content = s[:softTrimChars] + "... [truncated — old result]"
+					}
 				}
 			}
 		}
@@ -1019,25 +1221,47 @@ func (a *AgentRun) pruneOldToolResults(messages []chatMessage, currentTurn int)
 //  2. Second attempt: compact messages (keep last N) + truncate tool results harder.
 //  3. Third attempt: aggressive compaction (keep fewer messages).
 func (a *AgentRun) doLLMCallWithOverflowRetry(ctx context.Context, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, error) {
-	toolResultTruncated := false
-	keepRecent := 20
+	compactor := &contextCompactor{run: a, keepRecent: 20}
+
+	// Compact up front when the prompt clearly won't fit, instead of
+	// spending a call on a guaranteed 400.
+	messages = a.fitToContextWindow(messages, tools, compactor)
 
 	for attempt := 0; attempt < a.maxCompactionAttempts; attempt++ {
 		// Use the shorter of: run context deadline or llmCallTimeout safety net.
 		callCtx, cancel := context.WithTimeout(ctx, a.llmCallTimeout)
+		callCtx, span := startSpan(callCtx, "llm.call",
+			"llm.messages", len(messages), "llm.tools", len(tools), "llm.compaction_attempt", attempt)
 		var resp *LLMResponse
 		var err error
-		if a.streamCallback != nil {
-			resp, err = a.llm.CompleteWithToolsStreamUsingModel(callCtx, a.modelOverride, messages, tools, a.streamCallback)
+		sent, stream, flush := messages, a.streamCallback, func() {}
+		if a.redactor != nil {
+			sent = a.redactor.redactMessages(messages)
+			if stream != nil {
+				stream, flush = a.redactor.restoreStream(stream)
+			}
+		}
+		if stream != nil {
+			resp, err = a.llm.CompleteWithToolsStreamUsingModel(callCtx, a.modelOverride, sent, tools, stream)
 		} else {
-			resp, err = a.llm.CompleteWithFallbackUsingModel(callCtx, a.modelOverride, messages, tools)
+			resp, err = a.llm.CompleteWithFallbackUsingModel(callCtx, a.modelOverride, sent, tools)
 		}
 		cancel()
+		flush()
+		a.redactor.restoreResponse(resp)
+		if resp != nil {
+			span.SetAttrs("llm.model", resp.ModelUsed, "llm.cached", resp.Cached)
+		}
+		span.SetError(err)
+		span.End()
 
 		if err == nil {
 			if a.usageRecorder != nil && resp.Usage.TotalTokens > 0 {
 				a.usageRecorder(resp.ModelUsed, resp.Usage)
 			}
+			if len(tools) > 0 {
+				a.tools.recordCall()
+			}
 			return resp, nil
 		}
 
@@ -1050,34 +1274,47 @@ func (a *AgentRun) doLLMCallWithOverflowRetry(ctx context.Context, messages []ch
 			"max_attempts", a.maxCompactionAttempts,
 			"messages_before", len(messages),
 		)
+		messages = compactor.step(messages)
+	}
 
-		// ── Compaction strategy ──
-		// Step 1: Try truncating oversized tool results first (cheap operation).
-		if !toolResultTruncated {
-			if hasOversizedToolResults(messages, 4000) {
-				a.logger.Info("truncating oversized tool results before compaction")
-				messages = a.truncateToolResults(messages, 4000)
-				toolResultTruncated = true
-				continue // Retry without compacting messages.
-			}
-		}
+	return nil, fmt.Errorf("context overflow: compacted %d times but still exceeded context limit", a.maxCompactionAttempts)
+}
 
-		// Step 2+3: Compact messages (keep system + last N).
-		a.logger.Info("compacting messages",
-			"keep_recent", keepRecent,
-			"messages_before", len(messages),
-		)
-		messages = a.compactMessages(messages, keepRecent)
-		messages = a.truncateToolResults(messages, 2000)
+// contextCompactor applies escalating compaction steps to a prompt, shared
+// by proactive budgeting and overflow recovery within one LLM call.
+type contextCompactor struct {
+	run                 *AgentRun
+	toolResultTruncated bool
+	keepRecent          int
+}
 
-		// Next attempt: keep fewer messages.
-		keepRecent -= 5
-		if keepRecent < 6 {
-			keepRecent = 6
+// step applies the next compaction step.
+func (c *contextCompactor) step(messages []chatMessage) []chatMessage {
+	a := c.run
+
+	// Step 1: Try truncating oversized tool results first (cheap operation).
+	if !c.toolResultTruncated {
+		c.toolResultTruncated = true
+		if hasOversizedToolResults(messages, 4000) {
+			a.logger.Info("truncating oversized tool results before compaction")
+			return a.truncateToolResults(messages, 4000)
 		}
 	}
 
-	return nil, fmt.Errorf("context overflow: compacted %d times but still exceeded context limit", a.maxCompactionAttempts)
+	// Step 2+3: Compact messages (keep system + last N).
+	a.logger.Info("compacting messages",
+		"keep_recent", c.keepRecent,
+		"messages_before", len(messages),
+	)
+	messages = a.compactMessages(messages, c.keepRecent)
+	messages = a.truncateToolResults(messages, 2000)
+
+	// Next attempt: keep fewer messages.
+	c.keepRecent -= 5
+	if c.keepRecent < 6 {
+		c.keepRecent = 6
+	}
+	return messages
 }
 
 // hasOversizedToolResults checks if any tool result message exceeds maxLen.
@@ -1087,6 +1324,9 @@ func hasOversizedToolResults(messages []chatMessage, maxLen int) bool {
 			if s, ok := m.Content.(string); ok && len(s) > maxLen {
 				return true
 			}
+			if _, ok := m.Content.([]contentPart); ok {
+				return true
+			}
 		}
 	}
 	return false
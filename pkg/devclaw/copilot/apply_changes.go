@@ -0,0 +1,402 @@
+// Package copilot – apply_changes.go implements the apply_changes tool, which
+// applies a batch of file creations, edits and deletions as a single atomic
+// transaction. Every change is validated in memory before anything touches the
+// disk, the original files are snapshotted to a backup directory, and any
+// failure mid-way rolls the whole batch back.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxApplyChanges caps how many file operations a single transaction may hold.
+const maxApplyChanges = 100
+
+// fileChange is one entry of an apply_changes transaction.
+type fileChange struct {
+	Action     string `json:"action"` // create, write, edit, delete
+	Path       string `json:"path"`
+	Content    string `json:"content,omitempty"`
+	OldText    string `json:"old_text,omitempty"`
+	NewText    string `json:"new_text,omitempty"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// plannedChange is a validated change with its final content resolved.
+type plannedChange struct {
+	change   fileChange
+	absPath  string
+	existed  bool
+	original []byte
+	mode     os.FileMode
+	final    []byte // nil for deletions
+}
+
+// backupManifestEntry records one file captured in a backup snapshot.
+type backupManifestEntry struct {
+	Path    string `json:"path"`
+	Action  string `json:"action"`
+	Existed bool   `json:"existed"`
+	Backup  string `json:"backup,omitempty"`
+}
+
+// parseFileChanges extracts the "changes" array from tool args.
+func parseFileChanges(args map[string]any) ([]fileChange, error) {
+	raw, ok := args["changes"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("changes is required and must be a non-empty array")
+	}
+	if len(raw) > maxApplyChanges {
+		return nil, fmt.Errorf("too many changes (%d), max is %d", len(raw), maxApplyChanges)
+	}
+
+	changes := make([]fileChange, 0, len(raw))
+	for i, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("changes[%d]: must be an object", i)
+		}
+		c := fileChange{}
+		c.Action, _ = m["action"].(string)
+		c.Path, _ = m["path"].(string)
+		c.Content, _ = m["content"].(string)
+		c.OldText, _ = m["old_text"].(string)
+		c.NewText, _ = m["new_text"].(string)
+		c.ReplaceAll, _ = m["replace_all"].(bool)
+		c.Action = strings.ToLower(strings.TrimSpace(c.Action))
+		if c.Path == "" {
+			return nil, fmt.Errorf("changes[%d]: path is required", i)
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// changePaths returns the paths referenced by an apply_changes call. Used by
+// the tool guard (protected paths) and approval prompts. Malformed entries are
+// skipped.
+func changePaths(args map[string]any) []string {
+	raw, _ := args["changes"].([]any)
+	paths := make([]string, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		if p, _ := m["path"].(string); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// formatApplyChangesSummary builds the combined confirmation text listing
+// every path touched by the transaction.
+func formatApplyChangesSummary(args map[string]any) string {
+	raw, _ := args["changes"].([]any)
+	if len(raw) == 0 {
+		return "apply_changes"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "apply_changes (%d files):", len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		action, _ := m["action"].(string)
+		path, _ := m["path"].(string)
+		fmt.Fprintf(&b, "\n  • %s %s", action, path)
+	}
+	return b.String()
+}
+
+// planChanges validates every change against the current filesystem state and
+// computes the resulting file contents. Nothing is written.
+func planChanges(changes []fileChange) ([]*plannedChange, error) {
+	plans := make([]*plannedChange, 0, len(changes))
+	byPath := make(map[string]*plannedChange)
+
+	for i, c := range changes {
+		abs, err := filepath.Abs(resolvePath(c.Path))
+		if err != nil {
+			return nil, fmt.Errorf("changes[%d]: resolving path: %w", i, err)
+		}
+		if _, dup := byPath[abs]; dup {
+			return nil, fmt.Errorf("changes[%d]: %s is changed more than once — merge the edits into a single entry", i, c.Path)
+		}
+
+		p := &plannedChange{change: c, absPath: abs, mode: 0o644}
+		info, statErr := os.Stat(abs)
+		switch {
+		case statErr == nil:
+			if info.IsDir() {
+				return nil, fmt.Errorf("changes[%d]: %s is a directory", i, c.Path)
+			}
+			p.existed = true
+			p.mode = info.Mode().Perm()
+			if p.original, err = os.ReadFile(abs); err != nil {
+				return nil, fmt.Errorf("changes[%d]: reading %s: %w", i, c.Path, err)
+			}
+		case !os.IsNotExist(statErr):
+			return nil, fmt.Errorf("changes[%d]: stat %s: %w", i, c.Path, statErr)
+		}
+
+		switch c.Action {
+		case "create":
+			if p.existed {
+				return nil, fmt.Errorf("changes[%d]: %s already exists (use action=write to overwrite)", i, c.Path)
+			}
+			p.final = []byte(c.Content)
+		case "write":
+			p.final = []byte(c.Content)
+		case "edit":
+			if !p.existed {
+				return nil, fmt.Errorf("changes[%d]: %s does not exist", i, c.Path)
+			}
+			if c.OldText == "" {
+				return nil, fmt.Errorf("changes[%d]: old_text is required for edit", i)
+			}
+			text := string(p.original)
+			count := strings.Count(text, c.OldText)
+			if count == 0 {
+				return nil, fmt.Errorf("changes[%d]: old_text not found in %s", i, c.Path)
+			}
+			if count > 1 && !c.ReplaceAll {
+				return nil, fmt.Errorf("changes[%d]: old_text found %d times in %s — provide more context or set replace_all=true", i, count, c.Path)
+			}
+			if c.ReplaceAll {
+				p.final = []byte(strings.ReplaceAll(text, c.OldText, c.NewText))
+			} else {
+				p.final = []byte(strings.Replace(text, c.OldText, c.NewText, 1))
+			}
+		case "delete":
+			if !p.existed {
+				return nil, fmt.Errorf("changes[%d]: %s does not exist", i, c.Path)
+			}
+		default:
+			return nil, fmt.Errorf("changes[%d]: unknown action %q (use create, write, edit or delete)", i, c.Action)
+		}
+
+		byPath[abs] = p
+		plans = append(plans, p)
+	}
+	return plans, nil
+}
+
+// snapshotChanges copies the original content of every affected file into a
+// timestamped directory under backupRoot and writes a manifest describing the
+// transaction. Returns the snapshot directory.
+func snapshotChanges(backupRoot string, plans []*plannedChange) (string, error) {
+	dir := filepath.Join(backupRoot, time.Now().Format("20060102-150405.000000"))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating backup dir: %w", err)
+	}
+
+	manifest := make([]backupManifestEntry, 0, len(plans))
+	for i, p := range plans {
+		entry := backupManifestEntry{Path: p.absPath, Action: p.change.Action, Existed: p.existed}
+		if p.existed {
+			name := fmt.Sprintf("%03d_%s", i, filepath.Base(p.absPath))
+			if err := os.WriteFile(filepath.Join(dir, name), p.original, 0o600); err != nil {
+				return "", fmt.Errorf("backing up %s: %w", p.absPath, err)
+			}
+			entry.Backup = name
+		}
+		manifest = append(manifest, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0o600); err != nil {
+		return "", fmt.Errorf("writing manifest: %w", err)
+	}
+	return dir, nil
+}
+
+// writeFileAtomic writes data to a temp file in the target directory and
+// renames it into place so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// commitChanges applies the planned changes in order. On the first failure,
+// every change applied so far is reverted and the error is returned.
+func commitChanges(plans []*plannedChange) error {
+	var createdDirs []string
+	applied := make([]*plannedChange, 0, len(plans))
+
+	rollback := func() []string {
+		var failures []string
+		for i := len(applied) - 1; i >= 0; i-- {
+			p := applied[i]
+			var err error
+			if p.existed {
+				err = writeFileAtomic(p.absPath, p.original, p.mode)
+			} else {
+				err = os.Remove(p.absPath)
+			}
+			if err != nil && !os.IsNotExist(err) {
+				failures = append(failures, fmt.Sprintf("%s: %v", p.absPath, err))
+			}
+		}
+		// Remove directories we created, deepest first. Non-empty dirs stay.
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			os.Remove(createdDirs[i])
+		}
+		return failures
+	}
+
+	for _, p := range plans {
+		var err error
+		if p.change.Action == "delete" {
+			err = os.Remove(p.absPath)
+		} else {
+			createdDirs = append(createdDirs, missingDirs(filepath.Dir(p.absPath))...)
+			if err = os.MkdirAll(filepath.Dir(p.absPath), 0o755); err == nil {
+				err = writeFileAtomic(p.absPath, p.final, p.mode)
+			}
+		}
+		if err != nil {
+			failures := rollback()
+			if len(failures) > 0 {
+				return fmt.Errorf("%s %s: %w (rollback incomplete: %s)", p.change.Action, p.absPath, err, strings.Join(failures, "; "))
+			}
+			return fmt.Errorf("%s %s: %w (all changes rolled back)", p.change.Action, p.absPath, err)
+		}
+		applied = append(applied, p)
+	}
+	return nil
+}
+
+// missingDirs returns the ancestors of dir (including dir) that do not exist
+// yet, ordered from shallowest to deepest.
+func missingDirs(dir string) []string {
+	var dirs []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		dirs = append([]string{d}, dirs...)
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	return dirs
+}
+
+// registerApplyChangesTool registers the apply_changes tool. Backups are stored
+// under dataDir/backups/apply_changes.
+func registerApplyChangesTool(executor *ToolExecutor, dataDir string) {
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	backupRoot := filepath.Join(dataDir, "backups", "apply_changes")
+
+	executor.Register(
+		MakeToolDefinition("apply_changes",
+			"Apply a batch of file changes atomically (all or nothing). Use for refactors that touch several files. "+
+				"Every change is validated before any file is written, originals are backed up, and if any step fails "+
+				"all files are restored. Actions: create (new file, must not exist), write (create or overwrite), "+
+				"edit (replace old_text with new_text), delete.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"changes": map[string]any{
+						"type":        "array",
+						"description": "Ordered list of file changes. Each path may appear only once.",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"action": map[string]any{
+									"type":        "string",
+									"enum":        []string{"create", "write", "edit", "delete"},
+									"description": "Operation to perform",
+								},
+								"path": map[string]any{
+									"type":        "string",
+									"description": "File path (absolute or relative)",
+								},
+								"content": map[string]any{
+									"type":        "string",
+									"description": "Full file content (create/write)",
+								},
+								"old_text": map[string]any{
+									"type":        "string",
+									"description": "Exact text to replace (edit; must be unique unless replace_all)",
+								},
+								"new_text": map[string]any{
+									"type":        "string",
+									"description": "Replacement text (edit)",
+								},
+								"replace_all": map[string]any{
+									"type":        "boolean",
+									"description": "Replace every occurrence of old_text (edit). Default: false",
+								},
+							},
+							"required": []string{"action", "path"},
+						},
+					},
+				},
+				"required": []string{"changes"},
+			}),
+		func(_ context.Context, args map[string]any) (any, error) {
+			changes, err := parseFileChanges(args)
+			if err != nil {
+				return nil, err
+			}
+
+			plans, err := planChanges(changes)
+			if err != nil {
+				return nil, fmt.Errorf("validation failed, no files changed: %w", err)
+			}
+
+			backupDir, err := snapshotChanges(backupRoot, plans)
+			if err != nil {
+				return nil, fmt.Errorf("backup failed, no files changed: %w", err)
+			}
+
+			if err := commitChanges(plans); err != nil {
+				return nil, fmt.Errorf("apply_changes: %w", err)
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Applied %d change(s):\n", len(plans))
+			for _, p := range plans {
+				fmt.Fprintf(&b, "  %s %s\n", p.change.Action, p.absPath)
+			}
+			fmt.Fprintf(&b, "Backup: %s", backupDir)
+			return b.String(), nil
+		},
+	)
+}
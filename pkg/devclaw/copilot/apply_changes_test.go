@@ -0,0 +1,185 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyChanges_ValidationLeavesFilesUntouched(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := planChanges([]fileChange{
+		{Action: "edit", Path: a, OldText: "hello", NewText: "bye"},
+		{Action: "edit", Path: filepath.Join(dir, "missing.txt"), OldText: "x", NewText: "y"},
+	})
+	if err == nil {
+		t.Fatalf("expected validation error, got plans %v", plans)
+	}
+
+	got, _ := os.ReadFile(a)
+	if string(got) != "hello world" {
+		t.Errorf("a.txt modified during validation: %q", got)
+	}
+}
+
+func TestApplyChanges_CommitAndSnapshot(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	c := filepath.Join(dir, "sub", "c.txt")
+	os.WriteFile(a, []byte("foo bar"), 0o644)
+	os.WriteFile(b, []byte("remove me"), 0o644)
+
+	plans, err := planChanges([]fileChange{
+		{Action: "edit", Path: a, OldText: "foo", NewText: "baz"},
+		{Action: "delete", Path: b},
+		{Action: "create", Path: c, Content: "new"},
+	})
+	if err != nil {
+		t.Fatalf("planChanges: %v", err)
+	}
+
+	backup, err := snapshotChanges(filepath.Join(dir, "backups"), plans)
+	if err != nil {
+		t.Fatalf("snapshotChanges: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(backup, "manifest.json")); err != nil {
+		t.Errorf("manifest missing: %v", err)
+	}
+
+	if err := commitChanges(plans); err != nil {
+		t.Fatalf("commitChanges: %v", err)
+	}
+
+	if got, _ := os.ReadFile(a); string(got) != "baz bar" {
+		t.Errorf("a.txt = %q, want %q", got, "baz bar")
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Errorf("b.txt should be deleted, stat err = %v", err)
+	}
+	if got, _ := os.ReadFile(c); string(got) != "new" {
+		t.Errorf("c.txt = %q, want %q", got, "new")
+	}
+}
+
+func TestApplyChanges_RollbackOnFailure(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("original"), 0o644)
+
+	plans, err := planChanges([]fileChange{
+		{Action: "write", Path: a, Content: "changed"},
+		{Action: "create", Path: filepath.Join(dir, "blocker", "x.txt"), Content: "x"},
+	})
+	if err != nil {
+		t.Fatalf("planChanges: %v", err)
+	}
+
+	// Make the second write fail after validation by placing a file where
+	// its parent directory should be created.
+	os.WriteFile(filepath.Join(dir, "blocker"), []byte("file"), 0o644)
+
+	err = commitChanges(plans)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "original" {
+		t.Errorf("a.txt = %q after rollback, want %q", got, "original")
+	}
+}
+
+func TestApplyChanges_Rejects(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "exists.txt")
+	os.WriteFile(existing, []byte("dup dup"), 0o644)
+
+	tests := []struct {
+		name    string
+		changes []fileChange
+	}{
+		{"create existing", []fileChange{{Action: "create", Path: existing}}},
+		{"ambiguous edit", []fileChange{{Action: "edit", Path: existing, OldText: "dup", NewText: "x"}}},
+		{"delete missing", []fileChange{{Action: "delete", Path: filepath.Join(dir, "nope")}}},
+		{"duplicate path", []fileChange{{Action: "write", Path: existing}, {Action: "delete", Path: existing}}},
+		{"unknown action", []fileChange{{Action: "move", Path: existing}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := planChanges(tt.changes); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestFormatApplyChangesSummary_ListsEveryPath(t *testing.T) {
+	t.Parallel()
+	args := map[string]any{"changes": []any{
+		map[string]any{"action": "edit", "path": "/tmp/a.go"},
+		map[string]any{"action": "delete", "path": "/tmp/b.go"},
+	}}
+	got := formatApplyChangesSummary(args)
+	for _, p := range []string{"/tmp/a.go", "/tmp/b.go"} {
+		if !strings.Contains(got, p) {
+			t.Errorf("summary missing %s: %q", p, got)
+		}
+	}
+}
+
+func TestApplyChanges_DiffReviewAppliesApprovedFilesOnly(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("one\ntwo\n"), 0o644)
+	os.WriteFile(b, []byte("keep\n"), 0o644)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	exec := NewToolExecutor(logger)
+	registerApplyChangesTool(exec, dir)
+	guardCfg := DefaultToolGuardConfig()
+	guardCfg.AuditLogPath = ""
+	guardCfg.Forensics.Enabled = false
+	guardCfg.RequireConfirmation = []string{"apply_changes"}
+	exec.SetGuard(NewToolGuard(guardCfg, logger))
+
+	var reviewed []ProposedFileChange
+	exec.SetDiffReviewer(func(_ context.Context, _, _, _ string, files []ProposedFileChange) ([]bool, string, error) {
+		reviewed = files
+		return []bool{true, false}, "b must not change", nil
+	})
+
+	args := `{"changes":[{"action":"edit","path":"` + a + `","old_text":"two","new_text":"2"},` +
+		`{"action":"write","path":"` + b + `","content":"changed\n"}]}`
+	ctx := ContextWithCaller(context.Background(), AccessAdmin, "admin@test")
+	res := exec.executeSingle(ctx, ToolCall{ID: "c1", Function: FunctionCall{Name: "apply_changes", Arguments: args}})
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v (%s)", res.Error, res.Content)
+	}
+
+	if len(reviewed) != 2 || !strings.Contains(reviewed[0].Diff, "-two\n+2\n") {
+		t.Fatalf("reviewer should see a diff per file, got %+v", reviewed)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "one\n2\n" {
+		t.Errorf("approved file not written: %q", got)
+	}
+	if got, _ := os.ReadFile(b); string(got) != "keep\n" {
+		t.Errorf("rejected file was written: %q", got)
+	}
+	if !strings.Contains(res.Content, "NOT modified") || !strings.Contains(res.Content, "+changed") || !strings.Contains(res.Content, "b must not change") {
+		t.Errorf("rejected diff and reason should be fed back to the model:\n%s", res.Content)
+	}
+}
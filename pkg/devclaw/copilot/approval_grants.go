@@ -0,0 +1,358 @@
+// Package copilot – approval_grants.go implements scoped approvals. When a
+// confirmation prompt is answered, the answer decides how far it reaches:
+//
+//	/approve <id> once         this call only
+//	/approve <id> [session]    the tool, for the rest of the session (default)
+//	/approve <id> 30m          the tool in this session, for 30 minutes
+//	/approve <id> always [pat] calls of the tool matching pat, in every session
+//
+// Patterns are globs over the call's subject: the command (bash, exec),
+// "host command" (ssh), the path (file tools) or, for tools with none of
+// these (web_fetch, MCP tools), the arguments as canonical JSON. "always"
+// without a pattern approves the exact call again, and for tools without a
+// command or path the session and timed scopes do too, so approving one
+// URL or secret name never approves another. In commands, * does not match
+// shell operators, so "git *" never approves "git status && rm -rf ~". Grants are kept in
+// security.approvals.grants_file and survive restarts; security.approvals.always
+// adds fixed "tool: pattern" grants from config.
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Approval scopes.
+const (
+	ScopeOnce    = "once"
+	ScopeSession = "session"
+	ScopeFor     = "for"
+	ScopeAlways  = "always"
+)
+
+// ApprovalScope is how far an answered approval reaches.
+type ApprovalScope struct {
+	// Kind is one of the Scope* constants ("" = session).
+	Kind string
+
+	// Duration bounds a ScopeFor grant.
+	Duration time.Duration
+
+	// Pattern limits a ScopeAlways grant ("" = the approved call).
+	Pattern string
+}
+
+// ApprovalGrant auto-approves later calls of a tool.
+type ApprovalGrant struct {
+	Tool string `json:"tool"`
+
+	// SessionID limits the grant to one session ("" = every session).
+	SessionID string `json:"session_id,omitempty"`
+
+	// Pattern limits the grant to calls whose subject matches it ("*" =
+	// any call, "" = only the call in Call).
+	Pattern string `json:"pattern,omitempty"`
+
+	// Call is the subject of the one call a grant without a pattern
+	// approves.
+	Call string `json:"call,omitempty"`
+
+	// ExpiresAt ends the grant (zero = until revoked or the session resets).
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+
+	GrantedBy string    `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// expired reports whether the grant has run out at now.
+func (g ApprovalGrant) expired(now time.Time) bool {
+	return !g.ExpiresAt.IsZero() && !now.Before(g.ExpiresAt)
+}
+
+// matches reports whether the grant approves a call of tool in sessionID.
+func (g ApprovalGrant) matches(sessionID, tool string, args map[string]any) bool {
+	if g.Tool != tool || (g.SessionID != "" && g.SessionID != sessionID) {
+		return false
+	}
+	switch g.Pattern {
+	case "*":
+		return true
+	case "":
+		return g.Call != "" && g.Call == approvalSubject(tool, args)
+	}
+	return approvalGlob(g.Pattern, approvalSubject(tool, args), isShellTool(tool))
+}
+
+// String describes the grant for /approvals.
+func (g ApprovalGrant) String() string {
+	s := g.Tool
+	switch {
+	case g.Pattern != "" && g.Pattern != "*":
+		s += ": " + g.Pattern
+	case g.Pattern == "":
+		s += " (only " + g.Call + ")"
+	}
+	if g.SessionID != "" {
+		s += " (session " + g.SessionID + ")"
+	} else {
+		s += " (all sessions)"
+	}
+	if !g.ExpiresAt.IsZero() {
+		s += ", " + formatGrantDuration(time.Until(g.ExpiresAt)) + " left"
+	}
+	return s
+}
+
+// parseApprovalScope reads a scope from the arguments of /approve. ok is
+// false when args do not start with a scope; err is set for a bad one.
+func parseApprovalScope(args []string) (scope ApprovalScope, ok bool, err error) {
+	if len(args) == 0 {
+		return ApprovalScope{}, false, nil
+	}
+	switch word := strings.ToLower(args[0]); word {
+	case ScopeOnce, ScopeSession:
+		return ApprovalScope{Kind: word}, true, nil
+	case ScopeAlways:
+		return ApprovalScope{Kind: ScopeAlways, Pattern: strings.TrimSpace(strings.Join(args[1:], " "))}, true, nil
+	case ScopeFor:
+		if len(args) < 2 {
+			return ApprovalScope{}, true, fmt.Errorf("usage: /approve [id] for <duration>, e.g. for 30m")
+		}
+		args = args[1:]
+	default:
+		if args[0] == "" || !strings.ContainsAny(args[0][:1], "0123456789") {
+			return ApprovalScope{}, false, nil
+		}
+	}
+	d, err := parseGrantDuration(args[0])
+	if err != nil {
+		return ApprovalScope{}, true, err
+	}
+	return ApprovalScope{Kind: ScopeFor, Duration: d}, true, nil
+}
+
+// isShellTool reports tools whose grant patterns match shell commands.
+func isShellTool(tool string) bool {
+	return tool == "bash" || tool == "exec" || tool == "ssh"
+}
+
+// approvalSubject is the part of a call grant patterns match: the command,
+// "host command" for ssh, the path of file tools, or else the arguments as
+// canonical JSON (sorted keys).
+func approvalSubject(tool string, args map[string]any) string {
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return strings.TrimSpace(s)
+	}
+	switch tool {
+	case "bash", "exec":
+		return str("command")
+	case "ssh":
+		return strings.TrimSpace(str("host") + " " + str("command"))
+	case "scp":
+		return strings.TrimSpace(str("source") + " " + str("destination"))
+	}
+	if path := str("path"); path != "" {
+		return path
+	}
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
+// namedSubject reports whether a call's subject is a command or path,
+// rather than its whole arguments.
+func namedSubject(tool string, args map[string]any) bool {
+	if tool == "bash" || tool == "exec" || tool == "ssh" || tool == "scp" {
+		return true
+	}
+	path, _ := args["path"].(string)
+	return strings.TrimSpace(path) != ""
+}
+
+// shellOperators may not be matched by * in a shell pattern, so a grant
+// for one command never covers a chained, piped or substituted one.
+const shellOperators = ";&|`$()<>\n"
+
+// approvalGlob reports whether subject matches pattern, where * matches any
+// run of characters (no shell operators when shell is set) and ? one.
+func approvalGlob(pattern, subject string, shell bool) bool {
+	if subject == "" {
+		return pattern == "*"
+	}
+	star, one := ".*", "."
+	if shell {
+		class := regexp.QuoteMeta(shellOperators)
+		star, one = "[^"+class+"]*", "[^"+class+"]"
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(star)
+		case '?':
+			b.WriteString(one)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(subject)
+}
+
+// configGrants returns the "tool: pattern" grants of approvals.always.
+func configGrants(always []string) []ApprovalGrant {
+	var grants []ApprovalGrant
+	for _, entry := range always {
+		tool, pattern, _ := strings.Cut(entry, ":")
+		if tool = strings.TrimSpace(tool); tool != "" {
+			// A config entry without a pattern covers the whole tool.
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				pattern = "*"
+			}
+			grants = append(grants, ApprovalGrant{Tool: tool, Pattern: pattern, GrantedBy: "config"})
+		}
+	}
+	return grants
+}
+
+// LoadGrants reads the grants kept in path and keeps later grants there.
+// A missing file starts empty.
+func (m *ApprovalManager) LoadGrants(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.grantsPath = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var grants []ApprovalGrant
+	if err := json.Unmarshal(data, &grants); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	now := time.Now()
+	m.grants = slices.DeleteFunc(grants, func(g ApprovalGrant) bool { return g.expired(now) })
+	return nil
+}
+
+// saveGrantsLocked writes the grants to the grants file. Callers hold m.mu.
+func (m *ApprovalManager) saveGrantsLocked() {
+	if m.grantsPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.grants, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(m.grantsPath), 0o755); err == nil {
+			err = writeFileAtomic(m.grantsPath, data, 0o600)
+		}
+	}
+	if err != nil {
+		m.logger.Warn("failed to save approval grants", "path", m.grantsPath, "error", err)
+	}
+}
+
+// grantFor returns the grant approving a call, if any.
+func (m *ApprovalManager) grantFor(sessionID, tool string, args map[string]any) (ApprovalGrant, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, g := range append(configGrants(m.cfg.Always), m.grants...) {
+		if !g.expired(now) && g.matches(sessionID, tool, args) {
+			return g, true
+		}
+	}
+	return ApprovalGrant{}, false
+}
+
+// applyScope records the grant an answered approval of a call asks for.
+func (m *ApprovalManager) applyScope(sessionID, tool string, args map[string]any, res ApprovalResult) {
+	g := ApprovalGrant{Tool: tool, SessionID: sessionID, GrantedBy: res.ResolvedBy, GrantedAt: time.Now()}
+	// Session and timed grants cover the tool when its calls have a command
+	// or path; otherwise only this exact call.
+	g.Pattern = "*"
+	if !namedSubject(tool, args) {
+		g.Pattern, g.Call = "", approvalSubject(tool, args)
+	}
+	switch res.Scope.Kind {
+	case ScopeOnce:
+		return
+	case ScopeFor:
+		g.ExpiresAt = g.GrantedAt.Add(res.Scope.Duration)
+	case ScopeAlways:
+		g.SessionID, g.Pattern, g.Call = "", res.Scope.Pattern, ""
+		if g.Pattern == "" {
+			g.Call = approvalSubject(tool, args)
+		}
+	}
+	m.AddGrant(g)
+}
+
+// AddGrant records a grant, replacing one for the same tool, session,
+// pattern and call.
+func (m *ApprovalManager) AddGrant(g ApprovalGrant) {
+	if g.GrantedAt.IsZero() {
+		g.GrantedAt = time.Now()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.grants = slices.DeleteFunc(m.grants, func(old ApprovalGrant) bool {
+		return old.expired(now) || (old.Tool == g.Tool && old.SessionID == g.SessionID && old.Pattern == g.Pattern && old.Call == g.Call)
+	})
+	m.grants = append(m.grants, g)
+	m.saveGrantsLocked()
+	m.logger.Info("approval grant added", "grant", g.String(), "by", g.GrantedBy)
+}
+
+// Grants returns the active grants, oldest first, followed by those of
+// approvals.always.
+func (m *ApprovalManager) Grants() []ApprovalGrant {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var out []ApprovalGrant
+	for _, g := range m.grants {
+		if !g.expired(now) {
+			out = append(out, g)
+		}
+	}
+	return append(out, configGrants(m.cfg.Always)...)
+}
+
+// RevokeGrant removes a grant returned by Grants. Grants from config
+// cannot be revoked.
+func (m *ApprovalManager) RevokeGrant(g ApprovalGrant) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.grants)
+	m.grants = slices.DeleteFunc(m.grants, func(old ApprovalGrant) bool {
+		return old.Tool == g.Tool && old.SessionID == g.SessionID && old.Pattern == g.Pattern && old.Call == g.Call && old.GrantedAt.Equal(g.GrantedAt)
+	})
+	if len(m.grants) == n {
+		return false
+	}
+	m.saveGrantsLocked()
+	return true
+}
+
+// RevokeAllGrants removes every recorded grant and returns how many.
+func (m *ApprovalManager) RevokeAllGrants() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.grants)
+	m.grants = nil
+	if n > 0 {
+		m.saveGrantsLocked()
+	}
+	return n
+}
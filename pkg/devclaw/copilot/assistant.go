@@ -12,11 +12,15 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels/webhook"
+	"github.com/jholhewres/devclaw/pkg/devclaw/coordination"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/security"
 	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
@@ -89,11 +93,16 @@ type Assistant struct {
 	activeRuns   map[string]context.CancelFunc
 	activeRunsMu sync.Mutex
 
+	// runProgress tracks the turn state of in-flight runs for the stall
+	// watchdog (same keys as activeRuns).
+	runProgress   map[string]*runProgress
+	runProgressMu sync.Mutex
+
 	// interruptInboxes maps sessionID (channel:chatID) → channel for injecting
 	// follow-up messages into active agent runs. When a user sends a message
 	// while the agent is processing, the enriched content is pushed here so the
 	// agent loop picks it up on its next turn (Claude Code-style).
-	interruptInboxes   map[string]chan string
+	interruptInboxes   map[string]chan Interrupt
 	interruptInboxesMu sync.Mutex
 
 	// followupQueues holds messages received while a session is busy.
@@ -116,6 +125,49 @@ type Assistant struct {
 	// scheduler, session persistence, and audit logger.
 	devclawDB *sql.DB
 
+	// topicAnalyzer builds monthly conversation topic reports (nil if disabled).
+	topicAnalyzer *TopicAnalyzer
+
+	// quotaMgr enforces per-workspace monthly spend quotas (nil if disabled).
+	quotaMgr *QuotaManager
+
+	// reviewQueue holds replies of workspaces with review enabled.
+	reviewQueue *ReviewQueue
+
+	// usageLedger records every LLM call for exports and usage reports
+	// (nil if billing is disabled).
+	usageLedger *UsageLedger
+
+	// usageLog persists usage tracking to daily JSONL files (nil if
+	// disabled).
+	usageLog *UsageLog
+
+	// tracer exports a trace per handled message over OTLP (nil if
+	// tracing is disabled).
+	tracer *Tracer
+
+	// msgDedup drops redelivered messages (nil if idempotency is disabled).
+	msgDedup *MessageDedup
+
+	// eventLog records state changes (grants, reloads, workspaces, skills)
+	// for `devclaw events tail` and GET /api/events (nil if disabled).
+	eventLog *StateEventLog
+
+	// warmup tracks the cold-start warmup phase reported by /health.
+	warmup warmupState
+
+	// ownerAlerter delivers critical notifications with channel failover.
+	ownerAlerter *OwnerAlerter
+
+	// coordinator runs per-channel leader election when several instances
+	// share the same channel sessions (nil when coordination is disabled).
+	coordinator *coordination.Coordinator
+
+	// budgetAlerted tracks which budget thresholds were already reported,
+	// keyed by "month:level" (e.g. "2026-10:80").
+	budgetAlertMu sync.Mutex
+	budgetAlerted map[string]bool
+
 	// ttsProvider handles text-to-speech synthesis (nil if TTS is disabled).
 	ttsProvider tts.Provider
 
@@ -125,6 +177,19 @@ type Assistant struct {
 	// daemonMgr manages background processes (dev servers, watchers, etc.).
 	daemonMgr *DaemonManager
 
+	// cmdWatcher runs watch_command checks in the background.
+	cmdWatcher *CommandWatcher
+
+	// knowledgeBase holds idle-session summaries and workspace facts.
+	knowledgeBase *KnowledgeBase
+
+	// corpora holds the document corpora searched by corpus_search (nil if
+	// disabled).
+	corpora *CorpusStore
+
+	// glossary holds the workspace glossaries (nil if disabled).
+	glossary *Glossary
+
 	// pluginMgr manages installed plugins (GitHub, Jira, Sentry, etc.).
 	pluginMgr *PluginManager
 
@@ -158,6 +223,12 @@ func New(cfg *Config, logger *slog.Logger) *Assistant {
 
 	// Initialize approval manager for RequireConfirmation tools.
 	approvalMgr := NewApprovalManager(logger)
+	approvalMgr.SetConfig(cfg.Security.Approvals)
+	if path := cfg.Security.Approvals.GrantsFile; path != "" {
+		if err := approvalMgr.LoadGrants(path); err != nil {
+			logger.Warn("failed to load approval grants", "path", path, "error", err)
+		}
+	}
 
 	// Initialize project manager for coding skills.
 	dataDir := filepath.Dir(cfg.Memory.Path)
@@ -183,13 +254,24 @@ func New(cfg *Config, logger *slog.Logger) *Assistant {
 		subagentMgr:      NewSubagentManager(cfg.Subagents, logger),
 		hookMgr:          NewHookManager(logger),
 		projectMgr:       projectMgr,
+		reviewQueue:      NewReviewQueue(filepath.Join(dataDir, "review_queue.json"), logger),
 		activeRuns:       make(map[string]context.CancelFunc),
-		interruptInboxes: make(map[string]chan string),
+		interruptInboxes: make(map[string]chan Interrupt),
 		followupQueues:   make(map[string][]*channels.IncomingMessage),
 		usageTracker:     NewUsageTracker(logger.With("component", "usage")),
 		logger:           logger,
 	}
 
+	secrets := security.NewSecretRedactor(cfg.Security.SecretRedaction, logger)
+	a.outputGuard.SetSecretRedactor(secrets)
+	te.SetSecretRedactor(secrets)
+	if err := te.SetInjectionScan(cfg.Security.InjectionScan); err != nil {
+		logger.Warn("injection scan config", "error", err)
+	}
+
+	a.llmClient.SetCacheHitHandler(a.usageTracker.RecordCacheHit)
+	a.usageTracker.SetBudgets(cfg.Budget)
+
 	// Initialize tool loop detection config (detectors are created per-run to avoid races).
 	// Use defaults, then apply user overrides. NewToolLoopDetector normalizes zero-values.
 	a.loopDetectorConfig = cfg.Agent.ToolLoop
@@ -209,21 +291,76 @@ func New(cfg *Config, logger *slog.Logger) *Assistant {
 	}
 	a.messageQueue = NewMessageQueue(debounceMs, maxPending, a.handleDrainedMessages, logger)
 
+	// Model registry: models.yaml limits, prices and capabilities.
+	if registry, err := LoadModelRegistry(cfg.ModelsFile); err != nil {
+		logger.Warn("model registry not loaded, using built-in model defaults", "error", err)
+	} else {
+		if registry.Len() > 0 {
+			logger.Info("model registry loaded", "path", cfg.ModelsFile, "models", registry.Len())
+		}
+		a.llmClient.SetModelRegistry(registry)
+		a.usageTracker.SetModelRegistry(registry)
+	}
+	a.promptComposer.SetKnowledgeCutoff(a.llmClient.KnowledgeCutoff)
+
+	// Owner alerts: critical events fail over across the configured contacts.
+	a.ownerAlerter = NewOwnerAlerter(cfg.OwnerAlerts, a.channelMgr, logger)
+	te.SetGuardBlockHandler(func(toolName, callerJID string, level AccessLevel, reason string) {
+		a.AlertOwner(OwnerAlertSecurity,
+			fmt.Sprintf("Tool %s blocked", toolName),
+			fmt.Sprintf("Caller: %s (%s)\nReason: %s", callerJID, level, reason))
+	})
+
+	// Temporary access grants (/grant) are recorded in the audit log.
+	a.accessMgr.SetGrantHook(func(event GrantEvent, e AccessEntry) {
+		by := e.AddedBy
+		if event == GrantEventExpired {
+			by = "system"
+		}
+		a.toolExecutor.Guard().AuditLog("access_grant", by, AccessOwner, map[string]any{
+			"contact":    e.JID,
+			"level":      string(e.Level),
+			"expires_at": e.ExpiresAt.Format(time.RFC3339),
+		}, true, string(event))
+		eventType := EventAccessTempGrant
+		if event == GrantEventExpired {
+			eventType = EventAccessGrantExpired
+		}
+		a.eventLog.Emit(eventType, by, e.JID, map[string]any{
+			"level":      string(e.Level),
+			"expires_at": e.ExpiresAt.Format(time.RFC3339),
+		})
+	})
+
 	// Wire confirmation requester for tools in RequireConfirmation list.
-	te.SetConfirmationRequester(func(sessionID, callerJID, toolName string, args map[string]any) (bool, error) {
-		sendMsg := func(msg string) {
+	sendToSession := func(sessionID string) func(msg *channels.OutgoingMessage) {
+		return func(msg *channels.OutgoingMessage) {
 			channel, chatID, ok := strings.Cut(sessionID, ":")
 			if !ok {
 				return
 			}
-			_ = a.channelMgr.Send(a.ctx, channel, chatID, &channels.OutgoingMessage{Content: msg})
+			_ = a.channelMgr.Send(a.ctx, channel, chatID, msg)
 		}
-		return approvalMgr.Request(sessionID, callerJID, toolName, args, sendMsg)
+	}
+	te.SetConfirmationRequester(func(sessionID, callerJID, toolName string, args map[string]any) (bool, error) {
+		return approvalMgr.Request(sessionID, callerJID, toolName, args, sendToSession(sessionID))
+	})
+	// File changes are reviewed as per-file diffs instead.
+	te.SetDiffReviewer(func(_ context.Context, sessionID, callerJID, toolName string, files []ProposedFileChange) ([]bool, string, error) {
+		return approvalMgr.RequestFiles(sessionID, callerJID, toolName, files, sendToSession(sessionID))
 	})
 
 	// Wire subagent announce callback: when a subagent completes, push the
 	// result to the parent's channel instead of requiring the agent to poll
 	// with wait_subagent.
+	// Subagent context selectors read facts/turns from the parent session.
+	a.subagentMgr.SetSessionResolver(func(sessionID string) *Session {
+		if s := a.workspaceMgr.FindSessionByID(sessionID); s != nil {
+			return s
+		}
+		return a.sessionStore.GetByID(sessionID)
+	})
+
 	a.subagentMgr.SetAnnounceCallback(func(run *SubagentRun) {
 		sessionID := run.ParentSessionID
 		channel, chatID, ok := strings.Cut(sessionID, ":")
@@ -278,6 +415,12 @@ func (a *Assistant) Start(ctx context.Context) error {
 	// 0pre-b. Auto-resolve media transcription provider from main API config.
 	a.config.Media.ResolveForProvider(a.config.API.Provider, a.config.API.BaseURL)
 
+	// 0pre-c. Local models: pull the configured models into Ollama now
+	// rather than on the first message.
+	if err := a.llmClient.EnsureLocalModels(a.ctx); err != nil {
+		a.logger.Warn("local model not ready", "error", err)
+	}
+
 	// 0. Initialize memory stores.
 	memDir := filepath.Join(filepath.Dir(a.config.Memory.Path), "memory")
 	memStore, err := memory.NewFileStore(memDir)
@@ -287,14 +430,24 @@ func (a *Assistant) Start(ctx context.Context) error {
 		a.memoryStore = memStore
 	}
 
+	embedCfg := a.config.Memory.Embedding
+	// Use main API key if embedding key not set.
+	if embedCfg.APIKey == "" {
+		embedCfg.APIKey = a.config.API.APIKey
+	}
+	embedder := memory.NewEmbeddingProvider(embedCfg)
+
+	// 0-1. Semantic recall over MEMORY.md (incrementally indexed on save).
+	if memStore != nil && embedder.Name() != "none" {
+		memStore.SetEmbedder(embedder)
+		a.logger.Info("file memory semantic recall enabled",
+			"embedding_provider", embedder.Name(),
+			"model", embedder.Model(),
+		)
+	}
+
 	// 0a. Initialize SQLite memory with FTS5 + vector search (if configured).
 	if a.config.Memory.Type == "sqlite" {
-		embedCfg := a.config.Memory.Embedding
-		// Use main API key if embedding key not set.
-		if embedCfg.APIKey == "" {
-			embedCfg.APIKey = a.config.API.APIKey
-		}
-		embedder := memory.NewEmbeddingProvider(embedCfg)
 
 		dbPath := a.config.Memory.Path
 		if dbPath == "" {
@@ -337,6 +490,14 @@ func (a *Assistant) Start(ctx context.Context) error {
 	if a.sqliteMemory != nil {
 		a.promptComposer.SetSQLiteMemory(a.sqliteMemory)
 	}
+	// 0b-1. Knowledge base: idle sessions are summarized into per-workspace
+	// KB entries; sessions pruned before the sweeper saw them are caught by
+	// the prune hook.
+	if a.config.Memory.KnowledgeBase.Enabled {
+		a.knowledgeBase = NewKnowledgeBase(a.config.Memory.KnowledgeBase.Dir, a.logger)
+		a.promptComposer.SetKnowledgeBase(a.knowledgeBase)
+		a.workspaceMgr.SetPruneHook(a.summarizeIdleSession)
+	}
 	a.promptComposer.SetSkillGetter(func(name string) (interface{ SystemPrompt() string }, bool) {
 		skill, ok := a.skillRegistry.Get(name)
 		if !ok {
@@ -344,6 +505,15 @@ func (a *Assistant) Start(ctx context.Context) error {
 		}
 		return skill, true
 	})
+	// Workspace personas see only their own tools.
+	a.toolExecutor.SetWorkspaceScopes(func(wsID string) ToolScope {
+		ws, ok := a.workspaceMgr.Get(wsID)
+		if !ok {
+			return ToolScope{}
+		}
+		denied := append(slices.Clone(ws.DeniedTools), a.featureDeniedTools(ws)...)
+		return ToolScope{Tools: ws.Tools, Denied: denied, Skills: ws.Skills}
+	})
 
 	// 0c. Open the central devclaw.db and wire all SQLite-backed storage.
 	dbPath := a.config.Database.Path
@@ -363,9 +533,13 @@ func (a *Assistant) Start(ctx context.Context) error {
 		MigrateToSQLite(devclawDB, dataDir, a.logger.With("component", "migrate"))
 	}
 
-	// 0c-1. Session persistence: prefer SQLite, fall back to JSONL.
+	// 0c-1. Session persistence (memory.backend): SQLite by default, falling
+	// back to JSONL when devclaw.db is unavailable; "memory" disables it.
 	var sessPersister SessionPersister
-	if a.devclawDB != nil {
+	backend := strings.ToLower(a.config.Memory.Backend)
+	if backend == "memory" {
+		a.logger.Warn("session persistence disabled (memory backend), sessions are lost on restart")
+	} else if a.devclawDB != nil && backend != "jsonl" {
 		sessPersister = NewSQLiteSessionPersistence(a.devclawDB, a.logger.With("component", "session-persist"))
 		a.sessionStore.SetPersistence(sessPersister)
 		a.logger.Info("session persistence enabled (SQLite)")
@@ -389,6 +563,13 @@ func (a *Assistant) Start(ctx context.Context) error {
 	if sessPersister != nil && a.workspaceMgr != nil {
 		a.workspaceMgr.SetPersistence(sessPersister)
 	}
+	if days := a.config.Memory.SessionRetentionDays; days > 0 {
+		retention := time.Duration(days) * 24 * time.Hour
+		a.sessionStore.SetRetention(retention)
+		if a.workspaceMgr != nil {
+			a.workspaceMgr.SetSessionRetention(retention)
+		}
+	}
 
 	// 0c-2. Audit logger: prefer SQLite, fall back to file-based.
 	if a.devclawDB != nil {
@@ -409,6 +590,97 @@ func (a *Assistant) Start(ctx context.Context) error {
 		a.logger.Info("subagent persistence enabled (SQLite)")
 	}
 
+	// 0c-4. Topic analytics: monthly per-workspace conversation reports.
+	if a.devclawDB != nil && a.config.Analytics.Enabled {
+		ta, err := NewTopicAnalyzer(a.devclawDB, a.config.Analytics, a.workspaceMgr.WorkspaceIDForSession, a.logger)
+		if err != nil {
+			a.logger.Warn("topic analytics not available", "error", err)
+		} else {
+			a.topicAnalyzer = ta
+			go ta.Run(a.ctx)
+		}
+	}
+
+	// 0c-5. Workspace quotas: monthly spend limits checked before each run.
+	if a.config.Quotas.Enabled {
+		if a.devclawDB == nil {
+			a.logger.Warn("workspace quotas need devclaw.db, not enforced")
+		} else if qm, err := NewQuotaManager(a.devclawDB, a.config.Quotas, a.logger); err != nil {
+			a.logger.Warn("workspace quotas not available", "error", err)
+		} else {
+			a.quotaMgr = qm
+		}
+	}
+
+	// 0c-5b. Usage ledger: per-call usage for exports and monthly reports.
+	if a.config.Billing.Enabled && a.devclawDB != nil {
+		if ul, err := NewUsageLedger(a.devclawDB, a.config.Billing, a.logger); err != nil {
+			a.logger.Warn("usage ledger not available", "error", err)
+		} else {
+			a.usageLedger = ul
+			if a.config.Billing.MonthlyReport || len(a.config.Billing.Push) > 0 {
+				go ul.Run(a.ctx, a.deliverUsageReport)
+			}
+		}
+	}
+
+	// 0c-5c. Usage log: usage history on disk, replayed so totals and
+	// budgets survive restarts.
+	if a.config.UsageLog.Enabled {
+		if ul, err := OpenUsageLog(a.config.UsageLog, a.logger); err != nil {
+			a.logger.Warn("usage log not available", "error", err)
+		} else {
+			records, err := ul.Load()
+			if err != nil {
+				a.logger.Warn("failed to read usage log", "error", err)
+			}
+			a.usageTracker.Restore(records)
+			a.primeBudgetAlerts()
+			a.usageTracker.SetLog(ul)
+			a.usageLog = ul
+			go ul.Run(a.ctx)
+		}
+	}
+
+	// 0c-5d. Glossary: workspace domain terms injected when mentioned.
+	if a.config.Memory.Glossary.Enabled && a.devclawDB != nil {
+		if g, err := NewGlossary(a.devclawDB, a.logger); err != nil {
+			a.logger.Warn("glossary not available", "error", err)
+		} else {
+			a.glossary = g
+			a.promptComposer.SetGlossary(g)
+		}
+	}
+
+	// 0c-6. State event log: append-only record of admin changes.
+	if a.config.EventLog.Enabled {
+		el, err := OpenStateEventLog(a.config.EventLog.Path, a.logger)
+		if err != nil {
+			a.logger.Warn("state event log not available", "error", err)
+		} else {
+			a.eventLog = el
+			a.accessMgr.SetEventLog(el)
+			a.workspaceMgr.SetEventLog(el)
+		}
+	}
+
+	// 0c-7. Idempotency: remember incoming message IDs so redeliveries
+	// (webhook retries, reconnect replays) are handled once.
+	if a.config.Idempotency.Enabled {
+		md, err := NewMessageDedup(a.devclawDB, a.config.Idempotency, a.logger)
+		if err != nil {
+			a.logger.Warn("message deduplication not available", "error", err)
+		} else {
+			a.msgDedup = md
+		}
+	}
+
+	// 0c-8. Tracing: OpenTelemetry spans across the message pipeline.
+	if a.config.Tracing.Enabled {
+		a.tracer = NewTracer(a.config.Tracing, a.logger)
+		go a.tracer.Run(a.ctx)
+	}
+
 	// 1. Register skill loaders and load all skills.
 	a.registerSkillLoaders()
 	if err := a.skillRegistry.LoadAll(a.ctx); err != nil {
@@ -429,7 +701,30 @@ func (a *Assistant) Start(ctx context.Context) error {
 	// 1e. Register system tools (needs scheduler to be created first).
 	a.registerSystemTools()
 
+	// 1f. Leader election: only the owning instance connects each channel.
+	if a.config.Coordination.Enabled {
+		coord, err := coordination.New(a.config.Coordination, a.logger)
+		if err != nil {
+			a.logger.Error("coordination not available, connecting all channels", "error", err)
+		} else {
+			a.coordinator = coord
+			a.channelMgr.SetCoordinator(coord)
+			if a.msgDedup != nil {
+				a.msgDedup.SetCoordinator(coord)
+			}
+			a.logger.Info("multi-instance coordination enabled",
+				"backend", a.config.Coordination.Backend,
+				"instance", coord.InstanceID(),
+			)
+		}
+	}
+
+	// 1g. Route webhook events to their configured workspaces.
+	a.assignWebhookWorkspaces()
+
 	// 2. Start channel manager (non-fatal: webui/gateway can work without channels).
+	// Native command menus (Discord slash commands) register on connect.
+	a.channelMgr.SetCommands(a.ctx, chatCommandSpecs)
 	if err := a.channelMgr.Start(a.ctx); err != nil {
 		a.logger.Warn("channels not connected yet (will retry in background)", "error", err)
 	}
@@ -450,11 +745,23 @@ func (a *Assistant) Start(ctx context.Context) error {
 		a.heartbeat.Start(a.ctx)
 	}
 
+	// 5b. Warm up the LLM connection, prompt layers and indexes so the
+	// first message doesn't pay the cold-start cost.
+	go a.runWarmup()
+
 	// 6. Start main message processing loop.
 	go a.messageLoop()
 
 	// 6b. Start session watchdog to recover stuck sessions.
 	go a.sessionWatchdog()
+	if a.config.Agent.Watchdog.Enabled {
+		go a.runStallWatchdog()
+	}
+
+	// 6c. Summarize idle sessions into the workspace knowledge base.
+	if a.knowledgeBase != nil {
+		go a.runKnowledgeBaseSweeper()
+	}
 
 	// 7. Run BOOT.md if present (gateway startup).
 	// Executes after all channels are connected, with a short delay for stabilization.
@@ -465,8 +772,8 @@ func (a *Assistant) Start(ctx context.Context) error {
 	// re-submitted so the user doesn't lose work in progress.
 	go a.resumeInterruptedRuns()
 
-	// 8. Initialize TTS provider if enabled.
-	if a.config.TTS.Enabled {
+	// 8. Initialize TTS provider if enabled (voice replies need it too).
+	if a.config.TTS.Enabled || a.config.Media.VoiceReplies {
 		a.ttsProvider = a.buildTTSProvider()
 		if a.ttsProvider != nil {
 			a.logger.Info("TTS enabled", "provider", a.config.TTS.Provider, "voice", a.config.TTS.Voice, "mode", a.config.TTS.AutoMode)
@@ -537,6 +844,9 @@ func (a *Assistant) Stop() {
 		a.scheduler.Stop()
 	}
 	a.channelMgr.Stop()
+	if a.coordinator != nil {
+		_ = a.coordinator.Close()
+	}
 	a.skillRegistry.ShutdownAll()
 
 	// Close SQLite memory store.
@@ -546,6 +856,19 @@ func (a *Assistant) Stop() {
 		}
 	}
 
+	if err := a.eventLog.Close(); err != nil {
+		a.logger.Warn("error closing event log", "error", err)
+	}
+	if err := a.usageLog.Close(); err != nil {
+		a.logger.Warn("error closing usage log", "error", err)
+	}
+	if err := a.tracer.Close(); err != nil {
+		a.logger.Warn("error exporting traces", "error", err)
+	}
+	if guard := a.toolExecutor.Guard(); guard != nil {
+		guard.Close() // flushes the audit sinks
+	}
+
 	// Close central devclaw.db.
 	if a.devclawDB != nil {
 		if err := a.devclawDB.Close(); err != nil {
@@ -556,6 +879,11 @@ func (a *Assistant) Stop() {
 	a.logger.Info("DevClaw Copilot stopped")
 }
 
+// EventLog returns the state event log (nil if disabled).
+func (a *Assistant) EventLog() *StateEventLog {
+	return a.eventLog
+}
+
 // ApplyConfigUpdate applies hot-reloadable config changes. Updates: access control,
 // instructions, tool guard, heartbeat, token budget. Does NOT update: API, channels,
 // model, plugins (require restart).
@@ -567,19 +895,28 @@ func (a *Assistant) ApplyConfigUpdate(newCfg *Config) {
 	a.config.Access = newCfg.Access
 	a.config.Security.ToolGuard = newCfg.Security.ToolGuard
 	a.config.Security.ToolExecutor = newCfg.Security.ToolExecutor
+	a.config.Security.Approvals = newCfg.Security.Approvals
+	a.config.Security.InjectionScan = newCfg.Security.InjectionScan
 	a.config.Heartbeat = newCfg.Heartbeat
 	a.config.TokenBudget = newCfg.TokenBudget
+	a.config.Workspaces.Features = newCfg.Workspaces.Features
+	a.config.Budget = newCfg.Budget
 
 	a.accessMgr.ApplyConfig(newCfg.Access)
 	a.toolExecutor.UpdateGuardConfig(newCfg.Security.ToolGuard)
 	a.toolExecutor.Configure(newCfg.Security.ToolExecutor)
+	a.approvalMgr.SetConfig(newCfg.Security.Approvals)
+	if err := a.toolExecutor.SetInjectionScan(newCfg.Security.InjectionScan); err != nil {
+		a.logger.Warn("injection scan config", "error", err)
+	}
+	a.usageTracker.SetBudgets(newCfg.Budget)
 	if a.heartbeat != nil {
 		a.heartbeat.UpdateConfig(newCfg.Heartbeat)
 	}
 
-	a.logger.Info("config hot-reload applied",
-		"updated", []string{"access", "instructions", "tool_guard", "heartbeat", "token_budget"},
-	)
+	updated := []string{"access", "instructions", "tool_guard", "approvals", "injection_scan", "heartbeat", "token_budget", "workspace_features", "budget"}
+	a.logger.Info("config hot-reload applied", "updated", updated)
+	a.eventLog.Emit(EventConfigReload, "config", "", map[string]any{"updated": updated})
 }
 
 // UpdateMediaConfig safely updates the media configuration under lock.
@@ -601,6 +938,15 @@ func (a *Assistant) ChannelManager() *channels.Manager {
 	return a.channelMgr
 }
 
+// CoordinationStatus returns which instance owns each channel, or nil when
+// multi-instance coordination is disabled.
+func (a *Assistant) CoordinationStatus() []coordination.Ownership {
+	if a.coordinator == nil {
+		return nil
+	}
+	return a.coordinator.Status()
+}
+
 // SetVault sets the unlocked vault for the assistant (enables vault tools).
 func (a *Assistant) SetVault(v *Vault) {
 	a.vault = v
@@ -736,7 +1082,7 @@ func (a *Assistant) handleBusySession(msg *channels.IncomingMessage, sessionID s
 		if hasInbox {
 			enriched := a.enrichMessageContent(a.ctx, msg, logger)
 			select {
-			case inbox <- enriched:
+			case inbox <- Interrupt{Content: enriched}:
 				logger.Debug("message injected into active run (steer)", "session", sessionID)
 				a.channelMgr.SendReaction(a.ctx, msg.Channel, msg.ChatID, msg.ID, "👀")
 				return
@@ -827,7 +1173,7 @@ func (a *Assistant) messageLoop() {
 			if !ok {
 				return
 			}
-			go a.handleMessage(msg)
+			go a.handleIncoming(msg)
 
 		case <-a.ctx.Done():
 			return
@@ -835,9 +1181,25 @@ func (a *Assistant) messageLoop() {
 	}
 }
 
+// handleIncoming handles a message delivered by a channel, unless the same
+// message was already delivered before. Only channel deliveries are claimed:
+// queued followups re-enter handleMessage with their original ID.
+func (a *Assistant) handleIncoming(msg *channels.IncomingMessage) {
+	if a.msgDedup != nil && !a.msgDedup.Claim(a.ctx, msg.Channel, msg.ID) {
+		a.logger.Info("duplicate message ignored",
+			"channel", msg.Channel,
+			"chat_id", msg.ChatID,
+			"msg_id", msg.ID,
+		)
+		return
+	}
+	a.handleMessage(msg)
+}
+
 // handleMessage processes an individual message following the full flow:
 // access check → command → trigger → workspace → validate → build → execute → validate → send.
 func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
+	defer a.recoverCrash("handleMessage", msg.Channel+":"+msg.ChatID)
 	start := time.Now()
 	logger := a.logger.With(
 		"channel", msg.Channel,
@@ -846,6 +1208,11 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 		"msg_id", msg.ID,
 	)
 
+	// Every message is one trace (tracing.go).
+	msgCtx, span := a.tracer.Start(a.ctx, "handleMessage",
+		"channel", msg.Channel, "chat_id", msg.ChatID, "message_id", msg.ID)
+	defer span.End()
+
 	logger.Info("incoming message",
 		"content_preview", truncate(msg.Content, 50),
 		"type", msg.Type,
@@ -876,7 +1243,24 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 
 	// ── Step 1: Admin commands ──
 	// Check for /commands BEFORE trigger check (commands always work).
-	if IsCommand(msg.Content) {
+	// "/plan <task>" is not a command but a dry run of the task.
+	// "/urgent" and "/cancel-and-ask" go to the active run (interrupts.go),
+	// or are regular messages without one.
+	planTask, planMode := planCommandTask(msg.Content)
+	if planMode {
+		planMsg := *msg
+		planMsg.Content = planTask
+		msg = &planMsg
+	} else if prio, text, ok := parseInterruptCommand(msg.Content); ok {
+		if a.interruptRun(msg, prio, text) {
+			logger.Info("interrupt delivered to active run", "priority", prio,
+				"duration_ms", time.Since(start).Milliseconds())
+			return
+		}
+		plain := *msg
+		plain.Content = text
+		msg = &plain
+	} else if IsCommand(msg.Content) {
 		result := a.HandleCommand(msg)
 		if result.Handled {
 			if result.Response != "" {
@@ -938,15 +1322,39 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 	logger = logger.With("workspace", workspace.ID)
 
 	// ── Step 3: Check trigger ──
-	// Use workspace trigger if set, otherwise global.
-	trigger := a.config.Trigger
-	if workspace.Trigger != "" {
-		trigger = workspace.Trigger
-	}
-	if !a.matchesTrigger(msg.Content, trigger, msg.IsGroup) {
+	// Use workspace triggers if set, otherwise global. /plan and the answer
+	// to a pending plan need none.
+	pendingPlan := session.PendingPlan()
+	planAnswer := pendingPlan != nil && matchNaturalApproval(msg.Content) != ""
+	if !planMode && !planAnswer && !a.triggerFor(workspace).matches(msg) {
 		return
 	}
 
+	// ── Step 3a: Enforce the workspace quota before spending anything ──
+	// Owners are never locked out so they can still run /quota commands
+	// and talk to the bot when a workspace runs dry.
+	if a.quotaMgr != nil && accessResult.Level != AccessOwner {
+		if st, ok := a.quotaMgr.Allow(workspace.ID); !ok {
+			logger.Info("workspace quota exhausted", "spent_usd", st.SpentUSD)
+			a.sendReply(msg, fmt.Sprintf(
+				"This workspace has used its monthly quota ($%.2f). It resets on %s — ask an owner for a top-up.",
+				st.LimitUSD+st.TopUpUSD, st.ResetsAt.Format("2006-01-02")))
+			return
+		}
+	}
+	// Per-user and per-workspace token and cost budgets (usage_budgets.go).
+	if accessResult.Level != AccessOwner {
+		budget := a.usageTracker.CheckBudgets(msg.From, workspace.ID)
+		if budget.Exhausted {
+			logger.Info("usage budget exhausted", "reason", budget.Message)
+			a.sendReply(msg, budget.Message)
+			return
+		}
+		if budget.Message != "" {
+			a.sendReply(msg, budget.Message)
+		}
+	}
+
 	logger.Info("message received, processing...",
 		"access_level", accessResult.Level)
 
@@ -968,6 +1376,35 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 		return
 	}
 
+	// ── Step 5a: Plan mode (plan_mode.go) ──
+	// A pending plan runs on "go", is dropped on "no", and is revised by any
+	// other message; /plan <task> proposes a new one.
+	var agentInput, planRequest string
+	var plan *planPolicy
+	switch {
+	case planMode:
+		planRequest = userContent
+		agentInput = planPrompt(planRequest, "", "")
+	case pendingPlan != nil:
+		switch matchNaturalApproval(userContent) {
+		case "approve":
+			session.SetPendingPlan(nil)
+			agentInput = executePlanPrompt(pendingPlan)
+			logger.Info("plan approved, executing")
+		case "deny":
+			session.SetPendingPlan(nil)
+			a.sendReply(msg, "Plan dropped.")
+			a.channelMgr.SendReaction(a.ctx, msg.Channel, msg.ChatID, msg.ID, "✅")
+			return
+		default:
+			planRequest = pendingPlan.Request
+			agentInput = planPrompt(planRequest, pendingPlan.Plan, userContent)
+		}
+	}
+	if planRequest != "" {
+		plan = &planPolicy{}
+	}
+
 	// ── Step 6: Caller context is now passed via context.Context (see Step 8).
 	// The old global SetCallerContext/SetSessionContext is kept for backward
 	// compatibility (CLI, scheduler) but the agent run uses per-request context.
@@ -983,9 +1420,22 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 	// ── Step 8: Execute agent (with optional block streaming) ──
 	// Propagate caller, session, and delivery target through context so
 	// tools get per-request security context without shared mutable state.
-	agentCtx := ContextWithSession(a.ctx, sessionID)
+	agentCtx := ContextWithSession(msgCtx, sessionID)
 	agentCtx = ContextWithDelivery(agentCtx, msg.Channel, msg.ChatID)
+	agentCtx, replyBlocks := ContextWithReplyBlocks(agentCtx)
 	agentCtx = ContextWithCaller(agentCtx, accessResult.Level, msg.From)
+	agentCtx = ContextWithLocale(agentCtx, a.config.LocaleFor(session.GetConfig().Language, msg.From, msg.ChatID))
+	agentCtx = a.withWorkspaceEnv(agentCtx, workspace.ID)
+	if session.GetConfig().ReadOnly {
+		agentCtx = ContextWithReadOnly(agentCtx)
+	}
+	if plan != nil {
+		agentCtx = ContextWithExecutionPolicy(agentCtx, plan)
+	}
+
+	// Replies of reviewed workspaces go to the review queue, so nothing may
+	// reach the chat on the way: no progress, streaming or voice.
+	review := reviewsReplies(workspace, accessResult.Level)
 
 	// Inject ProgressSender with per-channel cooldown.
 	// WhatsApp doesn't support editing messages, so we rate-limit progress
@@ -997,6 +1447,9 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 		progressCooldown = 10 * time.Second
 	}
 	agentCtx = ContextWithProgressSender(agentCtx, func(_ context.Context, progressMsg string) {
+		if msg.Channel == "email" || msg.Channel == webhook.ChannelName || review || plan != nil {
+			return // every progress update would be a separate email or notification
+		}
 		lastProgressMu.Lock()
 		if time.Since(lastProgressAt) < progressCooldown {
 			lastProgressMu.Unlock()
@@ -1012,10 +1465,14 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 		_ = a.channelMgr.Send(a.ctx, msg.Channel, msg.ChatID, outMsg)
 	})
 
+	// Voice notes answered by voice must not stream text blocks first.
+	voiceReply := !review && plan == nil && a.wantsVoiceReply(msg)
+
 	bsCfg := a.config.BlockStream.Effective()
 	var blockStreamer *BlockStreamer
-	if bsCfg.Enabled {
+	if bsCfg.Enabled && msg.Channel != "email" && !voiceReply && !review && plan == nil {
 		blockStreamer = NewBlockStreamer(bsCfg, a.channelMgr, msg.Channel, msg.ChatID, msg.ID)
+		blockStreamer.SetRedactor(func(s string) string { return a.redactReplySecrets(agentCtx, s) })
 	}
 
 	// Start a typing heartbeat goroutine that re-sends typing indicators
@@ -1043,8 +1500,14 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 		go a.enrichMediaAsync(a.ctx, msg, sessionID, logger)
 	}
 
+	// Several unrelated requests in one message become ordered sub-tasks.
+	// Only the agent sees the guidance; the session keeps the original text.
+	if agentInput == "" {
+		agentInput = a.splitIntents(agentCtx, workspace.ID, userContent)
+	}
+
 	agentStart := time.Now()
-	response := a.executeAgentWithStream(agentCtx, workspace.ID, session, sessionID, prompt, userContent, blockStreamer)
+	response := a.executeAgentWithStream(agentCtx, workspace.ID, session, sessionID, prompt, agentInput, blockStreamer)
 	logger.Info("agent execution complete",
 		"agent_duration_ms", time.Since(agentStart).Milliseconds(),
 		"response_len", len(response),
@@ -1059,9 +1522,14 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 	}
 
 	// ── Step 9: Validate output ──
+	response = a.redactReplySecrets(agentCtx, response)
 	if err := a.outputGuard.Validate(response); err != nil {
 		logger.Warn("output rejected, applying fallback", "error", err)
 		response = "Sorry, I encountered an issue generating the response. Could you rephrase?"
+	} else if plan != nil {
+		response = strings.TrimSpace(response + "\n\n" + formatPlannedCalls(plan.Calls()))
+		session.SetPendingPlan(&PendingPlan{Request: planRequest, Plan: response, CreatedAt: time.Now()})
+		response += "\n\nReply *go* to execute this plan, send changes to revise it, or /plan cancel to drop it."
 	}
 
 	// ── Step 10: Update session ──
@@ -1080,12 +1548,27 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 	go a.maybeCompactSession(session)
 
 	// ── Step 11: Send reply (skip if block streamer already sent everything) ──
-	if blockStreamer == nil || !blockStreamer.HasSentBlocks() {
+	// Voice notes get a voice reply when enabled; text is the fallback.
+	voiceSent := voiceReply && a.sendVoiceReply(msg, response)
+	switch {
+	case review:
+		a.holdReply(workspace, msg, userContent, response)
+		if blocks := replyBlocks.Take(); len(blocks) > 0 {
+			logger.Info("rich blocks dropped: workspace replies are reviewed", "blocks", len(blocks))
+		}
+	case !voiceSent && (blockStreamer == nil || !blockStreamer.HasSentBlocks()):
 		a.sendReply(msg, response)
 	}
 
+	// ── Step 11a: Send rich blocks tools marked for the user ──
+	if !review {
+		a.deliverToolBlocks(msg.Channel, msg.ChatID, msg.ID, replyBlocks.Take())
+	}
+
 	// ── Step 11b: TTS — synthesize and send audio if enabled ──
-	a.maybeSendTTS(msg, response)
+	if !voiceSent && !review {
+		a.maybeSendTTS(msg, response)
+	}
 
 	// React with ✅ to signal processing is complete.
 	a.channelMgr.SendReaction(a.ctx, msg.Channel, msg.ChatID, msg.ID, "✅")
@@ -1096,9 +1579,6 @@ func (a *Assistant) handleMessage(msg *channels.IncomingMessage) {
 	)
 }
 
-// matchesTrigger checks if a message matches the activation keyword.
-// In DMs, the trigger is optional (always responds).
-// In groups, the trigger is required unless the group has its own trigger.
 // matchNaturalApproval checks if a short message matches common approval/denial
 // patterns in Portuguese and English. Returns "approve", "deny", or "".
 func matchNaturalApproval(content string) string {
@@ -1138,23 +1618,6 @@ func matchNaturalApproval(content string) string {
 	return ""
 }
 
-func (a *Assistant) matchesTrigger(content, trigger string, isGroup bool) bool {
-	// No trigger configured = always respond.
-	if trigger == "" {
-		return true
-	}
-
-	// In DMs, respond even without trigger.
-	if !isGroup {
-		return true
-	}
-
-	// In groups, require the trigger.
-	content = strings.TrimSpace(content)
-	return len(content) >= len(trigger) &&
-		strings.EqualFold(content[:len(trigger)], trigger)
-}
-
 // composeWorkspacePrompt builds the prompt using workspace overrides.
 func (a *Assistant) composeWorkspacePrompt(ws *Workspace, session *Session, input string) string {
 	// If workspace has custom instructions, inject them as business context.
@@ -1174,9 +1637,11 @@ func (a *Assistant) composeWorkspacePrompt(ws *Workspace, session *Session, inpu
 // sessionID is the channel:chatID key used for interrupt inbox routing.
 func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID string, session *Session, sessionID string, systemPrompt string, userMessage string, streamer *BlockStreamer) string {
 	runKey := workspaceID + ":" + session.ID
+	ctx, span := startSpan(ctx, "executeAgent", "workspace", workspaceID, "session", sessionID, "stream", streamer != nil)
+	defer span.End()
 
 	// Create interrupt inbox so follow-up messages can be injected mid-run.
-	interruptInbox := make(chan string, 10)
+	interruptInbox := make(chan Interrupt, 10)
 	a.interruptInboxesMu.Lock()
 	a.interruptInboxes[sessionID] = interruptInbox
 	a.interruptInboxesMu.Unlock()
@@ -1184,8 +1649,11 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 	runCtx, cancel := context.WithCancel(ctx)
 
 	// ── Persist active run for restart recovery ──
+	// Dry runs are not resumed: without their policy they would execute.
 	channel, chatID, _ := strings.Cut(sessionID, ":")
-	a.markRunActive(sessionID, channel, chatID, userMessage)
+	if ExecutionPolicyFromContext(ctx) == nil {
+		a.markRunActive(sessionID, channel, chatID, userMessage)
+	}
 
 	defer func() {
 		// Remove interrupt inbox before releasing the processing lock.
@@ -1207,6 +1675,9 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 	a.activeRuns[runKey] = cancel
 	a.activeRunsMu.Unlock()
 
+	progress := a.trackRun(runKey, workspaceID, session, userMessage)
+	defer a.untrackRun(runKey, progress)
+
 	// 10 recent entries ≈ 2-3K tokens: enough context without bloating the
 	// prompt. Older history is summarized by session memory if enabled.
 	history := session.RecentHistory(10)
@@ -1214,6 +1685,9 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 	modelOverride := session.GetConfig().Model
 	agent := NewAgentRunWithConfig(a.llmClient, a.toolExecutor, a.config.Agent, a.logger)
 	agent.SetModelOverride(modelOverride)
+	agent.SetWorkspace(workspaceID)
+	agent.setProgress(progress)
+	agent.SetExecutionPolicy(ExecutionPolicyFromContext(ctx))
 
 	// Wire interrupt channel for live message injection.
 	agent.SetInterruptChannel(interruptInbox)
@@ -1226,10 +1700,11 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 		agent.SetOnBeforeToolExec(streamer.FlushNow)
 	}
 
-	// Wire auto-send media hook for tools that produce files (e.g. generate_image).
+	// Wire auto-send media hook for tools that produce files (e.g. generate_image)
+	// or return blocks meant for the user.
 	dt := DeliveryTargetFromContext(ctx)
 	if dt.Channel != "" {
-		agent.SetOnToolResult(a.makeToolResultHook(dt.Channel, dt.ChatID))
+		agent.SetOnToolResult(a.makeToolResultHook(dt.Channel, dt.ChatID, ReplyBlocksFromContext(ctx)))
 	}
 
 	// Wire tool loop detector (new instance per-run to avoid cross-session races).
@@ -1238,14 +1713,20 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 		agent.SetLoopDetector(detector)
 	}
 
-	if a.usageTracker != nil {
-		agent.SetUsageRecorder(func(model string, usage LLMUsage) {
-			a.usageTracker.Record(session.ID, model, usage)
-		})
-	}
+	turn := a.recordTurnUsage(ctx, agent, session, workspaceID)
+	redactor := a.redactorFor(workspaceID)
+	agent.SetRedactor(redactor)
 
+	runStart := time.Now()
 	response, usage, err := agent.RunWithUsage(runCtx, systemPrompt, history, userMessage)
+	a.auditRedaction(ctx, workspaceID, redactor)
+	turn.Tools = agent.ToolLog()
+	turn.DurationMs = time.Since(runStart).Milliseconds()
+	session.setTurnMeta(userMessage, turn)
 	if err != nil {
+		if progress.wasCancelled() {
+			return a.stallResumeMessage()
+		}
 		if runCtx.Err() != nil {
 			return "Agent stopped."
 		}
@@ -1263,7 +1744,15 @@ func (a *Assistant) executeAgentWithStream(ctx context.Context, workspaceID stri
 // executeAgent runs the agentic loop with tool use support.
 // Uses a cancelable context so /stop can abort the run.
 func (a *Assistant) executeAgent(ctx context.Context, workspaceID string, session *Session, systemPrompt string, userMessage string) string {
+	return a.executeAgentWithCallback(ctx, workspaceID, session, systemPrompt, userMessage, nil)
+}
+
+// executeAgentWithCallback is executeAgent with an optional stream callback
+// that receives text deltas as the LLM produces them.
+func (a *Assistant) executeAgentWithCallback(ctx context.Context, workspaceID string, session *Session, systemPrompt string, userMessage string, onDelta StreamCallback) string {
 	runKey := workspaceID + ":" + session.ID
+	ctx, span := startSpan(ctx, "executeAgent", "workspace", workspaceID, "session", session.ID)
+	defer span.End()
 
 	runCtx, cancel := context.WithCancel(ctx)
 	defer func() {
@@ -1277,11 +1766,19 @@ func (a *Assistant) executeAgent(ctx context.Context, workspaceID string, sessio
 	a.activeRuns[runKey] = cancel
 	a.activeRunsMu.Unlock()
 
+	progress := a.trackRun(runKey, workspaceID, session, userMessage)
+	defer a.untrackRun(runKey, progress)
+
 	history := session.RecentHistory(10)
 
 	modelOverride := session.GetConfig().Model
 	agent := NewAgentRunWithConfig(a.llmClient, a.toolExecutor, a.config.Agent, a.logger)
 	agent.SetModelOverride(modelOverride)
+	agent.SetWorkspace(workspaceID)
+	agent.setProgress(progress)
+	if onDelta != nil {
+		agent.SetStreamCallback(onDelta)
+	}
 
 	// Wire tool loop detector (new instance per-run to avoid cross-session races).
 	if a.loopDetectorConfig.Enabled {
@@ -1289,14 +1786,20 @@ func (a *Assistant) executeAgent(ctx context.Context, workspaceID string, sessio
 		agent.SetLoopDetector(detector)
 	}
 
-	if a.usageTracker != nil {
-		agent.SetUsageRecorder(func(model string, usage LLMUsage) {
-			a.usageTracker.Record(session.ID, model, usage)
-		})
-	}
+	turn := a.recordTurnUsage(ctx, agent, session, workspaceID)
+	redactor := a.redactorFor(workspaceID)
+	agent.SetRedactor(redactor)
 
+	runStart := time.Now()
 	response, usage, err := agent.RunWithUsage(runCtx, systemPrompt, history, userMessage)
+	a.auditRedaction(ctx, workspaceID, redactor)
+	turn.Tools = agent.ToolLog()
+	turn.DurationMs = time.Since(runStart).Milliseconds()
+	session.setTurnMeta(userMessage, turn)
 	if err != nil {
+		if progress.wasCancelled() {
+			return a.stallResumeMessage()
+		}
 		if runCtx.Err() != nil {
 			return "Agent stopped."
 		}
@@ -1311,6 +1814,46 @@ func (a *Assistant) executeAgent(ctx context.Context, workspaceID string, sessio
 	return response
 }
 
+// recordTurnUsage wires usage accounting (tracker, quotas, ledger, budget
+// alerts, tool compression savings) into a run and returns the turn metadata it fills for the transcript.
+func (a *Assistant) recordTurnUsage(ctx context.Context, agent *AgentRun, session *Session, workspaceID string) *TurnMeta {
+	turn := &TurnMeta{}
+	runID := uuid.New().String()[:8]
+	user := CallerJIDFromContext(ctx)
+	agent.SetUsageRecorder(func(model string, usage LLMUsage) {
+		var cost float64
+		if a.usageTracker != nil {
+			a.usageTracker.RecordFor(session.ID, workspaceID, user, model, usage)
+			cost = a.usageTracker.EstimateCost(model, usage)
+			a.usageTracker.ChargeBudgets(user, workspaceID, usage.TotalTokens, cost)
+			if a.quotaMgr != nil {
+				a.quotaMgr.Record(workspaceID, cost)
+			}
+			a.checkBudgetAlert()
+		}
+		if a.usageLedger != nil {
+			a.usageLedger.Record(UsageEntry{
+				WorkspaceID:      workspaceID,
+				SessionID:        session.ID,
+				UserID:           user,
+				RunID:            runID,
+				Model:            model,
+				PromptTokens:     usage.PromptTokens,
+				CompletionTokens: usage.CompletionTokens,
+				CostUSD:          cost,
+			})
+		}
+		turn.addUsage(model, usage, cost)
+	})
+
+	agent.setToolView(newToolView(a.config.Agent.ToolCompression, session, func(tokens int) {
+		if a.usageTracker != nil {
+			a.usageTracker.RecordToolCompression(session.ID, tokens)
+		}
+	}))
+	return turn
+}
+
 // ToolExecutor returns the tool executor for external tool registration.
 func (a *Assistant) ToolExecutor() *ToolExecutor {
 	return a.toolExecutor
@@ -1321,6 +1864,16 @@ func (a *Assistant) UsageTracker() *UsageTracker {
 	return a.usageTracker
 }
 
+// QuotaManager returns the workspace quota manager (nil if quotas are disabled).
+func (a *Assistant) QuotaManager() *QuotaManager {
+	return a.quotaMgr
+}
+
+// UsageLedger returns the per-call usage ledger (nil if billing is disabled).
+func (a *Assistant) UsageLedger() *UsageLedger {
+	return a.usageLedger
+}
+
 // HookManager returns the lifecycle hook manager for registering plugin hooks.
 func (a *Assistant) HookManager() *HookManager {
 	return a.hookMgr
@@ -1378,6 +1931,12 @@ func (a *Assistant) ExecuteAgent(ctx context.Context, systemPrompt string, sessi
 	return a.executeAgent(ctx, "default", session, systemPrompt, userMessage)
 }
 
+// ExecuteAgentStream is like ExecuteAgent but forwards text deltas to onDelta
+// while the agent runs. The full response is still returned at the end.
+func (a *Assistant) ExecuteAgentStream(ctx context.Context, systemPrompt string, session *Session, userMessage string, onDelta StreamCallback) string {
+	return a.executeAgentWithCallback(ctx, "default", session, systemPrompt, userMessage, onDelta)
+}
+
 // StopActiveRun cancels the active agent run for the given workspace and session.
 // It also signals the tool executor to abort all running tools and forces the
 // session out of "processing" state so new messages are handled immediately.
@@ -1503,6 +2062,7 @@ func (a *Assistant) initScheduler() {
 		// This replaces the old global SetCallerContext/SetSessionContext pattern.
 		jobCtx := ContextWithCaller(ctx, AccessOwner, "scheduler")
 		jobCtx = ContextWithSession(jobCtx, schedulerSessionID)
+		jobCtx = ContextWithResponseCache(jobCtx)
 		if job.Channel != "" && job.ChatID != "" {
 			jobCtx = ContextWithDelivery(jobCtx, job.Channel, job.ChatID)
 		}
@@ -1554,7 +2114,12 @@ func (a *Assistant) initScheduler() {
 	}
 
 	a.scheduler = scheduler.New(storage, handler, a.logger)
-	a.logger.Info("scheduler initialized")
+	tz := firstNonEmpty(a.config.Scheduler.Timezone, a.config.Timezone)
+	if err := a.scheduler.SetTimezone(tz); err != nil {
+		a.logger.Warn("invalid scheduler timezone, using local time", "timezone", tz, "error", err)
+	}
+	a.scheduler.SetLocale(firstNonEmpty(a.config.Locale.Default, a.config.Language))
+	a.logger.Info("scheduler initialized", "timezone", tz)
 }
 
 // registerSkillLoaders registers the builtin and clawdhub skill loaders
@@ -1661,6 +2226,27 @@ func (a *Assistant) registerSkillTools() {
 	)
 }
 
+// assignWebhookWorkspaces assigns each hook's sender to its configured
+// workspace, so the events are handled there.
+func (a *Assistant) assignWebhookWorkspaces() {
+	cfg := a.config.Channels.Webhook
+	if !cfg.Enabled {
+		return
+	}
+	for _, h := range cfg.Hooks {
+		if h.Workspace == "" || h.Name == "" {
+			continue
+		}
+		sender := webhook.Sender(h.Name)
+		if ws, ok := a.workspaceMgr.GetForUser(sender); ok && ws.ID == h.Workspace {
+			continue
+		}
+		if err := a.workspaceMgr.AssignUser(sender, h.Workspace, "config"); err != nil {
+			a.logger.Warn("webhook workspace not assigned", "hook", h.Name, "error", err)
+		}
+	}
+}
+
 // registerSystemTools registers core system tools (web_fetch, exec, file I/O)
 // that are always available to the agent regardless of skills configuration.
 func (a *Assistant) registerSystemTools() {
@@ -1682,13 +2268,19 @@ func (a *Assistant) registerSystemTools() {
 
 	ssrfGuard := security.NewSSRFGuard(a.config.Security.SSRF, a.logger)
 	RegisterSystemTools(a.toolExecutor, sandboxRunner, a.memoryStore, a.sqliteMemory, a.config.Memory, a.scheduler, dataDir, ssrfGuard, a.vault, a.config.WebSearch)
+	if a.vault != nil {
+		// Vault secrets are exported to the environment; keep them out of
+		// tool results the model sees.
+		a.toolExecutor.SetOutputRedactor(a.vault.Redact)
+		a.toolExecutor.SetSecretSender(a.deliverSecret)
+	}
 
 	// Register skill creator tools (including install_skill, search_skills, remove_skill).
 	skillsDir := "./skills"
 	if len(a.config.Skills.ClawdHubDirs) > 0 {
 		skillsDir = a.config.Skills.ClawdHubDirs[0]
 	}
-	RegisterSkillCreatorTools(a.toolExecutor, a.skillRegistry, skillsDir, a.logger)
+	RegisterSkillCreatorTools(a.toolExecutor, a.skillRegistry, skillsDir, a.eventLog, a.logger)
 
 	// Register subagent tools (spawn, list, wait, stop).
 	RegisterSubagentTools(a.toolExecutor, a.subagentMgr, a.llmClient, a.promptComposer, a.logger)
@@ -1696,8 +2288,12 @@ func (a *Assistant) registerSystemTools() {
 	// Register session management tools (sessions_list, sessions_send) for multi-agent routing.
 	RegisterSessionTools(a.toolExecutor, a.workspaceMgr)
 
-	// Register media tools (describe_image, transcribe_audio).
+	// Register delegate_to_workspace when workspaces are wired together.
+	a.registerDelegationTool()
+
+	// Register media tools (describe_image, transcribe_audio, generate_image).
 	RegisterMediaTools(a.toolExecutor, a.llmClient, a.config, a.logger)
+	RegisterImageGenTool(a.toolExecutor, a.config.ImageGen, dataDir)
 
 	// Register native developer tools (git, docker, db, env, utils, codebase, testing, ops, product, IDE).
 	RegisterGitTools(a.toolExecutor)
@@ -1708,7 +2304,10 @@ func (a *Assistant) registerSystemTools() {
 	RegisterCodebaseTools(a.toolExecutor)
 	RegisterTestingTools(a.toolExecutor)
 	RegisterOpsTools(a.toolExecutor)
-	RegisterProductTools(a.toolExecutor)
+	RegisterProductTools(a.toolExecutor, a.config.DORA)
+	if err := RegisterDeployTools(a.toolExecutor, a.config.Deploy, a.config.Timezone, a.notifyDeploy); err != nil {
+		a.logger.Warn("deploy tools not registered", "error", err)
+	}
 	RegisterIDETools(a.toolExecutor)
 
 	// Register daemon manager for background process control.
@@ -1717,6 +2316,32 @@ func (a *Assistant) registerSystemTools() {
 	}
 	RegisterDaemonTools(a.toolExecutor, a.daemonMgr)
 
+	// Register command watchers (watch_command) — they need the sandbox.
+	if sandboxRunner != nil {
+		if a.cmdWatcher == nil {
+			a.cmdWatcher = NewCommandWatcher(a.ctx, sandboxRunner, func(channel, chatID, msg string) {
+				outMsg := &channels.OutgoingMessage{Content: FormatForChannel(msg, channel)}
+				if err := a.channelMgr.Send(a.ctx, channel, chatID, outMsg); err != nil {
+					a.logger.Warn("failed to deliver watch result", "channel", channel, "error", err)
+				}
+			}, a.logger)
+		}
+		RegisterWatchTools(a.toolExecutor, a.cmdWatcher)
+	}
+
+	// Register the workspace knowledge base and document corpus search.
+	workspaceFor := func(ctx context.Context) string {
+		target := DeliveryTargetFromContext(ctx)
+		return a.workspaceMgr.WorkspaceIDForSession(target.Channel + ":" + target.ChatID)
+	}
+	if a.knowledgeBase != nil {
+		RegisterKnowledgeBaseTools(a.toolExecutor, a.knowledgeBase, workspaceFor)
+	}
+	if a.config.Corpora.Enabled {
+		a.corpora = NewCorpusStore(a.config.Corpora, a.logger)
+		RegisterCorpusTools(a.toolExecutor, a.corpora, workspaceFor)
+	}
+
 	// Register plugin system.
 	if a.pluginMgr == nil {
 		a.pluginMgr = NewPluginManager()
@@ -1860,7 +2485,8 @@ func (a *Assistant) autoCaptureFacts(userMessage, assistantResponse, sessionID s
 	ctx, cancel := context.WithTimeout(a.ctx, 30*time.Second)
 	defer cancel()
 
-	result, err := a.llmClient.Complete(ctx, "", nil, extractPrompt)
+	redactor := a.redactorFor(a.workspaceMgr.WorkspaceIDForSession(sessionID))
+	result, err := redactor.complete(ctx, a.llmClient, "", nil, extractPrompt)
 	if err != nil || strings.TrimSpace(result) == "NOTHING" || strings.TrimSpace(result) == "" {
 		return
 	}
@@ -1888,12 +2514,21 @@ func (a *Assistant) autoCaptureFacts(userMessage, assistantResponse, sessionID s
 		if fact == "" || len(fact) < 5 {
 			continue
 		}
-		_ = a.memoryStore.Save(memory.Entry{
+		// Auto-captured facts never overwrite memory on their own: a
+		// contradiction is queued for the owner (/memory conflicts).
+		res, err := a.memoryStore.SaveVersioned(memory.Entry{
 			Content:   fact,
 			Source:    "auto-capture",
 			Category:  "fact",
 			Timestamp: time.Now(),
-		})
+		}, false)
+		if err == nil && res.Outcome == memory.QueuedConflict {
+			a.logger.Info("memory conflict queued for review",
+				"key", res.Key,
+				"conflict", res.Conflict.ID,
+			)
+			continue
+		}
 		a.logger.Debug("auto-captured memory fact",
 			"fact_preview", truncateForCapture(fact, 60),
 			"session", sessionID,
@@ -1927,6 +2562,7 @@ func (a *Assistant) compactSummarize(session *Session, threshold int) {
 			"If nothing important, reply with NO_REPLY."
 
 		agent := NewAgentRunWithConfig(a.llmClient, a.toolExecutor, a.config.Agent, a.logger)
+		agent.SetRedactor(a.redactorFor(a.workspaceMgr.WorkspaceIDForSession(session.ID)))
 		systemPrompt := a.promptComposer.Compose(session, flushPrompt)
 
 		flushCtx, cancel := context.WithTimeout(a.ctx, 60*time.Second)
@@ -1944,6 +2580,7 @@ func (a *Assistant) compactSummarize(session *Session, threshold int) {
 	// Transient errors (rate-limits, timeouts) are retried up to 3 times with
 	// backoff: 2s → 4s → 8s. On permanent failure, a static fallback is used.
 	summaryPrompt := "Summarize the key points of this conversation in 2-3 sentences. Focus on decisions made, tasks completed, and important context."
+	redactor := a.redactorFor(a.workspaceMgr.WorkspaceIDForSession(session.ID))
 	var summary string
 	var summaryErr error
 
@@ -1951,7 +2588,7 @@ func (a *Assistant) compactSummarize(session *Session, threshold int) {
 	const maxSummaryRetries = 3
 
 	for attempt := 1; attempt <= maxSummaryRetries; attempt++ {
-		summary, summaryErr = a.llmClient.Complete(a.ctx, "", session.RecentHistory(20), summaryPrompt)
+		summary, summaryErr = redactor.complete(a.ctx, a.llmClient, "", session.RecentHistory(20), summaryPrompt)
 		if summaryErr == nil {
 			break
 		}
@@ -2136,7 +2773,7 @@ func (a *Assistant) enrichMediaAsync(ctx context.Context, msg *channels.Incoming
 
 	if hasInbox {
 		select {
-		case inbox <- result:
+		case inbox <- Interrupt{Content: result}:
 			logger.Info("media enrichment injected into agent", "type", msg.Media.Type)
 		default:
 			logger.Warn("interrupt inbox full, media enrichment dropped")
@@ -2204,7 +2841,18 @@ func (a *Assistant) enrichMessageContent(ctx context.Context, msg *channels.Inco
 		if filename == "" {
 			filename = "audio.ogg"
 		}
-		transcript, err := a.llmClient.TranscribeAudio(ctx, data, filename, media.TranscriptionModel, media)
+		// Long audio is transcribed in parallel segments; tell the user up
+		// front so a 10-minute voice note does not look like a stall.
+		ack := func(total, limit time.Duration) {
+			minutes := max(1, int(total.Round(time.Minute)/time.Minute))
+			text := fmt.Sprintf("🎙️ Transcribing your %d-min audio…", minutes)
+			if limit < total {
+				text = fmt.Sprintf("🎙️ Transcribing the first %d min of your %d-min audio…", int(limit/time.Minute), minutes)
+			}
+			_ = a.channelMgr.Send(ctx, msg.Channel, msg.ChatID, &channels.OutgoingMessage{Content: text, ReplyTo: msg.ID})
+		}
+		duration := time.Duration(msg.Media.Duration) * time.Second
+		transcript, err := a.llmClient.TranscribeAudioChunked(ctx, data, filename, duration, media, ack)
 		if err != nil {
 			logger.Warn("audio transcription failed", "error", err)
 			return msg.Content
@@ -2364,6 +3012,9 @@ func (a *Assistant) buildTTSProvider() tts.Provider {
 	case "edge":
 		return tts.NewEdgeProvider(a.logger)
 
+	case "piper":
+		return tts.NewPiperProvider(a.config.TTS.PiperBinary, a.config.TTS.PiperModel, a.logger)
+
 	case "auto":
 		// Try OpenAI first, fall back to Edge TTS.
 		apiKey := a.config.API.APIKey
@@ -2425,34 +3076,110 @@ func (a *Assistant) maybeSendTTS(msg *channels.IncomingMessage, response string)
 	if len(text) > 4096 {
 		text = text[:4093] + "..."
 	}
+	if err := a.sendSpeech(msg, text, voice); err != nil {
+		a.logger.Warn("TTS audio not sent", "error", err)
+	}
+}
+
+// wantsVoiceReply reports whether msg is a voice note that should be
+// answered with a voice message (media.voice_replies).
+func (a *Assistant) wantsVoiceReply(msg *channels.IncomingMessage) bool {
+	a.configMu.RLock()
+	enabled := a.config.Media.VoiceReplies
+	a.configMu.RUnlock()
+	return enabled && msg.Type == channels.MessageAudio && a.ttsProvider != nil
+}
+
+// sendVoiceReply sends response as a voice message in place of text. It
+// returns false when the reply should go out as text instead: control
+// tokens, code blocks and replies too long to speak, or when synthesis or
+// delivery fails.
+func (a *Assistant) sendVoiceReply(msg *channels.IncomingMessage, response string) bool {
+	trimmed := strings.TrimSpace(response)
+	if trimmed == "" || strings.EqualFold(trimmed, TokenNoReply) || strings.EqualFold(trimmed, TokenHeartbeatOK) {
+		return false
+	}
+	if strings.Contains(response, "```") {
+		return false
+	}
+	text := FormatForChannel(response, "plain")
+	if text == "" || len(text) > 4096 {
+		return false
+	}
 
+	a.configMu.RLock()
+	voice := a.config.TTS.Voice
+	a.configMu.RUnlock()
+	if err := a.sendSpeech(msg, text, voice); err != nil {
+		a.logger.Warn("voice reply failed, replying with text", "error", err)
+		return false
+	}
+	return true
+}
+
+// sendSpeech synthesizes text and sends it as an audio reply to msg.
+func (a *Assistant) sendSpeech(msg *channels.IncomingMessage, text, voice string) error {
 	ctx, cancel := context.WithTimeout(a.ctx, 30*time.Second)
 	defer cancel()
 
 	audio, mimeType, err := a.ttsProvider.Synthesize(ctx, text, voice)
 	if err != nil {
-		a.logger.Warn("TTS synthesis failed", "error", err)
-		return
+		return fmt.Errorf("synthesis: %w", err)
 	}
 
+	filename := "response.ogg"
+	switch mimeType {
+	case "audio/mpeg":
+		filename = "response.mp3"
+	case "audio/wav":
+		filename = "response.wav"
+	}
 	media := &channels.MediaMessage{
 		Type:     channels.MessageAudio,
 		Data:     audio,
 		MimeType: mimeType,
-		Filename: "response.ogg",
+		Filename: filename,
 		ReplyTo:  msg.ID,
 	}
 	if err := a.channelMgr.SendMedia(a.ctx, msg.Channel, msg.ChatID, media); err != nil {
-		a.logger.Warn("failed to send TTS audio", "error", err)
+		return fmt.Errorf("send: %w", err)
 	}
+	return nil
+}
+
+// withWorkspaceEnv attaches the workspace's environment variables to ctx so
+// tool and script runs layer them over the base environment.
+func (a *Assistant) withWorkspaceEnv(ctx context.Context, wsID string) context.Context {
+	env, err := a.workspaceMgr.Env(wsID)
+	if err != nil {
+		a.logger.Warn("workspace env not loaded", "workspace", wsID, "error", err)
+	}
+	return sandbox.WithEnv(ctx, env)
 }
 
 // makeToolResultHook returns a callback that auto-sends media files produced by
 // tools (e.g. generate_image) to the channel. This avoids the LLM having to
 // describe "image saved to /tmp/..." — the user sees the actual image.
-func (a *Assistant) makeToolResultHook(channel, chatID string) func(string, ToolResult) {
+// Blocks marked for delivery are collected into replyBlocks to follow the
+// reply, or sent right away when the caller doesn't collect them (e.g.
+// scheduled jobs).
+func (a *Assistant) makeToolResultHook(channel, chatID string, replyBlocks *ReplyBlocks) func(string, ToolResult) {
 	return func(toolName string, result ToolResult) {
-		if toolName != "generate_image" && toolName != "image-gen_generate_image" {
+		if replyBlocks != nil {
+			replyBlocks.Add(result.Blocks)
+		} else {
+			var deliver []ToolBlock
+			for _, b := range result.Blocks {
+				if b.Deliver {
+					deliver = append(deliver, b)
+				}
+			}
+			a.deliverToolBlocks(channel, chatID, "", deliver)
+		}
+
+		// The built-in generate_image returns an attachment block; only the
+		// image-gen skill reports a temp file path.
+		if toolName != "image-gen_generate_image" {
 			return
 		}
 		// Parse the JSON result to find image_path.
@@ -2488,8 +3215,61 @@ func (a *Assistant) makeToolResultHook(channel, chatID string) func(string, Tool
 	}
 }
 
+// deliverToolBlocks sends blocks tools marked for the user: attachments as
+// media (a text note on channels without media), markdown, tables and links
+// as text rendered for the channel.
+func (a *Assistant) deliverToolBlocks(channel, chatID, replyTo string, blocks []ToolBlock) {
+	for _, b := range blocks {
+		if b.Type != ToolBlockAttachment && b.Type != ToolBlockImage {
+			a.sendText(channel, chatID, replyTo, b.markdownFor(channel))
+			continue
+		}
+		data := b.Data
+		if len(data) == 0 && b.Path != "" {
+			var err error
+			if data, err = os.ReadFile(b.Path); err != nil {
+				a.logger.Warn("failed to read tool attachment", "path", b.Path, "error", err)
+				continue
+			}
+		}
+		if len(data) == 0 {
+			continue
+		}
+		media := &channels.MediaMessage{
+			Type:     mediaTypeForMime(b.MimeType),
+			Data:     data,
+			MimeType: b.MimeType,
+			Filename: filepath.Base(b.Path),
+			Caption:  b.Text,
+		}
+		out := &channels.OutgoingMessage{ReplyTo: replyTo, Attachments: []*channels.MediaMessage{media}}
+		if err := a.channelMgr.Send(a.ctx, channel, chatID, out); err != nil {
+			a.logger.Warn("failed to send tool attachment", "path", b.Path, "error", err)
+		}
+	}
+}
+
+// mediaTypeForMime maps a MIME type to the channel media type.
+func mediaTypeForMime(mimeType string) channels.MessageType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return channels.MessageImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return channels.MessageAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return channels.MessageVideo
+	default:
+		return channels.MessageDocument
+	}
+}
+
 func (a *Assistant) sendReply(original *channels.IncomingMessage, content string) {
-	content = FormatForChannel(content, original.Channel)
+	a.sendText(original.Channel, original.ChatID, original.ID, content)
+}
+
+// sendText formats content for the channel and sends it in chunks.
+func (a *Assistant) sendText(channel, chatID, replyTo, content string) {
+	content = FormatForChannel(content, channel)
 	if content == "" {
 		return // Nothing to send (e.g. NO_REPLY, HEARTBEAT_OK, or only tags).
 	}
@@ -2504,12 +3284,12 @@ func (a *Assistant) sendReply(original *channels.IncomingMessage, content string
 	for _, chunk := range chunks {
 		outMsg := &channels.OutgoingMessage{
 			Content: chunk,
-			ReplyTo: original.ID,
+			ReplyTo: replyTo,
 		}
-		if err := a.channelMgr.Send(a.ctx, original.Channel, original.ChatID, outMsg); err != nil {
+		if err := a.channelMgr.Send(a.ctx, channel, chatID, outMsg); err != nil {
 			a.logger.Error("failed to send reply chunk",
-				"channel", original.Channel,
-				"chat_id", original.ChatID,
+				"channel", channel,
+				"chat_id", chatID,
 				"error", err,
 			)
 		}
@@ -2602,18 +3382,25 @@ func (a *Assistant) resumeInterruptedRuns() {
 			preview = preview[:100] + "..."
 		}
 
+		// Replies of reviewed workspaces are held, so the customer gets
+		// neither the notice nor streamed text.
+		ws, _ := a.workspaceMgr.Get(a.workspaceMgr.WorkspaceIDForSession(r.Channel + ":" + r.ChatID))
+		reviewed := ws != nil && ws.Review.Enabled
+
 		// Notify the user that we're resuming.
-		resumeNotice := fmt.Sprintf(
-			"🔄 *Retomando tarefa interrompida*\n\nEu fui reiniciado enquanto processava sua solicitação:\n> %s\n\nContinuando de onde parei...",
-			preview,
-		)
-		outMsg := &channels.OutgoingMessage{
-			Content: FormatForChannel(resumeNotice, r.Channel),
-		}
-		if err := a.channelMgr.Send(a.ctx, r.Channel, r.ChatID, outMsg); err != nil {
-			a.logger.Error("failed to notify about resumed run",
-				"channel", r.Channel, "chat_id", r.ChatID, "error", err)
-			continue
+		if !reviewed {
+			resumeNotice := fmt.Sprintf(
+				"🔄 *Retomando tarefa interrompida*\n\nEu fui reiniciado enquanto processava sua solicitação:\n> %s\n\nContinuando de onde parei...",
+				preview,
+			)
+			outMsg := &channels.OutgoingMessage{
+				Content: FormatForChannel(resumeNotice, r.Channel),
+			}
+			if err := a.channelMgr.Send(a.ctx, r.Channel, r.ChatID, outMsg); err != nil {
+				a.logger.Error("failed to notify about resumed run",
+					"channel", r.Channel, "chat_id", r.ChatID, "error", err)
+				continue
+			}
 		}
 
 		a.logger.Info("re-submitting interrupted task",
@@ -2643,15 +3430,30 @@ func (a *Assistant) resumeInterruptedRuns() {
 			resumeCtx := ContextWithCaller(a.ctx, AccessOwner, "system:resume")
 			resumeCtx = ContextWithSession(resumeCtx, sessionID)
 			resumeCtx = ContextWithDelivery(resumeCtx, run.Channel, run.ChatID)
+			resumeCtx = a.withWorkspaceEnv(resumeCtx, resolved.Workspace.ID)
+			if session.GetConfig().ReadOnly {
+				resumeCtx = ContextWithReadOnly(resumeCtx)
+			}
 
 			prompt := a.composeWorkspacePrompt(resolved.Workspace, session, run.UserMessage)
 
+			if reviewed {
+				response := a.executeAgentWithStream(
+					resumeCtx, resolved.Workspace.ID, session, sessionID,
+					prompt, run.UserMessage, nil,
+				)
+				session.AddMessage(run.UserMessage, response)
+				a.holdReply(resolved.Workspace, &channels.IncomingMessage{Channel: run.Channel, ChatID: run.ChatID}, run.UserMessage, response)
+				return
+			}
+
 			// Build block streamer for progressive output.
 			blockStreamer := NewBlockStreamer(
 				DefaultBlockStreamConfig(),
 				a.channelMgr,
 				run.Channel, run.ChatID, "",
 			)
+			blockStreamer.SetRedactor(func(s string) string { return a.redactReplySecrets(resumeCtx, s) })
 			defer blockStreamer.Finish()
 
 			response := a.executeAgentWithStream(
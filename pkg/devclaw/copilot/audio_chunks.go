@@ -0,0 +1,193 @@
+// Package copilot – audio_chunks.go transcribes long audio in segments.
+// A single transcription request for a 10-minute voice note blocks the
+// reply for as long as the provider takes on the whole file; instead the
+// audio is cut into media.transcription_chunk_seconds segments with ffmpeg,
+// the segments are transcribed in parallel and the partial transcripts are
+// stitched back in order. Audio beyond media.max_audio_duration is dropped.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audio helpers shell out to ffmpeg/ffprobe; they are variables so tests
+// can run without the binaries.
+var (
+	probeAudioDuration = ffprobeDuration
+	splitAudio         = ffmpegSegments
+)
+
+// TranscribeAudioChunked transcribes audio, splitting it into segments
+// transcribed in parallel when it is longer than one chunk. duration is the
+// length reported by the channel (0 when unknown, then ffprobe is asked).
+// onLong, if set, is called once before a segmented transcription starts,
+// with the audio length and the length that will be transcribed, so the
+// caller can acknowledge the wait. Without ffmpeg, or for short audio, this
+// is a plain TranscribeAudio call.
+func (c *LLMClient) TranscribeAudioChunked(ctx context.Context, audioData []byte, filename string, duration time.Duration, media MediaConfig, onLong func(total, limit time.Duration)) (string, error) {
+	media = media.Effective()
+	chunk := time.Duration(media.TranscriptionChunkSeconds) * time.Second
+	if duration <= 0 {
+		duration = probeAudioDuration(ctx, audioData, filename)
+	}
+	if duration <= chunk {
+		return c.TranscribeAudio(ctx, audioData, filename, media.TranscriptionModel, media)
+	}
+
+	limit := duration
+	if maxDur := time.Duration(media.MaxAudioDuration) * time.Second; maxDur > 0 && duration > maxDur {
+		limit = maxDur
+	}
+	segments, err := splitAudio(ctx, audioData, filename, chunk, limit)
+	if err != nil || len(segments) < 2 {
+		if err != nil {
+			c.logger.Warn("audio segmentation failed, transcribing whole file", "error", err)
+		}
+		return c.TranscribeAudio(ctx, audioData, filename, media.TranscriptionModel, media)
+	}
+	if onLong != nil {
+		onLong(duration, limit)
+	}
+
+	start := time.Now()
+	parts := make([]string, len(segments))
+	errs := make([]error, len(segments))
+	sem := make(chan struct{}, media.TranscriptionParallel)
+	var wg sync.WaitGroup
+	for i, seg := range segments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			parts[i], errs[i] = c.TranscribeAudio(ctx, seg, fmt.Sprintf("part%03d.mp3", i), media.TranscriptionModel, media)
+		}()
+	}
+	wg.Wait()
+
+	text, failed := stitchTranscripts(parts, errs, chunk)
+	if failed == len(segments) {
+		return "", fmt.Errorf("all %d audio segments failed: %w", len(segments), errs[0])
+	}
+	if limit < duration {
+		text += fmt.Sprintf("\n\n[Transcript covers the first %s of %s.]", formatClock(limit), formatClock(duration))
+	}
+	c.logger.Info("chunked audio transcription done",
+		"segments", len(segments),
+		"failed", failed,
+		"audio_duration", duration.Round(time.Second),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return text, nil
+}
+
+// stitchTranscripts joins segment transcripts in order. A failed segment
+// leaves a marker with its time range so the agent knows a part is missing.
+func stitchTranscripts(parts []string, errs []error, chunk time.Duration) (string, int) {
+	var b strings.Builder
+	failed := 0
+	for i, part := range parts {
+		if errs[i] != nil {
+			failed++
+			part = fmt.Sprintf("[%s–%s not transcribed]",
+				formatClock(time.Duration(i)*chunk), formatClock(time.Duration(i+1)*chunk))
+		}
+		if part == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(part)
+	}
+	return b.String(), failed
+}
+
+// formatClock renders d as m:ss (or h:mm:ss).
+func formatClock(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// ffprobeDuration returns the length of the audio, or 0 when ffprobe is
+// unavailable or cannot read it.
+func ffprobeDuration(ctx context.Context, data []byte, filename string) time.Duration {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		return 0
+	}
+	tmp, err := os.CreateTemp("", "dcaudio-*"+filepath.Ext(filename))
+	if err != nil {
+		return 0
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil {
+		return 0
+	}
+
+	out, err := exec.CommandContext(ctx, "ffprobe", "-v", "error",
+		"-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1",
+		tmp.Name()).Output()
+	if err != nil {
+		return 0
+	}
+	secs, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// ffmpegSegments cuts the first limit of the audio into MP3 segments of
+// chunk length, returned in order.
+func ffmpegSegments(ctx context.Context, data []byte, filename string, chunk, limit time.Duration) ([][]byte, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "dcaudio-seg-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "input"+filepath.Ext(filename))
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", in,
+		"-t", strconv.Itoa(int(limit/time.Second)), "-vn",
+		"-acodec", "libmp3lame", "-q:a", "4",
+		"-f", "segment", "-segment_time", strconv.Itoa(int(chunk/time.Second)),
+		filepath.Join(dir, "seg%03d.mp3"))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, truncate(string(out), 200))
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "seg*.mp3"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	segments := make([][]byte, 0, len(names))
+	for _, name := range names {
+		seg, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+	return segments, nil
+}
@@ -0,0 +1,83 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTranscribeAudioChunked(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		if string(data) == "fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, `{"text":"`+string(data)+`"}`)
+	}))
+	defer srv.Close()
+
+	var gotChunk, gotLimit time.Duration
+	origSplit, origProbe := splitAudio, probeAudioDuration
+	defer func() { splitAudio, probeAudioDuration = origSplit, origProbe }()
+	splitAudio = func(_ context.Context, _ []byte, _ string, chunk, limit time.Duration) ([][]byte, error) {
+		gotChunk, gotLimit = chunk, limit
+		return [][]byte{[]byte("one"), []byte("fail"), []byte("three")}, nil
+	}
+	probeAudioDuration = func(context.Context, []byte, string) time.Duration { return 5 * time.Minute }
+
+	cfg := DefaultConfig()
+	cfg.API.BaseURL = srv.URL
+	llm := NewLLMClient(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	media := MediaConfig{TranscriptionBaseURL: srv.URL, TranscriptionAPIKey: "k", MaxAudioDuration: 240}
+
+	var acked time.Duration
+	text, err := llm.TranscribeAudioChunked(context.Background(), []byte("whole"), "a.mp3", 0, media,
+		func(total, limit time.Duration) { acked = total })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked != 5*time.Minute {
+		t.Errorf("ack total = %v, want 5m", acked)
+	}
+	if gotChunk != 2*time.Minute || gotLimit != 4*time.Minute {
+		t.Errorf("split chunk=%v limit=%v, want 2m/4m", gotChunk, gotLimit)
+	}
+	want := "one [2:00–4:00 not transcribed] three\n\n[Transcript covers the first 4:00 of 5:00.]"
+	if text != want {
+		t.Errorf("transcript = %q, want %q", text, want)
+	}
+
+	// Short audio is a single request of the whole file.
+	requests.Store(0)
+	acked = 0
+	text, err = llm.TranscribeAudioChunked(context.Background(), []byte("whole"), "a.mp3", time.Minute, media,
+		func(total, limit time.Duration) { acked = total })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "whole" || requests.Load() != 1 || acked != 0 {
+		t.Errorf("short audio: text=%q requests=%d acked=%v", text, requests.Load(), acked)
+	}
+}
+
+func TestStitchTranscriptsAllFailed(t *testing.T) {
+	errs := []error{io.EOF, io.EOF}
+	text, failed := stitchTranscripts([]string{"", ""}, errs, time.Minute)
+	if failed != 2 || !strings.Contains(text, "1:00–2:00 not transcribed") {
+		t.Errorf("stitch = %q, %d failed", text, failed)
+	}
+}
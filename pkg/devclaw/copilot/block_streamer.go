@@ -7,6 +7,23 @@
 //   - Wait until at least MinChars are accumulated.
 //   - Flush when MaxChars is reached or the idle timer fires.
 //   - Always try to flush at a natural boundary (newline, sentence end).
+//
+// Flush policies make chunked sends arrive sooner while text keeps streaming
+// (the idle timer never fires then): FlushOnSentence flushes at the first
+// sentence end, FlushEveryChars every N characters, and MaxLatencyMs when the
+// oldest buffered text has waited too long. No policy sends a block shorter
+// than MinChars, so users never get one-word messages.
+//
+// On channels that can edit sent messages (WhatsApp, Telegram, Discord) and
+// with EditInPlace enabled, the streamer instead keeps a single live message
+// and edits it every EditEveryTokens tokens. When the live message reaches
+// MaxChars, or a tool starts running, it is finalized and the next text goes
+// to a new message. If an edit fails, streaming falls back to chunked sends.
+//
+// With a redactor set (the output guardrail's secret pass), every block is
+// redacted before it is sent, and text is only cut where no secret can
+// straddle the cut: the tail that might hold the start of one stays
+// buffered until it is complete.
 package copilot
 
 import (
@@ -33,6 +50,32 @@ type BlockStreamConfig struct {
 	// IdleMs is the idle timeout in milliseconds: if no new tokens arrive within
 	// this window, flush whatever is buffered (default: 200).
 	IdleMs int `yaml:"idle_ms"`
+
+	// EditInPlace streams into a single message that is edited as tokens
+	// arrive, on channels that support edits (default: true).
+	EditInPlace bool `yaml:"edit_in_place"`
+
+	// EditEveryTokens is how many new tokens (~4 chars each) trigger an edit
+	// of the live message (default: 40).
+	EditEveryTokens int `yaml:"edit_every_tokens"`
+
+	// EditIntervalMs is the minimum time between edits, to stay under
+	// platform rate limits (default: 1000).
+	EditIntervalMs int `yaml:"edit_interval_ms"`
+
+	// FlushOnSentence flushes at the first sentence end or line break once
+	// MinChars are buffered (default: false).
+	FlushOnSentence bool `yaml:"flush_on_sentence"`
+
+	// FlushEveryChars flushes once this many characters are buffered, at the
+	// nearest natural break (0 = only at MaxChars).
+	FlushEveryChars int `yaml:"flush_every_chars"`
+
+	// MaxLatencyMs is the longest buffered text waits while tokens keep
+	// arriving; past it, the buffer is flushed at a word boundary
+	// (0 = no limit, default: 4000). Policies apply to chunked sends only:
+	// edit-in-place already shows text as it arrives.
+	MaxLatencyMs int `yaml:"max_latency_ms"`
 }
 
 // DefaultBlockStreamConfig returns sensible defaults for block streaming.
@@ -45,6 +88,12 @@ func DefaultBlockStreamConfig() BlockStreamConfig {
 		MinChars: 200,   // ~40 words — avoids tiny fragments as separate messages
 		MaxChars: 1500,  // Full paragraph; WhatsApp supports up to 65K chars
 		IdleMs:   1500,  // Flush 1.5s after last token — allows sentences to complete
+
+		EditInPlace:     true,
+		EditEveryTokens: 40,
+		EditIntervalMs:  1000,
+
+		MaxLatencyMs: 4000,
 	}
 }
 
@@ -60,6 +109,12 @@ func (c BlockStreamConfig) Effective() BlockStreamConfig {
 	if out.IdleMs <= 0 {
 		out.IdleMs = 1500
 	}
+	if out.EditEveryTokens <= 0 {
+		out.EditEveryTokens = 40
+	}
+	if out.EditIntervalMs <= 0 {
+		out.EditIntervalMs = 1000
+	}
 	return out
 }
 
@@ -79,6 +134,20 @@ type BlockStreamer struct {
 	done    bool // Finish() was called
 	flushed bool // at least one block was sent
 
+	// bufSince is when the oldest buffered character arrived.
+	bufSince time.Time
+
+	// Edit-in-place state. liveID is the message being edited ("" when the
+	// next edit must start a new message); liveLen is the buffer length at
+	// the last successful edit.
+	editable bool
+	liveID   string
+	liveLen  int
+	lastEdit time.Time
+
+	// redact rewrites text before it is sent (nil = unchanged).
+	redact func(string) string
+
 	idleTimer *time.Timer
 	ctx       context.Context
 	cancel    context.CancelFunc
@@ -98,11 +167,37 @@ func NewBlockStreamer(
 		channel:    channel,
 		chatID:     chatID,
 		replyTo:    replyTo,
+		editable:   cfg.EditInPlace && channelMgr != nil && channelMgr.SupportsEdit(channel),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
 }
 
+// SetRedactor sets the function applied to every block before it is sent.
+func (bs *BlockStreamer) SetRedactor(fn func(string) string) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.redact = fn
+}
+
+// prepareLocked redacts and formats text for the channel. Must be called
+// with mu held.
+func (bs *BlockStreamer) prepareLocked(text string) string {
+	if bs.redact != nil {
+		text = bs.redact(text)
+	}
+	return FormatForChannel(text, bs.channel)
+}
+
+// safeCutLocked moves a cut in text back so no secret straddles it. With
+// no redactor the cut is kept. Must be called with mu held.
+func (bs *BlockStreamer) safeCutLocked(text string, cut int) int {
+	if bs.redact == nil {
+		return cut
+	}
+	return secretSafeCut(text, cut)
+}
+
 // StreamCallback returns a StreamCallback function suitable for AgentRun.SetStreamCallback.
 func (bs *BlockStreamer) StreamCallback() StreamCallback {
 	return func(chunk string) {
@@ -113,18 +208,49 @@ func (bs *BlockStreamer) StreamCallback() StreamCallback {
 			return
 		}
 
+		prev := bs.buf.Len()
+		if prev == 0 {
+			bs.bufSince = time.Now()
+		}
 		bs.buf.WriteString(chunk)
 
 		// Reset idle timer on every token.
 		bs.resetIdleTimer()
 
+		if bs.editable {
+			bs.maybeEditLocked()
+			return
+		}
+
 		// Check if we should flush.
-		if bs.buf.Len() >= bs.cfg.MaxChars {
+		if bs.buf.Len() >= bs.cfg.MaxChars || bs.policyFlushLocked(prev) {
 			bs.flushLocked()
 		}
 	}
 }
 
+// policyFlushLocked reports whether a flush policy asks for a flush after
+// the buffer grew from prev characters. Must be called with mu held.
+func (bs *BlockStreamer) policyFlushLocked(prev int) bool {
+	n := bs.buf.Len()
+	if len(strings.TrimSpace(bs.buf.String())) < bs.cfg.MinChars {
+		return false
+	}
+	if bs.cfg.FlushEveryChars > 0 && n >= bs.cfg.FlushEveryChars {
+		return true
+	}
+	if bs.cfg.MaxLatencyMs > 0 && time.Since(bs.bufSince) >= time.Duration(bs.cfg.MaxLatencyMs)*time.Millisecond {
+		return true
+	}
+	if bs.cfg.FlushOnSentence {
+		// Only the new text (and the character before it) can complete a
+		// boundary; it must lie past MinChars to be a valid break.
+		from := max(prev-1, bs.cfg.MinChars)
+		return from < n && hasSentenceEnd(bs.buf.String()[from:])
+	}
+	return false
+}
+
 // FlushNow immediately sends any buffered text to the channel, regardless of
 // MinChars threshold. Use this before tool execution to ensure the user sees
 // the LLM's intermediate text (thoughts/reasoning) before tools start running.
@@ -138,7 +264,13 @@ func (bs *BlockStreamer) FlushNow() {
 	if bs.idleTimer != nil {
 		bs.idleTimer.Stop()
 	}
-	bs.flushLocked()
+	if bs.editable {
+		// Finalize the live message; text after the tool runs starts a new one
+		// so tool progress messages stay in order.
+		bs.finalizeLiveLocked()
+		return
+	}
+	bs.flushUpToLocked(true)
 }
 
 // Finish flushes any remaining buffer and marks the streamer as done.
@@ -158,7 +290,9 @@ func (bs *BlockStreamer) Finish() {
 	// IMPORTANT: Flush remaining text BEFORE cancelling the context.
 	// The send operation uses bs.ctx, so cancelling first would silently
 	// drop the final message — causing the user to never receive the response.
-	if bs.buf.Len() > 0 {
+	if bs.editable {
+		bs.finalizeLiveLocked()
+	} else if bs.buf.Len() > 0 {
 		bs.flushLocked()
 	}
 
@@ -195,6 +329,14 @@ func (bs *BlockStreamer) resetIdleTimer() {
 			return
 		}
 
+		// In edit mode a pause just refreshes the live message.
+		if bs.editable {
+			if bs.buf.Len() > bs.liveLen {
+				bs.editLocked(true)
+			}
+			return
+		}
+
 		// If the buffer is too small, don't send a tiny fragment — reschedule
 		// the timer to give the LLM more time to produce a coherent block.
 		if bs.buf.Len() < idleMinChars {
@@ -214,6 +356,13 @@ func (bs *BlockStreamer) resetIdleTimer() {
 
 // flushLocked sends the current buffer as a message block. Must be called with mu held.
 func (bs *BlockStreamer) flushLocked() {
+	bs.flushUpToLocked(bs.done)
+}
+
+// flushUpToLocked sends the buffer as a message block. Unless final, the
+// tail a secret may still be arriving in stays buffered. Must be called
+// with mu held.
+func (bs *BlockStreamer) flushUpToLocked(final bool) {
 	text := bs.buf.String()
 	if len(strings.TrimSpace(text)) == 0 {
 		return
@@ -231,9 +380,13 @@ func (bs *BlockStreamer) flushLocked() {
 			remainder = text[breakIdx:]
 		}
 	}
+	if !final {
+		cut := bs.safeCutLocked(text, len(sendText))
+		sendText, remainder = text[:cut], text[cut:]
+	}
 
-	// Format for channel (also strips reply tags like [[reply_to_current]]).
-	sendText = FormatForChannel(sendText, bs.channel)
+	// Redact, and format for channel (also strips reply tags like [[reply_to_current]]).
+	sendText = bs.prepareLocked(sendText)
 	if len(strings.TrimSpace(sendText)) == 0 {
 		return // Empty after stripping tags — nothing to send.
 	}
@@ -256,7 +409,132 @@ func (bs *BlockStreamer) flushLocked() {
 	bs.buf.Reset()
 	if remainder != "" {
 		bs.buf.WriteString(remainder)
+		bs.bufSince = time.Now()
+	}
+}
+
+// streamingSuffix marks a live message that is still being written.
+const streamingSuffix = " ▍"
+
+// maybeEditLocked edits the live message when enough new text arrived and the
+// rate limit allows it, rolling over to a new message at MaxChars.
+// Must be called with mu held.
+func (bs *BlockStreamer) maybeEditLocked() {
+	if bs.buf.Len() >= bs.cfg.MaxChars {
+		text := bs.buf.String()
+		breakIdx := findNaturalBreak(text, bs.cfg.MinChars, bs.cfg.MaxChars)
+		if breakIdx <= 0 || breakIdx > len(text) {
+			breakIdx = len(text)
+		}
+		if cut := bs.safeCutLocked(text, breakIdx); cut > 0 {
+			breakIdx = cut
+		}
+		remainder := text[breakIdx:]
+		bs.buf.Reset()
+		bs.buf.WriteString(text[:breakIdx])
+		bs.finalizeLiveLocked()
+		// Next message (or chunked delivery, if edits failed) continues here.
+		bs.buf.WriteString(remainder)
+		return
+	}
+
+	newChars := bs.buf.Len() - bs.liveLen
+	if newChars < bs.cfg.EditEveryTokens*4 {
+		return
+	}
+	if time.Since(bs.lastEdit) < time.Duration(bs.cfg.EditIntervalMs)*time.Millisecond {
+		return
+	}
+	bs.editLocked(true)
+}
+
+// finalizeLiveLocked writes the final text of the live message and resets
+// the buffer so subsequent text starts a new message. Must be called with mu held.
+func (bs *BlockStreamer) finalizeLiveLocked() {
+	if bs.buf.Len() == 0 {
+		return
+	}
+	if !bs.editLocked(false) {
+		return
+	}
+	bs.buf.Reset()
+	bs.liveID = ""
+	bs.liveLen = 0
+}
+
+// editLocked sends or edits the live message with the current buffer.
+// Returns false if the edit failed and the streamer fell back to chunked
+// sends. Must be called with mu held.
+func (bs *BlockStreamer) editLocked(inProgress bool) bool {
+	// A live message shows only the text no secret can still be arriving in.
+	shown := bs.buf.Len()
+	if inProgress {
+		shown = bs.safeCutLocked(bs.buf.String(), shown)
+		if shown <= bs.liveLen {
+			return true
+		}
+	}
+	text := strings.TrimSpace(bs.prepareLocked(bs.buf.String()[:shown]))
+	if text == "" {
+		return true
+	}
+	if inProgress {
+		text += streamingSuffix
+	}
+
+	var err error
+	if bs.liveID == "" {
+		bs.liveID, err = bs.channelMgr.SendEditable(bs.ctx, bs.channel, bs.chatID, &channels.OutgoingMessage{
+			Content: text,
+			ReplyTo: bs.replyTo,
+		})
+	} else {
+		err = bs.channelMgr.EditMessage(bs.ctx, bs.channel, bs.chatID, bs.liveID, text)
+	}
+
+	if err != nil {
+		bs.fallbackLocked()
+		return false
+	}
+
+	bs.flushed = true
+	bs.liveLen = shown
+	bs.lastEdit = time.Now()
+	return true
+}
+
+// fallbackLocked switches to chunked sends after an edit failure. Text
+// already visible in the live message is dropped from the buffer so it is
+// not sent twice. Must be called with mu held.
+func (bs *BlockStreamer) fallbackLocked() {
+	bs.editable = false
+	if bs.liveID != "" {
+		text := bs.buf.String()
+		bs.buf.Reset()
+		if bs.liveLen < len(text) {
+			bs.buf.WriteString(text[bs.liveLen:])
+		}
+		bs.sent += bs.liveLen
+	}
+	bs.liveID = ""
+	bs.liveLen = 0
+	if bs.buf.Len() > 0 {
+		bs.flushLocked()
+	}
+}
+
+// hasSentenceEnd reports whether s holds a line break or a sentence end
+// (". ", "! ", "? ").
+func hasSentenceEnd(s string) bool {
+	if strings.Contains(s, "\n") {
+		return true
+	}
+	for i := 0; i+1 < len(s); i++ {
+		if (s[i] == '.' || s[i] == '!' || s[i] == '?') && s[i+1] == ' ' {
+			return true
+		}
 	}
+	return false
 }
 
 // findNaturalBreak finds a good text break point between minIdx and maxIdx.
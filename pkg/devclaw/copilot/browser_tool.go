@@ -504,7 +504,7 @@ func RegisterBrowserTools(executor *ToolExecutor, browserMgr *BrowserManager, lo
 	// browser_screenshot
 	executor.Register(
 		MakeToolDefinition("browser_screenshot",
-			"Take a screenshot of the current browser page. Returns base64-encoded PNG.",
+			"Take a screenshot of the current browser page. Vision-capable models see the image directly.",
 			map[string]any{
 				"type":       "object",
 				"properties": map[string]any{},
@@ -515,10 +515,14 @@ func RegisterBrowserTools(executor *ToolExecutor, browserMgr *BrowserManager, lo
 			if err != nil {
 				return nil, err
 			}
-			// Return truncated info + base64 ref.
-			sizeKB := len(data) * 3 / 4 / 1024
-			_ = base64.StdEncoding // Ensure import is used.
-			return fmt.Sprintf("Screenshot captured (%d KB). Base64 data available for vision analysis.", sizeKB), nil
+			png, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return nil, fmt.Errorf("decoding screenshot: %w", err)
+			}
+			return ToolBlocks{
+				TextBlock(fmt.Sprintf("Screenshot captured (%d KB).", len(png)/1024)),
+				ImageBlock(png, "image/png", ""),
+			}, nil
 		},
 	)
 
@@ -9,6 +9,11 @@
 //	/revoke <phone>          - Revoke user access
 //	/admin <phone>           - Promote user to admin
 //	/users                   - List all authorized users
+//	/grant <level> <phone> <duration> - Temporarily elevate a user (owner only)
+//	/grants                  - List active temporary grants
+//	/approvals [revoke <n|all>] - List or revoke tool approval grants
+//	/review [list]           - List replies held for review
+//	/review approve|edit|reject <id> - Send, rewrite or drop a held reply
 //	/ws create <id> <name>   - Create a workspace
 //	/ws delete <id>          - Delete a workspace
 //	/ws assign <phone> <id>  - Assign user to workspace
@@ -22,16 +27,43 @@
 //	/skills defaults         - List available default skills
 //	/skills install <n|all>  - Install default skills
 //	/status                  - Show bot status
+//	/analytics [YYYY-MM|now] - Show conversation topic report
+//	/alerts [test]           - Show or test the owner alert failover chain
+//	/quota                   - Show this workspace's remaining monthly quota
+//	/quota list|set|topup|reset - Manage workspace quotas (owner only)
+//	/usage today             - Show today's tokens and estimated cost per model
+//	/usage export [csv|json] [YYYY-MM] [ws] - Send the usage ledger as a file
+//	/usage invoice [YYYY-MM] [ws] - Show the monthly usage report per workspace
+//	/memory conflicts        - List contradicting facts waiting for review (owner only)
+//	/memory resolve <id> new|old - Keep the new or the old fact of a conflict
+//	/memory history <subject/attribute> - Show the versions of a fact
+//	/checkpoint [name|list]  - Save a named snapshot of the conversation
+//	/rewind [name]           - Restore the conversation to a checkpoint
+//	/fork <name> [checkpoint] - Branch the conversation into a new session
+//	/fork list|switch <name|main> - List or switch conversation branches
+//	/export [md|json|html]   - Send the session transcript as a file
+//	/share                   - Publish the transcript as a redacted HTML page (owner only)
+//	/receipt                 - List the tool calls behind the last reply
+//	/plan [task|cancel]      - Plan a task without running tools, or show/drop the pending plan
+//	/urgent <msg>            - Make the active run address msg at its next step
+//	/cancel-and-ask <msg>    - Abort the active run and restart with msg
+//	/readonly [on|off]       - Show or set read-only mode for this chat (admin to change)
+//	/define [term [= meaning]] - List the glossary, show a term or define one
+//	/glossary accept|reject|remove <term> - Review suggested terms (admin)
 //	/help                    - Show available commands
 package copilot
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
 	"github.com/jholhewres/devclaw/pkg/devclaw/skills"
 )
 
@@ -49,6 +81,56 @@ func IsCommand(content string) bool {
 	return strings.HasPrefix(strings.TrimSpace(content), "/")
 }
 
+// chatCommandSpecs lists the commands HandleCommand understands, for channels
+// with a native command menu (Discord slash commands). Permission checks
+// still happen in HandleCommand.
+var chatCommandSpecs = []channels.CommandSpec{
+	{Name: "help", Description: "Show available commands"},
+	{Name: "status", Description: "Bot status"},
+	{Name: "stop", Description: "Stop the active agent run"},
+	{Name: "model", Description: "Show or change the model", TakesArgs: true},
+	{Name: "compact", Description: "Compact session history"},
+	{Name: "new", Description: "Start a new session (keep facts & config)"},
+	{Name: "reset", Description: "Full session reset"},
+	{Name: "checkpoint", Description: "Save a snapshot of the conversation", TakesArgs: true},
+	{Name: "rewind", Description: "Restore the conversation to a checkpoint", TakesArgs: true},
+	{Name: "fork", Description: "Branch the conversation (name|list|switch)", TakesArgs: true},
+	{Name: "export", Description: "Export the session transcript (md|json|html)", TakesArgs: true},
+	{Name: "share", Description: "Publish the transcript as an HTML page (owner only)"},
+	{Name: "receipt", Description: "Tool calls behind the last reply"},
+	{Name: "plan", Description: "Plan a task before running anything (go to execute)", TakesArgs: true},
+	{Name: "urgent", Description: "Make the running task address this first", TakesArgs: true},
+	{Name: "cancel-and-ask", Description: "Abort the running task and ask this instead", TakesArgs: true},
+	{Name: "readonly", Description: "Read-only mode for this chat (on|off)", TakesArgs: true},
+	{Name: "usage", Description: "Show token usage", TakesArgs: true},
+	{Name: "define", Description: "Define a workspace term (term = meaning)", TakesArgs: true},
+	{Name: "glossary", Description: "Workspace glossary (list|accept|reject|remove)", TakesArgs: true},
+	{Name: "think", Description: "Set thinking level (off|low|medium|high)", TakesArgs: true},
+	{Name: "tts", Description: "Text-to-speech mode (off|always|inbound)", TakesArgs: true},
+	{Name: "verbose", Description: "Toggle verbose tool narration", TakesArgs: true},
+	{Name: "queue", Description: "Set queue mode (collect|steer|followup|interrupt)", TakesArgs: true},
+	{Name: "approve", Description: "Approve a pending tool execution", TakesArgs: true},
+	{Name: "deny", Description: "Deny a pending tool execution", TakesArgs: true},
+	{Name: "skills", Description: "List or install skills", TakesArgs: true},
+	{Name: "allow", Description: "Grant user access", TakesArgs: true},
+	{Name: "block", Description: "Block a user", TakesArgs: true},
+	{Name: "unblock", Description: "Unblock a user", TakesArgs: true},
+	{Name: "revoke", Description: "Revoke user access", TakesArgs: true},
+	{Name: "admin", Description: "Promote a user to admin", TakesArgs: true},
+	{Name: "users", Description: "List authorized users"},
+	{Name: "grant", Description: "Temporarily elevate a user (owner only)", TakesArgs: true},
+	{Name: "grants", Description: "List active temporary grants"},
+	{Name: "approvals", Description: "List or revoke tool approval grants", TakesArgs: true},
+	{Name: "review", Description: "Review held replies (list|approve|edit|reject)", TakesArgs: true},
+	{Name: "ws", Description: "Manage workspaces", TakesArgs: true},
+	{Name: "group", Description: "Allow, block or assign this group", TakesArgs: true},
+	{Name: "analytics", Description: "Conversation topic report", TakesArgs: true},
+	{Name: "alerts", Description: "Show or test owner alert contacts", TakesArgs: true},
+	{Name: "activation", Description: "Set group activation mode (always|mention)", TakesArgs: true},
+	{Name: "quota", Description: "Show or manage workspace quotas", TakesArgs: true},
+	{Name: "memory", Description: "Review memory conflicts and fact versions", TakesArgs: true},
+}
+
 // HandleCommand processes an admin command from a chat message.
 // Returns handled=true if it was a valid command (even if permission denied).
 func (a *Assistant) HandleCommand(msg *channels.IncomingMessage) CommandResult {
@@ -115,6 +197,30 @@ func (a *Assistant) HandleCommand(msg *channels.IncomingMessage) CommandResult {
 		}
 		return CommandResult{Response: a.usersCommand(), Handled: true}
 
+	case "/grant":
+		if senderLevel != AccessOwner {
+			return CommandResult{Response: "Only owners can grant temporary access.", Handled: true}
+		}
+		return CommandResult{Response: a.grantCommand(args, msg.From), Handled: true}
+
+	case "/grants":
+		if !isAdmin {
+			return CommandResult{Response: "Permission denied.", Handled: true}
+		}
+		return CommandResult{Response: a.grantsCommand(), Handled: true}
+
+	case "/approvals":
+		if !isAdmin {
+			return CommandResult{Response: "Permission denied.", Handled: true}
+		}
+		return CommandResult{Response: a.approvalsCommand(args), Handled: true}
+
+	case "/review":
+		if !isAdmin {
+			return CommandResult{Response: "Permission denied.", Handled: true}
+		}
+		return CommandResult{Response: a.reviewCommand(args, msg), Handled: true}
+
 	case "/ws", "/workspace":
 		if !isAdmin {
 			return CommandResult{Response: "Permission denied.", Handled: true}
@@ -129,7 +235,7 @@ func (a *Assistant) HandleCommand(msg *channels.IncomingMessage) CommandResult {
 
 	// Approval commands (work even when session is busy).
 	case "/approve":
-		return CommandResult{Response: a.approveCommand(args, msg), Handled: true}
+		return CommandResult{Response: a.approveCommand(args, msg, senderLevel), Handled: true}
 	case "/deny":
 		return CommandResult{Response: a.denyCommand(args, msg), Handled: true}
 
@@ -148,6 +254,32 @@ func (a *Assistant) HandleCommand(msg *channels.IncomingMessage) CommandResult {
 		return CommandResult{Response: a.newCommand(msg), Handled: true}
 	case "/reset":
 		return CommandResult{Response: a.resetCommand(msg), Handled: true}
+	case "/checkpoint":
+		return CommandResult{Response: a.checkpointCommand(args, msg), Handled: true}
+	case "/rewind":
+		return CommandResult{Response: a.rewindCommand(args, msg), Handled: true}
+	case "/fork":
+		return CommandResult{Response: a.forkCommand(args, msg), Handled: true}
+	case "/export":
+		return CommandResult{Response: a.exportCommand(args, msg), Handled: true}
+	case "/share":
+		if senderLevel != AccessOwner {
+			return CommandResult{Response: "Only owners can publish transcripts.", Handled: true}
+		}
+		return CommandResult{Response: a.shareCommand(msg), Handled: true}
+	case "/receipt":
+		return CommandResult{Response: a.receiptCommand(msg), Handled: true}
+	case "/plan":
+		return CommandResult{Response: a.planCommand(args, msg), Handled: true}
+	case "/readonly":
+		return CommandResult{Response: a.readonlyCommand(args, msg, isAdmin), Handled: true}
+	case "/define":
+		return CommandResult{Response: a.defineCommand(msg), Handled: true}
+	case "/glossary":
+		return CommandResult{Response: a.glossaryCommand(args, msg, isAdmin), Handled: true}
+	case "/urgent", "/cancel-and-ask":
+		// With a message these never reach HandleCommand (see interrupts.go).
+		return CommandResult{Response: "Usage: " + cmd + " <message>", Handled: true}
 	case "/think":
 		return CommandResult{Response: a.thinkCommand(args, msg), Handled: true}
 
@@ -163,11 +295,28 @@ func (a *Assistant) HandleCommand(msg *channels.IncomingMessage) CommandResult {
 		return CommandResult{Response: a.queueCommand(args, msg), Handled: true}
 	case "/usage":
 		return CommandResult{Response: a.usageCommand(args, msg), Handled: true}
+	case "/analytics":
+		if !isAdmin {
+			return CommandResult{Response: "Permission denied.", Handled: true}
+		}
+		return CommandResult{Response: a.analyticsCommand(args, msg, senderLevel == AccessOwner), Handled: true}
+	case "/alerts":
+		if senderLevel != AccessOwner {
+			return CommandResult{Response: "Only owners can manage alerts.", Handled: true}
+		}
+		return CommandResult{Response: a.alertsCommand(args), Handled: true}
 	case "/activation":
 		if !isAdmin {
 			return CommandResult{Response: "Permission denied.", Handled: true}
 		}
 		return CommandResult{Response: a.activationCommand(args, msg), Handled: true}
+	case "/quota":
+		return CommandResult{Response: a.quotaCommand(args, msg, senderLevel == AccessOwner), Handled: true}
+	case "/memory":
+		if senderLevel != AccessOwner {
+			return CommandResult{Response: "Only owners can manage memory.", Handled: true}
+		}
+		return CommandResult{Response: a.memoryCommand(args), Handled: true}
 
 	default:
 		return CommandResult{Handled: false}
@@ -187,7 +336,11 @@ func (a *Assistant) helpCommand(isAdmin bool) string {
 		b.WriteString("/unblock <phone> - Unblock a user\n")
 		b.WriteString("/revoke <phone> - Revoke access\n")
 		b.WriteString("/admin <phone> - Promote to admin\n")
-		b.WriteString("/users - List authorized users\n\n")
+		b.WriteString("/users - List authorized users\n")
+		b.WriteString("/grant <admin|user> <phone> <duration> - Temporary elevation (e.g. 2h)\n")
+		b.WriteString("/grants - List active temporary grants\n")
+		b.WriteString("/approvals [revoke <n|all>] - List or revoke tool approval grants\n")
+		b.WriteString("/review [list] | approve|edit|reject <id> - Review replies held for customer workspaces\n\n")
 
 		b.WriteString("*Workspaces:*\n")
 		b.WriteString("/ws create <id> <name> - Create workspace\n")
@@ -202,10 +355,15 @@ func (a *Assistant) helpCommand(isAdmin bool) string {
 		b.WriteString("/group assign <ws_id> - Assign to workspace\n\n")
 
 		b.WriteString("/status - Bot status\n")
+		b.WriteString("/analytics [YYYY-MM|now] - Topic report per workspace\n")
+		b.WriteString("/alerts [test] - Owner alert contacts\n")
+		b.WriteString("/quota list|set|topup|reset - Manage workspace quotas (owner)\n")
+		b.WriteString("/memory conflicts|resolve|history - Review contradicting facts (owner)\n")
 	}
 
 	b.WriteString("\n*Approval:*\n")
-	b.WriteString("/approve <id> - Approve a pending tool execution\n")
+	b.WriteString("/approve <id> [files] - Approve a pending tool execution (or only some files of a diff review)\n")
+	b.WriteString("/approve <id> once|session|30m|always [pattern] - How far the approval reaches (always: owner)\n")
 	b.WriteString("/deny <id> - Deny a pending tool execution\n\n")
 
 	b.WriteString("*Skills:*\n")
@@ -219,6 +377,18 @@ func (a *Assistant) helpCommand(isAdmin bool) string {
 	b.WriteString("/compact - Compact session history\n")
 	b.WriteString("/new - Start new session (keep facts & config)\n")
 	b.WriteString("/reset - Full session reset\n")
+	b.WriteString("/checkpoint [name|list] - Save a snapshot of the conversation\n")
+	b.WriteString("/rewind [name] - Restore the conversation to a checkpoint\n")
+	b.WriteString("/fork <name> [checkpoint] - Branch the conversation (/fork list, /fork switch <name|main>)\n")
+	b.WriteString("/export [md|json|html] - Send the session transcript (with tool calls and usage) as a file\n")
+	b.WriteString("/share - Publish the transcript as a redacted HTML page and get a link (owner only)\n")
+	b.WriteString("/receipt - Tool calls behind the last reply: duration, guard, approval, output size\n")
+	b.WriteString("/plan <task> - Step-by-step plan with the tools it would call; reply go to execute (/plan cancel drops it)\n")
+	b.WriteString("/urgent <msg> - Have the running task address msg at its next step\n")
+	b.WriteString("/cancel-and-ask <msg> - Abort the running task and restart with msg, keeping what it already did\n")
+	b.WriteString("/readonly [on|off] - Only reads and searches run in this chat (admin to change)\n")
+	b.WriteString("/define <term> = <meaning> - Teach the workspace glossary a term (/define alone lists it)\n")
+	b.WriteString("/glossary - Workspace glossary and suggested terms (admins: accept|reject|remove <term>)\n")
 	b.WriteString("/usage [reset] - Show token usage\n")
 	b.WriteString("/think [off|low|medium|high] - Set thinking level\n")
 	b.WriteString("/tts [off|always|inbound] - Toggle text-to-speech\n")
@@ -226,8 +396,12 @@ func (a *Assistant) helpCommand(isAdmin bool) string {
 	b.WriteString("/reasoning [off|low|medium|high] - Set reasoning level (alias: /think)\n")
 	b.WriteString("/queue [collect|steer|followup|interrupt] - Set queue mode\n")
 	b.WriteString("/usage [reset|global] - Show token usage\n")
+	b.WriteString("/usage today - Today's tokens and estimated cost per model\n")
+	b.WriteString("/quota - Remaining monthly quota for this workspace\n")
 
 	if isAdmin {
+		b.WriteString("/usage export [csv|json] [YYYY-MM] [workspace] - Usage ledger as a file\n")
+		b.WriteString("/usage invoice [YYYY-MM] [workspace] - Monthly usage report per workspace\n")
 		b.WriteString("/activation [always|mention] - Set group activation mode\n")
 	}
 
@@ -249,6 +423,15 @@ func (a *Assistant) usageCommand(args []string, msg *channels.IncomingMessage) s
 			}
 			return "Usage counters reset."
 		}
+		if arg == "today" {
+			return a.usageTodayCommand(msg, isAdmin)
+		}
+		if arg == "export" || arg == "invoice" {
+			if !isAdmin {
+				return "Permission denied."
+			}
+			return a.usageLedgerCommand(arg, args[1:], msg)
+		}
 		if arg == "global" {
 			if !isAdmin {
 				return "Permission denied."
@@ -271,38 +454,217 @@ func (a *Assistant) usageCommand(args []string, msg *channels.IncomingMessage) s
 	// No args: show usage for current chat's session (Session + UsageTracker)
 	promptTok, completionTok, requests := session.GetTokenUsage()
 	total := promptTok + completionTok
+	loc := a.config.LocaleFor(session.GetConfig().Language, msg.From, msg.ChatID).Locale
+	n := func(v int) string { return loc.FormatInt(int64(v)) }
 	var b strings.Builder
 	b.WriteString("*Token Usage*\n\n")
-	b.WriteString(fmt.Sprintf("Prompt: %d | Completion: %d | Total: %d\n", promptTok, completionTok, total))
-	b.WriteString(fmt.Sprintf("Requests: %d\n", requests))
+	b.WriteString(fmt.Sprintf("Prompt: %s | Completion: %s | Total: %s\n", n(promptTok), n(completionTok), n(total)))
+	b.WriteString(fmt.Sprintf("Requests: %s\n", n(requests)))
 	if a.usageTracker != nil {
 		if su := a.usageTracker.GetSession(session.ID); su != nil && su.EstimatedCostUSD > 0 {
-			b.WriteString(fmt.Sprintf("Est. cost: $%.4f\n", su.EstimatedCostUSD))
+			b.WriteString(fmt.Sprintf("Est. cost: $%s\n", loc.FormatNumber(su.EstimatedCostUSD, 4)))
 		}
 	}
 	return b.String()
 }
 
-func (a *Assistant) approveCommand(args []string, msg *channels.IncomingMessage) string {
+// usageLedgerCommand implements /usage export [csv|json] [YYYY-MM] [workspace]
+// and /usage invoice [YYYY-MM] [workspace]. The month defaults to the
+// current one and the workspace to all of them.
+func (a *Assistant) usageLedgerCommand(sub string, args []string, msg *channels.IncomingMessage) string {
+	if a.usageLedger == nil {
+		return "Usage ledger not available (billing.enabled is off or devclaw.db is missing)."
+	}
+	format, month, wsID := "csv", time.Now().UTC().Format("2006-01"), ""
+	for _, arg := range args {
+		switch {
+		case arg == "csv" || arg == "json":
+			format = arg
+		case len(arg) == 7 && arg[4] == '-':
+			month = arg
+		default:
+			wsID = arg
+		}
+	}
+
+	if sub == "invoice" {
+		invoices, err := a.usageLedger.Invoices(month, wsID)
+		if err != nil {
+			return "Usage report failed: " + err.Error()
+		}
+		if len(invoices) == 0 {
+			return fmt.Sprintf("No usage recorded for %s.", month)
+		}
+		return formatUsageReport(invoices, a.config.LocaleFor("", msg.From, msg.ChatID).Locale)
+	}
+
+	entries, err := a.usageLedger.Entries(month, wsID)
+	if err != nil {
+		return "Export failed: " + err.Error()
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("No usage recorded for %s.", month)
+	}
+	var buf bytes.Buffer
+	if err := ExportUsage(&buf, format, entries); err != nil {
+		return "Export failed: " + err.Error()
+	}
+	name := "usage-" + month
+	if wsID != "" {
+		name += "-" + wsID
+	}
+	mime := "text/csv"
+	if format == "json" {
+		mime = "application/json"
+	}
+	media := &channels.MediaMessage{
+		Type:     channels.MessageDocument,
+		Data:     buf.Bytes(),
+		MimeType: mime,
+		Filename: name + "." + format,
+		Caption:  fmt.Sprintf("Usage %s: %d LLM calls", month, len(entries)),
+	}
+	if err := a.channelMgr.SendMedia(a.ctx, msg.Channel, msg.ChatID, media); err != nil {
+		a.logger.Warn("failed to send usage export", "month", month, "error", err)
+		return fmt.Sprintf("Could not send the file on this channel (%v). Use GET /api/usage/export instead.", err)
+	}
+	return fmt.Sprintf("Exported %d LLM calls.", len(entries))
+}
+
+// usageTodayCommand implements /usage today: today's LLM calls, tokens and
+// estimated cost per model from the usage ledger, for the chat's workspace
+// or, for admins, all workspaces with a per-workspace breakdown.
+func (a *Assistant) usageTodayCommand(msg *channels.IncomingMessage, isAdmin bool) string {
+	if a.usageLedger == nil {
+		return "Usage ledger not available (billing.enabled is off or devclaw.db is missing)."
+	}
+	ul := a.config.LocaleFor("", msg.From, msg.ChatID)
+	now := time.Now().In(ul.Location)
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, ul.Location)
+	wsID := ""
+	if !isAdmin {
+		wsID = a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace.ID
+	}
+	entries, err := a.usageLedger.EntriesSince(since, wsID)
+	if err != nil {
+		return "Usage report failed: " + err.Error()
+	}
+	return BuildUsageReport(since, wsID, entries, isAdmin).Format(ul.Locale)
+}
+
+func (a *Assistant) approveCommand(args []string, msg *channels.IncomingMessage, senderLevel AccessLevel) string {
 	sessionID := MakeSessionID(msg.Channel, msg.ChatID)
 
 	// If no ID provided, approve the most recent pending request for this session.
+	// A trailing file list ("1,3") approves only those files of a diff review;
+	// a trailing scope (once, session, 30m, always [pattern]) sets how far the
+	// approval reaches.
 	var targetID string
 	if len(args) >= 1 && args[0] != "" {
-		targetID = args[0]
-	} else {
+		_, isSelection := parseFileSelection(args[0], 0)
+		_, isScope, _ := parseApprovalScope(args)
+		if _, err := uuid.Parse(args[0]); err == nil || (!isSelection && !isScope) {
+			targetID, args = args[0], args[1:]
+		}
+	}
+	if targetID == "" {
 		targetID = a.approvalMgr.LatestPendingForSession(sessionID)
 		if targetID == "" {
 			return "No pending approvals."
 		}
 	}
 
-	if a.approvalMgr.Resolve(targetID, sessionID, msg.From, true, "") {
+	var scope ApprovalScope
+	isScope := false
+	if len(args) > 0 {
+		if _, isSelection := parseFileSelection(args[0], 0); !isSelection {
+			var err error
+			if scope, isScope, err = parseApprovalScope(args); err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+		}
+	}
+	if isScope {
+		if scope.Kind == ScopeAlways && senderLevel != AccessOwner {
+			return "Only owners can approve a tool for every session."
+		}
+		if a.approvalMgr.PendingFiles(targetID) > 0 && scope.Kind != ScopeOnce {
+			return "Diff reviews are approved one at a time: use /approve " + targetID + " [files]."
+		}
+		if !a.approvalMgr.ResolveScoped(targetID, sessionID, msg.From, scope) {
+			return "Approval not found or already resolved."
+		}
+		switch scope.Kind {
+		case ScopeOnce:
+			return "✅ Approved once."
+		case ScopeFor:
+			return fmt.Sprintf("✅ Approved for %s in this session.", formatGrantDuration(scope.Duration))
+		case ScopeAlways:
+			if scope.Pattern != "" {
+				return fmt.Sprintf("✅ Approved, and always for %q. Revoke with /approvals.", scope.Pattern)
+			}
+			return "✅ Approved, and always for this exact call. Revoke with /approvals."
+		}
+		return "✅ Approved for this session."
+	}
+
+	var files []int
+	if len(args) > 0 {
+		n := a.approvalMgr.PendingFiles(targetID)
+		sel, ok := parseFileSelection(strings.Join(args, ","), n)
+		if !ok || n == 0 {
+			return fmt.Sprintf("Invalid file selection %q: use numbers from the review list, e.g. /approve %s 1,3", strings.Join(args, " "), targetID)
+		}
+		files = sel
+	}
+
+	if a.approvalMgr.ResolveFiles(targetID, sessionID, msg.From, true, files, "") {
+		if files != nil {
+			return fmt.Sprintf("✅ Approved %d file(s).", len(files))
+		}
 		return "✅ Approved."
 	}
 	return "Approval not found or already resolved."
 }
 
+func (a *Assistant) approvalsCommand(args []string) string {
+	grants := a.approvalMgr.Grants()
+	if len(args) >= 2 && strings.EqualFold(args[0], "revoke") {
+		if strings.EqualFold(args[1], "all") {
+			return fmt.Sprintf("Revoked %d approval grant(s). Grants from config stay.", a.approvalMgr.RevokeAllGrants())
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || n > len(grants) {
+			return "Usage: /approvals revoke <n|all> (n from /approvals)"
+		}
+		g := grants[n-1]
+		if g.GrantedBy == "config" {
+			return "That grant comes from security.approvals.always in the config."
+		}
+		if !a.approvalMgr.RevokeGrant(g) {
+			return "Grant not found (it may have expired)."
+		}
+		return "Revoked: " + g.String()
+	}
+	if len(args) > 0 {
+		return "Usage: /approvals [revoke <n|all>]"
+	}
+
+	if len(grants) == 0 {
+		return "No approval grants."
+	}
+	var b strings.Builder
+	b.WriteString("*Approval Grants:*\n\n")
+	for i, g := range grants {
+		by := g.GrantedBy
+		if by == "" {
+			by = "unknown"
+		}
+		b.WriteString(fmt.Sprintf("%d. %s — by %s\n", i+1, g, by))
+	}
+	return b.String()
+}
+
 func (a *Assistant) denyCommand(args []string, msg *channels.IncomingMessage) string {
 	sessionID := MakeSessionID(msg.Channel, msg.ChatID)
 
@@ -416,6 +778,164 @@ func (a *Assistant) resetCommand(msg *channels.IncomingMessage) string {
 	return "Session reset completely."
 }
 
+func (a *Assistant) checkpointCommand(args []string, msg *channels.IncomingMessage) string {
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	session := resolved.Session
+
+	if len(args) > 0 && strings.EqualFold(args[0], "list") {
+		cps := session.Checkpoints()
+		if len(cps) == 0 {
+			return "No checkpoints. Use /checkpoint [name] to save one."
+		}
+		var b strings.Builder
+		b.WriteString("*Checkpoints:*\n")
+		for _, cp := range cps {
+			b.WriteString(fmt.Sprintf("- %s (%d entries, %s)\n",
+				cp.Name, len(cp.History), cp.CreatedAt.Format("2006-01-02 15:04")))
+		}
+		return strings.TrimRight(b.String(), "\n")
+	}
+
+	cp, err := session.Checkpoint(strings.Join(args, "-"))
+	if err != nil {
+		return "Checkpoint failed: " + err.Error()
+	}
+	return fmt.Sprintf("Checkpoint *%s* saved (%d entries). Use /rewind %s to return to it.",
+		cp.Name, len(cp.History), cp.Name)
+}
+
+func (a *Assistant) rewindCommand(args []string, msg *channels.IncomingMessage) string {
+	if a.messageQueue.IsProcessing(MakeSessionID(msg.Channel, msg.ChatID)) {
+		return "An agent run is in progress. Use /stop first, then /rewind."
+	}
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	cp, err := resolved.Session.Rewind(strings.Join(args, "-"))
+	if err != nil {
+		return "Rewind failed: " + err.Error()
+	}
+	return fmt.Sprintf("Rewound to checkpoint *%s* (%d entries).", cp.Name, len(cp.History))
+}
+
+func (a *Assistant) forkCommand(args []string, msg *channels.IncomingMessage) string {
+	const usage = "Usage: /fork <name> [checkpoint] | /fork list | /fork switch <name|main>"
+	if len(args) == 0 {
+		return usage
+	}
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	store := resolved.SessionStore
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		names, active := store.Branches(msg.Channel, msg.ChatID)
+		var b strings.Builder
+		b.WriteString("*Branches:*\n")
+		for _, name := range append([]string{mainBranch}, names...) {
+			marker := ""
+			if name == active {
+				marker = " (active)"
+			}
+			b.WriteString("- " + name + marker + "\n")
+		}
+		return strings.TrimRight(b.String(), "\n")
+
+	case "switch":
+		if len(args) < 2 {
+			return usage
+		}
+		if a.messageQueue.IsProcessing(MakeSessionID(msg.Channel, msg.ChatID)) {
+			return "An agent run is in progress. Use /stop first, then /fork switch."
+		}
+		if err := store.SwitchBranch(msg.Channel, msg.ChatID, args[1]); err != nil {
+			return "Switch failed: " + err.Error()
+		}
+		return fmt.Sprintf("Switched to branch *%s*.", args[1])
+	}
+
+	if a.messageQueue.IsProcessing(MakeSessionID(msg.Channel, msg.ChatID)) {
+		return "An agent run is in progress. Use /stop first, then /fork."
+	}
+	checkpoint := ""
+	if len(args) > 1 {
+		checkpoint = args[1]
+	}
+	fork, err := store.Fork(msg.Channel, msg.ChatID, args[0], checkpoint)
+	if err != nil {
+		return "Fork failed: " + err.Error()
+	}
+	return fmt.Sprintf("Forked into branch *%s* (%d entries). Messages now go to this branch; /fork switch main returns to the original.",
+		fork.Branch, fork.HistoryLen())
+}
+
+func (a *Assistant) exportCommand(args []string, msg *channels.IncomingMessage) string {
+	format := "md"
+	if len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	export := resolved.SessionStore.Export(resolved.Session.ID)
+	if export == nil || len(export.Messages) == 0 {
+		return "Nothing to export: the conversation is empty."
+	}
+	data, err := export.Render(format)
+	if err != nil {
+		return "Export failed: " + err.Error()
+	}
+
+	ext, mime := "md", "text/markdown"
+	switch format {
+	case "json":
+		ext, mime = "json", "application/json"
+	case "html":
+		ext, mime = "html", "text/html"
+	}
+	media := &channels.MediaMessage{
+		Type:     channels.MessageDocument,
+		Data:     data,
+		MimeType: mime,
+		Filename: fmt.Sprintf("session-%s-%s.%s", export.ID, time.Now().Format("20060102-1504"), ext),
+		Caption:  fmt.Sprintf("Transcript: %d messages", len(export.Messages)),
+	}
+	if err := a.channelMgr.SendMedia(a.ctx, msg.Channel, msg.ChatID, media); err != nil {
+		a.logger.Warn("failed to send session export", "session", export.ID, "error", err)
+		return fmt.Sprintf("Could not send the file on this channel (%v). "+
+			"On the server, run: devclaw sessions export %s --format %s", err, export.ID, ext)
+	}
+	return fmt.Sprintf("Exported %d messages.", len(export.Messages))
+}
+
+// shareCommand publishes the session transcript as a static HTML page.
+func (a *Assistant) shareCommand(msg *channels.IncomingMessage) string {
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	export := resolved.SessionStore.Export(resolved.Session.ID)
+	if export == nil || len(export.Messages) == 0 {
+		return "Nothing to share: the conversation is empty."
+	}
+	link, err := PublishSessionExport(a.ctx, a.config, export)
+	if err != nil {
+		a.logger.Warn("failed to publish session", "session", export.ID, "error", err)
+		return "Share failed: " + err.Error()
+	}
+	a.logger.Info("session published", "session", export.ID, "link", link)
+	if a.config.Share.BaseURL == "" {
+		return fmt.Sprintf("Transcript (%d messages, secrets redacted) written to %s. Set share.base_url to get a public link.",
+			len(export.Messages), link)
+	}
+	return fmt.Sprintf("Transcript (%d messages, secrets redacted): %s", len(export.Messages), link)
+}
+
+// receiptCommand lists the tool calls made for the last reply.
+func (a *Assistant) receiptCommand(msg *channels.IncomingMessage) string {
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	last := resolved.Session.RecentHistory(1)
+	if len(last) == 0 {
+		return "No reply yet in this conversation."
+	}
+	if last[0].Meta == nil {
+		return "No receipt for the last reply (it was not recorded)."
+	}
+	return last[0].Meta.Receipt()
+}
+
 func (a *Assistant) thinkCommand(args []string, msg *channels.IncomingMessage) string {
 	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
 	session := resolved.Session
@@ -539,6 +1059,43 @@ func (a *Assistant) adminCommand(args []string, grantedBy string) string {
 	return fmt.Sprintf("User %s promoted to admin.", jid)
 }
 
+func (a *Assistant) grantCommand(args []string, grantedBy string) string {
+	if len(args) < 3 {
+		return "Usage: /grant <admin|user> <phone_number> <duration>\nExample: /grant admin 5511999999999 2h"
+	}
+	level := AccessLevel(strings.ToLower(args[0]))
+	jid := args[1]
+	d, err := parseGrantDuration(args[2])
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	entry, err := a.accessMgr.GrantTemporary(jid, level, d, grantedBy)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return fmt.Sprintf("User %s elevated to %s for %s (until %s).",
+		entry.JID, entry.Level, formatGrantDuration(d), entry.ExpiresAt.Format("2006-01-02 15:04"))
+}
+
+func (a *Assistant) grantsCommand() string {
+	grants := a.accessMgr.ListGrants()
+	if len(grants) == 0 {
+		return "No active temporary grants."
+	}
+
+	var b strings.Builder
+	b.WriteString("*Temporary Grants:*\n\n")
+	for _, g := range grants {
+		revertsTo := string(AccessUnknown)
+		if g.Previous != nil {
+			revertsTo = string(g.Previous.Level)
+		}
+		b.WriteString(fmt.Sprintf("• %s [%s] by %s — %s left (then %s)\n",
+			g.JID, g.Level, g.AddedBy, formatGrantDuration(time.Until(g.ExpiresAt)), revertsTo))
+	}
+	return b.String()
+}
+
 func (a *Assistant) usersCommand() string {
 	entries := a.accessMgr.ListUsers()
 	if len(entries) == 0 {
@@ -550,6 +1107,9 @@ func (a *Assistant) usersCommand() string {
 
 	for _, e := range entries {
 		b.WriteString(fmt.Sprintf("• %s [%s]", e.JID, e.Level))
+		if !e.ExpiresAt.IsZero() {
+			b.WriteString(fmt.Sprintf(" (until %s)", e.ExpiresAt.Format("2006-01-02 15:04")))
+		}
 		if e.Note != "" {
 			b.WriteString(fmt.Sprintf(" - %s", e.Note))
 		}
@@ -664,6 +1224,19 @@ func (a *Assistant) workspaceCommand(args []string, msg *channels.IncomingMessag
 		if len(ws.Skills) > 0 {
 			b.WriteString(fmt.Sprintf("Skills: %s\n", strings.Join(ws.Skills, ", ")))
 		}
+		if len(ws.Tools) > 0 {
+			b.WriteString(fmt.Sprintf("Tools: %s\n", strings.Join(ws.Tools, ", ")))
+		}
+		if len(ws.DeniedTools) > 0 {
+			b.WriteString(fmt.Sprintf("Denied tools: %s\n", strings.Join(ws.DeniedTools, ", ")))
+		}
+		b.WriteString(fmt.Sprintf("Features: %s\n", a.featureSummary(ws)))
+		if ws.PromptDir != "" {
+			b.WriteString(fmt.Sprintf("Prompt dir: %s\n", ws.PromptDir))
+		}
+		if len(ws.DelegateTo) > 0 {
+			b.WriteString(fmt.Sprintf("Delegates to: %s\n", strings.Join(ws.DelegateTo, ", ")))
+		}
 		b.WriteString(fmt.Sprintf("Members (%d): %s\n", len(ws.Members), strings.Join(ws.Members, ", ")))
 		b.WriteString(fmt.Sprintf("Groups (%d): %s\n", len(ws.Groups), strings.Join(ws.Groups, ", ")))
 		if !ws.CreatedAt.IsZero() {
@@ -740,6 +1313,9 @@ func (a *Assistant) skillsCommand(args []string, msg *channels.IncomingMessage)
 		if len(subArgs) == 0 {
 			return "Usage: /skills install <name1> <name2> ... or /skills install all"
 		}
+		if ws := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace; !a.featureEnabled(ws, FeatureSkillInstall) {
+			return fmt.Sprintf("Skill installation is disabled for workspace %s.", ws.ID)
+		}
 
 		names := subArgs
 		if len(names) == 1 && strings.ToLower(names[0]) == "all" {
@@ -747,6 +1323,11 @@ func (a *Assistant) skillsCommand(args []string, msg *channels.IncomingMessage)
 		}
 
 		installed, skipped, failed := skills.InstallDefaultSkills(skillsDir, names)
+		if installed > 0 {
+			a.eventLog.Emit(EventSkillInstall, msg.From, strings.Join(names, ","), map[string]any{
+				"source": "defaults", "installed": installed,
+			})
+		}
 
 		// Hot-reload registry.
 		reloadCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -836,6 +1417,7 @@ func (a *Assistant) activationCommand(args []string, msg *channels.IncomingMessa
 	case "always":
 		a.configMu.Lock()
 		a.config.Trigger = ""
+		a.config.Triggers = TriggerConfig{}
 		a.configMu.Unlock()
 		return "Activation mode: always (responds to all messages in groups)"
 	case "mention":
@@ -845,8 +1427,9 @@ func (a *Assistant) activationCommand(args []string, msg *channels.IncomingMessa
 			name = "devclaw"
 		}
 		a.config.Trigger = name
+		a.config.Triggers.MentionOrReply = true
 		a.configMu.Unlock()
-		return fmt.Sprintf("Activation mode: mention-only (requires '%s' in message)", name)
+		return fmt.Sprintf("Activation mode: mention-only (message starting with '%s', an @mention or a reply to me)", name)
 	default:
 		return "Usage: /activation [always|mention]"
 	}
@@ -890,3 +1473,259 @@ func (a *Assistant) groupCommand(args []string, msg *channels.IncomingMessage) s
 		return "Unknown group command. Use: allow, block, assign"
 	}
 }
+
+// analyticsCommand shows the conversation topic report. Owners see every
+// workspace; admins only the workspace of the current chat.
+// Usage: /analytics [YYYY-MM|now] (default: last month).
+func (a *Assistant) analyticsCommand(args []string, msg *channels.IncomingMessage, isOwner bool) string {
+	if a.topicAnalyzer == nil {
+		return "Topic analytics is not enabled."
+	}
+
+	wsID := ""
+	if !isOwner {
+		wsID = a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace.ID
+	}
+
+	month := time.Now().UTC().AddDate(0, -1, 0)
+	if len(args) > 0 {
+		if strings.EqualFold(args[0], "now") {
+			// Live report for the current month (not stored).
+			reports, err := a.topicAnalyzer.BuildReports(a.ctx, time.Now().UTC())
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			return FormatTopicReports(time.Now().UTC().Format("2006-01"), filterTopicReports(reports, wsID))
+		}
+		parsed, err := time.Parse("2006-01", args[0])
+		if err != nil {
+			return "Usage: /analytics [YYYY-MM|now]"
+		}
+		month = parsed
+	}
+
+	key := month.Format("2006-01")
+	reports, err := a.topicAnalyzer.LoadReports(key, wsID)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if len(reports) == 0 {
+		// Not generated yet (e.g. first run or a month before analytics was
+		// enabled): build on demand and store it.
+		reports, err = a.topicAnalyzer.BuildReports(a.ctx, month)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if key != time.Now().UTC().Format("2006-01") {
+			if err := a.topicAnalyzer.SaveReports(reports); err != nil {
+				a.logger.Warn("saving topic report failed", "month", key, "error", err)
+			}
+		}
+		reports = filterTopicReports(reports, wsID)
+	}
+	return FormatTopicReports(key, reports)
+}
+
+// alertsCommand shows the owner alert contact chain, or sends a test alert
+// down the chain to verify failover.
+// Usage: /alerts [test]
+func (a *Assistant) alertsCommand(args []string) string {
+	contacts := a.ownerAlerter.Contacts()
+	if len(contacts) == 0 {
+		return "No owner alert contacts configured. Add owner_alerts.contacts to config.yaml."
+	}
+	if !a.ownerAlerter.Enabled() {
+		return "Owner alerts are disabled (owner_alerts.enabled: false)."
+	}
+
+	if len(args) > 0 && strings.ToLower(args[0]) == "test" {
+		ctx, cancel := context.WithTimeout(a.ctx, 2*time.Minute)
+		defer cancel()
+		used, err := a.ownerAlerter.Send(ctx, OwnerAlert{
+			Kind:  OwnerAlertTest,
+			Title: "Test alert",
+			Body:  "If you can read this, owner alerts reach you.",
+		})
+		if err != nil {
+			return fmt.Sprintf("Test alert failed: %v", err)
+		}
+		return fmt.Sprintf("Test alert delivered via %s.", used.Label())
+	}
+
+	var b strings.Builder
+	b.WriteString("*Owner alert contacts (in failover order):*\n")
+	for i, c := range contacts {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, c.Label())
+	}
+	fmt.Fprintf(&b, "\nEvents: %s\nUse /alerts test to verify delivery.", strings.Join(a.ownerAlerter.cfg.Events, ", "))
+	return b.String()
+}
+
+const quotaUsage = "Usage: /quota [list | set <ws_id> <usd> | topup <ws_id> <usd> | reset <ws_id>]"
+
+// quotaCommand shows the caller's workspace quota; owners can also list,
+// set, top up and reset quotas of any workspace.
+func (a *Assistant) quotaCommand(args []string, msg *channels.IncomingMessage, isOwner bool) string {
+	if a.quotaMgr == nil {
+		return "Workspace quotas are not enabled (quotas.enabled: false)."
+	}
+
+	if len(args) == 0 {
+		wsID := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace.ID
+		st, err := a.quotaMgr.Status(wsID)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return st.Format()
+	}
+
+	if !isOwner {
+		return "Only owners can manage quotas."
+	}
+
+	sub := strings.ToLower(args[0])
+	switch sub {
+	case "list":
+		var ids []string
+		for _, ws := range a.workspaceMgr.List() {
+			ids = append(ids, ws.ID)
+		}
+		statuses, err := a.quotaMgr.List(ids...)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		var b strings.Builder
+		b.WriteString("*Workspace quotas:*\n")
+		for _, st := range statuses {
+			if st.Unlimited {
+				fmt.Fprintf(&b, "- %s: $%.2f spent, unlimited\n", st.WorkspaceID, st.SpentUSD)
+				continue
+			}
+			fmt.Fprintf(&b, "- %s: $%.2f / $%.2f ($%.2f left)\n",
+				st.WorkspaceID, st.SpentUSD, st.LimitUSD+st.TopUpUSD, st.RemainingUSD)
+		}
+		return strings.TrimRight(b.String(), "\n")
+
+	case "set", "topup":
+		if len(args) < 3 {
+			return quotaUsage
+		}
+		wsID := args[1]
+		amount, err := strconv.ParseFloat(strings.TrimPrefix(args[2], "$"), 64)
+		if err != nil {
+			return quotaUsage
+		}
+		if sub == "set" {
+			err = a.quotaMgr.SetLimit(wsID, amount)
+		} else {
+			err = a.quotaMgr.TopUp(wsID, amount)
+		}
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		st, err := a.quotaMgr.Status(wsID)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return st.Format()
+
+	case "reset":
+		if len(args) < 2 {
+			return quotaUsage
+		}
+		if err := a.quotaMgr.ResetLimit(args[1]); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return fmt.Sprintf("Quota for %s reverted to the configured limit.", args[1])
+
+	default:
+		return quotaUsage
+	}
+}
+
+const memoryUsage = "Usage: /memory [conflicts | resolve <id> new|old | history <subject/attribute>]"
+
+// memoryCommand lets the owner review facts that contradict memory and
+// browse the versions of a fact.
+func (a *Assistant) memoryCommand(args []string) string {
+	if a.memoryStore == nil {
+		return "Memory is not available."
+	}
+	sub := "conflicts"
+	if len(args) > 0 {
+		sub = strings.ToLower(args[0])
+	}
+
+	switch sub {
+	case "conflicts":
+		conflicts, err := a.memoryStore.Conflicts()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if len(conflicts) == 0 {
+			return "No memory conflicts to review."
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*Memory conflicts (%d):*\n", len(conflicts))
+		for _, c := range conflicts {
+			fmt.Fprintf(&b, "\n*%s* — %s\n", c.ID, c.Key)
+			fmt.Fprintf(&b, "  old (%s): %s\n", c.Current.Timestamp.Format("2006-01-02"), c.Current.Content)
+			fmt.Fprintf(&b, "  new (%s): %s\n", c.Proposed.Timestamp.Format("2006-01-02"), c.Proposed.Content)
+		}
+		b.WriteString("\nResolve with /memory resolve <id> new|old")
+		return b.String()
+
+	case "resolve":
+		if len(args) < 3 {
+			return memoryUsage
+		}
+		var keepNew bool
+		switch strings.ToLower(args[2]) {
+		case "new":
+			keepNew = true
+		case "old":
+		default:
+			return memoryUsage
+		}
+		c, err := a.memoryStore.ResolveConflict(args[1], keepNew)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if keepNew {
+			return fmt.Sprintf("Updated %s: %s", c.Key, c.Proposed.Content)
+		}
+		return fmt.Sprintf("Kept %s: %s", c.Key, c.Current.Content)
+
+	case "history":
+		if len(args) < 2 {
+			return memoryUsage
+		}
+		key, ok := memory.NormalizeFactKey(strings.Join(args[1:], " "))
+		if !ok {
+			return memoryUsage
+		}
+		current, err := a.memoryStore.Versions(key)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		past, err := a.memoryStore.History(key)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if len(current) == 0 && len(past) == 0 {
+			return fmt.Sprintf("No facts stored for %s.", key)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%s*\n", key)
+		for _, e := range current {
+			fmt.Fprintf(&b, "- current (%s): %s\n", e.Timestamp.Format("2006-01-02"), e.Content)
+		}
+		for i := len(past) - 1; i >= 0; i-- {
+			fmt.Fprintf(&b, "- %s: %s\n", past[i].Timestamp.Format("2006-01-02"), past[i].Content)
+		}
+		return strings.TrimRight(b.String(), "\n")
+
+	default:
+		return memoryUsage
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"strings"
 
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/discord"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels/email"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/slack"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/telegram"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels/webhook"
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels/whatsapp"
+	"github.com/jholhewres/devclaw/pkg/devclaw/coordination"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/security"
 	"github.com/jholhewres/devclaw/pkg/devclaw/plugins"
@@ -24,12 +27,21 @@ type Config struct {
 	// Trigger is the keyword that activates the bot (e.g. "@devclaw").
 	Trigger string `yaml:"trigger"`
 
+	// Triggers adds aliases, regex patterns and mention-or-reply activation
+	// on top of Trigger (see trigger.go).
+	Triggers TriggerConfig `yaml:"triggers"`
+
 	// Model is the LLM model to use (e.g. "glm-4.7-flash").
 	Model string `yaml:"model"`
 
 	// API configures the LLM provider endpoint.
 	API APIConfig `yaml:"api"`
 
+	// ModelsFile is a models.yaml registry overriding built-in context
+	// windows, prices and capabilities (default: ./models.yaml; a missing
+	// file means built-ins only).
+	ModelsFile string `yaml:"models_file"`
+
 	// Instructions are the base system prompt instructions.
 	Instructions string `yaml:"instructions"`
 
@@ -39,6 +51,10 @@ type Config struct {
 	// Language is the preferred response language (e.g. "pt-BR").
 	Language string `yaml:"language"`
 
+	// Locale decides how dates, times and numbers are read and written
+	// (default: the convention of Language), with per-user overrides.
+	Locale LocaleConfig `yaml:"locale"`
+
 	// Access configures who can use the bot (allowlist/blocklist).
 	Access AccessConfig `yaml:"access"`
 
@@ -84,12 +100,36 @@ type Config struct {
 	// Budget configures monthly cost tracking and limits.
 	Budget BudgetConfig `yaml:"budget"`
 
+	// ResponseCache caches responses to identical idempotent LLM requests.
+	ResponseCache ResponseCacheConfig `yaml:"response_cache"`
+
+	// Quotas configures per-workspace monthly spend quotas.
+	Quotas QuotaConfig `yaml:"quotas"`
+
+	// Billing configures the usage ledger, exports and monthly usage reports.
+	Billing BillingConfig `yaml:"billing"`
+
+	// UsageLog persists usage tracking to daily JSONL files.
+	UsageLog UsageLogConfig `yaml:"usage_log"`
+
+	// Tracing exports OpenTelemetry traces of the message pipeline.
+	Tracing TracingConfig `yaml:"tracing"`
+
+	// Idempotency configures deduplication of redelivered messages.
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+
+	// EventLog configures the append-only log of state changes.
+	EventLog EventLogConfig `yaml:"event_log"`
+
 	// Team configures multi-user mode.
 	Team TeamConfig `yaml:"team"`
 
 	// Media configures vision and audio transcription.
 	Media MediaConfig `yaml:"media"`
 
+	// ImageGen configures the generate_image tool.
+	ImageGen ImageGenConfig `yaml:"image_gen"`
+
 	// Logging configures log output.
 	Logging LoggingConfig `yaml:"logging"`
 
@@ -106,6 +146,12 @@ type Config struct {
 	// in chunks instead of waiting for the complete response).
 	BlockStream BlockStreamConfig `yaml:"block_stream"`
 
+	// DORA configures the CI/CD data behind the dora_metrics tool.
+	DORA DORAConfig `yaml:"dora"`
+
+	// Deploy configures the chat-ops deploy tools and change windows.
+	Deploy DeployConfig `yaml:"deploy"`
+
 	// WebSearch configures the web search tool provider.
 	WebSearch WebSearchConfig `yaml:"web_search"`
 
@@ -127,6 +173,25 @@ type Config struct {
 
 	// Browser configures the native browser automation tool.
 	Browser BrowserConfig `yaml:"browser"`
+
+	// Analytics configures monthly conversation topic reports.
+	Analytics AnalyticsConfig `yaml:"analytics"`
+
+	// OwnerAlerts configures critical owner notifications with failover.
+	OwnerAlerts OwnerAlertsConfig `yaml:"owner_alerts"`
+
+	// Warmup configures the cold-start warmup phase at startup.
+	Warmup WarmupConfig `yaml:"warmup"`
+
+	// Corpora configures user document corpora searched by corpus_search.
+	Corpora CorpusConfig `yaml:"corpora"`
+
+	// Share configures session pages published with /share.
+	Share ShareConfig `yaml:"share"`
+
+	// Coordination configures leader election between instances that
+	// share the same channel sessions.
+	Coordination coordination.Config `yaml:"coordination"`
 }
 
 // IntentRouterConfig configures the 3-layer intent routing system.
@@ -289,11 +354,29 @@ type MediaConfig struct {
 	// For Z.AI GLM-ASR: used as a prompt hint for auto-detection.
 	TranscriptionLanguage string `yaml:"transcription_language"`
 
+	// VoiceReplies answers voice notes with a voice message synthesized by
+	// the tts provider instead of text. Replies with code blocks, or too
+	// long to speak, are still sent as text (default: false).
+	VoiceReplies bool `yaml:"voice_replies"`
+
 	// MaxImageSize is the max image size in bytes to process (default: 20MB).
 	MaxImageSize int64 `yaml:"max_image_size"`
 
 	// MaxAudioSize is the max audio size in bytes (default: 25MB).
 	MaxAudioSize int64 `yaml:"max_audio_size"`
+
+	// TranscriptionChunkSeconds is the segment length for long audio: audio
+	// longer than this is split with ffmpeg and the segments are transcribed
+	// in parallel (default: 120).
+	TranscriptionChunkSeconds int `yaml:"transcription_chunk_seconds"`
+
+	// TranscriptionParallel caps the segments transcribed at once (default: 4).
+	TranscriptionParallel int `yaml:"transcription_parallel"`
+
+	// MaxAudioDuration is the max audio length in seconds that is
+	// transcribed; the rest is dropped with a note (default: 1800, -1 for
+	// no limit).
+	MaxAudioDuration int `yaml:"max_audio_duration"`
 }
 
 // DefaultMediaConfig returns sensible defaults for media processing.
@@ -305,6 +388,10 @@ func DefaultMediaConfig() MediaConfig {
 		TranscriptionModel:   "whisper-1",
 		MaxImageSize:         20 * 1024 * 1024, // 20MB
 		MaxAudioSize:         25 * 1024 * 1024, // 25MB (Whisper limit)
+
+		TranscriptionChunkSeconds: 120,
+		TranscriptionParallel:     4,
+		MaxAudioDuration:          1800, // 30 min
 	}
 }
 
@@ -323,6 +410,15 @@ func (m MediaConfig) Effective() MediaConfig {
 	if out.TranscriptionModel == "" {
 		out.TranscriptionModel = "whisper-1"
 	}
+	if out.TranscriptionChunkSeconds <= 0 {
+		out.TranscriptionChunkSeconds = 120
+	}
+	if out.TranscriptionParallel <= 0 {
+		out.TranscriptionParallel = 4
+	}
+	if out.MaxAudioDuration == 0 {
+		out.MaxAudioDuration = 1800
+	}
 	return out
 }
 
@@ -395,6 +491,17 @@ type BudgetConfig struct {
 
 	// ActionAtLimit defines behavior when limit is reached: "warn", "block", "fallback_local".
 	ActionAtLimit string `yaml:"action_at_limit"`
+
+	// Users caps each user's daily and monthly tokens and cost; PerUser
+	// replaces it for specific users (by JID). Owners are never cut off.
+	// WarnAtPercent applies to these budgets too (usage_budgets.go).
+	Users   UsageLimits            `yaml:"users"`
+	PerUser map[string]UsageLimits `yaml:"per_user"`
+
+	// Workspaces caps each workspace the same way; PerWorkspace replaces it
+	// for specific workspaces (by ID).
+	Workspaces   UsageLimits            `yaml:"workspaces"`
+	PerWorkspace map[string]UsageLimits `yaml:"per_workspace"`
 }
 
 // DefaultBudgetConfig returns sensible defaults for budget tracking.
@@ -448,7 +555,7 @@ type APIConfig struct {
 	// Can also be set via the DEVCLAW_API_KEY environment variable.
 	APIKey string `yaml:"api_key"`
 
-	// Provider hints which SDK to use ("openai", "anthropic", "glm").
+	// Provider hints which SDK to use ("openai", "anthropic", "glm", "gemini").
 	// Auto-detected from base_url if omitted.
 	Provider string `yaml:"provider"`
 
@@ -456,6 +563,28 @@ type APIConfig struct {
 	//   context1m: true   — enable Anthropic 1M context beta for Opus/Sonnet
 	//   tool_stream: true — enable real-time tool call streaming (Z.AI)
 	Params map[string]any `yaml:"params"`
+
+	// Fallbacks is an ordered chain of providers tried when the primary
+	// model keeps failing with a retryable error (429, 5xx, overload), after
+	// fallback.models. Empty base_url/api_key reuse the primary endpoint.
+	Fallbacks []ProviderChainEntry `yaml:"fallbacks"`
+
+	// Subscription authenticates with a ChatGPT or Claude subscription
+	// ("chatgpt", "claude") instead of api_key, using the OAuth tokens
+	// stored by 'devclaw auth login'. base_url defaults to the
+	// subscription's API endpoint.
+	Subscription string `yaml:"subscription"`
+
+	// OAuth overrides the subscription's OAuth endpoints and client ID.
+	OAuth SubscriptionProvider `yaml:"oauth"`
+
+	// Providers configures additional providers, such as Gemini for
+	// large-context single-shot requests (see gemini.go).
+	Providers ProvidersConfig `yaml:"providers"`
+
+	// Debug writes raw provider requests and responses, with secrets
+	// redacted, to rotating files (see llm_debug.go).
+	Debug LLMDebugConfig `yaml:"debug"`
 }
 
 // ChannelsConfig holds configuration for all channels.
@@ -471,6 +600,12 @@ type ChannelsConfig struct {
 
 	// Slack is the Slack channel config (core).
 	Slack slack.Config `yaml:"slack"`
+
+	// Email is the IMAP/SMTP email channel config.
+	Email email.Config `yaml:"email"`
+
+	// Webhook is the inbound webhook channel config (POST /hooks/{name}).
+	Webhook webhook.Config `yaml:"webhook"`
 }
 
 // MemoryConfig configures the memory and persistence system.
@@ -482,6 +617,15 @@ type MemoryConfig struct {
 	// Path is the database file path (for sqlite).
 	Path string `yaml:"path"`
 
+	// Backend selects where conversation sessions (history, facts, token
+	// usage, config) are persisted: "sqlite" (devclaw.db, default),
+	// "jsonl" (one file per session) or "memory" (lost on restart).
+	Backend string `yaml:"backend"`
+
+	// SessionRetentionDays deletes persisted sessions inactive for longer
+	// than this, per workspace (0 = keep forever).
+	SessionRetentionDays int `yaml:"session_retention_days"`
+
 	// MaxMessages is the max messages kept per session.
 	MaxMessages int `yaml:"max_messages"`
 
@@ -500,6 +644,14 @@ type MemoryConfig struct {
 
 	// SessionMemory configures automatic session summarization.
 	SessionMemory SessionMemoryConfig `yaml:"session_memory"`
+
+	// KnowledgeBase configures idle-session summaries into the per-workspace
+	// knowledge base (see knowledge_base.go).
+	KnowledgeBase KnowledgeBaseConfig `yaml:"knowledge_base"`
+
+	// Glossary configures the per-workspace glossary of domain terms (see
+	// glossary.go).
+	Glossary GlossaryConfig `yaml:"glossary"`
 }
 
 // SearchConfig configures hybrid search behavior.
@@ -556,8 +708,20 @@ type SecurityConfig struct {
 	// ToolExecutor configures parallel tool execution.
 	ToolExecutor ToolExecutorConfig `yaml:"tool_executor"`
 
+	// Approvals configures confirmation prompts for tools that require
+	// them: timeout, the answer assumed on timeout, and buttons.
+	Approvals ApprovalConfig `yaml:"approvals"`
+
 	// SSRF configures URL validation for web_fetch (private IPs, metadata, etc.).
 	SSRF security.SSRFConfig `yaml:"ssrf"`
+
+	// SecretRedaction replaces API keys, tokens, private keys and passwords
+	// in tool results and replies with placeholders (default: enabled).
+	SecretRedaction security.SecretRedactionConfig `yaml:"secret_redaction"`
+
+	// InjectionScan flags, strips or blocks prompt injection in the results
+	// of web, browser and MCP tools (see injection_scan.go).
+	InjectionScan InjectionScanConfig `yaml:"injection_scan"`
 }
 
 // ToolExecutorConfig configures tool execution behavior.
@@ -573,6 +737,16 @@ type ToolExecutorConfig struct {
 
 	// DefaultTimeoutSeconds is the executor-level timeout for all other tools (default: 30).
 	DefaultTimeoutSeconds int `yaml:"default_timeout_seconds"`
+
+	// Exclusive lists extra tools (or groups) that never run concurrently
+	// with other tools. bash, exec, ssh, scp, write_file, edit_file,
+	// apply_changes and set_env are always exclusive.
+	Exclusive []string `yaml:"exclusive"`
+
+	// Timeouts overrides the timeout per tool or tool group, in seconds
+	// (e.g. web_fetch: 60, "group:web": 45). Timed-out tools are cancelled
+	// and return a structured timeout result.
+	Timeouts map[string]int `yaml:"timeouts"`
 }
 
 // TokenBudgetConfig configures per-layer token allocation.
@@ -609,6 +783,10 @@ type SchedulerConfig struct {
 
 	// Storage is the path to the scheduler database.
 	Storage string `yaml:"storage"`
+
+	// Timezone is the default zone for jobs that don't set their own
+	// (empty = the top-level timezone, then the host's local time).
+	Timezone string `yaml:"timezone"`
 }
 
 // LoggingConfig configures logging.
@@ -630,8 +808,10 @@ func DefaultConfig() *Config {
 		Trigger: "@devclaw",
 		Model:   "gpt-5-mini",
 		API: APIConfig{
-			BaseURL: "https://api.openai.com/v1",
+			BaseURL:   "https://api.openai.com/v1",
+			Providers: ProvidersConfig{Gemini: DefaultGeminiConfig()},
 		},
+		ModelsFile:   "./models.yaml",
 		Instructions: "You are a helpful personal assistant. Be concise and practical.",
 		Timezone:     "America/Sao_Paulo",
 		Language:     "pt-BR",
@@ -639,10 +819,15 @@ func DefaultConfig() *Config {
 		Workspaces:   DefaultWorkspaceConfig(),
 		Channels: ChannelsConfig{
 			WhatsApp: whatsapp.DefaultConfig(),
+			Telegram: telegram.DefaultConfig(),
+			Discord:  discord.DefaultConfig(),
+			Email:    email.DefaultConfig(),
+			Webhook:  webhook.DefaultConfig(),
 		},
 		Memory: MemoryConfig{
 			Type:                "sqlite",
 			Path:                "./data/memory.db",
+			Backend:             "sqlite",
 			MaxMessages:         100,
 			CompressionStrategy: "summarize",
 			Embedding:           memory.DefaultEmbeddingConfig(),
@@ -660,6 +845,8 @@ func DefaultConfig() *Config {
 				Enabled:  false,
 				Messages: 15,
 			},
+			KnowledgeBase: DefaultKnowledgeBaseConfig(),
+			Glossary:      DefaultGlossaryConfig(),
 		},
 		Security: SecurityConfig{
 			MaxInputLength:      4096,
@@ -673,6 +860,9 @@ func DefaultConfig() *Config {
 				BashTimeoutSeconds:    300,
 				DefaultTimeoutSeconds: 30,
 			},
+			Approvals:       DefaultApprovalConfig(),
+			SecretRedaction: security.SecretRedactionConfig{Enabled: true},
+			InjectionScan:   DefaultInjectionScanConfig(),
 		},
 		TokenBudget: TokenBudgetConfig{
 			Total:    128000,
@@ -694,13 +884,15 @@ func DefaultConfig() *Config {
 			Enabled: true,
 			Storage: "./data/scheduler.db",
 		},
-		Heartbeat: DefaultHeartbeatConfig(),
-		Subagents: DefaultSubagentConfig(),
-		Agent:     DefaultAgentConfig(),
-		Fallback:  DefaultFallbackConfig(),
-		Budget:    DefaultBudgetConfig(),
-		Team:      DefaultTeamConfig(),
-		Media:     DefaultMediaConfig(),
+		Heartbeat:     DefaultHeartbeatConfig(),
+		Subagents:     DefaultSubagentConfig(),
+		Agent:         DefaultAgentConfig(),
+		Fallback:      DefaultFallbackConfig(),
+		Budget:        DefaultBudgetConfig(),
+		ResponseCache: DefaultResponseCacheConfig(),
+		Team:          DefaultTeamConfig(),
+		Media:         DefaultMediaConfig(),
+		ImageGen:      DefaultImageGenConfig(),
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
@@ -713,6 +905,8 @@ func DefaultConfig() *Config {
 			Address: ":8085",
 		},
 		BlockStream: DefaultBlockStreamConfig(),
+		DORA:        DefaultDORAConfig(),
+		Deploy:      DefaultDeployConfig(),
 		WebSearch: WebSearchConfig{
 			Provider:   "duckduckgo",
 			MaxResults: 8,
@@ -727,7 +921,18 @@ func DefaultConfig() *Config {
 			Enabled: false,
 			Address: ":8090",
 		},
-		Browser: DefaultBrowserConfig(),
+		Browser:      DefaultBrowserConfig(),
+		Analytics:    DefaultAnalyticsConfig(),
+		Quotas:       DefaultQuotaConfig(),
+		Billing:      DefaultBillingConfig(),
+		UsageLog:     DefaultUsageLogConfig(),
+		Idempotency:  DefaultIdempotencyConfig(),
+		EventLog:     DefaultEventLogConfig(),
+		OwnerAlerts:  DefaultOwnerAlertsConfig(),
+		Warmup:       DefaultWarmupConfig(),
+		Corpora:      DefaultCorpusConfig(),
+		Share:        DefaultShareConfig(),
+		Coordination: coordination.DefaultConfig(),
 	}
 }
 
@@ -749,8 +954,9 @@ type TTSConfig struct {
 	// Enabled activates TTS for assistant responses.
 	Enabled bool `yaml:"enabled"`
 
-	// Provider is the TTS provider to use: "openai" (default), "edge", "auto".
-	// "auto" tries OpenAI first, falls back to Edge TTS if OpenAI is unavailable.
+	// Provider is the TTS provider to use: "openai" (default), "edge",
+	// "piper" (local), "auto". "auto" tries OpenAI first, falls back to
+	// Edge TTS if OpenAI is unavailable.
 	Provider string `yaml:"provider"`
 
 	// Voice is the voice to use.
@@ -766,6 +972,12 @@ type TTSConfig struct {
 	// Only used for OpenAI provider.
 	Model string `yaml:"model"`
 
+	// PiperBinary is the piper executable (default: "piper" from PATH).
+	PiperBinary string `yaml:"piper_binary"`
+
+	// PiperModel is the .onnx voice model used by the piper provider.
+	PiperModel string `yaml:"piper_model"`
+
 	// AutoMode controls when TTS is used:
 	//   "off"     - disabled (default)
 	//   "always"  - always generate audio alongside text
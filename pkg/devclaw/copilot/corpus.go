@@ -0,0 +1,560 @@
+// Package copilot – corpus.go implements user-registered document corpora.
+// A folder of PDFs, a mail export or a Confluence dump is registered with
+// `devclaw corpus add <name> <path>`, split into chunks and indexed under
+// corpora.dir/<name>/, and searched by the agent with corpus_search. A
+// corpus can be limited to some workspaces; reindexing only re-reads files
+// whose size or modification time changed.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
+)
+
+// CorpusConfig configures user document corpora.
+type CorpusConfig struct {
+	// Enabled registers the corpus_search tool (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Dir holds the corpus registry and indexes (default: ./data/corpora).
+	Dir string `yaml:"dir"`
+
+	// MaxFileMB skips larger files when indexing (default: 25).
+	MaxFileMB int `yaml:"max_file_mb"`
+}
+
+// DefaultCorpusConfig returns the default corpus config.
+func DefaultCorpusConfig() CorpusConfig {
+	return CorpusConfig{
+		Enabled:   true,
+		Dir:       "./data/corpora",
+		MaxFileMB: 25,
+	}
+}
+
+// corpusRegistryFile lists the registered corpora inside CorpusConfig.Dir.
+const corpusRegistryFile = "corpora.json"
+
+// corpusIndexFile is the chunk index inside a corpus directory.
+const corpusIndexFile = "index.json"
+
+// corpusName is the accepted form of corpus names.
+var corpusName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Corpus is a registered document collection.
+type Corpus struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+
+	// Workspaces limits the corpus to these workspace IDs (empty = all).
+	Workspaces []string `json:"workspaces,omitempty"`
+
+	AddedAt   time.Time `json:"added_at"`
+	IndexedAt time.Time `json:"indexed_at"`
+	Files     int       `json:"files"`
+	Chunks    int       `json:"chunks"`
+}
+
+// AvailableIn reports whether the corpus may be searched from workspace wsID.
+func (c Corpus) AvailableIn(wsID string) bool {
+	return len(c.Workspaces) == 0 || slices.Contains(c.Workspaces, wsID)
+}
+
+// CorpusIndexStats summarizes one indexing pass.
+type CorpusIndexStats struct {
+	Indexed   int // new or changed files read
+	Unchanged int // files kept from the previous index
+	Skipped   int // unsupported, unreadable, empty or too large
+	Removed   int // files gone since the previous index
+}
+
+// CorpusHit is a corpus_search result.
+type CorpusHit struct {
+	File    string
+	Chunk   int
+	Snippet string
+	Score   float64
+}
+
+// corpusIndex is the on-disk chunk index of a corpus.
+type corpusIndex struct {
+	Files map[string]*corpusFile `json:"files"`
+}
+
+// corpusFile is an indexed file, keyed by its path relative to the corpus.
+type corpusFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Chunks  []string  `json:"chunks"`
+}
+
+// loadedCorpus is a parsed index with the term statistics BM25 needs.
+type loadedCorpus struct {
+	modTime time.Time
+	docs    []corpusDoc
+	df      map[string]int
+	avgLen  float64
+}
+
+// corpusDoc is one searchable chunk.
+type corpusDoc struct {
+	file   string
+	chunk  int
+	text   string
+	tf     map[string]int
+	length int
+}
+
+// CorpusStore manages the corpus registry and indexes. The CLI writes them
+// and the running assistant reads them, so the registry is re-read on every
+// call and indexes are cached by modification time.
+type CorpusStore struct {
+	cfg    CorpusConfig
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]*loadedCorpus
+}
+
+// NewCorpusStore creates a corpus store.
+func NewCorpusStore(cfg CorpusConfig, logger *slog.Logger) *CorpusStore {
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultCorpusConfig().Dir
+	}
+	if cfg.MaxFileMB <= 0 {
+		cfg.MaxFileMB = DefaultCorpusConfig().MaxFileMB
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CorpusStore{cfg: cfg, logger: logger, cache: make(map[string]*loadedCorpus)}
+}
+
+// List returns the registered corpora sorted by name.
+func (s *CorpusStore) List() ([]Corpus, error) {
+	data, err := os.ReadFile(filepath.Join(s.cfg.Dir, corpusRegistryFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Corpus
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parsing corpus registry: %w", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns the named corpus.
+func (s *CorpusStore) Get(name string) (Corpus, bool, error) {
+	list, err := s.List()
+	if err != nil {
+		return Corpus{}, false, err
+	}
+	for _, c := range list {
+		if c.Name == name {
+			return c, true, nil
+		}
+	}
+	return Corpus{}, false, nil
+}
+
+// Add registers path as a corpus and builds its index.
+func (s *CorpusStore) Add(name, path string, workspaces []string) (Corpus, CorpusIndexStats, error) {
+	if !corpusName.MatchString(name) {
+		return Corpus{}, CorpusIndexStats{}, fmt.Errorf("invalid corpus name %q (lowercase letters, digits, - and _)", name)
+	}
+	if _, exists, err := s.Get(name); err != nil {
+		return Corpus{}, CorpusIndexStats{}, err
+	} else if exists {
+		return Corpus{}, CorpusIndexStats{}, fmt.Errorf("corpus %q already exists (use reindex or remove it first)", name)
+	}
+	abs, err := filepath.Abs(expandPath(path))
+	if err != nil {
+		return Corpus{}, CorpusIndexStats{}, err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return Corpus{}, CorpusIndexStats{}, fmt.Errorf("corpus path: %w", err)
+	}
+
+	c := Corpus{Name: name, Path: abs, Workspaces: workspaces, AddedAt: time.Now()}
+	return s.index(c)
+}
+
+// Reindex refreshes the index of the named corpus.
+func (s *CorpusStore) Reindex(name string) (Corpus, CorpusIndexStats, error) {
+	c, ok, err := s.Get(name)
+	if err != nil {
+		return Corpus{}, CorpusIndexStats{}, err
+	}
+	if !ok {
+		return Corpus{}, CorpusIndexStats{}, fmt.Errorf("unknown corpus %q", name)
+	}
+	return s.index(c)
+}
+
+// Remove unregisters the named corpus and deletes its index. The source
+// documents are left alone.
+func (s *CorpusStore) Remove(name string) error {
+	list, err := s.List()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(list, func(c Corpus) bool { return c.Name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown corpus %q", name)
+	}
+	if err := s.saveRegistry(slices.Delete(list, i, i+1)); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.cache, name)
+	s.mu.Unlock()
+	return os.RemoveAll(filepath.Join(s.cfg.Dir, name))
+}
+
+// index walks the corpus path, re-reads new and changed files, writes the
+// index and updates the registry.
+func (s *CorpusStore) index(c Corpus) (Corpus, CorpusIndexStats, error) {
+	var stats CorpusIndexStats
+	indexPath := filepath.Join(s.cfg.Dir, c.Name, corpusIndexFile)
+	prev := &corpusIndex{}
+	if data, err := os.ReadFile(indexPath); err == nil {
+		_ = json.Unmarshal(data, prev)
+	}
+	next := &corpusIndex{Files: make(map[string]*corpusFile)}
+	maxBytes := int64(s.cfg.MaxFileMB) << 20
+
+	root := c.Path
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			stats.Skipped++
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !corpusSupported(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > maxBytes {
+			stats.Skipped++
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		if rel == "." {
+			rel = filepath.Base(path) // a single-file corpus
+		}
+		rel = filepath.ToSlash(rel)
+
+		if old, ok := prev.Files[rel]; ok && old.Size == info.Size() && old.ModTime.Equal(info.ModTime()) {
+			next.Files[rel] = old
+			stats.Unchanged++
+			return nil
+		}
+		text := s.extractText(path)
+		if strings.TrimSpace(text) == "" {
+			stats.Skipped++
+			return nil
+		}
+		f := &corpusFile{Size: info.Size(), ModTime: info.ModTime()}
+		for _, ch := range memory.ChunkMarkdown(text, memory.DefaultChunkConfig()) {
+			f.Chunks = append(f.Chunks, ch.Text)
+		}
+		next.Files[rel] = f
+		stats.Indexed++
+		return nil
+	})
+	if err != nil {
+		return c, stats, fmt.Errorf("walking %s: %w", root, err)
+	}
+	for rel := range prev.Files {
+		if _, ok := next.Files[rel]; !ok {
+			stats.Removed++
+		}
+	}
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return c, stats, err
+	}
+	if err := writeCorpusFile(indexPath, data); err != nil {
+		return c, stats, fmt.Errorf("writing corpus index: %w", err)
+	}
+
+	c.IndexedAt = time.Now()
+	c.Files, c.Chunks = len(next.Files), 0
+	for _, f := range next.Files {
+		c.Chunks += len(f.Chunks)
+	}
+	list, err := s.List()
+	if err != nil {
+		return c, stats, err
+	}
+	if i := slices.IndexFunc(list, func(o Corpus) bool { return o.Name == c.Name }); i >= 0 {
+		list[i] = c
+	} else {
+		list = append(list, c)
+	}
+	return c, stats, s.saveRegistry(list)
+}
+
+// saveRegistry writes the corpus registry.
+func (s *CorpusStore) saveRegistry(list []Corpus) error {
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeCorpusFile(filepath.Join(s.cfg.Dir, corpusRegistryFile), data)
+}
+
+// Search ranks the chunks of the named corpus against query with BM25.
+func (s *CorpusStore) Search(name, query string, maxResults int) ([]CorpusHit, error) {
+	terms := kbTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+	lc, err := s.load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	const k1, b = 1.2, 0.75
+	n := float64(len(lc.docs))
+	var hits []CorpusHit
+	for _, d := range lc.docs {
+		score := 0.0
+		for _, t := range terms {
+			tf := float64(d.tf[t])
+			if tf == 0 {
+				continue
+			}
+			df := float64(lc.df[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(d.length)/lc.avgLen))
+		}
+		if score > 0 {
+			hits = append(hits, CorpusHit{File: d.file, Chunk: d.chunk, Snippet: truncate(strings.TrimSpace(d.text), 700), Score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > maxResults {
+		hits = hits[:maxResults]
+	}
+	return hits, nil
+}
+
+// Preload parses the index of every registered corpus into the cache and
+// returns how many corpora and chunks were loaded. Corpora without an index
+// yet are skipped.
+func (s *CorpusStore) Preload() (int, int, error) {
+	list, err := s.List()
+	if err != nil {
+		return 0, 0, err
+	}
+	corpora, chunks := 0, 0
+	for _, c := range list {
+		lc, err := s.load(c.Name)
+		if err != nil {
+			s.logger.Debug("corpus not preloaded", "corpus", c.Name, "error", err)
+			continue
+		}
+		corpora++
+		chunks += len(lc.docs)
+	}
+	return corpora, chunks, nil
+}
+
+// load returns the parsed index of a corpus, re-reading it when the file
+// changed since the cached copy.
+func (s *CorpusStore) load(name string) (*loadedCorpus, error) {
+	path := filepath.Join(s.cfg.Dir, name, corpusIndexFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("corpus %q has no index (run devclaw corpus reindex %s)", name, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lc, ok := s.cache[name]; ok && lc.modTime.Equal(info.ModTime()) {
+		return lc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idx corpusIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parsing index of corpus %q: %w", name, err)
+	}
+
+	lc := &loadedCorpus{modTime: info.ModTime(), df: make(map[string]int)}
+	total := 0
+	for file, f := range idx.Files {
+		for i, text := range f.Chunks {
+			d := corpusDoc{file: file, chunk: i, text: text, tf: make(map[string]int)}
+			for _, t := range kbTerms(text) {
+				d.tf[t]++
+				d.length++
+			}
+			for t := range d.tf {
+				lc.df[t]++
+			}
+			total += d.length
+			lc.docs = append(lc.docs, d)
+		}
+	}
+	if len(lc.docs) > 0 {
+		lc.avgLen = float64(total) / float64(len(lc.docs))
+	}
+	if lc.avgLen == 0 {
+		lc.avgLen = 1
+	}
+	s.cache[name] = lc
+	return lc, nil
+}
+
+// corpusExtensions are the file types a corpus indexes, besides the plain
+// text formats isPlainText accepts.
+var corpusExtensions = []string{".pdf", ".docx", ".eml", ".mbox", ".rst", ".org", ".tex"}
+
+// corpusSupported reports whether a file is indexed.
+func corpusSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return isPlainText("", ext) || slices.Contains(corpusExtensions, ext)
+}
+
+// extractText returns the searchable text of a corpus file: PDFs through
+// pdftotext, DOCX through its document XML, HTML (Confluence exports)
+// without markup, and everything else as-is (mail exports are plain text).
+func (s *CorpusStore) extractText(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".pdf":
+		if _, err := exec.LookPath("pdftotext"); err != nil {
+			s.logger.Warn("skipping PDF: pdftotext not installed", "file", path)
+			return ""
+		}
+		text := extractPDFText(data, s.logger)
+		if strings.HasPrefix(text, "[PDF") { // scanned PDF placeholder
+			return ""
+		}
+		return text
+	case ".docx":
+		return extractDOCXText(data, s.logger)
+	case ".html", ".htm":
+		return html.UnescapeString(stripXMLTags(string(data)))
+	default:
+		return string(data)
+	}
+}
+
+// writeCorpusFile creates the parent directory and writes path atomically.
+func writeCorpusFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
+
+// RegisterCorpusTools registers corpus_search. workspaceFor maps the
+// caller's context to a workspace ID; corpora limited to other workspaces
+// are not visible.
+func RegisterCorpusTools(executor *ToolExecutor, store *CorpusStore, workspaceFor func(ctx context.Context) string) {
+	executor.Register(
+		MakeToolDefinition("corpus_search",
+			"Search a document corpus the user registered (a folder of PDFs, a mail export, a wiki dump, ...) "+
+				"and return the best matching passages with their file names. Use it to answer questions "+
+				"about the user's private documents; cite the files. Call without a corpus to list the available ones.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"corpus": map[string]any{
+						"type":        "string",
+						"description": "Corpus name. May be omitted when only one corpus is available.",
+					},
+					"query": map[string]any{
+						"type":        "string",
+						"description": "Keywords to search for",
+					},
+					"max_results": map[string]any{
+						"type":        "integer",
+						"description": "Maximum passages (default: 5)",
+					},
+				},
+				"required": []string{"query"},
+			},
+		),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			name, _ := args["corpus"].(string)
+			query, _ := args["query"].(string)
+			maxResults := 5
+			if v, ok := args["max_results"].(float64); ok && v > 0 {
+				maxResults = int(v)
+			}
+
+			list, err := store.List()
+			if err != nil {
+				return nil, err
+			}
+			wsID := workspaceFor(ctx)
+			var names []string
+			for _, c := range list {
+				if c.AvailableIn(wsID) {
+					names = append(names, c.Name)
+				}
+			}
+			if len(names) == 0 {
+				return "No document corpora are available. The user can add one with: devclaw corpus add <name> <path>", nil
+			}
+			if name == "" && len(names) == 1 {
+				name = names[0]
+			}
+			if !slices.Contains(names, name) {
+				return fmt.Sprintf("Available corpora: %s. Pass one as corpus.", strings.Join(names, ", ")), nil
+			}
+
+			hits, err := store.Search(name, query, maxResults)
+			if err != nil {
+				return nil, err
+			}
+			if len(hits) == 0 {
+				return fmt.Sprintf("No passages in corpus %q match %q.", name, query), nil
+			}
+			var b strings.Builder
+			for _, h := range hits {
+				fmt.Fprintf(&b, "### %s (part %d, score %.1f)\n%s\n\n", h.File, h.Chunk+1, h.Score, h.Snippet)
+			}
+			return strings.TrimSpace(b.String()), nil
+		},
+	)
+}
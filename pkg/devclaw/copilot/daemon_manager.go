@@ -67,7 +67,7 @@ func (dm *DaemonManager) StartDaemon(label, command string, port int, readyPatte
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd := shellCommand(ctx, command)
 
 	rb := newRingBuffer(defaultRingSize)
 	cmd.Stdout = rb
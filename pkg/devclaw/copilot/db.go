@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite driver.
 )
@@ -20,6 +21,8 @@ CREATE TABLE IF NOT EXISTS jobs (
     id          TEXT PRIMARY KEY,
     schedule    TEXT NOT NULL,
     type        TEXT NOT NULL DEFAULT 'cron',
+    timezone    TEXT DEFAULT '',
+    catch_up    TEXT DEFAULT '',
     command     TEXT NOT NULL,
     channel     TEXT DEFAULT '',
     chat_id     TEXT DEFAULT '',
@@ -61,6 +64,28 @@ CREATE TABLE IF NOT EXISTS session_facts (
 );
 CREATE INDEX IF NOT EXISTS idx_session_facts_sid ON session_facts(session_id);
 
+-- Session state: token usage, owning workspace and last activity
+-- (one row per session). Used for per-workspace retention pruning.
+CREATE TABLE IF NOT EXISTS session_state (
+    session_id        TEXT PRIMARY KEY,
+    workspace_id      TEXT DEFAULT '',
+    prompt_tokens     INTEGER DEFAULT 0,
+    completion_tokens INTEGER DEFAULT 0,
+    requests          INTEGER DEFAULT 0,
+    last_active_at    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_session_state_ws ON session_state(workspace_id, last_active_at);
+
+-- Named session checkpoints for /checkpoint and /rewind. data holds the
+-- JSON-encoded history and facts at the time of the snapshot.
+CREATE TABLE IF NOT EXISTS session_checkpoints (
+    session_id TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    data       TEXT NOT NULL,
+    PRIMARY KEY (session_id, name)
+);
+
 -- Active agent runs (for restart recovery).
 -- When a run starts, a row is inserted; when it completes, the row is deleted.
 -- On startup, any remaining rows indicate runs that were interrupted by a restart.
@@ -133,6 +158,28 @@ func OpenDatabase(path string) (*sql.DB, error) {
 		db.Close()
 		return nil, fmt.Errorf("create schema: %w", err)
 	}
+	if err := migrateDatabase(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return db, nil
 }
+
+// columnMigrations add columns to tables created by older versions.
+// CREATE TABLE IF NOT EXISTS leaves existing tables alone, so new columns
+// are listed both in the schema and here.
+var columnMigrations = []string{
+	`ALTER TABLE jobs ADD COLUMN timezone TEXT DEFAULT ''`,
+	`ALTER TABLE jobs ADD COLUMN catch_up TEXT DEFAULT ''`,
+}
+
+// migrateDatabase applies columnMigrations, skipping columns that exist.
+func migrateDatabase(db *sql.DB) error {
+	for _, stmt := range columnMigrations {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("migrate database (%s): %w", stmt, err)
+		}
+	}
+	return nil
+}
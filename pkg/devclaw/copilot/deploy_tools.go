@@ -0,0 +1,825 @@
+// Package copilot – deploy_tools.go implements chat-ops deployments:
+// deploy_trigger starts the deploy pipeline (a GitHub Actions workflow
+// dispatch or a GitLab pipeline), deploy_status reports on it and
+// deploy_rollback re-runs the previous successful deployment.
+//
+// Safety rails:
+//   - Change windows: trigger and rollback are refused during a freeze
+//     (default Friday 17:00 – Monday 08:00). Only the owner can override,
+//     with override=true and a reason.
+//   - Approvals: trigger and rollback require admin access, and calls by
+//     anyone but the owner wait for approval in the chat (approval manager).
+//   - Every action is written to the audit log, and started runs are
+//     watched until they finish, announcing the outcome to deploy.notify.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DeployConfig configures the deploy tools.
+type DeployConfig struct {
+	// Enabled registers deploy_trigger, deploy_status and deploy_rollback
+	// (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// Provider is "github" or "gitlab" (default: derived from the origin remote).
+	Provider string `yaml:"provider"`
+
+	// Repo is "owner/name" (GitHub) or the project path (GitLab).
+	// Default: derived from the origin remote of the working directory.
+	Repo string `yaml:"repo"`
+
+	// Token authenticates API calls. Default: $GITHUB_TOKEN (or $GH_TOKEN)
+	// for GitHub, $GITLAB_TOKEN for GitLab.
+	Token string `yaml:"token"`
+
+	// BaseURL points at GitHub Enterprise or a self-hosted GitLab.
+	BaseURL string `yaml:"base_url"`
+
+	// Workflow is the GitHub Actions workflow that deploys, as a file name
+	// (deploy.yml) or ID. It must have a workflow_dispatch trigger.
+	Workflow string `yaml:"workflow"`
+
+	// RollbackWorkflow, when set, is dispatched with a "sha" input for
+	// rollbacks instead of re-running the previous successful deploy run.
+	RollbackWorkflow string `yaml:"rollback_workflow"`
+
+	// Ref is the branch or tag that is deployed (default: "main").
+	Ref string `yaml:"ref"`
+
+	// Environment is the deployment environment GitLab rollbacks look up
+	// (default: "production").
+	Environment string `yaml:"environment"`
+
+	// Freeze lists the change windows during which deploys are refused.
+	// Default: Friday 17:00 to Monday 08:00. An empty list disables it.
+	Freeze []ChangeWindow `yaml:"freeze"`
+
+	// Timezone the freeze windows are read in (default: the top-level
+	// timezone).
+	Timezone string `yaml:"timezone"`
+
+	// Notify is where deploy events are announced (optional).
+	Notify DeployNotifyConfig `yaml:"notify"`
+}
+
+// ChangeWindow is a weekly freeze from From to To, each written as
+// "<weekday> HH:MM" (e.g. "fri 17:00"). A window may wrap around the week.
+type ChangeWindow struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// DeployNotifyConfig addresses the chat that receives deploy events.
+type DeployNotifyConfig struct {
+	// Workspace whose session records the notifications (default workspace
+	// when empty).
+	Workspace string `yaml:"workspace"`
+	Channel   string `yaml:"channel"`
+	To        string `yaml:"to"`
+}
+
+// DefaultDeployConfig returns the default deploy configuration.
+func DefaultDeployConfig() DeployConfig {
+	return DeployConfig{
+		Ref:         "main",
+		Environment: "production",
+		Freeze:      []ChangeWindow{{From: "fri 17:00", To: "mon 08:00"}},
+	}
+}
+
+// deployToolNames are the tools that change what is deployed.
+var deployToolNames = []string{"deploy_trigger", "deploy_rollback"}
+
+const (
+	// deployWatchInterval is how often a started run is polled.
+	deployWatchInterval = 30 * time.Second
+
+	// deployWatchMax bounds how long a run is watched.
+	deployWatchMax = 2 * time.Hour
+)
+
+// ---------- Change windows ----------
+
+// weekMinutes is a position in the week, in minutes since Sunday 00:00.
+type weekMinutes int
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekPoint parses "fri 17:00" (full weekday names work too).
+func parseWeekPoint(s string) (weekMinutes, error) {
+	day, clock, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), " ")
+	if !ok || len(day) < 3 {
+		return 0, fmt.Errorf("invalid change window point %q (want e.g. \"fri 17:00\")", s)
+	}
+	wd, ok := weekdayNames[day[:3]]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday in %q", s)
+	}
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time in %q", s)
+	}
+	return weekMinutes(int(wd)*24*60 + t.Hour()*60 + t.Minute()), nil
+}
+
+func weekPosition(t time.Time) weekMinutes {
+	return weekMinutes(int(t.Weekday())*24*60 + t.Hour()*60 + t.Minute())
+}
+
+// activeFreeze returns the window that contains t, if any.
+func activeFreeze(windows []ChangeWindow, t time.Time) (ChangeWindow, bool, error) {
+	now := weekPosition(t)
+	for _, w := range windows {
+		from, err := parseWeekPoint(w.From)
+		if err != nil {
+			return ChangeWindow{}, false, err
+		}
+		to, err := parseWeekPoint(w.To)
+		if err != nil {
+			return ChangeWindow{}, false, err
+		}
+		in := from <= now && now < to
+		if from > to {
+			in = now >= from || now < to
+		}
+		if in {
+			return w, true, nil
+		}
+	}
+	return ChangeWindow{}, false, nil
+}
+
+// ---------- Provider runs ----------
+
+// deployRun is one pipeline or workflow run, with a normalized status:
+// queued, running, success, failed or canceled.
+type deployRun struct {
+	ID        string
+	Status    string
+	Ref       string
+	SHA       string
+	URL       string
+	CreatedAt time.Time
+}
+
+func (r *deployRun) done() bool {
+	return r.Status == "success" || r.Status == "failed" || r.Status == "canceled"
+}
+
+func (r *deployRun) String() string {
+	s := fmt.Sprintf("run %s: %s", r.ID, r.Status)
+	if r.Ref != "" || r.SHA != "" {
+		s += fmt.Sprintf(" (%s %s)", r.Ref, shortSHA(r.SHA))
+	}
+	if r.URL != "" {
+		s += "\n" + r.URL
+	}
+	return s
+}
+
+// deployProvider drives a CI provider's deploy pipeline.
+type deployProvider interface {
+	// trigger starts a deploy of ref. The returned run may have no ID when
+	// the provider has not listed it yet.
+	trigger(ctx context.Context, ref string, inputs map[string]string) (*deployRun, error)
+
+	// latest returns the newest deploy run created at or after since
+	// (any time when zero), or nil.
+	latest(ctx context.Context, since time.Time) (*deployRun, error)
+
+	// run returns a run by ID.
+	run(ctx context.Context, id string) (*deployRun, error)
+
+	// rollback redeploys the last successful deployment before the current
+	// one and returns the new run and the commit it deploys.
+	rollback(ctx context.Context, ref string) (*deployRun, string, error)
+}
+
+// newDeployProvider builds the provider from the configuration.
+func newDeployProvider(cfg DeployConfig) (deployProvider, string, error) {
+	provider, repo, err := resolveCIRepo(cfg.Provider, cfg.Repo, "deploy.provider")
+	if err != nil {
+		return nil, "", err
+	}
+	client := &http.Client{Timeout: 20 * time.Second}
+	base := strings.TrimRight(cfg.BaseURL, "/")
+
+	switch provider {
+	case "github":
+		if cfg.Workflow == "" {
+			return nil, "", fmt.Errorf("deploy.workflow is required for GitHub")
+		}
+		if base == "" {
+			base = "https://api.github.com"
+		}
+		token := firstNonEmpty(cfg.Token, os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN"))
+		return &githubDeploy{
+			api:              doraAPI{client: client, base: base + "/repos/" + repo, auth: bearerAuth(token)},
+			workflow:         cfg.Workflow,
+			rollbackWorkflow: cfg.RollbackWorkflow,
+		}, "github:" + repo, nil
+	case "gitlab":
+		if base == "" {
+			base = "https://gitlab.com"
+		}
+		token := firstNonEmpty(cfg.Token, os.Getenv("GITLAB_TOKEN"))
+		return &gitlabDeploy{
+			api: doraAPI{
+				client: client,
+				base:   base + "/api/v4/projects/" + url.PathEscape(repo),
+				auth:   func(r *http.Request) { r.Header.Set("PRIVATE-TOKEN", token) },
+			},
+			env: firstNonEmpty(cfg.Environment, DefaultDeployConfig().Environment),
+		}, "gitlab:" + repo, nil
+	default:
+		return nil, "", fmt.Errorf("unknown CI provider %q (use github or gitlab)", provider)
+	}
+}
+
+// ---------- GitHub ----------
+
+type githubDeploy struct {
+	api              doraAPI
+	workflow         string
+	rollbackWorkflow string
+}
+
+type githubRun struct {
+	ID         int64     `json:"id"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	HTMLURL    string    `json:"html_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (r githubRun) toRun() *deployRun {
+	status := "running"
+	switch r.Status {
+	case "queued", "requested", "waiting", "pending":
+		status = "queued"
+	case "completed":
+		switch r.Conclusion {
+		case "success":
+			status = "success"
+		case "cancelled", "skipped":
+			status = "canceled"
+		default:
+			status = "failed"
+		}
+	}
+	return &deployRun{
+		ID: strconv.FormatInt(r.ID, 10), Status: status, Ref: r.HeadBranch,
+		SHA: r.HeadSHA, URL: r.HTMLURL, CreatedAt: r.CreatedAt,
+	}
+}
+
+func (g *githubDeploy) workflowPath(workflow string) string {
+	return "/actions/workflows/" + url.PathEscape(workflow)
+}
+
+func (g *githubDeploy) dispatch(ctx context.Context, workflow, ref string, inputs map[string]string) (*deployRun, error) {
+	body := map[string]any{"ref": ref}
+	if len(inputs) > 0 {
+		body["inputs"] = inputs
+	}
+	started := time.Now().Add(-time.Minute)
+	if err := g.api.do(ctx, http.MethodPost, g.workflowPath(workflow)+"/dispatches", body, nil); err != nil {
+		return nil, err
+	}
+	// Dispatches return no run; it shows up in the list after a moment.
+	for i := 0; i < 4; i++ {
+		select {
+		case <-ctx.Done():
+			return &deployRun{Status: "queued", Ref: ref}, nil
+		case <-time.After(2 * time.Second):
+		}
+		if runs, err := g.runs(ctx, workflow, url.Values{"event": {"workflow_dispatch"}}, started); err == nil && len(runs) > 0 {
+			return runs[0].toRun(), nil
+		}
+	}
+	return &deployRun{Status: "queued", Ref: ref}, nil
+}
+
+func (g *githubDeploy) runs(ctx context.Context, workflow string, query url.Values, since time.Time) ([]githubRun, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", "20")
+	if !since.IsZero() {
+		query.Set("created", ">="+since.UTC().Format(time.RFC3339))
+	}
+	var resp struct {
+		Runs []githubRun `json:"workflow_runs"`
+	}
+	if err := g.api.get(ctx, g.workflowPath(workflow)+"/runs", query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Runs, nil
+}
+
+func (g *githubDeploy) trigger(ctx context.Context, ref string, inputs map[string]string) (*deployRun, error) {
+	return g.dispatch(ctx, g.workflow, ref, inputs)
+}
+
+func (g *githubDeploy) latest(ctx context.Context, since time.Time) (*deployRun, error) {
+	runs, err := g.runs(ctx, g.workflow, nil, since)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0].toRun(), nil
+}
+
+func (g *githubDeploy) run(ctx context.Context, id string) (*deployRun, error) {
+	var r githubRun
+	if err := g.api.get(ctx, "/actions/runs/"+url.PathEscape(id), nil, &r); err != nil {
+		return nil, err
+	}
+	return r.toRun(), nil
+}
+
+// rollback finds the newest successful run whose commit differs from the
+// current deploy and re-runs it (or dispatches the rollback workflow).
+func (g *githubDeploy) rollback(ctx context.Context, ref string) (*deployRun, string, error) {
+	ok, err := g.runs(ctx, g.workflow, url.Values{"status": {"success"}}, time.Time{})
+	if err != nil {
+		return nil, "", err
+	}
+	current := ""
+	if latest, err := g.latest(ctx, time.Time{}); err == nil && latest != nil {
+		current = latest.SHA
+	}
+	var target *githubRun
+	for i := range ok {
+		if ok[i].HeadSHA != current {
+			target = &ok[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, "", fmt.Errorf("no earlier successful deploy to roll back to")
+	}
+
+	if g.rollbackWorkflow != "" {
+		run, err := g.dispatch(ctx, g.rollbackWorkflow, ref, map[string]string{"sha": target.HeadSHA})
+		return run, target.HeadSHA, err
+	}
+	id := strconv.FormatInt(target.ID, 10)
+	if err := g.api.do(ctx, http.MethodPost, "/actions/runs/"+id+"/rerun", nil, nil); err != nil {
+		return nil, "", err
+	}
+	run, err := g.run(ctx, id)
+	return run, target.HeadSHA, err
+}
+
+// ---------- GitLab ----------
+
+type gitlabDeploy struct {
+	api doraAPI
+	env string
+}
+
+type gitlabPipeline struct {
+	ID        int64     `json:"id"`
+	Status    string    `json:"status"`
+	Ref       string    `json:"ref"`
+	SHA       string    `json:"sha"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (p gitlabPipeline) toRun() *deployRun {
+	status := "running"
+	switch p.Status {
+	case "created", "waiting_for_resource", "preparing", "pending", "scheduled", "manual":
+		status = "queued"
+	case "success":
+		status = "success"
+	case "failed":
+		status = "failed"
+	case "canceled", "skipped":
+		status = "canceled"
+	}
+	return &deployRun{
+		ID: strconv.FormatInt(p.ID, 10), Status: status, Ref: p.Ref,
+		SHA: p.SHA, URL: p.WebURL, CreatedAt: p.CreatedAt,
+	}
+}
+
+func (g *gitlabDeploy) trigger(ctx context.Context, ref string, inputs map[string]string) (*deployRun, error) {
+	body := map[string]any{"ref": ref}
+	if len(inputs) > 0 {
+		vars := make([]map[string]string, 0, len(inputs))
+		for k, v := range inputs {
+			vars = append(vars, map[string]string{"key": k, "value": v})
+		}
+		body["variables"] = vars
+	}
+	var p gitlabPipeline
+	if err := g.api.do(ctx, http.MethodPost, "/pipeline", body, &p); err != nil {
+		return nil, err
+	}
+	return p.toRun(), nil
+}
+
+func (g *gitlabDeploy) latest(ctx context.Context, since time.Time) (*deployRun, error) {
+	query := url.Values{"per_page": {"1"}, "order_by": {"id"}, "sort": {"desc"}}
+	if !since.IsZero() {
+		query.Set("updated_after", since.UTC().Format(time.RFC3339))
+	}
+	var ps []gitlabPipeline
+	if err := g.api.get(ctx, "/pipelines", query, &ps); err != nil || len(ps) == 0 {
+		return nil, err
+	}
+	return ps[0].toRun(), nil
+}
+
+func (g *gitlabDeploy) run(ctx context.Context, id string) (*deployRun, error) {
+	var p gitlabPipeline
+	if err := g.api.get(ctx, "/pipelines/"+url.PathEscape(id), nil, &p); err != nil {
+		return nil, err
+	}
+	return p.toRun(), nil
+}
+
+// rollback retries the deploy job of the previous successful deployment to
+// the environment, which is what GitLab's own rollback button does.
+func (g *gitlabDeploy) rollback(ctx context.Context, _ string) (*deployRun, string, error) {
+	var deps []struct {
+		SHA        string `json:"sha"`
+		Deployable struct {
+			ID       int64          `json:"id"`
+			Pipeline gitlabPipeline `json:"pipeline"`
+		} `json:"deployable"`
+	}
+	query := url.Values{
+		"environment": {g.env}, "status": {"success"},
+		"order_by": {"id"}, "sort": {"desc"}, "per_page": {"10"},
+	}
+	if err := g.api.get(ctx, "/deployments", query, &deps); err != nil {
+		return nil, "", err
+	}
+	if len(deps) < 2 {
+		return nil, "", fmt.Errorf("no earlier successful deployment to %s", g.env)
+	}
+	target := deps[1]
+	for _, d := range deps[1:] {
+		if d.SHA != deps[0].SHA {
+			target = d
+			break
+		}
+	}
+	var job struct {
+		Pipeline gitlabPipeline `json:"pipeline"`
+	}
+	path := fmt.Sprintf("/jobs/%d/retry", target.Deployable.ID)
+	if err := g.api.do(ctx, http.MethodPost, path, nil, &job); err != nil {
+		return nil, "", err
+	}
+	run, err := g.run(ctx, strconv.FormatInt(job.Pipeline.ID, 10))
+	return run, target.SHA, err
+}
+
+// ---------- Tools ----------
+
+// deployer holds the state shared by the deploy tools.
+type deployer struct {
+	cfg      DeployConfig
+	loc      *time.Location
+	provider deployProvider
+	source   string
+	guard    *ToolGuard
+	notify   func(ctx context.Context, target DeliveryTarget, text string)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	watching map[string]bool
+}
+
+// freezeCheck enforces the change windows for a deploy action. Only the
+// owner can override a freeze, and only with a reason.
+func (d *deployer) freezeCheck(callerLevel AccessLevel, args map[string]any) ToolCheckResult {
+	w, frozen, err := activeFreeze(d.cfg.Freeze, d.now().In(d.loc))
+	if err != nil {
+		return ToolCheckResult{Reason: "deploy.freeze: " + err.Error()}
+	}
+	if !frozen {
+		return ToolCheckResult{Allowed: true}
+	}
+	override, _ := args["override"].(bool)
+	reason, _ := args["reason"].(string)
+	switch {
+	case override && callerLevel == AccessOwner && strings.TrimSpace(reason) != "":
+		return ToolCheckResult{Allowed: true}
+	case override && callerLevel == AccessOwner:
+		return ToolCheckResult{Reason: "overriding the change freeze requires a reason"}
+	default:
+		return ToolCheckResult{Reason: fmt.Sprintf(
+			"change freeze in effect (%s – %s, %s); only the owner can override with override=true and a reason",
+			w.From, w.To, d.loc)}
+	}
+}
+
+// policy is registered with the tool guard for trigger and rollback: admin
+// access plus the change windows, checked before the approval prompt that
+// every non-owner call goes through.
+func (d *deployer) policy(callerLevel AccessLevel, args map[string]any) ToolCheckResult {
+	if !hasPermission(callerLevel, PermAdmin) {
+		return ToolCheckResult{Reason: fmt.Sprintf("deploys require admin access (you have %s)", callerLevel)}
+	}
+	result := d.freezeCheck(callerLevel, args)
+	result.RequiresConfirmation = result.Allowed
+	return result
+}
+
+// audit records a deploy action in the tool audit log.
+func (d *deployer) audit(ctx context.Context, action string, args map[string]any, allowed bool, result string) {
+	if d.guard == nil {
+		return
+	}
+	entry := map[string]any{"action": action, "source": d.source}
+	for k, v := range args {
+		entry[k] = v
+	}
+	d.guard.AuditLog("deploy", CallerJIDFromContext(ctx), CallerLevelFromContext(ctx), entry, allowed, result)
+}
+
+// watch polls a run in the background until it finishes and announces the
+// outcome. A run without ID is looked up as the newest run since started.
+func (d *deployer) watch(target DeliveryTarget, action string, run *deployRun, started time.Time) {
+	if d.notify == nil {
+		return
+	}
+	d.mu.Lock()
+	if run.ID != "" && d.watching[run.ID] {
+		d.mu.Unlock()
+		return
+	}
+	if run.ID != "" {
+		d.watching[run.ID] = true
+	}
+	d.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), deployWatchMax)
+		defer cancel()
+		defer func() {
+			d.mu.Lock()
+			delete(d.watching, run.ID)
+			d.mu.Unlock()
+		}()
+
+		current := run
+		for !current.done() {
+			select {
+			case <-ctx.Done():
+				d.notify(context.Background(), target, fmt.Sprintf("⏱️ Stopped watching %s %s after %s.", action, current, deployWatchMax))
+				return
+			case <-time.After(deployWatchInterval):
+			}
+			var next *deployRun
+			var err error
+			if current.ID == "" {
+				next, err = d.provider.latest(ctx, started)
+			} else {
+				next, err = d.provider.run(ctx, current.ID)
+			}
+			if err == nil && next != nil {
+				current = next
+			}
+		}
+
+		icon := "✅"
+		if current.Status != "success" {
+			icon = "❌"
+		}
+		d.notify(context.Background(), target, fmt.Sprintf("%s %s finished on %s: %s", icon, action, d.source, current))
+	}()
+}
+
+// RegisterDeployTools registers the deploy tools when deploy.enabled is
+// set. notify delivers deploy events to the configured chat and the chat
+// that asked; it may be nil.
+func RegisterDeployTools(executor *ToolExecutor, cfg DeployConfig, timezone string, notify func(ctx context.Context, target DeliveryTarget, text string)) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	provider, source, err := newDeployProvider(cfg)
+	if err != nil {
+		return err
+	}
+	for _, w := range cfg.Freeze {
+		if _, err := parseWeekPoint(w.From); err != nil {
+			return fmt.Errorf("deploy.freeze: %w", err)
+		}
+		if _, err := parseWeekPoint(w.To); err != nil {
+			return fmt.Errorf("deploy.freeze: %w", err)
+		}
+	}
+	loc := time.Local
+	if tz := firstNonEmpty(cfg.Timezone, timezone); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	ref := firstNonEmpty(cfg.Ref, DefaultDeployConfig().Ref)
+
+	d := &deployer{
+		cfg: cfg, loc: loc, provider: provider, source: source,
+		guard: executor.Guard(), notify: notify, now: time.Now,
+		watching: make(map[string]bool),
+	}
+	if d.guard != nil {
+		for _, name := range deployToolNames {
+			d.guard.SetPolicy(name, d.policy)
+		}
+	}
+
+	overrideProps := map[string]any{
+		"override": map[string]any{
+			"type":        "boolean",
+			"description": "Deploy during a change freeze (owner only, requires reason)",
+		},
+		"reason": map[string]any{
+			"type":        "string",
+			"description": "Why this deploy is needed; required to override a freeze",
+		},
+	}
+	props := func(extra map[string]any) map[string]any {
+		out := map[string]any{}
+		for k, v := range overrideProps {
+			out[k] = v
+		}
+		for k, v := range extra {
+			out[k] = v
+		}
+		return out
+	}
+
+	// ── deploy_trigger ──
+	executor.Register(
+		MakeToolDefinition("deploy_trigger",
+			"Start a deployment through the CI pipeline ("+source+"). Refused during change freezes; the run is watched and its outcome announced.",
+			map[string]any{
+				"type": "object",
+				"properties": props(map[string]any{
+					"ref": map[string]any{
+						"type":        "string",
+						"description": "Branch or tag to deploy (default: " + ref + ")",
+					},
+					"inputs": map[string]any{
+						"type":        "object",
+						"description": "Workflow inputs (GitHub) or pipeline variables (GitLab)",
+					},
+				}),
+			}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			if check := d.freezeCheck(CallerLevelFromContext(ctx), args); !check.Allowed {
+				d.audit(ctx, "trigger", args, false, check.Reason)
+				return nil, fmt.Errorf("%s", check.Reason)
+			}
+			r := ref
+			if v, _ := args["ref"].(string); v != "" {
+				r = v
+			}
+			inputs := map[string]string{}
+			if m, ok := args["inputs"].(map[string]any); ok {
+				for k, v := range m {
+					inputs[k] = fmt.Sprint(v)
+				}
+			}
+
+			started := time.Now()
+			run, err := provider.trigger(ctx, r, inputs)
+			if err != nil {
+				d.audit(ctx, "trigger", args, true, "ERROR: "+err.Error())
+				return nil, fmt.Errorf("triggering deploy: %w", err)
+			}
+			d.audit(ctx, "trigger", args, true, run.String())
+
+			msg := fmt.Sprintf("🚀 Deploy of %s started on %s by %s", r, source, callerName(ctx))
+			if reason, _ := args["reason"].(string); reason != "" {
+				msg += "\nReason: " + reason
+			}
+			if override, _ := args["override"].(bool); override {
+				msg += "\n⚠️ Change freeze overridden by the owner."
+			}
+			target := DeliveryTargetFromContext(ctx)
+			if notify != nil {
+				notify(ctx, DeliveryTarget{}, msg+"\n"+run.String())
+			}
+			d.watch(target, "deploy of "+r, run, started)
+			return fmt.Sprintf("Deploy started (%s).\n%s", source, run), nil
+		},
+	)
+
+	// ── deploy_status ──
+	executor.Register(
+		MakeToolDefinition("deploy_status",
+			"Show the status of a deploy run, or of the latest one.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"run_id": map[string]any{
+						"type":        "string",
+						"description": "Run or pipeline ID (default: latest)",
+					},
+				},
+			}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			var run *deployRun
+			var err error
+			if id, _ := args["run_id"].(string); id != "" {
+				run, err = provider.run(ctx, id)
+			} else {
+				run, err = provider.latest(ctx, time.Time{})
+			}
+			if err != nil {
+				return nil, err
+			}
+			if run == nil {
+				return "No deploy runs found on " + source + ".", nil
+			}
+			out := fmt.Sprintf("%s — %s", source, run)
+			if w, frozen, _ := activeFreeze(cfg.Freeze, d.now().In(loc)); frozen {
+				out += fmt.Sprintf("\nChange freeze in effect (%s – %s).", w.From, w.To)
+			}
+			return out, nil
+		},
+	)
+
+	// ── deploy_rollback ──
+	executor.Register(
+		MakeToolDefinition("deploy_rollback",
+			"Roll back to the previous successful deployment. Refused during change freezes; the run is watched and its outcome announced.",
+			map[string]any{
+				"type":       "object",
+				"properties": props(nil),
+			}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			if check := d.freezeCheck(CallerLevelFromContext(ctx), args); !check.Allowed {
+				d.audit(ctx, "rollback", args, false, check.Reason)
+				return nil, fmt.Errorf("%s", check.Reason)
+			}
+			started := time.Now()
+			run, sha, err := provider.rollback(ctx, ref)
+			if err != nil {
+				d.audit(ctx, "rollback", args, true, "ERROR: "+err.Error())
+				return nil, fmt.Errorf("rolling back: %w", err)
+			}
+			d.audit(ctx, "rollback", args, true, "to "+shortSHA(sha)+": "+run.String())
+
+			msg := fmt.Sprintf("⏪ Rollback to %s started on %s by %s", shortSHA(sha), source, callerName(ctx))
+			if reason, _ := args["reason"].(string); reason != "" {
+				msg += "\nReason: " + reason
+			}
+			if notify != nil {
+				notify(ctx, DeliveryTarget{}, msg+"\n"+run.String())
+			}
+			d.watch(DeliveryTargetFromContext(ctx), "rollback to "+shortSHA(sha), run, started)
+			return fmt.Sprintf("Rollback to %s started (%s).\n%s", shortSHA(sha), source, run), nil
+		},
+	)
+
+	return nil
+}
+
+// callerName names the caller for deploy announcements.
+func callerName(ctx context.Context) string {
+	if jid := CallerJIDFromContext(ctx); jid != "" {
+		return jid
+	}
+	return "an approved request"
+}
+
+// notifyDeploy announces a deploy event to deploy.notify and, when target is
+// set, to the chat that started the deploy.
+func (a *Assistant) notifyDeploy(ctx context.Context, target DeliveryTarget, text string) {
+	n := a.config.Deploy.Notify
+	if n.Channel != "" && n.To != "" {
+		if _, err := a.Notify(ctx, n.Workspace, text, NotifyOptions{Channel: n.Channel, To: n.To}); err != nil {
+			a.logger.Warn("deploy notification failed", "channel", n.Channel, "error", err)
+		}
+	}
+	if target.Channel == "" || target.ChatID == "" || (target.Channel == n.Channel && target.ChatID == n.To) {
+		return
+	}
+	wsID := a.workspaceMgr.WorkspaceIDForSession(target.Channel + ":" + target.ChatID)
+	if _, err := a.Notify(ctx, wsID, text, NotifyOptions{Channel: target.Channel, To: target.ChatID}); err != nil {
+		a.logger.Warn("deploy notification failed", "channel", target.Channel, "error", err)
+	}
+}
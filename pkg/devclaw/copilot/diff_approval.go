@@ -0,0 +1,240 @@
+// Package copilot – diff_approval.go implements per-file diff review for
+// write_file, edit_file and apply_changes when they require confirmation.
+//
+// Instead of a generic "confirm write_file?", the user sees a unified diff
+// of every file the call would change and approves or rejects each one. The
+// review blocks the tool call, so the outcome goes straight back to the
+// model: approved files are written (apply_changes keeps its all-or-nothing
+// transaction for the approved subset), rejected diffs are returned in the
+// tool result so the model can revise them. A file that changed on disk
+// while the review was open is not written.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxReviewDiffChars caps each diff shown in a chat approval message.
+const maxReviewDiffChars = 3000
+
+// ProposedFileChange is one file a tool call would change.
+type ProposedFileChange struct {
+	Path   string
+	Action string // create, write, edit, delete
+	Diff   string
+
+	// before is the file hash when the diff was computed ("" = missing).
+	before string
+}
+
+// DiffReviewer asks the user to review the proposed changes of a tool call.
+// It returns one decision per file, in order; reason explains a rejection.
+type DiffReviewer func(ctx context.Context, sessionID, callerJID, toolName string, files []ProposedFileChange) (approved []bool, reason string, err error)
+
+// isDiffReviewTool reports whether a tool's confirmation is a diff review.
+func isDiffReviewTool(name string) bool {
+	return name == "write_file" || name == "edit_file" || name == "apply_changes"
+}
+
+// SetDiffReviewer sets the callback used to review file changes of tools
+// requiring confirmation. When nil, those tools use the generic approval.
+func (e *ToolExecutor) SetDiffReviewer(fn DiffReviewer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.diffReviewer = fn
+}
+
+// toolFileChanges expresses a write_file / edit_file / apply_changes call
+// as apply_changes entries, so all three share validation and diffing.
+func toolFileChanges(toolName string, args map[string]any) ([]fileChange, error) {
+	path, _ := args["path"].(string)
+	switch toolName {
+	case "apply_changes":
+		return parseFileChanges(args)
+	case "write_file":
+		if path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		content, _ := args["content"].(string)
+		if appendMode, _ := args["append"].(bool); appendMode {
+			existing := snapshotFile(resolvePath(path))
+			if existing.exists && existing.content == nil {
+				return nil, fmt.Errorf("%s is too large to review", path)
+			}
+			content = string(existing.content) + content
+		}
+		return []fileChange{{Action: "write", Path: path, Content: content}}, nil
+	case "edit_file":
+		if path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		c := fileChange{Action: "edit", Path: path}
+		c.OldText, _ = args["old_text"].(string)
+		c.NewText, _ = args["new_text"].(string)
+		c.ReplaceAll, _ = args["replace_all"].(bool)
+		return []fileChange{c}, nil
+	}
+	return nil, fmt.Errorf("%s does not change files", toolName)
+}
+
+// proposeFileChanges validates a call and returns the diff of every file it
+// would change. Nothing is written.
+func proposeFileChanges(toolName string, args map[string]any) ([]ProposedFileChange, error) {
+	changes, err := toolFileChanges(toolName, args)
+	if err != nil {
+		return nil, err
+	}
+	plans, err := planChanges(changes)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]ProposedFileChange, len(plans))
+	for i, p := range plans {
+		before := ""
+		if p.existed {
+			before = sha256Hex(p.original)
+		}
+		diff := ""
+		switch {
+		case isBinary(p.original) || isBinary(p.final):
+			diff = "(binary file)"
+		default:
+			diff = unifiedDiff(p.change.Path, string(p.original), string(p.final))
+		}
+		files[i] = ProposedFileChange{Path: p.absPath, Action: p.change.Action, Diff: diff, before: before}
+	}
+	return files, nil
+}
+
+// unchangedSinceReview reports whether the file still has the content the
+// reviewed diff was computed against.
+func (f ProposedFileChange) unchangedSinceReview() bool {
+	return snapshotFile(f.Path).sha == f.before
+}
+
+// reviewFileChanges runs the diff review of a call. It returns the args to
+// execute (apply_changes narrowed to the approved files) and a note for the
+// model about rejected files, or a final result when nothing is written.
+func (e *ToolExecutor) reviewFileChanges(ctx context.Context, reviewer DiffReviewer, name string, args map[string]any, callerJID string) (map[string]any, string, *ToolResult) {
+	result := func(err error, content string) *ToolResult {
+		if content == "" {
+			content = formatToolError(name, err)
+		}
+		return &ToolResult{Name: name, Content: content, Error: err}
+	}
+
+	files, err := proposeFileChanges(name, args)
+	if err != nil {
+		return nil, "", result(fmt.Errorf("validation failed, no files changed: %w", err), "")
+	}
+
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" {
+		e.mu.RLock()
+		sessionID = e.sessionID
+		e.mu.RUnlock()
+	}
+	approved, reason, err := reviewer(ctx, sessionID, callerJID, name, files)
+	if err != nil {
+		return nil, "", result(fmt.Errorf("review failed, no files changed: %w", err), "")
+	}
+
+	var accepted, rejected []ProposedFileChange
+	keep := make([]bool, len(files))
+	for i, f := range files {
+		if i < len(approved) && approved[i] {
+			if !f.unchangedSinceReview() {
+				return nil, "", result(fmt.Errorf("%s changed on disk during the review, no files changed; re-read it and propose the change again", f.Path), "")
+			}
+			accepted = append(accepted, f)
+			keep[i] = true
+		} else {
+			rejected = append(rejected, f)
+		}
+	}
+
+	note := formatRejectedChanges(rejected, reason)
+	if len(accepted) == 0 {
+		return nil, "", result(fmt.Errorf("rejected by user"), note)
+	}
+	if name == "apply_changes" && len(rejected) > 0 {
+		raw, _ := args["changes"].([]any)
+		narrowed := make([]any, 0, len(accepted))
+		for i, c := range raw {
+			if i < len(keep) && keep[i] {
+				narrowed = append(narrowed, c)
+			}
+		}
+		args = cloneArgs(args)
+		args["changes"] = narrowed
+	}
+	return args, note, nil
+}
+
+// formatRejectedChanges tells the model which proposed changes the user
+// rejected, with the diffs, so it can revise them.
+func formatRejectedChanges(rejected []ProposedFileChange, reason string) string {
+	if len(rejected) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "The user rejected %d proposed change(s); these files were NOT modified:\n", len(rejected))
+	for _, f := range rejected {
+		fmt.Fprintf(&b, "\n%s %s\n%s", f.Action, f.Path, f.Diff)
+	}
+	if reason != "" {
+		fmt.Fprintf(&b, "\nReason given: %s\n", reason)
+	}
+	b.WriteString("\nDo not retry the same change; ask the user or propose a different one.")
+	return b.String()
+}
+
+// formatDiffReviewMessage builds the chat message asking the user to
+// review the diffs of a pending approval.
+func formatDiffReviewMessage(id, toolName string, files []ProposedFileChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📝 Review %s (%d file(s)):\n", toolName, len(files))
+	for i, f := range files {
+		diff := f.Diff
+		if len(diff) > maxReviewDiffChars {
+			diff = truncateUTF8(diff, maxReviewDiffChars) + "\n... (diff truncated)"
+		}
+		fmt.Fprintf(&b, "\n%d. %s %s\n```diff\n%s```\n", i+1, f.Action, f.Path, diff)
+	}
+	if len(files) > 1 {
+		fmt.Fprintf(&b, "\nReply /approve %s to apply all, /approve %s 1,3 to apply only those files, or /deny %s [reason].", id, id, id)
+	} else {
+		fmt.Fprintf(&b, "\nReply /approve %s or /deny %s [reason].", id, id)
+	}
+	return b.String()
+}
+
+// parseFileSelection parses a 1-based list like "1,3" or "1 3" into file
+// indexes. ok is false when s is not a selection.
+func parseFileSelection(s string, n int) ([]int, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, false
+	}
+	sel := make([]int, 0, len(fields))
+	for _, f := range fields {
+		i, err := strconv.Atoi(f)
+		if err != nil || i < 1 || (n > 0 && i > n) {
+			return nil, false
+		}
+		sel = append(sel, i-1)
+	}
+	return sel, true
+}
+
+// cloneArgs returns a shallow copy of tool args.
+func cloneArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	return out
+}
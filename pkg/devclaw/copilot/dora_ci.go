@@ -0,0 +1,576 @@
+// Package copilot – dora_ci.go feeds dora_metrics from CI/CD provider data.
+// Deployment frequency and lead time come from the GitHub deployments API
+// (what GitHub Actions environments record) or GitLab environment
+// deployments; change failure rate and time to restore come from failed
+// deployments and issues carrying an incident label. The git-tag heuristics
+// in product_tools.go remain the fallback.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DORAConfig configures the CI/CD data source of the dora_metrics tool.
+type DORAConfig struct {
+	// Provider is "github" or "gitlab". Empty keeps the git-tag heuristics
+	// unless the tool is asked for a provider explicitly.
+	Provider string `yaml:"provider"`
+
+	// Repo is "owner/name" (GitHub) or the project path (GitLab).
+	// Default: derived from the origin remote of the working directory.
+	Repo string `yaml:"repo"`
+
+	// Token authenticates API calls. Default: $GITHUB_TOKEN (or $GH_TOKEN)
+	// for GitHub, $GITLAB_TOKEN for GitLab.
+	Token string `yaml:"token"`
+
+	// BaseURL points at GitHub Enterprise or a self-hosted GitLab
+	// (default: https://api.github.com, https://gitlab.com).
+	BaseURL string `yaml:"base_url"`
+
+	// Environment is the deployment environment that counts as production
+	// (default: "production").
+	Environment string `yaml:"environment"`
+
+	// IncidentLabels are the issue labels that mark an incident
+	// (default: ["incident"]).
+	IncidentLabels []string `yaml:"incident_labels"`
+}
+
+// DefaultDORAConfig returns the default DORA configuration.
+func DefaultDORAConfig() DORAConfig {
+	return DORAConfig{
+		Environment:    "production",
+		IncidentLabels: []string{"incident"},
+	}
+}
+
+// doraMaxLeadTimeDeploys bounds the compare calls made for lead time.
+const doraMaxLeadTimeDeploys = 30
+
+// doraDeploy is one finished deployment to the production environment.
+type doraDeploy struct {
+	SHA    string
+	At     time.Time
+	Failed bool
+}
+
+// doraIncident is one issue labeled as an incident.
+type doraIncident struct {
+	Opened time.Time
+	Closed time.Time // zero while open
+}
+
+// doraSource reads deployments, commits and incidents from a provider.
+type doraSource interface {
+	// deployments returns finished deployments created since the given
+	// time, plus the newest one before it (the lead-time base), if any.
+	deployments(ctx context.Context, since time.Time) ([]doraDeploy, error)
+
+	// commitTimes returns the commit dates of base..head.
+	commitTimes(ctx context.Context, base, head string) ([]time.Time, error)
+
+	// incidents returns incident issues opened since the given time.
+	incidents(ctx context.Context, since time.Time) ([]doraIncident, error)
+}
+
+// doraPipelineData is what a provider contributes to the metrics.
+type doraPipelineData struct {
+	Deploys       int
+	FailedDeploys int
+	LeadHours     []float64
+	Incidents     int
+	RestoreHours  []float64
+}
+
+// newDORASource resolves the provider ("github" or "gitlab"), repository
+// and token. Repo and provider fall back to the origin remote.
+func newDORASource(cfg DORAConfig, provider string) (doraSource, string, error) {
+	provider, repo, err := resolveCIRepo(provider, cfg.Repo, "dora.provider")
+	if err != nil {
+		return nil, "", err
+	}
+
+	env := cfg.Environment
+	if env == "" {
+		env = DefaultDORAConfig().Environment
+	}
+	labels := cfg.IncidentLabels
+	if len(labels) == 0 {
+		labels = DefaultDORAConfig().IncidentLabels
+	}
+	client := &http.Client{Timeout: 20 * time.Second}
+
+	switch provider {
+	case "github":
+		base := strings.TrimRight(cfg.BaseURL, "/")
+		if base == "" {
+			base = "https://api.github.com"
+		}
+		token := firstNonEmpty(cfg.Token, os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN"))
+		return &githubDORA{
+			api:    doraAPI{client: client, base: base + "/repos/" + repo, auth: bearerAuth(token)},
+			env:    env,
+			labels: labels,
+		}, "github:" + repo, nil
+	case "gitlab":
+		base := strings.TrimRight(cfg.BaseURL, "/")
+		if base == "" {
+			base = "https://gitlab.com"
+		}
+		token := firstNonEmpty(cfg.Token, os.Getenv("GITLAB_TOKEN"))
+		return &gitlabDORA{
+			api: doraAPI{
+				client: client,
+				base:   base + "/api/v4/projects/" + url.PathEscape(repo),
+				auth:   func(r *http.Request) { r.Header.Set("PRIVATE-TOKEN", token) },
+			},
+			env:    env,
+			labels: labels,
+		}, "gitlab:" + repo, nil
+	default:
+		return nil, "", fmt.Errorf("unknown CI provider %q (use github or gitlab)", provider)
+	}
+}
+
+// resolveCIRepo fills in the CI provider ("github" or "gitlab") and the
+// repository path from the origin remote of the working directory when
+// they are not configured. setting names the config key for error messages.
+func resolveCIRepo(provider, repo, setting string) (string, string, error) {
+	if repo != "" && provider != "" {
+		return provider, repo, nil
+	}
+	remote, err := runGit("remote", "get-url", "origin")
+	if err != nil {
+		return "", "", fmt.Errorf("no repo configured and no origin remote: %w", err)
+	}
+	host, path := parseRemoteRepo(remote)
+	if path == "" {
+		return "", "", fmt.Errorf("cannot parse origin remote %q", remote)
+	}
+	if repo == "" {
+		repo = path
+	}
+	if provider == "" {
+		switch {
+		case strings.Contains(host, "github"):
+			provider = "github"
+		case strings.Contains(host, "gitlab"):
+			provider = "gitlab"
+		default:
+			return "", "", fmt.Errorf("cannot tell the CI provider of %s (set %s)", host, setting)
+		}
+	}
+	return provider, repo, nil
+}
+
+// collectDORA gathers pipeline and incident data since the given time.
+// Change failures are failed deployments plus incidents; lead time is
+// measured from each commit to the deployment that first shipped it.
+func collectDORA(ctx context.Context, src doraSource, since time.Time) (*doraPipelineData, error) {
+	deploys, err := src.deployments(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("deployments: %w", err)
+	}
+	sort.Slice(deploys, func(i, j int) bool { return deploys[i].At.Before(deploys[j].At) })
+
+	data := &doraPipelineData{}
+	var prevSHA string
+	for i, d := range deploys {
+		inPeriod := !d.At.Before(since)
+		if inPeriod {
+			if d.Failed {
+				data.FailedDeploys++
+			} else {
+				data.Deploys++
+			}
+		}
+		if d.Failed {
+			continue
+		}
+		// Only the most recent deploys are compared, to bound API calls.
+		recent := len(deploys)-i <= doraMaxLeadTimeDeploys
+		if inPeriod && recent && prevSHA != "" && prevSHA != d.SHA {
+			times, err := src.commitTimes(ctx, prevSHA, d.SHA)
+			if err != nil {
+				return nil, fmt.Errorf("commits %s..%s: %w", shortSHA(prevSHA), shortSHA(d.SHA), err)
+			}
+			for _, t := range times {
+				data.LeadHours = append(data.LeadHours, d.At.Sub(t).Hours())
+			}
+		}
+		prevSHA = d.SHA
+	}
+
+	incidents, err := src.incidents(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("incidents: %w", err)
+	}
+	data.Incidents = len(incidents)
+	for _, inc := range incidents {
+		if !inc.Closed.IsZero() {
+			data.RestoreHours = append(data.RestoreHours, inc.Closed.Sub(inc.Opened).Hours())
+		}
+	}
+	return data, nil
+}
+
+// pipelineDORAMetrics turns pipeline data into the tool's report.
+func pipelineDORAMetrics(data *doraPipelineData, days int, source string) doraMetrics {
+	avgLead := meanHours(data.LeadHours)
+	failureRate := 0.0
+	if attempts := data.Deploys + data.FailedDeploys; attempts > 0 {
+		failureRate = math.Min(float64(data.FailedDeploys+data.Incidents)/float64(attempts), 1)
+	}
+
+	restore := "N/A (no resolved incidents)"
+	avgRestore := meanHours(data.RestoreHours)
+	if len(data.RestoreHours) > 0 {
+		restore = doraRestoreCategory(avgRestore)
+	}
+
+	return doraMetrics{
+		Source:             source,
+		DeployFrequency:    doraFrequencyCategory(data.Deploys, days),
+		LeadTimeForChanges: doraLeadCategory(avgLead),
+		ChangeFailureRate:  fmt.Sprintf("%.1f%%", failureRate*100),
+		TimeToRestore:      restore,
+		DeploysInPeriod:    data.Deploys,
+		FailedDeploys:      data.FailedDeploys,
+		Incidents:          data.Incidents,
+		PeriodDays:         days,
+		AvgLeadTimeHours:   math.Round(avgLead*10) / 10,
+		FailureRatePercent: math.Round(failureRate*1000) / 10,
+		AvgRestoreHours:    math.Round(avgRestore*10) / 10,
+	}
+}
+
+func doraRestoreCategory(hours float64) string {
+	switch {
+	case hours < 1:
+		return "Less than one hour"
+	case hours < 24:
+		return "Less than one day"
+	case hours < 168:
+		return "Less than one week"
+	default:
+		return "More than one week"
+	}
+}
+
+func meanHours(vals []float64) float64 {
+	if len(vals) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// parseRemoteRepo extracts host and repository path from a git remote URL
+// (git@host:path.git, https://host/path.git, ssh://git@host/path).
+func parseRemoteRepo(remote string) (host, path string) {
+	remote = strings.TrimSpace(remote)
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if at := strings.Index(remote, "@"); at >= 0 {
+		if colon := strings.Index(remote[at:], ":"); colon > 0 {
+			host, path = remote[at+1:at+colon], remote[at+colon+1:]
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return host, path
+}
+
+// ---------- HTTP ----------
+
+// doraAPI issues authenticated GET requests below a repository base URL.
+type doraAPI struct {
+	client *http.Client
+	base   string
+	auth   func(*http.Request)
+}
+
+func bearerAuth(token string) func(*http.Request) {
+	return func(r *http.Request) {
+		r.Header.Set("Accept", "application/vnd.github+json")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+func (a doraAPI) get(ctx context.Context, path string, query url.Values, out any) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return a.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out (when non-nil and the response has a body).
+func (a doraAPI) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.base+path, body)
+	if err != nil {
+		return err
+	}
+	a.auth(req)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s: %s", method, strings.SplitN(path, "?", 2)[0], resp.Status, truncate(strings.TrimSpace(string(data)), 200))
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// doraMaxPages bounds pagination (100 items per page).
+const doraMaxPages = 10
+
+// ---------- GitHub ----------
+
+type githubDORA struct {
+	api    doraAPI
+	env    string
+	labels []string
+}
+
+func (g *githubDORA) deployments(ctx context.Context, since time.Time) ([]doraDeploy, error) {
+	var out []doraDeploy
+	for page := 1; page <= doraMaxPages; page++ {
+		var batch []struct {
+			ID        int64     `json:"id"`
+			SHA       string    `json:"sha"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		q := url.Values{"environment": {g.env}, "per_page": {"100"}, "page": {fmt.Sprint(page)}}
+		if err := g.api.get(ctx, "/deployments", q, &batch); err != nil {
+			return nil, err
+		}
+		for _, d := range batch {
+			// The latest status decides the outcome; unfinished ones are skipped.
+			var statuses []struct {
+				State string `json:"state"`
+			}
+			path := fmt.Sprintf("/deployments/%d/statuses", d.ID)
+			if err := g.api.get(ctx, path, url.Values{"per_page": {"1"}}, &statuses); err != nil {
+				return nil, err
+			}
+			if len(statuses) == 0 {
+				continue
+			}
+			switch statuses[0].State {
+			case "success", "inactive":
+				out = append(out, doraDeploy{SHA: d.SHA, At: d.CreatedAt})
+			case "failure", "error":
+				out = append(out, doraDeploy{SHA: d.SHA, At: d.CreatedAt, Failed: true})
+			default:
+				continue
+			}
+			if d.CreatedAt.Before(since) && !out[len(out)-1].Failed {
+				return out, nil // base for the oldest lead time
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (g *githubDORA) commitTimes(ctx context.Context, base, head string) ([]time.Time, error) {
+	var cmp struct {
+		Commits []struct {
+			Commit struct {
+				Committer struct {
+					Date time.Time `json:"date"`
+				} `json:"committer"`
+			} `json:"commit"`
+		} `json:"commits"`
+	}
+	if err := g.api.get(ctx, "/compare/"+base+"..."+head, nil, &cmp); err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(cmp.Commits))
+	for _, c := range cmp.Commits {
+		times = append(times, c.Commit.Committer.Date)
+	}
+	return times, nil
+}
+
+func (g *githubDORA) incidents(ctx context.Context, since time.Time) ([]doraIncident, error) {
+	// Labels in one query are ANDed, so each label is queried on its own.
+	seen := map[int]bool{}
+	var out []doraIncident
+	for _, label := range g.labels {
+		for page := 1; page <= doraMaxPages; page++ {
+			var batch []struct {
+				Number      int        `json:"number"`
+				CreatedAt   time.Time  `json:"created_at"`
+				ClosedAt    *time.Time `json:"closed_at"`
+				PullRequest any        `json:"pull_request"`
+			}
+			q := url.Values{
+				"labels": {label}, "state": {"all"}, "since": {since.Format(time.RFC3339)},
+				"per_page": {"100"}, "page": {fmt.Sprint(page)},
+			}
+			if err := g.api.get(ctx, "/issues", q, &batch); err != nil {
+				return nil, err
+			}
+			for _, is := range batch {
+				if is.PullRequest != nil || seen[is.Number] || is.CreatedAt.Before(since) {
+					continue
+				}
+				seen[is.Number] = true
+				inc := doraIncident{Opened: is.CreatedAt}
+				if is.ClosedAt != nil {
+					inc.Closed = *is.ClosedAt
+				}
+				out = append(out, inc)
+			}
+			if len(batch) < 100 {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// ---------- GitLab ----------
+
+type gitlabDORA struct {
+	api    doraAPI
+	env    string
+	labels []string
+}
+
+func (g *gitlabDORA) deployments(ctx context.Context, since time.Time) ([]doraDeploy, error) {
+	var out []doraDeploy
+	for page := 1; page <= doraMaxPages; page++ {
+		var batch []struct {
+			SHA       string    `json:"sha"`
+			Status    string    `json:"status"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		q := url.Values{
+			"environment": {g.env}, "order_by": {"created_at"}, "sort": {"desc"},
+			"per_page": {"100"}, "page": {fmt.Sprint(page)},
+		}
+		if err := g.api.get(ctx, "/deployments", q, &batch); err != nil {
+			return nil, err
+		}
+		for _, d := range batch {
+			switch d.Status {
+			case "success":
+				out = append(out, doraDeploy{SHA: d.SHA, At: d.CreatedAt})
+				if d.CreatedAt.Before(since) {
+					return out, nil
+				}
+			case "failed":
+				out = append(out, doraDeploy{SHA: d.SHA, At: d.CreatedAt, Failed: true})
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (g *gitlabDORA) commitTimes(ctx context.Context, base, head string) ([]time.Time, error) {
+	var cmp struct {
+		Commits []struct {
+			CommittedDate time.Time `json:"committed_date"`
+		} `json:"commits"`
+	}
+	if err := g.api.get(ctx, "/repository/compare", url.Values{"from": {base}, "to": {head}}, &cmp); err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, 0, len(cmp.Commits))
+	for _, c := range cmp.Commits {
+		times = append(times, c.CommittedDate)
+	}
+	return times, nil
+}
+
+func (g *gitlabDORA) incidents(ctx context.Context, since time.Time) ([]doraIncident, error) {
+	seen := map[int]bool{}
+	var out []doraIncident
+	for _, label := range g.labels {
+		for page := 1; page <= doraMaxPages; page++ {
+			var batch []struct {
+				IID       int        `json:"iid"`
+				CreatedAt time.Time  `json:"created_at"`
+				ClosedAt  *time.Time `json:"closed_at"`
+			}
+			q := url.Values{
+				"labels": {label}, "created_after": {since.Format(time.RFC3339)},
+				"per_page": {"100"}, "page": {fmt.Sprint(page)},
+			}
+			if err := g.api.get(ctx, "/issues", q, &batch); err != nil {
+				return nil, err
+			}
+			for _, is := range batch {
+				if seen[is.IID] {
+					continue
+				}
+				seen[is.IID] = true
+				inc := doraIncident{Opened: is.CreatedAt}
+				if is.ClosedAt != nil {
+					inc.Closed = *is.ClosedAt
+				}
+				out = append(out, inc)
+			}
+			if len(batch) < 100 {
+				break
+			}
+		}
+	}
+	return out, nil
+}
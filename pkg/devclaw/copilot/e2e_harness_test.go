@@ -0,0 +1,370 @@
+package copilot
+
+// End-to-end harness: a full Assistant started in a temp directory, talking
+// to a scripted OpenAI-compatible mock LLM and an in-memory channel. Tests
+// send messages through the real pipeline (access → commands → workspace →
+// agent → tools → reply) and assert on replies, tool executions, audit
+// entries and session state.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+const (
+	e2eChannelName = "e2e"
+	e2eChatID      = "chat-1"
+	e2eOwner       = "owner@e2e"
+)
+
+// mockReply is one scripted LLM response: text, or a single tool call.
+type mockReply struct {
+	Content  string
+	ToolName string
+	ToolArgs map[string]any
+}
+
+// mockLLM is an OpenAI-compatible chat completions server that answers
+// with scripted replies in order, then with defaultReply. It records every
+// request body.
+type mockLLM struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	script   []mockReply
+	requests []string
+	calls    int
+}
+
+const defaultReply = "OK."
+
+func newMockLLM(t *testing.T) *mockLLM {
+	t.Helper()
+	m := &mockLLM{}
+	m.srv = httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(m.srv.Close)
+	return m
+}
+
+// Reply queues a text answer.
+func (m *mockLLM) Reply(content string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, mockReply{Content: content})
+}
+
+// CallTool queues a tool call.
+func (m *mockLLM) CallTool(name string, args map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, mockReply{ToolName: name, ToolArgs: args})
+}
+
+// Requests returns the request bodies received so far.
+func (m *mockLLM) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+func (m *mockLLM) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(body, &req)
+
+	m.mu.Lock()
+	m.requests = append(m.requests, string(body))
+	reply := mockReply{Content: defaultReply}
+	if len(m.script) > 0 {
+		reply, m.script = m.script[0], m.script[1:]
+	}
+	m.calls++
+	callID := fmt.Sprintf("call_%d", m.calls)
+	m.mu.Unlock()
+
+	message := map[string]any{"role": "assistant", "content": reply.Content}
+	finish := "stop"
+	if reply.ToolName != "" {
+		args, _ := json.Marshal(reply.ToolArgs)
+		message["content"] = ""
+		message["tool_calls"] = []map[string]any{{
+			"index": 0, "id": callID, "type": "function",
+			"function": map[string]any{"name": reply.ToolName, "arguments": string(args)},
+		}}
+		finish = "tool_calls"
+	}
+	usage := map[string]any{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+
+	if !req.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": message, "finish_reason": finish}},
+			"usage":   usage,
+		})
+		return
+	}
+	// Streaming requests get the whole reply in a single SSE chunk.
+	chunk, _ := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"delta": message, "finish_reason": finish}},
+		"usage":   usage,
+	})
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", chunk)
+}
+
+// e2eChannel is an in-memory channel that records what the bot sends.
+type e2eChannel struct {
+	mu        sync.Mutex
+	sent      []string
+	reactions []string
+}
+
+func (c *e2eChannel) Name() string                              { return e2eChannelName }
+func (c *e2eChannel) Connect(context.Context) error             { return nil }
+func (c *e2eChannel) Disconnect() error                         { return nil }
+func (c *e2eChannel) Receive() <-chan *channels.IncomingMessage { return nil }
+func (c *e2eChannel) IsConnected() bool                         { return true }
+func (c *e2eChannel) Health() channels.HealthStatus {
+	return channels.HealthStatus{Connected: true}
+}
+
+func (c *e2eChannel) Send(_ context.Context, _ string, msg *channels.OutgoingMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg.Content)
+	return nil
+}
+
+func (c *e2eChannel) SendReaction(_ context.Context, _, _, emoji string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reactions = append(c.reactions, emoji)
+	return nil
+}
+
+func (c *e2eChannel) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+// toolExecution is one call of a harness-registered tool.
+type toolExecution struct {
+	Tool string
+	Args map[string]any
+}
+
+// e2eHarness drives a started Assistant.
+type e2eHarness struct {
+	t   *testing.T
+	A   *Assistant
+	LLM *mockLLM
+	Ch  *e2eChannel
+
+	mu    sync.Mutex
+	execs []toolExecution
+	msgN  int
+}
+
+// newE2EHarness starts an Assistant in a fresh temp working directory
+// (every ./data path lands there) with the owner allowed in DMs.
+// Background work that would talk to the LLM unprompted (warmup,
+// scheduler, heartbeat) is off; configure overrides the rest.
+func newE2EHarness(t *testing.T, configure ...func(*Config)) *e2eHarness {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	llm := newMockLLM(t)
+	cfg := DefaultConfig()
+	cfg.Model = "gpt-4o"
+	cfg.API.BaseURL = llm.srv.URL
+	cfg.API.APIKey = "key"
+	cfg.ModelsFile = ""
+	cfg.Access.Owners = []string{e2eOwner}
+	cfg.Memory.Path = filepath.Join(dir, "data", "memory.db")
+	cfg.Memory.Embedding.Provider = "none"
+	cfg.Memory.Index.Auto = false
+	cfg.Database.Path = filepath.Join(dir, "data", "devclaw.db")
+	cfg.Security.ToolGuard.AuditLogPath = filepath.Join(dir, "data", "audit.log")
+	cfg.Skills.Builtin = nil
+	cfg.Scheduler.Enabled = false
+	cfg.Heartbeat.Enabled = false
+	cfg.Warmup.Enabled = false
+	cfg.BlockStream.Enabled = false
+	cfg.Queue.DebounceMs = 10
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := New(cfg, logger)
+	ch := &e2eChannel{}
+	if err := a.channelMgr.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Stop)
+
+	return &e2eHarness{t: t, A: a, LLM: llm, Ch: ch}
+}
+
+// RegisterTool adds a tool that records its calls and returns result.
+func (h *e2eHarness) RegisterTool(name, result string) {
+	h.A.toolExecutor.Register(
+		MakeToolDefinition(name, "Test tool "+name, map[string]any{"type": "object"}),
+		func(_ context.Context, args map[string]any) (any, error) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.execs = append(h.execs, toolExecution{Tool: name, Args: args})
+			return result, nil
+		})
+}
+
+// Executions returns the calls of harness-registered tools.
+func (h *e2eHarness) Executions() []toolExecution {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]toolExecution(nil), h.execs...)
+}
+
+// Send delivers a DM from the owner through the full pipeline and returns
+// the messages the bot sent in response.
+func (h *e2eHarness) Send(content string) []string {
+	return h.SendFrom(e2eOwner, content)
+}
+
+// SendFrom is Send for another sender.
+func (h *e2eHarness) SendFrom(from, content string) []string {
+	h.t.Helper()
+	h.mu.Lock()
+	h.msgN++
+	id := fmt.Sprintf("msg-%d", h.msgN)
+	h.mu.Unlock()
+
+	before := len(h.Ch.messages())
+	h.A.handleMessage(&channels.IncomingMessage{
+		ID:        id,
+		Channel:   e2eChannelName,
+		ChatID:    e2eChatID,
+		From:      from,
+		Type:      channels.MessageText,
+		Content:   content,
+		Timestamp: time.Now(),
+	})
+	return h.Ch.messages()[before:]
+}
+
+// Audit returns the tool audit entries, oldest first.
+func (h *e2eHarness) Audit() []AuditRecord {
+	audit := h.A.toolExecutor.Guard().SQLiteAudit()
+	if audit == nil {
+		h.t.Fatal("audit log not backed by devclaw.db")
+	}
+	records := audit.RecentRecords(1000)
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records
+}
+
+// Session returns the chat's session.
+func (h *e2eHarness) Session() *Session {
+	return h.A.workspaceMgr.Resolve(e2eChannelName, e2eChatID, e2eOwner, false).Session
+}
+
+func TestE2E_TextReply(t *testing.T) {
+	h := newE2EHarness(t)
+	h.LLM.Reply("Hello from the mock.")
+
+	replies := h.Send("hi there")
+	if len(replies) == 0 || !strings.Contains(replies[len(replies)-1], "Hello from the mock.") {
+		t.Fatalf("replies = %q", replies)
+	}
+	reqs := h.LLM.Requests()
+	if len(reqs) == 0 || !strings.Contains(reqs[0], "hi there") {
+		t.Fatalf("the user message should reach the LLM, got %d requests", len(reqs))
+	}
+	if n := len(h.Session().RecentHistory(10)); n == 0 {
+		t.Error("the exchange should be stored in the session")
+	}
+}
+
+func TestE2E_ToolCallIsExecutedAndAudited(t *testing.T) {
+	h := newE2EHarness(t)
+	h.RegisterTool("lookup_order", "order 42: shipped")
+	h.LLM.CallTool("lookup_order", map[string]any{"id": "42"})
+	h.LLM.Reply("Your order has shipped.")
+
+	replies := h.Send("where is order 42?")
+	if len(replies) == 0 || !strings.Contains(replies[len(replies)-1], "Your order has shipped.") {
+		t.Fatalf("replies = %q", replies)
+	}
+	execs := h.Executions()
+	if len(execs) != 1 || execs[0].Args["id"] != "42" {
+		t.Fatalf("executions = %+v", execs)
+	}
+	if reqs := h.LLM.Requests(); !strings.Contains(reqs[len(reqs)-1], "order 42: shipped") {
+		t.Error("the tool result should be sent back to the LLM")
+	}
+
+	var audited bool
+	for _, r := range h.Audit() {
+		if r.Tool == "lookup_order" && r.Allowed && r.Caller == e2eOwner {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Errorf("tool call not audited: %+v", h.Audit())
+	}
+}
+
+func TestE2E_ReadOnlySessionBlocksTools(t *testing.T) {
+	h := newE2EHarness(t)
+	h.RegisterTool("write_file", "written")
+
+	if replies := h.Send("/readonly on"); len(replies) != 1 || !strings.Contains(replies[0], "Read-only mode: on") {
+		t.Fatalf("/readonly replies = %q", replies)
+	}
+	if !h.Session().GetConfig().ReadOnly {
+		t.Fatal("the session should be read-only")
+	}
+
+	h.LLM.CallTool("write_file", map[string]any{"path": "notes.txt", "content": "x"})
+	h.LLM.Reply("I can't write files here.")
+	h.Send("save a note")
+
+	if execs := h.Executions(); len(execs) != 0 {
+		t.Fatalf("a write ran in a read-only session: %+v", execs)
+	}
+	if reqs := h.LLM.Requests(); !strings.Contains(reqs[len(reqs)-1], "read-only") {
+		t.Error("the LLM should be told the call was blocked")
+	}
+}
+
+func TestE2E_UnknownSenderIgnored(t *testing.T) {
+	h := newE2EHarness(t)
+
+	if replies := h.SendFrom("stranger@e2e", "hello?"); len(replies) != 0 {
+		t.Fatalf("an unknown sender got replies: %q", replies)
+	}
+	if n := len(h.LLM.Requests()); n != 0 {
+		t.Fatalf("an unknown sender reached the LLM (%d requests)", n)
+	}
+}
@@ -0,0 +1,481 @@
+// Package copilot – editor_proxy.go implements the backend for editor
+// extensions (`devclaw lsp-proxy`). It speaks JSON-RPC 2.0 over stdio with
+// LSP-style Content-Length framing, so any LSP client library can drive it,
+// and exposes a few editor-shaped requests (explain selection, fix
+// diagnostics, generate tests). Results are streamed back as notifications
+// while the agent runs, keeping the editor plugin itself a thin shell.
+package copilot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Editor proxy JSON-RPC methods.
+const (
+	EditorMethodExplainSelection = "devclaw/explainSelection"
+	EditorMethodFixDiagnostics   = "devclaw/fixDiagnostics"
+	EditorMethodGenerateTests    = "devclaw/generateTests"
+	EditorMethodCancel           = "devclaw/cancel"
+	EditorMethodResetSession     = "devclaw/resetSession"
+
+	// EditorNotifyStream is sent by the proxy for every text delta of a
+	// running request. Params: {"id": <request id>, "delta": "..."}.
+	EditorNotifyStream = "devclaw/stream"
+)
+
+// editorSessionChannel is the session channel used for editor requests.
+// Sessions are keyed by project root so every request from the same
+// workspace shares history.
+const editorSessionChannel = "editor"
+
+// maxEditorFrameSize caps the body of one framed message, so a bad or
+// hostile Content-Length can't make the proxy allocate unbounded memory.
+const maxEditorFrameSize = 16 << 20
+
+// EditorRange is an LSP-compatible range (zero-based lines and characters).
+type EditorRange struct {
+	Start EditorPosition `json:"start"`
+	End   EditorPosition `json:"end"`
+}
+
+// EditorPosition is an LSP-compatible position.
+type EditorPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// EditorDiagnostic is the subset of an LSP diagnostic the proxy uses.
+type EditorDiagnostic struct {
+	Range    EditorRange `json:"range"`
+	Severity int         `json:"severity,omitempty"`
+	Source   string      `json:"source,omitempty"`
+	Message  string      `json:"message"`
+}
+
+// EditorRequest carries the parameters shared by all editor methods.
+type EditorRequest struct {
+	// Path is the file path (or file:// URI) the request refers to.
+	Path string `json:"path"`
+	// Language is the editor language id (go, typescript, python...).
+	Language string `json:"language,omitempty"`
+	// Text is the selected text, or the whole document when nothing is selected.
+	Text string `json:"text"`
+	// Range locates Text inside the document, when known.
+	Range *EditorRange `json:"range,omitempty"`
+	// Diagnostics is used by fixDiagnostics.
+	Diagnostics []EditorDiagnostic `json:"diagnostics,omitempty"`
+	// Framework is an optional test framework hint for generateTests.
+	Framework string `json:"framework,omitempty"`
+	// Instructions are extra free-form instructions from the user.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// EditorResult is returned when an editor request completes.
+type EditorResult struct {
+	Text    string `json:"text"`
+	Session string `json:"session"`
+}
+
+// editorRunFunc runs a prompt in the session for the given project root,
+// forwarding deltas to onDelta, and returns the final response.
+type editorRunFunc func(ctx context.Context, root, prompt string, onDelta StreamCallback) (string, error)
+
+// EditorProxy serves editor extension requests over a JSON-RPC stream.
+type EditorProxy struct {
+	run    editorRunFunc
+	reset  func(root string)
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	root    string
+	pending map[string]context.CancelFunc
+
+	writeMu sync.Mutex
+	w       io.Writer
+}
+
+// NewEditorProxy creates an editor proxy backed by the assistant. The
+// default project root is the current working directory; editors can
+// override it with rootUri/rootPath in "initialize".
+func NewEditorProxy(a *Assistant, logger *slog.Logger) *EditorProxy {
+	p := newEditorProxy(func(ctx context.Context, root, prompt string, onDelta StreamCallback) (string, error) {
+		session := a.SessionStore().GetOrCreate(editorSessionChannel, root)
+		systemPrompt := a.ComposePrompt(session, prompt)
+		response := a.ExecuteAgentStream(ctx, systemPrompt, session, prompt, onDelta)
+		if ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		session.AddMessage(prompt, response)
+		return response, nil
+	}, logger)
+	p.reset = func(root string) {
+		a.SessionStore().Delete(editorSessionChannel, root)
+	}
+	return p
+}
+
+func newEditorProxy(run editorRunFunc, logger *slog.Logger) *EditorProxy {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	root, _ := os.Getwd()
+	return &EditorProxy{
+		run:     run,
+		logger:  logger.With("component", "editor-proxy"),
+		root:    root,
+		pending: make(map[string]context.CancelFunc),
+	}
+}
+
+// editorMessage is a JSON-RPC request, response or notification.
+type editorMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *editorRPCError `json:"error,omitempty"`
+}
+
+type editorRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC / LSP error codes used by the proxy.
+const (
+	editorErrParse          = -32700
+	editorErrInvalidParams  = -32602
+	editorErrMethodNotFound = -32601
+	editorErrInternal       = -32603
+	editorErrCancelled      = -32800
+)
+
+// Serve reads framed JSON-RPC messages from r and writes replies to w until
+// the input closes, "exit" is received, or ctx is cancelled. Agent requests
+// run concurrently so "devclaw/cancel" can interrupt them.
+func (p *EditorProxy) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	p.w = w
+	reader := bufio.NewReader(r)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		body, err := readEditorFrame(reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("reading editor frame: %w", err)
+		}
+
+		var msg editorMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			p.replyError(nil, editorErrParse, "parse error")
+			continue
+		}
+
+		switch msg.Method {
+		case "initialize":
+			p.handleInitialize(&msg)
+		case "initialized":
+		case "$/cancelRequest":
+			p.handleCancel(&msg)
+		case "shutdown":
+			p.cancelAll()
+			p.reply(msg.ID, nil)
+		case "exit":
+			return nil
+		case EditorMethodCancel:
+			p.handleCancel(&msg)
+			if msg.ID != nil {
+				p.reply(msg.ID, map[string]any{"ok": true})
+			}
+		case EditorMethodResetSession:
+			p.mu.Lock()
+			root := p.root
+			p.mu.Unlock()
+			if p.reset != nil {
+				p.reset(root)
+			}
+			p.reply(msg.ID, map[string]any{"ok": true})
+		case EditorMethodExplainSelection, EditorMethodFixDiagnostics, EditorMethodGenerateTests:
+			wg.Add(1)
+			go func(m editorMessage) {
+				defer wg.Done()
+				p.handleAgentRequest(ctx, &m)
+			}(msg)
+		default:
+			if msg.ID != nil {
+				p.replyError(msg.ID, editorErrMethodNotFound, "method not found: "+msg.Method)
+			}
+		}
+	}
+}
+
+func (p *EditorProxy) handleInitialize(msg *editorMessage) {
+	var params struct {
+		RootURI  string `json:"rootUri"`
+		RootPath string `json:"rootPath"`
+	}
+	_ = json.Unmarshal(msg.Params, &params)
+
+	root := params.RootPath
+	if params.RootURI != "" {
+		root = uriToPath(params.RootURI)
+	}
+	if root != "" {
+		p.mu.Lock()
+		p.root = filepath.Clean(root)
+		p.mu.Unlock()
+	}
+
+	p.reply(msg.ID, map[string]any{
+		"capabilities": map[string]any{
+			"devclaw": map[string]any{
+				"methods": []string{
+					EditorMethodExplainSelection,
+					EditorMethodFixDiagnostics,
+					EditorMethodGenerateTests,
+					EditorMethodCancel,
+					EditorMethodResetSession,
+				},
+				"streaming": EditorNotifyStream,
+			},
+		},
+		"serverInfo": map[string]any{"name": "devclaw-lsp-proxy", "version": "1.0.0"},
+	})
+}
+
+func (p *EditorProxy) handleCancel(msg *editorMessage) {
+	var params struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil || params.ID == nil {
+		return
+	}
+	p.mu.Lock()
+	cancel, ok := p.pending[string(params.ID)]
+	p.mu.Unlock()
+	if ok {
+		cancel()
+	}
+}
+
+func (p *EditorProxy) cancelAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, cancel := range p.pending {
+		cancel()
+	}
+}
+
+func (p *EditorProxy) handleAgentRequest(ctx context.Context, msg *editorMessage) {
+	var req EditorRequest
+	if err := json.Unmarshal(msg.Params, &req); err != nil {
+		p.replyError(msg.ID, editorErrInvalidParams, "invalid params: "+err.Error())
+		return
+	}
+	req.Path = uriToPath(req.Path)
+
+	prompt, err := buildEditorPrompt(msg.Method, req)
+	if err != nil {
+		p.replyError(msg.ID, editorErrInvalidParams, err.Error())
+		return
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	key := string(msg.ID)
+	p.mu.Lock()
+	p.pending[key] = cancel
+	root := p.root
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, key)
+		p.mu.Unlock()
+		cancel()
+	}()
+
+	p.logger.Info("editor request", "method", msg.Method, "path", req.Path)
+
+	onDelta := func(chunk string) {
+		if chunk == "" {
+			return
+		}
+		p.notify(EditorNotifyStream, map[string]any{"id": msg.ID, "delta": chunk})
+	}
+
+	text, err := p.run(runCtx, root, prompt, onDelta)
+	if err != nil {
+		if runCtx.Err() != nil {
+			p.replyError(msg.ID, editorErrCancelled, "request cancelled")
+			return
+		}
+		p.replyError(msg.ID, editorErrInternal, err.Error())
+		return
+	}
+
+	p.reply(msg.ID, EditorResult{Text: text, Session: root})
+}
+
+// buildEditorPrompt turns an editor request into an agent prompt.
+func buildEditorPrompt(method string, req EditorRequest) (string, error) {
+	if strings.TrimSpace(req.Text) == "" {
+		return "", fmt.Errorf("text is required")
+	}
+
+	var sb strings.Builder
+	switch method {
+	case EditorMethodExplainSelection:
+		sb.WriteString("Explain this code from my editor — what it does, why, and anything surprising. Be concise.\n\n")
+	case EditorMethodFixDiagnostics:
+		if len(req.Diagnostics) == 0 {
+			return "", fmt.Errorf("diagnostics are required")
+		}
+		sb.WriteString("Fix the following diagnostics reported by my editor. Reply with the corrected code and a short explanation of each fix.\n\nDiagnostics:\n")
+		for _, d := range req.Diagnostics {
+			source := ""
+			if d.Source != "" {
+				source = " [" + d.Source + "]"
+			}
+			fmt.Fprintf(&sb, "- line %d:%d%s %s\n", d.Range.Start.Line+1, d.Range.Start.Character+1, source, d.Message)
+		}
+		sb.WriteString("\n")
+	case EditorMethodGenerateTests:
+		sb.WriteString("Generate unit tests for this code following the project's existing test conventions. Reply with the test code only, plus the file it belongs in.")
+		if req.Framework != "" {
+			fmt.Fprintf(&sb, " Use %s.", req.Framework)
+		}
+		sb.WriteString("\n\n")
+	default:
+		return "", fmt.Errorf("unsupported method: %s", method)
+	}
+
+	if req.Path != "" {
+		fmt.Fprintf(&sb, "File: %s", req.Path)
+		if req.Range != nil {
+			fmt.Fprintf(&sb, " (lines %d-%d)", req.Range.Start.Line+1, req.Range.End.Line+1)
+		}
+		sb.WriteString("\n")
+	}
+	if req.Instructions != "" {
+		fmt.Fprintf(&sb, "Instructions: %s\n", req.Instructions)
+	}
+	fmt.Fprintf(&sb, "```%s\n%s\n```", req.Language, strings.TrimRight(req.Text, "\n"))
+	return sb.String(), nil
+}
+
+// uriToPath converts a file:// URI to a local path. Other values are
+// returned unchanged.
+func uriToPath(s string) string {
+	if !strings.HasPrefix(s, "file://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return strings.TrimPrefix(s, "file://")
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (p *EditorProxy) reply(id json.RawMessage, result any) {
+	if result == nil {
+		result = json.RawMessage("null")
+	}
+	p.write(editorMessage{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (p *EditorProxy) replyError(id json.RawMessage, code int, message string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	p.write(editorMessage{JSONRPC: "2.0", ID: id, Error: &editorRPCError{Code: code, Message: message}})
+}
+
+func (p *EditorProxy) notify(method string, params any) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	p.write(editorMessage{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+func (p *EditorProxy) write(msg editorMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		p.logger.Error("encoding editor message", "error", err)
+		return
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if err := writeEditorFrame(p.w, data); err != nil {
+		p.logger.Error("writing editor message", "error", err)
+	}
+}
+
+// readEditorFrame reads one Content-Length framed message body.
+func readEditorFrame(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if length < 0 {
+				continue
+			}
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+			if n > maxEditorFrameSize {
+				return nil, fmt.Errorf("frame of %d bytes exceeds the %d byte limit", n, maxEditorFrameSize)
+			}
+			length = n
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeEditorFrame writes a Content-Length framed message.
+func writeEditorFrame(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
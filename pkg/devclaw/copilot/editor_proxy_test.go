@@ -0,0 +1,156 @@
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func editorFrames(t *testing.T, msgs ...map[string]any) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeEditorFrame(&buf, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func readEditorMessages(t *testing.T, out *bytes.Buffer) []editorMessage {
+	t.Helper()
+	r := bufio.NewReader(out)
+	var msgs []editorMessage
+	for {
+		body, err := readEditorFrame(r)
+		if err != nil {
+			break
+		}
+		var m editorMessage
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatalf("bad frame %q: %v", body, err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func TestEditorProxy_StreamsAndReusesProjectSession(t *testing.T) {
+	t.Parallel()
+	var roots []string
+	proxy := newEditorProxy(func(_ context.Context, root, prompt string, onDelta StreamCallback) (string, error) {
+		roots = append(roots, root)
+		onDelta("part one ")
+		onDelta("part two")
+		return "part one part two", nil
+	}, nil)
+
+	in := editorFrames(t,
+		map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{"rootUri": "file:///home/dev/project"}},
+		map[string]any{"jsonrpc": "2.0", "id": 2, "method": EditorMethodExplainSelection, "params": map[string]any{"path": "file:///home/dev/project/main.go", "text": "func main() {}"}},
+		map[string]any{"jsonrpc": "2.0", "method": "exit"},
+	)
+	var out bytes.Buffer
+	if err := proxy.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	msgs := readEditorMessages(t, &out)
+	var deltas []string
+	var final *editorMessage
+	for i, m := range msgs {
+		if m.Method == EditorNotifyStream {
+			var p struct{ Delta string }
+			json.Unmarshal(m.Params, &p)
+			deltas = append(deltas, p.Delta)
+		}
+		if string(m.ID) == "2" {
+			final = &msgs[i]
+		}
+	}
+	if strings.Join(deltas, "") != "part one part two" {
+		t.Errorf("streamed deltas = %q", deltas)
+	}
+	if final == nil || final.Error != nil {
+		t.Fatalf("missing successful response for request 2: %+v", final)
+	}
+	if len(roots) != 1 || roots[0] != "/home/dev/project" {
+		t.Errorf("session root = %v, want /home/dev/project", roots)
+	}
+}
+
+func TestEditorProxy_UnknownMethodAndBadParams(t *testing.T) {
+	t.Parallel()
+	proxy := newEditorProxy(func(context.Context, string, string, StreamCallback) (string, error) {
+		return "", nil
+	}, nil)
+
+	in := editorFrames(t,
+		map[string]any{"jsonrpc": "2.0", "id": 1, "method": "devclaw/nope"},
+		map[string]any{"jsonrpc": "2.0", "id": 2, "method": EditorMethodFixDiagnostics, "params": map[string]any{"text": "x := 1"}},
+	)
+	var out bytes.Buffer
+	if err := proxy.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	codes := map[string]int{}
+	for _, m := range readEditorMessages(t, &out) {
+		if m.Error != nil {
+			codes[string(m.ID)] = m.Error.Code
+		}
+	}
+	if codes["1"] != editorErrMethodNotFound {
+		t.Errorf("unknown method code = %d", codes["1"])
+	}
+	if codes["2"] != editorErrInvalidParams {
+		t.Errorf("missing diagnostics code = %d", codes["2"])
+	}
+}
+
+func TestReadEditorFrame_RejectsOversizedFrames(t *testing.T) {
+	t.Parallel()
+	for _, header := range []string{
+		fmt.Sprintf("Content-Length: %d\r\n\r\n", maxEditorFrameSize+1),
+		"Content-Length: 99999999999999999999\r\n\r\n",
+		"Content-Length: -1\r\n\r\n",
+	} {
+		if _, err := readEditorFrame(bufio.NewReader(strings.NewReader(header))); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+
+	body := `{"jsonrpc":"2.0"}`
+	frame := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	if got, err := readEditorFrame(bufio.NewReader(strings.NewReader(frame))); err != nil || string(got) != body {
+		t.Errorf("readEditorFrame = %q, %v", got, err)
+	}
+}
+
+func TestBuildEditorPrompt_IncludesDiagnostics(t *testing.T) {
+	t.Parallel()
+	prompt, err := buildEditorPrompt(EditorMethodFixDiagnostics, EditorRequest{
+		Path:     "main.go",
+		Language: "go",
+		Text:     "x := 1\n",
+		Diagnostics: []EditorDiagnostic{
+			{Range: EditorRange{Start: EditorPosition{Line: 4, Character: 1}}, Source: "compiler", Message: "x declared and not used"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"line 5:2 [compiler] x declared and not used", "File: main.go", "```go\nx := 1\n```"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
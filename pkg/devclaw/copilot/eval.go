@@ -0,0 +1,390 @@
+// Package copilot – eval.go runs a suite of prompts against several models
+// and reports their answers, latency, tokens and cost side by side, so the
+// default model can be chosen with data.
+//
+// A suite is a YAML file:
+//
+//	name: support triage
+//	system: You are a support assistant for ACME.
+//	tools:                       # optional, always mocked
+//	  - name: lookup_order
+//	    description: Look up an order by id
+//	    parameters: {type: object, properties: {id: {type: string}}}
+//	    result: '{"status": "shipped"}'
+//	prompts:
+//	  - name: refund
+//	    prompt: Order 42 arrived broken, what can I do?
+//	    expect: [refund]         # optional, case-insensitive substrings
+//
+// Tools are never executed: every call gets the tool's mocked result, and
+// the model may call tools for up to max_tool_rounds rounds. Each model is
+// called on its own endpoint — the fallback provider that lists it, else the
+// primary one — without fallback, so a failure is reported, not hidden.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EvalSuite is a set of prompts to compare models on.
+type EvalSuite struct {
+	Name          string       `yaml:"name" json:"name"`
+	System        string       `yaml:"system" json:"system,omitempty"`
+	Tools         []EvalTool   `yaml:"tools" json:"tools,omitempty"`
+	Prompts       []EvalPrompt `yaml:"prompts" json:"prompts"`
+	MaxToolRounds int          `yaml:"max_tool_rounds" json:"max_tool_rounds,omitempty"`
+}
+
+// EvalPrompt is one case of a suite.
+type EvalPrompt struct {
+	Name   string   `yaml:"name" json:"name"`
+	Prompt string   `yaml:"prompt" json:"prompt"`
+	Expect []string `yaml:"expect" json:"expect,omitempty"`
+}
+
+// EvalTool is a mocked tool offered to the models.
+type EvalTool struct {
+	Name        string         `yaml:"name" json:"name"`
+	Description string         `yaml:"description" json:"description"`
+	Parameters  map[string]any `yaml:"parameters" json:"parameters,omitempty"`
+	Result      string         `yaml:"result" json:"result"`
+}
+
+// defaultEvalToolRounds bounds the tool loop of a case.
+const defaultEvalToolRounds = 5
+
+// LoadEvalSuite reads and validates a suite file.
+func LoadEvalSuite(path string) (*EvalSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var suite EvalSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(suite.Prompts) == 0 {
+		return nil, fmt.Errorf("%s has no prompts", path)
+	}
+	for i := range suite.Prompts {
+		p := &suite.Prompts[i]
+		if strings.TrimSpace(p.Prompt) == "" {
+			return nil, fmt.Errorf("%s: prompt %d is empty", path, i+1)
+		}
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("prompt-%d", i+1)
+		}
+	}
+	for _, t := range suite.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("%s: tool without a name", path)
+		}
+	}
+	if suite.Name == "" {
+		suite.Name = path
+	}
+	if suite.MaxToolRounds <= 0 {
+		suite.MaxToolRounds = defaultEvalToolRounds
+	}
+	return &suite, nil
+}
+
+// toolDefinitions returns the suite's mocked tools in API format.
+func (s *EvalSuite) toolDefinitions() ([]ToolDefinition, error) {
+	defs := make([]ToolDefinition, 0, len(s.Tools))
+	for _, t := range s.Tools {
+		params := t.Parameters
+		if params == nil {
+			params = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		defs = append(defs, ToolDefinition{
+			Type:     "function",
+			Function: FunctionDef{Name: t.Name, Description: t.Description, Parameters: raw},
+		})
+	}
+	return defs, nil
+}
+
+// mockResult returns the canned output of a tool call.
+func (s *EvalSuite) mockResult(name string) string {
+	for _, t := range s.Tools {
+		if t.Name == name {
+			if t.Result == "" {
+				return `{"ok": true}`
+			}
+			return t.Result
+		}
+	}
+	return fmt.Sprintf("Error: unknown tool %q", name)
+}
+
+// EvalResult is the outcome of one prompt on one model.
+type EvalResult struct {
+	Model     string        `json:"model"`
+	Prompt    string        `json:"prompt"`
+	Response  string        `json:"response"`
+	ToolCalls []string      `json:"tool_calls,omitempty"`
+	Latency   time.Duration `json:"latency_ns"`
+	Usage     LLMUsage      `json:"usage"`
+	CostUSD   float64       `json:"cost_usd"`
+	Passed    *bool         `json:"passed,omitempty"` // nil when the prompt has no expectations
+	Error     string        `json:"error,omitempty"`
+}
+
+// EvalModelSummary aggregates a model's results.
+type EvalModelSummary struct {
+	Model        string        `json:"model"`
+	Cases        int           `json:"cases"`
+	Errors       int           `json:"errors"`
+	Passed       int           `json:"passed"`
+	Checked      int           `json:"checked"` // cases with expectations
+	AvgLatency   time.Duration `json:"avg_latency_ns"`
+	MaxLatency   time.Duration `json:"max_latency_ns"`
+	TotalTokens  int           `json:"total_tokens"`
+	TotalCostUSD float64       `json:"total_cost_usd"`
+}
+
+// EvalReport is the result of comparing models on a suite.
+type EvalReport struct {
+	Suite     string             `json:"suite"`
+	Models    []string           `json:"models"`
+	StartedAt time.Time          `json:"started_at"`
+	Duration  time.Duration      `json:"duration_ns"`
+	Prompts   []EvalPrompt       `json:"prompts"`
+	Results   [][]EvalResult     `json:"results"` // [prompt][model]
+	Summary   []EvalModelSummary `json:"summary"`
+}
+
+// CompareModels runs every prompt of suite on every model. Models run in
+// parallel, each working through the prompts in order.
+func CompareModels(ctx context.Context, cfg *Config, suite *EvalSuite, models []string, caseTimeout time.Duration, logger *slog.Logger) (*EvalReport, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to compare")
+	}
+	tools, err := suite.toolDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewLLMClient(cfg, logger)
+	tracker := NewUsageTracker(logger)
+	if registry, err := LoadModelRegistry(cfg.ModelsFile); err != nil {
+		logger.Warn("models registry not loaded", "error", err)
+	} else {
+		client.SetModelRegistry(registry)
+		tracker.SetModelRegistry(registry)
+	}
+
+	report := &EvalReport{
+		Suite:     suite.Name,
+		Models:    models,
+		StartedAt: time.Now(),
+		Prompts:   suite.Prompts,
+		Results:   make([][]EvalResult, len(suite.Prompts)),
+	}
+	for i := range report.Results {
+		report.Results[i] = make([]EvalResult, len(models))
+	}
+
+	var wg sync.WaitGroup
+	for m, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			endpoint := client.endpointFor(model)
+			for p, prompt := range suite.Prompts {
+				caseCtx, cancel := context.WithTimeout(ctx, caseTimeout)
+				res := runEvalCase(caseCtx, endpoint, model, suite, prompt, tools)
+				cancel()
+				res.CostUSD = tracker.EstimateCost(model, res.Usage)
+				report.Results[p][m] = res
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(report.StartedAt)
+	report.Summary = summarizeEval(models, report.Results)
+	return report, nil
+}
+
+// endpointFor returns the client serving model: the fallback provider that
+// lists it, or the primary endpoint.
+func (c *LLMClient) endpointFor(model string) *LLMClient {
+	for _, t := range c.chain {
+		if t.model == model {
+			return t.client
+		}
+	}
+	return c
+}
+
+// runEvalCase sends one prompt to one model, answering tool calls with the
+// mocked results until the model gives a final answer.
+func runEvalCase(ctx context.Context, client *LLMClient, model string, suite *EvalSuite, prompt EvalPrompt, tools []ToolDefinition) EvalResult {
+	res := EvalResult{Model: model, Prompt: prompt.Name}
+	var messages []chatMessage
+	if suite.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: suite.System})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt.Prompt})
+
+	start := time.Now()
+	defer func() { res.Latency = time.Since(start) }()
+
+	for round := 0; ; round++ {
+		offered := tools
+		if round >= suite.MaxToolRounds {
+			offered = nil // force a final answer
+		}
+		resp, err := client.completeOnce(ctx, model, messages, offered)
+		if err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		res.Usage.PromptTokens += resp.Usage.PromptTokens
+		res.Usage.CompletionTokens += resp.Usage.CompletionTokens
+		res.Usage.TotalTokens += resp.Usage.TotalTokens
+		res.Usage.CacheReadTokens += resp.Usage.CacheReadTokens
+		res.Usage.CacheWriteTokens += resp.Usage.CacheWriteTokens
+
+		if len(resp.ToolCalls) == 0 || offered == nil {
+			res.Response = resp.Content
+			break
+		}
+		messages = append(messages, chatMessage{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, tc := range resp.ToolCalls {
+			res.ToolCalls = append(res.ToolCalls, tc.Function.Name)
+			messages = append(messages, chatMessage{Role: "tool", ToolCallID: tc.ID, Content: suite.mockResult(tc.Function.Name)})
+		}
+	}
+
+	if len(prompt.Expect) > 0 {
+		passed := true
+		lower := strings.ToLower(res.Response)
+		for _, e := range prompt.Expect {
+			if !strings.Contains(lower, strings.ToLower(e)) {
+				passed = false
+				break
+			}
+		}
+		res.Passed = &passed
+	}
+	return res
+}
+
+// summarizeEval aggregates results per model.
+func summarizeEval(models []string, results [][]EvalResult) []EvalModelSummary {
+	summary := make([]EvalModelSummary, len(models))
+	for m, model := range models {
+		s := EvalModelSummary{Model: model}
+		var total time.Duration
+		for _, row := range results {
+			r := row[m]
+			s.Cases++
+			total += r.Latency
+			if r.Latency > s.MaxLatency {
+				s.MaxLatency = r.Latency
+			}
+			s.TotalTokens += r.Usage.TotalTokens
+			s.TotalCostUSD += r.CostUSD
+			if r.Error != "" {
+				s.Errors++
+			}
+			if r.Passed != nil {
+				s.Checked++
+				if *r.Passed {
+					s.Passed++
+				}
+			}
+		}
+		if s.Cases > 0 {
+			s.AvgLatency = total / time.Duration(s.Cases)
+		}
+		summary[m] = s
+	}
+	return summary
+}
+
+// Markdown renders the report: a summary table, then each prompt with the
+// models' answers side by side. Answers longer than maxChars are cut.
+func (r *EvalReport) Markdown(maxChars int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Model comparison: %s\n\n", r.Suite)
+	fmt.Fprintf(&b, "%d prompt(s) × %d model(s), run %s in %s.\n\n",
+		len(r.Prompts), len(r.Models), r.StartedAt.Format("2006-01-02 15:04"), r.Duration.Round(time.Second))
+
+	b.WriteString("| Model | Pass | Errors | Avg latency | Max latency | Tokens | Cost |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, s := range r.rankedSummary() {
+		pass := "-"
+		if s.Checked > 0 {
+			pass = fmt.Sprintf("%d/%d", s.Passed, s.Checked)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %s | %s | %d | $%.4f |\n",
+			s.Model, pass, s.Errors, s.AvgLatency.Round(time.Millisecond), s.MaxLatency.Round(time.Millisecond), s.TotalTokens, s.TotalCostUSD)
+	}
+
+	for p, prompt := range r.Prompts {
+		fmt.Fprintf(&b, "\n## %s\n\n> %s\n\n", prompt.Name, strings.ReplaceAll(strings.TrimSpace(prompt.Prompt), "\n", "\n> "))
+		b.WriteString("| Model | Latency | Tokens | Cost | Tools | Response |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, res := range r.Results[p] {
+			answer := res.Response
+			if res.Error != "" {
+				answer = "**error:** " + res.Error
+			}
+			if maxChars > 0 && len(answer) > maxChars {
+				answer = truncateUTF8(answer, maxChars) + "…"
+			}
+			if res.Passed != nil {
+				mark := "✅ "
+				if !*res.Passed {
+					mark = "❌ "
+				}
+				answer = mark + answer
+			}
+			fmt.Fprintf(&b, "| %s | %s | %d | $%.4f | %s | %s |\n",
+				res.Model, res.Latency.Round(time.Millisecond), res.Usage.TotalTokens, res.CostUSD,
+				strings.Join(res.ToolCalls, ", "), markdownCell(answer))
+		}
+	}
+	return b.String()
+}
+
+// rankedSummary orders models by passes minus errors, then cost, then
+// latency.
+func (r *EvalReport) rankedSummary() []EvalModelSummary {
+	ranked := append([]EvalModelSummary(nil), r.Summary...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Passed-a.Errors != b.Passed-b.Errors {
+			return a.Passed-a.Errors > b.Passed-b.Errors
+		}
+		if a.TotalCostUSD != b.TotalCostUSD {
+			return a.TotalCostUSD < b.TotalCostUSD
+		}
+		return a.AvgLatency < b.AvgLatency
+	})
+	return ranked
+}
+
+// markdownCell makes text safe for a single table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", "\\|")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
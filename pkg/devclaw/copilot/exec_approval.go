@@ -1,14 +1,18 @@
 // Package copilot – exec_approval.go implements interactive approval for tools
 // that require confirmation before execution (e.g. bash, ssh, write_file).
+// Prompts carry Approve/Deny buttons on channels that support them; a press
+// arrives as "/approve <id>" or "/deny <id>" from the user who pressed it.
 package copilot
 
 import (
 	"fmt"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
 )
 
 const (
@@ -17,10 +21,48 @@ const (
 	ApprovalTimeout = 120 * time.Second
 )
 
+// ApprovalConfig configures confirmation prompts.
+type ApprovalConfig struct {
+	// Timeout is how long a prompt waits for an answer (default: 2m).
+	Timeout time.Duration `yaml:"timeout"`
+
+	// OnTimeout is the answer assumed when nobody answers: "deny" (default)
+	// or "approve". A timed-out approval never grants session trust.
+	OnTimeout string `yaml:"on_timeout"`
+
+	// Buttons adds Approve/Deny buttons to prompts on channels that
+	// support them (default: true).
+	Buttons bool `yaml:"buttons"`
+
+	// Always approves matching calls without asking, as "tool: pattern"
+	// entries (e.g. "bash: git *"; see approval_grants.go).
+	Always []string `yaml:"always"`
+
+	// GrantsFile keeps the grants given with /approve across restarts
+	// (default: ./data/approval_grants.json).
+	GrantsFile string `yaml:"grants_file"`
+}
+
+// DefaultApprovalConfig returns the default approval config.
+func DefaultApprovalConfig() ApprovalConfig {
+	return ApprovalConfig{Timeout: ApprovalTimeout, OnTimeout: "deny", Buttons: true, GrantsFile: "./data/approval_grants.json"}
+}
+
 // ApprovalResult holds the outcome of an approval request.
 type ApprovalResult struct {
 	Approved bool
 	Reason   string
+
+	// Files selects the approved files of a diff review (indexes into
+	// PendingApproval.Files); nil approves all of them.
+	Files []int
+
+	// TimedOut is set when nobody answered and on_timeout decided.
+	TimedOut bool
+
+	// Scope is how far an approval reaches; ResolvedBy is who answered.
+	Scope      ApprovalScope
+	ResolvedBy string
 }
 
 // PendingApproval represents a tool call waiting for user approval.
@@ -33,6 +75,9 @@ type PendingApproval struct {
 	CallerJID   string
 	CreatedAt   time.Time
 	Result      chan ApprovalResult
+
+	// Files are the proposed file changes of a diff review (nil otherwise).
+	Files []ProposedFileChange
 }
 
 // ApprovalManager manages pending tool approvals and their resolution.
@@ -45,6 +90,11 @@ type ApprovalManager struct {
 	// key: "sessionID:toolName" → true means auto-approved for this session.
 	sessionTrust map[string]bool
 
+	// grants are the scoped grants given with /approve, kept in grantsPath.
+	grants     []ApprovalGrant
+	grantsPath string
+
+	cfg    ApprovalConfig
 	mu     sync.Mutex
 	logger *slog.Logger
 }
@@ -57,10 +107,38 @@ func NewApprovalManager(logger *slog.Logger) *ApprovalManager {
 	return &ApprovalManager{
 		pending:      make(map[string]*PendingApproval),
 		sessionTrust: make(map[string]bool),
+		cfg:          DefaultApprovalConfig(),
 		logger:       logger.With("component", "approval_manager"),
 	}
 }
 
+// SetConfig sets the timeout, timeout answer and button settings.
+func (m *ApprovalManager) SetConfig(cfg ApprovalConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = ApprovalTimeout
+	}
+	m.mu.Lock()
+	m.cfg = cfg
+	m.mu.Unlock()
+}
+
+// prompt builds the message asking for approval id, with Approve and Deny
+// buttons when enabled.
+func (m *ApprovalManager) prompt(id, text string) *channels.OutgoingMessage {
+	m.mu.Lock()
+	buttons := m.cfg.Buttons
+	m.mu.Unlock()
+	msg := &channels.OutgoingMessage{Content: text}
+	if buttons {
+		msg.Buttons = []channels.Button{
+			{Label: "Approve once", Data: "/approve " + id + " once", Style: channels.ButtonPrimary},
+			{Label: "This session", Data: "/approve " + id + " session", Style: channels.ButtonSuccess},
+			{Label: "Deny", Data: "/deny " + id, Style: channels.ButtonDanger},
+		}
+	}
+	return msg
+}
+
 // Create creates a pending approval and returns the ID and message for the user.
 // The caller should send the message to the chat, then call Wait to block for the result.
 func (m *ApprovalManager) Create(sessionID, callerJID, toolName string, args map[string]any) (id string, message string) {
@@ -82,7 +160,7 @@ func (m *ApprovalManager) Create(sessionID, callerJID, toolName string, args map
 	m.pending[id] = pa
 	m.mu.Unlock()
 
-	message = fmt.Sprintf("⚠️ Approval required: %s\n\nReply /approve %s or /deny %s", desc, id, id)
+	message = fmt.Sprintf("⚠️ Approval required: %s\n\nReply /approve %s [once|session|30m|always] or /deny %s", desc, id, id)
 
 	m.logger.Info("approval created",
 		"id", id,
@@ -96,12 +174,19 @@ func (m *ApprovalManager) Create(sessionID, callerJID, toolName string, args map
 // Wait blocks until the approval is resolved or times out.
 // Must be called after Create. Removes the pending approval when done.
 func (m *ApprovalManager) Wait(id string) (approved bool, err error) {
+	res, err := m.waitResult(id)
+	return res.Approved, err
+}
+
+// waitResult is Wait returning the full result.
+func (m *ApprovalManager) waitResult(id string) (ApprovalResult, error) {
 	m.mu.Lock()
 	pa, ok := m.pending[id]
+	cfg := m.cfg
 	m.mu.Unlock()
 
 	if !ok {
-		return false, fmt.Errorf("approval not found: %s", id)
+		return ApprovalResult{}, fmt.Errorf("approval not found: %s", id)
 	}
 
 	defer func() {
@@ -114,24 +199,77 @@ func (m *ApprovalManager) Wait(id string) (approved bool, err error) {
 	case res := <-pa.Result:
 		if res.Approved {
 			m.logger.Info("approval granted", "id", id, "tool", pa.ToolName)
-			return true, nil
+			return res, nil
 		}
 		m.logger.Info("approval denied", "id", id, "reason", res.Reason)
-		return false, nil
+		return res, nil
 
-	case <-time.After(ApprovalTimeout):
+	case <-time.After(cfg.Timeout):
+		if cfg.OnTimeout == "approve" {
+			m.logger.Warn("approval timed out, approving (on_timeout: approve)", "id", id, "tool", pa.ToolName)
+			return ApprovalResult{Approved: true, Reason: "approved on timeout", TimedOut: true}, nil
+		}
 		m.logger.Warn("approval timed out", "id", id, "tool", pa.ToolName)
-		return false, fmt.Errorf("approval timed out")
+		return ApprovalResult{TimedOut: true}, fmt.Errorf("approval timed out")
+	}
+}
+
+// RequestFiles asks the user to review the diffs of a file-changing tool
+// call and blocks until each file is approved or rejected. Session trust
+// approves everything without prompting, but a review never grants trust:
+// every later change is shown again.
+func (m *ApprovalManager) RequestFiles(sessionID, callerJID, toolName string, files []ProposedFileChange, sendMsg func(msg *channels.OutgoingMessage)) (approved []bool, reason string, err error) {
+	approved = make([]bool, len(files))
+	if m.IsTrusted(sessionID, toolName) {
+		for i := range approved {
+			approved[i] = true
+		}
+		return approved, "", nil
+	}
+
+	id, _ := m.Create(sessionID, callerJID, toolName, nil)
+	m.mu.Lock()
+	m.pending[id].Files = files
+	m.mu.Unlock()
+	if sendMsg != nil {
+		sendMsg(m.prompt(id, formatDiffReviewMessage(id, toolName, files)))
+	}
+
+	res, err := m.waitResult(id)
+	if err != nil || !res.Approved {
+		return approved, res.Reason, err
+	}
+	if res.Files == nil {
+		for i := range approved {
+			approved[i] = true
+		}
+		return approved, "", nil
+	}
+	for _, i := range res.Files {
+		if i >= 0 && i < len(approved) {
+			approved[i] = true
+		}
 	}
+	return approved, "", nil
 }
 
 // Request creates a pending approval, invokes sendMsg with the approval message,
 // then blocks until the user approves, denies, or timeout.
 // sendMsg is called so the user sees the approval request (e.g. send to channel).
 //
-// If the tool has already been approved in this session (session trust), the
-// request is auto-approved without prompting the user.
-func (m *ApprovalManager) Request(sessionID, callerJID, toolName string, args map[string]any, sendMsg func(msg string)) (bool, error) {
+// If the tool has already been approved in this session (session trust) or a
+// grant covers the call, the request is auto-approved without prompting the user.
+func (m *ApprovalManager) Request(sessionID, callerJID, toolName string, args map[string]any, sendMsg func(msg *channels.OutgoingMessage)) (bool, error) {
+	// Tools approved call by call (vault_get) neither use nor leave grants.
+	if ownerApprovedTools[toolName] {
+		id, message := m.Create(sessionID, callerJID, toolName, args)
+		if sendMsg != nil {
+			sendMsg(m.prompt(id, message))
+		}
+		res, err := m.waitResult(id)
+		return res.Approved && !res.TimedOut, err
+	}
+
 	// Check session trust — if already approved in this session, auto-approve.
 	if m.IsTrusted(sessionID, toolName) {
 		m.logger.Debug("tool auto-approved (session trust)",
@@ -140,24 +278,60 @@ func (m *ApprovalManager) Request(sessionID, callerJID, toolName string, args ma
 		)
 		return true, nil
 	}
+	if g, ok := m.grantFor(sessionID, toolName, args); ok {
+		m.logger.Debug("tool auto-approved (grant)",
+			"tool", toolName,
+			"session", sessionID,
+			"grant", g.String(),
+		)
+		return true, nil
+	}
 
 	id, message := m.Create(sessionID, callerJID, toolName, args)
 	if sendMsg != nil {
-		sendMsg(message)
+		sendMsg(m.prompt(id, message))
 	}
-	approved, err := m.Wait(id)
+	res, err := m.waitResult(id)
 
-	// If approved, grant session trust so future calls skip the prompt.
-	if approved && err == nil {
-		m.GrantTrust(sessionID, toolName)
+	// If the user approved, record the grant the answer asked for (session
+	// trust by default) so future calls skip the prompt.
+	if res.Approved && !res.TimedOut && err == nil {
+		m.applyScope(sessionID, toolName, args, res)
 	}
 
-	return approved, err
+	return res.Approved, err
 }
 
 // Resolve resolves a pending approval by ID. Returns true if the approval was found and resolved.
 // resolverJID is the user resolving (must match CallerJID for "own requests only").
 func (m *ApprovalManager) Resolve(id, sessionID, resolverJID string, approved bool, reason string) bool {
+	return m.ResolveFiles(id, sessionID, resolverJID, approved, nil, reason)
+}
+
+// ResolveScoped approves a pending approval with the given scope.
+func (m *ApprovalManager) ResolveScoped(id, sessionID, resolverJID string, scope ApprovalScope) bool {
+	return m.resolve(id, sessionID, resolverJID, ApprovalResult{Approved: true, Scope: scope})
+}
+
+// PendingFiles returns the number of files under review in a pending
+// approval (0 when it is not a diff review or does not exist).
+func (m *ApprovalManager) PendingFiles(id string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pa, ok := m.pending[id]; ok {
+		return len(pa.Files)
+	}
+	return 0
+}
+
+// ResolveFiles is Resolve for diff reviews: files selects the approved
+// files by index (nil = all).
+func (m *ApprovalManager) ResolveFiles(id, sessionID, resolverJID string, approved bool, files []int, reason string) bool {
+	return m.resolve(id, sessionID, resolverJID, ApprovalResult{Approved: approved, Reason: reason, Files: files})
+}
+
+// resolve delivers res to a pending approval after checking who answers.
+func (m *ApprovalManager) resolve(id, sessionID, resolverJID string, res ApprovalResult) bool {
 	m.mu.Lock()
 	pa, ok := m.pending[id]
 	m.mu.Unlock()
@@ -180,8 +354,9 @@ func (m *ApprovalManager) Resolve(id, sessionID, resolverJID string, approved bo
 		return false
 	}
 
+	res.ResolvedBy = resolverJID
 	select {
-	case pa.Result <- ApprovalResult{Approved: approved, Reason: reason}:
+	case pa.Result <- res:
 		return true
 	default:
 		// Already resolved (e.g. timeout)
@@ -226,11 +401,21 @@ func (m *ApprovalManager) PendingCountForSession(sessionID string) int {
 
 // ── Session Trust ──
 
-// IsTrusted returns true if the tool has been previously approved in this session.
+// IsTrusted returns true if the tool has been previously approved in this
+// session, or a grant without a pattern covers every call of it.
 func (m *ApprovalManager) IsTrusted(sessionID, toolName string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.sessionTrust[sessionID+":"+toolName]
+	if m.sessionTrust[sessionID+":"+toolName] {
+		return true
+	}
+	now := time.Now()
+	for _, g := range m.grants {
+		if g.Pattern == "*" && !g.expired(now) && g.matches(sessionID, toolName, nil) {
+			return true
+		}
+	}
+	return false
 }
 
 // GrantTrust marks a tool as trusted for the given session.
@@ -245,7 +430,8 @@ func (m *ApprovalManager) GrantTrust(sessionID, toolName string) {
 	)
 }
 
-// ClearSessionTrust removes all trusted tools for a session (e.g. on /new or /reset).
+// ClearSessionTrust removes all trusted tools and grants of a session (e.g.
+// on /new or /reset). Grants for every session stay.
 func (m *ApprovalManager) ClearSessionTrust(sessionID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -255,6 +441,11 @@ func (m *ApprovalManager) ClearSessionTrust(sessionID string) {
 			delete(m.sessionTrust, key)
 		}
 	}
+	n := len(m.grants)
+	m.grants = slices.DeleteFunc(m.grants, func(g ApprovalGrant) bool { return g.SessionID == sessionID })
+	if len(m.grants) != n {
+		m.saveGrantsLocked()
+	}
 }
 
 // formatApprovalDescription builds a human-readable description of the tool action.
@@ -281,6 +472,9 @@ func formatApprovalDescription(toolName string, args map[string]any) string {
 		}
 		return "edit_file"
 
+	case "apply_changes":
+		return formatApplyChangesSummary(args)
+
 	case "ssh":
 		if host, ok := args["host"].(string); ok && host != "" {
 			cmd, _ := args["command"].(string)
@@ -0,0 +1,656 @@
+// Package copilot – forensics.go records forensic bundles for high-risk tool
+// calls (bash, ssh, file writes). A bundle holds what a security review needs
+// to reconstruct a call after the fact:
+//
+//   - the exact command or arguments, unredacted and untruncated
+//   - an environment fingerprint (host, user, working directory, and a hash
+//     of the environment; variable values are never stored)
+//   - SHA-256 and size of the output and of the error text
+//   - a unified diff of every file the call wrote, or the git status change
+//     of the working tree for shell commands
+//
+// Bundles are gzipped JSON files stored next to the audit log under
+// forensics/<date>/, one per call, and referenced from the audit entry as
+// [forensics:<id>]. Old bundles are pruned by age and total size.
+package copilot
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ForensicsConfig configures forensic bundles for high-risk tool calls.
+type ForensicsConfig struct {
+	// Enabled turns on bundle capture (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Tools lists the tools that get a bundle.
+	Tools []string `yaml:"tools"`
+
+	// Dir is where bundles are stored (default: forensics/ next to the
+	// audit log).
+	Dir string `yaml:"dir"`
+
+	// RetentionDays drops bundles older than this (default: 30, 0 = keep).
+	RetentionDays int `yaml:"retention_days"`
+
+	// MaxTotalMB caps the size of all bundles; the oldest are dropped first
+	// (default: 500, 0 = no cap).
+	MaxTotalMB int `yaml:"max_total_mb"`
+}
+
+// DefaultForensicsConfig returns the default forensic bundle settings.
+func DefaultForensicsConfig() ForensicsConfig {
+	return ForensicsConfig{
+		Enabled:       true,
+		Tools:         []string{"bash", "exec", "ssh", "scp", "write_file", "edit_file", "apply_changes"},
+		RetentionDays: 30,
+		MaxTotalMB:    500,
+	}
+}
+
+const (
+	// forensicMaxFileBytes is the largest file whose content is kept for
+	// diffing; larger files are recorded by hash only.
+	forensicMaxFileBytes = 512 * 1024
+
+	// forensicPruneInterval limits how often retention is enforced.
+	forensicPruneInterval = time.Hour
+)
+
+// ForensicBundle is the record stored for one tool call.
+type ForensicBundle struct {
+	ID         string         `json:"id"`
+	Time       time.Time      `json:"time"`
+	Tool       string         `json:"tool"`
+	Caller     string         `json:"caller"`
+	Level      string         `json:"level"`
+	Session    string         `json:"session,omitempty"`
+	Command    string         `json:"command,omitempty"`
+	Args       map[string]any `json:"args"`
+	Env        EnvFingerprint `json:"env"`
+	DurationMS int64          `json:"duration_ms"`
+	Outcome    string         `json:"outcome"` // ok, error, timeout
+
+	OutputSHA256 string `json:"output_sha256"`
+	OutputBytes  int    `json:"output_bytes"`
+	ErrorSHA256  string `json:"error_sha256,omitempty"`
+	ErrorBytes   int    `json:"error_bytes,omitempty"`
+
+	Files     []ForensicFileChange `json:"files,omitempty"`
+	GitBefore []string             `json:"git_status_before,omitempty"`
+	GitAfter  []string             `json:"git_status_after,omitempty"`
+}
+
+// EnvFingerprint identifies the environment a call ran in without storing
+// secrets: variable names are kept, values only contribute to the hash.
+type EnvFingerprint struct {
+	Hostname  string   `json:"hostname"`
+	OS        string   `json:"os"`
+	Arch      string   `json:"arch"`
+	User      string   `json:"user"`
+	PID       int      `json:"pid"`
+	Cwd       string   `json:"cwd"`
+	EnvSHA256 string   `json:"env_sha256"`
+	EnvNames  []string `json:"env_names"`
+}
+
+// ForensicFileChange is the before/after state of one written file.
+type ForensicFileChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before_sha256,omitempty"` // empty = file did not exist
+	After  string `json:"after_sha256,omitempty"`  // empty = file is gone
+	Diff   string `json:"diff,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// ForensicRecorder captures and stores forensic bundles.
+type ForensicRecorder struct {
+	cfg    ForensicsConfig
+	tools  map[string]bool
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewForensicRecorder creates a recorder storing bundles under cfg.Dir, or
+// under forensics/ next to auditLogPath. Returns nil when disabled.
+func NewForensicRecorder(cfg ForensicsConfig, auditLogPath string, logger *slog.Logger) *ForensicRecorder {
+	if !cfg.Enabled || len(cfg.Tools) == 0 {
+		return nil
+	}
+	cfg.Dir = ForensicsDir(cfg, auditLogPath)
+	if logger == nil {
+		logger = slog.Default()
+	}
+	tools := make(map[string]bool, len(cfg.Tools))
+	for _, t := range ExpandToolGroups(cfg.Tools) {
+		tools[t] = true
+	}
+	return &ForensicRecorder{cfg: cfg, tools: tools, logger: logger.With("component", "forensics")}
+}
+
+// ForensicsDir returns the directory bundles are stored in: cfg.Dir, or
+// forensics/ next to the audit log.
+func ForensicsDir(cfg ForensicsConfig, auditLogPath string) string {
+	if cfg.Dir != "" {
+		return cfg.Dir
+	}
+	base := "./data"
+	if auditLogPath != "" {
+		base = filepath.Dir(auditLogPath)
+	}
+	return filepath.Join(base, "forensics")
+}
+
+// Dir returns the directory bundles are stored in.
+func (r *ForensicRecorder) Dir() string {
+	return r.cfg.Dir
+}
+
+// forensicCapture is an in-flight bundle between Begin and Finish.
+type forensicCapture struct {
+	bundle ForensicBundle
+	start  time.Time
+	before map[string]*fileSnapshot
+	gitDir string
+}
+
+// fileSnapshot is a file's state at one point in time.
+type fileSnapshot struct {
+	exists  bool
+	sha     string
+	content []byte // nil when too large to diff
+}
+
+// Begin starts a bundle for a call, snapshotting the files it may write.
+// Returns nil when the tool is not covered.
+func (r *ForensicRecorder) Begin(ctx context.Context, toolName, callerJID string, level AccessLevel, args map[string]any) *forensicCapture {
+	if r == nil || !r.tools[toolName] {
+		return nil
+	}
+	c := &forensicCapture{
+		start:  time.Now(),
+		before: make(map[string]*fileSnapshot),
+		bundle: ForensicBundle{
+			Time:    time.Now().UTC(),
+			Tool:    toolName,
+			Caller:  callerJID,
+			Level:   string(level),
+			Session: SessionIDFromContext(ctx),
+			Args:    args,
+			Env:     captureEnvFingerprint(),
+		},
+	}
+	c.bundle.Command, _ = args["command"].(string)
+
+	for _, p := range forensicPaths(toolName, args) {
+		c.before[p] = snapshotFile(p)
+	}
+	if toolName == "bash" || toolName == "exec" {
+		dir, _ := args["working_dir"].(string)
+		if dir == "" {
+			dir = c.bundle.Env.Cwd
+		}
+		if status, ok := gitStatus(ctx, resolvePath(dir)); ok {
+			c.gitDir = resolvePath(dir)
+			c.bundle.GitBefore = status
+		}
+	}
+	return c
+}
+
+// Finish completes the bundle with the call's result, stores it and
+// returns its ID ("" when nothing was stored).
+func (r *ForensicRecorder) Finish(c *forensicCapture, output string, callErr error, timedOut bool) string {
+	if r == nil || c == nil {
+		return ""
+	}
+	b := &c.bundle
+	b.DurationMS = time.Since(c.start).Milliseconds()
+	switch {
+	case timedOut:
+		b.Outcome = "timeout"
+	case callErr != nil:
+		b.Outcome = "error"
+	default:
+		b.Outcome = "ok"
+	}
+	b.OutputSHA256, b.OutputBytes = sha256Hex([]byte(output)), len(output)
+	if callErr != nil {
+		msg := callErr.Error()
+		b.ErrorSHA256, b.ErrorBytes = sha256Hex([]byte(msg)), len(msg)
+	}
+
+	paths := make([]string, 0, len(c.before))
+	for p := range c.before {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if change, changed := diffSnapshots(p, c.before[p], snapshotFile(p)); changed {
+			b.Files = append(b.Files, change)
+		}
+	}
+	if c.gitDir != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		b.GitAfter, _ = gitStatus(ctx, c.gitDir)
+		cancel()
+	}
+
+	id, err := r.write(b)
+	if err != nil {
+		r.logger.Warn("cannot store forensic bundle", "tool", b.Tool, "error", err)
+		return ""
+	}
+	r.maybePrune()
+	return id
+}
+
+// write stores the bundle as <dir>/<date>/<time>-<tool>.json.gz.
+func (r *ForensicRecorder) write(b *ForensicBundle) (string, error) {
+	day := b.Time.Format("2006-01-02")
+	name := fmt.Sprintf("%s-%s", b.Time.Format("150405.000000000"), b.Tool)
+	b.ID = day + "/" + name
+
+	dir := filepath.Join(r.cfg.Dir, day)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name+".json.gz"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return "", err
+	}
+	return b.ID, f.Close()
+}
+
+// maybePrune enforces retention at most once per forensicPruneInterval.
+func (r *ForensicRecorder) maybePrune() {
+	r.mu.Lock()
+	due := time.Since(r.lastPrune) >= forensicPruneInterval
+	if due {
+		r.lastPrune = time.Now()
+	}
+	r.mu.Unlock()
+	if due {
+		if n := r.Prune(time.Now()); n > 0 {
+			r.logger.Info("pruned forensic bundles", "removed", n)
+		}
+	}
+}
+
+// Prune removes bundles older than the retention period, then the oldest
+// bundles until the total size fits the cap. Returns how many were removed.
+func (r *ForensicRecorder) Prune(now time.Time) int {
+	entries, err := ListForensicBundles(r.cfg.Dir)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	cutoff := time.Time{}
+	if r.cfg.RetentionDays > 0 {
+		cutoff = now.AddDate(0, 0, -r.cfg.RetentionDays)
+	}
+	limit := int64(r.cfg.MaxTotalMB) << 20
+	for _, e := range entries { // oldest first
+		expired := !cutoff.IsZero() && e.Time.Before(cutoff)
+		oversize := limit > 0 && total > limit
+		if !expired && !oversize {
+			break
+		}
+		if os.Remove(e.Path) == nil {
+			removed++
+			total -= e.Size
+		}
+	}
+	// Drop day directories left empty.
+	days, _ := os.ReadDir(r.cfg.Dir)
+	for _, d := range days {
+		if d.IsDir() {
+			_ = os.Remove(filepath.Join(r.cfg.Dir, d.Name())) // fails unless empty
+		}
+	}
+	return removed
+}
+
+// ForensicBundleEntry is a stored bundle as listed on disk.
+type ForensicBundleEntry struct {
+	ID   string
+	Path string
+	Time time.Time
+	Size int64
+}
+
+// ListForensicBundles returns the bundles stored in dir, oldest first.
+func ListForensicBundles(dir string) ([]ForensicBundleEntry, error) {
+	var out []ForensicBundleEntry
+	days, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, d := range days {
+		if !d.IsDir() {
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(dir, d.Name()))
+		for _, f := range files {
+			name, ok := strings.CutSuffix(f.Name(), ".json.gz")
+			if !ok {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			ts, err := time.Parse("2006-01-02 150405.000000000", d.Name()+" "+strings.SplitN(name, "-", 2)[0])
+			if err != nil {
+				ts = info.ModTime()
+			}
+			out = append(out, ForensicBundleEntry{
+				ID:   d.Name() + "/" + name,
+				Path: filepath.Join(dir, d.Name(), f.Name()),
+				Time: ts,
+				Size: info.Size(),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out, nil
+}
+
+// ReadForensicBundle loads the bundle with the given ID from dir.
+func ReadForensicBundle(dir, id string) (*ForensicBundle, error) {
+	if strings.Contains(id, "..") {
+		return nil, fmt.Errorf("invalid bundle id %q", id)
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(id)+".json.gz"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var b ForensicBundle
+	if err := json.NewDecoder(zr).Decode(&b); err != nil {
+		return nil, fmt.Errorf("decoding bundle %s: %w", id, err)
+	}
+	return &b, nil
+}
+
+// forensicPaths returns the files a tool call may write.
+func forensicPaths(toolName string, args map[string]any) []string {
+	var paths []string
+	switch toolName {
+	case "write_file", "edit_file":
+		if p, _ := args["path"].(string); p != "" {
+			paths = append(paths, p)
+		}
+	case "apply_changes":
+		paths = changePaths(args)
+	case "scp":
+		// Only downloads write locally; remote destinations look like host:path.
+		if dst, _ := args["destination"].(string); dst != "" && !strings.Contains(dst, ":") {
+			paths = append(paths, dst)
+		}
+	}
+	for i, p := range paths {
+		paths[i] = resolvePath(p)
+	}
+	return paths
+}
+
+// snapshotFile reads the current state of path.
+func snapshotFile(path string) *fileSnapshot {
+	st, err := os.Stat(path)
+	if err != nil || !st.Mode().IsRegular() {
+		return &fileSnapshot{}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return &fileSnapshot{}
+	}
+	defer f.Close()
+	h := sha256.New()
+	s := &fileSnapshot{exists: true}
+	if st.Size() <= forensicMaxFileBytes {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return &fileSnapshot{}
+		}
+		h.Write(data)
+		s.content = data
+	} else if _, err := io.Copy(h, f); err != nil {
+		return &fileSnapshot{}
+	}
+	s.sha = hex.EncodeToString(h.Sum(nil))
+	return s
+}
+
+// diffSnapshots describes the change of one file; changed is false when
+// the file is identical before and after.
+func diffSnapshots(path string, before, after *fileSnapshot) (ForensicFileChange, bool) {
+	if before.exists == after.exists && before.sha == after.sha {
+		return ForensicFileChange{}, false
+	}
+	change := ForensicFileChange{Path: path, Before: before.sha, After: after.sha}
+	switch {
+	case (before.exists && before.content == nil) || (after.exists && after.content == nil):
+		change.Note = fmt.Sprintf("larger than %d KB, recorded by hash only", forensicMaxFileBytes>>10)
+	case isBinary(before.content) || isBinary(after.content):
+		change.Note = "binary file, recorded by hash only"
+	default:
+		change.Diff = unifiedDiff(path, string(before.content), string(after.content))
+	}
+	return change, true
+}
+
+// isBinary reports whether data looks like a binary file.
+func isBinary(data []byte) bool {
+	return strings.IndexByte(string(data[:min(len(data), 8000)]), 0) >= 0
+}
+
+// gitStatus returns `git status --porcelain` for dir; ok is false when dir
+// is not inside a git work tree.
+func gitStatus(ctx context.Context, dir string) ([]string, bool) {
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "--untracked-files=all")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+	var lines []string
+	for _, l := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, true
+}
+
+// captureEnvFingerprint describes the current process environment.
+func captureEnvFingerprint() EnvFingerprint {
+	fp := EnvFingerprint{OS: runtime.GOOS, Arch: runtime.GOARCH, PID: os.Getpid()}
+	fp.Hostname, _ = os.Hostname()
+	fp.Cwd, _ = os.Getwd()
+	if u, err := user.Current(); err == nil {
+		fp.User = u.Username
+	}
+	env := os.Environ()
+	sort.Strings(env)
+	h := sha256.New()
+	for _, kv := range env {
+		h.Write([]byte(kv))
+		h.Write([]byte{0})
+		name, _, _ := strings.Cut(kv, "=")
+		fp.EnvNames = append(fp.EnvNames, name)
+	}
+	fp.EnvSHA256 = hex.EncodeToString(h.Sum(nil))
+	return fp
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// unifiedDiff returns a unified diff of two texts with three lines of
+// context. Inputs that would make the comparison too expensive fall back to
+// replacing the whole file.
+func unifiedDiff(path, before, after string) string {
+	a, b := splitLines(before), splitLines(after)
+
+	// Trim the common prefix and suffix so the table only covers the
+	// changed region.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	am, bm := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	type op struct {
+		kind byte // ' ', '-', '+'
+		text string
+	}
+	var ops []op
+	for _, l := range a[:pre] {
+		ops = append(ops, op{' ', l})
+	}
+	if len(am)*len(bm) > 4_000_000 {
+		for _, l := range am {
+			ops = append(ops, op{'-', l})
+		}
+		for _, l := range bm {
+			ops = append(ops, op{'+', l})
+		}
+	} else {
+		// Longest common subsequence, walked front to back.
+		n, m := len(am), len(bm)
+		lcs := make([][]int32, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && am[i] == bm[j]:
+				ops = append(ops, op{' ', am[i]})
+				i++
+				j++
+			case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, op{'-', am[i]})
+				i++
+			default:
+				ops = append(ops, op{'+', bm[j]})
+				j++
+			}
+		}
+	}
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, op{' ', l})
+	}
+
+	// Group changes into hunks with three lines of context.
+	const contextLines = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		from := max(0, start-contextLines)
+		end := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k
+			} else if k-end > 2*contextLines {
+				break
+			}
+		}
+		to := min(len(ops), end+contextLines+1)
+
+		aLine, bLine := 1, 1
+		for _, o := range ops[:from] {
+			if o.kind != '+' {
+				aLine++
+			}
+			if o.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				aCount++
+			}
+			if o.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, o := range ops[from:to] {
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.text)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines without their terminators.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
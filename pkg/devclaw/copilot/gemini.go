@@ -0,0 +1,547 @@
+// Package copilot – gemini.go adds Google Gemini through its native
+// generateContent API (api.provider: gemini, or a generativelanguage base
+// URL), and routes very large single-shot requests to a large-context model.
+//
+// Gemini can be the primary provider, or only the large-context one:
+//
+//	api:
+//	  providers:
+//	    gemini:
+//	      api_key: ${GEMINI_API_KEY}
+//	      large_context_model: gemini-2.5-pro   # "" disables routing
+//	      large_context_min_tokens: 100000
+//
+// Routing only applies to requests without tools on the default model (huge
+// diffs, long documents sent to `devclaw diff`, `explain`, summaries): agent
+// runs with tools always stay on the configured model. When the routed call
+// fails, the request falls back to the default model.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGeminiBaseURL is the Gemini API root (native, not OpenAI-compatible).
+	defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+	// defaultLargeContextMinTokens is the prompt size from which single-shot
+	// requests go to the large-context model.
+	defaultLargeContextMinTokens = 100000
+
+	// maxGeminiSignatures caps the remembered thought signatures.
+	maxGeminiSignatures = 2000
+)
+
+// ProvidersConfig configures providers used next to the primary one.
+type ProvidersConfig struct {
+	// Gemini enables Google Gemini for large-context routing.
+	Gemini GeminiConfig `yaml:"gemini"`
+}
+
+// GeminiConfig configures the Gemini provider.
+type GeminiConfig struct {
+	// APIKey is the Gemini API key (default: $GEMINI_API_KEY, or api.api_key
+	// when Gemini is the primary provider).
+	APIKey string `yaml:"api_key"`
+
+	// BaseURL overrides the API root (default: generativelanguage.googleapis.com/v1beta).
+	BaseURL string `yaml:"base_url"`
+
+	// LargeContextModel receives single-shot requests (no tools) whose
+	// prompt reaches LargeContextMinTokens (default: gemini-2.5-pro;
+	// empty disables routing).
+	LargeContextModel string `yaml:"large_context_model"`
+
+	// LargeContextMinTokens is the estimated prompt size that triggers
+	// routing (default: 100000).
+	LargeContextMinTokens int `yaml:"large_context_min_tokens"`
+}
+
+// DefaultGeminiConfig returns defaults for the Gemini provider.
+func DefaultGeminiConfig() GeminiConfig {
+	return GeminiConfig{
+		LargeContextModel:     "gemini-2.5-pro",
+		LargeContextMinTokens: defaultLargeContextMinTokens,
+	}
+}
+
+// isGemini reports whether the client talks to the native Gemini API.
+func (c *LLMClient) isGemini() bool {
+	return c.provider == "gemini"
+}
+
+// newLargeContextTarget returns the target large single-shot requests are
+// routed to, or nil when routing is off or Gemini has no credentials.
+func newLargeContextTarget(cfg GeminiConfig, primary *LLMClient) *fallbackTarget {
+	model := strings.TrimSpace(cfg.LargeContextModel)
+	if model == "" {
+		return nil
+	}
+	if primary.isGemini() && cfg.APIKey == "" && cfg.BaseURL == "" {
+		return &fallbackTarget{client: primary, model: model}
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" || IsEnvReference(apiKey) {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if apiKey == "" {
+		return nil
+	}
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	return &fallbackTarget{
+		client: &LLMClient{
+			baseURL:    baseURL,
+			provider:   "gemini",
+			apiKey:     apiKey,
+			model:      model,
+			fallback:   primary.fallback,
+			registry:   primary.registry,
+			httpClient: primary.httpClient,
+			logger:     primary.logger.With("large_context_provider", "gemini"),
+		},
+		model: model,
+	}
+}
+
+// completeLargeContext serves a single-shot request (no tools, default
+// model) from the large-context model when its prompt is big enough.
+// Returns nil when the request is not routed or the routed call failed, so
+// the caller continues with the default model.
+func (c *LLMClient) completeLargeContext(ctx context.Context, modelOverride string, messages []chatMessage, tools []ToolDefinition) *LLMResponse {
+	if c.largeContext == nil || modelOverride != "" || len(tools) > 0 {
+		return nil
+	}
+	target := *c.largeContext
+	if target.client == c && target.model == c.model {
+		return nil
+	}
+	minTokens := c.largeContextMinTokens
+	if minTokens <= 0 {
+		minTokens = defaultLargeContextMinTokens
+	}
+	tokens := estimatePromptTokens(messages, nil)
+	if tokens < minTokens {
+		return nil
+	}
+
+	c.logger.Info("routing large single-shot request to large-context model",
+		"model", target.model, "estimated_tokens", tokens, "default_model", c.model)
+	resp, err := target.client.completeWithFallback(ctx, target.model, messages, nil)
+	if err != nil {
+		c.logger.Warn("large-context model failed, using the default model",
+			"model", target.model, "error", err)
+		return nil
+	}
+	return resp
+}
+
+// ---------- Wire Types (Gemini generateContent) ----------
+
+// geminiRequest is the generateContent request body.
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// geminiContent is one turn: role "user" or "model" and its parts.
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart is a text, inline image, function call or function response.
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiBlob is base64 inline data (images).
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// geminiFunctionCall is a tool call requested by the model.
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// geminiFunctionResponse carries a tool result back to the model.
+type geminiFunctionResponse struct {
+	ID       string         `json:"id,omitempty"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// geminiTool groups the function declarations offered to the model.
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDecl `json:"functionDeclarations"`
+}
+
+// geminiFunctionDecl declares a tool; the JSON Schema is passed as is.
+type geminiFunctionDecl struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description,omitempty"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema,omitempty"`
+}
+
+// geminiGenerationConfig holds sampling parameters.
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+}
+
+// geminiResponse is the generateContent response.
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+}
+
+// geminiSignatures remembers the thought signature of each function call,
+// by tool call ID, so it can be sent back with the call on later turns
+// (required by thinking models).
+var geminiSignatures = struct {
+	sync.Mutex
+	m map[string]string
+}{m: make(map[string]string)}
+
+func rememberGeminiSignature(callID, signature string) {
+	if signature == "" {
+		return
+	}
+	geminiSignatures.Lock()
+	defer geminiSignatures.Unlock()
+	if len(geminiSignatures.m) >= maxGeminiSignatures {
+		geminiSignatures.m = make(map[string]string)
+	}
+	geminiSignatures.m[callID] = signature
+}
+
+func geminiSignature(callID string) string {
+	geminiSignatures.Lock()
+	defer geminiSignatures.Unlock()
+	return geminiSignatures.m[callID]
+}
+
+// convertToGeminiRequest converts OpenAI-format messages and tools.
+func convertToGeminiRequest(messages []chatMessage, tools []ToolDefinition, temp *float64, maxTokens int) *geminiRequest {
+	req := &geminiRequest{}
+	if temp != nil || maxTokens > 0 {
+		req.GenerationConfig = &geminiGenerationConfig{Temperature: temp, MaxOutputTokens: maxTokens}
+	}
+
+	callNames := make(map[string]string) // tool call ID -> function name
+	var system []geminiPart
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			if text := contentText(m.Content); text != "" {
+				system = append(system, geminiPart{Text: text})
+			}
+
+		case "tool":
+			req.Contents = appendGeminiContent(req.Contents, "user", geminiPart{
+				FunctionResponse: &geminiFunctionResponse{
+					Name:     callNames[m.ToolCallID],
+					Response: map[string]any{"content": contentText(m.Content)},
+				},
+			})
+			// Images returned by the tool follow its response in the same turn.
+			if _, ok := m.Content.([]contentPart); ok {
+				for _, p := range geminiUserParts(m.Content) {
+					if p.InlineData != nil {
+						req.Contents = appendGeminiContent(req.Contents, "user", p)
+					}
+				}
+			}
+
+		case "assistant":
+			var parts []geminiPart
+			if text := contentText(m.Content); text != "" {
+				parts = append(parts, geminiPart{Text: text})
+			}
+			for _, tc := range m.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{
+					FunctionCall:     &geminiFunctionCall{Name: tc.Function.Name, Args: args},
+					ThoughtSignature: geminiSignature(tc.ID),
+				})
+			}
+			if len(parts) > 0 {
+				req.Contents = appendGeminiContent(req.Contents, "model", parts...)
+			}
+
+		default:
+			if parts := geminiUserParts(m.Content); len(parts) > 0 {
+				req.Contents = appendGeminiContent(req.Contents, "user", parts...)
+			}
+		}
+	}
+	if len(system) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDecl, 0, len(tools))
+		for _, t := range tools {
+			decls = append(decls, geminiFunctionDecl{
+				Name:                 t.Function.Name,
+				Description:          t.Function.Description,
+				ParametersJSONSchema: t.Function.Parameters,
+			})
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+	return req
+}
+
+// appendGeminiContent appends parts, merging consecutive turns of the same
+// role (parallel function responses must share one turn).
+func appendGeminiContent(contents []geminiContent, role string, parts ...geminiPart) []geminiContent {
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, geminiContent{Role: role, Parts: parts})
+}
+
+// geminiUserParts converts user content (text or multimodal parts).
+func geminiUserParts(content any) []geminiPart {
+	cp, ok := content.([]contentPart)
+	if !ok {
+		if text := contentText(content); text != "" {
+			return []geminiPart{{Text: text}}
+		}
+		return nil
+	}
+	var parts []geminiPart
+	for _, p := range cp {
+		switch {
+		case p.ImageURL != nil:
+			if blob := geminiBlobFromDataURL(p.ImageURL.URL); blob != nil {
+				parts = append(parts, geminiPart{InlineData: blob})
+			} else {
+				parts = append(parts, geminiPart{Text: "[image: " + p.ImageURL.URL + "]"})
+			}
+		case p.Text != "":
+			parts = append(parts, geminiPart{Text: p.Text})
+		}
+	}
+	return parts
+}
+
+// geminiBlobFromDataURL parses a base64 data URL; nil for other URLs.
+func geminiBlobFromDataURL(u string) *geminiBlob {
+	mime, data, ok := parseDataURL(u)
+	if !ok {
+		return nil
+	}
+	return &geminiBlob{MimeType: mime, Data: data}
+}
+
+// contentText returns the text of string or multimodal content.
+func contentText(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []contentPart:
+		var texts []string
+		for _, p := range v {
+			if p.Text != "" {
+				texts = append(texts, p.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// geminiEndpoint returns the generateContent URL for model.
+func (c *LLMClient) geminiEndpoint(model string) string {
+	model = strings.TrimPrefix(model, "models/")
+	return c.baseURL + "/models/" + url.PathEscape(model) + ":generateContent"
+}
+
+// completeOnceGemini performs a single generateContent request.
+func (c *LLMClient) completeOnceGemini(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, error) {
+	defaults := c.modelDefaults(model)
+	var temp *float64
+	if defaults.SupportsTemperature && defaults.DefaultTemperature > 0 {
+		t := defaults.DefaultTemperature
+		temp = &t
+	}
+	if !defaults.SupportsTools {
+		tools = nil
+	}
+
+	reqBody := convertToGeminiRequest(messages, tools, temp, defaults.MaxOutputTokens)
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	endpoint := c.geminiEndpoint(model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+
+	c.logger.Debug("sending gemini generateContent",
+		"model", model,
+		"contents", len(reqBody.Contents),
+		"tools", len(tools),
+	)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	bodyStr := string(respBody)
+
+	if resp.StatusCode != http.StatusOK {
+		apierr := &apiError{statusCode: resp.StatusCode, body: bodyStr}
+		if resp.StatusCode == 429 {
+			if ra := resp.Header.Get("Retry-After"); ra != "" {
+				if sec, err := strconv.Atoi(ra); err == nil && sec > 0 {
+					apierr.retryAfterSec = sec
+				}
+			}
+		}
+		c.logger.Error("API error",
+			"model", model,
+			"status", resp.StatusCode,
+			"body", truncate(bodyStr, 500),
+		)
+		return nil, apierr
+	}
+
+	var gemResp geminiResponse
+	if err := json.Unmarshal(respBody, &gemResp); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	result, err := parseGeminiResponse(&gemResp)
+	if err != nil {
+		return nil, err
+	}
+	result.ModelUsed = model
+
+	c.logger.Info("gemini completion done",
+		"model", model,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"prompt_tokens", result.Usage.PromptTokens,
+		"completion_tokens", result.Usage.CompletionTokens,
+		"tool_calls", len(result.ToolCalls),
+	)
+	return result, nil
+}
+
+// parseGeminiResponse converts a generateContent response.
+func parseGeminiResponse(gr *geminiResponse) (*LLMResponse, error) {
+	if len(gr.Candidates) == 0 {
+		if gr.PromptFeedback != nil && gr.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini blocked the prompt: %s", gr.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("gemini returned no candidates")
+	}
+
+	cand := gr.Candidates[0]
+	result := &LLMResponse{
+		Usage: LLMUsage{
+			PromptTokens:     gr.UsageMetadata.PromptTokenCount,
+			CompletionTokens: gr.UsageMetadata.CandidatesTokenCount + gr.UsageMetadata.ThoughtsTokenCount,
+			TotalTokens:      gr.UsageMetadata.TotalTokenCount,
+			CacheReadTokens:  gr.UsageMetadata.CachedContentTokenCount,
+		},
+	}
+
+	var text strings.Builder
+	for i, p := range cand.Content.Parts {
+		switch {
+		case p.FunctionCall != nil:
+			id := p.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), i)
+			}
+			args := string(p.FunctionCall.Args)
+			if strings.TrimSpace(args) == "" || args == "null" {
+				args = "{}"
+			}
+			rememberGeminiSignature(id, p.ThoughtSignature)
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:       id,
+				Type:     "function",
+				Function: FunctionCall{Name: p.FunctionCall.Name, Arguments: args},
+			})
+		case p.Thought:
+			// Thought summaries are not part of the answer.
+		case p.Text != "":
+			text.WriteString(p.Text)
+		}
+	}
+	result.Content = text.String()
+
+	switch {
+	case len(result.ToolCalls) > 0:
+		result.FinishReason = "tool_calls"
+	case cand.FinishReason == "STOP":
+		result.FinishReason = "stop"
+	case cand.FinishReason == "MAX_TOKENS":
+		result.FinishReason = "length"
+	default:
+		result.FinishReason = strings.ToLower(cand.FinishReason)
+	}
+	return result, nil
+}
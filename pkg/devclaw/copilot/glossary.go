@@ -0,0 +1,417 @@
+// Package copilot – glossary.go keeps a per-workspace glossary of domain
+// terms ("the blue pipeline", "MCV report") so the model reads team jargon
+// the way the team means it. Terms are defined with /define term = meaning
+// or suggested by the knowledge-base extractor when an idle session is
+// summarized; suggestions wait for an admin (/glossary accept|reject).
+// When a message mentions defined terms, their meanings are injected as a
+// compact per-turn prompt layer.
+//
+// The glossary lives in devclaw.db (table glossary_terms) and is cached in
+// memory per workspace.
+package copilot
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// GlossaryConfig configures the workspace glossary.
+type GlossaryConfig struct {
+	// Enabled turns the glossary and its prompt layer on (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// MaxTerms caps the terms injected per message (default: 10).
+	MaxTerms int `yaml:"max_terms"`
+
+	// Suggest lets the knowledge-base extractor propose terms from idle
+	// sessions for admin review (default: true).
+	Suggest bool `yaml:"suggest"`
+}
+
+// DefaultGlossaryConfig returns the default glossary configuration.
+func DefaultGlossaryConfig() GlossaryConfig {
+	return GlossaryConfig{Enabled: true, MaxTerms: 10, Suggest: true}
+}
+
+// Glossary term statuses.
+const (
+	glossaryActive    = "active"
+	glossarySuggested = "suggested"
+	glossaryRejected  = "rejected"
+)
+
+// maxGlossaryMeaning caps a stored meaning.
+const maxGlossaryMeaning = 300
+
+// GlossaryTerm is a term and what it means in a workspace.
+type GlossaryTerm struct {
+	Term    string `json:"term"`
+	Meaning string `json:"meaning"`
+
+	Status    string    `json:"-"`
+	AddedBy   string    `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// Glossary stores glossary terms per workspace.
+type Glossary struct {
+	db     *sql.DB
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string][]GlossaryTerm // workspace → terms (all statuses)
+}
+
+// NewGlossary creates the glossary_terms table if needed.
+func NewGlossary(db *sql.DB, logger *slog.Logger) (*Glossary, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS glossary_terms (
+			workspace_id TEXT NOT NULL,
+			term_key     TEXT NOT NULL,
+			term         TEXT NOT NULL,
+			meaning      TEXT NOT NULL,
+			status       TEXT NOT NULL,
+			added_by     TEXT NOT NULL DEFAULT '',
+			updated_at   TEXT NOT NULL,
+			PRIMARY KEY (workspace_id, term_key)
+		)`); err != nil {
+		return nil, fmt.Errorf("create glossary table: %w", err)
+	}
+	return &Glossary{
+		db:     db,
+		logger: logger.With("component", "glossary"),
+		cache:  make(map[string][]GlossaryTerm),
+	}, nil
+}
+
+// glossaryKey normalizes a term for lookups.
+func glossaryKey(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
+
+// Define sets a term's meaning, replacing any earlier definition or
+// suggestion.
+func (g *Glossary) Define(wsID, term, meaning, by string) error {
+	return g.put(wsID, term, meaning, glossaryActive, by)
+}
+
+// Suggest records proposed terms for review, skipping terms the workspace
+// already has (including rejected ones). Returns how many were added.
+func (g *Glossary) Suggest(wsID string, terms []GlossaryTerm) (int, error) {
+	existing, err := g.terms(wsID)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		seen[glossaryKey(t.Term)] = true
+	}
+	added := 0
+	for _, t := range terms {
+		key := glossaryKey(t.Term)
+		if key == "" || strings.TrimSpace(t.Meaning) == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := g.put(wsID, t.Term, t.Meaning, glossarySuggested, "extractor"); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// put upserts a term.
+func (g *Glossary) put(wsID, term, meaning, status, by string) error {
+	term = strings.Join(strings.Fields(term), " ")
+	meaning = truncate(strings.Join(strings.Fields(meaning), " "), maxGlossaryMeaning)
+	if term == "" || meaning == "" {
+		return fmt.Errorf("term and meaning are required")
+	}
+	_, err := g.db.Exec(`
+		INSERT INTO glossary_terms (workspace_id, term_key, term, meaning, status, added_by, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (workspace_id, term_key) DO UPDATE SET
+			term = excluded.term, meaning = excluded.meaning, status = excluded.status,
+			added_by = excluded.added_by, updated_at = excluded.updated_at`,
+		wsID, glossaryKey(term), term, meaning, status, by, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("save glossary term: %w", err)
+	}
+	g.invalidate(wsID)
+	return nil
+}
+
+// Review accepts or rejects a suggested term. Returns false if the term is
+// not waiting for review.
+func (g *Glossary) Review(wsID, term string, accept bool) (bool, error) {
+	status := glossaryRejected
+	if accept {
+		status = glossaryActive
+	}
+	res, err := g.db.Exec(`UPDATE glossary_terms SET status = ?, updated_at = ?
+		WHERE workspace_id = ? AND term_key = ? AND status = ?`,
+		status, time.Now().UTC().Format(time.RFC3339), wsID, glossaryKey(term), glossarySuggested)
+	if err != nil {
+		return false, fmt.Errorf("review glossary term: %w", err)
+	}
+	g.invalidate(wsID)
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Remove deletes a term. Returns false if it did not exist.
+func (g *Glossary) Remove(wsID, term string) (bool, error) {
+	res, err := g.db.Exec(`DELETE FROM glossary_terms WHERE workspace_id = ? AND term_key = ?`, wsID, glossaryKey(term))
+	if err != nil {
+		return false, fmt.Errorf("remove glossary term: %w", err)
+	}
+	g.invalidate(wsID)
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Lookup returns a term of the workspace (any status but rejected).
+func (g *Glossary) Lookup(wsID, term string) (GlossaryTerm, bool) {
+	terms, err := g.terms(wsID)
+	if err != nil {
+		return GlossaryTerm{}, false
+	}
+	key := glossaryKey(term)
+	for _, t := range terms {
+		if glossaryKey(t.Term) == key && t.Status != glossaryRejected {
+			return t, true
+		}
+	}
+	return GlossaryTerm{}, false
+}
+
+// List returns the workspace's defined terms and pending suggestions,
+// sorted by term.
+func (g *Glossary) List(wsID string) (active, suggested []GlossaryTerm, err error) {
+	terms, err := g.terms(wsID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, t := range terms {
+		switch t.Status {
+		case glossaryActive:
+			active = append(active, t)
+		case glossarySuggested:
+			suggested = append(suggested, t)
+		}
+	}
+	return active, suggested, nil
+}
+
+// Match returns the defined terms mentioned in text (whole words, case
+// insensitive), longest terms first, at most max.
+func (g *Glossary) Match(wsID, text string, max int) []GlossaryTerm {
+	if g == nil || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	terms, err := g.terms(wsID)
+	if err != nil {
+		g.logger.Warn("glossary lookup failed", "workspace", wsID, "error", err)
+		return nil
+	}
+	lower := strings.ToLower(text)
+	var found []GlossaryTerm
+	for _, t := range terms {
+		if t.Status == glossaryActive && containsWord(lower, glossaryKey(t.Term)) {
+			found = append(found, t)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return len(found[i].Term) > len(found[j].Term) })
+	if max > 0 && len(found) > max {
+		found = found[:max]
+	}
+	return found
+}
+
+// terms returns all terms of a workspace, from the cache when possible.
+func (g *Glossary) terms(wsID string) ([]GlossaryTerm, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if terms, ok := g.cache[wsID]; ok {
+		return terms, nil
+	}
+	rows, err := g.db.Query(`SELECT term, meaning, status, added_by, updated_at
+		FROM glossary_terms WHERE workspace_id = ? ORDER BY term_key`, wsID)
+	if err != nil {
+		return nil, fmt.Errorf("query glossary: %w", err)
+	}
+	defer rows.Close()
+	var terms []GlossaryTerm
+	for rows.Next() {
+		var t GlossaryTerm
+		var updated string
+		if err := rows.Scan(&t.Term, &t.Meaning, &t.Status, &t.AddedBy, &updated); err != nil {
+			return nil, fmt.Errorf("scan glossary term: %w", err)
+		}
+		t.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
+		terms = append(terms, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	g.cache[wsID] = terms
+	return terms, nil
+}
+
+func (g *Glossary) invalidate(wsID string) {
+	g.mu.Lock()
+	delete(g.cache, wsID)
+	g.mu.Unlock()
+}
+
+// containsWord reports whether text contains word with no word character
+// directly before or after it (see isWordRune). Both are lower case.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// formatGlossaryLayer renders matched terms for the prompt.
+func formatGlossaryLayer(terms []GlossaryTerm) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Glossary\n\nTerms in this message, as this workspace uses them:\n")
+	for _, t := range terms {
+		fmt.Fprintf(&b, "- **%s**: %s\n", t.Term, t.Meaning)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// defineCommand handles /define: list the glossary, show a term, or set
+// one with "term = meaning".
+func (a *Assistant) defineCommand(msg *channels.IncomingMessage) string {
+	if a.glossary == nil {
+		return "Glossary not available (memory.glossary.enabled is off or devclaw.db is missing)."
+	}
+	wsID := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace.ID
+	_, rest, _ := strings.Cut(strings.TrimSpace(msg.Content), " ")
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return a.glossaryList(wsID)
+	}
+
+	term, meaning, ok := strings.Cut(rest, "=")
+	term, meaning = strings.TrimSpace(term), strings.TrimSpace(meaning)
+	if !ok {
+		t, found := a.glossary.Lookup(wsID, term)
+		if !found {
+			return fmt.Sprintf("%q is not in the glossary. Define it with /define %s = <meaning>", term, term)
+		}
+		if t.Status == glossarySuggested {
+			return fmt.Sprintf("*%s* (suggested, not yet accepted): %s", t.Term, t.Meaning)
+		}
+		return fmt.Sprintf("*%s*: %s", t.Term, t.Meaning)
+	}
+	if term == "" || meaning == "" {
+		return "Usage: /define <term> = <meaning>"
+	}
+	if err := a.glossary.Define(wsID, term, meaning, msg.From); err != nil {
+		return "Failed to save the term: " + err.Error()
+	}
+	a.logger.Info("glossary term defined", "workspace", wsID, "term", term, "by", msg.From)
+	return fmt.Sprintf("Glossary: *%s* = %s", term, truncate(meaning, maxGlossaryMeaning))
+}
+
+// glossaryCommand handles /glossary: list terms and suggestions, and (for
+// admins) accept, reject or remove terms.
+func (a *Assistant) glossaryCommand(args []string, msg *channels.IncomingMessage, isAdmin bool) string {
+	if a.glossary == nil {
+		return "Glossary not available (memory.glossary.enabled is off or devclaw.db is missing)."
+	}
+	wsID := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup).Workspace.ID
+	if len(args) == 0 || strings.EqualFold(args[0], "list") {
+		return a.glossaryList(wsID)
+	}
+	sub := strings.ToLower(args[0])
+	term := strings.Join(args[1:], " ")
+	if sub != "accept" && sub != "reject" && sub != "remove" || term == "" {
+		return "Usage: /glossary [list] | accept|reject|remove <term>"
+	}
+	if !isAdmin {
+		return "Only admins can review or remove glossary terms."
+	}
+
+	var (
+		ok  bool
+		err error
+	)
+	if sub == "remove" {
+		ok, err = a.glossary.Remove(wsID, term)
+	} else {
+		ok, err = a.glossary.Review(wsID, term, sub == "accept")
+	}
+	switch {
+	case err != nil:
+		return "Glossary update failed: " + err.Error()
+	case !ok && sub == "remove":
+		return fmt.Sprintf("%q is not in the glossary.", term)
+	case !ok:
+		return fmt.Sprintf("%q is not a pending suggestion.", term)
+	}
+	a.logger.Info("glossary term updated", "workspace", wsID, "term", term, "action", sub, "by", msg.From)
+	switch sub {
+	case "accept":
+		return fmt.Sprintf("Accepted *%s* into the glossary.", term)
+	case "reject":
+		return fmt.Sprintf("Rejected the suggestion *%s*; it won't be suggested again.", term)
+	default:
+		return fmt.Sprintf("Removed *%s* from the glossary.", term)
+	}
+}
+
+// glossaryList renders a workspace's glossary for chat.
+func (a *Assistant) glossaryList(wsID string) string {
+	active, suggested, err := a.glossary.List(wsID)
+	if err != nil {
+		return "Glossary lookup failed: " + err.Error()
+	}
+	if len(active) == 0 && len(suggested) == 0 {
+		return "The glossary is empty. Add a term with /define <term> = <meaning>"
+	}
+	var b strings.Builder
+	b.WriteString("*Glossary*\n")
+	for _, t := range active {
+		fmt.Fprintf(&b, "- *%s*: %s\n", t.Term, t.Meaning)
+	}
+	if len(suggested) > 0 {
+		b.WriteString("\n*Suggested* (/glossary accept|reject <term>):\n")
+		for _, t := range suggested {
+			fmt.Fprintf(&b, "- *%s*: %s\n", t.Term, t.Meaning)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,88 @@
+package copilot
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlossary_DefineSuggestAndMatch(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	g, err := NewGlossary(db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Define("sales", "MCV report", "monthly customer value report from the BI team", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Define("sales", "blue pipeline", "the staging deploy pipeline", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := g.Match("sales", "Can you rerun the Blue Pipeline and send the mcv report?", 10)
+	if len(got) != 2 || got[0].Term != "blue pipeline" || got[1].Term != "MCV report" {
+		t.Fatalf("matches = %+v", got)
+	}
+	if got := g.Match("sales", "the bluepipeline is fine, no MCV reports", 10); len(got) != 0 {
+		t.Errorf("partial words should not match, got %+v", got)
+	}
+	if got := g.Match("support", "send the MCV report", 10); len(got) != 0 {
+		t.Errorf("terms should be per workspace, got %+v", got)
+	}
+
+	n, err := g.Suggest("sales", []GlossaryTerm{
+		{Term: "mcv REPORT", Meaning: "already defined"},
+		{Term: "QBR", Meaning: "quarterly business review"},
+		{Term: "NPS", Meaning: "net promoter score"},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("Suggest = %d, %v; want 2 new suggestions", n, err)
+	}
+	if got := g.Match("sales", "prepare the QBR", 10); len(got) != 0 {
+		t.Error("suggestions should not be injected before they are accepted")
+	}
+	if ok, err := g.Review("sales", "qbr", true); !ok || err != nil {
+		t.Fatalf("accept = %v, %v", ok, err)
+	}
+	if ok, _ := g.Review("sales", "NPS", false); !ok {
+		t.Fatal("reject should succeed")
+	}
+	if n, _ := g.Suggest("sales", []GlossaryTerm{{Term: "NPS", Meaning: "again"}}); n != 0 {
+		t.Error("a rejected term should not be suggested again")
+	}
+
+	active, suggested, err := g.List("sales")
+	if err != nil || len(active) != 3 || len(suggested) != 0 {
+		t.Fatalf("List = %+v, %+v, %v", active, suggested, err)
+	}
+	if ok, _ := g.Remove("sales", "QBR"); !ok {
+		t.Error("remove should find the term")
+	}
+	if _, ok := g.Lookup("sales", "qbr"); ok {
+		t.Error("the removed term should be gone")
+	}
+}
+
+func TestE2E_GlossaryLayer(t *testing.T) {
+	h := newE2EHarness(t)
+
+	if replies := h.Send("/define MCV report = monthly customer value report"); len(replies) != 1 || !strings.Contains(replies[0], "*MCV report*") {
+		t.Fatalf("define replies = %q", replies)
+	}
+	h.Send("please send the MCV report")
+	reqs := h.LLM.Requests()
+	if len(reqs) == 0 || !strings.Contains(reqs[len(reqs)-1], "**MCV report**: monthly customer value report") {
+		t.Fatal("the glossary term should be injected into the prompt")
+	}
+
+	h.Send("how are you?")
+	reqs = h.LLM.Requests()
+	if strings.Contains(reqs[len(reqs)-1], "## Glossary") {
+		t.Error("the glossary layer should only appear when a term is mentioned")
+	}
+}
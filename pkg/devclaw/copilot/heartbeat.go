@@ -1,6 +1,7 @@
 // Package copilot – heartbeat.go implements a periodic heartbeat that checks
-// for pending scheduled jobs, reads HEARTBEAT.md for custom checklists,
-// and triggers proactive agent turns that can send messages to channels.
+// for pending scheduled jobs, reads HEARTBEAT.md for custom checklists or
+// named checks (see heartbeat_checks.go), and triggers proactive agent turns
+// that can send messages to channels.
 package copilot
 
 import (
@@ -10,9 +11,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
 )
 
 // HeartbeatConfig configures the heartbeat system.
@@ -38,6 +41,29 @@ type HeartbeatConfig struct {
 
 	// WorkspaceDir is the workspace directory where HEARTBEAT.md is located.
 	WorkspaceDir string `yaml:"workspace_dir"`
+
+	// QuietHours is a local "HH:MM-HH:MM" window (may wrap midnight) in
+	// which proactive messages are held and delivered when it ends.
+	// Workspaces can add their own window (dnd).
+	QuietHours string `yaml:"quiet_hours"`
+
+	// Checks are named checks with their own schedules. HEARTBEAT.md can
+	// define more; with none, the heartbeat runs a single prompt.
+	Checks []HeartbeatCheck `yaml:"checks"`
+}
+
+// heartbeatResolution is how often the loop looks for due checks.
+const heartbeatResolution = time.Minute
+
+// maxHeldHeartbeats bounds the messages held per chat during quiet hours;
+// the oldest are dropped first.
+const maxHeldHeartbeats = 20
+
+// heldHeartbeat is a proactive message held back by quiet hours.
+type heldHeartbeat struct {
+	channel, chatID string
+	text            string
+	at              time.Time
 }
 
 // DefaultHeartbeatConfig returns sensible defaults for the heartbeat.
@@ -53,10 +79,18 @@ func DefaultHeartbeatConfig() HeartbeatConfig {
 
 // Heartbeat runs periodic checks and proactive behavior.
 type Heartbeat struct {
+	mu        sync.Mutex
 	config    HeartbeatConfig
 	assistant *Assistant
 	logger    *slog.Logger
 	cancel    context.CancelFunc
+
+	// lastPrompt is when the single heartbeat prompt last ran, state the
+	// history of each named check and held the messages waiting for quiet
+	// hours to end. All are only used by the loop.
+	lastPrompt time.Time
+	state      map[string]*heartbeatCheckState
+	held       []heldHeartbeat
 }
 
 // NewHeartbeat creates a new heartbeat instance.
@@ -87,9 +121,17 @@ func (h *Heartbeat) Start(ctx context.Context) {
 		"interval", interval.String(),
 		"active_hours", fmt.Sprintf("%02d:00-%02d:00", h.config.ActiveStart, h.config.ActiveEnd),
 		"channel", h.config.Channel,
+		"checks", len(h.loadChecks(h.config)),
 	)
 
-	go h.loop(hbCtx, interval)
+	if h.config.QuietHours != "" {
+		if _, _, err := parseQuietHours(h.config.QuietHours); err != nil {
+			h.logger.Warn("heartbeat: ignoring quiet_hours", "error", err)
+		}
+	}
+
+	h.lastPrompt = time.Now()
+	go h.loop(hbCtx)
 }
 
 // Stop shuts down the heartbeat.
@@ -101,7 +143,9 @@ func (h *Heartbeat) Stop() {
 
 // UpdateConfig updates the heartbeat config from hot-reload.
 func (h *Heartbeat) UpdateConfig(cfg HeartbeatConfig) {
+	h.mu.Lock()
 	h.config = cfg
+	h.mu.Unlock()
 	h.logger.Info("heartbeat config hot-reloaded",
 		"enabled", cfg.Enabled,
 		"interval", cfg.Interval,
@@ -109,14 +153,14 @@ func (h *Heartbeat) UpdateConfig(cfg HeartbeatConfig) {
 }
 
 // loop is the main heartbeat goroutine.
-func (h *Heartbeat) loop(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
+func (h *Heartbeat) loop(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatResolution)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			h.tick(ctx)
+		case now := <-ticker.C:
+			h.tick(ctx, now)
 		case <-ctx.Done():
 			h.logger.Info("heartbeat stopped")
 			return
@@ -124,35 +168,77 @@ func (h *Heartbeat) loop(ctx context.Context, interval time.Duration) {
 	}
 }
 
-// tick performs a single heartbeat check.
-func (h *Heartbeat) tick(ctx context.Context) {
-	now := time.Now()
-	hour := now.Hour()
+// tick runs the named checks that are due or, without checks, the single
+// heartbeat prompt once per interval.
+func (h *Heartbeat) tick(ctx context.Context, now time.Time) {
+	h.mu.Lock()
+	cfg := h.config
+	h.mu.Unlock()
+
+	h.releaseHeld(ctx, cfg, now)
+
+	checks := h.loadChecks(cfg)
+	if len(checks) == 0 {
+		interval := cfg.Interval
+		if interval <= 0 {
+			interval = 30 * time.Minute
+		}
+		if now.Sub(h.lastPrompt) < interval-heartbeatResolution/2 {
+			return
+		}
+		h.lastPrompt = now
 
-	// Check if we're in active hours.
-	if hour < h.config.ActiveStart || hour >= h.config.ActiveEnd {
-		h.logger.Debug("heartbeat: outside active hours, skipping")
+		// Check if we're in active hours.
+		if hour := now.Hour(); hour < cfg.ActiveStart || hour >= cfg.ActiveEnd {
+			h.logger.Debug("heartbeat: outside active hours, skipping")
+			return
+		}
+		h.logger.Debug("heartbeat tick", "time", now.Format("15:04"))
+		if response := h.runTurn(ctx, "main", h.buildHeartbeatPrompt(now), nil); response != "" {
+			h.deliver(ctx, cfg, cfg.Channel, cfg.ChatID, response, now)
+		}
 		return
 	}
 
-	h.logger.Debug("heartbeat tick", "time", now.Format("15:04"))
-
-	// Build the heartbeat prompt.
-	prompt := h.buildHeartbeatPrompt(now)
+	for _, c := range checks {
+		if !h.checkDue(cfg, c, now) {
+			continue
+		}
+		h.stateFor(c.Name).lastRun = now
+		h.logger.Debug("heartbeat check", "check", c.Name, "time", now.Format("15:04"))
+
+		prompt := fmt.Sprintf("[HEARTBEAT CHECK %q at %s]\n\n%s\n\nIf there is nothing to report, respond with HEARTBEAT_OK.",
+			c.Name, now.Format("2006-01-02 15:04"), c.Prompt)
+		response := h.runTurn(ctx, "check:"+c.Name, prompt, func(alert string) bool {
+			return h.isDuplicateAlert(c, alert, now)
+		})
+		if response == "" {
+			continue
+		}
+		channel, chatID := cfg.Channel, cfg.ChatID
+		if c.Channel != "" && c.ChatID != "" {
+			channel, chatID = c.Channel, c.ChatID
+		}
+		h.deliver(ctx, cfg, channel, chatID, response, now)
+	}
+}
 
-	// Run an agent turn with the heartbeat prompt.
-	session := h.assistant.sessionStore.GetOrCreate("heartbeat", "main")
+// runTurn runs one heartbeat agent turn in the heartbeat session chatID and
+// returns the reply, or "" when there is nothing to deliver. Replies that
+// suppress reports as duplicates are dropped.
+func (h *Heartbeat) runTurn(ctx context.Context, chatID, prompt string, suppress func(string) bool) string {
+	session := h.assistant.sessionStore.GetOrCreate("heartbeat", chatID)
 	systemPrompt := h.assistant.promptComposer.Compose(session, prompt)
 
 	agent := NewAgentRun(h.assistant.llmClient, h.assistant.toolExecutor, h.logger)
 
-	turnCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	turnCtx, cancel := context.WithTimeout(ContextWithResponseCache(ctx), 2*time.Minute)
 	defer cancel()
 
 	response, err := agent.Run(turnCtx, systemPrompt, session.RecentHistory(5), prompt)
 	if err != nil {
-		h.logger.Error("heartbeat agent turn failed", "error", err)
-		return
+		h.logger.Error("heartbeat agent turn failed", "session", chatID, "error", err)
+		return ""
 	}
 
 	// If the response is just HEARTBEAT_OK or empty, skip delivery AND skip
@@ -160,25 +246,50 @@ func (h *Heartbeat) tick(ctx context.Context) {
 	// bloating the transcript over time ("Heartbeat Transcript Pruning").
 	trimmed := strings.TrimSpace(response)
 	if trimmed == "" || strings.EqualFold(trimmed, TokenHeartbeatOK) || strings.EqualFold(trimmed, TokenNoReply) {
-		h.logger.Debug("heartbeat: nothing to deliver, pruning from transcript")
-		return
+		h.logger.Debug("heartbeat: nothing to deliver, pruning from transcript", "session", chatID)
+		return ""
+	}
+	if suppress != nil && suppress(trimmed) {
+		h.logger.Info("heartbeat: duplicate alert suppressed", "session", chatID)
+		return ""
 	}
 
 	// Only save to session when the heartbeat produced an actionable response.
 	session.AddMessage(prompt, response)
+	return response
+}
 
-	// Deliver proactive message to configured channel.
-	if h.config.Channel != "" && h.config.ChatID != "" {
-		outMsg := &channels.OutgoingMessage{Content: response}
-		if err := h.assistant.channelMgr.Send(ctx, h.config.Channel, h.config.ChatID, outMsg); err != nil {
-			h.logger.Error("heartbeat: failed to deliver message", "error", err)
-		} else {
-			h.logger.Info("heartbeat: proactive message delivered",
-				"channel", h.config.Channel,
-				"response_len", len(response),
-			)
+// deliver sends a proactive heartbeat message to a chat, or holds it while
+// the chat is in quiet hours.
+func (h *Heartbeat) deliver(ctx context.Context, cfg HeartbeatConfig, channel, chatID, response string, now time.Time) {
+	if channel == "" || chatID == "" {
+		return
+	}
+	if h.assistant != nil && h.assistant.workspaceMgr != nil {
+		if ws, ok := h.assistant.workspaceMgr.Get(h.assistant.workspaceMgr.WorkspaceIDForSession(channel + ":" + chatID)); ok && !h.assistant.featureEnabled(ws, FeatureHeartbeat) {
+			h.logger.Info("heartbeat: proactive messages are disabled for the workspace", "channel", channel, "workspace", ws.ID)
+			return
 		}
 	}
+	if window, quiet := h.quietWindow(cfg, channel, chatID, now); quiet {
+		h.hold(heldHeartbeat{channel: channel, chatID: chatID, text: response, at: now})
+		h.logger.Info("heartbeat: message held for quiet hours", "channel", channel, "window", window)
+		return
+	}
+	h.send(ctx, channel, chatID, response)
+}
+
+// send delivers a message to a chat.
+func (h *Heartbeat) send(ctx context.Context, channel, chatID, response string) {
+	outMsg := &channels.OutgoingMessage{Content: response}
+	if err := h.assistant.channelMgr.Send(ctx, channel, chatID, outMsg); err != nil {
+		h.logger.Error("heartbeat: failed to deliver message", "error", err)
+	} else {
+		h.logger.Info("heartbeat: proactive message delivered",
+			"channel", channel,
+			"response_len", len(response),
+		)
+	}
 }
 
 // buildHeartbeatPrompt builds the prompt for a heartbeat turn.
@@ -201,3 +312,81 @@ Review recent memory for anything time-sensitive.
 If there is nothing to do, respond with HEARTBEAT_OK.
 If there is something to communicate to the user, write a concise message.`, now.Format("2006-01-02 15:04"))
 }
+
+// quietWindow reports whether proactive messages to the chat are held at
+// now, and the window responsible: heartbeat.quiet_hours (local time) or the
+// dnd window of the chat's workspace (in the workspace's timezone).
+func (h *Heartbeat) quietWindow(cfg HeartbeatConfig, channel, chatID string, now time.Time) (string, bool) {
+	if cfg.QuietHours != "" && inQuietHours(cfg.QuietHours, now) {
+		return cfg.QuietHours, true
+	}
+	if h.assistant == nil || h.assistant.workspaceMgr == nil {
+		return "", false
+	}
+	ws, ok := h.assistant.workspaceMgr.Get(h.assistant.workspaceMgr.WorkspaceIDForSession(channel + ":" + chatID))
+	if !ok || ws.DND == "" {
+		return "", false
+	}
+	local := now
+	if ws.Timezone != "" {
+		if loc, err := scheduler.LoadTimezone(ws.Timezone); err == nil {
+			local = now.In(loc)
+		}
+	}
+	if inQuietHours(ws.DND, local) {
+		return ws.DND + " (workspace " + ws.ID + ")", true
+	}
+	return "", false
+}
+
+// hold queues a message until the chat's quiet hours end.
+func (h *Heartbeat) hold(m heldHeartbeat) {
+	n := 0
+	for i := len(h.held) - 1; i >= 0; i-- {
+		if h.held[i].channel == m.channel && h.held[i].chatID == m.chatID {
+			if n++; n >= maxHeldHeartbeats {
+				h.held = append(h.held[:i], h.held[i+1:]...)
+			}
+		}
+	}
+	h.held = append(h.held, m)
+}
+
+// releaseHeld delivers the held messages of chats whose quiet hours ended,
+// combined into one message per chat.
+func (h *Heartbeat) releaseHeld(ctx context.Context, cfg HeartbeatConfig, now time.Time) {
+	if len(h.held) == 0 {
+		return
+	}
+	var (
+		keep  []heldHeartbeat
+		order []string
+		ready = make(map[string][]heldHeartbeat)
+	)
+	for _, m := range h.held {
+		if _, quiet := h.quietWindow(cfg, m.channel, m.chatID, now); quiet {
+			keep = append(keep, m)
+			continue
+		}
+		key := m.channel + ":" + m.chatID
+		if ready[key] == nil {
+			order = append(order, key)
+		}
+		ready[key] = append(ready[key], m)
+	}
+	h.held = keep
+
+	for _, key := range order {
+		msgs := ready[key]
+		if len(msgs) == 1 {
+			h.send(ctx, msgs[0].channel, msgs[0].chatID, msgs[0].text)
+			continue
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "%d messages held during quiet hours:", len(msgs))
+		for _, m := range msgs {
+			fmt.Fprintf(&b, "\n\n[%s]\n%s", m.at.Format("15:04"), m.text)
+		}
+		h.send(ctx, msgs[0].channel, msgs[0].chatID, b.String())
+	}
+}
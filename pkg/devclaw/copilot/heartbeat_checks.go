@@ -0,0 +1,263 @@
+// Package copilot – heartbeat_checks.go implements named heartbeat checks.
+// Instead of one periodic prompt, the heartbeat can run several checks, each
+// with its own interval, quiet hours, target chat and deduplication window,
+// defined in config (heartbeat.checks) or as sections of HEARTBEAT.md:
+//
+//	## disk-space
+//	every: 1h
+//	quiet: 22:00-07:00
+//	channel: telegram:123456789
+//	dedup: 12h
+//
+//	Check disk usage with df -h and alert if a filesystem is above 90%.
+//
+// A HEARTBEAT.md without any "every:" line keeps the single-prompt behavior.
+package copilot
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultHeartbeatDedup is how long an identical alert of a check is
+// suppressed when the check sets no dedup window.
+const defaultHeartbeatDedup = 24 * time.Hour
+
+// HeartbeatCheck is a named heartbeat check with its own schedule.
+type HeartbeatCheck struct {
+	// Name identifies the check in logs and its session.
+	Name string `yaml:"name"`
+
+	// Prompt is what the agent checks.
+	Prompt string `yaml:"prompt"`
+
+	// Interval between runs (default: heartbeat.interval).
+	Interval time.Duration `yaml:"interval"`
+
+	// QuietHours is a local "HH:MM-HH:MM" range (may wrap midnight) in which
+	// the check does not run. Empty = heartbeat active_start/active_end.
+	QuietHours string `yaml:"quiet_hours"`
+
+	// Channel and ChatID override the heartbeat's delivery target.
+	Channel string `yaml:"channel"`
+	ChatID  string `yaml:"chat_id"`
+
+	// DedupWindow suppresses repeating the same alert within the window
+	// (default: 24h).
+	DedupWindow time.Duration `yaml:"dedup_window"`
+}
+
+// heartbeatCheckState is the run and alert history of one check.
+type heartbeatCheckState struct {
+	lastRun   time.Time
+	lastAlert string // hash of the last delivered alert
+	alertedAt time.Time
+}
+
+// heartbeatMeta matches the metadata lines at the top of a check section.
+var heartbeatMeta = regexp.MustCompile(`(?i)^(every|interval|quiet|quiet_hours|channel|dedup):\s*(.+)$`)
+
+// parseHeartbeatChecks reads the checks of a HEARTBEAT.md. It returns nil
+// when no section has an "every:" line (a plain checklist).
+func parseHeartbeatChecks(content string) ([]HeartbeatCheck, error) {
+	var (
+		checks    []HeartbeatCheck
+		scheduled bool
+		cur       *HeartbeatCheck
+		body      []string
+		inMeta    bool
+	)
+	flush := func() {
+		if cur != nil {
+			cur.Prompt = strings.TrimSpace(strings.Join(body, "\n"))
+			if cur.Prompt != "" {
+				checks = append(checks, *cur)
+			}
+		}
+		cur, body = nil, nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if name, ok := strings.CutPrefix(trimmed, "## "); ok {
+			flush()
+			cur, inMeta = &HeartbeatCheck{Name: strings.TrimSpace(name)}, true
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if inMeta {
+			if trimmed == "" {
+				continue
+			}
+			if m := heartbeatMeta.FindStringSubmatch(trimmed); m != nil {
+				if err := setHeartbeatMeta(cur, strings.ToLower(m[1]), strings.TrimSpace(m[2])); err != nil {
+					return nil, fmt.Errorf("check %q: %w", cur.Name, err)
+				}
+				if strings.EqualFold(m[1], "every") || strings.EqualFold(m[1], "interval") {
+					scheduled = true
+				}
+				continue
+			}
+			inMeta = false
+		}
+		body = append(body, line)
+	}
+	flush()
+
+	if !scheduled {
+		return nil, nil
+	}
+	return checks, nil
+}
+
+// setHeartbeatMeta applies one metadata line to a check.
+func setHeartbeatMeta(c *HeartbeatCheck, key, value string) error {
+	switch key {
+	case "every", "interval":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < time.Minute {
+			return fmt.Errorf("invalid interval %q (e.g. 15m, 2h; at least 1m)", value)
+		}
+		c.Interval = d
+	case "quiet", "quiet_hours":
+		if _, _, err := parseQuietHours(value); err != nil {
+			return err
+		}
+		c.QuietHours = value
+	case "channel":
+		channel, chatID, ok := strings.Cut(value, ":")
+		if !ok || channel == "" || chatID == "" {
+			return fmt.Errorf("invalid channel %q (use channel:chat_id)", value)
+		}
+		c.Channel, c.ChatID = channel, chatID
+	case "dedup":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid dedup window %q", value)
+		}
+		c.DedupWindow = d
+	}
+	return nil
+}
+
+// parseQuietHours parses "HH:MM-HH:MM" into minutes since midnight.
+func parseQuietHours(s string) (start, end int, err error) {
+	from, to, ok := strings.Cut(strings.ReplaceAll(s, " ", ""), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid quiet hours %q (use HH:MM-HH:MM)", s)
+	}
+	clock := func(v string) (int, error) {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid quiet hours %q (use HH:MM-HH:MM)", s)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if start, err = clock(from); err != nil {
+		return 0, 0, err
+	}
+	if end, err = clock(to); err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// inQuietHours reports whether now falls in the "HH:MM-HH:MM" range. Ranges
+// where the end is before the start wrap midnight.
+func inQuietHours(quiet string, now time.Time) bool {
+	start, end, err := parseQuietHours(quiet)
+	if err != nil || start == end {
+		return false
+	}
+	m := now.Hour()*60 + now.Minute()
+	if start < end {
+		return m >= start && m < end
+	}
+	return m >= start || m < end
+}
+
+// loadChecks returns the configured checks followed by those of
+// HEARTBEAT.md; a file check replaces a config check with the same name.
+func (h *Heartbeat) loadChecks(cfg HeartbeatConfig) []HeartbeatCheck {
+	checks := append([]HeartbeatCheck(nil), cfg.Checks...)
+	content, err := os.ReadFile(filepath.Join(cfg.WorkspaceDir, "HEARTBEAT.md"))
+	if err != nil {
+		return checks
+	}
+	fileChecks, err := parseHeartbeatChecks(string(content))
+	if err != nil {
+		h.logger.Warn("heartbeat: invalid HEARTBEAT.md check", "error", err)
+		return checks
+	}
+	for _, fc := range fileChecks {
+		replaced := false
+		for i := range checks {
+			if checks[i].Name == fc.Name {
+				checks[i], replaced = fc, true
+			}
+		}
+		if !replaced {
+			checks = append(checks, fc)
+		}
+	}
+	return checks
+}
+
+// checkDue reports whether check should run at now.
+func (h *Heartbeat) checkDue(cfg HeartbeatConfig, c HeartbeatCheck, now time.Time) bool {
+	if c.QuietHours != "" {
+		if inQuietHours(c.QuietHours, now) {
+			return false
+		}
+	} else if now.Hour() < cfg.ActiveStart || now.Hour() >= cfg.ActiveEnd {
+		return false
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = cfg.Interval
+	}
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	st := h.state[c.Name]
+	return st == nil || now.Sub(st.lastRun) >= interval
+}
+
+// isDuplicateAlert reports whether alert repeats the last alert of the check
+// within its dedup window, and records it otherwise. Case and whitespace are
+// ignored when comparing.
+func (h *Heartbeat) isDuplicateAlert(c HeartbeatCheck, alert string, now time.Time) bool {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(alert)), " ")))
+	hash := fmt.Sprintf("%x", sum[:8])
+	window := c.DedupWindow
+	if window == 0 {
+		window = defaultHeartbeatDedup
+	}
+
+	st := h.stateFor(c.Name)
+	if st.lastAlert == hash && now.Sub(st.alertedAt) < window {
+		return true
+	}
+	st.lastAlert, st.alertedAt = hash, now
+	return false
+}
+
+// stateFor returns the state of a check, creating it on first use.
+func (h *Heartbeat) stateFor(name string) *heartbeatCheckState {
+	if h.state == nil {
+		h.state = make(map[string]*heartbeatCheckState)
+	}
+	st := h.state[name]
+	if st == nil {
+		st = &heartbeatCheckState{}
+		h.state[name] = st
+	}
+	return st
+}
@@ -0,0 +1,153 @@
+package copilot
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+func TestParseHeartbeatChecks(t *testing.T) {
+	t.Parallel()
+
+	content := `# Heartbeat
+
+## disk-space
+every: 1h
+quiet: 22:00-07:00
+channel: telegram:123456
+dedup: 12h
+
+Check disk usage with df -h.
+Alert above 90%.
+
+## inbox
+every: 15m
+Check email for anything urgent.
+
+## empty
+every: 5m
+`
+	checks, err := parseHeartbeatChecks(content)
+	if err != nil {
+		t.Fatalf("parseHeartbeatChecks: %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checks (empty one dropped), got %+v", checks)
+	}
+	disk := checks[0]
+	if disk.Name != "disk-space" || disk.Interval != time.Hour || disk.QuietHours != "22:00-07:00" ||
+		disk.Channel != "telegram" || disk.ChatID != "123456" || disk.DedupWindow != 12*time.Hour {
+		t.Errorf("unexpected check: %+v", disk)
+	}
+	if disk.Prompt != "Check disk usage with df -h.\nAlert above 90%." {
+		t.Errorf("unexpected prompt: %q", disk.Prompt)
+	}
+	if checks[1].Name != "inbox" || checks[1].Interval != 15*time.Minute {
+		t.Errorf("unexpected check: %+v", checks[1])
+	}
+
+	// A checklist without schedules keeps the single-prompt heartbeat.
+	if checks, err := parseHeartbeatChecks("## Todo\n- water the plants\n"); err != nil || checks != nil {
+		t.Errorf("expected no checks, got %+v (err %v)", checks, err)
+	}
+	if _, err := parseHeartbeatChecks("## x\nevery: soon\nbody"); err == nil {
+		t.Error("expected error for an invalid interval")
+	}
+}
+
+func TestHeartbeatCheckScheduling(t *testing.T) {
+	t.Parallel()
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.Local) }
+
+	if !inQuietHours("22:00-07:00", day(23, 30)) || !inQuietHours("22:00-07:00", day(6, 59)) || inQuietHours("22:00-07:00", day(7, 0)) {
+		t.Error("expected quiet hours to wrap midnight")
+	}
+	if !inQuietHours("12:00-13:00", day(12, 15)) || inQuietHours("12:00-13:00", day(13, 0)) {
+		t.Error("unexpected same-day quiet hours")
+	}
+
+	h := NewHeartbeat(HeartbeatConfig{Interval: 30 * time.Minute, ActiveStart: 9, ActiveEnd: 22}, nil, slog.Default())
+	cfg := h.config
+	check := HeartbeatCheck{Name: "disk", Interval: time.Hour}
+
+	if !h.checkDue(cfg, check, day(10, 0)) {
+		t.Fatal("expected a new check to be due")
+	}
+	h.stateFor("disk").lastRun = day(10, 0)
+	if h.checkDue(cfg, check, day(10, 30)) || !h.checkDue(cfg, check, day(11, 0)) {
+		t.Error("expected the check to follow its own interval")
+	}
+	if h.checkDue(cfg, check, day(23, 0)) {
+		t.Error("expected the check to respect the active hours")
+	}
+	check.QuietHours = "01:00-02:00"
+	if !h.checkDue(cfg, check, day(23, 0)) {
+		t.Error("expected quiet hours to replace the active hours")
+	}
+
+	// The same alert is suppressed within the dedup window.
+	if h.isDuplicateAlert(check, "Disk /var is 91% full", day(11, 0)) {
+		t.Error("first alert must not be a duplicate")
+	}
+	if !h.isDuplicateAlert(check, "disk /var is  91% full", day(12, 0)) {
+		t.Error("expected the repeated alert to be suppressed")
+	}
+	if h.isDuplicateAlert(check, "Disk /var is 97% full", day(13, 0)) {
+		t.Error("a changed alert must be delivered")
+	}
+	if h.isDuplicateAlert(check, "Disk /var is 97% full", day(13, 0).Add(25*time.Hour)) {
+		t.Error("expected the alert again after the dedup window")
+	}
+}
+
+func TestHeartbeatQuietHoursHoldAndRelease(t *testing.T) {
+	t.Parallel()
+	ch := &fakeEditChannel{messages: map[string]string{}}
+	mgr := channels.NewManager(slog.Default())
+	if err := mgr.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+	wsCfg := DefaultWorkspaceConfig()
+	wsCfg.Workspaces = append(wsCfg.Workspaces, Workspace{ID: "family", Active: true, DND: "20:00-21:00", Members: []string{"mom"}})
+	a := &Assistant{config: DefaultConfig(), channelMgr: mgr, workspaceMgr: NewWorkspaceManager(DefaultConfig(), wsCfg, nil)}
+	h := NewHeartbeat(HeartbeatConfig{}, a, slog.Default())
+	cfg := HeartbeatConfig{QuietHours: "23:00-08:00"}
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 2, h, m, 0, 0, time.Local) }
+	ctx := context.Background()
+
+	h.deliver(ctx, cfg, "fake", "owner", "Disk almost full", day(23, 30))
+	h.deliver(ctx, cfg, "fake", "owner", "Backup failed", day(3, 0))
+	h.deliver(ctx, cfg, "fake", "mom", "Dinner reminder", day(20, 15)) // workspace DND
+	if len(ch.texts()) != 0 || len(h.held) != 3 {
+		t.Fatalf("expected 3 held messages, sent %q, held %d", ch.texts(), len(h.held))
+	}
+
+	h.releaseHeld(ctx, cfg, day(7, 59))
+	if len(ch.texts()) != 0 {
+		t.Fatalf("released during quiet hours: %q", ch.texts())
+	}
+	h.releaseHeld(ctx, cfg, day(8, 0))
+	got := ch.texts()
+	if len(got) != 2 || !strings.Contains(got[0], "2 messages held") || !strings.Contains(got[0], "Backup failed") || got[1] != "Dinner reminder" {
+		t.Fatalf("unexpected release: %q", got)
+	}
+	if len(h.held) != 0 {
+		t.Errorf("expected no held messages, got %d", len(h.held))
+	}
+
+	// Outside every window messages go straight out, and the queue is bounded.
+	h.deliver(ctx, cfg, "fake", "owner", "Now", day(12, 0))
+	if got := ch.texts(); got[len(got)-1] != "Now" {
+		t.Errorf("expected immediate delivery, got %q", got)
+	}
+	for i := range maxHeldHeartbeats + 5 {
+		h.deliver(ctx, cfg, "fake", "owner", strings.Repeat("x", i+1), day(1, 0))
+	}
+	if len(h.held) != maxHeldHeartbeats || h.held[0].text != strings.Repeat("x", 6) {
+		t.Errorf("expected the %d newest messages held, got %d", maxHeldHeartbeats, len(h.held))
+	}
+}
@@ -0,0 +1,89 @@
+// Package copilot – host_shell.go builds the host shell invocations used
+// by the bash tool and the daemon manager: bash on Unix and PowerShell on
+// Windows. The bash tool wraps each command so the shell reports its final
+// working directory, which makes cd persist between calls on both.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+// cwdMarker prefixes the working-directory line appended to bash tool output.
+const cwdMarker = "__DEVCLAW_CWD="
+
+// bashToolDescription describes the bash tool for the host shell.
+func bashToolDescription() string {
+	if runtime.GOOS == "windows" {
+		return "Execute a PowerShell command with full system access (this host runs Windows). " +
+			"Inherits the user's complete environment. Supports cd/Set-Location (persistent between calls), " +
+			"git, ssh, docker, package managers, builds, system administration, or any shell operation. " +
+			"Use PowerShell syntax (Get-ChildItem, $env:VAR, `;` to chain). The command runs directly on " +
+			"the host machine as the current user."
+	}
+	return "Execute a bash command with full system access. Inherits the user's complete environment (PATH, SSH keys, etc). Supports cd (persistent between calls), git, ssh, docker, package managers, builds, system administration, or any shell operation. The command runs directly on the host machine as the current user."
+}
+
+// shellCommand runs command through the host shell (bash -c, or
+// PowerShell -Command on Windows).
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, sandbox.PowerShell(), sandbox.PowerShellCommandArgs(command)...)
+	}
+	return exec.CommandContext(ctx, "bash", "-c", command)
+}
+
+// shellQuote quotes s as one word for the shell shellCommand uses.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// bashToolCommand runs command in wd (if set) and prints cwdMarker with
+// the final working directory, keeping the command's exit status. On Unix
+// it uses a login shell to inherit the user's full environment
+// (~/.bashrc, ~/.profile, SSH agent, etc). The command's process tree is
+// killed when ctx ends.
+func bashToolCommand(ctx context.Context, command, wd string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, sandbox.PowerShell(), sandbox.PowerShellCommandArgs(wrapPowerShell(command, wd))...)
+	} else {
+		cmd = exec.CommandContext(ctx, "bash", "-l", "-c", wrapBash(command, wd))
+	}
+	// Kill all child processes (nohup, background &, etc.) on timeout.
+	sandbox.KillTreeOnCancel(cmd)
+	return cmd
+}
+
+// wrapBash prepends cd and appends the cwd capture for bash.
+func wrapBash(command, wd string) string {
+	if wd != "" {
+		command = fmt.Sprintf("cd %q && %s", wd, command)
+	}
+	return command + " ; __exit=$?; echo \"" + cwdMarker + "$(pwd)\"; exit $__exit"
+}
+
+// wrapPowerShell prepends Set-Location and appends the cwd capture for
+// PowerShell. A failed cmdlet or a non-zero native exit code both make
+// the script exit non-zero. Output is forced to UTF-8 so non-ASCII text
+// survives the console code page.
+func wrapPowerShell(command, wd string) string {
+	var b strings.Builder
+	b.WriteString("[Console]::OutputEncoding = [System.Text.Encoding]::UTF8\n")
+	if wd != "" {
+		fmt.Fprintf(&b, "Set-Location -LiteralPath '%s'\n", strings.ReplaceAll(wd, "'", "''"))
+	}
+	b.WriteString(command)
+	b.WriteString("\n$__ok = $?; $__code = $LASTEXITCODE\n")
+	b.WriteString("Write-Output \"" + cwdMarker + "$((Get-Location).Path)\"\n")
+	b.WriteString("if (-not $__ok) { if ($__code) { exit $__code }; exit 1 }\nexit 0")
+	return b.String()
+}
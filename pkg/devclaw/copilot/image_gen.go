@@ -0,0 +1,282 @@
+// Package copilot – image_gen.go registers the generate_image tool. It calls
+// an OpenAI-compatible images API (DALL·E, gpt-image-1) or a Stable
+// Diffusion WebUI endpoint (SDXL), saves the PNG under the data directory
+// and hands it back as an attachment, which the channel delivers after the
+// reply. Size and per-day limits are enforced by the ToolGuard
+// (max_image_size, daily_limits).
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImageGenConfig configures the generate_image tool.
+type ImageGenConfig struct {
+	// Enabled registers the generate_image tool (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// Provider is "openai" (default, any OpenAI-compatible images API) or
+	// "sdxl" (Stable Diffusion WebUI txt2img API).
+	Provider string `yaml:"provider"`
+
+	// BaseURL is the API endpoint. Defaults to https://api.openai.com/v1
+	// for openai and http://127.0.0.1:7860 for sdxl.
+	BaseURL string `yaml:"base_url"`
+
+	// APIKey authenticates with the provider. Falls back to OPENAI_API_KEY
+	// for openai.
+	APIKey string `yaml:"api_key"`
+
+	// Model is the image model (default: dall-e-3; ignored by sdxl).
+	Model string `yaml:"model"`
+
+	// DefaultSize is used when the agent doesn't ask for one
+	// (default: 1024x1024).
+	DefaultSize string `yaml:"default_size"`
+
+	// Steps is the number of sampling steps for sdxl (default: 30).
+	Steps int `yaml:"steps"`
+}
+
+// DefaultImageGenConfig returns the default image generation configuration.
+func DefaultImageGenConfig() ImageGenConfig {
+	return ImageGenConfig{
+		Provider:    "openai",
+		Model:       "dall-e-3",
+		DefaultSize: "1024x1024",
+		Steps:       30,
+	}
+}
+
+// imageGenTimeout bounds a single generation request.
+const imageGenTimeout = 3 * time.Minute
+
+// imageGenerator produces PNG bytes for a prompt.
+type imageGenerator struct {
+	cfg    ImageGenConfig
+	client *http.Client
+}
+
+// RegisterImageGenTool registers generate_image when enabled. Images are
+// saved under dataDir/images.
+func RegisterImageGenTool(executor *ToolExecutor, cfg ImageGenConfig, dataDir string) {
+	if !cfg.Enabled {
+		return
+	}
+	gen := newImageGenerator(cfg)
+	outDir := filepath.Join(dataDir, "images")
+
+	executor.Register(
+		MakeToolDefinition("generate_image", "Generate an image from a text prompt and send it to the user. Write a detailed prompt in English (subject, style, lighting, composition). The image is delivered after your reply; don't repeat the file path.", map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"prompt": map[string]any{
+					"type":        "string",
+					"description": "Detailed description of the image",
+				},
+				"size": map[string]any{
+					"type":        "string",
+					"description": "WIDTHxHEIGHT, e.g. 1024x1024, 1024x1792 (portrait), 1792x1024 (landscape). Default: " + gen.cfg.DefaultSize,
+				},
+				"caption": map[string]any{
+					"type":        "string",
+					"description": "Optional caption sent with the image",
+				},
+			},
+			"required": []string{"prompt"},
+		}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			prompt, _ := args["prompt"].(string)
+			if strings.TrimSpace(prompt) == "" {
+				return nil, fmt.Errorf("prompt is required")
+			}
+			size, _ := args["size"].(string)
+			if _, _, ok := parseImageSize(size); !ok {
+				size = gen.cfg.DefaultSize
+			}
+			caption, _ := args["caption"].(string)
+
+			data, err := gen.generate(ctx, prompt, size)
+			if err != nil {
+				return nil, err
+			}
+			path, err := saveGeneratedImage(outDir, data)
+			if err != nil {
+				return nil, err
+			}
+			return ToolBlocks{
+				TextBlock(fmt.Sprintf("Image generated (%s, %d KB).", size, len(data)/1024)),
+				AttachmentBlock(path, "image/png", caption),
+			}, nil
+		},
+	)
+}
+
+func newImageGenerator(cfg ImageGenConfig) *imageGenerator {
+	def := DefaultImageGenConfig()
+	if cfg.Provider == "" {
+		cfg.Provider = def.Provider
+	}
+	if cfg.Model == "" {
+		cfg.Model = def.Model
+	}
+	if _, _, ok := parseImageSize(cfg.DefaultSize); !ok {
+		cfg.DefaultSize = def.DefaultSize
+	}
+	if cfg.Steps <= 0 {
+		cfg.Steps = def.Steps
+	}
+	if cfg.BaseURL == "" {
+		if cfg.Provider == "sdxl" {
+			cfg.BaseURL = "http://127.0.0.1:7860"
+		} else {
+			cfg.BaseURL = "https://api.openai.com/v1"
+		}
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.APIKey == "" && cfg.Provider != "sdxl" {
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return &imageGenerator{cfg: cfg, client: &http.Client{Timeout: imageGenTimeout}}
+}
+
+// generate returns the PNG bytes of one image.
+func (g *imageGenerator) generate(ctx context.Context, prompt, size string) ([]byte, error) {
+	if g.cfg.Provider == "sdxl" {
+		return g.generateSDXL(ctx, prompt, size)
+	}
+	return g.generateOpenAI(ctx, prompt, size)
+}
+
+func (g *imageGenerator) generateOpenAI(ctx context.Context, prompt, size string) ([]byte, error) {
+	if g.cfg.APIKey == "" {
+		return nil, fmt.Errorf("image generation requires image_gen.api_key or OPENAI_API_KEY")
+	}
+	body := map[string]any{
+		"model":  g.cfg.Model,
+		"prompt": prompt,
+		"n":      1,
+		"size":   size,
+	}
+	// gpt-image models always return base64 and reject response_format.
+	if !strings.HasPrefix(g.cfg.Model, "gpt-image") {
+		body["response_format"] = "b64_json"
+	}
+
+	var resp struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := g.post(ctx, g.cfg.BaseURL+"/images/generations", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("image API error: %s", resp.Error.Message)
+	}
+	if len(resp.Data) == 0 || resp.Data[0].B64JSON == "" {
+		return nil, fmt.Errorf("image API returned no image")
+	}
+	return base64.StdEncoding.DecodeString(resp.Data[0].B64JSON)
+}
+
+func (g *imageGenerator) generateSDXL(ctx context.Context, prompt, size string) ([]byte, error) {
+	w, h, _ := parseImageSize(size)
+	body := map[string]any{
+		"prompt": prompt,
+		"width":  w,
+		"height": h,
+		"steps":  g.cfg.Steps,
+	}
+	var resp struct {
+		Images []string `json:"images"`
+	}
+	if err := g.post(ctx, g.cfg.BaseURL+"/sdapi/v1/txt2img", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Images) == 0 {
+		return nil, fmt.Errorf("image API returned no image")
+	}
+	return base64.StdEncoding.DecodeString(resp.Images[0])
+}
+
+// post sends a JSON request and decodes the JSON response into out.
+func (g *imageGenerator) post(ctx context.Context, url string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.APIKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("image API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return fmt.Errorf("reading image API response: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("image API returned %s", resp.Status)
+		}
+		return fmt.Errorf("decoding image API response: %w", err)
+	}
+	return nil
+}
+
+// saveGeneratedImage writes the PNG to dir with owner-only permissions.
+func saveGeneratedImage(dir string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating image directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, time.Now().Format("20060102-150405")+"-*.png")
+	if err != nil {
+		return "", fmt.Errorf("creating image file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writing image: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// parseImageSize parses "WIDTHxHEIGHT".
+func parseImageSize(s string) (w, h int, ok bool) {
+	ws, hs, found := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !found {
+		return 0, 0, false
+	}
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
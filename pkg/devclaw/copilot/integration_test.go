@@ -90,21 +90,6 @@ func TestStrategyLoopIntegration(t *testing.T) {
 	t.Logf("Strategy loop detected after %d attempts with message: %s", result.Streak, result.Message)
 }
 
-// TestReflectionIntervalReduction verifies that reflection happens every 5 turns
-func TestReflectionIntervalReduction(t *testing.T) {
-	t.Parallel()
-	
-	// Verify the constant is set correctly
-	if reflectionInterval != 5 {
-		t.Errorf("Expected reflectionInterval to be 5, got %d", reflectionInterval)
-	}
-	
-	// Verify it's documented
-	const expectedComment = "Reduced from 15 to 5 to catch stuck patterns earlier"
-	// This is a compile-time check - if the constant exists, the code compiles
-	_ = reflectionInterval
-}
-
 // TestPromptLayerIntegration verifies that new prompt sections are included
 func TestPromptLayerIntegration(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,134 @@
+// Package copilot – intent_split.go detects messages that bundle several
+// unrelated requests ("fix the build, also what's the weather, and remind me
+// at 5") and tells the agent to handle them as ordered sub-tasks, answering
+// each in its own section. A cheap text check gates the LLM call that does
+// the actual split, so ordinary messages cost nothing extra.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IntentSplitConfig configures multi-intent detection.
+type IntentSplitConfig struct {
+	// Enabled turns on intent splitting (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// MinChars is the shortest message considered (default: 40).
+	MinChars int `yaml:"min_chars"`
+
+	// MaxIntents caps the sub-tasks of one message (default: 5).
+	MaxIntents int `yaml:"max_intents"`
+}
+
+// DefaultIntentSplitConfig returns the default intent splitting configuration.
+func DefaultIntentSplitConfig() IntentSplitConfig {
+	return IntentSplitConfig{Enabled: true, MinChars: 40, MaxIntents: 5}
+}
+
+// intentSplitMaxChars skips long messages: they are documents or detailed
+// specs of one task more often than lists of unrelated requests.
+const intentSplitMaxChars = 2000
+
+// intentConnectors join separate requests in English and Portuguese.
+var intentConnectors = []string{
+	" also ", " and also ", "additionally", "another thing", "besides that",
+	"one more thing", "oh and", "plus,", " then also ",
+	" também ", "além disso", "outra coisa", "mais uma coisa", "ah, e ", " e ainda ",
+}
+
+// intentListItem matches numbered or bulleted lines.
+var intentListItem = regexp.MustCompile(`(?m)^\s*(?:\d+[.)]|[-*•])\s+\S`)
+
+// looksMultiIntent is the cheap gate before the split call: a connector
+// between requests, several questions, a semicolon, or a list. Messages
+// with code blocks are left alone.
+func looksMultiIntent(text string, minChars int) bool {
+	if len(text) < minChars || len(text) > intentSplitMaxChars || strings.Contains(text, "```") {
+		return false
+	}
+	lower := " " + strings.ToLower(text) + " "
+	for _, c := range intentConnectors {
+		if strings.Contains(lower, c) {
+			return true
+		}
+	}
+	if strings.Count(text, "?") >= 2 || strings.Count(text, ";") >= 1 {
+		return true
+	}
+	return len(intentListItem.FindAllString(text, -1)) >= 2
+}
+
+// intentSplitPrompt asks for the separate requests of a message.
+const intentSplitPrompt = `Split the user message below into its separate, unrelated requests.
+Steps of one task (e.g. "fix the bug and open a PR for it") are ONE request.
+Reply with only a JSON array of short imperative strings, in the user's language and in the order given.
+If there is only one request, reply with a one-element array.
+
+Message:
+`
+
+// parseIntents reads the JSON array of the split reply.
+func parseIntents(raw string, max int) []string {
+	start := strings.Index(raw, "[")
+	end := strings.LastIndex(raw, "]")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var items []string
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &items); err != nil {
+		return nil
+	}
+	var intents []string
+	for _, it := range items {
+		if it = strings.TrimSpace(it); it != "" {
+			intents = append(intents, it)
+		}
+	}
+	if max > 0 && len(intents) > max {
+		intents = intents[:max]
+	}
+	return intents
+}
+
+// intentGuidance is appended to the agent's copy of the user message.
+func intentGuidance(intents []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[System: This message contains %d separate requests. "+
+		"Handle them in order as separate sub-tasks, finishing (or clearly failing) one before starting the next:\n", len(intents))
+	for i, it := range intents {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, it)
+	}
+	b.WriteString("Reply with one short section per request, in the same order, each starting with the request in bold.]")
+	return b.String()
+}
+
+// splitIntents returns the message the agent should see: userContent, plus
+// sub-task guidance when it holds several unrelated requests. Failures
+// leave the message unchanged.
+func (a *Assistant) splitIntents(ctx context.Context, workspaceID, userContent string) string {
+	cfg := a.config.Agent.IntentSplit
+	if !cfg.Enabled || !looksMultiIntent(userContent, cfg.MinChars) {
+		return userContent
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	redactor := a.redactorFor(workspaceID)
+	raw, err := redactor.complete(ctx, a.llmClient, "You split messages into requests. Output only JSON.", nil, intentSplitPrompt+userContent)
+	if err != nil {
+		a.logger.Debug("intent split failed", "error", err)
+		return userContent
+	}
+	intents := parseIntents(raw, cfg.MaxIntents)
+	if len(intents) < 2 {
+		return userContent
+	}
+	a.logger.Info("multi-intent message split", "intents", len(intents))
+	return userContent + "\n\n" + intentGuidance(intents)
+}
@@ -0,0 +1,44 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIntentSplit_GateParseAndGuidance(t *testing.T) {
+	t.Parallel()
+	multi := []string{
+		"fix the build, also what's the weather in Lisbon, and remind me at 5",
+		"Can you check the deploy? And what time is the standup tomorrow?",
+		"corrige o teste de login; além disso manda o relatório pro cliente",
+		"todo for today:\n1. renew the certificate\n2. book the flight to Porto",
+	}
+	for _, m := range multi {
+		if !looksMultiIntent(m, 40) {
+			t.Errorf("expected multi-intent candidate: %q", m)
+		}
+	}
+	single := []string{
+		"fix the build",
+		"please refactor the session store so it persists to sqlite and update the docs",
+		"why does this fail?\n```go\nx := a; y := b\n```\nany idea? thanks",
+	}
+	for _, m := range single {
+		if looksMultiIntent(m, 40) {
+			t.Errorf("unexpected multi-intent candidate: %q", m)
+		}
+	}
+
+	intents := parseIntents("Sure:\n```json\n[\"Fix the build\", \" \", \"Check the weather\", \"Set a reminder for 17:00\"]\n```", 2)
+	if len(intents) != 2 || intents[0] != "Fix the build" || intents[1] != "Check the weather" {
+		t.Errorf("unexpected intents: %q", intents)
+	}
+	if parseIntents("NOTHING", 5) != nil {
+		t.Error("expected no intents without a JSON array")
+	}
+
+	g := intentGuidance([]string{"Fix the build", "Check the weather"})
+	if !strings.Contains(g, "2 separate requests") || !strings.Contains(g, "1. Fix the build\n2. Check the weather") {
+		t.Errorf("unexpected guidance: %s", g)
+	}
+}
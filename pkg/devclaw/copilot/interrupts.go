@@ -0,0 +1,121 @@
+// Package copilot – interrupts.go gives follow-ups sent during an agent run
+// a priority. A normal follow-up (steer queue mode) is added to the run's
+// context between turns. "/urgent <msg>" is injected ahead of any other
+// follow-up and the agent must address it at the next turn boundary before
+// it continues; an urgent message that arrives while the final answer is
+// being written reopens the run. "/cancel-and-ask <msg>" aborts the run and
+// starts over with the new instruction plus what the cancelled run had
+// already done. Without an active run both are ordinary messages.
+package copilot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// InterruptPriority is how a follow-up affects the active run.
+type InterruptPriority int
+
+const (
+	// InterruptNormal adds the follow-up to the run's context.
+	InterruptNormal InterruptPriority = iota
+
+	// InterruptUrgent must be addressed at the next turn boundary.
+	InterruptUrgent
+
+	// InterruptCancel aborts the run and restarts with the follow-up.
+	InterruptCancel
+)
+
+// Interrupt is a user message delivered to an active agent run.
+type Interrupt struct {
+	Priority InterruptPriority
+	Content  string
+}
+
+// message is the conversation text the agent sees for the interrupt.
+func (i Interrupt) message() string {
+	if i.Priority == InterruptUrgent {
+		return "[URGENT message from the user while you were working — address it now, before continuing the current task]\n" + i.Content
+	}
+	return "[Follow-up from user while processing]\n" + i.Content
+}
+
+// hasUrgent reports whether any of the interrupts is urgent.
+func hasUrgent(interrupts []Interrupt) bool {
+	return slices.ContainsFunc(interrupts, func(i Interrupt) bool { return i.Priority == InterruptUrgent })
+}
+
+// parseInterruptCommand recognizes "/urgent <msg>" and
+// "/cancel-and-ask <msg>".
+func parseInterruptCommand(content string) (InterruptPriority, string, bool) {
+	content = strings.TrimSpace(content)
+	for cmd, prio := range map[string]InterruptPriority{"/urgent": InterruptUrgent, "/cancel-and-ask": InterruptCancel} {
+		rest, ok := strings.CutPrefix(content, cmd)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\n') {
+			continue
+		}
+		if text := strings.TrimSpace(rest); text != "" {
+			return prio, text, true
+		}
+	}
+	return InterruptNormal, "", false
+}
+
+// interruptRun delivers an /urgent or /cancel-and-ask message to the
+// session's active run. It returns false when there is none, so the message
+// is handled as a regular one.
+func (a *Assistant) interruptRun(msg *channels.IncomingMessage, prio InterruptPriority, text string) bool {
+	sessionID := MakeSessionID(msg.Channel, msg.ChatID)
+	a.interruptInboxesMu.Lock()
+	inbox, active := a.interruptInboxes[sessionID]
+	a.interruptInboxesMu.Unlock()
+	if !active {
+		return false
+	}
+
+	if prio == InterruptUrgent {
+		select {
+		case inbox <- Interrupt{Priority: InterruptUrgent, Content: text}:
+			a.logger.Info("urgent message injected into active run", "session", sessionID)
+			a.channelMgr.SendReaction(a.ctx, msg.Channel, msg.ChatID, msg.ID, "⚡")
+			return true
+		default:
+			a.logger.Warn("interrupt inbox full, urgent message handled as a follow-up", "session", sessionID)
+			return false
+		}
+	}
+
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	runKey := resolved.Workspace.ID + ":" + resolved.Session.ID
+	a.runProgressMu.Lock()
+	progress := a.runProgress[runKey]
+	a.runProgressMu.Unlock()
+	a.activeRunsMu.Lock()
+	cancel, ok := a.activeRuns[runKey]
+	delete(a.activeRuns, runKey)
+	a.activeRunsMu.Unlock()
+	if !ok {
+		return false
+	}
+	salvaged := progress.salvage()
+	cancel()
+
+	// The new instruction supersedes queued follow-ups and runs as soon as
+	// the cancelled run releases the session.
+	restart := *msg
+	restart.Content = text
+	if salvaged != "" {
+		restart.Content = fmt.Sprintf("%s\n\n[The user cancelled the previous task to ask this. What that task had done so far:\n%s]", text, salvaged)
+	}
+	a.followupQueuesMu.Lock()
+	delete(a.followupQueues, sessionID)
+	a.followupQueuesMu.Unlock()
+	a.enqueueFollowup(&restart, sessionID, a.logger)
+	a.logger.Info("run cancelled for a new instruction", "session", sessionID, "salvaged_chars", len(salvaged))
+	a.channelMgr.SendReaction(a.ctx, msg.Channel, msg.ChatID, msg.ID, "🔁")
+	return true
+}
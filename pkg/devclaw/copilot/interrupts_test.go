@@ -0,0 +1,98 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAgentRun_UrgentInterruptReopensFinalAnswer(t *testing.T) {
+	inbox := make(chan Interrupt, 4)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			// Both arrive while the first answer is being written.
+			inbox <- Interrupt{Content: "also check the logs"}
+			inbox <- Interrupt{Priority: InterruptUrgent, Content: "the site is down"}
+			io.WriteString(w, `{"choices":[{"message":{"content":"Report done."},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+			return
+		}
+		s := string(body)
+		urgent, normal := strings.Index(s, "URGENT message"), strings.Index(s, "also check the logs")
+		if urgent < 0 || normal < 0 || urgent > normal {
+			t.Errorf("the urgent message should come first, got %s", s)
+		}
+		io.WriteString(w, `{"choices":[{"message":{"content":"Looking at the outage first."},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DefaultConfig()
+	cfg.Model = "gpt-4o"
+	cfg.API.BaseURL = srv.URL
+	cfg.API.APIKey = "key"
+	exec := NewToolExecutor(logger)
+	exec.Register(MakeToolDefinition("calc", "calc", map[string]any{"type": "object"}),
+		func(context.Context, map[string]any) (any, error) { return "ok", nil })
+
+	agent := NewAgentRun(NewLLMClient(cfg, logger), exec, logger)
+	agent.SetInterruptChannel(inbox)
+	reply, _, err := agent.RunWithUsage(context.Background(), "system", nil, "write the report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 || reply != "Looking at the outage first." {
+		t.Errorf("the urgent message should reopen the run: %d calls, reply %q", calls.Load(), reply)
+	}
+}
+
+func TestParseInterruptCommandAndSalvage(t *testing.T) {
+	t.Parallel()
+
+	for content, want := range map[string]InterruptPriority{
+		"/urgent the site is down":    InterruptUrgent,
+		"/cancel-and-ask deploy v2":   InterruptCancel,
+		"/urgent":                     -1,
+		"/urgently do it":             -1,
+		"urgent: the site is down":    -1,
+		"/cancel-and-ask\nnew  steps": InterruptCancel,
+	} {
+		prio, text, ok := parseInterruptCommand(content)
+		if want < 0 {
+			if ok {
+				t.Errorf("%q should not be an interrupt command", content)
+			}
+			continue
+		}
+		if !ok || prio != want || text == "" {
+			t.Errorf("parseInterruptCommand(%q) = %v, %q, %v", content, prio, text, ok)
+		}
+	}
+
+	var p *runProgress
+	if p.salvage() != "" {
+		t.Error("a nil progress has nothing to salvage")
+	}
+	p = &runProgress{request: "migrate the database"}
+	if p.salvage() != "" {
+		t.Error("nothing ran yet, nothing to salvage")
+	}
+	p.toolsDone([]ToolResult{
+		{Name: "bash", Content: "backup written\nto /tmp/db.sql"},
+		{Name: "bash", Content: "permission denied", Error: errors.New("exit 1")},
+	})
+	got := p.salvage()
+	for _, want := range []string{"migrate the database", "bash (ok): backup written to /tmp/db.sql", "bash (failed): permission denied"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("salvage should contain %q, got %q", want, got)
+		}
+	}
+}
@@ -127,6 +127,11 @@ func ResolveAPIKey(cfg *Config, logger *slog.Logger) *Vault {
 		return nil
 	}
 
+	if cfg.API.Subscription != "" {
+		logger.Debug("no API key, using subscription login", "subscription", cfg.API.Subscription)
+		return nil
+	}
+
 	logger.Warn("no API key found. Set one with: devclaw config set-key or devclaw config vault-set")
 	return nil
 }
@@ -0,0 +1,580 @@
+// Package copilot – knowledge_base.go implements the per-workspace knowledge
+// base. When a session has been idle for a while (24h by default) it is
+// summarized into a KB entry (topic, decisions, follow-ups), its durable
+// facts are promoted to the workspace memory scope and the session history
+// is compacted. KB entries are markdown files under <dir>/<workspace>/ and
+// are searchable with the docs_search tool.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
+)
+
+// KnowledgeBaseConfig configures the idle-session summarizer.
+type KnowledgeBaseConfig struct {
+	// Enabled turns idle-session summarization on/off (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Dir is the root of the per-workspace KB directories (default: ./data/kb).
+	Dir string `yaml:"dir"`
+
+	// IdleHours is how long a session must be inactive before it is
+	// summarized (default: 24).
+	IdleHours int `yaml:"idle_hours"`
+
+	// MinMessages skips sessions with fewer conversation turns (default: 4).
+	MinMessages int `yaml:"min_messages"`
+}
+
+// DefaultKnowledgeBaseConfig returns the default knowledge base config.
+func DefaultKnowledgeBaseConfig() KnowledgeBaseConfig {
+	return KnowledgeBaseConfig{
+		Enabled:     true,
+		Dir:         "./data/kb",
+		IdleHours:   24,
+		MinMessages: 4,
+	}
+}
+
+// kbSweepInterval is how often live sessions are checked for idleness.
+const kbSweepInterval = 10 * time.Minute
+
+// kbWorkspaceMemoryFile is the workspace-scoped fact file inside a KB dir.
+const kbWorkspaceMemoryFile = "MEMORY.md"
+
+// KBEntry is a knowledge-base entry written for an idle session.
+type KBEntry struct {
+	Topic     string   `json:"topic"`
+	Summary   string   `json:"summary"`
+	Decisions []string `json:"decisions"`
+	FollowUps []string `json:"follow_ups"`
+	Facts     []string `json:"facts"`
+
+	// Glossary holds team-specific terms the conversation used, suggested
+	// for the workspace glossary.
+	Glossary []GlossaryTerm `json:"glossary"`
+
+	SessionID string    `json:"-"`
+	Date      time.Time `json:"-"`
+}
+
+// KBHit is a docs_search result.
+type KBHit struct {
+	File    string
+	Heading string
+	Snippet string
+	Score   int
+}
+
+// KnowledgeBase stores KB entries and workspace-scoped facts.
+type KnowledgeBase struct {
+	dir    string
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// stores holds the workspace memory scope (one FileStore per workspace).
+	stores map[string]*memory.FileStore
+	// done maps session ID → last activity already summarized.
+	done map[string]time.Time
+	// inflight tracks sessions being summarized.
+	inflight map[string]bool
+}
+
+// NewKnowledgeBase creates a knowledge base rooted at dir.
+func NewKnowledgeBase(dir string, logger *slog.Logger) *KnowledgeBase {
+	if dir == "" {
+		dir = "./data/kb"
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &KnowledgeBase{
+		dir:      dir,
+		logger:   logger.With("component", "knowledge-base"),
+		stores:   make(map[string]*memory.FileStore),
+		done:     make(map[string]time.Time),
+		inflight: make(map[string]bool),
+	}
+}
+
+// workspaceDir returns the KB directory of a workspace.
+func (kb *KnowledgeBase) workspaceDir(wsID string) string {
+	if wsID == "" {
+		wsID = "default"
+	}
+	return filepath.Join(kb.dir, sanitizeKBName(wsID))
+}
+
+// WorkspaceMemory returns the workspace memory scope: a fact store shared by
+// every session in the workspace.
+func (kb *KnowledgeBase) WorkspaceMemory(wsID string) (*memory.FileStore, error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if s, ok := kb.stores[wsID]; ok {
+		return s, nil
+	}
+	s, err := memory.NewFileStore(kb.workspaceDir(wsID))
+	if err != nil {
+		return nil, err
+	}
+	kb.stores[wsID] = s
+	return s, nil
+}
+
+// WorkspaceFacts returns workspace facts relevant to query, formatted for
+// the prompt ("" if none).
+func (kb *KnowledgeBase) WorkspaceFacts(wsID, query string, maxFacts int) string {
+	if _, err := os.Stat(filepath.Join(kb.workspaceDir(wsID), kbWorkspaceMemoryFile)); err != nil {
+		return ""
+	}
+	store, err := kb.WorkspaceMemory(wsID)
+	if err != nil {
+		return ""
+	}
+	return store.RecentFacts(maxFacts, query)
+}
+
+// PromoteFacts saves facts to the workspace memory scope, skipping ones
+// already there. Returns how many were added.
+func (kb *KnowledgeBase) PromoteFacts(wsID string, facts []string) (int, error) {
+	if len(facts) == 0 {
+		return 0, nil
+	}
+	store, err := kb.WorkspaceMemory(wsID)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := store.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, e := range existing {
+		seen[strings.ToLower(strings.TrimSpace(e.Content))] = true
+	}
+
+	added := 0
+	for _, f := range facts {
+		f = strings.TrimSpace(strings.ReplaceAll(f, "\n", " "))
+		key := strings.ToLower(f)
+		if f == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := store.Save(memory.Entry{
+			Content:   f,
+			Source:    "session-summary",
+			Category:  "fact",
+			Timestamp: time.Now(),
+		}); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// WriteEntry writes a KB entry as markdown and returns its path.
+func (kb *KnowledgeBase) WriteEntry(wsID string, e KBEntry) (string, error) {
+	dir := kb.workspaceDir(wsID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating KB directory: %w", err)
+	}
+	if e.Date.IsZero() {
+		e.Date = time.Now()
+	}
+
+	base := fmt.Sprintf("%s-%s", e.Date.Format("2006-01-02"), generateSlug(e.Topic, 6))
+	path := filepath.Join(dir, base+".md")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.md", base, n))
+	}
+
+	if err := os.WriteFile(path, []byte(e.Markdown()), 0o644); err != nil {
+		return "", fmt.Errorf("writing KB entry: %w", err)
+	}
+	return path, nil
+}
+
+// Markdown renders the entry.
+func (e KBEntry) Markdown() string {
+	var b strings.Builder
+	topic := e.Topic
+	if topic == "" {
+		topic = "Session summary"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", topic)
+	fmt.Fprintf(&b, "- Date: %s\n", e.Date.Format("2006-01-02 15:04"))
+	if e.SessionID != "" {
+		fmt.Fprintf(&b, "- Session: %s\n", e.SessionID)
+	}
+	if e.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n\n%s\n", strings.TrimSpace(e.Summary))
+	}
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		for _, it := range items {
+			fmt.Fprintf(&b, "- %s\n", strings.TrimSpace(it))
+		}
+	}
+	writeList("Decisions", e.Decisions)
+	writeList("Follow-ups", e.FollowUps)
+	writeList("Facts", e.Facts)
+	return b.String()
+}
+
+// Search scores the markdown sections of a workspace KB against query and
+// returns the best matches.
+func (kb *KnowledgeBase) Search(wsID, query string, maxResults int) ([]KBHit, error) {
+	terms := kbTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+
+	dir := kb.workspaceDir(wsID)
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []KBHit
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		title, sections := splitKBSections(string(data))
+		for _, sec := range sections {
+			text := strings.ToLower(title + "\n" + sec.heading + "\n" + sec.body)
+			score := 0
+			for _, t := range terms {
+				score += strings.Count(text, t)
+			}
+			if score == 0 {
+				continue
+			}
+			heading := title
+			if sec.heading != "" {
+				heading = title + " › " + sec.heading
+			}
+			hits = append(hits, KBHit{
+				File:    filepath.Base(path),
+				Heading: heading,
+				Snippet: truncate(strings.TrimSpace(sec.body), 500),
+				Score:   score,
+			})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].File > hits[j].File // newer entries first on ties
+	})
+	if len(hits) > maxResults {
+		hits = hits[:maxResults]
+	}
+	return hits, nil
+}
+
+// claim marks a session as being summarized. Returns false if it is already
+// in flight or its current activity was summarized before.
+func (kb *KnowledgeBase) claim(s *Session) bool {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.inflight[s.ID] {
+		return false
+	}
+	if last, ok := kb.done[s.ID]; ok && !s.LastActiveAt().After(last) {
+		return false
+	}
+	kb.inflight[s.ID] = true
+	return true
+}
+
+// release ends a claim; the session's activity is recorded as summarized.
+func (kb *KnowledgeBase) release(s *Session, lastActive time.Time) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	delete(kb.inflight, s.ID)
+	kb.done[s.ID] = lastActive
+
+	// Forget sessions that have been quiet for a month; they are long gone
+	// from the session stores.
+	cutoff := time.Now().Add(-30 * 24 * time.Hour)
+	for id, t := range kb.done {
+		if t.Before(cutoff) {
+			delete(kb.done, id)
+		}
+	}
+}
+
+// kbSection is a "## heading" block of a KB markdown file.
+type kbSection struct {
+	heading string
+	body    string
+}
+
+// splitKBSections splits markdown into its "# title" and "## " sections.
+// Text before the first "## " heading forms a section with an empty heading.
+func splitKBSections(doc string) (string, []kbSection) {
+	var title string
+	var sections []kbSection
+	cur := kbSection{}
+	var body strings.Builder
+
+	flush := func() {
+		cur.body = body.String()
+		if strings.TrimSpace(cur.body) != "" || cur.heading != "" {
+			sections = append(sections, cur)
+		}
+		body.Reset()
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			flush()
+			cur = kbSection{heading: strings.TrimSpace(line[3:])}
+		case strings.HasPrefix(line, "# ") && title == "":
+			title = strings.TrimSpace(line[2:])
+		default:
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+	}
+	flush()
+	return title, sections
+}
+
+// kbTerms lowercases and splits a query into search terms.
+func kbTerms(query string) []string {
+	var out []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	}) {
+		if len(f) >= 2 {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// sanitizeKBName makes a workspace ID safe to use as a directory name.
+func sanitizeKBName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// parseKBEntry extracts the JSON object from an LLM reply, tolerating code
+// fences and surrounding prose.
+func parseKBEntry(raw string) (KBEntry, error) {
+	var e KBEntry
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return e, fmt.Errorf("no JSON object in summary")
+	}
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &e); err != nil {
+		return e, fmt.Errorf("parsing summary: %w", err)
+	}
+	if strings.TrimSpace(e.Topic) == "" && strings.TrimSpace(e.Summary) == "" {
+		return e, fmt.Errorf("summary has no topic or summary")
+	}
+	return e, nil
+}
+
+// kbSummaryPrompt asks for the structured KB entry.
+const kbSummaryPrompt = `This conversation has gone idle. Write a knowledge-base entry for it as a single JSON object with these fields:
+- "topic": short title (max 8 words)
+- "summary": 2-4 sentences on what was discussed and done
+- "decisions": decisions that were made (array of strings, may be empty)
+- "follow_ups": open questions or next steps (array of strings, may be empty)
+- "facts": durable facts worth remembering across conversations in this workspace, e.g. preferences, names, environments, conventions (array of strings, may be empty; skip anything transient)
+- "glossary": project-specific jargon, internal names or acronyms whose meaning the conversation made clear (array of {"term", "meaning"} objects, may be empty; skip common words)
+Output only the JSON object.
+
+Conversation:
+`
+
+// runKnowledgeBaseSweeper periodically summarizes sessions that have been
+// idle for longer than the configured threshold.
+func (a *Assistant) runKnowledgeBaseSweeper() {
+	idle := time.Duration(a.config.Memory.KnowledgeBase.IdleHours) * time.Hour
+	if idle <= 0 {
+		idle = 24 * time.Hour
+	}
+
+	ticker := time.NewTicker(kbSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, s := range a.workspaceMgr.IdleSessions(time.Now().Add(-idle)) {
+				if a.ctx.Err() != nil {
+					return
+				}
+				a.summarizeIdleSession(s)
+			}
+		}
+	}
+}
+
+// summarizeIdleSession writes the KB entry for an idle session, promotes its
+// durable facts to the workspace memory scope and compacts its history.
+// Sessions are summarized at most once per period of activity.
+func (a *Assistant) summarizeIdleSession(session *Session) {
+	kb := a.knowledgeBase
+	if kb == nil || !kb.claim(session) {
+		return
+	}
+	lastActive := session.LastActiveAt()
+	defer kb.release(session, lastActive)
+
+	minMessages := a.config.Memory.KnowledgeBase.MinMessages
+	if minMessages <= 0 {
+		minMessages = 4
+	}
+	history := session.RecentHistory(60)
+	if len(history) < minMessages {
+		return
+	}
+
+	var transcript strings.Builder
+	for _, entry := range history {
+		fmt.Fprintf(&transcript, "User: %s\nAssistant: %s\n\n",
+			truncate(entry.UserMessage, 500),
+			truncate(entry.AssistantResponse, 1000),
+		)
+	}
+
+	ctx, cancel := context.WithTimeout(a.ctx, 90*time.Second)
+	defer cancel()
+	redactor := a.redactorFor(a.workspaceMgr.WorkspaceIDForSession(session.ID))
+	raw, err := redactor.complete(ctx, a.llmClient, "You write concise knowledge-base entries. Output only JSON.", nil, kbSummaryPrompt+transcript.String())
+	if err != nil {
+		a.logger.Warn("idle session summary failed", "session", session.ID, "error", err)
+		return
+	}
+	entry, err := parseKBEntry(raw)
+	if err != nil {
+		a.logger.Warn("idle session summary unusable", "session", session.ID, "error", err)
+		return
+	}
+	entry.SessionID = session.Channel + ":" + session.ChatID
+	entry.Date = lastActive
+
+	wsID := session.workspaceID
+	path, err := kb.WriteEntry(wsID, entry)
+	if err != nil {
+		a.logger.Warn("failed to write KB entry", "session", session.ID, "error", err)
+		return
+	}
+
+	promoted, err := kb.PromoteFacts(wsID, append(entry.Facts, session.GetFacts()...))
+	if err != nil {
+		a.logger.Warn("failed to promote facts to workspace memory", "workspace", wsID, "error", err)
+	}
+	suggested := 0
+	if a.glossary != nil && a.config.Memory.Glossary.Suggest {
+		if suggested, err = a.glossary.Suggest(wsID, entry.Glossary); err != nil {
+			a.logger.Warn("failed to suggest glossary terms", "workspace", wsID, "error", err)
+		}
+	}
+
+	// Compact: the KB summary replaces the history, keeping the last turns
+	// so a returning user still has immediate context.
+	summary := entry.Topic
+	if entry.Summary != "" {
+		summary += "\n\n" + entry.Summary
+	}
+	oldEntries := session.CompactHistory(summary, 4)
+	if a.memoryStore != nil && len(oldEntries) > 0 {
+		_ = a.memoryStore.SaveDailyLog(time.Now(), fmt.Sprintf(
+			"### Idle session summarized: %s\n\nTopic: %s\n\nKB entry: %s\n",
+			session.ID, entry.Topic, filepath.Base(path)))
+	}
+
+	a.logger.Info("idle session summarized to knowledge base",
+		"session", session.ID,
+		"workspace", wsID,
+		"entry", path,
+		"facts_promoted", promoted,
+		"glossary_suggested", suggested,
+		"entries_compacted", len(oldEntries),
+	)
+}
+
+// RegisterKnowledgeBaseTools registers docs_search. workspaceFor maps the
+// caller's context to a workspace ID.
+func RegisterKnowledgeBaseTools(executor *ToolExecutor, kb *KnowledgeBase, workspaceFor func(ctx context.Context) string) {
+	executor.Register(
+		MakeToolDefinition("docs_search",
+			"Search this workspace's knowledge base: summaries of past conversations with their decisions, follow-ups and facts. Use it to recall what was decided or left open in earlier sessions.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "Keywords to search for",
+					},
+					"max_results": map[string]any{
+						"type":        "integer",
+						"description": "Maximum results (default: 5)",
+					},
+				},
+				"required": []string{"query"},
+			},
+		),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			query, _ := args["query"].(string)
+			maxResults := 5
+			if v, ok := args["max_results"].(float64); ok && v > 0 {
+				maxResults = int(v)
+			}
+
+			hits, err := kb.Search(workspaceFor(ctx), query, maxResults)
+			if err != nil {
+				return nil, err
+			}
+			if len(hits) == 0 {
+				return "No knowledge base entries found.", nil
+			}
+
+			var b strings.Builder
+			for _, h := range hits {
+				fmt.Fprintf(&b, "### %s (%s)\n%s\n\n", h.Heading, h.File, h.Snippet)
+			}
+			return strings.TrimSpace(b.String()), nil
+		},
+	)
+}
@@ -0,0 +1,154 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKBEntry(t *testing.T) {
+	raw := "Here you go:\n```json\n" +
+		`{"topic":"Staging deploy","summary":"Moved staging to k3s.","decisions":["Use k3s"],"follow_ups":["Rotate certs"],"facts":["Staging runs on k3s"]}` +
+		"\n```"
+	e, err := parseKBEntry(raw)
+	if err != nil {
+		t.Fatalf("parseKBEntry: %v", err)
+	}
+	if e.Topic != "Staging deploy" || len(e.Decisions) != 1 || len(e.FollowUps) != 1 || len(e.Facts) != 1 {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	if _, err := parseKBEntry("no summary here"); err == nil {
+		t.Error("expected error without JSON")
+	}
+	if _, err := parseKBEntry(`{"decisions":[]}`); err == nil {
+		t.Error("expected error without topic or summary")
+	}
+}
+
+func TestKnowledgeBase_WriteAndSearch(t *testing.T) {
+	kb := NewKnowledgeBase(t.TempDir(), nil)
+	date := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	path, err := kb.WriteEntry("work", KBEntry{
+		Topic:     "Staging deploy",
+		Summary:   "Moved staging to k3s.",
+		Decisions: []string{"Use k3s for staging"},
+		FollowUps: []string{"Rotate the ingress certificates"},
+		Date:      date,
+	})
+	if err != nil {
+		t.Fatalf("WriteEntry: %v", err)
+	}
+	if !strings.HasSuffix(path, "2026-03-02-staging-deploy.md") {
+		t.Errorf("unexpected path %s", path)
+	}
+	// Same topic on the same day gets a distinct file.
+	path2, err := kb.WriteEntry("work", KBEntry{Topic: "Staging deploy", Summary: "Again.", Date: date})
+	if err != nil || path2 == path {
+		t.Fatalf("second WriteEntry: path=%s err=%v", path2, err)
+	}
+
+	hits, err := kb.Search("work", "ingress certificates", 5)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) == 0 || !strings.Contains(hits[0].Heading, "Follow-ups") {
+		t.Fatalf("expected follow-ups section first, got %+v", hits)
+	}
+
+	// Other workspaces don't see the entry.
+	if hits, _ := kb.Search("personal", "ingress", 5); len(hits) != 0 {
+		t.Errorf("expected no hits in another workspace, got %+v", hits)
+	}
+}
+
+func TestKnowledgeBase_PromoteFactsDedup(t *testing.T) {
+	kb := NewKnowledgeBase(t.TempDir(), nil)
+
+	n, err := kb.PromoteFacts("work", []string{"Staging runs on k3s", "staging runs on K3s", ""})
+	if err != nil || n != 1 {
+		t.Fatalf("PromoteFacts: n=%d err=%v", n, err)
+	}
+	n, err = kb.PromoteFacts("work", []string{"Staging runs on k3s", "Deploys happen on Tuesdays"})
+	if err != nil || n != 1 {
+		t.Fatalf("second PromoteFacts: n=%d err=%v", n, err)
+	}
+
+	facts := kb.WorkspaceFacts("work", "", 10)
+	if !strings.Contains(facts, "Tuesdays") || strings.Count(facts, "k3s") != 1 {
+		t.Errorf("unexpected workspace facts: %q", facts)
+	}
+	if kb.WorkspaceFacts("personal", "", 10) != "" {
+		t.Error("expected no facts for an empty workspace")
+	}
+}
+
+func TestCorpusStore_IndexAndSearch(t *testing.T) {
+	docs := t.TempDir()
+	write := func(name, body string) {
+		path := filepath.Join(docs, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("contracts/acme.md", "# Acme\n\nThe termination notice period is ninety days.")
+	write("mail/inbox.eml", "Subject: lunch\n\nShall we get lunch on Friday?")
+	write("wiki/page.html", "<html><body><p>Deploys run on Tuesdays &amp; Thursdays.</p></body></html>")
+	write("image.png", "not indexed")
+	write(".git/HEAD", "ref: refs/heads/main")
+
+	store := NewCorpusStore(CorpusConfig{Dir: t.TempDir()}, nil)
+	c, stats, err := store.Add("docs", docs, []string{"work"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if c.Files != 3 || stats.Indexed != 3 {
+		t.Fatalf("expected 3 indexed files, got %+v %+v", c, stats)
+	}
+	if _, _, err := store.Add("docs", docs, nil); err == nil {
+		t.Error("expected error adding a duplicate corpus")
+	}
+	if _, _, err := store.Add("Bad Name", docs, nil); err == nil {
+		t.Error("expected error for an invalid name")
+	}
+
+	hits, err := store.Search("docs", "termination notice", 5)
+	if err != nil || len(hits) == 0 || hits[0].File != "contracts/acme.md" {
+		t.Fatalf("unexpected hits %+v (err %v)", hits, err)
+	}
+	if hits, _ := store.Search("docs", "tuesdays", 5); len(hits) != 1 || strings.Contains(hits[0].Snippet, "<p>") {
+		t.Errorf("expected the HTML page without markup, got %+v", hits)
+	}
+
+	// Reindex keeps unchanged files and drops deleted ones.
+	if err := os.Remove(filepath.Join(docs, "mail/inbox.eml")); err != nil {
+		t.Fatal(err)
+	}
+	write("notes.txt", "Quarterly planning starts in October.")
+	c, stats, err = store.Reindex("docs")
+	if err != nil {
+		t.Fatalf("Reindex: %v", err)
+	}
+	if stats.Indexed != 1 || stats.Unchanged != 2 || stats.Removed != 1 || c.Files != 3 {
+		t.Errorf("unexpected reindex stats %+v", stats)
+	}
+	if hits, _ := store.Search("docs", "quarterly planning", 5); len(hits) != 1 {
+		t.Errorf("expected the new file to be searchable, got %+v", hits)
+	}
+
+	if !c.AvailableIn("work") || c.AvailableIn("personal") {
+		t.Error("expected the corpus to be limited to the work workspace")
+	}
+	if err := store.Remove("docs"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if list, _ := store.List(); len(list) != 0 {
+		t.Errorf("expected no corpora after remove, got %+v", list)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -32,11 +33,27 @@ type StreamCallback func(chunk string)
 // LLMClient handles communication with the LLM provider API.
 type LLMClient struct {
 	baseURL    string
-	provider   string // "openai", "zai", "zai-coding", "zai-anthropic", "anthropic", ""
+	provider   string // "openai", "zai", "zai-coding", "zai-anthropic", "anthropic", "gemini", ""
 	apiKey     string
 	model      string
 	fallback   FallbackConfig
 	params     map[string]any // provider-specific params (context1m, tool_stream, etc.)
+	registry   *ModelRegistry // models.yaml overrides; nil = built-in defaults only
+	chain      []fallbackTarget // other providers from api.fallbacks / fallback.chain
+	oauth      *oauthTokenSource // subscription login; replaces apiKey when set
+
+	// Large-context model for big single-shot requests (gemini.go; nil = off).
+	largeContext          *fallbackTarget
+	largeContextMinTokens int
+
+	// Installed Ollama models and their capabilities (provider "ollama").
+	ollamaMu     sync.Mutex
+	ollamaModels map[string]ollamaModel
+
+	// Response cache for identical idempotent requests (nil = disabled).
+	respCache  *responseCache
+	onCacheHit func(model string, saved LLMUsage)
+
 	httpClient *http.Client
 	logger     *slog.Logger
 
@@ -54,6 +71,27 @@ type LLMClient struct {
 // NewLLMClient creates a new LLM client from config.
 func NewLLMClient(cfg *Config, logger *slog.Logger) *LLMClient {
 	baseURL := cfg.API.BaseURL
+
+	// Subscription login: the OAuth tokens replace the API key and the
+	// subscription decides the default endpoint and API format.
+	var subscription *SubscriptionProvider
+	if cfg.API.Subscription != "" {
+		p, err := ResolveSubscription(cfg.API.Subscription, cfg.API.OAuth)
+		if err != nil {
+			logger.Error("ignoring api.subscription", "error", err)
+		} else {
+			subscription = &p
+			if baseURL == "" {
+				baseURL = p.BaseURL
+			}
+		}
+	}
+	if baseURL == "" && cfg.API.Provider == "ollama" {
+		baseURL = defaultOllamaBaseURL
+	}
+	if baseURL == "" && cfg.API.Provider == "gemini" {
+		baseURL = defaultGeminiBaseURL
+	}
 	if baseURL == "" {
 		baseURL = "https://api.openai.com/v1"
 	}
@@ -67,8 +105,11 @@ func NewLLMClient(cfg *Config, logger *slog.Logger) *LLMClient {
 	if provider == "openai" && cfg.API.Provider != "" && cfg.API.Provider != "openai" {
 		provider = cfg.API.Provider
 	}
+	if subscription != nil && provider == "openai" {
+		provider = subscription.Provider
+	}
 
-	return &LLMClient{
+	c := &LLMClient{
 		baseURL:          baseURL,
 		provider:         provider,
 		apiKey:           cfg.API.APIKey,
@@ -94,6 +135,93 @@ func NewLLMClient(cfg *Config, logger *slog.Logger) *LLMClient {
 		},
 		logger: logger.With("component", "llm", "provider", provider),
 	}
+	c.httpClient.Transport = wrapLLMDebugTransport(c.httpClient.Transport, cfg.API.Debug, llmSecrets(cfg), c.logger)
+	c.respCache = newResponseCache(cfg.ResponseCache, c.logger)
+	c.largeContext = newLargeContextTarget(cfg.API.Providers.Gemini, c)
+	c.largeContextMinTokens = cfg.API.Providers.Gemini.LargeContextMinTokens
+
+	entries := append(append([]ProviderChainEntry{}, cfg.API.Fallbacks...), cfg.Fallback.Chain...)
+	for _, e := range entries {
+		if e.Model == "" {
+			c.logger.Warn("ignoring fallback provider without a model", "base_url", e.BaseURL)
+			continue
+		}
+		c.chain = append(c.chain, fallbackTarget{client: c.chainClient(e), model: e.Model})
+	}
+
+	// Wrap the transport after building the chain so fallback providers on
+	// other endpoints keep using their own API keys.
+	if subscription != nil {
+		c.oauth = newOAuthTokenSource(*subscription)
+		host := ""
+		if u, err := url.Parse(baseURL); err == nil {
+			host = u.Host
+		}
+		c.httpClient = &http.Client{Transport: &oauthTransport{
+			base:      c.httpClient.Transport,
+			source:    c.oauth,
+			host:      host,
+			anthropic: c.isAnthropicAPI(),
+		}}
+	}
+	return c
+}
+
+// llmSecrets lists the configured provider keys, redacted from debug logs.
+func llmSecrets(cfg *Config) []string {
+	secrets := []string{cfg.API.APIKey, cfg.API.Providers.Gemini.APIKey,
+		envOrEmpty("DEVCLAW_API_KEY"), envOrEmpty("OPENAI_API_KEY"), envOrEmpty("GEMINI_API_KEY")}
+	for _, e := range append(append([]ProviderChainEntry{}, cfg.API.Fallbacks...), cfg.Fallback.Chain...) {
+		secrets = append(secrets, e.APIKey)
+	}
+	return secrets
+}
+
+// fallbackTarget is one step of the fallback chain: a model and the client
+// for the endpoint serving it.
+type fallbackTarget struct {
+	client *LLMClient
+	model  string
+}
+
+// cooldownKey identifies the target for rate-limit cooldowns; models on
+// other providers are qualified so they don't collide with the primary's.
+func (t fallbackTarget) cooldownKey(primary *LLMClient) string {
+	if t.client == primary {
+		return t.model
+	}
+	return t.client.provider + "/" + t.model
+}
+
+// chainClient returns a client for a fallback provider. Entries without a
+// base URL use the primary endpoint; without an API key, the primary key
+// when the endpoint is the same.
+func (c *LLMClient) chainClient(e ProviderChainEntry) *LLMClient {
+	baseURL := strings.TrimRight(e.BaseURL, "/")
+	if baseURL == "" || baseURL == c.baseURL {
+		if e.APIKey == "" || e.APIKey == c.apiKey {
+			return c
+		}
+		baseURL = c.baseURL
+	}
+	apiKey := e.APIKey
+	if apiKey == "" && baseURL == c.baseURL {
+		apiKey = c.apiKey
+	}
+	provider := detectProvider(baseURL)
+	if provider == "openai" && e.Provider != "" && e.Provider != "openai" {
+		provider = e.Provider
+	}
+	return &LLMClient{
+		baseURL:    baseURL,
+		provider:   provider,
+		apiKey:     apiKey,
+		model:      e.Model,
+		fallback:   c.fallback,
+		registry:   c.registry,
+		httpClient: c.httpClient,
+		logger:     c.logger.With("fallback_provider", provider),
+	}
 }
 
 // detectProvider infers the provider from the base URL.
@@ -113,6 +241,9 @@ func detectProvider(baseURL string) string {
 		return "openrouter"
 	case strings.Contains(baseURL, "api.x.ai"):
 		return "xai"
+	case strings.Contains(baseURL, "generativelanguage.googleapis.com") &&
+		!strings.Contains(baseURL, "/openai"):
+		return "gemini"
 	case strings.Contains(baseURL, "localhost:11434"),
 		strings.Contains(baseURL, "127.0.0.1:11434"),
 		strings.Contains(baseURL, "ollama"):
@@ -122,6 +253,12 @@ func detectProvider(baseURL string) string {
 	}
 }
 
+// hasCredentials reports whether requests can be authenticated: an API
+// key, a subscription login, or a provider that needs neither.
+func (c *LLMClient) hasCredentials() bool {
+	return c.apiKey != "" || c.oauth != nil || c.provider == "ollama"
+}
+
 // isAnthropicAPI returns true if the provider uses the Anthropic Messages API format.
 func (c *LLMClient) isAnthropicAPI() bool {
 	return c.provider == "zai-anthropic" || c.provider == "anthropic"
@@ -233,6 +370,12 @@ type modelDefaults struct {
 	MaxOutputTokens int
 	// SupportsTools indicates if the model supports function/tool calling.
 	SupportsTools bool
+	// SupportsVision indicates if the model accepts image input.
+	SupportsVision bool
+	// ContextWindow is the model's context window in tokens (prompt + output).
+	ContextWindow int
+	// KnowledgeCutoff is the end of the training data, "YYYY-MM" ("" = unknown).
+	KnowledgeCutoff string
 }
 
 // getModelDefaults returns the known defaults for a given model and provider.
@@ -243,6 +386,8 @@ func getModelDefaults(model, provider string) modelDefaults {
 		DefaultTemperature:  0.7,
 		MaxOutputTokens:     0, // let server decide
 		SupportsTools:       true,
+		SupportsVision:      true,
+		ContextWindow:       128000,
 	}
 
 	switch {
@@ -250,32 +395,43 @@ func getModelDefaults(model, provider string) modelDefaults {
 	case strings.HasPrefix(model, "gpt-5"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 16384
+		d.ContextWindow = 400000
+		d.KnowledgeCutoff = "2024-09"
 	case strings.HasPrefix(model, "gpt-4o"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 16384
+		d.KnowledgeCutoff = "2023-10"
 	case strings.HasPrefix(model, "gpt-4.5"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 16384
+		d.KnowledgeCutoff = "2023-10"
 
 	// ── Anthropic models ──
 	case strings.HasPrefix(model, "claude-opus-4"):
 		d.DefaultTemperature = 1.0
 		d.MaxOutputTokens = 16384
+		d.ContextWindow = 200000
+		d.KnowledgeCutoff = "2025-03"
 	case strings.HasPrefix(model, "claude-sonnet-4-6"),
 		strings.HasPrefix(model, "claude-sonnet-4.6"):
 		d.DefaultTemperature = 1.0
 		d.MaxOutputTokens = 16384
+		d.ContextWindow = 200000
 	case strings.HasPrefix(model, "claude-sonnet-4"):
 		d.DefaultTemperature = 1.0
 		d.MaxOutputTokens = 16384
+		d.ContextWindow = 200000
+		d.KnowledgeCutoff = "2025-03"
 	case strings.HasPrefix(model, "claude-3"):
 		d.DefaultTemperature = 1.0
 		d.MaxOutputTokens = 4096
+		d.ContextWindow = 200000
 
 	// ── GLM models (Z.AI) ──
 	case strings.HasPrefix(model, "glm-5"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 8192
+		d.ContextWindow = 200000
 	case strings.HasPrefix(model, "glm-4"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 4096
@@ -284,6 +440,21 @@ func getModelDefaults(model, provider string) modelDefaults {
 	case strings.HasPrefix(model, "grok"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 16384
+		d.ContextWindow = 131072
+
+	// ── Google Gemini models ──
+	case strings.HasPrefix(model, "gemini-2.5"),
+		strings.HasPrefix(model, "gemini-3"):
+		d.DefaultTemperature = 1.0
+		d.MaxOutputTokens = 65536
+		d.ContextWindow = 1048576
+		if strings.HasPrefix(model, "gemini-2.5") {
+			d.KnowledgeCutoff = "2025-01"
+		}
+	case strings.HasPrefix(model, "gemini"):
+		d.DefaultTemperature = 1.0
+		d.MaxOutputTokens = 8192
+		d.ContextWindow = 1048576
 
 	// ── Ollama / local models ──
 	case strings.HasPrefix(model, "llama"),
@@ -296,6 +467,7 @@ func getModelDefaults(model, provider string) modelDefaults {
 		strings.HasPrefix(model, "command-r"):
 		d.DefaultTemperature = 0.7
 		d.MaxOutputTokens = 4096
+		d.ContextWindow = 32768
 	}
 
 	// Provider-level overrides.
@@ -305,6 +477,7 @@ func getModelDefaults(model, provider string) modelDefaults {
 	case "ollama":
 		// Ollama models generally support tools but have smaller context windows.
 		// Let the server decide max tokens unless model-specific above.
+		d.ContextWindow = min(d.ContextWindow, 32768)
 		if d.MaxOutputTokens == 0 {
 			d.MaxOutputTokens = 4096
 		}
@@ -313,9 +486,116 @@ func getModelDefaults(model, provider string) modelDefaults {
 	return d
 }
 
+// SetModelRegistry sets the models.yaml registry consulted for model
+// limits and capabilities.
+func (c *LLMClient) SetModelRegistry(r *ModelRegistry) {
+	c.registry = r
+	for _, t := range c.chain {
+		t.client.registry = r
+	}
+	if c.largeContext != nil {
+		c.largeContext.client.registry = r
+	}
+}
+
+// modelDefaults returns the built-in defaults for model with the registry
+// entry applied.
+func (c *LLMClient) modelDefaults(model string) modelDefaults {
+	d := getModelDefaults(model, c.provider)
+	if c.isOllama() {
+		d = c.applyOllamaCapabilities(model, d)
+	}
+	return c.registry.apply(model, d)
+}
+
+// ContextWindow returns the context window in tokens of the model the
+// client would use for modelOverride (empty = the configured model),
+// including Anthropic's 1M beta when enabled.
+func (c *LLMClient) ContextWindow(modelOverride string) int {
+	model := modelOverride
+	if model == "" {
+		model = c.model
+	}
+	if c.paramBool("context1m") && isAnthropic1MModel(model) {
+		return 1000000
+	}
+	return c.modelDefaults(model).ContextWindow
+}
+
+// KnowledgeCutoff returns the training data cutoff ("YYYY-MM") of the model
+// the client would use for modelOverride, or "" when unknown.
+func (c *LLMClient) KnowledgeCutoff(modelOverride string) string {
+	model := modelOverride
+	if model == "" {
+		model = c.model
+	}
+	return c.modelDefaults(model).KnowledgeCutoff
+}
+
+// maxOutputTokens returns the output tokens reserved for modelOverride's
+// reply (4096 when the server decides).
+func (c *LLMClient) maxOutputTokens(modelOverride string) int {
+	model := modelOverride
+	if model == "" {
+		model = c.model
+	}
+	if n := c.modelDefaults(model).MaxOutputTokens; n > 0 {
+		return n
+	}
+	return 4096
+}
+
+// hoistToolImages moves the image parts of tool results into a user message
+// after the tool messages of the turn: OpenAI-compatible APIs accept only
+// text in tool messages.
+func hoistToolImages(messages []chatMessage) []chatMessage {
+	hasImages := false
+	for _, m := range messages {
+		if _, ok := m.Content.([]contentPart); ok && m.Role == "tool" {
+			hasImages = true
+			break
+		}
+	}
+	if !hasImages {
+		return messages
+	}
+
+	out := make([]chatMessage, 0, len(messages)+1)
+	var pending []contentPart
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		parts := append([]contentPart{{Type: "text", Text: "[Images returned by the tool calls above]"}}, pending...)
+		out = append(out, chatMessage{Role: "user", Content: parts})
+		pending = nil
+	}
+	for _, m := range messages {
+		if m.Role != "tool" {
+			flush()
+			out = append(out, m)
+			continue
+		}
+		if parts, ok := m.Content.([]contentPart); ok {
+			var text []string
+			for _, p := range parts {
+				if p.ImageURL != nil {
+					pending = append(pending, p)
+				} else if p.Text != "" {
+					text = append(text, p.Text)
+				}
+			}
+			m.Content = strings.Join(text, "\n")
+		}
+		out = append(out, m)
+	}
+	flush()
+	return out
+}
+
 // applyModelDefaults populates a chatRequest with model-specific defaults.
 func (c *LLMClient) applyModelDefaults(req *chatRequest) {
-	d := getModelDefaults(req.Model, c.provider)
+	d := c.modelDefaults(req.Model)
 
 	if d.SupportsTemperature && d.DefaultTemperature > 0 && req.Temperature == nil {
 		t := d.DefaultTemperature
@@ -328,6 +608,15 @@ func (c *LLMClient) applyModelDefaults(req *chatRequest) {
 	if !d.SupportsTools {
 		req.Tools = nil
 	}
+	// Likewise images, which would otherwise fail the whole request.
+	if !d.SupportsVision {
+		req.Messages = stripImages(req.Messages)
+	}
+	// Tool messages only take text here; their images follow as user content.
+	req.Messages = hoistToolImages(req.Messages)
+
+	// Prompt cache tier breaks are only meaningful to the Anthropic API.
+	req.Messages = stripPromptCacheBreaks(req.Messages)
 
 	// Prompt caching: mark system messages with cache_control for supported providers.
 	// Anthropic and Z.AI (anthropic proxy) support prompt caching via cache_control.
@@ -458,11 +747,27 @@ type streamToolCall struct {
 // streamResponse is the SSE chunk format.
 type streamResponse struct {
 	Choices []streamChoice `json:"choices"`
-	Usage   *struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage,omitempty"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+// openAIUsage is the usage object of the OpenAI chat completions API.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"` // automatic prompt caching
+	} `json:"prompt_tokens_details"`
+}
+
+// toLLMUsage converts the API usage to LLMUsage.
+func (u openAIUsage) toLLMUsage() LLMUsage {
+	return LLMUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CacheReadTokens:  u.PromptTokensDetails.CachedTokens,
+	}
 }
 
 // chatResponse is the OpenAI-compatible chat completions response.
@@ -474,11 +779,7 @@ type chatResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage openAIUsage `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -491,7 +792,7 @@ type chatResponse struct {
 type anthropicRequest struct {
 	Model       string             `json:"model"`
 	MaxTokens   int                `json:"max_tokens"`
-	System      string             `json:"system,omitempty"`
+	System      []anthropicContent `json:"system,omitempty"` // text blocks
 	Messages    []anthropicMessage `json:"messages"`
 	Tools       []anthropicTool    `json:"tools,omitempty"`
 	Stream      bool               `json:"stream,omitempty"`
@@ -512,8 +813,10 @@ type anthropicContent struct {
 	Name      string          `json:"name,omitempty"`       // for type=tool_use
 	Input     json.RawMessage `json:"input,omitempty"`      // for type=tool_use
 	ToolUseID string          `json:"tool_use_id,omitempty"` // for type=tool_result
-	Content   string          `json:"content,omitempty"`    // for type=tool_result (string shorthand)
+	Content   any             `json:"content,omitempty"`    // for type=tool_result: string or []anthropicContent (text + images)
 	Source    *anthropicImage `json:"source,omitempty"`     // for type=image
+
+	CacheControl *cacheControl `json:"cache_control,omitempty"` // prompt caching breakpoint
 }
 
 // anthropicImage holds base64 image data for vision.
@@ -539,8 +842,10 @@ type anthropicResponse struct {
 	Content    []anthropicContent `json:"content"`
 	StopReason string             `json:"stop_reason"` // "end_turn", "tool_use", "max_tokens"
 	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
+		InputTokens              int `json:"input_tokens"` // excludes cached tokens
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	} `json:"usage"`
 	Error *struct {
 		Type    string `json:"type"`
@@ -580,12 +885,11 @@ func convertToAnthropicRequest(model string, messages []chatMessage, tools []Too
 	var anthropicMsgs []anthropicMessage
 	for _, m := range messages {
 		if m.Role == "system" {
-			switch v := m.Content.(type) {
-			case string:
-				if req.System != "" {
-					req.System += "\n\n"
+			if v, ok := m.Content.(string); ok {
+				// One block per prompt cache tier (see prompt_cache.go).
+				for _, part := range splitPromptCacheBreaks(v) {
+					req.System = append(req.System, anthropicContent{Type: "text", Text: part})
 				}
-				req.System += v
 			}
 			continue
 		}
@@ -599,6 +903,8 @@ func convertToAnthropicRequest(model string, messages []chatMessage, tools []Too
 			switch v := m.Content.(type) {
 			case string:
 				toolResult.Content = v
+			case []contentPart:
+				toolResult.Content = anthropicParts(v)
 			}
 			// Wrap in a user message.
 			anthropicMsgs = append(anthropicMsgs, anthropicMessage{
@@ -632,9 +938,13 @@ func convertToAnthropicRequest(model string, messages []chatMessage, tools []Too
 		}
 
 		// Regular user or assistant message.
+		content := m.Content
+		if parts, ok := content.([]contentPart); ok {
+			content = anthropicParts(parts)
+		}
 		anthropicMsgs = append(anthropicMsgs, anthropicMessage{
 			Role:    m.Role,
-			Content: m.Content,
+			Content: content,
 		})
 	}
 
@@ -674,6 +984,41 @@ func mergeConsecutiveAnthropicMessages(msgs []anthropicMessage) []anthropicMessa
 	return result
 }
 
+// anthropicParts converts multimodal parts to Anthropic content blocks;
+// data URL images become base64 image sources.
+func anthropicParts(parts []contentPart) []anthropicContent {
+	out := make([]anthropicContent, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.ImageURL != nil:
+			if mime, data, ok := parseDataURL(p.ImageURL.URL); ok {
+				out = append(out, anthropicContent{
+					Type:   "image",
+					Source: &anthropicImage{Type: "base64", MediaType: mime, Data: data},
+				})
+			} else {
+				out = append(out, anthropicContent{Type: "text", Text: "[image: " + p.ImageURL.URL + "]"})
+			}
+		case p.Text != "":
+			out = append(out, anthropicContent{Type: "text", Text: p.Text})
+		}
+	}
+	return out
+}
+
+// parseDataURL splits a base64 data URL into its mime type and data.
+func parseDataURL(u string) (mime, data string, ok bool) {
+	rest, found := strings.CutPrefix(u, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, data, found := strings.Cut(rest, ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
+
 // toAnthropicContentBlocks converts any content to []anthropicContent.
 func toAnthropicContentBlocks(content any) []anthropicContent {
 	switch v := content.(type) {
@@ -741,11 +1086,20 @@ func convertFromAnthropicResponse(resp *anthropicResponse) *LLMResponse {
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		ModelUsed:    resp.Model,
-		Usage: LLMUsage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		Usage:        anthropicUsage(resp.Usage.InputTokens, resp.Usage.CacheReadInputTokens, resp.Usage.CacheCreationInputTokens, resp.Usage.OutputTokens),
+	}
+}
+
+// anthropicUsage converts Anthropic token counts, where input excludes the
+// cached prefix, into LLMUsage, where prompt tokens include it.
+func anthropicUsage(input, cacheRead, cacheWrite, output int) LLMUsage {
+	prompt := input + cacheRead + cacheWrite
+	return LLMUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: output,
+		TotalTokens:      prompt + output,
+		CacheReadTokens:  cacheRead,
+		CacheWriteTokens: cacheWrite,
 	}
 }
 
@@ -786,6 +1140,7 @@ type LLMResponse struct {
 	FinishReason string
 	Usage        LLMUsage
 	ModelUsed    string // The model that actually produced the response
+	Cached       bool   // Served from the response cache; Usage is zero
 }
 
 // LLMUsage holds token usage information from the API response.
@@ -793,6 +1148,11 @@ type LLMUsage struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+
+	// Prompt tokens read from / written to the provider's prompt cache
+	// (included in PromptTokens).
+	CacheReadTokens  int
+	CacheWriteTokens int
 }
 
 // ---------- Error Classification ----------
@@ -995,6 +1355,12 @@ func (c *LLMClient) CompleteWithVision(ctx context.Context, systemPrompt, imageB
 	if len(visionModel) > 0 && visionModel[0] != "" {
 		model = visionModel[0]
 	}
+	if err := c.ensureOllamaModel(ctx, model); err != nil {
+		return "", err
+	}
+	if !c.modelDefaults(model).SupportsVision {
+		return "", fmt.Errorf("model %s does not support image input", model)
+	}
 
 	resp, err := c.completeOnce(ctx, model, messages, nil)
 	if err != nil {
@@ -1164,10 +1530,24 @@ func envOrEmpty(key string) string {
 
 // completeOnce performs a single chat completion request. Returns *apiError on HTTP errors
 // so the caller can classify and decide retry/fallback.
-func (c *LLMClient) completeOnce(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, error) {
+func (c *LLMClient) completeOnce(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition) (resp *LLMResponse, err error) {
+	ctx, span := startSpan(ctx, "llm.request", "llm.provider", c.provider, "llm.model", model, "llm.stream", false)
+	defer func() { endLLMSpan(span, resp, err) }()
+
+	if c.isOllama() {
+		if err := c.ensureOllamaModel(ctx, model); err != nil {
+			return nil, err
+		}
+		resp, err := c.completeOnceOpenAI(ctx, model, messages, tools)
+		normalizeToolCalls(resp)
+		return resp, err
+	}
 	if c.isAnthropicAPI() {
 		return c.completeOnceAnthropic(ctx, model, messages, tools)
 	}
+	if c.isGemini() {
+		return c.completeOnceGemini(ctx, model, messages, tools)
+	}
 	return c.completeOnceOpenAI(ctx, model, messages, tools)
 }
 
@@ -1185,6 +1565,9 @@ func (c *LLMClient) completeOnceAnthropic(ctx context.Context, model string, mes
 	}
 
 	reqBody := convertToAnthropicRequest(model, messages, tools, temp, &maxTok)
+	if c.promptCachingEnabled() {
+		applyAnthropicPromptCaching(reqBody)
+	}
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
@@ -1212,7 +1595,7 @@ func (c *LLMClient) completeOnceAnthropic(ctx context.Context, model string, mes
 		"messages", len(reqBody.Messages),
 		"tools", len(reqBody.Tools),
 		"endpoint", endpoint,
-		"system_len", len(reqBody.System),
+		"system_blocks", len(reqBody.System),
 	)
 
 	start := time.Now()
@@ -1267,6 +1650,8 @@ func (c *LLMClient) completeOnceAnthropic(ctx context.Context, model string, mes
 		"duration_ms", duration.Milliseconds(),
 		"prompt_tokens", result.Usage.PromptTokens,
 		"completion_tokens", result.Usage.CompletionTokens,
+		"cache_read_tokens", result.Usage.CacheReadTokens,
+		"cache_write_tokens", result.Usage.CacheWriteTokens,
 		"finish_reason", result.FinishReason,
 		"tool_calls", len(result.ToolCalls),
 	)
@@ -1365,6 +1750,7 @@ func (c *LLMClient) completeOnceOpenAI(ctx context.Context, model string, messag
 		"duration_ms", duration.Milliseconds(),
 		"prompt_tokens", chatResp.Usage.PromptTokens,
 		"completion_tokens", chatResp.Usage.CompletionTokens,
+		"cache_read_tokens", chatResp.Usage.PromptTokensDetails.CachedTokens,
 		"finish_reason", choice.FinishReason,
 		"tool_calls", len(choice.Message.ToolCalls),
 	)
@@ -1374,11 +1760,7 @@ func (c *LLMClient) completeOnceOpenAI(ctx context.Context, model string, messag
 		ToolCalls:    choice.Message.ToolCalls,
 		FinishReason: choice.FinishReason,
 		ModelUsed:    model,
-		Usage: LLMUsage{
-			PromptTokens:     chatResp.Usage.PromptTokens,
-			CompletionTokens: chatResp.Usage.CompletionTokens,
-			TotalTokens:      chatResp.Usage.TotalTokens,
-		},
+		Usage:        chatResp.Usage.toLLMUsage(),
 	}, nil
 }
 
@@ -1416,7 +1798,7 @@ func (c *LLMClient) CompleteWithToolsStream(ctx context.Context, messages []chat
 // when non-empty. Empty = use c.model. Includes retry for transient HTTP errors
 // before falling back to non-streaming.
 func (c *LLMClient) CompleteWithToolsStreamUsingModel(ctx context.Context, modelOverride string, messages []chatMessage, tools []ToolDefinition, onChunk StreamCallback) (*LLMResponse, error) {
-	if c.apiKey == "" && c.provider != "ollama" {
+	if !c.hasCredentials() {
 		return nil, fmt.Errorf("API key not configured. Run 'devclaw config set-key' or set DEVCLAW_API_KEY")
 	}
 
@@ -1425,6 +1807,21 @@ func (c *LLMClient) CompleteWithToolsStreamUsingModel(ctx context.Context, model
 		model = modelOverride
 	}
 
+	if resp := c.completeLargeContext(ctx, modelOverride, messages, tools); resp != nil {
+		if onChunk != nil && resp.Content != "" {
+			onChunk(resp.Content)
+		}
+		return resp, nil
+	}
+
+	cached, cacheKey := c.lookupResponseCache(ctx, model, messages, tools)
+	if cached != nil {
+		if onChunk != nil {
+			onChunk(cached.Content)
+		}
+		return cached, nil
+	}
+
 	// Try streaming with 1 retry for transient errors.
 	const maxStreamRetries = 1
 	const transientRetryDelay = 2500 * time.Millisecond
@@ -1433,6 +1830,7 @@ func (c *LLMClient) CompleteWithToolsStreamUsingModel(ctx context.Context, model
 	for attempt := 0; attempt <= maxStreamRetries; attempt++ {
 		resp, err := c.completeOnceStream(ctx, model, messages, tools, onChunk)
 		if err == nil {
+			c.storeResponseCache(cacheKey, resp)
 			return resp, nil
 		}
 		lastErr = err
@@ -1463,10 +1861,37 @@ func (c *LLMClient) CompleteWithToolsStreamUsingModel(ctx context.Context, model
 }
 
 // completeOnceStream performs a single streaming chat completion. Uses SSE parsing.
-func (c *LLMClient) completeOnceStream(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition, onChunk StreamCallback) (*LLMResponse, error) {
+func (c *LLMClient) completeOnceStream(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition, onChunk StreamCallback) (resp *LLMResponse, err error) {
+	ctx, span := startSpan(ctx, "llm.request", "llm.provider", c.provider, "llm.model", model, "llm.stream", true)
+	defer func() { endLLMSpan(span, resp, err) }()
+
+	if c.isOllama() {
+		if err := c.ensureOllamaModel(ctx, model); err != nil {
+			return nil, err
+		}
+		if len(tools) > 0 && !c.ollamaStreamsTools() {
+			// Tool calls only come back unstreamed; deliver the text at once.
+			resp, err := c.completeOnce(ctx, model, messages, tools)
+			if err == nil && resp.Content != "" && onChunk != nil {
+				onChunk(resp.Content)
+			}
+			return resp, err
+		}
+		resp, err := c.completeOnceStreamOpenAI(ctx, model, messages, tools, onChunk)
+		normalizeToolCalls(resp)
+		return resp, err
+	}
 	if c.isAnthropicAPI() {
 		return c.completeOnceStreamAnthropic(ctx, model, messages, tools, onChunk)
 	}
+	if c.isGemini() {
+		// generateContent is used unstreamed; deliver the text at once.
+		resp, err := c.completeOnceGemini(ctx, model, messages, tools)
+		if err == nil && resp.Content != "" && onChunk != nil {
+			onChunk(resp.Content)
+		}
+		return resp, err
+	}
 	return c.completeOnceStreamOpenAI(ctx, model, messages, tools, onChunk)
 }
 
@@ -1484,6 +1909,9 @@ func (c *LLMClient) completeOnceStreamAnthropic(ctx context.Context, model strin
 	}
 
 	reqBody := convertToAnthropicRequest(model, messages, tools, temp, &maxTok)
+	if c.promptCachingEnabled() {
+		applyAnthropicPromptCaching(reqBody)
+	}
 	reqBody.Stream = true
 
 	bodyBytes, err := json.Marshal(reqBody)
@@ -1562,7 +1990,8 @@ func (c *LLMClient) completeOnceStreamAnthropic(ctx context.Context, model strin
 		switch evType {
 		case "message_start":
 			if event.Message != nil {
-				usage.PromptTokens = event.Message.Usage.InputTokens
+				u := event.Message.Usage
+				usage = anthropicUsage(u.InputTokens, u.CacheReadInputTokens, u.CacheCreationInputTokens, 0)
 			}
 
 		case "content_block_start":
@@ -1713,6 +2142,7 @@ func (c *LLMClient) completeOnceStreamOpenAI(ctx context.Context, model string,
 	var contentBuilder strings.Builder
 	toolCallsAccum := make(map[int]*ToolCall) // index -> accumulated tool call
 	finishReason := ""
+	var usage LLMUsage
 
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // 64KB initial, 1MB max line
@@ -1770,9 +2200,9 @@ func (c *LLMClient) completeOnceStreamOpenAI(ctx context.Context, model string,
 			}
 		}
 
-		// Usage in final chunk
+		// Usage in final chunk (when the provider sends it).
 		if chunk.Usage != nil {
-			// Could capture usage if needed
+			usage = chunk.Usage.toLLMUsage()
 		}
 	}
 
@@ -1808,7 +2238,7 @@ func (c *LLMClient) completeOnceStreamOpenAI(ctx context.Context, model string,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		ModelUsed:    model,
-		Usage:        LLMUsage{},
+		Usage:        usage,
 	}, nil
 }
 
@@ -1905,7 +2335,7 @@ func (c *LLMClient) isInCooldown(model string) bool {
 // calls use fallback models. Near cooldown expiry, a probe is sent to the
 // primary model to check if it recovered. On success, cooldown is cleared.
 func (c *LLMClient) CompleteWithFallbackUsingModel(ctx context.Context, modelOverride string, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, error) {
-	if c.apiKey == "" && c.provider != "ollama" {
+	if !c.hasCredentials() {
 		return nil, fmt.Errorf("API key not configured. Run 'devclaw config set-key' or set DEVCLAW_API_KEY")
 	}
 
@@ -1914,9 +2344,32 @@ func (c *LLMClient) CompleteWithFallbackUsingModel(ctx context.Context, modelOve
 		primary = modelOverride
 	}
 
-	models := make([]string, 0, 1+len(c.fallback.Models))
-	models = append(models, primary)
-	models = append(models, c.fallback.Models...)
+	if resp := c.completeLargeContext(ctx, modelOverride, messages, tools); resp != nil {
+		return resp, nil
+	}
+
+	cached, cacheKey := c.lookupResponseCache(ctx, primary, messages, tools)
+	if cached != nil {
+		return cached, nil
+	}
+	resp, err := c.completeWithFallback(ctx, primary, messages, tools)
+	if err == nil {
+		c.storeResponseCache(cacheKey, resp)
+	}
+	return resp, err
+}
+
+// completeWithFallback runs the retry and fallback chain for primary.
+func (c *LLMClient) completeWithFallback(ctx context.Context, primary string, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, error) {
+
+	// Primary, then fallback.models on the same endpoint, then the
+	// providers from api.fallbacks.
+	targets := make([]fallbackTarget, 0, 1+len(c.fallback.Models)+len(c.chain))
+	targets = append(targets, fallbackTarget{client: c, model: primary})
+	for _, m := range c.fallback.Models {
+		targets = append(targets, fallbackTarget{client: c, model: m})
+	}
+	targets = append(targets, c.chain...)
 
 	initialBackoff := time.Duration(c.fallback.InitialBackoffMs) * time.Millisecond
 	maxBackoff := time.Duration(c.fallback.MaxBackoffMs) * time.Millisecond
@@ -1950,19 +2403,24 @@ func (c *LLMClient) CompleteWithFallbackUsingModel(ctx context.Context, modelOve
 	}
 
 	var lastErr error
-	for _, model := range models {
+	for i, target := range targets {
+		model := target.model
+		key := target.cooldownKey(c)
 		// Skip models currently in cooldown (rate-limited).
-		if c.isInCooldown(model) {
-			c.logger.Debug("skipping model in cooldown", "model", model)
+		if c.isInCooldown(key) {
+			c.logger.Debug("skipping model in cooldown", "model", key)
 			continue
 		}
 
 		for attempt := 0; attempt <= c.fallback.MaxRetries; attempt++ {
-			resp, err := c.completeOnce(ctx, model, messages, tools)
+			resp, err := target.client.completeOnce(ctx, model, messages, tools)
 			if err == nil {
 				// If this is the primary model recovering, clear cooldown.
-				if model == primary {
-					c.clearCooldown(model)
+				if i == 0 {
+					c.clearCooldown(key)
+				} else {
+					c.logger.Info("turn served by fallback model",
+						"model", key, "primary", primary)
 				}
 				return resp, nil
 			}
@@ -1985,7 +2443,7 @@ func (c *LLMClient) CompleteWithFallbackUsingModel(ctx context.Context, modelOve
 				if retryAfterSec <= 0 {
 					retryAfterSec = 60
 				}
-				c.setCooldown(model, retryAfterSec)
+				c.setCooldown(key, retryAfterSec)
 				break // skip remaining retries for this model
 			}
 
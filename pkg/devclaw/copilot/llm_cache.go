@@ -0,0 +1,268 @@
+// Package copilot – llm_cache.go caches LLM responses for identical
+// requests, so repeated idempotent completions (summaries, classifications,
+// unchanged heartbeat and scheduled prompts) don't hit the API again.
+//
+// The key is a SHA-256 of the endpoint, model, messages and tool
+// definitions. Entries live in an in-memory LRU and, when a directory is
+// configured, on disk so they survive restarts. Only final text answers are
+// cached; responses with tool calls or cut off by the token limit never are.
+//
+// Which requests are eligible:
+//   - completions without tools (Complete: summaries, extraction, KB entries)
+//   - requests whose context was marked with ContextWithResponseCache
+//     (heartbeat turns, scheduled jobs)
+//
+// Hits are reported to the UsageTracker with the tokens they saved.
+package copilot
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCacheConfig configures the LLM response cache.
+type ResponseCacheConfig struct {
+	// Enabled turns the cache on (default: false; a cached answer is
+	// replayed verbatim for the whole TTL).
+	Enabled bool `yaml:"enabled"`
+
+	// TTLSeconds is how long a response stays valid (default: 3600).
+	TTLSeconds int `yaml:"ttl_seconds"`
+
+	// MaxEntries caps the in-memory LRU (default: 512).
+	MaxEntries int `yaml:"max_entries"`
+
+	// Dir stores entries on disk (default: ./data/llm_cache; empty = memory
+	// only).
+	Dir string `yaml:"dir"`
+}
+
+// DefaultResponseCacheConfig returns the default cache settings.
+func DefaultResponseCacheConfig() ResponseCacheConfig {
+	return ResponseCacheConfig{
+		TTLSeconds: 3600,
+		MaxEntries: 512,
+		Dir:        "./data/llm_cache",
+	}
+}
+
+type ctxKeyResponseCache struct{}
+
+// ContextWithResponseCache marks the requests made with ctx as idempotent,
+// so their responses may be served from and stored in the cache even when
+// tools are offered.
+func ContextWithResponseCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyResponseCache{}, true)
+}
+
+// responseCacheRequested reports whether ctx was marked cacheable.
+func responseCacheRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxKeyResponseCache{}).(bool)
+	return v
+}
+
+// cachedResponse is one cache entry, in memory and on disk.
+type cachedResponse struct {
+	Key      string      `json:"key"`
+	Expires  time.Time   `json:"expires"`
+	Response LLMResponse `json:"response"`
+}
+
+// responseCache is an LRU of responses with optional disk persistence.
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+	dir        string
+	logger     *slog.Logger
+
+	mu    sync.Mutex
+	lru   *list.List // of *cachedResponse, most recent first
+	items map[string]*list.Element
+}
+
+// newResponseCache creates the cache, or returns nil when disabled. Expired
+// disk entries are removed; the directory is created on the first write.
+func newResponseCache(cfg ResponseCacheConfig, logger *slog.Logger) *responseCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	def := DefaultResponseCacheConfig()
+	if cfg.TTLSeconds <= 0 {
+		cfg.TTLSeconds = def.TTLSeconds
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = def.MaxEntries
+	}
+	rc := &responseCache{
+		ttl:        time.Duration(cfg.TTLSeconds) * time.Second,
+		maxEntries: cfg.MaxEntries,
+		dir:        cfg.Dir,
+		logger:     logger,
+		lru:        list.New(),
+		items:      make(map[string]*list.Element),
+	}
+	if rc.dir != "" {
+		rc.pruneDisk(time.Now())
+	}
+	return rc
+}
+
+// responseCacheKey hashes everything that determines a response.
+func responseCacheKey(baseURL, model string, messages []chatMessage, tools []ToolDefinition) string {
+	h := sha256.New()
+	h.Write([]byte(baseURL + "\x00" + model + "\x00"))
+	_ = json.NewEncoder(h).Encode(messages)
+	_ = json.NewEncoder(h).Encode(tools)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the live entry for key, from memory or disk.
+func (rc *responseCache) get(key string) (*LLMResponse, bool) {
+	now := time.Now()
+	rc.mu.Lock()
+	if el, ok := rc.items[key]; ok {
+		entry := el.Value.(*cachedResponse)
+		if now.Before(entry.Expires) {
+			rc.lru.MoveToFront(el)
+			rc.mu.Unlock()
+			resp := entry.Response
+			return &resp, true
+		}
+		rc.lru.Remove(el)
+		delete(rc.items, key)
+	}
+	rc.mu.Unlock()
+
+	if rc.dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(rc.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedResponse
+	if json.Unmarshal(data, &entry) != nil || entry.Key != key || !now.Before(entry.Expires) {
+		_ = os.Remove(rc.path(key))
+		return nil, false
+	}
+	rc.remember(&entry)
+	resp := entry.Response
+	return &resp, true
+}
+
+// put stores a response under key.
+func (rc *responseCache) put(key string, resp *LLMResponse) {
+	entry := &cachedResponse{Key: key, Expires: time.Now().Add(rc.ttl), Response: *resp}
+	rc.remember(entry)
+	if rc.dir == "" {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	path := rc.path(key)
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(rc.dir, 0o700); err != nil {
+		rc.logger.Debug("response cache directory unavailable", "dir", rc.dir, "error", err)
+		return
+	}
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		rc.logger.Debug("response cache write failed", "error", err)
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// remember adds an entry to the LRU, evicting the least recently used.
+func (rc *responseCache) remember(entry *cachedResponse) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.items[entry.Key]; ok {
+		el.Value = entry
+		rc.lru.MoveToFront(el)
+		return
+	}
+	rc.items[entry.Key] = rc.lru.PushFront(entry)
+	for rc.lru.Len() > rc.maxEntries {
+		oldest := rc.lru.Back()
+		rc.lru.Remove(oldest)
+		delete(rc.items, oldest.Value.(*cachedResponse).Key)
+	}
+}
+
+func (rc *responseCache) path(key string) string {
+	return filepath.Join(rc.dir, key+".json")
+}
+
+// pruneDisk removes expired entries from the cache directory.
+func (rc *responseCache) pruneDisk(now time.Time) {
+	files, err := os.ReadDir(rc.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(rc.dir, f.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var entry cachedResponse
+		if json.Unmarshal(data, &entry) != nil || !now.Before(entry.Expires) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// cacheable reports whether a response may be stored: a complete text
+// answer with no tool calls.
+func (resp *LLMResponse) cacheable() bool {
+	return resp != nil && len(resp.ToolCalls) == 0 && strings.TrimSpace(resp.Content) != "" &&
+		resp.FinishReason != "length" && resp.FinishReason != "max_tokens"
+}
+
+// SetCacheHitHandler registers a callback invoked on every cache hit with
+// the model and the usage of the original call (the tokens saved).
+func (c *LLMClient) SetCacheHitHandler(fn func(model string, saved LLMUsage)) {
+	c.onCacheHit = fn
+}
+
+// lookupResponseCache returns the cached response for a request, and the key
+// to store the fresh one under ("" when the request is not cacheable).
+func (c *LLMClient) lookupResponseCache(ctx context.Context, model string, messages []chatMessage, tools []ToolDefinition) (*LLMResponse, string) {
+	if c.respCache == nil || (len(tools) > 0 && !responseCacheRequested(ctx)) {
+		return nil, ""
+	}
+	key := responseCacheKey(c.baseURL, model, messages, tools)
+	resp, ok := c.respCache.get(key)
+	if !ok {
+		return nil, key
+	}
+	c.logger.Debug("response served from cache", "model", model, "saved_tokens", resp.Usage.TotalTokens)
+	if c.onCacheHit != nil {
+		c.onCacheHit(resp.ModelUsed, resp.Usage)
+	}
+	// No tokens were spent on this call.
+	resp.Usage = LLMUsage{}
+	resp.Cached = true
+	return resp, ""
+}
+
+// storeResponseCache caches resp under key when it is cacheable.
+func (c *LLMClient) storeResponseCache(key string, resp *LLMResponse) {
+	if key != "" && resp.cacheable() {
+		c.respCache.put(key, resp)
+	}
+}
@@ -0,0 +1,312 @@
+// Package copilot – llm_debug.go records raw LLM provider traffic for
+// debugging. When api.debug.enabled is set, every request and response
+// body is written to its own JSON file under api.debug.dir, keeping only the
+// newest max_files. Authorization headers, configured API keys and common
+// secret formats are redacted before anything touches the disk.
+//
+// `devclaw debug last-request` prints the newest exchange — useful when a
+// provider rejects a payload with an unhelpful error.
+package copilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LLMDebugConfig configures raw request/response logging.
+type LLMDebugConfig struct {
+	// Enabled writes every provider request and response to Dir
+	// (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// Dir holds one JSON file per exchange (default: ./data/debug/llm).
+	Dir string `yaml:"dir"`
+
+	// MaxFiles is how many exchanges are kept; older files are deleted
+	// (default: 50).
+	MaxFiles int `yaml:"max_files"`
+
+	// MaxBodyKB truncates request and response bodies (default: 1024).
+	MaxBodyKB int `yaml:"max_body_kb"`
+}
+
+// DefaultLLMDebugConfig returns the default debug logging configuration.
+func DefaultLLMDebugConfig() LLMDebugConfig {
+	return LLMDebugConfig{Dir: "./data/debug/llm", MaxFiles: 50, MaxBodyKB: 1024}
+}
+
+// Effective fills zero values with defaults.
+func (c LLMDebugConfig) Effective() LLMDebugConfig {
+	def := DefaultLLMDebugConfig()
+	if c.Dir == "" {
+		c.Dir = def.Dir
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = def.MaxFiles
+	}
+	if c.MaxBodyKB <= 0 {
+		c.MaxBodyKB = def.MaxBodyKB
+	}
+	return c
+}
+
+// LLMExchange is one recorded request/response pair.
+type LLMExchange struct {
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	Status          int               `json:"status,omitempty"`
+	DurationMs      int64             `json:"duration_ms"`
+	Error           string            `json:"error,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     string            `json:"request_body,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body,omitempty"`
+	Truncated       bool              `json:"truncated,omitempty"`
+}
+
+// llmDebugRecorder is an http.RoundTripper that records exchanges.
+type llmDebugRecorder struct {
+	base    http.RoundTripper
+	cfg     LLMDebugConfig
+	secrets []string
+	logger  *slog.Logger
+	seq     atomic.Int64
+	pruneMu sync.Mutex
+}
+
+// wrapLLMDebugTransport returns base wrapped with a recorder when debug
+// logging is enabled, or base unchanged. secrets are literal values
+// (API keys) to redact in addition to the built-in patterns.
+func wrapLLMDebugTransport(base http.RoundTripper, cfg LLMDebugConfig, secrets []string, logger *slog.Logger) http.RoundTripper {
+	if !cfg.Enabled {
+		return base
+	}
+	cfg = cfg.Effective()
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		logger.Warn("llm debug logging disabled", "dir", cfg.Dir, "error", err)
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var keep []string
+	for _, s := range secrets {
+		if len(s) >= 8 && !strings.HasPrefix(s, "${") {
+			keep = append(keep, s)
+		}
+	}
+	logger.Warn("llm debug logging enabled, request bodies are written to disk", "dir", cfg.Dir)
+	return &llmDebugRecorder{base: base, cfg: cfg, secrets: keep, logger: logger}
+}
+
+func (r *llmDebugRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	ex := &LLMExchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            r.redact(redactURL(req.URL)),
+		RequestHeaders: r.headers(req.Header),
+	}
+	limit := int64(r.cfg.MaxBodyKB) << 10
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, truncated := readLimited(body, limit)
+			body.Close()
+			ex.RequestBody = r.redact(string(data))
+			ex.Truncated = truncated
+		}
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	ex.DurationMs = time.Since(ex.Time).Milliseconds()
+	if err != nil {
+		ex.Error = r.redact(err.Error())
+		r.write(ex)
+		return nil, err
+	}
+	ex.Status = resp.StatusCode
+	ex.ResponseHeaders = r.headers(resp.Header)
+
+	// Tee the body so streaming responses still reach the caller as they
+	// arrive; the exchange is written once the caller closes the body.
+	resp.Body = &debugBodyTee{ReadCloser: resp.Body, limit: limit, done: func(data []byte, truncated bool) {
+		ex.ResponseBody = r.redact(string(data))
+		ex.Truncated = ex.Truncated || truncated
+		ex.DurationMs = time.Since(ex.Time).Milliseconds()
+		r.write(ex)
+	}}
+	return resp, nil
+}
+
+// headers copies h with credentials redacted.
+func (r *llmDebugRecorder) headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		value := strings.Join(v, ", ")
+		switch strings.ToLower(k) {
+		case "authorization", "proxy-authorization", "x-api-key", "api-key", "x-goog-api-key", "cookie", "set-cookie":
+			value = "[REDACTED]"
+		default:
+			value = r.redact(value)
+		}
+		out[k] = value
+	}
+	return out
+}
+
+// redact removes configured API keys and known secret formats.
+func (r *llmDebugRecorder) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+	return RedactSecrets(s)
+}
+
+// write stores the exchange and prunes old files.
+func (r *llmDebugRecorder) write(ex *LLMExchange) {
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return
+	}
+	name := fmt.Sprintf("%s-%04d.json", ex.Time.Format("20060102-150405.000"), r.seq.Add(1)%10000)
+	if err := os.WriteFile(filepath.Join(r.cfg.Dir, name), data, 0o600); err != nil {
+		r.logger.Warn("failed to write llm debug log", "error", err)
+		return
+	}
+
+	r.pruneMu.Lock()
+	defer r.pruneMu.Unlock()
+	files, _ := listLLMExchangeFiles(r.cfg.Dir)
+	for len(files) > r.cfg.MaxFiles {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+}
+
+// debugBodyTee copies what the caller reads, up to limit bytes, and reports
+// it once on Close.
+type debugBodyTee struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+	once      sync.Once
+	done      func(data []byte, truncated bool)
+}
+
+func (t *debugBodyTee) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		room := t.limit - int64(t.buf.Len())
+		switch {
+		case room >= int64(n):
+			t.buf.Write(p[:n])
+		case room > 0:
+			t.buf.Write(p[:room])
+			t.truncated = true
+		default:
+			t.truncated = true
+		}
+	}
+	return n, err
+}
+
+func (t *debugBodyTee) Close() error {
+	err := t.ReadCloser.Close()
+	t.once.Do(func() { t.done(t.buf.Bytes(), t.truncated) })
+	return err
+}
+
+// readLimited reads up to limit bytes and reports whether more remained.
+func readLimited(r io.Reader, limit int64) ([]byte, bool) {
+	data, _ := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(data)) > limit {
+		return data[:limit], true
+	}
+	return data, false
+}
+
+// redactURL hides credentials passed as query parameters (e.g. Gemini's
+// ?key=).
+func redactURL(u *url.URL) string {
+	c := *u
+	c.User = nil
+	q := c.Query()
+	for k := range q {
+		switch strings.ToLower(k) {
+		case "key", "api_key", "apikey", "access_token", "token":
+			q.Set(k, "REDACTED")
+		}
+	}
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// secretPatterns match common credential formats.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}`),                             // OpenAI / Anthropic
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),                                                    // AWS access key
+	regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`),                                              // Google API key
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}`),             // GitHub tokens
+	regexp.MustCompile(`glpat-[A-Za-z0-9_\-]{20,}`),                                           // GitLab token
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9\-]{10,}`),                                       // Slack token
+	regexp.MustCompile(`eyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`), // JWT
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// RedactSecrets replaces API keys, tokens and private keys in s with
+// [REDACTED].
+func RedactSecrets(s string) string {
+	for _, re := range secretPatterns {
+		s = re.ReplaceAllString(s, "[REDACTED]")
+	}
+	return s
+}
+
+// listLLMExchangeFiles returns the recorded exchanges, oldest first.
+func listLLMExchangeFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ReadLLMExchange returns the nth most recent exchange in dir (1 = last)
+// and the file it was read from.
+func ReadLLMExchange(dir string, nth int) (*LLMExchange, string, error) {
+	files, err := listLLMExchangeFiles(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if nth < 1 {
+		nth = 1
+	}
+	if len(files) < nth {
+		return nil, "", fmt.Errorf("no recorded request #%d in %s (enable api.debug)", nth, dir)
+	}
+	path := files[len(files)-nth]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, path, err
+	}
+	var ex LLMExchange
+	if err := json.Unmarshal(data, &ex); err != nil {
+		return nil, path, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &ex, path, nil
+}
@@ -108,6 +108,35 @@ func ParseConfig(data []byte) (*Config, error) {
 		}
 	}
 
+	// Block streaming: edits in place unless explicitly disabled.
+	if bsMap, ok := raw["block_stream"].(map[string]any); ok {
+		if _, set := bsMap["edit_in_place"]; !set {
+			cfg.BlockStream.EditInPlace = DefaultBlockStreamConfig().EditInPlace
+		}
+	}
+
+	// Warmup
+	if warmMap, ok := raw["warmup"].(map[string]any); !ok {
+		cfg.Warmup = DefaultWarmupConfig()
+	} else if _, set := warmMap["enabled"]; !set {
+		cfg.Warmup.Enabled = DefaultWarmupConfig().Enabled
+	}
+
+	// Owner alerts
+	if alertMap, ok := raw["owner_alerts"].(map[string]any); ok {
+		if _, set := alertMap["enabled"]; !set {
+			cfg.OwnerAlerts.Enabled = DefaultOwnerAlertsConfig().Enabled
+		}
+	}
+
+	// Tool guard preset: re-layer the preset underneath the explicit keys.
+	if secMap, ok := raw["security"].(map[string]any); ok {
+		guardMap, _ := secMap["tool_guard"].(map[string]any)
+		if err := applyGuardPresetFromYAML(cfg, guardMap); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
 
@@ -0,0 +1,125 @@
+// Package copilot – locale.go resolves the convention a user reads and
+// writes dates, times and numbers in. The locale comes from a per-identity
+// override (locale.identities), else locale.default, else the session or
+// configured language; the timezone from the override, else the configured
+// timezone. The resolved locale travels with each agent run so cron_add
+// reads "15/03" the way the caller meant it, and the temporal prompt layer
+// and usage reports render in the same convention.
+package copilot
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
+)
+
+// LocaleConfig configures date and number conventions.
+type LocaleConfig struct {
+	// Default is the locale tag ("pt-BR", "en-US", "de-DE"). Empty = the
+	// language setting.
+	Default string `yaml:"default"`
+
+	// Identities overrides the locale and timezone per user or chat, keyed
+	// by JID, user ID or chat ID.
+	Identities map[string]IdentityLocale `yaml:"identities"`
+}
+
+// IdentityLocale is the convention of one user or chat.
+type IdentityLocale struct {
+	// Locale is the locale tag (empty = locale.default).
+	Locale string `yaml:"locale"`
+
+	// Timezone is an IANA name or city (empty = the configured timezone).
+	Timezone string `yaml:"timezone"`
+}
+
+// UserLocale is a resolved locale and timezone.
+type UserLocale struct {
+	scheduler.Locale
+
+	// Timezone is the identity's own timezone ("" = the configured one).
+	Timezone string
+
+	// Location is the user's wall clock.
+	Location *time.Location
+}
+
+// LocaleFor resolves the locale of the first of ids with an override.
+// language is the session's language ("" = the configured one).
+func (c *Config) LocaleFor(language string, ids ...string) UserLocale {
+	var override IdentityLocale
+	for _, id := range ids {
+		if o, ok := c.identityLocale(id); ok {
+			override = o
+			break
+		}
+	}
+
+	tag := firstNonEmpty(override.Locale, c.Locale.Default, language, c.Language)
+	ul := UserLocale{Locale: scheduler.ParseLocale(tag), Location: time.Local}
+	if override.Timezone != "" {
+		if loc, err := scheduler.LoadTimezone(override.Timezone); err == nil {
+			ul.Timezone, ul.Location = loc.String(), loc
+			return ul
+		}
+	}
+	if c.Timezone != "" {
+		if loc, err := scheduler.LoadTimezone(c.Timezone); err == nil {
+			ul.Location = loc
+		}
+	}
+	return ul
+}
+
+// identityLocale finds the override of id. Keys match exactly, after JID
+// normalization, or as the user part of a JID ("5511999999999").
+func (c *Config) identityLocale(id string) (IdentityLocale, bool) {
+	if id == "" || len(c.Locale.Identities) == 0 {
+		return IdentityLocale{}, false
+	}
+	if o, ok := c.Locale.Identities[id]; ok {
+		return o, true
+	}
+	norm := normalizeJID(id)
+	user, _, _ := strings.Cut(norm, "@")
+	for key, o := range c.Locale.Identities {
+		if k := normalizeJID(key); k == norm || k == user {
+			return o, true
+		}
+	}
+	return IdentityLocale{}, false
+}
+
+// ctxKeyLocale is the context key for the caller's locale.
+type ctxKeyLocale struct{}
+
+// ContextWithLocale returns a context carrying the caller's locale.
+func ContextWithLocale(ctx context.Context, ul UserLocale) context.Context {
+	return context.WithValue(ctx, ctxKeyLocale{}, ul)
+}
+
+// LocaleFromContext returns the caller's locale, if the run set one.
+func LocaleFromContext(ctx context.Context) (UserLocale, bool) {
+	ul, ok := ctx.Value(ctxKeyLocale{}).(UserLocale)
+	return ul, ok
+}
+
+// localeHint describes the locale's conventions for the temporal layer.
+func localeHint(l scheduler.Locale) string {
+	if l.Tag == "" {
+		return ""
+	}
+	order := "day/month"
+	switch l.DateOrder {
+	case scheduler.MonthDayYear:
+		order = "month/day"
+	case scheduler.YearMonthDay:
+		order = "year-month-day"
+	}
+	sample := time.Date(2026, 3, 15, 14, 5, 0, 0, time.UTC)
+	return "Locale: " + l.Tag + " — dates are written " + l.FormatDate(sample) + " (" + order + "), times " +
+		l.FormatTime(sample) + ", numbers " + l.FormatNumber(1234.56, 2) + ". " +
+		"Read numeric dates the user writes in this order and use these conventions in replies."
+}
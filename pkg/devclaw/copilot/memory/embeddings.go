@@ -1,5 +1,6 @@
 // Package memory – embeddings.go implements embedding generation for semantic search.
-// Supports multiple providers: OpenAI, and a zero-cost local fallback.
+// Supports multiple providers: OpenAI (and compatible APIs), and a zero-cost
+// local hashing embedder that needs no network.
 // Embeddings are cached by content hash + provider + model to avoid redundant API calls.
 package memory
 
@@ -8,9 +9,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // EmbeddingProvider generates vector embeddings from text.
@@ -31,7 +35,9 @@ type EmbeddingProvider interface {
 
 // EmbeddingConfig configures the embedding provider.
 type EmbeddingConfig struct {
-	// Provider is the embedding provider ("openai", "none").
+	// Provider is the embedding provider ("openai", "local", "none").
+	// "openai" works with any OpenAI-compatible /embeddings API via BaseURL;
+	// "local" uses feature hashing (no API calls, lexical similarity only).
 	Provider string `yaml:"provider"`
 
 	// Model is the embedding model name (e.g. "text-embedding-3-small").
@@ -204,11 +210,96 @@ func (e *NullEmbedder) Name() string { return "none" }
 // Model returns "none".
 func (e *NullEmbedder) Model() string { return "none" }
 
+// ---------- Local Hashing Embedding Provider ----------
+
+// localEmbedDims is the default vector size for the local embedder.
+const localEmbedDims = 512
+
+// LocalEmbedder maps text to vectors with the hashing trick: each lowercase
+// word and word bigram is hashed into a fixed number of buckets. It needs no
+// network or model download and captures lexical overlap (including partial
+// phrase matches), which is a large step up from substring search.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder creates a local hashing embedder.
+func NewLocalEmbedder(cfg EmbeddingConfig) *LocalEmbedder {
+	dims := cfg.Dimensions
+	if dims <= 0 || dims > 4096 {
+		dims = localEmbedDims
+	}
+	return &LocalEmbedder{dimensions: dims}
+}
+
+// Embed generates embeddings for a batch of texts.
+func (e *LocalEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, e.dimensions)
+		words := tokenizeForEmbedding(text)
+		for j, w := range words {
+			e.add(vec, w, 1)
+			if j > 0 {
+				e.add(vec, words[j-1]+" "+w, 0.5)
+			}
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+func (e *LocalEmbedder) add(vec []float32, feature string, weight float32) {
+	h := fnv.New32a()
+	h.Write([]byte(feature))
+	sum := h.Sum32()
+	// The top bit picks the sign so collisions tend to cancel out.
+	if sum&(1<<31) != 0 {
+		weight = -weight
+	}
+	vec[int(sum%uint32(e.dimensions))] += weight
+}
+
+// Dimensions returns the output vector size.
+func (e *LocalEmbedder) Dimensions() int { return e.dimensions }
+
+// Name returns "local".
+func (e *LocalEmbedder) Name() string { return "local" }
+
+// Model returns the hashing scheme identifier.
+func (e *LocalEmbedder) Model() string { return fmt.Sprintf("hash-%d", e.dimensions) }
+
+// tokenizeForEmbedding lowercases text and splits it into words, dropping
+// very short tokens and common stopwords.
+func tokenizeForEmbedding(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) < 2 || embeddingStopwords[f] {
+			continue
+		}
+		words = append(words, f)
+	}
+	return words
+}
+
+var embeddingStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "with": true,
+	"that": true, "this": true, "from": true, "have": true, "has": true, "is": true,
+	"of": true, "to": true, "in": true, "on": true, "at": true, "it": true, "an": true,
+	"de": true, "da": true, "do": true, "em": true, "um": true, "uma": true, "que": true,
+	"os": true, "as": true, "no": true, "na": true, "para": true, "com": true,
+}
+
 // NewEmbeddingProvider creates an embedding provider from config.
 func NewEmbeddingProvider(cfg EmbeddingConfig) EmbeddingProvider {
 	switch cfg.Provider {
 	case "openai":
 		return NewOpenAIEmbedder(cfg)
+	case "local":
+		return NewLocalEmbedder(cfg)
 	default:
 		return &NullEmbedder{}
 	}
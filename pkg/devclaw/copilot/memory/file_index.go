@@ -0,0 +1,229 @@
+// Package memory – file_index.go adds semantic recall to FileStore. Facts in
+// MEMORY.md are embedded with the configured EmbeddingProvider and the
+// vectors are kept in a sidecar file (MEMORY.vectors.json) keyed by content
+// hash. New facts are indexed as they are saved; facts written by hand are
+// picked up, and vectors of removed facts dropped, on the next search.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// vectorIndexFile is the sidecar holding fact embeddings.
+const vectorIndexFile = "MEMORY.vectors.json"
+
+// recallTimeout bounds query embedding + ranking during prompt composition.
+const recallTimeout = 1500 * time.Millisecond
+
+// vectorIndex is the on-disk format of the sidecar. Vectors are only valid
+// for the provider/model that produced them.
+type vectorIndex struct {
+	Provider string               `json:"provider"`
+	Model    string               `json:"model"`
+	Vectors  map[string][]float32 `json:"vectors"`
+}
+
+// SetEmbedder enables semantic search with the given provider. Passing nil
+// or a NullEmbedder keeps substring search.
+func (fs *FileStore) SetEmbedder(e EmbeddingProvider) {
+	fs.idxMu.Lock()
+	defer fs.idxMu.Unlock()
+	if e == nil || e.Name() == "none" {
+		fs.embedder = nil
+		fs.index = nil
+		return
+	}
+	fs.embedder = e
+	fs.index = fs.loadIndexLocked()
+}
+
+// HasEmbedder reports whether semantic search is enabled.
+func (fs *FileStore) HasEmbedder() bool {
+	fs.idxMu.Lock()
+	defer fs.idxMu.Unlock()
+	return fs.embedder != nil
+}
+
+// IndexFact embeds a single fact and stores its vector. Called after Save
+// so memory_save keeps the index current without a full rebuild. The
+// embedding call runs without holding the index lock.
+func (fs *FileStore) IndexFact(ctx context.Context, content string) error {
+	fs.idxMu.Lock()
+	embedder, idx := fs.embedder, fs.index
+	indexed := idx != nil && idx.Vectors[hashText(content)] != nil
+	fs.idxMu.Unlock()
+	if embedder == nil || indexed {
+		return nil
+	}
+
+	vecs, err := embedTexts(ctx, embedder, []string{content})
+	if err != nil {
+		return err
+	}
+
+	fs.idxMu.Lock()
+	defer fs.idxMu.Unlock()
+	if fs.index != idx {
+		return nil // the embedder changed meanwhile; the vector is stale
+	}
+	maps.Copy(fs.index.Vectors, vecs)
+	return fs.saveIndexLocked()
+}
+
+// SemanticSearch returns the facts most similar to query, best first. Facts
+// missing from the index are embedded first and vectors of facts no longer
+// in MEMORY.md (superseded, deleted by hand) are pruned. Embedding calls run
+// without holding the index lock.
+func (fs *FileStore) SemanticSearch(ctx context.Context, query string, maxResults int) ([]Entry, error) {
+	all, err := fs.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	fs.idxMu.Lock()
+	embedder, idx := fs.embedder, fs.index
+	if embedder == nil {
+		fs.idxMu.Unlock()
+		return nil, fmt.Errorf("no embedding provider configured")
+	}
+	// Incrementally index facts that are not in the sidecar yet.
+	var missing []string
+	live := make(map[string]bool, len(all))
+	for _, e := range all {
+		h := hashText(e.Content)
+		if _, ok := idx.Vectors[h]; !ok && !live[h] {
+			missing = append(missing, e.Content)
+		}
+		live[h] = true
+	}
+	fs.idxMu.Unlock()
+
+	added, err := embedTexts(ctx, embedder, missing)
+	if err != nil {
+		return nil, err
+	}
+	qv, err := embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+
+	// Store the new vectors, prune stale ones and copy what ranking needs.
+	fs.idxMu.Lock()
+	vectors := make(map[string][]float32, len(live))
+	if fs.index == idx {
+		maps.Copy(idx.Vectors, added)
+		pruned := 0
+		for h := range idx.Vectors {
+			if !live[h] {
+				delete(idx.Vectors, h)
+				pruned++
+			}
+		}
+		if len(added) > 0 || pruned > 0 {
+			if err := fs.saveIndexLocked(); err != nil {
+				fs.idxMu.Unlock()
+				return nil, err
+			}
+		}
+	}
+	for h := range live {
+		if v, ok := idx.Vectors[h]; ok {
+			vectors[h] = v
+		} else if v, ok := added[h]; ok {
+			vectors[h] = v
+		}
+	}
+	fs.idxMu.Unlock()
+
+	if len(qv) == 0 {
+		return nil, nil
+	}
+
+	type scored struct {
+		entry Entry
+		score float64
+	}
+	var ranked []scored
+	for _, e := range all {
+		score := cosineSimilarity(qv[0], vectors[hashText(e.Content)])
+		if score > 0 {
+			ranked = append(ranked, scored{e, score})
+		}
+	}
+	// Stable sort keeps newer facts after older ones on ties; reverse the
+	// input order first so ties favour recent facts.
+	for i, j := 0, len(ranked)-1; i < j; i, j = i+1, j-1 {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	if maxResults > 0 && len(ranked) > maxResults {
+		ranked = ranked[:maxResults]
+	}
+	out := make([]Entry, len(ranked))
+	for i, r := range ranked {
+		out[i] = r.entry
+	}
+	return out, nil
+}
+
+// embedTexts embeds texts in batches and returns their vectors keyed by
+// content hash.
+func embedTexts(ctx context.Context, embedder EmbeddingProvider, texts []string) (map[string][]float32, error) {
+	const batch = 64
+	out := make(map[string][]float32, len(texts))
+	for start := 0; start < len(texts); start += batch {
+		end := min(start+batch, len(texts))
+		vecs, err := embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("embedding facts: %w", err)
+		}
+		for i, v := range vecs {
+			if len(v) > 0 && start+i < end {
+				out[hashText(texts[start+i])] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+// loadIndexLocked reads the sidecar, discarding it if it was built by a
+// different provider or model.
+func (fs *FileStore) loadIndexLocked() *vectorIndex {
+	fresh := &vectorIndex{
+		Provider: fs.embedder.Name(),
+		Model:    fs.embedder.Model(),
+		Vectors:  make(map[string][]float32),
+	}
+	data, err := os.ReadFile(filepath.Join(fs.baseDir, vectorIndexFile))
+	if err != nil {
+		return fresh
+	}
+	var idx vectorIndex
+	if err := json.Unmarshal(data, &idx); err != nil ||
+		idx.Provider != fresh.Provider || idx.Model != fresh.Model || idx.Vectors == nil {
+		return fresh
+	}
+	return &idx
+}
+
+// saveIndexLocked writes the sidecar atomically.
+func (fs *FileStore) saveIndexLocked() error {
+	data, err := json.Marshal(fs.index)
+	if err != nil {
+		return fmt.Errorf("encoding vector index: %w", err)
+	}
+	path := filepath.Join(fs.baseDir, vectorIndexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing vector index: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
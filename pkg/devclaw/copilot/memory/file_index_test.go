@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// keywordEmbedder maps texts to one axis per keyword. Texts containing
+// "slow" block until release is closed.
+type keywordEmbedder struct {
+	mu      sync.Mutex
+	calls   int
+	release chan struct{}
+	started chan struct{}
+}
+
+func (e *keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		if strings.Contains(t, "slow") && e.release != nil {
+			e.started <- struct{}{}
+			<-e.release
+		}
+		v := []float32{0, 0, 0.1}
+		if strings.Contains(t, "coffee") {
+			v[0] = 1
+		}
+		if strings.Contains(t, "tea") {
+			v[1] = 1
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (e *keywordEmbedder) Dimensions() int { return 3 }
+func (e *keywordEmbedder) Name() string    { return "keyword" }
+func (e *keywordEmbedder) Model() string   { return "test" }
+
+func sidecarVectors(t *testing.T, dir string) map[string][]float32 {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, vectorIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx vectorIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		t.Fatal(err)
+	}
+	return idx.Vectors
+}
+
+func TestFileStoreSemanticSearch(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+	fs.SetEmbedder(&keywordEmbedder{})
+
+	now := time.Now()
+	for _, c := range []string{"likes coffee in the morning", "drinks tea after lunch", "lives in Lisbon"} {
+		if err := fs.Save(Entry{Content: c, Category: "fact", Timestamp: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(sidecarVectors(t, dir)); got != 3 {
+		t.Errorf("sidecar has %d vectors after saving 3 facts", got)
+	}
+
+	hits, err := fs.SemanticSearch(context.Background(), "tea", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Content != "drinks tea after lunch" {
+		t.Errorf("hits = %+v", hits)
+	}
+}
+
+func TestFileStoreSemanticSearch_PrunesRemovedFacts(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+	fs.SetEmbedder(&keywordEmbedder{})
+
+	now := time.Now()
+	if _, err := fs.SaveVersioned(Entry{Content: "drinks coffee", Key: "user/drink", Category: "fact", Timestamp: now}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.SaveVersioned(Entry{Content: "drinks tea", Key: "user/drink", Category: "fact", Timestamp: now}, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sidecarVectors(t, dir)); got != 2 {
+		t.Fatalf("sidecar has %d vectors before the search, want both versions", got)
+	}
+
+	hits, err := fs.SemanticSearch(context.Background(), "coffee", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hits {
+		if h.Content == "drinks coffee" {
+			t.Error("superseded fact returned")
+		}
+	}
+	vectors := sidecarVectors(t, dir)
+	if _, ok := vectors[hashText("drinks coffee")]; ok || len(vectors) != 1 {
+		t.Errorf("superseded fact not pruned from the sidecar: %d vectors", len(vectors))
+	}
+}
+
+func TestFileStoreSemanticSearch_EmbedsWithoutLock(t *testing.T) {
+	t.Parallel()
+	emb := &keywordEmbedder{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	dir := t.TempDir()
+	fs, _ := NewFileStore(dir)
+	fs.SetEmbedder(emb)
+	if err := fs.appendFact(Entry{Content: "slow fact about coffee", Category: "fact", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := fs.SemanticSearch(context.Background(), "coffee", 1)
+		done <- err
+	}()
+	<-emb.started
+
+	// The index lock is free while the provider is busy.
+	locked := make(chan struct{})
+	go func() {
+		fs.HasEmbedder()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the index lock is held during the embedding call")
+	}
+
+	close(emb.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sidecarVectors(t, dir)[hashText("slow fact about coffee")]; !ok {
+		t.Error("lazily embedded fact not stored")
+	}
+}
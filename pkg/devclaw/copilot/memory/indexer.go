@@ -189,6 +189,10 @@ func IndexDirectory(dir string, cfg ChunkConfig) (map[string][]Chunk, error) {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
 			continue
 		}
+		// Superseded fact versions must not come back through search.
+		if entry.Name() == historyFile {
+			continue
+		}
 
 		filePath := filepath.Join(dir, entry.Name())
 		data, err := os.ReadFile(filePath)
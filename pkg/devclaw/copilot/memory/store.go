@@ -3,12 +3,16 @@
 // the filesystem (MEMORY.md + daily markdown files).
 //
 // Architecture:
-//   - MEMORY.md: Long-term facts (append-only, curated by the agent)
+//   - MEMORY.md: Long-term facts (current versions, curated by the agent)
+//   - MEMORY.history.md: Superseded fact versions (see versioning.go)
 //   - memory/YYYY-MM-DD.md: Daily conversation summaries (append-only)
-//   - Search uses simple substring matching (future: BM25 / embeddings)
+//   - MEMORY.vectors.json: Optional embedding index for semantic recall
+//   - Search uses substring matching; SemanticSearch uses embeddings when
+//     an EmbeddingProvider is set (see file_index.go)
 package memory
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,6 +28,10 @@ type Entry struct {
 	Source    string    `json:"source"`    // "user", "agent", "system"
 	Category string    `json:"category"`  // "fact", "preference", "event", "summary"
 	Timestamp time.Time `json:"timestamp"`
+
+	// Key is "subject/attribute" for facts that state one value of
+	// something ("user/location"); newer versions supersede older ones.
+	Key string `json:"key,omitempty"`
 }
 
 // Store defines the interface for memory persistence.
@@ -52,6 +60,11 @@ type Store interface {
 type FileStore struct {
 	baseDir string
 	mu      sync.RWMutex
+
+	// Semantic index (optional, see file_index.go).
+	idxMu    sync.Mutex
+	embedder EmbeddingProvider
+	index    *vectorIndex
 }
 
 // NewFileStore creates a file-based memory store at the given directory.
@@ -67,20 +80,31 @@ func NewFileStore(baseDir string) (*FileStore, error) {
 	return &FileStore{baseDir: baseDir}, nil
 }
 
-// Save appends a memory entry to MEMORY.md.
+// Save appends a memory entry to MEMORY.md and, when an embedder is set,
+// indexes it for semantic recall.
 func (fs *FileStore) Save(entry Entry) error {
+	if err := fs.appendFact(entry); err != nil {
+		return err
+	}
+	if fs.HasEmbedder() {
+		// Indexing failures are not fatal: the fact is picked up by the
+		// lazy pass in SemanticSearch.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = fs.IndexFact(ctx, entry.Content)
+	}
+	return nil
+}
+
+func (fs *FileStore) appendFact(entry Entry) error {
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
+	return fs.appendFactLocked(entry)
+}
 
+func (fs *FileStore) appendFactLocked(entry Entry) error {
 	memFile := filepath.Join(fs.baseDir, "MEMORY.md")
 
-	// Format the entry as a markdown list item.
-	line := fmt.Sprintf("- [%s] [%s] %s\n",
-		entry.Timestamp.Format("2006-01-02 15:04"),
-		entry.Category,
-		entry.Content,
-	)
-
 	f, err := os.OpenFile(memFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
 		return fmt.Errorf("opening memory file: %w", err)
@@ -93,10 +117,25 @@ func (fs *FileStore) Save(entry Entry) error {
 		f.WriteString("# DevClaw Memory\n\nLong-term facts and preferences.\n\n")
 	}
 
-	_, err = f.WriteString(line)
+	_, err = f.WriteString(formatEntry(entry))
 	return err
 }
 
+// formatEntry formats an entry as a markdown list item:
+// - [YYYY-MM-DD HH:MM] [category] [subject/attribute] content
+func formatEntry(entry Entry) string {
+	key := ""
+	if entry.Key != "" {
+		key = "[" + entry.Key + "] "
+	}
+	return fmt.Sprintf("- [%s] [%s] %s%s\n",
+		entry.Timestamp.Format("2006-01-02 15:04"),
+		entry.Category,
+		key,
+		entry.Content,
+	)
+}
+
 // Search returns entries whose content matches the query (case-insensitive substring).
 func (fs *FileStore) Search(query string, maxResults int) ([]Entry, error) {
 	all, err := fs.GetAll()
@@ -191,12 +230,20 @@ func (fs *FileStore) SaveDailyLog(date time.Time, content string) error {
 }
 
 // RecentFacts returns a formatted string of recent facts suitable for
-// injection into the system prompt.
+// injection into the system prompt. With an embedder the query is matched
+// semantically; otherwise (or if embedding fails) by substring.
 func (fs *FileStore) RecentFacts(maxFacts int, query string) string {
 	var entries []Entry
 	var err error
 
-	if query != "" {
+	if query != "" && fs.HasEmbedder() {
+		ctx, cancel := context.WithTimeout(context.Background(), recallTimeout)
+		entries, err = fs.SemanticSearch(ctx, query, maxFacts)
+		cancel()
+		if err != nil {
+			entries, err = fs.Search(query, maxFacts)
+		}
+	} else if query != "" {
 		entries, err = fs.Search(query, maxFacts)
 	} else {
 		entries, err = fs.GetRecent(maxFacts)
@@ -226,7 +273,7 @@ func (fs *FileStore) ListDailyLogs() ([]string, error) {
 	var dates []string
 	for _, e := range entries {
 		name := e.Name()
-		if strings.HasSuffix(name, ".md") && name != "MEMORY.md" {
+		if strings.HasSuffix(name, ".md") && name != "MEMORY.md" && name != historyFile {
 			dates = append(dates, strings.TrimSuffix(name, ".md"))
 		}
 	}
@@ -238,46 +285,61 @@ func (fs *FileStore) ListDailyLogs() ([]string, error) {
 // ---------- Parsing ----------
 
 // parseMemoryFile parses a memory markdown file into entries.
-// Recognizes lines formatted as: - [YYYY-MM-DD HH:MM] [category] content
+// Recognizes lines formatted as: - [YYYY-MM-DD HH:MM] [category] content,
+// with an optional [subject/attribute] key before the content.
 func parseMemoryFile(content, source string) []Entry {
 	var entries []Entry
 
 	for _, line := range strings.Split(content, "\n") {
-		line = strings.TrimSpace(line)
-		if !strings.HasPrefix(line, "- ") {
-			continue
+		if entry, ok := parseMemoryLine(line, source); ok {
+			entries = append(entries, entry)
 		}
+	}
+
+	return entries
+}
+
+// parseMemoryLine parses one list item of a memory file.
+func parseMemoryLine(line, source string) (Entry, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "- ") {
+		return Entry{}, false
+	}
 
-		line = strings.TrimPrefix(line, "- ")
-		entry := Entry{Source: source}
-
-		// Try to parse timestamp: [YYYY-MM-DD HH:MM]
-		if strings.HasPrefix(line, "[") {
-			closeBracket := strings.Index(line, "]")
-			if closeBracket > 0 {
-				ts := line[1:closeBracket]
-				t, err := time.Parse("2006-01-02 15:04", ts)
-				if err == nil {
-					entry.Timestamp = t
-				}
-				line = strings.TrimSpace(line[closeBracket+1:])
+	line = strings.TrimPrefix(line, "- ")
+	entry := Entry{Source: source}
+
+	// Try to parse timestamp: [YYYY-MM-DD HH:MM]
+	if strings.HasPrefix(line, "[") {
+		closeBracket := strings.Index(line, "]")
+		if closeBracket > 0 {
+			ts := line[1:closeBracket]
+			t, err := time.Parse("2006-01-02 15:04", ts)
+			if err == nil {
+				entry.Timestamp = t
 			}
+			line = strings.TrimSpace(line[closeBracket+1:])
 		}
+	}
 
-		// Try to parse category: [category]
-		if strings.HasPrefix(line, "[") {
-			closeBracket := strings.Index(line, "]")
-			if closeBracket > 0 {
-				entry.Category = line[1:closeBracket]
-				line = strings.TrimSpace(line[closeBracket+1:])
-			}
+	// Try to parse category: [category]
+	if strings.HasPrefix(line, "[") {
+		closeBracket := strings.Index(line, "]")
+		if closeBracket > 0 {
+			entry.Category = line[1:closeBracket]
+			line = strings.TrimSpace(line[closeBracket+1:])
 		}
+	}
 
-		entry.Content = line
-		if entry.Content != "" {
-			entries = append(entries, entry)
+	// Optional key: [subject/attribute]
+	if strings.HasPrefix(line, "[") {
+		closeBracket := strings.Index(line, "]")
+		if closeBracket > 0 && factKeyRe.MatchString(line[1:closeBracket]) {
+			entry.Key = line[1:closeBracket]
+			line = strings.TrimSpace(line[closeBracket+1:])
 		}
 	}
 
-	return entries
+	entry.Content = line
+	return entry, entry.Content != ""
 }
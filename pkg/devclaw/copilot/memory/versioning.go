@@ -0,0 +1,445 @@
+// Package memory – versioning.go keeps one current version of each fact.
+// Facts that state a single value of something ("User lives in Lisbon",
+// "Ana's email is ana@example.com") get a subject/attribute key, either
+// given by the caller or derived from common phrasings. When a fact with
+// the same key and a different value is saved, the store either supersedes
+// the old version (moved to MEMORY.history.md) or, for facts that should
+// not overwrite memory on their own (auto-captured ones), queues the
+// contradiction in MEMORY.conflicts.json for the owner to resolve.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	historyFile   = "MEMORY.history.md"
+	conflictsFile = "MEMORY.conflicts.json"
+)
+
+// factKeyRe matches a "subject/attribute" key.
+var factKeyRe = regexp.MustCompile(`^[\p{Ll}\p{N}][\p{Ll}\p{N} _.-]*/[\p{Ll}\p{N}][\p{Ll}\p{N} _.-]*$`)
+
+var (
+	// "Ana's email is ana@example.com", "User's timezone: UTC-3"
+	possessiveFactRe = regexp.MustCompile(`(?i)^(.{1,40}?)'s ([\p{L} ]{2,30}?)(?: is| are| was|:) (.+)$`)
+
+	// "User lives in Lisbon", "Ana works at Acme"
+	verbFactRe = regexp.MustCompile(`(?i)^(.{1,40}?) (lives in|lives at|is based in|is located in|moved to|works at|works for|works as|is from|is called|is named|goes by|was born on|was born in) (.+)$`)
+)
+
+// verbAttributes maps the phrasings of verbFactRe to attributes, so that
+// "moved to Porto" supersedes "lives in Lisbon".
+var verbAttributes = map[string]string{
+	"lives in":      "location",
+	"lives at":      "location",
+	"is based in":   "location",
+	"is located in": "location",
+	"moved to":      "location",
+	"works at":      "employer",
+	"works for":     "employer",
+	"works as":      "role",
+	"is from":       "origin",
+	"is called":     "name",
+	"is named":      "name",
+	"goes by":       "name",
+	"was born on":   "birthday",
+	"was born in":   "birthplace",
+}
+
+// ParseFactKey derives the subject/attribute key and the value a fact
+// states. ok is false when the fact doesn't follow a known phrasing.
+func ParseFactKey(content string) (key, value string, ok bool) {
+	content = strings.TrimSpace(content)
+	var subject, attribute string
+	if m := verbFactRe.FindStringSubmatch(content); m != nil {
+		subject, attribute, value = m[1], verbAttributes[strings.ToLower(m[2])], m[3]
+	} else if m := possessiveFactRe.FindStringSubmatch(content); m != nil {
+		subject, attribute, value = m[1], m[2], m[3]
+	} else {
+		return "", "", false
+	}
+
+	subject = normalizeSubject(subject)
+	attribute = strings.Join(strings.Fields(strings.ToLower(attribute)), " ")
+	if subject == "" || len(strings.Fields(subject)) > 4 || attribute == "" {
+		return "", "", false
+	}
+	key = subject + "/" + attribute
+	if !factKeyRe.MatchString(key) {
+		return "", "", false
+	}
+	return key, normalizeFactValue(value), true
+}
+
+// NormalizeFactKey lowercases a caller-supplied key and reports whether it
+// is a valid "subject/attribute" key.
+func NormalizeFactKey(key string) (string, bool) {
+	subject, attribute, found := strings.Cut(strings.ToLower(strings.TrimSpace(key)), "/")
+	if !found {
+		return "", false
+	}
+	key = normalizeSubject(subject) + "/" + strings.Join(strings.Fields(attribute), " ")
+	return key, factKeyRe.MatchString(key)
+}
+
+func normalizeSubject(s string) string {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	s = strings.TrimPrefix(s, "the ")
+	switch s {
+	case "i", "me", "my", "user", "the user":
+		return "user"
+	}
+	return strings.ReplaceAll(s, "/", " ")
+}
+
+func normalizeFactValue(v string) string {
+	v = strings.ToLower(strings.Join(strings.Fields(v), " "))
+	return strings.TrimRight(v, ".!;, ")
+}
+
+// factKeyOf returns the entry's key, derived from its content if unset.
+func factKeyOf(e Entry) string {
+	if e.Key != "" {
+		return e.Key
+	}
+	key, _, _ := ParseFactKey(e.Content)
+	return key
+}
+
+// factValueOf returns what an entry states about key, for comparing versions.
+func factValueOf(e Entry, key string) string {
+	if k, v, ok := ParseFactKey(e.Content); ok && k == key {
+		return v
+	}
+	return normalizeFactValue(e.Content)
+}
+
+// SaveOutcome describes what SaveVersioned did with an entry.
+type SaveOutcome string
+
+const (
+	// SavedNew means the entry was saved with no earlier version.
+	SavedNew SaveOutcome = "saved"
+	// SavedDuplicate means the current version already says the same; nothing was written.
+	SavedDuplicate SaveOutcome = "duplicate"
+	// SavedSuperseded means the entry replaced the current version(s).
+	SavedSuperseded SaveOutcome = "superseded"
+	// QueuedConflict means the entry contradicts memory and awaits review.
+	QueuedConflict SaveOutcome = "conflict"
+)
+
+// SaveResult reports the outcome of SaveVersioned.
+type SaveResult struct {
+	Outcome    SaveOutcome
+	Key        string
+	Superseded []Entry   // versions moved to history
+	Conflict   *Conflict // set for QueuedConflict
+}
+
+// Conflict is a fact that contradicts the current version of its key and
+// waits for the owner to pick one.
+type Conflict struct {
+	ID         string    `json:"id"`
+	Key        string    `json:"key"`
+	Current    Entry     `json:"current"`
+	Proposed   Entry     `json:"proposed"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// SaveVersioned saves entry with contradiction detection. Entries without
+// a key (given or derived) are simply appended. When the current version
+// of the key states a different value, supersede replaces it (the old
+// version moves to MEMORY.history.md); otherwise the entry is queued as a
+// Conflict and not saved.
+func (fs *FileStore) SaveVersioned(entry Entry, supersede bool) (SaveResult, error) {
+	if entry.Key != "" {
+		key, ok := NormalizeFactKey(entry.Key)
+		if !ok {
+			return SaveResult{}, fmt.Errorf("invalid fact key %q (use subject/attribute)", entry.Key)
+		}
+		entry.Key = key
+	} else {
+		entry.Key, _, _ = ParseFactKey(entry.Content)
+	}
+	if entry.Key == "" {
+		return SaveResult{Outcome: SavedNew}, fs.Save(entry)
+	}
+
+	res, err := fs.saveVersionedLocked(entry, supersede)
+	if err != nil || (res.Outcome != SavedNew && res.Outcome != SavedSuperseded) {
+		return res, err
+	}
+	if fs.HasEmbedder() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = fs.IndexFact(ctx, entry.Content)
+	}
+	return res, nil
+}
+
+func (fs *FileStore) saveVersionedLocked(entry Entry, supersede bool) (SaveResult, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	res := SaveResult{Key: entry.Key}
+	current, err := fs.versionsLocked(entry.Key)
+	if err != nil {
+		return res, err
+	}
+	value := factValueOf(entry, entry.Key)
+	for _, c := range current {
+		if factValueOf(c, entry.Key) == value {
+			res.Outcome = SavedDuplicate
+			return res, nil
+		}
+	}
+
+	switch {
+	case len(current) == 0:
+		res.Outcome = SavedNew
+	case supersede:
+		if res.Superseded, err = fs.supersedeLocked(entry.Key, entry.Timestamp); err != nil {
+			return res, err
+		}
+		res.Outcome = SavedSuperseded
+	default:
+		c, err := fs.queueConflictLocked(current[len(current)-1], entry)
+		if err != nil {
+			return res, err
+		}
+		res.Outcome = QueuedConflict
+		res.Conflict = c
+		return res, nil
+	}
+	return res, fs.appendFactLocked(entry)
+}
+
+// versionsLocked returns the entries of MEMORY.md with the given key.
+func (fs *FileStore) versionsLocked(key string) ([]Entry, error) {
+	content, err := os.ReadFile(filepath.Join(fs.baseDir, "MEMORY.md"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []Entry
+	for _, e := range parseMemoryFile(string(content), "memory") {
+		if factKeyOf(e) == key {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// supersedeLocked moves every version of key from MEMORY.md to the
+// history file.
+func (fs *FileStore) supersedeLocked(key string, at time.Time) ([]Entry, error) {
+	memFile := filepath.Join(fs.baseDir, "MEMORY.md")
+	content, err := os.ReadFile(memFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		kept    []string
+		removed []Entry
+	)
+	for _, line := range strings.Split(string(content), "\n") {
+		if e, ok := parseMemoryLine(line, "memory"); ok && factKeyOf(e) == key {
+			if e.Key == "" {
+				e.Key = key
+			}
+			removed = append(removed, e)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	var hist strings.Builder
+	for _, e := range removed {
+		hist.WriteString(historyLine(e, "superseded", at))
+	}
+	if err := fs.appendHistoryLocked(hist.String()); err != nil {
+		return nil, err
+	}
+
+	tmp := memFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")), 0o644); err != nil {
+		return nil, fmt.Errorf("rewriting memory file: %w", err)
+	}
+	if err := os.Rename(tmp, memFile); err != nil {
+		return nil, fmt.Errorf("rewriting memory file: %w", err)
+	}
+	return removed, nil
+}
+
+// historyLine formats a retired version with why and when it was retired.
+func historyLine(e Entry, reason string, at time.Time) string {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	line := strings.TrimSuffix(formatEntry(e), "\n")
+	return fmt.Sprintf("%s (%s %s)\n", line, reason, at.Format("2006-01-02 15:04"))
+}
+
+func (fs *FileStore) appendHistoryLocked(lines string) error {
+	f, err := os.OpenFile(filepath.Join(fs.baseDir, historyFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening memory history: %w", err)
+	}
+	defer f.Close()
+
+	if info, _ := f.Stat(); info != nil && info.Size() == 0 {
+		f.WriteString("# DevClaw Memory History\n\nSuperseded and rejected fact versions.\n\n")
+	}
+	_, err = f.WriteString(lines)
+	return err
+}
+
+// History returns the retired versions of key, oldest first.
+func (fs *FileStore) History(key string) ([]Entry, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	content, err := os.ReadFile(filepath.Join(fs.baseDir, historyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []Entry
+	for _, e := range parseMemoryFile(string(content), "history") {
+		if e.Key == key {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Versions returns the current versions of key in MEMORY.md.
+func (fs *FileStore) Versions(key string) ([]Entry, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.versionsLocked(key)
+}
+
+// ---------- Conflict queue ----------
+
+func (fs *FileStore) loadConflictsLocked() ([]Conflict, error) {
+	data, err := os.ReadFile(filepath.Join(fs.baseDir, conflictsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []Conflict
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", conflictsFile, err)
+	}
+	return out, nil
+}
+
+func (fs *FileStore) saveConflictsLocked(conflicts []Conflict) error {
+	path := filepath.Join(fs.baseDir, conflictsFile)
+	if len(conflicts) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(conflicts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (fs *FileStore) queueConflictLocked(current, proposed Entry) (*Conflict, error) {
+	conflicts, err := fs.loadConflictsLocked()
+	if err != nil {
+		return nil, err
+	}
+	value := factValueOf(proposed, proposed.Key)
+	for i := range conflicts {
+		if conflicts[i].Key == proposed.Key && factValueOf(conflicts[i].Proposed, proposed.Key) == value {
+			return &conflicts[i], nil // already waiting for review
+		}
+	}
+
+	var id [3]byte
+	_, _ = rand.Read(id[:])
+	c := Conflict{
+		ID:         hex.EncodeToString(id[:]),
+		Key:        proposed.Key,
+		Current:    current,
+		Proposed:   proposed,
+		DetectedAt: time.Now(),
+	}
+	conflicts = append(conflicts, c)
+	if err := fs.saveConflictsLocked(conflicts); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Conflicts returns the contradictions waiting for review, oldest first.
+func (fs *FileStore) Conflicts() ([]Conflict, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.loadConflictsLocked()
+}
+
+// ResolveConflict settles a queued conflict. With keepProposed the proposed
+// fact supersedes the current version; otherwise it is discarded and
+// recorded in the history as rejected.
+func (fs *FileStore) ResolveConflict(id string, keepProposed bool) (Conflict, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	conflicts, err := fs.loadConflictsLocked()
+	if err != nil {
+		return Conflict{}, err
+	}
+	idx := -1
+	for i, c := range conflicts {
+		if c.ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return Conflict{}, fmt.Errorf("no pending conflict %q", id)
+	}
+	c := conflicts[idx]
+
+	now := time.Now()
+	if keepProposed {
+		if _, err := fs.supersedeLocked(c.Key, now); err != nil {
+			return c, err
+		}
+		if err := fs.appendFactLocked(c.Proposed); err != nil {
+			return c, err
+		}
+	} else if err := fs.appendHistoryLocked(historyLine(c.Proposed, "rejected", now)); err != nil {
+		return c, err
+	}
+
+	conflicts = append(conflicts[:idx], conflicts[idx+1:]...)
+	return c, fs.saveConflictsLocked(conflicts)
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryFactVersioning(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	save := func(content string, supersede bool) SaveResult {
+		t.Helper()
+		at = at.Add(time.Hour)
+		res, err := store.SaveVersioned(Entry{Content: content, Category: "fact", Timestamp: at}, supersede)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := save("User lives in Lisbon", true); res.Outcome != SavedNew || res.Key != "user/location" {
+		t.Fatalf("first save = %+v", res)
+	}
+	if res := save("User prefers dark mode", true); res.Outcome != SavedNew || res.Key != "" {
+		t.Fatalf("unkeyed save = %+v", res)
+	}
+	if res := save("The user lives in lisbon.", true); res.Outcome != SavedDuplicate {
+		t.Fatalf("restated fact = %+v", res)
+	}
+
+	// Auto-captured contradictions wait for review.
+	res := save("User moved to Porto", false)
+	if res.Outcome != QueuedConflict || res.Conflict == nil {
+		t.Fatalf("contradiction = %+v", res)
+	}
+	if facts := store.RecentFacts(10, ""); !strings.Contains(facts, "Lisbon") || strings.Contains(facts, "Porto") {
+		t.Errorf("queued fact reached memory:\n%s", facts)
+	}
+	if _, err := store.ResolveConflict(res.Conflict.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	facts := store.RecentFacts(10, "")
+	if strings.Contains(facts, "Lisbon") || !strings.Contains(facts, "Porto") || !strings.Contains(facts, "dark mode") {
+		t.Errorf("after resolving, memory is:\n%s", facts)
+	}
+	if pending, _ := store.Conflicts(); len(pending) != 0 {
+		t.Errorf("conflicts left: %+v", pending)
+	}
+
+	// Explicit saves supersede right away; old versions go to history.
+	res = save("User's location: Madrid", true)
+	if res.Outcome != SavedSuperseded || len(res.Superseded) != 1 || res.Superseded[0].Content != "User moved to Porto" {
+		t.Fatalf("supersede = %+v", res)
+	}
+	hist, err := store.History("user/location")
+	if err != nil || len(hist) != 2 {
+		t.Fatalf("history = %+v, %v", hist, err)
+	}
+	if cur, _ := store.Versions("user/location"); len(cur) != 1 || cur[0].Content != "User's location: Madrid" {
+		t.Errorf("current versions = %+v", cur)
+	}
+}
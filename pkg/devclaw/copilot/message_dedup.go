@@ -0,0 +1,169 @@
+// Package copilot – message_dedup.go makes incoming message handling
+// idempotent. Webhook retries, channel reconnects and history replays can
+// deliver the same message ID more than once; each (channel, message ID)
+// pair is claimed in a persistent seen-set before the message is handled,
+// so a redelivery produces no second agent run and no second reply.
+//
+// The seen-set lives in devclaw.db (table seen_messages) so it survives
+// restarts. When multi-instance coordination uses redis or etcd, the claim
+// is also taken there, covering instances that do not share a database.
+package copilot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/coordination"
+)
+
+// IdempotencyConfig configures duplicate message suppression.
+type IdempotencyConfig struct {
+	// Enabled drops messages whose (channel, message ID) was already seen
+	// (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// TTLHours is how long a message ID is remembered (default: 24).
+	TTLHours int `yaml:"ttl_hours"`
+}
+
+// DefaultIdempotencyConfig returns the default idempotency configuration.
+func DefaultIdempotencyConfig() IdempotencyConfig {
+	return IdempotencyConfig{Enabled: true, TTLHours: 24}
+}
+
+// dedupPruneInterval is how often expired entries are removed.
+const dedupPruneInterval = time.Hour
+
+// MessageDedup is the seen-set of incoming message IDs.
+type MessageDedup struct {
+	db     *sql.DB // nil = in-memory only
+	coord  *coordination.Coordinator
+	ttl    time.Duration
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu         sync.Mutex
+	seen       map[string]time.Time // used when db is nil
+	lastPruned time.Time
+}
+
+// NewMessageDedup creates the seen_messages table if needed. With a nil db
+// the seen-set is kept in memory and only covers the current process.
+func NewMessageDedup(db *sql.DB, cfg IdempotencyConfig, logger *slog.Logger) (*MessageDedup, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.TTLHours <= 0 {
+		cfg.TTLHours = DefaultIdempotencyConfig().TTLHours
+	}
+	if db != nil {
+		if _, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS seen_messages (
+				channel    TEXT NOT NULL,
+				message_id TEXT NOT NULL,
+				seen_at    INTEGER NOT NULL,
+				PRIMARY KEY (channel, message_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_seen_messages_seen_at ON seen_messages(seen_at)`); err != nil {
+			return nil, fmt.Errorf("create seen_messages table: %w", err)
+		}
+	}
+	return &MessageDedup{
+		db:     db,
+		ttl:    time.Duration(cfg.TTLHours) * time.Hour,
+		logger: logger.With("component", "idempotency"),
+		now:    time.Now,
+		seen:   make(map[string]time.Time),
+	}, nil
+}
+
+// SetCoordinator also claims message IDs in the coordination lease store,
+// so instances with separate databases agree on who handles a message.
+func (d *MessageDedup) SetCoordinator(c *coordination.Coordinator) {
+	d.coord = c
+}
+
+// Claim records the message and reports whether it is new. Messages without
+// an ID cannot be deduplicated and are always new. Storage errors fail open:
+// a rare double reply is better than a dropped message.
+func (d *MessageDedup) Claim(ctx context.Context, channel, messageID string) bool {
+	if messageID == "" {
+		return true
+	}
+	now := d.now()
+	d.prune(now)
+
+	fresh, err := d.claimLocal(channel, messageID, now)
+	if err != nil {
+		d.logger.Warn("seen-set unavailable, accepting message", "error", err)
+	}
+	if !fresh {
+		return false
+	}
+
+	if d.coord != nil {
+		ok, err := d.coord.Claim(ctx, channel+"/"+messageID, d.ttl)
+		if err != nil {
+			d.logger.Warn("shared message claim failed, accepting message", "error", err)
+			return true
+		}
+		return ok
+	}
+	return true
+}
+
+// claimLocal inserts the ID, or takes over an entry older than the TTL.
+func (d *MessageDedup) claimLocal(channel, messageID string, now time.Time) (bool, error) {
+	cutoff := now.Add(-d.ttl)
+	if d.db == nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		key := channel + "\x00" + messageID
+		if at, ok := d.seen[key]; ok && at.After(cutoff) {
+			return false, nil
+		}
+		d.seen[key] = now
+		return true, nil
+	}
+
+	res, err := d.db.Exec(`
+		INSERT INTO seen_messages (channel, message_id, seen_at) VALUES (?, ?, ?)
+		ON CONFLICT (channel, message_id) DO UPDATE SET seen_at = excluded.seen_at
+		WHERE seen_messages.seen_at <= ?`,
+		channel, messageID, now.Unix(), cutoff.Unix())
+	if err != nil {
+		return true, fmt.Errorf("claim message: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return true, err
+	}
+	return n > 0, nil
+}
+
+// prune drops expired entries at most once per dedupPruneInterval.
+func (d *MessageDedup) prune(now time.Time) {
+	d.mu.Lock()
+	if now.Sub(d.lastPruned) < dedupPruneInterval {
+		d.mu.Unlock()
+		return
+	}
+	d.lastPruned = now
+	cutoff := now.Add(-d.ttl)
+	for key, at := range d.seen {
+		if !at.After(cutoff) {
+			delete(d.seen, key)
+		}
+	}
+	d.mu.Unlock()
+
+	if d.db != nil {
+		if _, err := d.db.Exec(`DELETE FROM seen_messages WHERE seen_at <= ?`, cutoff.Unix()); err != nil {
+			d.logger.Warn("failed to prune seen messages", "error", err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package copilot
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMessageDedup_ClaimsOncePerTTL(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	for _, store := range []struct {
+		name string
+		db   *sql.DB
+	}{{"sqlite", db}, {"memory", nil}} {
+		t.Run(store.name, func(t *testing.T) {
+			d, err := NewMessageDedup(store.db, IdempotencyConfig{Enabled: true, TTLHours: 1}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clock := now
+			d.now = func() time.Time { return clock }
+
+			if !d.Claim(ctx, "telegram", "42") {
+				t.Fatal("first delivery rejected")
+			}
+			if d.Claim(ctx, "telegram", "42") {
+				t.Fatal("redelivery accepted")
+			}
+			if !d.Claim(ctx, "whatsapp", "42") {
+				t.Error("same ID on another channel rejected")
+			}
+			if !d.Claim(ctx, "telegram", "") || !d.Claim(ctx, "telegram", "") {
+				t.Error("messages without ID must always be accepted")
+			}
+
+			clock = now.Add(2 * time.Hour)
+			if !d.Claim(ctx, "telegram", "42") {
+				t.Error("ID still rejected after TTL")
+			}
+			if d.Claim(ctx, "telegram", "42") {
+				t.Error("reclaimed ID accepted twice")
+			}
+		})
+		now = now.Add(24 * time.Hour)
+	}
+}
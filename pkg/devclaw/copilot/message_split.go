@@ -1,9 +1,15 @@
 // Package copilot – message_split.go provides splitting of long messages
 // for channels with character limits (e.g. WhatsApp 4096).
+//
+// The splitter is markdown-aware: the text is first cut into blocks
+// (paragraphs, fenced code blocks, tables) and chunks are filled with whole
+// blocks, so a break falls on a paragraph boundary whenever possible. A
+// block that doesn't fit in a chunk on its own is split by lines; code
+// blocks are re-fenced on every chunk and tables repeat their header, so
+// each chunk renders correctly on its own.
 package copilot
 
 import (
-	"fmt"
 	"strings"
 )
 
@@ -15,51 +21,115 @@ const (
 	MaxMessageDefault = 4000
 )
 
-// extractCodeBlocks replaces ```...``` blocks with placeholders. Returns the
-// modified text and a list of (placeholder, original) pairs.
-func extractCodeBlocks(text string) (string, []struct{ placeholder, content string }) {
-	var blocks []struct{ placeholder, content string }
-	var result strings.Builder
-	i := 0
-	for i < len(text) {
-		if strings.HasPrefix(text[i:], "```") {
-			// Find start of code block
-			start := i
-			i += 3
-			// Optional language tag
-			for i < len(text) && (text[i] >= 'a' && text[i] <= 'z' || text[i] >= 'A' && text[i] <= 'Z' || text[i] >= '0' && text[i] <= '9') {
-				i++
+// mdBlockKind classifies a markdown block for splitting.
+type mdBlockKind int
+
+const (
+	mdProse mdBlockKind = iota
+	mdCode
+	mdTable
+)
+
+// mdBlock is a run of lines of one kind; start and end are byte offsets
+// into the original text.
+type mdBlock struct {
+	kind       mdBlockKind
+	start, end int
+}
+
+// fenceMarker returns the fence (``` or ~~~, possibly longer) opening a
+// code block on line, or "".
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return ""
+	}
+	ch := trimmed[0]
+	if ch != '`' && ch != '~' {
+		return ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == ch {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+	return trimmed[:n]
+}
+
+// closesFence reports whether line closes a block opened with marker.
+func closesFence(line, marker string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, marker[:1]) == ""
+}
+
+// isTableLine reports whether line is a row of a pipe table.
+func isTableLine(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// parseMarkdownBlocks cuts text into paragraphs, code blocks and tables.
+// Blank lines separate blocks and belong to none; an unclosed fence runs to
+// the end of the text.
+func parseMarkdownBlocks(text string) []mdBlock {
+	var blocks []mdBlock
+	var cur *mdBlock
+	fence := ""
+
+	flush := func() {
+		if cur != nil {
+			blocks = append(blocks, *cur)
+			cur = nil
+		}
+	}
+
+	for pos := 0; pos < len(text); {
+		end := strings.IndexByte(text[pos:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += pos
+		}
+		line := text[pos:end]
+
+		switch {
+		case fence != "":
+			cur.end = end
+			if closesFence(line, fence) {
+				fence = ""
+				flush()
 			}
-			// Optional newline
-			if i < len(text) && text[i] == '\n' {
-				i++
+		case fenceMarker(line) != "":
+			flush()
+			fence = fenceMarker(line)
+			cur = &mdBlock{kind: mdCode, start: pos, end: end}
+		case strings.TrimSpace(line) == "":
+			flush()
+		case isTableLine(line):
+			if cur == nil || cur.kind != mdTable {
+				flush()
+				cur = &mdBlock{kind: mdTable, start: pos}
 			}
-			// Find closing ```
-			closeIdx := strings.Index(text[i:], "```")
-			var blockContent string
-			if closeIdx < 0 {
-				// No closing, treat rest as code
-				blockContent = text[start:]
-				i = len(text)
-			} else {
-				blockContent = text[start : i+closeIdx+3]
-				i += closeIdx + 3
+			cur.end = end
+		default:
+			if cur == nil || cur.kind != mdProse {
+				flush()
+				cur = &mdBlock{kind: mdProse, start: pos}
 			}
-
-			ph := fmt.Sprintf("<<<DEVCLAW_CODE_%d>>>", len(blocks))
-			blocks = append(blocks, struct{ placeholder, content string }{ph, blockContent})
-			result.WriteString(ph)
-			continue
+			cur.end = end
 		}
-		result.WriteByte(text[i])
-		i++
+		pos = end + 1
 	}
-	return result.String(), blocks
+	flush()
+	return blocks
 }
 
-// SplitMessage splits a long message into chunks respecting maxLen.
-// Tries to break on paragraph boundaries, then sentence boundaries, then word boundaries.
-// Does not split inside ``` code blocks.
+// SplitMessage splits a long message into chunks of at most maxLen bytes.
+// Chunks break between paragraphs, code blocks and tables when possible;
+// blocks that are too long on their own are split by lines (then
+// sentences, then words), re-opening code fences and repeating table
+// headers on the following chunk.
 func SplitMessage(text string, maxLen int) []string {
 	if maxLen <= 0 {
 		maxLen = MaxMessageDefault
@@ -71,98 +141,186 @@ func SplitMessage(text string, maxLen int) []string {
 		return []string{text}
 	}
 
-	// Extract code blocks and replace with placeholders to avoid splitting inside them.
-	textWithPlaceholders, blocks := extractCodeBlocks(text)
-
 	var chunks []string
-	remain := textWithPlaceholders
+	var cur strings.Builder
+	prevEnd := -1
 
-	for len(remain) > maxLen {
-		segment := remain[:maxLen]
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+	}
 
-		// Find the best split point (priority: paragraph > line > sentence > word > hard)
-		splitAt := -1
-		searchIn := segment
+	for _, b := range parseMarkdownBlocks(text) {
+		block := text[b.start:b.end]
+		sep := ""
+		if cur.Len() > 0 {
+			// Keep the original spacing between blocks in the same chunk.
+			sep = text[prevEnd:b.start]
+		}
+		prevEnd = b.end
 
-		// 1. Paragraph boundary (\n\n)
-		if idx := strings.LastIndex(searchIn, "\n\n"); idx >= 0 && idx > maxLen/2 {
-			splitAt = idx + 2 // include \n\n in first chunk
+		if cur.Len()+len(sep)+len(block) <= maxLen {
+			cur.WriteString(sep)
+			cur.WriteString(block)
+			continue
+		}
+		flush()
+		if len(block) <= maxLen {
+			cur.WriteString(block)
+			continue
 		}
 
-		// 2. Line boundary (\n)
-		if splitAt < 0 {
-			if idx := strings.LastIndex(searchIn, "\n"); idx >= 0 && idx > maxLen/2 {
-				splitAt = idx + 1
+		var pieces []string
+		switch b.kind {
+		case mdCode:
+			pieces = splitCodeBlock(block, maxLen)
+		case mdTable:
+			pieces = splitTable(block, maxLen)
+		default:
+			pieces = splitProse(block, maxLen)
+		}
+		for i, p := range pieces {
+			if i == len(pieces)-1 {
+				// The last piece may share its chunk with what follows.
+				cur.WriteString(p)
+			} else {
+				chunks = append(chunks, strings.TrimSpace(p))
 			}
 		}
+	}
+	flush()
+	return chunks
+}
 
-		// 3. Sentence boundary (. )
-		if splitAt < 0 {
-			if idx := strings.LastIndex(searchIn, ". "); idx >= 0 && idx > maxLen/2 {
-				splitAt = idx + 2
+// splitProse splits a paragraph at the last line, sentence or word
+// boundary in the second half of each chunk, cutting hard when there is
+// none.
+func splitProse(text string, maxLen int) []string {
+	var out []string
+	remain := text
+	for len(remain) > maxLen {
+		segment := truncateUTF8(remain, maxLen)
+		splitAt := -1
+		for _, sep := range []string{"\n", ". ", " "} {
+			if idx := strings.LastIndex(segment, sep); idx >= 0 && idx > maxLen/2 {
+				splitAt = idx + len(sep)
+				break
 			}
 		}
-
-		// 4. Word boundary (space)
 		if splitAt < 0 {
-			if idx := strings.LastIndex(searchIn, " "); idx >= 0 && idx > maxLen/2 {
-				splitAt = idx + 1
-			}
+			splitAt = len(segment)
 		}
-
-		// 5. Avoid splitting in the middle of a placeholder
-		if splitAt >= 0 {
-			for _, b := range blocks {
-				idx := strings.Index(segment, b.placeholder)
-				if idx >= 0 {
-					end := idx + len(b.placeholder)
-					if splitAt > idx && splitAt < end {
-						splitAt = idx
-						break
-					}
-				}
-			}
+		if chunk := strings.TrimSpace(remain[:splitAt]); chunk != "" {
+			out = append(out, chunk)
 		}
+		remain = strings.TrimLeft(remain[splitAt:], " \n")
+	}
+	if remain != "" {
+		out = append(out, remain)
+	}
+	return out
+}
 
-		if splitAt > 0 {
-			chunk := strings.TrimSpace(remain[:splitAt])
-			remain = strings.TrimLeft(remain[splitAt:], " \n")
-			if chunk != "" {
-				chunks = append(chunks, restoreCodeBlocks(chunk, blocks))
-			}
-		} else {
-			// Hard split - avoid cutting a placeholder
-			cut := maxLen
-			for _, b := range blocks {
-				idx := strings.Index(remain, b.placeholder)
-				if idx >= 0 {
-					end := idx + len(b.placeholder)
-					if idx < maxLen && end > maxLen {
-						// Placeholder spans the cut - include it entirely in this chunk
-						cut = end
-						break
-					}
-				}
+// splitCodeBlock splits a fenced code block by lines, closing the fence at
+// the end of each piece and re-opening it (with the same info string) at
+// the start of the next.
+func splitCodeBlock(block string, maxLen int) []string {
+	lines := strings.Split(block, "\n")
+	open := lines[0]
+	marker := fenceMarker(open)
+	body := lines[1:]
+	if n := len(body); n > 0 && closesFence(body[n-1], marker) {
+		body = body[:n-1]
+	}
+	closing := "\n" + strings.TrimSpace(open)[:len(marker)]
+
+	// Room for code once the fences are added; fall back to plain splitting
+	// when the limit is too small to hold them.
+	room := maxLen - len(open) - 1 - len(closing)
+	if room < 1 {
+		return splitProse(block, maxLen)
+	}
+
+	var out []string
+	var cur []string
+	size := 0
+	emit := func() {
+		out = append(out, open+"\n"+strings.Join(cur, "\n")+closing)
+		cur, size = nil, 0
+	}
+	for _, line := range body {
+		for len(line) > room {
+			// A single line longer than the chunk: cut it hard.
+			if len(cur) > 0 {
+				emit()
 			}
-			chunk := strings.TrimSpace(remain[:cut])
-			remain = remain[cut:]
-			if chunk != "" {
-				chunks = append(chunks, restoreCodeBlocks(chunk, blocks))
+			head := truncateUTF8(line, room)
+			if head == "" {
+				head = line[:room]
 			}
+			cur = []string{head}
+			emit()
+			line = line[len(head):]
+		}
+		add := len(line)
+		if len(cur) > 0 {
+			add++ // newline
 		}
+		if size+add > room && len(cur) > 0 {
+			emit()
+			add = len(line)
+		}
+		cur = append(cur, line)
+		size += add
 	}
-
-	if remain != "" {
-		chunks = append(chunks, restoreCodeBlocks(strings.TrimSpace(remain), blocks))
+	if len(cur) > 0 {
+		emit()
 	}
-
-	return chunks
+	return out
 }
 
-// restoreCodeBlocks replaces placeholders with original code block content.
-func restoreCodeBlocks(s string, blocks []struct{ placeholder, content string }) string {
-	for _, b := range blocks {
-		s = strings.ReplaceAll(s, b.placeholder, b.content)
+// splitTable splits a pipe table by rows, repeating the header row and its
+// separator on every piece.
+func splitTable(block string, maxLen int) []string {
+	lines := strings.Split(block, "\n")
+	header := ""
+	rows := lines
+	if len(lines) >= 2 && strings.Trim(strings.TrimSpace(lines[1]), "|-: ") == "" {
+		header = lines[0] + "\n" + lines[1]
+		rows = lines[2:]
+	}
+	if header != "" && len(header)+1 >= maxLen/2 {
+		header = "" // a header this large would leave little room for rows
+		rows = lines
+	}
+
+	var out []string
+	var cur strings.Builder
+	emit := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, row := range rows {
+		if len(row) > maxLen-len(header)-1 {
+			emit()
+			out = append(out, splitProse(row, maxLen)...)
+			continue
+		}
+		if cur.Len() > 0 && cur.Len()+1+len(row) > maxLen {
+			emit()
+		}
+		if cur.Len() == 0 && header != "" {
+			cur.WriteString(header)
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte('\n')
+		}
+		cur.WriteString(row)
 	}
-	return s
+	emit()
+	return out
 }
@@ -113,3 +113,69 @@ func TestSplitMessage_AllChunksWithinLimit(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitMessage_KeepsCodeBlockWhole(t *testing.T) {
+	t.Parallel()
+	intro := strings.Repeat("intro ", 10)
+	code := "```go\n" + strings.Repeat("x := 1\n", 8) + "```"
+	text := intro + "\n\n" + code + "\n\nafter"
+	got := SplitMessage(text, 80)
+	if len(got) != 2 || got[1] != code+"\n\nafter" {
+		t.Fatalf("expected the code block to start the second chunk intact, got %q", got)
+	}
+}
+
+func TestSplitMessage_ReopensLongCodeBlock(t *testing.T) {
+	t.Parallel()
+	code := "```python\n" + strings.Repeat("print('hello world')\n", 20) + "```"
+	got := SplitMessage(code, 120)
+	if len(got) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(got))
+	}
+	lines := 0
+	for i, c := range got {
+		if len(c) > 120 {
+			t.Errorf("chunk %d too long: %d", i, len(c))
+		}
+		if !strings.HasPrefix(c, "```python\n") || !strings.HasSuffix(c, "\n```") {
+			t.Errorf("chunk %d is not fenced: %q", i, c)
+		}
+		lines += strings.Count(c, "print(")
+	}
+	if lines != 20 {
+		t.Errorf("expected 20 code lines across chunks, got %d", lines)
+	}
+}
+
+func TestSplitMessage_RepeatsTableHeader(t *testing.T) {
+	t.Parallel()
+	var b strings.Builder
+	b.WriteString("| Name | Qty |\n| --- | --- |")
+	for i := 0; i < 12; i++ {
+		b.WriteString("\n| item | 10 |")
+	}
+	got := SplitMessage(b.String(), 80)
+	if len(got) < 2 {
+		t.Fatalf("expected the table to be split, got %d chunks", len(got))
+	}
+	rows := 0
+	for i, c := range got {
+		if !strings.HasPrefix(c, "| Name | Qty |\n| --- | --- |\n") {
+			t.Errorf("chunk %d lacks the header: %q", i, c)
+		}
+		rows += strings.Count(c, "| item |")
+	}
+	if rows != 12 {
+		t.Errorf("expected 12 rows across chunks, got %d", rows)
+	}
+}
+
+func TestSplitMessage_PrefersParagraphBoundary(t *testing.T) {
+	t.Parallel()
+	first := strings.Repeat("one ", 8)
+	second := strings.Repeat("two ", 15)
+	got := SplitMessage(first+"\n\n"+second, 70)
+	if len(got) != 2 || got[0] != strings.TrimSpace(first) || got[1] != strings.TrimSpace(second) {
+		t.Errorf("expected a break between paragraphs, got %q", got)
+	}
+}
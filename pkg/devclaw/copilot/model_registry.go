@@ -0,0 +1,179 @@
+// Package copilot – model_registry.go loads models.yaml, a registry of
+// per-model limits, prices and capabilities. Entries override the built-in
+// defaults (getModelDefaults, defaultModelCosts), so a new model or a price
+// change doesn't need a release. The registry is consulted by LLMClient
+// (output limit, tool and vision support), UsageTracker (cost estimates),
+// the agent's context budgeting (context window) and the prompt's temporal
+// layer (knowledge cutoff).
+//
+// Example models.yaml:
+//
+//	models:
+//	  gpt-4o:
+//	    context_window: 128000
+//	    max_output_tokens: 16384
+//	    input_per_1m: 2.50
+//	    output_per_1m: 10.00
+//	    vision: true
+//	    tools: true
+//	    knowledge_cutoff: 2023-10
+//	  my-local-llama:
+//	    context_window: 8192
+//	    tools: false
+//
+// Model names match exactly or by the longest prefix, so "gpt-4o" also
+// covers "gpt-4o-2024-08-06".
+package copilot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelInfo describes one model. Zero values (and nil capabilities) fall
+// back to the built-in defaults.
+type ModelInfo struct {
+	// ContextWindow is the context window in tokens (prompt + output).
+	ContextWindow int `yaml:"context_window"`
+
+	// MaxOutputTokens is the default max_tokens for replies.
+	MaxOutputTokens int `yaml:"max_output_tokens"`
+
+	// InputPer1M and OutputPer1M are USD prices per 1M tokens.
+	InputPer1M  float64 `yaml:"input_per_1m"`
+	OutputPer1M float64 `yaml:"output_per_1m"`
+
+	// CacheReadPer1M and CacheWritePer1M price prompt cache reads and
+	// writes (zero = the input price).
+	CacheReadPer1M  float64 `yaml:"cache_read_per_1m"`
+	CacheWritePer1M float64 `yaml:"cache_write_per_1m"`
+
+	// Vision and Tools report image input and function calling support.
+	Vision *bool `yaml:"vision"`
+	Tools  *bool `yaml:"tools"`
+
+	// KnowledgeCutoff is the end of the training data ("YYYY-MM").
+	KnowledgeCutoff string `yaml:"knowledge_cutoff"`
+}
+
+// Cost returns the model's pricing, if the registry sets one.
+func (m ModelInfo) Cost() (ModelCost, bool) {
+	if m.InputPer1M == 0 && m.OutputPer1M == 0 {
+		return ModelCost{}, false
+	}
+	return ModelCost{
+		InputPer1M:      m.InputPer1M,
+		OutputPer1M:     m.OutputPer1M,
+		CacheReadPer1M:  m.CacheReadPer1M,
+		CacheWritePer1M: m.CacheWritePer1M,
+	}, true
+}
+
+// ModelRegistry holds the models loaded from models.yaml. A nil registry
+// knows no models, so callers fall back to the built-ins.
+type ModelRegistry struct {
+	models map[string]ModelInfo
+}
+
+// modelRegistryFile is the on-disk format of models.yaml.
+type modelRegistryFile struct {
+	Models map[string]ModelInfo `yaml:"models"`
+}
+
+// LoadModelRegistry reads the registry at path. A missing file yields an
+// empty registry.
+func LoadModelRegistry(path string) (*ModelRegistry, error) {
+	r := &ModelRegistry{models: make(map[string]ModelInfo)}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("reading model registry: %w", err)
+	}
+
+	var file modelRegistryFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing model registry %s: %w", path, err)
+	}
+	for name, info := range file.Models {
+		if info.ContextWindow < 0 || info.MaxOutputTokens < 0 || info.InputPer1M < 0 || info.OutputPer1M < 0 {
+			return nil, fmt.Errorf("model registry %s: %s has a negative limit or price", path, name)
+		}
+		if info.KnowledgeCutoff != "" && parseCutoff(info.KnowledgeCutoff).IsZero() {
+			return nil, fmt.Errorf("model registry %s: %s knowledge_cutoff %q is not YYYY-MM", path, name, info.KnowledgeCutoff)
+		}
+		r.models[name] = info
+	}
+	return r, nil
+}
+
+// Len returns the number of registered models.
+func (r *ModelRegistry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.models)
+}
+
+// Lookup returns the entry for model, matching exactly or by the longest
+// registered prefix.
+func (r *ModelRegistry) Lookup(model string) (ModelInfo, bool) {
+	if r == nil || model == "" {
+		return ModelInfo{}, false
+	}
+	if info, ok := r.models[model]; ok {
+		return info, true
+	}
+	best := ""
+	for name := range r.models {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelInfo{}, false
+	}
+	return r.models[best], true
+}
+
+// apply overlays the registry entry for model onto the built-in defaults.
+func (r *ModelRegistry) apply(model string, d modelDefaults) modelDefaults {
+	info, ok := r.Lookup(model)
+	if !ok {
+		return d
+	}
+	if info.ContextWindow > 0 {
+		d.ContextWindow = info.ContextWindow
+	}
+	if info.MaxOutputTokens > 0 {
+		d.MaxOutputTokens = info.MaxOutputTokens
+	}
+	if info.Tools != nil {
+		d.SupportsTools = *info.Tools
+	}
+	if info.Vision != nil {
+		d.SupportsVision = *info.Vision
+	}
+	if info.KnowledgeCutoff != "" {
+		d.KnowledgeCutoff = info.KnowledgeCutoff
+	}
+	return d
+}
+
+// parseCutoff parses a "YYYY-MM" or "YYYY-MM-DD" cutoff (zero when invalid).
+func parseCutoff(s string) time.Time {
+	for _, layout := range []string{"2006-01", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
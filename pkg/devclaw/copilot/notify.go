@@ -0,0 +1,123 @@
+// Package copilot – notify.go implements Assistant.Notify, the programmatic
+// way to push a proactive message (embedders, the gateway's POST /v1/notify).
+// Notifications go through the output guardrail and the same channel
+// formatting and chunking as replies, and are recorded in the chat's
+// session so a reply to them has context.
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// NotifyOptions selects the target of a notification.
+type NotifyOptions struct {
+	// Channel and To address a chat explicitly. When both are empty, the
+	// workspace's most recently active chat on a connected channel is used.
+	Channel string
+	To      string
+
+	// Attachments are sent after the text. An empty Type is derived from
+	// MimeType.
+	Attachments []*channels.MediaMessage
+
+	// NoRecord skips adding the message to the chat's session history.
+	NoRecord bool
+}
+
+// NotifyResult reports where a notification was delivered.
+type NotifyResult struct {
+	WorkspaceID string `json:"workspace_id"`
+	Channel     string `json:"channel"`
+	To          string `json:"to"`
+	Chunks      int    `json:"chunks"`
+}
+
+// notifyHistoryMarker stands in for the user turn of a recorded notification.
+const notifyHistoryMarker = "[notification]"
+
+// ErrNoNotifyTarget is returned when no chat could be chosen for a
+// notification without an explicit target.
+var ErrNoNotifyTarget = errors.New("no target: set channel and to, or talk to the workspace on a channel first")
+
+// Notify sends a proactive message to a chat of the workspace (the default
+// workspace when workspaceID is empty).
+func (a *Assistant) Notify(ctx context.Context, workspaceID, text string, opts NotifyOptions) (*NotifyResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" && len(opts.Attachments) == 0 {
+		return nil, fmt.Errorf("text or attachments required")
+	}
+	if workspaceID == "" {
+		workspaceID = a.workspaceMgr.defaultWSID
+	}
+	if _, ok := a.workspaceMgr.Get(workspaceID); !ok {
+		return nil, fmt.Errorf("workspace %q not found", workspaceID)
+	}
+	if text != "" {
+		text = a.redactReplySecrets(ctx, text)
+		if err := a.outputGuard.Validate(text); err != nil {
+			return nil, fmt.Errorf("rejected by output guardrail: %w", err)
+		}
+	}
+
+	for _, att := range opts.Attachments {
+		if att.Type == "" {
+			att.Type = mediaTypeForMime(att.MimeType)
+		}
+	}
+
+	channel, to := opts.Channel, opts.To
+	switch {
+	case channel != "" && to != "":
+	case channel != "" || to != "":
+		return nil, fmt.Errorf("channel and to must be set together")
+	default:
+		var ok bool
+		channel, to, ok = a.workspaceMgr.LastActiveChat(workspaceID, a.channelConnected)
+		if !ok {
+			return nil, ErrNoNotifyTarget
+		}
+	}
+	if !a.channelConnected(channel) {
+		return nil, fmt.Errorf("channel %q is not connected", channel)
+	}
+
+	var chunks []string
+	if formatted := FormatForChannel(text, channel); formatted != "" {
+		if chunks = SplitMessage(formatted, MaxMessageDefault); chunks == nil {
+			chunks = []string{formatted}
+		}
+	}
+	if len(chunks) == 0 && len(opts.Attachments) == 0 {
+		return nil, fmt.Errorf("nothing to send after formatting")
+	}
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+	for i, chunk := range chunks {
+		out := &channels.OutgoingMessage{Content: chunk}
+		if i == len(chunks)-1 {
+			out.Attachments = opts.Attachments
+		}
+		if err := a.channelMgr.Send(ctx, channel, to, out); err != nil {
+			return nil, fmt.Errorf("sending to %s:%s: %w", channel, to, err)
+		}
+	}
+
+	if !opts.NoRecord && text != "" {
+		a.workspaceMgr.SessionIn(workspaceID, channel, to).AddMessage(notifyHistoryMarker, text)
+	}
+	a.logger.Info("notification delivered",
+		"workspace", workspaceID, "channel", channel, "chat_id", to, "chunks", len(chunks))
+	return &NotifyResult{WorkspaceID: workspaceID, Channel: channel, To: to, Chunks: len(chunks)}, nil
+}
+
+// channelConnected reports whether a registered channel is connected.
+func (a *Assistant) channelConnected(name string) bool {
+	ch, ok := a.channelMgr.Channel(name)
+	return ok && ch.IsConnected()
+}
@@ -0,0 +1,410 @@
+// Package copilot – oauth.go implements subscription login: an OAuth 2.0
+// device authorization grant (RFC 8628) against the ChatGPT or Claude
+// identity provider, with the resulting tokens kept in the OS keyring.
+// When api.subscription is set, LLMClient authenticates with the access
+// token (refreshing it when it expires) instead of an API key, so a ChatGPT
+// Plus or Claude Pro plan can be used without separate API billing.
+//
+// Login is done once from a terminal:
+//
+//	devclaw auth login claude
+//
+// Endpoints and client IDs can be overridden under api.oauth when a
+// provider changes them.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SubscriptionProvider describes the OAuth endpoints of a subscription
+// and the API its tokens are accepted by.
+type SubscriptionProvider struct {
+	// Name is the subscription key ("chatgpt", "claude").
+	Name string `yaml:"-"`
+
+	// ClientID is the public OAuth client identifier.
+	ClientID string `yaml:"client_id"`
+
+	// DeviceURL is the device authorization endpoint.
+	DeviceURL string `yaml:"device_url"`
+
+	// TokenURL is the token endpoint (device code and refresh grants).
+	TokenURL string `yaml:"token_url"`
+
+	// Scopes requested at login.
+	Scopes []string `yaml:"scopes"`
+
+	// BaseURL is the API endpoint used when api.base_url is empty.
+	BaseURL string `yaml:"base_url"`
+
+	// Provider is the API format spoken by BaseURL ("openai", "anthropic").
+	Provider string `yaml:"provider"`
+}
+
+// subscriptionProviders are the built-in subscriptions.
+var subscriptionProviders = map[string]SubscriptionProvider{
+	"chatgpt": {
+		Name:      "chatgpt",
+		ClientID:  "app_EMoamEEZ73f0CkXaXp7hrann",
+		DeviceURL: "https://auth.openai.com/oauth/device/code",
+		TokenURL:  "https://auth.openai.com/oauth/token",
+		Scopes:    []string{"openid", "profile", "email", "offline_access"},
+		BaseURL:   "https://api.openai.com/v1",
+		Provider:  "openai",
+	},
+	"claude": {
+		Name:      "claude",
+		ClientID:  "9d1c250a-e61b-44d9-88ed-5944d1962f5e",
+		DeviceURL: "https://console.anthropic.com/v1/oauth/device/code",
+		TokenURL:  "https://console.anthropic.com/v1/oauth/token",
+		Scopes:    []string{"user:inference", "user:profile"},
+		BaseURL:   "https://api.anthropic.com",
+		Provider:  "anthropic",
+	},
+}
+
+// SubscriptionNames lists the built-in subscriptions.
+func SubscriptionNames() []string {
+	names := make([]string, 0, len(subscriptionProviders))
+	for name := range subscriptionProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveSubscription returns the provider for name with the non-empty
+// fields of override applied.
+func ResolveSubscription(name string, override SubscriptionProvider) (SubscriptionProvider, error) {
+	p, ok := subscriptionProviders[strings.ToLower(name)]
+	if !ok {
+		return SubscriptionProvider{}, fmt.Errorf("unknown subscription %q (available: %s)", name, strings.Join(SubscriptionNames(), ", "))
+	}
+	if override.ClientID != "" {
+		p.ClientID = override.ClientID
+	}
+	if override.DeviceURL != "" {
+		p.DeviceURL = override.DeviceURL
+	}
+	if override.TokenURL != "" {
+		p.TokenURL = override.TokenURL
+	}
+	if len(override.Scopes) > 0 {
+		p.Scopes = override.Scopes
+	}
+	if override.BaseURL != "" {
+		p.BaseURL = override.BaseURL
+	}
+	if override.Provider != "" {
+		p.Provider = override.Provider
+	}
+	return p, nil
+}
+
+// OAuthToken is a subscription token set as stored in the keyring.
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+}
+
+// oauthRefreshMargin refreshes tokens slightly before they expire, so a
+// request doesn't race the expiry.
+const oauthRefreshMargin = time.Minute
+
+// Expired reports whether the access token is (about to be) unusable.
+func (t *OAuthToken) Expired() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(oauthRefreshMargin).After(t.ExpiresAt)
+}
+
+// oauthKeyringKey is the keyring entry holding a subscription's tokens.
+func oauthKeyringKey(subscription string) string {
+	return "oauth_" + subscription
+}
+
+// SaveOAuthToken stores the subscription tokens in the OS keyring.
+func SaveOAuthToken(subscription string, tok *OAuthToken) error {
+	data, err := json.Marshal(tok)
+	if err != nil {
+		return err
+	}
+	return StoreKeyring(oauthKeyringKey(subscription), string(data))
+}
+
+// LoadOAuthToken reads the subscription tokens from the OS keyring.
+// Returns nil when the user hasn't logged in.
+func LoadOAuthToken(subscription string) *OAuthToken {
+	raw := GetKeyring(oauthKeyringKey(subscription))
+	if raw == "" {
+		return nil
+	}
+	var tok OAuthToken
+	if err := json.Unmarshal([]byte(raw), &tok); err != nil || tok.AccessToken == "" {
+		return nil
+	}
+	return &tok
+}
+
+// DeleteOAuthToken removes the subscription tokens from the OS keyring.
+func DeleteOAuthToken(subscription string) error {
+	return DeleteKeyring(oauthKeyringKey(subscription))
+}
+
+// DeviceCode is the device authorization response shown to the user.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// oauthTokenResponse is the token endpoint response (success or error).
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// token converts a successful response, keeping refresh when the server
+// doesn't rotate the refresh token.
+func (r oauthTokenResponse) token(refresh string) *OAuthToken {
+	tok := &OAuthToken{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken, Scope: r.Scope}
+	if tok.RefreshToken == "" {
+		tok.RefreshToken = refresh
+	}
+	if r.ExpiresIn > 0 {
+		tok.ExpiresAt = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return tok
+}
+
+// OAuthClient runs the device flow and refreshes tokens for one provider.
+type OAuthClient struct {
+	provider   SubscriptionProvider
+	httpClient *http.Client
+}
+
+// NewOAuthClient creates an OAuth client for the subscription provider.
+func NewOAuthClient(p SubscriptionProvider) *OAuthClient {
+	return &OAuthClient{provider: p, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// RequestDeviceCode starts a device login.
+func (o *OAuthClient) RequestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{"client_id": {o.provider.ClientID}}
+	if len(o.provider.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.provider.Scopes, " "))
+	}
+	body, status, err := o.post(ctx, o.provider.DeviceURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("requesting device code: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("device authorization failed (%d): %s", status, strings.TrimSpace(string(body)))
+	}
+	var dc DeviceCode
+	if err := json.Unmarshal(body, &dc); err != nil {
+		return nil, fmt.Errorf("parsing device code: %w", err)
+	}
+	if dc.DeviceCode == "" || dc.UserCode == "" {
+		return nil, fmt.Errorf("device authorization returned no code")
+	}
+	return &dc, nil
+}
+
+// deviceMinInterval is the polling interval when the server sets none
+// (RFC 8628 §3.5).
+var deviceMinInterval = 5 * time.Second
+
+// PollToken waits until the user approves the device login, following the
+// server's polling interval and slow_down requests.
+func (o *OAuthClient) PollToken(ctx context.Context, dc *DeviceCode) (*OAuthToken, error) {
+	interval := max(time.Duration(dc.Interval)*time.Second, deviceMinInterval)
+	expires := time.Duration(dc.ExpiresIn) * time.Second
+	if expires <= 0 {
+		expires = 15 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, expires)
+	defer cancel()
+
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {dc.DeviceCode},
+		"client_id":   {o.provider.ClientID},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("login code expired, run the login again")
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		resp, err := o.tokenRequest(ctx, form)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Error {
+		case "":
+			return resp.token(""), nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += deviceMinInterval
+		case "access_denied":
+			return nil, fmt.Errorf("login was denied")
+		case "expired_token":
+			return nil, fmt.Errorf("login code expired, run the login again")
+		default:
+			return nil, fmt.Errorf("login failed: %s %s", resp.Error, resp.Description)
+		}
+	}
+}
+
+// Refresh exchanges the refresh token for a new access token.
+func (o *OAuthClient) Refresh(ctx context.Context, tok *OAuthToken) (*OAuthToken, error) {
+	if tok.RefreshToken == "" {
+		return nil, fmt.Errorf("token expired and has no refresh token, run 'devclaw auth login %s'", o.provider.Name)
+	}
+	resp, err := o.tokenRequest(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tok.RefreshToken},
+		"client_id":     {o.provider.ClientID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("refreshing token: %s %s (run 'devclaw auth login %s')", resp.Error, resp.Description, o.provider.Name)
+	}
+	return resp.token(tok.RefreshToken), nil
+}
+
+// tokenRequest posts to the token endpoint. OAuth errors come back in the
+// response, transport and unexpected HTTP errors as err.
+func (o *OAuthClient) tokenRequest(ctx context.Context, form url.Values) (*oauthTokenResponse, error) {
+	body, status, err := o.post(ctx, o.provider.TokenURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	var resp oauthTokenResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	if resp.Error == "" && (status != http.StatusOK || resp.AccessToken == "") {
+		return nil, fmt.Errorf("token endpoint returned %d without a token", status)
+	}
+	return &resp, nil
+}
+
+func (o *OAuthClient) post(ctx context.Context, endpoint string, form url.Values) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return body, resp.StatusCode, err
+}
+
+// oauthTokenSource hands out a valid access token, refreshing and
+// persisting it when it expires.
+type oauthTokenSource struct {
+	client       *OAuthClient
+	subscription string
+
+	mu  sync.Mutex
+	tok *OAuthToken
+
+	// save persists refreshed tokens (SaveOAuthToken; replaced in tests).
+	save func(string, *OAuthToken) error
+}
+
+// newOAuthTokenSource creates a token source backed by the keyring.
+func newOAuthTokenSource(p SubscriptionProvider) *oauthTokenSource {
+	return &oauthTokenSource{client: NewOAuthClient(p), subscription: p.Name, save: SaveOAuthToken}
+}
+
+// Token returns the current access token.
+func (s *oauthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok == nil {
+		s.tok = LoadOAuthToken(s.subscription)
+		if s.tok == nil {
+			return "", fmt.Errorf("not logged in to %s, run 'devclaw auth login %s'", s.subscription, s.subscription)
+		}
+	}
+	if s.tok.Expired() {
+		tok, err := s.client.Refresh(ctx, s.tok)
+		if err != nil {
+			return "", err
+		}
+		s.tok = tok
+		if s.save != nil {
+			if err := s.save(s.subscription, tok); err != nil {
+				return "", fmt.Errorf("saving refreshed token: %w", err)
+			}
+		}
+	}
+	return s.tok.AccessToken, nil
+}
+
+// oauthTransport authenticates requests to the subscription API with the
+// access token. Requests to other hosts (e.g. a separate transcription
+// endpoint) pass through untouched.
+type oauthTransport struct {
+	base      http.RoundTripper
+	source    *oauthTokenSource
+	host      string
+	anthropic bool
+}
+
+// anthropicOAuthBeta is the beta flag Anthropic requires for OAuth tokens.
+const anthropicOAuthBeta = "oauth-2025-04-20"
+
+// RoundTrip implements http.RoundTripper.
+func (t *oauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Del("x-api-key")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if t.anthropic {
+		beta := anthropicOAuthBeta
+		if existing := req.Header.Get("anthropic-beta"); existing != "" {
+			beta = existing + "," + beta
+		}
+		req.Header.Set("anthropic-beta", beta)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,93 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOAuthDeviceFlowAndRefresh(t *testing.T) {
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/device":
+			io.WriteString(w, `{"device_code":"dev","user_code":"ABCD-1234","verification_uri":"https://example.com/device","interval":0}`)
+		case r.Form.Get("grant_type") == "refresh_token":
+			io.WriteString(w, `{"access_token":"fresh","expires_in":3600}`)
+		default:
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			io.WriteString(w, `{"access_token":"first","refresh_token":"r1","expires_in":1}`)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := ResolveSubscription("claude", SubscriptionProvider{DeviceURL: srv.URL + "/device", TokenURL: srv.URL + "/token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewOAuthClient(p)
+	dc, err := client.RequestDeviceCode(context.Background())
+	if err != nil || dc.UserCode != "ABCD-1234" {
+		t.Fatalf("device code: %+v, %v", dc, err)
+	}
+	defer func(d time.Duration) { deviceMinInterval = d }(deviceMinInterval)
+	deviceMinInterval = time.Millisecond
+	tok, err := client.PollToken(context.Background(), dc)
+	if err != nil || tok.AccessToken != "first" || polls != 2 {
+		t.Fatalf("poll: %+v, %v (polls=%d)", tok, err, polls)
+	}
+	if !tok.Expired() {
+		t.Fatal("a token expiring within the refresh margin should count as expired")
+	}
+
+	var saved *OAuthToken
+	src := &oauthTokenSource{client: client, subscription: "claude", tok: tok,
+		save: func(_ string, t *OAuthToken) error { saved = t; return nil }}
+	got, err := src.Token(context.Background())
+	if err != nil || got != "fresh" || saved == nil || saved.RefreshToken != "r1" {
+		t.Fatalf("refresh: %q, %v, saved %+v", got, err, saved)
+	}
+}
+
+func TestOAuthTransport_AuthenticatesSubscriptionHostOnly(t *testing.T) {
+	var gotAuth, gotKey, gotBeta string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotKey, gotBeta = r.Header.Get("Authorization"), r.Header.Get("x-api-key"), r.Header.Get("anthropic-beta")
+	}))
+	defer api.Close()
+	u, _ := url.Parse(api.URL)
+
+	src := &oauthTokenSource{subscription: "claude", tok: &OAuthToken{AccessToken: "tok", ExpiresAt: time.Now().Add(time.Hour)}}
+	client := &http.Client{Transport: &oauthTransport{base: http.DefaultTransport, source: src, host: u.Host, anthropic: true}}
+
+	req, _ := http.NewRequest(http.MethodPost, api.URL+"/v1/messages", nil)
+	req.Header.Set("x-api-key", "unused")
+	req.Header.Set("anthropic-beta", "context-1m-2025-08-07")
+	if _, err := client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer tok" || gotKey != "" || gotBeta != "context-1m-2025-08-07,"+anthropicOAuthBeta {
+		t.Errorf("unexpected headers: auth=%q key=%q beta=%q", gotAuth, gotKey, gotBeta)
+	}
+
+	other := &oauthTransport{base: http.DefaultTransport, source: src, host: "elsewhere:1"}
+	req, _ = http.NewRequest(http.MethodGet, api.URL, nil)
+	req.Header.Set("Authorization", "Bearer own-key")
+	if _, err := (&http.Client{Transport: other}).Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer own-key" {
+		t.Errorf("requests to other hosts should keep their credentials, got %q", gotAuth)
+	}
+}
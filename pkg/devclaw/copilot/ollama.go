@@ -0,0 +1,281 @@
+// Package copilot – ollama.go adds local model support through an Ollama
+// daemon (api.provider: ollama, default http://localhost:11434/v1). Chat
+// goes through Ollama's OpenAI-compatible endpoint; the native API is used
+// to pull missing models on first use and to read each model's
+// capabilities, so tools and image input are dropped gracefully for models
+// that don't support them instead of failing the request.
+//
+// Provider params:
+//
+//	auto_pull_disabled: true  — fail instead of pulling a missing model
+//	stream_tools: true        — stream requests with tools (needs a recent
+//	                            Ollama; by default they are sent unstreamed)
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultOllamaBaseURL is the OpenAI-compatible endpoint of a local daemon.
+const defaultOllamaBaseURL = "http://localhost:11434/v1"
+
+// ollamaModel is what the daemon reports about an installed model.
+type ollamaModel struct {
+	tools         bool
+	vision        bool
+	contextLength int
+	known         bool // capabilities were reported (older daemons omit them)
+}
+
+// ollamaShowResponse is the subset of /api/show the client uses.
+type ollamaShowResponse struct {
+	Capabilities []string       `json:"capabilities"`
+	ModelInfo    map[string]any `json:"model_info"`
+}
+
+// ollamaPullStatus is one line of the /api/pull progress stream.
+type ollamaPullStatus struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// isOllama reports whether the client talks to an Ollama daemon.
+func (c *LLMClient) isOllama() bool {
+	return c.provider == "ollama"
+}
+
+// ollamaRoot returns the native API root for the OpenAI-compatible base URL.
+func (c *LLMClient) ollamaRoot() string {
+	return strings.TrimSuffix(c.baseURL, "/v1")
+}
+
+// EnsureLocalModels pulls the configured model and fallback models when
+// they are missing from the Ollama daemon. No-op for other providers.
+func (c *LLMClient) EnsureLocalModels(ctx context.Context) error {
+	if !c.isOllama() {
+		return nil
+	}
+	for _, m := range append([]string{c.model}, c.fallback.Models...) {
+		if err := c.ensureOllamaModel(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureOllamaModel makes sure model is installed, pulling it if needed,
+// and caches its capabilities. Later calls for the same model are free.
+func (c *LLMClient) ensureOllamaModel(ctx context.Context, model string) error {
+	if !c.isOllama() || model == "" {
+		return nil
+	}
+	c.ollamaMu.Lock()
+	defer c.ollamaMu.Unlock()
+	if _, ok := c.ollamaModels[model]; ok {
+		return nil
+	}
+
+	info, found, err := c.ollamaShow(ctx, model)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if c.paramBool("auto_pull_disabled") {
+			return fmt.Errorf("model %s is not installed in Ollama (run: ollama pull %s)", model, model)
+		}
+		if err := c.ollamaPull(ctx, model); err != nil {
+			return err
+		}
+		if info, found, err = c.ollamaShow(ctx, model); err != nil {
+			return err
+		} else if !found {
+			return fmt.Errorf("model %s still missing after pull", model)
+		}
+	}
+
+	if c.ollamaModels == nil {
+		c.ollamaModels = make(map[string]ollamaModel)
+	}
+	c.ollamaModels[model] = info
+	c.logger.Debug("ollama model ready", "model", model,
+		"tools", info.tools, "vision", info.vision, "context_length", info.contextLength)
+	return nil
+}
+
+// ollamaShow fetches a model's details; found is false when it isn't
+// installed.
+func (c *LLMClient) ollamaShow(ctx context.Context, model string) (ollamaModel, bool, error) {
+	body, _ := json.Marshal(map[string]string{"model": model})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ollamaRoot()+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return ollamaModel{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ollamaModel{}, false, fmt.Errorf("ollama not reachable at %s: %w", c.ollamaRoot(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ollamaModel{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return ollamaModel{}, false, fmt.Errorf("ollama show %s: %d %s", model, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var show ollamaShowResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return ollamaModel{}, false, fmt.Errorf("parsing ollama show: %w", err)
+	}
+	info := ollamaModel{known: len(show.Capabilities) > 0}
+	for _, capability := range show.Capabilities {
+		switch capability {
+		case "tools":
+			info.tools = true
+		case "vision":
+			info.vision = true
+		}
+	}
+	for k, v := range show.ModelInfo {
+		if n, ok := v.(float64); ok && strings.HasSuffix(k, ".context_length") {
+			info.contextLength = int(n)
+		}
+	}
+	return info, true, nil
+}
+
+// ollamaPull downloads model, logging progress every few seconds.
+func (c *LLMClient) ollamaPull(ctx context.Context, model string) error {
+	c.logger.Info("pulling ollama model, this may take a while", "model", model)
+	body, _ := json.Marshal(map[string]any{"model": model, "stream": true})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ollamaRoot()+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("pulling %s: %d %s", model, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var lastLog time.Time
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var st ollamaPullStatus
+		if json.Unmarshal(scanner.Bytes(), &st) != nil {
+			continue
+		}
+		if st.Error != "" {
+			return fmt.Errorf("pulling %s: %s", model, st.Error)
+		}
+		if st.Status == "success" {
+			c.logger.Info("ollama model pulled", "model", model)
+			return nil
+		}
+		if time.Since(lastLog) >= 5*time.Second {
+			lastLog = time.Now()
+			args := []any{"model", model, "status", st.Status}
+			if st.Total > 0 {
+				args = append(args, "progress", fmt.Sprintf("%d%%", st.Completed*100/st.Total))
+			}
+			c.logger.Info("pulling ollama model", args...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	return fmt.Errorf("pulling %s: stream ended before completion", model)
+}
+
+// applyOllamaCapabilities overlays what the daemon reported for model.
+func (c *LLMClient) applyOllamaCapabilities(model string, d modelDefaults) modelDefaults {
+	c.ollamaMu.Lock()
+	info, ok := c.ollamaModels[model]
+	c.ollamaMu.Unlock()
+	if !ok {
+		return d
+	}
+	if info.known {
+		d.SupportsTools = info.tools
+		d.SupportsVision = info.vision
+	}
+	if info.contextLength > 0 {
+		d.ContextWindow = min(d.ContextWindow, info.contextLength)
+	}
+	return d
+}
+
+// ollamaStreamsTools reports whether requests with tools may be streamed.
+// Older daemons only return tool calls in non-streamed responses.
+func (c *LLMClient) ollamaStreamsTools() bool {
+	return c.paramBool("stream_tools")
+}
+
+// stripImages replaces image parts with a short note, for models without
+// image input.
+func stripImages(messages []chatMessage) []chatMessage {
+	out := messages
+	copied := false
+	for i, m := range messages {
+		parts, ok := m.Content.([]contentPart)
+		if !ok {
+			continue
+		}
+		var text []string
+		images := 0
+		for _, p := range parts {
+			if p.ImageURL != nil {
+				images++
+			} else if p.Text != "" {
+				text = append(text, p.Text)
+			}
+		}
+		if images == 0 {
+			continue
+		}
+		if !copied {
+			out = append([]chatMessage(nil), messages...)
+			copied = true
+		}
+		text = append(text, fmt.Sprintf("[%d image(s) omitted: the model has no image input]", images))
+		out[i].Content = strings.Join(text, "\n")
+	}
+	return out
+}
+
+// normalizeToolCalls fills in what some local servers leave out of tool
+// calls: an ID to match the tool result against and empty arguments.
+func normalizeToolCalls(resp *LLMResponse) {
+	if resp == nil {
+		return
+	}
+	for i := range resp.ToolCalls {
+		tc := &resp.ToolCalls[i]
+		if tc.ID == "" {
+			tc.ID = fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), i)
+		}
+		if tc.Type == "" {
+			tc.Type = "function"
+		}
+		if strings.TrimSpace(tc.Function.Arguments) == "" {
+			tc.Function.Arguments = "{}"
+		}
+	}
+}
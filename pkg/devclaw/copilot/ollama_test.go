@@ -0,0 +1,71 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOllama_PullsMissingModelAndDropsUnsupportedInput(t *testing.T) {
+	pulled := false
+	var chatBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			if !pulled {
+				http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, `{"capabilities":["completion"],"model_info":{"llama.context_length":8192}}`)
+		case "/api/pull":
+			pulled = true
+			io.WriteString(w, "{\"status\":\"pulling manifest\"}\n{\"status\":\"downloading\",\"total\":10,\"completed\":10}\n{\"status\":\"success\"}\n")
+		case "/v1/chat/completions":
+			json.NewDecoder(r.Body).Decode(&chatBody)
+			io.WriteString(w, `{"choices":[{"message":{"content":"","tool_calls":[{"function":{"name":"noop","arguments":""}}]},"finish_reason":"tool_calls"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig()
+	cfg.Model = "llama3.1"
+	cfg.API.BaseURL = srv.URL + "/v1"
+	cfg.API.Provider = "ollama"
+	c := NewLLMClient(cfg, slog.Default())
+	if !c.isOllama() {
+		t.Fatalf("expected the ollama provider, got %q", c.provider)
+	}
+
+	messages := []chatMessage{{Role: "user", Content: []contentPart{
+		{Type: "text", Text: "what is this?"},
+		{Type: "image_url", ImageURL: &imageURL{URL: "data:image/png;base64,AA=="}},
+	}}}
+	tools := []ToolDefinition{{Type: "function", Function: FunctionDef{Name: "noop"}}}
+	resp, err := c.completeOnce(context.Background(), "llama3.1", messages, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pulled {
+		t.Error("missing model should be pulled")
+	}
+	if _, ok := chatBody["tools"]; ok {
+		t.Error("tools should be dropped for a model without tool support")
+	}
+	content, _ := chatBody["messages"].([]any)[0].(map[string]any)["content"].(string)
+	if !strings.Contains(content, "what is this?") || !strings.Contains(content, "image(s) omitted") {
+		t.Errorf("images should be replaced by a note, got %q", content)
+	}
+	if got := c.ContextWindow(""); got != 8192 {
+		t.Errorf("context window should follow the model, got %d", got)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID == "" || resp.ToolCalls[0].Function.Arguments != "{}" {
+		t.Errorf("tool calls should be normalized, got %+v", resp.ToolCalls)
+	}
+}
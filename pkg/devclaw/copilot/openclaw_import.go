@@ -0,0 +1,603 @@
+// Package copilot – openclaw_import.go converts an OpenClaw (ClawdHub)
+// installation into the DevClaw layout, for `devclaw import openclaw`.
+//
+// OpenClaw keeps its settings in openclaw.json (JSON5) and its persona in a
+// workspace of bootstrap files (SOUL.md, AGENTS.md, …) plus ClawdHub
+// SKILL.md skills. Bootstrap files and skills use the same format in both
+// projects, so they are copied as is; the config is translated key by key
+// and every option without a DevClaw equivalent is reported.
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// openClawConfigNames are the config file names OpenClaw used over time.
+var openClawConfigNames = []string{"openclaw.json", "clawdbot.json", "moltbot.json"}
+
+// openClawBootstrapFiles are the workspace files DevClaw loads as well.
+var openClawBootstrapFiles = []string{
+	"SOUL.md", "AGENTS.md", "IDENTITY.md", "USER.md", "TOOLS.md", "MEMORY.md", "HEARTBEAT.md",
+}
+
+// OpenClawImport is the result of converting an OpenClaw installation.
+type OpenClawImport struct {
+	// Source is the config file that was read ("" when none was found).
+	Source string
+
+	// Config is the converted DevClaw configuration.
+	Config *Config
+
+	// Files are the bootstrap, memory and skill files to copy.
+	Files []ImportFile
+
+	// Skills lists the names of the imported skills.
+	Skills []string
+
+	// Unsupported lists OpenClaw options that were not carried over.
+	Unsupported []string
+
+	// Notes are follow-ups for the user (secrets to export, etc.).
+	Notes []string
+}
+
+// ImportFile is a file to copy, with Dst relative to the output directory.
+type ImportFile struct {
+	Src string
+	Dst string
+}
+
+// openClawProviders maps OpenClaw provider prefixes to a base URL, the
+// DevClaw provider name and the environment variable holding the key.
+var openClawProviders = map[string]struct {
+	baseURL, provider, keyEnv string
+}{
+	"anthropic":  {"https://api.anthropic.com", "anthropic", "ANTHROPIC_API_KEY"},
+	"openai":     {"https://api.openai.com/v1", "openai", "OPENAI_API_KEY"},
+	"google":     {defaultGeminiBaseURL, "gemini", "GEMINI_API_KEY"},
+	"gemini":     {defaultGeminiBaseURL, "gemini", "GEMINI_API_KEY"},
+	"openrouter": {"https://openrouter.ai/api/v1", "openrouter", "OPENROUTER_API_KEY"},
+	"xai":        {"https://api.x.ai/v1", "xai", "XAI_API_KEY"},
+	"zai":        {"https://api.z.ai/api/paas/v4", "zai", "ZAI_API_KEY"},
+	"groq":       {"https://api.groq.com/openai/v1", "openai", "GROQ_API_KEY"},
+	"ollama":     {"http://localhost:11434/v1", "ollama", ""},
+}
+
+// ImportOpenClaw reads the OpenClaw installation in dir (usually
+// ~/.openclaw) and converts it. Nothing is written.
+func ImportOpenClaw(dir string) (*OpenClawImport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	imp := &OpenClawImport{Config: DefaultConfig()}
+	raw := map[string]any{}
+	for _, name := range openClawConfigNames {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(json5ToJSON(data), &raw); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		imp.Source = path
+		break
+	}
+
+	c := &openClawConverter{imp: imp, raw: raw, used: map[string]bool{}}
+	c.convert()
+
+	workspace := c.workspaceDir(dir)
+	if workspace == "" {
+		imp.Notes = append(imp.Notes, "no OpenClaw workspace found; only the config was converted")
+	} else {
+		c.collectWorkspace(workspace)
+	}
+	skillDirs := []string{filepath.Join(dir, "skills")}
+	if workspace != "" {
+		skillDirs = append([]string{filepath.Join(workspace, "skills")}, skillDirs...)
+	}
+	for _, extra := range c.list("skills.load.extraDirs") {
+		skillDirs = append(skillDirs, expandHome(extra))
+	}
+	c.collectSkills(skillDirs)
+	if len(imp.Skills) > 0 {
+		imp.Config.Skills.ClawdHubDirs = []string{"./skills"}
+	}
+
+	imp.Unsupported = c.unsupported()
+	if imp.Source == "" && len(imp.Files) == 0 {
+		return nil, fmt.Errorf("no OpenClaw config (%s) or workspace found in %s",
+			strings.Join(openClawConfigNames, ", "), dir)
+	}
+	return imp, nil
+}
+
+// CopyFiles copies the import's files under outDir. Existing files are kept
+// unless overwrite is set; the skipped destinations are returned.
+func (imp *OpenClawImport) CopyFiles(outDir string, overwrite bool) ([]string, error) {
+	var skipped []string
+	for _, f := range imp.Files {
+		dst := filepath.Join(outDir, f.Dst)
+		if _, err := os.Stat(dst); err == nil && !overwrite {
+			skipped = append(skipped, f.Dst)
+			continue
+		}
+		if err := copyImportFile(f.Src, dst); err != nil {
+			return skipped, fmt.Errorf("copying %s: %w", f.Src, err)
+		}
+	}
+	return skipped, nil
+}
+
+// copyImportFile copies src to dst, creating parent directories and keeping
+// the executable bit (skill scripts).
+func copyImportFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm()|0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// openClawConverter walks the raw OpenClaw config, recording which keys it
+// consumed so the rest can be reported as unsupported.
+type openClawConverter struct {
+	imp  *OpenClawImport
+	raw  map[string]any
+	used map[string]bool
+}
+
+// lookup returns the value at a dotted path and marks it as consumed.
+func (c *openClawConverter) lookup(path string) (any, bool) {
+	var cur any = c.raw
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	c.used[path] = true
+	return cur, true
+}
+
+func (c *openClawConverter) str(path string) string {
+	v, _ := c.lookup(path)
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return ""
+}
+
+func (c *openClawConverter) list(path string) []string {
+	v, _ := c.lookup(path)
+	list, _ := v.([]any)
+	var out []string
+	for _, item := range list {
+		switch s := item.(type) {
+		case string:
+			out = append(out, strings.TrimSpace(s))
+		case float64:
+			out = append(out, strconv.FormatFloat(s, 'f', -1, 64))
+		}
+	}
+	return out
+}
+
+func (c *openClawConverter) object(path string) map[string]any {
+	v, _ := c.lookup(path)
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// convert maps the supported OpenClaw options onto the DevClaw config.
+func (c *openClawConverter) convert() {
+	cfg := c.imp.Config
+
+	if name := c.str("identity.name"); name != "" {
+		cfg.Name = name
+	}
+	if agents, ok := c.lookup("agents.list"); ok {
+		if list, _ := agents.([]any); len(list) > 0 {
+			if first, ok := list[0].(map[string]any); ok {
+				if name, _ := first["name"].(string); name != "" {
+					cfg.Name = name
+				}
+			}
+			if len(list) > 1 {
+				c.imp.Unsupported = append(c.imp.Unsupported,
+					"agents.list: only the first agent was imported; model the others as workspaces")
+			}
+		}
+	}
+
+	c.convertModel()
+
+	if tz := c.str("agents.defaults.userTimezone"); tz != "" {
+		cfg.Timezone = tz
+	}
+	if every := c.str("agents.defaults.heartbeat.every"); every != "" {
+		if d, err := parseGrantDuration(every); err == nil && d > 0 {
+			cfg.Heartbeat.Enabled = true
+			cfg.Heartbeat.Interval = d
+		} else {
+			c.imp.Unsupported = append(c.imp.Unsupported,
+				fmt.Sprintf("agents.defaults.heartbeat.every: cannot parse %q", every))
+		}
+	}
+
+	// OpenClaw answers in groups when mentioned; mention patterns are regexes.
+	if patterns := c.list("messages.groupChat.mentionPatterns"); len(patterns) > 0 {
+		cfg.Triggers.Patterns = append(cfg.Triggers.Patterns, patterns...)
+		cfg.Triggers.MentionOrReply = true
+	}
+
+	c.convertChannels()
+
+	if port := c.str("gateway.port"); port != "" {
+		cfg.Gateway.Address = ":" + port
+	}
+	if token := c.str("gateway.auth.token"); token != "" {
+		cfg.Gateway.AuthToken = c.secret("gateway.auth.token", token, "DEVCLAW_GATEWAY_TOKEN")
+	}
+
+	for name, v := range c.object("skills.entries") {
+		entry, _ := v.(map[string]any)
+		if enabled, ok := entry["enabled"].(bool); ok && !enabled {
+			c.imp.Notes = append(c.imp.Notes,
+				fmt.Sprintf("skill %q was disabled in OpenClaw; remove skills/%s to keep it off", name, name))
+		}
+		if _, ok := entry["apiKey"]; ok {
+			c.imp.Notes = append(c.imp.Notes,
+				fmt.Sprintf("skill %q had an API key in openclaw.json; set it in the skill's environment instead", name))
+		}
+		if env, ok := entry["env"].(map[string]any); ok && len(env) > 0 {
+			keys := make([]string, 0, len(env))
+			for k := range env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			c.imp.Notes = append(c.imp.Notes,
+				fmt.Sprintf("skill %q expects env %s; export it before `devclaw serve`", name, strings.Join(keys, ", ")))
+		}
+	}
+}
+
+// convertModel maps agents.defaults.model ("provider/model" references).
+func (c *openClawConverter) convertModel() {
+	cfg := c.imp.Config
+	primary := c.str("agents.defaults.model.primary")
+	if primary == "" {
+		primary = c.str("agents.defaults.model")
+	}
+	if primary == "" {
+		return
+	}
+	provider, model := splitOpenClawModel(primary)
+	p, known := openClawProviders[provider]
+	if !known {
+		c.imp.Unsupported = append(c.imp.Unsupported,
+			fmt.Sprintf("agents.defaults.model.primary: unknown provider %q; set api.base_url by hand", provider))
+		cfg.Model = model
+		return
+	}
+	cfg.Model = model
+	cfg.API.BaseURL = p.baseURL
+	cfg.API.Provider = p.provider
+	cfg.API.APIKey = ""
+	if p.keyEnv != "" {
+		cfg.API.APIKey = "${" + p.keyEnv + "}"
+		c.imp.Notes = append(c.imp.Notes, fmt.Sprintf("export %s (or run `devclaw config set-key`)", p.keyEnv))
+	}
+
+	for _, ref := range c.list("agents.defaults.model.fallbacks") {
+		fbProvider, fbModel := splitOpenClawModel(ref)
+		if fbProvider == provider {
+			cfg.Fallback.Models = append(cfg.Fallback.Models, fbModel)
+			continue
+		}
+		fp, ok := openClawProviders[fbProvider]
+		if !ok {
+			c.imp.Unsupported = append(c.imp.Unsupported,
+				fmt.Sprintf("agents.defaults.model.fallbacks: unknown provider in %q", ref))
+			continue
+		}
+		entry := ProviderChainEntry{Provider: fp.provider, BaseURL: fp.baseURL, Model: fbModel}
+		if fp.keyEnv != "" {
+			entry.APIKey = "${" + fp.keyEnv + "}"
+		}
+		cfg.API.Fallbacks = append(cfg.API.Fallbacks, entry)
+	}
+}
+
+// splitOpenClawModel splits "provider/model"; OpenRouter models keep their
+// own vendor prefix ("openrouter/anthropic/claude-sonnet-4").
+func splitOpenClawModel(ref string) (provider, model string) {
+	provider, model, ok := strings.Cut(ref, "/")
+	if !ok {
+		return "", ref
+	}
+	return strings.ToLower(provider), model
+}
+
+// convertChannels maps channel tokens and allowlists.
+func (c *openClawConverter) convertChannels() {
+	cfg := c.imp.Config
+	var allowed []string
+	for _, ch := range []string{"whatsapp", "telegram", "discord", "slack"} {
+		for _, id := range c.list("channels." + ch + ".allowFrom") {
+			if id == "*" {
+				continue
+			}
+			allowed = append(allowed, strings.TrimPrefix(id, "+"))
+		}
+	}
+	if len(allowed) > 0 {
+		cfg.Access.AllowedUsers = append(cfg.Access.AllowedUsers, allowed...)
+		if len(cfg.Access.Owners) == 0 {
+			cfg.Access.Owners = []string{allowed[0]}
+			c.imp.Notes = append(c.imp.Notes,
+				fmt.Sprintf("%s (first allowFrom entry) was made owner; adjust access.owners if needed", allowed[0]))
+		}
+	}
+
+	if token := c.str("channels.telegram.botToken"); token != "" {
+		cfg.Channels.Telegram.Token = c.secret("channels.telegram.botToken", token, "TELEGRAM_BOT_TOKEN")
+	}
+	if token := c.str("channels.discord.token"); token != "" {
+		cfg.Channels.Discord.Token = c.secret("channels.discord.token", token, "DISCORD_BOT_TOKEN")
+	}
+	if token := c.str("channels.slack.botToken"); token != "" {
+		cfg.Channels.Slack.BotToken = c.secret("channels.slack.botToken", token, "SLACK_BOT_TOKEN")
+	}
+	if token := c.str("channels.slack.appToken"); token != "" {
+		cfg.Channels.Slack.AppToken = c.secret("channels.slack.appToken", token, "SLACK_APP_TOKEN")
+	}
+}
+
+// secret keeps env references and replaces literal secrets with one, so
+// the converted config never holds plaintext credentials.
+func (c *openClawConverter) secret(path, value, env string) string {
+	if IsEnvReference(value) {
+		return value
+	}
+	c.imp.Notes = append(c.imp.Notes,
+		fmt.Sprintf("%s was a literal secret; export it as %s", path, env))
+	return "${" + env + "}"
+}
+
+// workspaceDir resolves the OpenClaw workspace. The configured path is
+// tried first, then <dir>/workspace (an installation copied elsewhere).
+func (c *openClawConverter) workspaceDir(dir string) string {
+	var candidates []string
+	if ws := c.str("agents.defaults.workspace"); ws != "" {
+		ws = expandHome(ws)
+		if !filepath.IsAbs(ws) {
+			ws = filepath.Join(dir, ws)
+		}
+		candidates = append(candidates, ws)
+	}
+	candidates = append(candidates, filepath.Join(dir, "workspace"), dir)
+	for _, ws := range candidates {
+		for _, name := range openClawBootstrapFiles {
+			if _, err := os.Stat(filepath.Join(ws, name)); err == nil {
+				return ws
+			}
+		}
+	}
+	return ""
+}
+
+// collectWorkspace plans the copy of bootstrap files (to the output root,
+// where DevClaw looks for them) and daily memory notes (to data/memory).
+func (c *openClawConverter) collectWorkspace(ws string) {
+	for _, name := range openClawBootstrapFiles {
+		src := filepath.Join(ws, name)
+		if _, err := os.Stat(src); err == nil {
+			c.imp.Files = append(c.imp.Files, ImportFile{Src: src, Dst: name})
+		}
+	}
+	memDir := filepath.Join(filepath.Dir(c.imp.Config.Memory.Path), "memory")
+	entries, _ := os.ReadDir(filepath.Join(ws, "memory"))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		c.imp.Files = append(c.imp.Files, ImportFile{
+			Src: filepath.Join(ws, "memory", e.Name()),
+			Dst: filepath.Join(memDir, e.Name()),
+		})
+	}
+}
+
+// collectSkills plans the copy of every skill directory (one with a
+// SKILL.md) into skills/<name>. The first directory wins on name clashes.
+func (c *openClawConverter) collectSkills(dirs []string) {
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			skillDir := filepath.Join(dir, e.Name())
+			if !e.IsDir() || seen[e.Name()] {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(skillDir, "SKILL.md")); err != nil {
+				continue
+			}
+			seen[e.Name()] = true
+			c.imp.Skills = append(c.imp.Skills, e.Name())
+			_ = filepath.WalkDir(skillDir, func(path string, d os.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if d.IsDir() {
+					if path != skillDir && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+				rel, _ := filepath.Rel(skillDir, path)
+				c.imp.Files = append(c.imp.Files, ImportFile{
+					Src: path,
+					Dst: filepath.Join("skills", e.Name(), rel),
+				})
+				return nil
+			})
+		}
+	}
+}
+
+// unsupported lists the config keys that were not consumed, plus the
+// problems found while converting.
+func (c *openClawConverter) unsupported() []string {
+	var leaves []string
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		if c.used[prefix] {
+			return
+		}
+		m, ok := v.(map[string]any)
+		if !ok || len(m) == 0 {
+			leaves = append(leaves, prefix)
+			return
+		}
+		for k, child := range m {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			walk(p, child)
+		}
+	}
+	for k, v := range c.raw {
+		walk(k, v)
+	}
+	sort.Strings(leaves)
+
+	out := append([]string{}, c.imp.Unsupported...)
+	for _, l := range leaves {
+		out = append(out, l+": no DevClaw equivalent")
+	}
+	return out
+}
+
+// expandHome expands a leading "~/".
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// json5ToJSON rewrites the JSON5 subset OpenClaw configs use — comments,
+// trailing commas, unquoted keys and single-quoted strings — as plain JSON.
+func json5ToJSON(src []byte) []byte {
+	s := []rune(string(src))
+	out := make([]rune, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '/' && i+1 < len(s) && s[i+1] == '/':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			out = append(out, '\n')
+		case ch == '/' && i+1 < len(s) && s[i+1] == '*':
+			i += 2
+			for i+1 < len(s) && !(s[i] == '*' && s[i+1] == '/') {
+				i++
+			}
+			i++
+		case ch == '"' || ch == '\'':
+			quote := ch
+			var str strings.Builder
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					if s[i] == '\'' {
+						str.WriteRune('\'')
+						continue
+					}
+					str.WriteRune('\\')
+				} else if s[i] == '"' {
+					str.WriteRune('\\')
+				}
+				str.WriteRune(s[i])
+			}
+			out = append(out, '"')
+			out = append(out, []rune(str.String())...)
+			out = append(out, '"')
+		case ch == '}' || ch == ']':
+			// Drop a trailing comma, possibly followed by comments.
+			j := len(out) - 1
+			for j >= 0 && unicode.IsSpace(out[j]) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = out[:j]
+			}
+			out = append(out, ch)
+		case unicode.IsLetter(ch) || ch == '_' || ch == '$':
+			j := i
+			for j < len(s) && (unicode.IsLetter(s[j]) || unicode.IsDigit(s[j]) || s[j] == '_' || s[j] == '$') {
+				j++
+			}
+			word := string(s[i:j])
+			k := j
+			for k < len(s) && unicode.IsSpace(s[k]) {
+				k++
+			}
+			if k < len(s) && s[k] == ':' {
+				word = strconv.Quote(word)
+			}
+			out = append(out, []rune(word)...)
+			i = j - 1
+		default:
+			out = append(out, ch)
+		}
+	}
+	return []byte(string(out))
+}
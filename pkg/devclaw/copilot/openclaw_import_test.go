@@ -0,0 +1,81 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImportOpenClaw(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFile := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("openclaw.json", `// JSON5, as OpenClaw writes it
+{
+  identity: { name: 'Clawd', emoji: "🦞" },
+  agents: { defaults: {
+    model: { primary: "anthropic/claude-sonnet-4-5", fallbacks: ["anthropic/claude-haiku-4-5", "openai/gpt-4o",] },
+    heartbeat: { every: "1h" }, /* every hour */
+  } },
+  channels: { telegram: { botToken: "123:abc", allowFrom: ["+15555550123"] } },
+  gateway: { port: 18789, bind: "loopback" },
+}`)
+	writeFile("workspace/SOUL.md", "# Soul")
+	writeFile("workspace/memory/2026-01-01.md", "notes")
+	writeFile("workspace/skills/weather/SKILL.md", "---\nname: weather\n---\n")
+	writeFile("workspace/skills/notaskill/README.md", "ignored")
+
+	imp, err := ImportOpenClaw(dir)
+	if err != nil {
+		t.Fatalf("ImportOpenClaw: %v", err)
+	}
+	cfg := imp.Config
+	if cfg.Name != "Clawd" || cfg.Model != "claude-sonnet-4-5" || cfg.API.Provider != "anthropic" {
+		t.Errorf("name/model/provider = %q/%q/%q", cfg.Name, cfg.Model, cfg.API.Provider)
+	}
+	if cfg.API.APIKey != "${ANTHROPIC_API_KEY}" {
+		t.Errorf("api key = %q, want env reference", cfg.API.APIKey)
+	}
+	if len(cfg.Fallback.Models) != 1 || len(cfg.API.Fallbacks) != 1 || cfg.API.Fallbacks[0].Model != "gpt-4o" {
+		t.Errorf("fallbacks = %v / %+v", cfg.Fallback.Models, cfg.API.Fallbacks)
+	}
+	if cfg.Channels.Telegram.Token != "${TELEGRAM_BOT_TOKEN}" {
+		t.Errorf("telegram token = %q, want env reference", cfg.Channels.Telegram.Token)
+	}
+	if !cfg.Heartbeat.Enabled || cfg.Heartbeat.Interval.Hours() != 1 || cfg.Gateway.Address != ":18789" {
+		t.Errorf("heartbeat/gateway = %v %v %q", cfg.Heartbeat.Enabled, cfg.Heartbeat.Interval, cfg.Gateway.Address)
+	}
+	if len(imp.Skills) != 1 || imp.Skills[0] != "weather" {
+		t.Errorf("skills = %v", imp.Skills)
+	}
+	unsupported := strings.Join(imp.Unsupported, "\n")
+	if !strings.Contains(unsupported, "gateway.bind") || !strings.Contains(unsupported, "identity.emoji") {
+		t.Errorf("unsupported options not reported:\n%s", unsupported)
+	}
+
+	out := t.TempDir()
+	if _, err := imp.CopyFiles(out, false); err != nil {
+		t.Fatalf("CopyFiles: %v", err)
+	}
+	for _, rel := range []string{"SOUL.md", "data/memory/2026-01-01.md", "skills/weather/SKILL.md"} {
+		if _, err := os.Stat(filepath.Join(out, rel)); err != nil {
+			t.Errorf("%s not copied: %v", rel, err)
+		}
+	}
+	if err := SaveConfigToFile(cfg, filepath.Join(out, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFromFile(filepath.Join(out, "config.yaml")); err != nil {
+		t.Errorf("imported config does not load: %v", err)
+	}
+}
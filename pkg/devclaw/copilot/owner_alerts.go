@@ -0,0 +1,385 @@
+// Package copilot – owner_alerts.go delivers critical notifications to the
+// owner with channel failover. Contact points are tried in order until one
+// delivery succeeds, so a WhatsApp outage doesn't swallow a security alert.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// OwnerAlertKind classifies critical owner notifications.
+type OwnerAlertKind string
+
+const (
+	OwnerAlertSecurity OwnerAlertKind = "security"
+	OwnerAlertBudget   OwnerAlertKind = "budget"
+	OwnerAlertCrash    OwnerAlertKind = "crash"
+	OwnerAlertStall    OwnerAlertKind = "stall"
+	OwnerAlertTest     OwnerAlertKind = "test"
+	OwnerAlertReport   OwnerAlertKind = "report"
+)
+
+// OwnerAlertsConfig configures critical owner notifications.
+type OwnerAlertsConfig struct {
+	// Enabled turns owner alerts on (default: true when contacts are set).
+	Enabled bool `yaml:"enabled"`
+
+	// Contacts is the ordered list of contact points. Each alert is sent to
+	// the first contact that accepts it; failures fall through to the next.
+	Contacts []OwnerContact `yaml:"contacts"`
+
+	// Events selects which alert kinds are delivered
+	// (default: security, budget, crash, stall, report).
+	Events []string `yaml:"events"`
+
+	// TimeoutSeconds bounds each delivery attempt (default: 10).
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+
+	// DedupMinutes suppresses identical alerts within this window (default: 10).
+	DedupMinutes int `yaml:"dedup_minutes"`
+}
+
+// OwnerContact is one contact point in the failover chain. Either Channel+To
+// (deliver through a connected channel) or URL (HTTP POST sink) must be set.
+type OwnerContact struct {
+	// Channel is the channel name (whatsapp, telegram, discord, slack...).
+	Channel string `yaml:"channel,omitempty"`
+
+	// To is the chat/user ID on that channel.
+	To string `yaml:"to,omitempty"`
+
+	// URL is a webhook that receives the alert as JSON.
+	URL string `yaml:"url,omitempty"`
+
+	// Headers are extra HTTP headers for webhook contacts (e.g. auth).
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Label returns a short description of the contact for logs.
+func (c OwnerContact) Label() string {
+	if c.URL != "" {
+		return "webhook:" + c.URL
+	}
+	return c.Channel + ":" + c.To
+}
+
+// DefaultOwnerAlertsConfig returns defaults for owner alerts.
+func DefaultOwnerAlertsConfig() OwnerAlertsConfig {
+	return OwnerAlertsConfig{
+		Enabled:        true,
+		Events:         []string{string(OwnerAlertSecurity), string(OwnerAlertBudget), string(OwnerAlertCrash), string(OwnerAlertStall), string(OwnerAlertReport)},
+		TimeoutSeconds: 10,
+		DedupMinutes:   10,
+	}
+}
+
+// OwnerAlert is a single critical notification.
+type OwnerAlert struct {
+	Kind  OwnerAlertKind `json:"kind"`
+	Title string         `json:"title"`
+	Body  string         `json:"body"`
+	Time  time.Time      `json:"time"`
+}
+
+// ownerSender delivers a message to a channel contact.
+type ownerSender func(ctx context.Context, channel, to, content string) error
+
+// OwnerAlerter sends owner alerts down the configured contact chain.
+type OwnerAlerter struct {
+	cfg    OwnerAlertsConfig
+	send   ownerSender
+	client *http.Client
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	recent map[string]time.Time
+}
+
+// NewOwnerAlerter creates an alerter that delivers channel contacts through
+// the channel manager.
+func NewOwnerAlerter(cfg OwnerAlertsConfig, mgr *channels.Manager, logger *slog.Logger) *OwnerAlerter {
+	send := func(ctx context.Context, channel, to, content string) error {
+		return mgr.Send(ctx, channel, to, &channels.OutgoingMessage{Content: content})
+	}
+	return newOwnerAlerter(cfg, send, logger)
+}
+
+func newOwnerAlerter(cfg OwnerAlertsConfig, send ownerSender, logger *slog.Logger) *OwnerAlerter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	if len(cfg.Events) == 0 {
+		cfg.Events = DefaultOwnerAlertsConfig().Events
+	}
+	return &OwnerAlerter{
+		cfg:    cfg,
+		send:   send,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+		logger: logger.With("component", "owner-alerts"),
+		recent: make(map[string]time.Time),
+	}
+}
+
+// Enabled reports whether alerts will be delivered anywhere.
+func (o *OwnerAlerter) Enabled() bool {
+	return o != nil && o.cfg.Enabled && len(o.cfg.Contacts) > 0
+}
+
+// Contacts returns the configured contact chain.
+func (o *OwnerAlerter) Contacts() []OwnerContact {
+	if o == nil {
+		return nil
+	}
+	return o.cfg.Contacts
+}
+
+func (o *OwnerAlerter) wants(kind OwnerAlertKind) bool {
+	if kind == OwnerAlertTest {
+		return true
+	}
+	for _, e := range o.cfg.Events {
+		if strings.EqualFold(e, string(kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// duplicate records the alert and reports whether an identical one was sent
+// within the dedup window.
+func (o *OwnerAlerter) duplicate(alert OwnerAlert) bool {
+	window := time.Duration(o.cfg.DedupMinutes) * time.Minute
+	if window <= 0 || alert.Kind == OwnerAlertTest {
+		return false
+	}
+	key := string(alert.Kind) + "|" + alert.Title
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if last, ok := o.recent[key]; ok && alert.Time.Sub(last) < window {
+		return true
+	}
+	o.recent[key] = alert.Time
+	return false
+}
+
+// Send delivers the alert to the first contact that accepts it and returns
+// the contact used. It returns an error only when every contact failed.
+func (o *OwnerAlerter) Send(ctx context.Context, alert OwnerAlert) (OwnerContact, error) {
+	if !o.Enabled() || !o.wants(alert.Kind) {
+		return OwnerContact{}, nil
+	}
+	if alert.Time.IsZero() {
+		alert.Time = time.Now()
+	}
+	if o.duplicate(alert) {
+		return OwnerContact{}, nil
+	}
+
+	var errs []string
+	for _, c := range o.cfg.Contacts {
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Duration(o.cfg.TimeoutSeconds)*time.Second)
+		err := o.deliver(attemptCtx, c, alert)
+		cancel()
+		if err == nil {
+			if len(errs) > 0 {
+				o.logger.Warn("owner alert delivered after failover",
+					"kind", alert.Kind, "contact", c.Label(), "failed", len(errs))
+			}
+			return c, nil
+		}
+		o.logger.Warn("owner alert delivery failed",
+			"kind", alert.Kind, "contact", c.Label(), "error", err)
+		errs = append(errs, fmt.Sprintf("%s: %v", c.Label(), err))
+	}
+
+	o.logger.Error("owner alert undeliverable",
+		"kind", alert.Kind, "title", alert.Title, "attempts", len(errs))
+	return OwnerContact{}, fmt.Errorf("all %d owner contacts failed: %s", len(errs), strings.Join(errs, "; "))
+}
+
+// SendAsync delivers the alert in the background.
+func (o *OwnerAlerter) SendAsync(alert OwnerAlert) {
+	if !o.Enabled() {
+		return
+	}
+	go func() {
+		_, _ = o.Send(context.Background(), alert)
+	}()
+}
+
+func (o *OwnerAlerter) deliver(ctx context.Context, c OwnerContact, alert OwnerAlert) error {
+	if c.URL != "" {
+		return o.deliverWebhook(ctx, c, alert)
+	}
+	if c.Channel == "" || c.To == "" {
+		return fmt.Errorf("contact needs channel and to, or url")
+	}
+	return o.send(ctx, c.Channel, c.To, formatOwnerAlert(alert))
+}
+
+func (o *OwnerAlerter) deliverWebhook(ctx context.Context, c OwnerContact, alert OwnerAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func formatOwnerAlert(alert OwnerAlert) string {
+	icon := "⚠️"
+	switch alert.Kind {
+	case OwnerAlertSecurity:
+		icon = "🛡️"
+	case OwnerAlertBudget:
+		icon = "💸"
+	case OwnerAlertCrash:
+		icon = "💥"
+	case OwnerAlertStall:
+		icon = "⏱️"
+	case OwnerAlertTest:
+		icon = "🔔"
+	case OwnerAlertReport:
+		icon = "🧾"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s *%s*", icon, alert.Title)
+	if alert.Body != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(alert.Body)
+	}
+	fmt.Fprintf(&sb, "\n\n_%s · %s_", alert.Kind, alert.Time.Format("2006-01-02 15:04:05"))
+	return sb.String()
+}
+
+// AlertOwner sends a critical notification to the owner in the background,
+// failing over across the configured contact points.
+func (a *Assistant) AlertOwner(kind OwnerAlertKind, title, body string) {
+	a.ownerAlerter.SendAsync(OwnerAlert{Kind: kind, Title: title, Body: body})
+}
+
+// recoverCrash reports a panic in a message-handling goroutine: it logs the
+// stack, alerts the owner and then re-panics, so the crash is not hidden.
+func (a *Assistant) recoverCrash(where, sessionID string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	a.logger.Error("panic in "+where, "session", sessionID, "panic", r, "stack", string(stack))
+
+	trace := string(stack)
+	if len(trace) > 1500 {
+		trace = trace[:1500] + "\n..."
+	}
+	// Deliver synchronously: the process exits once the panic resumes.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	_, _ = a.ownerAlerter.Send(ctx, OwnerAlert{
+		Kind:  OwnerAlertCrash,
+		Title: fmt.Sprintf("Crash in %s", where),
+		Body:  fmt.Sprintf("Session: %s\nPanic: %v\n\n%s", sessionID, r, trace),
+	})
+	cancel()
+	panic(r)
+}
+
+// checkBudgetAlert alerts the owner when this calendar month's estimated
+// spend crosses the budget warning threshold or the limit itself. Each
+// threshold is reported once per month.
+func (a *Assistant) checkBudgetAlert() {
+	month, level, spent := a.budgetThreshold()
+	if level == 0 || !a.claimBudgetAlert(month, level) {
+		return
+	}
+
+	limit := a.config.Budget.MonthlyLimitUSD
+	title := fmt.Sprintf("Budget %d%% reached", level)
+	if level >= 100 {
+		title = "Budget limit reached"
+	}
+	a.AlertOwner(OwnerAlertBudget, title,
+		fmt.Sprintf("Estimated spend in %s: $%.2f of $%.2f (%d%%).\nAction at limit: %s",
+			month, spent, limit, int(spent/limit*100), a.config.Budget.ActionAtLimit))
+}
+
+// primeBudgetAlerts marks the threshold already crossed by the spend
+// replayed from the usage log as reported, so a restart does not repeat an
+// alert the owner got before it.
+func (a *Assistant) primeBudgetAlerts() {
+	if month, level, _ := a.budgetThreshold(); level > 0 {
+		a.claimBudgetAlert(month, level)
+	}
+}
+
+// budgetThreshold returns the current month, the budget threshold its spend
+// has crossed (the warning percentage, 100, or 0 for none) and the spend.
+func (a *Assistant) budgetThreshold() (month string, level int, spent float64) {
+	limit := a.config.Budget.MonthlyLimitUSD
+	if limit <= 0 || a.usageTracker == nil {
+		return "", 0, 0
+	}
+	month, spent = a.usageTracker.MonthSpendUSD()
+	pct := int(spent / limit * 100)
+
+	warnAt := a.config.Budget.WarnAtPercent
+	if warnAt <= 0 {
+		warnAt = 80
+	}
+	switch {
+	case pct >= 100:
+		level = 100
+	case pct >= warnAt:
+		level = warnAt
+	}
+	return month, level, spent
+}
+
+// claimBudgetAlert records that a threshold was reported for a month and
+// reports whether it was new. Entries for earlier months are dropped.
+func (a *Assistant) claimBudgetAlert(month string, level int) bool {
+	a.budgetAlertMu.Lock()
+	defer a.budgetAlertMu.Unlock()
+	key := fmt.Sprintf("%s:%d", month, level)
+	if a.budgetAlerted[key] {
+		return false
+	}
+	if a.budgetAlerted == nil {
+		a.budgetAlerted = make(map[string]bool)
+	}
+	for k := range a.budgetAlerted {
+		if !strings.HasPrefix(k, month+":") {
+			delete(a.budgetAlerted, k)
+		}
+	}
+	a.budgetAlerted[key] = true
+	return true
+}
@@ -0,0 +1,224 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSender records channel deliveries and fails for the listed channels.
+type recordingSender struct {
+	mu   sync.Mutex
+	fail map[string]bool
+	sent []string
+}
+
+func (r *recordingSender) send(_ context.Context, channel, to, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail[channel] {
+		return errors.New(channel + " is down")
+	}
+	r.sent = append(r.sent, channel+":"+to+"|"+content)
+	return nil
+}
+
+func testOwnerAlerter(cfg OwnerAlertsConfig, send ownerSender) *OwnerAlerter {
+	return newOwnerAlerter(cfg, send, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestOwnerAlerter_Failover(t *testing.T) {
+	t.Parallel()
+	type delivery struct {
+		alert OwnerAlert
+		auth  string
+	}
+	got := make(chan delivery, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d delivery
+		d.auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&d.alert)
+		got <- d
+	}))
+	defer srv.Close()
+
+	rec := &recordingSender{fail: map[string]bool{"whatsapp": true}}
+	o := testOwnerAlerter(OwnerAlertsConfig{
+		Enabled: true,
+		Contacts: []OwnerContact{
+			{Channel: "whatsapp", To: "5511"},
+			{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}},
+			{Channel: "telegram", To: "42"},
+		},
+	}, rec.send)
+
+	used, err := o.Send(context.Background(), OwnerAlert{Kind: OwnerAlertSecurity, Title: "Tool bash blocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used.URL != srv.URL {
+		t.Errorf("delivered via %s, want the webhook after the WhatsApp failure", used.Label())
+	}
+	if d := <-got; d.alert.Title != "Tool bash blocked" || d.alert.Kind != OwnerAlertSecurity || d.auth != "Bearer t" {
+		t.Errorf("webhook got %+v, auth %q", d.alert, d.auth)
+	}
+	if len(rec.sent) != 0 {
+		t.Errorf("later contacts were tried after a delivery: %v", rec.sent)
+	}
+}
+
+func TestOwnerAlerter_AllContactsFail(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	rec := &recordingSender{fail: map[string]bool{"whatsapp": true}}
+	o := testOwnerAlerter(OwnerAlertsConfig{
+		Enabled:  true,
+		Contacts: []OwnerContact{{Channel: "whatsapp", To: "5511"}, {URL: srv.URL}, {Channel: "slack"}},
+	}, rec.send)
+
+	_, err := o.Send(context.Background(), OwnerAlert{Kind: OwnerAlertBudget, Title: "Budget limit reached"})
+	if err == nil {
+		t.Fatal("expected an error when every contact fails")
+	}
+	for _, want := range []string{"all 3 owner contacts failed", "whatsapp is down", "502", "contact needs channel and to"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q lacks %q", err, want)
+		}
+	}
+}
+
+func TestOwnerAlerter_Filtering(t *testing.T) {
+	t.Parallel()
+	rec := &recordingSender{}
+	o := testOwnerAlerter(OwnerAlertsConfig{
+		Enabled:      true,
+		Contacts:     []OwnerContact{{Channel: "telegram", To: "42"}},
+		Events:       []string{"security"},
+		DedupMinutes: 10,
+	}, rec.send)
+
+	now := time.Now()
+	alerts := []OwnerAlert{
+		{Kind: OwnerAlertSecurity, Title: "Tool bash blocked", Time: now},
+		{Kind: OwnerAlertSecurity, Title: "Tool bash blocked", Time: now.Add(time.Minute)},      // duplicate
+		{Kind: OwnerAlertBudget, Title: "Budget 80% reached", Time: now},                        // not selected
+		{Kind: OwnerAlertSecurity, Title: "Tool bash blocked", Time: now.Add(11 * time.Minute)}, // window passed
+		{Kind: OwnerAlertTest, Title: "Test", Time: now},                                        // always sent
+		{Kind: OwnerAlertTest, Title: "Test", Time: now},                                        // never deduped
+	}
+	for _, a := range alerts {
+		if _, err := o.Send(context.Background(), a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rec.sent) != 4 {
+		t.Fatalf("sent %d alerts, want 4: %v", len(rec.sent), rec.sent)
+	}
+	if !strings.HasPrefix(rec.sent[0], "telegram:42|🛡️ *Tool bash blocked*") {
+		t.Errorf("formatted alert = %q", rec.sent[0])
+	}
+
+	if (&OwnerAlerter{}).Enabled() || (*OwnerAlerter)(nil).Enabled() {
+		t.Error("an alerter without contacts should be disabled")
+	}
+}
+
+func TestRecoverCrash_AlertsAndRepanics(t *testing.T) {
+	t.Parallel()
+	rec := &recordingSender{}
+	a := &Assistant{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ownerAlerter: testOwnerAlerter(OwnerAlertsConfig{
+			Enabled:  true,
+			Contacts: []OwnerContact{{Channel: "telegram", To: "42"}},
+		}, rec.send),
+	}
+
+	recovered := func() (r any) {
+		defer func() { r = recover() }()
+		defer a.recoverCrash("handleMessage", "telegram:42")
+		panic("nil map write")
+	}()
+
+	if recovered != "nil map write" {
+		t.Errorf("panic not propagated: %v", recovered)
+	}
+	if len(rec.sent) != 1 || !strings.Contains(rec.sent[0], "Crash in handleMessage") || !strings.Contains(rec.sent[0], "Panic: nil map write") {
+		t.Errorf("crash alert = %v", rec.sent)
+	}
+}
+
+func TestCheckBudgetAlert_MonthToDate(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 9, 30, 22, 0, 0, 0, time.UTC)
+	u := NewUsageTracker(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.now = func() time.Time { return now }
+	// August's spend is in the totals but not in September's budget.
+	u.Restore([]UsageRecord{
+		{Time: now.AddDate(0, -1, 0), CostUSD: 50},
+		{Time: now.Add(-time.Hour), CostUSD: 8.5},
+	})
+
+	rec := &recordingSender{}
+	a := &Assistant{
+		config:       DefaultConfig(),
+		usageTracker: u,
+		ownerAlerter: testOwnerAlerter(OwnerAlertsConfig{Enabled: true, Contacts: []OwnerContact{{Channel: "telegram", To: "42"}}}, rec.send),
+	}
+	a.config.Budget.MonthlyLimitUSD = 10
+	waitSent := func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			rec.mu.Lock()
+			sent := append([]string(nil), rec.sent...)
+			rec.mu.Unlock()
+			if len(sent) >= n || time.Now().After(deadline) {
+				return sent
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	spend := func(usd float64) {
+		// glm-5 input costs $1 per 1M tokens.
+		u.RecordFor("s1", "", "", "glm-5", LLMUsage{PromptTokens: int(usd * 1e6), TotalTokens: int(usd * 1e6)})
+		a.checkBudgetAlert()
+	}
+
+	// The 85% replayed at startup was reported before the restart.
+	a.primeBudgetAlerts()
+	a.checkBudgetAlert()
+	spend(1) // 95%
+	if sent := waitSent(1); len(sent) != 0 {
+		t.Fatalf("alerts before the limit: %v", sent)
+	}
+	spend(1) // 105%
+	spend(1)
+	if sent := waitSent(2); len(sent) != 1 || !strings.Contains(sent[0], "Budget limit reached") || !strings.Contains(sent[0], "2026-09") {
+		t.Fatalf("at the limit: %v", sent)
+	}
+
+	// A new month starts from zero and warns again.
+	now = now.Add(3 * time.Hour)
+	a.checkBudgetAlert()
+	spend(8)
+	sent := waitSent(2)
+	if len(sent) != 2 || !strings.Contains(sent[1], "Budget 80% reached") || !strings.Contains(sent[1], "$8.00 of $10.00 (80%)") {
+		t.Fatalf("next month: %v", sent)
+	}
+	if len(a.budgetAlerted) != 1 || !a.budgetAlerted["2026-10:80"] {
+		t.Errorf("budgetAlerted = %v", a.budgetAlerted)
+	}
+}
@@ -0,0 +1,57 @@
+// Package copilot – paths.go normalizes the file paths the tools receive
+// so the same checks hold on every platform: ~ and %VAR% are expanded,
+// separators are cleaned, and on Windows paths compare case-insensitively
+// (C:\Users\Me and c:/users/me are the same file).
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// windowsEnvRef matches %VAR% references in Windows paths.
+var windowsEnvRef = regexp.MustCompile(`%([A-Za-z_][A-Za-z0-9_()]*)%`)
+
+// expandPath expands a leading ~ (~/ or ~\) to the home directory and, on
+// Windows, %VAR% references such as %APPDATA%. Unknown variables are kept.
+func expandPath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, p[1:])
+		}
+	}
+	if runtime.GOOS == "windows" && strings.Contains(p, "%") {
+		p = windowsEnvRef.ReplaceAllStringFunc(p, func(ref string) string {
+			if v, ok := os.LookupEnv(ref[1 : len(ref)-1]); ok {
+				return v
+			}
+			return ref
+		})
+	}
+	return p
+}
+
+// pathKey returns the form of a cleaned path used for comparisons:
+// lower-cased on Windows, whose file systems are case-insensitive.
+func pathKey(p string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(p)
+	}
+	return p
+}
+
+// pathWithin reports whether path is root or lies under it. Both must be
+// absolute and cleaned.
+func pathWithin(path, root string) bool {
+	path, root = pathKey(path), pathKey(root)
+	if path == root {
+		return true
+	}
+	if !strings.HasSuffix(root, string(os.PathSeparator)) {
+		root += string(os.PathSeparator)
+	}
+	return strings.HasPrefix(path, root)
+}
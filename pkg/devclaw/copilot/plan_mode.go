@@ -0,0 +1,183 @@
+// Package copilot – plan_mode.go implements /plan, a dry run of the agent.
+// "/plan <task>" runs the agent with an execution policy that intercepts
+// every tool call: nothing runs, each call is recorded as an intended step,
+// and the reply is a step-by-step plan followed by the tools the agent would
+// call. The plan stays pending on the session: "go" (or another approval
+// word) executes it, any other message revises it, and /plan cancel drops
+// it. Pending plans expire after planTTL.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// planTTL is how long a proposed plan waits for "go".
+const planTTL = time.Hour
+
+// maxPlanToolTurns is how many turns of tool calls a planning run may
+// record before the agent is told to stop calling tools.
+const maxPlanToolTurns = 3
+
+// ExecutionPolicy decides whether the tool calls of a turn run. Intercept
+// returns the results to feed back to the model instead, with ok=true, or
+// ok=false to run the calls.
+type ExecutionPolicy interface {
+	Intercept(ctx context.Context, calls []ToolCall) (results []ToolResult, ok bool)
+}
+
+// planPolicy records tool calls instead of running them.
+type planPolicy struct {
+	mu    sync.Mutex
+	calls []ToolCall
+	turns int
+}
+
+// Intercept records calls as planned steps and tells the model they did
+// not run.
+func (p *planPolicy) Intercept(_ context.Context, calls []ToolCall) ([]ToolResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, calls...)
+	p.turns++
+
+	note := "Not executed: plan mode. The call is recorded as a planned step. Assume it succeeds and continue the plan."
+	if p.turns >= maxPlanToolTurns {
+		note = "Not executed: plan mode. Stop calling tools now and write the step-by-step plan."
+	}
+	results := make([]ToolResult, len(calls))
+	for i, tc := range calls {
+		results[i] = ToolResult{ToolCallID: tc.ID, Name: tc.Function.Name, Content: note, Blocked: "plan mode"}
+	}
+	return results, true
+}
+
+// Calls returns the recorded tool calls.
+func (p *planPolicy) Calls() []ToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ToolCall(nil), p.calls...)
+}
+
+// SetExecutionPolicy sets the policy consulted before each turn's tool calls
+// run (nil = run them).
+func (a *AgentRun) SetExecutionPolicy(p ExecutionPolicy) {
+	a.policy = p
+}
+
+// ctxKeyExecutionPolicy is the context key for a run's execution policy.
+type ctxKeyExecutionPolicy struct{}
+
+// ContextWithExecutionPolicy returns a context whose agent run uses p.
+func ContextWithExecutionPolicy(ctx context.Context, p ExecutionPolicy) context.Context {
+	return context.WithValue(ctx, ctxKeyExecutionPolicy{}, p)
+}
+
+// ExecutionPolicyFromContext returns the execution policy of the run, if any.
+func ExecutionPolicyFromContext(ctx context.Context) ExecutionPolicy {
+	p, _ := ctx.Value(ctxKeyExecutionPolicy{}).(ExecutionPolicy)
+	return p
+}
+
+// PendingPlan is a plan waiting for the user's "go".
+type PendingPlan struct {
+	// Request is the task the plan is for.
+	Request string
+
+	// Plan is the agent's plan, with the tools it intends to call.
+	Plan string
+
+	CreatedAt time.Time
+}
+
+// PendingPlan returns the session's unexpired plan, if any.
+func (s *Session) PendingPlan() *PendingPlan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pendingPlan != nil && time.Since(s.pendingPlan.CreatedAt) > planTTL {
+		s.pendingPlan = nil
+	}
+	return s.pendingPlan
+}
+
+// SetPendingPlan sets or (with nil) clears the session's pending plan.
+func (s *Session) SetPendingPlan(p *PendingPlan) {
+	s.mu.Lock()
+	s.pendingPlan = p
+	s.mu.Unlock()
+}
+
+// planCommandTask returns the task of a "/plan <task>" message. Bare /plan
+// and its subcommands are chat commands and return ok=false.
+func planCommandTask(content string) (task string, ok bool) {
+	content = strings.TrimSpace(content)
+	rest, found := strings.CutPrefix(content, "/plan")
+	if !found || (rest != "" && rest[0] != ' ' && rest[0] != '\n') {
+		return "", false
+	}
+	task = strings.TrimSpace(rest)
+	switch strings.ToLower(task) {
+	case "", "show", "cancel":
+		return "", false
+	}
+	return task, true
+}
+
+// planPrompt is the agent input of a planning run. With a previous plan the
+// user's message is feedback on it.
+func planPrompt(request, previous, feedback string) string {
+	var b strings.Builder
+	b.WriteString("[PLAN MODE] Do not execute anything: tool calls are recorded, not run. ")
+	b.WriteString("Reply with a numbered, step-by-step plan for the request below. For each step name the tool you would call and its key arguments, ")
+	b.WriteString("and point out risky or irreversible steps. End without asking questions unless the request is ambiguous.\n\n")
+	b.WriteString("Request: " + request)
+	if previous != "" {
+		b.WriteString("\n\nYour previous plan:\n" + previous)
+		b.WriteString("\n\nRevise it according to the user's feedback: " + feedback)
+	}
+	return b.String()
+}
+
+// executePlanPrompt is the agent input that carries out an approved plan.
+func executePlanPrompt(p *PendingPlan) string {
+	return "The user approved your plan. Execute it now, step by step, and report the outcome.\n\n" +
+		"Request: " + p.Request + "\n\nApproved plan:\n" + p.Plan
+}
+
+// formatPlannedCalls lists the tool calls a planning run intended to make.
+func formatPlannedCalls(calls []ToolCall) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("*Tools it would call:*\n")
+	for i, tc := range calls {
+		args := strings.Join(strings.Fields(tc.Function.Arguments), " ")
+		b.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, tc.Function.Name, truncate(args, 160)))
+	}
+	return b.String()
+}
+
+// planCommand handles bare /plan (show the pending plan) and /plan cancel.
+func (a *Assistant) planCommand(args []string, msg *channels.IncomingMessage) string {
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	session := resolved.Session
+	plan := session.PendingPlan()
+	if len(args) > 0 && strings.EqualFold(args[0], "cancel") {
+		if plan == nil {
+			return "No pending plan."
+		}
+		session.SetPendingPlan(nil)
+		return "Plan dropped."
+	}
+	if plan == nil {
+		return "No pending plan. Use /plan <task> to get a plan before anything runs."
+	}
+	return fmt.Sprintf("*Pending plan* (%s ago) for: %s\n\n%s\n\nReply *go* to execute it, send changes to revise it, or /plan cancel.",
+		formatGrantDuration(time.Since(plan.CreatedAt)), truncate(plan.Request, 200), plan.Plan)
+}
@@ -0,0 +1,88 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAgentRun_PlanPolicyInterceptsToolCalls(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			io.WriteString(w, `{"choices":[{"message":{"content":"","tool_calls":[{"id":"c1","type":"function","function":{"name":"bash","arguments":"{\"command\":\"rm -rf build\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+			return
+		}
+		if !strings.Contains(string(body), "Not executed: plan mode") {
+			t.Errorf("the model should be told the call did not run, got %s", body)
+		}
+		io.WriteString(w, `{"choices":[{"message":{"content":"1. Delete the build directory (bash)"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DefaultConfig()
+	cfg.Model = "gpt-4o"
+	cfg.API.BaseURL = srv.URL
+	cfg.API.APIKey = "key"
+
+	var ran atomic.Int32
+	exec := NewToolExecutor(logger)
+	exec.Register(MakeToolDefinition("bash", "Run a command", map[string]any{"type": "object"}),
+		func(context.Context, map[string]any) (any, error) { ran.Add(1); return "done", nil })
+
+	agent := NewAgentRun(NewLLMClient(cfg, logger), exec, logger)
+	policy := &planPolicy{}
+	agent.SetExecutionPolicy(policy)
+	reply, _, err := agent.RunWithUsage(context.Background(), "system", nil, planPrompt("clean the build", "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran.Load() != 0 {
+		t.Fatal("a tool ran in plan mode")
+	}
+	if !strings.Contains(reply, "Delete the build directory") {
+		t.Errorf("unexpected plan %q", reply)
+	}
+	planned := policy.Calls()
+	if len(planned) != 1 || planned[0].Function.Name != "bash" {
+		t.Fatalf("planned calls = %+v", planned)
+	}
+	if list := formatPlannedCalls(planned); !strings.Contains(list, `1. bash {"command":"rm -rf build"}`) {
+		t.Errorf("unexpected tool list %q", list)
+	}
+}
+
+func TestPlanCommandTaskAndPendingPlan(t *testing.T) {
+	t.Parallel()
+
+	for content, want := range map[string]string{
+		"/plan deploy the site": "deploy the site",
+		"/plan":                 "",
+		"/plan cancel":          "",
+		"/planner x":            "",
+		"plan the trip":         "",
+	} {
+		if got, _ := planCommandTask(content); got != want {
+			t.Errorf("planCommandTask(%q) = %q, want %q", content, got, want)
+		}
+	}
+
+	s := &Session{}
+	s.SetPendingPlan(&PendingPlan{Request: "deploy", Plan: "1. build", CreatedAt: time.Now()})
+	if p := s.PendingPlan(); p == nil || p.Plan != "1. build" {
+		t.Fatalf("pending plan = %+v", p)
+	}
+	s.SetPendingPlan(&PendingPlan{Request: "deploy", CreatedAt: time.Now().Add(-planTTL - time.Minute)})
+	if s.PendingPlan() != nil {
+		t.Error("an expired plan should not be pending")
+	}
+}
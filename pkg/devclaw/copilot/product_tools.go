@@ -33,20 +33,26 @@ type burndownPoint struct {
 }
 
 type doraMetrics struct {
-	DeployFrequency     string  `json:"deploy_frequency"`
-	LeadTimeForChanges  string  `json:"lead_time_for_changes"`
-	ChangeFailureRate   string  `json:"change_failure_rate"`
-	TimeToRestore       string  `json:"time_to_restore"`
-	DeploysInPeriod     int     `json:"deploys_in_period"`
-	PeriodDays          int     `json:"period_days"`
-	AvgLeadTimeHours    float64 `json:"avg_lead_time_hours"`
-	FailureRatePercent  float64 `json:"failure_rate_percent"`
+	Source             string  `json:"source"`
+	DeployFrequency    string  `json:"deploy_frequency"`
+	LeadTimeForChanges string  `json:"lead_time_for_changes"`
+	ChangeFailureRate  string  `json:"change_failure_rate"`
+	TimeToRestore      string  `json:"time_to_restore"`
+	DeploysInPeriod    int     `json:"deploys_in_period"`
+	FailedDeploys      int     `json:"failed_deploys,omitempty"`
+	Incidents          int     `json:"incidents,omitempty"`
+	PeriodDays         int     `json:"period_days"`
+	AvgLeadTimeHours   float64 `json:"avg_lead_time_hours"`
+	FailureRatePercent float64 `json:"failure_rate_percent"`
+	AvgRestoreHours    float64 `json:"avg_restore_hours,omitempty"`
+	Fallback           string  `json:"fallback,omitempty"`
 }
 
 // ---------- Tool Registration ----------
 
-// RegisterProductTools registers product management tools.
-func RegisterProductTools(executor *ToolExecutor) {
+// RegisterProductTools registers product management tools. dora reads
+// deployments and incidents from a CI/CD provider when configured.
+func RegisterProductTools(executor *ToolExecutor, dora DORAConfig) {
 	// sprint_report
 	executor.Register(ToolDefinition{
 		Type: "function",
@@ -101,72 +107,55 @@ func RegisterProductTools(executor *ToolExecutor) {
 		Type: "function",
 		Function: FunctionDef{
 			Name:        "dora_metrics",
-			Description: "Calculate DORA metrics from Git history: deployment frequency, lead time for changes, change failure rate (requires git tags for deploys).",
+			Description: "Calculate DORA metrics: deployment frequency, lead time for changes, change failure rate and time to restore. Uses GitHub/GitLab deployments and incident-labeled issues when a CI provider is configured, otherwise git tags (deploys) and hotfix tag names (failures).",
 			Parameters: mustJSON(map[string]any{
 				"type": "object",
 				"properties": map[string]any{
 					"days":       map[string]any{"type": "integer", "description": "Period in days to analyze (default: 30)"},
-					"deploy_tag": map[string]any{"type": "string", "description": "Tag pattern for deploys (default: 'v*')"},
+					"source":     map[string]any{"type": "string", "enum": []string{"auto", "github", "gitlab", "git"}, "description": "Data source (default: auto = configured CI provider, else git tags)"},
+					"deploy_tag": map[string]any{"type": "string", "description": "Tag pattern for deploys with the git source (default: 'v*')"},
 				},
 			}),
 		},
-	}, func(_ context.Context, args map[string]any) (any, error) {
+	}, func(ctx context.Context, args map[string]any) (any, error) {
 		days := 30
-		if v, ok := args["days"].(float64); ok {
+		if v, ok := args["days"].(float64); ok && v > 0 {
 			days = int(v)
 		}
 		deployTag := "v*"
 		if v, ok := args["deploy_tag"].(string); ok && v != "" {
 			deployTag = v
 		}
-
-		since := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
-
-		// Deployment frequency: count tags in period
-		deploysCount := countTagsInPeriod(deployTag, since)
-
-		// Lead time: average time from first commit to tag
-		avgLeadTime := calculateAvgLeadTime(deployTag, days)
-
-		// Change failure rate: tags with "hotfix" or "fix" in name / total tags
-		failureRate := calculateFailureRate(deployTag, since)
-
-		// Deploy frequency category
-		var freqCategory string
-		deploysPerDay := float64(deploysCount) / float64(days)
-		switch {
-		case deploysPerDay >= 1:
-			freqCategory = "On-demand (multiple per day)"
-		case deploysPerDay >= 1.0/7:
-			freqCategory = "Weekly"
-		case deploysPerDay >= 1.0/30:
-			freqCategory = "Monthly"
-		default:
-			freqCategory = "Less than monthly"
+		source, _ := args["source"].(string)
+		provider := dora.Provider
+		switch source {
+		case "github", "gitlab":
+			provider = source
+		case "git":
+			provider = ""
 		}
 
-		// Lead time category
-		var leadCategory string
-		switch {
-		case avgLeadTime < 24:
-			leadCategory = "Less than one day"
-		case avgLeadTime < 168:
-			leadCategory = "Less than one week"
-		case avgLeadTime < 720:
-			leadCategory = "Less than one month"
-		default:
-			leadCategory = "More than one month"
+		var metrics doraMetrics
+		fallback := ""
+		if provider != "" {
+			src, name, err := newDORASource(dora, provider)
+			if err == nil {
+				var data *doraPipelineData
+				if data, err = collectDORA(ctx, src, time.Now().AddDate(0, 0, -days)); err == nil {
+					if data.Deploys+data.FailedDeploys > 0 {
+						metrics = pipelineDORAMetrics(data, days, name)
+					} else {
+						err = fmt.Errorf("no deployments to %q in %s", firstNonEmpty(dora.Environment, "production"), name)
+					}
+				}
+			}
+			if err != nil {
+				fallback = err.Error()
+			}
 		}
-
-		metrics := doraMetrics{
-			DeployFrequency:    freqCategory,
-			LeadTimeForChanges: leadCategory,
-			ChangeFailureRate:  fmt.Sprintf("%.1f%%", failureRate*100),
-			TimeToRestore:      "N/A (requires incident data)",
-			DeploysInPeriod:    deploysCount,
-			PeriodDays:         days,
-			AvgLeadTimeHours:   math.Round(avgLeadTime*10) / 10,
-			FailureRatePercent: math.Round(failureRate*1000) / 10,
+		if metrics.Source == "" {
+			metrics = gitTagDORAMetrics(deployTag, days)
+			metrics.Fallback = fallback
 		}
 
 		data, _ := json.MarshalIndent(metrics, "", "  ")
@@ -246,6 +235,62 @@ func RegisterProductTools(executor *ToolExecutor) {
 	})
 }
 
+// ---------- DORA ----------
+
+// gitTagDORAMetrics estimates DORA metrics from deploy tags: each tag is a
+// deployment and tags named hotfix/fix/patch are failures.
+func gitTagDORAMetrics(deployTag string, days int) doraMetrics {
+	since := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+
+	// Deployment frequency: count tags in period
+	deploysCount := countTagsInPeriod(deployTag, since)
+
+	// Lead time: average time from first commit to tag
+	avgLeadTime := calculateAvgLeadTime(deployTag, days)
+
+	// Change failure rate: tags with "hotfix" or "fix" in name / total tags
+	failureRate := calculateFailureRate(deployTag, since)
+
+	return doraMetrics{
+		Source:             "git tags " + deployTag,
+		DeployFrequency:    doraFrequencyCategory(deploysCount, days),
+		LeadTimeForChanges: doraLeadCategory(avgLeadTime),
+		ChangeFailureRate:  fmt.Sprintf("%.1f%%", failureRate*100),
+		TimeToRestore:      "N/A (requires incident data)",
+		DeploysInPeriod:    deploysCount,
+		PeriodDays:         days,
+		AvgLeadTimeHours:   math.Round(avgLeadTime*10) / 10,
+		FailureRatePercent: math.Round(failureRate*1000) / 10,
+	}
+}
+
+func doraFrequencyCategory(deploys, days int) string {
+	deploysPerDay := float64(deploys) / float64(days)
+	switch {
+	case deploysPerDay >= 1:
+		return "On-demand (multiple per day)"
+	case deploysPerDay >= 1.0/7:
+		return "Weekly"
+	case deploysPerDay >= 1.0/30:
+		return "Monthly"
+	default:
+		return "Less than monthly"
+	}
+}
+
+func doraLeadCategory(hours float64) string {
+	switch {
+	case hours < 24:
+		return "Less than one day"
+	case hours < 168:
+		return "Less than one week"
+	case hours < 720:
+		return "Less than one month"
+	default:
+		return "More than one month"
+	}
+}
+
 // ---------- Git Helpers ----------
 
 func gitCountCommits(since, until string) (int, error) {
@@ -0,0 +1,130 @@
+// Package copilot – prompt_cache.go lets providers reuse the stable prefix
+// of the system prompt across calls (prompt caching).
+//
+// Prompt layers are assembled in priority order, which is also the order of
+// volatility: core and safety are the same for every session; identity,
+// bootstrap files, business context and skills change per workspace or
+// session; memory, time, history and runtime change on every turn.
+// joinPromptLayers puts a break between those tiers. The LLM client turns
+// the breaks into cache breakpoints for providers that need explicit
+// markers (Anthropic cache_control) and removes them for the others —
+// OpenAI-compatible APIs cache a repeated prefix automatically.
+//
+// Cache reads and writes reported by the provider land in LLMUsage and are
+// accumulated by the UsageTracker with the money they saved.
+package copilot
+
+import "strings"
+
+// promptCacheBreak separates cache tiers in a composed system prompt. It
+// never reaches a provider: requests either split on it or replace it.
+const promptCacheBreak = "\n\n<!-- prompt-cache-break -->\n\n"
+
+// maxSystemCacheBreakpoints caps the breakpoints placed in the system prompt
+// (Anthropic allows 4 per request; one is kept for the conversation).
+const maxSystemCacheBreakpoints = 3
+
+// promptCacheTier groups layers that change at the same rate.
+func promptCacheTier(l PromptLayer) int {
+	switch {
+	case l <= LayerSafety:
+		return 0 // identical for every session
+	case l <= LayerSkills:
+		return 1 // per workspace / session
+	default:
+		return 2 // per turn
+	}
+}
+
+// joinPromptLayers joins sorted layers, separating cache tiers with
+// promptCacheBreak.
+func joinPromptLayers(layers []layerEntry) string {
+	var b strings.Builder
+	for i, l := range layers {
+		if i > 0 {
+			if promptCacheTier(layers[i-1].layer) != promptCacheTier(l.layer) {
+				b.WriteString(promptCacheBreak)
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(l.content)
+	}
+	return b.String()
+}
+
+// splitPromptCacheBreaks returns the non-empty segments of a system prompt.
+func splitPromptCacheBreaks(s string) []string {
+	var parts []string
+	for _, p := range strings.Split(s, promptCacheBreak) {
+		if strings.TrimSpace(p) != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// stripPromptCacheBreaks replaces the tier breaks in system messages with
+// plain paragraph breaks. messages is not modified.
+func stripPromptCacheBreaks(messages []chatMessage) []chatMessage {
+	var out []chatMessage
+	for i, m := range messages {
+		s, ok := m.Content.(string)
+		if m.Role != "system" || !ok || !strings.Contains(s, promptCacheBreak) {
+			continue
+		}
+		if out == nil {
+			out = append([]chatMessage(nil), messages...)
+		}
+		out[i].Content = strings.ReplaceAll(s, promptCacheBreak, "\n\n")
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
+
+// promptCachingEnabled reports whether explicit cache breakpoints are sent.
+// Opt out per provider with params.prompt_cache_disabled: true.
+func (c *LLMClient) promptCachingEnabled() bool {
+	return c.isAnthropicAPI() && !c.paramBool("prompt_cache_disabled")
+}
+
+// applyAnthropicPromptCaching marks the end of each stable system tier and
+// the last conversation block with cache_control, so the next call (the
+// next tool iteration or turn) reads the whole prefix from the cache.
+func applyAnthropicPromptCaching(req *anthropicRequest) {
+	// The last system block is the per-turn tier unless the prompt has a
+	// single block, in which case all of it is stable.
+	stable := len(req.System) - 1
+	if len(req.System) == 1 {
+		stable = 1
+	}
+	marked := 0
+	for i := stable - 1; i >= 0 && marked < maxSystemCacheBreakpoints; i-- {
+		req.System[i].CacheControl = &cacheControl{Type: "ephemeral"}
+		marked++
+	}
+
+	if len(req.Messages) == 0 {
+		return
+	}
+	last := &req.Messages[len(req.Messages)-1]
+	var blocks []anthropicContent
+	switch v := last.Content.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+		blocks = []anthropicContent{{Type: "text", Text: v}}
+	case []anthropicContent:
+		blocks = append([]anthropicContent(nil), v...)
+	default:
+		return // multimodal content is left untouched
+	}
+	if len(blocks) == 0 {
+		return
+	}
+	blocks[len(blocks)-1].CacheControl = &cacheControl{Type: "ephemeral"}
+	last.Content = blocks
+}
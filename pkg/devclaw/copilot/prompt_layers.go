@@ -35,6 +35,7 @@ const (
 	LayerBusiness     PromptLayer = 20 // User/workspace context.
 	LayerSkills       PromptLayer = 40 // Active skill instructions.
 	LayerMemory       PromptLayer = 50 // Long-term memory facts.
+	LayerGlossary     PromptLayer = 55 // Workspace glossary terms found in the input.
 	LayerTemporal     PromptLayer = 60 // Date/time context.
 	LayerConversation PromptLayer = 70 // Recent history summary.
 	LayerRuntime      PromptLayer = 80 // Runtime info (final line).
@@ -73,9 +74,14 @@ type PromptComposer struct {
 	config       *Config
 	memoryStore  *memory.FileStore
 	sqliteMemory *memory.SQLiteStore
+	kb           *KnowledgeBase
+	glossary     *Glossary
 	skillGetter  func(name string) (interface{ SystemPrompt() string }, bool)
 	isSubagent   bool // When true, only AGENTS.md + TOOLS.md are loaded.
 
+	// knowledgeCutoff returns the training cutoff of a model ("" = unknown).
+	knowledgeCutoff func(model string) string
+
 	// bootstrapCache caches bootstrap file contents to avoid re-reading from disk
 	// on every prompt compose. Invalidated when file content changes (hash mismatch).
 	bootstrapCacheMu sync.RWMutex
@@ -111,11 +117,27 @@ func (p *PromptComposer) SetSQLiteMemory(store *memory.SQLiteStore) {
 	p.sqliteMemory = store
 }
 
+// SetKnowledgeBase enables workspace-scoped facts in the memory layer.
+func (p *PromptComposer) SetKnowledgeBase(kb *KnowledgeBase) {
+	p.kb = kb
+}
+
+// SetGlossary enables the glossary layer for terms found in the input.
+func (p *PromptComposer) SetGlossary(g *Glossary) {
+	p.glossary = g
+}
+
 // SetSkillGetter sets the function used to retrieve skill system prompts.
 func (p *PromptComposer) SetSkillGetter(getter func(name string) (interface{ SystemPrompt() string }, bool)) {
 	p.skillGetter = getter
 }
 
+// SetKnowledgeCutoff sets how the temporal layer looks up the model's
+// knowledge cutoff (normally LLMClient.KnowledgeCutoff).
+func (p *PromptComposer) SetKnowledgeCutoff(fn func(model string) string) {
+	p.knowledgeCutoff = fn
+}
+
 // Compose builds the complete system prompt for a session and user input.
 // Heavy layers (bootstrap, memory, skills, conversation) are built concurrently
 // to minimize prompt composition latency.
@@ -123,10 +145,10 @@ func (p *PromptComposer) Compose(session *Session, input string) string {
 	// ── Fast layers (in-memory, no I/O) ──
 	layers := make([]layerEntry, 0, 10)
 
-	layers = append(layers, layerEntry{layer: LayerCore, content: p.buildCoreLayer()})
-	layers = append(layers, layerEntry{layer: LayerSafety, content: p.buildSafetyLayer()})
-	layers = append(layers, layerEntry{layer: LayerTemporal, content: p.buildTemporalLayer()})
-	layers = append(layers, layerEntry{layer: LayerRuntime, content: p.buildRuntimeLayer()})
+	layers = append(layers, layerEntry{layer: LayerCore, content: p.coreLayer()})
+	layers = append(layers, layerEntry{layer: LayerSafety, content: p.safetyLayer()})
+	layers = append(layers, layerEntry{layer: LayerTemporal, content: p.buildTemporalLayer(session)})
+	layers = append(layers, layerEntry{layer: LayerRuntime, content: p.runtimeLayer()})
 
 	if p.config.Instructions != "" {
 		layers = append(layers, layerEntry{
@@ -144,6 +166,12 @@ func (p *PromptComposer) Compose(session *Session, input string) string {
 			content: "## Workspace Context\n\n" + cfg.BusinessContext,
 		})
 	}
+	if p.glossary != nil {
+		terms := p.glossary.Match(session.workspaceID, input, p.config.Memory.Glossary.MaxTerms)
+		if glossary := formatGlossaryLayer(terms); glossary != "" {
+			layers = append(layers, layerEntry{layer: LayerGlossary, content: glossary})
+		}
+	}
 
 	// ── Heavy layers (I/O, search) ──
 	// Critical layers (bootstrap + history) are loaded synchronously because
@@ -158,7 +186,7 @@ func (p *PromptComposer) Compose(session *Session, input string) string {
 	)
 
 	wg.Add(2)
-	go func() { defer wg.Done(); bootstrap = p.buildBootstrapLayer() }()
+	go func() { defer wg.Done(); bootstrap = p.buildBootstrapLayer(cfg.PromptDir) }()
 	go func() { defer wg.Done(); history = p.buildConversationLayer(session) }()
 
 	// Memory and skills: use cached versions to avoid blocking.
@@ -194,9 +222,9 @@ func (p *PromptComposer) Compose(session *Session, input string) string {
 // history to minimize token count and latency.
 func (p *PromptComposer) ComposeMinimal() string {
 	layers := []layerEntry{
-		{layer: LayerCore, content: p.buildCoreLayer()},
-		{layer: LayerSafety, content: p.buildSafetyLayer()},
-		{layer: LayerTemporal, content: p.buildTemporalLayer()},
+		{layer: LayerCore, content: p.coreLayer()},
+		{layer: LayerSafety, content: p.safetyLayer()},
+		{layer: LayerTemporal, content: p.buildTemporalLayer(nil)},
 	}
 
 	if p.config.Instructions != "" {
@@ -231,6 +259,33 @@ func (p *PromptComposer) setCachedLayer(sessionID, layerType, content string) {
 	p.layerCacheMu.Unlock()
 }
 
+// staticLayerKey is the layer cache "session" of layers that only depend on
+// the config.
+const staticLayerKey = "static"
+
+// staticLayer returns a config-only layer from the layer cache, building and
+// caching it when missing or stale.
+func (p *PromptComposer) staticLayer(layerType string, build func() string) string {
+	if content := p.getCachedLayer(staticLayerKey, layerType); content != "" {
+		return content
+	}
+	content := build()
+	p.setCachedLayer(staticLayerKey, layerType, content)
+	return content
+}
+
+func (p *PromptComposer) coreLayer() string {
+	return p.staticLayer("core", p.buildCoreLayer)
+}
+
+func (p *PromptComposer) safetyLayer() string {
+	return p.staticLayer("safety", p.buildSafetyLayer)
+}
+
+func (p *PromptComposer) runtimeLayer() string {
+	return p.staticLayer("runtime", p.buildRuntimeLayer)
+}
+
 // refreshLayerCache rebuilds memory and skills layers in background and caches them.
 // This runs asynchronously so it doesn't block prompt composition.
 func (p *PromptComposer) refreshLayerCache(session *Session, input string) {
@@ -320,7 +375,8 @@ func (p *PromptComposer) buildCoreLayer() string {
 	b.WriteString("- Sub-agents have limited tools (no spawning, no memory, no cron)\n")
 	b.WriteString("- Do NOT repeatedly poll `list_subagents` in a loop — wait for auto-announced results\n")
 	b.WriteString("- Max 5 concurrent sub-agents per session\n")
-	b.WriteString("- Sub-agents cannot spawn their own sub-agents (depth = 1)\n\n")
+	b.WriteString("- Sub-agents cannot spawn their own sub-agents (depth = 1)\n")
+	b.WriteString("- Sub-agents see ONLY their task by default. Share context explicitly with `context`: facts, last_turns, artifacts (earlier run_ids), files — pick the minimum the task needs\n\n")
 	b.WriteString("### Example:\n")
 	b.WriteString("User: 'Create a React project and check if Docker is installed'\n")
 	b.WriteString("You: spawn_subagent(task='Check if Docker is installed...') → get run_id\n")
@@ -434,7 +490,7 @@ SSH/remote: only connect to known hosts. Don't store passwords in plaintext. Use
 You have an encrypted vault (AES-256-GCM + Argon2id) for storing secrets. Use these tools:
 
 - **vault_list** — List all stored secret names (no arguments needed).
-- **vault_get** — Retrieve a secret by name. Args: {"name": "key_name"}
+- **vault_get** — Show a secret to the owner, after their approval. The value goes to the chat, never to you. Args: {"name": "key_name"}
 - **vault_save** — Store a secret. Args: {"name": "key_name", "value": "secret_value"}
 - **vault_delete** — Remove a secret. Args: {"name": "key_name"}
 
@@ -442,7 +498,7 @@ You have an encrypted vault (AES-256-GCM + Argon2id) for storing secrets. Use th
 - When the user provides an API key, token, or password, ALWAYS save it with vault_save immediately.
 - NEVER store secrets in .env, config files, or any plain text file. The vault is the ONLY place.
 - NEVER echo/print secret values back to the user — confirm storage only.
-- To use a stored secret (e.g. in a script or API call), retrieve it with vault_get at runtime.
+- To use a stored secret (e.g. in a script or API call), read its environment variable: the name uppercased (github_token → $GITHUB_TOKEN). Secret values in tool output reach you as [vault:name].
 - Use vault_list to check what's already stored before asking the user for credentials.
 
 ## Media Capabilities
@@ -481,10 +537,11 @@ func (p *PromptComposer) buildThinkingLayer(session *Session) string {
 	return ""
 }
 
-// buildBootstrapLayer loads bootstrap files from the workspace root.
+// buildBootstrapLayer loads bootstrap files from the workspace root, or
+// first from promptDir when a workspace persona sets one.
 // Uses an in-memory cache with hash-based invalidation to avoid repeated disk reads.
 // In subagent mode, only AGENTS.md and TOOLS.md are loaded.
-func (p *PromptComposer) buildBootstrapLayer() string {
+func (p *PromptComposer) buildBootstrapLayer(promptDir string) string {
 	// Full list of bootstrap files.
 	allBootstrapFiles := []struct {
 		Path    string
@@ -519,6 +576,9 @@ func (p *PromptComposer) buildBootstrapLayer() string {
 		searchDirs = append([]string{p.config.Heartbeat.WorkspaceDir}, searchDirs...)
 	}
 	searchDirs = append(searchDirs, "configs")
+	if promptDir != "" {
+		searchDirs = append([]string{promptDir}, searchDirs...)
+	}
 
 	var files []struct {
 		path    string
@@ -577,9 +637,13 @@ func (p *PromptComposer) buildBootstrapLayer() string {
 // Within the TTL window (30s), returns cached content with zero disk I/O.
 // After TTL expires, re-reads the file and invalidates if content changed.
 func (p *PromptComposer) loadBootstrapFileCached(filename string, searchDirs []string) string {
+	// Workspaces with their own prompt directory resolve the same file
+	// differently, so the search path is part of the key.
+	key := strings.Join(searchDirs, string(os.PathListSeparator)) + "|" + filename
+
 	// Fast path: check if cache is still fresh (no disk I/O).
 	p.bootstrapCacheMu.RLock()
-	cached, ok := p.bootstrapCache[filename]
+	cached, ok := p.bootstrapCache[key]
 	p.bootstrapCacheMu.RUnlock()
 
 	if ok && time.Since(cached.cachedAt) < bootstrapCacheTTL {
@@ -599,7 +663,7 @@ func (p *PromptComposer) loadBootstrapFileCached(filename string, searchDirs []s
 	if err != nil || len(strings.TrimSpace(string(content))) == 0 {
 		// File not found or empty: cache empty result to avoid repeated lookups.
 		p.bootstrapCacheMu.Lock()
-		p.bootstrapCache[filename] = &bootstrapCacheEntry{
+		p.bootstrapCache[key] = &bootstrapCacheEntry{
 			content:  "",
 			cachedAt: time.Now(),
 		}
@@ -624,7 +688,7 @@ func (p *PromptComposer) loadBootstrapFileCached(filename string, searchDirs []s
 	}
 
 	p.bootstrapCacheMu.Lock()
-	p.bootstrapCache[filename] = &bootstrapCacheEntry{
+	p.bootstrapCache[key] = &bootstrapCacheEntry{
 		content:  text,
 		hash:     hash,
 		cachedAt: time.Now(),
@@ -767,6 +831,13 @@ func (p *PromptComposer) buildMemoryLayer(session *Session, input string) string
 		}
 	}
 
+	// Workspace-level facts promoted from summarized sessions.
+	if p.kb != nil {
+		if facts := p.kb.WorkspaceFacts(session.workspaceID, input, 10); facts != "" {
+			parts = append(parts, "## Workspace Knowledge\n\nFacts shared across this workspace's conversations:\n\n"+facts)
+		}
+	}
+
 	// Session-level facts.
 	sessionFacts := session.GetFacts()
 	if len(sessionFacts) > 0 {
@@ -781,22 +852,60 @@ func (p *PromptComposer) buildMemoryLayer(session *Session, input string) string
 	return strings.Join(parts, "\n")
 }
 
-// buildTemporalLayer adds date/time context.
-func (p *PromptComposer) buildTemporalLayer() string {
-	loc, err := time.LoadLocation(p.config.Timezone)
-	if err != nil {
+// buildTemporalLayer adds date/time context and the model's knowledge
+// cutoff, with the rule to search before answering about anything newer.
+// With a session, the time and conventions follow the chat's locale.
+func (p *PromptComposer) buildTemporalLayer(session *Session) string {
+	language, chatID := "", ""
+	if session != nil {
+		language, chatID = session.GetConfig().Language, session.ChatID
+	}
+	ul := p.config.LocaleFor(language, chatID)
+	loc := ul.Location
+	if p.config.Timezone == "" && ul.Timezone == "" {
 		loc = time.UTC
 	}
 
 	now := time.Now().In(loc)
 
-	return fmt.Sprintf("## Current Date & Time\n\n%s\nTimezone: %s\nDay: %s",
+	var cutoff string
+	if p.knowledgeCutoff != nil {
+		cutoff = p.knowledgeCutoff(p.config.Model)
+	}
+
+	var hint string
+	if h := localeHint(ul.Locale); h != "" {
+		hint = h + "\n"
+	}
+	return fmt.Sprintf("## Current Date & Time\n\n%s\nTimezone: %s\nDay: %s\n%s\n%s",
 		now.Format("2006-01-02 15:04:05"),
-		p.config.Timezone,
+		loc,
 		now.Format("Monday"),
+		hint,
+		freshnessRule(cutoff, now),
 	)
 }
 
+// freshnessRule tells the model how old its knowledge is and to verify
+// time-sensitive facts with web_search instead of answering from memory.
+func freshnessRule(cutoff string, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("### Knowledge Freshness\n\n")
+	if t := parseCutoff(cutoff); !t.IsZero() {
+		months := (now.Year()-t.Year())*12 + int(now.Month()-t.Month())
+		fmt.Fprintf(&b, "Your knowledge cutoff is %s, about %d months before today. ", t.Format("January 2006"), max(months, 0))
+		b.WriteString("Anything after it is unknown to you unless a tool tells you.\n")
+	} else {
+		b.WriteString("Your training data ends at an unknown date, likely months or years before today.\n")
+	}
+	b.WriteString("For time-sensitive questions (news and recent events, latest versions and releases, prices, " +
+		"current office holders, schedules, anything described as \"latest\", \"current\" or \"new\"), " +
+		"run web_search first and answer from the results, citing them. " +
+		"Never state from memory that something is the latest or does not exist yet; " +
+		"if you cannot search, say your information may be out of date.")
+	return b.String()
+}
+
 // buildConversationLayer creates a summary of recent history, using a
 // token-aware sliding window to stay within the history token budget.
 func (p *PromptComposer) buildConversationLayer(session *Session) string {
@@ -937,7 +1046,8 @@ func (p *PromptComposer) assembleLayers(layers []layerEntry) string {
 		LayerBusiness:     1000, // workspace context
 		LayerSkills:       p.config.TokenBudget.Skills,
 		LayerMemory:       p.config.TokenBudget.Memory,
-		LayerTemporal:     200, // timestamp
+		LayerGlossary:     400, // matched glossary terms
+		LayerTemporal:     300, // timestamp + knowledge cutoff
 		LayerConversation: p.config.TokenBudget.History,
 		LayerRuntime:      200, // runtime line
 	}
@@ -961,11 +1071,11 @@ func (p *PromptComposer) assembleLayers(layers []layerEntry) string {
 
 	// Phase 2: if within budget, return as-is.
 	if totalTokens <= systemBudget {
-		var parts []string
+		kept := make([]layerEntry, 0, len(entries))
 		for _, m := range entries {
-			parts = append(parts, m.entry.content)
+			kept = append(kept, m.entry)
 		}
-		return strings.Join(parts, "\n\n")
+		return joinPromptLayers(kept)
 	}
 
 	// Phase 3: trim from lowest priority (highest layer number) first.
@@ -1002,12 +1112,12 @@ func (p *PromptComposer) assembleLayers(layers []layerEntry) string {
 		}
 	}
 
-	var parts []string
+	kept := make([]layerEntry, 0, len(entries))
 	for _, m := range entries {
 		if m.entry.content != "" {
-			parts = append(parts, m.entry.content)
+			kept = append(kept, m.entry)
 		}
 	}
 
-	return strings.Join(parts, "\n\n")
+	return joinPromptLayers(kept)
 }
@@ -0,0 +1,272 @@
+// Package copilot – quota.go implements per-workspace monthly spend quotas.
+// Each workspace gets a monthly limit in USD (from config or set by an
+// owner), owners can top it up for the current month, and spend is tracked
+// per calendar month so quotas reset automatically when the month rolls
+// over. Quotas are checked before an agent run starts: a workspace that has
+// used up its quota gets a short reply instead of another LLM call.
+package copilot
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuotaConfig configures per-workspace monthly quotas.
+type QuotaConfig struct {
+	// Enabled turns on quota enforcement (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// DefaultMonthlyUSD is the monthly quota of workspaces without their
+	// own entry (0 = unlimited).
+	DefaultMonthlyUSD float64 `yaml:"default_monthly_usd"`
+
+	// Workspaces sets the monthly quota per workspace ID (0 = unlimited).
+	// Limits set with /quota set or the API take precedence.
+	Workspaces map[string]float64 `yaml:"workspaces"`
+}
+
+// DefaultQuotaConfig returns the default quota configuration.
+func DefaultQuotaConfig() QuotaConfig {
+	return QuotaConfig{Enabled: false}
+}
+
+// QuotaStatus is a workspace's quota for the current month.
+type QuotaStatus struct {
+	WorkspaceID  string    `json:"workspace_id"`
+	Month        string    `json:"month"` // YYYY-MM
+	LimitUSD     float64   `json:"limit_usd"`
+	TopUpUSD     float64   `json:"topup_usd"`
+	SpentUSD     float64   `json:"spent_usd"`
+	RemainingUSD float64   `json:"remaining_usd"`
+	Unlimited    bool      `json:"unlimited"`
+	ResetsAt     time.Time `json:"resets_at"`
+}
+
+// Exhausted reports whether the workspace has no budget left this month.
+func (s QuotaStatus) Exhausted() bool {
+	return !s.Unlimited && s.RemainingUSD <= 0
+}
+
+// Format renders the status for chat.
+func (s QuotaStatus) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Quota — %s (%s)*\n", s.WorkspaceID, s.Month)
+	if s.Unlimited {
+		fmt.Fprintf(&b, "Spent: $%.2f\nLimit: unlimited", s.SpentUSD)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Spent: $%.2f of $%.2f", s.SpentUSD, s.LimitUSD+s.TopUpUSD)
+	if s.TopUpUSD > 0 {
+		fmt.Fprintf(&b, " ($%.2f monthly + $%.2f top-up)", s.LimitUSD, s.TopUpUSD)
+	}
+	fmt.Fprintf(&b, "\nRemaining: $%.2f\nResets: %s", s.RemainingUSD, s.ResetsAt.Format("2006-01-02"))
+	return b.String()
+}
+
+// QuotaManager tracks monthly spend per workspace in devclaw.db.
+type QuotaManager struct {
+	db     *sql.DB
+	cfg    QuotaConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	// mu serializes read-modify-write updates of the current month's row.
+	mu sync.Mutex
+}
+
+// NewQuotaManager creates the quota tables if needed.
+func NewQuotaManager(db *sql.DB, cfg QuotaConfig, logger *slog.Logger) (*QuotaManager, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS workspace_quotas (
+			workspace_id TEXT PRIMARY KEY,
+			limit_usd    REAL NOT NULL,
+			updated_at   TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS quota_usage (
+			month        TEXT NOT NULL,
+			workspace_id TEXT NOT NULL,
+			spent_usd    REAL NOT NULL DEFAULT 0,
+			topup_usd    REAL NOT NULL DEFAULT 0,
+			PRIMARY KEY (month, workspace_id)
+		)`); err != nil {
+		return nil, fmt.Errorf("create quota tables: %w", err)
+	}
+	return &QuotaManager{
+		db:     db,
+		cfg:    cfg,
+		logger: logger.With("component", "quota"),
+		now:    time.Now,
+	}, nil
+}
+
+// month returns the current quota period (UTC calendar month) and the time
+// it ends.
+func (q *QuotaManager) month() (string, time.Time) {
+	now := q.now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01"), start.AddDate(0, 1, 0)
+}
+
+// limitFor returns the monthly limit of a workspace: an owner override,
+// the per-workspace config entry or the default.
+func (q *QuotaManager) limitFor(workspaceID string) (float64, error) {
+	var limit float64
+	err := q.db.QueryRow(`SELECT limit_usd FROM workspace_quotas WHERE workspace_id = ?`, workspaceID).Scan(&limit)
+	switch {
+	case err == nil:
+		return limit, nil
+	case err != sql.ErrNoRows:
+		return 0, fmt.Errorf("query workspace quota: %w", err)
+	}
+	if v, ok := q.cfg.Workspaces[workspaceID]; ok {
+		return v, nil
+	}
+	return q.cfg.DefaultMonthlyUSD, nil
+}
+
+// Status returns the workspace's quota for the current month.
+func (q *QuotaManager) Status(workspaceID string) (QuotaStatus, error) {
+	month, resets := q.month()
+	st := QuotaStatus{WorkspaceID: workspaceID, Month: month, ResetsAt: resets}
+
+	limit, err := q.limitFor(workspaceID)
+	if err != nil {
+		return st, err
+	}
+	err = q.db.QueryRow(`SELECT spent_usd, topup_usd FROM quota_usage WHERE month = ? AND workspace_id = ?`,
+		month, workspaceID).Scan(&st.SpentUSD, &st.TopUpUSD)
+	if err != nil && err != sql.ErrNoRows {
+		return st, fmt.Errorf("query quota usage: %w", err)
+	}
+
+	st.LimitUSD = limit
+	if limit <= 0 {
+		st.Unlimited = true
+		return st, nil
+	}
+	st.RemainingUSD = math.Max(0, limit+st.TopUpUSD-st.SpentUSD)
+	return st, nil
+}
+
+// Allow reports whether the workspace may start a new run. Storage errors
+// fail open so a database hiccup doesn't take the assistant down.
+func (q *QuotaManager) Allow(workspaceID string) (QuotaStatus, bool) {
+	st, err := q.Status(workspaceID)
+	if err != nil {
+		q.logger.Warn("quota check failed", "workspace", workspaceID, "error", err)
+		return st, true
+	}
+	return st, !st.Exhausted()
+}
+
+// Record adds spend to the workspace's current month.
+func (q *QuotaManager) Record(workspaceID string, costUSD float64) {
+	if costUSD <= 0 {
+		return
+	}
+	month, _ := q.month()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.db.Exec(`
+		INSERT INTO quota_usage (month, workspace_id, spent_usd) VALUES (?, ?, ?)
+		ON CONFLICT(month, workspace_id) DO UPDATE SET spent_usd = spent_usd + excluded.spent_usd`,
+		month, workspaceID, costUSD); err != nil {
+		q.logger.Warn("recording quota usage failed", "workspace", workspaceID, "error", err)
+	}
+}
+
+// SetLimit overrides the workspace's monthly limit (0 = unlimited).
+func (q *QuotaManager) SetLimit(workspaceID string, limitUSD float64) error {
+	if limitUSD < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.db.Exec(`
+		INSERT OR REPLACE INTO workspace_quotas (workspace_id, limit_usd, updated_at) VALUES (?, ?, ?)`,
+		workspaceID, limitUSD, q.now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("set workspace quota: %w", err)
+	}
+	return nil
+}
+
+// ResetLimit drops the owner override so the configured limit applies again.
+func (q *QuotaManager) ResetLimit(workspaceID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.db.Exec(`DELETE FROM workspace_quotas WHERE workspace_id = ?`, workspaceID); err != nil {
+		return fmt.Errorf("reset workspace quota: %w", err)
+	}
+	return nil
+}
+
+// TopUp adds budget to the workspace for the current month only.
+func (q *QuotaManager) TopUp(workspaceID string, amountUSD float64) error {
+	if amountUSD <= 0 {
+		return fmt.Errorf("top-up must be positive")
+	}
+	month, _ := q.month()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.db.Exec(`
+		INSERT INTO quota_usage (month, workspace_id, topup_usd) VALUES (?, ?, ?)
+		ON CONFLICT(month, workspace_id) DO UPDATE SET topup_usd = topup_usd + excluded.topup_usd`,
+		month, workspaceID, amountUSD); err != nil {
+		return fmt.Errorf("top up workspace quota: %w", err)
+	}
+	return nil
+}
+
+// List returns the current status of every workspace that has a limit or
+// spend this month, plus the given extra IDs, sorted by workspace ID.
+func (q *QuotaManager) List(workspaceIDs ...string) ([]QuotaStatus, error) {
+	month, _ := q.month()
+	ids := make(map[string]bool)
+	for _, id := range workspaceIDs {
+		ids[id] = true
+	}
+	for id := range q.cfg.Workspaces {
+		ids[id] = true
+	}
+
+	rows, err := q.db.Query(`
+		SELECT workspace_id FROM workspace_quotas
+		UNION SELECT workspace_id FROM quota_usage WHERE month = ?`, month)
+	if err != nil {
+		return nil, fmt.Errorf("list quotas: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan quota: %w", err)
+		}
+		ids[id] = true
+	}
+	rows.Close()
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	out := make([]QuotaStatus, 0, len(sorted))
+	for _, id := range sorted {
+		st, err := q.Status(id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
+)
+
+func TestQuotaManager_LimitsTopUpsAndMonthlyReset(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	q, err := NewQuotaManager(db, QuotaConfig{
+		Enabled:           true,
+		DefaultMonthlyUSD: 10,
+		Workspaces:        map[string]float64{"free": 0},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	q.Record("sales", 6)
+	q.Record("sales", 4.5)
+	st, ok := q.Allow("sales")
+	if ok || st.SpentUSD != 10.5 || st.RemainingUSD != 0 {
+		t.Fatalf("expected sales to be exhausted, got %+v", st)
+	}
+	if !st.ResetsAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected reset time %v", st.ResetsAt)
+	}
+
+	// A top-up only covers the current month.
+	if err := q.TopUp("sales", 5); err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := q.Allow("sales"); !ok || st.RemainingUSD != 4.5 {
+		t.Fatalf("expected $4.50 left after top-up, got %+v", st)
+	}
+
+	// Workspaces configured with 0 are unlimited.
+	q.Record("free", 1000)
+	if st, ok := q.Allow("free"); !ok || !st.Unlimited {
+		t.Errorf("expected free to be unlimited, got %+v", st)
+	}
+
+	// An owner override wins over config; resetting it restores config.
+	if err := q.SetLimit("free", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := q.Allow("free"); ok {
+		t.Error("expected override to exhaust free")
+	}
+	if err := q.ResetLimit("free"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := q.Allow("free"); !ok {
+		t.Error("expected free to be unlimited again after reset")
+	}
+
+	// Next month starts from zero, without last month's top-up.
+	now = time.Date(2026, 4, 1, 0, 0, 1, 0, time.UTC)
+	st, ok = q.Allow("sales")
+	if !ok || st.Month != "2026-04" || st.SpentUSD != 0 || st.TopUpUSD != 0 || st.RemainingUSD != 10 {
+		t.Fatalf("expected a fresh month, got %+v", st)
+	}
+
+	list, err := q.List("idle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, s := range list {
+		ids = append(ids, s.WorkspaceID)
+	}
+	if len(ids) != 2 || ids[0] != "free" || ids[1] != "idle" {
+		t.Errorf("unexpected list %v", ids)
+	}
+}
+
+func TestUsageLedger_ExportInvoicesAndMonthlyJob(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var pushed struct {
+		Month string       `json:"month"`
+		Rows  []UsageEntry `json:"rows"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&pushed)
+	}))
+	defer srv.Close()
+
+	l, err := NewUsageLedger(db, BillingConfig{
+		Enabled:       true,
+		MonthlyReport: true,
+		TopUsers:      1,
+		Push:          []UsagePushTarget{{Type: "webhook", URL: srv.URL}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	march := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	l.Record(UsageEntry{At: march, WorkspaceID: "acme", SessionID: "s1", UserID: "alice", RunID: "r1", Model: "gpt-5", PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.5})
+	l.Record(UsageEntry{At: march, WorkspaceID: "acme", SessionID: "s1", UserID: "alice", RunID: "r1", Model: "gpt-5", PromptTokens: 500, CompletionTokens: 100, CostUSD: 0.25})
+	l.Record(UsageEntry{At: march, WorkspaceID: "acme", SessionID: "s2", UserID: "bob", RunID: "r2", Model: "gpt-5-mini", PromptTokens: 100, CompletionTokens: 10, CostUSD: 0.01})
+	l.Record(UsageEntry{At: march, WorkspaceID: "globex", SessionID: "s3", RunID: "r3", Model: "gpt-5", PromptTokens: 10, CompletionTokens: 1, CostUSD: 0.02})
+	l.Record(UsageEntry{At: march.AddDate(0, 1, 0), WorkspaceID: "acme", RunID: "r4", Model: "gpt-5"})
+
+	invoices, err := l.Invoices("2026-03", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != 2 || invoices[0].WorkspaceID != "acme" {
+		t.Fatalf("want invoices for acme and globex, got %+v", invoices)
+	}
+	acme := invoices[0]
+	if acme.Runs != 2 || acme.Requests != 3 || acme.PromptTokens != 1600 || len(acme.Models) != 2 {
+		t.Errorf("unexpected acme invoice %+v", acme)
+	}
+	if len(acme.TopUsers) != 1 || acme.TopUsers[0].Name != "alice" {
+		t.Errorf("alice should be the top user, got %+v", acme.TopUsers)
+	}
+
+	entries, _ := l.Entries("2026-03", "acme")
+	var buf bytes.Buffer
+	if err := ExportUsage(&buf, "csv", entries); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[0], "id,at,workspace_id") {
+		t.Errorf("unexpected csv export:\n%s", buf.String())
+	}
+
+	recent, err := l.EntriesSince(march.Add(-time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := BuildUsageReport(march.Add(-time.Hour), "", recent, true)
+	if report.Requests != 5 || len(report.Models) != 2 || report.Models[0].Name != "gpt-5" || len(report.Workspaces) != 2 {
+		t.Errorf("unexpected usage report %+v", report)
+	}
+	if text := report.Format(scheduler.Locale{}); !strings.Contains(text, "- gpt-5-mini: 1 calls, 110 tokens, $0.01") {
+		t.Errorf("unexpected formatted report:\n%s", text)
+	}
+	if later, _ := l.EntriesSince(march.Add(time.Hour), "acme"); len(later) != 1 {
+		t.Errorf("want only April's acme entry, got %+v", later)
+	}
+
+	// The job reports the month that just ended, once.
+	l.now = func() time.Time { return time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC) }
+	var reports []string
+	deliver := func(_ context.Context, month string, inv []UsageInvoice) error {
+		reports = append(reports, month+": "+formatUsageReport(inv, scheduler.Locale{}))
+		return nil
+	}
+	l.reportLastMonth(context.Background(), deliver)
+	l.reportLastMonth(context.Background(), deliver)
+	if len(reports) != 1 || !strings.Contains(reports[0], "2026-03") || !strings.Contains(reports[0], "Total: $0.78") {
+		t.Fatalf("want one March report, got %q", reports)
+	}
+	if pushed.Month != "2026-03" || len(pushed.Rows) != 4 {
+		t.Errorf("want 4 March rows pushed, got %d for %q", len(pushed.Rows), pushed.Month)
+	}
+}
@@ -0,0 +1,344 @@
+// Package copilot – readonly_mode.go implements /readonly, a per-session
+// mode for chats where less-trusted users talk to the bot. In a read-only
+// session the tool guard lets through only tools that read or search (plus
+// those in tool_guard.read_only_tools) and shell commands that cannot
+// change anything; writes, edits, cron_add, skill installs, subagents and
+// every other tool are blocked for everyone in the session. Only admins
+// turn the mode on or off.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// readOnlyTools are the tools that cannot change anything.
+var readOnlyTools = map[string]bool{
+	"read_file": true, "list_files": true, "search_files": true, "glob_files": true,
+	"memory_search": true, "memory_list": true, "docs_search": true, "corpus_search": true,
+	"web_search": true, "web_fetch": true,
+	"cron_list": true, "list_skills": true, "search_skills": true,
+	"describe_image": true, "transcribe_audio": true,
+}
+
+// readOnlyShellTools run commands, allowed when the command is read-only.
+var readOnlyShellTools = map[string]bool{"bash": true, "exec": true, "ssh": true}
+
+// readOnlyArgv lists the commands allowed in read-only sessions. A nil entry
+// accepts any arguments; otherwise the function gets the arguments after the
+// command name and reports whether that argv shape only reads. Commands not
+// listed (tee, awk, xargs, interpreters, wrappers, ...) are refused.
+var readOnlyArgv = map[string]func(args []string) bool{
+	"ls": nil, "cat": nil, "head": nil, "tail": nil, "less": nil, "more": nil,
+	"grep": nil, "wc": nil, "cut": nil, "tr": nil, "which": nil, "whereis": nil,
+	"type": nil, "echo": nil, "printf": nil, "cal": nil, "uptime": nil,
+	"whoami": nil, "id": nil, "uname": nil, "printenv": nil, "pwd": nil,
+	"realpath": nil, "dirname": nil, "basename": nil, "df": nil, "du": nil,
+	"free": nil, "top": nil, "ps": nil, "pgrep": nil, "dig": nil, "nslookup": nil,
+	"ping": nil, "traceroute": nil, "stat": nil, "md5sum": nil, "sha256sum": nil,
+	"sha1sum": nil, "diff": nil, "cmp": nil, "comm": nil, "jq": nil,
+
+	"rg":       denyFlags("--pre"),
+	"sort":     denyFlags("-o", "--output"),
+	"tree":     denyFlags("-o"),
+	"file":     denyFlags("-C", "--compile"),
+	"yq":       denyFlags("-i", "--inplace"),
+	"date":     denyFlags("-s", "--set"),
+	"hostname": func(args []string) bool { return len(operands(args)) == 0 },
+	// uniq writes its second operand.
+	"uniq": func(args []string) bool { return len(operands(args)) <= 1 },
+	"find": denyFlags("-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"),
+	"sed":  readOnlySed,
+	"curl": readOnlyCurl,
+	"git":  readOnlyGit,
+	"go": func(args []string) bool {
+		return len(args) > 0 && (args[0] == "version" || args[0] == "env" && denyFlags("-w", "-u")(args[1:]))
+	},
+	"pm2":    subcommands("list", "status", "logs"),
+	"docker": subcommands("ps", "images", "logs"),
+}
+
+// denyFlags returns an argv check refusing the given options, also in the
+// --opt=value form. Single-letter options are refused anywhere in a short
+// option cluster (-ni, -sSo), erring on the side of refusing their values.
+func denyFlags(flags ...string) func([]string) bool {
+	return func(args []string) bool {
+		for _, a := range args {
+			short := len(a) > 1 && a[0] == '-' && a[1] != '-'
+			for _, f := range flags {
+				if a == f || strings.HasPrefix(a, f+"=") ||
+					len(f) == 2 && f[0] == '-' && short && strings.IndexByte(a[1:], f[1]) >= 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+}
+
+// subcommands returns an argv check allowing only the given subcommands.
+func subcommands(names ...string) func([]string) bool {
+	return func(args []string) bool {
+		return len(args) > 0 && slices.Contains(names, args[0])
+	}
+}
+
+// operands returns the arguments that are not options.
+func operands(args []string) []string {
+	var out []string
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// sedWriteRe matches sed commands that write files or run commands: w, W
+// and e commands and the w/e flags of s.
+var sedWriteRe = regexp.MustCompile(`(?:^|[;{}\n!$0-9/])\s*[wWe](?:\s|$)|/[gpiImM0-9]*[we](?:\s|;|$)`)
+
+// readOnlySed refuses in-place edits and scripts that write or execute.
+func readOnlySed(args []string) bool {
+	if !denyFlags("-i", "--in-place", "-f", "--file")(args) {
+		return false
+	}
+	script := false
+	for i, a := range args {
+		switch {
+		case a == "-e" || a == "--expression":
+			if i+1 < len(args) && sedWriteRe.MatchString(args[i+1]) {
+				return false
+			}
+			script = true
+		case strings.HasPrefix(a, "--expression="):
+			if sedWriteRe.MatchString(strings.TrimPrefix(a, "--expression=")) {
+				return false
+			}
+			script = true
+		case !strings.HasPrefix(a, "-") && !script && (i == 0 || args[i-1] != "-e" && args[i-1] != "--expression"):
+			// The first operand is the script when no -e was given.
+			if sedWriteRe.MatchString(a) {
+				return false
+			}
+			script = true
+		}
+	}
+	return true
+}
+
+// curlWriteShort and curlWriteLong are curl options that write files, upload data or read
+// options from a file. Single letters also match inside short clusters
+// (-sSo).
+var (
+	curlWriteShort = "oOTdFXKcDJ"
+	curlWriteLong  = []string{
+		"--output", "--remote-name", "--remote-name-all", "--output-dir", "--upload-file",
+		"--data", "--data-raw", "--data-binary", "--data-urlencode", "--data-ascii",
+		"--json", "--form", "--form-string", "--request", "--config", "--cookie-jar",
+		"--dump-header", "--trace", "--trace-ascii", "--libcurl", "--stderr", "--create-dirs",
+	}
+)
+
+// readOnlyCurl allows plain GET requests printing to stdout.
+func readOnlyCurl(args []string) bool {
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "--"):
+			name, _, _ := strings.Cut(a, "=")
+			if slices.Contains(curlWriteLong, name) {
+				return false
+			}
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			if strings.ContainsAny(a[1:], curlWriteShort) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// readOnlyGitSubcommands are the git subcommands allowed, with their
+// refused options.
+var readOnlyGitSubcommands = map[string][]string{
+	"status": nil,
+	"log":    nil,
+	"diff":   {"--ext-diff"},
+	"show":   {"--ext-diff"},
+	"branch": {"-d", "-D", "-m", "-M", "-c", "-C", "-f", "-u", "--delete", "--move", "--copy",
+		"--force", "--set-upstream-to", "--unset-upstream", "--edit-description"},
+}
+
+// readOnlyGit allows the read-only subcommands. Options before the
+// subcommand (-c, -C, --exec-path) and --output are refused; "git branch
+// <name>" creates a branch, so branch takes no operands unless listing.
+func readOnlyGit(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	deny, ok := readOnlyGitSubcommands[args[0]]
+	if !ok || !denyFlags(append([]string{"--output"}, deny...)...)(args[1:]) {
+		return false
+	}
+	if args[0] == "branch" && len(operands(args[1:])) > 0 {
+		return slices.ContainsFunc(args[1:], func(a string) bool {
+			return a == "-l" || a == "--list" || a == "--contains" || a == "--merged" || a == "--no-merged" || a == "--points-at"
+		})
+	}
+	return true
+}
+
+// readOnlyShellCommand reports whether a command can only read. The command
+// is parsed into a shell AST (as in tool_guard_shell.go); every simple
+// command in it must be an allowed argv shape (readOnlyArgv), with no
+// substitution, assignment, output redirection (other than to /dev/null or
+// another descriptor) or compound command.
+func readOnlyShellCommand(command string) bool {
+	if strings.TrimSpace(command) == "" {
+		return false
+	}
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return false
+	}
+
+	ok := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if !ok {
+			return false
+		}
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			ok = !n.Coprocess && readOnlyRedirects(n.Redirs)
+			switch n.Cmd.(type) {
+			case nil, *syntax.CallExpr, *syntax.BinaryCmd, *syntax.Subshell, *syntax.Block:
+			default:
+				ok = false
+			}
+		case *syntax.CallExpr:
+			ok = len(n.Assigns) == 0 && readOnlyCall(n.Args)
+		case *syntax.CmdSubst, *syntax.ProcSubst, *syntax.Assign:
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+// readOnlyRedirects allows input redirections, here-docs, descriptor
+// duplication (2>&1) and output to /dev/null.
+func readOnlyRedirects(redirs []*syntax.Redirect) bool {
+	for _, r := range redirs {
+		target := ""
+		if r.Word != nil {
+			target = wordText(r.Word)
+		}
+		switch r.Op {
+		case syntax.RdrIn, syntax.Hdoc, syntax.DashHdoc, syntax.WordHdoc, syntax.DplIn:
+		case syntax.DplOut:
+			if _, err := strconv.Atoi(target); err != nil && target != "-" {
+				return false
+			}
+		case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+			if target != "/dev/null" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readOnlyCall checks one simple command against readOnlyArgv. The command
+// name must be a literal: "$CMD args" could run anything.
+func readOnlyCall(words []*syntax.Word) bool {
+	if len(words) == 0 {
+		return true
+	}
+	if words[0].Lit() == "" {
+		return false
+	}
+	args := make([]string, len(words))
+	for i, w := range words {
+		args[i] = wordText(w)
+	}
+	check, ok := readOnlyArgv[args[0]]
+	if !ok {
+		return false
+	}
+	return check == nil || check(args[1:])
+}
+
+// CheckReadOnly blocks calls that could change something, for read-only
+// sessions. It applies on top of Check, even when the guard is disabled.
+func (g *ToolGuard) CheckReadOnly(toolName string, args map[string]any) ToolCheckResult {
+	g.mu.Lock()
+	extra := slices.Contains(g.cfg.ReadOnlyTools, toolName)
+	g.mu.Unlock()
+	if readOnlyTools[toolName] || extra {
+		return ToolCheckResult{Allowed: true}
+	}
+	if readOnlyShellTools[toolName] {
+		command, _ := args["command"].(string)
+		if readOnlyShellCommand(command) {
+			return ToolCheckResult{Allowed: true}
+		}
+		return ToolCheckResult{Reason: "this session is read-only: only commands that read are allowed"}
+	}
+	return ToolCheckResult{Reason: fmt.Sprintf("this session is read-only: %s may change things", toolName)}
+}
+
+// ctxKeyReadOnly is the context key marking runs of read-only sessions.
+type ctxKeyReadOnly struct{}
+
+// ContextWithReadOnly returns a context whose tool calls are limited to
+// reads.
+func ContextWithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyReadOnly{}, true)
+}
+
+// ReadOnlyFromContext reports whether the run belongs to a read-only session.
+func ReadOnlyFromContext(ctx context.Context) bool {
+	ro, _ := ctx.Value(ctxKeyReadOnly{}).(bool)
+	return ro
+}
+
+// readonlyCommand shows or (for admins) toggles read-only mode.
+func (a *Assistant) readonlyCommand(args []string, msg *channels.IncomingMessage, isAdmin bool) string {
+	resolved := a.workspaceMgr.Resolve(msg.Channel, msg.ChatID, msg.From, msg.IsGroup)
+	session := resolved.Session
+	cfg := session.GetConfig()
+
+	if len(args) == 0 {
+		if cfg.ReadOnly {
+			return "Read-only mode: on — tools that write or change things are blocked in this chat."
+		}
+		return "Read-only mode: off"
+	}
+	if !isAdmin {
+		return "Only admins can change read-only mode."
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on", "true", "1":
+		cfg.ReadOnly = true
+		session.SetConfig(cfg)
+		a.logger.Info("session set read-only", "session", session.ID, "by", msg.From)
+		return "Read-only mode: on — only reads and searches will run in this chat."
+	case "off", "false", "0":
+		cfg.ReadOnly = false
+		session.SetConfig(cfg)
+		a.logger.Info("session read-only mode off", "session", session.ID, "by", msg.From)
+		return "Read-only mode: off"
+	default:
+		return "Usage: /readonly [on|off]"
+	}
+}
@@ -0,0 +1,356 @@
+// Package copilot – redaction.go keeps personal data of a workspace's chats
+// away from the LLM provider. With redaction.enabled on a workspace, every
+// LLM call of its agent runs sends the messages with emails, phone numbers,
+// card numbers, CPFs and names (found by cue phrases such as "my name is"
+// or "Mr.") replaced by placeholders like [EMAIL_3fa91c]; the placeholders
+// in the reply, its streamed text and its tool call arguments are swapped
+// back, so users and tools still see the real values. A placeholder is
+// derived from a hash of the value, so the same value gets the same
+// placeholder across calls and turns. Each run that redacted anything
+// leaves a report (counts per kind, never values) in the audit log.
+package copilot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// RedactionConfig configures PII redaction of a workspace's LLM calls.
+type RedactionConfig struct {
+	// Enabled redacts the workspace's messages before LLM calls.
+	Enabled bool `yaml:"enabled"`
+
+	// Kinds limits the built-in detectors: email, phone, card, cpf, name.
+	// Empty = all of them.
+	Kinds []string `yaml:"kinds,omitempty"`
+
+	// Patterns are extra regular expressions, keyed by placeholder label
+	// (e.g. order_id: "ORD-[0-9]{6}" → [ORDER_ID_…]).
+	Patterns map[string]string `yaml:"patterns,omitempty"`
+
+	// Allow lists values that are never redacted (e.g. the business's own
+	// support address).
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// redactionRule detects one kind of value. With group > 0 only that
+// submatch is redacted (the cue phrase stays).
+type redactionRule struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+	valid func(string) bool
+}
+
+// builtinRedactionRules are the detectors selectable by kind, in the order
+// they run: the specific number formats come before phone numbers.
+var builtinRedactionRules = []redactionRule{
+	{kind: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{kind: "card", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: luhnValid},
+	{kind: "cpf", re: regexp.MustCompile(`\b\d{3}\.\d{3}\.\d{3}-\d{2}\b`)},
+	{kind: "phone", re: regexp.MustCompile(`\+?\(?\d[\d ().-]{6,}\d`), valid: phoneLike},
+	{kind: "name", re: regexp.MustCompile(
+		`(?:\b(?:[Mm]y name is|[Ii]'m|I am|[Mm]eu nome é|[Mm]e chamo|Mrs?\.?|Ms\.?|Dr\.?|Sra?\.?)\s+)` +
+			`(\p{Lu}\p{Ll}+(?:\s+\p{Lu}\p{Ll}+){0,2})`), group: 1},
+}
+
+// redactionPlaceholderRe matches a placeholder in LLM output.
+var redactionPlaceholderRe = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_[0-9a-f]{6,}\]`)
+
+// Redactor replaces PII with placeholders and restores them. One redactor
+// serves one agent run.
+type Redactor struct {
+	rules []redactionRule
+	allow map[string]bool
+
+	mu     sync.Mutex
+	values map[string]string // placeholder → original value
+	tokens map[string]string // original value → placeholder
+	counts map[string]int    // kind → distinct values redacted
+}
+
+// NewRedactor builds a redactor for cfg, or returns nil when redaction is
+// disabled. Invalid custom patterns are logged and skipped.
+func NewRedactor(cfg RedactionConfig, logger *slog.Logger) *Redactor {
+	if !cfg.Enabled {
+		return nil
+	}
+	r := &Redactor{
+		allow:  make(map[string]bool, len(cfg.Allow)),
+		values: make(map[string]string),
+		tokens: make(map[string]string),
+		counts: make(map[string]int),
+	}
+	for _, v := range cfg.Allow {
+		r.allow[strings.ToLower(v)] = true
+	}
+	for _, rule := range builtinRedactionRules {
+		if len(cfg.Kinds) == 0 || slices.Contains(cfg.Kinds, rule.kind) {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	labels := make([]string, 0, len(cfg.Patterns))
+	for label := range cfg.Patterns {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		re, err := regexp.Compile(cfg.Patterns[label])
+		if err != nil {
+			if logger != nil {
+				logger.Warn("invalid redaction pattern skipped", "label", label, "error", err)
+			}
+			continue
+		}
+		r.rules = append(r.rules, redactionRule{kind: label, re: re})
+	}
+	return r
+}
+
+// Redact replaces the PII in s with placeholders.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, rule := range r.rules {
+		s = r.apply(rule, s)
+	}
+	return s
+}
+
+// apply replaces the matches of one rule.
+func (r *Redactor) apply(rule redactionRule, s string) string {
+	matches := rule.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if rule.group > 0 {
+			start, end = m[2*rule.group], m[2*rule.group+1]
+		}
+		if start < 0 || start < last {
+			continue
+		}
+		value := s[start:end]
+		if r.allow[strings.ToLower(value)] || (rule.valid != nil && !rule.valid(value)) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(r.placeholder(rule.kind, value))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// placeholder returns the stable placeholder of value, recording it.
+func (r *Redactor) placeholder(kind, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if token, ok := r.tokens[value]; ok {
+		return token
+	}
+	label := strings.ToUpper(strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return c
+		}
+		return '_'
+	}, kind))
+	sum := sha256.Sum256([]byte(kind + ":" + value))
+	token := ""
+	for n := 3; n <= len(sum); n++ {
+		token = "[" + label + "_" + hex.EncodeToString(sum[:n]) + "]"
+		if _, taken := r.values[token]; !taken {
+			break
+		}
+	}
+	r.values[token] = value
+	r.tokens[value] = token
+	r.counts[kind]++
+	return token
+}
+
+// Restore swaps the known placeholders in s back for their values.
+func (r *Redactor) Restore(s string) string {
+	if r == nil || !strings.Contains(s, "[") {
+		return s
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return redactionPlaceholderRe.ReplaceAllStringFunc(s, func(token string) string {
+		if v, ok := r.values[token]; ok {
+			return v
+		}
+		return token
+	})
+}
+
+// Report returns the distinct values redacted so far, by kind.
+func (r *Redactor) Report() map[string]int {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]int, len(r.counts))
+	for k, v := range r.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// redactMessages returns a copy of messages with their text redacted.
+// Images and tool call names are sent as they are.
+func (r *Redactor) redactMessages(messages []chatMessage) []chatMessage {
+	out := make([]chatMessage, len(messages))
+	for i, m := range messages {
+		switch c := m.Content.(type) {
+		case string:
+			m.Content = r.Redact(c)
+		case []contentPart:
+			parts := make([]contentPart, len(c))
+			for j, p := range c {
+				p.Text = r.Redact(p.Text)
+				parts[j] = p
+			}
+			m.Content = parts
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(m.ToolCalls))
+			for j, tc := range m.ToolCalls {
+				tc.Function.Arguments = r.Redact(tc.Function.Arguments)
+				calls[j] = tc
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// restoreResponse swaps the placeholders in a reply and its tool calls.
+func (r *Redactor) restoreResponse(resp *LLMResponse) {
+	if resp == nil {
+		return
+	}
+	resp.Content = r.Restore(resp.Content)
+	for i := range resp.ToolCalls {
+		resp.ToolCalls[i].Function.Arguments = r.Restore(resp.ToolCalls[i].Function.Arguments)
+	}
+}
+
+// restoreStream wraps a stream callback so streamed text has its
+// placeholders restored. A chunk ending inside a possible placeholder is
+// held until the rest arrives; flush sends whatever is still held.
+func (r *Redactor) restoreStream(cb StreamCallback) (wrapped StreamCallback, flush func()) {
+	var held string
+	wrapped = func(chunk string) {
+		text := held + chunk
+		held = ""
+		if i := strings.LastIndexByte(text, '['); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < 48 {
+			text, held = text[:i], text[i:]
+		}
+		if text != "" {
+			cb(r.Restore(text))
+		}
+	}
+	flush = func() {
+		if held != "" {
+			cb(r.Restore(held))
+			held = ""
+		}
+	}
+	return wrapped, flush
+}
+
+// complete is LLMClient.Complete with the prompts and history redacted and
+// the reply restored. A nil redactor calls Complete unchanged.
+func (r *Redactor) complete(ctx context.Context, llm *LLMClient, systemPrompt string, history []ConversationEntry, userMessage string) (string, error) {
+	if r == nil {
+		return llm.Complete(ctx, systemPrompt, history, userMessage)
+	}
+	redacted := make([]ConversationEntry, len(history))
+	for i, e := range history {
+		e.UserMessage = r.Redact(e.UserMessage)
+		e.AssistantResponse = r.Redact(e.AssistantResponse)
+		redacted[i] = e
+	}
+	out, err := llm.Complete(ctx, r.Redact(systemPrompt), redacted, r.Redact(userMessage))
+	return r.Restore(out), err
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// phoneLike accepts 10-15 digits, or 8-15 with a leading "+", so dates
+// and short numbers stay.
+func phoneLike(s string) bool {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	if strings.HasPrefix(s, "+") {
+		return n >= 8 && n <= 15
+	}
+	return n >= 10 && n <= 15
+}
+
+// redactorFor returns the redactor of a run in the workspace (nil when the
+// workspace does not redact).
+func (a *Assistant) redactorFor(workspaceID string) *Redactor {
+	ws, ok := a.workspaceMgr.Get(workspaceID)
+	if !ok {
+		return nil
+	}
+	return NewRedactor(ws.Redaction, a.logger)
+}
+
+// auditRedaction records what a run redacted in the audit log.
+func (a *Assistant) auditRedaction(ctx context.Context, workspaceID string, r *Redactor) {
+	report := r.Report()
+	if len(report) == 0 {
+		return
+	}
+	total := 0
+	args := map[string]any{"workspace": workspaceID}
+	for kind, n := range report {
+		args[kind] = n
+		total += n
+	}
+	if guard := a.toolExecutor.Guard(); guard != nil {
+		guard.AuditLog("pii_redaction", CallerJIDFromContext(ctx), CallerLevelFromContext(ctx), args, true,
+			fmt.Sprintf("%d values redacted before LLM calls", total))
+	}
+}
@@ -0,0 +1,87 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactorRoundTrip(t *testing.T) {
+	r := NewRedactor(RedactionConfig{
+		Enabled:  true,
+		Patterns: map[string]string{"order_id": `ORD-[0-9]{6}`},
+		Allow:    []string{"help@shop.com"},
+	}, nil)
+	in := "My name is Ana Souza, mail ana@example.com or call +55 11 99876-5432. " +
+		"Card 4111 1111 1111 1111, CPF 123.456.789-09, order ORD-123456, on 2024-01-15. Ask help@shop.com."
+	out := r.Redact(in)
+	for _, pii := range []string{"Ana Souza", "ana@example.com", "99876-5432", "4111", "123.456.789-09", "ORD-123456"} {
+		if strings.Contains(out, pii) {
+			t.Errorf("%q not redacted: %s", pii, out)
+		}
+	}
+	for _, kept := range []string{"My name is [NAME_", "[ORDER_ID_", "2024-01-15", "help@shop.com"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("%q missing from %s", kept, out)
+		}
+	}
+	if again := r.Redact(in); again != out {
+		t.Error("placeholders should be stable")
+	}
+	if got := r.Restore(out); got != in {
+		t.Errorf("restore = %q", got)
+	}
+	report := r.Report()
+	if report["email"] != 1 || report["phone"] != 1 || report["card"] != 1 || report["name"] != 1 {
+		t.Errorf("report = %v", report)
+	}
+
+	var streamed strings.Builder
+	cb, flush := r.restoreStream(func(s string) { streamed.WriteString(s) })
+	token := r.Redact("ana@example.com")
+	cb("Writing to " + token[:5])
+	cb(token[5:] + " now [")
+	flush()
+	if streamed.String() != "Writing to ana@example.com now [" {
+		t.Errorf("streamed = %q", streamed.String())
+	}
+
+	if NewRedactor(RedactionConfig{}, nil) != nil {
+		t.Error("a disabled config should give no redactor")
+	}
+}
+
+func TestE2E_RedactionHidesPIIFromLLM(t *testing.T) {
+	redaction := RedactionConfig{Enabled: true}
+	h := newE2EHarness(t, func(cfg *Config) {
+		for i := range cfg.Workspaces.Workspaces {
+			cfg.Workspaces.Workspaces[i].Redaction = redaction
+		}
+	})
+	token := NewRedactor(redaction, nil).Redact("ana@example.com")
+	h.RegisterTool("send_invoice", "sent")
+	h.LLM.CallTool("send_invoice", map[string]any{"email": token})
+	h.LLM.Reply("Invoice sent to " + token + ".")
+
+	replies := h.Send("please send the invoice to ana@example.com")
+	if len(replies) == 0 || !strings.Contains(replies[len(replies)-1], "Invoice sent to ana@example.com.") {
+		t.Fatalf("replies = %q", replies)
+	}
+	for _, req := range h.LLM.Requests() {
+		if strings.Contains(req, "ana@example.com") {
+			t.Fatal("the email address reached the LLM")
+		}
+	}
+	if execs := h.Executions(); len(execs) != 1 || execs[0].Args["email"] != "ana@example.com" {
+		t.Fatalf("executions = %+v", execs)
+	}
+
+	var reported bool
+	for _, r := range h.Audit() {
+		if r.Tool == "pii_redaction" && strings.Contains(r.ArgsSummary, "email:1") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Errorf("redaction report not audited: %+v", h.Audit())
+	}
+}
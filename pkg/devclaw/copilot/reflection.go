@@ -0,0 +1,155 @@
+// Package copilot – reflection.go decides when the agent gets a reflection
+// nudge. Instead of a checkpoint every few turns, the run is watched for
+// signs that it stopped making progress, and targeted guidance is injected
+// only then:
+//
+//   - repeat: a tool called again with the same arguments returned the
+//     same output as before
+//   - stall: several consecutive turns produced no new information (every
+//     result was an error or something already seen)
+//   - burn: the prompt grew sharply in one turn (a huge tool output), or
+//     most of the run timeout is gone
+//
+// The tool loop detector still handles hard loops and circuit breaking;
+// these nudges come earlier and are softer.
+package copilot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// reflectionStallTurns is how many turns without new information
+	// trigger a stall nudge.
+	reflectionStallTurns = 3
+
+	// reflectionCooldownTurns keeps the same kind of nudge from repeating
+	// on consecutive turns.
+	reflectionCooldownTurns = 3
+
+	// reflectionBurnMinTokens and reflectionBurnFactor define a burn spike:
+	// the prompt grew by at least this many tokens and this factor in one turn.
+	reflectionBurnMinTokens = 8000
+	reflectionBurnFactor    = 2.0
+
+	// reflectionTimeShare is the share of the run timeout after which the
+	// agent is told to wrap up (once per run).
+	reflectionTimeShare = 0.75
+)
+
+// reflectionMonitor tracks progress signals across the turns of one run.
+type reflectionMonitor struct {
+	callOutputs map[string]string // tool call hash → output hash of its last result
+	seenOutputs map[string]bool   // output hashes already returned in this run
+
+	staleTurns   int
+	repeated     []string // tools repeated with identical output this turn
+	prevPrompt   int
+	promptGrowth int  // prompt token growth detected on the last LLM call
+	timeWarned   bool // the wrap-up nudge was already given
+	lastNudge    map[string]int
+}
+
+func newReflectionMonitor() *reflectionMonitor {
+	return &reflectionMonitor{
+		callOutputs: make(map[string]string),
+		seenOutputs: make(map[string]bool),
+		lastNudge:   make(map[string]int),
+	}
+}
+
+// recordUsage notes the prompt size of an LLM call.
+func (m *reflectionMonitor) recordUsage(promptTokens int) {
+	m.promptGrowth = 0
+	if m.prevPrompt > 0 && promptTokens >= m.prevPrompt+reflectionBurnMinTokens &&
+		float64(promptTokens) >= float64(m.prevPrompt)*reflectionBurnFactor {
+		m.promptGrowth = promptTokens - m.prevPrompt
+	}
+	if promptTokens > 0 {
+		m.prevPrompt = promptTokens
+	}
+}
+
+// recordTurn notes the tool calls of a turn and their results.
+func (m *reflectionMonitor) recordTurn(calls []ToolCall, results []ToolResult) {
+	callHash := make(map[string]string, len(calls))
+	for _, tc := range calls {
+		args, _ := parseToolArgs(tc.Function.Arguments)
+		callHash[tc.ID] = hashToolCall(tc.Function.Name, args)
+	}
+
+	m.repeated = m.repeated[:0]
+	newInfo := false
+	for _, r := range results {
+		out := hashOutput(r.Content)
+		if h := callHash[r.ToolCallID]; h != "" {
+			if prev, ok := m.callOutputs[h]; ok && prev == out {
+				m.repeated = append(m.repeated, r.Name)
+			}
+			m.callOutputs[h] = out
+		}
+		if r.Error == nil && out != "" && !m.seenOutputs[out] {
+			newInfo = true
+		}
+		if out != "" {
+			m.seenOutputs[out] = true
+		}
+	}
+	if newInfo {
+		m.staleTurns = 0
+	} else {
+		m.staleTurns++
+	}
+}
+
+// nudge returns the guidance to inject before the next LLM call, or "".
+func (m *reflectionMonitor) nudge(turn int, elapsed, timeout time.Duration) string {
+	var lines []string
+	fire := func(kind, line string) {
+		if last, ok := m.lastNudge[kind]; ok && turn-last < reflectionCooldownTurns {
+			return
+		}
+		m.lastNudge[kind] = turn
+		lines = append(lines, line)
+	}
+
+	if len(m.repeated) > 0 {
+		fire("repeat", fmt.Sprintf("You called %s again with the same arguments and got the same result as before. "+
+			"Use the earlier result instead of repeating the call, or change the arguments.", joinUnique(m.repeated)))
+	}
+	if m.staleTurns >= reflectionStallTurns {
+		fire("stall", fmt.Sprintf("The last %d turns produced no new information (only errors or results you already had). "+
+			"Stop and reason: what do you know, what is missing, and which different approach could get it? "+
+			"If nothing can, answer with what you have.", m.staleTurns))
+	}
+	if m.promptGrowth > 0 {
+		fire("burn", fmt.Sprintf("The context grew by ~%dk tokens in one turn. "+
+			"Avoid reading large outputs whole: narrow searches, use offset/limit, or filter with grep.", m.promptGrowth/1000))
+	}
+	if !m.timeWarned && timeout > 0 && float64(elapsed) >= float64(timeout)*reflectionTimeShare {
+		m.timeWarned = true
+		lines = append(lines, fmt.Sprintf("About %.0fs of the run remain (%.0fs elapsed). "+
+			"Prioritize: finish the most important part and deliver a result.",
+			(timeout-elapsed).Seconds(), elapsed.Seconds()))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "[System: " + strings.Join(lines, " ") + "]"
+}
+
+// joinUnique joins names without duplicates, in first-seen order.
+func joinUnique(names []string) string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return strings.Join(out, ", ")
+}
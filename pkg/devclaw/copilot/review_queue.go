@@ -0,0 +1,282 @@
+// Package copilot – review_queue.go holds the replies of customer-facing
+// workspaces for human review before they are sent. With review.enabled on
+// a workspace, the agent's reply to anyone but an owner or admin is queued
+// instead of delivered: the reviewers get the draft with Approve and Reject
+// buttons, and the customer's chat receives the approved text, or the
+// reviewer's edited version of it. Streaming, progress updates, voice
+// replies and tool-attached media are off while a workspace is reviewed, so
+// nothing reaches the customer unseen. Held replies survive restarts.
+package copilot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// ReviewConfig configures pre-send review of a workspace's replies.
+type ReviewConfig struct {
+	// Enabled holds every agent reply to non-admins for review.
+	Enabled bool `yaml:"enabled"`
+
+	// Reviewers are the "channel:chat_id" chats notified of held replies.
+	// Empty = the owner alert contacts that name a channel.
+	Reviewers []string `yaml:"reviewers,omitempty"`
+}
+
+// HeldReply is an agent reply waiting for review.
+type HeldReply struct {
+	ID          string    `json:"id"`
+	WorkspaceID string    `json:"workspace_id"`
+	Channel     string    `json:"channel"`
+	ChatID      string    `json:"chat_id"`
+	ReplyTo     string    `json:"reply_to,omitempty"`
+	From        string    `json:"from,omitempty"`
+	Question    string    `json:"question"`
+	Draft       string    `json:"draft"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReviewQueue stores held replies, persisted to a JSON file.
+type ReviewQueue struct {
+	path   string
+	held   []HeldReply
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// NewReviewQueue opens the queue kept in path ("" = in memory only).
+func NewReviewQueue(path string, logger *slog.Logger) *ReviewQueue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	q := &ReviewQueue{path: path, logger: logger.With("component", "review_queue")}
+	if path == "" {
+		return q
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			q.logger.Warn("failed to read review queue", "path", path, "error", err)
+		}
+		return q
+	}
+	if err := json.Unmarshal(data, &q.held); err != nil {
+		q.logger.Warn("failed to parse review queue", "path", path, "error", err)
+	}
+	return q
+}
+
+// Add queues a reply and returns it with its ID.
+func (q *ReviewQueue) Add(r HeldReply) HeldReply {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for r.ID == "" || q.indexLocked(r.ID) >= 0 {
+		b := make([]byte, 3)
+		rand.Read(b)
+		r.ID = hex.EncodeToString(b)
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	q.held = append(q.held, r)
+	q.saveLocked()
+	return r
+}
+
+// List returns the held replies of a workspace, oldest first ("" = all).
+func (q *ReviewQueue) List(workspaceID string) []HeldReply {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []HeldReply
+	for _, r := range q.held {
+		if workspaceID == "" || r.WorkspaceID == workspaceID {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Take removes a held reply and returns it.
+func (q *ReviewQueue) Take(id string) (HeldReply, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.indexLocked(id)
+	if i < 0 {
+		return HeldReply{}, false
+	}
+	r := q.held[i]
+	q.held = slices.Delete(q.held, i, i+1)
+	q.saveLocked()
+	return r, true
+}
+
+// indexLocked returns the position of a held reply or -1. Callers hold q.mu.
+func (q *ReviewQueue) indexLocked(id string) int {
+	return slices.IndexFunc(q.held, func(r HeldReply) bool { return strings.EqualFold(r.ID, id) })
+}
+
+// saveLocked writes the queue to its file. Callers hold q.mu.
+func (q *ReviewQueue) saveLocked() {
+	if q.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(q.held, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(q.path), 0o755); err == nil {
+			err = writeFileAtomic(q.path, data, 0o600)
+		}
+	}
+	if err != nil {
+		q.logger.Warn("failed to save review queue", "path", q.path, "error", err)
+	}
+}
+
+// reviewsReplies reports whether replies of the workspace to a caller at
+// level are held for review. Owners and admins are the reviewers and get
+// their replies directly.
+func reviewsReplies(ws *Workspace, level AccessLevel) bool {
+	return ws != nil && ws.Review.Enabled && level != AccessOwner && level != AccessAdmin
+}
+
+// holdReply queues the agent's reply to msg and notifies the reviewers.
+func (a *Assistant) holdReply(ws *Workspace, msg *channels.IncomingMessage, question, draft string) {
+	if FormatForChannel(draft, msg.Channel) == "" {
+		return // NO_REPLY and friends need no review
+	}
+	r := a.reviewQueue.Add(HeldReply{
+		WorkspaceID: ws.ID,
+		Channel:     msg.Channel,
+		ChatID:      msg.ChatID,
+		ReplyTo:     msg.ID,
+		From:        firstNonEmpty(msg.FromName, msg.From),
+		Question:    question,
+		Draft:       draft,
+	})
+	a.logger.Info("reply held for review", "id", r.ID, "workspace", ws.ID, "chat", msg.Channel+":"+msg.ChatID)
+	a.notifyReviewers(ws, r)
+}
+
+// notifyReviewers sends a held reply to the workspace's reviewers.
+func (a *Assistant) notifyReviewers(ws *Workspace, r HeldReply) {
+	targets := ws.Review.Reviewers
+	if len(targets) == 0 {
+		for _, c := range a.config.OwnerAlerts.Contacts {
+			if c.Channel != "" && c.To != "" {
+				targets = append(targets, c.Channel+":"+c.To)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		a.logger.Warn("reply held for review but no reviewers are configured; use /review", "id", r.ID, "workspace", ws.ID)
+		return
+	}
+
+	out := &channels.OutgoingMessage{
+		Content: fmt.Sprintf("📝 Reply %s awaits review (workspace %s, %s:%s, %s)\n\n*Customer:* %s\n\n*Draft:*\n%s\n\n/review approve %s · /review edit %s <text> · /review reject %s",
+			r.ID, ws.ID, r.Channel, r.ChatID, r.From, truncate(r.Question, 500), r.Draft, r.ID, r.ID, r.ID),
+		Buttons: []channels.Button{
+			{Label: "Approve", Data: "/review approve " + r.ID, Style: channels.ButtonSuccess},
+			{Label: "Reject", Data: "/review reject " + r.ID, Style: channels.ButtonDanger},
+		},
+	}
+	for _, target := range targets {
+		channel, chatID, ok := strings.Cut(target, ":")
+		if !ok || channel == "" || chatID == "" {
+			a.logger.Warn("invalid reviewer (use channel:chat_id)", "reviewer", target)
+			continue
+		}
+		if err := a.channelMgr.Send(a.ctx, channel, chatID, out); err != nil {
+			a.logger.Warn("failed to notify reviewer", "reviewer", target, "id", r.ID, "error", err)
+		}
+	}
+}
+
+// reviewCommand handles /review: list held replies, or approve, edit or
+// reject one.
+func (a *Assistant) reviewCommand(args []string, msg *channels.IncomingMessage) string {
+	if len(args) == 0 || strings.EqualFold(args[0], "list") {
+		held := a.reviewQueue.List("")
+		if len(held) == 0 {
+			return "No replies awaiting review."
+		}
+		var b strings.Builder
+		b.WriteString("*Replies awaiting review:*\n\n")
+		for _, r := range held {
+			b.WriteString(fmt.Sprintf("• %s — %s, %s:%s, %s ago\n  %s\n",
+				r.ID, r.WorkspaceID, r.Channel, r.ChatID, formatGrantDuration(time.Since(r.CreatedAt)), truncate(r.Draft, 120)))
+		}
+		return b.String()
+	}
+
+	usage := "Usage: /review [list] | approve <id> | edit <id> <text> | reject <id> [reason]"
+	action := strings.ToLower(args[0])
+	if len(args) < 2 || (action != "approve" && action != "edit" && action != "reject") {
+		return usage
+	}
+	id := args[1]
+	if action == "edit" && len(args) < 3 {
+		return "Usage: /review edit <id> <text>"
+	}
+	r, ok := a.reviewQueue.Take(id)
+	if !ok {
+		return fmt.Sprintf("No held reply %q (already reviewed?).", id)
+	}
+
+	switch action {
+	case "approve":
+		a.sendText(r.Channel, r.ChatID, r.ReplyTo, r.Draft)
+		a.logger.Info("held reply approved", "id", r.ID, "by", msg.From)
+		return fmt.Sprintf("✅ Reply %s sent to %s:%s.", r.ID, r.Channel, r.ChatID)
+
+	case "edit":
+		// Keep the reviewer's line breaks: skip "/review edit <id>" and take
+		// the rest of the message verbatim.
+		text := strings.TrimSpace(msg.Content)
+		for range 3 {
+			text = strings.TrimLeftFunc(text, unicode.IsSpace)
+			if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+				text = text[i:]
+			} else {
+				text = ""
+			}
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			text = strings.Join(args[2:], " ")
+		}
+		a.sendText(r.Channel, r.ChatID, r.ReplyTo, text)
+		a.reviseHeldReply(r, text)
+		a.logger.Info("held reply edited and sent", "id", r.ID, "by", msg.From)
+		return fmt.Sprintf("✏️ Edited reply %s sent to %s:%s.", r.ID, r.Channel, r.ChatID)
+
+	default: // reject
+		reason := strings.Join(args[2:], " ")
+		note := "(reply not sent: rejected by a reviewer)"
+		if reason != "" {
+			note = "(reply not sent: rejected by a reviewer: " + reason + ")"
+		}
+		a.reviseHeldReply(r, note)
+		a.logger.Info("held reply rejected", "id", r.ID, "by", msg.From, "reason", reason)
+		return fmt.Sprintf("❌ Reply %s rejected; the customer received nothing.", r.ID)
+	}
+}
+
+// reviseHeldReply records in the session what the customer actually got,
+// so later turns build on the sent text rather than the draft.
+func (a *Assistant) reviseHeldReply(r HeldReply, sent string) {
+	session := a.workspaceMgr.SessionIn(r.WorkspaceID, r.Channel, r.ChatID)
+	if !session.ReviseResponse(r.Draft, sent) {
+		a.logger.Debug("held reply not found in session history", "id", r.ID)
+	}
+}
@@ -0,0 +1,76 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+func TestReviewQueue_HoldEditReject(t *testing.T) {
+	t.Parallel()
+
+	ch := &fakeEditChannel{messages: map[string]string{}}
+	mgr := channels.NewManager(slog.Default())
+	if err := mgr.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wm := NewWorkspaceManager(DefaultConfig(), WorkspaceConfig{
+		DefaultWorkspace: "shop",
+		Workspaces:       []Workspace{{ID: "shop", Active: true, Review: ReviewConfig{Enabled: true, Reviewers: []string{"fake:owner"}}}},
+	}, logger)
+	path := filepath.Join(t.TempDir(), "review_queue.json")
+	a := &Assistant{config: DefaultConfig(), channelMgr: mgr, workspaceMgr: wm, reviewQueue: NewReviewQueue(path, logger), logger: logger, ctx: context.Background()}
+	ws, _ := wm.Get("shop")
+
+	if !reviewsReplies(ws, AccessUser) || reviewsReplies(ws, AccessAdmin) {
+		t.Fatal("replies to customers are reviewed, replies to admins are not")
+	}
+
+	customer := &channels.IncomingMessage{ID: "in1", Channel: "fake", ChatID: "customer", From: "customer"}
+	session := wm.SessionIn("shop", "fake", "customer")
+	session.AddMessage("Do you ship to Lisbon?", "Yes, in 3 days.")
+	a.holdReply(ws, customer, "Do you ship to Lisbon?", "Yes, in 3 days.")
+
+	held := NewReviewQueue(path, logger).List("shop") // survives a restart
+	if len(held) != 1 || held[0].Draft != "Yes, in 3 days." {
+		t.Fatalf("held = %+v", held)
+	}
+	if got := ch.texts(); len(got) != 1 || !strings.Contains(got[0], "awaits review") || !strings.Contains(got[0], "Yes, in 3 days.") {
+		t.Fatalf("reviewer should get the draft and nothing else should be sent, got %q", got)
+	}
+
+	id := held[0].ID
+	owner := &channels.IncomingMessage{Channel: "fake", ChatID: "owner", Content: "/review edit " + id + " Yes, in 2-3 business days.\nFree over 50 EUR."}
+	if reply := a.reviewCommand(strings.Fields(owner.Content)[1:], owner); !strings.Contains(reply, "Edited reply") {
+		t.Fatalf("edit: %s", reply)
+	}
+	if got := ch.texts(); got[len(got)-1] != "Yes, in 2-3 business days.\nFree over 50 EUR." {
+		t.Errorf("customer should get the edited text verbatim, got %q", got[len(got)-1])
+	}
+	if h := session.RecentHistory(1); h[0].AssistantResponse != "Yes, in 2-3 business days.\nFree over 50 EUR." {
+		t.Errorf("session should record what was sent, got %q", h[0].AssistantResponse)
+	}
+
+	session.AddMessage("Discount?", "Sure, 90% off!")
+	a.holdReply(ws, customer, "Discount?", "Sure, 90% off!")
+	id = a.reviewQueue.List("")[0].ID
+	sent := len(ch.texts())
+	if reply := a.reviewCommand([]string{"reject", id, "no discounts"}, owner); !strings.Contains(reply, "rejected") {
+		t.Fatalf("reject: %s", reply)
+	}
+	if len(ch.texts()) != sent || len(a.reviewQueue.List("")) != 0 {
+		t.Error("a rejected reply must not be sent and must leave the queue")
+	}
+	if h := session.RecentHistory(1); !strings.Contains(h[0].AssistantResponse, "no discounts") {
+		t.Errorf("session should note the rejection, got %q", h[0].AssistantResponse)
+	}
+	if reply := a.reviewCommand([]string{"approve", id}, owner); !strings.Contains(reply, "No held reply") {
+		t.Errorf("a reviewed reply cannot be approved again, got %q", reply)
+	}
+}
@@ -0,0 +1,353 @@
+// Package copilot – run_watchdog.go detects agent runs that stopped making
+// progress long before the run timeout.
+//
+// Every run reports progress to a runProgress: an LLM call starting or
+// returning, a batch of tools completing. When a run goes longer than
+// stall_seconds without any of those, the watchdog writes a diagnostic
+// snapshot (turn state plus the stacks of all goroutines), alerts the owner
+// and — with auto_cancel — stops the run, telling the user how to resume.
+package copilot
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunWatchdogConfig configures stall detection for agent runs.
+type RunWatchdogConfig struct {
+	// Enabled turns the watchdog on (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// StallSeconds is how long a run may go without an LLM call or tool
+	// completion before it counts as stalled (default: 240).
+	StallSeconds int `yaml:"stall_seconds"`
+
+	// AutoCancel stops stalled runs instead of only reporting them.
+	AutoCancel bool `yaml:"auto_cancel"`
+
+	// Dir is where diagnostic snapshots are written (default: ./data/watchdog).
+	Dir string `yaml:"dir"`
+}
+
+// DefaultRunWatchdogConfig returns defaults for the run watchdog.
+func DefaultRunWatchdogConfig() RunWatchdogConfig {
+	return RunWatchdogConfig{
+		Enabled:      true,
+		StallSeconds: 240,
+		Dir:          "./data/watchdog",
+	}
+}
+
+// stallResumeHint is returned to the user when the watchdog cancels a run.
+const stallResumeHint = "⏱️ I stopped because this run made no progress for %s " +
+	"(the owner was notified). Send \"continue\" to resume from where I was, " +
+	"or rephrase the request."
+
+// runProgress is the live turn state of one agent run. Methods are safe on
+// a nil receiver so AgentRun can report unconditionally.
+type runProgress struct {
+	runKey    string
+	workspace string
+	sessionID string
+	channel   string
+	chatID    string
+	request   string
+	startedAt time.Time
+
+	mu           sync.Mutex
+	lastProgress time.Time
+	turn         int
+	phase        string // "llm", "tools" or "" between steps
+	phaseSince   time.Time
+	tools        []string
+	llmCalls     int
+	toolBatches  int
+	reported     bool // stall already reported; re-armed by progress
+	cancelled    bool // cancelled by the watchdog
+
+	// completed summarizes the latest finished tool calls, kept so a
+	// cancelled run's work can be handed to the next one (salvage).
+	completed []string
+}
+
+// maxSalvagedSteps is how many finished tool calls salvage reports.
+const maxSalvagedSteps = 15
+
+// llmStart records the start of an LLM call for the given turn.
+func (p *runProgress) llmStart(turn int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.turn = turn
+	p.llmCalls++
+	p.step("llm")
+}
+
+// llmDone records the end of an LLM call.
+func (p *runProgress) llmDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step("")
+}
+
+// toolsStart records the tools about to run. Starting tools is not progress
+// by itself: a hung tool must still trip the watchdog.
+func (p *runProgress) toolsStart(names []string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase = "tools"
+	p.phaseSince = time.Now()
+	p.tools = append([]string(nil), names...)
+}
+
+// toolsDone records the completion of a tool batch.
+func (p *runProgress) toolsDone(results []ToolResult) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.toolBatches++
+	for _, r := range results {
+		status := "ok"
+		if r.Error != nil {
+			status = "failed"
+		}
+		p.completed = append(p.completed, fmt.Sprintf("%s (%s): %s", r.Name, status,
+			truncateStr(strings.Join(strings.Fields(r.Content), " "), 200)))
+	}
+	if n := len(p.completed); n > maxSalvagedSteps {
+		p.completed = p.completed[n-maxSalvagedSteps:]
+	}
+	p.step("")
+}
+
+// salvage describes the request and the finished tool calls of the run,
+// for the run that replaces it ("" when nothing ran yet).
+func (p *runProgress) salvage() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.completed) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Previous request: %s\n", truncateStr(p.request, 300))
+	b.WriteString("Tool calls completed (oldest first):\n")
+	for _, c := range p.completed {
+		b.WriteString("- " + c + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// step marks progress and moves to phase. Caller must hold p.mu.
+func (p *runProgress) step(phase string) {
+	now := time.Now()
+	p.lastProgress = now
+	p.phase = phase
+	p.phaseSince = now
+	p.tools = nil
+	p.reported = false
+}
+
+// stalledFor returns how long the run has gone without progress, and
+// whether that crosses limit and was not reported yet. A true result marks
+// the stall as reported.
+func (p *runProgress) stalledFor(limit time.Duration) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	idle := time.Since(p.lastProgress)
+	if idle < limit || p.reported {
+		return idle, false
+	}
+	p.reported = true
+	return idle, true
+}
+
+// wasCancelled reports whether the watchdog cancelled the run.
+func (p *runProgress) wasCancelled() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cancelled
+}
+
+// describe renders the turn state for logs, alerts and snapshots.
+func (p *runProgress) describe() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	phase := p.phase
+	switch phase {
+	case "llm":
+		phase = "waiting for the LLM"
+	case "tools":
+		phase = "running tools: " + strings.Join(p.tools, ", ")
+	default:
+		phase = "between steps"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Workspace: %s\nSession: %s (%s:%s)\n", p.workspace, p.sessionID, p.channel, p.chatID)
+	fmt.Fprintf(&b, "Request: %s\n", truncateStr(p.request, 200))
+	fmt.Fprintf(&b, "Running for %s, turn %d (%d LLM calls, %d tool batches)\n",
+		now.Sub(p.startedAt).Round(time.Second), p.turn, p.llmCalls, p.toolBatches)
+	fmt.Fprintf(&b, "Stuck %s for %s; last progress %s ago",
+		phase, now.Sub(p.phaseSince).Round(time.Second), now.Sub(p.lastProgress).Round(time.Second))
+	return b.String()
+}
+
+// trackRun registers the progress of a run for the watchdog.
+func (a *Assistant) trackRun(runKey, workspaceID string, session *Session, request string) *runProgress {
+	now := time.Now()
+	p := &runProgress{
+		runKey:       runKey,
+		workspace:    workspaceID,
+		sessionID:    session.ID,
+		channel:      session.Channel,
+		chatID:       session.ChatID,
+		request:      request,
+		startedAt:    now,
+		lastProgress: now,
+		phaseSince:   now,
+	}
+	a.runProgressMu.Lock()
+	if a.runProgress == nil {
+		a.runProgress = make(map[string]*runProgress)
+	}
+	a.runProgress[runKey] = p
+	a.runProgressMu.Unlock()
+	return p
+}
+
+// untrackRun removes a finished run from the watchdog.
+func (a *Assistant) untrackRun(runKey string, p *runProgress) {
+	a.runProgressMu.Lock()
+	if a.runProgress[runKey] == p {
+		delete(a.runProgress, runKey)
+	}
+	a.runProgressMu.Unlock()
+}
+
+// runStallWatchdog checks in-flight runs for stalls until the assistant stops.
+func (a *Assistant) runStallWatchdog() {
+	cfg := a.config.Agent.Watchdog
+	limit := time.Duration(cfg.StallSeconds) * time.Second
+	if limit <= 0 {
+		limit = time.Duration(DefaultRunWatchdogConfig().StallSeconds) * time.Second
+	}
+	interval := limit / 4
+	if interval > 30*time.Second {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			a.checkStalledRuns(limit, cfg)
+		}
+	}
+}
+
+// checkStalledRuns reports (and optionally cancels) runs stalled past limit.
+func (a *Assistant) checkStalledRuns(limit time.Duration, cfg RunWatchdogConfig) {
+	a.runProgressMu.Lock()
+	runs := make([]*runProgress, 0, len(a.runProgress))
+	for _, p := range a.runProgress {
+		runs = append(runs, p)
+	}
+	a.runProgressMu.Unlock()
+
+	for _, p := range runs {
+		idle, stalled := p.stalledFor(limit)
+		if !stalled {
+			continue
+		}
+		state := p.describe()
+		path, err := writeStallSnapshot(cfg.Dir, p, state)
+		if err != nil {
+			a.logger.Warn("watchdog: failed to write stall snapshot", "run", p.runKey, "error", err)
+		}
+		a.logger.Warn("watchdog: agent run made no progress",
+			"run", p.runKey, "idle", idle.Round(time.Second), "snapshot", path)
+
+		body := state
+		if path != "" {
+			body += "\n\nGoroutine dump: " + path
+		}
+		if cfg.AutoCancel {
+			body += "\n\nThe run was cancelled."
+			a.activeRunsMu.Lock()
+			cancel := a.activeRuns[p.runKey]
+			a.activeRunsMu.Unlock()
+			if cancel != nil {
+				p.mu.Lock()
+				p.cancelled = true
+				p.mu.Unlock()
+				a.toolExecutor.Abort()
+				cancel()
+				a.toolExecutor.ResetAbort()
+			}
+		} else {
+			body += "\n\nUse /stop in that chat to cancel it."
+		}
+		a.AlertOwner(OwnerAlertStall,
+			fmt.Sprintf("Agent run stalled for %s", idle.Round(time.Second)), body)
+	}
+}
+
+// stallResumeMessage is the reply for a run cancelled by the watchdog.
+func (a *Assistant) stallResumeMessage() string {
+	secs := a.config.Agent.Watchdog.StallSeconds
+	if secs <= 0 {
+		secs = DefaultRunWatchdogConfig().StallSeconds
+	}
+	return fmt.Sprintf(stallResumeHint, time.Duration(secs)*time.Second)
+}
+
+// writeStallSnapshot writes the turn state and all goroutine stacks to dir
+// and returns the file path.
+func writeStallSnapshot(dir string, p *runProgress, state string) (string, error) {
+	if dir == "" {
+		dir = DefaultRunWatchdogConfig().Dir
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "DevClaw stalled run snapshot — %s\n\n%s\n\n", time.Now().Format(time.RFC3339), state)
+	buf.WriteString("── Goroutines ──\n\n")
+	if prof := pprof.Lookup("goroutine"); prof != nil {
+		_ = prof.WriteTo(&buf, 2)
+	}
+
+	name := fmt.Sprintf("stall-%s-%s.txt", time.Now().Format("20060102-150405"), sanitizeSessionID(p.sessionID))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,175 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunProgress_StallDetection(t *testing.T) {
+	t.Parallel()
+	p := &runProgress{lastProgress: time.Now().Add(-time.Minute)}
+
+	if _, stalled := p.stalledFor(2 * time.Minute); stalled {
+		t.Error("run within the limit reported as stalled")
+	}
+	if idle, stalled := p.stalledFor(30 * time.Second); !stalled || idle < time.Minute {
+		t.Errorf("stalledFor = %s, %v; want a stall", idle, stalled)
+	}
+	if _, stalled := p.stalledFor(30 * time.Second); stalled {
+		t.Error("the same stall was reported twice")
+	}
+
+	// Starting tools is not progress: a hung tool still counts as a stall.
+	p.mu.Lock()
+	p.reported = false
+	p.mu.Unlock()
+	p.toolsStart([]string{"bash"})
+	if _, stalled := p.stalledFor(30 * time.Second); !stalled {
+		t.Error("starting tools reset the stall timer")
+	}
+	if !strings.Contains(p.describe(), "running tools: bash") {
+		t.Errorf("describe = %q", p.describe())
+	}
+
+	// Finishing tools is progress and re-arms reporting.
+	p.toolsDone([]ToolResult{{Name: "bash", Content: "ok"}})
+	p.mu.Lock()
+	p.lastProgress = time.Now().Add(-time.Minute)
+	p.mu.Unlock()
+	if _, stalled := p.stalledFor(30 * time.Second); !stalled {
+		t.Error("a new stall after progress was not reported")
+	}
+
+	var none *runProgress
+	none.llmStart(1)
+	none.llmDone()
+	none.toolsStart([]string{"bash"})
+	none.toolsDone(nil)
+	if none.wasCancelled() || none.salvage() != "" {
+		t.Error("nil progress should be inert")
+	}
+}
+
+func TestRunProgress_Salvage(t *testing.T) {
+	t.Parallel()
+	p := &runProgress{request: "migrate the billing tables"}
+	if got := p.salvage(); got != "" {
+		t.Errorf("salvage before any tool = %q", got)
+	}
+
+	p.toolsDone([]ToolResult{
+		{Name: "read_file", Content: "schema:\n  billing   v2"},
+		{Name: "bash", Error: errors.New("exit 1"), Content: "permission denied"},
+	})
+	for i := 0; i < maxSalvagedSteps; i++ {
+		p.toolsDone([]ToolResult{{Name: fmt.Sprintf("step%d", i), Content: "done"}})
+	}
+
+	got := p.salvage()
+	if !strings.HasPrefix(got, "Previous request: migrate the billing tables\nTool calls completed") {
+		t.Errorf("salvage = %q", got)
+	}
+	if n := strings.Count(got, "\n- "); n != maxSalvagedSteps {
+		t.Errorf("salvage lists %d steps, want %d", n, maxSalvagedSteps)
+	}
+	if strings.Contains(got, "read_file") || !strings.Contains(got, "- step14 (ok): done") {
+		t.Errorf("salvage should keep the latest steps:\n%s", got)
+	}
+
+	p = &runProgress{request: "x"}
+	p.toolsDone([]ToolResult{{Name: "bash", Error: errors.New("exit 1"), Content: "permission\n  denied"}})
+	if got := p.salvage(); !strings.HasSuffix(got, "- bash (failed): permission denied") {
+		t.Errorf("failed step = %q", got)
+	}
+}
+
+func TestCheckStalledRuns(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		autoCancel bool
+		wantBody   string
+	}{
+		{false, "Use /stop in that chat to cancel it."},
+		{true, "The run was cancelled."},
+	}
+	for _, tc := range cases {
+		alerts := make(chan string, 4)
+		a := &Assistant{
+			config:       DefaultConfig(),
+			logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+			toolExecutor: NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			activeRuns:   make(map[string]context.CancelFunc),
+			ownerAlerter: testOwnerAlerter(OwnerAlertsConfig{
+				Enabled:  true,
+				Contacts: []OwnerContact{{Channel: "telegram", To: "42"}},
+			}, func(_ context.Context, _, _, content string) error {
+				alerts <- content
+				return nil
+			}),
+		}
+		runCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		const key = "default:telegram:42"
+		a.activeRuns[key] = cancel
+
+		p := a.trackRun(key, "default", &Session{ID: "telegram:42", Channel: "telegram", ChatID: "42"}, "deploy the app")
+		p.llmStart(3)
+		p.mu.Lock()
+		p.lastProgress = time.Now().Add(-5 * time.Minute)
+		p.mu.Unlock()
+
+		cfg := RunWatchdogConfig{Enabled: true, StallSeconds: 60, AutoCancel: tc.autoCancel, Dir: t.TempDir()}
+		a.checkStalledRuns(time.Minute, cfg)
+		a.checkStalledRuns(time.Minute, cfg)
+
+		var alert string
+		select {
+		case alert = <-alerts:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("auto_cancel=%v: no owner alert", tc.autoCancel)
+		}
+		for _, want := range []string{"Agent run stalled for 5m0s", "Request: deploy the app", "waiting for the LLM", "Goroutine dump: ", tc.wantBody} {
+			if !strings.Contains(alert, want) {
+				t.Errorf("auto_cancel=%v: alert lacks %q:\n%s", tc.autoCancel, want, alert)
+			}
+		}
+		select {
+		case extra := <-alerts:
+			t.Errorf("auto_cancel=%v: stall reported twice:\n%s", tc.autoCancel, extra)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		entries, err := os.ReadDir(cfg.Dir)
+		if err != nil || len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "stall-") {
+			t.Fatalf("auto_cancel=%v: snapshots = %v, %v", tc.autoCancel, entries, err)
+		}
+		if out, _ := os.ReadFile(filepath.Join(cfg.Dir, entries[0].Name())); !strings.Contains(string(out), "── Goroutines ──") {
+			t.Errorf("auto_cancel=%v: snapshot lacks goroutine stacks", tc.autoCancel)
+		}
+
+		if cancelled := runCtx.Err() != nil; cancelled != tc.autoCancel || p.wasCancelled() != tc.autoCancel {
+			t.Errorf("auto_cancel=%v: run cancelled = %v", tc.autoCancel, cancelled)
+		}
+		if a.toolExecutor.IsAborted() {
+			t.Errorf("auto_cancel=%v: tool abort was not reset", tc.autoCancel)
+		}
+
+		a.untrackRun(key, p)
+		if len(a.runProgress) != 0 {
+			t.Errorf("auto_cancel=%v: run still tracked", tc.autoCancel)
+		}
+	}
+
+	a := &Assistant{config: DefaultConfig()}
+	if got := a.stallResumeMessage(); !strings.Contains(got, "no progress for 4m0s") {
+		t.Errorf("stallResumeMessage = %q", got)
+	}
+}
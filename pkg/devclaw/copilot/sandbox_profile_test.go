@@ -0,0 +1,52 @@
+package copilot
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+func TestToolExecutorAppliesSandboxProfile(t *testing.T) {
+	t.Parallel()
+	cfg := sandbox.DefaultConfig()
+	cfg.DefaultIsolation = sandbox.IsolationNone
+	cfg.TempDir = t.TempDir()
+	cfg.Profiles = map[string]sandbox.Profile{
+		"short": {Isolation: sandbox.IsolationNone, Timeout: 300 * time.Millisecond},
+		"jail":  {Isolation: sandbox.IsolationNsjail, MaxMemoryMB: 128},
+	}
+	cfg.Tools = map[string]string{"slow": "short", "mcp_*": "jail"}
+	runner, err := sandbox.NewRunner(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+
+	exec := NewToolExecutor(slog.New(slog.DiscardHandler))
+	var result *sandbox.ExecResult
+	exec.Register(MakeToolDefinition("slow", "Sleep", map[string]any{"type": "object"}),
+		func(ctx context.Context, _ map[string]any) (any, error) {
+			result, err = runner.RunShell(ctx, "-c", []string{"sleep 5"}, "")
+			return "done", err
+		})
+
+	exec.Execute(ContextWithCaller(context.Background(), AccessOwner, "owner@x"),
+		[]ToolCall{{ID: "1", Function: FunctionCall{Name: "slow", Arguments: "{}"}}})
+	if err != nil || result == nil || !result.Killed || result.KillReason != "timeout" {
+		t.Errorf("profile timeout not applied: result = %+v, err = %v", result, err)
+	}
+
+	if got := cfg.ProfileForTool("mcp_github_search"); got != "jail" {
+		t.Errorf("ProfileForTool(mcp_github_search) = %q", got)
+	}
+	if got := cfg.ProfileForTool("read_file"); got != "" {
+		t.Errorf("ProfileForTool(read_file) = %q", got)
+	}
+
+	cfg.Tools["exec"] = "missing"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate accepted a tool mapped to an unknown profile")
+	}
+}
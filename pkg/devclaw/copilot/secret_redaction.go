@@ -0,0 +1,203 @@
+// Package copilot – secret_redaction.go wires the secret pass of the output
+// guardrail (security/secrets.go) into the two places text leaves the
+// process: tool results on their way to the LLM and replies on their way
+// to a chat (and into the session history the next LLM call reads), both
+// when streamed block by block (block_streamer.go) and when sent whole. Every
+// pass that replaced something is logged and recorded in the audit log as
+// a secret_redaction entry with counts per kind, never the values.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/security"
+)
+
+// SetSecretRedactor sets the secret redactor applied to tool results.
+func (e *ToolExecutor) SetSecretRedactor(r *security.SecretRedactor) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.secretRedactor = r
+}
+
+// redactOutput applies the vault redactor and the secret pass to a tool
+// result bound for the model.
+func (e *ToolExecutor) redactOutput(name, callerJID string, level AccessLevel, s string) string {
+	e.mu.RLock()
+	fn, secrets, guard := e.outputRedactor, e.secretRedactor, e.guard
+	e.mu.RUnlock()
+	if fn != nil {
+		s = fn(s)
+	}
+	s, counts := secrets.Redact(s)
+	if len(counts) > 0 {
+		e.logger.Warn("secrets redacted from tool result", "tool", name, "kinds", formatKindCounts(counts))
+		auditSecretRedaction(guard, "tool:"+name, callerJID, level, counts)
+	}
+	return s
+}
+
+// redactText applies the output redactors to s without logging, for the
+// pieces of a result already accounted for by redactOutput.
+func (e *ToolExecutor) redactText(s string) string {
+	e.mu.RLock()
+	fn, secrets := e.outputRedactor, e.secretRedactor
+	e.mu.RUnlock()
+	if fn != nil {
+		s = fn(s)
+	}
+	s, _ = secrets.Redact(s)
+	return s
+}
+
+// redactBlocks redacts the text fields of each block, keeping its type. A
+// JSON block that no longer parses after redaction becomes a text block.
+func (e *ToolExecutor) redactBlocks(blocks []ToolBlock) []ToolBlock {
+	out := make([]ToolBlock, len(blocks))
+	for i, b := range blocks {
+		b.Text = e.redactText(b.Text)
+		b.Title = e.redactText(b.Title)
+		b.URL = e.redactText(b.URL)
+		if len(b.JSON) > 0 {
+			if s := e.redactText(string(b.JSON)); s != string(b.JSON) {
+				if json.Valid([]byte(s)) {
+					b.JSON = json.RawMessage(s)
+				} else {
+					b.Type, b.Text, b.JSON = ToolBlockText, s, nil
+				}
+			}
+		}
+		if len(b.Rows) > 0 {
+			rows := make([][]string, len(b.Rows))
+			for r, row := range b.Rows {
+				rows[r] = make([]string, len(row))
+				for c, cell := range row {
+					rows[r][c] = e.redactText(cell)
+				}
+			}
+			b.Rows = rows
+		}
+		out[i] = b
+	}
+	return out
+}
+
+// redactReplySecrets applies the output guardrail's secret pass to a reply.
+func (a *Assistant) redactReplySecrets(ctx context.Context, reply string) string {
+	reply, counts := a.outputGuard.RedactSecrets(reply)
+	if len(counts) > 0 {
+		a.logger.Warn("secrets redacted from reply", "kinds", formatKindCounts(counts))
+		auditSecretRedaction(a.toolExecutor.Guard(), "reply", CallerJIDFromContext(ctx), CallerLevelFromContext(ctx), counts)
+	}
+	return reply
+}
+
+// auditSecretRedaction records a redaction event in the audit log.
+func auditSecretRedaction(guard *ToolGuard, source, callerJID string, level AccessLevel, counts map[string]int) {
+	if guard == nil {
+		return
+	}
+	args := map[string]any{"source": source}
+	total := 0
+	for kind, n := range counts {
+		args[kind] = n
+		total += n
+	}
+	guard.AuditLog("secret_redaction", callerJID, level, args, true,
+		fmt.Sprintf("%d secrets redacted (%s)", total, formatKindCounts(counts)))
+}
+
+// formatKindCounts renders counts per kind as "api_key=1, password=2".
+func formatKindCounts(counts map[string]int) string {
+	parts := make([]string, 0, len(counts))
+	for kind, n := range counts {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, n))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// secretSafeCut moves a cut in streamed text back to the start of a token
+// no secret can straddle: whitespace precedes it, the token has begun, it
+// does not continue an assignment ("password: x") or a "Bearer x" header,
+// and no private key block is open before it. Returns 0 when there is none.
+func secretSafeCut(text string, cut int) int {
+	if i := strings.LastIndex(text, "-----BEGIN "); i >= 0 && !strings.Contains(text[i:], "-----END ") {
+		cut = min(cut, i)
+	}
+	for ; cut > 0; cut-- {
+		if cut >= len(text) || !isASCIISpace(text[cut-1]) || isASCIISpace(text[cut]) {
+			continue
+		}
+		if text[cut] == ':' || text[cut] == '=' {
+			continue
+		}
+		before := strings.TrimRight(text[:cut], " \t\r\n")
+		if before == "" {
+			return cut
+		}
+		if strings.ContainsRune(":=\"'", rune(before[len(before)-1])) {
+			continue
+		}
+		if strings.EqualFold(before[max(0, len(before)-len("bearer")):], "bearer") {
+			continue
+		}
+		return cut
+	}
+	return 0
+}
+
+// isASCIISpace reports whether c is a space, tab or line break.
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// redactedPlaceholderRe matches the placeholders redaction leaves in tool
+// results ([REDACTED_PASSWORD], [vault:github_token]).
+var redactedPlaceholderRe = regexp.MustCompile(`\[(?:REDACTED_[A-Z0-9_]+|vault:[^\]\s]+)\]`)
+
+// checkRedactedWrite refuses file writes whose new text carries a
+// redaction placeholder. The model saw the placeholder where the file holds
+// the real secret (read_file results are redacted too), so writing it back
+// would destroy the secret on disk. A placeholder also in old_text is
+// literal file text and may stay.
+func (e *ToolExecutor) checkRedactedWrite(tool string, args map[string]any) error {
+	e.mu.RLock()
+	active := e.secretRedactor != nil || e.outputRedactor != nil
+	e.mu.RUnlock()
+	if !active {
+		return nil
+	}
+	check := func(m map[string]any) error {
+		oldText, _ := m["old_text"].(string)
+		for _, key := range []string{"content", "new_text"} {
+			s, _ := m[key].(string)
+			for _, p := range redactedPlaceholderRe.FindAllString(s, -1) {
+				if !strings.Contains(oldText, p) {
+					return fmt.Errorf("%s contains %s, a placeholder for a secret hidden from you; "+
+						"edit around that line instead of rewriting it, or ask the user to change the secret", key, p)
+				}
+			}
+		}
+		return nil
+	}
+	switch tool {
+	case "write_file", "edit_file":
+		return check(args)
+	case "apply_changes":
+		changes, _ := args["changes"].([]any)
+		for i, c := range changes {
+			if m, ok := c.(map[string]any); ok {
+				if err := check(m); err != nil {
+					return fmt.Errorf("changes[%d]: %w", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -88,7 +88,10 @@ func detectPromptInjection(input string) bool {
 // --- Output Guardrails ---
 
 // OutputGuardrail valida respostas geradas pelo LLM antes do envio.
-type OutputGuardrail struct{}
+type OutputGuardrail struct {
+	// secrets remove credenciais do output (nil = desativado).
+	secrets *SecretRedactor
+}
 
 // NewOutputGuardrail cria um novo guardrail de output.
 func NewOutputGuardrail() *OutputGuardrail {
@@ -113,6 +116,19 @@ func (g *OutputGuardrail) Validate(output string) error {
 	return nil
 }
 
+// SetSecretRedactor configura a remoção de credenciais do output
+// (ver secrets.go). nil desativa.
+func (g *OutputGuardrail) SetSecretRedactor(r *SecretRedactor) {
+	g.secrets = r
+}
+
+// RedactSecrets substitui credenciais (API keys, bearer tokens, chaves
+// privadas, senhas) no output por placeholders e retorna quantas de cada
+// tipo foram removidas.
+func (g *OutputGuardrail) RedactSecrets(output string) (string, map[string]int) {
+	return g.secrets.Redact(output)
+}
+
 // detectSystemPromptLeak verifica se o output contém trechos do system prompt.
 func detectSystemPromptLeak(output string) bool {
 	lower := strings.ToLower(output)
@@ -0,0 +1,182 @@
+// Package security – secrets.go detects credentials in text bound for the
+// LLM or a chat: API keys with well-known prefixes, bearer tokens, JWTs,
+// private keys and password-like assignments (password=..., api_key: ...,
+// credentials in URLs). Each one is replaced with a placeholder such as
+// [REDACTED_API_KEY]. Unlike PII redaction the values are never restored.
+package security
+
+import (
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SecretRedactionConfig configures the secret redaction pass.
+type SecretRedactionConfig struct {
+	// Enabled turns the pass on for tool results and replies (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Allow lists values that are never redacted (e.g. a public demo key).
+	Allow []string `yaml:"allow"`
+
+	// Patterns are extra regular expressions, keyed by kind
+	// (e.g. internal_token: "itk_[a-z0-9]{32}" → [REDACTED_INTERNAL_TOKEN]).
+	Patterns map[string]string `yaml:"patterns"`
+}
+
+// secretRule detects one kind of secret. With group > 0 only that submatch
+// is replaced (the key name or "Bearer" stays). valid, when set, receives
+// the whole match and the value to replace.
+type secretRule struct {
+	kind  string
+	re    *regexp.Regexp
+	group int
+	valid func(match, value string) bool
+}
+
+// builtinSecretRules run in order: whole private keys first, then tokens
+// with a known shape, then the generic assignments.
+var builtinSecretRules = []secretRule{
+	{kind: "private_key", re: regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----.*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{kind: "api_key", re: regexp.MustCompile(`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,}|xox[abposr]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|[sr]k_live_[0-9A-Za-z]{20,})`)},
+	{kind: "token", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{kind: "bearer_token", re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`), group: 1},
+	{kind: "password", re: regexp.MustCompile(`(?i)(?:\b|_)(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?key|token)["']?\s*[:=]\s*["']?([^\s"',;]{6,})`), group: 1, valid: notCode},
+	{kind: "password", re: regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:([^/\s@]+)@`), group: 1},
+}
+
+// SecretRedactor replaces secrets in text with placeholders.
+type SecretRedactor struct {
+	rules []secretRule
+	allow map[string]bool
+}
+
+// NewSecretRedactor builds a redactor for cfg, or returns nil when the pass
+// is disabled. Invalid custom patterns are logged and skipped.
+func NewSecretRedactor(cfg SecretRedactionConfig, logger *slog.Logger) *SecretRedactor {
+	if !cfg.Enabled {
+		return nil
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &SecretRedactor{
+		rules: append([]secretRule(nil), builtinSecretRules...),
+		allow: make(map[string]bool, len(cfg.Allow)),
+	}
+	for _, v := range cfg.Allow {
+		r.allow[v] = true
+	}
+	kinds := make([]string, 0, len(cfg.Patterns))
+	for kind := range cfg.Patterns {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		re, err := regexp.Compile(cfg.Patterns[kind])
+		if err != nil {
+			logger.Warn("invalid secret pattern skipped", "kind", kind, "error", err)
+			continue
+		}
+		r.rules = append(r.rules, secretRule{kind: kind, re: re})
+	}
+	return r
+}
+
+// Redact replaces the secrets in text and returns how many of each kind it
+// replaced. A nil redactor returns text unchanged.
+func (r *SecretRedactor) Redact(text string) (string, map[string]int) {
+	if r == nil || text == "" {
+		return text, nil
+	}
+	var counts map[string]int
+	for _, rule := range r.rules {
+		var n int
+		if text, n = r.apply(rule, text); n > 0 {
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[rule.kind] += n
+		}
+	}
+	return text, counts
+}
+
+// apply replaces the matches of one rule.
+func (r *SecretRedactor) apply(rule secretRule, text string) (string, int) {
+	matches := rule.re.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text, 0
+	}
+	var b strings.Builder
+	last, n := 0, 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if rule.group > 0 {
+			start, end = m[2*rule.group], m[2*rule.group+1]
+		}
+		if start < 0 || start < last {
+			continue
+		}
+		value := text[start:end]
+		if r.allow[value] || !secretValue(value) || (rule.valid != nil && !rule.valid(text[m[0]:m[1]], value)) {
+			continue
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(secretPlaceholder(rule.kind))
+		last = end
+		n++
+	}
+	b.WriteString(text[last:])
+	return b.String(), n
+}
+
+// secretValue rejects matches that are not secrets themselves: existing
+// placeholders ([vault:x], [REDACTED_...]), ${VAR} references and masks
+// such as ******.
+func secretValue(v string) bool {
+	if strings.HasPrefix(v, "[") || strings.HasPrefix(v, "$") || strings.HasPrefix(v, "<") {
+		return false
+	}
+	return strings.Trim(v, "*x•.") != ""
+}
+
+// codeIdentRe matches identifiers and field paths (cfg.Password,
+// db_password, userPassword) assigned in source code.
+var codeIdentRe = regexp.MustCompile(`^[A-Za-z_]\w*(?:\.[A-Za-z_]\w*)+$|^[a-z]+(?:_[a-z]+)+$|^[a-z]+(?:[A-Z][a-z]+)+$`)
+
+// codeTypeWords are type names and schema keywords that follow a key in
+// type annotations and schemas (password: string, token: Optional[str]).
+var codeTypeWords = map[string]bool{
+	"string": true, "boolean": true, "bool": true, "number": true, "int": true, "str": true,
+	"any": true, "required": true, "true": true, "false": true, "null": true, "none": true,
+}
+
+// notCode rejects assignment values that are code rather than a literal
+// (calls, identifiers, types), so source files read by tools stay intact.
+// After a colon an unquoted value must contain a non-letter: a bare word
+// there is a type or schema keyword far more often than a password.
+func notCode(match, v string) bool {
+	if strings.ContainsAny(v, "(){}[]<>") || codeIdentRe.MatchString(v) || codeTypeWords[strings.ToLower(v)] {
+		return false
+	}
+	prefix := strings.TrimRightFunc(match[:len(match)-len(v)], unicode.IsSpace)
+	quoted := strings.HasSuffix(prefix, `"`) || strings.HasSuffix(prefix, "'")
+	sep := strings.TrimRightFunc(strings.TrimRight(prefix, `"'`), unicode.IsSpace)
+	if strings.HasSuffix(sep, ":") && !quoted {
+		return strings.ContainsFunc(v, func(c rune) bool { return !unicode.IsLetter(c) })
+	}
+	return true
+}
+
+// secretPlaceholder returns the placeholder of a kind.
+func secretPlaceholder(kind string) string {
+	return "[REDACTED_" + strings.ToUpper(strings.Map(func(c rune) rune {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return c
+		}
+		return '_'
+	}, kind)) + "]"
+}
@@ -29,6 +29,9 @@ type Session struct {
 	// ChatID é o identificador do grupo ou DM.
 	ChatID string
 
+	// Branch is the fork name ("" for the main session of the chat).
+	Branch string
+
 	// config contém configurações específicas desta sessão.
 	config SessionConfig
 
@@ -57,6 +60,27 @@ type Session struct {
 
 	persistence SessionPersister
 
+	// workspaceID is the workspace owning this session (for retention pruning).
+	workspaceID string
+
+	// checkpoints are the named snapshots of this session (see
+	// session_checkpoint.go), loaded from persistence on first use.
+	checkpoints       []SessionCheckpoint
+	checkpointsLoaded bool
+
+	// pendingTurn is the metadata of the last agent run, waiting for the
+	// AddMessage of its user message (pendingTurnFor).
+	pendingTurn    *TurnMeta
+	pendingTurnFor string
+
+	// pendingPlan is the /plan proposal waiting for the user's "go".
+	pendingPlan *PendingPlan
+
+	// usedTools and toolFamilies are the tools used successfully and the
+	// lazy tool families loaded so far (see tool_compression.go).
+	usedTools    map[string]bool
+	toolFamilies map[string]bool
+
 	mu sync.RWMutex
 }
 
@@ -72,6 +96,25 @@ type SessionPersister interface {
 	Close() error
 }
 
+// SessionState is the per-session state persisted alongside history:
+// token usage, owning workspace and last activity.
+type SessionState struct {
+	WorkspaceID      string
+	PromptTokens     int
+	CompletionTokens int
+	Requests         int
+	LastActiveAt     time.Time
+}
+
+// SessionStatePersister is implemented by backends that also persist session
+// state and metadata and can prune per workspace (SQLite).
+type SessionStatePersister interface {
+	SaveState(sessionID string, state SessionState) error
+	LoadState(sessionID string) (SessionState, bool)
+	LoadMeta(sessionID string) (channel, chatID string, config SessionConfig, activeSkills []string)
+	PruneWorkspace(workspaceID string, inactiveSince time.Time) (int, error)
+}
+
 // SessionConfig contém configurações específicas de uma sessão.
 type SessionConfig struct {
 	// Trigger é a palavra-chave que ativa o copilot nesta sessão.
@@ -89,11 +132,18 @@ type SessionConfig struct {
 	// BusinessContext é o contexto de negócio/usuário para esta sessão.
 	BusinessContext string `yaml:"business_context"`
 
+	// PromptDir is the workspace persona's bootstrap directory, searched
+	// before the global one.
+	PromptDir string `yaml:"prompt_dir"`
+
 	// ThinkingLevel controls extended thinking: "", "off", "low", "medium", "high".
 	ThinkingLevel string `yaml:"thinking_level"`
 
 	// Verbose enables narration of tool calls and internal steps.
 	Verbose bool `yaml:"verbose"`
+
+	// ReadOnly limits tool calls to reads and searches (see readonly_mode.go).
+	ReadOnly bool `yaml:"read_only"`
 }
 
 // ConversationEntry representa uma troca de mensagem na sessão.
@@ -101,6 +151,10 @@ type ConversationEntry struct {
 	UserMessage       string
 	AssistantResponse string
 	Timestamp         time.Time
+
+	// Meta holds the model, tool calls and usage behind the reply (nil for
+	// entries written without an agent run, or before it was recorded).
+	Meta *TurnMeta `json:",omitempty"`
 }
 
 // AddMessage adiciona uma nova entrada de conversa à sessão.
@@ -114,6 +168,10 @@ func (s *Session) AddMessage(userMsg, assistantResp string) {
 	}
 
 	s.mu.Lock()
+	if s.pendingTurn != nil && s.pendingTurnFor == userMsg {
+		entry.Meta = s.pendingTurn
+		s.pendingTurn = nil
+	}
 	s.history = append(s.history, entry)
 
 	// Trim histórico se exceder o limite para evitar leak de memória.
@@ -130,6 +188,16 @@ func (s *Session) AddMessage(userMsg, assistantResp string) {
 			// Log is done inside SaveEntry; avoid holding lock during I/O
 		}
 	}
+	s.persistState()
+}
+
+// setTurnMeta stores the metadata of the agent run that answered userMsg;
+// the next AddMessage for that message attaches it to the entry.
+func (s *Session) setTurnMeta(userMsg string, meta *TurnMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingTurn = meta
+	s.pendingTurnFor = userMsg
 }
 
 // RecentHistory retorna as últimas N entradas de conversa (cópia thread-safe).
@@ -187,9 +255,10 @@ func (s *Session) GetActiveSkills() []string {
 // SetActiveSkills define as skills ativas da sessão.
 func (s *Session) SetActiveSkills(skills []string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.activeSkills = make([]string, len(skills))
 	copy(s.activeSkills, skills)
+	s.mu.Unlock()
+	s.persistMeta()
 }
 
 // GetConfig retorna uma cópia thread-safe da configuração da sessão.
@@ -200,10 +269,15 @@ func (s *Session) GetConfig() SessionConfig {
 }
 
 // SetConfig atualiza a configuração da sessão.
+// Persiste os metadados apenas quando a configuração muda.
 func (s *Session) SetConfig(cfg SessionConfig) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	changed := s.config != cfg
 	s.config = cfg
+	s.mu.Unlock()
+	if changed {
+		s.persistMeta()
+	}
 }
 
 // LastActiveAt retorna o timestamp da última atividade (thread-safe).
@@ -237,10 +311,11 @@ func (s *Session) HistoryLen() int {
 // AddTokenUsage records token usage from an LLM response. Thread-safe.
 func (s *Session) AddTokenUsage(promptTokens, completionTokens int) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.totalPromptTokens += promptTokens
 	s.totalCompletionTokens += completionTokens
 	s.totalRequests++
+	s.mu.Unlock()
+	s.persistState()
 }
 
 // GetTokenUsage returns a copy of the token usage. Thread-safe.
@@ -253,10 +328,11 @@ func (s *Session) GetTokenUsage() (promptTokens, completionTokens, requests int)
 // ResetTokenUsage clears token counters. Thread-safe.
 func (s *Session) ResetTokenUsage() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.totalPromptTokens = 0
 	s.totalCompletionTokens = 0
 	s.totalRequests = 0
+	s.mu.Unlock()
+	s.persistState()
 }
 
 // GetThinkingLevel returns the session thinking level. Thread-safe.
@@ -269,8 +345,43 @@ func (s *Session) GetThinkingLevel() string {
 // SetThinkingLevel sets the session thinking level. Thread-safe.
 func (s *Session) SetThinkingLevel(level string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.config.ThinkingLevel = level
+	s.mu.Unlock()
+	s.persistMeta()
+}
+
+// persistMeta saves channel, config and active skills, if persistence is set.
+func (s *Session) persistMeta() {
+	s.mu.RLock()
+	persistence := s.persistence
+	id, channel, chatID, cfg := s.ID, s.Channel, s.ChatID, s.config
+	skills := make([]string, len(s.activeSkills))
+	copy(skills, s.activeSkills)
+	s.mu.RUnlock()
+
+	if persistence != nil {
+		_ = persistence.SaveMeta(id, channel, chatID, cfg, skills)
+	}
+}
+
+// persistState saves token usage and last activity when the backend
+// supports session state.
+func (s *Session) persistState() {
+	s.mu.RLock()
+	sp, ok := s.persistence.(SessionStatePersister)
+	id := s.ID
+	state := SessionState{
+		WorkspaceID:      s.workspaceID,
+		PromptTokens:     s.totalPromptTokens,
+		CompletionTokens: s.totalCompletionTokens,
+		Requests:         s.totalRequests,
+		LastActiveAt:     s.lastActiveAt,
+	}
+	s.mu.RUnlock()
+
+	if ok {
+		_ = sp.SaveState(id, state)
+	}
 }
 
 // CompactHistory replaces the full history with a summary entry,
@@ -310,6 +421,22 @@ type SessionStore struct {
 	logger      *slog.Logger
 	mu          sync.RWMutex
 	persistence SessionPersister
+
+	// workspaceID identifies the owning workspace in persisted state.
+	workspaceID string
+
+	// retention deletes persisted sessions inactive for longer than this
+	// (0 = keep forever). Only applies to backends with session state.
+	retention time.Duration
+
+	// onPrune is called in its own goroutine with each session removed by
+	// Prune (used by the knowledge base summarizer).
+	onPrune func(*Session)
+
+	// branches tracks /fork branches per chat (keyed by the main session
+	// key). Branch sessions are persisted; which one is active is not, so
+	// a restart routes every chat back to main.
+	branches map[string]*sessionBranches
 }
 
 // NewSessionStore cria um novo store de sessões.
@@ -325,17 +452,74 @@ func NewSessionStore(logger *slog.Logger) *SessionStore {
 	}
 }
 
-// SetPersistence configures disk persistence for sessions.
+// SetPersistence configures disk persistence for sessions. Sessions that so
+// far lived only in memory are migrated into the new backend.
 func (ss *SessionStore) SetPersistence(p SessionPersister) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 	ss.persistence = p
+	if p == nil {
+		return
+	}
+
+	migrated := 0
+	for _, session := range ss.sessions {
+		session.mu.Lock()
+		if session.persistence != nil {
+			session.mu.Unlock()
+			continue
+		}
+		session.persistence = p
+		history := make([]ConversationEntry, len(session.history))
+		copy(history, session.history)
+		facts := make([]string, len(session.facts))
+		copy(facts, session.facts)
+		session.mu.Unlock()
+
+		for _, entry := range history {
+			_ = p.SaveEntry(session.ID, entry)
+		}
+		if len(facts) > 0 {
+			_ = p.SaveFacts(session.ID, facts)
+		}
+		session.persistMeta()
+		session.persistState()
+		migrated++
+	}
+	if migrated > 0 {
+		ss.logger.Info("in-memory sessions migrated to persistent store", "sessions", migrated)
+	}
+}
+
+// SetRetention sets how long inactive sessions are kept in the persistent
+// store (0 = forever).
+func (ss *SessionStore) SetRetention(d time.Duration) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.retention = d
+}
+
+// SetPruneHook registers a callback for sessions removed by Prune.
+func (ss *SessionStore) SetPruneHook(fn func(*Session)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.onPrune = fn
 }
 
 // GetOrCreate retorna a sessão existente ou cria uma nova para o canal e chatID.
 // Se persistence estiver configurada, tenta carregar do disco antes de criar.
+// Messages of a chat with an active fork are routed to the fork.
 func (ss *SessionStore) GetOrCreate(channel, chatID string) *Session {
-	key := sessionKey(channel, chatID)
+	ss.mu.RLock()
+	sk := ss.activeKey(channel, chatID)
+	ss.mu.RUnlock()
+	return ss.getOrCreate(sk)
+}
+
+// getOrCreate returns or creates the session for a structured key.
+func (ss *SessionStore) getOrCreate(sk SessionKey) *Session {
+	key := sk.Hash()
+	channel, chatID := sk.Channel, sk.ChatID
 
 	ss.mu.RLock()
 	if session, exists := ss.sessions[key]; exists {
@@ -357,18 +541,40 @@ func (ss *SessionStore) GetOrCreate(channel, chatID string) *Session {
 
 	if persistence != nil {
 		entries, facts, loadErr := persistence.LoadSession(key)
-		if loadErr == nil && (len(entries) > 0 || len(facts) > 0) {
+		var state SessionState
+		var hasState bool
+		sp, isStateful := persistence.(SessionStatePersister)
+		if isStateful {
+			state, hasState = sp.LoadState(key)
+		}
+		if loadErr == nil && (len(entries) > 0 || len(facts) > 0 || hasState) {
+			if len(entries) > DefaultMaxHistory {
+				entries = entries[len(entries)-DefaultMaxHistory:]
+			}
 			session = &Session{
-				ID:           key,
-				Channel:      channel,
-				ChatID:       chatID,
-				config:       SessionConfig{},
-				activeSkills: []string{},
-				facts:        facts,
-				history:      entries,
-				maxHistory:   DefaultMaxHistory,
-				CreatedAt:    time.Now(),
-				lastActiveAt: time.Now(),
+				ID:                    key,
+				Channel:               channel,
+				ChatID:                chatID,
+				Branch:                sk.Branch,
+				config:                SessionConfig{},
+				activeSkills:          []string{},
+				facts:                 facts,
+				history:               entries,
+				maxHistory:            DefaultMaxHistory,
+				totalPromptTokens:     state.PromptTokens,
+				totalCompletionTokens: state.CompletionTokens,
+				totalRequests:         state.Requests,
+				CreatedAt:             time.Now(),
+				lastActiveAt:          time.Now(),
+				persistence:           persistence,
+				workspaceID:           ss.workspaceID,
+			}
+			if isStateful {
+				_, _, cfg, skills := sp.LoadMeta(key)
+				session.config = cfg
+				if skills != nil {
+					session.activeSkills = skills
+				}
 			}
 			ss.sessions[key] = session
 			ss.logger.Info("sessão restaurada do disco",
@@ -384,6 +590,7 @@ func (ss *SessionStore) GetOrCreate(channel, chatID string) *Session {
 		ID:           key,
 		Channel:      channel,
 		ChatID:       chatID,
+		Branch:       sk.Branch,
 		config:       SessionConfig{},
 		activeSkills: []string{},
 		facts:        []string{},
@@ -392,6 +599,7 @@ func (ss *SessionStore) GetOrCreate(channel, chatID string) *Session {
 		CreatedAt:    time.Now(),
 		lastActiveAt: time.Now(),
 		persistence:  persistence,
+		workspaceID:  ss.workspaceID,
 	}
 
 	if persistence != nil {
@@ -409,11 +617,11 @@ func (ss *SessionStore) GetOrCreate(channel, chatID string) *Session {
 	return session
 }
 
-// Get retorna a sessão pelo canal e chatID, ou nil se não existir.
+// Get retorna a sessão ativa pelo canal e chatID, ou nil se não existir.
 func (ss *SessionStore) Get(channel, chatID string) *Session {
 	ss.mu.RLock()
 	defer ss.mu.RUnlock()
-	return ss.sessions[sessionKey(channel, chatID)]
+	return ss.sessions[ss.activeKey(channel, chatID).Hash()]
 }
 
 // Count retorna o número de sessões ativas.
@@ -431,14 +639,22 @@ func (ss *SessionStore) Prune() int {
 
 	cutoff := time.Now().Add(-ss.sessionTTL)
 	pruned := 0
+	var removed []*Session
 
 	for key, session := range ss.sessions {
 		if session.LastActiveAt().Before(cutoff) {
 			delete(ss.sessions, key)
+			removed = append(removed, session)
 			pruned++
 		}
 	}
 
+	if ss.onPrune != nil {
+		for _, session := range removed {
+			go ss.onPrune(session)
+		}
+	}
+
 	if pruned > 0 {
 		ss.logger.Info("sessões inativas removidas",
 			"pruned", pruned,
@@ -446,6 +662,18 @@ func (ss *SessionStore) Prune() int {
 		)
 	}
 
+	// Apaga do armazenamento persistente as sessões do workspace que
+	// excederam o período de retenção.
+	if sp, ok := ss.persistence.(SessionStatePersister); ok && ss.retention > 0 {
+		removed, err := sp.PruneWorkspace(ss.workspaceID, time.Now().Add(-ss.retention))
+		if err != nil {
+			ss.logger.Warn("failed to prune persisted sessions", "error", err)
+		} else if removed > 0 {
+			ss.logger.Info("persisted sessions past retention deleted",
+				"workspace", ss.workspaceID, "removed", removed)
+		}
+	}
+
 	return pruned
 }
 
@@ -545,44 +773,11 @@ func (ss *SessionStore) Export(id string) *SessionExport {
 	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	export := &SessionExport{
-		ID:        s.ID,
-		Channel:   s.Channel,
-		ChatID:    s.ChatID,
-		Config:    s.config,
-		Facts:     make([]string, len(s.facts)),
-		CreatedAt: s.CreatedAt,
-		Messages:  make([]ExportedMessage, 0, len(s.history)),
-	}
-	copy(export.Facts, s.facts)
-	for _, entry := range s.history {
-		export.Messages = append(export.Messages, ExportedMessage{
-			User:      entry.UserMessage,
-			Assistant: entry.AssistantResponse,
-			Timestamp: entry.Timestamp,
-		})
-	}
+	export := newSessionExport(s.ID, s.Channel, s.ChatID, s.config, s.facts, s.history, s.CreatedAt)
+	export.Branch = s.Branch
 	return export
 }
 
-// SessionExport is a portable representation of a session for backup/export.
-type SessionExport struct {
-	ID        string            `json:"id"`
-	Channel   string            `json:"channel"`
-	ChatID    string            `json:"chat_id"`
-	Config    SessionConfig     `json:"config"`
-	Facts     []string          `json:"facts"`
-	CreatedAt time.Time         `json:"created_at"`
-	Messages  []ExportedMessage `json:"messages"`
-}
-
-// ExportedMessage is a single message in an exported session.
-type ExportedMessage struct {
-	User      string    `json:"user"`
-	Assistant string    `json:"assistant"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
 // RenameSession changes the ChatID of a session (e.g. for aliasing).
 func (ss *SessionStore) RenameSession(oldID, newChannel, newChatID string) bool {
 	ss.mu.Lock()
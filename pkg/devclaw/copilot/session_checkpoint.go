@@ -0,0 +1,366 @@
+// Package copilot – session_checkpoint.go implements named snapshots of a
+// session (/checkpoint, /rewind) and conversation branches (/fork).
+//
+// A checkpoint copies the history and facts of a session under a name;
+// rewinding restores them and drops everything said since. A fork creates a
+// new session for the same chat (SessionKey.Branch = fork name) that starts
+// with the current history, or that of a checkpoint, and makes it the active
+// one: messages of the chat are routed to the branch until the user switches
+// back to main. Branches are ordinary sessions, so they are persisted and
+// pruned like any other.
+package copilot
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSessionCheckpoints caps the checkpoints kept per session; the oldest
+// are dropped first.
+const maxSessionCheckpoints = 20
+
+// mainBranch is the name of the original session of a chat.
+const mainBranch = "main"
+
+// SessionCheckpoint is a named snapshot of a session's history and facts.
+type SessionCheckpoint struct {
+	Name      string              `json:"name"`
+	CreatedAt time.Time           `json:"created_at"`
+	History   []ConversationEntry `json:"history"`
+	Facts     []string            `json:"facts,omitempty"`
+}
+
+// SessionCheckpointPersister is implemented by backends that persist
+// checkpoints and can rewrite a session's history (JSONL and SQLite).
+type SessionCheckpointPersister interface {
+	SaveCheckpoints(sessionID string, checkpoints []SessionCheckpoint) error
+	LoadCheckpoints(sessionID string) ([]SessionCheckpoint, error)
+	ReplaceHistory(sessionID string, entries []ConversationEntry) error
+}
+
+// Checkpoint saves the current history and facts under name, replacing a
+// checkpoint with the same name. An empty name gets "cp1", "cp2", ...
+func (s *Session) Checkpoint(name string) (SessionCheckpoint, error) {
+	s.loadCheckpoints()
+
+	s.mu.Lock()
+	if len(s.history) == 0 {
+		s.mu.Unlock()
+		return SessionCheckpoint{}, fmt.Errorf("nothing to checkpoint: the conversation is empty")
+	}
+	if name == "" {
+		for i := len(s.checkpoints) + 1; ; i++ {
+			name = fmt.Sprintf("cp%d", i)
+			if s.checkpointIndex(name) < 0 {
+				break
+			}
+		}
+	}
+	cp := SessionCheckpoint{
+		Name:      name,
+		CreatedAt: time.Now(),
+		History:   append([]ConversationEntry(nil), s.history...),
+		Facts:     append([]string(nil), s.facts...),
+	}
+	if i := s.checkpointIndex(name); i >= 0 {
+		s.checkpoints = append(s.checkpoints[:i], s.checkpoints[i+1:]...)
+	}
+	s.checkpoints = append(s.checkpoints, cp)
+	if len(s.checkpoints) > maxSessionCheckpoints {
+		s.checkpoints = s.checkpoints[len(s.checkpoints)-maxSessionCheckpoints:]
+	}
+	all := append([]SessionCheckpoint(nil), s.checkpoints...)
+	s.mu.Unlock()
+
+	s.saveCheckpoints(all)
+	return cp, nil
+}
+
+// Checkpoints returns the session's checkpoints, oldest first.
+func (s *Session) Checkpoints() []SessionCheckpoint {
+	s.loadCheckpoints()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]SessionCheckpoint(nil), s.checkpoints...)
+}
+
+// Rewind restores the history and facts saved by a checkpoint (the latest
+// one when name is empty). The checkpoint is kept, so the session can be
+// rewound to it again.
+func (s *Session) Rewind(name string) (SessionCheckpoint, error) {
+	cp, err := s.findCheckpoint(name)
+	if err != nil {
+		return SessionCheckpoint{}, err
+	}
+
+	s.mu.Lock()
+	s.history = append([]ConversationEntry(nil), cp.History...)
+	s.facts = append([]string(nil), cp.Facts...)
+	s.lastActiveAt = time.Now()
+	id, persistence := s.ID, s.persistence
+	s.mu.Unlock()
+
+	if persistence != nil {
+		if cpp, ok := persistence.(SessionCheckpointPersister); ok {
+			_ = cpp.ReplaceHistory(id, cp.History)
+		}
+		_ = persistence.SaveFacts(id, cp.Facts)
+	}
+	return cp, nil
+}
+
+// ReviseResponse replaces the latest reply equal to draft with revised, e.g.
+// the text a reviewer sent instead of the agent's draft. It reports whether
+// the reply was found.
+func (s *Session) ReviseResponse(draft, revised string) bool {
+	s.mu.Lock()
+	found := false
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].AssistantResponse == draft {
+			s.history[i].AssistantResponse = revised
+			found = true
+			break
+		}
+	}
+	history := append([]ConversationEntry(nil), s.history...)
+	id, persistence := s.ID, s.persistence
+	s.mu.Unlock()
+
+	if found && persistence != nil {
+		if cpp, ok := persistence.(SessionCheckpointPersister); ok {
+			_ = cpp.ReplaceHistory(id, history)
+		}
+	}
+	return found
+}
+
+// findCheckpoint returns a copy of the named checkpoint (latest when empty).
+func (s *Session) findCheckpoint(name string) (SessionCheckpoint, error) {
+	s.loadCheckpoints()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.checkpoints) == 0 {
+		return SessionCheckpoint{}, fmt.Errorf("no checkpoints in this session")
+	}
+	if name == "" {
+		return s.checkpoints[len(s.checkpoints)-1], nil
+	}
+	i := s.checkpointIndex(name)
+	if i < 0 {
+		return SessionCheckpoint{}, fmt.Errorf("checkpoint %q not found", name)
+	}
+	return s.checkpoints[i], nil
+}
+
+// checkpointIndex returns the position of the named checkpoint or -1.
+// Caller must hold s.mu.
+func (s *Session) checkpointIndex(name string) int {
+	for i, cp := range s.checkpoints {
+		if strings.EqualFold(cp.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// loadCheckpoints reads persisted checkpoints on first use.
+func (s *Session) loadCheckpoints() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpointsLoaded {
+		return
+	}
+	s.checkpointsLoaded = true
+	cpp, ok := s.persistence.(SessionCheckpointPersister)
+	if !ok {
+		return
+	}
+	loaded, err := cpp.LoadCheckpoints(s.ID)
+	if err == nil && len(loaded) > 0 {
+		s.checkpoints = append(loaded, s.checkpoints...)
+	}
+}
+
+// saveCheckpoints persists the checkpoint list when the backend supports it.
+func (s *Session) saveCheckpoints(all []SessionCheckpoint) {
+	s.mu.RLock()
+	cpp, ok := s.persistence.(SessionCheckpointPersister)
+	id := s.ID
+	s.mu.RUnlock()
+	if ok {
+		_ = cpp.SaveCheckpoints(id, all)
+	}
+}
+
+// sessionBranches tracks the forks of one chat.
+type sessionBranches struct {
+	active string   // "" = main
+	names  []string // in creation order
+}
+
+// Fork creates the branch named branch for the chat, starting from the
+// current history (or from the named checkpoint), and makes it the active
+// session of the chat.
+func (ss *SessionStore) Fork(channel, chatID, branch, checkpoint string) (*Session, error) {
+	branch = strings.TrimSpace(branch)
+	if branch == "" || strings.EqualFold(branch, mainBranch) || strings.ContainsAny(branch, ": ") {
+		return nil, fmt.Errorf("invalid branch name %q", branch)
+	}
+	if ss.hasBranch(channel, chatID, branch) {
+		return nil, fmt.Errorf("branch %q already exists", branch)
+	}
+
+	src := ss.GetOrCreate(channel, chatID)
+	var (
+		history []ConversationEntry
+		facts   []string
+	)
+	if checkpoint != "" {
+		cp, err := src.findCheckpoint(checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		history, facts = cp.History, cp.Facts
+	} else {
+		src.mu.RLock()
+		history = append([]ConversationEntry(nil), src.history...)
+		facts = append([]string(nil), src.facts...)
+		src.mu.RUnlock()
+	}
+
+	src.mu.RLock()
+	cfg := src.config
+	skills := append([]string(nil), src.activeSkills...)
+	src.mu.RUnlock()
+
+	key := SessionKey{Channel: channel, ChatID: chatID, Branch: branch}
+	id := key.Hash()
+
+	ss.mu.Lock()
+	fork := &Session{
+		ID:           id,
+		Channel:      channel,
+		ChatID:       chatID,
+		Branch:       branch,
+		config:       cfg,
+		activeSkills: skills,
+		facts:        facts,
+		history:      history,
+		maxHistory:   DefaultMaxHistory,
+		CreatedAt:    time.Now(),
+		lastActiveAt: time.Now(),
+		persistence:  ss.persistence,
+		workspaceID:  ss.workspaceID,
+	}
+	ss.sessions[id] = fork
+	b := ss.branchesFor(channel, chatID)
+	b.names = append(b.names, branch)
+	b.active = branch
+	persistence := ss.persistence
+	ss.mu.Unlock()
+
+	if persistence != nil {
+		if cpp, ok := persistence.(SessionCheckpointPersister); ok {
+			_ = cpp.ReplaceHistory(id, history)
+		} else {
+			for _, e := range history {
+				_ = persistence.SaveEntry(id, e)
+			}
+		}
+		_ = persistence.SaveFacts(id, facts)
+		_ = persistence.SaveMeta(id, channel, chatID, cfg, skills)
+	}
+
+	ss.logger.Info("session forked",
+		"channel", channel, "chat_id", chatID,
+		"branch", branch, "entries", len(history))
+	return fork, nil
+}
+
+// SwitchBranch makes branch the active session of the chat ("main" returns
+// to the original session). Branches persisted before a restart can be
+// switched to by name.
+func (ss *SessionStore) SwitchBranch(channel, chatID, branch string) error {
+	branch = strings.TrimSpace(branch)
+	if branch == "" || strings.EqualFold(branch, mainBranch) {
+		ss.mu.Lock()
+		if b := ss.branches[sessionKey(channel, chatID)]; b != nil {
+			b.active = ""
+		}
+		ss.mu.Unlock()
+		return nil
+	}
+	if !ss.hasBranch(channel, chatID, branch) {
+		return fmt.Errorf("branch %q not found", branch)
+	}
+
+	ss.mu.Lock()
+	b := ss.branchesFor(channel, chatID)
+	if !slices.Contains(b.names, branch) {
+		b.names = append(b.names, branch)
+	}
+	b.active = branch
+	ss.mu.Unlock()
+	return nil
+}
+
+// Branches returns the forks known for the chat and the active one
+// ("main" when the original session is active).
+func (ss *SessionStore) Branches(channel, chatID string) (names []string, active string) {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	active = mainBranch
+	b := ss.branches[sessionKey(channel, chatID)]
+	if b == nil {
+		return nil, active
+	}
+	if b.active != "" {
+		active = b.active
+	}
+	names = append(names, b.names...)
+	sort.Strings(names)
+	return names, active
+}
+
+// activeKey returns the session key messages of the chat are routed to.
+// Caller must hold ss.mu.
+func (ss *SessionStore) activeKey(channel, chatID string) SessionKey {
+	sk := SessionKey{Channel: channel, ChatID: chatID}
+	if b := ss.branches[sk.Hash()]; b != nil {
+		sk.Branch = b.active
+	}
+	return sk
+}
+
+// branchesFor returns the branch set of the chat, creating it.
+// Caller must hold ss.mu for writing.
+func (ss *SessionStore) branchesFor(channel, chatID string) *sessionBranches {
+	key := sessionKey(channel, chatID)
+	if ss.branches == nil {
+		ss.branches = make(map[string]*sessionBranches)
+	}
+	b := ss.branches[key]
+	if b == nil {
+		b = &sessionBranches{}
+		ss.branches[key] = b
+	}
+	return b
+}
+
+// hasBranch reports whether the branch exists in memory or in persistence.
+func (ss *SessionStore) hasBranch(channel, chatID, branch string) bool {
+	id := SessionKey{Channel: channel, ChatID: chatID, Branch: branch}.Hash()
+	ss.mu.RLock()
+	_, loaded := ss.sessions[id]
+	persistence := ss.persistence
+	ss.mu.RUnlock()
+	if loaded || persistence == nil {
+		return loaded
+	}
+	entries, facts, err := persistence.LoadSession(id)
+	return err == nil && (len(entries) > 0 || len(facts) > 0)
+}
@@ -0,0 +1,367 @@
+// Package copilot – session_export.go renders a session's full transcript
+// (messages, tool calls and usage) as Markdown, JSON or HTML, for /export
+// and `devclaw sessions export`.
+//
+// Each turn records which model answered, the tools it called and the tokens
+// it spent (TurnMeta). The metadata is persisted with the conversation entry,
+// so exports of older sessions keep it across restarts.
+package copilot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits for tool call details kept in the transcript.
+const (
+	turnToolArgsMax   = 300
+	turnToolResultMax = 500
+)
+
+// TurnMeta is what happened behind one assistant reply.
+type TurnMeta struct {
+	Model            string         `json:"model,omitempty"`
+	PromptTokens     int            `json:"prompt_tokens,omitempty"`
+	CompletionTokens int            `json:"completion_tokens,omitempty"`
+	CostUSD          float64        `json:"cost_usd,omitempty"`
+	DurationMs       int64          `json:"duration_ms,omitempty"`
+	Tools            []TurnToolCall `json:"tools,omitempty"`
+}
+
+// TurnToolCall is one tool call of a turn, with truncated arguments and
+// result, and what /receipt reports about it.
+type TurnToolCall struct {
+	Name        string `json:"name"`
+	Args        string `json:"args,omitempty"`
+	Result      string `json:"result,omitempty"`
+	Error       bool   `json:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms,omitempty"`
+	Blocked     string `json:"blocked,omitempty"`
+	Approval    string `json:"approval,omitempty"`
+	OutputBytes int    `json:"output_bytes,omitempty"`
+	Truncated   bool   `json:"truncated,omitempty"`
+}
+
+// addUsage accounts one LLM call. The last model used names the turn.
+func (m *TurnMeta) addUsage(model string, usage LLMUsage, cost float64) {
+	if model != "" {
+		m.Model = model
+	}
+	m.PromptTokens += usage.PromptTokens
+	m.CompletionTokens += usage.CompletionTokens
+	m.CostUSD += cost
+}
+
+// Receipt renders the turn for /receipt: one line per tool call with its
+// duration, guard or approval outcome and the output fed to the model.
+func (m *TurnMeta) Receipt() string {
+	var b strings.Builder
+	b.WriteString("*Receipt — last reply*\n")
+	var parts []string
+	if m.Model != "" {
+		parts = append(parts, m.Model)
+	}
+	if m.DurationMs > 0 {
+		parts = append(parts, receiptDuration(m.DurationMs))
+	}
+	if m.PromptTokens+m.CompletionTokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d+%d tokens", m.PromptTokens, m.CompletionTokens))
+	}
+	if m.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("~$%.4f", m.CostUSD))
+	}
+	if len(parts) > 0 {
+		b.WriteString(strings.Join(parts, " · ") + "\n")
+	}
+	if len(m.Tools) == 0 {
+		b.WriteString("\nNo tools were called.")
+		return b.String()
+	}
+
+	var total int
+	for i, tc := range m.Tools {
+		status := "ok"
+		switch {
+		case tc.Blocked != "":
+			status = "⛔ blocked by " + tc.Blocked
+		case tc.Approval == "pending":
+			status = "✋ required approval"
+		case tc.Approval == "rejected":
+			status = "✋ changes rejected in review"
+		case tc.Error:
+			status = "❌ error"
+		case tc.Approval == "reviewed":
+			status = "✋ approved in review"
+		}
+		dur := receiptDuration(tc.DurationMs)
+		if tc.Blocked != "" || tc.Approval == "pending" {
+			dur = "not run"
+		}
+		size := receiptBytes(tc.OutputBytes)
+		if tc.Truncated {
+			size += ", capped"
+		}
+		fmt.Fprintf(&b, "\n%d. `%s` — %s · %s · %s", i+1, tc.Name, dur, size, status)
+		total += tc.OutputBytes
+	}
+	fmt.Fprintf(&b, "\n\n%d tool calls, %s of output.", len(m.Tools), receiptBytes(total))
+	return b.String()
+}
+
+// receiptDuration renders milliseconds compactly ("850ms", "12.3s").
+func receiptDuration(ms int64) string {
+	if ms < 1000 {
+		return fmt.Sprintf("%dms", ms)
+	}
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
+
+// receiptBytes renders a byte count ("512 B", "3.4 KB").
+func receiptBytes(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
+
+// SessionExport is a portable representation of a session for backup/export.
+type SessionExport struct {
+	ID               string            `json:"id"`
+	Channel          string            `json:"channel"`
+	ChatID           string            `json:"chat_id"`
+	Branch           string            `json:"branch,omitempty"`
+	Config           SessionConfig     `json:"config"`
+	Facts            []string          `json:"facts"`
+	CreatedAt        time.Time         `json:"created_at"`
+	PromptTokens     int               `json:"prompt_tokens"`
+	CompletionTokens int               `json:"completion_tokens"`
+	CostUSD          float64           `json:"cost_usd"`
+	Messages         []ExportedMessage `json:"messages"`
+}
+
+// ExportedMessage is a single message in an exported session.
+type ExportedMessage struct {
+	User      string    `json:"user"`
+	Assistant string    `json:"assistant"`
+	Timestamp time.Time `json:"timestamp"`
+	Turn      *TurnMeta `json:"turn,omitempty"`
+}
+
+// newSessionExport builds an export from a session's history and facts.
+func newSessionExport(id, channel, chatID string, cfg SessionConfig, facts []string, history []ConversationEntry, createdAt time.Time) *SessionExport {
+	export := &SessionExport{
+		ID:        id,
+		Channel:   channel,
+		ChatID:    chatID,
+		Config:    cfg,
+		Facts:     append([]string{}, facts...),
+		CreatedAt: createdAt,
+		Messages:  make([]ExportedMessage, 0, len(history)),
+	}
+	for _, entry := range history {
+		export.Messages = append(export.Messages, ExportedMessage{
+			User:      entry.UserMessage,
+			Assistant: entry.AssistantResponse,
+			Timestamp: entry.Timestamp,
+			Turn:      entry.Meta,
+		})
+		if entry.Meta != nil {
+			export.PromptTokens += entry.Meta.PromptTokens
+			export.CompletionTokens += entry.Meta.CompletionTokens
+			export.CostUSD += entry.Meta.CostUSD
+		}
+	}
+	if export.CreatedAt.IsZero() && len(history) > 0 {
+		export.CreatedAt = history[0].Timestamp
+	}
+	return export
+}
+
+// JSON renders the export as indented JSON.
+func (e *SessionExport) JSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// Markdown renders the export as a readable transcript.
+func (e *SessionExport) Markdown() string {
+	var b strings.Builder
+	title := e.Channel + ":" + e.ChatID
+	if e.Branch != "" {
+		title += " (branch " + e.Branch + ")"
+	}
+	fmt.Fprintf(&b, "# Session %s\n\n", title)
+	fmt.Fprintf(&b, "- ID: `%s`\n", e.ID)
+	if !e.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "- Started: %s\n", e.CreatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- Messages: %d\n", len(e.Messages))
+	if e.PromptTokens+e.CompletionTokens > 0 {
+		fmt.Fprintf(&b, "- Tokens: %d prompt, %d completion", e.PromptTokens, e.CompletionTokens)
+		if e.CostUSD > 0 {
+			fmt.Fprintf(&b, " (~$%.4f)", e.CostUSD)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "- Exported: %s\n", time.Now().Format(time.RFC3339))
+
+	if len(e.Facts) > 0 {
+		b.WriteString("\n## Facts\n\n")
+		for _, f := range e.Facts {
+			fmt.Fprintf(&b, "- %s\n", f)
+		}
+	}
+
+	b.WriteString("\n## Conversation\n")
+	for i, m := range e.Messages {
+		fmt.Fprintf(&b, "\n### %d. %s\n\n", i+1, m.Timestamp.Format("2006-01-02 15:04:05"))
+		if m.User != "" {
+			fmt.Fprintf(&b, "**User:**\n\n%s\n\n", m.User)
+		}
+		if t := m.Turn; t != nil && len(t.Tools) > 0 {
+			b.WriteString("**Tools:**\n\n")
+			for _, tc := range t.Tools {
+				status := "ok"
+				if tc.Error {
+					status = "error"
+				}
+				if tc.DurationMs > 0 {
+					status += ", " + receiptDuration(tc.DurationMs)
+				}
+				fmt.Fprintf(&b, "- `%s` (%s)", tc.Name, status)
+				if tc.Args != "" {
+					fmt.Fprintf(&b, " `%s`", markdownCode(tc.Args))
+				}
+				b.WriteString("\n")
+				if tc.Result != "" {
+					fmt.Fprintf(&b, "  > %s\n", strings.ReplaceAll(tc.Result, "\n", "\n  > "))
+				}
+			}
+			b.WriteString("\n")
+		}
+		if m.Assistant != "" {
+			fmt.Fprintf(&b, "**Assistant:**\n\n%s\n", m.Assistant)
+		}
+		if t := m.Turn; t != nil && (t.Model != "" || t.PromptTokens+t.CompletionTokens > 0) {
+			parts := []string{}
+			if t.Model != "" {
+				parts = append(parts, t.Model)
+			}
+			if t.PromptTokens+t.CompletionTokens > 0 {
+				parts = append(parts, fmt.Sprintf("%d+%d tokens", t.PromptTokens, t.CompletionTokens))
+			}
+			if t.CostUSD > 0 {
+				parts = append(parts, fmt.Sprintf("~$%.4f", t.CostUSD))
+			}
+			if t.DurationMs > 0 {
+				parts = append(parts, (time.Duration(t.DurationMs) * time.Millisecond).Round(100*time.Millisecond).String())
+			}
+			fmt.Fprintf(&b, "\n_%s_\n", strings.Join(parts, " · "))
+		}
+	}
+	return b.String()
+}
+
+// markdownCode makes s safe inside an inline code span.
+func markdownCode(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "`", "'")
+}
+
+// Render returns the export in the given format ("md"/"markdown", "json" or
+// "html"). HTML is not redacted; see PublishSessionExport.
+func (e *SessionExport) Render(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "", "md", "markdown":
+		return []byte(e.Markdown()), nil
+	case "json":
+		return e.JSON()
+	case "html":
+		return e.HTML()
+	default:
+		return nil, fmt.Errorf("unknown export format %q (use md, json or html)", format)
+	}
+}
+
+// LoadSessionExport reads a session straight from the persistence backend
+// selected by cfg, without starting the assistant. ref is a session ID or a
+// "channel:chatID[:branch]" key.
+func LoadSessionExport(cfg *Config, ref string) (*SessionExport, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("session reference is required")
+	}
+	id := ref
+	var key SessionKey
+	if strings.Contains(ref, ":") {
+		key = ParseSessionKey(ref)
+		id = key.Hash()
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	backend := strings.ToLower(cfg.Memory.Backend)
+	if backend == "memory" {
+		return nil, fmt.Errorf("sessions are not persisted with memory.backend=memory")
+	}
+
+	var (
+		history []ConversationEntry
+		facts   []string
+		channel = key.Channel
+		chatID  = key.ChatID
+		scfg    SessionConfig
+	)
+	if backend != "jsonl" {
+		dbPath := cfg.Database.Path
+		if dbPath == "" {
+			dbPath = "./data/devclaw.db"
+		}
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil, fmt.Errorf("database not found: %w", err)
+		}
+		db, err := OpenDatabase(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+		p := NewSQLiteSessionPersistence(db, logger)
+		if history, facts, err = p.LoadSession(id); err != nil {
+			return nil, err
+		}
+		ch, chat, sessionCfg, _ := p.LoadMeta(id)
+		scfg = sessionCfg
+		if channel == "" {
+			channel, chatID = ch, chat
+		}
+	} else {
+		dir := filepath.Join(filepath.Dir(cfg.Memory.Path), "sessions")
+		p, err := NewSessionPersistence(dir, logger)
+		if err != nil {
+			return nil, err
+		}
+		data, err := p.loadSessionData(sanitizeSessionID(id))
+		if err != nil {
+			return nil, err
+		}
+		history, facts, scfg = data.History, data.Facts, data.Config
+		if channel == "" {
+			channel, chatID = data.Channel, data.ChatID
+		}
+	}
+
+	if len(history) == 0 && len(facts) == 0 {
+		return nil, fmt.Errorf("session %q not found", ref)
+	}
+	export := newSessionExport(id, channel, chatID, scfg, facts, history, time.Time{})
+	export.Branch = key.Branch
+	return export, nil
+}
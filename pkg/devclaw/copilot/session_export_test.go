@@ -0,0 +1,36 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTurnMeta_Receipt(t *testing.T) {
+	t.Parallel()
+	meta := &TurnMeta{
+		Model:      "gpt-5-mini",
+		DurationMs: 4200,
+		Tools: []TurnToolCall{
+			{Name: "read_file", DurationMs: 12, OutputBytes: 3500},
+			{Name: "bash", Blocked: "guard: destructive command"},
+			{Name: "ssh", Approval: "pending", OutputBytes: 120},
+			{Name: "web_fetch", DurationMs: 1500, OutputBytes: 2 << 20, Truncated: true},
+		},
+	}
+	receipt := meta.Receipt()
+	for _, want := range []string{
+		"gpt-5-mini · 4.2s",
+		"1. `read_file` — 12ms · 3.4 KB · ok",
+		"2. `bash` — not run · 0 B · ⛔ blocked by guard: destructive command",
+		"3. `ssh` — not run · 120 B · ✋ required approval",
+		"4. `web_fetch` — 1.5s · 2.0 MB, capped · ok",
+		"4 tool calls",
+	} {
+		if !strings.Contains(receipt, want) {
+			t.Errorf("receipt missing %q:\n%s", want, receipt)
+		}
+	}
+	if got := (&TurnMeta{}).Receipt(); !strings.Contains(got, "No tools were called") {
+		t.Errorf("empty receipt = %q", got)
+	}
+}
@@ -23,6 +23,7 @@ type jsonlEntry struct {
 	User      string                 `json:"user"`
 	Assistant string                 `json:"assistant"`
 	Meta      map[string]interface{} `json:"meta,omitempty"`
+	Turn      *TurnMeta              `json:"turn,omitempty"`
 }
 
 // metaFile represents session metadata stored in .meta.json.
@@ -119,6 +120,7 @@ func (p *SessionPersistence) SaveEntry(sessionID string, entry ConversationEntry
 		User:      entry.UserMessage,
 		Assistant: entry.AssistantResponse,
 		Meta:      map[string]interface{}{},
+		Turn:      entry.Meta,
 	}
 	data, err := json.Marshal(je)
 	if err != nil {
@@ -284,6 +286,7 @@ func (p *SessionPersistence) readJSONL(path string) ([]ConversationEntry, error)
 			UserMessage:       je.User,
 			AssistantResponse: je.Assistant,
 			Timestamp:         ts,
+			Meta:              je.Turn,
 		})
 	}
 	if err := scanner.Err(); err != nil {
@@ -357,7 +360,7 @@ func (p *SessionPersistence) SaveMeta(sessionID, channel, chatID string, config
 // DeleteSession removes the session's JSONL and facts files.
 func (p *SessionPersistence) DeleteSession(sessionID string) error {
 	sanitized := sanitizeSessionID(sessionID)
-	for _, ext := range []string{".jsonl", ".facts.json", ".meta.json", ".jsonl.bak"} {
+	for _, ext := range []string{".jsonl", ".facts.json", ".meta.json", ".jsonl.bak", ".checkpoints.json"} {
 		path := filepath.Join(p.dir, sanitized+ext)
 		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 			p.logger.Warn("failed to remove session file", "path", path, "err", err)
@@ -391,29 +394,87 @@ func (p *SessionPersistence) Rotate(sessionID string, maxLines int) error {
 		return fmt.Errorf("rename to bak: %w", err)
 	}
 
+	if err := writeJSONL(path, keep); err != nil {
+		return err
+	}
+
+	p.logger.Info("session rotated", "session", sessionID, "kept", len(keep), "removed", len(entries)-len(keep))
+	return nil
+}
+
+// ReplaceHistory rewrites the JSONL file of a session with entries (used by /rewind).
+func (p *SessionPersistence) ReplaceHistory(sessionID string, entries []ConversationEntry) error {
+	mu := p.fileMuFor(sessionID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := filepath.Join(p.dir, sanitizeSessionID(sessionID)+".jsonl")
+	return writeJSONL(path, entries)
+}
+
+// writeJSONL truncates path and writes one line per entry.
+func writeJSONL(path string, entries []ConversationEntry) error {
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
 		return fmt.Errorf("create fresh file: %w", err)
 	}
 	defer f.Close()
 
-	for _, e := range keep {
+	for _, e := range entries {
 		je := jsonlEntry{
 			TS:        e.Timestamp.UTC().Format(time.RFC3339),
 			User:      e.UserMessage,
 			Assistant: e.AssistantResponse,
 			Meta:      map[string]interface{}{},
+			Turn:      e.Meta,
 		}
 		data, _ := json.Marshal(je)
 		if _, err := f.Write(append(data, '\n')); err != nil {
-			return fmt.Errorf("write rotated entry: %w", err)
+			return fmt.Errorf("write entry: %w", err)
 		}
 	}
+	return nil
+}
 
-	p.logger.Info("session rotated", "session", sessionID, "kept", len(keep), "removed", len(entries)-len(keep))
+// SaveCheckpoints writes the checkpoints of a session to {session_id}.checkpoints.json.
+func (p *SessionPersistence) SaveCheckpoints(sessionID string, checkpoints []SessionCheckpoint) error {
+	mu := p.fileMuFor(sessionID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := filepath.Join(p.dir, sanitizeSessionID(sessionID)+".checkpoints.json")
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal checkpoints: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		p.logger.Error("failed to write checkpoints", "session", sessionID, "err", err)
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
 	return nil
 }
 
+// LoadCheckpoints reads {session_id}.checkpoints.json. A missing file is not an error.
+func (p *SessionPersistence) LoadCheckpoints(sessionID string) ([]SessionCheckpoint, error) {
+	mu := p.fileMuFor(sessionID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := filepath.Join(p.dir, sanitizeSessionID(sessionID)+".checkpoints.json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var checkpoints []SessionCheckpoint
+	if err := json.Unmarshal(b, &checkpoints); err != nil {
+		return nil, fmt.Errorf("unmarshal checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
 // Close flushes any buffers. JSONL writes are unbuffered (direct write), so this is a no-op for now.
 func (p *SessionPersistence) Close() error {
 	return nil
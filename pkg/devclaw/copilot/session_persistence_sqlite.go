@@ -12,7 +12,7 @@ import (
 )
 
 // SQLiteSessionPersistence stores session data in the devclaw.db tables:
-// session_entries, session_meta, session_facts.
+// session_entries, session_meta, session_facts, session_state.
 type SQLiteSessionPersistence struct {
 	db     *sql.DB
 	logger *slog.Logger
@@ -31,11 +31,12 @@ func NewSQLiteSessionPersistence(db *sql.DB, logger *slog.Logger) *SQLiteSession
 func (p *SQLiteSessionPersistence) SaveEntry(sessionID string, entry ConversationEntry) error {
 	_, err := p.db.Exec(`
 		INSERT INTO session_entries (session_id, user_message, assistant_response, created_at, meta)
-		VALUES (?, ?, ?, ?, '{}')`,
+		VALUES (?, ?, ?, ?, ?)`,
 		sessionID,
 		entry.UserMessage,
 		entry.AssistantResponse,
 		entry.Timestamp.UTC().Format(time.RFC3339),
+		encodeEntryMeta(entry.Meta),
 	)
 	if err != nil {
 		p.logger.Error("failed to save session entry", "session", sessionID, "err", err)
@@ -48,7 +49,7 @@ func (p *SQLiteSessionPersistence) SaveEntry(sessionID string, entry Conversatio
 func (p *SQLiteSessionPersistence) LoadSession(sessionID string) ([]ConversationEntry, []string, error) {
 	// Load entries.
 	rows, err := p.db.Query(`
-		SELECT user_message, assistant_response, created_at
+		SELECT user_message, assistant_response, created_at, meta
 		FROM session_entries
 		WHERE session_id = ?
 		ORDER BY id ASC`, sessionID)
@@ -62,11 +63,13 @@ func (p *SQLiteSessionPersistence) LoadSession(sessionID string) ([]Conversation
 		var (
 			e         ConversationEntry
 			createdAt string
+			meta      sql.NullString
 		)
-		if err := rows.Scan(&e.UserMessage, &e.AssistantResponse, &createdAt); err != nil {
+		if err := rows.Scan(&e.UserMessage, &e.AssistantResponse, &createdAt, &meta); err != nil {
 			return nil, nil, fmt.Errorf("scan session entry: %w", err)
 		}
 		e.Timestamp, _ = time.Parse(time.RFC3339, createdAt)
+		e.Meta = decodeEntryMeta(meta.String)
 		entries = append(entries, e)
 	}
 	if err := rows.Err(); err != nil {
@@ -133,7 +136,7 @@ func (p *SQLiteSessionPersistence) LoadAll() (map[string]*SessionData, error) {
 			continue
 		}
 
-		channel, chatID, config, activeSkills := p.loadMeta(id)
+		channel, chatID, config, activeSkills := p.LoadMeta(id)
 		result[id] = &SessionData{
 			ID:           id,
 			Channel:      channel,
@@ -195,9 +198,9 @@ func (p *SQLiteSessionPersistence) SaveMeta(sessionID, channel, chatID string, c
 	return nil
 }
 
-// DeleteSession removes all data for a session (entries, facts, meta).
+// DeleteSession removes all data for a session (entries, facts, meta, checkpoints).
 func (p *SQLiteSessionPersistence) DeleteSession(sessionID string) error {
-	for _, table := range []string{"session_entries", "session_facts", "session_meta"} {
+	for _, table := range []string{"session_entries", "session_facts", "session_meta", "session_state", "session_checkpoints"} {
 		if _, err := p.db.Exec(
 			fmt.Sprintf("DELETE FROM %s WHERE session_id = ?", table), sessionID,
 		); err != nil {
@@ -244,8 +247,8 @@ func (p *SQLiteSessionPersistence) Close() error {
 	return nil
 }
 
-// loadMeta reads session metadata from the session_meta table.
-func (p *SQLiteSessionPersistence) loadMeta(sessionID string) (channel, chatID string, config SessionConfig, activeSkills []string) {
+// LoadMeta reads session metadata from the session_meta table.
+func (p *SQLiteSessionPersistence) LoadMeta(sessionID string) (channel, chatID string, config SessionConfig, activeSkills []string) {
 	var configJSON, skillsJSON string
 	err := p.db.QueryRow(`
 		SELECT channel, chat_id, config, active_skills
@@ -258,3 +261,185 @@ func (p *SQLiteSessionPersistence) loadMeta(sessionID string) (channel, chatID s
 	_ = json.Unmarshal([]byte(skillsJSON), &activeSkills)
 	return channel, chatID, config, activeSkills
 }
+
+// SaveState upserts the session's token usage, workspace and last activity.
+func (p *SQLiteSessionPersistence) SaveState(sessionID string, state SessionState) error {
+	lastActive := state.LastActiveAt
+	if lastActive.IsZero() {
+		lastActive = time.Now()
+	}
+	_, err := p.db.Exec(`
+		INSERT INTO session_state
+			(session_id, workspace_id, prompt_tokens, completion_tokens, requests, last_active_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET
+			workspace_id      = excluded.workspace_id,
+			prompt_tokens     = excluded.prompt_tokens,
+			completion_tokens = excluded.completion_tokens,
+			requests          = excluded.requests,
+			last_active_at    = excluded.last_active_at`,
+		sessionID, state.WorkspaceID,
+		state.PromptTokens, state.CompletionTokens, state.Requests,
+		lastActive.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		p.logger.Error("failed to save session state", "session", sessionID, "err", err)
+		return fmt.Errorf("save session state: %w", err)
+	}
+	return nil
+}
+
+// LoadState reads the persisted session state. Returns false if none exists.
+func (p *SQLiteSessionPersistence) LoadState(sessionID string) (SessionState, bool) {
+	var (
+		st         SessionState
+		lastActive string
+	)
+	err := p.db.QueryRow(`
+		SELECT workspace_id, prompt_tokens, completion_tokens, requests, last_active_at
+		FROM session_state WHERE session_id = ?`, sessionID,
+	).Scan(&st.WorkspaceID, &st.PromptTokens, &st.CompletionTokens, &st.Requests, &lastActive)
+	if err != nil {
+		return SessionState{}, false
+	}
+	st.LastActiveAt, _ = time.Parse(time.RFC3339, lastActive)
+	return st, true
+}
+
+// PruneWorkspace deletes every session of the workspace whose last activity
+// is older than inactiveSince. Returns the number of sessions removed.
+func (p *SQLiteSessionPersistence) PruneWorkspace(workspaceID string, inactiveSince time.Time) (int, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const stale = `SELECT session_id FROM session_state
+		WHERE workspace_id = ? AND last_active_at < ?`
+	cutoff := inactiveSince.UTC().Format(time.RFC3339)
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM ("+stale+")", workspaceID, cutoff).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count stale sessions: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	// session_state goes last: the other deletes select from it.
+	for _, table := range []string{"session_entries", "session_facts", "session_meta", "session_checkpoints", "session_state"} {
+		if _, err := tx.Exec(
+			fmt.Sprintf("DELETE FROM %s WHERE session_id IN (%s)", table, stale),
+			workspaceID, cutoff,
+		); err != nil {
+			return 0, fmt.Errorf("prune %s: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit prune: %w", err)
+	}
+	return count, nil
+}
+
+// ReplaceHistory replaces all persisted entries of a session (used by /rewind).
+func (p *SQLiteSessionPersistence) ReplaceHistory(sessionID string, entries []ConversationEntry) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM session_entries WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("delete old entries: %w", err)
+	}
+	for _, e := range entries {
+		if _, err := tx.Exec(`
+			INSERT INTO session_entries (session_id, user_message, assistant_response, created_at, meta)
+			VALUES (?, ?, ?, ?, ?)`,
+			sessionID, e.UserMessage, e.AssistantResponse,
+			e.Timestamp.UTC().Format(time.RFC3339), encodeEntryMeta(e.Meta),
+		); err != nil {
+			return fmt.Errorf("insert entry: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveCheckpoints replaces the stored checkpoints of a session.
+func (p *SQLiteSessionPersistence) SaveCheckpoints(sessionID string, checkpoints []SessionCheckpoint) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM session_checkpoints WHERE session_id = ?", sessionID); err != nil {
+		return fmt.Errorf("delete old checkpoints: %w", err)
+	}
+	for _, cp := range checkpoints {
+		data, err := json.Marshal(cp)
+		if err != nil {
+			return fmt.Errorf("marshal checkpoint: %w", err)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO session_checkpoints (session_id, name, created_at, data) VALUES (?, ?, ?, ?)",
+			sessionID, cp.Name, cp.CreatedAt.UTC().Format(time.RFC3339Nano), string(data),
+		); err != nil {
+			return fmt.Errorf("insert checkpoint: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadCheckpoints reads the checkpoints of a session, oldest first.
+func (p *SQLiteSessionPersistence) LoadCheckpoints(sessionID string) ([]SessionCheckpoint, error) {
+	rows, err := p.db.Query(`
+		SELECT data FROM session_checkpoints
+		WHERE session_id = ?
+		ORDER BY created_at ASC`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SessionCheckpoint
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("scan checkpoint: %w", err)
+		}
+		var cp SessionCheckpoint
+		if err := json.Unmarshal([]byte(data), &cp); err != nil {
+			p.logger.Warn("skipping corrupt checkpoint", "session", sessionID, "err", err)
+			continue
+		}
+		out = append(out, cp)
+	}
+	return out, rows.Err()
+}
+
+// encodeEntryMeta serializes turn metadata for the meta column ("{}" when
+// there is none).
+func encodeEntryMeta(meta *TurnMeta) string {
+	if meta == nil {
+		return "{}"
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// decodeEntryMeta parses the meta column; empty objects yield nil.
+func decodeEntryMeta(s string) *TurnMeta {
+	if s == "" || s == "{}" {
+		return nil
+	}
+	var meta TurnMeta
+	if json.Unmarshal([]byte(s), &meta) != nil {
+		return nil
+	}
+	return &meta
+}
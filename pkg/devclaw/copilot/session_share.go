@@ -0,0 +1,197 @@
+// Package copilot – session_share.go publishes a session transcript as a
+// self-contained static HTML page, for /share and `devclaw sessions
+// publish`. Secrets are redacted before rendering, tool calls are collapsed
+// into <details> blocks, and the page is written to share.dir under an
+// unguessable name. With share.upload_command set, the page is uploaded
+// (aws s3 cp, gsutil cp, rclone copyto, ...) and the link is built from
+// share.base_url.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ShareConfig configures published session pages.
+type ShareConfig struct {
+	// Dir is where pages are written (default: ./data/shared).
+	Dir string `yaml:"dir"`
+
+	// BaseURL is the public URL the pages are served under, e.g. a bucket
+	// website or a reverse proxy on Dir. Links are BaseURL/<name>.
+	BaseURL string `yaml:"base_url"`
+
+	// UploadCommand uploads a page after it is written. {file} is the local
+	// path and {name} the file name, e.g.
+	// "aws s3 cp {file} s3://my-bucket/shared/{name} --content-type text/html".
+	UploadCommand string `yaml:"upload_command"`
+}
+
+// DefaultShareConfig returns the default share config.
+func DefaultShareConfig() ShareConfig {
+	return ShareConfig{Dir: "./data/shared"}
+}
+
+// shareUploadTimeout bounds the upload command.
+const shareUploadTimeout = 2 * time.Minute
+
+// Redacted returns a copy of the export with API keys, tokens and private
+// keys replaced by [REDACTED]. secrets are literal values to remove too.
+func (e *SessionExport) Redacted(secrets []string) *SessionExport {
+	var keep []string
+	for _, s := range secrets {
+		if len(s) >= 8 {
+			keep = append(keep, s)
+		}
+	}
+	redact := func(s string) string {
+		for _, secret := range keep {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+		return RedactSecrets(s)
+	}
+
+	out := *e
+	out.Facts = make([]string, len(e.Facts))
+	for i, f := range e.Facts {
+		out.Facts[i] = redact(f)
+	}
+	out.Messages = make([]ExportedMessage, len(e.Messages))
+	for i, m := range e.Messages {
+		m.User, m.Assistant = redact(m.User), redact(m.Assistant)
+		if m.Turn != nil {
+			turn := *m.Turn
+			turn.Tools = make([]TurnToolCall, len(m.Turn.Tools))
+			for j, tc := range m.Turn.Tools {
+				tc.Args, tc.Result = redact(tc.Args), redact(tc.Result)
+				turn.Tools[j] = tc
+			}
+			m.Turn = &turn
+		}
+		out.Messages[i] = m
+	}
+	return &out
+}
+
+// HTML renders the export as a standalone page with inline styles and no
+// external resources. Callers publishing the page should redact it first.
+func (e *SessionExport) HTML() ([]byte, error) {
+	title := e.Channel + ":" + e.ChatID
+	if e.Branch != "" {
+		title += " (branch " + e.Branch + ")"
+	}
+	var buf bytes.Buffer
+	err := sharePage.Execute(&buf, map[string]any{
+		"Title":    title,
+		"Export":   e,
+		"Exported": time.Now().Format("2006-01-02 15:04"),
+	})
+	return buf.Bytes(), err
+}
+
+// PublishSessionExport redacts and renders export, writes it to share.dir,
+// runs the upload command when configured, and returns the page's link
+// (base_url/<name>, or the local path without a base URL). The configured
+// API keys are redacted along with known secret formats.
+func PublishSessionExport(ctx context.Context, config *Config, export *SessionExport) (string, error) {
+	cfg := config.Share
+	page, err := export.Redacted(llmSecrets(config)).HTML()
+	if err != nil {
+		return "", fmt.Errorf("rendering page: %w", err)
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultShareConfig().Dir
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	token := make([]byte, 12)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("session-%s.html", hex.EncodeToString(token))
+	path, err := filepath.Abs(filepath.Join(cfg.Dir, name))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, page, 0o644); err != nil {
+		return "", fmt.Errorf("writing page: %w", err)
+	}
+
+	if cfg.UploadCommand != "" {
+		command := strings.NewReplacer("{file}", shellQuote(path), "{name}", name).Replace(cfg.UploadCommand)
+		uctx, cancel := context.WithTimeout(ctx, shareUploadTimeout)
+		defer cancel()
+		if out, err := shellCommand(uctx, command).CombinedOutput(); err != nil {
+			return "", fmt.Errorf("upload failed: %v: %s", err, truncate(strings.TrimSpace(string(out)), 300))
+		}
+	}
+
+	if cfg.BaseURL == "" {
+		return path, nil
+	}
+	return strings.TrimRight(cfg.BaseURL, "/") + "/" + name, nil
+}
+
+// sharePage is the template of a published transcript.
+var sharePage = template.Must(template.New("share").Funcs(template.FuncMap{
+	"when": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"dur":  receiptDuration,
+	"cost": func(v float64) string { return fmt.Sprintf("$%.4f", v) },
+	"inc":  func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Session {{.Title}}</title>
+<style>
+body{font:15px/1.55 -apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif;max-width:860px;margin:2rem auto;padding:0 1rem;color:#1f2328;background:#fff}
+header{border-bottom:1px solid #d0d7de;margin-bottom:1.5rem;padding-bottom:1rem}
+h1{font-size:1.4rem;margin:0 0 .4rem}
+.meta{color:#656d76;font-size:.85rem}
+.msg{margin:1.2rem 0}
+.who{font-weight:600;font-size:.8rem;text-transform:uppercase;letter-spacing:.04em;color:#656d76;margin-bottom:.2rem}
+.text{white-space:pre-wrap;word-wrap:break-word;padding:.7rem .9rem;border-radius:8px}
+.user .text{background:#ddf4ff}
+.assistant .text{background:#f6f8fa}
+details{margin:.3rem 0 .3rem 1rem;font-size:.85rem}
+summary{cursor:pointer;color:#0969da}
+summary .err{color:#cf222e}
+pre{white-space:pre-wrap;word-wrap:break-word;background:#f6f8fa;border:1px solid #d0d7de;border-radius:6px;padding:.5rem;font-size:.8rem;margin:.3rem 0}
+.turn{color:#656d76;font-size:.75rem;margin-top:.2rem}
+footer{border-top:1px solid #d0d7de;margin-top:2rem;padding-top:.8rem;color:#656d76;font-size:.75rem}
+@media (prefers-color-scheme:dark){body{background:#0d1117;color:#e6edf3}.user .text{background:#0c2d4d}.assistant .text,pre{background:#161b22}pre,header,footer{border-color:#30363d}}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<div class="meta">{{len .Export.Messages}} messages{{if not .Export.CreatedAt.IsZero}} · started {{when .Export.CreatedAt}}{{end}}{{if .Export.PromptTokens}} · {{.Export.PromptTokens}}+{{.Export.CompletionTokens}} tokens{{end}}{{if .Export.CostUSD}} · ~{{cost .Export.CostUSD}}{{end}}</div>
+</header>
+<main>
+{{range $i, $m := .Export.Messages}}
+<section id="m{{inc $i}}">
+{{if $m.User}}<div class="msg user"><div class="who">User · {{when $m.Timestamp}}</div><div class="text">{{$m.User}}</div></div>{{end}}
+{{with $m.Turn}}{{range .Tools}}
+<details><summary>{{.Name}}{{if .Error}} <span class="err">error</span>{{end}}{{if .Blocked}} <span class="err">blocked</span>{{end}}{{if .DurationMs}} · {{dur .DurationMs}}{{end}}</summary>
+{{if .Args}}<pre>{{.Args}}</pre>{{end}}{{if .Result}}<pre>{{.Result}}</pre>{{end}}{{if .Blocked}}<pre>{{.Blocked}}</pre>{{end}}
+</details>{{end}}{{end}}
+{{if $m.Assistant}}<div class="msg assistant"><div class="who">Assistant</div><div class="text">{{$m.Assistant}}</div>{{with $m.Turn}}{{if .Model}}<div class="turn">{{.Model}}{{if .PromptTokens}} · {{.PromptTokens}}+{{.CompletionTokens}} tokens{{end}}</div>{{end}}{{end}}</div>{{end}}
+</section>
+{{end}}
+</main>
+<footer>Shared from DevClaw on {{.Exported}}. Secrets were redacted automatically.</footer>
+</body>
+</html>
+`))
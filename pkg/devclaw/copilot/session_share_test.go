@@ -0,0 +1,54 @@
+package copilot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublishSessionExport_RedactedHTML(t *testing.T) {
+	t.Parallel()
+	key := "sk-proj-abcdefghijklmnopqrstuvwxyz0123"
+	export := newSessionExport("abc", "telegram", "42", SessionConfig{}, nil, []ConversationEntry{{
+		UserMessage:       "deploy with key " + key + " <script>alert(1)</script>",
+		AssistantResponse: "Done, token sekrit-config-key-123 set.",
+		Timestamp:         time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+		Meta: &TurnMeta{Model: "gpt-5-mini", Tools: []TurnToolCall{
+			{Name: "bash", Args: `{"command":"export KEY=` + key + `"}`, Result: "ok"},
+		}},
+	}}, time.Time{})
+
+	dir := t.TempDir()
+	cfg := &Config{Share: ShareConfig{Dir: dir, BaseURL: "https://share.example.com/s/"}}
+	cfg.API.APIKey = "sekrit-config-key-123"
+	link, err := PublishSessionExport(context.Background(), cfg, export)
+	if err != nil {
+		t.Fatalf("PublishSessionExport: %v", err)
+	}
+	name := strings.TrimPrefix(link, "https://share.example.com/s/")
+	if name == link || !strings.HasPrefix(name, "session-") {
+		t.Fatalf("unexpected link %q", link)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, leaked := range []string{key, "sekrit-config-key-123", "<script>alert"} {
+		if strings.Contains(page, leaked) {
+			t.Errorf("page contains %q", leaked)
+		}
+	}
+	for _, want := range []string{"<details><summary>bash", "[REDACTED]", "gpt-5-mini", "telegram:42"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	if export.Messages[0].Turn.Tools[0].Args == export.Redacted(nil).Messages[0].Turn.Tools[0].Args {
+		t.Error("Redacted must not modify the original export")
+	}
+}
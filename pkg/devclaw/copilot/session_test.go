@@ -1,6 +1,11 @@
 package copilot
 
-import "testing"
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestParseSessionKey(t *testing.T) {
 	t.Parallel()
@@ -97,3 +102,189 @@ func TestMakeSessionID(t *testing.T) {
 		t.Error("different inputs should produce different IDs")
 	}
 }
+
+func TestSessionStore_SQLiteRestoresState(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	persist := NewSQLiteSessionPersistence(db, nil)
+
+	// Sessions created before persistence is set are migrated.
+	store := NewSessionStore(nil)
+	store.workspaceID = "acme"
+	s := store.GetOrCreate("whatsapp", "123")
+	s.AddMessage("hi", "hello")
+	s.AddFact("prefers short answers")
+	store.SetPersistence(persist)
+
+	s.AddMessage("status?", "all green")
+	s.AddTokenUsage(100, 20)
+	s.SetThinkingLevel("high")
+
+	restarted := NewSessionStore(nil)
+	restarted.SetPersistence(persist)
+	r := restarted.GetOrCreate("whatsapp", "123")
+
+	if got := r.HistoryLen(); got != 2 {
+		t.Errorf("history = %d entries, want 2", got)
+	}
+	if facts := r.GetFacts(); len(facts) != 1 {
+		t.Errorf("facts = %v", facts)
+	}
+	if p, c, n := r.GetTokenUsage(); p != 100 || c != 20 || n != 1 {
+		t.Errorf("usage = %d/%d/%d", p, c, n)
+	}
+	if lvl := r.GetThinkingLevel(); lvl != "high" {
+		t.Errorf("thinking level = %q", lvl)
+	}
+}
+
+func TestSQLiteSessionPersistence_PruneWorkspace(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	p := NewSQLiteSessionPersistence(db, nil)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, tc := range []struct {
+		id, ws string
+		at     time.Time
+	}{
+		{"stale", "acme", old},
+		{"fresh", "acme", time.Now()},
+		{"other", "globex", old},
+	} {
+		_ = p.SaveEntry(tc.id, ConversationEntry{UserMessage: "u", AssistantResponse: "a", Timestamp: tc.at})
+		_ = p.SaveState(tc.id, SessionState{WorkspaceID: tc.ws, LastActiveAt: tc.at})
+	}
+
+	n, err := p.PruneWorkspace("acme", time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("pruned %d sessions, want 1", n)
+	}
+	if entries, _, _ := p.LoadSession("stale"); len(entries) != 0 {
+		t.Error("stale session entries not deleted")
+	}
+	for _, id := range []string{"fresh", "other"} {
+		if _, ok := p.LoadState(id); !ok {
+			t.Errorf("session %q should be kept", id)
+		}
+	}
+}
+
+func TestSessionStore_CheckpointRewindFork(t *testing.T) {
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	persist := NewSQLiteSessionPersistence(db, nil)
+
+	store := NewSessionStore(nil)
+	store.SetPersistence(persist)
+	s := store.GetOrCreate("whatsapp", "123")
+	s.AddMessage("plan the migration", "step 1, step 2")
+	if _, err := s.Checkpoint("before-db"); err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage("use postgres", "ok, postgres")
+
+	// Fork from the checkpoint: the branch shares only the first entry and
+	// becomes the session the chat is routed to.
+	fork, err := store.Fork("whatsapp", "123", "mysql", "before-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetOrCreate("whatsapp", "123"); got != fork || fork.HistoryLen() != 1 {
+		t.Fatalf("chat not routed to the fork (history %d)", fork.HistoryLen())
+	}
+	fork.AddMessage("use mysql", "ok, mysql")
+	if _, err := store.Fork("whatsapp", "123", "mysql", ""); err == nil {
+		t.Error("duplicate branch name accepted")
+	}
+
+	if err := store.SwitchBranch("whatsapp", "123", "main"); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.GetOrCreate("whatsapp", "123"); got != s || s.HistoryLen() != 2 {
+		t.Fatalf("main session not restored (history %d)", s.HistoryLen())
+	}
+
+	// Rewind drops what was said after the checkpoint, also on disk.
+	if _, err := s.Rewind(""); err != nil {
+		t.Fatal(err)
+	}
+	if s.HistoryLen() != 1 {
+		t.Errorf("history after rewind = %d, want 1", s.HistoryLen())
+	}
+	restarted := NewSessionStore(nil)
+	restarted.SetPersistence(persist)
+	r := restarted.GetOrCreate("whatsapp", "123")
+	if r.HistoryLen() != 1 || len(r.Checkpoints()) != 1 {
+		t.Errorf("restored history %d, checkpoints %d", r.HistoryLen(), len(r.Checkpoints()))
+	}
+	if err := restarted.SwitchBranch("whatsapp", "123", "mysql"); err != nil {
+		t.Fatalf("persisted branch not found: %v", err)
+	}
+	if got := restarted.GetOrCreate("whatsapp", "123"); got.Branch != "mysql" || got.HistoryLen() != 2 {
+		t.Errorf("branch %q with %d entries", got.Branch, got.HistoryLen())
+	}
+}
+
+func TestSessionExport_TurnMetaPersisted(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "devclaw.db")
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSessionStore(nil)
+	store.SetPersistence(NewSQLiteSessionPersistence(db, nil))
+	s := store.GetOrCreate("telegram", "42")
+
+	s.setTurnMeta("disk usage?", &TurnMeta{
+		Model:            "gpt-5-mini",
+		PromptTokens:     1200,
+		CompletionTokens: 80,
+		Tools:            []TurnToolCall{{Name: "bash", Args: `{"command":"df -h"}`, Result: "/dev/sda1 42%"}},
+	})
+	s.AddMessage("disk usage?", "42% used on /")
+	s.setTurnMeta("another message", &TurnMeta{Model: "other"})
+	s.AddMessage("thanks", "anytime") // metadata of a different message is not attached
+
+	export, err := LoadSessionExport(&Config{Database: DatabaseConfig{Path: dbPath}}, "telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Messages) != 2 || export.Channel != "telegram" || export.ChatID != "42" {
+		t.Fatalf("export = %+v", export)
+	}
+	turn := export.Messages[0].Turn
+	if turn == nil || turn.Model != "gpt-5-mini" || len(turn.Tools) != 1 || turn.Tools[0].Name != "bash" {
+		t.Fatalf("turn meta not restored: %+v", turn)
+	}
+	if export.Messages[1].Turn != nil {
+		t.Errorf("unexpected turn meta on second entry: %+v", export.Messages[1].Turn)
+	}
+	if export.PromptTokens != 1200 || export.CompletionTokens != 80 {
+		t.Errorf("totals = %d/%d", export.PromptTokens, export.CompletionTokens)
+	}
+
+	md := export.Markdown()
+	for _, want := range []string{"# Session telegram:42", "`bash` (ok)", "df -h", "42% used on /", "gpt-5-mini"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q", want)
+		}
+	}
+	if _, err := export.Render("xml"); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
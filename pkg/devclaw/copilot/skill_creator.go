@@ -25,7 +25,7 @@ import (
 
 // RegisterSkillCreatorTools registers skill management tools in the executor.
 // skillsDir is the workspace-level directory where user-created skills live.
-func RegisterSkillCreatorTools(executor *ToolExecutor, registry *skills.Registry, skillsDir string, logger *slog.Logger) {
+func RegisterSkillCreatorTools(executor *ToolExecutor, registry *skills.Registry, skillsDir string, events *StateEventLog, logger *slog.Logger) {
 	if skillsDir == "" {
 		skillsDir = "./skills"
 	}
@@ -293,6 +293,9 @@ metadata: %s
 			if err != nil {
 				return nil, fmt.Errorf("install failed: %w", err)
 			}
+			events.Emit(EventSkillInstall, CallerJIDFromContext(ctx), result.Name, map[string]any{
+				"source": result.Source, "updated": !result.IsNew,
+			})
 
 			// Hot-reload: reload the registry to pick up the new skill.
 			reloadCtx, reloadCancel := context.WithTimeout(ctx, 10*time.Second)
@@ -415,6 +418,11 @@ metadata: %s
 			}
 
 			installed, skipped, failed := skills.InstallDefaultSkills(skillsDir, names)
+			if installed > 0 {
+				events.Emit(EventSkillInstall, CallerJIDFromContext(ctx), strings.Join(names, ","), map[string]any{
+					"source": "defaults", "installed": installed,
+				})
+			}
 
 			// Hot-reload the registry to pick up new skills.
 			reloadCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -453,7 +461,7 @@ metadata: %s
 			},
 			"required": []string{"name"},
 		}),
-		func(_ context.Context, args map[string]any) (any, error) {
+		func(ctx context.Context, args map[string]any) (any, error) {
 			name, _ := args["name"].(string)
 			if name == "" {
 				return nil, fmt.Errorf("name is required")
@@ -469,6 +477,7 @@ metadata: %s
 			}
 
 			registry.Remove(name)
+			events.Emit(EventSkillRemove, CallerJIDFromContext(ctx), name, nil)
 
 			return fmt.Sprintf("Skill '%s' removed successfully.", name), nil
 		},
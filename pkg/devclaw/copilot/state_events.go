@@ -0,0 +1,352 @@
+// Package copilot – state_events.go implements an append-only log of
+// internal state changes: access grants, config reloads, workspace changes
+// and skill installs. Each change is one JSON line in data/events.jsonl with
+// a monotonically increasing sequence number, so external sync tools can
+// resume from the last seq they saw and owners can answer "who changed what
+// when" without digging through logs. `devclaw events tail` follows the file
+// and GET /api/events filters it.
+package copilot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State event types. The prefix before the dot is the event category, used
+// for filtering (e.g. "access" matches every access.* event).
+const (
+	EventAccessGrant        = "access.grant"
+	EventAccessRevoke       = "access.revoke"
+	EventAccessBlock        = "access.block"
+	EventAccessUnblock      = "access.unblock"
+	EventAccessGroupGrant   = "access.group_grant"
+	EventAccessTempGrant    = "access.temp_grant"
+	EventAccessGrantExpired = "access.grant_expired"
+	EventConfigReload       = "config.reload"
+	EventWorkspaceCreate    = "workspace.create"
+	EventWorkspaceDelete    = "workspace.delete"
+	EventWorkspaceUpdate    = "workspace.update"
+	EventWorkspaceAssign    = "workspace.assign"
+	EventWorkspaceUnassign  = "workspace.unassign"
+	EventSkillInstall       = "skill.install"
+	EventSkillRemove        = "skill.remove"
+)
+
+// stateEventPollInterval is how often FollowStateEvents checks for new lines.
+const stateEventPollInterval = 500 * time.Millisecond
+
+// EventLogConfig configures the state change event log.
+type EventLogConfig struct {
+	// Enabled turns on the event log (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Path is the JSONL file events are appended to (default: ./data/events.jsonl).
+	Path string `yaml:"path"`
+}
+
+// DefaultEventLogConfig returns the default event log configuration.
+func DefaultEventLogConfig() EventLogConfig {
+	return EventLogConfig{
+		Enabled: true,
+		Path:    "./data/events.jsonl",
+	}
+}
+
+// StateEvent is one recorded state change.
+type StateEvent struct {
+	Seq     int64          `json:"seq"`
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`
+	Actor   string         `json:"actor,omitempty"`   // who made the change ("config", "system", a JID)
+	Subject string         `json:"subject,omitempty"` // what changed (contact, workspace ID, skill name)
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// Format renders the event as a single human-readable line.
+func (e StateEvent) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s %-22s", e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Type)
+	if e.Subject != "" {
+		fmt.Fprintf(&b, " %s", e.Subject)
+	}
+	if e.Actor != "" {
+		fmt.Fprintf(&b, " by %s", e.Actor)
+	}
+	if len(e.Data) > 0 {
+		data, _ := json.Marshal(e.Data)
+		fmt.Fprintf(&b, " %s", data)
+	}
+	return b.String()
+}
+
+// StateEventFilter selects events. Zero fields match everything.
+type StateEventFilter struct {
+	// Types matches event types exactly or by category ("access" or "access.*").
+	Types []string
+
+	// Actor and Subject match exactly.
+	Actor   string
+	Subject string
+
+	// Since keeps events at or after this time.
+	Since time.Time
+
+	// AfterSeq keeps events with a higher sequence number (resume point).
+	AfterSeq int64
+
+	// Limit caps the result (0 = all): the most recent N matches, or the
+	// first N after AfterSeq when resuming, so a sync client can page.
+	Limit int
+}
+
+// Match reports whether the event passes the filter (ignores Limit).
+func (f StateEventFilter) Match(e StateEvent) bool {
+	if e.Seq <= f.AfterSeq {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Subject != "" && e.Subject != f.Subject {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		t = strings.TrimSuffix(t, ".*")
+		if e.Type == t || strings.HasPrefix(e.Type, t+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// StateEventLog appends state events to a JSONL file. A nil log discards
+// events, so callers don't need to check whether it is enabled.
+type StateEventLog struct {
+	path   string
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+	size int64 // file size after our last write
+}
+
+// OpenStateEventLog opens (or creates) the event log at path and resumes
+// the sequence from the last recorded event.
+func OpenStateEventLog(path string, logger *slog.Logger) (*StateEventLog, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create event log directory: %w", err)
+	}
+
+	var last int64
+	size, err := scanStateEvents(path, 0, func(e StateEvent) {
+		last = max(last, e.Seq)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read event log: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+	return &StateEventLog{
+		path:   path,
+		logger: logger.With("component", "event-log"),
+		file:   f,
+		seq:    last,
+		size:   size,
+	}, nil
+}
+
+// Path returns the event log file path.
+func (l *StateEventLog) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Emit appends an event. Write errors are logged, never returned: a full
+// disk must not fail the change being recorded.
+func (l *StateEventLog) Emit(eventType, actor, subject string, data map[string]any) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+
+	// Another process (e.g. `devclaw skill install` while serving) may have
+	// appended since our last write; continue after its sequence numbers.
+	if st, err := l.file.Stat(); err == nil && st.Size() != l.size {
+		next, _ := scanStateEvents(l.path, min(l.size, st.Size()), func(e StateEvent) {
+			l.seq = max(l.seq, e.Seq)
+		})
+		l.size = next
+	}
+
+	l.seq++
+	line, err := json.Marshal(StateEvent{
+		Seq:     l.seq,
+		Time:    time.Now().UTC(),
+		Type:    eventType,
+		Actor:   actor,
+		Subject: subject,
+		Data:    data,
+	})
+	if err != nil {
+		l.logger.Warn("encoding state event failed", "type", eventType, "error", err)
+		return
+	}
+	n, err := l.file.Write(append(line, '\n'))
+	l.size += int64(n)
+	if err != nil {
+		l.logger.Warn("writing state event failed", "type", eventType, "error", err)
+	}
+}
+
+// Query returns the events matching the filter, oldest first.
+func (l *StateEventLog) Query(f StateEventFilter) ([]StateEvent, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return ReadStateEvents(l.path, f)
+}
+
+// Close closes the underlying file.
+func (l *StateEventLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// ReadStateEvents reads the events in the file at path that match the
+// filter, oldest first. A missing file yields no events.
+func ReadStateEvents(path string, f StateEventFilter) ([]StateEvent, error) {
+	var out []StateEvent
+	_, err := scanStateEvents(path, 0, func(e StateEvent) {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		if f.AfterSeq > 0 {
+			out = out[:f.Limit]
+		} else {
+			out = out[len(out)-f.Limit:]
+		}
+	}
+	return out, nil
+}
+
+// FollowStateEvents calls fn for every new matching event appended to the
+// file at path after the current end, until ctx is done. Truncation (e.g.
+// the file was rotated) restarts from the beginning.
+func FollowStateEvents(ctx context.Context, path string, f StateEventFilter, fn func(StateEvent)) error {
+	var offset int64
+	if st, err := os.Stat(path); err == nil {
+		offset = st.Size()
+	}
+
+	ticker := time.NewTicker(stateEventPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		st, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if st.Size() < offset {
+			offset = 0
+		}
+		if st.Size() == offset {
+			continue
+		}
+		next, err := scanStateEvents(path, offset, func(e StateEvent) {
+			if f.Match(e) {
+				fn(e)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		offset = next
+	}
+}
+
+// scanStateEvents decodes complete lines starting at offset and returns
+// the offset just after the last complete line. Malformed lines are skipped.
+func scanStateEvents(path string, offset int64, fn func(StateEvent)) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// A partial trailing line is still being written; pick it up
+			// on the next scan.
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+		offset += int64(len(line))
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var e StateEvent
+		if json.Unmarshal(line, &e) == nil {
+			fn(e)
+		}
+	}
+}
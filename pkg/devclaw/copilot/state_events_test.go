@@ -0,0 +1,52 @@
+package copilot
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAccess_ChangesRecordedInEventLog(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	el, err := OpenStateEventLog(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := NewAccessManager(AccessConfig{DefaultPolicy: PolicyDeny}, nil)
+	am.SetEventLog(el)
+
+	_ = am.Grant("alice@s.whatsapp.net", AccessUser, "owner")
+	am.Block("bob@s.whatsapp.net", "owner")
+	am.Revoke("alice@s.whatsapp.net", "admin")
+	el.Emit(EventWorkspaceCreate, "owner", "sales", nil)
+	el.Close()
+
+	// Reopening resumes the sequence.
+	el, err = OpenStateEventLog(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	el.Emit(EventConfigReload, "config", "", nil)
+	el.Close()
+
+	all, err := ReadStateEvents(path, StateEventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 || all[4].Seq != 5 || all[4].Type != EventConfigReload {
+		t.Fatalf("unexpected events: %+v", all)
+	}
+
+	access, _ := ReadStateEvents(path, StateEventFilter{Types: []string{"access"}})
+	if len(access) != 3 || access[0].Type != EventAccessGrant || access[0].Subject != "alice@s.whatsapp.net" {
+		t.Errorf("unexpected access events: %+v", access)
+	}
+	byOwner, _ := ReadStateEvents(path, StateEventFilter{Actor: "owner", Limit: 1})
+	if len(byOwner) != 1 || byOwner[0].Type != EventWorkspaceCreate {
+		t.Errorf("expected most recent owner event, got %+v", byOwner)
+	}
+	page, _ := ReadStateEvents(path, StateEventFilter{AfterSeq: 2, Limit: 2})
+	if len(page) != 2 || page[0].Seq != 3 || page[1].Seq != 4 {
+		t.Errorf("expected seq 3-4 when resuming, got %+v", page)
+	}
+}
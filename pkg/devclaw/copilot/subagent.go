@@ -15,6 +15,8 @@
 //   - Cannot spawn nested subagents (no recursion).
 //   - Have a configurable subset of tools (deny list applied).
 //   - Results are collected and can be polled or waited on.
+//   - Report progress (turn, current tool, partial output) to the parent
+//     chat while they run, and can be inspected with subagent_status.
 //   - Are announced back to the parent session when complete.
 package copilot
 
@@ -23,6 +25,10 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -51,6 +57,19 @@ type SubagentConfig struct {
 
 	// Model overrides the LLM model for subagents (empty = use parent model).
 	Model string `yaml:"model"`
+
+	// ContextMaxChars caps the parent context shared via spawn selectors
+	// (default: 24000).
+	ContextMaxChars int `yaml:"context_max_chars"`
+
+	// WorkspaceRoot is the directory files shared via spawn selectors are
+	// resolved against and must stay inside (default: the directory
+	// DevClaw was started in).
+	WorkspaceRoot string `yaml:"workspace_root"`
+
+	// Templates defines predefined roles spawn_subagent can reference by
+	// name (default: researcher, coder, reviewer).
+	Templates map[string]SubagentTemplate `yaml:"templates"`
 }
 
 // DefaultSubagentDeniedTools lists tools subagents should not access.
@@ -61,6 +80,7 @@ var DefaultSubagentDeniedTools = []string{
 	"list_subagents",
 	"wait_subagent",
 	"stop_subagent",
+	"subagent_status",
 	// Memory tools (subagents should not pollute parent's memory).
 	"memory_save",
 	"memory_search",
@@ -78,11 +98,13 @@ var DefaultSubagentDeniedTools = []string{
 // DefaultSubagentConfig returns safe defaults.
 func DefaultSubagentConfig() SubagentConfig {
 	return SubagentConfig{
-		Enabled:        true,
-		MaxConcurrent:  8,
-		MaxTurns:       0,   // Unlimited (aligned with agent loop)
-		TimeoutSeconds: 600, // 10 minutes — enough for research tasks that do many web searches
-		DeniedTools:    DefaultSubagentDeniedTools,
+		Enabled:         true,
+		MaxConcurrent:   8,
+		MaxTurns:        0,   // Unlimited (aligned with agent loop)
+		TimeoutSeconds:  600, // 10 minutes — enough for research tasks that do many web searches
+		DeniedTools:     DefaultSubagentDeniedTools,
+		ContextMaxChars: DefaultSubagentContextMaxChars,
+		Templates:       DefaultSubagentTemplates(),
 	}
 }
 
@@ -121,6 +143,9 @@ type SubagentRun struct {
 	// Model is the LLM model used for this run.
 	Model string `json:"model,omitempty"`
 
+	// Template is the subagent template the run was spawned from.
+	Template string `json:"template,omitempty"`
+
 	// ParentSessionID is the session that spawned this subagent.
 	ParentSessionID string `json:"parent_session_id"`
 
@@ -136,6 +161,24 @@ type SubagentRun struct {
 	// TokensUsed tracks approximate token usage.
 	TokensUsed int `json:"tokens_used,omitempty"`
 
+	// Turns counts the agent turns that called tools so far.
+	Turns int `json:"turns,omitempty"`
+
+	// CurrentTool names the tools of the latest turn.
+	CurrentTool string `json:"current_tool,omitempty"`
+
+	// PartialOutput is the latest intermediate text of the subagent.
+	PartialOutput string `json:"partial_output,omitempty"`
+
+	// LastProgressAt is when the subagent last reported progress.
+	LastProgressAt time.Time `json:"last_progress_at,omitempty"`
+
+	// progress forwards progress messages to the parent chat (nil = none).
+	progress ProgressSender
+
+	// progressSentAt is when progress was last forwarded.
+	progressSentAt time.Time
+
 	// cancel is the context cancel function for this run.
 	cancel context.CancelFunc `json:"-"`
 
@@ -169,6 +212,9 @@ type SubagentManager struct {
 	// instead of requiring the parent to poll with wait_subagent.
 	announceCallback AnnounceCallback
 
+	// sessionResolver finds the parent session for context selectors.
+	sessionResolver func(sessionID string) *Session
+
 	mu sync.RWMutex
 }
 
@@ -186,6 +232,12 @@ func NewSubagentManager(cfg SubagentConfig, logger *slog.Logger) *SubagentManage
 	if cfg.TimeoutSeconds <= 0 {
 		cfg.TimeoutSeconds = 300
 	}
+	if cfg.WorkspaceRoot == "" {
+		cfg.WorkspaceRoot, _ = os.Getwd()
+	}
+	if abs, err := filepath.Abs(cfg.WorkspaceRoot); err == nil {
+		cfg.WorkspaceRoot = abs
+	}
 
 	return &SubagentManager{
 		cfg:       cfg,
@@ -204,6 +256,14 @@ func (m *SubagentManager) SetAnnounceCallback(cb AnnounceCallback) {
 	m.announceCallback = cb
 }
 
+// SetSessionResolver registers the lookup used to share parent session
+// context (facts, recent turns) with subagents.
+func (m *SubagentManager) SetSessionResolver(fn func(sessionID string) *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessionResolver = fn
+}
+
 // SetDB wires the central SQLite database for persisting subagent runs.
 // When set, completed/failed runs survive process restarts.
 func (m *SubagentManager) SetDB(db *sql.DB) {
@@ -360,11 +420,28 @@ func (m *SubagentManager) PruneOldRuns(days int) int {
 
 // SpawnParams holds parameters for spawning a subagent.
 type SpawnParams struct {
-	Task            string
-	Label           string
-	Model           string
+	Task           string
+	Label          string
+	Model          string
+	TimeoutSeconds int
+
+	// ParentSessionID is the session ("channel:chatID") that spawned the
+	// subagent. Context selectors read facts and turns from it, and the
+	// announce callback posts the result to that chat when the run ends.
+	// Empty = no session context and no announcement.
 	ParentSessionID string
-	TimeoutSeconds  int
+
+	// Template names a subagents.templates entry that sets the role
+	// prompt, model, tools and turn limit. Empty = a generic subagent.
+	Template string
+
+	// Context selects the parent context shared with the subagent.
+	// Nil = the subagent sees only its task.
+	Context *SubagentContext
+
+	// Progress receives progress messages for the parent chat.
+	// Nil = the subagent runs silently until it is announced.
+	Progress ProgressSender
 }
 
 // Spawn creates and starts a new subagent. Returns the run ID immediately.
@@ -380,12 +457,26 @@ func (m *SubagentManager) Spawn(
 		return nil, fmt.Errorf("subagent system is disabled")
 	}
 
+	var tmpl SubagentTemplate
+	if params.Template != "" {
+		var err error
+		if tmpl, err = m.template(params.Template); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check concurrency limit.
 	activeCount := m.ActiveCount()
 	if activeCount >= m.cfg.MaxConcurrent {
 		return nil, fmt.Errorf("max concurrent subagents reached (%d/%d)", activeCount, m.cfg.MaxConcurrent)
 	}
 
+	// Resolve context selectors up front so bad selectors fail the spawn.
+	sharedContext, shared, err := m.buildSharedContext(params.Context, params.ParentSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("context selectors: %w", err)
+	}
+
 	// Create the run.
 	runID := uuid.New().String()[:8]
 	timeout := time.Duration(m.cfg.TimeoutSeconds) * time.Second
@@ -401,12 +492,18 @@ func (m *SubagentManager) Spawn(
 		Task:            params.Task,
 		Status:          SubagentStatusRunning,
 		Model:           params.Model,
+		Template:        params.Template,
 		ParentSessionID: params.ParentSessionID,
 		StartedAt:       time.Now(),
 		cancel:          cancel,
 		done:            make(chan struct{}),
+		progress:        params.Progress,
 	}
+	run.progressSentAt = run.StartedAt
 
+	if run.Label == "" && params.Template != "" {
+		run.Label = fmt.Sprintf("%s-%s", params.Template, runID)
+	}
 	if run.Label == "" {
 		run.Label = fmt.Sprintf("subagent-%s", runID)
 	}
@@ -423,16 +520,20 @@ func (m *SubagentManager) Spawn(
 		"label", run.Label,
 		"task_preview", truncate(params.Task, 80),
 		"timeout", timeout,
+		"shared_context", shared,
 	)
 
 	// Create a filtered tool executor for the subagent.
-	childExecutor := m.createChildExecutor(parentExecutor)
+	childExecutor := m.createChildExecutor(parentExecutor, WorkspaceIDFromContext(parentCtx), tmpl.Tools)
 
-	// Determine model (subagent override > spawn param > parent).
+	// Determine model (spawn param > template > subagent config > parent).
 	model := llmClient.model
 	if m.cfg.Model != "" {
 		model = m.cfg.Model
 	}
+	if tmpl.Model != "" {
+		model = tmpl.Model
+	}
 	if params.Model != "" {
 		model = params.Model
 	}
@@ -455,6 +556,10 @@ func (m *SubagentManager) Spawn(
 		defer close(run.done)
 		defer cancel()
 
+		// Joins the parent's trace when the spawning tool call was traced.
+		runCtx, span := startSpan(ctx, "subagent", "subagent.id", runID, "subagent.label", params.Label, "llm.model", model)
+		defer span.End()
+
 		// Acquire semaphore slot.
 		select {
 		case m.semaphore <- struct{}{}:
@@ -484,18 +589,28 @@ func (m *SubagentManager) Spawn(
 		}
 
 		// Build a minimal system prompt for the subagent.
-		systemPrompt := m.buildSubagentPrompt(promptComposer, session, params.Task)
+		systemPrompt := m.buildSubagentPrompt(promptComposer, session, params.Task, tmpl.SystemPrompt)
+		if sharedContext != "" {
+			systemPrompt += "\n" + sharedContext
+		}
 
 		// Create and run the agent.
 		agent := NewAgentRun(childLLM, childExecutor, m.logger)
 		if m.cfg.MaxTurns > 0 {
 			agent.maxTurns = m.cfg.MaxTurns // 0 = unlimited
 		}
+		if tmpl.MaxTurns > 0 {
+			agent.maxTurns = tmpl.MaxTurns
+		}
 		// Subagent run timeout is driven by the context timeout set above,
 		// so set the agent's own run timeout generously (it won't exceed ctx).
 		agent.runTimeout = timeout + 30*time.Second
+		agent.SetOnTurn(func(turn int, content string, calls []ToolCall) {
+			m.recordProgress(run, turn, content, calls)
+		})
 
-		result, err := agent.Run(ctx, systemPrompt, nil, params.Task)
+		result, err := agent.Run(runCtx, systemPrompt, nil, params.Task)
+		span.SetError(err)
 
 		if ctx.Err() == context.DeadlineExceeded {
 			m.completeRun(run, result, fmt.Errorf("timeout after %v", timeout))
@@ -507,6 +622,88 @@ func (m *SubagentManager) Spawn(
 	return run, nil
 }
 
+// subagentProgressInterval is the minimum time between progress messages of
+// one subagent, so a busy subagent does not flood the parent chat.
+const subagentProgressInterval = 20 * time.Second
+
+// subagentPartialMax caps the partial output kept for subagent_status.
+const subagentPartialMax = 400
+
+// recordProgress stores a turn of a running subagent and forwards it to the
+// parent chat when the run has a ProgressSender.
+func (m *SubagentManager) recordProgress(run *SubagentRun, turn int, content string, calls []ToolCall) {
+	names := make([]string, len(calls))
+	for i, tc := range calls {
+		names[i] = tc.Function.Name
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	run.Turns = turn
+	run.CurrentTool = strings.Join(names, ", ")
+	if content = strings.TrimSpace(content); content != "" {
+		run.PartialOutput = truncate(content, subagentPartialMax)
+	}
+	run.LastProgressAt = now
+	send := run.progress
+	if send != nil && now.Sub(run.progressSentAt) < subagentProgressInterval {
+		send = nil
+	}
+	if send != nil {
+		run.progressSentAt = now
+	}
+	label := run.Label
+	m.mu.Unlock()
+
+	if send != nil {
+		send(context.Background(), formatSubagentProgress(label, turn, calls))
+	}
+}
+
+// formatSubagentProgress builds the progress message of a subagent turn.
+func formatSubagentProgress(label string, turn int, calls []ToolCall) string {
+	msg := fmt.Sprintf("🧵 %s (turn %d)", label, turn)
+	if desc := formatToolProgressMessage(calls); desc != "" {
+		msg += ": " + desc
+	}
+	return msg
+}
+
+// describeRun formats the status of a run for subagent_status.
+func (m *SubagentManager) describeRun(run *SubagentRun) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	elapsed := run.Duration
+	if run.Status == SubagentStatusRunning {
+		elapsed = time.Since(run.StartedAt)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s (id: %s) — %s\n", run.Status, run.Label, run.ID, elapsed.Round(time.Second))
+	fmt.Fprintf(&b, "  task: %s\n", truncate(run.Task, 100))
+	if run.Turns > 0 {
+		fmt.Fprintf(&b, "  turns: %d\n", run.Turns)
+	}
+	if run.Status == SubagentStatusRunning {
+		if run.CurrentTool != "" {
+			fmt.Fprintf(&b, "  current tool: %s\n", run.CurrentTool)
+		}
+		if !run.LastProgressAt.IsZero() {
+			fmt.Fprintf(&b, "  last progress: %s ago\n", time.Since(run.LastProgressAt).Round(time.Second))
+		}
+		if run.PartialOutput != "" {
+			fmt.Fprintf(&b, "  partial output: %s\n", run.PartialOutput)
+		}
+	}
+	if run.Error != "" {
+		fmt.Fprintf(&b, "  error: %s\n", run.Error)
+	}
+	if run.Status != SubagentStatusRunning && run.Result != "" {
+		fmt.Fprintf(&b, "  result: %s\n", truncate(run.Result, 300))
+	}
+	return b.String()
+}
+
 // completeRun finalizes a subagent run with its result or error.
 // After updating state, fires the announce callback if registered.
 func (m *SubagentManager) completeRun(run *SubagentRun, result string, err error) {
@@ -667,7 +864,9 @@ func (m *SubagentManager) Cleanup(maxAge time.Duration) int {
 // createChildExecutor creates a filtered ToolExecutor for the subagent,
 // excluding denied tools to prevent recursion and unsafe operations.
 // Supports group references (e.g. "group:memory") in the deny list.
-func (m *SubagentManager) createChildExecutor(parent *ToolExecutor) *ToolExecutor {
+// Tools outside the parent's workspace registry are left out too, and a
+// non-empty allow list (a template's tools) keeps only the tools it names.
+func (m *SubagentManager) createChildExecutor(parent *ToolExecutor, wsID string, allow []string) *ToolExecutor {
 	child := NewToolExecutor(m.logger)
 
 	// Copy the guard from parent.
@@ -690,11 +889,17 @@ func (m *SubagentManager) createChildExecutor(parent *ToolExecutor) *ToolExecuto
 	denySet["list_subagents"] = true
 	denySet["wait_subagent"] = true
 	denySet["stop_subagent"] = true
+	denySet["subagent_status"] = true
 
 	// Copy allowed tools from parent.
+	scope := parent.workspaceScope(wsID)
+	allowed := ExpandToolGroups(allow)
 	parent.mu.RLock()
 	for name, rt := range parent.tools {
-		if denySet[name] {
+		if denySet[name] || !scope.empty() && !scope.permits(name, rt.Skill) {
+			continue
+		}
+		if len(allowed) > 0 && !slices.Contains(allowed, name) {
 			continue
 		}
 		child.tools[name] = rt
@@ -713,7 +918,8 @@ func (m *SubagentManager) createChildExecutor(parent *ToolExecutor) *ToolExecuto
 // buildSubagentPrompt creates a focused, minimal system prompt for the subagent.
 // Subagents get a lightweight bootstrap prompt, NOT the
 // full Compose() — this saves tokens and keeps the subagent focused on its task.
-func (m *SubagentManager) buildSubagentPrompt(composer *PromptComposer, session *Session, task string) string {
+// role is the system prompt of the subagent's template ("" = none).
+func (m *SubagentManager) buildSubagentPrompt(composer *PromptComposer, session *Session, task, role string) string {
 	// Use ComposeMinimal instead of full Compose — saves ~60% of system prompt tokens.
 	base := composer.ComposeMinimal()
 
@@ -740,13 +946,17 @@ You are a **subagent** spawned by the main agent for a specific task.
 - Try alternative approaches when one fails.
 `, task)
 
+	if role = strings.TrimSpace(role); role != "" {
+		subagentInstructions += "\n## Template Role\n" + role + "\n"
+	}
+
 	return base + "\n" + subagentInstructions
 }
 
 // ─── Tool Registration ───
 
 // RegisterSubagentTools registers the spawn_subagent, list_subagents,
-// wait_subagent, and stop_subagent tools in the tool executor. These allow
+// wait_subagent, stop_subagent and subagent_status tools in the tool executor. These allow
 // the main agent to create and manage child agents.
 func RegisterSubagentTools(
 	executor *ToolExecutor,
@@ -760,15 +970,25 @@ func RegisterSubagentTools(
 	}
 
 	// ── spawn_subagent ──
+	spawnDesc := "Spawn a subagent to handle a task concurrently. The subagent runs " +
+		"independently with its own context and tools. Use this for parallelizable " +
+		"tasks like: researching multiple topics, running commands while writing code, " +
+		"or handling independent subtasks. Returns immediately with a run_id."
+	templateParam := map[string]any{
+		"type":        "string",
+		"description": "Name of a predefined role that sets the subagent's prompt, model, tools and turn limit.",
+	}
+	if names := manager.templateNames(); len(names) > 0 {
+		spawnDesc += " Prefer a template when one fits the task:" + manager.describeTemplates()
+		templateParam["enum"] = names
+	}
 	executor.Register(
 		MakeToolDefinition("spawn_subagent",
-			"Spawn a subagent to handle a task concurrently. The subagent runs "+
-				"independently with its own context and tools. Use this for parallelizable "+
-				"tasks like: researching multiple topics, running commands while writing code, "+
-				"or handling independent subtasks. Returns immediately with a run_id.",
+			spawnDesc,
 			map[string]any{
 				"type": "object",
 				"properties": map[string]any{
+					"template": templateParam,
 					"task": map[string]any{
 						"type":        "string",
 						"description": "The task description for the subagent. Be specific and provide all context needed.",
@@ -779,12 +999,37 @@ func RegisterSubagentTools(
 					},
 					"model": map[string]any{
 						"type":        "string",
-						"description": "Override the LLM model for this subagent. Empty = use default.",
+						"description": "Override the LLM model for this subagent. Empty = the template's model or the default.",
 					},
 					"timeout_seconds": map[string]any{
 						"type":        "integer",
 						"description": "Max execution time in seconds. Default: 300 (5 minutes).",
 					},
+					"context": map[string]any{
+						"type": "object",
+						"description": "Parent context to share. Omit to give the subagent only its task. " +
+							"Share only what the task needs.",
+						"properties": map[string]any{
+							"facts": map[string]any{
+								"type":        "boolean",
+								"description": "Include this session's pinned facts.",
+							},
+							"last_turns": map[string]any{
+								"type":        "integer",
+								"description": "Include the last N conversation turns.",
+							},
+							"artifacts": map[string]any{
+								"type":        "array",
+								"items":       map[string]any{"type": "string"},
+								"description": "run_ids of completed subagents whose results to include.",
+							},
+							"files": map[string]any{
+								"type":        "array",
+								"items":       map[string]any{"type": "string"},
+								"description": "Paths of text files (relative to and inside the workspace root) to include.",
+							},
+						},
+					},
 				},
 				"required": []string{"task"},
 			},
@@ -797,18 +1042,25 @@ func RegisterSubagentTools(
 
 			label, _ := args["label"].(string)
 			model, _ := args["model"].(string)
+			template, _ := args["template"].(string)
 			timeoutSec := 0
 			if v, ok := args["timeout_seconds"].(float64); ok {
 				timeoutSec = int(v)
 			}
 
+			// The subagent outlives this tool call: only the workspace
+			// and the parent chat's progress sender carry over.
 			run, err := manager.Spawn(
-				context.Background(),
+				contextWithSpanOf(ContextWithWorkspace(context.Background(), WorkspaceIDFromContext(ctx)), ctx),
 				SpawnParams{
-					Task:           task,
-					Label:          label,
-					Model:          model,
-					TimeoutSeconds: timeoutSec,
+					Task:            task,
+					Label:           label,
+					Model:           model,
+					ParentSessionID: SessionIDFromContext(ctx),
+					TimeoutSeconds:  timeoutSec,
+					Template:        template,
+					Context:         parseSubagentContext(args["context"]),
+					Progress:        ProgressSenderFromContext(ctx),
 				},
 				llmClient,
 				executor,
@@ -823,7 +1075,7 @@ func RegisterSubagentTools(
 					"  run_id: %s\n"+
 					"  label: %s\n"+
 					"  status: running\n\n"+
-					"Use wait_subagent to get the result, or list_subagents to check status.",
+					"Use wait_subagent to get the result, or subagent_status to see its progress.",
 				run.ID, run.Label,
 			), nil
 		},
@@ -994,8 +1246,45 @@ func RegisterSubagentTools(
 		},
 	)
 
+	// ── subagent_status ──
+	executor.Register(
+		MakeToolDefinition("subagent_status",
+			"Show what subagents are doing: turn count, current tool and latest partial output. "+
+				"Without run_id, reports every running subagent.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"run_id": map[string]any{
+						"type":        "string",
+						"description": "The run_id of one subagent. Empty = all running subagents.",
+					},
+				},
+			},
+		),
+		func(_ context.Context, args map[string]any) (any, error) {
+			if runID, _ := args["run_id"].(string); runID != "" {
+				run, ok := manager.Get(runID)
+				if !ok {
+					return nil, fmt.Errorf("subagent run %q not found", runID)
+				}
+				return manager.describeRun(run), nil
+			}
+
+			var b strings.Builder
+			for _, run := range manager.List() {
+				if run.Status == SubagentStatusRunning {
+					b.WriteString(manager.describeRun(run))
+				}
+			}
+			if b.Len() == 0 {
+				return "No subagents running.", nil
+			}
+			return b.String(), nil
+		},
+	)
+
 	logger.Info("subagent tools registered",
-		"tools", []string{"spawn_subagent", "list_subagents", "wait_subagent", "stop_subagent"},
+		"tools", []string{"spawn_subagent", "list_subagents", "wait_subagent", "stop_subagent", "subagent_status"},
 		"max_concurrent", manager.cfg.MaxConcurrent,
 	)
 }
@@ -0,0 +1,185 @@
+// Package copilot – subagent_context.go implements explicit context selectors
+// for spawn_subagent. By default a subagent sees only its task; the parent
+// can opt in to share the session's pinned facts, the last N turns, results
+// of earlier subagent runs (artifacts) and a list of files. Everything shared
+// comes from the parent's own session, so nothing crosses workspaces.
+package copilot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultSubagentContextMaxChars caps the shared context injected into a
+// subagent prompt when not configured.
+const DefaultSubagentContextMaxChars = 24000
+
+// SubagentContext selects which parts of the parent context a subagent sees.
+type SubagentContext struct {
+	// Facts shares the parent session's pinned long-term facts.
+	Facts bool
+
+	// LastTurns shares the last N conversation turns of the parent session.
+	LastTurns int
+
+	// Artifacts lists run IDs of earlier subagents (spawned by the same
+	// parent session) whose results are shared.
+	Artifacts []string
+
+	// Files lists file paths whose contents are shared. Relative paths are
+	// resolved against the subagent workspace root, and every path must
+	// stay inside it.
+	Files []string
+}
+
+// IsEmpty reports whether nothing is selected.
+func (c *SubagentContext) IsEmpty() bool {
+	return c == nil || (!c.Facts && c.LastTurns <= 0 && len(c.Artifacts) == 0 && len(c.Files) == 0)
+}
+
+// parseSubagentContext reads the "context" argument of spawn_subagent.
+func parseSubagentContext(raw any) *SubagentContext {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil
+	}
+	sc := &SubagentContext{}
+	sc.Facts, _ = m["facts"].(bool)
+	if n, ok := m["last_turns"].(float64); ok && n > 0 {
+		sc.LastTurns = int(n)
+	}
+	sc.Artifacts = stringList(m["artifacts"])
+	sc.Files = stringList(m["files"])
+	return sc
+}
+
+func stringList(raw any) []string {
+	items, _ := raw.([]any)
+	var out []string
+	for _, it := range items {
+		if s, ok := it.(string); ok && strings.TrimSpace(s) != "" {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
+}
+
+// buildSharedContext renders the selected parent context as a prompt section.
+// It returns the section and a short description of what was included.
+func (m *SubagentManager) buildSharedContext(sel *SubagentContext, parentSessionID string) (string, []string, error) {
+	if sel.IsEmpty() {
+		return "", nil, nil
+	}
+
+	var parent *Session
+	if (sel.Facts || sel.LastTurns > 0) && parentSessionID != "" {
+		m.mu.RLock()
+		resolve := m.sessionResolver
+		m.mu.RUnlock()
+		if resolve != nil {
+			parent = resolve(parentSessionID)
+		}
+	}
+
+	budget := m.cfg.ContextMaxChars
+	if budget <= 0 {
+		budget = DefaultSubagentContextMaxChars
+	}
+
+	var b strings.Builder
+	var included []string
+	write := func(section string) {
+		if b.Len() >= budget {
+			return
+		}
+		if b.Len()+len(section) > budget {
+			section = truncateUTF8(section, budget-b.Len()) + "\n... (shared context truncated)\n"
+		}
+		b.WriteString(section)
+	}
+
+	if sel.Facts && parent != nil {
+		if facts := parent.GetFacts(); len(facts) > 0 {
+			var s strings.Builder
+			s.WriteString("### Pinned facts\n")
+			for _, f := range facts {
+				s.WriteString("- " + f + "\n")
+			}
+			write(s.String() + "\n")
+			included = append(included, fmt.Sprintf("%d facts", len(facts)))
+		}
+	}
+
+	if sel.LastTurns > 0 && parent != nil {
+		if turns := parent.RecentHistory(sel.LastTurns); len(turns) > 0 {
+			var s strings.Builder
+			s.WriteString("### Recent conversation\n")
+			for _, t := range turns {
+				s.WriteString("User: " + t.UserMessage + "\n")
+				if t.AssistantResponse != "" {
+					s.WriteString("Assistant: " + t.AssistantResponse + "\n")
+				}
+			}
+			write(s.String() + "\n")
+			included = append(included, fmt.Sprintf("%d turns", len(turns)))
+		}
+	}
+
+	for _, id := range sel.Artifacts {
+		run, ok := m.Get(id)
+		if !ok {
+			return "", nil, fmt.Errorf("artifact %q: subagent run not found", id)
+		}
+		if run.ParentSessionID != parentSessionID {
+			return "", nil, fmt.Errorf("artifact %q belongs to another session", id)
+		}
+		if run.Status != SubagentStatusCompleted {
+			return "", nil, fmt.Errorf("artifact %q is not completed (status: %s)", id, run.Status)
+		}
+		write(fmt.Sprintf("### Result of subagent %s (%s)\n%s\n\n", run.Label, run.ID, run.Result))
+		included = append(included, "artifact "+id)
+	}
+
+	if len(sel.Files) > 0 {
+		if m.cfg.WorkspaceRoot == "" {
+			return "", nil, fmt.Errorf("files: no workspace root configured")
+		}
+		containment := NewWorkspaceContainment(m.cfg.WorkspaceRoot)
+		for _, f := range sel.Files {
+			path, err := containment.AssertSandboxPath(f)
+			if err != nil {
+				return "", nil, fmt.Errorf("file %q: %w", f, err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", nil, fmt.Errorf("file %q: %w", f, err)
+			}
+			if !utf8.Valid(data) {
+				return "", nil, fmt.Errorf("file %q is not a text file", f)
+			}
+			write(fmt.Sprintf("### File: %s\n```\n%s\n```\n\n", f, string(data)))
+			included = append(included, "file "+f)
+		}
+	}
+
+	if b.Len() == 0 {
+		return "", included, nil
+	}
+	return "## Shared Context (selected by the parent agent)\n\n" + b.String(), included, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,119 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newContextTestManager(t *testing.T, root string, parent *Session) *SubagentManager {
+	t.Helper()
+	cfg := DefaultSubagentConfig()
+	cfg.WorkspaceRoot = root
+	m := NewSubagentManager(cfg, nil)
+	m.SetSessionResolver(func(id string) *Session {
+		if parent != nil && id == parent.ID {
+			return parent
+		}
+		return nil
+	})
+	return m
+}
+
+func TestBuildSharedContext_Selectors(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.md"), []byte("deploy on fridays"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	parent := &Session{ID: "telegram:42"}
+	parent.AddFact("the prod db is read-only")
+	parent.AddMessage("first question", "first answer")
+	parent.AddMessage("second question", "second answer")
+
+	m := newContextTestManager(t, root, parent)
+	m.runs["r1"] = &SubagentRun{ID: "r1", Label: "research", Status: SubagentStatusCompleted,
+		Result: "found three options", ParentSessionID: "telegram:42"}
+
+	got, included, err := m.buildSharedContext(&SubagentContext{
+		Facts: true, LastTurns: 1, Artifacts: []string{"r1"}, Files: []string{"notes.md"},
+	}, "telegram:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"the prod db is read-only", "second answer", "found three options",
+		"### File: notes.md", "deploy on fridays",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("shared context lacks %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "first question") {
+		t.Error("last_turns=1 should share only the latest turn")
+	}
+	if len(included) != 4 {
+		t.Errorf("included = %v", included)
+	}
+
+	if got, _, err := m.buildSharedContext(nil, "telegram:42"); got != "" || err != nil {
+		t.Errorf("no selectors = %q, %v", got, err)
+	}
+}
+
+func TestBuildSharedContext_Rejects(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin.dat"), []byte{0xff, 0xfe, 0x00}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newContextTestManager(t, root, nil)
+	m.runs["other"] = &SubagentRun{ID: "other", Status: SubagentStatusCompleted, ParentSessionID: "telegram:7"}
+	m.runs["busy"] = &SubagentRun{ID: "busy", Status: SubagentStatusRunning, ParentSessionID: "telegram:42"}
+
+	cases := map[string]*SubagentContext{
+		"missing artifact":     {Artifacts: []string{"nope"}},
+		"other session":        {Artifacts: []string{"other"}},
+		"running artifact":     {Artifacts: []string{"busy"}},
+		"relative escape":      {Files: []string{"../secret.txt"}},
+		"absolute escape":      {Files: []string{outside}},
+		"symlink escape":       {Files: []string{"link.txt"}},
+		"binary file":          {Files: []string{"bin.dat"}},
+		"missing file in root": {Files: []string{"missing.txt"}},
+	}
+	for name, sel := range cases {
+		if _, _, err := m.buildSharedContext(sel, "telegram:42"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBuildSharedContext_Budget(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "big.txt"), []byte(strings.Repeat("é", 400)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m := newContextTestManager(t, root, nil)
+	m.cfg.ContextMaxChars = 100
+
+	got, _, err := m.buildSharedContext(&SubagentContext{Files: []string{"big.txt"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "shared context truncated") || strings.Count(got, "é") > 50 {
+		t.Errorf("context not truncated to the budget: %d bytes", len(got))
+	}
+	if !strings.HasPrefix(got, "## Shared Context") {
+		t.Errorf("missing section header: %q", got)
+	}
+}
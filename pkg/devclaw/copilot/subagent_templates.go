@@ -0,0 +1,91 @@
+// Package copilot – subagent_templates.go implements predefined subagent
+// roles. A template (subagents.templates in the config) fixes the model,
+// role prompt, tool allowlist and turn limit of a subagent, so the main
+// agent can spawn a "researcher" or "reviewer" by name instead of
+// describing the role in every task.
+package copilot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SubagentTemplate defines a predefined subagent role.
+type SubagentTemplate struct {
+	// Description tells the main agent when to use the template.
+	Description string `yaml:"description"`
+
+	// Model overrides the subagent model (empty = subagents.model or parent).
+	Model string `yaml:"model"`
+
+	// SystemPrompt describes the role; it is added to the subagent prompt.
+	SystemPrompt string `yaml:"system_prompt"`
+
+	// Tools is the tool allowlist ("group:..." references expand).
+	// Empty = every tool subagents may use. subagents.denied_tools still applies.
+	Tools []string `yaml:"tools"`
+
+	// MaxTurns overrides subagents.max_turns (0 = keep it).
+	MaxTurns int `yaml:"max_turns"`
+}
+
+// DefaultSubagentTemplates returns the built-in roles. Templates with the
+// same name in the config replace them.
+func DefaultSubagentTemplates() map[string]SubagentTemplate {
+	readOnly := []string{"read_file", "list_files", "search_files", "glob_files"}
+	return map[string]SubagentTemplate{
+		"researcher": {
+			Description: "Researches a topic on the web and in local files and reports findings with sources.",
+			SystemPrompt: "You are a researcher. Gather facts from several sources, cross-check them, " +
+				"and report findings as a structured summary with the source of each claim. " +
+				"Say clearly what you could not confirm.",
+			Tools: append([]string{"group:web"}, readOnly...),
+		},
+		"coder": {
+			Description: "Implements a well-defined code change and verifies it builds and passes tests.",
+			SystemPrompt: "You are a software engineer. Read the surrounding code before changing it, " +
+				"follow its conventions, keep the change minimal, and run the build and tests. " +
+				"Report the files changed and the verification you ran.",
+			Tools: []string{"group:fs", "group:runtime"},
+		},
+		"reviewer": {
+			Description: "Reviews code or a diff for bugs, risks and style issues without changing anything.",
+			SystemPrompt: "You are a code reviewer. Do not modify files. Look for bugs, security issues, " +
+				"missing error handling and deviations from the codebase's conventions. " +
+				"Report findings ordered by severity, each with file, line and a suggested fix.",
+			Tools: readOnly,
+		},
+	}
+}
+
+// template returns the named template, or an error listing the known ones.
+func (m *SubagentManager) template(name string) (SubagentTemplate, error) {
+	if tmpl, ok := m.cfg.Templates[name]; ok {
+		return tmpl, nil
+	}
+	names := m.templateNames()
+	if len(names) == 0 {
+		return SubagentTemplate{}, fmt.Errorf("unknown subagent template %q (none configured)", name)
+	}
+	return SubagentTemplate{}, fmt.Errorf("unknown subagent template %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// templateNames returns the configured template names, sorted.
+func (m *SubagentManager) templateNames() []string {
+	names := make([]string, 0, len(m.cfg.Templates))
+	for name := range m.cfg.Templates {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// describeTemplates lists the templates for the spawn_subagent description.
+func (m *SubagentManager) describeTemplates() string {
+	var b strings.Builder
+	for _, name := range m.templateNames() {
+		fmt.Fprintf(&b, "\n- %s: %s", name, m.cfg.Templates[name].Description)
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSubagentTemplates_ToolsAndErrors(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewSubagentManager(DefaultSubagentConfig(), logger)
+	parent := NewToolExecutor(logger)
+	for _, name := range []string{"read_file", "write_file", "web_search", "bash", "spawn_subagent"} {
+		parent.Register(MakeToolDefinition(name, name, map[string]any{"type": "object"}),
+			func(context.Context, map[string]any) (any, error) { return "ok", nil })
+	}
+
+	tmpl, err := m.template("reviewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := m.createChildExecutor(parent, "", tmpl.Tools)
+	if _, ok := child.tools["read_file"]; !ok || len(child.tools) != 1 {
+		t.Errorf("reviewer should only get read_file, got %d tools", len(child.tools))
+	}
+	researcher, _ := m.template("researcher")
+	child = m.createChildExecutor(parent, "", researcher.Tools)
+	if _, ok := child.tools["web_search"]; !ok {
+		t.Error("researcher should get web_search through group:web")
+	}
+	if all := m.createChildExecutor(parent, "", nil); len(all.tools) != 4 {
+		t.Errorf("without a template only spawn_subagent is removed, got %d tools", len(all.tools))
+	}
+
+	if _, err := m.template("poet"); err == nil || !strings.Contains(err.Error(), "coder, researcher, reviewer") {
+		t.Errorf("unknown template error should list the templates, got %v", err)
+	}
+}
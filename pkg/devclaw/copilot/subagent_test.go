@@ -0,0 +1,76 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubagentSpawn_AnnouncesToParentSession(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"choices":[{"message":{"content":"all done"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DefaultConfig()
+	cfg.Model = "gpt-4o"
+	cfg.API.BaseURL = srv.URL
+	cfg.API.APIKey = "key"
+
+	m := NewSubagentManager(DefaultSubagentConfig(), logger)
+	announced := make(chan *SubagentRun, 2)
+	m.SetAnnounceCallback(func(run *SubagentRun) { announced <- run })
+
+	for _, parent := range []string{"telegram:42", ""} {
+		if _, err := m.Spawn(context.Background(), SpawnParams{Task: "summarize", ParentSessionID: parent},
+			NewLLMClient(cfg, logger), NewToolExecutor(logger), NewPromptComposer(cfg)); err != nil {
+			t.Fatalf("Spawn: %v", err)
+		}
+		select {
+		case run := <-announced:
+			if run.ParentSessionID != parent || run.Status != SubagentStatusCompleted || run.Result != "all done" {
+				t.Errorf("announced run = %+v", run)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("parent %q: completion was not announced", parent)
+		}
+	}
+}
+
+func TestSubagentProgress_ThrottledAndReported(t *testing.T) {
+	t.Parallel()
+
+	m := NewSubagentManager(DefaultSubagentConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var sent []string
+	run := &SubagentRun{
+		ID:        "abc",
+		Label:     "research",
+		Task:      "compare vendors",
+		Status:    SubagentStatusRunning,
+		StartedAt: time.Now(),
+		progress:  func(_ context.Context, msg string) { sent = append(sent, msg) },
+	}
+	m.runs[run.ID] = run
+
+	calls := []ToolCall{{Function: FunctionCall{Name: "web_search", Arguments: `{"query":"vendors"}`}}}
+	m.recordProgress(run, 1, "Looking up vendors", calls)
+	m.recordProgress(run, 2, "", calls)
+	if len(sent) != 1 || !strings.Contains(sent[0], "research (turn 1)") {
+		t.Fatalf("want one throttled progress message, got %q", sent)
+	}
+
+	status := m.describeRun(run)
+	for _, want := range []string{"turns: 2", "current tool: web_search", "partial output: Looking up vendors"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("status missing %q:\n%s", want, status)
+		}
+	}
+}
@@ -8,13 +8,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/memory"
@@ -30,6 +30,7 @@ func RegisterSystemTools(executor *ToolExecutor, sandboxRunner *sandbox.Runner,
 	registerWebSearchTool(executor, webSearchCfg)
 	registerWebFetchTool(executor, ssrfGuard)
 	registerFileTools(executor, dataDir)
+	registerApplyChangesTool(executor, dataDir)
 	registerBashTool(executor)
 
 	if sandboxRunner != nil {
@@ -405,12 +406,12 @@ func registerBashTool(executor *ToolExecutor) {
 
 	// bash — full access command execution inheriting the user's environment.
 	executor.Register(
-		MakeToolDefinition("bash", "Execute a bash command with full system access. Inherits the user's complete environment (PATH, SSH keys, etc). Supports cd (persistent between calls), git, ssh, docker, package managers, builds, system administration, or any shell operation. The command runs directly on the host machine as the current user.", map[string]any{
+		MakeToolDefinition("bash", bashToolDescription(), map[string]any{
 			"type": "object",
 			"properties": map[string]any{
 				"command": map[string]any{
 					"type":        "string",
-					"description": "Bash command to execute. cd is tracked between calls.",
+					"description": "Command to execute (bash; PowerShell on Windows hosts). cd is tracked between calls.",
 				},
 				"working_dir": map[string]any{
 					"type":        "string",
@@ -440,11 +441,7 @@ func registerBashTool(executor *ToolExecutor) {
 			cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 			defer cancel()
 
-			// Wrap in a login shell to inherit the user's full environment
-			// (~/.bashrc, ~/.profile, SSH agent, etc).
-			wrappedCmd := command
-
-			// If we have a persistent cwd, prepend cd.
+			// If we have a persistent cwd, run the command there.
 			wd := ""
 			if w, ok := args["working_dir"].(string); ok && w != "" {
 				wd = w
@@ -452,34 +449,17 @@ func registerBashTool(executor *ToolExecutor) {
 				wd = shellState.cwd
 			}
 
-			if wd != "" {
-				wrappedCmd = fmt.Sprintf("cd %q && %s", wd, command)
-			}
-
-			// Append pwd capture to track cd.
-			wrappedCmd += " ; __exit=$?; echo \"__DEVCLAW_CWD=$(pwd)\"; exit $__exit"
-
-			cmd := exec.CommandContext(cmdCtx, "bash", "-l", "-c", wrappedCmd)
-			// Create a new process group so we can kill all child processes
-			// (nohup, background &, etc.) when the timeout fires.
-			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-			cmd.Cancel = func() error {
-				// Kill the entire process group (negative PID).
-				return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			}
-			cmd.Env = os.Environ() // Inherit full user environment.
-
-			// Add any extra env vars set via set_env.
-			for k, v := range shellState.env {
-				cmd.Env = append(cmd.Env, k+"="+v)
-			}
+			cmd := bashToolCommand(cmdCtx, command, wd)
+			// Inherit the full user environment, with the workspace env and
+			// any vars set via set_env layered on top.
+			cmd.Env = sandbox.Environ(ctx, shellState.env)
 
 			out, err := cmd.CombinedOutput()
 			output := string(out)
 
 			// Extract and update persistent cwd.
-			if idx := strings.LastIndex(output, "__DEVCLAW_CWD="); idx >= 0 {
-				cwdLine := output[idx+len("__DEVCLAW_CWD="):]
+			if idx := strings.LastIndex(output, cwdMarker); idx >= 0 {
+				cwdLine := output[idx+len(cwdMarker):]
 				if nl := strings.Index(cwdLine, "\n"); nl >= 0 {
 					shellState.cwd = strings.TrimSpace(cwdLine[:nl])
 				} else {
@@ -489,7 +469,7 @@ func registerBashTool(executor *ToolExecutor) {
 				output = output[:idx]
 			}
 
-			output = strings.TrimRight(output, "\n ")
+			output = strings.TrimRight(output, "\r\n ")
 
 			// Truncate very long output.
 			if len(output) > 50000 {
@@ -571,11 +551,8 @@ func registerBashTool(executor *ToolExecutor) {
 			sshArgs = append(sshArgs, host, command)
 
 			cmd := exec.CommandContext(cmdCtx, "ssh", sshArgs...)
-			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-			cmd.Cancel = func() error {
-				return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			}
-			cmd.Env = os.Environ() // Inherit SSH agent, keys, etc.
+			sandbox.KillTreeOnCancel(cmd)
+			cmd.Env = sandbox.Environ(ctx, nil) // Inherit SSH agent, keys, etc. plus the workspace env.
 
 			out, err := cmd.CombinedOutput()
 			output := strings.TrimRight(string(out), "\n ")
@@ -641,11 +618,8 @@ func registerBashTool(executor *ToolExecutor) {
 			scpArgs = append(scpArgs, source, dest)
 
 			cmd := exec.CommandContext(cmdCtx, "scp", scpArgs...)
-			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
-			cmd.Cancel = func() error {
-				return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			}
-			cmd.Env = os.Environ()
+			sandbox.KillTreeOnCancel(cmd)
+			cmd.Env = sandbox.Environ(ctx, nil)
 
 			out, err := cmd.CombinedOutput()
 			output := strings.TrimRight(string(out), "\n ")
@@ -763,6 +737,56 @@ func registerFileTools(executor *ToolExecutor, _ string) {
 		},
 	)
 
+	// send_file — delivers a file (chart, PDF, screenshot) to the chat.
+	executor.Register(
+		MakeToolDefinition("send_file", "Send a file from disk to the user in the current chat (image, PDF, document, audio). Use to deliver artifacts you generated, e.g. charts, reports or screenshots. The file follows your reply.", map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "File path (absolute or relative)",
+				},
+				"caption": map[string]any{
+					"type":        "string",
+					"description": "Optional caption shown with the file",
+				},
+				"mime_type": map[string]any{
+					"type":        "string",
+					"description": "MIME type (default: detected from the extension or content)",
+				},
+			},
+			"required": []string{"path"},
+		}),
+		func(_ context.Context, args map[string]any) (any, error) {
+			filePath, _ := args["path"].(string)
+			if filePath == "" {
+				return nil, fmt.Errorf("path is required")
+			}
+			filePath = resolvePath(filePath)
+
+			st, err := os.Stat(filePath)
+			if err != nil {
+				return nil, fmt.Errorf("reading file: %w", err)
+			}
+			if st.IsDir() {
+				return nil, fmt.Errorf("%s is a directory", filePath)
+			}
+			if st.Size() == 0 {
+				return nil, fmt.Errorf("%s is empty", filePath)
+			}
+			if st.Size() > sendFileMaxBytes {
+				return nil, fmt.Errorf("file too large: %d bytes (max %d)", st.Size(), sendFileMaxBytes)
+			}
+
+			mimeType, _ := args["mime_type"].(string)
+			if mimeType == "" {
+				mimeType = detectFileMime(filePath)
+			}
+			caption, _ := args["caption"].(string)
+			return AttachmentBlock(filePath, mimeType, caption), nil
+		},
+	)
+
 	// write_file — writes to any file on the machine.
 	executor.Register(
 		MakeToolDefinition("write_file", "Write content to any file on the machine. Creates parent directories if needed. Supports absolute and relative paths.", map[string]any{
@@ -822,7 +846,7 @@ func registerFileTools(executor *ToolExecutor, _ string) {
 				_, err = f.WriteString(content)
 				f.Close()
 			} else {
-				err = os.WriteFile(filePath, []byte(content), fileMode)
+				err = writeFileAtomic(filePath, []byte(content), fileMode)
 			}
 			if err != nil {
 				return nil, fmt.Errorf("writing file: %w", err)
@@ -897,7 +921,7 @@ func registerFileTools(executor *ToolExecutor, _ string) {
 				mode = info.Mode()
 			}
 
-			if err := os.WriteFile(filePath, []byte(newContent), mode); err != nil {
+			if err := writeFileAtomic(filePath, []byte(newContent), mode.Perm()); err != nil {
 				return nil, fmt.Errorf("writing file: %w", err)
 			}
 
@@ -1160,15 +1184,30 @@ func registerFileTools(executor *ToolExecutor, _ string) {
 	)
 }
 
-// resolvePath resolves a file path, expanding ~ and making relative paths absolute.
-func resolvePath(p string) string {
-	if strings.HasPrefix(p, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			p = filepath.Join(home, p[2:])
-		}
+// sendFileMaxBytes caps files sent with send_file (most channels reject
+// larger uploads anyway).
+const sendFileMaxBytes = 50 << 20
+
+// detectFileMime returns the MIME type of a file from its extension,
+// falling back to sniffing the first 512 bytes.
+func detectFileMime(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
 	}
-	abs, err := filepath.Abs(p)
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := io.ReadFull(f, buf)
+	return http.DetectContentType(buf[:n])
+}
+
+// resolvePath resolves a file path, expanding ~ (and %VAR% on Windows) and
+// making relative paths absolute.
+func resolvePath(p string) string {
+	abs, err := filepath.Abs(expandPath(p))
 	if err != nil {
 		return p
 	}
@@ -1240,7 +1279,7 @@ func (c *osExecCmd) CombinedOutput() ([]byte, error) {
 func registerMemoryTools(executor *ToolExecutor, store *memory.FileStore, sqliteStore *memory.SQLiteStore, cfg MemoryConfig) {
 	// memory_save
 	executor.Register(
-		MakeToolDefinition("memory_save", "Save an important fact, preference, or piece of information to long-term memory. Use this to remember things about the user or important context.", map[string]any{
+		MakeToolDefinition("memory_save", "Save an important fact, preference, or piece of information to long-term memory. Use this to remember things about the user or important context. A fact that changes a remembered value (new address, new job) replaces the old version.", map[string]any{
 			"type": "object",
 			"properties": map[string]any{
 				"content": map[string]any{
@@ -1252,6 +1291,10 @@ func registerMemoryTools(executor *ToolExecutor, store *memory.FileStore, sqlite
 					"description": "Category: 'fact', 'preference', 'event', or 'summary'",
 					"enum":        []string{"fact", "preference", "event", "summary"},
 				},
+				"key": map[string]any{
+					"type":        "string",
+					"description": "What the fact is about, as subject/attribute (e.g. 'user/location', 'acme/deadline'). A newer fact with the same key replaces the older one. Optional: common phrasings like 'X lives in Y' are detected.",
+				},
 			},
 			"required": []string{"content"},
 		}),
@@ -1265,15 +1308,21 @@ func registerMemoryTools(executor *ToolExecutor, store *memory.FileStore, sqlite
 				category = "fact"
 			}
 
-			err := store.Save(memory.Entry{
+			key, _ := args["key"].(string)
+
+			res, err := store.SaveVersioned(memory.Entry{
 				Content:   content,
 				Source:    "agent",
 				Category:  category,
 				Timestamp: time.Now(),
-			})
+				Key:       key,
+			}, true)
 			if err != nil {
 				return nil, err
 			}
+			if res.Outcome == memory.SavedDuplicate {
+				return fmt.Sprintf("Already in memory: %s", content), nil
+			}
 
 			// Re-index the MEMORY.md file if SQLite memory is available.
 			if sqliteStore != nil && cfg.Index.Auto {
@@ -1287,6 +1336,9 @@ func registerMemoryTools(executor *ToolExecutor, store *memory.FileStore, sqlite
 				}()
 			}
 
+			if len(res.Superseded) > 0 {
+				return fmt.Sprintf("Saved to memory: %s (replaces: %s)", content, res.Superseded[len(res.Superseded)-1].Content), nil
+			}
 			return fmt.Sprintf("Saved to memory: %s", content), nil
 		},
 	)
@@ -1429,7 +1481,7 @@ func registerMemoryTools(executor *ToolExecutor, store *memory.FileStore, sqlite
 func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 	// cron_add
 	executor.Register(
-		MakeToolDefinition("cron_add", "Schedule a task. Use type='at' for ONE-TIME tasks (reminders, delayed messages). Use type='every' or 'cron' ONLY for RECURRING tasks.", map[string]any{
+		MakeToolDefinition("cron_add", "Schedule a task. Write the schedule in plain words as the user said it (e.g. 'in 20 minutes', 'tomorrow at 9am', 'every weekday at 9:00') instead of building cron expressions; the type is inferred. One-time tasks (reminders, delayed messages) fire once and are removed.", map[string]any{
 			"type": "object",
 			"properties": map[string]any{
 				"id": map[string]any{
@@ -1438,13 +1490,22 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 				},
 				"schedule": map[string]any{
 					"type":        "string",
-					"description": "For type='at': relative duration ('5m','1h') or absolute time ('14:30','2026-01-15 09:00'). For type='every': interval ('5m','1h'). For type='cron': cron expression ('0 9 * * *').",
+					"description": "When to run, in plain words or as a cron expression. One-time: 'in 20 minutes', 'at 14:30', 'tomorrow at 9am', 'next friday at 10', 'at 2026-01-15 09:00', or a date as the user wrote it ('15/03 at 9am', read in the user's locale). Interval: 'every 20m', 'every 2 hours'. Recurring: 'every day at 9am', 'every weekday at 18:30', 'every monday and thursday at 8:00', 'every month on the 1st at 9am', or cron '0 9 * * 1-5'.",
 				},
 				"type": map[string]any{
 					"type":        "string",
-					"description": "IMPORTANT: 'at' = fires ONCE then auto-removes (use for reminders, delayed tasks). 'every' = fires REPEATEDLY at interval (use for recurring checks). 'cron' = fires by cron schedule (use for daily/weekly tasks).",
+					"description": "Optional; inferred from the schedule. Only needed for bare values like '5m': 'at' = fires ONCE then auto-removes, 'every' = fires REPEATEDLY at that interval.",
 					"enum":        []string{"cron", "every", "at"},
 				},
+				"timezone": map[string]any{
+					"type":        "string",
+					"description": "Timezone the times in the schedule are meant in, when the user names one (e.g. 'every day at 9am São Paulo time' → 'America/Sao_Paulo'). IANA name or city. Default: the configured timezone. Daylight saving time is handled.",
+				},
+				"catch_up": map[string]any{
+					"type":        "string",
+					"description": "What to do with runs missed while DevClaw was offline: 'skip' (default), 'run_once' (run once on restart, e.g. a daily report), 'run_all' (replay each missed run, up to 24).",
+					"enum":        []string{"skip", "run_once", "run_all"},
+				},
 				"command": map[string]any{
 					"type":        "string",
 					"description": "The prompt/command to execute when the job fires",
@@ -1464,6 +1525,8 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 			id, _ := args["id"].(string)
 			schedule, _ := args["schedule"].(string)
 			jobType, _ := args["type"].(string)
+			timezone, _ := args["timezone"].(string)
+			catchUp, _ := args["catch_up"].(string)
 			command, _ := args["command"].(string)
 			channel, _ := args["channel"].(string)
 			chatID, _ := args["chat_id"].(string)
@@ -1471,9 +1534,6 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 			if id == "" || schedule == "" || command == "" {
 				return nil, fmt.Errorf("id, schedule, and command are required")
 			}
-			if jobType == "" {
-				jobType = "cron"
-			}
 
 			// Auto-fill channel/chatID from the context-propagated delivery target.
 			// This is goroutine-safe: each agent run carries its own context
@@ -1489,10 +1549,20 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 				}
 			}
 
+			// Add parses the schedule in the job's timezone and the
+			// caller's locale ("15/03" vs "03/15") and stores the
+			// normalized form.
+			ul, _ := LocaleFromContext(ctx)
+			if timezone == "" {
+				timezone = ul.Timezone
+			}
 			job := &scheduler.Job{
 				ID:       id,
 				Schedule: schedule,
 				Type:     jobType,
+				Timezone: timezone,
+				Locale:   ul.Tag,
+				CatchUp:  catchUp,
 				Command:  command,
 				Channel:  channel,
 				ChatID:   chatID,
@@ -1503,7 +1573,14 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 				return nil, err
 			}
 
-			return fmt.Sprintf("Job '%s' scheduled: %s (%s) → %s:%s", id, schedule, jobType, channel, chatID), nil
+			loc := sched.Location(job)
+			result := fmt.Sprintf("Job '%s' scheduled: %s (%s, %s) → %s:%s", id, job.Schedule, job.Type, loc, channel, chatID)
+			if next, err := sched.NextRun(job, time.Now()); err == nil {
+				next = next.In(loc)
+				result += fmt.Sprintf("\nNext run: %s %s %s. Check this matches what the user asked for.",
+					next.Format("Mon"), ul.FormatDateTime(next), next.Format("MST"))
+			}
+			return result, nil
 		},
 	)
 
@@ -1526,8 +1603,15 @@ func registerCronTools(executor *ToolExecutor, sched *scheduler.Scheduler) {
 				if !j.Enabled {
 					status = "disabled"
 				}
-				sb.WriteString(fmt.Sprintf("- **%s** [%s] schedule=%s type=%s\n  Command: %s\n  Runs: %d",
-					j.ID, status, j.Schedule, j.Type, j.Command, j.RunCount))
+				loc := sched.Location(j)
+				sb.WriteString(fmt.Sprintf("- **%s** [%s] schedule=%s type=%s timezone=%s\n  Command: %s\n  Runs: %d",
+					j.ID, status, j.Schedule, j.Type, loc, j.Command, j.RunCount))
+				if next, err := sched.NextRun(j, time.Now()); err == nil && j.Enabled {
+					sb.WriteString(fmt.Sprintf("  Next run: %s", next.In(loc).Format("Mon 2006-01-02 15:04 MST")))
+				}
+				if j.CatchUp != "" && j.CatchUp != scheduler.CatchUpSkip {
+					sb.WriteString(fmt.Sprintf("  Catch-up: %s", j.CatchUp))
+				}
 				if j.LastRunAt != nil {
 					sb.WriteString(fmt.Sprintf("  Last run: %s", j.LastRunAt.Format("2006-01-02 15:04")))
 				}
@@ -1599,7 +1683,7 @@ func registerVaultTools(executor *ToolExecutor, vault *Vault) {
 
 	// vault_get — retrieve a secret from the encrypted vault.
 	executor.Register(
-		MakeToolDefinition("vault_get", "Retrieve a secret from the encrypted vault by name.", map[string]any{
+		MakeToolDefinition("vault_get", "Show a secret from the encrypted vault to the owner. Every call waits for the owner's approval and the value is sent to the chat, not returned to you. Scripts and commands read vault secrets from environment variables instead (name uppercased, e.g. $GITHUB_TOKEN).", map[string]any{
 			"type": "object",
 			"properties": map[string]any{
 				"name": map[string]any{
@@ -1609,7 +1693,7 @@ func registerVaultTools(executor *ToolExecutor, vault *Vault) {
 			},
 			"required": []string{"name"},
 		}),
-		func(_ context.Context, args map[string]any) (any, error) {
+		func(ctx context.Context, args map[string]any) (any, error) {
 			name, _ := args["name"].(string)
 			if name == "" {
 				return nil, fmt.Errorf("name is required")
@@ -1621,7 +1705,11 @@ func registerVaultTools(executor *ToolExecutor, vault *Vault) {
 			if val == "" {
 				return fmt.Sprintf("Secret '%s' not found in vault.", name), nil
 			}
-			return val, nil
+			// The value goes to the chat; the model only learns it was sent.
+			if err := executor.sendSecret(ctx, fmt.Sprintf("🔐 %s: %s", name, val)); err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("Secret '%s' delivered to the owner's chat.", name), nil
 		},
 	)
 
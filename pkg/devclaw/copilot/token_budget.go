@@ -0,0 +1,94 @@
+// Package copilot – token_budget.go estimates the prompt size of an LLM
+// call against the model's context window, so the agent can compact the
+// conversation before sending it rather than after the provider rejects it
+// with a context overflow error.
+package copilot
+
+import "encoding/json"
+
+const (
+	// messageOverheadTokens approximates the per-message framing (role,
+	// separators) providers add around the content.
+	messageOverheadTokens = 4
+
+	// imagePartTokens is a flat estimate for an image content part; its
+	// base64 data says little about what the provider actually bills.
+	imagePartTokens = 1000
+
+	// contextSafetyRatio leaves headroom for estimation error: the
+	// heuristic can undercount code and non-Latin text.
+	contextSafetyRatio = 0.9
+)
+
+// estimatePromptTokens approximates the tokens a request with these
+// messages and tools will use.
+func estimatePromptTokens(messages []chatMessage, tools []ToolDefinition) int {
+	total := 0
+	for _, m := range messages {
+		total += messageOverheadTokens
+		switch c := m.Content.(type) {
+		case string:
+			total += estimateTokens(c)
+		case []contentPart:
+			for _, p := range c {
+				if p.ImageURL != nil {
+					total += imagePartTokens
+				} else {
+					total += estimateTokens(p.Text)
+				}
+			}
+		}
+		for _, tc := range m.ToolCalls {
+			total += estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+		}
+	}
+	if len(tools) > 0 {
+		if data, err := json.Marshal(tools); err == nil {
+			total += estimateTokens(string(data))
+		}
+	}
+	return total
+}
+
+// promptTokenBudget returns how many prompt tokens fit in the context
+// window after reserving room for the reply (0 = unknown, don't budget).
+func (a *AgentRun) promptTokenBudget() int {
+	if a.llm == nil {
+		return 0
+	}
+	window := a.contextWindow
+	if window <= 0 {
+		window = a.llm.ContextWindow(a.modelOverride)
+	}
+	if window <= 0 {
+		return 0
+	}
+	budget := int(float64(window)*contextSafetyRatio) - a.llm.maxOutputTokens(a.modelOverride)
+	return max(budget, 0)
+}
+
+// fitToContextWindow compacts messages until their estimated size fits the
+// prompt budget, giving up after maxCompactionAttempts steps or when a step
+// no longer helps (e.g. the system prompt alone is too large) — the call is
+// then sent as is and overflow recovery takes over.
+func (a *AgentRun) fitToContextWindow(messages []chatMessage, tools []ToolDefinition, compactor *contextCompactor) []chatMessage {
+	budget := a.promptTokenBudget()
+	if budget <= 0 {
+		return messages
+	}
+	estimate := estimatePromptTokens(messages, tools)
+	for i := 0; i < a.maxCompactionAttempts && estimate > budget; i++ {
+		a.logger.Info("prompt exceeds context budget, compacting before sending",
+			"estimated_tokens", estimate,
+			"budget_tokens", budget,
+			"messages", len(messages),
+		)
+		next := compactor.step(messages)
+		nextEstimate := estimatePromptTokens(next, tools)
+		if nextEstimate >= estimate {
+			break
+		}
+		messages, estimate = next, nextEstimate
+	}
+	return messages
+}
@@ -0,0 +1,153 @@
+package copilot
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFitToContextWindow_CompactsBeforeSending(t *testing.T) {
+	run := NewAgentRun(&LLMClient{model: "llama3.1", provider: "ollama"}, nil, slog.Default())
+	// 32768 * 0.9 - 4096 reserved for the reply.
+	if got := run.promptTokenBudget(); got != 25395 {
+		t.Fatalf("unexpected budget %d", got)
+	}
+
+	messages := []chatMessage{{Role: "system", Content: "You are helpful."}}
+	for i := 0; i < 40; i++ {
+		messages = append(messages, chatMessage{Role: "user", Content: strings.Repeat("word ", 800)})
+	}
+	if est := estimatePromptTokens(messages, nil); est <= run.promptTokenBudget() {
+		t.Fatalf("test prompt should overflow, estimated %d", est)
+	}
+
+	fitted := run.fitToContextWindow(messages, nil, &contextCompactor{run: run, keepRecent: 20})
+	if est := estimatePromptTokens(fitted, nil); est > run.promptTokenBudget() {
+		t.Errorf("still over budget after compaction: %d tokens, %d messages", est, len(fitted))
+	}
+	if fitted[0].Role != "system" {
+		t.Error("system prompt must survive compaction")
+	}
+
+	// A prompt that fits is left alone.
+	small := messages[:3]
+	if got := run.fitToContextWindow(small, nil, &contextCompactor{run: run, keepRecent: 20}); len(got) != 3 {
+		t.Errorf("expected no compaction, got %d messages", len(got))
+	}
+}
+
+func TestModelRegistry_OverridesBuiltins(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	os.WriteFile(path, []byte(`models:
+  llama:
+    context_window: 8192
+    tools: false
+  llama3.1-70b:
+    context_window: 65536
+    input_per_1m: 1
+    output_per_1m: 2
+`), 0o644)
+	reg, err := LoadModelRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llm := &LLMClient{model: "llama3.1-70b-instruct", provider: "openai"}
+	llm.SetModelRegistry(reg)
+	if got := llm.ContextWindow(""); got != 65536 {
+		t.Errorf("longest prefix should win, got context window %d", got)
+	}
+	if d := llm.modelDefaults("llama3.2"); d.ContextWindow != 8192 || d.SupportsTools {
+		t.Errorf("unexpected defaults for llama3.2: %+v", d)
+	}
+	if got := llm.ContextWindow("gpt-5-mini"); got != 400000 {
+		t.Errorf("unregistered models keep built-ins, got %d", got)
+	}
+
+	usage := NewUsageTracker(nil)
+	usage.SetModelRegistry(reg)
+	if got := usage.EstimateCost("llama3.1-70b", LLMUsage{PromptTokens: 1e6, CompletionTokens: 1e6}); got != 3 {
+		t.Errorf("expected registry price $3, got %v", got)
+	}
+	if got := usage.EstimateCost("gpt-4o", LLMUsage{PromptTokens: 1e6}); got != 2.5 {
+		t.Errorf("expected built-in price $2.50, got %v", got)
+	}
+
+	if reg, err := LoadModelRegistry(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || reg.Len() != 0 {
+		t.Errorf("a missing file should give an empty registry, got %v, %v", reg, err)
+	}
+}
+
+func TestKnowledgeCutoff_RegistryAndTemporalLayer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	os.WriteFile(path, []byte("models:\n  my-llama:\n    knowledge_cutoff: 2024-12\n"), 0o644)
+	reg, err := LoadModelRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := &LLMClient{model: "my-llama-70b", provider: "openai"}
+	llm.SetModelRegistry(reg)
+	if got := llm.KnowledgeCutoff(""); got != "2024-12" {
+		t.Errorf("registry cutoff = %q", got)
+	}
+	if got := llm.KnowledgeCutoff("gpt-4o-mini"); got != "2023-10" {
+		t.Errorf("built-in cutoff = %q", got)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Model = "my-llama-70b"
+	pc := NewPromptComposer(cfg)
+	pc.SetKnowledgeCutoff(llm.KnowledgeCutoff)
+	layer := pc.buildTemporalLayer(nil)
+	if !strings.Contains(layer, "December 2024") || !strings.Contains(layer, "web_search") {
+		t.Errorf("temporal layer lacks cutoff or search rule:\n%s", layer)
+	}
+
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	if rule := freshnessRule("", now); !strings.Contains(rule, "unknown date") {
+		t.Errorf("unknown cutoff rule = %q", rule)
+	}
+	if rule := freshnessRule("2025-03", now); !strings.Contains(rule, "about 12 months") {
+		t.Errorf("cutoff age missing: %q", rule)
+	}
+
+	os.WriteFile(path, []byte("models:\n  bad:\n    knowledge_cutoff: last spring\n"), 0o644)
+	if _, err := LoadModelRegistry(path); err == nil {
+		t.Error("expected an error for an invalid knowledge_cutoff")
+	}
+}
+
+func TestLocaleFor_IdentityOverridesAndRendering(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Language, cfg.Timezone = "pt-BR", "America/Sao_Paulo"
+	cfg.Locale.Identities = map[string]IdentityLocale{
+		"15551234567": {Locale: "en-US", Timezone: "new york"},
+	}
+
+	if ul := cfg.LocaleFor(""); ul.Tag != "pt-BR" || ul.Timezone != "" || ul.Location.String() != "America/Sao_Paulo" {
+		t.Errorf("default locale = %+v", ul)
+	}
+	ul := cfg.LocaleFor("", "15551234567@s.whatsapp.net")
+	if ul.Tag != "en-US" || ul.Timezone != "America/New_York" {
+		t.Errorf("identity locale = %+v", ul)
+	}
+	if got := cfg.LocaleFor("de-DE", "someone-else").Tag; got != "de-DE" {
+		t.Errorf("session language locale = %q", got)
+	}
+
+	pc := NewPromptComposer(cfg)
+	session := &Session{ChatID: "15551234567@s.whatsapp.net"}
+	if layer := pc.buildTemporalLayer(session); !strings.Contains(layer, "Locale: en-US") ||
+		!strings.Contains(layer, "03/15/2026 (month/day)") || !strings.Contains(layer, "America/New_York") {
+		t.Errorf("temporal layer ignores the identity locale:\n%s", layer)
+	}
+
+	inv := UsageInvoice{WorkspaceID: "acme", Month: "2026-03", PromptTokens: 1234567, CostUSD: 1234.5}
+	if got := formatUsageReport([]UsageInvoice{inv}, cfg.LocaleFor("").Locale); !strings.Contains(got, "Tokens: 1.234.567 in") ||
+		!strings.Contains(got, "Total: $1.234,50") {
+		t.Errorf("pt-BR report:\n%s", got)
+	}
+}
@@ -0,0 +1,311 @@
+// Package copilot – tool_compression.go trims the tool definitions sent
+// with every LLM call. A tool the session has already used successfully is
+// sent in short mode: the first sentence of its description, capped at
+// agent.tool_compression.short_chars, with parameter descriptions shortened
+// the same way. Tool families listed in lazy_families (tools named
+// "<family>_…", e.g. git_status or docker_ps) are replaced by a single
+// load_tools tool until the agent loads the family; a loaded family stays
+// loaded for the session. The estimated prompt tokens saved per call are
+// reported by /usage.
+//
+// Definitions are chosen when a run starts and only change within the run
+// when a family is loaded, so the provider's prompt cache stays valid
+// between the run's calls.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// loadToolsName is the tool that exposes a lazy tool family.
+const loadToolsName = "load_tools"
+
+// ToolCompressionConfig configures tool definition compression.
+type ToolCompressionConfig struct {
+	// Enabled sends tools the session already used successfully with short
+	// descriptions (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// ShortChars caps a short description (default: 100).
+	ShortChars int `yaml:"short_chars"`
+
+	// LazyFamilies are tool name prefixes whose tools are only offered once
+	// the agent loads the family with load_tools (e.g. ["git", "docker"]).
+	LazyFamilies []string `yaml:"lazy_families"`
+}
+
+// DefaultToolCompressionConfig returns the default compression settings.
+func DefaultToolCompressionConfig() ToolCompressionConfig {
+	return ToolCompressionConfig{Enabled: true, ShortChars: 100}
+}
+
+// toolView decides which tool definitions a run offers. It reads and
+// updates the session's used tools and loaded families.
+type toolView struct {
+	cfg     ToolCompressionConfig
+	session *Session
+	onSaved func(tokens int) // called per LLM call with the tokens saved
+
+	mu      sync.Mutex
+	hidden  map[string][]string // lazy family → its tools not offered yet
+	saved   int                 // tokens saved by the current definitions
+	changed bool                // a family was loaded since the last apply
+}
+
+// newToolView creates the tool view of a run in session.
+func newToolView(cfg ToolCompressionConfig, session *Session, onSaved func(tokens int)) *toolView {
+	if cfg.ShortChars <= 0 {
+		cfg.ShortChars = 100
+	}
+	return &toolView{cfg: cfg, session: session, onSaved: onSaved}
+}
+
+// apply returns the definitions to send out of all the run may use.
+func (v *toolView) apply(all []ToolDefinition) []ToolDefinition {
+	if v == nil {
+		return all
+	}
+	used, loaded := v.session.toolState()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.changed = false
+	v.hidden = make(map[string][]string)
+	out := make([]ToolDefinition, 0, len(all)+1)
+	for _, def := range all {
+		name := def.Function.Name
+		if fam := v.family(name); fam != "" && !loaded[fam] {
+			v.hidden[fam] = append(v.hidden[fam], name)
+			continue
+		}
+		if v.cfg.Enabled && used[name] {
+			def = compressToolDefinition(def, v.cfg.ShortChars)
+		}
+		out = append(out, def)
+	}
+	if len(v.hidden) > 0 {
+		out = append(out, v.loadToolsDefinition())
+	}
+	v.saved = max(toolDefinitionTokens(all)-toolDefinitionTokens(out), 0)
+	return out
+}
+
+// family returns the lazy family of a tool ("" = none).
+func (v *toolView) family(name string) string {
+	for _, fam := range v.cfg.LazyFamilies {
+		if strings.HasPrefix(name, fam+"_") {
+			return fam
+		}
+	}
+	return ""
+}
+
+// loadToolsDefinition describes load_tools with the families still hidden.
+func (v *toolView) loadToolsDefinition() ToolDefinition {
+	families := make([]string, 0, len(v.hidden))
+	for fam := range v.hidden {
+		families = append(families, fam)
+	}
+	sort.Strings(families)
+	parts := make([]string, len(families))
+	for i, fam := range families {
+		parts[i] = fmt.Sprintf("%s (%d tools)", fam, len(v.hidden[fam]))
+	}
+	return MakeToolDefinition(loadToolsName,
+		"Load a family of tools before using them; they are available from your next step. Families: "+strings.Join(parts, ", ")+".",
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"family": map[string]any{"type": "string", "enum": families, "description": "Tool family to load"},
+			},
+			"required": []string{"family"},
+		})
+}
+
+// load handles a load_tools call.
+func (v *toolView) load(call ToolCall) ToolResult {
+	result := ToolResult{ToolCallID: call.ID, Name: call.Function.Name}
+	args, _ := parseToolArgs(call.Function.Arguments)
+	fam, _ := args["family"].(string)
+
+	v.mu.Lock()
+	tools, ok := v.hidden[fam]
+	if ok {
+		delete(v.hidden, fam)
+		v.changed = true
+	}
+	v.mu.Unlock()
+	if !ok {
+		result.Content = fmt.Sprintf("Unknown or already loaded tool family %q.", fam)
+		result.Error = fmt.Errorf("unknown tool family %q", fam)
+		return result
+	}
+	v.session.addToolFamily(fam)
+	result.Content = fmt.Sprintf("Loaded %d %s tools: %s. They are available from your next step.",
+		len(tools), fam, strings.Join(tools, ", "))
+	return result
+}
+
+// takeChanged reports (once) whether a family was loaded, so the run must
+// apply the view again.
+func (v *toolView) takeChanged() bool {
+	if v == nil {
+		return false
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	changed := v.changed
+	v.changed = false
+	return changed
+}
+
+// recordCall reports the savings of an LLM call.
+func (v *toolView) recordCall() {
+	if v == nil || v.onSaved == nil {
+		return
+	}
+	v.mu.Lock()
+	saved := v.saved
+	v.mu.Unlock()
+	if saved > 0 {
+		v.onSaved(saved)
+	}
+}
+
+// recordResults remembers the tools that ran successfully.
+func (v *toolView) recordResults(results []ToolResult) {
+	if v == nil {
+		return
+	}
+	for _, r := range results {
+		if r.Error == nil && r.Blocked == "" && r.Name != loadToolsName {
+			v.session.markToolUsed(r.Name)
+		}
+	}
+}
+
+// executeCalls runs a turn's tool calls, handling load_tools itself.
+// Results keep the order of the calls.
+func (a *AgentRun) executeCalls(ctx context.Context, calls []ToolCall) []ToolResult {
+	if a.tools == nil || !slices.ContainsFunc(calls, func(c ToolCall) bool { return c.Function.Name == loadToolsName }) {
+		return a.executor.Execute(ctx, calls)
+	}
+	var rest []ToolCall
+	for _, c := range calls {
+		if c.Function.Name != loadToolsName {
+			rest = append(rest, c)
+		}
+	}
+	byID := make(map[string]ToolResult, len(calls))
+	if len(rest) > 0 {
+		for _, r := range a.executor.Execute(ctx, rest) {
+			byID[r.ToolCallID] = r
+		}
+	}
+	results := make([]ToolResult, 0, len(calls))
+	for _, c := range calls {
+		if c.Function.Name == loadToolsName {
+			results = append(results, a.tools.load(c))
+		} else if r, ok := byID[c.ID]; ok {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// compressToolDefinition shortens a definition's description and the
+// descriptions of its parameters.
+func compressToolDefinition(def ToolDefinition, maxChars int) ToolDefinition {
+	def.Function.Description = shortDescription(def.Function.Description, maxChars)
+	var schema map[string]any
+	if json.Unmarshal(def.Function.Parameters, &schema) == nil {
+		shortenSchemaDescriptions(schema, maxChars/2)
+		if data, err := json.Marshal(schema); err == nil {
+			def.Function.Parameters = data
+		}
+	}
+	return def
+}
+
+// shortenSchemaDescriptions shortens the descriptions of the properties
+// (and nested properties and items) of a JSON schema in place.
+func shortenSchemaDescriptions(schema map[string]any, maxChars int) {
+	props, _ := schema["properties"].(map[string]any)
+	for _, p := range props {
+		prop, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		if desc, ok := prop["description"].(string); ok {
+			prop["description"] = shortDescription(desc, maxChars)
+		}
+		shortenSchemaDescriptions(prop, maxChars)
+		if items, ok := prop["items"].(map[string]any); ok {
+			shortenSchemaDescriptions(items, maxChars)
+		}
+	}
+}
+
+// shortDescription returns the first sentence of s, capped at maxChars.
+func shortDescription(s string, maxChars int) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "\n"); i > 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, ". "); i > 0 {
+		s = s[:i+1]
+	}
+	return truncateStr(s, maxChars)
+}
+
+// toolDefinitionTokens estimates the prompt tokens of tool definitions.
+func toolDefinitionTokens(defs []ToolDefinition) int {
+	total := 0
+	for _, d := range defs {
+		total += estimateTokens(d.Function.Name) + estimateTokens(d.Function.Description) + estimateTokens(string(d.Function.Parameters))
+	}
+	return total
+}
+
+// toolState returns copies of the session's used tools and loaded families.
+func (s *Session) toolState() (used, families map[string]bool) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.usedTools), maps.Clone(s.toolFamilies)
+}
+
+// markToolUsed records a tool that ran successfully in the session.
+func (s *Session) markToolUsed(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.usedTools == nil {
+		s.usedTools = make(map[string]bool)
+	}
+	s.usedTools[name] = true
+}
+
+// addToolFamily records a lazy tool family loaded in the session.
+func (s *Session) addToolFamily(fam string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.toolFamilies == nil {
+		s.toolFamilies = make(map[string]bool)
+	}
+	s.toolFamilies[fam] = true
+}
@@ -0,0 +1,112 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolView_CompressAndLoadFamilies(t *testing.T) {
+	t.Parallel()
+
+	long := "Read a file from the workspace. Supports offsets and limits for very large files, binary detection and more."
+	all := []ToolDefinition{
+		MakeToolDefinition("read_file", long, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string", "description": "Path of the file to read. Relative paths resolve against the workspace root."},
+			},
+		}),
+		MakeToolDefinition("git_status", "Show the working tree status: staged, unstaged and untracked files of the repository, with the current branch and how far it is ahead or behind its upstream.", map[string]any{"type": "object"}),
+		MakeToolDefinition("git_log", "Show commit logs with author, date and message, optionally limited to a path, a range of revisions or a number of commits.", map[string]any{"type": "object"}),
+	}
+	session := &Session{ID: "s1"}
+	var saved int
+	v := newToolView(ToolCompressionConfig{Enabled: true, ShortChars: 100, LazyFamilies: []string{"git"}}, session,
+		func(n int) { saved += n })
+
+	defs := v.apply(all)
+	if names := toolNames(defs); strings.Join(names, ",") != "read_file,load_tools" {
+		t.Fatalf("tools = %v, want read_file and load_tools", names)
+	}
+	if !strings.Contains(defs[1].Function.Description, "git (2 tools)") {
+		t.Errorf("load_tools should list the git family, got %q", defs[1].Function.Description)
+	}
+	if defs[0].Function.Description != long {
+		t.Error("an unused tool should keep its full description")
+	}
+
+	// A successful call compresses the tool from the next apply.
+	v.recordResults([]ToolResult{{Name: "read_file"}})
+	defs = v.apply(all)
+	if got := defs[0].Function.Description; got != "Read a file from the workspace." {
+		t.Errorf("short description = %q", got)
+	}
+	if strings.Contains(string(defs[0].Function.Parameters), "Relative paths") {
+		t.Errorf("parameter descriptions should be shortened, got %s", defs[0].Function.Parameters)
+	}
+	v.recordCall()
+	if saved <= 0 {
+		t.Error("the savings should be reported")
+	}
+
+	res := v.load(ToolCall{ID: "c1", Function: FunctionCall{Name: loadToolsName, Arguments: `{"family":"git"}`}})
+	if res.Error != nil || !strings.Contains(res.Content, "Loaded 2 git tools: git_status, git_log") {
+		t.Fatalf("load = %+v", res)
+	}
+	if !v.takeChanged() || v.takeChanged() {
+		t.Error("takeChanged should report the load once")
+	}
+	if names := toolNames(v.apply(all)); strings.Join(names, ",") != "read_file,git_status,git_log" {
+		t.Errorf("tools after loading = %v", names)
+	}
+	if res := v.load(ToolCall{Function: FunctionCall{Arguments: `{"family":"docker"}`}}); res.Error == nil {
+		t.Error("loading an unknown family should fail")
+	}
+
+	// The loaded family stays loaded for the session's next runs.
+	next := newToolView(ToolCompressionConfig{LazyFamilies: []string{"git"}}, session, nil)
+	if names := toolNames(next.apply(all)); len(names) != 3 {
+		t.Errorf("next run tools = %v", names)
+	}
+	if got := next.apply(all)[0].Function.Description; got != long {
+		t.Error("compression disabled should keep full descriptions")
+	}
+}
+
+func TestE2E_LazyToolFamily(t *testing.T) {
+	h := newE2EHarness(t, func(cfg *Config) {
+		cfg.Agent.ToolCompression.LazyFamilies = []string{"e2efam"}
+	})
+	h.RegisterTool("e2efam_ping", "pong")
+	h.LLM.CallTool(loadToolsName, map[string]any{"family": "e2efam"})
+	h.LLM.CallTool("e2efam_ping", map[string]any{})
+	h.LLM.Reply("Done.")
+
+	h.Send("ping it")
+	reqs := h.LLM.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 LLM calls, got %d", len(reqs))
+	}
+	if strings.Contains(reqs[0], `"name":"e2efam_ping"`) || !strings.Contains(reqs[0], `"name":"load_tools"`) {
+		t.Error("the first call should offer load_tools instead of the family")
+	}
+	if !strings.Contains(reqs[1], `"name":"e2efam_ping"`) {
+		t.Error("the family should be offered after load_tools")
+	}
+	if execs := h.Executions(); len(execs) != 1 {
+		t.Errorf("executions = %+v", execs)
+	}
+
+	h.Send("again")
+	if reqs := h.LLM.Requests(); strings.Contains(reqs[3], `"name":"load_tools"`) || !strings.Contains(reqs[3], `"name":"e2efam_ping"`) {
+		t.Error("the loaded family should stay loaded for the session")
+	}
+}
+
+func toolNames(defs []ToolDefinition) []string {
+	names := make([]string, len(defs))
+	for i, d := range defs {
+		names[i] = d.Function.Name
+	}
+	return names
+}
@@ -6,6 +6,7 @@ package copilot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
@@ -13,6 +14,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jholhewres/devclaw/pkg/devclaw/copilot/security"
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
 	"github.com/jholhewres/devclaw/pkg/devclaw/skills"
 )
 
@@ -127,6 +130,10 @@ const (
 	DefaultToolTimeout = 30 * time.Second
 )
 
+// ErrToolTimeout is wrapped by ToolResult.Error when a tool exceeds its
+// timeout.
+var ErrToolTimeout = errors.New("tool execution timed out")
+
 // ToolHandlerFunc is the signature for tool execution handlers.
 // Receives parsed arguments and returns the result or an error.
 type ToolHandlerFunc func(ctx context.Context, args map[string]any) (any, error)
@@ -135,6 +142,8 @@ type ToolHandlerFunc func(ctx context.Context, args map[string]any) (any, error)
 type registeredTool struct {
 	Definition ToolDefinition
 	Handler    ToolHandlerFunc
+	Skill      string // skill that registered the tool ("" = built-in)
+	MCPServer  string // MCP server the tool proxies ("" = none)
 }
 
 // ToolResult holds the output of a single tool execution.
@@ -143,11 +152,30 @@ type ToolResult struct {
 	Name       string
 	Content    string
 	Error      error
+
+	// Blocks is the typed form of a successful result (see tool_result.go);
+	// Content is its rendering. Nil for errors and timeouts.
+	Blocks []ToolBlock
+
+	// Duration is how long the handler ran.
+	Duration time.Duration
+
+	// Blocked is why the guard or a before-hook refused the call.
+	Blocked string
+
+	// Approval is "pending" when the call waits for user approval, and
+	// "reviewed" or "rejected" after a diff review.
+	Approval string
+
+	// Truncated is true when the size guard capped the output.
+	Truncated bool
 }
 
-// sequentialTools are tools that must not run in parallel (shared state).
-var sequentialTools = map[string]bool{
-	"bash": true, "write_file": true, "edit_file": true,
+// exclusiveTools are tools that must not run concurrently with other tools
+// (shared state: the shell, the filesystem, the environment). More can be
+// added via security.tool_executor.exclusive.
+var exclusiveTools = map[string]bool{
+	"bash": true, "write_file": true, "edit_file": true, "apply_changes": true,
 	"ssh": true, "scp": true, "exec": true, "set_env": true,
 }
 
@@ -172,6 +200,7 @@ type ToolExecutor struct {
 	tools       map[string]*registeredTool
 	timeout     time.Duration
 	bashTimeout time.Duration // timeout for bash/ssh/scp/exec (default: 5min)
+	toolTimeouts map[string]time.Duration // per-tool overrides (security.tool_executor.timeouts)
 	logger      *slog.Logger
 	guard       *ToolGuard
 	mu          sync.RWMutex
@@ -185,6 +214,10 @@ type ToolExecutor struct {
 	parallel    bool
 	maxParallel int
 
+	// exclusive lists configured tools that run alone, in addition to
+	// exclusiveTools.
+	exclusive map[string]bool
+
 	// callerLevel is the access level of the current caller.
 	// Set per-request via SetCallerContext before Execute.
 	callerLevel AccessLevel
@@ -197,9 +230,36 @@ type ToolExecutor struct {
 	// If nil, tools requiring confirmation are denied.
 	confirmationRequester func(sessionID, callerJID, toolName string, args map[string]any) (approved bool, err error)
 
+	// diffReviewer reviews file changes of write_file/edit_file/apply_changes
+	// per file when they require confirmation (see diff_approval.go).
+	diffReviewer DiffReviewer
+
+	// outputRedactor rewrites tool results before they go to the model
+	// (vault secrets → [vault:name], see vault_redact.go). Nil = unchanged.
+	outputRedactor func(string) string
+
+	// secretSender sends a vault secret straight to a chat, past the model
+	// (vault_get, see vault_redact.go). Nil = vault_get cannot deliver.
+	secretSender func(ctx context.Context, target DeliveryTarget, text string) error
+
+	// secretRedactor replaces API keys, tokens and passwords in tool
+	// results (see secret_redaction.go). Nil = disabled.
+	secretRedactor *security.SecretRedactor
+
+	// injectionScanner flags prompt injection in results of tools bringing
+	// in outside content (see injection_scan.go). Nil = off.
+	injectionScanner *injectionScanner
+
+	// onGuardBlock is called when the guard denies a tool call (security alerts).
+	onGuardBlock func(toolName, callerJID string, level AccessLevel, reason string)
+
 	// hooks holds registered before/after tool execution hooks.
 	hooks []*ToolHook
 
+	// workspaceScopes looks up the tool scope of a workspace
+	// (see workspace_tools.go). Nil = every workspace sees every tool.
+	workspaceScopes func(wsID string) ToolScope
+
 	// abortCh is closed when an abort is requested, signaling all running
 	// tools to stop as soon as possible. Each run creates a fresh channel.
 	abortCh   chan struct{}
@@ -259,6 +319,14 @@ func (e *ToolExecutor) SetGuard(guard *ToolGuard) {
 	e.guard = guard
 }
 
+// SetGuardBlockHandler registers a callback invoked whenever the guard
+// denies a tool call.
+func (e *ToolExecutor) SetGuardBlockHandler(fn func(toolName, callerJID string, level AccessLevel, reason string)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onGuardBlock = fn
+}
+
 // RegisterHook adds a before/after tool execution hook.
 // Hooks are called in registration order. Multiple hooks can be registered.
 func (e *ToolExecutor) RegisterHook(hook *ToolHook) {
@@ -309,6 +377,14 @@ func (e *ToolExecutor) SessionContext() string {
 	return e.sessionID
 }
 
+// SetOutputRedactor sets the function applied to every tool result
+// returned to the model.
+func (e *ToolExecutor) SetOutputRedactor(fn func(string) string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.outputRedactor = fn
+}
+
 // SetConfirmationRequester sets the callback for tools requiring user approval.
 // When a tool is in RequireConfirmation list, this callback is invoked.
 func (e *ToolExecutor) SetConfirmationRequester(fn func(sessionID, callerJID, toolName string, args map[string]any) (bool, error)) {
@@ -332,6 +408,55 @@ func (e *ToolExecutor) Configure(cfg ToolExecutorConfig) {
 	if cfg.BashTimeoutSeconds > 0 {
 		e.bashTimeout = time.Duration(cfg.BashTimeoutSeconds) * time.Second
 	}
+	e.exclusive = make(map[string]bool, len(cfg.Exclusive))
+	for _, name := range ExpandToolGroups(cfg.Exclusive) {
+		e.exclusive[name] = true
+	}
+	e.toolTimeouts = make(map[string]time.Duration, len(cfg.Timeouts))
+	for name, secs := range cfg.Timeouts {
+		if secs <= 0 {
+			continue
+		}
+		// Group entries ("group:web") apply to each member tool. Explicit
+		// tool entries always win; a tool in several groups gets the
+		// longest of their timeouts, regardless of map order.
+		d := time.Duration(secs) * time.Second
+		for _, tool := range ExpandToolGroups([]string{name}) {
+			if tool != name {
+				if _, explicit := cfg.Timeouts[tool]; explicit {
+					continue
+				}
+				if prev, ok := e.toolTimeouts[tool]; ok && prev > d {
+					continue
+				}
+			}
+			e.toolTimeouts[tool] = d
+		}
+	}
+}
+
+// TimeoutFor returns the execution timeout for a tool: a per-tool override,
+// else the bash timeout for shell tools, else the default.
+func (e *ToolExecutor) TimeoutFor(name string) time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if d, ok := e.toolTimeouts[name]; ok {
+		return d
+	}
+	// Give bash/ssh/scp longer timeouts (configurable via bash_timeout_seconds).
+	if name == "bash" || name == "ssh" || name == "scp" || name == "exec" {
+		return e.bashTimeout
+	}
+	// Claude Code manages its own internal timeout (default 15min);
+	// give the executor wrapper enough headroom.
+	if name == "claude-code_execute" {
+		return 20 * time.Minute
+	}
+	// A delegated task is a whole agent run in another workspace.
+	if name == "delegate_to_workspace" {
+		return delegationTimeout + 30*time.Second
+	}
+	return e.timeout
 }
 
 // Register adds a tool with its definition and handler.
@@ -364,9 +489,30 @@ func (e *ToolExecutor) RegisterSkillTools(skill skills.Skill) {
 		handler := makeSkillToolHandler(skill, tool)
 
 		e.Register(def, handler)
+		e.mu.Lock()
+		e.tools[fullName].Skill = meta.Name
+		e.mu.Unlock()
 	}
 }
 
+// RegisterMCPTool adds a tool proxied from an MCP server. The tool is tagged
+// with the server so policies can select MCP tools whatever their prefix
+// (see "group:mcp" in injection_scan.go).
+func (e *ToolExecutor) RegisterMCPTool(server string, def ToolDefinition, handler ToolHandlerFunc) {
+	e.Register(def, handler)
+	e.mu.Lock()
+	e.tools[def.Function.Name].MCPServer = server
+	e.mu.Unlock()
+}
+
+// isMCPTool reports whether a tool was registered with RegisterMCPTool.
+func (e *ToolExecutor) isMCPTool(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rt, ok := e.tools[name]
+	return ok && rt.MCPServer != ""
+}
+
 // sanitizeToolName ensures a tool name matches OpenAI's required pattern
 // ^[a-zA-Z0-9_-]+$ by replacing invalid characters with underscores.
 func sanitizeToolName(name string) string {
@@ -432,7 +578,9 @@ func (e *ToolExecutor) HasTool(name string) bool {
 
 // Execute dispatches a batch of tool calls to their registered handlers.
 // Each tool is executed with a per-tool timeout.
-// When Parallel is true and no sequential tools are in the batch, runs concurrently.
+// When Parallel is true, independent calls run concurrently (up to
+// MaxParallel); exclusive tools (bash, write_file, ...) run alone, after the
+// calls before them and before the calls after them.
 // Returns results in the same order as the input calls.
 func (e *ToolExecutor) Execute(ctx context.Context, calls []ToolCall) []ToolResult {
 	e.mu.RLock()
@@ -440,19 +588,20 @@ func (e *ToolExecutor) Execute(ctx context.Context, calls []ToolCall) []ToolResu
 	maxParallel := e.maxParallel
 	e.mu.RUnlock()
 
-	if !parallel || len(calls) <= 1 || e.hasSequentialTool(calls) {
+	if !parallel || len(calls) <= 1 {
 		return e.executeSequential(ctx, calls)
 	}
 	return e.executeParallel(ctx, calls, maxParallel)
 }
 
-func (e *ToolExecutor) hasSequentialTool(calls []ToolCall) bool {
-	for _, c := range calls {
-		if sequentialTools[c.Function.Name] {
-			return true
-		}
+// isExclusive reports whether a tool must run alone.
+func (e *ToolExecutor) isExclusive(name string) bool {
+	if exclusiveTools[name] {
+		return true
 	}
-	return false
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.exclusive[name]
 }
 
 func (e *ToolExecutor) executeSequential(ctx context.Context, calls []ToolCall) []ToolResult {
@@ -463,12 +612,19 @@ func (e *ToolExecutor) executeSequential(ctx context.Context, calls []ToolCall)
 	return results
 }
 
+// executeParallel runs non-exclusive calls concurrently, with each
+// exclusive call acting as a barrier between them.
 func (e *ToolExecutor) executeParallel(ctx context.Context, calls []ToolCall, maxParallel int) []ToolResult {
 	results := make([]ToolResult, len(calls))
 	sem := make(chan struct{}, maxParallel)
 	var wg sync.WaitGroup
 
 	for i, call := range calls {
+		if e.isExclusive(call.Function.Name) {
+			wg.Wait()
+			results[i] = e.executeSingle(ctx, call)
+			continue
+		}
 		wg.Add(1)
 		go func(idx int, tc ToolCall) {
 			defer wg.Done()
@@ -484,9 +640,18 @@ func (e *ToolExecutor) executeParallel(ctx context.Context, calls []ToolCall, ma
 
 // executeSingle runs a single tool call and returns the result.
 // If a ToolGuard is configured, it checks permissions before executing.
-func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolResult {
+func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) (result ToolResult) {
 	name := call.Function.Name
-	result := ToolResult{
+	ctx, span := startSpan(ctx, "tool "+name, "tool.name", name)
+	defer func() {
+		if result.Blocked != "" {
+			span.SetAttrs("tool.blocked", result.Blocked)
+		}
+		span.SetError(result.Error)
+		span.End()
+	}()
+
+	result = ToolResult{
 		ToolCallID: call.ID,
 		Name:       name,
 	}
@@ -510,6 +675,12 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 		e.logger.Warn("unknown tool called", "name", name)
 		return result
 	}
+	if wsID := WorkspaceIDFromContext(ctx); wsID != "" && !e.inWorkspace(wsID, name, tool) {
+		result.Content = formatToolError(name, fmt.Errorf("tool %q is not available in this workspace", name))
+		result.Error = fmt.Errorf("tool %s not in workspace %s", name, wsID)
+		result.Blocked = "workspace: " + wsID
+		return result
+	}
 
 	// Parse arguments from JSON string.
 	args, err := parseToolArgs(call.Function.Arguments)
@@ -524,9 +695,15 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 	var check ToolCheckResult
 	if guard != nil {
 		check = guard.Check(name, callerLevel, args)
+		if check.Allowed && ReadOnlyFromContext(ctx) {
+			if ro := guard.CheckReadOnly(name, args); !ro.Allowed {
+				check = ro
+			}
+		}
 		if !check.Allowed {
 			result.Content = formatToolError(name, fmt.Errorf("access denied: %s", check.Reason))
 			result.Error = fmt.Errorf("access denied: %s", check.Reason)
+			result.Blocked = "guard: " + check.Reason
 			e.logger.Warn("tool blocked by guard",
 				"name", name,
 				"caller", callerJID,
@@ -534,10 +711,50 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 				"reason", check.Reason,
 			)
 			guard.AuditLog(name, callerJID, callerLevel, args, false, check.Reason)
+			e.mu.RLock()
+			onBlock := e.onGuardBlock
+			e.mu.RUnlock()
+			if onBlock != nil {
+				onBlock(name, callerJID, callerLevel, check.Reason)
+			}
 			return result
 		}
 	}
 
+	// File writes must not replace a secret with its redaction placeholder.
+	if err := e.checkRedactedWrite(name, args); err != nil {
+		result.Content = formatToolError(name, err)
+		result.Error = err
+		if guard != nil {
+			guard.AuditLog(name, callerJID, callerLevel, args, false, err.Error())
+		}
+		return result
+	}
+
+	// Diff review: file changes requiring confirmation are shown as diffs
+	// and approved per file. The review blocks so the outcome, including
+	// rejected diffs, goes back to the model in this tool result.
+	reviewNote := ""
+	if check.RequiresConfirmation && isDiffReviewTool(name) {
+		e.mu.RLock()
+		reviewer := e.diffReviewer
+		e.mu.RUnlock()
+		if reviewer != nil {
+			reviewed, note, final := e.reviewFileChanges(ctx, reviewer, name, args, callerJID)
+			if final != nil {
+				final.ToolCallID = call.ID
+				final.Approval = "rejected"
+				if guard != nil {
+					guard.AuditLog(name, callerJID, callerLevel, args, false, final.Error.Error())
+				}
+				return *final
+			}
+			args, reviewNote = reviewed, note
+			check.RequiresConfirmation = false
+			result.Approval = "reviewed"
+		}
+	}
+
 	// Confirmation flow: if tool requires approval, return "approval-pending"
 	// immediately (non-blocking) and run the tool in the
 	// background once approved. The result is sent to the user via ProgressSender.
@@ -570,6 +787,7 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 		desc := formatApprovalSummary(name, args)
 
 		// Return immediately — the agent loop continues without blocking.
+		result.Approval = "pending"
 		result.Content = fmt.Sprintf(
 			"⚠️ Approval required: %s\nStatus: pending. Waiting for user to approve. "+
 				"The command will execute in the background once approved and the result will be sent to the user.",
@@ -578,6 +796,7 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 
 		// Fire-and-forget: handle approval + execution asynchronously.
 		progressSend := ProgressSenderFromContext(ctx)
+		target := DeliveryTargetFromContext(ctx)
 		go func() {
 			approved, err := req(sessionID, callerJID, name, args)
 			if err != nil {
@@ -602,14 +821,26 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 
 			// Approved — execute the tool now.
 			e.logger.Info("async approval granted, executing", "tool", name)
-			bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			bgCtx, cancel := context.WithTimeout(context.Background(), max(e.TimeoutFor(name), 5*time.Minute))
 			defer cancel()
-
-			output, execErr := tool.Handler(bgCtx, args)
+			// Keep the chat and caller, so tools that deliver to the chat
+			// (vault_get) still know where to.
+			bgCtx = ContextWithCaller(ContextWithDelivery(bgCtx, target.Channel, target.ChatID), callerLevel, callerJID)
+
+			capture := guard.Forensics().Begin(ContextWithSession(bgCtx, sessionID), name, callerJID, callerLevel, args)
+			output, execErr := tool.Handler(sandbox.WithTool(bgCtx, name), args)
+			forensicID := ""
+			if capture != nil {
+				out := ""
+				if execErr == nil {
+					out = formatToolOutput(output)
+				}
+				forensicID = guard.Forensics().Finish(capture, out, execErr, errors.Is(execErr, context.DeadlineExceeded))
+			}
 			if execErr != nil {
 				e.logger.Warn("async tool execution failed", "tool", name, "error", execErr)
 				if guard != nil {
-					guard.AuditLog(name, callerJID, callerLevel, args, true, "ERROR: "+execErr.Error())
+					guard.AuditLog(name, callerJID, callerLevel, args, true, withForensicTag(forensicID, "ERROR: "+execErr.Error()))
 				}
 				if progressSend != nil {
 					progressSend(context.Background(),
@@ -621,7 +852,7 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 			outputStr := formatToolOutput(output)
 			e.logger.Info("async tool executed", "tool", name, "output_len", len(outputStr))
 			if guard != nil {
-				guard.AuditLog(name, callerJID, callerLevel, args, true, outputStr)
+				guard.AuditLog(name, callerJID, callerLevel, args, true, withForensicTag(forensicID, outputStr))
 			}
 
 			// Send result to the user via their channel.
@@ -637,25 +868,26 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 		return result
 	}
 
-	// Execute with timeout.
-	timeout := e.timeout
-	// Give bash/ssh/scp longer timeouts (configurable via bash_timeout_seconds).
-	if name == "bash" || name == "ssh" || name == "scp" || name == "exec" {
-		timeout = e.bashTimeout
-	}
-	// Claude Code manages its own internal timeout (default 15min);
-	// give the executor wrapper enough headroom.
-	if name == "claude-code_execute" {
-		timeout = 20 * time.Minute
-	}
-
+	// Execute with timeout. The context is also cancelled on Abort so
+	// handlers that honour ctx stop with the run.
+	timeout := e.TimeoutFor(name)
 	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	abortCh, done := e.AbortCh(), execCtx.Done()
+	go func() {
+		select {
+		case <-abortCh:
+			cancel()
+		case <-done:
+		}
+	}()
 
 	// Propagate ProgressSender to the tool context so long-running tools
 	// can send intermediate feedback to the user.
 	if ps := ProgressSenderFromContext(ctx); ps != nil {
 		execCtx = ContextWithProgressSender(execCtx, ps)
 	}
+	// Scripts the tool runs use the sandbox profile mapped to it.
+	execCtx = sandbox.WithTool(execCtx, name)
 	defer cancel()
 
 	// ── Before-tool hooks ──
@@ -669,6 +901,7 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 			if blocked {
 				result.Content = formatToolError(name, fmt.Errorf("blocked by hook %q: %s", hook.Name, reason))
 				result.Error = fmt.Errorf("blocked by hook: %s", reason)
+				result.Blocked = fmt.Sprintf("hook %s: %s", hook.Name, reason)
 				e.logger.Info("tool blocked by before-hook",
 					"tool", name, "hook", hook.Name, "reason", reason)
 				return result
@@ -686,10 +919,41 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 	// a redundant generic heartbeat to avoid flooding the user.
 	progressDone := make(chan struct{})
 
+	forensics := guard.Forensics()
+	capture := forensics.Begin(ctx, name, callerJID, callerLevel, args)
+
 	start := time.Now()
-	output, err := tool.Handler(execCtx, args)
+	output, err := e.runHandler(execCtx, tool.Handler, args, e.isExclusive(name))
 	close(progressDone)
 	duration := time.Since(start)
+	result.Duration = duration
+
+	forensicID := ""
+	if capture != nil {
+		out := ""
+		if err == nil {
+			out = formatToolOutput(output)
+		}
+		forensicID = forensics.Finish(capture, out, err, errors.Is(err, ErrToolTimeout))
+	}
+
+	if err != nil && errors.Is(err, ErrToolTimeout) {
+		result.Content = formatToolTimeout(name, timeout)
+		result.Error = fmt.Errorf("%s: %w after %s", name, ErrToolTimeout, timeout)
+		e.logger.Warn("tool execution timed out",
+			"name", name,
+			"timeout", timeout,
+		)
+		if guard != nil {
+			guard.AuditLogTimed(name, callerJID, callerLevel, args, true, withForensicTag(forensicID, "TIMEOUT: "+timeout.String()), duration)
+		}
+		for _, hook := range hooks {
+			if hook.AfterToolCall != nil {
+				hook.AfterToolCall(name, args, result.Content, result.Error)
+			}
+		}
+		return result
+	}
 
 	// ── After-tool hooks ──
 	resultStr := ""
@@ -707,7 +971,7 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 	if err != nil {
 		// Structured JSON error result ({ status, tool, error }) for parseable LLM retry logic.
 		// This makes tool errors parseable by the LLM for better retry logic.
-		result.Content = formatToolError(name, err)
+		result.Content = e.redactOutput(name, callerJID, callerLevel, formatToolError(name, err)) + withReviewNote(reviewNote)
 		result.Error = err
 		e.logger.Warn("tool execution failed",
 			"name", name,
@@ -715,20 +979,33 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 			"duration_ms", duration.Milliseconds(),
 		)
 		if guard != nil {
-			guard.AuditLog(name, callerJID, callerLevel, args, true, "ERROR: "+err.Error())
+			guard.AuditLogTimed(name, callerJID, callerLevel, args, true, withForensicTag(forensicID, "ERROR: "+err.Error()), duration)
 		}
 		return result
 	}
 
 	// Serialize output to string.
 	result.Content = resultStr
+	result.Blocks = blocksFromOutput(output)
+	redacted := e.redactOutput(name, callerJID, callerLevel, resultStr)
+	if redacted != resultStr {
+		// Redact each block in place so JSON, tables and images keep their type.
+		result.Content = redacted
+		result.Blocks = e.redactBlocks(result.Blocks)
+	}
+	if filtered := e.scanInjection(name, callerJID, callerLevel, redacted); filtered != redacted {
+		// The blocks hold the flagged text too: pass the filtered text alone.
+		result.Content = filtered
+		result.Blocks = []ToolBlock{{Type: ToolBlockText, Text: filtered}}
+	}
 
 	// ── Tool result size guard ──
-	// Cap oversized results proactively to prevent context overflow.
+	// Cap oversized results proactively to prevent context overflow. JSON
+	// blocks are shrunk structurally, so the capped result stays parseable.
 	if len(result.Content) > HardMaxToolResultChars {
 		original := len(result.Content)
-		result.Content = result.Content[:HardMaxToolResultChars] + "\n\n... [truncated: result was " +
-			fmt.Sprintf("%d", original) + " chars, capped at " + fmt.Sprintf("%d", HardMaxToolResultChars) + "]"
+		result.Content = RenderToolBlocks(result.Blocks, HardMaxToolResultChars)
+		result.Truncated = true
 		e.logger.Warn("tool result truncated by size guard",
 			"name", name,
 			"original_chars", original,
@@ -736,6 +1013,8 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 		)
 	}
 
+	result.Content += withReviewNote(reviewNote)
+
 	e.logger.Info("tool executed",
 		"name", name,
 		"duration_ms", duration.Milliseconds(),
@@ -744,12 +1023,78 @@ func (e *ToolExecutor) executeSingle(ctx context.Context, call ToolCall) ToolRes
 
 	// Audit log successful execution.
 	if guard != nil {
-		guard.AuditLog(name, callerJID, callerLevel, args, true, result.Content)
+		guard.AuditLogTimed(name, callerJID, callerLevel, args, true, withForensicTag(forensicID, result.Content), duration)
 	}
 
 	return result
 }
 
+// withReviewNote formats the note about rejected diffs appended to a tool
+// result.
+func withReviewNote(note string) string {
+	if note == "" {
+		return ""
+	}
+	return "\n\n" + note
+}
+
+// withForensicTag prefixes an audit result with the forensic bundle ID, so
+// the audit entry survives truncation with its reference intact.
+func withForensicTag(id, result string) string {
+	if id == "" {
+		return result
+	}
+	return "[forensics:" + id + "] " + result
+}
+
+// runHandler runs a tool handler and returns when it finishes or when ctx
+// is done, whichever comes first. A handler that ignores ctx keeps running
+// in the background; its late result is discarded. With wait (exclusive
+// tools) it returns only once the handler has stopped, so a cancelled bash
+// or write never overlaps the next exclusive call.
+func (e *ToolExecutor) runHandler(ctx context.Context, handler ToolHandlerFunc, args map[string]any, wait bool) (any, error) {
+	type handlerResult struct {
+		output any
+		err    error
+	}
+	done := make(chan handlerResult, 1)
+	go func() {
+		output, err := handler(ctx, args)
+		done <- handlerResult{output, err}
+	}()
+
+	select {
+	case r := <-done:
+		// A handler that returned because its context expired is reported
+		// as a timeout, not a generic failure.
+		if r.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrToolTimeout
+		}
+		return r.output, r.err
+	case <-ctx.Done():
+		if wait {
+			<-done
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrToolTimeout
+		}
+		return nil, fmt.Errorf("tool cancelled: %w", ctx.Err())
+	}
+}
+
+// formatToolTimeout creates the structured result for a timed-out tool, with
+// a hint so the agent can retry differently instead of giving up.
+func formatToolTimeout(toolName string, timeout time.Duration) string {
+	b, _ := json.Marshal(map[string]any{
+		"status":          "timeout",
+		"tool":            toolName,
+		"timeout_seconds": int(timeout.Seconds()),
+		"error":           fmt.Sprintf("%s did not finish within %s and was cancelled", toolName, timeout),
+		"hint":            "Retry with a smaller scope (fewer files, a narrower query, a shorter command), run long commands in the background, or continue without this result.",
+	})
+	return string(b)
+}
+
 // HardMaxToolResultChars is the absolute maximum size for a tool result.
 // Results exceeding this are truncated before entering the conversation
 // to prevent context overflow.
@@ -801,11 +1146,19 @@ func SkillToolToDefinition(name string, tool skills.Tool) ToolDefinition {
 
 	schemaJSON, _ := json.Marshal(schema)
 
+	// Advertise the result contract so the agent can chain typed outputs.
+	description := tool.Description
+	if len(tool.OutputSchema) > 0 {
+		if out, err := json.Marshal(tool.OutputSchema); err == nil {
+			description += "\nReturns JSON matching schema: " + string(out)
+		}
+	}
+
 	return ToolDefinition{
 		Type: "function",
 		Function: FunctionDef{
 			Name:        name,
-			Description: tool.Description,
+			Description: description,
 			Parameters:  schemaJSON,
 		},
 	}
@@ -836,7 +1189,41 @@ func MakeToolDefinition(name, description string, params map[string]any) ToolDef
 }
 
 // makeSkillToolHandler creates a ToolHandlerFunc that delegates to a skill's tool handler.
+// Tools that declare an OutputSchema have their results decoded and validated.
 func makeSkillToolHandler(skill skills.Skill, tool skills.Tool) ToolHandlerFunc {
+	handler := makeRawSkillToolHandler(skill, tool)
+	if len(tool.OutputSchema) == 0 {
+		return handler
+	}
+	return structuredOutputHandler(tool.OutputSchema, handler)
+}
+
+// structuredOutputHandler enforces a skill's structured result contract:
+// the result is decoded into JSON and validated against schema. Results that
+// break the contract are reported as tool errors so the agent doesn't chain
+// malformed data into the next step.
+func structuredOutputHandler(schema map[string]any, handler ToolHandlerFunc) ToolHandlerFunc {
+	return func(ctx context.Context, args map[string]any) (any, error) {
+		raw, err := handler(ctx, args)
+		if err != nil {
+			return raw, err
+		}
+		value, err := skills.DecodeStructuredOutput(raw)
+		if err != nil {
+			return nil, fmt.Errorf("structured output: %w", err)
+		}
+		if err := skills.ValidateOutput(schema, value); err != nil {
+			return nil, fmt.Errorf("output does not match schema: %w", err)
+		}
+		// Run details stay out of the validated value, in their own block.
+		if res, ok := raw.(skills.StructuredResult); ok && len(res.Meta) > 0 {
+			return ToolBlocks{JSONBlock(value), JSONBlock(map[string]any{"meta": res.Meta})}, nil
+		}
+		return value, nil
+	}
+}
+
+func makeRawSkillToolHandler(skill skills.Skill, tool skills.Tool) ToolHandlerFunc {
 	if tool.Handler != nil {
 		// Skill tool has an explicit handler — use it directly.
 		return ToolHandlerFunc(tool.Handler)
@@ -880,6 +1267,8 @@ func formatToolOutput(output any) string {
 		return v
 	case []byte:
 		return string(v)
+	case ToolBlocks, []ToolBlock, ToolBlock:
+		return RenderToolBlocks(blocksFromOutput(v), 0)
 	case error:
 		return fmt.Sprintf("Error: %v", v)
 	default:
@@ -905,6 +1294,8 @@ func formatApprovalSummary(toolName string, args map[string]any) string {
 		if path, ok := args["path"].(string); ok && path != "" {
 			return toolName + " " + path
 		}
+	case "apply_changes":
+		return formatApplyChangesSummary(args)
 	case "ssh":
 		if host, ok := args["host"].(string); ok {
 			return "ssh " + host
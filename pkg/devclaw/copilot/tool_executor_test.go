@@ -0,0 +1,240 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// callTracker records when each tool call starts and ends, and how many
+// calls overlapped.
+type callTracker struct {
+	mu        sync.Mutex
+	seq       int
+	start     map[string]int
+	end       map[string]int
+	active    int
+	maxActive int
+}
+
+func (c *callTracker) handler(name string) ToolHandlerFunc {
+	return func(context.Context, map[string]any) (any, error) {
+		c.mu.Lock()
+		c.seq++
+		c.start[name] = c.seq
+		c.active++
+		if c.active > c.maxActive {
+			c.maxActive = c.active
+		}
+		c.mu.Unlock()
+
+		time.Sleep(30 * time.Millisecond)
+
+		c.mu.Lock()
+		c.seq++
+		c.end[name] = c.seq
+		c.active--
+		c.mu.Unlock()
+		return name + " done", nil
+	}
+}
+
+func newTrackedExecutor(t *testing.T, cfg ToolExecutorConfig, names ...string) (*ToolExecutor, *callTracker) {
+	t.Helper()
+	e := NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.Configure(cfg)
+	tr := &callTracker{start: map[string]int{}, end: map[string]int{}}
+	for _, name := range names {
+		e.Register(MakeToolDefinition(name, name, map[string]any{"type": "object"}), tr.handler(name))
+	}
+	return e, tr
+}
+
+func toolCalls(names ...string) []ToolCall {
+	calls := make([]ToolCall, len(names))
+	for i, name := range names {
+		calls[i] = ToolCall{ID: fmt.Sprintf("call_%d", i), Type: "function",
+			Function: FunctionCall{Name: name, Arguments: "{}"}}
+	}
+	return calls
+}
+
+func TestExecuteParallel_ExclusiveBarrier(t *testing.T) {
+	t.Parallel()
+	e, tr := newTrackedExecutor(t,
+		ToolExecutorConfig{Parallel: true, MaxParallel: 4, Exclusive: []string{"migrate"}},
+		"read_a", "read_b", "migrate", "read_c", "read_d")
+
+	results := e.Execute(context.Background(), toolCalls("read_a", "read_b", "migrate", "read_c", "read_d"))
+
+	for i, name := range []string{"read_a", "read_b", "migrate", "read_c", "read_d"} {
+		if results[i].Name != name || results[i].Content != name+" done" || results[i].ToolCallID != fmt.Sprintf("call_%d", i) {
+			t.Errorf("result %d = %+v, want %s in input order", i, results[i], name)
+		}
+	}
+	for _, before := range []string{"read_a", "read_b"} {
+		if tr.end[before] > tr.start["migrate"] {
+			t.Errorf("migrate started before %s finished", before)
+		}
+	}
+	for _, after := range []string{"read_c", "read_d"} {
+		if tr.start[after] < tr.end["migrate"] {
+			t.Errorf("%s started before migrate finished", after)
+		}
+	}
+	if tr.maxActive < 2 {
+		t.Error("calls around the exclusive tool did not run concurrently")
+	}
+}
+
+func TestExecuteParallel_Concurrency(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name      string
+		cfg       ToolExecutorConfig
+		calls     []string
+		maxActive int
+	}{
+		{"capped by max_parallel", ToolExecutorConfig{Parallel: true, MaxParallel: 2}, []string{"a", "b", "c", "d"}, 2},
+		{"all exclusive", ToolExecutorConfig{Parallel: true, MaxParallel: 4, Exclusive: []string{"a", "b", "c"}}, []string{"a", "b", "c"}, 1},
+		{"parallel disabled", ToolExecutorConfig{MaxParallel: 4}, []string{"a", "b", "c"}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, tr := newTrackedExecutor(t, tc.cfg, tc.calls...)
+			results := e.Execute(context.Background(), toolCalls(tc.calls...))
+			for i, r := range results {
+				if r.Error != nil || r.Name != tc.calls[i] {
+					t.Errorf("result %d = %+v", i, r)
+				}
+			}
+			if tr.maxActive != tc.maxActive {
+				t.Errorf("max concurrent calls = %d, want %d", tr.maxActive, tc.maxActive)
+			}
+		})
+	}
+}
+
+func TestToolExecutor_TimeoutFor(t *testing.T) {
+	t.Parallel()
+	e := NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	e.Configure(ToolExecutorConfig{
+		DefaultTimeoutSeconds: 20,
+		BashTimeoutSeconds:    100,
+		Timeouts:              map[string]int{"group:web": 45, "web_fetch": 60, "read_file": 0},
+	})
+	cases := []struct {
+		tool string
+		want time.Duration
+	}{
+		{"web_fetch", 60 * time.Second},
+		{"web_search", 45 * time.Second},
+		{"bash", 100 * time.Second},
+		{"read_file", 20 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := e.TimeoutFor(tc.tool); got != tc.want {
+			t.Errorf("TimeoutFor(%q) = %s, want %s", tc.tool, got, tc.want)
+		}
+	}
+}
+
+// Not parallel: it adds groups to the global ToolGroups.
+func TestToolExecutor_TimeoutFor_OverlappingGroups(t *testing.T) {
+	ToolGroups["group:test_a"] = []string{"shared_tool", "a_tool"}
+	ToolGroups["group:test_b"] = []string{"shared_tool"}
+	defer func() {
+		delete(ToolGroups, "group:test_a")
+		delete(ToolGroups, "group:test_b")
+	}()
+
+	// Map order varies between runs; the longest group timeout must win
+	// every time.
+	for i := 0; i < 20; i++ {
+		e := NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		e.Configure(ToolExecutorConfig{Timeouts: map[string]int{"group:test_a": 10, "group:test_b": 50}})
+		if got := e.TimeoutFor("shared_tool"); got != 50*time.Second {
+			t.Fatalf("shared_tool timeout = %s, want 50s", got)
+		}
+		if got := e.TimeoutFor("a_tool"); got != 10*time.Second {
+			t.Fatalf("a_tool timeout = %s, want 10s", got)
+		}
+	}
+}
+
+func TestExecute_ToolTimeout(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name      string
+		exclusive bool
+	}{
+		{"detached", false},
+		{"exclusive waits for the handler", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := ToolExecutorConfig{Timeouts: map[string]int{"slow": 1}}
+			if tc.exclusive {
+				cfg.Exclusive = []string{"slow"}
+			}
+			e := NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+			e.Configure(cfg)
+			// The handler keeps working for a while after its context ends.
+			var finished atomic.Bool
+			e.Register(MakeToolDefinition("slow", "slow", map[string]any{"type": "object"}),
+				func(ctx context.Context, _ map[string]any) (any, error) {
+					<-ctx.Done()
+					time.Sleep(200 * time.Millisecond)
+					finished.Store(true)
+					return nil, ctx.Err()
+				})
+
+			res := e.Execute(context.Background(), toolCalls("slow"))[0]
+			if !errors.Is(res.Error, ErrToolTimeout) {
+				t.Errorf("error = %v, want ErrToolTimeout", res.Error)
+			}
+			if !strings.Contains(res.Content, `"status":"timeout"`) || !strings.Contains(res.Content, `"timeout_seconds":1`) {
+				t.Errorf("content = %s", res.Content)
+			}
+			if got := finished.Load(); got != tc.exclusive {
+				t.Errorf("handler finished before Execute returned = %v, want %v", got, tc.exclusive)
+			}
+		})
+	}
+}
+
+func TestExecute_AbortCancelsHandler(t *testing.T) {
+	t.Parallel()
+	e := NewToolExecutor(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	started := make(chan struct{})
+	e.Register(MakeToolDefinition("wait", "wait", map[string]any{"type": "object"}),
+		func(ctx context.Context, _ map[string]any) (any, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	done := make(chan ToolResult, 1)
+	go func() { done <- e.Execute(context.Background(), toolCalls("wait"))[0] }()
+	<-started
+	e.Abort()
+	defer e.ResetAbort()
+
+	select {
+	case res := <-done:
+		if res.Error == nil || errors.Is(res.Error, ErrToolTimeout) || !strings.Contains(res.Error.Error(), "tool cancelled") {
+			t.Errorf("error = %v, want a cancellation", res.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Abort did not cancel the running handler")
+	}
+}
@@ -8,16 +8,20 @@
 //   - SSH host allowlist
 //   - Full audit logging of every tool execution
 //   - Configurable confirmation for dangerous operations
+//   - Per-tool daily call limits and image size limits
 package copilot
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -36,9 +40,15 @@ type ToolGuardConfig struct {
 	// Enable turns on the tool security guard (default: true).
 	Enabled bool `yaml:"enabled"`
 
-	// AuditLog path for recording all tool executions.
+	// AuditLog is the path of the JSONL audit log recording all tool
+	// executions (see tool_guard_audit_jsonl.go).
 	AuditLogPath string `yaml:"audit_log"`
 
+	// AuditSinks ship every audit entry off-host: syslog, a signed https
+	// endpoint or an S3-compatible bucket (see tool_guard_audit_sinks.go).
+	// Read at startup only.
+	AuditSinks []AuditSinkConfig `yaml:"audit_sinks"`
+
 	// ToolPermissions overrides per-tool permission levels.
 	// key = tool name, value = "owner"/"admin"/"user"/"public".
 	ToolPermissions map[string]string `yaml:"tool_permissions"`
@@ -80,31 +90,67 @@ type ToolGuardConfig struct {
 	// the chat before executing. The agent will ask "Confirm: <action>?" and
 	// wait for approval. Example: ["bash", "ssh", "scp", "write_file"]
 	RequireConfirmation []string `yaml:"require_confirmation"`
+
+	// Preset selects a named security preset ("personal", "family-shared",
+	// "business-strict", "developer-yolo") or a custom rule pack. Keys set
+	// explicitly in this section are layered on top of the preset.
+	// See tool_guard_presets.go for the merge rules.
+	Preset string `yaml:"preset,omitempty"`
+
+	// RulePacks defines custom rule packs that can extend the built-in ones
+	// and be selected via Preset.
+	RulePacks map[string]GuardRulePack `yaml:"rule_packs,omitempty"`
+
+	// Forensics stores a forensic bundle (exact command, environment
+	// fingerprint, output hashes, file diffs) for high-risk tool calls.
+	// See forensics.go.
+	Forensics ForensicsConfig `yaml:"forensics"`
+
+	// DailyLimits caps how many times a tool may run per calendar day,
+	// across all callers (key = tool name, 0 = unlimited). Counters are
+	// kept in memory and reset at local midnight.
+	DailyLimits map[string]int `yaml:"daily_limits"`
+
+	// MaxImageSize is the largest size generate_image may request, as
+	// WIDTHxHEIGHT (compared by pixel count). Empty = no limit.
+	MaxImageSize string `yaml:"max_image_size"`
+
+	// ReadOnlyTools are extra tools (e.g. MCP or plugin lookups) allowed in
+	// read-only sessions besides the built-in reads and searches.
+	ReadOnlyTools []string `yaml:"read_only_tools"`
 }
 
 // DefaultToolGuardConfig returns safe defaults for the tool security guard.
 func DefaultToolGuardConfig() ToolGuardConfig {
 	return ToolGuardConfig{
 		Enabled:          true,
-		AuditLogPath:     "./data/audit.log",
+		AuditLogPath:     DefaultAuditLogPath,
 		BlockSudo:        true,
 		AllowDestructive: false,
 		AllowSudo:        false,
 		AllowReboot:      false,
+		Forensics:        DefaultForensicsConfig(),
+		DailyLimits:      map[string]int{"generate_image": 25},
+		MaxImageSize:     "1792x1024",
 		ToolPermissions: map[string]string{
 			// System tools with machine access.
-			"bash":         "owner",
-			"ssh":          "owner",
-			"scp":          "owner",
-			"exec":         "admin",
-			"set_env":      "owner",
+			"bash":          "owner",
+			"ssh":           "owner",
+			"scp":           "owner",
+			"exec":          "admin",
+			"watch_command": "admin",
+			"set_env":       "owner",
 			// File tools.
-			"write_file":   "admin",
-			"edit_file":    "admin",
-			"read_file":    "user",
-			"list_files":   "user",
-			"search_files": "user",
-			"glob_files":   "user",
+			"write_file":    "admin",
+			"edit_file":     "admin",
+			"apply_changes": "admin",
+			"read_file":     "user",
+			"list_files":    "user",
+			"search_files":  "user",
+			"glob_files":    "user",
+			"send_file":     "user",
+			// Media.
+			"generate_image": "user",
 			// Skill management.
 			"install_skill": "admin",
 			"remove_skill":  "admin",
@@ -117,6 +163,8 @@ func DefaultToolGuardConfig() ToolGuardConfig {
 			// Memory.
 			"memory_save":   "user",
 			"memory_search": "user",
+			"docs_search":   "user",
+			"corpus_search": "user",
 			"memory_list":   "user",
 			// Scheduler.
 			"cron_add":    "admin",
@@ -125,10 +173,21 @@ func DefaultToolGuardConfig() ToolGuardConfig {
 			// Web.
 			"web_search": "user",
 			"web_fetch":  "user",
+			// Vault.
+			"vault_save":   "owner",
+			"vault_get":    "owner",
+			"vault_delete": "owner",
+			"vault_list":   "admin",
 		},
 	}
 }
 
+// ownerApprovedTools wait for approval on every call, the owner's
+// included, and approval grants never cover them: vault_get reveals a
+// secret, so each read is confirmed and the value goes to the chat rather
+// than back to the model.
+var ownerApprovedTools = map[string]bool{"vault_get": true}
+
 // ── Tool Groups ──
 // Groups can be used in Allow/Deny lists with "group:" prefix.
 // Example: deny: ["group:sessions", "group:runtime"]
@@ -136,15 +195,15 @@ func DefaultToolGuardConfig() ToolGuardConfig {
 // ToolGroups maps group names to tool name lists.
 // Allows policy management at a higher level than individual tools.
 var ToolGroups = map[string][]string{
-	"group:memory":    {"memory_save", "memory_search", "memory_list", "memory_index"},
+	"group:memory":    {"memory_save", "memory_search", "memory_list", "memory_index", "docs_search", "corpus_search"},
 	"group:web":       {"web_search", "web_fetch"},
-	"group:fs":        {"read_file", "write_file", "edit_file", "list_files", "search_files", "glob_files"},
-	"group:runtime":   {"bash", "exec", "ssh", "scp", "set_env"},
-	"group:subagents": {"spawn_subagent", "list_subagents", "wait_subagent", "stop_subagent"},
+	"group:fs":        {"read_file", "write_file", "edit_file", "apply_changes", "list_files", "search_files", "glob_files", "send_file"},
+	"group:runtime":   {"bash", "exec", "ssh", "scp", "set_env", "watch_command"},
+	"group:subagents": {"spawn_subagent", "list_subagents", "wait_subagent", "stop_subagent", "subagent_status"},
 	"group:skills":    {"install_skill", "remove_skill", "search_skills", "list_skills", "test_skill", "edit_skill", "add_script", "init_skill", "skill_defaults_list", "skill_defaults_install"},
 	"group:scheduler": {"cron_add", "cron_list", "cron_remove"},
 	"group:vault":     {"vault_save", "vault_get", "vault_list", "vault_delete"},
-	"group:media":     {"describe_image", "transcribe_audio", "image-gen_generate_image"},
+	"group:media":     {"describe_image", "transcribe_audio", "generate_image", "image-gen_generate_image"},
 }
 
 // ExpandToolGroups expands group references (e.g. "group:memory") into
@@ -167,14 +226,32 @@ type ToolGuard struct {
 	logger    *slog.Logger
 	auditFile *os.File
 
-	// SQLite audit logger (optional; when set, replaces the file-based audit).
+	// auditSinks ship entries off-host (nil = none).
+	auditSinks []*auditShipper
+
+	// SQLite audit logger (optional; written in addition to the JSONL file,
+	// it backs the web UI's audit view).
 	sqliteAudit *SQLiteAuditLogger
 
+	// forensics records bundles for high-risk calls (nil = disabled).
+	forensics *ForensicRecorder
+
 	// Compiled patterns.
 	dangerousPatterns   []*regexp.Regexp
-	defaultPatternCount []bool // tracks which indices are default patterns
+	defaultPatternCount []bool   // tracks which indices are default patterns
+	patternNeedles      []string // literal each pattern requires ("" = always run)
 	protectedPaths      []string
 
+	// rules is the compiled snapshot Check reads (see tool_guard_cache.go).
+	rules atomic.Pointer[guardRules]
+
+	// Daily call counters for DailyLimits, reset when the day changes.
+	dailyCounts map[string]int
+	countsDay   string
+
+	// policies are extra checks registered by tools (see SetPolicy).
+	policies map[string]ToolPolicy
+
 	mu sync.Mutex
 }
 
@@ -194,6 +271,7 @@ func NewToolGuard(cfg ToolGuardConfig, logger *slog.Logger) *ToolGuard {
 
 	// Set protected paths.
 	guard.initProtectedPaths()
+	guard.compileRules()
 
 	// Open audit log.
 	if cfg.AuditLogPath != "" {
@@ -207,6 +285,9 @@ func NewToolGuard(cfg ToolGuardConfig, logger *slog.Logger) *ToolGuard {
 		}
 	}
 
+	guard.forensics = NewForensicRecorder(cfg.Forensics, cfg.AuditLogPath, logger)
+	guard.auditSinks = newAuditShippers(cfg.AuditSinks, guard.logger)
+
 	logger.Info("tool guard initialized",
 		"enabled", cfg.Enabled,
 		"audit_log", cfg.AuditLogPath,
@@ -224,17 +305,45 @@ type ToolCheckResult struct {
 	RequiresConfirmation  bool // true if tool needs user approval before execution
 }
 
+// ToolPolicy is an extra check a tool registers with the guard, such as the
+// deploy change windows. It runs before confirmation is requested, so a call
+// it rejects never reaches the approval prompt. A policy that sets
+// RequiresConfirmation sends non-owner calls through the approval flow.
+type ToolPolicy func(callerLevel AccessLevel, args map[string]any) ToolCheckResult
+
+// SetPolicy registers a policy for a tool. Policies apply even when the guard
+// is disabled or the tool is auto-approved.
+func (g *ToolGuard) SetPolicy(toolName string, policy ToolPolicy) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.policies == nil {
+		g.policies = make(map[string]ToolPolicy)
+	}
+	g.policies[toolName] = policy
+}
+
 // Check evaluates whether a tool call is permitted for the given access level.
 func (g *ToolGuard) Check(toolName string, callerLevel AccessLevel, args map[string]any) ToolCheckResult {
+	g.mu.Lock()
+	policy := g.policies[toolName]
+	g.mu.Unlock()
+	policyConfirm := false
+	if policy != nil {
+		result := policy(callerLevel, args)
+		if !result.Allowed {
+			return result
+		}
+		policyConfirm = result.RequiresConfirmation && callerLevel != AccessOwner
+	}
+
 	if !g.cfg.Enabled {
-		return ToolCheckResult{Allowed: true}
+		return ToolCheckResult{Allowed: true, RequiresConfirmation: policyConfirm}
 	}
+	rules := g.rules.Load()
 
 	// 0. Check auto-approve list (bypass all checks).
-	for _, name := range g.cfg.AutoApprove {
-		if name == toolName {
-			return ToolCheckResult{Allowed: true}
-		}
+	if rules.autoApprove[toolName] {
+		return ToolCheckResult{Allowed: true}
 	}
 
 	// Check if tool requires confirmation (after permission checks pass).
@@ -242,12 +351,7 @@ func (g *ToolGuard) Check(toolName string, callerLevel AccessLevel, args map[str
 	// For bash/exec, read-only commands (ls, cat, curl, etc.) also skip confirmation.
 	requiresConfirmation := false
 	if callerLevel != AccessOwner {
-		for _, name := range g.cfg.RequireConfirmation {
-			if name == toolName {
-				requiresConfirmation = true
-				break
-			}
-		}
+		requiresConfirmation = rules.requireConfirm[toolName]
 		if requiresConfirmation && (toolName == "bash" || toolName == "exec") {
 			command, _ := args["command"].(string)
 			if isReadOnlyCommand(command) {
@@ -255,15 +359,39 @@ func (g *ToolGuard) Check(toolName string, callerLevel AccessLevel, args map[str
 			}
 		}
 	}
+	if ownerApprovedTools[toolName] {
+		requiresConfirmation = true
+	}
+
+	// 1-4. Tool permission and argument checks (cached per call).
+	if result := g.checkArguments(rules, toolName, callerLevel, args); !result.Allowed {
+		return result
+	}
+
+	// 5. Image size and per-day call limits (checked last so denied calls
+	// don't use up the day's quota).
+	if toolName == "generate_image" {
+		if result := g.checkImageSize(args); !result.Allowed {
+			return result
+		}
+	}
+	if result := g.takeDailyQuota(toolName); !result.Allowed {
+		return result
+	}
+
+	return ToolCheckResult{Allowed: true, RequiresConfirmation: requiresConfirmation || policyConfirm}
+}
 
+// checkArgumentsUncached runs the tool permission check and the checks on
+// the call's arguments: command safety, SSH hosts and protected paths.
+func (g *ToolGuard) checkArgumentsUncached(toolName string, callerLevel AccessLevel, args map[string]any) ToolCheckResult {
 	// 1. Check tool-level permission.
-	permResult := g.checkToolPermission(toolName, callerLevel)
-	if !permResult.Allowed {
-		return permResult
+	if result := g.checkToolPermission(toolName, callerLevel); !result.Allowed {
+		return result
 	}
 
-	// 2. For bash/exec, check command safety.
-	if toolName == "bash" || toolName == "exec" {
+	// 2. For bash/exec/watch_command, check command safety.
+	if toolName == "bash" || toolName == "exec" || toolName == "watch_command" {
 		command, _ := args["command"].(string)
 		if result := g.checkCommandSafety(command, callerLevel); !result.Allowed {
 			return result
@@ -288,14 +416,65 @@ func (g *ToolGuard) Check(toolName string, callerLevel AccessLevel, args map[str
 	}
 
 	// 4. For file operations, check protected paths.
-	if toolName == "read_file" || toolName == "write_file" || toolName == "edit_file" {
+	if toolName == "read_file" || toolName == "write_file" || toolName == "edit_file" || toolName == "send_file" {
 		path, _ := args["path"].(string)
 		if result := g.checkPathSafety(path, callerLevel, toolName); !result.Allowed {
 			return result
 		}
 	}
+	if toolName == "apply_changes" {
+		for _, path := range changePaths(args) {
+			if result := g.checkPathSafety(path, callerLevel, toolName); !result.Allowed {
+				return result
+			}
+		}
+	}
 
-	return ToolCheckResult{Allowed: true, RequiresConfirmation: requiresConfirmation}
+	return ToolCheckResult{Allowed: true}
+}
+
+// checkImageSize rejects image requests larger than MaxImageSize.
+func (g *ToolGuard) checkImageSize(args map[string]any) ToolCheckResult {
+	maxW, maxH, ok := parseImageSize(g.cfg.MaxImageSize)
+	if !ok {
+		return ToolCheckResult{Allowed: true}
+	}
+	size, _ := args["size"].(string)
+	w, h, ok := parseImageSize(size)
+	if !ok {
+		return ToolCheckResult{Allowed: true} // tool default applies
+	}
+	if w*h > maxW*maxH {
+		return ToolCheckResult{
+			Allowed: false,
+			Reason:  fmt.Sprintf("image size %s exceeds the limit of %s", size, g.cfg.MaxImageSize),
+		}
+	}
+	return ToolCheckResult{Allowed: true}
+}
+
+// takeDailyQuota counts a call against the tool's daily limit.
+func (g *ToolGuard) takeDailyQuota(toolName string) ToolCheckResult {
+	limit := g.cfg.DailyLimits[toolName]
+	if limit <= 0 {
+		return ToolCheckResult{Allowed: true}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	today := time.Now().Format("2006-01-02")
+	if g.countsDay != today || g.dailyCounts == nil {
+		g.countsDay = today
+		g.dailyCounts = make(map[string]int)
+	}
+	if g.dailyCounts[toolName] >= limit {
+		return ToolCheckResult{
+			Allowed: false,
+			Reason:  fmt.Sprintf("daily limit of %d calls for %s reached, resets at midnight", limit, toolName),
+		}
+	}
+	g.dailyCounts[toolName]++
+	return ToolCheckResult{Allowed: true}
 }
 
 // SetSQLiteAudit configures a SQLite-backed audit logger. When set, audit
@@ -313,8 +492,21 @@ func (g *ToolGuard) SQLiteAudit() *SQLiteAuditLogger {
 	return g.sqliteAudit
 }
 
+// Forensics returns the forensic bundle recorder (nil when disabled).
+func (g *ToolGuard) Forensics() *ForensicRecorder {
+	if g == nil {
+		return nil
+	}
+	return g.forensics
+}
+
 // AuditLog records a tool execution to the audit log.
 func (g *ToolGuard) AuditLog(toolName string, callerJID string, callerLevel AccessLevel, args map[string]any, allowed bool, result string) {
+	g.AuditLogTimed(toolName, callerJID, callerLevel, args, allowed, result, 0)
+}
+
+// AuditLogTimed is AuditLog for a call that ran for duration.
+func (g *ToolGuard) AuditLogTimed(toolName string, callerJID string, callerLevel AccessLevel, args map[string]any, allowed bool, result string, duration time.Duration) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
@@ -342,16 +534,28 @@ func (g *ToolGuard) AuditLog(toolName string, callerJID string, callerLevel Acce
 
 	g.logger.Info("tool execution", "entry", entry)
 
-	// Write to SQLite if configured, otherwise fall back to text file.
 	if g.sqliteAudit != nil {
 		g.sqliteAudit.Log(toolName, callerJID, string(callerLevel), allowed, argsSummary, resultSummary)
-	} else if g.auditFile != nil {
-		_, _ = g.auditFile.WriteString(entry + "\n")
+	}
+	if g.auditFile == nil && len(g.auditSinks) == 0 {
+		return
+	}
+	e := newAuditEntry(toolName, callerJID, callerLevel, args, allowed, result, duration)
+	if g.auditFile != nil {
+		if line, err := json.Marshal(e); err == nil {
+			_, _ = g.auditFile.Write(append(line, '\n'))
+		}
+	}
+	for _, sink := range g.auditSinks {
+		sink.ship(e)
 	}
 }
 
-// Close closes the audit log file.
+// Close flushes the audit sinks and closes the audit log file.
 func (g *ToolGuard) Close() {
+	for _, sink := range g.auditSinks {
+		sink.close()
+	}
 	if g.auditFile != nil {
 		g.auditFile.Close()
 	}
@@ -367,8 +571,10 @@ func (g *ToolGuard) UpdateConfig(cfg ToolGuardConfig) {
 	g.cfg = cfg
 	g.dangerousPatterns = nil
 	g.defaultPatternCount = nil
+	g.patternNeedles = nil
 	g.compileDangerousPatterns()
 	g.initProtectedPaths()
+	g.compileRules()
 
 	g.logger.Info("tool guard config hot-reloaded",
 		"enabled", cfg.Enabled,
@@ -396,78 +602,103 @@ func (g *ToolGuard) checkToolPermission(toolName string, callerLevel AccessLevel
 	}
 }
 
-// checkCommandSafety inspects a bash/exec command for dangerous patterns.
+// checkCommandSafety inspects a bash/exec command. The command is parsed
+// into a shell AST (see tool_guard_shell.go) so every simple command,
+// including ones chained with ;/&&, nested in $(...) or passed to sh -c, is
+// checked on its own. Commands that cannot be parsed fall back to text checks.
 func (g *ToolGuard) checkCommandSafety(command string, callerLevel AccessLevel) ToolCheckResult {
 	if command == "" {
 		return ToolCheckResult{Allowed: true}
 	}
+	return g.inspectShell(command, callerLevel, 0)
+}
 
-	// --- Sudo check ---
-	isSudo := strings.Contains(command, "sudo ") || strings.HasPrefix(command, "sudo")
-	if isSudo {
-		if g.cfg.AllowSudo {
-			// AllowSudo: owner and admin can use sudo.
-			if callerLevel != AccessOwner && callerLevel != AccessAdmin {
-				return ToolCheckResult{
-					Allowed: false,
-					Reason:  "sudo commands require at least admin access",
-				}
-			}
-		} else if g.cfg.BlockSudo {
-			// Legacy BlockSudo: only owner can use.
-			if callerLevel != AccessOwner {
-				return ToolCheckResult{
-					Allowed: false,
-					Reason:  "sudo commands are disabled in config (allow_sudo: false)",
-				}
-			}
+// checkCommandText applies the sudo, reboot and pattern checks to the raw
+// command text. Used when the command does not parse as shell.
+func (g *ToolGuard) checkCommandText(command string, callerLevel AccessLevel) ToolCheckResult {
+	if strings.Contains(command, "sudo ") || strings.HasPrefix(command, "sudo") {
+		if r := g.checkSudo(callerLevel); !r.Allowed {
+			return r
 		}
 	}
-
-	// --- Reboot/shutdown check ---
 	for _, kw := range []string{"shutdown", "reboot", "poweroff", "halt"} {
 		if strings.Contains(command, kw) {
-			if !g.cfg.AllowReboot {
-				return ToolCheckResult{
-					Allowed: false,
-					Reason:  fmt.Sprintf("'%s' is blocked (allow_reboot: false in config)", kw),
-				}
+			if r := g.checkReboot(kw, callerLevel); !r.Allowed {
+				return r
 			}
-			// Even if allowed, require owner.
-			if callerLevel != AccessOwner {
-				return ToolCheckResult{
-					Allowed: false,
-					Reason:  fmt.Sprintf("'%s' requires owner access", kw),
-				}
+		}
+	}
+	return g.checkDangerousPatterns(command, callerLevel)
+}
+
+// checkSudo applies the sudo policy.
+func (g *ToolGuard) checkSudo(callerLevel AccessLevel) ToolCheckResult {
+	if g.cfg.AllowSudo {
+		// AllowSudo: owner and admin can use sudo.
+		if callerLevel != AccessOwner && callerLevel != AccessAdmin {
+			return ToolCheckResult{
+				Allowed: false,
+				Reason:  "sudo commands require at least admin access",
+			}
+		}
+	} else if g.cfg.BlockSudo {
+		// Legacy BlockSudo: only owner can use.
+		if callerLevel != AccessOwner {
+			return ToolCheckResult{
+				Allowed: false,
+				Reason:  "sudo commands are disabled in config (allow_sudo: false)",
 			}
 		}
 	}
+	return ToolCheckResult{Allowed: true}
+}
+
+// checkReboot applies the reboot/shutdown policy.
+func (g *ToolGuard) checkReboot(kw string, callerLevel AccessLevel) ToolCheckResult {
+	if !g.cfg.AllowReboot {
+		return ToolCheckResult{
+			Allowed: false,
+			Reason:  fmt.Sprintf("'%s' is blocked (allow_reboot: false in config)", kw),
+		}
+	}
+	// Even if allowed, require owner.
+	if callerLevel != AccessOwner {
+		return ToolCheckResult{
+			Allowed: false,
+			Reason:  fmt.Sprintf("'%s' requires owner access", kw),
+		}
+	}
+	return ToolCheckResult{Allowed: true}
+}
 
-	// --- Destructive command patterns ---
+// checkDangerousPatterns matches text against the destructive command patterns.
+func (g *ToolGuard) checkDangerousPatterns(text string, callerLevel AccessLevel) ToolCheckResult {
+	// Skip patterns whose literal is absent. Non-ASCII text always gets the
+	// full scan, since (?i) folds characters that ToLower does not.
+	lower, ascii := strings.ToLower(text), isASCII(text)
 	for i, pat := range g.dangerousPatterns {
-		if pat.MatchString(command) {
+		if ascii && i < len(g.patternNeedles) && g.patternNeedles[i] != "" && !strings.Contains(lower, g.patternNeedles[i]) {
+			continue
+		}
+		if pat.MatchString(text) {
 			// If allow_destructive is on, owner is permitted.
 			if g.cfg.AllowDestructive && callerLevel == AccessOwner {
 				g.logger.Warn("destructive command allowed via config",
-					"command", command,
+					"command", text,
 					"pattern", pat.String(),
 				)
 				continue
 			}
-			// Custom patterns (appended after defaults) always block non-owner.
-			if !g.cfg.AllowDestructive || callerLevel != AccessOwner {
-				label := "safety rule"
-				if i < len(g.defaultPatternCount) {
-					label = "default safety rule"
-				}
-				return ToolCheckResult{
-					Allowed: false,
-					Reason:  fmt.Sprintf("command blocked by %s: %s (set allow_destructive: true to override)", label, pat.String()),
-				}
+			label := "safety rule"
+			if i < len(g.defaultPatternCount) && g.defaultPatternCount[i] {
+				label = "default safety rule"
+			}
+			return ToolCheckResult{
+				Allowed: false,
+				Reason:  fmt.Sprintf("command blocked by %s: %s (set allow_destructive: true to override)", label, pat.String()),
 			}
 		}
 	}
-
 	return ToolCheckResult{Allowed: true}
 }
 
@@ -516,15 +747,13 @@ func (g *ToolGuard) checkPathSafety(path string, callerLevel AccessLevel, toolNa
 		return ToolCheckResult{Allowed: true}
 	}
 
-	// Resolve to absolute path.
-	absPath := path
-	if !filepath.IsAbs(path) {
-		absPath, _ = filepath.Abs(path)
-	}
+	// Resolve to a clean absolute path (also converts / to \ on Windows).
+	absPath := resolvePath(path)
+	key := pathKey(absPath)
 
-	for _, protected := range g.protectedPaths {
+	for _, protected := range g.rules.Load().protected {
 		// Check exact match or prefix match.
-		if absPath == protected || strings.HasPrefix(absPath, protected+"/") {
+		if pathWithin(absPath, protected.path) {
 			// Allow reading some protected paths but not writing.
 			if toolName == "read_file" && callerLevel == AccessAdmin {
 				continue
@@ -535,11 +764,14 @@ func (g *ToolGuard) checkPathSafety(path string, callerLevel AccessLevel, toolNa
 			}
 		}
 
-		// Glob match.
-		if matched, _ := filepath.Match(protected, absPath); matched {
+		// Glob match (literal entries are covered by the prefix match).
+		if !protected.glob {
+			continue
+		}
+		if matched, _ := filepath.Match(protected.key, key); matched {
 			return ToolCheckResult{
 				Allowed: false,
-				Reason:  fmt.Sprintf("path '%s' matches protected pattern '%s'", path, protected),
+				Reason:  fmt.Sprintf("path '%s' matches protected pattern '%s'", path, protected.path),
 			}
 		}
 	}
@@ -547,36 +779,48 @@ func (g *ToolGuard) checkPathSafety(path string, callerLevel AccessLevel, toolNa
 	return ToolCheckResult{Allowed: true}
 }
 
-// compileDangerousPatterns compiles the dangerous command regex patterns.
+// dangerousPattern is a default dangerous-command pattern with a lowercase
+// literal that any command it matches must contain, so the regex only runs
+// on commands that contain the literal.
+type dangerousPattern struct {
+	needle string
+	re     *regexp.Regexp
+}
+
+// defaultDangerousPatterns are compiled once at startup; only custom patterns
+// are compiled per config version.
+// Note: shutdown/reboot/halt are handled separately by AllowReboot check.
+var defaultDangerousPatterns = []dangerousPattern{
+	{"rm", regexp.MustCompile(`(?i)\brm\s+(-[a-zA-Z]*f[a-zA-Z]*\s+)?/`)}, // rm -rf /
+	{"mkfs", regexp.MustCompile(`(?i)\bmkfs\b`)},                         // format filesystem
+	{"of=/dev/", regexp.MustCompile(`(?i)\bdd\s+.*of=/dev/`)},            // dd to device
+	{"/dev/sd", regexp.MustCompile(`(?i)>\s*/dev/sd`)},                   // overwrite device
+	{"777", regexp.MustCompile(`(?i)\bchmod\s+(-R\s+)?777\s+/`)},         // chmod 777 /
+	{"chown", regexp.MustCompile(`(?i)\bchown\s+(-R\s+)?.*\s+/`)},        // chown / recursively
+	{"};:", regexp.MustCompile(`(?i):\(\)\{\s*:\|:&\s*\};:`)},            // fork bomb
+	{"iptables", regexp.MustCompile(`(?i)\biptables\s+-F`)},              // flush firewall
+	{"disable", regexp.MustCompile(`(?i)\bufw\s+disable`)},               // disable firewall
+	{"passwd", regexp.MustCompile(`(?i)\bpasswd\b`)},                     // change password
+	{"userdel", regexp.MustCompile(`(?i)\buserdel\b`)},                   // delete user
+	{"groupdel", regexp.MustCompile(`(?i)\bgroupdel\b`)},                 // delete group
+	{"database", regexp.MustCompile(`(?i)DROP\s+DATABASE`)},              // drop database (SQL)
+	{"table", regexp.MustCompile(`(?i)DROP\s+TABLE`)},                    // drop table
+	{"truncate", regexp.MustCompile(`(?i)TRUNCATE\s+TABLE`)},             // truncate table
+	// Windows (PowerShell / cmd).
+	{"remove-item", regexp.MustCompile(`(?i)\bRemove-Item\b.*-Recurse.*\s[a-z]:\\?\s*$`)}, // Remove-Item -Recurse C:\
+	{"format-volume", regexp.MustCompile(`(?i)\bFormat-Volume\b`)},                        // format volume
+	{"format", regexp.MustCompile(`(?i)(^|[;&|]\s*)format\s+[a-z]:`)},                     // format C:
+	{"vssadmin", regexp.MustCompile(`(?i)\bvssadmin\s+delete\s+shadows`)},                 // delete shadow copies
+	{"delete", regexp.MustCompile(`(?i)\breg\s+delete\s+HK(LM|EY_LOCAL_MACHINE)`)},        // delete machine registry keys
+	{"bcdedit", regexp.MustCompile(`(?i)\bbcdedit\b`)},                                    // edit boot configuration
+}
+
+// compileDangerousPatterns sets the dangerous command patterns: the
+// precompiled defaults followed by the custom patterns from config.
 func (g *ToolGuard) compileDangerousPatterns() {
-	// Default dangerous patterns (always compiled).
-	// Note: shutdown/reboot/halt are handled separately by AllowReboot check.
-	defaultPatterns := []string{
-		`\brm\s+(-[a-zA-Z]*f[a-zA-Z]*\s+)?/`, // rm -rf /
-		`\bmkfs\b`,                              // format filesystem
-		`\bdd\s+.*of=/dev/`,                     // dd to device
-		`>\s*/dev/sd`,                           // overwrite device
-		`\bchmod\s+(-R\s+)?777\s+/`,            // chmod 777 /
-		`\bchown\s+(-R\s+)?.*\s+/`,             // chown / recursively
-		`:\(\)\{\s*:\|:&\s*\};:`,               // fork bomb
-		`\biptables\s+-F`,                       // flush firewall
-		`\bufw\s+disable`,                       // disable firewall
-		`\bpasswd\b`,                            // change password
-		`\buserdel\b`,                           // delete user
-		`\bgroupdel\b`,                          // delete group
-		`DROP\s+DATABASE`,                       // drop database (SQL)
-		`DROP\s+TABLE`,                          // drop table
-		`TRUNCATE\s+TABLE`,                      // truncate table
-	}
-
-	// Compile default patterns.
-	for _, p := range defaultPatterns {
-		re, err := regexp.Compile("(?i)" + p)
-		if err != nil {
-			g.logger.Warn("invalid default dangerous pattern", "pattern", p, "error", err)
-			continue
-		}
-		g.dangerousPatterns = append(g.dangerousPatterns, re)
+	for _, p := range defaultDangerousPatterns {
+		g.dangerousPatterns = append(g.dangerousPatterns, p.re)
+		g.patternNeedles = append(g.patternNeedles, p.needle)
 		g.defaultPatternCount = append(g.defaultPatternCount, true)
 	}
 
@@ -588,17 +832,31 @@ func (g *ToolGuard) compileDangerousPatterns() {
 			continue
 		}
 		g.dangerousPatterns = append(g.dangerousPatterns, re)
+		g.patternNeedles = append(g.patternNeedles, "")
 		g.defaultPatternCount = append(g.defaultPatternCount, false)
 	}
 }
 
 // initProtectedPaths sets up the list of protected filesystem paths.
 func (g *ToolGuard) initProtectedPaths() {
-	g.protectedPaths = g.cfg.ProtectedPaths
+	g.protectedPaths = nil
+	for _, p := range g.cfg.ProtectedPaths {
+		p = expandPath(p)
+		if filepath.IsAbs(p) {
+			p = filepath.Clean(p)
+		}
+		g.protectedPaths = append(g.protectedPaths, p)
+	}
 	if len(g.protectedPaths) == 0 {
-		home, _ := os.UserHomeDir()
+		g.protectedPaths = defaultProtectedPaths()
+	}
+}
 
-		g.protectedPaths = []string{
+// defaultProtectedPaths returns the paths protected when none are configured.
+func defaultProtectedPaths() []string {
+	home, _ := os.UserHomeDir()
+
+	paths := []string{
 			// SSH keys and config.
 			filepath.Join(home, ".ssh"),
 			// GPG keys.
@@ -619,7 +877,48 @@ func (g *ToolGuard) initProtectedPaths() {
 			// Browser data.
 			filepath.Join(home, ".mozilla"),
 			filepath.Join(home, ".config/google-chrome"),
-		}
+	}
+	if runtime.GOOS == "windows" {
+		paths = append(paths, windowsProtectedPaths(home)...)
+	}
+	return paths
+}
+
+// windowsProtectedPaths returns the Windows secret stores: Credential
+// Manager and DPAPI data, registry hives, and credentials kept under
+// %APPDATA% and %LOCALAPPDATA%.
+func windowsProtectedPaths(home string) []string {
+	appData := os.Getenv("APPDATA")
+	if appData == "" {
+		appData = filepath.Join(home, "AppData", "Roaming")
+	}
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		localAppData = filepath.Join(home, "AppData", "Local")
+	}
+	systemRoot := os.Getenv("SystemRoot")
+	if systemRoot == "" {
+		systemRoot = `C:\Windows`
+	}
+
+	return []string{
+		// Credential Manager, Windows Vault and DPAPI master keys.
+		filepath.Join(appData, "Microsoft", "Credentials"),
+		filepath.Join(localAppData, "Microsoft", "Credentials"),
+		filepath.Join(appData, "Microsoft", "Vault"),
+		filepath.Join(localAppData, "Microsoft", "Vault"),
+		filepath.Join(appData, "Microsoft", "Protect"),
+		// Registry hives (SAM, SECURITY, user profile).
+		filepath.Join(systemRoot, "System32", "config"),
+		filepath.Join(home, "NTUSER.DAT"),
+		// CLI credentials stored under %APPDATA%.
+		filepath.Join(appData, "gcloud"),
+		filepath.Join(appData, "GitHub CLI"),
+		filepath.Join(appData, "Docker"),
+		// Browser data.
+		filepath.Join(localAppData, "Google", "Chrome", "User Data"),
+		filepath.Join(localAppData, "Microsoft", "Edge", "User Data"),
+		filepath.Join(appData, "Mozilla", "Firefox", "Profiles"),
 	}
 }
 
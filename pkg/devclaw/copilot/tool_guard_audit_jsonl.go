@@ -0,0 +1,200 @@
+// Package copilot – tool_guard_audit_jsonl.go defines the structured audit
+// log of the ToolGuard: one JSON object per line with the tool, caller,
+// access level, outcome, a hash of the arguments (never their values),
+// duration and result size. `devclaw audit query` reads it for incident
+// review. Lines that are not JSON (entries of the older free-text format)
+// are skipped by the reader.
+package copilot
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultAuditLogPath is where the audit log is written by default.
+const DefaultAuditLogPath = "./data/audit.jsonl"
+
+// Audit entry outcomes.
+const (
+	AuditOutcomeOK      = "ok"
+	AuditOutcomeError   = "error"
+	AuditOutcomeTimeout = "timeout"
+	AuditOutcomeDenied  = "denied"
+)
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Tool    string    `json:"tool"`
+	Caller  string    `json:"caller,omitempty"`
+	Level   string    `json:"level,omitempty"`
+	Allowed bool      `json:"allowed"`
+	Outcome string    `json:"outcome"`
+
+	// Reason is why a call was denied, or the error of a failed one.
+	Reason string `json:"reason,omitempty"`
+
+	// ArgsHash identifies the arguments without storing them (sha256 of
+	// their JSON encoding, truncated).
+	ArgsHash string `json:"args_hash,omitempty"`
+
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	ResultSize int    `json:"result_size"`
+	ForensicID string `json:"forensic_id,omitempty"`
+}
+
+// newAuditEntry builds the entry of a call. result is the audit result
+// string of ToolGuard.AuditLog: the output, "ERROR: ...", "TIMEOUT: ..." or
+// the denial reason, optionally prefixed with a forensic tag.
+func newAuditEntry(tool, caller string, level AccessLevel, args map[string]any, allowed bool, result string, duration time.Duration) AuditEntry {
+	e := AuditEntry{
+		Time:       time.Now().UTC(),
+		Tool:       tool,
+		Caller:     caller,
+		Level:      string(level),
+		Allowed:    allowed,
+		Outcome:    AuditOutcomeOK,
+		ArgsHash:   hashAuditArgs(args),
+		DurationMs: duration.Milliseconds(),
+	}
+	if rest, ok := strings.CutPrefix(result, "[forensics:"); ok {
+		if id, after, found := strings.Cut(rest, "] "); found {
+			e.ForensicID, result = id, after
+		}
+	}
+	switch {
+	case !allowed:
+		e.Outcome, e.Reason = AuditOutcomeDenied, result
+	case strings.HasPrefix(result, "ERROR: "):
+		e.Outcome, e.Reason = AuditOutcomeError, truncate(strings.TrimPrefix(result, "ERROR: "), 200)
+	case strings.HasPrefix(result, "TIMEOUT: "):
+		e.Outcome, e.Reason = AuditOutcomeTimeout, strings.TrimPrefix(result, "TIMEOUT: ")
+	default:
+		e.ResultSize = len(result)
+	}
+	return e
+}
+
+// hashAuditArgs returns a short sha256 of the JSON-encoded arguments (map
+// keys are sorted by encoding/json, so equal arguments hash alike).
+func hashAuditArgs(args map[string]any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		data = []byte(fmt.Sprint(args))
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Format renders the entry as one human-readable line.
+func (e AuditEntry) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-7s %-16s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Outcome, e.Tool)
+	if e.Caller != "" {
+		fmt.Fprintf(&b, " %s", e.Caller)
+	}
+	if e.Level != "" {
+		fmt.Fprintf(&b, " (%s)", e.Level)
+	}
+	if e.DurationMs > 0 {
+		fmt.Fprintf(&b, " %dms", e.DurationMs)
+	}
+	if e.ResultSize > 0 {
+		fmt.Fprintf(&b, " %dB", e.ResultSize)
+	}
+	if e.ArgsHash != "" {
+		fmt.Fprintf(&b, " args=%s", e.ArgsHash)
+	}
+	if e.ForensicID != "" {
+		fmt.Fprintf(&b, " [forensics:%s]", e.ForensicID)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(&b, " — %s", e.Reason)
+	}
+	return b.String()
+}
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	// Tools matches tool names exactly.
+	Tools []string
+
+	// Caller matches the caller JID exactly.
+	Caller string
+
+	// Since keeps entries at or after this time.
+	Since time.Time
+
+	// Denied keeps only calls the guard or the user refused.
+	Denied bool
+
+	// Outcome keeps entries with this outcome (ok, error, timeout, denied).
+	Outcome string
+
+	// Limit caps the result to the most recent N matches (0 = all).
+	Limit int
+}
+
+// Match reports whether the entry passes the filter (ignores Limit).
+func (f AuditFilter) Match(e AuditEntry) bool {
+	if len(f.Tools) > 0 && !slices.Contains(f.Tools, e.Tool) {
+		return false
+	}
+	if f.Caller != "" && e.Caller != f.Caller {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Denied && e.Allowed {
+		return false
+	}
+	return f.Outcome == "" || e.Outcome == f.Outcome
+}
+
+// ReadAuditEntries returns the entries of the audit log at path that match
+// f, oldest first. A missing file is an empty log.
+func ReadAuditEntries(path string, f AuditFilter) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var e AuditEntry
+		if json.Unmarshal(line, &e) != nil || e.Tool == "" {
+			continue
+		}
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[len(out)-f.Limit:]
+	}
+	return out, nil
+}
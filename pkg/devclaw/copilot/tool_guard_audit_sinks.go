@@ -0,0 +1,494 @@
+// Package copilot – tool_guard_audit_sinks.go ships the audit log off-host,
+// so tool activity can be centralized for compliance. Each sink configured
+// under security.tool_guard.audit_sinks gets every audit entry:
+//
+//   - syslog: one RFC 5424 message per entry (facility "log audit") to the
+//     local syslog socket, or to a remote collector over UDP, TCP or TLS.
+//   - https: batches POSTed as JSON, signed with HMAC-SHA256 of the body in
+//     X-Signature-256 ("sha256=<hex>", as the webhook channel verifies).
+//   - s3: batches written as JSONL objects to an S3-compatible bucket
+//     (AWS, MinIO, R2, ...) with SigV4-signed PUTs.
+//
+// Shipping never blocks a tool call: entries are queued per sink and sent
+// in the background; failed batches are retried on the next flush, and
+// entries beyond the queue limits are dropped with a warning. The local
+// JSONL file stays the record of truth. Sinks are set up at startup.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AuditSinkConfig configures one off-host audit sink.
+type AuditSinkConfig struct {
+	// Type is "syslog", "https" or "s3".
+	Type string `yaml:"type"`
+
+	// Network is the syslog transport: "udp", "tcp" or "tls". Empty = the
+	// local syslog socket (/dev/log).
+	Network string `yaml:"network"`
+
+	// Address is the syslog collector ("host:port").
+	Address string `yaml:"address"`
+
+	// Tag is the syslog app name (default: devclaw).
+	Tag string `yaml:"tag"`
+
+	// URL is the https endpoint batches are POSTed to.
+	URL string `yaml:"url"`
+
+	// Secret signs https batches with HMAC-SHA256 (X-Signature-256).
+	Secret string `yaml:"secret"`
+
+	// Headers are sent with every https request (e.g. an API key).
+	Headers map[string]string `yaml:"headers"`
+
+	// Bucket is the s3 bucket; Prefix is prepended to object keys
+	// (<prefix>/YYYY/MM/DD/<host>-<time>.jsonl).
+	Bucket string `yaml:"bucket"`
+	Prefix string `yaml:"prefix"`
+
+	// Region is the s3 region (default: us-east-1).
+	Region string `yaml:"region"`
+
+	// Endpoint is the base URL of an S3-compatible service (e.g.
+	// https://minio.internal:9000), addressed path-style. Empty = AWS.
+	Endpoint string `yaml:"endpoint"`
+
+	// AccessKeyID and SecretAccessKey sign s3 requests. Empty = the
+	// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+
+	// FlushSeconds is how often queued entries are sent (default: 1 for
+	// syslog, 10 for https, 300 for s3).
+	FlushSeconds int `yaml:"flush_seconds"`
+}
+
+const (
+	// auditSinkQueue is the per-sink queue of entries not yet picked up.
+	auditSinkQueue = 1024
+
+	// auditSinkMaxPending caps the entries held for retry per sink.
+	auditSinkMaxPending = 10000
+
+	// auditSinkBatch is the batch size that triggers an early flush.
+	auditSinkBatch = 500
+
+	// auditSinkTimeout bounds one delivery attempt.
+	auditSinkTimeout = 15 * time.Second
+)
+
+// auditSink delivers a batch of audit entries.
+type auditSink interface {
+	send(ctx context.Context, batch []AuditEntry) error
+	close()
+}
+
+// auditShipper queues entries for one sink and flushes them in the
+// background.
+type auditShipper struct {
+	sink     auditSink
+	interval time.Duration
+	logger   *slog.Logger
+
+	queue   chan AuditEntry
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// newAuditShippers builds and starts the configured sinks. Invalid sink
+// configs are logged and skipped.
+func newAuditShippers(cfgs []AuditSinkConfig, logger *slog.Logger) []*auditShipper {
+	var out []*auditShipper
+	for i, cfg := range cfgs {
+		sink, interval, err := newAuditSink(cfg)
+		if err != nil {
+			logger.Warn("audit sink disabled", "index", i, "type", cfg.Type, "error", err)
+			continue
+		}
+		if cfg.FlushSeconds > 0 {
+			interval = time.Duration(cfg.FlushSeconds) * time.Second
+		}
+		s := &auditShipper{
+			sink:     sink,
+			interval: interval,
+			logger:   logger.With("audit_sink", cfg.Type),
+			queue:    make(chan AuditEntry, auditSinkQueue),
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+		go s.run()
+		out = append(out, s)
+		logger.Info("audit sink enabled", "type", cfg.Type, "flush", interval)
+	}
+	return out
+}
+
+// newAuditSink creates the sink of cfg and its default flush interval.
+func newAuditSink(cfg AuditSinkConfig) (auditSink, time.Duration, error) {
+	switch cfg.Type {
+	case "syslog":
+		if cfg.Network != "" && cfg.Address == "" {
+			return nil, 0, fmt.Errorf("syslog over %s needs an address", cfg.Network)
+		}
+		tag := cfg.Tag
+		if tag == "" {
+			tag = "devclaw"
+		}
+		host, _ := os.Hostname()
+		return &syslogAuditSink{network: cfg.Network, address: cfg.Address, tag: tag, host: host}, time.Second, nil
+
+	case "https":
+		if cfg.URL == "" {
+			return nil, 0, fmt.Errorf("https sink needs a url")
+		}
+		host, _ := os.Hostname()
+		return &httpsAuditSink{url: cfg.URL, secret: cfg.Secret, headers: cfg.Headers, host: host,
+			client: &http.Client{Timeout: auditSinkTimeout}}, 10 * time.Second, nil
+
+	case "s3":
+		if cfg.Bucket == "" {
+			return nil, 0, fmt.Errorf("s3 sink needs a bucket")
+		}
+		s := &s3AuditSink{
+			bucket:    cfg.Bucket,
+			prefix:    strings.Trim(cfg.Prefix, "/"),
+			region:    cfg.Region,
+			endpoint:  strings.TrimSuffix(cfg.Endpoint, "/"),
+			accessKey: cfg.AccessKeyID,
+			secretKey: cfg.SecretAccessKey,
+			client:    &http.Client{Timeout: auditSinkTimeout},
+		}
+		if s.region == "" {
+			s.region = "us-east-1"
+		}
+		if s.accessKey == "" {
+			s.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if s.secretKey == "" {
+			s.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if s.accessKey == "" || s.secretKey == "" {
+			return nil, 0, fmt.Errorf("s3 sink needs access_key_id and secret_access_key")
+		}
+		s.host, _ = os.Hostname()
+		return s, 5 * time.Minute, nil
+
+	default:
+		return nil, 0, fmt.Errorf("unknown audit sink type %q (syslog, https, s3)", cfg.Type)
+	}
+}
+
+// ship queues an entry without blocking; a full queue drops it.
+func (s *auditShipper) ship(e AuditEntry) {
+	select {
+	case s.queue <- e:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// run collects queued entries and flushes them every interval, or early
+// when a batch fills up, until close.
+func (s *auditShipper) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var pending []AuditEntry
+	for {
+		select {
+		case e := <-s.queue:
+			pending = append(pending, e)
+			// Flush early at every full batch; while a sink is down the
+			// backlog is retried on the ticker, not on every new entry.
+			if len(pending)%auditSinkBatch == 0 {
+				pending = s.flush(pending)
+			}
+		case <-ticker.C:
+			pending = s.flush(pending)
+		case <-s.stop:
+			for len(s.queue) > 0 {
+				pending = append(pending, <-s.queue)
+			}
+			s.flush(pending)
+			s.sink.close()
+			return
+		}
+	}
+}
+
+// flush sends pending entries and returns those left for a retry.
+func (s *auditShipper) flush(pending []AuditEntry) []AuditEntry {
+	if n := s.dropped.Swap(0); n > 0 {
+		s.logger.Warn("audit sink queue full, entries dropped", "count", n)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), auditSinkTimeout)
+	defer cancel()
+	if err := s.sink.send(ctx, pending); err != nil {
+		s.logger.Warn("audit shipping failed, will retry", "entries", len(pending), "error", err)
+		if over := len(pending) - auditSinkMaxPending; over > 0 {
+			s.logger.Warn("audit sink backlog full, oldest entries dropped", "count", over)
+			pending = pending[over:]
+		}
+		return pending
+	}
+	return nil
+}
+
+// close flushes what is queued and stops the shipper (waits up to the
+// delivery timeout).
+func (s *auditShipper) close() {
+	s.once.Do(func() { close(s.stop) })
+	select {
+	case <-s.done:
+	case <-time.After(auditSinkTimeout + time.Second):
+	}
+}
+
+// ---------- syslog ----------
+
+// syslogAuditSink writes RFC 5424 messages to a syslog socket.
+type syslogAuditSink struct {
+	network, address, tag, host string
+	conn                        net.Conn
+}
+
+func (s *syslogAuditSink) send(ctx context.Context, batch []AuditEntry) error {
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	for i, e := range batch {
+		msg := s.format(e)
+		if s.network == "tcp" || s.network == "tls" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg) // octet-counting framing (RFC 6587)
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(auditSinkTimeout))
+		if _, err := io.WriteString(s.conn, msg); err != nil {
+			s.close()
+			if i > 0 {
+				return fmt.Errorf("syslog write after %d entries: %w", i, err)
+			}
+			return fmt.Errorf("syslog write: %w", err)
+		}
+	}
+	return nil
+}
+
+// dial connects to the collector, or to the local syslog socket.
+func (s *syslogAuditSink) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	switch s.network {
+	case "":
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			if conn, err := d.DialContext(ctx, "unixgram", path); err == nil {
+				return conn, nil
+			}
+		}
+		return nil, fmt.Errorf("no local syslog socket")
+	case "tls":
+		td := tls.Dialer{NetDialer: &d}
+		return td.DialContext(ctx, "tcp", s.address)
+	default:
+		return d.DialContext(ctx, s.network, s.address)
+	}
+}
+
+// format renders an entry as an RFC 5424 message with the JSON entry as
+// its body.
+func (s *syslogAuditSink) format(e AuditEntry) string {
+	const facilityLogAudit = 13
+	severity := 6 // informational
+	switch e.Outcome {
+	case AuditOutcomeDenied:
+		severity = 4 // warning
+	case AuditOutcomeError, AuditOutcomeTimeout:
+		severity = 5 // notice
+	}
+	body, _ := json.Marshal(e)
+	host := s.host
+	if host == "" {
+		host = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d audit - %s\n",
+		facilityLogAudit*8+severity, e.Time.UTC().Format(time.RFC3339Nano), host, s.tag, os.Getpid(), body)
+}
+
+func (s *syslogAuditSink) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// ---------- https ----------
+
+// httpsAuditSink POSTs batches as signed JSON.
+type httpsAuditSink struct {
+	url, secret, host string
+	headers           map[string]string
+	client            *http.Client
+}
+
+// auditBatch is the body of an https delivery.
+type auditBatch struct {
+	Host    string       `json:"host"`
+	Entries []AuditEntry `json:"entries"`
+}
+
+func (s *httpsAuditSink) send(ctx context.Context, batch []AuditEntry) error {
+	body, err := json.Marshal(auditBatch{Host: s.host, Entries: batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	if s.secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpsAuditSink) close() {}
+
+// ---------- s3 ----------
+
+// s3AuditSink writes batches as JSONL objects to an S3-compatible bucket.
+type s3AuditSink struct {
+	bucket, prefix, region, endpoint string
+	accessKey, secretKey, host       string
+	client                           *http.Client
+}
+
+func (s *s3AuditSink) send(ctx context.Context, batch []AuditEntry) error {
+	var body bytes.Buffer
+	for _, e := range batch {
+		line, _ := json.Marshal(e)
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+	now := time.Now().UTC()
+	key := fmt.Sprintf("%s/%s-%d.jsonl", now.Format("2006/01/02"), s.host, now.UnixNano())
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+
+	var target string
+	if s.endpoint != "" {
+		target = s.endpoint + "/" + s.bucket + "/" + s3EscapePath(key)
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, s3EscapePath(key))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	signS3Request(req, body.Bytes(), s.accessKey, s.secretKey, s.region, now)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: HTTP %d: %s", key, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *s3AuditSink) close() {}
+
+// signS3Request adds AWS Signature Version 4 headers to an S3 request.
+func signS3Request(req *http.Request, payload []byte, accessKey, secretKey, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(payload)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHex)
+
+	const signedHeaders = "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"content-type:" + req.Header.Get("Content-Type") + "\n" +
+			"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHex + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHex,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3EscapePath escapes an object key for a URL path, keeping "/".
+func s3EscapePath(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
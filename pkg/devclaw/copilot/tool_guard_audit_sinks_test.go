@@ -0,0 +1,106 @@
+package copilot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditSinks(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		batches  []auditBatch
+		sigOK    bool
+		s3Path   string
+		s3Auth   string
+		s3Body   string
+		syslogRx string
+	)
+	hooks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write(body)
+		var b auditBatch
+		_ = json.Unmarshal(body, &b)
+		mu.Lock()
+		sigOK = r.Header.Get("X-Signature-256") == "sha256="+hex.EncodeToString(mac.Sum(nil))
+		batches = append(batches, b)
+		mu.Unlock()
+	}))
+	defer hooks.Close()
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		s3Path, s3Auth, s3Body = r.Method+" "+r.URL.Path, r.Header.Get("Authorization"), string(body)
+		mu.Unlock()
+	}))
+	defer bucket.Close()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	go func() {
+		buf := make([]byte, 4096)
+		n, _, err := udp.ReadFrom(buf)
+		if err == nil {
+			mu.Lock()
+			syslogRx = string(buf[:n])
+			mu.Unlock()
+		}
+	}()
+
+	cfg := DefaultToolGuardConfig()
+	cfg.AuditLogPath = ""
+	cfg.AuditSinks = []AuditSinkConfig{
+		{Type: "https", URL: hooks.URL, Secret: "s3cret"},
+		{Type: "s3", Endpoint: bucket.URL, Bucket: "audit", Prefix: "devclaw/", AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		{Type: "syslog", Network: "udp", Address: udp.LocalAddr().String()},
+		{Type: "kafka"}, // unknown: skipped
+	}
+	g := NewToolGuard(cfg, slog.New(slog.DiscardHandler))
+	if len(g.auditSinks) != 3 {
+		t.Fatalf("sinks = %d, want 3", len(g.auditSinks))
+	}
+	g.AuditLog("bash", "user@x", AccessUser, map[string]any{"command": "rm -rf /"}, false, "destructive command")
+	g.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		got := syslogRx
+		mu.Unlock()
+		if got != "" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 1 || len(batches[0].Entries) != 1 || batches[0].Entries[0].Outcome != AuditOutcomeDenied || !sigOK {
+		t.Errorf("https batches = %+v, signature ok = %v", batches, sigOK)
+	}
+	if !strings.HasPrefix(s3Path, "PUT /audit/devclaw/") || !strings.HasSuffix(s3Path, ".jsonl") {
+		t.Errorf("s3 request = %q", s3Path)
+	}
+	if !strings.HasPrefix(s3Auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(s3Body, `"tool":"bash"`) {
+		t.Errorf("s3 auth = %q, body = %q", s3Auth, s3Body)
+	}
+	if !strings.HasPrefix(syslogRx, "<108>1 ") || !strings.Contains(syslogRx, `"outcome":"denied"`) {
+		t.Errorf("syslog message = %q", syslogRx)
+	}
+	if strings.Contains(s3Body+syslogRx, "rm -rf") {
+		t.Error("argument values must not leave the host")
+	}
+}
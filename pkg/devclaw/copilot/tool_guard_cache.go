@@ -0,0 +1,144 @@
+// Package copilot – tool_guard_cache.go keeps the per-call cost of the tool
+// guard low. Everything derived from the config (protected path keys,
+// auto-approve and confirmation sets) is compiled once per config version
+// into an immutable guardRules snapshot that Check reads without taking the
+// guard mutex. Decisions of the argument checks (command safety, SSH hosts,
+// protected paths) are cached per (tool, caller level, input hash) in the
+// snapshot, so a run that repeats the same call pays for the shell parse and
+// pattern scan once; a config reload starts a new cache.
+package copilot
+
+import (
+	"crypto/sha256"
+	"strings"
+	"sync"
+)
+
+// guardDecisionCacheSize bounds the decision cache; it is cleared when full.
+const guardDecisionCacheSize = 4096
+
+// guardRules is the compiled form of one config version.
+type guardRules struct {
+	autoApprove    map[string]bool
+	requireConfirm map[string]bool
+
+	protected []protectedPath
+	decisions guardDecisionCache
+}
+
+// protectedPath is a protected path with its comparison key precomputed.
+type protectedPath struct {
+	path string
+	key  string // pathKey(path)
+	glob bool   // contains glob metacharacters
+}
+
+// compileRules builds the rules snapshot from the current config. The caller
+// must hold g.mu (or own g exclusively, as in NewToolGuard).
+func (g *ToolGuard) compileRules() {
+	r := &guardRules{
+		autoApprove:    make(map[string]bool, len(g.cfg.AutoApprove)),
+		requireConfirm: make(map[string]bool, len(g.cfg.RequireConfirmation)),
+	}
+	for _, name := range g.cfg.AutoApprove {
+		r.autoApprove[name] = true
+	}
+	for _, name := range g.cfg.RequireConfirmation {
+		r.requireConfirm[name] = true
+	}
+
+	for _, p := range g.protectedPaths {
+		r.protected = append(r.protected, protectedPath{
+			path: p,
+			key:  pathKey(p),
+			glob: strings.ContainsAny(p, "*?["),
+		})
+	}
+	g.rules.Store(r)
+}
+
+// guardCacheInput returns the arguments the argument checks of toolName
+// depend on, or false when the tool has no argument checks worth caching.
+func guardCacheInput(toolName string, args map[string]any) (string, bool) {
+	switch toolName {
+	case "bash", "exec", "watch_command":
+		command, _ := args["command"].(string)
+		return command, true
+	case "ssh", "scp":
+		host, _ := args["host"].(string)
+		src, _ := args["source"].(string)
+		dst, _ := args["destination"].(string)
+		return host + "\x00" + src + "\x00" + dst, true
+	case "read_file", "write_file", "edit_file", "send_file":
+		path, _ := args["path"].(string)
+		return path, true
+	case "apply_changes":
+		return strings.Join(changePaths(args), "\x00"), true
+	}
+	return "", false
+}
+
+// guardDecisionKey identifies a decision. Inputs are hashed so long commands
+// don't pin memory in the cache.
+type guardDecisionKey [sha256.Size]byte
+
+func newGuardDecisionKey(toolName string, callerLevel AccessLevel, input string) guardDecisionKey {
+	h := sha256.New()
+	h.Write([]byte(toolName))
+	h.Write([]byte{0})
+	h.Write([]byte(callerLevel))
+	h.Write([]byte{0})
+	h.Write([]byte(input))
+	var k guardDecisionKey
+	h.Sum(k[:0])
+	return k
+}
+
+// guardDecisionCache maps decision keys to results. It has its own lock so
+// lookups never wait on audit writes.
+type guardDecisionCache struct {
+	mu      sync.Mutex
+	entries map[guardDecisionKey]ToolCheckResult
+}
+
+func (c *guardDecisionCache) get(k guardDecisionKey) (ToolCheckResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.entries[k]
+	return r, ok
+}
+
+func (c *guardDecisionCache) put(k guardDecisionKey, r ToolCheckResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= guardDecisionCacheSize {
+		c.entries = make(map[guardDecisionKey]ToolCheckResult)
+	}
+	c.entries[k] = r
+}
+
+// checkArguments runs the permission and argument checks of a call, from the
+// decision cache when the same call was checked under this config version.
+func (g *ToolGuard) checkArguments(r *guardRules, toolName string, callerLevel AccessLevel, args map[string]any) ToolCheckResult {
+	input, cacheable := guardCacheInput(toolName, args)
+	if !cacheable {
+		return g.checkArgumentsUncached(toolName, callerLevel, args)
+	}
+	k := newGuardDecisionKey(toolName, callerLevel, input)
+	if result, ok := r.decisions.get(k); ok {
+		return result
+	}
+	result := g.checkArgumentsUncached(toolName, callerLevel, args)
+	r.decisions.put(k, result)
+	return result
+}
+
+// isASCII reports whether s contains only ASCII bytes.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
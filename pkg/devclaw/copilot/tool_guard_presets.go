@@ -0,0 +1,361 @@
+// Package copilot – tool_guard_presets.go implements named security presets
+// for the tool guard, built from layered rule packs.
+//
+// A rule pack is a partial ToolGuardConfig. Packs can extend other packs, and
+// a preset is simply the pack selected by `security.tool_guard.preset`. The
+// effective policy is computed by applying, in order:
+//
+//  1. The built-in defaults (DefaultToolGuardConfig).
+//  2. Every pack in the preset's `extends` chain, parents first.
+//  3. The preset pack itself.
+//  4. Any keys set explicitly under `security.tool_guard` in config.yaml.
+//
+// Merge rules: booleans and scalars set in a later layer override earlier ones,
+// maps are merged key by key (later wins), and lists are appended with
+// duplicates removed. Users extend presets by defining their own packs under
+// `security.tool_guard.rule_packs` and selecting them as the preset.
+package copilot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GuardRulePack is a named, partial tool guard policy that can be layered on
+// top of other packs. Nil pointers and empty collections mean "inherit".
+type GuardRulePack struct {
+	// Description is a short human-readable summary shown in the CLI.
+	Description string `yaml:"description,omitempty"`
+
+	// Extends lists packs applied before this one (parents first).
+	Extends []string `yaml:"extends,omitempty"`
+
+	ToolPermissions     map[string]string `yaml:"tool_permissions,omitempty"`
+	AllowDestructive    *bool             `yaml:"allow_destructive,omitempty"`
+	AllowSudo           *bool             `yaml:"allow_sudo,omitempty"`
+	AllowReboot         *bool             `yaml:"allow_reboot,omitempty"`
+	BlockSudo           *bool             `yaml:"block_sudo,omitempty"`
+	DangerousCommands   []string          `yaml:"dangerous_commands,omitempty"`
+	ProtectedPaths      []string          `yaml:"protected_paths,omitempty"`
+	SSHAllowedHosts     []string          `yaml:"ssh_allowed_hosts,omitempty"`
+	AutoApprove         []string          `yaml:"auto_approve,omitempty"`
+	RequireConfirmation []string          `yaml:"require_confirmation,omitempty"`
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// BuiltinGuardPacks are the rule packs shipped with DevClaw. The four
+// top-level presets are "personal", "family-shared", "business-strict" and
+// "developer-yolo"; the remaining packs are building blocks.
+var BuiltinGuardPacks = map[string]GuardRulePack{
+	"confirm-writes": {
+		Description:         "Ask before modifying files or running shell commands",
+		RequireConfirmation: []string{"bash", "exec", "write_file", "edit_file", "apply_changes"},
+	},
+	"confirm-remote": {
+		Description:         "Ask before connecting to remote machines",
+		RequireConfirmation: []string{"ssh", "scp"},
+	},
+	"personal": {
+		Description: "Single owner on their own machine: sensible defaults, confirm remote access",
+		Extends:     []string{"confirm-remote"},
+	},
+	"family-shared": {
+		Description: "Shared household assistant: machine access is owner-only, everything risky asks first",
+		Extends:     []string{"confirm-writes", "confirm-remote"},
+		ToolPermissions: map[string]string{
+			"exec":          "owner",
+			"watch_command": "owner",
+			"write_file":    "owner",
+			"edit_file":     "owner",
+			"apply_changes": "owner",
+			"cron_add":      "owner",
+			"cron_remove":   "owner",
+			"install_skill": "owner",
+			"remove_skill":  "owner",
+		},
+		AllowSudo:        boolPtr(false),
+		AllowDestructive: boolPtr(false),
+		AllowReboot:      boolPtr(false),
+	},
+	"business-strict": {
+		Description: "Team or company use: least privilege, extra command blocklist, confirmation on every write",
+		Extends:     []string{"family-shared"},
+		ToolPermissions: map[string]string{
+			"set_env":   "owner",
+			"web_fetch": "user",
+			"vault_get": "owner",
+		},
+		BlockSudo: boolPtr(true),
+		DangerousCommands: []string{
+			`git\s+push\s+.*(--force|-f)\b`,
+			`(?i)\bdrop\s+schema\b`,
+			`curl\s+.*\|\s*(ba|z)?sh`,
+			`wget\s+.*\|\s*(ba|z)?sh`,
+		},
+		ProtectedPaths: []string{"~/.kube", "~/.docker/config.json", "~/.npmrc", "~/.pypirc"},
+	},
+	"developer-yolo": {
+		Description: "Solo developer who wants zero friction: sudo allowed, no confirmations (destructive commands still blocked)",
+		AllowSudo:   boolPtr(true),
+		BlockSudo:   boolPtr(false),
+		AutoApprove: []string{"read_file", "list_files", "search_files", "glob_files", "web_search", "web_fetch"},
+	},
+}
+
+// GuardPresetNames returns the user-facing preset names in display order.
+func GuardPresetNames() []string {
+	return []string{"personal", "family-shared", "business-strict", "developer-yolo"}
+}
+
+// lookupGuardPack resolves a pack by name, preferring user-defined packs so
+// built-ins can be overridden.
+func lookupGuardPack(name string, userPacks map[string]GuardRulePack) (GuardRulePack, bool) {
+	if p, ok := userPacks[name]; ok {
+		return p, true
+	}
+	p, ok := BuiltinGuardPacks[name]
+	return p, ok
+}
+
+// resolveGuardPackChain returns the packs to apply for name, parents first.
+// Each pack appears at most once; cycles are reported as errors.
+func resolveGuardPackChain(name string, userPacks map[string]GuardRulePack) ([]string, error) {
+	var order []string
+	seen := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(n string) error
+	visit = func(n string) error {
+		if seen[n] {
+			return nil
+		}
+		if visiting[n] {
+			return fmt.Errorf("rule pack cycle detected at %q", n)
+		}
+		pack, ok := lookupGuardPack(n, userPacks)
+		if !ok {
+			return fmt.Errorf("unknown rule pack %q", n)
+		}
+		visiting[n] = true
+		for _, parent := range pack.Extends {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		visiting[n] = false
+		seen[n] = true
+		order = append(order, n)
+		return nil
+	}
+
+	if err := visit(name); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// GuardPresetChain returns the rule packs applied for a preset, parents first.
+func GuardPresetChain(preset string, userPacks map[string]GuardRulePack) ([]string, error) {
+	return resolveGuardPackChain(preset, userPacks)
+}
+
+// EffectiveProtectedPaths returns the protected paths the guard enforces for
+// cfg, expanding an empty list to the built-in defaults.
+func EffectiveProtectedPaths(cfg ToolGuardConfig) []string {
+	if len(cfg.ProtectedPaths) == 0 {
+		return defaultProtectedPaths()
+	}
+	return cfg.ProtectedPaths
+}
+
+// applyGuardPack overlays pack onto cfg using the layering rules described in
+// the file header.
+func applyGuardPack(cfg *ToolGuardConfig, pack GuardRulePack) {
+	if len(pack.ToolPermissions) > 0 {
+		merged := make(map[string]string, len(cfg.ToolPermissions)+len(pack.ToolPermissions))
+		for k, v := range cfg.ToolPermissions {
+			merged[k] = v
+		}
+		for k, v := range pack.ToolPermissions {
+			merged[k] = v
+		}
+		cfg.ToolPermissions = merged
+	}
+	if pack.AllowDestructive != nil {
+		cfg.AllowDestructive = *pack.AllowDestructive
+	}
+	if pack.AllowSudo != nil {
+		cfg.AllowSudo = *pack.AllowSudo
+	}
+	if pack.AllowReboot != nil {
+		cfg.AllowReboot = *pack.AllowReboot
+	}
+	if pack.BlockSudo != nil {
+		cfg.BlockSudo = *pack.BlockSudo
+	}
+	cfg.DangerousCommands = appendUnique(cfg.DangerousCommands, pack.DangerousCommands...)
+	if len(cfg.ProtectedPaths) == 0 && len(pack.ProtectedPaths) > 0 {
+		// An empty list means "use the defaults"; keep them when a pack
+		// adds paths so layering never weakens protection.
+		cfg.ProtectedPaths = defaultProtectedPaths()
+	}
+	cfg.ProtectedPaths = appendUnique(cfg.ProtectedPaths, pack.ProtectedPaths...)
+	cfg.SSHAllowedHosts = appendUnique(cfg.SSHAllowedHosts, pack.SSHAllowedHosts...)
+	cfg.AutoApprove = appendUnique(cfg.AutoApprove, pack.AutoApprove...)
+	cfg.RequireConfirmation = appendUnique(cfg.RequireConfirmation, pack.RequireConfirmation...)
+}
+
+// appendUnique appends values to list, skipping ones already present.
+func appendUnique(list []string, values ...string) []string {
+	if len(values) == 0 {
+		return list
+	}
+	seen := make(map[string]bool, len(list))
+	for _, v := range list {
+		seen[v] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			list = append(list, v)
+			seen[v] = true
+		}
+	}
+	return list
+}
+
+// ResolveGuardPreset computes the effective tool guard policy for a preset.
+// overrides holds the keys set explicitly in config.yaml (as a pack) and is
+// applied last. Returns the merged config and the names of the layers applied.
+func ResolveGuardPreset(base ToolGuardConfig, preset string, userPacks map[string]GuardRulePack, overrides *GuardRulePack) (ToolGuardConfig, []string, error) {
+	layers := []string{"defaults"}
+	cfg := base
+
+	if preset != "" {
+		chain, err := resolveGuardPackChain(preset, userPacks)
+		if err != nil {
+			return base, nil, fmt.Errorf("resolving guard preset %q: %w", preset, err)
+		}
+		for _, name := range chain {
+			pack, _ := lookupGuardPack(name, userPacks)
+			applyGuardPack(&cfg, pack)
+			layers = append(layers, name)
+		}
+	}
+
+	if overrides != nil {
+		applyGuardPack(&cfg, *overrides)
+		layers = append(layers, "config.yaml")
+	}
+
+	cfg.Preset = preset
+	cfg.RulePacks = userPacks
+	return cfg, layers, nil
+}
+
+// applyGuardPresetFromYAML re-resolves security.tool_guard when a preset is
+// selected. rawGuard is the decoded tool_guard YAML section; only the keys it
+// contains are treated as overrides on top of the preset.
+func applyGuardPresetFromYAML(cfg *Config, rawGuard map[string]any) error {
+	preset := cfg.Security.ToolGuard.Preset
+	if preset == "" {
+		return nil
+	}
+
+	overrides, err := guardOverridesFromRaw(rawGuard)
+	if err != nil {
+		return err
+	}
+
+	// Presets only layer rules; every other field keeps its configured value.
+	parsed := cfg.Security.ToolGuard
+	base := DefaultToolGuardConfig()
+	base.Enabled = parsed.Enabled
+	base.AuditLogPath = parsed.AuditLogPath
+	base.Forensics = parsed.Forensics
+	base.AuditSinks = parsed.AuditSinks
+	base.DailyLimits = parsed.DailyLimits
+	base.MaxImageSize = parsed.MaxImageSize
+	base.ReadOnlyTools = parsed.ReadOnlyTools
+
+	resolved, _, err := ResolveGuardPreset(base, preset, cfg.Security.ToolGuard.RulePacks, overrides)
+	if err != nil {
+		return err
+	}
+	cfg.Security.ToolGuard = resolved
+	return nil
+}
+
+// guardOverridesFromRaw converts the explicitly-set keys of a tool_guard YAML
+// section into a rule pack, so unset booleans do not clobber the preset.
+func guardOverridesFromRaw(rawGuard map[string]any) (*GuardRulePack, error) {
+	filtered := make(map[string]any, len(rawGuard))
+	for k, v := range rawGuard {
+		switch k {
+		case "preset", "rule_packs", "enabled", "audit_log", "audit_sinks", "forensics", "description", "extends",
+			"daily_limits", "max_image_size", "read_only_tools":
+			continue
+		}
+		filtered[k] = v
+	}
+	if len(filtered) == 0 {
+		return nil, nil
+	}
+
+	data, err := yaml.Marshal(filtered)
+	if err != nil {
+		return nil, fmt.Errorf("encoding tool_guard overrides: %w", err)
+	}
+	var pack GuardRulePack
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("parsing tool_guard overrides: %w", err)
+	}
+	return &pack, nil
+}
+
+// FormatGuardPresets returns a human-readable list of available presets and
+// rule packs (built-in and user-defined).
+func FormatGuardPresets(userPacks map[string]GuardRulePack) string {
+	var b strings.Builder
+	b.WriteString("Presets:\n")
+	for _, name := range GuardPresetNames() {
+		fmt.Fprintf(&b, "  %-16s %s\n", name, BuiltinGuardPacks[name].Description)
+	}
+
+	presets := make(map[string]bool)
+	for _, name := range GuardPresetNames() {
+		presets[name] = true
+	}
+	var building []string
+	for name := range BuiltinGuardPacks {
+		if !presets[name] {
+			building = append(building, name)
+		}
+	}
+	sort.Strings(building)
+	b.WriteString("\nBuilt-in rule packs:\n")
+	for _, name := range building {
+		fmt.Fprintf(&b, "  %-16s %s\n", name, BuiltinGuardPacks[name].Description)
+	}
+
+	if len(userPacks) > 0 {
+		names := make([]string, 0, len(userPacks))
+		for name := range userPacks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("\nCustom rule packs:\n")
+		for _, name := range names {
+			p := userPacks[name]
+			desc := p.Description
+			if len(p.Extends) > 0 {
+				desc = strings.TrimSpace(desc + " (extends " + strings.Join(p.Extends, ", ") + ")")
+			}
+			fmt.Fprintf(&b, "  %-16s %s\n", name, desc)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,474 @@
+// Package copilot – tool_guard_shell.go parses bash/exec commands into a
+// shell AST (mvdan.cc/sh) for the tool guard. Every simple command found in
+// lists, pipelines, subshells, $(...), <(...), functions and nested
+// sh -c / eval strings is evaluated against the sudo, reboot and destructive
+// pattern policies on its own, after quotes and escapes are resolved, so
+// `echo ok; r"m" -rf /` is caught. Pipelines that decode data and feed it to
+// an interpreter (`base64 -d | sh`) are blocked outright.
+package copilot
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxShellDepth bounds recursion into sh -c / eval strings.
+const maxShellDepth = 4
+
+// shellInterpreters run code read from stdin or a -c argument.
+var shellInterpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true, "busybox": true,
+	"python": true, "python3": true, "perl": true, "ruby": true, "node": true, "php": true,
+}
+
+// shellWrappers run their arguments as a command (`sudo rm ...`).
+var shellWrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "time": true, "nice": true,
+	"ionice": true, "timeout": true, "exec": true, "command": true, "builtin": true,
+	"xargs": true, "stdbuf": true, "setsid": true, "chroot": true, "watch": true,
+}
+
+// rebootCommands are governed by allow_reboot.
+var rebootCommands = map[string]bool{
+	"shutdown": true, "reboot": true, "poweroff": true, "halt": true,
+}
+
+// inspectShell parses command and checks each simple command in it.
+func (g *ToolGuard) inspectShell(command string, callerLevel AccessLevel, depth int) ToolCheckResult {
+	if depth > maxShellDepth {
+		return ToolCheckResult{Allowed: false, Reason: "command blocked: shell nesting too deep"}
+	}
+
+	file, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(command), "")
+	if err != nil {
+		return g.checkCommandText(command, callerLevel)
+	}
+
+	result := ToolCheckResult{Allowed: true}
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if !result.Allowed {
+			return false
+		}
+		switch n := node.(type) {
+		case *syntax.Stmt:
+			result = g.checkShellStmt(n, callerLevel, depth)
+		case *syntax.BinaryCmd:
+			if (n.Op == syntax.Pipe || n.Op == syntax.PipeAll) && decodesIntoInterpreter(n) {
+				result = ToolCheckResult{
+					Allowed: false,
+					Reason:  "command blocked: decoded data piped into an interpreter",
+				}
+			}
+		case *syntax.FuncDecl:
+			if isForkBomb(n) {
+				result = ToolCheckResult{Allowed: false, Reason: "command blocked: fork bomb"}
+			}
+		}
+		return result.Allowed
+	})
+	return result
+}
+
+// checkShellStmt checks one statement: its simple command (if any) together
+// with its redirections, e.g. "dd if=x > /dev/sda".
+func (g *ToolGuard) checkShellStmt(stmt *syntax.Stmt, callerLevel AccessLevel, depth int) ToolCheckResult {
+	var redirs []string
+	for _, r := range stmt.Redirs {
+		if r.Word != nil {
+			redirs = append(redirs, r.Op.String(), wordText(r.Word))
+		}
+		if r.Hdoc != nil {
+			redirs = append(redirs, wordText(r.Hdoc))
+		}
+	}
+
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		if len(redirs) == 0 {
+			return ToolCheckResult{Allowed: true}
+		}
+		return g.checkDangerousPatterns(strings.Join(redirs, " "), callerLevel)
+	}
+
+	var args []string
+	for _, w := range call.Args {
+		// ${IFS} and friends split into several words at run time.
+		args = append(args, strings.Fields(wordText(w))...)
+	}
+	if len(args) == 0 {
+		return ToolCheckResult{Allowed: true}
+	}
+	if r := g.checkShellArgs(args, redirs, callerLevel, depth); !r.Allowed {
+		return r
+	}
+
+	// `bash -c "$(echo ... | base64 -d)"`, `eval "$(...)"`, `sh <(...)`.
+	name := path.Base(args[0])
+	if shellInterpreters[name] || name == "eval" || name == "source" || name == "." {
+		for _, w := range call.Args[1:] {
+			if wordRunsDecoder(w) {
+				return ToolCheckResult{
+					Allowed: false,
+					Reason:  "command blocked: decoded data executed by " + name,
+				}
+			}
+		}
+	}
+	return ToolCheckResult{Allowed: true}
+}
+
+// checkShellArgs evaluates a simple command given as unquoted words,
+// unwrapping sudo/env/... and recursing into sh -c and eval strings.
+func (g *ToolGuard) checkShellArgs(args, redirs []string, callerLevel AccessLevel, depth int) ToolCheckResult {
+	name := path.Base(args[0])
+
+	if name == "sudo" || name == "doas" {
+		if r := g.checkSudo(callerLevel); !r.Allowed {
+			return r
+		}
+	}
+	if kw := rebootKeyword(name, args[1:]); kw != "" {
+		if r := g.checkReboot(kw, callerLevel); !r.Allowed {
+			return r
+		}
+	}
+
+	text := strings.Join(append(append([]string{name}, args[1:]...), redirs...), " ")
+	if r := g.checkDangerousPatterns(text, callerLevel); !r.Allowed {
+		return r
+	}
+
+	if shellWrappers[name] {
+		if inner := unwrapCommand(args); len(inner) > 0 {
+			return g.checkShellArgs(inner, redirs, callerLevel, depth)
+		}
+	}
+
+	if script := inlineScript(name, args[1:]); script != "" {
+		return g.inspectShell(script, callerLevel, depth+1)
+	}
+	return ToolCheckResult{Allowed: true}
+}
+
+// rebootKeyword returns the reboot/shutdown keyword a command invokes, if any.
+func rebootKeyword(name string, args []string) string {
+	if rebootCommands[name] {
+		return name
+	}
+	switch name {
+	case "systemctl", "loginctl":
+		for _, a := range args {
+			if rebootCommands[a] || a == "kexec" {
+				return a
+			}
+		}
+	case "init", "telinit":
+		if len(args) > 0 && (args[0] == "0" || args[0] == "6") {
+			return "halt"
+		}
+	}
+	return ""
+}
+
+// unwrapCommand returns the command a wrapper runs: `sudo -u x rm -rf /`
+// → `rm -rf /`. Options and env assignments before the command are skipped.
+func unwrapCommand(args []string) []string {
+	name := path.Base(args[0])
+	rest := args[1:]
+	for len(rest) > 0 {
+		a := rest[0]
+		switch {
+		case a == "--":
+			return rest[1:]
+		case strings.HasPrefix(a, "-"):
+			rest = rest[1:]
+			// Options that take a separate value.
+			if len(rest) > 0 && wrapperOptionTakesValue(name, a) {
+				rest = rest[1:]
+			}
+		case name == "env" && strings.Contains(a, "="):
+			rest = rest[1:]
+		case name == "timeout" || name == "chroot":
+			// First operand is the duration / new root.
+			return rest[1:]
+		default:
+			return rest
+		}
+	}
+	return nil
+}
+
+// wrapperOptionTakesValue reports whether a wrapper option consumes the
+// following word.
+func wrapperOptionTakesValue(name, opt string) bool {
+	switch name {
+	case "sudo", "doas":
+		return opt == "-u" || opt == "-g" || opt == "-C" || opt == "-D" || opt == "-h" || opt == "-p"
+	case "nice", "ionice":
+		return opt == "-n" || opt == "-c"
+	case "timeout":
+		return opt == "-s" || opt == "-k"
+	case "xargs":
+		return opt == "-I" || opt == "-n" || opt == "-P" || opt == "-d" || opt == "-L" || opt == "-s" || opt == "-a"
+	case "env":
+		return opt == "-u" || opt == "-C" || opt == "-S"
+	case "watch":
+		return opt == "-n"
+	}
+	return false
+}
+
+// inlineScript returns the script string a command evaluates, for
+// `sh -c '...'`, `eval ...` and `su -c '...'`.
+func inlineScript(name string, args []string) string {
+	switch {
+	case name == "eval":
+		return strings.Join(args, " ")
+	case shellInterpreters[name] && !isScriptInterpreter(name), name == "su":
+		for i, a := range args {
+			if (a == "-c" || strings.HasPrefix(a, "-") && !strings.HasPrefix(a, "--") && strings.HasSuffix(a, "c")) && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// isScriptInterpreter reports non-shell interpreters (python, perl, ...),
+// whose -c/-e code is not shell syntax.
+func isScriptInterpreter(name string) bool {
+	switch name {
+	case "python", "python3", "perl", "ruby", "node", "php":
+		return true
+	}
+	return false
+}
+
+// decodesIntoInterpreter reports whether a pipeline feeds the output of a
+// decoder (base64 -d, xxd -r, ...) into an interpreter reading stdin.
+func decodesIntoInterpreter(pipe *syntax.BinaryCmd) bool {
+	stages := pipelineStages(pipe)
+	decoded := false
+	for _, st := range stages {
+		if decoded && readsStdinScript(st) {
+			return true
+		}
+		if nodeRunsDecoder(st) {
+			decoded = true
+		}
+	}
+	return false
+}
+
+// pipelineStages flattens a | b | c into its statements.
+func pipelineStages(pipe *syntax.BinaryCmd) []*syntax.Stmt {
+	var out []*syntax.Stmt
+	var walk func(s *syntax.Stmt)
+	walk = func(s *syntax.Stmt) {
+		if b, ok := s.Cmd.(*syntax.BinaryCmd); ok && (b.Op == syntax.Pipe || b.Op == syntax.PipeAll) {
+			walk(b.X)
+			walk(b.Y)
+			return
+		}
+		out = append(out, s)
+	}
+	walk(pipe.X)
+	walk(pipe.Y)
+	return out
+}
+
+// readsStdinScript reports whether a pipeline stage executes code from
+// stdin: `sh`, `bash -s`, `python3 -`, `source /dev/stdin`, `xargs sh -c`.
+func readsStdinScript(stmt *syntax.Stmt) bool {
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return false
+	}
+	args := make([]string, len(call.Args))
+	for i, w := range call.Args {
+		args[i] = wordText(w)
+	}
+	for shellWrappers[path.Base(args[0])] {
+		args = unwrapCommand(args)
+		if len(args) == 0 {
+			return false
+		}
+	}
+
+	name := path.Base(args[0])
+	switch name {
+	case "source", ".":
+		return len(args) > 1 && (args[1] == "/dev/stdin" || args[1] == "-")
+	case "eval":
+		return true
+	}
+	if !shellInterpreters[name] {
+		return false
+	}
+	for _, a := range args[1:] {
+		if a == "-" || a == "-s" || a == "/dev/stdin" {
+			return true
+		}
+		if !strings.HasPrefix(a, "-") {
+			return false // runs a script file
+		}
+	}
+	return true
+}
+
+// nodeRunsDecoder reports whether any command under node decodes data.
+func nodeRunsDecoder(node syntax.Node) bool {
+	found := false
+	syntax.Walk(node, func(n syntax.Node) bool {
+		if found {
+			return false
+		}
+		if call, ok := n.(*syntax.CallExpr); ok && len(call.Args) > 0 {
+			args := make([]string, len(call.Args))
+			for i, w := range call.Args {
+				args[i] = wordText(w)
+			}
+			found = isDecoder(args)
+		}
+		return !found
+	})
+	return found
+}
+
+// wordRunsDecoder reports whether a word contains a command or process
+// substitution that decodes data.
+func wordRunsDecoder(w *syntax.Word) bool {
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.CmdSubst, *syntax.ProcSubst:
+			if nodeRunsDecoder(p) {
+				return true
+			}
+		case *syntax.DblQuoted:
+			if nodeRunsDecoder(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDecoder reports commands that turn encoded text back into code.
+func isDecoder(args []string) bool {
+	name := path.Base(args[0])
+	has := func(flags ...string) bool {
+		for _, a := range args[1:] {
+			for _, f := range flags {
+				if a == f {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	switch name {
+	case "base64", "base32", "basenc":
+		return has("-d", "--decode", "-D")
+	case "xxd":
+		return has("-r", "-revert")
+	case "openssl":
+		return has("-d", "-base64", "-a")
+	case "uudecode", "rev", "zcat", "gunzip", "bunzip2", "xz", "unxz":
+		return name != "xz" || has("-d", "--decompress")
+	case "gzip":
+		return has("-d", "--decompress")
+	case "tr":
+		return true // rot13 and friends
+	}
+	return false
+}
+
+// isForkBomb reports a function that calls itself in a pipeline or in the
+// background, e.g. :(){ :|:& };:
+func isForkBomb(fn *syntax.FuncDecl) bool {
+	name := fn.Name.Value
+	bomb := false
+	syntax.Walk(fn.Body, func(n syntax.Node) bool {
+		if bomb {
+			return false
+		}
+		switch x := n.(type) {
+		case *syntax.BinaryCmd:
+			if x.Op == syntax.Pipe || x.Op == syntax.PipeAll {
+				for _, st := range pipelineStages(x) {
+					if callsName(st, name) {
+						bomb = true
+					}
+				}
+			}
+		case *syntax.Stmt:
+			if x.Background && callsName(x, name) {
+				bomb = true
+			}
+		}
+		return !bomb
+	})
+	return bomb
+}
+
+// callsName reports whether a statement is a call to name.
+func callsName(stmt *syntax.Stmt, name string) bool {
+	call, ok := stmt.Cmd.(*syntax.CallExpr)
+	return ok && len(call.Args) > 0 && wordText(call.Args[0]) == name
+}
+
+// wordText renders a word with quotes and escapes resolved. Expansions that
+// cannot be known statically are kept as written ("$HOME"), except $IFS,
+// which becomes a space so `rm${IFS}-rf${IFS}/` splits like it would at run
+// time.
+func wordText(w *syntax.Word) string {
+	var b strings.Builder
+	writeWordParts(&b, w.Parts)
+	return b.String()
+}
+
+func writeWordParts(b *strings.Builder, parts []syntax.WordPart) {
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescapeShell(p.Value))
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			writeWordParts(b, p.Parts)
+		case *syntax.ParamExp:
+			if p.Param != nil && p.Param.Value == "IFS" && p.Exp == nil && p.Repl == nil && p.Slice == nil {
+				b.WriteByte(' ')
+			} else if p.Param != nil {
+				fmt.Fprintf(b, "$%s", p.Param.Value)
+			}
+		case *syntax.CmdSubst:
+			b.WriteString("$(...)")
+		default:
+			var sb strings.Builder
+			_ = syntax.NewPrinter().Print(&sb, part)
+			b.WriteString(sb.String())
+		}
+	}
+}
+
+// unescapeShell removes backslash escapes from an unquoted literal.
+func unescapeShell(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == '\n' {
+				continue // line continuation
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
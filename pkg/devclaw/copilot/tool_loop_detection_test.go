@@ -1,8 +1,11 @@
 package copilot
 
 import (
+	"errors"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestDetector(cfg ToolLoopConfig) *ToolLoopDetector {
@@ -378,3 +381,46 @@ func TestExtractErrorMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestReflectionMonitor_NudgesOnlyOnStalls(t *testing.T) {
+	t.Parallel()
+	m := newReflectionMonitor()
+	call := func(id, path string) ToolCall {
+		return ToolCall{ID: id, Function: FunctionCall{Name: "read_file", Arguments: `{"path":"` + path + `"}`}}
+	}
+	result := func(id, content string) ToolResult {
+		return ToolResult{ToolCallID: id, Name: "read_file", Content: content}
+	}
+
+	// Steady progress: new files, new content, modest growth.
+	for i, path := range []string{"a.go", "b.go", "c.go"} {
+		m.recordUsage(1000 * (i + 1))
+		m.recordTurn([]ToolCall{call(path, path)}, []ToolResult{result(path, "content of "+path)})
+		if n := m.nudge(i+2, time.Minute, 20*time.Minute); n != "" {
+			t.Fatalf("unexpected nudge while progressing: %s", n)
+		}
+	}
+
+	// Re-reading a.go returns the same output: repeat nudge.
+	m.recordTurn([]ToolCall{call("x", "a.go")}, []ToolResult{result("x", "content of a.go")})
+	if n := m.nudge(5, time.Minute, 20*time.Minute); !strings.Contains(n, "same arguments") {
+		t.Errorf("expected repeat nudge, got %q", n)
+	}
+
+	// Two more turns with nothing new: stall nudge after the third.
+	m.recordTurn([]ToolCall{call("y", "b.go")}, []ToolResult{result("y", "content of b.go")})
+	m.recordTurn([]ToolCall{call("z", "d.go")}, []ToolResult{{ToolCallID: "z", Name: "read_file", Content: "not found", Error: errors.New("not found")}})
+	if n := m.nudge(7, time.Minute, 20*time.Minute); !strings.Contains(n, "no new information") {
+		t.Errorf("expected stall nudge, got %q", n)
+	}
+
+	// Context jump and most of the timeout used.
+	m.recordUsage(40000)
+	if n := m.nudge(8, 16*time.Minute, 20*time.Minute); !strings.Contains(n, "context grew") || !strings.Contains(n, "Prioritize") {
+		t.Errorf("expected burn and time nudges, got %q", n)
+	}
+	m.recordUsage(41000)
+	if n := m.nudge(12, 17*time.Minute, 20*time.Minute); strings.Contains(n, "Prioritize") {
+		t.Errorf("time nudge should fire once, got %q", n)
+	}
+}
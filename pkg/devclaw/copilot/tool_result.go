@@ -0,0 +1,627 @@
+// Package copilot – tool_result.go defines typed content blocks for tool
+// results. Handlers can return ToolBlocks instead of a flat string; plain
+// outputs are converted automatically (JSON-looking strings and structs
+// become json blocks, everything else text). Keeping the structure lets the
+// agent loop shrink a result without breaking it — long strings and arrays
+// inside JSON are cut while keys survive, blobs are replaced by a short
+// reference — and lets images reach vision models and MCP clients as images.
+//
+// Rich blocks (markdown, table, attachment, link) can also be marked for
+// delivery: besides being shown to the LLM, they are sent to the user after
+// the reply, rendered for the channel (a pipe table on the web UI, an
+// aligned code block on chat apps, attachments as media).
+package copilot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/mcp"
+)
+
+// ToolBlockType identifies the kind of content in a ToolBlock.
+type ToolBlockType string
+
+const (
+	ToolBlockText    ToolBlockType = "text"
+	ToolBlockJSON    ToolBlockType = "json"
+	ToolBlockFileRef ToolBlockType = "file_ref"
+	ToolBlockImage   ToolBlockType = "image"
+
+	ToolBlockMarkdown   ToolBlockType = "markdown"
+	ToolBlockTable      ToolBlockType = "table"
+	ToolBlockAttachment ToolBlockType = "attachment"
+	ToolBlockLink       ToolBlockType = "link"
+)
+
+// ToolBlock is one typed piece of a tool result.
+type ToolBlock struct {
+	Type ToolBlockType `json:"type"`
+
+	// Text is the content of a text or markdown block, the caption of an
+	// attachment or the description of a link.
+	Text string `json:"text,omitempty"`
+
+	// JSON is the content of a json block.
+	JSON json.RawMessage `json:"json,omitempty"`
+
+	// Path points to a file on disk (file_ref, or an image saved to disk).
+	Path string `json:"path,omitempty"`
+
+	// Data holds inline bytes (image). Never part of the rendered text;
+	// vision models get it as an image part (see llmContent).
+	Data []byte `json:"-"`
+
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+
+	// Columns and Rows hold the cells of a table block.
+	Columns []string   `json:"columns,omitempty"`
+	Rows    [][]string `json:"rows,omitempty"`
+
+	// URL and Title describe a link block.
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+
+	// Deliver sends the block to the user after the reply, in addition to
+	// showing it to the LLM.
+	Deliver bool `json:"deliver,omitempty"`
+}
+
+// ToolBlocks is a handler output made of typed blocks.
+type ToolBlocks []ToolBlock
+
+// TextBlock returns a text block.
+func TextBlock(text string) ToolBlock {
+	return ToolBlock{Type: ToolBlockText, Text: text}
+}
+
+// JSONBlock returns a json block for v (falls back to text if v can't be
+// encoded).
+func JSONBlock(v any) ToolBlock {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return TextBlock(fmt.Sprintf("%v", v))
+	}
+	return ToolBlock{Type: ToolBlockJSON, JSON: data}
+}
+
+// FileRefBlock returns a reference to a file on disk.
+func FileRefBlock(path, mimeType string, size int64) ToolBlock {
+	return ToolBlock{Type: ToolBlockFileRef, Path: path, MimeType: mimeType, Size: size}
+}
+
+// ImageBlock returns an image block with inline data and/or a path.
+func ImageBlock(data []byte, mimeType, path string) ToolBlock {
+	size := int64(len(data))
+	return ToolBlock{Type: ToolBlockImage, Data: data, Path: path, MimeType: mimeType, Size: size}
+}
+
+// MarkdownBlock returns a markdown block.
+func MarkdownBlock(text string) ToolBlock {
+	return ToolBlock{Type: ToolBlockMarkdown, Text: text}
+}
+
+// TableBlock returns a table block. Rows shorter than columns are padded
+// when rendered.
+func TableBlock(columns []string, rows [][]string) ToolBlock {
+	return ToolBlock{Type: ToolBlockTable, Columns: columns, Rows: rows}
+}
+
+// AttachmentBlock returns a file to send to the user, with an optional
+// caption. Attachments are delivered by default.
+func AttachmentBlock(path, mimeType, caption string) ToolBlock {
+	b := ToolBlock{Type: ToolBlockAttachment, Path: path, MimeType: mimeType, Text: caption, Deliver: true}
+	if st, err := os.Stat(path); err == nil {
+		b.Size = st.Size()
+	}
+	return b
+}
+
+// LinkBlock returns a link preview.
+func LinkBlock(url, title, description string) ToolBlock {
+	return ToolBlock{Type: ToolBlockLink, URL: url, Title: title, Text: description}
+}
+
+// ForUser marks the block for delivery to the user.
+func (b ToolBlock) ForUser() ToolBlock {
+	b.Deliver = true
+	return b
+}
+
+// blocksFromOutput converts a handler output to blocks.
+func blocksFromOutput(output any) []ToolBlock {
+	switch v := output.(type) {
+	case nil:
+		return []ToolBlock{TextBlock("OK")}
+	case ToolBlocks:
+		return v
+	case []ToolBlock:
+		return v
+	case ToolBlock:
+		return []ToolBlock{v}
+	case string:
+		return []ToolBlock{textOrJSONBlock(v)}
+	case []byte:
+		return []ToolBlock{textOrJSONBlock(string(v))}
+	case error:
+		return []ToolBlock{TextBlock(fmt.Sprintf("Error: %v", v))}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return []ToolBlock{TextBlock(fmt.Sprintf("%v", v))}
+		}
+		return []ToolBlock{{Type: ToolBlockJSON, JSON: data}}
+	}
+}
+
+// textOrJSONBlock returns a json block when s is a JSON object or array.
+func textOrJSONBlock(s string) ToolBlock {
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) > 1 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return ToolBlock{Type: ToolBlockJSON, JSON: json.RawMessage(s)}
+	}
+	return TextBlock(s)
+}
+
+// render returns the text the LLM sees for a block. Delivered blocks say
+// so, so the model doesn't repeat them in its reply.
+func (b ToolBlock) render() string {
+	var out string
+	switch b.Type {
+	case ToolBlockJSON:
+		return string(b.JSON)
+	case ToolBlockFileRef:
+		return "[file: " + b.describe() + "]"
+	case ToolBlockImage:
+		return "[image: " + b.describe() + "]"
+	case ToolBlockAttachment:
+		out = "[attachment: " + b.describe() + "]"
+	case ToolBlockMarkdown:
+		out = b.Text
+	case ToolBlockTable:
+		out = markdownTable(b.Columns, b.Rows)
+	case ToolBlockLink:
+		out = "[link: " + b.linkText() + "]"
+	default:
+		return b.Text
+	}
+	if b.Deliver {
+		out += "\n[shown to the user after your reply]"
+	}
+	return out
+}
+
+// linkText returns "Title — URL — description", skipping empty parts.
+func (b ToolBlock) linkText() string {
+	var parts []string
+	for _, p := range []string{b.Title, b.URL, b.Text} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " — ")
+}
+
+// describe summarizes a file or image block.
+func (b ToolBlock) describe() string {
+	var parts []string
+	if b.Path != "" {
+		parts = append(parts, b.Path)
+	}
+	if b.MimeType != "" {
+		parts = append(parts, b.MimeType)
+	}
+	if b.Size > 0 {
+		parts = append(parts, fmt.Sprintf("%d bytes", b.Size))
+	}
+	if len(parts) == 0 {
+		return "inline"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// flexible reports whether the block can be shortened.
+func (b ToolBlock) flexible() bool {
+	switch b.Type {
+	case ToolBlockText, ToolBlockJSON, ToolBlockMarkdown, ToolBlockTable:
+		return true
+	}
+	return false
+}
+
+// RenderToolBlocks renders blocks as the tool message content, fitting
+// them into maxChars (0 = no limit). File and image references are always
+// kept; text and JSON blocks share the remaining budget, JSON being shrunk
+// structurally so it stays valid.
+func RenderToolBlocks(blocks []ToolBlock, maxChars int) string {
+	rendered := make([]string, len(blocks))
+	total := 0
+	for i, b := range blocks {
+		rendered[i] = b.render()
+		total += len(rendered[i])
+	}
+	total += max(0, len(blocks)-1) // separators
+	if maxChars <= 0 || total <= maxChars {
+		return strings.Join(rendered, "\n")
+	}
+
+	// Budget left for flexible blocks after fixed ones and separators.
+	budget := maxChars - max(0, len(blocks)-1)
+	var flex []int
+	for i, b := range blocks {
+		if b.flexible() {
+			flex = append(flex, i)
+		} else {
+			budget -= len(rendered[i])
+		}
+	}
+	budget = max(budget, 0)
+
+	// Water-fill: blocks smaller than their fair share keep everything and
+	// hand the rest to the larger ones.
+	shares := make(map[int]int, len(flex))
+	remaining := flex
+	for len(remaining) > 0 {
+		fair := budget / len(remaining)
+		var next []int
+		for _, i := range remaining {
+			if len(rendered[i]) <= fair {
+				shares[i] = len(rendered[i])
+				budget -= len(rendered[i])
+			} else {
+				next = append(next, i)
+			}
+		}
+		if len(next) == len(remaining) {
+			for _, i := range next {
+				shares[i] = fair
+			}
+			break
+		}
+		remaining = next
+	}
+
+	for _, i := range flex {
+		if len(rendered[i]) <= shares[i] {
+			continue
+		}
+		if blocks[i].Type == ToolBlockJSON {
+			rendered[i] = shrinkJSON(blocks[i].JSON, shares[i])
+		} else {
+			rendered[i] = truncateToolText(rendered[i], shares[i])
+		}
+	}
+	return strings.Join(rendered, "\n")
+}
+
+// toolTruncSuffix marks a cut text block.
+const toolTruncSuffix = "... [truncated]"
+
+// truncateToolText cuts s to about n bytes, keeping the head.
+func truncateToolText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= len(toolTruncSuffix) {
+		return truncateUTF8(toolTruncSuffix, n)
+	}
+	return truncateUTF8(s, n-len(toolTruncSuffix)) + toolTruncSuffix
+}
+
+// jsonShrinkSteps are progressively tighter limits for long strings and
+// arrays inside a JSON block.
+var jsonShrinkSteps = []struct{ maxString, maxItems int }{
+	{1000, 50}, {300, 20}, {120, 10}, {60, 5}, {20, 3}, {0, 1},
+}
+
+// shrinkJSON shortens a JSON document to fit n bytes while keeping it
+// valid: long strings are cut and long arrays keep their first items plus
+// a count of what was dropped. Falls back to text truncation when even the
+// tightest step doesn't fit.
+func shrinkJSON(raw json.RawMessage, n int) string {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return truncateToolText(string(raw), n)
+	}
+	var out string
+	for _, step := range jsonShrinkSteps {
+		out = marshalCompactJSON(shrinkJSONValue(v, step.maxString, step.maxItems))
+		if len(out) <= n {
+			return out
+		}
+	}
+	return truncateToolText(out, n)
+}
+
+// marshalCompactJSON encodes v without HTML escaping, which would only
+// waste budget.
+func marshalCompactJSON(v any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func shrinkJSONValue(v any, maxString, maxItems int) any {
+	switch t := v.(type) {
+	case string:
+		if len(t) > maxString+len("…") {
+			return truncateUTF8(t, maxString) + "…"
+		}
+		return t
+	case []any:
+		keep := min(len(t), maxItems)
+		out := make([]any, 0, keep+1)
+		for _, item := range t[:keep] {
+			out = append(out, shrinkJSONValue(item, maxString, maxItems))
+		}
+		if dropped := len(t) - keep; dropped > 0 {
+			out = append(out, fmt.Sprintf("… %d more items", dropped))
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = shrinkJSONValue(item, maxString, maxItems)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// MCPContent converts the result to MCP content blocks: images with
+// inline data and image attachments are passed as image content, markdown
+// and tables as markdown text, links as a markdown link, everything else
+// as text (file references as their path).
+func (r ToolResult) MCPContent() []mcp.ContentBlock {
+	blocks := r.Blocks
+	if len(blocks) == 0 {
+		blocks = []ToolBlock{TextBlock(r.Content)}
+	}
+	out := make([]mcp.ContentBlock, 0, len(blocks))
+	for _, b := range blocks {
+		if data := b.imageData(); len(data) > 0 {
+			out = append(out, mcp.ContentBlock{
+				Type:     "image",
+				Data:     base64.StdEncoding.EncodeToString(data),
+				MimeType: b.MimeType,
+			})
+			continue
+		}
+		text := b.render()
+		switch b.Type {
+		case ToolBlockMarkdown, ToolBlockTable, ToolBlockLink:
+			text = b.markdownFor("")
+		}
+		out = append(out, mcp.ContentBlock{Type: "text", Text: text})
+	}
+	return out
+}
+
+// Limits for images passed to the model in tool results. Larger images
+// are left out (providers reject them); the text still describes them.
+const (
+	toolImageMaxBytes   = 5 << 20
+	toolImageMaxPerCall = 4
+)
+
+// llmContent returns the tool message content for the model: the rendered
+// text, followed by image parts for image blocks with inline data. The
+// request builder converts them for the provider, or replaces them with a
+// note for models without image input.
+func (r ToolResult) llmContent() any {
+	var images []contentPart
+	for _, b := range r.Blocks {
+		if b.Type != ToolBlockImage || len(b.Data) == 0 || len(b.Data) > toolImageMaxBytes {
+			continue
+		}
+		mime := b.MimeType
+		if mime == "" {
+			mime = http.DetectContentType(b.Data)
+		}
+		if !strings.HasPrefix(mime, "image/") {
+			continue
+		}
+		images = append(images, contentPart{
+			Type:     "image_url",
+			ImageURL: &imageURL{URL: "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(b.Data)},
+		})
+		if len(images) == toolImageMaxPerCall {
+			break
+		}
+	}
+	if len(images) == 0 {
+		return r.Content
+	}
+	return append([]contentPart{{Type: "text", Text: r.Content}}, images...)
+}
+
+// imageData returns the bytes of an image or image attachment, reading the
+// file when the block only has a path. Nil for anything else.
+func (b ToolBlock) imageData() []byte {
+	switch {
+	case b.Type == ToolBlockImage:
+		return b.Data
+	case b.Type == ToolBlockAttachment && strings.HasPrefix(b.MimeType, "image/"):
+		if len(b.Data) > 0 {
+			return b.Data
+		}
+		data, _ := os.ReadFile(b.Path)
+		return data
+	}
+	return nil
+}
+
+// markdownFor renders a markdown, table or link block as Markdown suited to
+// the channel, before FormatForChannel converts it to the channel's markup.
+// Chat apps don't render pipe tables, so tables become an aligned code
+// block there; the web UI (and MCP clients, channel "") get a pipe table.
+func (b ToolBlock) markdownFor(channel string) string {
+	switch b.Type {
+	case ToolBlockTable:
+		switch strings.ToLower(channel) {
+		case "", "webui":
+			return markdownTable(b.Columns, b.Rows)
+		case "plain", "sms":
+			return alignedTable(b.Columns, b.Rows)
+		default:
+			return "```\n" + alignedTable(b.Columns, b.Rows) + "\n```"
+		}
+	case ToolBlockLink:
+		title := b.Title
+		if title == "" {
+			title = b.URL
+		}
+		var s string
+		if strings.ToLower(channel) == "webui" || channel == "" {
+			s = "[" + title + "](" + b.URL + ")"
+		} else if title != b.URL {
+			// Chat apps build their own preview from a bare URL.
+			s = "**" + title + "**\n" + b.URL
+		} else {
+			s = b.URL
+		}
+		if b.Text != "" {
+			s += "\n" + b.Text
+		}
+		return s
+	default:
+		return b.Text
+	}
+}
+
+// markdownTable renders a GitHub-style pipe table.
+func markdownTable(columns []string, rows [][]string) string {
+	width := tableWidth(columns, rows)
+	if width == 0 {
+		return ""
+	}
+	cell := func(row []string, i int) string {
+		if i >= len(row) {
+			return ""
+		}
+		return strings.ReplaceAll(strings.ReplaceAll(row[i], "|", "\\|"), "\n", " ")
+	}
+	line := func(row []string) string {
+		cells := make([]string, width)
+		for i := range cells {
+			cells[i] = cell(row, i)
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+
+	var b strings.Builder
+	b.WriteString(line(columns))
+	b.WriteString("\n|" + strings.Repeat(" --- |", width))
+	for _, row := range rows {
+		b.WriteString("\n" + line(row))
+	}
+	return b.String()
+}
+
+// alignedTable renders a table as space-padded columns for monospace text.
+func alignedTable(columns []string, rows [][]string) string {
+	width := tableWidth(columns, rows)
+	if width == 0 {
+		return ""
+	}
+	all := append([][]string{columns}, rows...)
+	sizes := make([]int, width)
+	for _, row := range all {
+		for i, c := range row {
+			sizes[i] = max(sizes[i], utf8.RuneCountInString(strings.ReplaceAll(c, "\n", " ")))
+		}
+	}
+
+	var lines []string
+	for n, row := range all {
+		if n == 1 && len(columns) > 0 {
+			rule := make([]string, width)
+			for i, s := range sizes {
+				rule[i] = strings.Repeat("-", s)
+			}
+			lines = append(lines, strings.Join(rule, "  "))
+		}
+		cells := make([]string, width)
+		for i := range cells {
+			var c string
+			if i < len(row) {
+				c = strings.ReplaceAll(row[i], "\n", " ")
+			}
+			cells[i] = c + strings.Repeat(" ", sizes[i]-utf8.RuneCountInString(c))
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+	if len(columns) == 0 {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tableWidth returns the number of columns of the widest row.
+func tableWidth(columns []string, rows [][]string) int {
+	width := len(columns)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	return width
+}
+
+// ReplyBlocks collects the blocks tools mark for delivery during an agent
+// run, so they can be sent after the reply.
+type ReplyBlocks struct {
+	mu     sync.Mutex
+	blocks []ToolBlock
+}
+
+type ctxKeyReplyBlocks struct{}
+
+// ContextWithReplyBlocks returns a context whose agent run collects
+// delivered tool blocks into the returned ReplyBlocks instead of sending
+// them right away.
+func ContextWithReplyBlocks(ctx context.Context) (context.Context, *ReplyBlocks) {
+	rb := &ReplyBlocks{}
+	return context.WithValue(ctx, ctxKeyReplyBlocks{}, rb), rb
+}
+
+// ReplyBlocksFromContext returns the collector set by ContextWithReplyBlocks,
+// or nil.
+func ReplyBlocksFromContext(ctx context.Context) *ReplyBlocks {
+	rb, _ := ctx.Value(ctxKeyReplyBlocks{}).(*ReplyBlocks)
+	return rb
+}
+
+// Add keeps the blocks marked for delivery.
+func (rb *ReplyBlocks) Add(blocks []ToolBlock) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	for _, b := range blocks {
+		if b.Deliver {
+			rb.blocks = append(rb.blocks, b)
+		}
+	}
+}
+
+// Take returns the collected blocks and clears the collector.
+func (rb *ReplyBlocks) Take() []ToolBlock {
+	if rb == nil {
+		return nil
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	out := rb.blocks
+	rb.blocks = nil
+	return out
+}
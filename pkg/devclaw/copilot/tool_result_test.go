@@ -0,0 +1,175 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/skills"
+)
+
+func TestBlocksFromOutput(t *testing.T) {
+	if b := blocksFromOutput(`{"ok":true}`); len(b) != 1 || b[0].Type != ToolBlockJSON {
+		t.Errorf("JSON string should become a json block, got %+v", b)
+	}
+	if b := blocksFromOutput("{not json"); len(b) != 1 || b[0].Type != ToolBlockText {
+		t.Errorf("invalid JSON should stay text, got %+v", b)
+	}
+	if b := blocksFromOutput(map[string]int{"n": 1}); len(b) != 1 || string(b[0].JSON) != `{"n":1}` {
+		t.Errorf("structs should become json blocks, got %+v", b)
+	}
+	out := ToolBlocks{TextBlock("saved"), ImageBlock([]byte("png"), "image/png", "/tmp/a.png")}
+	if got := formatToolOutput(out); got != "saved\n[image: /tmp/a.png, image/png, 3 bytes]" {
+		t.Errorf("unexpected rendering %q", got)
+	}
+}
+
+func TestRenderToolBlocks_ShrinksJSONStructurally(t *testing.T) {
+	items := make([]map[string]any, 200)
+	for i := range items {
+		items[i] = map[string]any{"id": i, "body": strings.Repeat("x", 500)}
+	}
+	blocks := []ToolBlock{
+		TextBlock("Found 200 issues."),
+		JSONBlock(map[string]any{"total": 200, "items": items}),
+		FileRefBlock("/tmp/issues.csv", "text/csv", 90000),
+	}
+
+	out := RenderToolBlocks(blocks, 2000)
+	if len(out) > 2000 {
+		t.Fatalf("rendered %d chars, want <= 2000", len(out))
+	}
+	lines := strings.SplitN(out, "\n", 3)
+	if len(lines) != 3 || lines[0] != "Found 200 issues." || !strings.HasPrefix(lines[2], "[file: /tmp/issues.csv") {
+		t.Fatalf("text and file reference should be kept intact: %q", out)
+	}
+
+	var parsed struct {
+		Total int   `json:"total"`
+		Items []any `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &parsed); err != nil {
+		t.Fatalf("shrunk JSON is invalid: %v\n%s", err, lines[1])
+	}
+	if parsed.Total != 200 || len(parsed.Items) == 0 {
+		t.Errorf("expected keys to survive, got %+v", parsed)
+	}
+	if last, _ := parsed.Items[len(parsed.Items)-1].(string); !strings.Contains(last, "more items") {
+		t.Errorf("expected a dropped-items marker, got %v", parsed.Items[len(parsed.Items)-1])
+	}
+}
+
+func TestRenderToolBlocks_SmallBlocksKeepTheirShare(t *testing.T) {
+	blocks := []ToolBlock{TextBlock("short"), TextBlock(strings.Repeat("y", 5000))}
+	out := RenderToolBlocks(blocks, 300)
+	if !strings.HasPrefix(out, "short\n") || !strings.HasSuffix(out, toolTruncSuffix) || len(out) > 300 {
+		t.Errorf("unexpected output (%d chars): %q", len(out), out)
+	}
+}
+
+func TestRichBlocks_ChannelRendering(t *testing.T) {
+	table := TableBlock([]string{"Name", "Qty"}, [][]string{{"apple", "3"}, {"kiwi|gold"}}).ForUser()
+
+	if got := table.markdownFor("webui"); got != "| Name | Qty |\n| --- | --- |\n| apple | 3 |\n| kiwi\\|gold |  |" {
+		t.Errorf("unexpected web table:\n%s", got)
+	}
+	want := "```\nName       Qty\n---------  ---\napple      3\nkiwi|gold\n```"
+	if got := table.markdownFor("whatsapp"); got != want {
+		t.Errorf("unexpected chat table:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.HasSuffix(table.render(), "[shown to the user after your reply]") {
+		t.Errorf("delivered blocks should tell the LLM: %q", table.render())
+	}
+
+	link := LinkBlock("https://example.com", "Example", "A page")
+	if got := link.markdownFor("telegram"); got != "**Example**\nhttps://example.com\nA page" {
+		t.Errorf("unexpected link rendering %q", got)
+	}
+
+	rb := &ReplyBlocks{}
+	rb.Add([]ToolBlock{TextBlock("for the model"), table, link, AttachmentBlock("/tmp/r.pdf", "application/pdf", "")})
+	if got := rb.Take(); len(got) != 2 || got[0].Type != ToolBlockTable || got[1].Type != ToolBlockAttachment {
+		t.Errorf("only delivered blocks should be collected, got %+v", got)
+	}
+
+	content := ToolResult{Blocks: []ToolBlock{MarkdownBlock("**hi**"), link}}.MCPContent()
+	if len(content) != 2 || content[0].Text != "**hi**" || content[1].Text != "[Example](https://example.com)\nA page" {
+		t.Errorf("unexpected MCP content %+v", content)
+	}
+}
+
+func TestDetectFileMime(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "report.pdf")
+	noExt := filepath.Join(dir, "chart")
+	os.WriteFile(pdf, []byte("%PDF-1.4"), 0o644)
+	os.WriteFile(noExt, []byte("\x89PNG\r\n\x1a\n0000"), 0o644)
+
+	if got := detectFileMime(pdf); got != "application/pdf" {
+		t.Errorf("pdf: got %q", got)
+	}
+	if got := detectFileMime(noExt); got != "image/png" {
+		t.Errorf("sniffed: got %q", got)
+	}
+	if b := AttachmentBlock(pdf, "application/pdf", "Q3"); !b.Deliver || b.Size != 8 {
+		t.Errorf("attachment block %+v", b)
+	}
+}
+
+func TestToolResultImages_ReachVisionModels(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n fake")
+	r := ToolResult{Content: "[image: image/png]", Blocks: []ToolBlock{ImageBlock(png, "image/png", "")}}
+	parts, ok := r.llmContent().([]contentPart)
+	if !ok || len(parts) != 2 || parts[1].ImageURL == nil || !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Fatalf("image block should become an image part, got %#v", r.llmContent())
+	}
+	messages := []chatMessage{
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Function: FunctionCall{Name: "browser_screenshot"}}}},
+		{Role: "tool", ToolCallID: "c1", Content: parts},
+	}
+
+	// OpenAI-compatible: the tool message keeps the text, images follow in a user message.
+	hoisted := hoistToolImages(messages)
+	if len(hoisted) != 3 || hoisted[1].Content != "[image: image/png]" || hoisted[2].Role != "user" {
+		t.Fatalf("images should move to a user message, got %#v", hoisted)
+	}
+
+	// Anthropic: images stay inside the tool_result.
+	req := convertToAnthropicRequest("claude-sonnet-4.5", messages, nil, nil, nil)
+	blocks := req.Messages[1].Content.([]anthropicContent)
+	inner, _ := blocks[0].Content.([]anthropicContent)
+	if len(inner) != 2 || inner[1].Type != "image" || inner[1].Source.MediaType != "image/png" {
+		t.Fatalf("tool_result should carry an image block, got %#v", blocks[0].Content)
+	}
+
+	// Models without image input get a note instead.
+	if s, _ := stripImages(messages)[1].Content.(string); !strings.Contains(s, "image(s) omitted") {
+		t.Errorf("images should be stripped for text-only models, got %q", s)
+	}
+}
+
+func TestStructuredOutputHandler_KeepsMetaApart(t *testing.T) {
+	t.Parallel()
+	schema := map[string]any{"type": "object", "required": []string{"city"}}
+	handler := structuredOutputHandler(schema, func(context.Context, map[string]any) (any, error) {
+		return skills.StructuredResult{
+			Output: `{"city":"Lisbon"}`,
+			Meta:   map[string]any{"output_files": []string{"/tmp/report.txt"}},
+		}, nil
+	})
+
+	out, err := handler(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, ok := out.(ToolBlocks)
+	if !ok || len(blocks) != 2 {
+		t.Fatalf("output = %#v, want a value block and a meta block", out)
+	}
+	if string(blocks[0].JSON) != `{"city":"Lisbon"}` || !strings.Contains(string(blocks[1].JSON), "report.txt") {
+		t.Errorf("blocks = %s | %s", blocks[0].JSON, blocks[1].JSON)
+	}
+}
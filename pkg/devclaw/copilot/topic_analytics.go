@@ -0,0 +1,434 @@
+// Package copilot – topic_analytics.go implements a monthly analytics job that
+// clusters conversations by topic per workspace. Each session's activity in
+// the month (user messages plus compaction summaries) is treated as one
+// conversation summary and assigned to the topic whose keywords it matches
+// best. Reports are stored in devclaw.db so owners can see what the assistant
+// is actually used for and tune skills, budgets and prompts accordingly.
+package copilot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// topicOther is the bucket for conversations that match no topic.
+const topicOther = "other"
+
+// analyticsCheckInterval is how often the job checks whether last month's
+// report still needs to be generated.
+const analyticsCheckInterval = 6 * time.Hour
+
+// AnalyticsConfig configures conversation topic analytics.
+type AnalyticsConfig struct {
+	// Enabled turns on the monthly topic report job (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Topics adds keywords to built-in topics or defines new ones.
+	// key = topic name, value = lowercase keywords.
+	Topics map[string][]string `yaml:"topics"`
+}
+
+// DefaultAnalyticsConfig returns the default analytics configuration.
+func DefaultAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{Enabled: true}
+}
+
+// defaultTopicKeywords are the built-in topics. Keywords cover English and
+// Portuguese since both are common in DevClaw deployments.
+var defaultTopicKeywords = map[string][]string{
+	"coding": {
+		"code", "bug", "function", "refactor", "compile", "test", "commit", "branch",
+		"merge", "pull request", "golang", "python", "javascript", "typescript", "api",
+		"endpoint", "stack trace", "exception", "debug", "repo", "código", "função",
+		"erro", "teste",
+	},
+	"reminders": {
+		"remind", "reminder", "schedule", "tomorrow", "alarm", "cron", "every day",
+		"meeting", "calendar", "lembrete", "lembra", "lembrar", "amanhã", "agenda",
+		"reunião", "todo dia",
+	},
+	"operations": {
+		"deploy", "server", "docker", "kubernetes", "k8s", "nginx", "ssh", "logs",
+		"disk", "cpu", "memory usage", "restart", "systemctl", "backup", "database",
+		"monitor", "incident", "uptime", "servidor", "reiniciar",
+	},
+	"personal": {
+		"recipe", "travel", "birthday", "family", "movie", "music", "gift", "health",
+		"workout", "shopping", "weather", "receita", "viagem", "aniversário", "família",
+		"filme", "música", "presente", "saúde", "compras", "tempo",
+	},
+	"research": {
+		"search", "summarize", "summary", "explain", "compare", "article", "paper",
+		"news", "what is", "how does", "pesquisa", "resuma", "resumo", "explique",
+		"notícia", "o que é",
+	},
+}
+
+// TopicStat holds per-topic counts in a report.
+type TopicStat struct {
+	Topic         string   `json:"topic"`
+	Conversations int      `json:"conversations"`
+	Exchanges     int      `json:"exchanges"`
+	Share         float64  `json:"share"` // fraction of conversations (0-1)
+	TopKeywords   []string `json:"top_keywords,omitempty"`
+}
+
+// TopicReport is the monthly topic breakdown for one workspace.
+type TopicReport struct {
+	Month         string      `json:"month"` // YYYY-MM
+	WorkspaceID   string      `json:"workspace_id"`
+	Conversations int         `json:"conversations"`
+	Exchanges     int         `json:"exchanges"`
+	Topics        []TopicStat `json:"topics"`
+	GeneratedAt   time.Time   `json:"generated_at"`
+}
+
+// TopicAnalyzer builds and stores monthly topic reports.
+type TopicAnalyzer struct {
+	db          *sql.DB
+	workspaceOf func(sessionID string) string
+	topics      map[string][]string
+	logger      *slog.Logger
+}
+
+// NewTopicAnalyzer creates an analyzer backed by devclaw.db. workspaceOf maps
+// a session ID to its workspace (nil = everything in "default").
+func NewTopicAnalyzer(db *sql.DB, cfg AnalyticsConfig, workspaceOf func(sessionID string) string, logger *slog.Logger) (*TopicAnalyzer, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if workspaceOf == nil {
+		workspaceOf = func(string) string { return "default" }
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS topic_reports (
+			month        TEXT NOT NULL,
+			workspace_id TEXT NOT NULL,
+			report       TEXT NOT NULL,
+			created_at   TEXT NOT NULL,
+			PRIMARY KEY (month, workspace_id)
+		)`); err != nil {
+		return nil, fmt.Errorf("create topic_reports table: %w", err)
+	}
+
+	topics := make(map[string][]string, len(defaultTopicKeywords)+len(cfg.Topics))
+	for name, kws := range defaultTopicKeywords {
+		topics[name] = append([]string(nil), kws...)
+	}
+	for name, kws := range cfg.Topics {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, kw := range kws {
+			topics[name] = appendUnique(topics[name], strings.ToLower(kw))
+		}
+	}
+
+	return &TopicAnalyzer{
+		db:          db,
+		workspaceOf: workspaceOf,
+		topics:      topics,
+		logger:      logger.With("component", "topic_analytics"),
+	}, nil
+}
+
+// classifyTopic returns the best-matching topic for text and the keywords
+// that matched. Ties are broken alphabetically for stable output.
+func (t *TopicAnalyzer) classifyTopic(text string) (string, []string) {
+	lower := " " + normalizeForTopics(text) + " "
+	best, bestScore := topicOther, 0
+	var bestHits []string
+
+	names := make([]string, 0, len(t.topics))
+	for name := range t.topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		score := 0
+		var hits []string
+		for _, kw := range t.topics[name] {
+			if n := strings.Count(lower, " "+kw+" "); n > 0 {
+				score += n
+				hits = append(hits, kw)
+			}
+		}
+		if score > bestScore {
+			best, bestScore, bestHits = name, score, hits
+		}
+	}
+	return best, bestHits
+}
+
+// normalizeForTopics lowercases text and replaces punctuation with spaces so
+// keywords match on word boundaries.
+func normalizeForTopics(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// conversationSummary accumulates one session's activity in a month.
+type conversationSummary struct {
+	text      strings.Builder
+	exchanges int
+}
+
+// BuildReports computes topic reports for the month containing `month`,
+// one per workspace with activity.
+func (t *TopicAnalyzer) BuildReports(ctx context.Context, month time.Time) ([]*TopicReport, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT session_id, user_message, assistant_response
+		FROM session_entries
+		WHERE created_at >= ? AND created_at < ?
+		ORDER BY id`,
+		start.Format(time.RFC3339), end.Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query session entries: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[string]*conversationSummary)
+	for rows.Next() {
+		var sid, userMsg, resp string
+		if err := rows.Scan(&sid, &userMsg, &resp); err != nil {
+			return nil, fmt.Errorf("scan session entry: %w", err)
+		}
+		cs := sessions[sid]
+		if cs == nil {
+			cs = &conversationSummary{}
+			sessions[sid] = cs
+		}
+		// Compaction entries carry the summary in the response field.
+		if userMsg == "[session compacted]" {
+			cs.text.WriteString(resp)
+		} else {
+			cs.text.WriteString(userMsg)
+			cs.exchanges++
+		}
+		cs.text.WriteString("\n")
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate session entries: %w", err)
+	}
+
+	type topicAgg struct {
+		conversations int
+		exchanges     int
+		keywords      map[string]int
+	}
+	byWorkspace := make(map[string]map[string]*topicAgg)
+
+	for sid, cs := range sessions {
+		wsID := t.workspaceOf(sid)
+		topic, hits := t.classifyTopic(cs.text.String())
+		if byWorkspace[wsID] == nil {
+			byWorkspace[wsID] = make(map[string]*topicAgg)
+		}
+		agg := byWorkspace[wsID][topic]
+		if agg == nil {
+			agg = &topicAgg{keywords: make(map[string]int)}
+			byWorkspace[wsID][topic] = agg
+		}
+		agg.conversations++
+		agg.exchanges += cs.exchanges
+		for _, kw := range hits {
+			agg.keywords[kw]++
+		}
+	}
+
+	monthKey := start.Format("2006-01")
+	now := time.Now()
+	reports := make([]*TopicReport, 0, len(byWorkspace))
+	for wsID, topics := range byWorkspace {
+		r := &TopicReport{Month: monthKey, WorkspaceID: wsID, GeneratedAt: now}
+		for _, agg := range topics {
+			r.Conversations += agg.conversations
+			r.Exchanges += agg.exchanges
+		}
+		for name, agg := range topics {
+			r.Topics = append(r.Topics, TopicStat{
+				Topic:         name,
+				Conversations: agg.conversations,
+				Exchanges:     agg.exchanges,
+				Share:         float64(agg.conversations) / float64(r.Conversations),
+				TopKeywords:   topKeywords(agg.keywords, 5),
+			})
+		}
+		sort.Slice(r.Topics, func(i, j int) bool {
+			if r.Topics[i].Conversations != r.Topics[j].Conversations {
+				return r.Topics[i].Conversations > r.Topics[j].Conversations
+			}
+			return r.Topics[i].Topic < r.Topics[j].Topic
+		})
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].WorkspaceID < reports[j].WorkspaceID })
+	return reports, nil
+}
+
+// topKeywords returns the n most frequent keywords.
+func topKeywords(counts map[string]int, n int) []string {
+	kws := make([]string, 0, len(counts))
+	for kw := range counts {
+		kws = append(kws, kw)
+	}
+	sort.Slice(kws, func(i, j int) bool {
+		if counts[kws[i]] != counts[kws[j]] {
+			return counts[kws[i]] > counts[kws[j]]
+		}
+		return kws[i] < kws[j]
+	})
+	if len(kws) > n {
+		kws = kws[:n]
+	}
+	return kws
+}
+
+// SaveReports stores reports, replacing any existing report for the same
+// month and workspace.
+func (t *TopicAnalyzer) SaveReports(reports []*TopicReport) error {
+	for _, r := range reports {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("encode topic report: %w", err)
+		}
+		if _, err := t.db.Exec(`
+			INSERT OR REPLACE INTO topic_reports (month, workspace_id, report, created_at)
+			VALUES (?, ?, ?, ?)`,
+			r.Month, r.WorkspaceID, string(data), r.GeneratedAt.UTC().Format(time.RFC3339),
+		); err != nil {
+			return fmt.Errorf("save topic report: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadReports returns stored reports for a month (YYYY-MM). An empty
+// workspaceID returns every workspace.
+func (t *TopicAnalyzer) LoadReports(month, workspaceID string) ([]*TopicReport, error) {
+	query := `SELECT report FROM topic_reports WHERE month = ?`
+	args := []any{month}
+	if workspaceID != "" {
+		query += ` AND workspace_id = ?`
+		args = append(args, workspaceID)
+	}
+	query += ` ORDER BY workspace_id`
+
+	rows, err := t.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query topic reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []*TopicReport
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("scan topic report: %w", err)
+		}
+		var r TopicReport
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, fmt.Errorf("decode topic report: %w", err)
+		}
+		reports = append(reports, &r)
+	}
+	return reports, rows.Err()
+}
+
+// hasReport reports whether any report exists for the month.
+func (t *TopicAnalyzer) hasReport(month string) bool {
+	var n int
+	_ = t.db.QueryRow(`SELECT COUNT(*) FROM topic_reports WHERE month = ?`, month).Scan(&n)
+	return n > 0
+}
+
+// Run generates last month's reports once they are due, checking
+// periodically until ctx is cancelled.
+func (t *TopicAnalyzer) Run(ctx context.Context) {
+	check := func() {
+		prev := time.Now().UTC().AddDate(0, -1, 0)
+		month := prev.Format("2006-01")
+		if t.hasReport(month) {
+			return
+		}
+		reports, err := t.BuildReports(ctx, prev)
+		if err != nil {
+			t.logger.Warn("topic report failed", "month", month, "error", err)
+			return
+		}
+		if len(reports) == 0 {
+			// Store an empty marker so we don't rescan a quiet month.
+			reports = []*TopicReport{{Month: month, WorkspaceID: "", GeneratedAt: time.Now()}}
+		}
+		if err := t.SaveReports(reports); err != nil {
+			t.logger.Warn("saving topic report failed", "month", month, "error", err)
+			return
+		}
+		t.logger.Info("topic report generated", "month", month, "workspaces", len(reports))
+	}
+
+	check()
+	ticker := time.NewTicker(analyticsCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// filterTopicReports keeps only reports for workspaceID (empty = keep all).
+func filterTopicReports(reports []*TopicReport, workspaceID string) []*TopicReport {
+	if workspaceID == "" {
+		return reports
+	}
+	var out []*TopicReport
+	for _, r := range reports {
+		if r.WorkspaceID == workspaceID {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// FormatTopicReports renders reports for chat output.
+func FormatTopicReports(month string, reports []*TopicReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Topic report (%s)*\n", month)
+
+	shown := 0
+	for _, r := range reports {
+		if r.Conversations == 0 {
+			continue
+		}
+		shown++
+		fmt.Fprintf(&b, "\n*Workspace %s* — %d conversations, %d exchanges\n",
+			r.WorkspaceID, r.Conversations, r.Exchanges)
+		for _, ts := range r.Topics {
+			fmt.Fprintf(&b, "• %s: %.0f%% (%d conv, %d msgs)",
+				ts.Topic, ts.Share*100, ts.Conversations, ts.Exchanges)
+			if len(ts.TopKeywords) > 0 {
+				fmt.Fprintf(&b, " — %s", strings.Join(ts.TopKeywords, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if shown == 0 {
+		b.WriteString("\nNo conversations recorded.")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
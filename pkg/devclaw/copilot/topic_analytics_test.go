@@ -0,0 +1,167 @@
+package copilot
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTopicAnalyzer(t *testing.T, cfg AnalyticsConfig, workspaces map[string]string) (*TopicAnalyzer, *sql.DB) {
+	t.Helper()
+	db, err := OpenDatabase(filepath.Join(t.TempDir(), "devclaw.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	var workspaceOf func(string) string
+	if workspaces != nil {
+		workspaceOf = func(sid string) string { return workspaces[sid] }
+	}
+	ta, err := NewTopicAnalyzer(db, cfg, workspaceOf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ta, db
+}
+
+func addSessionEntry(t *testing.T, db *sql.DB, sessionID, userMsg, resp string, at time.Time) {
+	t.Helper()
+	if _, err := db.Exec(`INSERT INTO session_entries (session_id, user_message, assistant_response, created_at) VALUES (?, ?, ?, ?)`,
+		sessionID, userMsg, resp, at.UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClassifyTopic(t *testing.T) {
+	t.Parallel()
+	ta, _ := newTestTopicAnalyzer(t, AnalyticsConfig{
+		Topics: map[string][]string{"Finance": {"Invoice", "tax"}},
+	}, nil)
+
+	cases := []struct {
+		text  string
+		topic string
+	}{
+		{"There's a bug in this function, can you debug it?", "coding"},
+		{"Remind me tomorrow about the meeting", "reminders"},
+		{"restart the nginx server, then check the logs!", "operations"},
+		{"Lembrete: reunião amanhã", "reminders"},
+		{"send the invoice and the tax report", "finance"},
+		{"hello there", topicOther},
+		// Keywords match whole words only.
+		{"debugging codes", topicOther},
+		// One hit each: ties go to the alphabetically first topic.
+		{"deploy the code", "coding"},
+	}
+	for _, tc := range cases {
+		if got, _ := ta.classifyTopic(tc.text); got != tc.topic {
+			t.Errorf("classifyTopic(%q) = %q, want %q", tc.text, got, tc.topic)
+		}
+	}
+
+	_, hits := ta.classifyTopic("fix the bug, then commit and open a pull request")
+	if strings.Join(hits, ",") != "bug,commit,pull request" {
+		t.Errorf("hits = %v", hits)
+	}
+}
+
+func TestTopicAnalyzer_Reports(t *testing.T) {
+	t.Parallel()
+	ta, db := newTestTopicAnalyzer(t, AnalyticsConfig{}, map[string]string{
+		"whatsapp:1": "eng", "whatsapp:2": "eng", "whatsapp:3": "eng", "telegram:9": "home",
+	})
+
+	march := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	addSessionEntry(t, db, "whatsapp:1", "the build fails, there is a bug in the api", "...", march)
+	addSessionEntry(t, db, "whatsapp:1", "now the test passes, commit it", "...", march.Add(time.Hour))
+	addSessionEntry(t, db, "whatsapp:2", "fix the failing test", "...", march)
+	addSessionEntry(t, db, "whatsapp:3", "restart the docker server", "...", march)
+	// Compaction summaries count toward the topic but not as exchanges.
+	addSessionEntry(t, db, "telegram:9", "[session compacted]", "Planned a birthday gift and a recipe.", march)
+	// Outside the month.
+	addSessionEntry(t, db, "telegram:9", "restart the server", "...", march.AddDate(0, 1, 0))
+
+	reports, err := ta.BuildReports(context.Background(), march)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].WorkspaceID != "eng" || reports[1].WorkspaceID != "home" {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	eng := reports[0]
+	if eng.Month != "2026-03" || eng.Conversations != 3 || eng.Exchanges != 4 {
+		t.Errorf("eng report = %+v", eng)
+	}
+	if len(eng.Topics) != 2 || eng.Topics[0].Topic != "coding" || eng.Topics[0].Conversations != 2 || eng.Topics[1].Topic != "operations" {
+		t.Fatalf("eng topics = %+v", eng.Topics)
+	}
+	if eng.Topics[0].TopKeywords[0] != "test" || eng.Topics[0].Share < 0.66 || eng.Topics[0].Share > 0.67 {
+		t.Errorf("coding stat = %+v", eng.Topics[0])
+	}
+	home := reports[1]
+	if home.Exchanges != 0 || home.Topics[0].Topic != "personal" {
+		t.Errorf("home report = %+v", home)
+	}
+
+	if err := ta.SaveReports(reports); err != nil {
+		t.Fatal(err)
+	}
+	// Saving again replaces rather than duplicates.
+	if err := ta.SaveReports(reports); err != nil {
+		t.Fatal(err)
+	}
+	all, err := ta.LoadReports("2026-03", "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("LoadReports = %d reports, %v", len(all), err)
+	}
+	only, _ := ta.LoadReports("2026-03", "home")
+	if len(only) != 1 || only[0].Topics[0].Topic != "personal" {
+		t.Errorf("LoadReports(home) = %+v", only)
+	}
+	if len(filterTopicReports(all, "eng")) != 1 || len(filterTopicReports(all, "")) != 2 {
+		t.Error("filterTopicReports did not filter by workspace")
+	}
+
+	out := FormatTopicReports("2026-03", all)
+	for _, want := range []string{"*Topic report (2026-03)*", "*Workspace eng* — 3 conversations, 4 exchanges", "• coding: 67% (2 conv, 3 msgs) — test"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("formatted report lacks %q:\n%s", want, out)
+		}
+	}
+	if got := FormatTopicReports("2026-02", nil); !strings.HasSuffix(got, "No conversations recorded.") {
+		t.Errorf("empty report = %q", got)
+	}
+}
+
+func TestTopicAnalyzer_RunStoresMarkerForQuietMonth(t *testing.T) {
+	t.Parallel()
+	ta, _ := newTestTopicAnalyzer(t, AnalyticsConfig{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		ta.Run(ctx)
+		close(done)
+	}()
+
+	month := time.Now().UTC().AddDate(0, -1, 0).Format("2006-01")
+	deadline := time.Now().Add(5 * time.Second)
+	for !ta.hasReport(month) {
+		if time.Now().After(deadline) {
+			t.Fatal("Run did not store a report for last month")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	reports, err := ta.LoadReports(month, "")
+	if err != nil || len(reports) != 1 || reports[0].Conversations != 0 {
+		t.Errorf("marker = %+v, %v", reports, err)
+	}
+}
@@ -0,0 +1,397 @@
+// Package copilot – tracing.go traces the message pipeline with
+// OpenTelemetry spans exported over OTLP/HTTP (JSON encoding) to
+// tracing.endpoint, e.g. an OpenTelemetry Collector, Jaeger or Tempo.
+//
+// A message produces one trace:
+//
+//	handleMessage
+//	└─ executeAgent
+//	   └─ agent.run
+//	      ├─ llm.call          (one per context-overflow attempt)
+//	      │  └─ llm.request    (one per retry and fallback model)
+//	      └─ tool <name>
+//	         └─ subagent       (spawned runs, with their own agent.run)
+//
+// Spans travel in the context.Context, so code only opens a span when its
+// caller is traced; with tracing disabled nothing is recorded.
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	// Enabled exports a trace per handled message (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// Endpoint is the OTLP/HTTP base URL; spans are posted to
+	// <endpoint>/v1/traces. Defaults to $OTEL_EXPORTER_OTLP_ENDPOINT, then
+	// http://localhost:4318.
+	Endpoint string `yaml:"endpoint"`
+
+	// ServiceName is the service.name resource attribute (default: devclaw).
+	ServiceName string `yaml:"service_name"`
+
+	// Headers are sent with every export (e.g. an API key of a hosted backend).
+	Headers map[string]string `yaml:"headers"`
+}
+
+const (
+	// traceExportInterval is how often finished spans are exported.
+	traceExportInterval = 5 * time.Second
+
+	// traceMaxPending caps the spans buffered between exports; spans
+	// beyond it are dropped.
+	traceMaxPending = 4096
+)
+
+// Tracer records spans and exports them in batches.
+type Tracer struct {
+	cfg      TracingConfig
+	endpoint string
+	client   *http.Client
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+}
+
+// NewTracer creates a tracer for cfg.
+func NewTracer(cfg TracingConfig, logger *slog.Logger) *Tracer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = "http://localhost:4318"
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "devclaw"
+	}
+	return &Tracer{
+		cfg:      cfg,
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger.With("component", "tracing"),
+	}
+}
+
+// Start opens a span. It continues the trace of the span in ctx, if any,
+// and starts a new trace otherwise. A nil tracer returns ctx and a nil span.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now()}
+	if parent := spanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	span.SetAttrs(attrs...)
+	return context.WithValue(ctx, spanCtxKey{}, span), span
+}
+
+// startSpan opens a child of the span in ctx. Without one (the caller is
+// not traced) it returns ctx and a nil span.
+func startSpan(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, attrs...)
+}
+
+// spanCtxKey carries the current span in a context.
+type spanCtxKey struct{}
+
+// spanFromContext returns the current span of ctx (nil if none).
+func spanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanCtxKey{}).(*Span)
+	return span
+}
+
+// contextWithSpanOf carries the span of src into ctx, so work detached
+// from src (e.g. a subagent outliving its tool call) joins the same trace.
+func contextWithSpanOf(ctx, src context.Context) context.Context {
+	if span := spanFromContext(src); span != nil {
+		return context.WithValue(ctx, spanCtxKey{}, span)
+	}
+	return ctx
+}
+
+// Span is one timed operation of a trace. All methods are no-ops on a nil
+// span.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+
+	mu     sync.Mutex
+	attrs  []spanAttr
+	errMsg string
+	end    time.Time
+}
+
+// spanAttr is a span attribute.
+type spanAttr struct {
+	key   string
+	value any
+}
+
+// SetAttrs adds attributes given as key, value pairs (like slog).
+func (s *Span) SetAttrs(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		s.attrs = append(s.attrs, spanAttr{key: key, value: kv[i+1]})
+	}
+}
+
+// SetError marks the span as failed (nil err is ignored).
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+// endLLMSpan records the outcome of an LLM request and ends its span.
+func endLLMSpan(span *Span, resp *LLMResponse, err error) {
+	if span == nil {
+		return
+	}
+	if resp != nil {
+		span.SetAttrs(
+			"llm.prompt_tokens", resp.Usage.PromptTokens,
+			"llm.completion_tokens", resp.Usage.CompletionTokens,
+			"llm.tool_calls", len(resp.ToolCalls),
+		)
+	}
+	span.SetError(err)
+	span.End()
+}
+
+// TraceID returns the hex trace ID ("" for a nil span).
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// enqueue buffers a finished span.
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.pending) >= traceMaxPending {
+		t.dropped++
+		return
+	}
+	t.pending = append(t.pending, s)
+}
+
+// Run exports finished spans every few seconds until ctx is done.
+func (t *Tracer) Run(ctx context.Context) {
+	ticker := time.NewTicker(traceExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Warn("trace export failed", "error", err)
+			}
+		}
+	}
+}
+
+// Close exports the spans still buffered. Safe to call on a nil tracer.
+func (t *Tracer) Close() error {
+	if t == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return t.Flush(ctx)
+}
+
+// Flush exports the finished spans. Spans of a failed export are dropped.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans, dropped := t.pending, t.dropped
+	t.pending, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.logger.Warn("trace buffer full, spans dropped", "count", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP export of %d spans: HTTP %d", len(spans), resp.StatusCode)
+	}
+	return nil
+}
+
+// ---------- OTLP/JSON encoding ----------
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 0 unset, 1 ok, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// otlpRequest encodes spans as an OTLP export request.
+func (t *Tracer) otlpRequest(spans []*Span) otlpExportRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              1, // SPAN_KIND_INTERNAL
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			span.Attributes = append(span.Attributes, otlpKeyValue{Key: a.key, Value: otlpValue(a.value)})
+		}
+		if s.errMsg != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.errMsg}
+		}
+		s.mu.Unlock()
+		out = append(out, span)
+	}
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue(t.cfg.ServiceName)},
+		}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "devclaw"}, Spans: out}},
+	}}}
+}
+
+// otlpValue encodes an attribute value as an OTLP AnyValue.
+func otlpValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
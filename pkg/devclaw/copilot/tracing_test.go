@@ -0,0 +1,73 @@
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestE2E_TracingSpans(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		spans []otlpSpan
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("export path = %s", r.URL.Path)
+		}
+		var req otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	h := newE2EHarness(t, func(cfg *Config) {
+		cfg.Tracing = TracingConfig{Enabled: true, Endpoint: collector.URL}
+	})
+	h.RegisterTool("trace_probe", "probed")
+	h.LLM.CallTool("trace_probe", map[string]any{})
+	h.LLM.Reply("Done.")
+	h.Send("probe it")
+
+	if err := h.A.tracer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	byName := make(map[string]otlpSpan)
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	for _, name := range []string{"handleMessage", "executeAgent", "agent.run", "llm.call", "llm.request", "tool trace_probe"} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("missing span %q (got %d spans)", name, len(spans))
+		}
+	}
+	root := byName["handleMessage"]
+	if root.ParentSpanID != "" {
+		t.Error("handleMessage should be the root span")
+	}
+	for _, s := range spans {
+		if s.TraceID != root.TraceID {
+			t.Errorf("span %q is in another trace", s.Name)
+		}
+	}
+	if byName["tool trace_probe"].ParentSpanID != byName["agent.run"].SpanID {
+		t.Error("tool spans should be children of agent.run")
+	}
+	if byName["llm.request"].ParentSpanID != byName["llm.call"].SpanID {
+		t.Error("llm.request should be a child of llm.call")
+	}
+}
@@ -0,0 +1,144 @@
+// Package copilot – trigger.go decides whether a message activates the bot.
+//
+// The legacy `trigger` keyword is still honoured; `triggers` extends it with
+// aliases, regular expressions and mention-or-reply activation, globally or
+// per workspace:
+//
+//	triggers:
+//	  keywords: ["@devclaw", "devclaw,", "/ask"]
+//	  patterns: ["(?i)^hey\\s+claw\\b"]
+//	  mention_or_reply: true   # @mention or reply to the bot also activates
+//	  require_in_dms: false    # true for noisy shared numbers
+package copilot
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// TriggerConfig configures how messages activate the bot.
+type TriggerConfig struct {
+	// Keywords are activation aliases matched at the start of the message,
+	// case-insensitively and on a word boundary ("@devclaw", "/ask").
+	Keywords []string `yaml:"keywords"`
+
+	// Patterns are regular expressions; a match anywhere in the message
+	// activates the bot.
+	Patterns []string `yaml:"patterns"`
+
+	// MentionOrReply also activates on an @mention of the bot or a reply to
+	// one of its messages, on channels that report them.
+	MentionOrReply bool `yaml:"mention_or_reply"`
+
+	// RequireInDMs makes direct messages need a trigger too. By default DMs
+	// always activate the bot.
+	RequireInDMs bool `yaml:"require_in_dms"`
+}
+
+// withKeyword returns a copy with keyword prepended to the aliases (the
+// legacy single `trigger` setting).
+func (t TriggerConfig) withKeyword(keyword string) TriggerConfig {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return t
+	}
+	for _, k := range t.Keywords {
+		if strings.EqualFold(k, keyword) {
+			return t
+		}
+	}
+	t.Keywords = append([]string{keyword}, t.Keywords...)
+	return t
+}
+
+// triggerRegexps caches compiled trigger patterns; nil marks an invalid one.
+var triggerRegexps sync.Map // pattern -> *regexp.Regexp
+
+// triggerRegexp compiles a pattern once. Invalid patterns never match.
+func triggerRegexp(pattern string) *regexp.Regexp {
+	if v, ok := triggerRegexps.Load(pattern); ok {
+		re, _ := v.(*regexp.Regexp)
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	triggerRegexps.Store(pattern, re)
+	return re
+}
+
+// matches reports whether msg activates the bot under t.
+func (t TriggerConfig) matches(msg *channels.IncomingMessage) bool {
+	if len(t.Keywords) == 0 && len(t.Patterns) == 0 && !t.MentionOrReply {
+		return true // No trigger configured = always respond.
+	}
+	if !msg.IsGroup && !t.RequireInDMs {
+		return true
+	}
+
+	if t.MentionOrReply && (msg.MetaBool(channels.MetaMentionsBot) || msg.MetaBool(channels.MetaReplyToBot)) {
+		return true
+	}
+
+	content := strings.TrimSpace(msg.Content)
+	for _, k := range t.Keywords {
+		if hasKeywordPrefix(content, k) {
+			return true
+		}
+	}
+	for _, p := range t.Patterns {
+		if re := triggerRegexp(p); re != nil && re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeywordPrefix reports whether content starts with keyword
+// (case-insensitive) followed by a word boundary: end of text, a space or
+// punctuation. Keywords ending in punctuation ("devclaw,") need no boundary.
+func hasKeywordPrefix(content, keyword string) bool {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" || len(content) < len(keyword) || !strings.EqualFold(content[:len(keyword)], keyword) {
+		return false
+	}
+	if len(content) == len(keyword) {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(keyword)
+	if !isWordRune(last) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(content[len(keyword):])
+	return !isWordRune(next)
+}
+
+// isWordRune reports whether r continues a word (letters, digits, _ and -).
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'
+}
+
+// triggerFor returns the trigger rules for a workspace: its own `triggers`
+// when set, else the global ones, plus the applicable legacy keyword.
+func (a *Assistant) triggerFor(ws *Workspace) TriggerConfig {
+	a.configMu.RLock()
+	keyword := a.config.Trigger
+	rules := a.config.Triggers
+	a.configMu.RUnlock()
+
+	if ws != nil {
+		if ws.Trigger != "" {
+			keyword = ws.Trigger
+		}
+		if ws.Triggers != nil {
+			rules = *ws.Triggers
+		}
+	}
+	return rules.withKeyword(keyword)
+}
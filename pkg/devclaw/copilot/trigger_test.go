@@ -0,0 +1,95 @@
+package copilot
+
+import (
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+func TestTriggerConfig_Matches(t *testing.T) {
+	t.Parallel()
+
+	keywords := TriggerConfig{Keywords: []string{"@devclaw", "claw,", "/ask"}}
+	patterns := TriggerConfig{Patterns: []string{`(?i)^hey\s+claw\b`, `[`}} // invalid pattern is ignored
+	mention := TriggerConfig{Keywords: []string{"@devclaw"}, MentionOrReply: true}
+	strictDM := TriggerConfig{Keywords: []string{"@devclaw"}, RequireInDMs: true}
+
+	tests := []struct {
+		name    string
+		cfg     TriggerConfig
+		content string
+		group   bool
+		meta    string
+		want    bool
+	}{
+		{"no trigger, group", TriggerConfig{}, "anything", true, "", true},
+		{"no trigger, dm", TriggerConfig{}, "anything", false, "", true},
+
+		{"keyword prefix", keywords, "@devclaw deploy", true, "", true},
+		{"keyword case-insensitive", keywords, "@DevClaw deploy", true, "", true},
+		{"keyword alone", keywords, "@devclaw", true, "", true},
+		{"keyword then punctuation", keywords, "@devclaw, deploy", true, "", true},
+		{"keyword without boundary", keywords, "@devclawbot deploy", true, "", false},
+		{"alias ending in punctuation", keywords, "claw,deploy", true, "", true},
+		{"slash alias", keywords, "/ask what is up", true, "", true},
+		{"slash alias prefix of longer word", keywords, "/asking", true, "", false},
+		{"keyword mid-message", keywords, "hey @devclaw", true, "", false},
+		{"no keyword", keywords, "deploy now", true, "", false},
+		{"dm without keyword", keywords, "deploy now", false, "", true},
+
+		{"pattern match", patterns, "Hey  claw, status?", true, "", true},
+		{"pattern no match", patterns, "hey clawson", true, "", false},
+
+		{"mention", mention, "can you check?", true, channels.MetaMentionsBot, true},
+		{"reply to bot", mention, "and the logs?", true, channels.MetaReplyToBot, true},
+		{"mention ignored when disabled", keywords, "can you check?", true, channels.MetaMentionsBot, false},
+		{"mention-only config still takes keyword", mention, "@devclaw hi", true, "", true},
+		{"mention-only config, plain message", mention, "hi all", true, "", false},
+
+		{"dm requires trigger", strictDM, "hello", false, "", false},
+		{"dm with trigger", strictDM, "@devclaw hello", false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			msg := &channels.IncomingMessage{Content: tt.content, IsGroup: tt.group}
+			if tt.meta != "" {
+				msg.SetMeta(tt.meta, true)
+			}
+			if got := tt.cfg.matches(msg); got != tt.want {
+				t.Errorf("matches(%q, group=%v) = %v, want %v", tt.content, tt.group, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssistant_TriggerFor(t *testing.T) {
+	a := &Assistant{config: &Config{
+		Trigger:  "@devclaw",
+		Triggers: TriggerConfig{Patterns: []string{"(?i)urgent"}},
+	}}
+	group := func(content string) *channels.IncomingMessage {
+		return &channels.IncomingMessage{Content: content, IsGroup: true}
+	}
+
+	global := a.triggerFor(&Workspace{ID: "default"})
+	if !global.matches(group("@devclaw hi")) || !global.matches(group("this is URGENT")) {
+		t.Error("global keyword and pattern should both activate")
+	}
+
+	// A workspace keyword replaces the global keyword; its own rules replace
+	// the global rules.
+	ws := &Workspace{ID: "sales", Trigger: "@sales", Triggers: &TriggerConfig{Keywords: []string{"vendas,"}}}
+	rules := a.triggerFor(ws)
+	for content, want := range map[string]bool{
+		"@sales quote":   true,
+		"vendas, quote":  true,
+		"@devclaw quote": false,
+		"urgent quote":   false,
+	} {
+		if got := rules.matches(group(content)); got != want {
+			t.Errorf("workspace rules: %q = %v, want %v", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package copilot – usage_budgets.go enforces daily and monthly token and
+// cost budgets per user and per workspace. The UsageTracker charges every
+// LLM call of an agent run to its user and workspace; before a run starts
+// the Assistant checks both, warns once per period when a budget passes
+// budget.warn_at_percent and, once a budget is used up, replies with a
+// short "budget exhausted" message instead of calling the LLM. Counters
+// are kept in memory, rebuilt from the usage log at startup (see
+// usage_log.go), and follow the server's calendar day and month.
+package copilot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UsageLimits caps usage over a day and a month (0 = unlimited).
+type UsageLimits struct {
+	DailyTokens   int64   `yaml:"daily_tokens"`
+	MonthlyTokens int64   `yaml:"monthly_tokens"`
+	DailyUSD      float64 `yaml:"daily_usd"`
+	MonthlyUSD    float64 `yaml:"monthly_usd"`
+}
+
+// budgetCounter is one user's or workspace's usage this day and month.
+type budgetCounter struct {
+	day, month             string
+	dayTokens, monthTokens int64
+	dayUSD, monthUSD       float64
+
+	// warned holds the budgets already warned about this period.
+	warned map[string]bool
+}
+
+// roll starts a new day or month when the calendar moved on.
+func (c *budgetCounter) roll(now time.Time) {
+	if day := now.Format("2006-01-02"); c.day != day {
+		c.day, c.dayTokens, c.dayUSD = day, 0, 0
+		delete(c.warned, "daily tokens")
+		delete(c.warned, "daily cost")
+	}
+	if month := now.Format("2006-01"); c.month != month {
+		c.month, c.monthTokens, c.monthUSD = month, 0, 0
+		delete(c.warned, "monthly tokens")
+		delete(c.warned, "monthly cost")
+	}
+}
+
+// globalBudgetKey is the counter of all LLM calls, whatever their user or
+// workspace. budgetKeys never returns it, so no limits apply to it.
+const globalBudgetKey = "all"
+
+// MonthSpendUSD returns the current calendar month ("2006-01") and the
+// estimated spend of all LLM calls in it, log replay included.
+func (u *UsageTracker) MonthSpendUSD() (month string, usd float64) {
+	if u == nil {
+		return "", 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	c := u.budgetCounter(globalBudgetKey, u.clock())
+	return c.month, c.monthUSD
+}
+
+// BudgetCheck is the outcome of checking a run against the budgets.
+type BudgetCheck struct {
+	// Exhausted means a budget is used up and the run must not start.
+	Exhausted bool
+
+	// Message is the reply for the user: why the run was refused, or a
+	// warning that a budget is nearly used up (empty when neither).
+	Message string
+}
+
+// SetBudgets sets the per-user and per-workspace limits.
+func (u *UsageTracker) SetBudgets(cfg BudgetConfig) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.budgets = cfg
+}
+
+// ChargeBudgets adds an LLM call to the user's and the workspace's budget
+// counters.
+func (u *UsageTracker) ChargeBudgets(user, workspaceID string, tokens int, cost float64) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := u.clock()
+	for _, key := range budgetKeys(user, workspaceID) {
+		c := u.budgetCounter(key, now)
+		c.dayTokens += int64(tokens)
+		c.monthTokens += int64(tokens)
+		c.dayUSD += cost
+		c.monthUSD += cost
+	}
+}
+
+// CheckBudgets checks the user's budgets, then the workspace's. A warning
+// is returned once per budget and period.
+func (u *UsageTracker) CheckBudgets(user, workspaceID string) BudgetCheck {
+	if u == nil {
+		return BudgetCheck{}
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := u.clock()
+	warnAt := u.budgets.WarnAtPercent
+	if warnAt <= 0 {
+		warnAt = 80
+	}
+
+	var warning string
+	for _, key := range budgetKeys(user, workspaceID) {
+		limits := u.budgetLimits(key)
+		if limits == (UsageLimits{}) {
+			continue
+		}
+		c := u.budgetCounter(key, now)
+		workspace := strings.HasPrefix(key, "workspace:")
+		for _, b := range []struct {
+			name, period string
+			used, limit  float64
+			usd          bool
+		}{
+			{"daily tokens", "daily", float64(c.dayTokens), float64(limits.DailyTokens), false},
+			{"daily cost", "daily", c.dayUSD, limits.DailyUSD, true},
+			{"monthly tokens", "monthly", float64(c.monthTokens), float64(limits.MonthlyTokens), false},
+			{"monthly cost", "monthly", c.monthUSD, limits.MonthlyUSD, true},
+		} {
+			if b.limit <= 0 {
+				continue
+			}
+			if b.used >= b.limit {
+				return BudgetCheck{Exhausted: true, Message: budgetExhaustedMessage(workspace, b.period, budgetAmount(b.limit, b.usd), now)}
+			}
+			pct := int(b.used / b.limit * 100)
+			if pct < warnAt || c.warned[b.name] || warning != "" {
+				continue
+			}
+			if c.warned == nil {
+				c.warned = make(map[string]bool)
+			}
+			c.warned[b.name] = true
+			who, whose := budgetHolder(workspace)
+			warning = fmt.Sprintf("⚠️ %s used %d%% of %s %s budget (%s of %s).",
+				who, pct, whose, b.period, budgetAmount(b.used, b.usd), budgetAmount(b.limit, b.usd))
+		}
+	}
+	return BudgetCheck{Message: warning}
+}
+
+// budgetKeys returns the counter keys of a run.
+func budgetKeys(user, workspaceID string) []string {
+	var keys []string
+	if user != "" {
+		keys = append(keys, "user:"+user)
+	}
+	if workspaceID != "" {
+		keys = append(keys, "workspace:"+workspaceID)
+	}
+	return keys
+}
+
+// budgetLimits returns the limits for a counter key: its own entry, else
+// the default for users or workspaces.
+func (u *UsageTracker) budgetLimits(key string) UsageLimits {
+	if id, ok := strings.CutPrefix(key, "user:"); ok {
+		if l, ok := u.budgets.PerUser[id]; ok {
+			return l
+		}
+		return u.budgets.Users
+	}
+	id, _ := strings.CutPrefix(key, "workspace:")
+	if l, ok := u.budgets.PerWorkspace[id]; ok {
+		return l
+	}
+	return u.budgets.Workspaces
+}
+
+// budgetCounter returns the counter for key, rolled to now. Callers hold mu.
+func (u *UsageTracker) budgetCounter(key string, now time.Time) *budgetCounter {
+	if u.budgetUsage == nil {
+		u.budgetUsage = make(map[string]*budgetCounter)
+	}
+	c, ok := u.budgetUsage[key]
+	if !ok {
+		c = &budgetCounter{}
+		u.budgetUsage[key] = c
+	}
+	c.roll(now)
+	return c
+}
+
+func (u *UsageTracker) clock() time.Time {
+	if u.now != nil {
+		return u.now()
+	}
+	return time.Now()
+}
+
+// budgetExhaustedMessage is the reply when a budget is used up.
+func budgetExhaustedMessage(workspace bool, period, limit string, now time.Time) string {
+	resets := "tomorrow"
+	if period == "monthly" {
+		y, m, _ := now.Date()
+		resets = "on " + time.Date(y, m+1, 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	}
+	who, whose := budgetHolder(workspace)
+	return fmt.Sprintf("%s used up %s %s budget (%s). It resets %s — ask an admin if you need more.", who, whose, period, limit, resets)
+}
+
+// budgetHolder words a message about a user's or a workspace's budget.
+func budgetHolder(workspace bool) (who, whose string) {
+	if workspace {
+		return "This workspace has", "its"
+	}
+	return "You've", "your"
+}
+
+// budgetAmount formats a budget figure as tokens or dollars.
+func budgetAmount(v float64, usd bool) string {
+	if usd {
+		return fmt.Sprintf("$%.2f", v)
+	}
+	return fmt.Sprintf("%d tokens", int64(v))
+}
@@ -0,0 +1,76 @@
+package copilot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsageTracker_Budgets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	u := NewUsageTracker(nil)
+	u.now = func() time.Time { return now }
+	u.SetBudgets(BudgetConfig{
+		WarnAtPercent: 80,
+		Users:         UsageLimits{DailyTokens: 1000},
+		PerUser:       map[string]UsageLimits{"vip": {}},
+		PerWorkspace:  map[string]UsageLimits{"sales": {MonthlyUSD: 5}},
+	})
+
+	u.ChargeBudgets("alice", "sales", 850, 1)
+	check := u.CheckBudgets("alice", "sales")
+	if check.Exhausted || !strings.Contains(check.Message, "85% of your daily budget (850 tokens of 1000 tokens)") {
+		t.Fatalf("expected a daily warning, got %+v", check)
+	}
+	if check := u.CheckBudgets("alice", "sales"); check.Message != "" {
+		t.Errorf("the warning should be sent once, got %q", check.Message)
+	}
+
+	u.ChargeBudgets("alice", "sales", 200, 1)
+	check = u.CheckBudgets("alice", "sales")
+	if !check.Exhausted || !strings.Contains(check.Message, "used up your daily budget") || !strings.Contains(check.Message, "resets tomorrow") {
+		t.Fatalf("expected the daily budget to be exhausted, got %+v", check)
+	}
+	if check := u.CheckBudgets("vip", "other"); check.Exhausted || check.Message != "" {
+		t.Errorf("an empty per-user entry should lift the limit, got %+v", check)
+	}
+
+	// A new day resets the user's budget but not the workspace's month.
+	now = now.Add(24 * time.Hour)
+	u.ChargeBudgets("bob", "sales", 10, 3)
+	check = u.CheckBudgets("alice", "sales")
+	if !check.Exhausted || !strings.Contains(check.Message, "This workspace has used up its monthly budget ($5.00). It resets on 2026-04-01") {
+		t.Fatalf("expected the workspace budget to be exhausted, got %+v", check)
+	}
+	if check := u.CheckBudgets("alice", "support"); check.Exhausted {
+		t.Errorf("alice's daily budget should have reset, got %+v", check)
+	}
+}
+
+func TestE2E_UserBudgetCutoff(t *testing.T) {
+	h := newE2EHarness(t, func(cfg *Config) {
+		cfg.Access.AllowedUsers = []string{"user@e2e"}
+		// The mock LLM reports 15 tokens per call.
+		cfg.Budget.WarnAtPercent = 50
+		cfg.Budget.Users = UsageLimits{DailyTokens: 20}
+	})
+
+	h.SendFrom("user@e2e", "first")
+	replies := h.SendFrom("user@e2e", "second")
+	if len(replies) < 2 || !strings.Contains(replies[0], "75% of your daily budget") {
+		t.Fatalf("expected a warning before the reply, got %q", replies)
+	}
+	calls := len(h.LLM.Requests())
+	replies = h.SendFrom("user@e2e", "third")
+	if len(replies) != 1 || !strings.Contains(replies[0], "used up your daily budget") {
+		t.Fatalf("expected the budget exhausted reply, got %q", replies)
+	}
+	if len(h.LLM.Requests()) != calls {
+		t.Error("no LLM call should be made once the budget is used up")
+	}
+	if replies := h.Send("owners are never cut off"); len(replies) == 0 {
+		t.Error("the owner should still get a reply")
+	}
+}
@@ -0,0 +1,537 @@
+// Package copilot – usage_ledger.go keeps a per-call usage ledger in
+// devclaw.db for billing clients per workspace. Every LLM call of an agent
+// run is recorded with its workspace, session, user, model, tokens and
+// estimated cost. The ledger exports to CSV or JSON, builds monthly
+// invoice-style reports per workspace, and a monthly job sends last month's
+// report to the owner and pushes the rows to external sinks (a webhook such
+// as a Google Sheets Apps Script, or BigQuery).
+package copilot
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
+)
+
+// BillingConfig configures the usage ledger and monthly usage reports.
+type BillingConfig struct {
+	// Enabled records every LLM call in devclaw.db (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// MonthlyReport sends last month's per-workspace report to the owner
+	// alert contacts at the start of each month (default: true).
+	MonthlyReport bool `yaml:"monthly_report"`
+
+	// TopUsers is how many users each report lists (default: 5).
+	TopUsers int `yaml:"top_users"`
+
+	// Push sends last month's ledger rows to external sinks with the report.
+	Push []UsagePushTarget `yaml:"push"`
+}
+
+// UsagePushTarget is an external sink for monthly usage rows.
+type UsagePushTarget struct {
+	// Type is "webhook" (POST {"month", "rows"} as JSON, e.g. to a Google
+	// Sheets Apps Script) or "bigquery" (tabledata.insertAll).
+	Type string `yaml:"type"`
+
+	// URL is the webhook endpoint.
+	URL string `yaml:"url"`
+
+	// Headers are extra HTTP headers for webhooks (e.g. auth).
+	Headers map[string]string `yaml:"headers"`
+
+	// Project, Dataset and Table address the BigQuery table.
+	Project string `yaml:"project"`
+	Dataset string `yaml:"dataset"`
+	Table   string `yaml:"table"`
+
+	// Token is the OAuth access token for BigQuery.
+	Token string `yaml:"token"`
+}
+
+// DefaultBillingConfig returns the default billing configuration.
+func DefaultBillingConfig() BillingConfig {
+	return BillingConfig{Enabled: true, MonthlyReport: true, TopUsers: 5}
+}
+
+// UsageEntry is one LLM call in the ledger.
+type UsageEntry struct {
+	ID               int64     `json:"id"`
+	At               time.Time `json:"at"`
+	WorkspaceID      string    `json:"workspace_id"`
+	SessionID        string    `json:"session_id"`
+	UserID           string    `json:"user_id,omitempty"`
+	RunID            string    `json:"run_id"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+// UsageLine is one row of a report breakdown (a model or a user).
+type UsageLine struct {
+	Name     string  `json:"name"`
+	Requests int     `json:"requests"`
+	Tokens   int64   `json:"tokens"`
+	CostUSD  float64 `json:"cost_usd"`
+}
+
+// UsageInvoice is a workspace's usage for one month.
+type UsageInvoice struct {
+	WorkspaceID      string      `json:"workspace_id"`
+	Month            string      `json:"month"`
+	PromptTokens     int64       `json:"prompt_tokens"`
+	CompletionTokens int64       `json:"completion_tokens"`
+	Requests         int         `json:"requests"`
+	Runs             int         `json:"runs"`
+	CostUSD          float64     `json:"cost_usd"`
+	Models           []UsageLine `json:"models"`
+	TopUsers         []UsageLine `json:"top_users"`
+}
+
+// Format renders the invoice for chat, with numbers in the locale's
+// convention.
+func (inv UsageInvoice) Format(loc scheduler.Locale) string {
+	n := func(v int64) string { return loc.FormatInt(v) }
+	usd := func(v float64) string { return "$" + loc.FormatNumber(v, 2) }
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Usage — %s (%s)*\n", inv.WorkspaceID, inv.Month)
+	fmt.Fprintf(&b, "Runs: %s | LLM calls: %s\n", n(int64(inv.Runs)), n(int64(inv.Requests)))
+	fmt.Fprintf(&b, "Tokens: %s in / %s out\n", n(inv.PromptTokens), n(inv.CompletionTokens))
+	fmt.Fprintf(&b, "Est. cost: %s\n", usd(inv.CostUSD))
+	if len(inv.Models) > 0 {
+		b.WriteString("\nModels:\n")
+		for _, l := range inv.Models {
+			fmt.Fprintf(&b, "- %s: %s calls, %s tokens, %s\n", l.Name, n(int64(l.Requests)), n(l.Tokens), usd(l.CostUSD))
+		}
+	}
+	if len(inv.TopUsers) > 0 {
+		b.WriteString("\nTop users:\n")
+		for _, l := range inv.TopUsers {
+			fmt.Fprintf(&b, "- %s: %s calls, %s\n", l.Name, n(int64(l.Requests)), usd(l.CostUSD))
+		}
+	}
+	return b.String()
+}
+
+// UsageLedger stores usage entries in devclaw.db.
+type UsageLedger struct {
+	db     *sql.DB
+	cfg    BillingConfig
+	client *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewUsageLedger creates the ledger tables if needed.
+func NewUsageLedger(db *sql.DB, cfg BillingConfig, logger *slog.Logger) (*UsageLedger, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.TopUsers <= 0 {
+		cfg.TopUsers = 5
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS usage_ledger (
+			id                INTEGER PRIMARY KEY AUTOINCREMENT,
+			at                TEXT NOT NULL,
+			month             TEXT NOT NULL,
+			workspace_id      TEXT NOT NULL,
+			session_id        TEXT NOT NULL,
+			user_id           TEXT NOT NULL DEFAULT '',
+			run_id            TEXT NOT NULL,
+			model             TEXT NOT NULL,
+			prompt_tokens     INTEGER NOT NULL,
+			completion_tokens INTEGER NOT NULL,
+			cost_usd          REAL NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_usage_ledger_month ON usage_ledger(month, workspace_id);
+		CREATE TABLE IF NOT EXISTS usage_reports (
+			month   TEXT PRIMARY KEY,
+			sent_at TEXT NOT NULL
+		)`); err != nil {
+		return nil, fmt.Errorf("create usage ledger tables: %w", err)
+	}
+	return &UsageLedger{
+		db:     db,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		logger: logger.With("component", "usage-ledger"),
+		now:    time.Now,
+	}, nil
+}
+
+// Record appends an entry. Storage errors are logged, never returned: a
+// ledger hiccup must not fail the run.
+func (l *UsageLedger) Record(e UsageEntry) {
+	if e.At.IsZero() {
+		e.At = l.now()
+	}
+	at := e.At.UTC()
+	if _, err := l.db.Exec(`
+		INSERT INTO usage_ledger (at, month, workspace_id, session_id, user_id, run_id, model,
+			prompt_tokens, completion_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		at.Format(time.RFC3339), at.Format("2006-01"), e.WorkspaceID, e.SessionID, e.UserID, e.RunID,
+		e.Model, e.PromptTokens, e.CompletionTokens, e.CostUSD); err != nil {
+		l.logger.Warn("recording usage failed", "workspace", e.WorkspaceID, "error", err)
+	}
+}
+
+// Entries returns the entries of a month (YYYY-MM), oldest first, for one
+// workspace or all of them (workspaceID "").
+func (l *UsageLedger) Entries(month, workspaceID string) ([]UsageEntry, error) {
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, fmt.Errorf("invalid month %q (want YYYY-MM)", month)
+	}
+	return l.queryEntries(`month = ?`, month, workspaceID)
+}
+
+// EntriesSince returns the entries recorded at or after since, oldest
+// first, for one workspace or all of them (workspaceID "").
+func (l *UsageLedger) EntriesSince(since time.Time, workspaceID string) ([]UsageEntry, error) {
+	return l.queryEntries(`at >= ?`, since.UTC().Format(time.RFC3339), workspaceID)
+}
+
+// queryEntries returns the entries matching cond (one placeholder, bound
+// to arg), optionally limited to a workspace.
+func (l *UsageLedger) queryEntries(cond string, arg any, workspaceID string) ([]UsageEntry, error) {
+	query := `SELECT id, at, workspace_id, session_id, user_id, run_id, model,
+		prompt_tokens, completion_tokens, cost_usd FROM usage_ledger WHERE ` + cond
+	args := []any{arg}
+	if workspaceID != "" {
+		query += ` AND workspace_id = ?`
+		args = append(args, workspaceID)
+	}
+	rows, err := l.db.Query(query+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query usage ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []UsageEntry
+	for rows.Next() {
+		var e UsageEntry
+		var at string
+		if err := rows.Scan(&e.ID, &at, &e.WorkspaceID, &e.SessionID, &e.UserID, &e.RunID, &e.Model,
+			&e.PromptTokens, &e.CompletionTokens, &e.CostUSD); err != nil {
+			return nil, fmt.Errorf("scan usage entry: %w", err)
+		}
+		e.At, _ = time.Parse(time.RFC3339, at)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ExportUsage writes entries as "csv" or "json".
+func ExportUsage(w io.Writer, format string, entries []UsageEntry) error {
+	switch format {
+	case "json":
+		if entries == nil {
+			entries = []UsageEntry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"id", "at", "workspace_id", "session_id", "user_id", "run_id", "model",
+			"prompt_tokens", "completion_tokens", "cost_usd"})
+		for _, e := range entries {
+			_ = cw.Write([]string{
+				strconv.FormatInt(e.ID, 10), e.At.Format(time.RFC3339), e.WorkspaceID, e.SessionID,
+				e.UserID, e.RunID, e.Model, strconv.Itoa(e.PromptTokens), strconv.Itoa(e.CompletionTokens),
+				strconv.FormatFloat(e.CostUSD, 'f', 6, 64),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown export format %q (use csv or json)", format)
+	}
+}
+
+// Invoices builds the month's report of each workspace (or one workspace),
+// ordered by workspace ID.
+func (l *UsageLedger) Invoices(month, workspaceID string) ([]UsageInvoice, error) {
+	entries, err := l.Entries(month, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return buildInvoices(month, entries, l.cfg.TopUsers), nil
+}
+
+// buildInvoices aggregates ledger entries per workspace.
+func buildInvoices(month string, entries []UsageEntry, topUsers int) []UsageInvoice {
+	type acc struct {
+		inv    UsageInvoice
+		runs   map[string]bool
+		models map[string]*UsageLine
+		users  map[string]*UsageLine
+	}
+	byWS := make(map[string]*acc)
+	for _, e := range entries {
+		a, ok := byWS[e.WorkspaceID]
+		if !ok {
+			a = &acc{
+				inv:    UsageInvoice{WorkspaceID: e.WorkspaceID, Month: month},
+				runs:   make(map[string]bool),
+				models: make(map[string]*UsageLine),
+				users:  make(map[string]*UsageLine),
+			}
+			byWS[e.WorkspaceID] = a
+		}
+		a.inv.PromptTokens += int64(e.PromptTokens)
+		a.inv.CompletionTokens += int64(e.CompletionTokens)
+		a.inv.Requests++
+		a.inv.CostUSD += e.CostUSD
+		a.runs[e.RunID] = true
+		addUsageLine(a.models, e.Model, e)
+		user := e.UserID
+		if user == "" {
+			user = "(system)"
+		}
+		addUsageLine(a.users, user, e)
+	}
+
+	invoices := make([]UsageInvoice, 0, len(byWS))
+	for _, a := range byWS {
+		a.inv.Runs = len(a.runs)
+		a.inv.Models = sortedUsageLines(a.models, 0)
+		a.inv.TopUsers = sortedUsageLines(a.users, topUsers)
+		invoices = append(invoices, a.inv)
+	}
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].WorkspaceID < invoices[j].WorkspaceID })
+	return invoices
+}
+
+// addUsageLine adds an entry to the breakdown line called name.
+func addUsageLine(lines map[string]*UsageLine, name string, e UsageEntry) {
+	line, ok := lines[name]
+	if !ok {
+		line = &UsageLine{Name: name}
+		lines[name] = line
+	}
+	line.Requests++
+	line.Tokens += int64(e.PromptTokens + e.CompletionTokens)
+	line.CostUSD += e.CostUSD
+}
+
+// sortedUsageLines returns a breakdown by cost, highest first, keeping at
+// most limit lines (0 = all).
+func sortedUsageLines(lines map[string]*UsageLine, limit int) []UsageLine {
+	out := make([]UsageLine, 0, len(lines))
+	for _, line := range lines {
+		out = append(out, *line)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CostUSD != out[j].CostUSD {
+			return out[i].CostUSD > out[j].CostUSD
+		}
+		return out[i].Name < out[j].Name
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// formatUsageReport renders the monthly report of every workspace.
+func formatUsageReport(invoices []UsageInvoice, loc scheduler.Locale) string {
+	if len(invoices) == 0 {
+		return "No usage recorded."
+	}
+	var total float64
+	parts := make([]string, 0, len(invoices))
+	for _, inv := range invoices {
+		total += inv.CostUSD
+		parts = append(parts, inv.Format(loc))
+	}
+	return strings.Join(parts, "\n") + fmt.Sprintf("\n*Total: $%s across %d workspaces*", loc.FormatNumber(total, 2), len(invoices))
+}
+
+// reported reports whether a month's report job already ran.
+func (l *UsageLedger) reported(month string) bool {
+	var sentAt string
+	err := l.db.QueryRow(`SELECT sent_at FROM usage_reports WHERE month = ?`, month).Scan(&sentAt)
+	return err == nil
+}
+
+// markReported records that a month's report job ran.
+func (l *UsageLedger) markReported(month string) error {
+	_, err := l.db.Exec(`INSERT OR REPLACE INTO usage_reports (month, sent_at) VALUES (?, ?)`,
+		month, l.now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// reportLastMonth runs the monthly job for the month that just ended, once:
+// its rows are pushed to the configured sinks and its report is passed to
+// deliver. A failed push or delivery is retried at the next check. Rows are
+// pushed first because BigQuery deduplicates a repeated push by insert ID.
+func (l *UsageLedger) reportLastMonth(ctx context.Context, deliver func(ctx context.Context, month string, invoices []UsageInvoice) error) {
+	month := l.now().UTC().AddDate(0, -1, 0).Format("2006-01")
+	if l.reported(month) {
+		return
+	}
+	entries, err := l.Entries(month, "")
+	if err != nil {
+		l.logger.Warn("usage report failed", "month", month, "error", err)
+		return
+	}
+	if err := l.push(ctx, month, entries); err != nil {
+		l.logger.Warn("usage push failed", "month", month, "error", err)
+		return
+	}
+	if l.cfg.MonthlyReport && deliver != nil {
+		if err := deliver(ctx, month, buildInvoices(month, entries, l.cfg.TopUsers)); err != nil {
+			l.logger.Warn("usage report delivery failed", "month", month, "error", err)
+			return
+		}
+	}
+	if err := l.markReported(month); err != nil {
+		l.logger.Warn("saving usage report state failed", "month", month, "error", err)
+		return
+	}
+	l.logger.Info("usage report sent", "month", month, "entries", len(entries))
+}
+
+// Run checks periodically whether last month still needs its report.
+func (l *UsageLedger) Run(ctx context.Context, deliver func(ctx context.Context, month string, invoices []UsageInvoice) error) {
+	l.reportLastMonth(ctx, deliver)
+	ticker := time.NewTicker(analyticsCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.reportLastMonth(ctx, deliver)
+		}
+	}
+}
+
+// usagePushBatch caps the rows per push request (BigQuery recommends 500).
+const usagePushBatch = 500
+
+// push sends a month's entries to every configured sink.
+func (l *UsageLedger) push(ctx context.Context, month string, entries []UsageEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, t := range l.cfg.Push {
+		for start := 0; start < len(entries); start += usagePushBatch {
+			batch := entries[start:min(start+usagePushBatch, len(entries))]
+			var err error
+			switch t.Type {
+			case "webhook":
+				err = l.pushWebhook(ctx, t, month, batch)
+			case "bigquery":
+				err = l.pushBigQuery(ctx, t, batch)
+			default:
+				err = fmt.Errorf("unknown push type %q", t.Type)
+			}
+			if err != nil {
+				return fmt.Errorf("%s push: %w", t.Type, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pushWebhook posts {"month", "rows"} to a webhook.
+func (l *UsageLedger) pushWebhook(ctx context.Context, t UsagePushTarget, month string, rows []UsageEntry) error {
+	if t.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	_, err := l.postJSON(ctx, t.URL, t.Headers, map[string]any{"month": month, "rows": rows})
+	return err
+}
+
+// pushBigQuery streams rows with tabledata.insertAll. Ledger IDs are used as
+// insert IDs so a retried push does not duplicate rows.
+func (l *UsageLedger) pushBigQuery(ctx context.Context, t UsagePushTarget, rows []UsageEntry) error {
+	if t.Project == "" || t.Dataset == "" || t.Table == "" || t.Token == "" {
+		return fmt.Errorf("project, dataset, table and token are required")
+	}
+	url := t.URL
+	if url == "" {
+		url = "https://bigquery.googleapis.com"
+	}
+	url = fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s/tables/%s/insertAll",
+		strings.TrimRight(url, "/"), t.Project, t.Dataset, t.Table)
+
+	type bqRow struct {
+		InsertID string     `json:"insertId"`
+		JSON     UsageEntry `json:"json"`
+	}
+	body := struct {
+		Rows []bqRow `json:"rows"`
+	}{}
+	for _, e := range rows {
+		body.Rows = append(body.Rows, bqRow{InsertID: strconv.FormatInt(e.ID, 10), JSON: e})
+	}
+	resp, err := l.postJSON(ctx, url, map[string]string{"Authorization": "Bearer " + t.Token}, body)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		InsertErrors []json.RawMessage `json:"insertErrors"`
+	}
+	if json.Unmarshal(resp, &result) == nil && len(result.InsertErrors) > 0 {
+		return fmt.Errorf("%d rows rejected: %s", len(result.InsertErrors), truncate(string(result.InsertErrors[0]), 200))
+	}
+	return nil
+}
+
+// postJSON sends a JSON body and returns the response body of a 2xx reply.
+func (l *UsageLedger) postJSON(ctx context.Context, url string, headers map[string]string, in any) ([]byte, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, truncate(string(out), 200))
+	}
+	return out, nil
+}
+
+// deliverUsageReport sends the monthly usage report to the owner.
+func (a *Assistant) deliverUsageReport(ctx context.Context, month string, invoices []UsageInvoice) error {
+	if !a.ownerAlerter.Enabled() {
+		a.logger.Info("usage report not delivered: no owner_alerts contacts", "month", month)
+		return nil
+	}
+	_, err := a.ownerAlerter.Send(ctx, OwnerAlert{
+		Kind:  OwnerAlertReport,
+		Title: "Usage report — " + month,
+		Body:  formatUsageReport(invoices, a.config.LocaleFor("").Locale),
+	})
+	return err
+}
@@ -0,0 +1,396 @@
+// Package copilot – usage_log.go persists the UsageTracker to disk so cost
+// history survives restarts. Every LLM call is appended as a JSON line to
+// usage_log.dir/usage-YYYY-MM-DD.jsonl (one file per day, so the log
+// rotates daily). On startup the kept files are replayed into the tracker:
+// session and global totals, and this day's and month's budget counters.
+//
+// A background job compacts days older than compact_after_days into
+// usage-YYYY-MM-DD.compact.jsonl, one line per session, workspace, user and
+// model with summed tokens, cost and request counts, and deletes files
+// older than retention_days.
+package copilot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UsageLogConfig configures the on-disk usage log.
+type UsageLogConfig struct {
+	// Enabled appends every LLM call to the usage log (default: true).
+	Enabled bool `yaml:"enabled"`
+
+	// Dir holds the daily JSONL files (default: ./data/usage).
+	Dir string `yaml:"dir"`
+
+	// CompactAfterDays compacts days older than this into one line per
+	// session, workspace, user and model (default: 7).
+	CompactAfterDays int `yaml:"compact_after_days"`
+
+	// RetentionDays deletes days older than this (default: 0 = keep).
+	RetentionDays int `yaml:"retention_days"`
+}
+
+// DefaultUsageLogConfig returns the default usage log configuration.
+func DefaultUsageLogConfig() UsageLogConfig {
+	return UsageLogConfig{Enabled: true, Dir: "./data/usage", CompactAfterDays: 7}
+}
+
+// usageCompactInterval is how often old days are compacted.
+const usageCompactInterval = 6 * time.Hour
+
+// UsageRecord is one line of the usage log: an LLM call, or the sum of
+// several calls in a compacted day.
+type UsageRecord struct {
+	Time             time.Time `json:"ts"`
+	SessionID        string    `json:"session"`
+	WorkspaceID      string    `json:"workspace,omitempty"`
+	UserID           string    `json:"user,omitempty"`
+	Model            string    `json:"model"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CacheReadTokens  int64     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64     `json:"cache_write_tokens,omitempty"`
+	CostUSD          float64   `json:"cost_usd"`
+	Requests         int64     `json:"requests"`
+}
+
+// UsageLog appends usage records to daily files.
+type UsageLog struct {
+	cfg    UsageLogConfig
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	day  string   // date of the open file
+	file *os.File // nil until the first record
+}
+
+// OpenUsageLog creates the usage directory.
+func OpenUsageLog(cfg UsageLogConfig, logger *slog.Logger) (*UsageLog, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = DefaultUsageLogConfig().Dir
+	}
+	if cfg.CompactAfterDays <= 0 {
+		cfg.CompactAfterDays = DefaultUsageLogConfig().CompactAfterDays
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create usage log dir: %w", err)
+	}
+	return &UsageLog{cfg: cfg, logger: logger.With("component", "usage-log")}, nil
+}
+
+// Append writes a record to its day's file. Errors are logged, never
+// returned: a full disk must not fail the run.
+func (l *UsageLog) Append(rec UsageRecord) {
+	if l == nil {
+		return
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	if rec.Requests == 0 {
+		rec.Requests = 1
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if day := rec.Time.Format("2006-01-02"); l.file == nil || day != l.day {
+		if l.file != nil {
+			_ = l.file.Close()
+		}
+		f, err := os.OpenFile(l.path(day, false), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			l.file = nil
+			l.logger.Warn("failed to open usage log", "error", err)
+			return
+		}
+		l.file, l.day = f, day
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		l.logger.Warn("failed to write usage record", "error", err)
+	}
+}
+
+// Close closes the open file.
+func (l *UsageLog) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// path returns the file of a day.
+func (l *UsageLog) path(day string, compact bool) string {
+	if compact {
+		return filepath.Join(l.cfg.Dir, "usage-"+day+".compact.jsonl")
+	}
+	return filepath.Join(l.cfg.Dir, "usage-"+day+".jsonl")
+}
+
+// usageLogFile is a file of the usage log.
+type usageLogFile struct {
+	day     string
+	path    string
+	compact bool
+}
+
+// files lists the log files, oldest day first.
+func (l *UsageLog) files() ([]usageLogFile, error) {
+	entries, err := os.ReadDir(l.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []usageLogFile
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), "usage-")
+		if !ok || e.IsDir() {
+			continue
+		}
+		f := usageLogFile{path: filepath.Join(l.cfg.Dir, e.Name())}
+		if day, ok := strings.CutSuffix(name, ".compact.jsonl"); ok {
+			f.day, f.compact = day, true
+		} else if day, ok := strings.CutSuffix(name, ".jsonl"); ok {
+			f.day = day
+		} else {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", f.day); err != nil {
+			continue
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].day < files[j].day })
+	return files, nil
+}
+
+// Load reads every record kept on disk, oldest first.
+func (l *UsageLog) Load() ([]UsageRecord, error) {
+	files, err := l.files()
+	if err != nil {
+		return nil, err
+	}
+	var records []UsageRecord
+	for _, f := range files {
+		recs, err := readUsageFile(f.path)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, recs...)
+	}
+	return records, nil
+}
+
+// readUsageFile reads a JSONL file, skipping malformed lines (e.g. a line
+// cut short by a crash).
+func readUsageFile(path string) ([]UsageRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []UsageRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var rec UsageRecord
+		if json.Unmarshal(sc.Bytes(), &rec) == nil {
+			records = append(records, rec)
+		}
+	}
+	return records, sc.Err()
+}
+
+// Compact compacts days older than compact_after_days and deletes days
+// older than retention_days. It returns how many days were compacted.
+func (l *UsageLog) Compact(now time.Time) (int, error) {
+	files, err := l.files()
+	if err != nil {
+		return 0, err
+	}
+	compactBefore := now.AddDate(0, 0, -l.cfg.CompactAfterDays).Format("2006-01-02")
+	deleteBefore := ""
+	if l.cfg.RetentionDays > 0 {
+		deleteBefore = now.AddDate(0, 0, -l.cfg.RetentionDays).Format("2006-01-02")
+	}
+
+	compacted := 0
+	for _, f := range files {
+		switch {
+		case f.day < deleteBefore:
+			if err := os.Remove(f.path); err != nil {
+				return compacted, err
+			}
+		case !f.compact && f.day < compactBefore:
+			if err := l.compactDay(f); err != nil {
+				return compacted, err
+			}
+			compacted++
+		}
+	}
+	return compacted, nil
+}
+
+// compactDay folds a day's raw records into its compact file.
+func (l *UsageLog) compactDay(f usageLogFile) error {
+	records, err := readUsageFile(f.path)
+	if err != nil {
+		return err
+	}
+	target := l.path(f.day, true)
+	if _, err := os.Stat(target); err == nil {
+		// A day already compacted got late records: fold them in too.
+		old, err := readUsageFile(target)
+		if err != nil {
+			return err
+		}
+		records = append(old, records...)
+	}
+
+	type key struct{ session, workspace, user, model string }
+	sums := make(map[key]*UsageRecord)
+	var order []key
+	for _, r := range records {
+		k := key{r.SessionID, r.WorkspaceID, r.UserID, r.Model}
+		s, ok := sums[k]
+		if !ok {
+			day, _ := time.ParseInLocation("2006-01-02", f.day, r.Time.Location())
+			s = &UsageRecord{Time: day, SessionID: k.session, WorkspaceID: k.workspace, UserID: k.user, Model: k.model}
+			sums[k] = s
+			order = append(order, k)
+		}
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+		s.CacheReadTokens += r.CacheReadTokens
+		s.CacheWriteTokens += r.CacheWriteTokens
+		s.CostUSD += r.CostUSD
+		s.Requests += max(r.Requests, 1)
+	}
+
+	var buf strings.Builder
+	for _, k := range order {
+		data, err := json.Marshal(sums[k])
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return err
+	}
+	return os.Remove(f.path)
+}
+
+// Run compacts the log at startup and every few hours until ctx is done.
+func (l *UsageLog) Run(ctx context.Context) {
+	compact := func() {
+		n, err := l.Compact(time.Now())
+		if err != nil {
+			l.logger.Warn("usage log compaction failed", "error", err)
+		} else if n > 0 {
+			l.logger.Info("usage log compacted", "days", n)
+		}
+	}
+	compact()
+	ticker := time.NewTicker(usageCompactInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			compact()
+		}
+	}
+}
+
+// SetLog makes the tracker append every recorded call to l.
+func (u *UsageTracker) SetLog(l *UsageLog) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.log = l
+}
+
+// Restore replays records from the usage log into the session and global
+// totals, and this day's and month's budget counters (including the
+// instance-wide one behind MonthSpendUSD).
+func (u *UsageTracker) Restore(records []UsageRecord) {
+	u.init()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := u.clock()
+	for _, r := range records {
+		su, ok := u.sessions[r.SessionID]
+		if !ok {
+			su = &SessionUsage{}
+			u.sessions[r.SessionID] = su
+		}
+		for _, s := range []*SessionUsage{su, u.global} {
+			s.restore(r)
+		}
+
+		for _, key := range append(budgetKeys(r.UserID, r.WorkspaceID), globalBudgetKey) {
+			c := u.budgetCounter(key, now)
+			tokens := r.PromptTokens + r.CompletionTokens
+			if r.Time.In(now.Location()).Format("2006-01") == c.month {
+				c.monthTokens += tokens
+				c.monthUSD += r.CostUSD
+			}
+			if r.Time.In(now.Location()).Format("2006-01-02") == c.day {
+				c.dayTokens += tokens
+				c.dayUSD += r.CostUSD
+			}
+		}
+	}
+}
+
+// restore adds a usage log record to the stats.
+func (su *SessionUsage) restore(r UsageRecord) {
+	requests := max(r.Requests, 1)
+	su.PromptTokens += r.PromptTokens
+	su.CompletionTokens += r.CompletionTokens
+	su.TotalTokens += r.PromptTokens + r.CompletionTokens
+	su.Requests += requests
+	su.EstimatedCostUSD += r.CostUSD
+	su.PromptCacheReadTokens += r.CacheReadTokens
+	su.PromptCacheWriteTokens += r.CacheWriteTokens
+	if r.Model != "" {
+		if su.ModelRequests == nil {
+			su.ModelRequests = make(map[string]int64)
+		}
+		su.ModelRequests[r.Model] += requests
+	}
+	if su.FirstRequestAt.IsZero() || r.Time.Before(su.FirstRequestAt) {
+		su.FirstRequestAt = r.Time
+	}
+	if r.Time.After(su.LastRequestAt) {
+		su.LastRequestAt = r.Time
+	}
+}
@@ -0,0 +1,65 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageLog_RotateCompactAndRestore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l, err := OpenUsageLog(UsageLogConfig{Dir: dir, CompactAfterDays: 7, RetentionDays: 30}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local)
+	old := now.AddDate(0, 0, -10)
+	for _, rec := range []UsageRecord{
+		{Time: now.AddDate(0, 0, -40), SessionID: "s0", Model: "gpt-4o", PromptTokens: 1, CostUSD: 9},
+		{Time: old, SessionID: "s1", WorkspaceID: "sales", UserID: "alice", Model: "gpt-4o", PromptTokens: 100, CompletionTokens: 10, CostUSD: 1},
+		{Time: old.Add(time.Hour), SessionID: "s1", WorkspaceID: "sales", UserID: "alice", Model: "gpt-4o", PromptTokens: 200, CompletionTokens: 20, CostUSD: 2},
+		{Time: now, SessionID: "s1", WorkspaceID: "sales", UserID: "alice", Model: "gpt-4o-mini", PromptTokens: 50, CompletionTokens: 5, CostUSD: 0.5},
+	} {
+		l.Append(rec)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 3 {
+		t.Fatalf("expected one file per day, got %d", len(files))
+	}
+
+	if n, err := l.Compact(now); err != nil || n != 1 {
+		t.Fatalf("Compact = %d, %v; want one day compacted", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "usage-"+now.AddDate(0, 0, -40).Format("2006-01-02")+".jsonl")); !os.IsNotExist(err) {
+		t.Error("days past retention should be deleted")
+	}
+	compact, err := readUsageFile(filepath.Join(dir, "usage-"+old.Format("2006-01-02")+".compact.jsonl"))
+	if err != nil || len(compact) != 1 || compact[0].Requests != 2 || compact[0].PromptTokens != 300 {
+		t.Fatalf("compacted day = %+v, %v", compact, err)
+	}
+
+	records, err := l.Load()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Load = %d records, %v", len(records), err)
+	}
+	u := NewUsageTracker(nil)
+	u.now = func() time.Time { return now }
+	u.SetBudgets(BudgetConfig{Users: UsageLimits{DailyUSD: 0.5}})
+	u.Restore(records)
+
+	g := u.GetGlobal()
+	if g.Requests != 3 || g.TotalTokens != 385 || g.EstimatedCostUSD != 3.5 || g.ModelRequests["gpt-4o"] != 2 {
+		t.Errorf("restored global usage = %+v", g)
+	}
+	if s := u.GetSession("s1"); s == nil || s.Requests != 3 || !s.FirstRequestAt.Equal(time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("restored session usage = %+v", s)
+	}
+	if check := u.CheckBudgets("alice", "sales"); !check.Exhausted {
+		t.Error("today's restored cost should count against the daily budget")
+	}
+}
@@ -0,0 +1,109 @@
+// Package copilot – usage_report.go summarizes the usage ledger over a
+// period: LLM calls, tokens and estimated cost per model and, optionally,
+// per workspace. It backs /usage today in chat and the `devclaw usage` CLI,
+// which reads the ledger straight from devclaw.db.
+package copilot
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/scheduler"
+)
+
+// UsageReport is the usage recorded since a point in time.
+type UsageReport struct {
+	Since            time.Time   `json:"since"`
+	WorkspaceID      string      `json:"workspace_id,omitempty"`
+	Requests         int         `json:"requests"`
+	PromptTokens     int64       `json:"prompt_tokens"`
+	CompletionTokens int64       `json:"completion_tokens"`
+	CostUSD          float64     `json:"cost_usd"`
+	Models           []UsageLine `json:"models"`
+	Workspaces       []UsageLine `json:"workspaces,omitempty"`
+}
+
+// BuildUsageReport aggregates ledger entries per model and, with
+// byWorkspace, per workspace.
+func BuildUsageReport(since time.Time, workspaceID string, entries []UsageEntry, byWorkspace bool) UsageReport {
+	r := UsageReport{Since: since, WorkspaceID: workspaceID}
+	models := make(map[string]*UsageLine)
+	workspaces := make(map[string]*UsageLine)
+	for _, e := range entries {
+		r.Requests++
+		r.PromptTokens += int64(e.PromptTokens)
+		r.CompletionTokens += int64(e.CompletionTokens)
+		r.CostUSD += e.CostUSD
+		addUsageLine(models, e.Model, e)
+		if byWorkspace {
+			addUsageLine(workspaces, e.WorkspaceID, e)
+		}
+	}
+	r.Models = sortedUsageLines(models, 0)
+	if byWorkspace {
+		r.Workspaces = sortedUsageLines(workspaces, 0)
+	}
+	return r
+}
+
+// Format renders the report for chat or a terminal, with numbers in the
+// locale's convention.
+func (r UsageReport) Format(loc scheduler.Locale) string {
+	n := func(v int64) string { return loc.FormatInt(v) }
+	usd := func(v float64) string { return "$" + loc.FormatNumber(v, 2) }
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Usage since %s*", r.Since.Format("2006-01-02 15:04"))
+	if r.WorkspaceID != "" {
+		fmt.Fprintf(&b, " — %s", r.WorkspaceID)
+	}
+	b.WriteString("\n")
+	if r.Requests == 0 {
+		b.WriteString("No usage recorded.")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "LLM calls: %s\n", n(int64(r.Requests)))
+	fmt.Fprintf(&b, "Tokens: %s in / %s out\n", n(r.PromptTokens), n(r.CompletionTokens))
+	fmt.Fprintf(&b, "Est. cost: %s\n", usd(r.CostUSD))
+	b.WriteString("\nModels:\n")
+	for _, l := range r.Models {
+		fmt.Fprintf(&b, "- %s: %s calls, %s tokens, %s\n", l.Name, n(int64(l.Requests)), n(l.Tokens), usd(l.CostUSD))
+	}
+	if len(r.Workspaces) > 0 {
+		b.WriteString("\nWorkspaces:\n")
+		for _, l := range r.Workspaces {
+			fmt.Fprintf(&b, "- %s: %s calls, %s tokens, %s\n", l.Name, n(int64(l.Requests)), n(l.Tokens), usd(l.CostUSD))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// LoadUsageReport reads the usage ledger from the configured devclaw.db
+// without starting the assistant, for `devclaw usage`.
+func LoadUsageReport(cfg *Config, since time.Time, workspaceID string, byWorkspace bool) (UsageReport, error) {
+	dbPath := cfg.Database.Path
+	if dbPath == "" {
+		dbPath = "./data/devclaw.db"
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return UsageReport{}, fmt.Errorf("database not found: %w", err)
+	}
+	db, err := OpenDatabase(dbPath)
+	if err != nil {
+		return UsageReport{}, err
+	}
+	defer db.Close()
+
+	ledger, err := NewUsageLedger(db, cfg.Billing, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		return UsageReport{}, err
+	}
+	entries, err := ledger.EntriesSince(since, workspaceID)
+	if err != nil {
+		return UsageReport{}, err
+	}
+	return BuildUsageReport(since, workspaceID, entries, byWorkspace), nil
+}
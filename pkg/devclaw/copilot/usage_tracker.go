@@ -5,6 +5,9 @@ package copilot
 import (
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,6 +16,10 @@ import (
 type ModelCost struct {
 	InputPer1M  float64 `yaml:"input_per_1m"`  // USD per 1M input tokens
 	OutputPer1M float64 `yaml:"output_per_1m"` // USD per 1M output tokens
+
+	// Prompt cache prices; zero means the same as InputPer1M.
+	CacheReadPer1M  float64 `yaml:"cache_read_per_1m"`
+	CacheWritePer1M float64 `yaml:"cache_write_per_1m"`
 }
 
 // SessionUsage holds token and cost stats for a session.
@@ -24,6 +31,38 @@ type SessionUsage struct {
 	EstimatedCostUSD float64
 	FirstRequestAt   time.Time
 	LastRequestAt    time.Time
+
+	// ModelRequests counts requests by the model that actually served them,
+	// which differs from the configured one when a fallback kicked in.
+	ModelRequests map[string]int64
+
+	// Response cache hits and what they would have cost (not included in
+	// the totals above).
+	CacheHits        int64
+	CacheSavedTokens int64
+	CacheSavedUSD    float64
+
+	// Provider prompt caching: prompt tokens read from / written to the
+	// cache (included in PromptTokens) and the net saving versus paying
+	// the full input price for them.
+	PromptCacheReadTokens  int64
+	PromptCacheWriteTokens int64
+	PromptCacheSavedUSD    float64
+
+	// ToolDefTokensSaved estimates the prompt tokens that tool definition
+	// compression kept out of the requests (see tool_compression.go).
+	ToolDefTokensSaved int64
+}
+
+// countModel records a request served by model.
+func (su *SessionUsage) countModel(model string) {
+	if model == "" {
+		return
+	}
+	if su.ModelRequests == nil {
+		su.ModelRequests = make(map[string]int64)
+	}
+	su.ModelRequests[model]++
 }
 
 // UsageTracker records usage per session and globally.
@@ -33,22 +72,34 @@ type UsageTracker struct {
 	sessions   map[string]*SessionUsage
 	global     *SessionUsage
 	modelCosts map[string]ModelCost
+	registry   *ModelRegistry // models.yaml prices take precedence over modelCosts
+
+	// Per-user and per-workspace budgets (usage_budgets.go).
+	budgets     BudgetConfig
+	budgetUsage map[string]*budgetCounter
+	now         func() time.Time
+
+	// log persists every call to disk (nil = memory only; usage_log.go).
+	log *UsageLog
 
 	logger *slog.Logger
 }
 
 var defaultModelCosts = map[string]ModelCost{
 	// OpenAI
-	"gpt-4o":          {InputPer1M: 2.50, OutputPer1M: 10.00},
-	"gpt-4o-mini":     {InputPer1M: 0.15, OutputPer1M: 0.60},
-	"gpt-4.5-preview": {InputPer1M: 75.00, OutputPer1M: 150.00},
-	"gpt-5":           {InputPer1M: 2.00, OutputPer1M: 8.00},
-	"gpt-5-mini":      {InputPer1M: 0.15, OutputPer1M: 0.60},
-	// Anthropic
-	"claude-opus-4.6":   {InputPer1M: 5.00, OutputPer1M: 25.00},
-	"claude-opus-4.5":   {InputPer1M: 5.00, OutputPer1M: 25.00},
-	"claude-sonnet-4.5": {InputPer1M: 3.00, OutputPer1M: 15.00},
-	"claude-3.5-sonnet": {InputPer1M: 3.00, OutputPer1M: 15.00},
+	"gpt-4o":          {InputPer1M: 2.50, OutputPer1M: 10.00, CacheReadPer1M: 1.25},
+	"gpt-4o-mini":     {InputPer1M: 0.15, OutputPer1M: 0.60, CacheReadPer1M: 0.075},
+	"gpt-4.5-preview": {InputPer1M: 75.00, OutputPer1M: 150.00, CacheReadPer1M: 37.50},
+	"gpt-5":           {InputPer1M: 2.00, OutputPer1M: 8.00, CacheReadPer1M: 0.20},
+	"gpt-5-mini":      {InputPer1M: 0.15, OutputPer1M: 0.60, CacheReadPer1M: 0.015},
+	// Anthropic (cache reads 0.1×, 5-minute cache writes 1.25× input)
+	"claude-opus-4.6":   {InputPer1M: 5.00, OutputPer1M: 25.00, CacheReadPer1M: 0.50, CacheWritePer1M: 6.25},
+	"claude-opus-4.5":   {InputPer1M: 5.00, OutputPer1M: 25.00, CacheReadPer1M: 0.50, CacheWritePer1M: 6.25},
+	"claude-sonnet-4.5": {InputPer1M: 3.00, OutputPer1M: 15.00, CacheReadPer1M: 0.30, CacheWritePer1M: 3.75},
+	"claude-3.5-sonnet": {InputPer1M: 3.00, OutputPer1M: 15.00, CacheReadPer1M: 0.30, CacheWritePer1M: 3.75},
+	// Google Gemini (prompts up to 200K tokens)
+	"gemini-2.5-pro":   {InputPer1M: 1.25, OutputPer1M: 10.00, CacheReadPer1M: 0.31},
+	"gemini-2.5-flash": {InputPer1M: 0.30, OutputPer1M: 2.50, CacheReadPer1M: 0.075},
 	// GLM (Z.AI)
 	"glm-5":           {InputPer1M: 1.00, OutputPer1M: 3.20},
 	"glm-5-code":      {InputPer1M: 1.20, OutputPer1M: 5.00},
@@ -93,8 +144,22 @@ func (u *UsageTracker) initModelCosts() {
 	}
 }
 
+// SetModelRegistry sets the models.yaml registry whose prices override the
+// built-in ones.
+func (u *UsageTracker) SetModelRegistry(r *ModelRegistry) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.registry = r
+}
+
 // Record adds usage for a session and globally.
 func (u *UsageTracker) Record(sessionID, model string, usage LLMUsage) {
+	u.RecordFor(sessionID, "", "", model, usage)
+}
+
+// RecordFor is Record for a call whose workspace and user are known, so
+// the usage log can attribute it.
+func (u *UsageTracker) RecordFor(sessionID, workspaceID, user, model string, usage LLMUsage) {
 	u.init()
 	u.mu.Lock()
 	defer u.mu.Unlock()
@@ -113,24 +178,145 @@ func (u *UsageTracker) Record(sessionID, model string, usage LLMUsage) {
 	su.TotalTokens += int64(usage.TotalTokens)
 	su.Requests++
 	su.LastRequestAt = now
+	su.countModel(model)
 
-	cost := u.estimateCost(model, usage.PromptTokens, usage.CompletionTokens)
+	cost := u.estimateCost(model, usage)
 	su.EstimatedCostUSD += cost
+	saved := u.promptCacheSavings(model, usage)
+	su.addPromptCache(usage, saved)
 
 	// Global
 	u.global.PromptTokens += int64(usage.PromptTokens)
 	u.global.CompletionTokens += int64(usage.CompletionTokens)
 	u.global.TotalTokens += int64(usage.TotalTokens)
 	u.global.Requests++
+	u.global.countModel(model)
 	if u.global.FirstRequestAt.IsZero() {
 		u.global.FirstRequestAt = now
 	}
 	u.global.LastRequestAt = now
 	u.global.EstimatedCostUSD += cost
+	u.global.addPromptCache(usage, saved)
+
+	// Instance-wide counter behind the owner's monthly budget alerts.
+	all := u.budgetCounter(globalBudgetKey, u.clock())
+	tokens := int64(usage.PromptTokens + usage.CompletionTokens)
+	all.dayTokens += tokens
+	all.monthTokens += tokens
+	all.dayUSD += cost
+	all.monthUSD += cost
+
+	u.log.Append(UsageRecord{
+		Time:             now,
+		SessionID:        sessionID,
+		WorkspaceID:      workspaceID,
+		UserID:           user,
+		Model:            model,
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+		CacheReadTokens:  int64(usage.CacheReadTokens),
+		CacheWriteTokens: int64(usage.CacheWriteTokens),
+		CostUSD:          cost,
+	})
+}
+
+// RecordToolCompression adds the prompt tokens an LLM call saved through
+// compressed or lazily loaded tool definitions.
+func (u *UsageTracker) RecordToolCompression(sessionID string, tokens int) {
+	u.init()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	su, ok := u.sessions[sessionID]
+	if !ok {
+		su = &SessionUsage{}
+		u.sessions[sessionID] = su
+	}
+	su.ToolDefTokensSaved += int64(tokens)
+	u.global.ToolDefTokensSaved += int64(tokens)
+}
+
+// addPromptCache records the prompt cache part of a call.
+func (su *SessionUsage) addPromptCache(usage LLMUsage, saved float64) {
+	su.PromptCacheReadTokens += int64(usage.CacheReadTokens)
+	su.PromptCacheWriteTokens += int64(usage.CacheWriteTokens)
+	su.PromptCacheSavedUSD += saved
+}
+
+// RecordCacheHit counts a response served from the response cache; saved
+// is the usage of the original call. Hits are global: the cache is shared
+// by all sessions.
+func (u *UsageTracker) RecordCacheHit(model string, saved LLMUsage) {
+	u.init()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.initModelCosts()
+
+	u.global.CacheHits++
+	u.global.CacheSavedTokens += int64(saved.TotalTokens)
+	u.global.CacheSavedUSD += u.estimateCost(model, saved)
+}
+
+// EstimateCost returns the estimated USD cost of a single LLM call.
+func (u *UsageTracker) EstimateCost(model string, usage LLMUsage) float64 {
+	u.init()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.initModelCosts()
+	return u.estimateCost(model, usage)
+}
+
+// estimateCost prices a call, billing cached prompt tokens at the cache
+// prices.
+func (u *UsageTracker) estimateCost(model string, usage LLMUsage) float64 {
+	cost, ok := u.modelCost(model)
+	if !ok {
+		return 0
+	}
+	read, write := cost.cachePrices()
+	uncached := usage.PromptTokens - usage.CacheReadTokens - usage.CacheWriteTokens
+	return (float64(uncached)/1e6)*cost.InputPer1M +
+		(float64(usage.CacheReadTokens)/1e6)*read +
+		(float64(usage.CacheWriteTokens)/1e6)*write +
+		(float64(usage.CompletionTokens)/1e6)*cost.OutputPer1M
 }
 
-func (u *UsageTracker) estimateCost(model string, prompt, completion int) float64 {
-	cost, ok := u.modelCosts[model]
+// promptCacheSavings returns what prompt caching saved on a call compared
+// to full input prices (negative when cache writes cost more than reads
+// saved).
+func (u *UsageTracker) promptCacheSavings(model string, usage LLMUsage) float64 {
+	cost, ok := u.modelCost(model)
+	if !ok {
+		return 0
+	}
+	read, write := cost.cachePrices()
+	return (float64(usage.CacheReadTokens)/1e6)*(cost.InputPer1M-read) -
+		(float64(usage.CacheWriteTokens)/1e6)*(write-cost.InputPer1M)
+}
+
+// cachePrices returns the prompt cache read and write prices, defaulting to
+// the input price.
+func (c ModelCost) cachePrices() (read, write float64) {
+	read, write = c.CacheReadPer1M, c.CacheWritePer1M
+	if read <= 0 {
+		read = c.InputPer1M
+	}
+	if write <= 0 {
+		write = c.InputPer1M
+	}
+	return read, write
+}
+
+// modelCost returns the pricing of model from the registry, the configured
+// costs or a prefix match.
+func (u *UsageTracker) modelCost(model string) (ModelCost, bool) {
+	var cost ModelCost
+	info, ok := u.registry.Lookup(model)
+	if ok {
+		cost, ok = info.Cost()
+	}
+	if !ok {
+		cost, ok = u.modelCosts[model]
+	}
 	if !ok {
 		// Try prefix match for model variants (e.g. gpt-4o-2024-04-09)
 		for k, v := range u.modelCosts {
@@ -141,10 +327,7 @@ func (u *UsageTracker) estimateCost(model string, prompt, completion int) float6
 			}
 		}
 	}
-	if !ok {
-		return 0
-	}
-	return (float64(prompt)/1e6)*cost.InputPer1M + (float64(completion)/1e6)*cost.OutputPer1M
+	return cost, ok
 }
 
 // GetSession returns a copy of the session's usage stats, or nil if not found.
@@ -164,6 +347,12 @@ func (u *UsageTracker) GetSession(sessionID string) *SessionUsage {
 		EstimatedCostUSD: su.EstimatedCostUSD,
 		FirstRequestAt:   su.FirstRequestAt,
 		LastRequestAt:    su.LastRequestAt,
+		ModelRequests:    maps.Clone(su.ModelRequests),
+
+		PromptCacheReadTokens:  su.PromptCacheReadTokens,
+		PromptCacheWriteTokens: su.PromptCacheWriteTokens,
+		PromptCacheSavedUSD:    su.PromptCacheSavedUSD,
+		ToolDefTokensSaved:     su.ToolDefTokensSaved,
 	}
 }
 
@@ -181,6 +370,15 @@ func (u *UsageTracker) GetGlobal() *SessionUsage {
 		EstimatedCostUSD: g.EstimatedCostUSD,
 		FirstRequestAt:   g.FirstRequestAt,
 		LastRequestAt:    g.LastRequestAt,
+		ModelRequests:    maps.Clone(g.ModelRequests),
+		CacheHits:        g.CacheHits,
+		CacheSavedTokens: g.CacheSavedTokens,
+		CacheSavedUSD:    g.CacheSavedUSD,
+
+		PromptCacheReadTokens:  g.PromptCacheReadTokens,
+		PromptCacheWriteTokens: g.PromptCacheWriteTokens,
+		PromptCacheSavedUSD:    g.PromptCacheSavedUSD,
+		ToolDefTokensSaved:     g.ToolDefTokensSaved,
 	}
 }
 
@@ -208,7 +406,7 @@ func (u *UsageTracker) FormatGlobalUsage() string {
 
 func formatSessionUsage(label string, su *SessionUsage) string {
 	var b string
-	if su.Requests == 0 {
+	if su.Requests == 0 && su.CacheHits == 0 {
 		b = fmt.Sprintf("*Usage (%s)*\n\nNo requests yet.", label)
 		return b
 	}
@@ -218,6 +416,23 @@ func formatSessionUsage(label string, su *SessionUsage) string {
 	b += fmt.Sprintf("Total tokens: %d\n", su.TotalTokens)
 	b += fmt.Sprintf("Requests: %d\n", su.Requests)
 	b += fmt.Sprintf("Est. cost: $%.4f\n", su.EstimatedCostUSD)
+	if len(su.ModelRequests) > 1 {
+		models := slices.Sorted(maps.Keys(su.ModelRequests))
+		parts := make([]string, len(models))
+		for i, m := range models {
+			parts[i] = fmt.Sprintf("%s ×%d", m, su.ModelRequests[m])
+		}
+		b += "Models: " + strings.Join(parts, ", ") + "\n"
+	}
+	if su.CacheHits > 0 {
+		b += fmt.Sprintf("Cache hits: %d (saved %d tokens, $%.4f)\n", su.CacheHits, su.CacheSavedTokens, su.CacheSavedUSD)
+	}
+	if su.PromptCacheReadTokens > 0 || su.PromptCacheWriteTokens > 0 {
+		b += fmt.Sprintf("Prompt cache: %d read, %d written (saved $%.4f)\n", su.PromptCacheReadTokens, su.PromptCacheWriteTokens, su.PromptCacheSavedUSD)
+	}
+	if su.ToolDefTokensSaved > 0 {
+		b += fmt.Sprintf("Tool definitions: ~%d prompt tokens saved by compression\n", su.ToolDefTokensSaved)
+	}
 	if !su.FirstRequestAt.IsZero() {
 		b += fmt.Sprintf("First request: %s\n", su.FirstRequestAt.Format("2006-01-02 15:04"))
 	}
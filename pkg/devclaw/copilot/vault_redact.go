@@ -0,0 +1,118 @@
+// Package copilot – vault_redact.go keeps vault secrets out of what the
+// model sees. Vault secrets are exported to the environment for scripts, so
+// a tool can echo one back (env, a verbose curl, a config dump); tool
+// results are scanned for the stored values and each occurrence is replaced
+// with [vault:<name>] before the result goes to the LLM. vault_get sends
+// the value it reads straight to the chat and returns only a marker.
+package copilot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// shortSecretLen is the length below which a vault value is only redacted
+// as a whole word: a PIN such as 1234 must not eat the digits of a port or
+// a timestamp.
+const shortSecretLen = 6
+
+// Redact replaces the vault's secret values in s with [vault:<name>]. A
+// nil or locked vault returns s unchanged.
+func (v *Vault) Redact(s string) string {
+	if v == nil || s == "" {
+		return s
+	}
+	type secret struct{ name, value string }
+	var secrets []secret
+	for _, name := range v.List() {
+		value, err := v.Get(name)
+		if err != nil || value == "" {
+			continue
+		}
+		secrets = append(secrets, secret{name, value})
+	}
+	// Longest first, so a secret containing another is replaced whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i].value) > len(secrets[j].value) })
+	for _, sec := range secrets {
+		if !strings.Contains(s, sec.value) {
+			continue
+		}
+		placeholder := "[vault:" + sec.name + "]"
+		if len(sec.value) >= shortSecretLen {
+			s = strings.ReplaceAll(s, sec.value, placeholder)
+		} else {
+			s = replaceWord(s, sec.value, placeholder)
+		}
+	}
+	return s
+}
+
+// replaceWord replaces the occurrences of old in s that are not part of a
+// longer run of letters and digits.
+func replaceWord(s, old, repl string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i+len(old) <= len(s); {
+		j := strings.Index(s[i:], old)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(old)
+		if !wordByteAt(s, start-1) && !wordByteAt(s, end) {
+			b.WriteString(s[last:start])
+			b.WriteString(repl)
+			last = end
+			i = end
+			continue
+		}
+		i = start + 1
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// wordByteAt reports whether s[i] is an ASCII letter or digit.
+func wordByteAt(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// SetSecretSender sets the function vault_get delivers secrets with. It
+// must send the text to the chat without recording it in the session.
+func (e *ToolExecutor) SetSecretSender(fn func(ctx context.Context, target DeliveryTarget, text string) error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.secretSender = fn
+}
+
+// sendSecret delivers a secret to the chat of the current call.
+func (e *ToolExecutor) sendSecret(ctx context.Context, text string) error {
+	e.mu.RLock()
+	send := e.secretSender
+	e.mu.RUnlock()
+	target := DeliveryTargetFromContext(ctx)
+	if send == nil || target.Channel == "" {
+		return errNoSecretChat
+	}
+	return send(ctx, target, text)
+}
+
+// deliverSecret sends a secret read by vault_get to the chat. It bypasses
+// the output guardrail and the session history on purpose.
+func (a *Assistant) deliverSecret(_ context.Context, target DeliveryTarget, text string) error {
+	return a.channelMgr.Send(a.ctx, target.Channel, target.ChatID,
+		&channels.OutgoingMessage{Content: FormatForChannel(text, target.Channel)})
+}
+
+// errNoSecretChat is returned by vault_get outside a chat.
+var errNoSecretChat = errors.New("no chat to deliver the secret to; use 'devclaw vault get' instead")
@@ -1,9 +1,13 @@
 package copilot
 
 import (
+	"context"
+	"log/slog"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func newTestVault(t *testing.T) (*Vault, string) {
@@ -252,3 +256,83 @@ func TestVault_PersistenceAcrossInstances(t *testing.T) {
 		t.Errorf("persistence failed: got %q, want %q", val, "data123")
 	}
 }
+
+func TestVault_RedactToolOutput(t *testing.T) {
+	t.Parallel()
+	v, _ := newTestVault(t)
+	if err := v.Create("pass"); err != nil {
+		t.Fatal(err)
+	}
+	v.Set("github_token", "ghp_abcdef123456")
+	v.Set("pin", "1234")
+
+	exec := NewToolExecutor(slog.New(slog.DiscardHandler))
+	exec.SetOutputRedactor(v.Redact)
+	exec.Register(MakeToolDefinition("bash", "Run a command", map[string]any{"type": "object"}),
+		func(context.Context, map[string]any) (any, error) {
+			return "GITHUB_TOKEN=ghp_abcdef123456\nPIN=1234", nil
+		})
+	results := exec.Execute(context.Background(), []ToolCall{{ID: "1", Function: FunctionCall{Name: "bash", Arguments: "{}"}}})
+	if len(results) != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+	want := "GITHUB_TOKEN=[vault:github_token]\nPIN=[vault:pin]"
+	if results[0].Content != want {
+		t.Errorf("content = %q, want %q", results[0].Content, want)
+	}
+	// Short values are matched as whole words only.
+	if got := v.Redact("port 12345, pin 1234."); got != "port 12345, pin [vault:pin]." {
+		t.Errorf("short secret redaction = %q", got)
+	}
+
+	v.Lock()
+	if got := v.Redact("ghp_abcdef123456"); got != "ghp_abcdef123456" {
+		t.Errorf("locked vault redacted %q", got)
+	}
+}
+
+func TestVault_GetDeliversToChat(t *testing.T) {
+	t.Parallel()
+	v, _ := newTestVault(t)
+	if err := v.Create("pass"); err != nil {
+		t.Fatal(err)
+	}
+	v.Set("github_token", "ghp_abcdef123456")
+
+	exec := NewToolExecutor(slog.New(slog.DiscardHandler))
+	registerVaultTools(exec, v)
+	call := []ToolCall{{ID: "1", Function: FunctionCall{Name: "vault_get", Arguments: `{"name":"github_token"}`}}}
+
+	// Without a chat there is nowhere to deliver the value.
+	if res := exec.Execute(context.Background(), call); res[0].Error == nil || strings.Contains(res[0].Content, "ghp_") {
+		t.Errorf("vault_get without a chat = %+v", res[0])
+	}
+
+	var sent DeliveryTarget
+	var text string
+	exec.SetSecretSender(func(_ context.Context, target DeliveryTarget, msg string) error {
+		sent, text = target, msg
+		return nil
+	})
+	res := exec.Execute(ContextWithDelivery(context.Background(), "telegram", "42"), call)
+	if strings.Contains(res[0].Content, "ghp_") || !strings.Contains(res[0].Content, "delivered") {
+		t.Errorf("model saw %q", res[0].Content)
+	}
+	if sent.ChatID != "42" || !strings.Contains(text, "ghp_abcdef123456") {
+		t.Errorf("chat got %q at %+v", text, sent)
+	}
+}
+
+func TestApprovalManager_VaultGetIgnoresGrants(t *testing.T) {
+	t.Parallel()
+	m := NewApprovalManager(slog.New(slog.DiscardHandler))
+	m.SetConfig(ApprovalConfig{Timeout: 10 * time.Millisecond, OnTimeout: "approve"})
+	m.AddGrant(ApprovalGrant{Tool: "vault_get", Pattern: "*"})
+	m.AddGrant(ApprovalGrant{Tool: "vault_get", SessionID: "s1", Pattern: "*"})
+	m.GrantTrust("s1", "vault_get")
+
+	ok, _ := m.Request("s1", "owner@x", "vault_get", map[string]any{"name": "k"}, nil)
+	if ok {
+		t.Error("grants and timeouts must not release a vault secret")
+	}
+}
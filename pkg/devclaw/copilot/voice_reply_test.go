@@ -0,0 +1,131 @@
+package copilot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
+)
+
+// fakeMediaChannel records the media it is asked to send.
+type fakeMediaChannel struct {
+	*fakeEditChannel
+	mu    sync.Mutex
+	media []*channels.MediaMessage
+}
+
+func (f *fakeMediaChannel) SendMedia(_ context.Context, _ string, m *channels.MediaMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.media = append(f.media, m)
+	return nil
+}
+
+func (f *fakeMediaChannel) DownloadMedia(context.Context, *channels.IncomingMessage) ([]byte, string, error) {
+	return nil, "", errors.New("not supported")
+}
+
+// fakeTTS returns the text it was given as audio of the given MIME type.
+type fakeTTS struct {
+	mimeType string
+	err      error
+	voices   []string
+}
+
+func (f *fakeTTS) Synthesize(_ context.Context, text, voice string) ([]byte, string, error) {
+	f.voices = append(f.voices, voice)
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	return []byte(text), f.mimeType, nil
+}
+
+func TestWantsVoiceReply(t *testing.T) {
+	t.Parallel()
+	voice := &channels.IncomingMessage{Type: channels.MessageAudio}
+	text := &channels.IncomingMessage{Type: channels.MessageText}
+	cases := []struct {
+		name    string
+		enabled bool
+		tts     bool
+		msg     *channels.IncomingMessage
+		want    bool
+	}{
+		{"voice note", true, true, voice, true},
+		{"text message", true, true, text, false},
+		{"disabled", false, true, voice, false},
+		{"no tts provider", true, false, voice, false},
+	}
+	for _, tc := range cases {
+		a := &Assistant{config: DefaultConfig()}
+		a.config.Media.VoiceReplies = tc.enabled
+		if tc.tts {
+			a.ttsProvider = &fakeTTS{}
+		}
+		if got := a.wantsVoiceReply(tc.msg); got != tc.want {
+			t.Errorf("%s: wantsVoiceReply = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSendVoiceReply(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		response string
+		tts      *fakeTTS
+		wantSent bool
+		wantFile string
+		wantText string
+	}{
+		{"spoken", "**Deploy** finished in 3 minutes.", &fakeTTS{mimeType: "audio/ogg"}, true, "response.ogg", "Deploy finished in 3 minutes."},
+		{"piper wav", "All good.", &fakeTTS{mimeType: "audio/wav"}, true, "response.wav", "All good."},
+		{"mp3", "All good.", &fakeTTS{mimeType: "audio/mpeg"}, true, "response.mp3", "All good."},
+		{"code block", "Run:\n```\nmake deploy\n```", &fakeTTS{}, false, "", ""},
+		{"too long", strings.Repeat("word ", 1000), &fakeTTS{}, false, "", ""},
+		{"no reply token", " NO_REPLY ", &fakeTTS{}, false, "", ""},
+		{"synthesis fails", "All good.", &fakeTTS{err: errors.New("quota")}, false, "", ""},
+	}
+	for _, tc := range cases {
+		ch := &fakeMediaChannel{fakeEditChannel: &fakeEditChannel{messages: map[string]string{}}}
+		mgr := channels.NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err := mgr.Register(ch); err != nil {
+			t.Fatal(err)
+		}
+		a := &Assistant{
+			ctx:         context.Background(),
+			config:      DefaultConfig(),
+			channelMgr:  mgr,
+			ttsProvider: tc.tts,
+			logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		a.config.TTS.Voice = "nova"
+		msg := &channels.IncomingMessage{ID: "m1", Channel: "fake", ChatID: "chat", Type: channels.MessageAudio}
+
+		if got := a.sendVoiceReply(msg, tc.response); got != tc.wantSent {
+			t.Errorf("%s: sent = %v, want %v", tc.name, got, tc.wantSent)
+			continue
+		}
+		if !tc.wantSent {
+			if len(ch.media) != 0 {
+				t.Errorf("%s: audio sent for a text fallback", tc.name)
+			}
+			continue
+		}
+		if len(ch.media) != 1 {
+			t.Fatalf("%s: sent %d media messages", tc.name, len(ch.media))
+		}
+		m := ch.media[0]
+		if m.Type != channels.MessageAudio || m.Filename != tc.wantFile || m.MimeType != tc.tts.mimeType || m.ReplyTo != "m1" {
+			t.Errorf("%s: media = %+v", tc.name, m)
+		}
+		if string(m.Data) != tc.wantText || tc.tts.voices[0] != "nova" {
+			t.Errorf("%s: spoke %q with voice %v", tc.name, m.Data, tc.tts.voices)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package copilot – warmup.go implements the optional cold-start warmup
+// phase. Right after Start, it preflights the LLM endpoint (establishing the
+// pooled TLS connection), caches the static prompt layers and the bootstrap
+// files, and loads the memory and document corpus indexes so the first real
+// message doesn't pay for all of it. Progress is logged and exposed via Readiness() for /health.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WarmupConfig configures the startup warmup phase.
+type WarmupConfig struct {
+	// Enabled runs the warmup steps in the background after startup
+	// (default: false).
+	Enabled bool `yaml:"enabled"`
+
+	// TimeoutSeconds bounds the whole warmup phase (default: 30).
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// DefaultWarmupConfig returns defaults for the warmup phase.
+func DefaultWarmupConfig() WarmupConfig {
+	return WarmupConfig{
+		Enabled:        false,
+		TimeoutSeconds: 30,
+	}
+}
+
+// Readiness states reported by Readiness().
+const (
+	ReadinessPending  = "pending"
+	ReadinessWarming  = "warming"
+	ReadinessReady    = "ready"
+	ReadinessDegraded = "degraded"
+	ReadinessSkipped  = "skipped"
+)
+
+// WarmupStep is the outcome of a single warmup step.
+type WarmupStep struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	DurationMs int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WarmupReport summarizes the warmup phase.
+type WarmupReport struct {
+	State      string       `json:"state"`
+	StartedAt  time.Time    `json:"started_at,omitempty"`
+	FinishedAt time.Time    `json:"finished_at,omitempty"`
+	Steps      []WarmupStep `json:"steps,omitempty"`
+}
+
+// Ready reports whether the assistant can serve requests at full speed.
+// A degraded warmup still counts as ready; the failing step is reported.
+func (r WarmupReport) Ready() bool {
+	return r.State == ReadinessReady || r.State == ReadinessDegraded || r.State == ReadinessSkipped
+}
+
+// warmupState tracks the report while warmup runs.
+type warmupState struct {
+	mu     sync.RWMutex
+	report WarmupReport
+}
+
+func (w *warmupState) set(fn func(r *WarmupReport)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(&w.report)
+}
+
+func (w *warmupState) get() WarmupReport {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	r := w.report
+	r.Steps = append([]WarmupStep(nil), w.report.Steps...)
+	if r.State == "" {
+		r.State = ReadinessPending
+	}
+	return r
+}
+
+// Readiness returns the current warmup report.
+func (a *Assistant) Readiness() WarmupReport {
+	return a.warmup.get()
+}
+
+// runWarmup executes the warmup steps sequentially. Failures are logged and
+// recorded but never stop startup.
+func (a *Assistant) runWarmup() {
+	cfg := a.config.Warmup
+	if !cfg.Enabled {
+		a.warmup.set(func(r *WarmupReport) { r.State = ReadinessSkipped })
+		return
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	a.warmup.set(func(r *WarmupReport) {
+		r.State = ReadinessWarming
+		r.StartedAt = start
+	})
+
+	steps := []struct {
+		name string
+		fn   func(ctx context.Context) (string, error)
+	}{
+		{"llm_preflight", a.llmClient.Preflight},
+		{"prompt_layers", func(context.Context) (string, error) {
+			return a.promptComposer.Warmup(), nil
+		}},
+		{"memory_index", a.warmMemoryIndex},
+		{"corpus_index", a.warmCorpusIndex},
+	}
+
+	failed := 0
+	for _, s := range steps {
+		stepStart := time.Now()
+		detail, err := s.fn(ctx)
+		step := WarmupStep{
+			Name:       s.name,
+			OK:         err == nil,
+			DurationMs: time.Since(stepStart).Milliseconds(),
+			Detail:     detail,
+		}
+		if err != nil {
+			failed++
+			step.Error = err.Error()
+			a.logger.Warn("warmup step failed", "step", s.name, "error", err)
+		} else {
+			a.logger.Debug("warmup step done", "step", s.name, "duration_ms", step.DurationMs, "detail", detail)
+		}
+		a.warmup.set(func(r *WarmupReport) { r.Steps = append(r.Steps, step) })
+	}
+
+	state := ReadinessReady
+	if failed > 0 {
+		state = ReadinessDegraded
+	}
+	a.warmup.set(func(r *WarmupReport) {
+		r.State = state
+		r.FinishedAt = time.Now()
+	})
+	a.logger.Info("warmup complete",
+		"state", state,
+		"failed_steps", failed,
+		"duration", time.Since(start).Round(time.Millisecond),
+	)
+}
+
+// warmMemoryIndex runs a cheap keyword query so the SQLite memory index and
+// its FTS tables are paged in before the first real search.
+func (a *Assistant) warmMemoryIndex(_ context.Context) (string, error) {
+	if a.sqliteMemory == nil {
+		return "no sqlite memory", nil
+	}
+	if _, err := a.sqliteMemory.SearchBM25("warmup", 1); err != nil {
+		return "", err
+	}
+	return "fts ready", nil
+}
+
+// warmCorpusIndex loads the registered document corpora so the first
+// corpus_search doesn't parse the indexes.
+func (a *Assistant) warmCorpusIndex(_ context.Context) (string, error) {
+	if a.corpora == nil {
+		return "corpora disabled", nil
+	}
+	n, chunks, err := a.corpora.Preload()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d corpora, %d chunks", n, chunks), nil
+}
+
+// Preflight opens a connection to the LLM endpoint and checks that the API
+// key is accepted, using the same pooled HTTP client as completions so the
+// TLS session is reused by the first real request. It lists models, which
+// costs no tokens.
+func (c *LLMClient) Preflight(ctx context.Context) (string, error) {
+	endpoint := c.baseURL + "/models"
+	if c.isAnthropicAPI() {
+		endpoint = c.baseURL + "/v1/models"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	if c.isAnthropicAPI() {
+		req.Header.Set("anthropic-version", "2023-06-01")
+		if c.provider == "zai-anthropic" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		} else {
+			req.Header.Set("x-api-key", c.apiKey)
+		}
+	} else if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	c.setProviderHeaders(req)
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("connecting to %s: %w", c.provider, err)
+	}
+	resp.Body.Close()
+
+	latency := time.Since(start).Round(time.Millisecond)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", fmt.Errorf("%s rejected the API key (%s)", c.provider, resp.Status)
+	case resp.StatusCode >= 500:
+		return "", fmt.Errorf("%s returned %s", c.provider, resp.Status)
+	}
+	// 404 and friends are fine: some compatible endpoints don't list models,
+	// but the connection is established.
+	return fmt.Sprintf("%s reachable in %s", c.provider, latency), nil
+}
+
+// Warmup builds the static prompt layers into the layer cache and primes the
+// bootstrap file cache, returning a short description of what was prepared.
+func (p *PromptComposer) Warmup() string {
+	static := p.coreLayer() + p.safetyLayer() + p.runtimeLayer()
+	bootstrap := p.buildBootstrapLayer("")
+	return fmt.Sprintf("static ~%d tokens, bootstrap ~%d tokens", estimateTokens(static), estimateTokens(bootstrap))
+}
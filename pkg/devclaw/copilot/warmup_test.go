@@ -0,0 +1,75 @@
+package copilot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPromptComposerWarmupCachesStaticLayers(t *testing.T) {
+	t.Parallel()
+	cfg := DefaultConfig()
+	cfg.Name = "Warm"
+	p := NewPromptComposer(cfg)
+
+	if detail := p.Warmup(); !strings.Contains(detail, "static ~") {
+		t.Errorf("detail = %q", detail)
+	}
+	for _, layer := range []string{"core", "safety", "runtime"} {
+		if p.getCachedLayer(staticLayerKey, layer) == "" {
+			t.Errorf("%s layer not cached by Warmup", layer)
+		}
+	}
+
+	// Compose serves the cached layers.
+	p.setCachedLayer(staticLayerKey, "core", "cached core layer")
+	if got := p.ComposeMinimal(); !strings.Contains(got, "cached core layer") {
+		t.Error("ComposeMinimal rebuilt the core layer instead of using the cache")
+	}
+}
+
+func TestAssistantWarmup(t *testing.T) {
+	if DefaultWarmupConfig().Enabled {
+		t.Error("warmup should be off by default")
+	}
+
+	docs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(docs, "runbook.md"), []byte("Restart the queue worker first."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	corpusDir := t.TempDir()
+	if _, _, err := NewCorpusStore(CorpusConfig{Dir: corpusDir}, nil).Add("ops", docs, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newE2EHarness(t, func(cfg *Config) {
+		cfg.Warmup.Enabled = true
+		cfg.Corpora.Dir = corpusDir
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !h.A.Readiness().Ready() {
+		if time.Now().After(deadline) {
+			t.Fatalf("warmup did not finish: %+v", h.A.Readiness())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	steps := map[string]WarmupStep{}
+	for _, s := range h.A.Readiness().Steps {
+		steps[s.Name] = s
+	}
+	for _, name := range []string{"llm_preflight", "prompt_layers", "memory_index", "corpus_index"} {
+		if s, ok := steps[name]; !ok || !s.OK {
+			t.Errorf("step %s = %+v", name, s)
+		}
+	}
+	if got := steps["corpus_index"].Detail; got != "1 corpora, 1 chunks" {
+		t.Errorf("corpus_index detail = %q", got)
+	}
+	if h.A.promptComposer.getCachedLayer(staticLayerKey, "core") == "" {
+		t.Error("warmup did not cache the core prompt layer")
+	}
+}
@@ -0,0 +1,420 @@
+// Package copilot – watch_command.go implements command output watchers.
+// A watch re-runs a command in the sandbox on an interval until its output
+// matches a condition or the watch expires, then notifies the chat it was
+// started from. Watches run in the background, so "tell me when the deploy
+// pod is Ready" does not tie up an agent run.
+package copilot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+const (
+	defaultWatchInterval    = 30 * time.Second
+	minWatchInterval        = 5 * time.Second
+	defaultWatchMaxDuration = 30 * time.Minute
+	maxWatchMaxDuration     = 24 * time.Hour
+	maxActiveWatches        = 20
+	watchOutputTail         = 1500
+)
+
+// WatchStatus is the lifecycle state of a command watch.
+type WatchStatus string
+
+const (
+	WatchStatusRunning   WatchStatus = "running"
+	WatchStatusMatched   WatchStatus = "matched"
+	WatchStatusExpired   WatchStatus = "expired"
+	WatchStatusCancelled WatchStatus = "cancelled"
+)
+
+// CommandWatch is a single background watch.
+type CommandWatch struct {
+	ID          string        `json:"id"`
+	Label       string        `json:"label,omitempty"`
+	Command     string        `json:"command"`
+	Condition   string        `json:"condition,omitempty"`
+	Interval    time.Duration `json:"interval"`
+	MaxDuration time.Duration `json:"max_duration"`
+	Channel     string        `json:"channel"`
+	ChatID      string        `json:"chat_id"`
+	Status      WatchStatus   `json:"status"`
+	Checks      int           `json:"checks"`
+	LastExit    int           `json:"last_exit_code"`
+	LastOutput  string        `json:"last_output,omitempty"`
+	StartedAt   time.Time     `json:"started_at"`
+	EndedAt     time.Time     `json:"ended_at,omitempty"`
+
+	re     *regexp.Regexp
+	cancel context.CancelFunc
+}
+
+// WatchNotifier delivers a watch result to a chat.
+type WatchNotifier func(channel, chatID, message string)
+
+// CommandWatcher runs and tracks command watches.
+type CommandWatcher struct {
+	runner *sandbox.Runner
+	notify WatchNotifier
+	logger *slog.Logger
+	ctx    context.Context
+
+	mu      sync.Mutex
+	watches map[string]*CommandWatch
+}
+
+// NewCommandWatcher creates a watcher. Watches stop when ctx is cancelled.
+func NewCommandWatcher(ctx context.Context, runner *sandbox.Runner, notify WatchNotifier, logger *slog.Logger) *CommandWatcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CommandWatcher{
+		runner:  runner,
+		notify:  notify,
+		logger:  logger.With("component", "watch"),
+		ctx:     ctx,
+		watches: make(map[string]*CommandWatch),
+	}
+}
+
+// Start begins a new watch. An empty condition means "exit code 0".
+func (w *CommandWatcher) Start(label, command, condition string, interval, maxDuration time.Duration, channel, chatID string) (*CommandWatch, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	if channel == "" || chatID == "" {
+		return nil, fmt.Errorf("watch_command needs a chat to notify; not available in this context")
+	}
+	var re *regexp.Regexp
+	if condition != "" {
+		var err error
+		if re, err = regexp.Compile(condition); err != nil {
+			return nil, fmt.Errorf("invalid condition regex: %w", err)
+		}
+	}
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+	if maxDuration <= 0 {
+		maxDuration = defaultWatchMaxDuration
+	}
+	if maxDuration > maxWatchMaxDuration {
+		maxDuration = maxWatchMaxDuration
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	active := 0
+	for _, cw := range w.watches {
+		if cw.Status == WatchStatusRunning {
+			active++
+		}
+	}
+	if active >= maxActiveWatches {
+		return nil, fmt.Errorf("too many active watches (%d), cancel one first", active)
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, maxDuration)
+	cw := &CommandWatch{
+		ID:          uuid.New().String()[:8],
+		Label:       label,
+		Command:     command,
+		Condition:   condition,
+		Interval:    interval,
+		MaxDuration: maxDuration,
+		Channel:     channel,
+		ChatID:      chatID,
+		Status:      WatchStatusRunning,
+		StartedAt:   time.Now(),
+		re:          re,
+		cancel:      cancel,
+	}
+	w.watches[cw.ID] = cw
+	go w.loop(ctx, cw)
+
+	w.logger.Info("watch started", "id", cw.ID, "command", command, "interval", interval, "max_duration", maxDuration)
+	return cw, nil
+}
+
+// Cancel stops a running watch without notifying.
+func (w *CommandWatcher) Cancel(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	cw, ok := w.watches[id]
+	if !ok {
+		return fmt.Errorf("watch %q not found", id)
+	}
+	if cw.Status != WatchStatusRunning {
+		return fmt.Errorf("watch %q is not running (status: %s)", id, cw.Status)
+	}
+	cw.Status = WatchStatusCancelled
+	cw.EndedAt = time.Now()
+	cw.cancel()
+	return nil
+}
+
+// List returns snapshots of the watches started from the given chat (all
+// watches if channel is empty), newest first.
+func (w *CommandWatcher) List(channel, chatID string) []CommandWatch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []CommandWatch
+	for _, cw := range w.watches {
+		if channel != "" && (cw.Channel != channel || cw.ChatID != chatID) {
+			continue
+		}
+		snap := *cw
+		snap.re, snap.cancel = nil, nil
+		out = append(out, snap)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.After(out[j].StartedAt) })
+	return out
+}
+
+func (w *CommandWatcher) loop(ctx context.Context, cw *CommandWatch) {
+	defer cw.cancel()
+
+	ticker := time.NewTicker(cw.Interval)
+	defer ticker.Stop()
+
+	for {
+		if w.check(ctx, cw) {
+			w.finish(cw, WatchStatusMatched)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			// Expired, cancelled, or shutting down. Only expiry notifies.
+			if w.ctx.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+				w.finish(cw, WatchStatusExpired)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runCommand runs a shell command in the sandbox. The runner executes script
+// files (isolation policies stat them and containers mount them), so the
+// command is written to a script in a temporary skill directory.
+func (w *CommandWatcher) runCommand(ctx context.Context, command string) (*sandbox.ExecResult, error) {
+	dir, err := os.MkdirTemp("", "devclaw-watch-")
+	if err != nil {
+		return nil, fmt.Errorf("creating watch script dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "check.sh")
+	if err := os.WriteFile(script, []byte(command+"\n"), 0o700); err != nil {
+		return nil, fmt.Errorf("writing watch script: %w", err)
+	}
+	return w.runner.RunShell(ctx, script, nil, dir)
+}
+
+// check runs the command once and reports whether the condition holds.
+func (w *CommandWatcher) check(ctx context.Context, cw *CommandWatch) bool {
+	runCtx, cancel := context.WithTimeout(ctx, cw.Interval)
+	defer cancel()
+
+	output := ""
+	exit := -1
+	result, err := w.runCommand(runCtx, cw.Command)
+	if err != nil {
+		output = "error: " + err.Error()
+	} else {
+		exit = result.ExitCode
+		output = result.Stdout
+		if result.Stderr != "" {
+			output += "\n" + result.Stderr
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cw.Status != WatchStatusRunning {
+		return false
+	}
+	cw.Checks++
+	cw.LastExit = exit
+	// Trim so anchored conditions like "Ready$" work on newline-terminated output.
+	output = strings.TrimSpace(output)
+	cw.LastOutput = tailString(output, watchOutputTail)
+
+	if err != nil {
+		return false
+	}
+	if cw.re != nil {
+		return cw.re.MatchString(output)
+	}
+	return exit == 0
+}
+
+func (w *CommandWatcher) finish(cw *CommandWatch, status WatchStatus) {
+	w.mu.Lock()
+	if cw.Status != WatchStatusRunning {
+		w.mu.Unlock()
+		return
+	}
+	cw.Status = status
+	cw.EndedAt = time.Now()
+	snap := *cw
+	w.mu.Unlock()
+
+	w.logger.Info("watch finished", "id", snap.ID, "status", status, "checks", snap.Checks)
+	if w.notify == nil {
+		return
+	}
+
+	name := snap.Label
+	if name == "" {
+		name = snap.Command
+	}
+	var msg string
+	switch status {
+	case WatchStatusMatched:
+		cond := "exit code 0"
+		if snap.Condition != "" {
+			cond = fmt.Sprintf("`%s`", snap.Condition)
+		}
+		msg = fmt.Sprintf("👀 Watch **%s** matched %s after %s (%d checks).",
+			name, cond, snap.EndedAt.Sub(snap.StartedAt).Round(time.Second), snap.Checks)
+	case WatchStatusExpired:
+		msg = fmt.Sprintf("⏱️ Watch **%s** expired after %s without matching (%d checks, last exit code %d).",
+			name, snap.MaxDuration.Round(time.Second), snap.Checks, snap.LastExit)
+	default:
+		return
+	}
+	if snap.LastOutput != "" {
+		msg += "\n\n```\n" + snap.LastOutput + "\n```"
+	}
+	w.notify(snap.Channel, snap.ChatID, msg)
+}
+
+// tailString keeps the last n bytes of s, prefixed with an ellipsis marker.
+func tailString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 && i < len(s)-1 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
+
+// ---------- Tool Registration ----------
+
+// RegisterWatchTools registers watch_command, watch_list and watch_cancel.
+func RegisterWatchTools(executor *ToolExecutor, w *CommandWatcher) {
+	executor.Register(
+		MakeToolDefinition("watch_command",
+			"Run a command periodically in the sandbox and notify this chat when its output matches a condition "+
+				"(or the watch expires). Returns immediately — use it for 'tell me when X is ready' instead of polling in a loop. "+
+				"Without a condition the watch succeeds when the command exits 0.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"command": map[string]any{
+						"type":        "string",
+						"description": "Shell command to run on each check (e.g. 'kubectl get pod deploy-x -o jsonpath={.status.phase}')",
+					},
+					"condition": map[string]any{
+						"type":        "string",
+						"description": "Regex matched against stdout+stderr; the watch succeeds on the first match (e.g. 'Running|Ready')",
+					},
+					"interval_seconds": map[string]any{
+						"type":        "integer",
+						"description": "Seconds between checks (default: 30, min: 5)",
+					},
+					"max_duration_minutes": map[string]any{
+						"type":        "integer",
+						"description": "Give up and notify after this many minutes (default: 30, max: 1440)",
+					},
+					"label": map[string]any{
+						"type":        "string",
+						"description": "Short name used in the notification",
+					},
+				},
+				"required": []string{"command"},
+			}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			command, _ := args["command"].(string)
+			condition, _ := args["condition"].(string)
+			label, _ := args["label"].(string)
+			interval := time.Duration(0)
+			if v, ok := args["interval_seconds"].(float64); ok {
+				interval = time.Duration(v) * time.Second
+			}
+			maxDuration := time.Duration(0)
+			if v, ok := args["max_duration_minutes"].(float64); ok {
+				maxDuration = time.Duration(v) * time.Minute
+			}
+
+			dt := DeliveryTargetFromContext(ctx)
+			cw, err := w.Start(label, command, condition, interval, maxDuration, dt.Channel, dt.ChatID)
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("Watch %s started: checking every %s for up to %s. The chat will be notified when it matches or expires.",
+				cw.ID, cw.Interval, cw.MaxDuration), nil
+		},
+	)
+
+	executor.Register(
+		MakeToolDefinition("watch_list", "List command watches started from this chat with their status, check count and last output.", map[string]any{
+			"type":       "object",
+			"properties": map[string]any{},
+		}),
+		func(ctx context.Context, _ map[string]any) (any, error) {
+			dt := DeliveryTargetFromContext(ctx)
+			if dt.Channel == "" {
+				return "No watches.", nil
+			}
+			watches := w.List(dt.Channel, dt.ChatID)
+			if len(watches) == 0 {
+				return "No watches.", nil
+			}
+			data, _ := json.MarshalIndent(watches, "", "  ")
+			return string(data), nil
+		},
+	)
+
+	executor.Register(
+		MakeToolDefinition("watch_cancel", "Cancel a running command watch.", map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id": map[string]any{"type": "string", "description": "Watch ID returned by watch_command"},
+			},
+			"required": []string{"id"},
+		}),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			id, _ := args["id"].(string)
+			dt := DeliveryTargetFromContext(ctx)
+			for _, cw := range w.List(dt.Channel, dt.ChatID) {
+				if cw.ID == id {
+					if err := w.Cancel(id); err != nil {
+						return nil, err
+					}
+					return fmt.Sprintf("Watch %s cancelled.", id), nil
+				}
+			}
+			return nil, fmt.Errorf("watch %q not found", id)
+		},
+	)
+}
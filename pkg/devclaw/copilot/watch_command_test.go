@@ -0,0 +1,101 @@
+package copilot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+func newTestWatcher(t *testing.T) (*CommandWatcher, chan string) {
+	t.Helper()
+	// Default (restricted) isolation, whose policy validates script paths.
+	cfg := sandbox.DefaultConfig()
+	cfg.TempDir = t.TempDir()
+	runner, err := sandbox.NewRunner(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	got := make(chan string, 4)
+	w := NewCommandWatcher(ctx, runner, func(channel, chatID, msg string) {
+		got <- channel + ":" + chatID + " " + msg
+	}, nil)
+	return w, got
+}
+
+func TestCommandWatcher_NotifiesOnMatch(t *testing.T) {
+	w, got := newTestWatcher(t)
+
+	cw, err := w.Start("pod", "echo 'pod/api Ready'", "Ready$", 0, time.Minute, "telegram", "42")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if !strings.HasPrefix(msg, "telegram:42 ") || !strings.Contains(msg, "matched") {
+			t.Errorf("unexpected notification: %q", msg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	list := w.List("telegram", "42")
+	if len(list) != 1 || list[0].ID != cw.ID || list[0].Status != WatchStatusMatched {
+		t.Errorf("List = %+v", list)
+	}
+}
+
+func TestCommandWatcher_ExpiresWithoutMatch(t *testing.T) {
+	w, got := newTestWatcher(t)
+
+	if _, err := w.Start("", "echo pending", "Ready", 0, 300*time.Millisecond, "discord", "c1"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if !strings.Contains(msg, "expired") || !strings.Contains(msg, "pending") {
+			t.Errorf("unexpected notification: %q", msg)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no notification")
+	}
+}
+
+func TestCommandWatcher_CancelDoesNotNotify(t *testing.T) {
+	w, got := newTestWatcher(t)
+
+	cw, err := w.Start("", "false", "", 0, time.Minute, "whatsapp", "u1")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := w.Cancel(cw.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		t.Errorf("unexpected notification after cancel: %q", msg)
+	case <-time.After(500 * time.Millisecond):
+	}
+	if list := w.List("", ""); list[0].Status != WatchStatusCancelled {
+		t.Errorf("status = %s, want cancelled", list[0].Status)
+	}
+}
+
+func TestCommandWatcher_RejectsInvalidInput(t *testing.T) {
+	w, _ := newTestWatcher(t)
+
+	if _, err := w.Start("", "echo hi", "(", 0, 0, "telegram", "1"); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	if _, err := w.Start("", "echo hi", "", 0, 0, "", ""); err == nil {
+		t.Error("expected error without a chat to notify")
+	}
+}
@@ -57,10 +57,32 @@ type Workspace struct {
 	// Empty = use global default.
 	Trigger string `yaml:"trigger"`
 
-	// Skills lists the skills available in this workspace.
+	// Triggers overrides the global trigger rules for this workspace.
+	// Nil = use global default.
+	Triggers *TriggerConfig `yaml:"triggers,omitempty"`
+
+	// Skills lists the skills available in this workspace. Tools of other
+	// skills are left out of the workspace's tool registry.
 	// Empty = use all globally enabled skills.
 	Skills []string `yaml:"skills"`
 
+	// Tools lists the tools the workspace's agent can use (names or
+	// "group:..." references). Empty = every registered tool.
+	Tools []string `yaml:"tools,omitempty"`
+
+	// DeniedTools removes tools from the workspace's set.
+	DeniedTools []string `yaml:"denied_tools,omitempty"`
+
+	// DelegateTo lists the workspaces whose agent this workspace's agent
+	// may hand tasks to with delegate_to_workspace. Empty = none.
+	DelegateTo []string `yaml:"delegate_to,omitempty"`
+
+	// PromptDir holds the persona's bootstrap files (SOUL.md, AGENTS.md,
+	// IDENTITY.md, USER.md, TOOLS.md, MEMORY.md). Files found there take
+	// the place of the global ones; missing ones fall back to them.
+	// Empty = the global bootstrap files only.
+	PromptDir string `yaml:"prompt_dir,omitempty"`
+
 	// TokenBudget overrides token limits for this workspace.
 	// Nil = use global defaults.
 	TokenBudget *TokenBudgetConfig `yaml:"token_budget,omitempty"`
@@ -69,6 +91,32 @@ type Workspace struct {
 	// 0 = use global default.
 	MaxMessages int `yaml:"max_messages"`
 
+	// Env sets environment variables for tool and script runs resolved to
+	// this workspace, layered over the env file.
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// EnvFile is a dotenv file layered over the base environment for runs
+	// resolved to this workspace.
+	// Empty = <env_dir>/<id>/workspace.env, if present.
+	EnvFile string `yaml:"env_file,omitempty"`
+
+	// DND is a do-not-disturb window ("HH:MM-HH:MM" in the workspace's
+	// timezone, may wrap midnight). Heartbeat messages to the workspace's
+	// chats are held during it and delivered when it ends.
+	DND string `yaml:"dnd,omitempty"`
+
+	// Review holds the agent's replies to non-admins until an owner or
+	// admin approves, edits or rejects them (customer-facing workspaces).
+	Review ReviewConfig `yaml:"review,omitempty"`
+
+	// Redaction replaces PII in the workspace's LLM calls with placeholders
+	// restored in the replies (see redaction.go).
+	Redaction RedactionConfig `yaml:"redaction,omitempty"`
+
+	// Features turns risky capabilities on or off for this workspace,
+	// overriding workspaces.features (see workspace_features.go).
+	Features FeatureFlags `yaml:"features,omitempty"`
+
 	// Members lists the user JIDs assigned to this workspace.
 	Members []string `yaml:"members"`
 
@@ -92,12 +140,20 @@ type WorkspaceConfig struct {
 
 	// Workspaces is the list of defined workspaces.
 	Workspaces []Workspace `yaml:"workspaces"`
+
+	// EnvDir holds per-workspace env files (<env_dir>/<id>/workspace.env)
+	// for workspaces without an explicit env_file (default: ./workspaces).
+	EnvDir string `yaml:"env_dir"`
+
+	// Features are the capability flags of workspaces that set none.
+	Features FeatureFlags `yaml:"features,omitempty"`
 }
 
 // DefaultWorkspaceConfig returns a minimal workspace configuration.
 func DefaultWorkspaceConfig() WorkspaceConfig {
 	return WorkspaceConfig{
 		DefaultWorkspace: "default",
+		EnvDir:           "./workspaces",
 		Workspaces: []Workspace{
 			{
 				ID:          "default",
@@ -132,9 +188,21 @@ type WorkspaceManager struct {
 	// persistence is propagated to all workspace session stores.
 	persistence SessionPersister
 
+	// retention is propagated to all workspace session stores.
+	retention time.Duration
+
+	// pruneHook is propagated to all workspace session stores.
+	pruneHook func(*Session)
+
+	// events records admin changes in the state event log (nil = off).
+	events *StateEventLog
+
 	// defaultWSID is the fallback workspace ID.
 	defaultWSID string
 
+	// envDir holds per-workspace env files.
+	envDir string
+
 	mu sync.RWMutex
 }
 
@@ -152,6 +220,7 @@ func NewWorkspaceManager(globalCfg *Config, wsCfg WorkspaceConfig, logger *slog.
 		groupMap:    make(map[string]string),
 		sessions:    make(map[string]*SessionStore),
 		defaultWSID: wsCfg.DefaultWorkspace,
+		envDir:      wsCfg.EnvDir,
 	}
 
 	// Load workspaces from config.
@@ -160,9 +229,7 @@ func NewWorkspaceManager(globalCfg *Config, wsCfg WorkspaceConfig, logger *slog.
 		wm.workspaces[ws.ID] = ws
 
 		// Create isolated session store for this workspace.
-		wm.sessions[ws.ID] = NewSessionStore(
-			logger.With("workspace", ws.ID),
-		)
+		wm.sessions[ws.ID] = wm.newSessionStore(ws.ID)
 
 		// Map members to workspace.
 		for _, jid := range ws.Members {
@@ -180,9 +247,7 @@ func NewWorkspaceManager(globalCfg *Config, wsCfg WorkspaceConfig, logger *slog.
 			Name:   "Default",
 			Active: true,
 		}
-		wm.sessions[wm.defaultWSID] = NewSessionStore(
-			logger.With("workspace", wm.defaultWSID),
-		)
+		wm.sessions[wm.defaultWSID] = wm.newSessionStore(wm.defaultWSID)
 	}
 
 	wm.logger.Info("workspace manager initialized",
@@ -205,6 +270,67 @@ func (wm *WorkspaceManager) SetPersistence(p SessionPersister) {
 	}
 }
 
+// SetSessionRetention propagates the persisted-session retention period to
+// all workspace session stores (0 = keep forever).
+func (wm *WorkspaceManager) SetSessionRetention(d time.Duration) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.retention = d
+	for _, store := range wm.sessions {
+		store.SetRetention(d)
+	}
+}
+
+// SetPruneHook registers a callback for sessions pruned from any workspace.
+func (wm *WorkspaceManager) SetPruneHook(fn func(*Session)) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.pruneHook = fn
+	for _, store := range wm.sessions {
+		store.SetPruneHook(fn)
+	}
+}
+
+// SetEventLog records workspace changes in the state event log.
+func (wm *WorkspaceManager) SetEventLog(events *StateEventLog) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.events = events
+}
+
+// IdleSessions returns live sessions inactive since before cutoff.
+func (wm *WorkspaceManager) IdleSessions(cutoff time.Time) []*Session {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	var out []*Session
+	for _, store := range wm.sessions {
+		store.mu.RLock()
+		for _, s := range store.sessions {
+			if s.LastActiveAt().Before(cutoff) {
+				out = append(out, s)
+			}
+		}
+		store.mu.RUnlock()
+	}
+	return out
+}
+
+// newSessionStore creates the isolated session store for a workspace,
+// inheriting the manager's persistence and retention.
+func (wm *WorkspaceManager) newSessionStore(wsID string) *SessionStore {
+	store := NewSessionStore(wm.logger.With("workspace", wsID))
+	store.workspaceID = wsID
+	store.retention = wm.retention
+	store.onPrune = wm.pruneHook
+	if wm.persistence != nil {
+		store.SetPersistence(wm.persistence)
+	}
+	return store
+}
+
 // ResolvedWorkspace contains the resolved workspace and session for a message.
 type ResolvedWorkspace struct {
 	// Workspace is the resolved workspace.
@@ -234,10 +360,7 @@ func (wm *WorkspaceManager) Resolve(channel, chatID, senderJID string, isGroup b
 
 	store := wm.sessions[wsID]
 	if store == nil {
-		store = NewSessionStore(wm.logger)
-		if wm.persistence != nil {
-			store.SetPersistence(wm.persistence)
-		}
+		store = wm.newSessionStore(wsID)
 		wm.sessions[wsID] = store
 	}
 
@@ -297,6 +420,10 @@ func (wm *WorkspaceManager) applyWorkspaceConfig(ws *Workspace, session *Session
 		cfg.BusinessContext = ws.Instructions
 		changed = true
 	}
+	if cfg.PromptDir != ws.PromptDir {
+		cfg.PromptDir = ws.PromptDir
+		changed = true
+	}
 
 	if changed {
 		session.SetConfig(cfg)
@@ -327,11 +454,7 @@ func (wm *WorkspaceManager) Create(ws Workspace, createdBy string) error {
 	ws.Active = true
 
 	wm.workspaces[ws.ID] = &ws
-	store := NewSessionStore(wm.logger.With("workspace", ws.ID))
-	if wm.persistence != nil {
-		store.SetPersistence(wm.persistence)
-	}
-	wm.sessions[ws.ID] = store
+	wm.sessions[ws.ID] = wm.newSessionStore(ws.ID)
 
 	// Map members.
 	for _, jid := range ws.Members {
@@ -343,6 +466,7 @@ func (wm *WorkspaceManager) Create(ws Workspace, createdBy string) error {
 
 	wm.logger.Info("workspace created",
 		"id", ws.ID, "name", ws.Name, "by", createdBy)
+	wm.events.Emit(EventWorkspaceCreate, createdBy, ws.ID, map[string]any{"name": ws.Name})
 	return nil
 }
 
@@ -373,6 +497,7 @@ func (wm *WorkspaceManager) Delete(wsID, deletedBy string) error {
 
 	wm.logger.Info("workspace deleted",
 		"id", wsID, "by", deletedBy)
+	wm.events.Emit(EventWorkspaceDelete, deletedBy, wsID, nil)
 	return nil
 }
 
@@ -399,6 +524,7 @@ func (wm *WorkspaceManager) AssignUser(jid, wsID, assignedBy string) error {
 
 	wm.logger.Info("user assigned to workspace",
 		"jid", norm, "workspace", wsID, "by", assignedBy)
+	wm.events.Emit(EventWorkspaceAssign, assignedBy, wsID, map[string]any{"user": norm})
 	return nil
 }
 
@@ -425,6 +551,7 @@ func (wm *WorkspaceManager) AssignGroup(groupJID, wsID, assignedBy string) error
 
 	wm.logger.Info("group assigned to workspace",
 		"group", norm, "workspace", wsID, "by", assignedBy)
+	wm.events.Emit(EventWorkspaceAssign, assignedBy, wsID, map[string]any{"group": norm})
 	return nil
 }
 
@@ -439,6 +566,7 @@ func (wm *WorkspaceManager) UnassignUser(jid string) {
 			ws.Members = removeFromSlice(ws.Members, jid)
 		}
 		delete(wm.userMap, norm)
+		wm.events.Emit(EventWorkspaceUnassign, "", wsID, map[string]any{"user": norm})
 	}
 }
 
@@ -487,6 +615,33 @@ func (wm *WorkspaceManager) GetSessionByID(sessionID string) (*Session, *Workspa
 	return nil, nil
 }
 
+// WorkspaceIDForSession returns the workspace a session ID ("channel:chatID")
+// belongs to. Live sessions are looked up directly; otherwise the chat is
+// resolved through group and member assignments, falling back to the default.
+func (wm *WorkspaceManager) WorkspaceIDForSession(sessionID string) string {
+	parts := strings.SplitN(sessionID, ":", 2)
+	if len(parts) != 2 {
+		return wm.defaultWSID
+	}
+	channel, chatID := parts[0], parts[1]
+
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+	for wsID, store := range wm.sessions {
+		if store.Get(channel, chatID) != nil {
+			return wsID
+		}
+	}
+	norm := normalizeJID(chatID)
+	if wsID, ok := wm.groupMap[norm]; ok {
+		return wsID
+	}
+	if wsID, ok := wm.userMap[norm]; ok {
+		return wsID
+	}
+	return wm.defaultWSID
+}
+
 // SessionCount returns the total number of sessions across all workspaces.
 func (wm *WorkspaceManager) SessionCount() int {
 	wm.mu.RLock()
@@ -519,6 +674,43 @@ func (wm *WorkspaceManager) DeleteSessionByID(sessionID string) bool {
 	return false
 }
 
+// SessionIn returns the session of a chat within a given workspace,
+// creating it if needed (unlike Resolve, which routes by sender).
+func (wm *WorkspaceManager) SessionIn(wsID, channel, chatID string) *Session {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	store := wm.sessions[wsID]
+	if store == nil {
+		store = wm.newSessionStore(wsID)
+		wm.sessions[wsID] = store
+	}
+	session := store.GetOrCreate(channel, chatID)
+	if ws := wm.workspaces[wsID]; ws != nil {
+		wm.applyWorkspaceConfig(ws, session)
+	}
+	return session
+}
+
+// LastActiveChat returns the channel and chat of the workspace's most
+// recently active session whose channel passes accept.
+func (wm *WorkspaceManager) LastActiveChat(wsID string, accept func(channel string) bool) (channel, chatID string, ok bool) {
+	wm.mu.RLock()
+	store := wm.sessions[wsID]
+	wm.mu.RUnlock()
+	if store == nil {
+		return "", "", false
+	}
+	var last time.Time
+	for _, m := range store.ListSessions() {
+		if m.ChatID == "" || !accept(m.Channel) || !m.LastActiveAt.After(last) {
+			continue
+		}
+		channel, chatID, last, ok = m.Channel, m.ChatID, m.LastActiveAt, true
+	}
+	return channel, chatID, ok
+}
+
 // GetForUser returns the workspace assigned to a user JID.
 func (wm *WorkspaceManager) GetForUser(jid string) (*Workspace, bool) {
 	wm.mu.RLock()
@@ -562,6 +754,7 @@ func (wm *WorkspaceManager) Update(wsID string, fn func(ws *Workspace)) error {
 	}
 
 	fn(ws)
+	wm.events.Emit(EventWorkspaceUpdate, "", wsID, nil)
 	return nil
 }
 
@@ -51,7 +51,7 @@ func (wc *WorkspaceContainment) AssertSandboxPath(path string) (string, error) {
 	path = filepath.Clean(path)
 
 	// Check for path traversal BEFORE resolving symlinks.
-	if !strings.HasPrefix(path, wc.Root) {
+	if !pathWithin(path, wc.Root) {
 		return "", fmt.Errorf("path %q escapes workspace root %q", path, wc.Root)
 	}
 
@@ -64,7 +64,7 @@ func (wc *WorkspaceContainment) AssertSandboxPath(path string) (string, error) {
 		if parentErr != nil {
 			return "", fmt.Errorf("cannot resolve path %q: %w", path, err)
 		}
-		if !strings.HasPrefix(resolvedParent, wc.Root) {
+		if !pathWithin(resolvedParent, wc.Root) {
 			return "", fmt.Errorf("path %q resolves to %q which escapes workspace root %q",
 				path, resolvedParent, wc.Root)
 		}
@@ -73,7 +73,7 @@ func (wc *WorkspaceContainment) AssertSandboxPath(path string) (string, error) {
 	}
 
 	// Verify the resolved path is still within the workspace.
-	if !strings.HasPrefix(resolved, wc.Root) {
+	if !pathWithin(resolved, wc.Root) {
 		return "", fmt.Errorf("path %q resolves to %q (symlink escape) which is outside workspace %q",
 			path, resolved, wc.Root)
 	}
@@ -118,7 +118,7 @@ func (wc *WorkspaceContainment) AssertNoSymlinkEscape(path string) error {
 			}
 			target = filepath.Clean(target)
 
-			if !strings.HasPrefix(target, wc.Root) {
+			if !pathWithin(target, wc.Root) {
 				return fmt.Errorf("symlink %q points to %q which escapes workspace %q",
 					current, target, wc.Root)
 			}
@@ -0,0 +1,133 @@
+// Package copilot – workspace_delegation.go implements delegate_to_workspace,
+// which lets the agent of one workspace hand a task to the agent of another
+// (with that workspace's prompt, persona, session history and tool
+// registry) and get its answer back. Delegation is off unless the owner
+// lists the target in the source workspace's delegate_to, and the delegated
+// run keeps the original caller's access level, so it can never do more
+// than the user could do in the target workspace directly.
+package copilot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// delegationTimeout bounds one delegated run.
+const delegationTimeout = 5 * time.Minute
+
+// delegationChannel is the session channel of delegated runs. Each source
+// workspace gets one session in the target, so follow-up delegations share
+// context.
+const delegationChannel = "delegate"
+
+// ctxKeyDelegation marks a context as belonging to a delegated run.
+type ctxKeyDelegation struct{}
+
+// delegationTargets returns the workspaces wsID may delegate to.
+func (a *Assistant) delegationTargets(wsID string) []string {
+	ws, ok := a.workspaceMgr.Get(wsID)
+	if !ok {
+		return nil
+	}
+	return ws.DelegateTo
+}
+
+// delegateToWorkspace runs task in the target workspace on behalf of the
+// caller in ctx and returns the target agent's answer.
+func (a *Assistant) delegateToWorkspace(ctx context.Context, target, task string) (string, error) {
+	if ctx.Value(ctxKeyDelegation{}) != nil {
+		return "", fmt.Errorf("a delegated task cannot be delegated again")
+	}
+	source := WorkspaceIDFromContext(ctx)
+	if source == "" {
+		source = a.workspaceMgr.defaultWSID
+	}
+	if target == source {
+		return "", fmt.Errorf("cannot delegate to the current workspace")
+	}
+	if !slices.Contains(a.delegationTargets(source), target) {
+		return "", fmt.Errorf("workspace %q may not delegate to %q (not in its delegate_to)", source, target)
+	}
+	ws, ok := a.workspaceMgr.Get(target)
+	if !ok || !ws.Active {
+		return "", fmt.Errorf("workspace %q not found or inactive", target)
+	}
+
+	// The run starts from the assistant's context, not the caller's, so no
+	// delivery target or progress sender of the source chat leaks into the
+	// target workspace; it still stops when the calling tool is cancelled.
+	session := a.workspaceMgr.SessionIn(target, delegationChannel, source)
+	runCtx, cancel := context.WithTimeout(a.ctx, delegationTimeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	runCtx = context.WithValue(runCtx, ctxKeyDelegation{}, source)
+	runCtx = ContextWithSession(runCtx, session.ID)
+	runCtx = ContextWithCaller(runCtx, CallerLevelFromContext(ctx), CallerJIDFromContext(ctx))
+	runCtx = a.withWorkspaceEnv(runCtx, target)
+
+	a.logger.Info("delegating task to workspace",
+		"from", source, "to", target, "caller", CallerJIDFromContext(ctx), "task_preview", truncate(task, 80))
+
+	message := fmt.Sprintf("[Task delegated by the agent of workspace %q. Reply with the result only.]\n%s", source, task)
+	prompt := a.composeWorkspacePrompt(ws, session, message)
+	start := time.Now()
+	response := a.executeAgent(runCtx, target, session, prompt, message)
+	session.AddMessage(message, response)
+
+	a.logger.Info("delegated task finished",
+		"from", source, "to", target, "duration", time.Since(start).Round(time.Second))
+	return response, nil
+}
+
+// registerDelegationTool registers delegate_to_workspace when at least one
+// workspace has delegation targets.
+func (a *Assistant) registerDelegationTool() {
+	var wired []string
+	for _, ws := range a.workspaceMgr.List() {
+		if len(ws.DelegateTo) > 0 {
+			wired = append(wired, ws.ID+" → "+strings.Join(ws.DelegateTo, ", "))
+		}
+	}
+	if len(wired) == 0 {
+		return
+	}
+
+	a.toolExecutor.Register(
+		MakeToolDefinition("delegate_to_workspace",
+			"Delegate a task to the agent of another workspace, which works on it with its own "+
+				"instructions, memory and tools and returns its answer. Only workspaces this workspace "+
+				"is allowed to delegate to are accepted. The task must be self-contained.",
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"workspace": map[string]any{
+						"type":        "string",
+						"description": "ID of the target workspace.",
+					},
+					"task": map[string]any{
+						"type":        "string",
+						"description": "The task, with all the context the other agent needs.",
+					},
+				},
+				"required": []string{"workspace", "task"},
+			},
+		),
+		func(ctx context.Context, args map[string]any) (any, error) {
+			target, _ := args["workspace"].(string)
+			task, _ := args["task"].(string)
+			if target == "" || strings.TrimSpace(task) == "" {
+				return nil, fmt.Errorf("workspace and task are required")
+			}
+			response, err := a.delegateToWorkspace(ctx, target, task)
+			if err != nil {
+				return nil, err
+			}
+			return fmt.Sprintf("Answer from workspace %q:\n\n%s", target, response), nil
+		},
+	)
+	a.logger.Info("workspace delegation enabled", "routes", wired)
+}
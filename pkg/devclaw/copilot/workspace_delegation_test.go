@@ -0,0 +1,42 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDelegateToWorkspace_Allowlist(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	wm := NewWorkspaceManager(DefaultConfig(), WorkspaceConfig{
+		DefaultWorkspace: "default",
+		Workspaces: []Workspace{
+			{ID: "default", Active: true, DelegateTo: []string{"finance", "archive"}},
+			{ID: "finance", Active: true},
+			{ID: "archive", Active: false},
+		},
+	}, logger)
+	a := &Assistant{workspaceMgr: wm, logger: logger, ctx: context.Background()}
+
+	finance := ContextWithWorkspace(context.Background(), "finance")
+	cases := []struct {
+		name   string
+		ctx    context.Context
+		target string
+		want   string
+	}{
+		{"not in delegate_to", finance, "default", "may not delegate"},
+		{"same workspace", finance, "finance", "current workspace"},
+		{"inactive target", context.Background(), "archive", "inactive"},
+		{"nested delegation", context.WithValue(context.Background(), ctxKeyDelegation{}, "default"), "finance", "delegated again"},
+	}
+	for _, tc := range cases {
+		if _, err := a.delegateToWorkspace(tc.ctx, tc.target, "sum the invoices"); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: want error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package copilot – workspace_env.go resolves per-workspace environment
+// variables. A workspace's env file (workspace.env, dotenv syntax) and its
+// inline `env:` map are layered over the base process environment for tool
+// and script runs resolved to that workspace, so client A's API endpoints
+// and tokens never reach client B's tool executions.
+//
+// Layering, lowest to highest precedence:
+//
+//	process env → workspace.env → workspace `env:` → set_env (per session)
+package copilot
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/joho/godotenv"
+)
+
+// workspaceEnvFile is the env file name inside a workspace's env directory.
+const workspaceEnvFile = "workspace.env"
+
+// EnvFilePath returns the env file used for the workspace: its env_file,
+// or <env_dir>/<id>/workspace.env.
+func (wm *WorkspaceManager) EnvFilePath(ws *Workspace) string {
+	if ws.EnvFile != "" {
+		return ws.EnvFile
+	}
+	dir := wm.envDir
+	if dir == "" {
+		dir = DefaultWorkspaceConfig().EnvDir
+	}
+	return filepath.Join(dir, sanitizeKBName(ws.ID), workspaceEnvFile)
+}
+
+// Env returns the environment variables of a workspace: its env file with
+// the inline env on top. The file is read on every call so edits apply to
+// the next run without a restart. A missing default file is not an error;
+// a missing explicit env_file is.
+func (wm *WorkspaceManager) Env(wsID string) (map[string]string, error) {
+	wm.mu.RLock()
+	ws, ok := wm.workspaces[wsID]
+	var inline map[string]string
+	var path string
+	if ok {
+		inline = maps.Clone(ws.Env)
+		path = wm.EnvFilePath(ws)
+	}
+	explicit := ok && ws.EnvFile != ""
+	wm.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	env, err := godotenv.Read(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			env = nil
+		} else {
+			return inline, fmt.Errorf("reading env file for workspace %s: %w", wsID, err)
+		}
+	}
+	if env == nil && len(inline) == 0 {
+		return nil, nil
+	}
+	if env == nil {
+		env = make(map[string]string, len(inline))
+	}
+	maps.Copy(env, inline)
+	return env, nil
+}
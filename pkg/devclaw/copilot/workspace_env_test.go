@@ -0,0 +1,82 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+func TestWorkspaceManagerEnv(t *testing.T) {
+	t.Parallel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	envDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(envDir, "acme"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(envDir, "acme", workspaceEnvFile),
+		[]byte("API_URL=https://acme.example\nTOKEN=file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	explicit := filepath.Join(t.TempDir(), "globex.env")
+	if err := os.WriteFile(explicit, []byte("API_URL=https://globex.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	wm := NewWorkspaceManager(DefaultConfig(), WorkspaceConfig{
+		DefaultWorkspace: "default",
+		EnvDir:           envDir,
+		Workspaces: []Workspace{
+			{ID: "default", Active: true},
+			{ID: "acme", Active: true, Env: map[string]string{"TOKEN": "inline", "REGION": "eu"}},
+			{ID: "globex", Active: true, EnvFile: explicit},
+			{ID: "broken", Active: true, EnvFile: filepath.Join(envDir, "missing.env"), Env: map[string]string{"REGION": "us"}},
+		},
+	}, logger)
+
+	cases := []struct {
+		ws      string
+		want    map[string]string
+		wantErr bool
+	}{
+		// The inline env wins over the workspace's env file.
+		{"acme", map[string]string{"API_URL": "https://acme.example", "TOKEN": "inline", "REGION": "eu"}, false},
+		{"globex", map[string]string{"API_URL": "https://globex.example"}, false},
+		// No default file is fine; nothing is layered.
+		{"default", nil, false},
+		{"unknown", nil, false},
+		// A missing explicit env_file is an error, but the inline env still applies.
+		{"broken", map[string]string{"REGION": "us"}, true},
+	}
+	for _, tc := range cases {
+		got, err := wm.Env(tc.ws)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v", tc.ws, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: env = %v, want %v", tc.ws, got, tc.want)
+			continue
+		}
+		for k, v := range tc.want {
+			if got[k] != v {
+				t.Errorf("%s: %s = %q, want %q", tc.ws, k, got[k], v)
+			}
+		}
+	}
+
+	// Edits to the file apply to the next run.
+	if err := os.WriteFile(explicit, []byte("API_URL=https://globex.example/v2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := &Assistant{workspaceMgr: wm, logger: logger}
+	if got := sandbox.EnvFromContext(a.withWorkspaceEnv(context.Background(), "globex")); got["API_URL"] != "https://globex.example/v2" {
+		t.Errorf("context env = %v", got)
+	}
+	if got := sandbox.EnvFromContext(a.withWorkspaceEnv(context.Background(), "acme")); got["API_URL"] == "https://globex.example/v2" {
+		t.Error("one workspace's env leaked into another")
+	}
+}
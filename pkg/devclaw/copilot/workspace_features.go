@@ -0,0 +1,109 @@
+// Package copilot – workspace_features.go gates risky capabilities per
+// workspace so they can be rolled out gradually. workspaces.features sets
+// the default for every workspace and a workspace's own features block
+// overrides it; a flag set nowhere is on. The Assistant resolves the flags
+// when it scopes a workspace's tools, so a disabled capability's tools are
+// neither offered to nor callable by the workspace's runs. The heartbeat
+// flag holds back proactive messages to the workspace's chats.
+package copilot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature names a capability gated per workspace.
+type Feature string
+
+// Gated capabilities.
+const (
+	FeatureSubagents    Feature = "subagents"
+	FeatureWeb          Feature = "web"
+	FeatureSSH          Feature = "ssh"
+	FeatureSkillInstall Feature = "skill_install"
+	FeatureHeartbeat    Feature = "heartbeat"
+)
+
+// allFeatures lists the gated capabilities in display order.
+var allFeatures = []Feature{FeatureSubagents, FeatureWeb, FeatureSSH, FeatureSkillInstall, FeatureHeartbeat}
+
+// featureTools are the tools each capability wires.
+var featureTools = map[Feature][]string{
+	FeatureSubagents:    {"group:subagents"},
+	FeatureWeb:          {"group:web", "browser_navigate", "browser_screenshot", "browser_content", "browser_click", "browser_fill"},
+	FeatureSSH:          {"ssh", "scp"},
+	FeatureSkillInstall: {"install_skill", "skill_defaults_install", "init_skill", "edit_skill", "add_script", "remove_skill"},
+}
+
+// FeatureFlags turns capabilities on or off. Unset flags inherit.
+type FeatureFlags struct {
+	// Subagents allows spawn_subagent and the other subagent tools.
+	Subagents *bool `yaml:"subagents,omitempty"`
+
+	// Web allows web_search, web_fetch and the browser tools.
+	Web *bool `yaml:"web,omitempty"`
+
+	// SSH allows ssh and scp.
+	SSH *bool `yaml:"ssh,omitempty"`
+
+	// SkillInstall allows installing, creating and editing skills.
+	SkillInstall *bool `yaml:"skill_install,omitempty"`
+
+	// Heartbeat allows proactive heartbeat messages to the workspace's chats.
+	Heartbeat *bool `yaml:"heartbeat,omitempty"`
+}
+
+// flag returns the setting of f (nil = unset).
+func (f FeatureFlags) flag(feature Feature) *bool {
+	switch feature {
+	case FeatureSubagents:
+		return f.Subagents
+	case FeatureWeb:
+		return f.Web
+	case FeatureSSH:
+		return f.SSH
+	case FeatureSkillInstall:
+		return f.SkillInstall
+	case FeatureHeartbeat:
+		return f.Heartbeat
+	}
+	return nil
+}
+
+// featureEnabled resolves a capability for ws (nil = no workspace): the
+// workspace's flag, else workspaces.features, else on.
+func (a *Assistant) featureEnabled(ws *Workspace, feature Feature) bool {
+	if ws != nil {
+		if v := ws.Features.flag(feature); v != nil {
+			return *v
+		}
+	}
+	a.configMu.RLock()
+	v := a.config.Workspaces.Features.flag(feature)
+	a.configMu.RUnlock()
+	return v == nil || *v
+}
+
+// featureDeniedTools lists the tools of the capabilities disabled for ws.
+func (a *Assistant) featureDeniedTools(ws *Workspace) []string {
+	var denied []string
+	for _, f := range allFeatures {
+		if !a.featureEnabled(ws, f) {
+			denied = append(denied, featureTools[f]...)
+		}
+	}
+	return denied
+}
+
+// featureSummary lists the workspace's capabilities for /ws info.
+func (a *Assistant) featureSummary(ws *Workspace) string {
+	parts := make([]string, 0, len(allFeatures))
+	for _, f := range allFeatures {
+		state := "on"
+		if !a.featureEnabled(ws, f) {
+			state = "off"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", f, state))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,63 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceFeatureFlags(t *testing.T) {
+	t.Parallel()
+	on, off := true, false
+	cfg := DefaultConfig()
+	cfg.Workspaces.Features = FeatureFlags{SSH: &off}
+	cfg.Workspaces.Workspaces = append(cfg.Workspaces.Workspaces,
+		Workspace{ID: "ops", Active: true, Features: FeatureFlags{SSH: &on}},
+		Workspace{ID: "kids", Active: true, Features: FeatureFlags{Web: &off, Subagents: &off, Heartbeat: &off}},
+	)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a := &Assistant{config: cfg, workspaceMgr: NewWorkspaceManager(cfg, cfg.Workspaces, logger), logger: logger}
+
+	exec := NewToolExecutor(logger)
+	handler := func(context.Context, map[string]any) (any, error) { return "ok", nil }
+	for _, name := range []string{"read_file", "ssh", "web_search", "browser_navigate", "spawn_subagent"} {
+		exec.Register(MakeToolDefinition(name, name, map[string]any{"type": "object"}), handler)
+	}
+	exec.SetWorkspaceScopes(func(wsID string) ToolScope {
+		ws, _ := a.workspaceMgr.Get(wsID)
+		return ToolScope{Denied: a.featureDeniedTools(ws)}
+	})
+	names := func(wsID string) string {
+		var out []string
+		for _, d := range exec.ToolsFor(wsID) {
+			out = append(out, d.Function.Name)
+		}
+		slices.Sort(out)
+		return strings.Join(out, ",")
+	}
+
+	if got := names("default"); got != "browser_navigate,read_file,spawn_subagent,web_search" {
+		t.Errorf("ssh is off by default, got %s", got)
+	}
+	if got := names("ops"); got != "browser_navigate,read_file,spawn_subagent,ssh,web_search" {
+		t.Errorf("ops turns ssh on, got %s", got)
+	}
+	if got := names("kids"); got != "read_file" {
+		t.Errorf("kids has no web, subagents or ssh, got %s", got)
+	}
+	call := ToolCall{ID: "1", Function: FunctionCall{Name: "web_search", Arguments: "{}"}}
+	if res := exec.Execute(ContextWithWorkspace(context.Background(), "kids"), []ToolCall{call}); res[0].Error == nil {
+		t.Error("a disabled capability's tools must not be callable")
+	}
+
+	kids, _ := a.workspaceMgr.Get("kids")
+	if a.featureEnabled(kids, FeatureHeartbeat) || !a.featureEnabled(nil, FeatureHeartbeat) {
+		t.Error("heartbeat should be off for kids only")
+	}
+	if got := a.featureSummary(kids); got != "subagents off, web off, ssh off, skill_install on, heartbeat off" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
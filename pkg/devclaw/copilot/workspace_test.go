@@ -0,0 +1,72 @@
+package copilot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspacePersona_ToolsAndPromptDir(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := DefaultConfig()
+	cfg.Heartbeat.WorkspaceDir = t.TempDir()
+	personaDir := t.TempDir()
+	os.WriteFile(filepath.Join(personaDir, "SOUL.md"), []byte("You are Ledger, the finance bot."), 0o644)
+
+	wm := NewWorkspaceManager(cfg, WorkspaceConfig{
+		DefaultWorkspace: "default",
+		Workspaces: []Workspace{{
+			ID: "finance", Active: true, Model: "finance-model",
+			Tools: []string{"read_file", "calc"}, PromptDir: personaDir,
+		}},
+	}, logger)
+
+	exec := NewToolExecutor(logger)
+	handler := func(context.Context, map[string]any) (any, error) { return "ok", nil }
+	for _, name := range []string{"read_file", "calc", "bash"} {
+		exec.Register(MakeToolDefinition(name, name, map[string]any{"type": "object"}), handler)
+	}
+	exec.SetWorkspaceScopes(func(wsID string) ToolScope {
+		ws, _ := wm.Get(wsID)
+		if ws == nil {
+			return ToolScope{}
+		}
+		return ToolScope{Tools: ws.Tools, Denied: ws.DeniedTools, Skills: ws.Skills}
+	})
+
+	if n := len(exec.ToolsFor("default")); n != 3 {
+		t.Errorf("default workspace should see every tool, got %d", n)
+	}
+	if n := len(exec.ToolsFor("finance")); n != 2 {
+		t.Errorf("finance workspace should see its 2 tools, got %d", n)
+	}
+	call := ToolCall{ID: "1", Function: FunctionCall{Name: "bash", Arguments: "{}"}}
+	res := exec.Execute(ContextWithWorkspace(context.Background(), "finance"), []ToolCall{call})
+	if res[0].Error == nil || !strings.Contains(res[0].Content, "not available") {
+		t.Errorf("bash must not run in the finance workspace, got %q", res[0].Content)
+	}
+	if res := exec.Execute(context.Background(), []ToolCall{call}); res[0].Error != nil {
+		t.Errorf("bash should run outside a workspace: %v", res[0].Error)
+	}
+
+	session := wm.Resolve("telegram", "42", "42", false).Session
+	if session.GetConfig().Model != "" {
+		// Resolve maps unknown chats to the default workspace.
+		t.Fatalf("unexpected model %q for the default workspace", session.GetConfig().Model)
+	}
+	finance := wm.SessionIn("finance", "telegram", "7")
+	if got := finance.GetConfig(); got.Model != "finance-model" || got.PromptDir != personaDir {
+		t.Fatalf("persona not applied: %+v", got)
+	}
+	composer := NewPromptComposer(cfg)
+	if p := composer.Compose(finance, "hi"); !strings.Contains(p, "Ledger") {
+		t.Error("persona SOUL.md missing from the workspace prompt")
+	}
+	if p := composer.Compose(session, "hi"); strings.Contains(p, "Ledger") {
+		t.Error("persona SOUL.md leaked into another workspace")
+	}
+}
@@ -0,0 +1,111 @@
+// Package copilot – workspace_tools.go gives each workspace its own view of
+// the tool registry, so a workspace persona (see Workspace.Tools,
+// Workspace.DeniedTools and Workspace.Skills) only sees and can only call
+// the tools it was given. The registry itself stays shared: tools are
+// registered once and each workspace's set is derived from it.
+package copilot
+
+import (
+	"context"
+	"slices"
+)
+
+// ToolScope selects the tools in a workspace's registry.
+type ToolScope struct {
+	// Tools lists the allowed tools ("group:..." references expand).
+	// Empty = every registered tool.
+	Tools []string
+
+	// Denied removes tools from the set.
+	Denied []string
+
+	// Skills hides the tools of skills not listed. Empty = all skills.
+	Skills []string
+}
+
+// empty reports whether the scope leaves the registry unrestricted.
+func (s ToolScope) empty() bool {
+	return len(s.Tools) == 0 && len(s.Denied) == 0 && len(s.Skills) == 0
+}
+
+// permits reports whether a tool, registered by skill ("" for built-in
+// tools), belongs to the scope.
+func (s ToolScope) permits(name, skill string) bool {
+	if slices.Contains(ExpandToolGroups(s.Denied), name) {
+		return false
+	}
+	if skill != "" && len(s.Skills) > 0 && !slices.Contains(s.Skills, skill) {
+		return false
+	}
+	if len(s.Tools) == 0 {
+		return true
+	}
+	// Listing a skill's tool or the skill itself both allow it.
+	allowed := ExpandToolGroups(s.Tools)
+	return slices.Contains(allowed, name) || (skill != "" && slices.Contains(allowed, skill))
+}
+
+// ctxKeyWorkspace is the context key for the workspace of a run.
+type ctxKeyWorkspace struct{}
+
+// ContextWithWorkspace returns a context carrying the workspace whose tool
+// registry applies to tool calls made with it.
+func ContextWithWorkspace(ctx context.Context, wsID string) context.Context {
+	return context.WithValue(ctx, ctxKeyWorkspace{}, wsID)
+}
+
+// WorkspaceIDFromContext returns the workspace set by ContextWithWorkspace,
+// or "".
+func WorkspaceIDFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxKeyWorkspace{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// SetWorkspaceScopes sets how the tool scope of a workspace is looked up.
+// It is called on every lookup, so workspace edits apply to the next run.
+func (e *ToolExecutor) SetWorkspaceScopes(fn func(wsID string) ToolScope) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.workspaceScopes = fn
+}
+
+// workspaceScope returns the scope of a workspace (empty when none is set).
+func (e *ToolExecutor) workspaceScope(wsID string) ToolScope {
+	e.mu.RLock()
+	fn := e.workspaceScopes
+	e.mu.RUnlock()
+	if fn == nil || wsID == "" {
+		return ToolScope{}
+	}
+	return fn(wsID)
+}
+
+// ToolsFor returns the tool definitions of a workspace's registry. An empty
+// workspace ID returns every tool.
+func (e *ToolExecutor) ToolsFor(wsID string) []ToolDefinition {
+	all := e.Tools()
+	scope := e.workspaceScope(wsID)
+	if scope.empty() {
+		return all
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	defs := make([]ToolDefinition, 0, len(all))
+	for _, def := range all {
+		name := def.Function.Name
+		if rt, ok := e.tools[name]; ok && scope.permits(name, rt.Skill) {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// inWorkspace reports whether a registered tool belongs to a workspace's
+// registry.
+func (e *ToolExecutor) inWorkspace(wsID, name string, rt *registeredTool) bool {
+	scope := e.workspaceScope(wsID)
+	return scope.empty() || scope.permits(name, rt.Skill)
+}
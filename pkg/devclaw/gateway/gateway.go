@@ -60,16 +60,29 @@ func (g *Gateway) Start(ctx context.Context) error {
 
 	// OpenAI-compatible chat
 	mux.HandleFunc("/v1/chat/completions", g.handleChatCompletions)
+	mux.HandleFunc("/v1/notify", g.handleNotify)
 
 	// API routes
 	mux.HandleFunc("/api/sessions", g.handleListSessions)
 	mux.HandleFunc("/api/sessions/", g.handleSessionByID)
 	mux.HandleFunc("/api/usage", g.handleGlobalUsage)
 	mux.HandleFunc("/api/usage/", g.handleSessionUsage)
+	mux.HandleFunc("/api/usage/export", g.handleUsageExport)
+	mux.HandleFunc("/api/usage/invoices", g.handleUsageInvoices)
 	mux.HandleFunc("/api/status", g.handleStatus)
+	mux.HandleFunc("/api/quotas", g.handleQuotas)
+	mux.HandleFunc("/api/quotas/", g.handleQuotaByWorkspace)
+	mux.HandleFunc("/api/events", g.handleEvents)
 	mux.HandleFunc("/api/webhooks", g.handleWebhooks)
 	mux.HandleFunc("/api/webhooks/", g.handleWebhookByID)
 
+	// Inbound webhooks (public, verified by per-hook signatures)
+	if ch, ok := g.assistant.ChannelManager().Channel("webhook"); ok {
+		if h, ok := ch.(http.Handler); ok {
+			mux.Handle("/hooks/", h)
+		}
+	}
+
 	handler := g.corsMiddleware(g.authMiddleware(mux))
 	g.server = &http.Server{
 		Addr:    g.config.Address,
@@ -1,13 +1,16 @@
 package gateway
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jholhewres/devclaw/pkg/devclaw/channels"
 	"github.com/jholhewres/devclaw/pkg/devclaw/copilot"
 )
 
@@ -124,20 +127,32 @@ func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 	channelsMap := make(map[string]string)
 	if g.assistant != nil {
-		for name, st := range g.assistant.ChannelManager().HealthAll() {
+		mgr := g.assistant.ChannelManager()
+		for name, st := range mgr.HealthAll() {
 			if st.Connected {
 				channelsMap[name] = "connected"
+			} else if !mgr.Owns(name) {
+				channelsMap[name] = "standby"
 			} else {
 				channelsMap[name] = "disconnected"
 			}
 		}
 	}
-	g.writeJSON(w, 200, map[string]any{
+	resp := map[string]any{
 		"status":   "ok",
 		"version":  version,
 		"uptime":   uptime,
 		"channels": channelsMap,
-	})
+	}
+	if g.assistant != nil {
+		readiness := g.assistant.Readiness()
+		resp["ready"] = readiness.Ready()
+		resp["warmup"] = readiness
+		if owners := g.assistant.CoordinationStatus(); owners != nil {
+			resp["coordination"] = owners
+		}
+	}
+	g.writeJSON(w, 200, resp)
 }
 
 // handleChatCompletions implements POST /v1/chat/completions (OpenAI-compatible)
@@ -446,6 +461,212 @@ func (g *Gateway) handleGlobalUsage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// usageLedgerQuery reads ?month=YYYY-MM (default: current) and ?workspace=.
+func usageLedgerQuery(r *http.Request) (month, workspaceID string) {
+	month = r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format("2006-01")
+	}
+	return month, r.URL.Query().Get("workspace")
+}
+
+// handleUsageExport implements GET /api/usage/export?format=csv|json&month=&workspace=.
+func (g *Gateway) handleUsageExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+	ledger := g.assistant.UsageLedger()
+	if ledger == nil {
+		g.writeError(w, "usage ledger is not enabled", 404)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		g.writeError(w, "format must be csv or json", 400)
+		return
+	}
+	month, wsID := usageLedgerQuery(r)
+	entries, err := ledger.Entries(month, wsID)
+	if err != nil {
+		g.writeError(w, err.Error(), 400)
+		return
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=usage-%s.%s", month, format))
+	if err := copilot.ExportUsage(w, format, entries); err != nil {
+		g.logger.Warn("usage export failed", "error", err)
+	}
+}
+
+// handleUsageInvoices implements GET /api/usage/invoices?month=&workspace=.
+func (g *Gateway) handleUsageInvoices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+	ledger := g.assistant.UsageLedger()
+	if ledger == nil {
+		g.writeError(w, "usage ledger is not enabled", 404)
+		return
+	}
+	month, wsID := usageLedgerQuery(r)
+	invoices, err := ledger.Invoices(month, wsID)
+	if err != nil {
+		g.writeError(w, err.Error(), 400)
+		return
+	}
+	g.writeJSON(w, 200, map[string]any{"month": month, "invoices": invoices})
+}
+
+// handleQuotas implements GET /api/quotas.
+func (g *Gateway) handleQuotas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+	qm := g.assistant.QuotaManager()
+	if qm == nil {
+		g.writeError(w, "workspace quotas are not enabled", 404)
+		return
+	}
+	var ids []string
+	for _, ws := range g.assistant.WorkspaceManager().List() {
+		ids = append(ids, ws.ID)
+	}
+	quotas, err := qm.List(ids...)
+	if err != nil {
+		g.writeError(w, err.Error(), 500)
+		return
+	}
+	g.writeJSON(w, 200, map[string]any{"quotas": quotas})
+}
+
+// handleQuotaByWorkspace implements GET /api/quotas/{workspace_id} and
+// POST /api/quotas/{workspace_id} with {"limit_usd": n} to set the monthly
+// limit, {"reset_limit": true} to revert to the configured one and/or
+// {"topup_usd": n} to add budget for the current month.
+func (g *Gateway) handleQuotaByWorkspace(w http.ResponseWriter, r *http.Request) {
+	wsID := strings.TrimPrefix(r.URL.Path, "/api/quotas/")
+	if wsID == "" {
+		g.writeError(w, "workspace id required", 400)
+		return
+	}
+	qm := g.assistant.QuotaManager()
+	if qm == nil {
+		g.writeError(w, "workspace quotas are not enabled", 404)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var req struct {
+			LimitUSD   *float64 `json:"limit_usd"`
+			ResetLimit bool     `json:"reset_limit"`
+			TopUpUSD   float64  `json:"topup_usd"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			g.writeError(w, "invalid request body", 400)
+			return
+		}
+		if req.LimitUSD == nil && !req.ResetLimit && req.TopUpUSD == 0 {
+			g.writeError(w, "limit_usd, reset_limit or topup_usd required", 400)
+			return
+		}
+		var err error
+		switch {
+		case req.ResetLimit:
+			err = qm.ResetLimit(wsID)
+		case req.LimitUSD != nil:
+			err = qm.SetLimit(wsID, *req.LimitUSD)
+		}
+		if err == nil && req.TopUpUSD != 0 {
+			err = qm.TopUp(wsID, req.TopUpUSD)
+		}
+		if err != nil {
+			g.writeError(w, err.Error(), 400)
+			return
+		}
+	default:
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+
+	st, err := qm.Status(wsID)
+	if err != nil {
+		g.writeError(w, err.Error(), 500)
+		return
+	}
+	g.writeJSON(w, 200, st)
+}
+
+// handleEvents implements GET /api/events. Query parameters: type
+// (repeatable, exact or category), actor, subject, since (RFC3339 or a
+// duration like 24h), after (sequence number to resume from) and limit.
+func (g *Gateway) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+	el := g.assistant.EventLog()
+	if el == nil {
+		g.writeError(w, "event log is not enabled", 404)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := copilot.StateEventFilter{
+		Types:   q["type"],
+		Actor:   q.Get("actor"),
+		Subject: q.Get("subject"),
+		Limit:   100,
+	}
+	if v := q.Get("since"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			filter.Since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, v); err == nil {
+			filter.Since = t
+		} else {
+			g.writeError(w, "invalid since: use RFC3339 or a duration", 400)
+			return
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			g.writeError(w, "invalid after", 400)
+			return
+		}
+		filter.AfterSeq = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			g.writeError(w, "invalid limit", 400)
+			return
+		}
+		filter.Limit = n
+	}
+
+	events, err := el.Query(filter)
+	if err != nil {
+		g.writeError(w, err.Error(), 500)
+		return
+	}
+	if events == nil {
+		events = []copilot.StateEvent{}
+	}
+	g.writeJSON(w, 200, map[string]any{"events": events})
+}
+
 // handleSessionUsage implements GET /api/usage/:session_id
 func (g *Gateway) handleSessionUsage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -610,3 +831,59 @@ func (g *Gateway) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
 		g.writeError(w, "method not allowed", 405)
 	}
 }
+
+// notifyRequest is the body of POST /v1/notify.
+type notifyRequest struct {
+	Workspace   string `json:"workspace"`
+	Text        string `json:"text"`
+	Channel     string `json:"channel"`
+	To          string `json:"to"`
+	NoRecord    bool   `json:"no_record"`
+	Attachments []struct {
+		Filename string `json:"filename"`
+		MimeType string `json:"mime_type"`
+		Data     string `json:"data"` // base64
+		Caption  string `json:"caption"`
+	} `json:"attachments"`
+}
+
+// handleNotify implements POST /v1/notify: a proactive message to a chat of
+// a workspace, through the output guardrail and channel formatting.
+func (g *Gateway) handleNotify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		g.writeError(w, "method not allowed", 405)
+		return
+	}
+	var req notifyRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<20)).Decode(&req); err != nil {
+		g.writeError(w, "invalid request body", 400)
+		return
+	}
+
+	opts := copilot.NotifyOptions{Channel: req.Channel, To: req.To, NoRecord: req.NoRecord}
+	for _, att := range req.Attachments {
+		data, err := base64.StdEncoding.DecodeString(att.Data)
+		if err != nil || len(data) == 0 {
+			g.writeError(w, "attachment data must be base64: "+att.Filename, 400)
+			return
+		}
+		mimeType := att.MimeType
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		opts.Attachments = append(opts.Attachments, &channels.MediaMessage{
+			Data:     data,
+			MimeType: mimeType,
+			Filename: att.Filename,
+			Caption:  att.Caption,
+		})
+	}
+
+	res, err := g.assistant.Notify(r.Context(), req.Workspace, req.Text, opts)
+	if err != nil {
+		g.writeError(w, err.Error(), 400)
+		return
+	}
+	g.writeJSON(w, 200, res)
+}
+
@@ -7,7 +7,8 @@ import (
 )
 
 // authMiddleware requires Authorization: Bearer <token> when authToken is non-empty.
-// Skips auth for /health. Applied to /api/* and /v1/* when token is set.
+// Skips auth for /health and /hooks/ (inbound webhooks verify their own
+// signatures). Applied to /api/* and /v1/* when token is set.
 func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if g.config.AuthToken == "" {
@@ -15,7 +16,7 @@ func (g *Gateway) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		path := r.URL.Path
-		if path == "/health" {
+		if path == "/health" || strings.HasPrefix(path, "/hooks/") {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -44,10 +44,13 @@ type ToolCallResult struct {
 	IsError bool           `json:"isError,omitempty"`
 }
 
-// ContentBlock is a single content item in a tool result.
+// ContentBlock is a single content item in a tool result: "text" uses
+// Text, "image" uses base64 Data and MimeType.
 type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
 }
 
 // Resource describes an MCP resource.
@@ -251,6 +254,16 @@ func (s *Server) handleToolsCall(ctx context.Context, params json.RawMessage) (a
 		}, nil
 	}
 
+	// Handlers may return rich content directly.
+	switch v := result.(type) {
+	case *ToolCallResult:
+		return v, nil
+	case ToolCallResult:
+		return &v, nil
+	case []ContentBlock:
+		return &ToolCallResult{Content: v}, nil
+	}
+
 	text := fmt.Sprintf("%v", result)
 	return &ToolCallResult{
 		Content: []ContentBlock{{Type: "text", Text: text}},
@@ -0,0 +1,70 @@
+// Package sandbox – env.go carries layered environment variables through a
+// context, so every script run for a request (skills, the exec tool) gets
+// them without each caller threading them into ExecRequest.Env.
+package sandbox
+
+import (
+	"context"
+	"maps"
+	"os"
+	"strings"
+)
+
+type ctxKeyEnv struct{}
+
+// WithEnv returns a context whose runs get env layered over the base
+// environment. Variables already in the context are kept unless env
+// overrides them.
+func WithEnv(ctx context.Context, env map[string]string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	merged := maps.Clone(EnvFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(env))
+	}
+	maps.Copy(merged, env)
+	return context.WithValue(ctx, ctxKeyEnv{}, merged)
+}
+
+// EnvFromContext returns the variables set with WithEnv, or nil. The map
+// must not be modified.
+func EnvFromContext(ctx context.Context) map[string]string {
+	env, _ := ctx.Value(ctxKeyEnv{}).(map[string]string)
+	return env
+}
+
+// LayerEnv returns base (KEY=VALUE entries, e.g. os.Environ()) with the
+// context's variables and then extra layered on top; later layers win.
+func LayerEnv(ctx context.Context, base []string, extra map[string]string) []string {
+	layers := []map[string]string{EnvFromContext(ctx), extra}
+	override := make(map[string]bool)
+	for _, l := range layers {
+		for k := range l {
+			override[k] = true
+		}
+	}
+	if len(override) == 0 {
+		return base
+	}
+
+	env := make([]string, 0, len(base)+len(override))
+	for _, kv := range base {
+		if k, _, _ := strings.Cut(kv, "="); !override[k] {
+			env = append(env, kv)
+		}
+	}
+	merged := make(map[string]string, len(override))
+	for _, l := range layers {
+		maps.Copy(merged, l)
+	}
+	for k, v := range merged {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// Environ is LayerEnv over the current process environment.
+func Environ(ctx context.Context, extra map[string]string) []string {
+	return LayerEnv(ctx, os.Environ(), extra)
+}
@@ -0,0 +1,75 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithEnv_Layers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	if WithEnv(ctx, nil) != ctx {
+		t.Error("an empty env should not wrap the context")
+	}
+
+	workspace := WithEnv(ctx, map[string]string{"API_URL": "https://a.example", "REGION": "eu"})
+	session := WithEnv(workspace, map[string]string{"API_URL": "https://staging.example"})
+
+	if got := EnvFromContext(session); got["API_URL"] != "https://staging.example" || got["REGION"] != "eu" {
+		t.Errorf("layered env = %v", got)
+	}
+	// The outer context is not modified by the inner layer.
+	if got := EnvFromContext(workspace)["API_URL"]; got != "https://a.example" {
+		t.Errorf("workspace layer changed to %q", got)
+	}
+}
+
+func TestLayerEnv(t *testing.T) {
+	t.Parallel()
+	base := []string{"PATH=/usr/bin", "API_URL=https://base.example", "TOKEN=base"}
+	ctx := WithEnv(context.Background(), map[string]string{"API_URL": "https://ws.example", "TOKEN": "ws"})
+
+	got := LayerEnv(ctx, base, map[string]string{"TOKEN": "call"})
+	slices.Sort(got)
+	want := []string{"API_URL=https://ws.example", "PATH=/usr/bin", "TOKEN=call"}
+	if !slices.Equal(got, want) {
+		t.Errorf("LayerEnv = %v, want %v", got, want)
+	}
+
+	if got := LayerEnv(context.Background(), base, nil); !slices.Equal(got, base) {
+		t.Errorf("no layers should return base unchanged, got %v", got)
+	}
+}
+
+func TestRunnerRun_UsesContextEnv(t *testing.T) {
+	t.Parallel()
+	script := filepath.Join(t.TempDir(), "env.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$API_URL $REGION\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.TempDir = t.TempDir()
+	runner, err := NewRunner(cfg, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	defer runner.Close()
+
+	ctx := WithEnv(context.Background(), map[string]string{"API_URL": "https://ws.example", "REGION": "eu"})
+	result, err := runner.Run(ctx, &ExecRequest{
+		Runtime: RuntimeShell,
+		Script:  script,
+		Env:     map[string]string{"REGION": "us"},
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := strings.TrimSpace(result.Stdout); got != "https://ws.example us" {
+		t.Errorf("stdout = %q, want the request env over the context env", got)
+	}
+}
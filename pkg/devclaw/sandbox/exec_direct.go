@@ -11,7 +11,6 @@ import (
 	"os"
 	"os/exec"
 	"strings"
-	"syscall"
 )
 
 // DirectExecutor runs scripts directly via exec.Command.
@@ -88,18 +87,8 @@ func (e *DirectExecutor) buildCommand(ctx context.Context, req *ExecRequest) (*e
 	// Build environment.
 	cmd.Env = e.buildEnv(req)
 
-	// Set process group so we can kill the whole tree on timeout.
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-	}
-
-	// Cancel kills the process group.
-	cmd.Cancel = func() error {
-		if cmd.Process != nil {
-			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-		}
-		return nil
-	}
+	// Kill the whole process tree on timeout.
+	KillTreeOnCancel(cmd)
 
 	return cmd, nil
 }
@@ -111,7 +100,7 @@ func (e *DirectExecutor) resolveCommand(req *ExecRequest) (string, []string) {
 	switch req.Runtime {
 	case RuntimePython:
 		if interpreter == "" {
-			interpreter = "python3"
+			interpreter = defaultPython()
 		}
 		args := append([]string{"-u", req.Script}, req.Args...)
 		return interpreter, args
@@ -125,11 +114,17 @@ func (e *DirectExecutor) resolveCommand(req *ExecRequest) (string, []string) {
 
 	case RuntimeShell:
 		if interpreter == "" {
-			interpreter = "/bin/sh"
+			interpreter = defaultShell()
 		}
 		args := append([]string{req.Script}, req.Args...)
 		return interpreter, args
 
+	case RuntimePowerShell:
+		if interpreter == "" {
+			interpreter = PowerShell()
+		}
+		return interpreter, PowerShellScriptArgs(req.Script, req.Args)
+
 	case RuntimeBinary:
 		return req.Script, req.Args
 
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -109,6 +110,7 @@ func (e *DockerExecutor) BuildImage(ctx context.Context, dockerfilePath string)
 // buildDockerArgs constructs the docker run command arguments.
 func (e *DockerExecutor) buildDockerArgs(req *ExecRequest) []string {
 	args := []string{"run", "--rm"}
+	profile := e.cfg.profileFor(req)
 
 	// Security options.
 	args = append(args, "--security-opt", "no-new-privileges")
@@ -116,13 +118,13 @@ func (e *DockerExecutor) buildDockerArgs(req *ExecRequest) []string {
 	args = append(args, "--read-only")
 
 	// Resource limits.
-	if e.cfg.MaxMemoryMB > 0 {
-		args = append(args, "--memory", fmt.Sprintf("%dm", e.cfg.MaxMemoryMB))
-		args = append(args, "--memory-swap", fmt.Sprintf("%dm", e.cfg.MaxMemoryMB))
+	if profile.MaxMemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", profile.MaxMemoryMB))
+		args = append(args, "--memory-swap", fmt.Sprintf("%dm", profile.MaxMemoryMB))
 	}
-	if e.cfg.MaxCPUPercent > 0 {
+	if profile.MaxCPUPercent > 0 {
 		// Docker --cpus expects a float (e.g., 0.5 = 50%).
-		cpus := float64(e.cfg.MaxCPUPercent) / 100.0
+		cpus := float64(profile.MaxCPUPercent) / 100.0
 		args = append(args, "--cpus", strconv.FormatFloat(cpus, 'f', 2, 64))
 	}
 
@@ -131,6 +133,15 @@ func (e *DockerExecutor) buildDockerArgs(req *ExecRequest) []string {
 	if network == "" {
 		network = "none"
 	}
+	// A profile's network switch overrides the configured mode.
+	if profile.Network != nil {
+		switch {
+		case !*profile.Network:
+			network = "none"
+		case network == "none":
+			network = "bridge"
+		}
+	}
 	args = append(args, "--network", network)
 
 	// Timeout via Docker's --stop-timeout.
@@ -161,6 +172,12 @@ func (e *DockerExecutor) buildDockerArgs(req *ExecRequest) []string {
 		args = append(args, "-v", vol)
 	}
 
+	// Read-only mounts from the profile.
+	for _, m := range profile.ReadOnlyMounts {
+		host, target := splitMount(m)
+		args = append(args, "-v", host+":"+target+":ro")
+	}
+
 	// Environment variables.
 	for k, v := range req.Env {
 		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
@@ -191,7 +208,8 @@ func (e *DockerExecutor) resolveContainerCommand(req *ExecRequest) (string, []st
 	// Inside the container, skill scripts are at /skill/scripts/...
 	script := req.Script
 	if req.SkillDir != "" && strings.HasPrefix(script, req.SkillDir) {
-		script = "/skill" + strings.TrimPrefix(script, req.SkillDir)
+		// Container paths are always slash-separated, even on Windows hosts.
+		script = "/skill" + filepath.ToSlash(strings.TrimPrefix(script, req.SkillDir))
 	}
 
 	switch req.Runtime {
@@ -201,6 +219,8 @@ func (e *DockerExecutor) resolveContainerCommand(req *ExecRequest) (string, []st
 		return "node", append([]string{script}, req.Args...)
 	case RuntimeShell:
 		return "/bin/sh", append([]string{script}, req.Args...)
+	case RuntimePowerShell:
+		return "pwsh", PowerShellScriptArgs(script, req.Args)
 	default:
 		return script, req.Args
 	}
@@ -0,0 +1,97 @@
+// Package sandbox – exec_firejail.go implements the firejail executor.
+//
+// This executor provides:
+//   - Seccomp filter, no capabilities, no new privileges
+//   - Network isolation (--net=none unless the profile allows it)
+//   - Memory and CPU-time limits via rlimits
+//   - Read-only skill and profile mounts; the working directory stays
+//     writable, as with the restricted and direct executors
+//
+// firejail cannot remap paths, so "host:sandbox" mounts are applied at
+// the host path.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+)
+
+// FirejailExecutor runs scripts under firejail.
+type FirejailExecutor struct {
+	cfg    Config
+	logger *slog.Logger
+	bin    string
+}
+
+// NewFirejailExecutor creates a new firejail executor.
+func NewFirejailExecutor(cfg Config, logger *slog.Logger) *FirejailExecutor {
+	return &FirejailExecutor{cfg: cfg, logger: logger, bin: jailBinary("firejail")}
+}
+
+// Execute runs the script inside a firejail sandbox.
+func (e *FirejailExecutor) Execute(ctx context.Context, req *ExecRequest) (*ExecResult, error) {
+	if !e.Available() {
+		return nil, fmt.Errorf("firejail not installed")
+	}
+
+	cmd := exec.CommandContext(ctx, e.bin, e.buildArgs(req)...)
+	if req.WorkDir != "" {
+		cmd.Dir = req.WorkDir
+	} else if req.SkillDir != "" {
+		cmd.Dir = req.SkillDir
+	}
+	cmd.Env = jailEnv(req)
+
+	return runJailed(ctx, cmd, req)
+}
+
+// Available reports whether firejail is installed.
+func (e *FirejailExecutor) Available() bool { return e.bin != "" }
+
+// Name returns the executor name.
+func (e *FirejailExecutor) Name() string { return "firejail" }
+
+// Close is a no-op.
+func (e *FirejailExecutor) Close() error { return nil }
+
+// buildArgs constructs the firejail arguments for the request.
+func (e *FirejailExecutor) buildArgs(req *ExecRequest) []string {
+	profile := e.cfg.profileFor(req)
+
+	args := []string{
+		"--quiet",
+		"--noprofile",
+		"--caps.drop=all",
+		"--nonewprivs",
+		"--noroot",
+		"--seccomp",
+		"--private-dev",
+	}
+
+	if !e.cfg.allowNetwork(profile) {
+		args = append(args, "--net=none")
+	}
+
+	// Resource limits.
+	if profile.MaxMemoryMB > 0 {
+		args = append(args, "--rlimit-as="+strconv.FormatInt(int64(profile.MaxMemoryMB)*1024*1024, 10))
+	}
+	if secs := cpuSeconds(profile.MaxCPUPercent, req.Timeout); secs > 0 {
+		args = append(args, "--rlimit-cpu="+strconv.Itoa(secs))
+	}
+
+	// Read-only mounts. The working directory stays writable.
+	if req.SkillDir != "" && req.SkillDir != req.WorkDir {
+		args = append(args, "--read-only="+req.SkillDir)
+	}
+	for _, m := range profile.ReadOnlyMounts {
+		host, _ := splitMount(m)
+		args = append(args, "--read-only="+host)
+	}
+
+	args = append(args, "--")
+	return append(args, jailedCommand(e.cfg, req)...)
+}
@@ -0,0 +1,101 @@
+// Package sandbox – exec_jail.go holds what the firejail and nsjail
+// executors share: locating the jail binary, the minimal environment and
+// running the wrapped command.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// jailBinary returns the path of a jail tool, or "" when it isn't
+// installed. Jails need Linux namespaces, so other systems report "".
+func jailBinary(name string) string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// jailedCommand resolves the interpreter of req to an absolute path (jails
+// exec it without a PATH lookup) and returns it with its arguments.
+func jailedCommand(cfg Config, req *ExecRequest) []string {
+	bin, args := resolveInterpreter(cfg, req)
+	if !filepath.IsAbs(bin) {
+		if path, err := exec.LookPath(bin); err == nil {
+			bin = path
+		}
+	}
+	return append([]string{bin}, args...)
+}
+
+// jailEnv returns the minimal environment of a jailed script.
+func jailEnv(req *ExecRequest) []string {
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"LANG=en_US.UTF-8",
+		"LC_ALL=en_US.UTF-8",
+		"TERM=xterm",
+	}
+	for k, v := range req.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// runJailed runs a jail command and collects its result. Jails exit with
+// 128+signal when the script is killed, so those codes are reported as
+// kill reasons too.
+func runJailed(ctx context.Context, cmd *exec.Cmd, req *ExecRequest) (*ExecResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if req.Stdin != "" {
+		cmd.Stdin = strings.NewReader(req.Stdin)
+	}
+
+	KillTreeOnCancel(cmd)
+	err := cmd.Run()
+
+	result := &ExecResult{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: 0,
+	}
+
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return result, fmt.Errorf("executing script: %w", err)
+		}
+		result.ExitCode = exitErr.ExitCode()
+		if reason, ok := signalKillReason(exitErr); ok {
+			result.Killed = true
+			result.KillReason = reason
+		}
+		switch result.ExitCode {
+		case 137: // SIGKILL, usually the memory limit.
+			result.Killed = true
+			result.KillReason = "killed (possible OOM)"
+		case 152: // SIGXCPU, the CPU-time limit.
+			result.Killed = true
+			result.KillReason = "cpu_limit"
+		}
+		if ctx.Err() != nil {
+			result.Killed = true
+			result.KillReason = "timeout"
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hasSeq reports whether seq appears in args as consecutive elements.
+func hasSeq(args []string, seq ...string) bool {
+	for i := 0; i+len(seq) <= len(args); i++ {
+		if slices.Equal(args[i:i+len(seq)], seq) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJailBuildArgs(t *testing.T) {
+	on, off := true, false
+	cfg := DefaultConfig()
+	cfg.MaxMemoryMB = 256
+	cfg.MaxCPUPercent = 50
+	cfg.Profiles = map[string]Profile{
+		"net":    {Network: &on, MaxMemoryMB: 64, ReadOnlyMounts: []string{"/data", "/srv/models:/models"}},
+		"closed": {Network: &off, MaxCPUPercent: 100},
+	}
+	req := func(profile string) *ExecRequest {
+		return &ExecRequest{
+			Runtime:  RuntimeShell,
+			Profile:  profile,
+			Script:   "/skills/demo/run.sh",
+			Args:     []string{"arg"},
+			SkillDir: "/skills/demo",
+			WorkDir:  "/work",
+			Timeout:  10 * time.Second,
+			Env:      map[string]string{"DEVCLAW_TMPDIR": "/tmp/run1"},
+		}
+	}
+
+	firejail := &FirejailExecutor{cfg: cfg, logger: slog.Default(), bin: "/usr/bin/firejail"}
+	nsjail := &NsjailExecutor{cfg: cfg, logger: slog.Default(), bin: "/usr/bin/nsjail"}
+
+	cases := []struct {
+		name    string
+		args    []string
+		want    [][]string
+		notWant [][]string
+	}{
+		{
+			name: "firejail defaults",
+			args: firejail.buildArgs(req("")),
+			want: [][]string{
+				{"--net=none"}, {"--rlimit-as=268435456"}, {"--rlimit-cpu=5"},
+				{"--read-only=/skills/demo"}, {"--", "/bin/sh", "/skills/demo/run.sh", "arg"},
+			},
+			notWant: [][]string{{"--read-only=/work"}},
+		},
+		{
+			name: "firejail profile",
+			args: firejail.buildArgs(req("net")),
+			want: [][]string{
+				{"--rlimit-as=67108864"}, {"--read-only=/data"}, {"--read-only=/srv/models"},
+			},
+			notWant: [][]string{{"--net=none"}, {"--read-only=/work"}},
+		},
+		{
+			name: "firejail no cpu limit",
+			args: firejail.buildArgs(req("closed")),
+			want: [][]string{{"--net=none"}},
+			notWant: [][]string{
+				{"--rlimit-cpu=5"}, {"--rlimit-cpu=10"},
+			},
+		},
+		{
+			name: "nsjail defaults",
+			args: nsjail.buildArgs(req("")),
+			want: [][]string{
+				{"--rlimit_as", "256"}, {"--rlimit_cpu", "5"},
+				{"-R", "/skills/demo"}, {"-B", "/work"}, {"-B", "/tmp/run1"},
+				{"--cwd", "/work"}, {"--env", "DEVCLAW_TMPDIR=/tmp/run1"},
+				{"--", "/bin/sh", "/skills/demo/run.sh", "arg"},
+			},
+			notWant: [][]string{{"--disable_clone_newnet"}, {"-R", "/work"}},
+		},
+		{
+			name: "nsjail profile",
+			args: nsjail.buildArgs(req("net")),
+			want: [][]string{
+				{"--disable_clone_newnet"}, {"--rlimit_as", "64"},
+				{"-R", "/data"}, {"-R", "/srv/models:/models"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, seq := range tc.want {
+				if !hasSeq(tc.args, seq...) {
+					t.Errorf("missing %q in %q", seq, tc.args)
+				}
+			}
+			for _, seq := range tc.notWant {
+				if hasSeq(tc.args, seq...) {
+					t.Errorf("unexpected %q in %q", seq, tc.args)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerFallsBackWhenJailMissing(t *testing.T) {
+	// A PATH without firejail, nsjail or docker.
+	bin := t.TempDir()
+	if err := os.Symlink("/bin/sh", filepath.Join(bin, "sh")); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	script := filepath.Join(t.TempDir(), "hello.sh")
+	if err := os.WriteFile(script, []byte("echo hello\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range []IsolationLevel{IsolationFirejail, IsolationNsjail} {
+		t.Run(string(level), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TempDir = t.TempDir()
+			cfg.Profiles = map[string]Profile{"jail": {Isolation: level}}
+			cfg.Tools = map[string]string{"skill_*": "jail"}
+			runner, err := NewRunner(cfg, slog.New(slog.DiscardHandler))
+			if err != nil {
+				t.Fatalf("NewRunner: %v", err)
+			}
+			defer runner.Close()
+
+			fallback := runner.fallbackExecutor(level)
+			if fallback == nil || (fallback.Name() != "restricted" && fallback.Name() != "direct") {
+				t.Fatalf("fallback executor = %v", fallback)
+			}
+			result, err := runner.RunShell(WithTool(context.Background(), "skill_hello"), script, nil, "")
+			if err != nil || strings.TrimSpace(result.Stdout) != "hello" {
+				t.Errorf("run = %+v, err = %v", result, err)
+			}
+		})
+	}
+}
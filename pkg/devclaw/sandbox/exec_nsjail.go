@@ -0,0 +1,121 @@
+// Package sandbox – exec_nsjail.go implements the nsjail executor.
+//
+// This executor provides:
+//   - PID, mount, IPC, UTS and network namespaces
+//   - A filesystem of read-only system directories, the skill and the
+//     profile mounts, plus the writable working directory and tmpdir
+//   - Memory and CPU-time limits via rlimits
+//   - An environment limited to the filtered request variables
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// nsjailSystemMounts are the host paths mounted read-only in every jail
+// so interpreters and their libraries run. Missing ones are skipped.
+var nsjailSystemMounts = []string{
+	"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/etc",
+	"/dev/null", "/dev/zero", "/dev/urandom",
+}
+
+// NsjailExecutor runs scripts under nsjail.
+type NsjailExecutor struct {
+	cfg    Config
+	logger *slog.Logger
+	bin    string
+}
+
+// NewNsjailExecutor creates a new nsjail executor.
+func NewNsjailExecutor(cfg Config, logger *slog.Logger) *NsjailExecutor {
+	return &NsjailExecutor{cfg: cfg, logger: logger, bin: jailBinary("nsjail")}
+}
+
+// Execute runs the script inside an nsjail sandbox.
+func (e *NsjailExecutor) Execute(ctx context.Context, req *ExecRequest) (*ExecResult, error) {
+	if !e.Available() {
+		return nil, fmt.Errorf("nsjail not installed")
+	}
+
+	cmd := exec.CommandContext(ctx, e.bin, e.buildArgs(req)...)
+	return runJailed(ctx, cmd, req)
+}
+
+// Available reports whether nsjail is installed.
+func (e *NsjailExecutor) Available() bool { return e.bin != "" }
+
+// Name returns the executor name.
+func (e *NsjailExecutor) Name() string { return "nsjail" }
+
+// Close is a no-op.
+func (e *NsjailExecutor) Close() error { return nil }
+
+// buildArgs constructs the nsjail arguments for the request.
+func (e *NsjailExecutor) buildArgs(req *ExecRequest) []string {
+	profile := e.cfg.profileFor(req)
+
+	args := []string{
+		"--mode", "o",
+		"--quiet",
+		// The runner enforces the timeout through the context.
+		"--time_limit", "0",
+		// nsjail defaults to 1MB files and 32 descriptors.
+		"--rlimit_fsize", "soft",
+		"--rlimit_nofile", "soft",
+	}
+
+	if e.cfg.allowNetwork(profile) {
+		args = append(args, "--disable_clone_newnet")
+	}
+
+	// Resource limits.
+	if profile.MaxMemoryMB > 0 {
+		args = append(args, "--rlimit_as", strconv.Itoa(profile.MaxMemoryMB))
+	}
+	if secs := cpuSeconds(profile.MaxCPUPercent, req.Timeout); secs > 0 {
+		args = append(args, "--rlimit_cpu", strconv.Itoa(secs))
+	}
+
+	// Filesystem: system directories and mounts read-only, the working
+	// directory and tmpdir writable.
+	for _, dir := range nsjailSystemMounts {
+		if _, err := os.Stat(dir); err == nil {
+			args = append(args, "-R", dir)
+		}
+	}
+	if req.SkillDir != "" && req.SkillDir != req.WorkDir {
+		args = append(args, "-R", req.SkillDir)
+	}
+	if req.WorkDir != "" {
+		args = append(args, "-B", req.WorkDir)
+	}
+	for _, m := range profile.ReadOnlyMounts {
+		if host, target := splitMount(m); host == target {
+			args = append(args, "-R", host)
+		} else {
+			args = append(args, "-R", host+":"+target)
+		}
+	}
+	if tmpDir := req.Env["DEVCLAW_TMPDIR"]; tmpDir != "" {
+		args = append(args, "-B", tmpDir)
+	}
+
+	if req.WorkDir != "" {
+		args = append(args, "--cwd", req.WorkDir)
+	} else if req.SkillDir != "" {
+		args = append(args, "--cwd", req.SkillDir)
+	}
+
+	// nsjail starts the script with an empty environment.
+	for _, kv := range jailEnv(req) {
+		args = append(args, "--env", kv)
+	}
+
+	args = append(args, "--")
+	return append(args, jailedCommand(e.cfg, req)...)
+}
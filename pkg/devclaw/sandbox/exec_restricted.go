@@ -4,8 +4,8 @@
 // This executor provides:
 //   - PID namespace isolation (process can't see other processes)
 //   - Network namespace isolation (optional, blocks network by default)
-//   - Mount namespace with read-only bind mounts
-//   - Resource limits via setrlimit
+//   - Mount namespace with the profile's read-only bind mounts
+//   - Memory and CPU-time limits via rlimits
 //   - Filtered environment variables
 //
 // Requires Linux with user namespaces enabled (most modern distros).
@@ -21,7 +21,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
-	"syscall"
+	"time"
 )
 
 // RestrictedExecutor runs scripts with Linux namespace isolation.
@@ -67,18 +67,9 @@ func (e *RestrictedExecutor) Execute(ctx context.Context, req *ExecRequest) (*Ex
 			result.ExitCode = exitErr.ExitCode()
 
 			// Check if killed by signal (e.g., OOM killer).
-			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				if status.Signaled() {
-					result.Killed = true
-					switch status.Signal() {
-					case syscall.SIGKILL:
-						result.KillReason = "killed (possible OOM)"
-					case syscall.SIGXCPU:
-						result.KillReason = "cpu_limit"
-					default:
-						result.KillReason = fmt.Sprintf("signal_%d", status.Signal())
-					}
-				}
+			if reason, ok := signalKillReason(exitErr); ok {
+				result.Killed = true
+				result.KillReason = reason
 			}
 
 			if ctx.Err() != nil {
@@ -116,7 +107,8 @@ func (e *RestrictedExecutor) Close() error { return nil }
 
 // buildCommand constructs an isolated exec.Cmd.
 func (e *RestrictedExecutor) buildCommand(ctx context.Context, req *ExecRequest) (*exec.Cmd, error) {
-	bin, args := resolveInterpreter(e.cfg, req)
+	profile := e.cfg.profileFor(req)
+	bin, args := restrictedCommand(e.cfg, req, profile)
 
 	cmd := exec.CommandContext(ctx, bin, args...)
 
@@ -131,53 +123,52 @@ func (e *RestrictedExecutor) buildCommand(ctx context.Context, req *ExecRequest)
 	cmd.Env = e.buildEnv(req)
 
 	// Apply Linux namespace isolation.
-	allowNet := e.cfg.AllowNetwork != nil && *e.cfg.AllowNetwork
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		// New process group for clean termination.
-		Setpgid: true,
-
-		// Linux namespace flags:
-		// CLONE_NEWPID:  Isolate process IDs.
-		// CLONE_NEWNS:   Isolate mount points.
-		// CLONE_NEWUSER: Unprivileged user namespace.
-		Cloneflags: syscall.CLONE_NEWPID |
-			syscall.CLONE_NEWNS |
-			syscall.CLONE_NEWUSER |
-			e.netCloneFlag(allowNet),
-
-		// Map current user to root inside the namespace.
-		UidMappings: []syscall.SysProcIDMap{{
-			ContainerID: 0,
-			HostID:      os.Getuid(),
-			Size:        1,
-		}},
-		GidMappings: []syscall.SysProcIDMap{{
-			ContainerID: 0,
-			HostID:      os.Getgid(),
-			Size:        1,
-		}},
-	}
-
-	// Set resource limits.
-	e.setResourceLimits(cmd)
+	cmd.SysProcAttr = namespaceAttr(e.cfg.allowNetwork(profile))
 
 	// Kill process group on cancel.
-	cmd.Cancel = func() error {
-		if cmd.Process != nil {
-			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-		}
-		return nil
-	}
+	KillTreeOnCancel(cmd)
 
 	return cmd, nil
 }
 
-// netCloneFlag returns CLONE_NEWNET if network should be isolated.
-func (e *RestrictedExecutor) netCloneFlag(allowNet bool) uintptr {
-	if !allowNet {
-		return syscall.CLONE_NEWNET
+// restrictedCommand returns the binary and arguments of a restricted run.
+// rlimits and bind mounts can't be set up from Go between clone and exec,
+// so when the profile has any, a shell inside the namespaces applies them
+// and then execs the interpreter.
+func restrictedCommand(cfg Config, req *ExecRequest, profile Profile) (string, []string) {
+	bin, args := resolveInterpreter(cfg, req)
+	prologue := restrictedPrologue(profile, req.Timeout)
+	if prologue == "" {
+		return bin, args
+	}
+	if path, err := exec.LookPath(bin); err == nil {
+		bin = path
+	}
+	return "/bin/sh", append([]string{"-c", prologue + `exec "$@"`, "sh", bin}, args...)
+}
+
+// restrictedPrologue returns the shell commands applying a profile:
+// read-only bind mounts (the mount namespace is the script's own) and
+// memory and CPU-time rlimits. A step that fails aborts the run with 126.
+func restrictedPrologue(profile Profile, timeout time.Duration) string {
+	var b strings.Builder
+	for _, m := range profile.ReadOnlyMounts {
+		host, target := splitMount(m)
+		fmt.Fprintf(&b, "mount --bind %s %s && mount -o remount,bind,ro %s || exit 126\n",
+			quoteShellWord(host), quoteShellWord(target), quoteShellWord(target))
+	}
+	if profile.MaxMemoryMB > 0 {
+		fmt.Fprintf(&b, "ulimit -v %d || exit 126\n", profile.MaxMemoryMB*1024)
+	}
+	if secs := cpuSeconds(profile.MaxCPUPercent, timeout); secs > 0 {
+		fmt.Fprintf(&b, "ulimit -t %d || exit 126\n", secs)
 	}
-	return 0
+	return b.String()
+}
+
+// quoteShellWord quotes s as one POSIX shell word.
+func quoteShellWord(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // buildEnv creates a minimal environment for the sandboxed process.
@@ -198,23 +189,6 @@ func (e *RestrictedExecutor) buildEnv(req *ExecRequest) []string {
 	return env
 }
 
-// setResourceLimits applies CPU and memory limits via rlimit.
-// Note: rlimits are inherited by the child process.
-func (e *RestrictedExecutor) setResourceLimits(_ *exec.Cmd) {
-	// Memory limit: set RLIMIT_AS (address space).
-	if e.cfg.MaxMemoryMB > 0 {
-		memBytes := uint64(e.cfg.MaxMemoryMB) * 1024 * 1024
-		var rlim syscall.Rlimit
-		rlim.Cur = memBytes
-		rlim.Max = memBytes
-		// Note: We can't set rlimits for the child process from Go
-		// before exec. The child would need to set them itself, or
-		// we use cgroups. For now, this is a best-effort approach.
-		// The Docker executor provides proper resource limits.
-		_ = rlim
-	}
-}
-
 // resolveInterpreter determines the binary and arguments from runtime.
 func resolveInterpreter(cfg Config, req *ExecRequest) (string, []string) {
 	interpreter := cfg.Runtimes[req.Runtime]
@@ -222,7 +196,7 @@ func resolveInterpreter(cfg Config, req *ExecRequest) (string, []string) {
 	switch req.Runtime {
 	case RuntimePython:
 		if interpreter == "" {
-			interpreter = "python3"
+			interpreter = defaultPython()
 		}
 		return interpreter, append([]string{"-u", req.Script}, req.Args...)
 
@@ -234,10 +208,16 @@ func resolveInterpreter(cfg Config, req *ExecRequest) (string, []string) {
 
 	case RuntimeShell:
 		if interpreter == "" {
-			interpreter = "/bin/sh"
+			interpreter = defaultShell()
 		}
 		return interpreter, append([]string{req.Script}, req.Args...)
 
+	case RuntimePowerShell:
+		if interpreter == "" {
+			interpreter = PowerShell()
+		}
+		return interpreter, PowerShellScriptArgs(req.Script, req.Args)
+
 	default:
 		return req.Script, req.Args
 	}
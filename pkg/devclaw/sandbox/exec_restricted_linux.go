@@ -0,0 +1,44 @@
+// Package sandbox – exec_restricted_linux.go builds the namespace
+// attributes of the restricted executor.
+package sandbox
+
+import (
+	"os"
+	"syscall"
+)
+
+// namespaceAttr returns the SysProcAttr that isolates the script in new
+// PID, mount and user namespaces, plus a network namespace unless
+// allowNet is set.
+func namespaceAttr(allowNet bool) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		// Linux namespace flags:
+		// CLONE_NEWPID:  Isolate process IDs.
+		// CLONE_NEWNS:   Isolate mount points.
+		// CLONE_NEWUSER: Unprivileged user namespace.
+		Cloneflags: syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWUSER |
+			netCloneFlag(allowNet),
+
+		// Map current user to root inside the namespace.
+		UidMappings: []syscall.SysProcIDMap{{
+			ContainerID: 0,
+			HostID:      os.Getuid(),
+			Size:        1,
+		}},
+		GidMappings: []syscall.SysProcIDMap{{
+			ContainerID: 0,
+			HostID:      os.Getgid(),
+			Size:        1,
+		}},
+	}
+}
+
+// netCloneFlag returns CLONE_NEWNET if network should be isolated.
+func netCloneFlag(allowNet bool) uintptr {
+	if !allowNet {
+		return syscall.CLONE_NEWNET
+	}
+	return 0
+}
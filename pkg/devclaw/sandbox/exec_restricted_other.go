@@ -0,0 +1,14 @@
+//go:build !linux
+
+// Package sandbox – exec_restricted_other.go stubs the namespace
+// attributes on systems without Linux namespaces, where the restricted
+// executor reports itself unavailable and the runner falls back.
+package sandbox
+
+import "syscall"
+
+// namespaceAttr returns an empty SysProcAttr; it is never used because
+// RestrictedExecutor.Available is false off Linux.
+func namespaceAttr(_ bool) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{}
+}
@@ -0,0 +1,88 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestrictedPrologue(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		profile Profile
+		timeout time.Duration
+		want    []string
+	}{
+		{"empty", Profile{}, time.Minute, nil},
+		{"memory", Profile{MaxMemoryMB: 128}, time.Minute, []string{"ulimit -v 131072"}},
+		{"cpu", Profile{MaxCPUPercent: 25}, 40 * time.Second, []string{"ulimit -t 10"}},
+		{"full cpu", Profile{MaxCPUPercent: 100}, time.Minute, nil},
+		{
+			"mounts", Profile{ReadOnlyMounts: []string{"/data", "/srv/it's:/models"}}, 0,
+			[]string{
+				"mount --bind '/data' '/data' && mount -o remount,bind,ro '/data'",
+				`mount --bind '/srv/it'\''s' '/models' && mount -o remount,bind,ro '/models'`,
+			},
+		},
+	}
+	for _, tc := range cases {
+		got := restrictedPrologue(tc.profile, tc.timeout)
+		if len(tc.want) == 0 && got != "" {
+			t.Errorf("%s: prologue = %q, want none", tc.name, got)
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: prologue %q lacks %q", tc.name, got, w)
+			}
+		}
+	}
+
+	bin, args := restrictedCommand(DefaultConfig(), &ExecRequest{Runtime: RuntimeShell, Script: "/s.sh", Timeout: time.Minute},
+		Profile{MaxMemoryMB: 64})
+	if bin != "/bin/sh" || len(args) != 5 || args[0] != "-c" || !strings.HasSuffix(args[1], `exec "$@"`) || args[4] != "/s.sh" {
+		t.Errorf("restrictedCommand = %q %q", bin, args)
+	}
+}
+
+func TestRestrictedExecutorAppliesProfile(t *testing.T) {
+	if !NewRestrictedExecutor(DefaultConfig(), nil).Available() {
+		t.Skip("restricted executor not available")
+	}
+	mount := t.TempDir()
+	script := filepath.Join(t.TempDir(), "probe.sh")
+	body := "ulimit -v; ulimit -t; touch " + mount + "/f && echo wrote || echo read-only\n"
+	if err := os.WriteFile(script, []byte(body), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.TempDir = t.TempDir()
+	cfg.Profiles = map[string]Profile{"tight": {
+		Isolation:      IsolationRestricted,
+		MaxMemoryMB:    128,
+		MaxCPUPercent:  20,
+		ReadOnlyMounts: []string{mount},
+		Timeout:        10 * time.Second,
+	}}
+	runner, err := NewRunner(cfg, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	defer runner.Close()
+
+	result, err := runner.Run(context.Background(), &ExecRequest{Runtime: RuntimeShell, Script: script, Profile: "tight"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if strings.Contains(result.Stderr, "Operation not permitted") {
+		t.Skipf("mounts not permitted here: %s", result.Stderr)
+	}
+	if got := strings.Fields(result.Stdout); len(got) != 3 || got[0] != "131072" || got[1] != "2" || got[2] != "read-only" {
+		t.Errorf("stdout = %q, stderr = %q", result.Stdout, result.Stderr)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build !windows
+
+// Package sandbox – proc_unix.go implements process-tree control on Unix:
+// scripts run in their own process group so a timeout kills everything
+// they spawned, and signal exits are reported as kill reasons.
+package sandbox
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// KillTreeOnCancel starts cmd in a new process group and makes context
+// cancellation kill the whole group (background jobs, nohup, etc.).
+// Call it after setting any other SysProcAttr fields.
+func KillTreeOnCancel(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// Negative PID signals the whole process group.
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// signalKillReason reports why a process was killed by a signal
+// (OOM killer, CPU rlimit). ok is false for normal exits.
+func signalKillReason(exitErr *exec.ExitError) (reason string, ok bool) {
+	status, isWait := exitErr.Sys().(syscall.WaitStatus)
+	if !isWait || !status.Signaled() {
+		return "", false
+	}
+	switch status.Signal() {
+	case syscall.SIGKILL:
+		return "killed (possible OOM)", true
+	case syscall.SIGXCPU:
+		return "cpu_limit", true
+	default:
+		return fmt.Sprintf("signal_%d", status.Signal()), true
+	}
+}
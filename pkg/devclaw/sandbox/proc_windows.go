@@ -0,0 +1,38 @@
+// Package sandbox – proc_windows.go implements process-tree control on
+// Windows. There are no process groups to signal, so cancellation asks
+// taskkill to end the process and all of its descendants.
+package sandbox
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// KillTreeOnCancel starts cmd in a new process group and makes context
+// cancellation kill the process tree. Call it after setting any other
+// SysProcAttr fields.
+func KillTreeOnCancel(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		// taskkill /T walks the child tree; fall back to killing the
+		// process itself when taskkill is unavailable or fails.
+		kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+		if err := kill.Run(); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+}
+
+// signalKillReason always reports false: Windows processes do not exit
+// on signals, so kills surface only as timeouts.
+func signalKillReason(_ *exec.ExitError) (reason string, ok bool) {
+	return "", false
+}
@@ -0,0 +1,160 @@
+// Package sandbox – profile.go resolves sandbox profiles: named sets of
+// isolation backend and resource limits (CPU, memory, network, read-only
+// mounts) selected per tool through Config.Tools. The tool name reaches the
+// runner through the context, so callers such as the exec tool don't need
+// to know which profile applies to them.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Profile is a named set of isolation settings. Zero fields inherit the
+// Config defaults.
+type Profile struct {
+	// Isolation selects the backend (none, restricted, container,
+	// firejail, nsjail). Empty uses Config.DefaultIsolation.
+	Isolation IsolationLevel `yaml:"isolation"`
+
+	// MaxMemoryMB limits memory usage.
+	MaxMemoryMB int `yaml:"max_memory_mb"`
+
+	// MaxCPUPercent limits CPU usage as percentage of one core (0-100).
+	MaxCPUPercent int `yaml:"max_cpu_percent"`
+
+	// Network turns network access on or off. Unset uses
+	// Config.AllowNetwork (and docker.network for containers).
+	Network *bool `yaml:"network"`
+
+	// ReadOnlyMounts are host paths made visible read-only to the script,
+	// as "path" or "host:sandbox" (container, nsjail).
+	ReadOnlyMounts []string `yaml:"read_only_mounts"`
+
+	// Timeout overrides Config.Timeout.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type ctxKeyTool struct{}
+
+// WithTool returns a context whose runs use the profile Config.Tools maps
+// to tool.
+func WithTool(ctx context.Context, tool string) context.Context {
+	return context.WithValue(ctx, ctxKeyTool{}, tool)
+}
+
+// ToolFromContext returns the tool name set with WithTool, or "".
+func ToolFromContext(ctx context.Context) string {
+	tool, _ := ctx.Value(ctxKeyTool{}).(string)
+	return tool
+}
+
+// ProfileForTool returns the profile name mapped to tool: an exact entry
+// first, then the first matching glob pattern (e.g. "mcp_*") in sorted
+// order. Returns "" when no entry matches.
+func (c *Config) ProfileForTool(tool string) string {
+	if tool == "" {
+		return ""
+	}
+	if name, ok := c.Tools[tool]; ok {
+		return name
+	}
+	best := ""
+	for pattern := range c.Tools {
+		if ok, _ := path.Match(pattern, tool); ok && (best == "" || pattern < best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return c.Tools[best]
+}
+
+// profileFor returns the effective profile of a request: its named profile
+// with zero limits filled from the Config.
+func (c *Config) profileFor(req *ExecRequest) Profile {
+	p := c.Profiles[req.Profile]
+	if p.MaxMemoryMB <= 0 {
+		p.MaxMemoryMB = c.MaxMemoryMB
+	}
+	if p.MaxCPUPercent <= 0 {
+		p.MaxCPUPercent = c.MaxCPUPercent
+	}
+	return p
+}
+
+// allowNetwork reports whether the profile lets the script reach the
+// network, falling back to Config.AllowNetwork.
+func (c *Config) allowNetwork(p Profile) bool {
+	if p.Network != nil {
+		return *p.Network
+	}
+	return c.AllowNetwork != nil && *c.AllowNetwork
+}
+
+// cpuSeconds converts a CPU percentage into a CPU-time budget for a run of
+// the given timeout, for backends limited to rlimits. Returns 0 when either
+// is unset.
+func cpuSeconds(percent int, timeout time.Duration) int {
+	if percent <= 0 || percent >= 100 || timeout <= 0 {
+		return 0
+	}
+	return max(1, int(timeout.Seconds()*float64(percent)/100))
+}
+
+// validIsolation reports whether level names a known backend.
+func validIsolation(level IsolationLevel) bool {
+	switch level {
+	case IsolationNone, IsolationRestricted, IsolationContainer, IsolationFirejail, IsolationNsjail:
+		return true
+	}
+	return false
+}
+
+// validateProfiles checks profile backends and the tool mapping.
+func (c *Config) validateProfiles() error {
+	for name, p := range c.Profiles {
+		if p.Isolation != "" && !validIsolation(p.Isolation) {
+			return fmt.Errorf("profile %q: invalid isolation level: %q", name, p.Isolation)
+		}
+		if p.MaxCPUPercent < 0 || p.MaxCPUPercent > 100 {
+			return fmt.Errorf("profile %q: max_cpu_percent must be between 0 and 100", name)
+		}
+	}
+	for tool, name := range c.Tools {
+		if _, err := path.Match(tool, ""); err != nil {
+			return fmt.Errorf("tools: invalid pattern %q: %w", tool, err)
+		}
+		if _, ok := c.Profiles[name]; !ok {
+			return fmt.Errorf("tools: %q uses unknown profile %q", tool, name)
+		}
+	}
+	return nil
+}
+
+// usesIsolation reports whether the default level or a profile selects
+// level.
+func (c *Config) usesIsolation(level IsolationLevel) bool {
+	if c.DefaultIsolation == level {
+		return true
+	}
+	for _, p := range c.Profiles {
+		if p.Isolation == level {
+			return true
+		}
+	}
+	return false
+}
+
+// splitMount splits a "host:sandbox" mount; a bare path is mounted at the
+// same location. The colon of a Windows drive letter is not a separator.
+func splitMount(m string) (host, target string) {
+	if i := strings.LastIndex(m, ":"); i > 1 && i < len(m)-1 {
+		return m[:i], m[i+1:]
+	}
+	return m, m
+}
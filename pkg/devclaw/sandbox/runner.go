@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -61,15 +62,40 @@ func NewRunner(cfg Config, logger *slog.Logger) (*Runner, error) {
 		logger.Warn("sandbox: container executor not available")
 	}
 
+	// Try to register the jail executors (firejail, nsjail).
+	for _, jail := range []Executor{NewFirejailExecutor(cfg, logger), NewNsjailExecutor(cfg, logger)} {
+		if jail.Available() {
+			r.executors[IsolationLevel(jail.Name())] = jail
+			logger.Info("sandbox: " + jail.Name() + " executor available")
+		} else if cfg.usesIsolation(IsolationLevel(jail.Name())) {
+			logger.Warn("sandbox: " + jail.Name() + " not installed, profiles using it will fall back")
+		}
+	}
+
 	return r, nil
 }
 
 // Run executes a script with the configured sandbox.
 func (r *Runner) Run(ctx context.Context, req *ExecRequest) (*ExecResult, error) {
+	// Resolve the profile: the request's, else the one mapped to the tool.
+	if req.Profile == "" {
+		req.Profile = r.cfg.ProfileForTool(ToolFromContext(ctx))
+	}
+	profile, ok := r.cfg.Profiles[req.Profile]
+	if req.Profile != "" && !ok {
+		return nil, fmt.Errorf("unknown sandbox profile %q", req.Profile)
+	}
+
 	// Apply defaults.
+	if req.Isolation == "" {
+		req.Isolation = profile.Isolation
+	}
 	if req.Isolation == "" {
 		req.Isolation = r.cfg.DefaultIsolation
 	}
+	if req.Timeout == 0 {
+		req.Timeout = profile.Timeout
+	}
 	if req.Timeout == 0 {
 		req.Timeout = r.cfg.Timeout
 	}
@@ -121,7 +147,13 @@ func (r *Runner) Run(ctx context.Context, req *ExecRequest) (*ExecResult, error)
 		}
 	}
 
-	// Filter environment variables.
+	// Layer the request's variables over those carried by the context
+	// (e.g. the workspace env), then filter.
+	if ctxEnv := EnvFromContext(ctx); len(ctxEnv) > 0 {
+		env := maps.Clone(ctxEnv)
+		maps.Copy(env, req.Env)
+		req.Env = env
+	}
 	req.Env = r.policy.FilterEnv(req.Env)
 
 	// Prepare temp directory for this execution.
@@ -160,6 +192,7 @@ func (r *Runner) Run(ctx context.Context, req *ExecRequest) (*ExecResult, error)
 		"script", req.Script,
 		"runtime", req.Runtime,
 		"isolation", req.Isolation,
+		"profile", req.Profile,
 		"executor", executor.Name(),
 		"timeout", req.Timeout,
 	)
@@ -239,8 +272,8 @@ func (r *Runner) Close() error {
 // fallbackExecutor finds the best available executor when the
 // requested one isn't available.
 func (r *Runner) fallbackExecutor(requested IsolationLevel) Executor {
-	// Fallback order: container → restricted → none
-	order := []IsolationLevel{IsolationContainer, IsolationRestricted, IsolationNone}
+	// Fallback order: container → nsjail → firejail → restricted → none
+	order := []IsolationLevel{IsolationContainer, IsolationNsjail, IsolationFirejail, IsolationRestricted, IsolationNone}
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
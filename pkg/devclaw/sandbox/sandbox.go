@@ -6,6 +6,12 @@
 //   - none:       Direct exec.Command (trusted/builtin skills only)
 //   - restricted: Linux namespaces + resource limits (community skills)
 //   - container:  Docker-based full isolation (untrusted scripts)
+//   - firejail:   firejail jail with seccomp and dropped capabilities
+//   - nsjail:     nsjail with a minimal read-only filesystem
+//
+// Profiles (profile.go) bundle a backend with resource limits and are
+// selected per tool; a backend that isn't installed falls back to the
+// strongest one available.
 //
 // The sandbox enforces:
 //   - Execution timeouts
@@ -19,6 +25,9 @@ package sandbox
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -37,6 +46,14 @@ const (
 	// IsolationContainer runs scripts inside a Docker container
 	// with a purpose-built sandbox image.
 	IsolationContainer IsolationLevel = "container"
+
+	// IsolationFirejail runs scripts under firejail (seccomp, no
+	// capabilities, private /tmp, rlimits).
+	IsolationFirejail IsolationLevel = "firejail"
+
+	// IsolationNsjail runs scripts under nsjail with only system
+	// directories, the skill and the configured mounts visible.
+	IsolationNsjail IsolationLevel = "nsjail"
 )
 
 // Runtime identifies the script interpreter.
@@ -47,6 +64,10 @@ const (
 	RuntimeNode   Runtime = "node"
 	RuntimeShell  Runtime = "shell"
 	RuntimeBinary Runtime = "binary"
+
+	// RuntimePowerShell runs .ps1 scripts with pwsh, or Windows
+	// PowerShell when pwsh is not installed.
+	RuntimePowerShell Runtime = "powershell"
 )
 
 // Config holds the sandbox configuration.
@@ -95,8 +116,18 @@ type Config struct {
 	AllowNetwork *bool `yaml:"allow_network"`
 
 	// Runtimes maps Runtime to interpreter paths.
-	// Defaults: python→python3, node→node, shell→/bin/sh
+	// Defaults: python→python3, node→node, shell→/bin/sh, powershell→pwsh.
+	// On Windows: python→python, shell→sh (Git for Windows),
+	// powershell→pwsh or powershell.exe.
 	Runtimes map[Runtime]string `yaml:"runtimes"`
+
+	// Profiles are named isolation settings (backend, CPU, memory,
+	// network, read-only mounts) that tools can be assigned to.
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Tools maps tool names or glob patterns (e.g. "exec", "mcp_*") to
+	// a profile name. Unmapped tools use the defaults above.
+	Tools map[string]string `yaml:"tools"`
 }
 
 // DockerConfig holds Docker-specific sandbox settings.
@@ -122,9 +153,13 @@ type ExecRequest struct {
 	Runtime Runtime
 
 	// Isolation overrides the default isolation level.
-	// If empty, uses Config.DefaultIsolation.
+	// If empty, uses the profile's, then Config.DefaultIsolation.
 	Isolation IsolationLevel
 
+	// Profile names the profile in Config.Profiles to run with. If
+	// empty, the runner uses the profile mapped to the context's tool.
+	Profile string
+
 	// Script is the path to the script file to execute.
 	Script string
 
@@ -196,7 +231,7 @@ func DefaultConfig() Config {
 		MaxOutputBytes:   1 * 1024 * 1024, // 1MB
 		MaxMemoryMB:      256,
 		MaxCPUPercent:     50,
-		TempDir:          "/tmp/devclaw-sandbox",
+		TempDir:          filepath.Join(os.TempDir(), "devclaw-sandbox"),
 		AllowNetwork:     &allowNet,
 		Docker: DockerConfig{
 			Image:        "devclaw-sandbox:latest",
@@ -204,9 +239,9 @@ func DefaultConfig() Config {
 			Network:      "none",
 		},
 		Runtimes: map[Runtime]string{
-			RuntimePython: "python3",
+			RuntimePython: defaultPython(),
 			RuntimeNode:   "node",
-			RuntimeShell:  "/bin/sh",
+			RuntimeShell:  defaultShell(),
 		},
 		BlockedEnv: defaultBlockedEnv(),
 	}
@@ -248,8 +283,10 @@ var blockedEnvPrefixes = []string{
 	"DYLD_",
 }
 
-// DetectRuntime guesses the runtime from a script path.
+// DetectRuntime guesses the runtime from a script path. Extensions are
+// matched case-insensitively (Windows file names are).
 func DetectRuntime(path string) Runtime {
+	path = strings.ToLower(path)
 	switch {
 	case hasSuffix(path, ".py"):
 		return RuntimePython
@@ -257,6 +294,8 @@ func DetectRuntime(path string) Runtime {
 		return RuntimeNode
 	case hasSuffix(path, ".sh", ".bash"):
 		return RuntimeShell
+	case hasSuffix(path, ".ps1"):
+		return RuntimePowerShell
 	default:
 		return RuntimeBinary
 	}
@@ -273,10 +312,7 @@ func hasSuffix(s string, suffixes ...string) bool {
 
 // Validate checks that the config is valid.
 func (c *Config) Validate() error {
-	switch c.DefaultIsolation {
-	case IsolationNone, IsolationRestricted, IsolationContainer:
-		// ok
-	default:
+	if !validIsolation(c.DefaultIsolation) {
 		return fmt.Errorf("invalid isolation level: %q", c.DefaultIsolation)
 	}
 	if c.Timeout <= 0 {
@@ -288,5 +324,8 @@ func (c *Config) Validate() error {
 	if c.MaxMemoryMB <= 0 {
 		return fmt.Errorf("max_memory_mb must be positive")
 	}
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
 	return nil
 }
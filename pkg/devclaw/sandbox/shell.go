@@ -0,0 +1,54 @@
+// Package sandbox – shell.go resolves the host shells: /bin/sh for shell
+// scripts on Unix and PowerShell for .ps1 scripts and for commands on
+// Windows, where no POSIX shell can be assumed.
+package sandbox
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// powerShellFlags keep PowerShell runs non-interactive and independent of
+// the user's profile and execution policy.
+var powerShellFlags = []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass"}
+
+// PowerShell returns the PowerShell binary: pwsh (PowerShell 7) when it
+// is on PATH, otherwise powershell.exe on Windows and pwsh elsewhere.
+func PowerShell() string {
+	if path, err := exec.LookPath("pwsh"); err == nil {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell.exe"
+	}
+	return "pwsh"
+}
+
+// PowerShellScriptArgs returns the arguments that run a .ps1 script.
+func PowerShellScriptArgs(script string, args []string) []string {
+	out := append(append([]string{}, powerShellFlags...), "-File", script)
+	return append(out, args...)
+}
+
+// PowerShellCommandArgs returns the arguments that run a command string.
+func PowerShellCommandArgs(command string) []string {
+	return append(append([]string{}, powerShellFlags...), "-Command", command)
+}
+
+// defaultShell returns the interpreter for RuntimeShell scripts. Windows
+// has no /bin/sh; sh from Git for Windows is used when on PATH.
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "sh"
+	}
+	return "/bin/sh"
+}
+
+// defaultPython returns the Python interpreter name; the python.org
+// installer on Windows does not provide python3.
+func defaultPython() string {
+	if runtime.GOOS == "windows" {
+		return "python"
+	}
+	return "python3"
+}
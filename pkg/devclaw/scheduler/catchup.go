@@ -0,0 +1,92 @@
+// Package scheduler – catchup.go handles runs missed while the daemon was
+// down. On Start, each recurring job's last run (LastRunAt, or CreatedAt if
+// it never ran) is compared with its schedule; the job's CatchUp policy
+// then decides whether the missed runs are dropped, run once, or replayed.
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Catch-up policies for Job.CatchUp.
+const (
+	// CatchUpSkip drops missed runs (the default).
+	CatchUpSkip = "skip"
+
+	// CatchUpRunOnce runs the job once on start if any run was missed.
+	CatchUpRunOnce = "run_once"
+
+	// CatchUpRunAll replays every missed run, up to maxCatchUpRuns.
+	CatchUpRunAll = "run_all"
+)
+
+// maxCatchUpRuns bounds run_all replays after a long downtime.
+const maxCatchUpRuns = 24
+
+// validCatchUp reports whether policy is a known catch-up policy.
+func validCatchUp(policy string) error {
+	switch policy {
+	case "", CatchUpSkip, CatchUpRunOnce, CatchUpRunAll:
+		return nil
+	}
+	return fmt.Errorf("invalid catch_up %q (use %s, %s or %s)", policy, CatchUpSkip, CatchUpRunOnce, CatchUpRunAll)
+}
+
+// missedRuns counts the runs of a recurring job scheduled after its last
+// run and up to now, in the job's timezone, stopping at limit. One-shot
+// jobs are not counted: an overdue one fires on start anyway.
+func missedRuns(job *Job, loc *time.Location, now time.Time, limit int) int {
+	if job.Type == "at" {
+		return 0
+	}
+	last := job.CreatedAt
+	if job.LastRunAt != nil {
+		last = *job.LastRunAt
+	}
+	if last.IsZero() {
+		return 0
+	}
+
+	spec := Spec{Type: job.Type, Schedule: job.Schedule}
+	n := 0
+	for t := last.In(loc); n < limit; n++ {
+		next, err := spec.Next(t)
+		if err != nil || next.After(now) {
+			break
+		}
+		t = next
+	}
+	return n
+}
+
+// catchUp runs the missed runs of jobs according to their policies. The
+// runs of one job are sequential and spaced by minJobInterval so the spin
+// loop guard in executeJob does not drop them.
+func (s *Scheduler) catchUp(jobs []*Job, now time.Time) {
+	for _, job := range jobs {
+		limit := 1
+		if job.CatchUp == CatchUpRunAll {
+			limit = maxCatchUpRuns
+		}
+		missed := missedRuns(job, s.Location(job), now, limit)
+		if missed == 0 {
+			continue
+		}
+		s.logger.Info("catching up missed job runs",
+			"id", job.ID, "policy", job.CatchUp, "runs", missed)
+		for i := 0; i < missed; i++ {
+			if i > 0 {
+				select {
+				case <-time.After(minJobInterval):
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			if _, ok := s.Get(job.ID); !ok {
+				break // removed meanwhile
+			}
+			s.executeJob(job)
+		}
+	}
+}
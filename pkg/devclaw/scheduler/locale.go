@@ -0,0 +1,283 @@
+// Package scheduler – locale.go reads and writes dates, times and numbers in
+// the user's convention. A Locale is derived from a language tag ("pt-BR",
+// "en-US", "de"): it decides whether "15/03" and "03/15" are day-first or
+// month-first, whether times are shown on a 12- or 24-hour clock, and which
+// decimal and thousands separators numbers use. The zero Locale keeps the
+// ISO forms (2026-03-15, 15:04, 1234.5) and refuses ambiguous numeric dates.
+package scheduler
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order of the fields of a numeric date.
+type DateOrder string
+
+const (
+	DayMonthYear DateOrder = "dmy" // 15/03/2026
+	MonthDayYear DateOrder = "mdy" // 03/15/2026
+	YearMonthDay DateOrder = "ymd" // 2026-03-15
+)
+
+// Locale is a date, time and number convention.
+type Locale struct {
+	// Tag is the language tag the locale was derived from ("pt-BR").
+	Tag string
+
+	// DateOrder decides how "15/03" is read. Empty accepts only numeric
+	// dates that are unambiguous.
+	DateOrder DateOrder
+
+	// DateSep separates the fields of rendered dates ("/", ".", "-").
+	DateSep string
+
+	// Hour12 renders times as "3:04 PM" instead of "15:04".
+	Hour12 bool
+
+	// Decimal and Group are the decimal and thousands separators. Empty
+	// Decimal means "."; empty Group means no grouping.
+	Decimal string
+	Group   string
+}
+
+// Month-first regions, and languages and regions that write the year first.
+var (
+	mdyRegions   = map[string]bool{"us": true, "ph": true, "fm": true, "pw": true, "mh": true}
+	ymdLanguages = map[string]bool{"zh": true, "ja": true, "ko": true, "hu": true, "lt": true, "mn": true}
+	ymdRegions   = map[string]bool{"cn": true, "jp": true, "kr": true, "tw": true, "hu": true}
+)
+
+// hour12Regions use the 12-hour clock in everyday writing.
+var hour12Regions = map[string]bool{
+	"us": true, "ca": true, "au": true, "nz": true, "in": true, "ph": true,
+	"pk": true, "bd": true, "eg": true, "sa": true, "kr": true,
+}
+
+// commaDecimal are the languages with a decimal comma; the value is their
+// thousands separator.
+var commaDecimal = map[string]string{
+	"pt": ".", "es": ".", "de": ".", "it": ".", "nl": ".", "id": ".", "tr": ".",
+	"da": ".", "el": ".", "ro": ".", "ca": ".",
+	"fr": " ", "ru": " ", "pl": " ", "uk": " ", "cs": " ", "sk": " ", "sv": " ",
+	"nb": " ", "no": " ", "fi": " ", "hu": " ",
+}
+
+// dotDates are the languages that write dates as 15.03.2026.
+var dotDates = map[string]bool{
+	"de": true, "ru": true, "pl": true, "fi": true, "cs": true, "sk": true, "nb": true,
+	"no": true, "da": true, "tr": true, "uk": true, "ro": true,
+}
+
+// ParseLocale derives a locale from a language tag such as "pt-BR", "en_US",
+// "en-GB" or "de". An empty tag returns the zero Locale.
+func ParseLocale(tag string) Locale {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return Locale{}
+	}
+	norm := strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	norm, _, _ = strings.Cut(norm, ".") // "pt_BR.UTF-8"
+	lang, region, _ := strings.Cut(norm, "-")
+	if lang == "en" && region == "" {
+		region = "us"
+	}
+
+	l := Locale{Tag: tag, DateOrder: DayMonthYear, DateSep: "/", Decimal: ".", Group: ","}
+	switch {
+	case mdyRegions[region]:
+		l.DateOrder = MonthDayYear
+	case ymdLanguages[lang] || ymdRegions[region]:
+		l.DateOrder, l.DateSep = YearMonthDay, "-"
+	case dotDates[lang]:
+		l.DateSep = "."
+	}
+	l.Hour12 = hour12Regions[region] && (lang == "en" || region == "kr")
+	if group, ok := commaDecimal[lang]; ok && region != "mx" {
+		l.Decimal, l.Group = ",", group
+	}
+	if lang == "de" && region == "ch" {
+		l.Decimal, l.Group = ".", "'"
+	}
+	return l
+}
+
+// String returns the locale's tag, or "iso" for the zero Locale.
+func (l Locale) String() string {
+	if l.Tag == "" {
+		return "iso"
+	}
+	return l.Tag
+}
+
+// DateLayout returns the time layout of a full date.
+func (l Locale) DateLayout() string {
+	sep := l.DateSep
+	if sep == "" {
+		sep = "-"
+	}
+	switch l.DateOrder {
+	case DayMonthYear:
+		return "02" + sep + "01" + sep + "2006"
+	case MonthDayYear:
+		return "01" + sep + "02" + sep + "2006"
+	}
+	return "2006" + sep + "01" + sep + "02"
+}
+
+// TimeLayout returns the time layout of a time of day.
+func (l Locale) TimeLayout() string {
+	if l.Hour12 {
+		return "3:04 PM"
+	}
+	return "15:04"
+}
+
+// FormatDate renders the date of t.
+func (l Locale) FormatDate(t time.Time) string {
+	return t.Format(l.DateLayout())
+}
+
+// FormatTime renders the time of day of t.
+func (l Locale) FormatTime(t time.Time) string {
+	return t.Format(l.TimeLayout())
+}
+
+// FormatDateTime renders the date and time of day of t.
+func (l Locale) FormatDateTime(t time.Time) string {
+	return t.Format(l.DateLayout() + " " + l.TimeLayout())
+}
+
+// FormatInt renders n with the locale's thousands separator.
+func (l Locale) FormatInt(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if l.Group == "" {
+		return s
+	}
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	return sign + groupDigits(s, l.Group)
+}
+
+// FormatNumber renders v with the given number of decimals in the locale's
+// convention (1.234,56 in pt-BR, 1,234.56 in en-US).
+func (l Locale) FormatNumber(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	if l.Group != "" {
+		intPart = groupDigits(intPart, l.Group)
+	}
+	if frac != "" {
+		dec := l.Decimal
+		if dec == "" {
+			dec = "."
+		}
+		intPart += dec + frac
+	}
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		return "-" + intPart
+	}
+	return intPart
+}
+
+// groupDigits inserts sep between groups of three digits.
+func groupDigits(digits, sep string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// numericDate matches "15/03", "15.03.2026", "3-15-26" and "2026/03/15".
+var numericDate = regexp.MustCompile(`^(\d{1,4})([/.-])(\d{1,2})(?:[/.-](\d{2,4}))?$`)
+
+// parseNumericDate reads a numeric date in the locale's order. Without a
+// year it is the next occurrence on or after today. ok is false when tok is
+// not a numeric date; err is set when it is one but invalid or ambiguous.
+func (l Locale) parseNumericDate(tok string, now time.Time) (day time.Time, ok bool, err error) {
+	m := numericDate.FindStringSubmatch(tok)
+	if m == nil {
+		return time.Time{}, false, nil
+	}
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[3])
+	year, haveYear := 0, m[4] != ""
+	if haveYear {
+		year, _ = strconv.Atoi(m[4])
+		if len(m[4]) == 2 {
+			year += 2000
+		}
+	}
+
+	var dd, mm int
+	switch {
+	case len(m[1]) == 4: // 2026/03/15 in any locale
+		if !haveYear || len(m[4]) > 2 {
+			return time.Time{}, true, fmt.Errorf("invalid date %q", tok)
+		}
+		year, mm = a, b
+		dd, _ = strconv.Atoi(m[4])
+	case l.DateOrder == DayMonthYear:
+		dd, mm = a, b
+	case l.DateOrder == MonthDayYear, l.DateOrder == YearMonthDay:
+		mm, dd = a, b
+	case a > 12 && b <= 12:
+		dd, mm = a, b
+	case b > 12 && a <= 12:
+		mm, dd = a, b
+	case a == b:
+		dd, mm = a, b
+	default:
+		return time.Time{}, true, fmt.Errorf("ambiguous date %q: write it as YYYY-MM-DD or set a locale", tok)
+	}
+
+	if !haveYear {
+		year = now.Year()
+	}
+	day = time.Date(year, time.Month(mm), dd, 0, 0, 0, 0, now.Location())
+	if mm < 1 || mm > 12 || day.Day() != dd {
+		order := "day/month"
+		if l.DateOrder == MonthDayYear {
+			order = "month/day"
+		}
+		return time.Time{}, true, fmt.Errorf("invalid date %q (%s reads it as %s)", tok, l, order)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !haveYear && day.Before(today) {
+		day = day.AddDate(1, 0, 0)
+	}
+	return day, true, nil
+}
+
+// SetLocale sets the default locale numeric dates in schedules are read in,
+// for jobs without their own. Empty accepts only unambiguous dates.
+func (s *Scheduler) SetLocale(tag string) {
+	s.mu.Lock()
+	s.locale = ParseLocale(tag)
+	s.mu.Unlock()
+}
+
+// jobLocale returns the locale the schedule of job is read in. Callers hold s.mu.
+func (s *Scheduler) jobLocale(job *Job) Locale {
+	if job.Locale != "" {
+		return ParseLocale(job.Locale)
+	}
+	return s.locale
+}
@@ -0,0 +1,498 @@
+// Package scheduler – parse.go turns schedules written in plain words
+// ("every weekday at 9am", "in 20 minutes", "at 2026-03-01 15:00") into the
+// normalized form the scheduler runs: a cron expression, an interval, or an
+// absolute one-shot time. Tools pass the user's wording straight through,
+// so the LLM no longer has to produce cron strings itself.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser parses the cron expressions the scheduler accepts.
+var cronParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Spec is a normalized job schedule.
+type Spec struct {
+	// Type is "cron" (recurring), "every" (interval) or "at" (one-shot).
+	Type string
+
+	// Schedule is a cron expression, an interval such as "20m", or an
+	// RFC 3339 time, depending on Type.
+	Schedule string
+}
+
+// Next returns when the schedule fires next after now.
+func (s Spec) Next(now time.Time) (time.Time, error) {
+	switch s.Type {
+	case "at":
+		return time.Parse(time.RFC3339, s.Schedule)
+	case "every":
+		d, err := time.ParseDuration(s.Schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	default:
+		sched, err := cronParser.Parse(s.Schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return sched.Next(now), nil
+	}
+}
+
+// errNotATime means the text does not describe a point in time at all, as
+// opposed to describing one that is invalid (e.g. in the past).
+var errNotATime = errors.New("not a time")
+
+// scheduleAliases rewrite shorthand into the "every ..." form.
+var scheduleAliases = [][2]string{
+	{"daily", "every day"},
+	{"hourly", "every hour"},
+	{"on weekdays", "every weekday"},
+	{"weekdays", "every weekday"},
+	{"weekly on", "every"},
+	{"monthly on", "every month on"},
+}
+
+// ParseSchedule normalizes a schedule. It accepts cron expressions and
+// descriptors ("0 9 * * 1-5", "@daily") and plain English:
+//
+//   - intervals: "every 20m", "every 2 hours", "@every 1h"
+//   - recurring: "every day at 9am", "daily at 18:30", "every weekday 9am",
+//     "every monday and thursday at 8:00", "every month on the 1st at 9am"
+//   - one-shot: "in 20 minutes", "at 15:00", "tomorrow at 9am",
+//     "next friday at 10", "at 2026-03-01 15:00", "on 25/12 at 8am"
+//
+// Numeric dates other than YYYY-MM-DD are accepted only when unambiguous;
+// Locale.ParseSchedule reads them in a locale's day/month order.
+// kind is the type the caller asked for ("cron", "every", "at" or ""). It
+// only decides bare forms such as "20m"; explicit wording wins over it.
+// One-shot times are resolved against now and returned as absolute times,
+// so they survive restarts.
+func ParseSchedule(text, kind string, now time.Time) (Spec, error) {
+	return Locale{}.ParseSchedule(text, kind, now)
+}
+
+// ParseSchedule is the package ParseSchedule reading numeric dates ("15/03",
+// "03/15/2026") in the locale's order.
+func (l Locale) ParseSchedule(text, kind string, now time.Time) (Spec, error) {
+	s := normalizeScheduleText(text)
+	if s == "" {
+		return Spec{}, fmt.Errorf("empty schedule")
+	}
+	for _, alias := range scheduleAliases {
+		if rest, ok := strings.CutPrefix(s, alias[0]); ok && (rest == "" || rest[0] == ' ') {
+			s = alias[1] + rest
+			break
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(s, "@every "):
+		return parseRecurring(strings.TrimPrefix(s, "@every "), text)
+	case strings.HasPrefix(s, "@"):
+		if _, err := cronParser.Parse(s); err != nil {
+			return Spec{}, fmt.Errorf("invalid schedule %q: %w", text, err)
+		}
+		return Spec{Type: "cron", Schedule: s}, nil
+	case strings.HasPrefix(s, "every "), strings.HasPrefix(s, "each "):
+		_, rest, _ := strings.Cut(s, " ")
+		return parseRecurring(rest, text)
+	case strings.HasPrefix(s, "at "), strings.HasPrefix(s, "on "), strings.HasPrefix(s, "today"),
+		strings.HasPrefix(s, "tomorrow"), strings.HasPrefix(s, "next "):
+		t, err := parseWhen(s, now, l)
+		if errors.Is(err, errNotATime) {
+			return Spec{}, fmt.Errorf("unrecognized time %q (try \"at 15:00\", \"tomorrow at 9am\" or \"at 2026-03-01 15:00\")", text)
+		} else if err != nil {
+			return Spec{}, fmt.Errorf("schedule %q: %w", text, err)
+		}
+		return atSpec(t), nil
+	case strings.HasPrefix(s, "in "):
+		d, ok := parseDuration(strings.TrimPrefix(s, "in "))
+		if !ok {
+			return Spec{}, fmt.Errorf("unrecognized delay in %q (try \"in 20 minutes\" or \"in 1h30m\")", text)
+		}
+		return atSpec(now.Add(d)), nil
+	}
+	if rest, ok := strings.CutSuffix(s, " from now"); ok {
+		if d, ok := parseDuration(rest); ok {
+			return atSpec(now.Add(d)), nil
+		}
+	}
+
+	// Bare forms: the requested kind decides.
+	switch kind {
+	case "every":
+		return parseRecurring(s, text)
+	case "at":
+		if d, ok := parseDuration(s); ok {
+			return atSpec(now.Add(d)), nil
+		}
+		t, err := parseWhen(s, now, l)
+		if errors.Is(err, errNotATime) {
+			if t, err = parseOneShotTime(strings.TrimSpace(text)); err != nil {
+				return Spec{}, fmt.Errorf("unrecognized time %q (try \"at 15:00\", \"tomorrow at 9am\" or \"in 20 minutes\")", text)
+			}
+		} else if err != nil {
+			return Spec{}, fmt.Errorf("schedule %q: %w", text, err)
+		}
+		return atSpec(t), nil
+	}
+	if _, err := cronParser.Parse(s); err == nil {
+		return Spec{Type: "cron", Schedule: s}, nil
+	}
+	t, err := parseWhen(s, now, l)
+	if err == nil {
+		return atSpec(t), nil
+	}
+	if !errors.Is(err, errNotATime) {
+		return Spec{}, fmt.Errorf("schedule %q: %w", text, err)
+	}
+	return Spec{}, fmt.Errorf("unrecognized schedule %q: use a cron expression or words like "+
+		"\"every 20m\", \"every weekday at 9:00\", \"at 2026-03-01 15:00\" or \"in 2 hours\"", text)
+}
+
+// normalizeScheduleText lowercases text, collapses spaces, joins "9 am"
+// into "9am" and drops trailing punctuation.
+func normalizeScheduleText(text string) string {
+	s := strings.ToLower(strings.TrimSpace(text))
+	s = strings.TrimRight(s, ".!")
+	s = strings.Join(strings.Fields(s), " ")
+	return meridiemGap.ReplaceAllString(s, "$1$2")
+}
+
+// meridiemGap matches a space between a time and am/pm.
+var meridiemGap = regexp.MustCompile(`(\d) (am|pm)\b`)
+
+// atSpec returns a one-shot spec firing at t.
+func atSpec(t time.Time) Spec {
+	return Spec{Type: "at", Schedule: t.Format(time.RFC3339)}
+}
+
+// parseRecurring parses what follows "every": an interval ("20m",
+// "2 hours", "day") or calendar days with a time of day ("weekday at 9am",
+// "monday and friday 18:00", "month on the 1st at 8").
+func parseRecurring(s, text string) (Spec, error) {
+	if d, ok := parseDuration(s); ok {
+		if d < time.Second {
+			return Spec{}, fmt.Errorf("interval in %q is too short", text)
+		}
+		return Spec{Type: "every", Schedule: shortDuration(d)}, nil
+	}
+
+	days, clock, found := strings.Cut(s, " at ")
+	if !found {
+		// "every weekday 9am": the time is the last word.
+		if i := strings.LastIndex(s, " "); i > 0 {
+			if _, _, ok := parseClock(s[i+1:]); ok {
+				days, clock = s[:i], s[i+1:]
+			}
+		}
+	}
+
+	dom, dow, err := parseDays(days)
+	if err != nil {
+		return Spec{}, fmt.Errorf("unrecognized schedule %q: %w", text, err)
+	}
+	if clock == "" {
+		return Spec{}, fmt.Errorf("schedule %q needs a time of day (e.g. \"every %s at 9:00\")", text, days)
+	}
+
+	// Several times are fine when they share the minute ("at 9am and 6pm").
+	var hours []string
+	minute := -1
+	for _, c := range splitList(clock) {
+		h, m, ok := parseClock(c)
+		if !ok {
+			return Spec{}, fmt.Errorf("unrecognized time of day %q in %q", c, text)
+		}
+		if minute >= 0 && m != minute {
+			return Spec{}, fmt.Errorf("times in %q must share the same minute; add one job per time", text)
+		}
+		minute = m
+		hours = append(hours, strconv.Itoa(h))
+	}
+	return Spec{Type: "cron", Schedule: fmt.Sprintf("%d %s %s * %s", minute, strings.Join(hours, ","), dom, dow)}, nil
+}
+
+// weekdays maps day names to cron day-of-week numbers.
+var weekdays = map[string]int{
+	"sunday": 0, "sun": 0, "monday": 1, "mon": 1, "tuesday": 2, "tue": 2, "tues": 2,
+	"wednesday": 3, "wed": 3, "thursday": 4, "thu": 4, "thurs": 4, "friday": 5, "fri": 5,
+	"saturday": 6, "sat": 6,
+}
+
+// monthDay matches "month on the 1st", "month on day 15" and similar.
+var monthDay = regexp.MustCompile(`^month(?: on)?(?: the| day)? (\d{1,2})(?:st|nd|rd|th)?$`)
+
+// parseDays returns the cron day-of-month and day-of-week fields for the
+// day part of a recurring schedule.
+func parseDays(s string) (dom, dow string, err error) {
+	s = strings.TrimPrefix(s, "on ")
+	switch s {
+	case "day", "":
+		return "*", "*", nil
+	case "weekday", "weekdays":
+		return "*", "1-5", nil
+	case "weekend", "weekends", "weekend day":
+		return "*", "0,6", nil
+	}
+	if m := monthDay.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > 31 {
+			return "", "", fmt.Errorf("day of month %d out of range", n)
+		}
+		return strconv.Itoa(n), "*", nil
+	}
+
+	var nums []int
+	for _, name := range splitList(s) {
+		n, ok := weekdays[strings.TrimSuffix(name, "s")]
+		if !ok {
+			if n, ok = weekdays[name]; !ok {
+				return "", "", fmt.Errorf("unknown day %q", name)
+			}
+		}
+		if !slices.Contains(nums, n) {
+			nums = append(nums, n)
+		}
+	}
+	slices.Sort(nums)
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = strconv.Itoa(n)
+	}
+	return "*", strings.Join(parts, ","), nil
+}
+
+// splitList splits "a, b and c" into its items.
+func splitList(s string) []string {
+	s = strings.ReplaceAll(s, ",", " and ")
+	var items []string
+	for _, item := range strings.Split(s, " and ") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// clockPattern matches "9", "9am", "9:30", "9:30pm" and "18h30".
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?:[:h](\d{2}))?(am|pm)?$`)
+
+// parseClock parses a time of day.
+func parseClock(s string) (hour, minute int, ok bool) {
+	switch s {
+	case "noon", "midday":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+		if m[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// durationUnits maps unit words to durations.
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// gluedAmount matches "20min" or "3days".
+var gluedAmount = regexp.MustCompile(`^(\d+)([a-z]+)$`)
+
+// parseDuration parses Go durations ("1h30m") and spelled-out ones
+// ("20 minutes", "2 hours and 15 minutes", "half an hour", "day").
+func parseDuration(s string) (time.Duration, bool) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, d > 0
+	}
+	s = strings.ReplaceAll(s, "half an hour", "30 minutes")
+	s = strings.ReplaceAll(s, ",", " ")
+
+	var total time.Duration
+	n, pending := 1, false
+	for _, tok := range strings.Fields(s) {
+		if m := gluedAmount.FindStringSubmatch(tok); m != nil {
+			unit, ok := durationUnits[m[2]]
+			if !ok || pending {
+				return 0, false
+			}
+			v, _ := strconv.Atoi(m[1])
+			total += time.Duration(v) * unit
+			continue
+		}
+		if v, err := strconv.Atoi(tok); err == nil {
+			n, pending = v, true
+			continue
+		}
+		switch tok {
+		case "and":
+			continue
+		case "a", "an":
+			n, pending = 1, true
+			continue
+		}
+		unit, ok := durationUnits[tok]
+		if !ok {
+			return 0, false
+		}
+		total += time.Duration(n) * unit
+		n, pending = 1, false
+	}
+	return total, total > 0 && !pending
+}
+
+// shortDuration formats d without zero units ("20m", not "20m0s").
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// absoluteLayouts are the full date-time layouts accepted for one-shot jobs.
+var absoluteLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05",
+}
+
+// parseWhen parses a point in time: an absolute date-time, or a day word
+// ("today", "tomorrow", "friday", "2026-03-01", "15/03" in l's order) and a
+// time of day, in any order with optional "at"/"on". A time of day alone means its next
+// occurrence. Returns errNotATime when s is not a time description.
+func parseWhen(s string, now time.Time, l Locale) (time.Time, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "at "), "on ")
+	upper := strings.ToUpper(s)
+	for _, layout := range absoluteLayouts {
+		if t, err := time.ParseInLocation(layout, upper, now.Location()); err == nil {
+			return futureTime(t, now)
+		}
+	}
+
+	var (
+		day            time.Time
+		haveDay        bool
+		hour, minute   int
+		haveClock      bool
+		weekdayPending bool
+		byWeekday      bool
+	)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, tok := range strings.Fields(s) {
+		if h, m, ok := parseClock(tok); ok && !haveClock {
+			hour, minute, haveClock = h, m, true
+			continue
+		}
+		if haveDay {
+			if tok == "at" {
+				continue
+			}
+			return time.Time{}, errNotATime
+		}
+		switch tok {
+		case "at", "on", "this":
+			continue
+		case "next":
+			weekdayPending = true
+			continue
+		case "today":
+			day, haveDay = today, true
+			continue
+		case "tomorrow":
+			day, haveDay = today.AddDate(0, 0, 1), true
+			continue
+		}
+		if wd, ok := weekdays[tok]; ok {
+			ahead := (wd - int(today.Weekday()) + 7) % 7
+			if ahead == 0 && weekdayPending {
+				ahead = 7
+			}
+			day, haveDay, byWeekday = today.AddDate(0, 0, ahead), true, true
+			continue
+		}
+		if d, err := time.ParseInLocation("2006-01-02", tok, now.Location()); err == nil {
+			day, haveDay = d, true
+			continue
+		}
+		if d, ok, err := l.parseNumericDate(tok, now); ok {
+			if err != nil {
+				return time.Time{}, err
+			}
+			day, haveDay = d, true
+			continue
+		}
+		return time.Time{}, errNotATime
+	}
+
+	switch {
+	case haveDay && haveClock:
+		t := atClock(day, hour, minute)
+		if byWeekday && !t.After(now) {
+			t = atClock(day.AddDate(0, 0, 7), hour, minute) // "friday at 10" on a Friday afternoon
+		}
+		return futureTime(t, now)
+	case haveDay:
+		return time.Time{}, fmt.Errorf("missing time of day (e.g. \"tomorrow at 9am\")")
+	case haveClock:
+		t := atClock(today, hour, minute)
+		if !t.After(now) {
+			t = atClock(today.AddDate(0, 0, 1), hour, minute)
+		}
+		return t, nil
+	}
+	return time.Time{}, errNotATime
+}
+
+// atClock returns the wall-clock time hour:minute on day, in day's zone.
+// Unlike adding hours to midnight it stays right on DST change days.
+func atClock(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// futureTime rejects times that already passed.
+func futureTime(t, now time.Time) (time.Time, error) {
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("%s is in the past", t.Format("2006-01-02 15:04"))
+	}
+	return t, nil
+}
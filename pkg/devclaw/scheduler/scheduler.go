@@ -38,6 +38,12 @@ type Scheduler struct {
 	// handler is called when a job triggers.
 	handler JobHandler
 
+	// loc is the default timezone for jobs without their own (nil = local).
+	loc *time.Location
+
+	// locale reads numeric dates of jobs without their own locale.
+	locale Locale
+
 	// jobTimeout is the maximum time a single job execution can take.
 	// Defaults to 5 minutes. Jobs exceeding this are cancelled.
 	jobTimeout time.Duration
@@ -59,11 +65,23 @@ type Job struct {
 
 	// Schedule is the cron expression or shorthand.
 	// Supports: standard 5-field cron, @daily, @hourly, @every 5m, etc.
+	// Add also accepts plain words (see ParseSchedule) and stores the
+	// normalized form.
 	Schedule string `json:"schedule" yaml:"schedule"`
 
 	// Type is the schedule type: "cron" (recurring), "at" (one-shot), "every" (interval).
 	Type string `json:"type" yaml:"type"`
 
+	// Timezone is the IANA zone the schedule is read in ("America/Sao_Paulo").
+	// Empty = the scheduler default (the config timezone). Cron times follow
+	// the zone's DST rules.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+
+	// Locale is the language tag numeric dates in the schedule are read in
+	// ("15/03" is March 15 in "pt-BR", invalid in "en-US"). Empty = the
+	// scheduler default. Only Add reads it: the stored schedule is normalized.
+	Locale string `json:"locale,omitempty" yaml:"locale,omitempty"`
+
 	// Command is the prompt/command executed by the agent.
 	Command string `json:"command" yaml:"command"`
 
@@ -124,6 +142,10 @@ type Job struct {
 	// Exact disables stagger for this job (fire at exact schedule time).
 	Exact bool `json:"exact,omitempty" yaml:"exact,omitempty"`
 
+	// CatchUp decides what happens to runs missed while the daemon was
+	// down: "skip" (default), "run_once" or "run_all". See catchup.go.
+	CatchUp string `json:"catch_up,omitempty" yaml:"catch_up,omitempty"`
+
 	// LastRunDuration is how long the last execution took.
 	LastRunDuration time.Duration `json:"last_run_duration,omitempty" yaml:"last_run_duration,omitempty"`
 
@@ -200,11 +222,25 @@ func (s *Scheduler) Add(job *Job) error {
 	if job.Schedule == "" {
 		return fmt.Errorf("job schedule is required")
 	}
+	if err := validCatchUp(job.CatchUp); err != nil {
+		return err
+	}
 
+	loc, err := s.jobLocation(job)
+	if err != nil {
+		return err
+	}
+	if job.Timezone != "" {
+		job.Timezone = loc.String() // canonical IANA name
+	}
+
+	// Times in the schedule are read in the job's timezone.
 	job.CreatedAt = time.Now()
-	if job.Type == "" {
-		job.Type = "cron"
+	spec, err := s.jobLocale(job).ParseSchedule(job.Schedule, job.Type, job.CreatedAt.In(loc))
+	if err != nil {
+		return err
 	}
+	job.Type, job.Schedule = spec.Type, spec.Schedule
 
 	// Register with cron if running and job is enabled.
 	if s.cron != nil && job.Enabled {
@@ -225,6 +261,7 @@ func (s *Scheduler) Add(job *Job) error {
 		"id", job.ID,
 		"schedule", job.Schedule,
 		"type", job.Type,
+		"timezone", loc.String(),
 		"channel", job.Channel,
 	)
 	return nil
@@ -282,11 +319,10 @@ func (s *Scheduler) Start(ctx context.Context) error {
 	s.ctx, s.cancel = context.WithCancel(ctx)
 
 	// Create the cron scheduler with seconds support.
-	s.cron = cron.New(cron.WithParser(cron.NewParser(
-		cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-	)))
+	s.cron = cron.New(cron.WithParser(cronParser))
 
 	// Load persisted jobs.
+	var catchUp []*Job
 	if s.storage != nil {
 		jobs, err := s.storage.LoadAll()
 		if err != nil {
@@ -299,6 +335,10 @@ func (s *Scheduler) Start(ctx context.Context) error {
 					if err := s.scheduleCronJob(job); err != nil {
 						s.logger.Warn("skipping job with invalid schedule",
 							"id", job.ID, "schedule", job.Schedule, "error", err)
+						continue
+					}
+					if job.CatchUp == CatchUpRunOnce || job.CatchUp == CatchUpRunAll {
+						catchUp = append(catchUp, job)
 					}
 				}
 			}
@@ -307,6 +347,11 @@ func (s *Scheduler) Start(ctx context.Context) error {
 		}
 	}
 
+	// Run what was missed while the daemon was down.
+	if len(catchUp) > 0 {
+		go s.catchUp(catchUp, time.Now())
+	}
+
 	// Start cron.
 	s.cron.Start()
 
@@ -362,6 +407,15 @@ func (s *Scheduler) scheduleCronJob(job *Job) error {
 		schedule = "@every " + schedule
 	}
 
+	// Evaluate cron schedules in the job's timezone (intervals don't care).
+	if !strings.HasPrefix(schedule, "@every ") {
+		loc, err := s.jobLocation(job)
+		if err != nil {
+			return err
+		}
+		schedule = "CRON_TZ=" + loc.String() + " " + schedule
+	}
+
 	entryID, err := s.cron.AddFunc(schedule, func() {
 		s.executeJob(job)
 	})
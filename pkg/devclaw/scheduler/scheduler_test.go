@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"log/slog"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -101,3 +102,291 @@ func TestMinJobInterval_Value(t *testing.T) {
 		t.Errorf("minJobInterval should be reasonable (<=10s), got %s", minJobInterval)
 	}
 }
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	// Monday 2026-03-02 10:00.
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	at := func(s string) string {
+		ts, _ := time.Parse("2006-01-02 15:04", s)
+		return ts.Format(time.RFC3339)
+	}
+
+	cases := []struct {
+		text, kind string
+		want       Spec
+	}{
+		{"0 9 * * 1-5", "", Spec{"cron", "0 9 * * 1-5"}},
+		{"@daily", "cron", Spec{"cron", "@daily"}},
+		{"@every 5m", "", Spec{"every", "5m"}},
+		{"every 20m", "", Spec{"every", "20m"}},
+		{"Every 2 hours", "cron", Spec{"every", "2h"}},
+		{"20m", "every", Spec{"every", "20m"}},
+		{"hourly", "", Spec{"every", "1h"}},
+		{"every day at 9am", "", Spec{"cron", "0 9 * * *"}},
+		{"daily at 18:30", "", Spec{"cron", "30 18 * * *"}},
+		{"every weekday 9 am", "", Spec{"cron", "0 9 * * 1-5"}},
+		{"every Monday and Thursday at 8:15pm", "", Spec{"cron", "15 20 * * 1,4"}},
+		{"every weekend at noon", "", Spec{"cron", "0 12 * * 0,6"}},
+		{"every day at 9am and 6pm", "", Spec{"cron", "0 9,18 * * *"}},
+		{"every month on the 1st at 8", "", Spec{"cron", "0 8 1 * *"}},
+		{"in 20 minutes", "", Spec{"at", at("2026-03-02 10:20")}},
+		{"in 1 hour and 30 minutes", "cron", Spec{"at", at("2026-03-02 11:30")}},
+		{"5m", "at", Spec{"at", at("2026-03-02 10:05")}},
+		{"at 2026-03-01 15:00", "", Spec{}}, // past
+		{"at 2026-03-05 15:00", "", Spec{"at", at("2026-03-05 15:00")}},
+		{"2026-03-05T15:00:00Z", "at", Spec{"at", at("2026-03-05 15:00")}},
+		{"at 9:30", "", Spec{"at", at("2026-03-03 09:30")}},
+		{"14:00", "at", Spec{"at", at("2026-03-02 14:00")}},
+		{"tomorrow at 9am", "", Spec{"at", at("2026-03-03 09:00")}},
+		{"next monday at 10", "", Spec{"at", at("2026-03-09 10:00")}},
+		{"friday 17:00", "", Spec{"at", at("2026-03-06 17:00")}},
+		{"every monday", "", Spec{}},      // no time of day
+		{"20m", "", Spec{}},               // ambiguous
+		{"whenever you like", "", Spec{}}, // not a schedule
+	}
+	for _, tc := range cases {
+		got, err := ParseSchedule(tc.text, tc.kind, now)
+		if tc.want == (Spec{}) {
+			if err == nil {
+				t.Errorf("ParseSchedule(%q, %q) = %+v, want error", tc.text, tc.kind, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSchedule(%q, %q): %v", tc.text, tc.kind, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseSchedule(%q, %q) = %+v, want %+v", tc.text, tc.kind, got, tc.want)
+		}
+		// Normalized specs parse back to themselves.
+		if again, err := ParseSchedule(got.Schedule, got.Type, now); err != nil || again != got {
+			t.Errorf("re-parsing %+v = %+v, %v", got, again, err)
+		}
+	}
+
+	next, err := Spec{"cron", "0 9 * * 1-5"}.Next(now)
+	if err != nil || !next.Equal(time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Next = %v, %v", next, err)
+	}
+}
+
+func TestAdd_NormalizesSchedule(t *testing.T) {
+	t.Parallel()
+
+	s := New(nil, nil, slog.Default())
+	job := &Job{ID: "remind", Schedule: "in 2 hours", Command: "stretch", Enabled: true}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	fires, err := time.Parse(time.RFC3339, job.Schedule)
+	if job.Type != "at" || err != nil || time.Until(fires) < time.Hour {
+		t.Errorf("one-shot not stored as absolute time: type=%q schedule=%q", job.Type, job.Schedule)
+	}
+
+	if err := s.Add(&Job{ID: "bad", Schedule: "sometime soon", Command: "x"}); err == nil {
+		t.Error("expected an error for an unparseable schedule")
+	}
+}
+
+func TestJobTimezone(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]string{
+		"America/Sao_Paulo": "America/Sao_Paulo",
+		"São Paulo":         "America/Sao_Paulo",
+		"sao paulo time":    "America/Sao_Paulo",
+		"new york":          "America/New_York",
+		"UTC":               "UTC",
+		"BRT":               "America/Sao_Paulo",
+	} {
+		loc, err := LoadTimezone(name)
+		if err != nil || loc.String() != want {
+			t.Errorf("LoadTimezone(%q) = %v, %v; want %s", name, loc, err, want)
+		}
+	}
+	if _, err := LoadTimezone("Atlantis"); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+
+	s := New(nil, nil, slog.Default())
+	if err := s.SetTimezone("UTC"); err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{ID: "brief", Schedule: "every day at 9am", Timezone: "são paulo", Command: "x", Enabled: true}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Timezone != "America/Sao_Paulo" || job.Schedule != "0 9 * * *" {
+		t.Fatalf("job not normalized: tz=%q schedule=%q", job.Timezone, job.Schedule)
+	}
+	next, err := s.NextRun(job, time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC))
+	if err != nil || !next.Equal(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("9am São Paulo (UTC-3) should be 12:00 UTC, got %v, %v", next.UTC(), err)
+	}
+	if s.Location(&Job{}).String() != "UTC" {
+		t.Error("jobs without a timezone should use the scheduler default")
+	}
+
+	// DST: 9am New York stays 9am local across the March change.
+	ny, _ := time.LoadLocation("America/New_York")
+	daily := &Job{Type: "cron", Schedule: "0 9 * * *", Timezone: "America/New_York"}
+	before, _ := s.NextRun(daily, time.Date(2026, 3, 6, 10, 0, 0, 0, ny))
+	after, _ := s.NextRun(daily, before)
+	if before.In(ny).Hour() != 9 || after.In(ny).Hour() != 9 || after.Sub(before) != 23*time.Hour {
+		t.Errorf("DST change mishandled: %v then %v", before.In(ny), after.In(ny))
+	}
+
+	// One-shot times are read in the job's timezone, also on a DST day.
+	now := time.Date(2026, 3, 7, 12, 0, 0, 0, ny)
+	spec, err := ParseSchedule("tomorrow at 9am", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fires, _ := time.Parse(time.RFC3339, spec.Schedule)
+	if got := fires.In(ny); got.Hour() != 9 || got.Day() != 8 {
+		t.Errorf("tomorrow at 9am on the DST day = %v, want 09:00 on the 8th", got)
+	}
+}
+
+func TestCatchUp_MissedRuns(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, time.UTC)
+	last := now.Add(-5 * time.Hour)
+	hourly := &Job{Type: "cron", Schedule: "0 * * * *", LastRunAt: &last}
+	if n := missedRuns(hourly, time.UTC, now, maxCatchUpRuns); n != 5 {
+		t.Errorf("hourly job down 5h: missed %d, want 5", n)
+	}
+	if n := missedRuns(hourly, time.UTC, now, 1); n != 1 {
+		t.Errorf("limit not applied: %d", n)
+	}
+	never := &Job{Type: "every", Schedule: "10m", CreatedAt: now.Add(-25 * time.Minute)}
+	if n := missedRuns(never, time.UTC, now, maxCatchUpRuns); n != 2 {
+		t.Errorf("job that never ran should count from CreatedAt: %d, want 2", n)
+	}
+	if n := missedRuns(&Job{Type: "at", Schedule: last.Format(time.RFC3339), CreatedAt: last}, time.UTC, now, 10); n != 0 {
+		t.Errorf("one-shot jobs are not caught up, got %d", n)
+	}
+
+	// On Start, run_once jobs run once; skip jobs don't run.
+	storage, err := NewFileJobStorage(t.TempDir() + "/jobs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := time.Now().Add(-3 * time.Hour)
+	for _, j := range []*Job{
+		{ID: "report", Type: "cron", Schedule: "30 * * * *", CatchUp: CatchUpRunOnce, Enabled: true, LastRunAt: &ran},
+		{ID: "ping", Type: "cron", Schedule: "30 * * * *", Enabled: true, LastRunAt: &ran},
+	} {
+		if err := storage.Save(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var reports, pings atomic.Int32
+	s := New(storage, func(_ context.Context, job *Job) (string, error) {
+		if job.ID == "report" {
+			reports.Add(1)
+		} else {
+			pings.Add(1)
+		}
+		return "ok", nil
+	}, slog.Default())
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for reports.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if reports.Load() != 1 || pings.Load() != 0 {
+		t.Errorf("catch-up runs: report=%d ping=%d, want 1 and 0", reports.Load(), pings.Load())
+	}
+
+	if err := s.Add(&Job{ID: "bad", Schedule: "every 1h", CatchUp: "sometimes", Command: "x"}); err == nil {
+		t.Error("expected an error for an unknown catch_up policy")
+	}
+}
+
+func TestLocale_NumericDatesAndFormatting(t *testing.T) {
+	t.Parallel()
+
+	// Monday 2026-03-02 10:00.
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	at := func(s string) Spec {
+		ts, _ := time.Parse("2006-01-02 15:04", s)
+		return Spec{"at", ts.Format(time.RFC3339)}
+	}
+	br, us := ParseLocale("pt-BR"), ParseLocale("en_US")
+	cases := []struct {
+		locale Locale
+		text   string
+		want   Spec
+	}{
+		{br, "05/03 at 9am", at("2026-03-05 09:00")},
+		{us, "03/05 at 9am", at("2026-03-05 09:00")},
+		{br, "15/03/2026 14:30", at("2026-03-15 14:30")},
+		{us, "on 3/15/26 at 2:30pm", at("2026-03-15 14:30")},
+		{ParseLocale("de"), "15.03. 8:00", Spec{}}, // trailing dot is not a date
+		{ParseLocale("de"), "15.03 8:00", at("2026-03-15 08:00")},
+		{br, "01/03 at 9am", at("2027-03-01 09:00")}, // passed this year
+		{Locale{}, "15/03 at 9am", at("2026-03-15 09:00")},
+		{Locale{}, "2026/03/15 9:00", at("2026-03-15 09:00")},
+		{Locale{}, "05/03 at 9am", Spec{}}, // ambiguous without a locale
+		{us, "15/03 at 9am", Spec{}},       // no month 15
+		{br, "31/02 at 9am", Spec{}},
+	}
+	for _, tc := range cases {
+		got, err := tc.locale.ParseSchedule(tc.text, "at", now)
+		if tc.want == (Spec{}) {
+			if err == nil {
+				t.Errorf("%s: ParseSchedule(%q) = %+v, want error", tc.locale, tc.text, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: ParseSchedule(%q) = %+v, %v; want %+v", tc.locale, tc.text, got, err, tc.want)
+		}
+	}
+
+	ts := time.Date(2026, 3, 15, 14, 5, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		locale       Locale
+		when, number string
+		value        float64
+		decimals     int
+		wantInt      string
+	}{
+		{br, "15/03/2026 14:05", "1.234,50", 1234.5, 2, "12.345"},
+		{us, "03/15/2026 2:05 PM", "1,234.50", 1234.5, 2, "12,345"},
+		{ParseLocale("fr-FR"), "15/03/2026 14:05", "1 234,5", 1234.5, 1, "12 345"},
+		{ParseLocale("ja-JP"), "2026-03-15 14:05", "-0.75", -0.75, 2, "12,345"},
+		{Locale{}, "2026-03-15 14:05", "1234.50", 1234.5, 2, "12345"},
+	} {
+		if got := tc.locale.FormatDateTime(ts); got != tc.when {
+			t.Errorf("%s: FormatDateTime = %q, want %q", tc.locale, got, tc.when)
+		}
+		if got := tc.locale.FormatNumber(tc.value, tc.decimals); got != tc.number {
+			t.Errorf("%s: FormatNumber = %q, want %q", tc.locale, got, tc.number)
+		}
+		if got := tc.locale.FormatInt(12345); got != tc.wantInt {
+			t.Errorf("%s: FormatInt = %q, want %q", tc.locale, got, tc.wantInt)
+		}
+	}
+
+	// Jobs are read in their own locale, or the scheduler default.
+	s := New(nil, nil, slog.Default())
+	s.SetLocale("en-US")
+	if err := s.Add(&Job{ID: "us", Schedule: "12/25/2099 at 8am", Command: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{ID: "br", Schedule: "25/12/2099 at 8am", Locale: "pt-BR", Command: "x"}
+	if err := s.Add(job); err != nil || !strings.HasPrefix(job.Schedule, "2099-12-25T08:00") {
+		t.Errorf("pt-BR job = %q, %v", job.Schedule, err)
+	}
+}
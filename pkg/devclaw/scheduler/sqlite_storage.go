@@ -15,7 +15,8 @@ type SQLiteJobStorage struct {
 }
 
 // NewSQLiteJobStorage creates a SQLite-backed job storage using the shared DB.
-// The "jobs" table must already exist (created by copilot.OpenDatabase).
+// The "jobs" table must already exist (created and migrated by
+// copilot.OpenDatabase).
 func NewSQLiteJobStorage(db *sql.DB) *SQLiteJobStorage {
 	return &SQLiteJobStorage{db: db}
 }
@@ -29,12 +30,14 @@ func (s *SQLiteJobStorage) Save(job *Job) error {
 
 	_, err := s.db.Exec(`
 		INSERT OR REPLACE INTO jobs
-			(id, schedule, type, command, channel, chat_id, enabled,
+			(id, schedule, type, timezone, catch_up, command, channel, chat_id, enabled,
 			 created_by, created_at, last_run_at, last_error, run_count)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		job.ID,
 		job.Schedule,
 		job.Type,
+		job.Timezone,
+		job.CatchUp,
 		job.Command,
 		job.Channel,
 		job.ChatID,
@@ -63,7 +66,7 @@ func (s *SQLiteJobStorage) Delete(id string) error {
 // LoadAll reads all persisted jobs.
 func (s *SQLiteJobStorage) LoadAll() ([]*Job, error) {
 	rows, err := s.db.Query(`
-		SELECT id, schedule, type, command, channel, chat_id, enabled,
+		SELECT id, schedule, type, timezone, catch_up, command, channel, chat_id, enabled,
 		       created_by, created_at, last_run_at, last_error, run_count
 		FROM jobs`)
 	if err != nil {
@@ -80,7 +83,7 @@ func (s *SQLiteJobStorage) LoadAll() ([]*Job, error) {
 			lastRunAt  sql.NullString
 		)
 		if err := rows.Scan(
-			&j.ID, &j.Schedule, &j.Type, &j.Command,
+			&j.ID, &j.Schedule, &j.Type, &j.Timezone, &j.CatchUp, &j.Command,
 			&j.Channel, &j.ChatID, &enabled,
 			&j.CreatedBy, &createdAt, &lastRunAt,
 			&j.LastError, &j.RunCount,
@@ -0,0 +1,121 @@
+// Package scheduler – timezone.go resolves job timezones. Each job may
+// name its own IANA zone; jobs without one use the scheduler's default
+// (the config timezone). Cron schedules are evaluated in that zone, so
+// "every day at 9am" stays at 9:00 local time across DST changes and
+// regardless of the host's TZ.
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// zoneRegions are tried in order when a timezone is given as a bare city.
+var zoneRegions = []string{
+	"America", "Europe", "Asia", "Africa", "Australia", "Pacific", "Atlantic", "Indian",
+	"America/Argentina", "America/Indiana",
+}
+
+// zoneAliases map common abbreviations and names to IANA zones.
+var zoneAliases = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"brt": "America/Sao_Paulo", "brasilia": "America/Sao_Paulo",
+	"et": "America/New_York", "eastern": "America/New_York",
+	"ct": "America/Chicago", "central": "America/Chicago",
+	"mt": "America/Denver", "mountain": "America/Denver",
+	"pt": "America/Los_Angeles", "pacific": "America/Los_Angeles",
+	"cet": "Europe/Paris", "bst": "Europe/London", "ist": "Asia/Kolkata", "jst": "Asia/Tokyo",
+	"new york": "America/New_York", "nyc": "America/New_York", "london": "Europe/London",
+}
+
+// accentFolds strips the accents of city names ("São Paulo" → "Sao Paulo").
+var accentFolds = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// LoadTimezone resolves a timezone name: an IANA name ("America/Sao_Paulo"),
+// "UTC"/"Local", a common abbreviation ("BRT", "ET") or a city in the IANA
+// database written naturally ("São Paulo", "new york", "São Paulo time").
+func LoadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("empty timezone")
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, nil
+	}
+
+	key := strings.ToLower(accentFolds.Replace(strings.ToLower(name)))
+	key = strings.TrimSpace(strings.TrimSuffix(key, " time"))
+	if iana, ok := zoneAliases[key]; ok {
+		return time.LoadLocation(iana)
+	}
+
+	// "sao paulo" → "Sao_Paulo", tried under each region.
+	words := strings.Fields(key)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	city := strings.Join(words, "_")
+	if city != "" {
+		for _, region := range zoneRegions {
+			if loc, err := time.LoadLocation(region + "/" + city); err == nil {
+				return loc, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown timezone %q (use an IANA name such as \"America/Sao_Paulo\")", name)
+}
+
+// SetTimezone sets the default timezone for jobs without their own.
+// Empty means the host's local time. Call it before Start.
+func (s *Scheduler) SetTimezone(name string) error {
+	loc := time.Local
+	if name != "" {
+		var err error
+		if loc, err = LoadTimezone(name); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.loc = loc
+	s.mu.Unlock()
+	return nil
+}
+
+// Location returns the timezone job runs in: its own, or the default.
+func (s *Scheduler) Location(job *Job) *time.Location {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if loc, err := s.jobLocation(job); err == nil {
+		return loc
+	}
+	return s.defaultLocation()
+}
+
+// jobLocation resolves the timezone of job. Callers hold s.mu.
+func (s *Scheduler) jobLocation(job *Job) (*time.Location, error) {
+	if job.Timezone == "" {
+		return s.defaultLocation(), nil
+	}
+	return LoadTimezone(job.Timezone)
+}
+
+// defaultLocation returns the default timezone. Callers hold s.mu.
+func (s *Scheduler) defaultLocation() *time.Location {
+	if s.loc == nil {
+		return time.Local
+	}
+	return s.loc
+}
+
+// NextRun returns when job fires next, in the job's timezone.
+func (s *Scheduler) NextRun(job *Job, now time.Time) (time.Time, error) {
+	return Spec{Type: job.Type, Schedule: job.Schedule}.Next(now.In(s.Location(job)))
+}
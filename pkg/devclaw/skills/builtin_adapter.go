@@ -440,6 +440,17 @@ func (s *datetimeSkill) Tools() []Tool {
 			Parameters: []ToolParameter{
 				{Name: "timezone", Type: "string", Description: "IANA timezone (e.g. 'America/Sao_Paulo', 'UTC')"},
 			},
+			OutputSchema: map[string]any{
+				"type":     "object",
+				"required": []string{"datetime", "timezone", "iso8601"},
+				"properties": map[string]any{
+					"datetime": map[string]any{"type": "string"},
+					"timezone": map[string]any{"type": "string"},
+					"day":      map[string]any{"type": "string"},
+					"unix":     map[string]any{"type": "string"},
+					"iso8601":  map[string]any{"type": "string"},
+				},
+			},
 			Handler: func(_ context.Context, args map[string]any) (any, error) {
 				tz, _ := args["timezone"].(string)
 				if tz == "" {
@@ -41,8 +41,14 @@ type ClawdHubSkillDef struct {
 	Name        string                 `yaml:"name"`
 	Description string                 `yaml:"description"`
 	Homepage    string                 `yaml:"homepage"`
+	Version     string                 `yaml:"version"`
 	Metadata    map[string]interface{} `yaml:"metadata"`
 
+	// OutputSchemas maps tool names ("execute", "run_<script>") to their
+	// optional structured result contract (inline JSON under the
+	// "output_schemas" frontmatter key).
+	OutputSchemas map[string]map[string]any `yaml:"output_schemas"`
+
 	// Parsed from metadata.openclaw
 	OpenClaw *OpenClawMeta
 
@@ -245,6 +251,8 @@ func parseFrontmatter(text string) (*ClawdHubSkillDef, string, error) {
 			def.Description = value
 		case "homepage":
 			def.Homepage = value
+		case "version":
+			def.Version = value
 		case "metadata":
 			// metadata is inline JSON.
 			var meta map[string]interface{}
@@ -259,6 +267,17 @@ func parseFrontmatter(text string) (*ClawdHubSkillDef, string, error) {
 			} else {
 				def.Metadata = meta
 			}
+		case "output_schemas":
+			var schemas map[string]map[string]any
+			if err := json.Unmarshal([]byte(value), &schemas); err != nil {
+				if jsonStr := extractJSONBlock(frontmatter, "output_schemas"); jsonStr != "" {
+					if err := json.Unmarshal([]byte(jsonStr), &schemas); err == nil {
+						def.OutputSchemas = schemas
+					}
+				}
+			} else {
+				def.OutputSchemas = schemas
+			}
 		}
 	}
 
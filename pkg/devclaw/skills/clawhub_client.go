@@ -8,9 +8,12 @@
 //   GET /resolve?slug=<slug>
 //   GET /skills/<slug>/file?path=SKILL.md
 //   GET /download?slug=<slug>&version=<version>
+//   POST /publish (Authorization: Bearer <token>)
 package skills
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -141,6 +144,51 @@ func (c *ClawHubClient) Download(slug, version string) ([]byte, error) {
 	return io.ReadAll(io.LimitReader(resp.Body, 50*1024*1024)) // 50MB limit
 }
 
+// ClawHubPublishResult is ClawHub's answer to a publish.
+type ClawHubPublishResult struct {
+	Slug    string `json:"slug"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// Publish uploads a skill bundle. token is the publisher's ClawHub API
+// token.
+func (c *ClawHubClient) Publish(ctx context.Context, token string, req PublishRequest) (*ClawHubPublishResult, error) {
+	if token == "" {
+		return nil, fmt.Errorf("a ClawHub token is required to publish")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/publish", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("User-Agent", "DevClaw/1.0")
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	// Uploads can be large; don't use the client's 30s timeout.
+	client := &http.Client{Timeout: 5 * time.Minute}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ClawHub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("ClawHub API %d: %s", resp.StatusCode, string(msg))
+	}
+
+	result := &ClawHubPublishResult{Slug: req.Slug, Version: req.Version}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parsing publish result: %w", err)
+	}
+	return result, nil
+}
+
 // get performs a GET request and checks for errors.
 func (c *ClawHubClient) get(rawURL string) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
@@ -29,6 +29,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
 )
 
 // claudeCodeResult represents the JSON result from Claude Code CLI (--output-format json).
@@ -367,8 +369,9 @@ func (s *claudeCodeSkill) handleExecute(ctx context.Context, args map[string]any
 
 	// Environment: inject API credentials so Claude Code CLI uses the same
 	// provider as DevClaw (e.g. Z.AI). This mirrors how OpenClaw passes
-	// ANTHROPIC_AUTH_TOKEN and ANTHROPIC_BASE_URL to the CLI.
-	env := os.Environ()
+	// ANTHROPIC_AUTH_TOKEN and ANTHROPIC_BASE_URL to the CLI. The workspace
+	// env is layered over the base environment first.
+	env := sandbox.Environ(ctx, nil)
 	env = clearEnvKeys(env, "ANTHROPIC_API_KEY", "ANTHROPIC_API_KEY_OLD",
 		"ANTHROPIC_AUTH_TOKEN", "ANTHROPIC_BASE_URL",
 		"ANTHROPIC_DEFAULT_SONNET_MODEL", "ANTHROPIC_DEFAULT_OPUS_MODEL")
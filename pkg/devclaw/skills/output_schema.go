@@ -0,0 +1,209 @@
+// Package skills – output_schema.go implements the structured result contract.
+// A tool may declare an OutputSchema (a JSON Schema subset); its result is then
+// decoded into typed JSON and validated before it reaches the agent, so
+// skill-to-skill pipelines can rely on the shape of each step's output.
+//
+// Supported keywords: type, properties, required, items, enum,
+// additionalProperties (false only), minItems, maxItems.
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StructuredResult is a result of a tool with an OutputSchema that also
+// carries run details. Output is decoded and validated against the schema;
+// Meta (output files and the like) is passed along beside it.
+type StructuredResult struct {
+	Output string
+	Meta   map[string]any
+}
+
+// DecodeStructuredOutput converts a raw tool result into a JSON value.
+// Strings, []byte and StructuredResult outputs are parsed as JSON, tolerating a surrounding
+// ```json fence; other values are round-tripped through encoding/json so
+// structs become plain maps/slices.
+func DecodeStructuredOutput(raw any) (any, error) {
+	var data []byte
+	switch v := raw.(type) {
+	case nil:
+		return nil, fmt.Errorf("empty result")
+	case string:
+		data = []byte(stripJSONFence(v))
+	case StructuredResult:
+		data = []byte(stripJSONFence(v.Output))
+	case []byte:
+		data = []byte(stripJSONFence(string(v)))
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encoding result: %w", err)
+		}
+		data = b
+	}
+
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("result is not valid JSON: %w", err)
+	}
+	return out, nil
+}
+
+// stripJSONFence removes a markdown code fence around a JSON payload.
+func stripJSONFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+		s = s[nl+1:]
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	return strings.TrimSpace(s)
+}
+
+// ValidateOutput checks value against schema and returns a descriptive
+// error for the first mismatch found.
+func ValidateOutput(schema map[string]any, value any) error {
+	// Normalize Go literals ([]string, int...) into their decoded JSON form.
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("invalid output schema: %w", err)
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return fmt.Errorf("invalid output schema: %w", err)
+	}
+	return validateSchema("$", normalized, value)
+}
+
+func validateSchema(path string, schema map[string]any, value any) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+		}
+	}
+
+	if t, ok := schema["type"]; ok {
+		if err := checkSchemaType(path, t, value); err != nil {
+			return err
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if req, ok := schema["required"].([]any); ok {
+			for _, r := range req {
+				name, _ := r.(string)
+				if _, present := v[name]; name != "" && !present {
+					return fmt.Errorf("%s: missing required field %q", path, name)
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				if ap, isBool := schema["additionalProperties"].(bool); isBool && !ap {
+					return fmt.Errorf("%s: unexpected field %q", path, k)
+				}
+				continue
+			}
+			if err := validateSchema(path+"."+k, sub, v[k]); err != nil {
+				return err
+			}
+		}
+
+	case []any:
+		if n, ok := schema["minItems"].(float64); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, int(n), len(v))
+		}
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, int(n), len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkSchemaType validates the "type" keyword, which may be a string or a
+// list of strings.
+func checkSchemaType(path string, t any, value any) error {
+	var types []string
+	switch tv := t.(type) {
+	case string:
+		types = []string{tv}
+	case []any:
+		for _, x := range tv {
+			if s, ok := x.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	actual := jsonTypeOf(value)
+	for _, want := range types {
+		if want == actual || (want == "number" && actual == "integer") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), actual)
+}
+
+// jsonTypeOf returns the JSON Schema type name of a decoded JSON value.
+func jsonTypeOf(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if x == float64(int64(x)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func jsonEqual(a, b any) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ab) == string(bb)
+}
@@ -0,0 +1,57 @@
+package skills
+
+import (
+	"strings"
+	"testing"
+)
+
+var weatherSchema = map[string]any{
+	"type":     "object",
+	"required": []string{"city", "temp_c"},
+	"properties": map[string]any{
+		"city":   map[string]any{"type": "string"},
+		"temp_c": map[string]any{"type": "number"},
+		"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"sky":    map[string]any{"enum": []string{"clear", "cloudy"}},
+	},
+}
+
+func TestValidateOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{"valid", `{"city":"Lisbon","temp_c":21.5,"tags":["sunny"],"sky":"clear"}`, ""},
+		{"fenced", "```json\n{\"city\":\"Lisbon\",\"temp_c\":21}\n```", ""},
+		{"missing required", `{"city":"Lisbon"}`, `missing required field "temp_c"`},
+		{"wrong type", `{"city":"Lisbon","temp_c":"hot"}`, "$.temp_c: expected number, got string"},
+		{"bad item", `{"city":"Lisbon","temp_c":1,"tags":[1]}`, "$.tags[0]: expected string"},
+		{"enum", `{"city":"Lisbon","temp_c":1,"sky":"storm"}`, "not one of"},
+		{"not an object", `[1,2]`, "expected object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := DecodeStructuredOutput(tt.raw)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			err = ValidateOutput(weatherSchema, value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeStructuredOutput_RejectsText(t *testing.T) {
+	if _, err := DecodeStructuredOutput("the weather is nice"); err == nil {
+		t.Error("expected error for non-JSON text")
+	}
+}
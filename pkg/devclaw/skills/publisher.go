@@ -0,0 +1,437 @@
+// Package skills – publisher.go implements the outbound half of the skill
+// marketplace: linting a local skill, running its tests, bumping its
+// version, bundling it as a zip and publishing it to ClawHub or to a git
+// repository.
+//
+// A publish runs these steps in order and stops at the first failure:
+//
+//	lint → tests → version bump → bundle → upload (ClawHub) / push (git)
+//
+// The bumped version is only written back to SKILL.md once the upload
+// succeeded, so a failed publish leaves the skill untouched. Dry runs stop
+// before the upload and return the exact payload instead.
+package skills
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// skillNamePattern is the allowed form of a published skill name.
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,63}$`)
+
+// semverPattern matches MAJOR.MINOR.PATCH with an optional suffix.
+var semverPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)(?:[-+][0-9A-Za-z.-]+)?$`)
+
+// maxBundleSize caps the zipped skill, matching the install-side limit.
+const maxBundleSize = 50 * 1024 * 1024
+
+// LintIssue is one problem found in a skill. Errors block publishing,
+// warnings don't.
+type LintIssue struct {
+	Error   bool
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Error {
+		return "error: " + i.Message
+	}
+	return "warning: " + i.Message
+}
+
+// LintSkill checks the SKILL.md of the skill in dir. The definition is
+// nil when the file can't be parsed at all.
+func LintSkill(dir string) (*ClawdHubSkillDef, []LintIssue) {
+	var issues []LintIssue
+	errorf := func(format string, args ...any) {
+		issues = append(issues, LintIssue{Error: true, Message: fmt.Sprintf(format, args...)})
+	}
+	warnf := func(format string, args ...any) {
+		issues = append(issues, LintIssue{Message: fmt.Sprintf(format, args...)})
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+	if err != nil {
+		errorf("SKILL.md not found in %s", dir)
+		return nil, issues
+	}
+	def, body, err := parseFrontmatter(string(content))
+	if err != nil {
+		errorf("SKILL.md frontmatter: %v", err)
+		return nil, issues
+	}
+	def.Body = body
+	def.Dir = dir
+
+	if !skillNamePattern.MatchString(def.Name) {
+		errorf("name %q must be 2-64 lowercase letters, digits or dashes", def.Name)
+	}
+	switch {
+	case def.Description == "":
+		errorf("description is required (it is what the agent and ClawHub search see)")
+	case len(def.Description) > 1024:
+		errorf("description is %d characters, the limit is 1024", len(def.Description))
+	case len(def.Description) < 20:
+		warnf("description is very short; say when the skill should be used")
+	}
+	if def.Version == "" {
+		warnf("no version in frontmatter, 0.1.0 will be used")
+	} else if !semverPattern.MatchString(def.Version) {
+		errorf("version %q is not MAJOR.MINOR.PATCH", def.Version)
+	}
+	if strings.Contains(string(content), "\nmetadata:") && len(def.Metadata) == 0 {
+		errorf("metadata is not valid JSON")
+	}
+	if meta, ok := def.Metadata["openclaw"]; ok {
+		if _, err := parseOpenClawMeta(meta); err != nil {
+			errorf("metadata.openclaw: %v", err)
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		errorf("SKILL.md has no instructions after the frontmatter")
+	}
+	if def.Homepage != "" && !strings.HasPrefix(def.Homepage, "http") {
+		warnf("homepage %q is not a URL", def.Homepage)
+	}
+	return def, issues
+}
+
+// HasLintErrors reports whether any issue blocks publishing.
+func HasLintErrors(issues []LintIssue) bool {
+	for _, i := range issues {
+		if i.Error {
+			return true
+		}
+	}
+	return false
+}
+
+// skillTestCommand returns the command that runs the skill's tests, or nil
+// when it has none. Conventions, in order: test.sh or tests/run.sh, Go
+// tests, a package.json test script, pytest tests.
+func skillTestCommand(ctx context.Context, dir string) *exec.Cmd {
+	for _, script := range []string{"test.sh", filepath.Join("tests", "run.sh")} {
+		if fileExists(filepath.Join(dir, script)) {
+			return exec.CommandContext(ctx, "bash", script)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*_test.go")); len(matches) > 0 || fileExists(filepath.Join(dir, "go.mod")) {
+		return exec.CommandContext(ctx, "go", "test", "./...")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil && strings.Contains(string(data), `"test"`) {
+		return exec.CommandContext(ctx, "npm", "test", "--silent")
+	}
+	if dirExists(filepath.Join(dir, "tests")) {
+		if matches, _ := filepath.Glob(filepath.Join(dir, "tests", "test_*.py")); len(matches) > 0 {
+			return exec.CommandContext(ctx, "python3", "-m", "pytest", "-q", "tests")
+		}
+	}
+	return nil
+}
+
+// RunSkillTests runs the skill's tests in dir. ran is false when the skill
+// has no tests.
+func RunSkillTests(ctx context.Context, dir string) (ran bool, output string, err error) {
+	cmd := skillTestCommand(ctx, dir)
+	if cmd == nil {
+		return false, "", nil
+	}
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return true, string(out), fmt.Errorf("%s failed: %w", strings.Join(cmd.Args, " "), err)
+	}
+	return true, string(out), nil
+}
+
+// BumpVersion increments the major, minor or patch part of version
+// (empty part = unchanged). An empty version starts at 0.1.0.
+func BumpVersion(version, part string) (string, error) {
+	if version == "" {
+		return "0.1.0", nil
+	}
+	m := semverPattern.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("version %q is not MAJOR.MINOR.PATCH", version)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	switch part {
+	case "":
+		return version, nil
+	case "major":
+		major, minor, patch = major+1, 0, 0
+	case "minor":
+		minor, patch = minor+1, 0
+	case "patch":
+		patch++
+	default:
+		return "", fmt.Errorf("unknown version part %q (use major, minor or patch)", part)
+	}
+	return fmt.Sprintf("%d.%d.%d", major, minor, patch), nil
+}
+
+// SetFrontmatterVersion returns skillMD with the version key set,
+// replacing an existing one or adding it after the name.
+func SetFrontmatterVersion(skillMD, version string) string {
+	lines := strings.Split(skillMD, "\n")
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			end = i
+			break
+		}
+	}
+	if !strings.HasPrefix(strings.TrimSpace(skillMD), "---") || end < 0 {
+		return skillMD
+	}
+	insertAt := 1
+	for i := 1; i < end; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "version:") {
+			lines[i] = "version: " + version
+			return strings.Join(lines, "\n")
+		}
+		if strings.HasPrefix(trimmed, "name:") {
+			insertAt = i + 1
+		}
+	}
+	lines = append(lines[:insertAt], append([]string{"version: " + version}, lines[insertAt:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// bundleSkip reports whether a path is left out of the bundle: VCS data,
+// dependencies and caches that the installer would rebuild anyway.
+func bundleSkip(name string, isDir bool) bool {
+	if strings.HasPrefix(name, ".") && name != ".env.example" {
+		return true
+	}
+	if isDir {
+		switch name {
+		case "node_modules", "__pycache__", "venv", "dist", "build":
+			return true
+		}
+	}
+	return strings.HasSuffix(name, ".pyc")
+}
+
+// Bundle is a zipped skill ready to upload.
+type Bundle struct {
+	Data   []byte
+	Files  []string
+	SHA256 string
+}
+
+// BundleSkill zips the skill in dir. overrides replaces the content of
+// files by relative path (e.g. the bumped SKILL.md). Files are stored in a
+// fixed order with a fixed timestamp, so the same content always yields
+// the same archive and checksum.
+func BundleSkill(dir string, overrides map[string][]byte) (*Bundle, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if bundleSkip(d.Name(), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading skill: %w", err)
+	}
+	sort.Strings(files)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	modified := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, rel := range files {
+		data, ok := overrides[rel]
+		if !ok {
+			if data, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+				return nil, err
+			}
+		}
+		mode := os.FileMode(0o644)
+		if st, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel))); err == nil && st.Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+		hdr := &zip.FileHeader{Name: rel, Method: zip.Deflate, Modified: modified}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxBundleSize {
+		return nil, fmt.Errorf("bundle is %d MB, the limit is %d MB", buf.Len()>>20, maxBundleSize>>20)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return &Bundle{Data: buf.Bytes(), Files: files, SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// PublishRequest is the payload uploaded to ClawHub.
+type PublishRequest struct {
+	Slug        string   `json:"slug"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Files       []string `json:"files"`
+	SHA256      string   `json:"sha256"`
+
+	// Archive is the base64 zip bundle.
+	Archive string `json:"archive"`
+}
+
+// NewPublishRequest builds the ClawHub payload for a bundled skill.
+func NewPublishRequest(def *ClawdHubSkillDef, slug string, b *Bundle) PublishRequest {
+	if slug == "" {
+		slug = def.Name
+	}
+	return PublishRequest{
+		Slug:        slug,
+		Name:        def.Name,
+		Version:     def.Version,
+		Description: def.Description,
+		Homepage:    def.Homepage,
+		Files:       b.Files,
+		SHA256:      b.SHA256,
+		Archive:     base64.StdEncoding.EncodeToString(b.Data),
+	}
+}
+
+// GitPublish describes a publish to a git repository: the skill is copied
+// to <repo>/<name>/, committed and pushed.
+type GitPublish struct {
+	// Repo is the clone URL (https or ssh).
+	Repo string
+
+	// Branch to push to (empty = the remote's default branch).
+	Branch string
+
+	// Token authenticates https pushes; ssh URLs use the local SSH agent.
+	Token string
+}
+
+// IsGitTarget reports whether a publish target is a git repository
+// rather than ClawHub.
+func IsGitTarget(target string) bool {
+	return strings.HasPrefix(target, "git@") || strings.HasPrefix(target, "ssh://") ||
+		strings.HasSuffix(target, ".git") || strings.HasPrefix(target, "git+")
+}
+
+// authURL returns the clone URL with the token embedded for https remotes.
+func (g GitPublish) authURL() string {
+	repo := strings.TrimPrefix(g.Repo, "git+")
+	if g.Token == "" || !strings.HasPrefix(repo, "https://") {
+		return repo
+	}
+	return "https://x-access-token:" + g.Token + "@" + strings.TrimPrefix(repo, "https://")
+}
+
+// Commands returns the git commands a publish runs, for dry runs. The
+// token is never included.
+func (g GitPublish) Commands(def *ClawdHubSkillDef) []string {
+	clone := "git clone --depth 1"
+	if g.Branch != "" {
+		clone += " --branch " + g.Branch
+	}
+	return []string{
+		clone + " " + strings.TrimPrefix(g.Repo, "git+") + " <tmp>",
+		fmt.Sprintf("replace <tmp>/%s/ with the bundle", def.Name),
+		fmt.Sprintf("git -C <tmp> commit -m %q", publishCommitMessage(def)),
+		fmt.Sprintf("git -C <tmp> tag -a %s-v%s", def.Name, def.Version),
+		"git -C <tmp> push --follow-tags origin HEAD",
+	}
+}
+
+func publishCommitMessage(def *ClawdHubSkillDef) string {
+	return fmt.Sprintf("Publish %s v%s", def.Name, def.Version)
+}
+
+// Publish pushes the bundled skill to the repository.
+func (g GitPublish) Publish(ctx context.Context, def *ClawdHubSkillDef, b *Bundle) error {
+	tmp, err := os.MkdirTemp("", "devclaw-publish-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = tmp
+		cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			msg := strings.TrimSpace(string(out))
+			if g.Token != "" {
+				msg = strings.ReplaceAll(msg, g.Token, "****")
+			}
+			return fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil
+	}
+
+	clone := []string{"clone", "--depth", "1"}
+	if g.Branch != "" {
+		clone = append(clone, "--branch", g.Branch)
+	}
+	if err := git(append(clone, g.authURL(), ".")...); err != nil {
+		return err
+	}
+
+	target := filepath.Join(tmp, def.Name)
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	if err := extractZip(b.Data, target); err != nil {
+		return err
+	}
+
+	if err := git("add", "--all", def.Name); err != nil {
+		return err
+	}
+	if err := git("commit", "-m", publishCommitMessage(def)); err != nil {
+		return err
+	}
+	if err := git("tag", "-a", def.Name+"-v"+def.Version, "-m", publishCommitMessage(def)); err != nil {
+		return err
+	}
+	return git("push", "--follow-tags", "origin", "HEAD")
+}
+
+func fileExists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && !st.IsDir()
+}
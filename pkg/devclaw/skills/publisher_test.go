@@ -0,0 +1,295 @@
+package skills
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const validSkillMD = `---
+name: weather
+description: Current weather and forecasts for any city.
+version: 1.2.3
+---
+Use the scripts to fetch the weather.
+`
+
+func writeSkill(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		mode := os.FileMode(0o644)
+		if strings.HasSuffix(rel, ".sh") {
+			mode = 0o755
+		}
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLintSkill(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		skillMD  string
+		errors   []string
+		warnings []string
+	}{
+		{"valid", validSkillMD, nil, nil},
+		{
+			"bad fields",
+			"---\nname: Weather Tool\ndescription: Weather\nversion: one\nhomepage: example.com\n---\n",
+			[]string{"name \"Weather Tool\"", "version \"one\"", "no instructions"},
+			[]string{"description is very short", "homepage"},
+		},
+		{"no description", "---\nname: weather\n---\nBody", []string{"description is required"}, []string{"no version"}},
+		{"invalid metadata", "---\nname: weather\ndescription: Current weather and forecasts.\nversion: 1.0.0\nmetadata: {nope\n---\nBody", []string{"metadata is not valid JSON"}, nil},
+	}
+	for _, tc := range cases {
+		def, issues := LintSkill(writeSkill(t, map[string]string{"SKILL.md": tc.skillMD}))
+		if def == nil {
+			t.Fatalf("%s: definition not parsed: %v", tc.name, issues)
+		}
+		var errs, warns []string
+		for _, i := range issues {
+			if i.Error {
+				errs = append(errs, i.Message)
+			} else {
+				warns = append(warns, i.Message)
+			}
+		}
+		for _, want := range tc.errors {
+			if !slices.ContainsFunc(errs, func(m string) bool { return strings.Contains(m, want) }) {
+				t.Errorf("%s: no error about %q in %v", tc.name, want, errs)
+			}
+		}
+		for _, want := range tc.warnings {
+			if !slices.ContainsFunc(warns, func(m string) bool { return strings.Contains(m, want) }) {
+				t.Errorf("%s: no warning about %q in %v", tc.name, want, warns)
+			}
+		}
+		if len(errs) != len(tc.errors) || HasLintErrors(issues) != (len(tc.errors) > 0) {
+			t.Errorf("%s: errors = %v", tc.name, errs)
+		}
+	}
+
+	if def, issues := LintSkill(t.TempDir()); def != nil || !HasLintErrors(issues) {
+		t.Error("a directory without SKILL.md should fail lint")
+	}
+}
+
+func TestBumpVersion(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		version, part, want string
+		wantErr             bool
+	}{
+		{"", "patch", "0.1.0", false},
+		{"1.2.3", "", "1.2.3", false},
+		{"1.2.3", "patch", "1.2.4", false},
+		{"1.2.3", "minor", "1.3.0", false},
+		{"1.2.3-beta.1", "major", "2.0.0", false},
+		{"1.2", "patch", "", true},
+		{"1.2.3", "build", "", true},
+	}
+	for _, tc := range cases {
+		got, err := BumpVersion(tc.version, tc.part)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("BumpVersion(%q, %q) = %q, %v", tc.version, tc.part, got, err)
+		}
+	}
+}
+
+func TestSetFrontmatterVersion(t *testing.T) {
+	t.Parallel()
+	if got := SetFrontmatterVersion(validSkillMD, "1.3.0"); !strings.Contains(got, "\nversion: 1.3.0\n") || strings.Contains(got, "1.2.3") {
+		t.Errorf("replace:\n%s", got)
+	}
+	if got := SetFrontmatterVersion("---\nname: weather\ndescription: d\n---\nBody", "0.1.0"); got != "---\nname: weather\nversion: 0.1.0\ndescription: d\n---\nBody" {
+		t.Errorf("insert:\n%s", got)
+	}
+	if got := SetFrontmatterVersion("no frontmatter", "0.1.0"); got != "no frontmatter" {
+		t.Errorf("without frontmatter = %q", got)
+	}
+}
+
+func TestBundleSkill(t *testing.T) {
+	t.Parallel()
+	dir := writeSkill(t, map[string]string{
+		"SKILL.md":                 validSkillMD,
+		"scripts/fetch.sh":         "#!/bin/sh\necho sunny\n",
+		".env.example":             "API_KEY=\n",
+		".git/config":              "[core]\n",
+		"node_modules/x/index.js":  "x",
+		"scripts/__pycache__/a.py": "x",
+		"scripts/helper.pyc":       "x",
+	})
+
+	b, err := BundleSkill(dir, map[string][]byte{"SKILL.md": []byte("bumped")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".env.example", "SKILL.md", "scripts/fetch.sh"}; !slices.Equal(b.Files, want) {
+		t.Errorf("files = %v, want %v", b.Files, want)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Data), int64(len(b.Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		switch f.Name {
+		case "SKILL.md":
+			if string(data) != "bumped" {
+				t.Errorf("override not applied: %q", data)
+			}
+		case "scripts/fetch.sh":
+			if f.Mode()&0o111 == 0 {
+				t.Error("script lost its executable bit")
+			}
+		}
+	}
+
+	again, err := BundleSkill(dir, map[string][]byte{"SKILL.md": []byte("bumped")})
+	if err != nil || again.SHA256 != b.SHA256 {
+		t.Errorf("bundles of the same content differ: %s vs %s (%v)", again.SHA256, b.SHA256, err)
+	}
+}
+
+func TestRunSkillTests(t *testing.T) {
+	t.Parallel()
+	if ran, _, err := RunSkillTests(context.Background(), writeSkill(t, map[string]string{"SKILL.md": validSkillMD})); ran || err != nil {
+		t.Errorf("skill without tests: ran=%v err=%v", ran, err)
+	}
+
+	pass := writeSkill(t, map[string]string{"test.sh": "echo all good\n"})
+	if ran, out, err := RunSkillTests(context.Background(), pass); !ran || err != nil || !strings.Contains(out, "all good") {
+		t.Errorf("passing tests: ran=%v out=%q err=%v", ran, out, err)
+	}
+	fail := writeSkill(t, map[string]string{"tests/run.sh": "echo broken; exit 1\n"})
+	if ran, out, err := RunSkillTests(context.Background(), fail); !ran || err == nil || !strings.Contains(out, "broken") {
+		t.Errorf("failing tests: ran=%v out=%q err=%v", ran, out, err)
+	}
+}
+
+func TestClawHubPublish(t *testing.T) {
+	t.Parallel()
+	got := make(chan PublishRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/publish" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req PublishRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		got <- req
+		io.WriteString(w, `{"url":"https://clawhub.example/skills/weather"}`)
+	}))
+	defer srv.Close()
+
+	dir := writeSkill(t, map[string]string{"SKILL.md": validSkillMD})
+	def, _ := LintSkill(dir)
+	b, err := BundleSkill(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClawHubClient(srv.URL)
+
+	res, err := client.Publish(context.Background(), "tok", NewPublishRequest(def, "", b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Slug != "weather" || res.Version != "1.2.3" || res.URL != "https://clawhub.example/skills/weather" {
+		t.Errorf("result = %+v", res)
+	}
+	req := <-got
+	archive, _ := base64.StdEncoding.DecodeString(req.Archive)
+	if req.Name != "weather" || req.SHA256 != b.SHA256 || !bytes.Equal(archive, b.Data) {
+		t.Errorf("payload = %+v", req)
+	}
+
+	if _, err := client.Publish(context.Background(), "wrong", NewPublishRequest(def, "", b)); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("rejected publish err = %v", err)
+	}
+	if _, err := client.Publish(context.Background(), "", NewPublishRequest(def, "", b)); err == nil {
+		t.Error("publish without a token should fail")
+	}
+}
+
+func TestGitPublish(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for _, kv := range [][2]string{
+		{"GIT_AUTHOR_NAME", "test"}, {"GIT_AUTHOR_EMAIL", "test@example.com"},
+		{"GIT_COMMITTER_NAME", "test"}, {"GIT_COMMITTER_EMAIL", "test@example.com"},
+	} {
+		t.Setenv(kv[0], kv[1])
+	}
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// A bare remote with one commit, like a skills repository.
+	remote := filepath.Join(t.TempDir(), "skills.git")
+	git("", "init", "-q", "--bare", remote)
+	seed := t.TempDir()
+	git(seed, "clone", "-q", remote, ".")
+	os.WriteFile(filepath.Join(seed, "README.md"), []byte("skills\n"), 0o644)
+	git(seed, "add", "README.md")
+	git(seed, "commit", "-q", "-m", "init")
+	git(seed, "push", "-q", "origin", "HEAD")
+
+	dir := writeSkill(t, map[string]string{"SKILL.md": validSkillMD, "scripts/fetch.sh": "#!/bin/sh\n"})
+	def, _ := LintSkill(dir)
+	b, err := BundleSkill(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := GitPublish{Repo: remote}
+	if !IsGitTarget(remote) || IsGitTarget("https://clawhub.ai") {
+		t.Error("IsGitTarget misclassifies targets")
+	}
+	if err := g.Publish(context.Background(), def, b); err != nil {
+		t.Fatal(err)
+	}
+
+	if files := git(remote, "ls-tree", "-r", "--name-only", "HEAD"); files != "README.md\nweather/SKILL.md\nweather/scripts/fetch.sh" {
+		t.Errorf("remote files:\n%s", files)
+	}
+	if tag := git(remote, "tag"); tag != "weather-v1.2.3" {
+		t.Errorf("tags = %q", tag)
+	}
+
+	cmds := GitPublish{Repo: "https://github.com/acme/skills.git", Token: "secret"}.Commands(def)
+	if strings.Contains(strings.Join(cmds, "\n"), "secret") {
+		t.Error("dry-run commands leak the token")
+	}
+}
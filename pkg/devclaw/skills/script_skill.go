@@ -42,6 +42,7 @@ func NewScriptSkill(def *ClawdHubSkillDef) *ScriptSkill {
 		def: def,
 		meta: Metadata{
 			Name:        def.Name,
+			Version:     def.Version,
 			Description: def.Description,
 			Author:      "clawdhub",
 			Category:    "community",
@@ -70,12 +71,13 @@ func (s *ScriptSkill) Metadata() Metadata {
 }
 
 // Tools returns the tools exposed by this skill.
-// Each discovered script becomes a tool.
+// Each discovered script becomes a tool. Output schemas are declared per
+// tool name in the output_schemas frontmatter key.
 func (s *ScriptSkill) Tools() []Tool {
 	tools := make([]Tool, 0, len(s.scripts)+1)
 
 	// Main "execute" tool that runs the skill with free-form input.
-	tools = append(tools, Tool{
+	execute := Tool{
 		Name:        "execute",
 		Description: fmt.Sprintf("Execute the %s skill with the given input", s.def.Name),
 		Parameters: []ToolParameter{
@@ -86,12 +88,26 @@ func (s *ScriptSkill) Tools() []Tool {
 				Required:    true,
 			},
 		},
-	})
+		OutputSchema: s.def.OutputSchemas["execute"],
+	}
+	if len(execute.OutputSchema) > 0 && len(s.scripts) > 0 {
+		execute.Handler = func(ctx context.Context, args map[string]any) (any, error) {
+			input, _ := args["input"].(string)
+			result, err := s.run(ctx, s.pickScript(input), parseArgs(input), "")
+			if err != nil {
+				return nil, err
+			}
+			return structuredScriptResult(result)
+		}
+	}
+	tools = append(tools, execute)
 
 	// Each script becomes a tool.
 	for _, script := range s.scripts {
+		name := "run_" + sanitizeToolName(script.Name)
+		schema := s.def.OutputSchemas[name]
 		tools = append(tools, Tool{
-			Name:        "run_" + sanitizeToolName(script.Name),
+			Name:        name,
 			Description: fmt.Sprintf("Run %s (%s)", script.Name, script.Runtime),
 			Parameters: []ToolParameter{
 				{
@@ -105,6 +121,19 @@ func (s *ScriptSkill) Tools() []Tool {
 					Description: "Standard input for the script",
 				},
 			},
+			OutputSchema: schema,
+			Handler: func(ctx context.Context, args map[string]any) (any, error) {
+				argv, _ := args["args"].(string)
+				stdin, _ := args["stdin"].(string)
+				result, err := s.run(ctx, script, parseArgs(argv), stdin)
+				if err != nil {
+					return nil, err
+				}
+				if len(schema) > 0 {
+					return structuredScriptResult(result)
+				}
+				return formatScriptResult(result), nil
+			},
 		})
 	}
 
@@ -143,25 +172,26 @@ func (s *ScriptSkill) Execute(ctx context.Context, input string) (string, error)
 		return "", fmt.Errorf("sandbox runner not configured for skill %s", s.def.Name)
 	}
 
-	// If there's exactly one script, run it.
-	if len(s.scripts) == 1 {
-		return s.runScript(ctx, s.scripts[0], input)
+	// No scripts — this is a prompt-only skill.
+	if len(s.scripts) == 0 {
+		return fmt.Sprintf("[%s] This skill is design-only/instruction-based and does not have an execution script. Please follow the instructions provided in its system prompt.", s.def.Name), nil
 	}
 
-	// If there are multiple scripts, try to find one matching the input.
-	for _, script := range s.scripts {
-		if strings.Contains(strings.ToLower(input), strings.ToLower(script.Name)) {
-			return s.runScript(ctx, script, input)
-		}
-	}
+	return s.runScript(ctx, s.pickScript(input), input)
+}
 
-	// Default: run the first script.
-	if len(s.scripts) > 0 {
-		return s.runScript(ctx, s.scripts[0], input)
+// pickScript chooses the script for free-form input: the only script, the
+// first one named in the input, or the first script. There must be at least
+// one script.
+func (s *ScriptSkill) pickScript(input string) SkillScript {
+	if len(s.scripts) > 1 {
+		for _, script := range s.scripts {
+			if strings.Contains(strings.ToLower(input), strings.ToLower(script.Name)) {
+				return script
+			}
+		}
 	}
-
-	// No scripts — this is a prompt-only skill.
-	return fmt.Sprintf("[%s] This skill is design-only/instruction-based and does not have an execution script. Please follow the instructions provided in its system prompt.", s.def.Name), nil
+	return s.scripts[0]
 }
 
 // Shutdown releases resources.
@@ -173,20 +203,38 @@ func (s *ScriptSkill) Shutdown() error {
 
 // runScript executes a specific script through the sandbox.
 func (s *ScriptSkill) runScript(ctx context.Context, script SkillScript, input string) (string, error) {
+	result, err := s.run(ctx, script, parseArgs(input), "")
+	if err != nil {
+		return "", err
+	}
+	return formatScriptResult(result), nil
+}
+
+// run executes a script through the sandbox. Killed runs are errors.
+func (s *ScriptSkill) run(ctx context.Context, script SkillScript, args []string, stdin string) (*sandbox.ExecResult, error) {
+	if s.runner == nil {
+		return nil, fmt.Errorf("sandbox runner not configured for skill %s", s.def.Name)
+	}
 	result, err := s.runner.Run(ctx, &sandbox.ExecRequest{
 		Runtime:  script.Runtime,
 		Script:   script.Path,
-		Args:     parseArgs(input),
+		Args:     args,
+		Stdin:    stdin,
 		SkillDir: s.def.Dir,
 	})
 	if err != nil {
-		return "", fmt.Errorf("running %s: %w", script.Name, err)
+		return nil, fmt.Errorf("running %s: %w", script.Name, err)
 	}
-
 	if result.Killed {
-		return "", fmt.Errorf("script killed: %s", result.KillReason)
+		return nil, fmt.Errorf("script killed: %s", result.KillReason)
 	}
+	return result, nil
+}
 
+// formatScriptResult renders a run as text for tools without an output
+// schema: stdout, or the exit code and both streams on failure, followed by
+// the output files.
+func formatScriptResult(result *sandbox.ExecResult) string {
 	output := result.Stdout
 	if result.ExitCode != 0 {
 		output = fmt.Sprintf("Exit code %d\nStdout: %s\nStderr: %s",
@@ -201,26 +249,32 @@ func (s *ScriptSkill) runScript(ctx context.Context, script SkillScript, input s
 		}
 	}
 
-	return output, nil
+	return output
+}
+
+// structuredScriptResult returns a run of a tool with an output schema:
+// stdout alone is the structured output, output files are metadata, and a
+// failed run is an error.
+func structuredScriptResult(result *sandbox.ExecResult) (StructuredResult, error) {
+	if result.ExitCode != 0 {
+		return StructuredResult{}, fmt.Errorf("exit code %d: %s",
+			result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	out := StructuredResult{Output: result.Stdout}
+	if len(result.OutputFiles) > 0 {
+		out.Meta = map[string]any{"output_files": result.OutputFiles}
+	}
+	return out, nil
 }
 
 // RunScriptByName runs a specific script by name (used by tool handlers).
 func (s *ScriptSkill) RunScriptByName(ctx context.Context, name, args, stdin string) (string, error) {
 	for _, script := range s.scripts {
 		if sanitizeToolName(script.Name) == name || script.Name == name {
-			result, err := s.runner.Run(ctx, &sandbox.ExecRequest{
-				Runtime:  script.Runtime,
-				Script:   script.Path,
-				Args:     parseArgs(args),
-				Stdin:    stdin,
-				SkillDir: s.def.Dir,
-			})
+			result, err := s.run(ctx, script, parseArgs(args), stdin)
 			if err != nil {
 				return "", err
 			}
-			if result.Killed {
-				return "", fmt.Errorf("script killed: %s", result.KillReason)
-			}
 			return result.Stdout, nil
 		}
 	}
@@ -0,0 +1,103 @@
+package skills
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jholhewres/devclaw/pkg/devclaw/sandbox"
+)
+
+func newTestScriptSkill(t *testing.T, scripts map[string]string, schemas map[string]map[string]any) *ScriptSkill {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, "scripts", name), []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := sandbox.DefaultConfig()
+	cfg.TempDir = t.TempDir()
+	runner, err := sandbox.NewRunner(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	t.Cleanup(func() { runner.Close() })
+
+	s := NewScriptSkill(&ClawdHubSkillDef{Name: "weather", Dir: dir, OutputSchemas: schemas})
+	if err := s.Init(context.Background(), map[string]any{"_sandbox_runner": runner}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestScriptSkill_PerToolOutputSchemas(t *testing.T) {
+	s := newTestScriptSkill(t, map[string]string{
+		"report.sh": "#!/bin/sh\necho hi > \"$DEVCLAW_TMPDIR/report.txt\"\necho '{\"city\":\"Lisbon\",\"temp_c\":21}'\n",
+		"notes.sh":  "#!/bin/sh\necho 'plain notes'\n",
+		"fail.sh":   "#!/bin/sh\necho boom >&2\nexit 3\n",
+	}, map[string]map[string]any{
+		"run_report": weatherSchema,
+		"run_fail":   weatherSchema,
+	})
+
+	tools := map[string]Tool{}
+	for _, tool := range s.Tools() {
+		tools[tool.Name] = tool
+	}
+	if len(tools["execute"].OutputSchema) != 0 || len(tools["run_notes"].OutputSchema) != 0 {
+		t.Error("tools without a declared schema should not get one")
+	}
+	if len(tools["run_report"].OutputSchema) == 0 {
+		t.Fatal("run_report lost its schema")
+	}
+
+	// A tool with a schema returns stdout and the output files apart.
+	out, err := tools["run_report"].Handler(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, ok := out.(StructuredResult)
+	if !ok {
+		t.Fatalf("result = %T, want StructuredResult", out)
+	}
+	value, err := DecodeStructuredOutput(res)
+	if err != nil {
+		t.Fatalf("stdout is not clean JSON: %v", err)
+	}
+	if err := ValidateOutput(weatherSchema, value); err != nil {
+		t.Error(err)
+	}
+	files, _ := res.Meta["output_files"].([]string)
+	if len(files) != 1 || filepath.Base(files[0]) != "report.txt" {
+		t.Errorf("meta = %v", res.Meta)
+	}
+
+	// A failed run is an error rather than an exit-code trailer.
+	if _, err := tools["run_fail"].Handler(context.Background(), map[string]any{}); err == nil || !strings.Contains(err.Error(), "exit code 3: boom") {
+		t.Errorf("failed run err = %v", err)
+	}
+
+	// Tools without a schema keep the text output, running their own script.
+	text, err := tools["run_notes"].Handler(context.Background(), map[string]any{})
+	if err != nil || strings.TrimSpace(text.(string)) != "plain notes" {
+		t.Errorf("run_notes = %q, %v", text, err)
+	}
+}
+
+func TestParseFrontmatter_OutputSchemas(t *testing.T) {
+	md := "---\nname: weather\ndescription: Weather\noutput_schemas: {\"run_report\": {\"type\": \"object\", \"required\": [\"city\"]}}\n---\nBody"
+	def, _, err := parseFrontmatter(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.OutputSchemas["run_report"]["type"] != "object" {
+		t.Errorf("output_schemas not parsed: %#v", def.OutputSchemas)
+	}
+}
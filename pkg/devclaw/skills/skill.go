@@ -67,6 +67,11 @@ type Tool struct {
 	// Parameters define os parâmetros aceitos pela ferramenta.
 	Parameters []ToolParameter `json:"parameters"`
 
+	// OutputSchema declara, opcionalmente, o formato do resultado (JSON Schema).
+	// Quando definido, o resultado é convertido em JSON e validado antes de
+	// chegar ao agente; resultados fora do contrato viram erro da ferramenta.
+	OutputSchema map[string]any `json:"output_schema,omitempty"`
+
 	// Handler é a função que executa a ferramenta.
 	Handler ToolHandler `json:"-"`
 }
@@ -1,6 +1,7 @@
 // Package tts provides text-to-speech synthesis for DevClaw.
 // Supports multiple providers: OpenAI TTS (paid, high quality),
-// Edge TTS (free, Microsoft Azure voices), and auto-fallback.
+// Edge TTS (free, Microsoft Azure voices), Piper (local, offline)
+// and auto-fallback.
 package tts
 
 import (
@@ -12,6 +13,9 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -219,6 +223,82 @@ func escapeXML(text string) string {
 	return r.Replace(text)
 }
 
+// ============================================================
+// Piper Provider (local, offline neural TTS)
+// ============================================================
+
+// PiperProvider implements TTS with a local piper binary
+// (https://github.com/rhasspy/piper) and an .onnx voice model. Piper writes
+// WAV; when ffmpeg is installed the audio is converted to Ogg/Opus so
+// channels can send it as a voice note.
+type PiperProvider struct {
+	binary string
+	model  string
+	logger *slog.Logger
+}
+
+// NewPiperProvider creates a Piper provider. binary defaults to "piper".
+func NewPiperProvider(binary, model string, logger *slog.Logger) *PiperProvider {
+	if binary == "" {
+		binary = "piper"
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PiperProvider{
+		binary: binary,
+		model:  model,
+		logger: logger.With("component", "piper-tts"),
+	}
+}
+
+// Synthesize converts text to audio with piper. A voice ending in ".onnx"
+// overrides the configured model.
+func (p *PiperProvider) Synthesize(ctx context.Context, text, voice string) ([]byte, string, error) {
+	model := p.model
+	if strings.HasSuffix(voice, ".onnx") {
+		model = voice
+	}
+	if model == "" {
+		return nil, "", fmt.Errorf("piper: no voice model configured")
+	}
+
+	dir, err := os.MkdirTemp("", "devclaw-piper-*")
+	if err != nil {
+		return nil, "", fmt.Errorf("piper: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	wav := filepath.Join(dir, "speech.wav")
+
+	cmd := exec.CommandContext(ctx, p.binary, "--model", model, "--output_file", wav)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, "", fmt.Errorf("piper: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		ogg := filepath.Join(dir, "speech.ogg")
+		conv := exec.CommandContext(ctx, "ffmpeg", "-y", "-loglevel", "error", "-i", wav,
+			"-c:a", "libopus", "-b:a", "32k", ogg)
+		if out, err := conv.CombinedOutput(); err == nil {
+			if audio, err := os.ReadFile(ogg); err == nil && len(audio) > 0 {
+				return audio, "audio/ogg", nil
+			}
+		} else {
+			p.logger.Warn("opus conversion failed, sending WAV", "error", err, "output", strings.TrimSpace(string(out)))
+		}
+	}
+
+	audio, err := os.ReadFile(wav)
+	if err != nil {
+		return nil, "", fmt.Errorf("piper: reading audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, "", fmt.Errorf("piper: empty audio output")
+	}
+	return audio, "audio/wav", nil
+}
+
 // ============================================================
 // Fallback Provider (tries primary, falls back to secondary)
 // ============================================================
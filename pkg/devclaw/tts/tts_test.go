@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePiper writes a script that behaves like the piper CLI: it reads the
+// text from stdin and writes "<model>:<text>" to --output_file.
+func fakePiper(t *testing.T, body string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "piper")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestPiperProvider(t *testing.T) {
+	// No ffmpeg on PATH, so the WAV output is returned as is.
+	t.Setenv("PATH", "/nonexistent")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ok := fakePiper(t, `model="$2"; out="$4"; printf '%s:' "$model" > "$out"; /bin/cat >> "$out"`)
+	empty := fakePiper(t, `: > "$4"`)
+	failing := fakePiper(t, `echo "model file not found" >&2; exit 1`)
+
+	cases := []struct {
+		name    string
+		binary  string
+		model   string
+		voice   string
+		want    string
+		wantErr string
+	}{
+		{"configured model", ok, "en_US-amy.onnx", "alloy", "en_US-amy.onnx:Hello there", ""},
+		{"voice overrides model", ok, "en_US-amy.onnx", "pt_BR-faber.onnx", "pt_BR-faber.onnx:Hello there", ""},
+		{"no model", ok, "", "alloy", "", "no voice model configured"},
+		{"piper fails", failing, "en_US-amy.onnx", "", "", "model file not found"},
+		{"empty output", empty, "en_US-amy.onnx", "", "", "empty audio output"},
+		{"missing binary", filepath.Join(t.TempDir(), "piper"), "en_US-amy.onnx", "", "", "piper:"},
+	}
+	for _, tc := range cases {
+		p := NewPiperProvider(tc.binary, tc.model, logger)
+		audio, mimeType, err := p.Synthesize(context.Background(), "Hello there", tc.voice)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if string(audio) != tc.want || mimeType != "audio/wav" {
+			t.Errorf("%s: got %q (%s), want %q", tc.name, audio, mimeType, tc.want)
+		}
+	}
+
+	if p := NewPiperProvider("", "m.onnx", nil); p.binary != "piper" {
+		t.Errorf("default binary = %q", p.binary)
+	}
+}
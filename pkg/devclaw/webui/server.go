@@ -138,6 +138,7 @@ type JobInfo struct {
 	ID        string    `json:"id"`
 	Schedule  string    `json:"schedule"`
 	Type      string    `json:"type"`
+	Timezone  string    `json:"timezone"`
 	Command   string    `json:"command"`
 	Enabled   bool      `json:"enabled"`
 	RunCount  int       `json:"run_count"`
@@ -251,6 +252,10 @@ type Server struct {
 	// onVaultInit is called during setup finalize to create the encrypted vault.
 	// Receives (masterPassword, secrets map[name]value) and returns error.
 	onVaultInit func(password string, secrets map[string]string) error
+
+	// setupHooks and setup back the multi-step setup wizard.
+	setupHooks SetupHooks
+	setup      setupState
 }
 
 // New creates a new web UI server.
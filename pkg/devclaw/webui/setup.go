@@ -18,40 +18,50 @@ import (
 
 // SetupRequest contains all data from the setup wizard frontend.
 type SetupRequest struct {
-	Name          string          `json:"name"`
-	Language      string          `json:"language"`
-	Timezone      string          `json:"timezone"`
-	Provider      string          `json:"provider"`
-	APIKey        string          `json:"apiKey"`
-	Model         string          `json:"model"`
-	BaseURL       string          `json:"baseUrl"`
-	OwnerPhone    string          `json:"ownerPhone"`
-	WebuiPassword string          `json:"webuiPassword"`
-	VaultPassword string          `json:"vaultPassword"`
-	AccessMode    string          `json:"accessMode"`
-	Channels      map[string]bool `json:"channels"`
-	EnabledSkills []string        `json:"enabledSkills"`
+	Name           string          `json:"name"`
+	Language       string          `json:"language"`
+	Timezone       string          `json:"timezone"`
+	Provider       string          `json:"provider"`
+	APIKey         string          `json:"apiKey"`
+	Model          string          `json:"model"`
+	BaseURL        string          `json:"baseUrl"`
+	OwnerPhone     string          `json:"ownerPhone"`
+	WebuiPassword  string          `json:"webuiPassword"`
+	VaultPassword  string          `json:"vaultPassword"`
+	AccessMode     string          `json:"accessMode"`
+	SecurityPreset string          `json:"securityPreset"`
+	Channels       map[string]bool `json:"channels"`
+	EnabledSkills  []string        `json:"enabledSkills"`
 }
 
 // handleAPISetup routes setup-related requests.
 func (s *Server) handleAPISetup(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/setup/")
 
-	switch path {
-	case "status":
+	switch {
+	case path == "status":
 		s.handleSetupStatus(w, r)
-	case "test-provider":
+	case path == "test-provider":
 		s.handleSetupTestProvider(w, r)
-	case "finalize":
+	case path == "owner/code":
+		s.handleSetupOwnerCode(w, r)
+	case path == "owner/verify":
+		s.handleSetupOwnerVerify(w, r)
+	case strings.HasPrefix(path, "whatsapp/"):
+		s.handleSetupWhatsApp(w, r, strings.TrimPrefix(path, "whatsapp/"))
+	case path == "validate":
+		s.handleSetupValidate(w, r)
+	case path == "finalize":
 		s.handleSetupFinalize(w, r)
-	case "skills":
+	case path == "skills":
 		s.handleSetupSkills(w, r)
 	default:
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 	}
 }
 
-// handleSetupStatus reports whether the system is already configured.
+// handleSetupStatus reports whether the system is already configured and
+// the wizard progress kept by the server.
 func (s *Server) handleSetupStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
@@ -59,9 +69,16 @@ func (s *Server) handleSetupStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	configured := configFileExists()
+	s.setup.mu.Lock()
+	tested, verified := s.setup.providerTested, s.setup.ownerVerified
+	s.setup.mu.Unlock()
 	writeJSON(w, http.StatusOK, map[string]any{
-		"configured":   configured,
-		"current_step": 0,
+		"configured":      configured,
+		"current_step":    0,
+		"steps":           setupSteps,
+		"provider_tested": tested,
+		"owner_verified":  verified,
+		"whatsapp":        s.setupWhatsAppStatus(),
 	})
 }
 
@@ -101,6 +118,9 @@ func (s *Server) handleSetupTestProvider(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	s.setup.mu.Lock()
+	s.setup.providerTested = true
+	s.setup.mu.Unlock()
 	writeJSON(w, http.StatusOK, map[string]any{"success": true})
 }
 
@@ -131,6 +151,19 @@ func (s *Server) handleSetupFinalize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Run the final validation step again; nothing is written on errors.
+	if checks, ok := s.validateSetup(&setup); !ok {
+		msg := "configuration is invalid"
+		for _, c := range checks {
+			if c.Level == "error" {
+				msg = c.Message
+				break
+			}
+		}
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": msg, "checks": checks})
+		return
+	}
+
 	// Generate and write config.yaml.
 	configYAML := generateConfigYAML(&setup)
 	if err := os.WriteFile("config.yaml", []byte(configYAML), 0o600); err != nil {
@@ -195,14 +228,15 @@ func (s *Server) handleSetupFinalize(w http.ResponseWriter, r *http.Request) {
 		s.logger.Warn("vault creation failed — API key may not be available until vault is initialized")
 	}
 
-	// Signal setup completion (for setup-only mode).
+	// Signal setup completion. In setup-only mode serve loads the new
+	// config and starts the assistant in the same process.
 	if s.onSetupDone != nil {
 		go s.onSetupDone()
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
 		"status":  "ok",
-		"message": "Configuration saved. Restarting server to apply changes.",
+		"message": "Configuration saved. Starting the assistant.",
 	})
 }
 
@@ -535,7 +569,7 @@ func generateConfigYAML(s *SetupRequest) string {
 	}
 	if s.OwnerPhone != "" {
 		// Format as WhatsApp JID for the access system.
-		jid := s.OwnerPhone + "@s.whatsapp.net"
+		jid := digitsOnly(s.OwnerPhone) + "@s.whatsapp.net"
 		fmt.Fprintf(&b, "  owners: [%q]\n\n", jid)
 	} else {
 		b.WriteString("  owners: []\n\n")
@@ -583,7 +617,12 @@ func generateConfigYAML(s *SetupRequest) string {
 	b.WriteString("# ── Security ──\n")
 	b.WriteString("security:\n")
 	b.WriteString("  max_input_length: 4096\n")
-	b.WriteString("  rate_limit: 30\n\n")
+	b.WriteString("  rate_limit: 30\n")
+	if s.SecurityPreset != "" {
+		b.WriteString("  tool_guard:\n")
+		fmt.Fprintf(&b, "    preset: %s\n", s.SecurityPreset)
+	}
+	b.WriteString("\n")
 
 	// ── Skills ──
 	b.WriteString("# ── Skills ──\n")
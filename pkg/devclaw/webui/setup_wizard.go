@@ -0,0 +1,347 @@
+package webui
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setupSteps are the pages of the setup wizard, in order.
+var setupSteps = []string{"identity", "provider", "owner", "channels", "skills", "security", "validate"}
+
+const (
+	// setupCodeTTL is how long an owner verification code stays valid.
+	setupCodeTTL = 10 * time.Minute
+
+	// setupCodeAttempts is how many wrong codes are accepted before the
+	// code is discarded.
+	setupCodeAttempts = 5
+)
+
+// SetupHooks connect the setup wizard to parts of DevClaw that live outside
+// the webui package. Every field is optional; the wizard skips the features
+// whose hooks are missing.
+type SetupHooks struct {
+	// WhatsApp carries the QR functions (GetWhatsAppStatusFn,
+	// SubscribeWhatsAppQRFn, RequestWhatsAppQRFn) of a WhatsApp channel
+	// linked during setup.
+	WhatsApp *AssistantAdapter
+
+	// StartWhatsAppFn connects the setup WhatsApp channel so it emits QR codes.
+	StartWhatsAppFn func() error
+
+	// SendWhatsAppFn sends a text to a phone number over the linked WhatsApp.
+	SendWhatsAppFn func(phone, text string) error
+
+	// ValidateConfigFn parses a generated config.yaml the way serve loads it.
+	ValidateConfigFn func(data []byte) error
+}
+
+// SetSetupHooks registers the hooks used by the setup wizard.
+func (s *Server) SetSetupHooks(h SetupHooks) { s.setupHooks = h }
+
+// setupState is the wizard progress the server keeps between requests.
+type setupState struct {
+	mu             sync.Mutex
+	providerTested bool
+	ownerPhone     string
+	ownerCode      string
+	codeExpires    time.Time
+	codeAttempts   int
+	ownerVerified  bool
+}
+
+// setupCheck is one result of the final validation step.
+type setupCheck struct {
+	Step    string `json:"step"`
+	Level   string `json:"level"` // "ok", "warning" or "error"
+	Message string `json:"message"`
+}
+
+// setupOpen reports whether the wizard endpoints that change state may be
+// used: in setup mode, or while no config.yaml exists.
+func (s *Server) setupOpen(w http.ResponseWriter) bool {
+	if s.setupMode || !configFileExists() {
+		return true
+	}
+	writeJSON(w, http.StatusConflict, map[string]string{"error": "setup already completed"})
+	return false
+}
+
+// handleSetupOwnerCode creates an owner verification code. The code is sent
+// over WhatsApp when a session is already linked; otherwise it is printed on
+// the server console, which proves the user controls the host.
+func (s *Server) handleSetupOwnerCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.setupOpen(w) {
+		return
+	}
+
+	var body struct {
+		OwnerPhone string `json:"ownerPhone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+	phone := digitsOnly(body.OwnerPhone)
+	if len(phone) < 8 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "enter the phone number with country and area code"})
+		return
+	}
+	code, err := newSetupCode()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create code"})
+		return
+	}
+
+	s.setup.mu.Lock()
+	s.setup.ownerPhone = phone
+	s.setup.ownerCode = code
+	s.setup.codeExpires = time.Now().Add(setupCodeTTL)
+	s.setup.codeAttempts = 0
+	s.setup.ownerVerified = false
+	s.setup.mu.Unlock()
+
+	delivery := "console"
+	if s.setupHooks.SendWhatsAppFn != nil && s.setupWhatsAppStatus().Connected {
+		err := s.setupHooks.SendWhatsAppFn(phone, fmt.Sprintf("Your DevClaw setup code is %s", code))
+		if err == nil {
+			delivery = "whatsapp"
+		} else {
+			s.logger.Warn("failed to send setup code over WhatsApp", "error", err)
+		}
+	}
+	if delivery == "console" {
+		s.logger.Warn("setup owner verification code — enter it in the setup wizard",
+			"phone", phone, "code", code)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"delivery":   delivery,
+		"expires_in": int(setupCodeTTL.Seconds()),
+	})
+}
+
+// handleSetupOwnerVerify checks an owner verification code.
+func (s *Server) handleSetupOwnerVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.setupOpen(w) {
+		return
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+
+	s.setup.mu.Lock()
+	defer s.setup.mu.Unlock()
+	switch {
+	case s.setup.ownerCode == "":
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "request a code first"})
+		return
+	case time.Now().After(s.setup.codeExpires):
+		s.setup.ownerCode = ""
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "the code expired, request a new one"})
+		return
+	case s.setup.codeAttempts >= setupCodeAttempts:
+		s.setup.ownerCode = ""
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too many attempts, request a new code"})
+		return
+	}
+	s.setup.codeAttempts++
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(body.Code)), []byte(s.setup.ownerCode)) != 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "wrong code"})
+		return
+	}
+	s.setup.ownerCode = ""
+	s.setup.ownerVerified = true
+	writeJSON(w, http.StatusOK, map[string]any{"verified": true, "ownerPhone": s.setup.ownerPhone})
+}
+
+// handleSetupWhatsApp links WhatsApp during setup.
+//
+//	POST /api/setup/whatsapp/start  → connect the setup channel
+//	GET  /api/setup/whatsapp/status → connection status
+//	GET  /api/setup/whatsapp/qr     → SSE stream of QR events
+//	POST /api/setup/whatsapp/qr     → request a new QR code
+func (s *Server) handleSetupWhatsApp(w http.ResponseWriter, r *http.Request, action string) {
+	adapter := s.setupHooks.WhatsApp
+	if adapter == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": "WhatsApp linking is not available during setup"})
+		return
+	}
+	if !s.setupOpen(w) {
+		return
+	}
+
+	switch {
+	case action == "start" && r.Method == http.MethodPost:
+		if s.setupHooks.StartWhatsAppFn != nil {
+			if err := s.setupHooks.StartWhatsAppFn(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case action == "status" && r.Method == http.MethodGet:
+		s.handleWhatsAppStatus(w, r, adapter)
+	case action == "qr" && r.Method == http.MethodGet:
+		s.handleWhatsAppQRStream(w, r, adapter)
+	case action == "qr" && r.Method == http.MethodPost:
+		s.handleWhatsAppQRRequest(w, r, adapter)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// setupWhatsAppStatus returns the status of the setup WhatsApp channel.
+func (s *Server) setupWhatsAppStatus() WhatsAppStatus {
+	if a := s.setupHooks.WhatsApp; a != nil && a.GetWhatsAppStatusFn != nil {
+		return a.GetWhatsAppStatusFn()
+	}
+	return WhatsAppStatus{}
+}
+
+// handleSetupValidate runs the final validation step without writing anything.
+func (s *Server) handleSetupValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	var setup SetupRequest
+	if err := json.NewDecoder(r.Body).Decode(&setup); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if setup.Name == "" {
+		setup.Name = "DevClaw"
+	}
+	checks, ok := s.validateSetup(&setup)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": ok, "checks": checks})
+}
+
+// validateSetup checks the wizard data step by step and parses the
+// config.yaml it would write. ok is false when any check is an error.
+func (s *Server) validateSetup(setup *SetupRequest) (checks []setupCheck, ok bool) {
+	add := func(step, level, format string, args ...any) {
+		checks = append(checks, setupCheck{Step: step, Level: level, Message: fmt.Sprintf(format, args...)})
+	}
+
+	s.setup.mu.Lock()
+	tested := s.setup.providerTested
+	verified := s.setup.ownerVerified && s.setup.ownerPhone == digitsOnly(setup.OwnerPhone)
+	s.setup.mu.Unlock()
+
+	// Provider.
+	switch {
+	case setup.Model == "":
+		add("provider", "error", "a model is required")
+	case setup.APIKey == "" && setup.Provider != "ollama":
+		add("provider", "warning", "no API key — set DEVCLAW_API_KEY before chatting")
+	case !tested:
+		add("provider", "warning", "the connection to %s was not tested", setup.Model)
+	default:
+		add("provider", "ok", "%s answered the test request", setup.Model)
+	}
+
+	// Owner.
+	switch {
+	case setup.OwnerPhone == "":
+		add("owner", "warning", "no owner — only the web UI has full access")
+	case !verified:
+		add("owner", "error", "verify the owner phone number %s", digitsOnly(setup.OwnerPhone))
+	default:
+		add("owner", "ok", "owner %s verified", digitsOnly(setup.OwnerPhone))
+	}
+
+	// Channels.
+	if setup.Channels["whatsapp"] {
+		if s.setupWhatsAppStatus().Connected {
+			add("channels", "ok", "WhatsApp linked")
+		} else {
+			add("channels", "warning", "WhatsApp is not linked yet — scan the QR code from the Channels page after startup")
+		}
+	}
+	for _, ch := range []struct{ name, env string }{
+		{"telegram", "TELEGRAM_BOT_TOKEN"},
+		{"discord", "DISCORD_BOT_TOKEN"},
+		{"slack", "SLACK_BOT_TOKEN"},
+	} {
+		if setup.Channels[ch.name] && os.Getenv(ch.env) == "" {
+			add("channels", "warning", "%s is enabled but %s is not set — it stays off until the token is added", ch.name, ch.env)
+		}
+	}
+
+	// Skills.
+	if n := len(setup.EnabledSkills); n > 0 {
+		add("skills", "ok", "%d skills will be installed", n)
+	}
+
+	// Security.
+	if setup.WebuiPassword == "" {
+		add("security", "warning", "the web UI has no password — do not expose it to the internet")
+	}
+
+	// The generated config must load.
+	data := []byte(generateConfigYAML(setup))
+	var err error
+	if s.setupHooks.ValidateConfigFn != nil {
+		err = s.setupHooks.ValidateConfigFn(data)
+	} else {
+		var raw map[string]any
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		add("validate", "error", "the generated config.yaml is invalid: %v", err)
+	} else {
+		add("validate", "ok", "config.yaml is valid")
+	}
+
+	ok = true
+	for _, c := range checks {
+		if c.Level == "error" {
+			ok = false
+		}
+	}
+	return checks, ok
+}
+
+// newSetupCode returns a random 6-digit code.
+func newSetupCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// digitsOnly strips everything but digits from a phone number.
+func digitsOnly(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
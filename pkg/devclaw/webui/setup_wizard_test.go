@@ -0,0 +1,218 @@
+package webui
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSetupServer(hooks SetupHooks) *Server {
+	s := New(Config{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.SetSetupMode(true)
+	s.SetSetupHooks(hooks)
+	return s
+}
+
+// setupCall sends a JSON request to a setup endpoint and decodes the reply.
+func setupCall(t *testing.T, s *Server, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handleAPISetup(rec, httptest.NewRequest(method, "/api/setup/"+path, strings.NewReader(body)))
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: invalid JSON %q", method, path, rec.Body.String())
+	}
+	return rec.Code, out
+}
+
+func TestSetupOwnerVerification(t *testing.T) {
+	t.Parallel()
+	s := newSetupServer(SetupHooks{})
+
+	if code, out := setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"123456"}`); code != http.StatusBadRequest || out["error"] != "request a code first" {
+		t.Errorf("verify without a code = %d %v", code, out)
+	}
+	if code, _ := setupCall(t, s, http.MethodPost, "owner/code", `{"ownerPhone":"123"}`); code != http.StatusBadRequest {
+		t.Errorf("short phone accepted: %d", code)
+	}
+
+	code, out := setupCall(t, s, http.MethodPost, "owner/code", `{"ownerPhone":"+55 (11) 98765-4321"}`)
+	if code != http.StatusOK || out["delivery"] != "console" {
+		t.Fatalf("owner/code = %d %v", code, out)
+	}
+	if code, out := setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"nope"}`); code != http.StatusBadRequest || out["error"] != "wrong code" {
+		t.Errorf("wrong code = %d %v", code, out)
+	}
+
+	s.setup.mu.Lock()
+	valid := s.setup.ownerCode
+	s.setup.mu.Unlock()
+	code, out = setupCall(t, s, http.MethodPost, "owner/verify", `{"code":" `+valid+` "}`)
+	if code != http.StatusOK || out["verified"] != true || out["ownerPhone"] != "5511987654321" {
+		t.Errorf("verify = %d %v", code, out)
+	}
+	// A code is used once.
+	if code, _ := setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"`+valid+`"}`); code != http.StatusBadRequest {
+		t.Errorf("code reused: %d", code)
+	}
+	if _, out := setupCall(t, s, http.MethodGet, "status", ""); out["owner_verified"] != true {
+		t.Errorf("status = %v", out)
+	}
+}
+
+func TestSetupOwnerVerification_Limits(t *testing.T) {
+	t.Parallel()
+	s := newSetupServer(SetupHooks{})
+	request := func() string {
+		if code, out := setupCall(t, s, http.MethodPost, "owner/code", `{"ownerPhone":"5511987654321"}`); code != http.StatusOK {
+			t.Fatalf("owner/code = %d %v", code, out)
+		}
+		s.setup.mu.Lock()
+		defer s.setup.mu.Unlock()
+		return s.setup.ownerCode
+	}
+
+	valid := request()
+	for i := 0; i < setupCodeAttempts; i++ {
+		setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"wrong"}`)
+	}
+	if code, _ := setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"`+valid+`"}`); code != http.StatusTooManyRequests {
+		t.Errorf("after %d wrong codes: %d, want 429", setupCodeAttempts, code)
+	}
+
+	valid = request()
+	s.setup.mu.Lock()
+	s.setup.codeExpires = time.Now().Add(-time.Second)
+	s.setup.mu.Unlock()
+	if code, out := setupCall(t, s, http.MethodPost, "owner/verify", `{"code":"`+valid+`"}`); code != http.StatusBadRequest || !strings.Contains(out["error"].(string), "expired") {
+		t.Errorf("expired code = %d %v", code, out)
+	}
+}
+
+func TestSetupOwnerCode_WhatsAppDelivery(t *testing.T) {
+	t.Parallel()
+	var sentTo, sentText string
+	connected := false
+	s := newSetupServer(SetupHooks{
+		WhatsApp: &AssistantAdapter{GetWhatsAppStatusFn: func() WhatsAppStatus { return WhatsAppStatus{Connected: connected} }},
+		SendWhatsAppFn: func(phone, text string) error {
+			sentTo, sentText = phone, text
+			return nil
+		},
+	})
+
+	if _, out := setupCall(t, s, http.MethodPost, "owner/code", `{"ownerPhone":"5511987654321"}`); out["delivery"] != "console" {
+		t.Errorf("unlinked WhatsApp delivery = %v", out["delivery"])
+	}
+	connected = true
+	if _, out := setupCall(t, s, http.MethodPost, "owner/code", `{"ownerPhone":"5511987654321"}`); out["delivery"] != "whatsapp" {
+		t.Errorf("linked WhatsApp delivery = %v", out["delivery"])
+	}
+	if sentTo != "5511987654321" || !strings.HasSuffix(sentText, s.setup.ownerCode) {
+		t.Errorf("sent %q to %s", sentText, sentTo)
+	}
+}
+
+func TestValidateSetup(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "")
+	base := SetupRequest{Name: "DevClaw", Provider: "openai", APIKey: "sk-test", Model: "gpt-4o", WebuiPassword: "pw"}
+
+	cases := []struct {
+		name     string
+		edit     func(*SetupRequest)
+		verified string // phone verified in the wizard
+		tested   bool
+		validate func([]byte) error
+		wantOK   bool
+		want     []string // "step level message-substring"
+	}{
+		{
+			name: "complete", tested: true, verified: "5511987654321",
+			edit: func(r *SetupRequest) {
+				r.OwnerPhone = "+55 11 98765-4321"
+				r.EnabledSkills = []string{"github", "weather"}
+			},
+			wantOK: true,
+			want:   []string{"provider ok gpt-4o answered", "owner ok owner 5511987654321 verified", "skills ok 2 skills", "validate ok config.yaml is valid"},
+		},
+		{
+			name: "warnings only",
+			edit: func(r *SetupRequest) {
+				r.APIKey = ""
+				r.WebuiPassword = ""
+				r.Channels = map[string]bool{"telegram": true}
+			},
+			wantOK: true,
+			want:   []string{"provider warning no API key", "owner warning no owner", "channels warning telegram is enabled", "security warning no password"},
+		},
+		{
+			name:   "untested provider",
+			wantOK: true,
+			want:   []string{"provider warning was not tested"},
+		},
+		{
+			name: "unverified owner", tested: true, verified: "5511000000000",
+			edit: func(r *SetupRequest) { r.OwnerPhone = "5511987654321" },
+			want: []string{"owner error verify the owner phone number 5511987654321"},
+		},
+		{
+			name: "no model", tested: true,
+			edit: func(r *SetupRequest) { r.Model = "" },
+			want: []string{"provider error a model is required"},
+		},
+		{
+			name: "config rejected", tested: true,
+			validate: func([]byte) error { return errors.New("unknown field") },
+			want:     []string{"validate error invalid: unknown field"},
+		},
+	}
+	for _, tc := range cases {
+		s := newSetupServer(SetupHooks{ValidateConfigFn: tc.validate})
+		s.setup.providerTested = tc.tested
+		s.setup.ownerPhone, s.setup.ownerVerified = tc.verified, tc.verified != ""
+		req := base
+		if tc.edit != nil {
+			tc.edit(&req)
+		}
+
+		checks, ok := s.validateSetup(&req)
+		if ok != tc.wantOK {
+			t.Errorf("%s: ok = %v, checks %+v", tc.name, ok, checks)
+		}
+		for _, want := range tc.want {
+			parts := strings.SplitN(want, " ", 3)
+			found := false
+			for _, c := range checks {
+				if c.Step == parts[0] && c.Level == parts[1] && strings.Contains(c.Message, parts[2]) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: no check %q in %+v", tc.name, want, checks)
+			}
+		}
+	}
+}
+
+func TestSetupWhatsAppStart(t *testing.T) {
+	t.Parallel()
+	s := newSetupServer(SetupHooks{})
+	if code, _ := setupCall(t, s, http.MethodPost, "whatsapp/start", ""); code != http.StatusNotImplemented {
+		t.Errorf("whatsapp/start without hooks = %d", code)
+	}
+
+	started := false
+	s = newSetupServer(SetupHooks{
+		WhatsApp:        &AssistantAdapter{},
+		StartWhatsAppFn: func() error { started = true; return nil },
+	})
+	if code, _ := setupCall(t, s, http.MethodPost, "whatsapp/start", ""); code != http.StatusOK || !started {
+		t.Errorf("whatsapp/start = %d, started %v", code, started)
+	}
+}
@@ -108,6 +108,16 @@ export interface DashboardData {
 export interface SetupStatus {
   configured: boolean
   current_step: number
+  steps?: string[]
+  provider_tested?: boolean
+  owner_verified?: boolean
+  whatsapp?: WhatsAppStatus
+}
+
+export interface SetupCheck {
+  step: string
+  level: 'ok' | 'warning' | 'error'
+  message: string
 }
 
 /* ── Security Types ── */
@@ -346,6 +356,26 @@ export const api = {
         method: 'POST',
         body: JSON.stringify({ provider, api_key: apiKey, model, base_url: baseUrl || '' }),
       }),
+    ownerCode: (ownerPhone: string) =>
+      request<{ delivery: 'console' | 'whatsapp'; expires_in: number }>('/setup/owner/code', {
+        method: 'POST',
+        body: JSON.stringify({ ownerPhone }),
+      }),
+    ownerVerify: (code: string) =>
+      request<{ verified: boolean; ownerPhone: string }>('/setup/owner/verify', {
+        method: 'POST',
+        body: JSON.stringify({ code }),
+      }),
+    whatsapp: {
+      start: () => request<{ status: string }>('/setup/whatsapp/start', { method: 'POST' }),
+      status: () => request<WhatsAppStatus>('/setup/whatsapp/status'),
+      requestQR: () => request<{ status: string; message: string }>('/setup/whatsapp/qr', { method: 'POST' }),
+    },
+    validate: (data: Record<string, unknown>) =>
+      request<{ ok: boolean; checks: SetupCheck[] }>('/setup/validate', {
+        method: 'POST',
+        body: JSON.stringify(data),
+      }),
     finalize: (data: Record<string, unknown>) =>
       request<{ status: string; message: string }>('/setup/finalize', {
         method: 'POST',
@@ -5,6 +5,8 @@ import { StepProvider } from './StepProvider'
 import { StepSecurity } from './StepSecurity'
 import { StepChannels } from './StepChannels'
 import { StepSkills } from './StepSkills'
+import { StepOwner } from './StepOwner'
+import { StepValidate } from './StepValidate'
 
 export interface SetupData {
   /* Step 1: Identity */
@@ -18,17 +20,21 @@ export interface SetupData {
   model: string
   baseUrl: string
 
-  /* Step 3: Security */
+  /* Step 3: Owner */
   ownerPhone: string
-  webuiPassword: string
-  vaultPassword: string
-  accessMode: 'relaxed' | 'strict' | 'paranoid'
+  ownerVerified: boolean
 
   /* Step 4: Channels */
   channels: Record<string, boolean>
 
   /* Step 5: Skills */
   enabledSkills: string[]
+
+  /* Step 6: Security */
+  webuiPassword: string
+  vaultPassword: string
+  accessMode: 'relaxed' | 'strict' | 'paranoid'
+  securityPreset: 'personal' | 'family-shared' | 'business-strict' | 'developer-yolo'
 }
 
 const INITIAL_DATA: SetupData = {
@@ -40,9 +46,11 @@ const INITIAL_DATA: SetupData = {
   model: '',
   baseUrl: '',
   ownerPhone: '',
+  ownerVerified: false,
   webuiPassword: '',
   vaultPassword: '',
   accessMode: 'strict',
+  securityPreset: 'personal',
   channels: {},
   enabledSkills: [],
 }
@@ -50,13 +58,19 @@ const INITIAL_DATA: SetupData = {
 const STEPS = [
   { id: 1, label: 'Identity' },
   { id: 2, label: 'Provider' },
-  { id: 3, label: 'Security' },
+  { id: 3, label: 'Owner' },
   { id: 4, label: 'Channels' },
   { id: 5, label: 'Skills' },
+  { id: 6, label: 'Security' },
+  { id: 7, label: 'Review' },
 ]
 
+const LAST_STEP = STEPS.length
+
 /**
- * 5-step setup wizard with modern visual stepper.
+ * Multi-step setup wizard with modern visual stepper. The last step asks
+ * the server to validate everything before config.yaml is written; the
+ * server then starts the assistant in the same process.
  */
 export function SetupWizard() {
   const [step, setStep] = useState(1)
@@ -64,12 +78,13 @@ export function SetupWizard() {
   const [submitting, setSubmitting] = useState(false)
   const [done, setDone] = useState(false)
   const [error, setError] = useState('')
+  const [valid, setValid] = useState(false)
 
   const updateData = (partial: Partial<SetupData>) => {
     setData((prev) => ({ ...prev, ...partial }))
   }
 
-  const next = () => setStep((s) => Math.min(s + 1, 5))
+  const next = () => setStep((s) => Math.min(s + 1, LAST_STEP))
   const prev = () => setStep((s) => Math.max(s - 1, 1))
 
   const handleFinalize = async () => {
@@ -157,9 +172,11 @@ export function SetupWizard() {
       <div className="min-h-[300px]">
         {step === 1 && <StepIdentity data={data} updateData={updateData} />}
         {step === 2 && <StepProvider data={data} updateData={updateData} />}
-        {step === 3 && <StepSecurity data={data} updateData={updateData} />}
+        {step === 3 && <StepOwner data={data} updateData={updateData} />}
         {step === 4 && <StepChannels data={data} updateData={updateData} />}
         {step === 5 && <StepSkills data={data} updateData={updateData} />}
+        {step === 6 && <StepSecurity data={data} updateData={updateData} />}
+        {step === 7 && <StepValidate data={data} onResult={setValid} />}
       </div>
 
       {/* Error */}
@@ -197,10 +214,11 @@ export function SetupWizard() {
             ))}
           </div>
 
-          {step < 5 ? (
+          {step < LAST_STEP ? (
             <button
               onClick={next}
-              className="group flex cursor-pointer items-center gap-2 rounded-xl bg-orange-500 px-5 py-2.5 text-sm font-medium text-white shadow-lg shadow-orange-500/20 transition-all hover:bg-orange-400 hover:shadow-orange-500/30"
+              disabled={step === 3 && data.ownerPhone !== '' && !data.ownerVerified}
+              className="group flex cursor-pointer items-center gap-2 rounded-xl bg-orange-500 px-5 py-2.5 text-sm font-medium text-white shadow-lg shadow-orange-500/20 transition-all hover:bg-orange-400 hover:shadow-orange-500/30 disabled:cursor-not-allowed disabled:opacity-40"
             >
               Next
               <ArrowRight className="h-3.5 w-3.5 transition-transform group-hover:translate-x-0.5" />
@@ -208,8 +226,8 @@ export function SetupWizard() {
           ) : (
             <button
               onClick={handleFinalize}
-              disabled={submitting}
-              className="group flex cursor-pointer items-center gap-2 rounded-xl bg-emerald-500 px-5 py-2.5 text-sm font-medium text-white shadow-lg shadow-emerald-500/20 transition-all hover:bg-emerald-400 hover:shadow-emerald-500/30 disabled:cursor-wait disabled:opacity-50"
+              disabled={submitting || !valid}
+              className="group flex cursor-pointer items-center gap-2 rounded-xl bg-emerald-500 px-5 py-2.5 text-sm font-medium text-white shadow-lg shadow-emerald-500/20 transition-all hover:bg-emerald-400 hover:shadow-emerald-500/30 disabled:cursor-not-allowed disabled:opacity-50"
             >
               {submitting ? (
                 <>
@@ -231,8 +249,8 @@ export function SetupWizard() {
 }
 
 /**
- * Post-setup screen: shows progress while the server restarts,
- * then auto-redirects to the dashboard.
+ * Post-setup screen: shows progress while the server starts the assistant
+ * with the new configuration, then auto-redirects to the dashboard.
  */
 function SetupComplete({ hasPassword }: { hasPassword: boolean }) {
   const [phase, setPhase] = useState<'restarting' | 'ready'>('restarting')
@@ -244,7 +262,7 @@ function SetupComplete({ hasPassword }: { hasPassword: boolean }) {
     const poll = async () => {
       while (!cancelled && attempts < 60) {
         attempts++
-        // Wait for server restart (pm2 auto-restarts)
+        // The setup server stops and the assistant's web UI starts.
         await new Promise((r) => setTimeout(r, 2000))
         try {
           const res = await fetch('/api/auth/status')
@@ -258,7 +276,7 @@ function SetupComplete({ hasPassword }: { hasPassword: boolean }) {
             return
           }
         } catch {
-          // Server still restarting — keep polling
+          // Assistant still starting — keep polling
         }
       }
     }
@@ -282,7 +300,7 @@ function SetupComplete({ hasPassword }: { hasPassword: boolean }) {
         </h2>
         <p className="mt-2 text-sm text-zinc-400 max-w-sm">
           {phase === 'restarting'
-            ? 'The server is restarting with the new configuration. Please wait...'
+            ? 'DevClaw is starting with the new configuration. Please wait...'
             : 'Redirecting to dashboard...'
           }
         </p>
@@ -1,5 +1,9 @@
-import { MessageSquare, Check, Phone } from 'lucide-react'
+import { useEffect, useRef, useState } from 'react'
+import { QRCodeSVG } from 'qrcode.react'
+import { MessageSquare, Check, CheckCircle2, Loader2, RefreshCw } from 'lucide-react'
+import { api, type WhatsAppStatus } from '@/lib/api'
 import type { SetupData } from './SetupWizard'
+import { setupErrorMessage } from './StepOwner'
 
 interface Props {
   data: SetupData
@@ -131,25 +135,8 @@ export function StepChannels({ data, updateData }: Props) {
         })}
       </div>
 
-      {/* Owner phone — only shown when WhatsApp is enabled */}
-      {data.channels['whatsapp'] && (
-        <div className="rounded-xl border border-emerald-500/20 bg-emerald-500/5 p-4 space-y-3">
-          <label className="flex items-center gap-2 text-sm font-medium text-zinc-300">
-            <Phone className="h-3.5 w-3.5 text-emerald-400" />
-            Owner phone number (WhatsApp)
-          </label>
-          <input
-            type="tel"
-            value={data.ownerPhone}
-            onChange={(e) => updateData({ ownerPhone: e.target.value.replace(/\D/g, '') })}
-            placeholder="5511999999999"
-            className="flex h-11 w-full rounded-xl border border-zinc-700/50 bg-zinc-800/50 px-4 text-sm text-white placeholder:text-zinc-600 outline-none transition-all focus:border-emerald-500/50 focus:ring-2 focus:ring-emerald-500/10"
-          />
-          <p className="text-xs text-zinc-500">
-            Country code + area code + number, no spaces. This number will have full access (owner).
-          </p>
-        </div>
-      )}
+      {/* Live WhatsApp linking — only shown when WhatsApp is enabled */}
+      {data.channels['whatsapp'] && <WhatsAppLink />}
 
       <div className="flex items-center gap-2.5 rounded-xl bg-zinc-800/40 px-4 py-3 ring-1 ring-zinc-700/30">
         <MessageSquare className="h-4 w-4 shrink-0 text-orange-400" />
@@ -160,3 +147,119 @@ export function StepChannels({ data, updateData }: Props) {
     </div>
   )
 }
+
+type LinkState = 'starting' | 'waiting_qr' | 'connected' | 'timeout' | 'error'
+
+/**
+ * Links WhatsApp during setup: starts the setup channel and shows the QR
+ * codes streamed by the server until the phone is linked.
+ */
+function WhatsAppLink() {
+  const [state, setState] = useState<LinkState>('starting')
+  const [qrCode, setQrCode] = useState('')
+  const [message, setMessage] = useState('')
+  const eventSourceRef = useRef<EventSource | null>(null)
+
+  const connectSSE = () => {
+    eventSourceRef.current?.close()
+    const es = new EventSource('/api/setup/whatsapp/qr')
+    eventSourceRef.current = es
+
+    es.addEventListener('status', (e) => {
+      const status: WhatsAppStatus = JSON.parse(e.data)
+      if (status.connected) {
+        setState('connected')
+      } else {
+        setState('waiting_qr')
+      }
+    })
+    es.addEventListener('code', (e) => {
+      setQrCode(JSON.parse(e.data).code)
+      setState('waiting_qr')
+    })
+    es.addEventListener('success', () => {
+      setState('connected')
+      setQrCode('')
+      es.close()
+    })
+    es.addEventListener('timeout', () => {
+      setState('timeout')
+      setQrCode('')
+    })
+    es.addEventListener('close', () => es.close())
+    es.onerror = () => {
+      es.close()
+    }
+  }
+
+  useEffect(() => {
+    api.setup.whatsapp.start()
+      .then(() => api.setup.whatsapp.status())
+      .then((status) => {
+        if (status.connected) {
+          setState('connected')
+        } else {
+          connectSSE()
+        }
+      })
+      .catch((err) => {
+        setState('error')
+        setMessage(setupErrorMessage(err, 'WhatsApp linking is not available'))
+      })
+    return () => {
+      eventSourceRef.current?.close()
+    }
+  }, [])
+
+  const handleRefresh = async () => {
+    try {
+      await api.setup.whatsapp.requestQR()
+      setState('waiting_qr')
+      setQrCode('')
+      connectSSE()
+    } catch (err) {
+      setMessage(setupErrorMessage(err, 'Failed to request a new QR code'))
+    }
+  }
+
+  return (
+    <div className="rounded-xl border border-emerald-500/20 bg-emerald-500/5 p-4">
+      {state === 'connected' ? (
+        <div className="flex items-center gap-2.5">
+          <CheckCircle2 className="h-4 w-4 text-emerald-400" />
+          <p className="text-sm text-emerald-300">WhatsApp linked</p>
+        </div>
+      ) : state === 'error' ? (
+        <p className="text-xs text-zinc-400">
+          {message} You can link WhatsApp from the Channels page after setup.
+        </p>
+      ) : (
+        <div className="flex items-center gap-4">
+          <div className="flex h-40 w-40 shrink-0 items-center justify-center rounded-lg bg-white p-2">
+            {qrCode ? (
+              <QRCodeSVG value={qrCode} size={144} />
+            ) : (
+              <Loader2 className="h-6 w-6 animate-spin text-zinc-400" />
+            )}
+          </div>
+          <div className="space-y-2">
+            <p className="text-sm text-zinc-300">
+              {state === 'timeout' ? 'The QR code expired.' : 'Scan with WhatsApp → Linked devices → Link a device.'}
+            </p>
+            <p className="text-xs text-zinc-500">Optional — you can also link later from the Channels page.</p>
+            {state === 'timeout' && (
+              <button
+                onClick={handleRefresh}
+                className="flex cursor-pointer items-center gap-1.5 text-xs text-emerald-400 hover:text-emerald-300"
+              >
+                <RefreshCw className="h-3 w-3" />
+                New QR code
+              </button>
+            )}
+            {message && <p className="text-xs text-red-400">{message}</p>}
+          </div>
+        </div>
+      )}
+    </div>
+  )
+}
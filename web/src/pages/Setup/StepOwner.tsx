@@ -0,0 +1,142 @@
+import { useState } from 'react'
+import { Phone, KeyRound, CheckCircle2, Loader2, Terminal } from 'lucide-react'
+import { api } from '@/lib/api'
+import type { SetupData } from './SetupWizard'
+
+interface Props {
+  data: SetupData
+  updateData: (partial: Partial<SetupData>) => void
+}
+
+/** Extracts the "error" field of a JSON API error body. */
+export function setupErrorMessage(err: unknown, fallback: string): string {
+  if (!(err instanceof Error)) return fallback
+  try {
+    return JSON.parse(err.message).error || fallback
+  } catch {
+    return err.message || fallback
+  }
+}
+
+export function StepOwner({ data, updateData }: Props) {
+  const [code, setCode] = useState('')
+  const [delivery, setDelivery] = useState<'console' | 'whatsapp' | null>(null)
+  const [sending, setSending] = useState(false)
+  const [verifying, setVerifying] = useState(false)
+  const [error, setError] = useState('')
+
+  const handleSend = async () => {
+    setSending(true)
+    setError('')
+    try {
+      const res = await api.setup.ownerCode(data.ownerPhone)
+      setDelivery(res.delivery)
+      setCode('')
+    } catch (err) {
+      setError(setupErrorMessage(err, 'Failed to send the code'))
+    } finally {
+      setSending(false)
+    }
+  }
+
+  const handleVerify = async () => {
+    setVerifying(true)
+    setError('')
+    try {
+      await api.setup.ownerVerify(code)
+      updateData({ ownerVerified: true })
+    } catch (err) {
+      setError(setupErrorMessage(err, 'Verification failed'))
+    } finally {
+      setVerifying(false)
+    }
+  }
+
+  return (
+    <div className="space-y-6">
+      <div>
+        <h2 className="text-lg font-semibold text-white">Owner</h2>
+        <p className="mt-1 text-sm text-zinc-400">
+          The owner has full access to the assistant from messaging channels
+        </p>
+      </div>
+
+      <div className="space-y-5">
+        <div>
+          <label className="mb-2 flex items-center gap-2 text-sm font-medium text-zinc-300">
+            <Phone className="h-3.5 w-3.5 text-zinc-500" />
+            Owner phone number
+          </label>
+          <div className="flex gap-2">
+            <input
+              type="tel"
+              value={data.ownerPhone}
+              onChange={(e) => {
+                updateData({ ownerPhone: e.target.value.replace(/\D/g, ''), ownerVerified: false })
+                setDelivery(null)
+              }}
+              placeholder="5511999999999"
+              className="flex h-11 w-full rounded-xl border border-zinc-700/50 bg-zinc-800/50 px-4 text-sm text-white placeholder:text-zinc-600 outline-none transition-all focus:border-orange-500/50 focus:ring-2 focus:ring-orange-500/10"
+            />
+            <button
+              onClick={handleSend}
+              disabled={sending || data.ownerPhone.length < 8 || data.ownerVerified}
+              className="flex shrink-0 cursor-pointer items-center gap-2 rounded-xl border border-zinc-700/50 bg-zinc-800/50 px-4 text-sm text-zinc-300 transition-all hover:border-zinc-600 hover:text-white disabled:cursor-not-allowed disabled:opacity-40"
+            >
+              {sending && <Loader2 className="h-3.5 w-3.5 animate-spin" />}
+              {delivery ? 'Resend code' : 'Send code'}
+            </button>
+          </div>
+          <p className="mt-1.5 text-xs text-zinc-500">
+            Country code + area code + number, no spaces. Leave empty to use only the web UI.
+          </p>
+        </div>
+
+        {delivery && !data.ownerVerified && (
+          <div className="rounded-xl border border-zinc-700/30 bg-zinc-800/20 p-4 space-y-3">
+            <div className="flex items-start gap-2.5">
+              <Terminal className="mt-0.5 h-4 w-4 shrink-0 text-orange-400" />
+              <p className="text-xs text-zinc-400">
+                {delivery === 'whatsapp'
+                  ? 'A 6-digit code was sent to this number on WhatsApp.'
+                  : 'A 6-digit code was printed on the server console (the terminal running devclaw serve).'}
+                {' '}It expires in 10 minutes.
+              </p>
+            </div>
+            <div className="flex gap-2">
+              <div className="relative w-full">
+                <KeyRound className="absolute left-3.5 top-1/2 h-4 w-4 -translate-y-1/2 text-zinc-500" />
+                <input
+                  value={code}
+                  onChange={(e) => setCode(e.target.value.replace(/\D/g, '').slice(0, 6))}
+                  placeholder="123456"
+                  inputMode="numeric"
+                  className="flex h-11 w-full rounded-xl border border-zinc-700/50 bg-zinc-800/50 pl-10 pr-4 text-sm tracking-widest text-white placeholder:text-zinc-600 outline-none transition-all focus:border-orange-500/50 focus:ring-2 focus:ring-orange-500/10"
+                />
+              </div>
+              <button
+                onClick={handleVerify}
+                disabled={verifying || code.length !== 6}
+                className="flex shrink-0 cursor-pointer items-center gap-2 rounded-xl bg-orange-500 px-4 text-sm font-medium text-white transition-all hover:bg-orange-400 disabled:cursor-not-allowed disabled:opacity-40"
+              >
+                {verifying && <Loader2 className="h-3.5 w-3.5 animate-spin" />}
+                Verify
+              </button>
+            </div>
+          </div>
+        )}
+
+        {data.ownerVerified && (
+          <div className="flex items-center gap-2.5 rounded-xl border border-emerald-500/20 bg-emerald-500/5 px-4 py-3">
+            <CheckCircle2 className="h-4 w-4 shrink-0 text-emerald-400" />
+            <p className="text-sm text-emerald-300">Owner verified</p>
+          </div>
+        )}
+
+        {error && (
+          <p className="text-xs text-red-400">{error}</p>
+        )}
+      </div>
+    </div>
+  )
+}
@@ -31,6 +31,13 @@ const MODES = [
   },
 ]
 
+const PRESETS = [
+  { value: 'personal' as const, label: 'Personal', description: 'Your own machine. Remote access asks first.' },
+  { value: 'family-shared' as const, label: 'Family / shared', description: 'File and shell access is owner-only; risky tools ask first.' },
+  { value: 'business-strict' as const, label: 'Business strict', description: 'Least privilege, extra command blocklist, approval on every write.' },
+  { value: 'developer-yolo' as const, label: 'Developer YOLO', description: 'No confirmations, sudo allowed. Destructive commands stay blocked.' },
+]
+
 const COLOR_MAP = {
   emerald: {
     active: 'border-emerald-500/50 bg-emerald-500/10 ring-1 ring-emerald-500/20',
@@ -207,6 +214,36 @@ export function StepSecurity({ data, updateData }: Props) {
             })}
           </div>
         </div>
+
+        {/* Tool guard preset */}
+        <div>
+          <label className="mb-3 flex items-center gap-2 text-sm font-medium text-zinc-300">
+            <ShieldCheck className="h-3.5 w-3.5 text-zinc-500" />
+            Tool guard preset
+          </label>
+          <div className="grid grid-cols-2 gap-2.5">
+            {PRESETS.map((preset) => {
+              const isActive = data.securityPreset === preset.value
+              return (
+                <button
+                  key={preset.value}
+                  onClick={() => updateData({ securityPreset: preset.value })}
+                  className={`rounded-xl border px-4 py-3 text-left transition-all ${
+                    isActive
+                      ? 'border-orange-500/50 bg-orange-500/10 ring-1 ring-orange-500/20'
+                      : 'border-zinc-700/50 bg-zinc-800/30 hover:border-zinc-600 hover:bg-zinc-800/60'
+                  }`}
+                >
+                  <span className="text-sm font-medium text-white">{preset.label}</span>
+                  <p className="mt-0.5 text-xs text-zinc-400">{preset.description}</p>
+                </button>
+              )
+            })}
+          </div>
+          <p className="mt-1.5 text-xs text-zinc-500">
+            Run <code>devclaw guard show-effective</code> to see the merged policy.
+          </p>
+        </div>
       </div>
     </div>
   )
@@ -0,0 +1,90 @@
+import { useEffect, useState, useCallback } from 'react'
+import { CheckCircle2, AlertTriangle, XCircle, Loader2, RefreshCw } from 'lucide-react'
+import { api, type SetupCheck } from '@/lib/api'
+import type { SetupData } from './SetupWizard'
+import { setupErrorMessage } from './StepOwner'
+
+interface Props {
+  data: SetupData
+  onResult: (ok: boolean) => void
+}
+
+const LEVEL_STYLE = {
+  ok: { icon: CheckCircle2, color: 'text-emerald-400' },
+  warning: { icon: AlertTriangle, color: 'text-amber-400' },
+  error: { icon: XCircle, color: 'text-red-400' },
+}
+
+/**
+ * Final step: the server checks every step and parses the config.yaml it
+ * will write. Errors block Finish; warnings do not.
+ */
+export function StepValidate({ data, onResult }: Props) {
+  const [checks, setChecks] = useState<SetupCheck[]>([])
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState('')
+
+  const run = useCallback(async () => {
+    setLoading(true)
+    setError('')
+    onResult(false)
+    try {
+      const res = await api.setup.validate(data as unknown as Record<string, unknown>)
+      setChecks(res.checks)
+      onResult(res.ok)
+    } catch (err) {
+      setError(setupErrorMessage(err, 'Validation failed'))
+    } finally {
+      setLoading(false)
+    }
+  }, [data, onResult])
+
+  useEffect(() => {
+    run()
+  }, [run])
+
+  return (
+    <div className="space-y-6">
+      <div className="flex items-start justify-between">
+        <div>
+          <h2 className="text-lg font-semibold text-white">Review</h2>
+          <p className="mt-1 text-sm text-zinc-400">
+            Check the configuration before DevClaw starts
+          </p>
+        </div>
+        <button
+          onClick={run}
+          disabled={loading}
+          aria-label="Validate again"
+          className="cursor-pointer rounded-lg p-2 text-zinc-500 transition-colors hover:text-zinc-300 disabled:opacity-40"
+        >
+          <RefreshCw className={`h-4 w-4 ${loading ? 'animate-spin' : ''}`} />
+        </button>
+      </div>
+
+      {loading && checks.length === 0 ? (
+        <div className="flex items-center gap-2 text-sm text-zinc-400">
+          <Loader2 className="h-4 w-4 animate-spin" />
+          Validating...
+        </div>
+      ) : (
+        <ul className="space-y-2">
+          {checks.map((c, i) => {
+            const { icon: Icon, color } = LEVEL_STYLE[c.level]
+            return (
+              <li key={i} className="flex items-start gap-3 rounded-xl border border-zinc-700/30 bg-zinc-800/20 px-4 py-3">
+                <Icon className={`mt-0.5 h-4 w-4 shrink-0 ${color}`} />
+                <div className="min-w-0">
+                  <span className="text-[11px] font-medium uppercase tracking-wide text-zinc-500">{c.step}</span>
+                  <p className="text-sm text-zinc-300">{c.message}</p>
+                </div>
+              </li>
+            )
+          })}
+        </ul>
+      )}
+
+      {error && <p className="text-xs text-red-400">{error}</p>}
+    </div>
+  )
+}